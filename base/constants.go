@@ -25,4 +25,10 @@ const (
 	// transaction fails to be heartbeat within 2x the heartbeat interval,
 	// it may be aborted by conflicting txns.
 	DefaultHeartbeatInterval = 5 * time.Second
+
+	// DefaultClosedTimestampTargetDuration is how far behind the present time
+	// the lease holder of a range closes timestamps. Writes at or below a
+	// closed timestamp are pushed above it, which allows followers to serve
+	// consistent reads at or below it.
+	DefaultClosedTimestampTargetDuration = 30 * time.Second
 )
@@ -53,6 +53,7 @@ type TestServerArgs struct {
 	SSLCA                 string
 	SSLCert               string
 	SSLCertKey            string
+	SQLMemoryPoolSize     int64
 
 	// If set, this will be appended to the Postgres URL by functions that
 	// automatically open a connection to the server. That's equivalent to running
@@ -595,9 +595,11 @@ func Example_sql() {
 	// x	y
 	// 42	69
 	// sql --execute=show databases
-	// 3 rows
+	// 5 rows
 	// Database
+	// crdb_internal
 	// information_schema
+	// pg_catalog
 	// system
 	// t
 	// sql -e explain select 3
@@ -836,7 +838,7 @@ Available Commands:
   node           list nodes and show their status
   dump           dump sql tables
 
-  gen            generate manpages and bash completion file
+  gen            generate manpages, bash completion file and encryption keys
   version        output version information
   debug          debugging commands
 
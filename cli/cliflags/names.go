@@ -19,9 +19,11 @@ package cliflags
 // AttrsName and others are flag names.
 const (
 	AttrsName             = "attrs"
+	LocalityName          = "locality"
 	ZoneConfigName        = "file"
 	BackgroundName        = "background"
 	CacheName             = "cache"
+	SQLMemName            = "max-sql-memory"
 	DatabaseName          = "database"
 	DepsName              = "deps"
 	ExecuteName           = "execute"
@@ -47,6 +49,10 @@ const (
 	ToName                = "to"
 	ValuesName            = "values"
 	SizesName             = "sizes"
+	EncryptionKeyName     = "encryption-key"
+	OldEncryptionKeysName = "old-encryption-keys"
+	KeyBitsName           = "bits"
 	RaftTickIntervalName  = "raft-tick-interval"
 	UndoFreezeClusterName = "undo"
+	DrainName             = "drain"
 )
@@ -23,6 +23,7 @@ import (
 	"github.com/cockroachdb/cockroach/base"
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/server"
 	"github.com/cockroachdb/cockroach/storage/engine"
 )
 
@@ -144,4 +145,5 @@ type debugContext struct {
 	startKey, endKey engine.MVCCKey
 	values           bool
 	sizes            bool
+	encryption       server.StoreEncryptionSpec
 }
@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
 
 	"golang.org/x/net/context"
@@ -70,6 +71,10 @@ func openStore(cmd *cobra.Command, dir string, stopper *stop.Stopper) (*engine.R
 	if err != nil {
 		return nil, err
 	}
+	encryption, err := debugCtx.encryption.Options()
+	if err != nil {
+		return nil, err
+	}
 	db := engine.NewRocksDB(
 		roachpb.Attributes{},
 		dir,
@@ -77,6 +82,8 @@ func openStore(cmd *cobra.Command, dir string, stopper *stop.Stopper) (*engine.R
 		10<<20,
 		0,
 		maxOpenFiles,
+		engine.RocksDBTuning{},
+		encryption,
 		stopper,
 	)
 	if err := db.Open(); err != nil {
@@ -471,7 +478,13 @@ func runDebugRaftLog(cmd *cobra.Command, args []string) error {
 		return errors.New("two arguments required: dir range_id")
 	}
 
-	db, err := openStore(cmd, args[0], stopper)
+	// The raft logs of a store which keeps them separately are in a RocksDB
+	// instance of their own.
+	dir := args[0]
+	if fi, err := os.Stat(server.RaftLogDir(dir)); err == nil && fi.IsDir() {
+		dir = server.RaftLogDir(dir)
+	}
+	db, err := openStore(cmd, dir, stopper)
 	if err != nil {
 		return err
 	}
@@ -558,7 +571,7 @@ func runDebugGCCmd(cmd *cobra.Command, args []string) error {
 		snap := db.NewSnapshot()
 		defer snap.Close()
 		_, info, err := storage.RunGC(context.Background(), &desc, snap, hlc.Timestamp{WallTime: timeutil.Now().UnixNano()},
-			config.GCPolicy{TTLSeconds: 24 * 60 * 60 /* 1 day */}, func(_ hlc.Timestamp, _ *roachpb.Transaction, _ roachpb.PushTxnType) {
+			config.GCPolicy{TTLSeconds: 24 * 60 * 60 /* 1 day */}, hlc.ZeroTimestamp, func(_ hlc.Timestamp, _ *roachpb.Transaction, _ roachpb.PushTxnType) {
 			}, func(_ []roachpb.Intent, _, _ bool) error { return nil })
 		if err != nil {
 			return err
@@ -688,7 +701,9 @@ var debugCompactCmd = &cobra.Command{
 	Use:   "compact [directory]",
 	Short: "compact the sstables in a store",
 	Long: `
-Compact the sstables in a store.
+Compact the sstables in a store, or only those spanning the keys between
+--from and --to. This reclaims the space taken by deleted data, e.g. after
+a table was dropped or ranges were rebalanced away from the store.
 `,
 	RunE: runDebugCompact,
 }
@@ -706,7 +721,10 @@ func runDebugCompact(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	return db.Compact()
+	if len(debugCtx.startKey.Key) == 0 && debugCtx.endKey.Key.Equal(roachpb.KeyMax) {
+		return db.Compact()
+	}
+	return db.CompactRange(debugCtx.startKey.Key, debugCtx.endKey.Key)
 }
 
 var debugSSTablesCmd = &cobra.Command{
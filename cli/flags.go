@@ -43,6 +43,7 @@ var connURL string
 var connUser, connHost, connPort, httpPort, httpAddr, connDBName, zoneConfig string
 var startBackground bool
 var undoFreezeCluster bool
+var quitDrain = true
 
 var serverCtx = server.MakeContext()
 var baseCtx = serverCtx.Context
@@ -71,6 +72,19 @@ nodes. For example:`) + `
   --attrs=us-west-1b:gpu
 `,
 
+	cliflags.LocalityName: wrapText(`
+An ordered, comma-separated list of key=value pairs describing the location
+of the node, from the most inclusive tier to the most specific one. The keys
+of the tiers should be the same on all the nodes. Replicas of a range are
+spread across the most diverse localities available, so that the failure of
+a single datacenter doesn't lose a quorum. The tiers are also matched by the
+constraints of the zone configs, e.g. a node started with
+--locality=region=us-east,datacenter=us-east-1 satisfies the constraint
++region=us-east. For example:`) + `
+
+  --locality=region=us-east,datacenter=us-east-1
+`,
+
 	cliflags.ZoneConfigName: wrapText(`
 File to read the zone configuration from. Specify "-" to read from standard input.`),
 
@@ -86,6 +100,14 @@ storage devices. Size suffixes are supported (e.g. 1GB and 1GiB).
 If left unspecified, defaults to 25% of the physical memory, or
 512MB if the memory size cannot be determined.`),
 
+	cliflags.SQLMemName: wrapText(`
+Maximum memory capacity available to store temporary data for SQL clients,
+including prepared queries and intermediate data rows during query
+execution. Queries exceeding it fail with an out of memory error. Size
+suffixes are supported (e.g. 1GB and 1GiB). If left unspecified, defaults
+to 25% of the physical memory, or 512MB if the memory size cannot be
+determined.`),
+
 	forClient(cliflags.HostName): wrapText(`
 Database server host to connect to.`),
 
@@ -231,6 +253,30 @@ memory that the store may consume, for example:`) + `
   --store=type=mem,size=20GiB
   --store=type=mem,size=90%
 
+` + wrapText(`
+The "key" field encrypts the files of a store with the AES key held in the given
+file, which can be generated with "cockroach gen encryption-key". To rotate the
+key, restart the node with a new key file and the previous one in the "old-key"
+field, which lists the files holding keys the existing files may still be
+encrypted with, separated by colons. The files are rewritten with the new key
+as they get compacted; the stores status endpoint reports which keys are still
+in use. Setting "key" to "plain" writes new files in plaintext, for example:`) + `
+
+  --store=path=/mnt/ssd01,key=/keys/a.key
+  --store=path=/mnt/ssd01,key=/keys/b.key,old-key=/keys/a.key
+  --store=path=/mnt/ssd01,key=plain,old-key=/keys/a.key:/keys/b.key
+
+` + wrapText(`
+The "compression", "cache", "write-buffer-size" and "max-open-files" fields
+override the RocksDB options of a store. "compression" lists the compression of
+each level, none or snappy, starting with L0; the last one applies to the
+remaining levels. "cache" gives the store a block cache of its own instead of
+sharing the one sized by --cache. "write-buffer-size" can't exceed the memtable
+budget and "max-open-files" the files available to each store, for example:`) + `
+
+  --store=path=/mnt/ssd01,compression=none:none:snappy,cache=4GiB
+  --store=path=/mnt/hda1,write-buffer-size=64MiB,max-open-files=10000
+
 ` + wrapText(`
 Commas are forbidden in all values, since they are used to separate fields.
 Also, if you use equal signs in the file path to a store, you must use the
@@ -258,12 +304,29 @@ Print values along with their associated key.`),
 	cliflags.SizesName: wrapText(`
 Print key and value sizes along with their associated key.`),
 
+	cliflags.EncryptionKeyName: wrapText(`
+The file holding the key the files of the store are encrypted with, as
+specified by the "key" field of the --store flag.`),
+
+	cliflags.OldEncryptionKeysName: wrapText(`
+The files holding the keys which files of the store may still be encrypted
+with, as specified by the "old-key" field of the --store flag.`),
+
+	cliflags.KeyBitsName: wrapText(`
+The size of the generated key in bits: 128, 192 or 256.`),
+
 	cliflags.RaftTickIntervalName: wrapText(`
 The resolution of the Raft timer; other raft timeouts are
 defined in terms of multiples of this value.`),
 
 	cliflags.UndoFreezeClusterName: wrapText(`
 Attempt to undo an earlier attempt to freeze the cluster.`),
+
+	cliflags.DrainName: wrapText(`
+Drain the node before shutting it down: stop accepting new SQL connections,
+wait for the open ones and the running distributed queries to finish, and
+transfer the range leases of the node to the other nodes. Set to false to
+shut the node down right away.`),
 }
 
 const usageIndentation = 8
@@ -411,6 +474,7 @@ func init() {
 		f.StringVar(&httpPort, cliflags.HTTPPortName, base.DefaultHTTPPort, usageNoEnv(forServer(cliflags.HTTPPortName)))
 		f.StringVar(&httpAddr, cliflags.HTTPAddrName, "", usageNoEnv(forServer(cliflags.HTTPAddrName)))
 		f.StringVar(&serverCtx.Attrs, cliflags.AttrsName, serverCtx.Attrs, usageNoEnv(cliflags.AttrsName))
+		f.StringVar(&serverCtx.Locality, cliflags.LocalityName, serverCtx.Locality, usageNoEnv(cliflags.LocalityName))
 		f.VarP(&serverCtx.Stores, cliflags.StoreName, "s", usageNoEnv(cliflags.StoreName))
 		f.DurationVar(&serverCtx.RaftTickInterval, cliflags.RaftTickIntervalName, base.DefaultRaftTickInterval, usageNoEnv(cliflags.RaftTickIntervalName))
 		f.BoolVar(&startBackground, cliflags.BackgroundName, false, usageNoEnv(cliflags.BackgroundName))
@@ -434,9 +498,10 @@ func init() {
 		f.VarP(&serverCtx.JoinList, cliflags.JoinName, "j", usageNoEnv(cliflags.JoinName))
 
 		// Engine flags.
-		setDefaultCacheSize(&serverCtx)
+		setDefaultSizeParameters(&serverCtx)
 		cacheSize = newBytesValue(&serverCtx.CacheSize)
 		f.Var(cacheSize, cliflags.CacheName, usageNoEnv(cliflags.CacheName))
+		f.Var(newBytesValue(&serverCtx.SQLMemoryPoolSize), cliflags.SQLMemName, usageNoEnv(cliflags.SQLMemName))
 	}
 
 	for _, cmd := range certCmds {
@@ -488,6 +553,10 @@ func init() {
 		f := freezeClusterCmd.PersistentFlags()
 		f.BoolVar(&undoFreezeCluster, cliflags.UndoFreezeClusterName, false, usageNoEnv(cliflags.UndoFreezeClusterName))
 	}
+	{
+		f := quitCmd.Flags()
+		f.BoolVar(&quitDrain, cliflags.DrainName, quitDrain, usageNoEnv(cliflags.DrainName))
+	}
 
 	// Commands that need the cockroach port.
 	simpleCmds := []*cobra.Command{quitCmd, freezeClusterCmd}
@@ -527,6 +596,33 @@ func init() {
 		f.BoolVar(&debugCtx.sizes, cliflags.SizesName, false, usageNoEnv(cliflags.SizesName))
 	}
 
+	{
+		f := debugCompactCmd.Flags()
+		f.Var((*mvccKey)(&debugCtx.startKey), cliflags.FromName, usageNoEnv(cliflags.FromName))
+		f.Var((*mvccKey)(&debugCtx.endKey), cliflags.ToName, usageNoEnv(cliflags.ToName))
+	}
+
+	// Encryption flags for the debug commands which open a store.
+	for _, cmd := range []*cobra.Command{
+		debugKeysCmd,
+		debugRangeDataCmd,
+		debugRangeDescriptorsCmd,
+		debugRaftLogCmd,
+		debugGCCmd,
+		debugCheckStoreCmd,
+		debugCompactCmd,
+		debugSSTablesCmd,
+	} {
+		f := cmd.Flags()
+		f.StringVar(&debugCtx.encryption.KeyFile, cliflags.EncryptionKeyName, "", usageNoEnv(cliflags.EncryptionKeyName))
+		f.StringSliceVar(&debugCtx.encryption.OldKeyFiles, cliflags.OldEncryptionKeysName, nil, usageNoEnv(cliflags.OldEncryptionKeysName))
+	}
+
+	{
+		f := genEncryptionKeyCmd.Flags()
+		f.IntVar(&encryptionKeyBits, cliflags.KeyBitsName, 256, usageNoEnv(cliflags.KeyBitsName))
+	}
+
 	{
 		f := versionCmd.Flags()
 		f.BoolVar(&versionIncludesDeps, cliflags.DepsName, false, usageNoEnv(cliflags.DepsName))
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/cockroachdb/cockroach/build"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
@@ -99,10 +100,36 @@ func runGenAutocompleteCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var encryptionKeyBits int
+
+var genEncryptionKeyCmd = &cobra.Command{
+	Use:   "encryption-key <key-file>",
+	Short: "generate a store encryption key",
+	Long: `Generate a random AES key to encrypt stores with, and write it to a new file.
+
+The key size defaults to 256 bits. Use "--bits=128" or "--bits=192" to override it.
+The file is only readable by its owner; it must be kept secret, and backed up since
+the stores encrypted with it can't be read without it.
+`,
+	RunE: runGenEncryptionKeyCmd,
+}
+
+func runGenEncryptionKeyCmd(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		mustUsage(cmd)
+		return errMissingParams
+	}
+	if err := engine.GenerateEncryptionKeyFile(args[0], encryptionKeyBits); err != nil {
+		return err
+	}
+	fmt.Printf("Generated %d-bit encryption key in %s\n", encryptionKeyBits, args[0])
+	return nil
+}
+
 var genCmd = &cobra.Command{
 	Use:   "gen [command]",
-	Short: "generate manpages and bash completion file",
-	Long:  "Generate manpages and bash completion file.",
+	Short: "generate manpages, bash completion file and encryption keys",
+	Long:  "Generate manpages, bash completion file and encryption keys.",
 	Run: func(cmd *cobra.Command, args []string) {
 		mustUsage(cmd)
 	},
@@ -111,6 +138,7 @@ var genCmd = &cobra.Command{
 var genCmds = []*cobra.Command{
 	genManCmd,
 	genAutocompleteCmd,
+	genEncryptionKeyCmd,
 }
 
 func init() {
@@ -104,13 +104,18 @@ uninitialized, specify the --join flag to point to any healthy node
 	RunE:         runStart,
 }
 
-func setDefaultCacheSize(ctx *server.Context) {
+func setDefaultSizeParameters(ctx *server.Context) {
 	if size, err := server.GetTotalMemory(); err == nil {
 		// Default the cache size to 1/4 of total memory. A larger cache size
 		// doesn't necessarily improve performance as this is memory that is
 		// dedicated to uncompressed blocks in RocksDB. A larger value here will
 		// compete with the OS buffer cache which holds compressed blocks.
 		ctx.CacheSize = size / 4
+
+		// Default the SQL memory pool size to 1/4 of total memory. Again
+		// we do not want to allow too much lest this will pressure
+		// against OS buffers and decrease overall client throughput.
+		ctx.SQLMemoryPoolSize = size / 4
 	}
 }
 
@@ -492,9 +497,12 @@ var quitCmd = &cobra.Command{
 	Use:   "quit",
 	Short: "drain and shutdown node\n",
 	Long: `
-Shutdown the server. The first stage is drain, where any new requests
-will be ignored by the server. When all extant requests have been
-completed, the server exits.
+Shutdown the server. The first stage is drain, where any new SQL
+connections are refused by the server, the open ones and the running
+distributed queries are given time to finish, and the range leases of
+the node are transferred to other nodes. When all extant requests have
+been completed, the server exits. With --drain=false, the server exits
+right away.
 `,
 	SilenceUsage: true,
 	RunE:         runQuit,
@@ -527,9 +535,11 @@ func doShutdown(c serverpb.AdminClient, ctx context.Context, onModes []int32) (s
 
 // runQuit accesses the quit shutdown path.
 func runQuit(_ *cobra.Command, _ []string) error {
-	onModes := make([]int32, len(server.GracefulDrainModes))
-	for i, m := range server.GracefulDrainModes {
-		onModes[i] = int32(m)
+	var onModes []int32
+	if quitDrain {
+		for _, m := range server.GracefulDrainModes {
+			onModes = append(onModes, int32(m))
+		}
 	}
 
 	c, stopper, err := getAdminClient()
@@ -77,7 +77,7 @@ func runLsUsers(cmd *cobra.Command, args []string) {
 	}
 	defer conn.Close()
 	err = runQueryAndFormatResults(conn, os.Stdout,
-		makeQuery(`SELECT username FROM system.users`), cliCtx.prettyFmt)
+		makeQuery(`SELECT username FROM system.users WHERE NOT isRole`), cliCtx.prettyFmt)
 	if err != nil {
 		panic(err)
 	}
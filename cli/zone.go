@@ -408,6 +408,9 @@ The zone config format has the following YAML schema:
   range_max_bytes: <size-in-bytes>
   gc:
     ttlseconds: <time-in-seconds>
+  lease_preferences:
+    - attrs: [comma-separated attribute list]
+    - attrs:  ...
 
 For example, to set the zone config for the system database, run:
 cockroach zone set system "replicas:
@@ -415,8 +418,17 @@ cockroach zone set system "replicas:
 - attrs: [us-east-1b, ssd]
 - attrs: [us-west-1b, ssd]"
 
+Each replica requires the stores holding it to have its attributes, which
+include the locality tiers of their node (e.g. region=us-east). An attribute
+prefixed with "-" is prohibited instead (e.g. -hdd). The lease of the ranges
+goes to a replica satisfying the first lease preference satisfied by any of
+the replicas.
+
 Note that the specified zone config is merged with the existing zone config for
 the database or table.
+
+The zone config can also be set from SQL, e.g.:
+ALTER TABLE db.t CONFIGURE ZONE USING num_replicas = 5, constraints = '[+ssd]'
 `,
 	SilenceUsage: true,
 	RunE:         runSetZone,
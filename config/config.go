@@ -29,6 +29,7 @@ import (
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
 )
 
@@ -40,6 +41,8 @@ const (
 
 type zoneConfigHook func(SystemConfig, uint32) (ZoneConfig, bool, error)
 
+type protectedTimestampsHook func(SystemConfig) ([]ProtectedTimestamp, error)
+
 var (
 	// defaultZoneConfig is the default zone configuration used when no custom
 	// config has been specified.
@@ -61,6 +64,10 @@ var (
 	// This is also used by testing to simplify fake configs.
 	ZoneConfigHook zoneConfigHook
 
+	// ProtectedTimestampsHook is a function used to lookup the protected
+	// timestamp records in the system.protected_ts table.
+	ProtectedTimestampsHook protectedTimestampsHook
+
 	// testingLargestIDHook is a function used to bypass GetLargestObjectID
 	// in tests.
 	testingLargestIDHook func(uint32) uint32
@@ -106,9 +113,35 @@ func (z ZoneConfig) Validate() error {
 		return fmt.Errorf("RangeMinBytes %d is greater than or equal to RangeMaxBytes %d",
 			z.RangeMinBytes, z.RangeMaxBytes)
 	}
+	for _, subzone := range z.Subzones {
+		if len(subzone.Config.Subzones) > 0 {
+			return fmt.Errorf("subzone for partition %q has subzones of its own", subzone.PartitionName)
+		}
+		if err := subzone.Config.Validate(); err != nil {
+			return errors.Wrapf(err, "subzone for partition %q", subzone.PartitionName)
+		}
+	}
+	for _, span := range z.SubzoneSpans {
+		if span.SubzoneIndex < 0 || int(span.SubzoneIndex) >= len(z.Subzones) {
+			return fmt.Errorf("subzone span %s-%s refers to unknown subzone %d",
+				span.Key, span.EndKey, span.SubzoneIndex)
+		}
+	}
 	return nil
 }
 
+// GetSubzoneForKey returns the subzone whose span contains key, or nil if the
+// key is not in the span of a partition with a subzone.
+func (z *ZoneConfig) GetSubzoneForKey(key roachpb.RKey) *Subzone {
+	i := sort.Search(len(z.SubzoneSpans), func(i int) bool {
+		return bytes.Compare(key, z.SubzoneSpans[i].EndKey) < 0
+	})
+	if i == len(z.SubzoneSpans) || bytes.Compare(key, z.SubzoneSpans[i].Key) < 0 {
+		return nil
+	}
+	return &z.Subzones[z.SubzoneSpans[i].SubzoneIndex]
+}
+
 // ObjectIDForKey returns the object ID (table or database) for 'key',
 // or (_, false) if not within the structured key space.
 func ObjectIDForKey(key roachpb.RKey) (uint32, bool) {
@@ -269,6 +302,8 @@ func (s SystemConfig) GetLargestObjectID(maxID uint32) (uint32, error) {
 }
 
 // GetZoneConfigForKey looks up the zone config for the range containing 'key'.
+// The zone config of a table is overridden by the subzone of the partition
+// containing the key, if any.
 // It is the caller's responsibility to ensure that the range does not need to be split.
 func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error) {
 	objectID, ok := ObjectIDForKey(key)
@@ -279,7 +314,14 @@ func (s SystemConfig) GetZoneConfigForKey(key roachpb.RKey) (ZoneConfig, error)
 		// For now, only user databases and tables get custom zone configs.
 		objectID = keys.RootNamespaceID
 	}
-	return s.getZoneConfigForID(objectID)
+	zone, err := s.getZoneConfigForID(objectID)
+	if err != nil {
+		return ZoneConfig{}, err
+	}
+	if subzone := zone.GetSubzoneForKey(key); subzone != nil {
+		return subzone.Config, nil
+	}
+	return zone, nil
 }
 
 // getZoneConfigForID looks up the zone config for the object (table or database)
@@ -294,9 +336,39 @@ func (s SystemConfig) getZoneConfigForID(id uint32) (ZoneConfig, error) {
 	return DefaultZoneConfig(), nil
 }
 
+// GetProtectedTimestamp returns the lowest timestamp protected from garbage
+// collection over any part of 'span', or the zero timestamp if none of it is
+// protected.
+func (s SystemConfig) GetProtectedTimestamp(span roachpb.Span) (hlc.Timestamp, error) {
+	testingLock.Lock()
+	hook := ProtectedTimestampsHook
+	testingLock.Unlock()
+	if hook == nil {
+		return hlc.ZeroTimestamp, nil
+	}
+	records, err := hook(s)
+	if err != nil {
+		return hlc.ZeroTimestamp, err
+	}
+	var ts hlc.Timestamp
+	for _, r := range records {
+		if ts != hlc.ZeroTimestamp && !r.Timestamp.Less(ts) {
+			continue
+		}
+		for _, sp := range r.Spans {
+			if sp.Overlaps(span) {
+				ts = r.Timestamp
+				break
+			}
+		}
+	}
+	return ts, nil
+}
+
 // ComputeSplitKeys takes a start and end key and returns an array of keys
 // at which to split the span [start, end).
-// The only required splits are at each user table prefix.
+// The required splits are at each user table prefix, and at the boundaries
+// of the spans of the partitions which have their own subzones.
 func (s SystemConfig) ComputeSplitKeys(startKey, endKey roachpb.RKey) []roachpb.RKey {
 	tableStart := roachpb.RKey(keys.SystemConfigTableDataMax)
 	if !tableStart.Less(endKey) {
@@ -305,6 +377,12 @@ func (s SystemConfig) ComputeSplitKeys(startKey, endKey roachpb.RKey) []roachpb.
 	}
 
 	startID, ok := ObjectIDForKey(startKey)
+	// The first user table whose partitions may require splits is the one
+	// containing startKey, if any.
+	firstUserID := startID
+	if !ok || firstUserID <= keys.MaxReservedDescID {
+		firstUserID = keys.MaxReservedDescID + 1
+	}
 	if !ok || startID <= keys.MaxSystemConfigDescID {
 		// The start key is either:
 		// - not part of the structured data span
@@ -363,9 +441,47 @@ func (s SystemConfig) ComputeSplitKeys(startKey, endKey roachpb.RKey) []roachpb.
 	}
 	appendSplitKeys(startID, endID)
 
+	// Append the boundaries of the subzone spans of the user tables within
+	// the span.
+	numTableSplits := len(splitKeys)
+	for id := firstUserID; id <= endID; id++ {
+		if !roachpb.RKey(keys.MakeTablePrefix(id)).Less(endKey) {
+			break
+		}
+		zone, err := s.getZoneConfigForID(id)
+		if err != nil {
+			log.Errorf(context.TODO(), "unable to look up zone config for object %d: %s", id, err)
+			return nil
+		}
+		for _, span := range zone.SubzoneSpans {
+			for _, k := range []roachpb.Key{span.Key, span.EndKey} {
+				if key := roachpb.RKey(k); startKey.Less(key) && key.Less(endKey) {
+					splitKeys = append(splitKeys, key)
+				}
+			}
+		}
+	}
+	if len(splitKeys) > numTableSplits {
+		sort.Sort(rKeySlice(splitKeys))
+		deduped := splitKeys[:1]
+		for _, key := range splitKeys[1:] {
+			if !key.Equal(deduped[len(deduped)-1]) {
+				deduped = append(deduped, key)
+			}
+		}
+		splitKeys = deduped
+	}
+
 	return splitKeys
 }
 
+// rKeySlice implements sort.Interface for a slice of keys.
+type rKeySlice []roachpb.RKey
+
+func (s rKeySlice) Len() int           { return len(s) }
+func (s rKeySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s rKeySlice) Less(i, j int) bool { return s[i].Less(s[j]) }
+
 // NeedsSplit returns whether the range [startKey, endKey) needs a split due
 // to zone configs.
 func (s SystemConfig) NeedsSplit(startKey, endKey roachpb.RKey) bool {
@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/testutils"
 	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/stop"
 )
 
 func plainKV(k, v string) roachpb.KeyValue {
@@ -369,3 +370,74 @@ func TestZoneConfigValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestSubzones(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	config.TestingSetupZoneConfigHook(stopper)
+
+	const id = keys.MaxReservedDescID + 1
+	indexPrefix := encoding.EncodeUvarintAscending(keys.MakeTablePrefix(id), 1)
+	key := func(v int64) roachpb.Key {
+		return encoding.EncodeVarintAscending(append([]byte(nil), indexPrefix...), v)
+	}
+
+	subzoneConfig := config.DefaultZoneConfig()
+	subzoneConfig.RangeMaxBytes *= 2
+	zone := config.DefaultZoneConfig()
+	zone.Subzones = []config.Subzone{{IndexID: 1, PartitionName: "p", Config: subzoneConfig}}
+	zone.SubzoneSpans = []config.SubzoneSpan{
+		{Key: key(10), EndKey: key(20), SubzoneIndex: 0},
+		{Key: key(30), EndKey: key(40), SubzoneIndex: 0},
+	}
+	if err := zone.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	config.TestingSetZoneConfig(id, zone)
+
+	cfg := config.SystemConfig{}
+	for _, tc := range []struct {
+		key      roachpb.Key
+		maxBytes int64
+	}{
+		{key(5), zone.RangeMaxBytes},
+		{key(10), subzoneConfig.RangeMaxBytes},
+		{key(15), subzoneConfig.RangeMaxBytes},
+		{key(20), zone.RangeMaxBytes},
+		{key(35), subzoneConfig.RangeMaxBytes},
+		{key(40), zone.RangeMaxBytes},
+	} {
+		z, err := cfg.GetZoneConfigForKey(roachpb.RKey(tc.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if z.RangeMaxBytes != tc.maxBytes {
+			t.Errorf("%s: expected range max bytes %d, got %d", tc.key, tc.maxBytes, z.RangeMaxBytes)
+		}
+	}
+
+	tableSplit := keys.MakeRowSentinelKey(keys.MakeTablePrefix(id))
+	for i, tc := range []struct {
+		start, end roachpb.Key
+		splits     []roachpb.Key
+	}{
+		{roachpb.KeyMin, roachpb.KeyMax, []roachpb.Key{tableSplit, key(10), key(20), key(30), key(40)}},
+		{tableSplit, roachpb.KeyMax, []roachpb.Key{key(10), key(20), key(30), key(40)}},
+		{key(15), key(35), []roachpb.Key{key(20), key(30)}},
+		{key(20), key(30), nil},
+	} {
+		var splits []roachpb.Key
+		for _, k := range cfg.ComputeSplitKeys(roachpb.RKey(tc.start), roachpb.RKey(tc.end)) {
+			splits = append(splits, k.AsRawKey())
+		}
+		if !reflect.DeepEqual(splits, tc.splits) {
+			t.Errorf("%d: expected splits %v, got %v", i, tc.splits, splits)
+		}
+	}
+
+	zone.SubzoneSpans[1].SubzoneIndex = 1
+	if err := zone.Validate(); !testutils.IsError(err, "refers to unknown subzone 1") {
+		t.Errorf("expected invalid subzone index error, got %v", err)
+	}
+}
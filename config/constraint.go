@@ -0,0 +1,121 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+)
+
+// ConstraintType is the kind of a replica placement constraint.
+type ConstraintType int
+
+const (
+	// ConstraintRequired requires the store holding the replica to have the
+	// attribute. It is written "+attr", or just "attr" as in the zone configs
+	// which predate the prohibited constraints.
+	ConstraintRequired ConstraintType = iota
+	// ConstraintProhibited requires the store holding the replica not to have
+	// the attribute. It is written "-attr".
+	ConstraintProhibited
+)
+
+// Constraint is a replica placement constraint on an attribute of the
+// stores, e.g. "+ssd" or "-region=us-west". The attributes of a store are
+// its own attributes, the attributes of its node and the tiers of the
+// locality of its node, formatted as "key=value".
+//
+// The constraints of a replica are stored in the attributes of the replica
+// in the zone config: required attributes as is, and prohibited attributes
+// prefixed with "-".
+type Constraint struct {
+	Type  ConstraintType
+	Value string
+}
+
+// ParseConstraint parses a constraint formatted as "+attr", "-attr" or
+// "attr".
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	switch {
+	case strings.HasPrefix(s, "+"):
+		c = Constraint{Type: ConstraintRequired, Value: s[1:]}
+	case strings.HasPrefix(s, "-"):
+		c = Constraint{Type: ConstraintProhibited, Value: s[1:]}
+	default:
+		c = Constraint{Type: ConstraintRequired, Value: s}
+	}
+	if c.Value == "" || strings.ContainsAny(c.Value[:1], "+-") {
+		return Constraint{}, errors.Errorf("invalid constraint %q", s)
+	}
+	return c, nil
+}
+
+// String formats the constraint as "+attr" or "-attr".
+func (c Constraint) String() string {
+	if c.Type == ConstraintProhibited {
+		return "-" + c.Value
+	}
+	return "+" + c.Value
+}
+
+// attr returns the representation of the constraint in the attributes of a
+// replica in the zone config.
+func (c Constraint) attr() string {
+	if c.Type == ConstraintProhibited {
+		return "-" + c.Value
+	}
+	return c.Value
+}
+
+// MakeReplicaConstraints returns the attributes of a replica in the zone
+// config holding the constraints.
+func MakeReplicaConstraints(constraints []Constraint) roachpb.Attributes {
+	var a roachpb.Attributes
+	for _, c := range constraints {
+		a.Attrs = append(a.Attrs, c.attr())
+	}
+	return a
+}
+
+// ReplicaConstraints returns the constraints held by the attributes of a
+// replica in the zone config. Invalid constraints are ignored.
+func ReplicaConstraints(replicaAttrs roachpb.Attributes) []Constraint {
+	constraints := make([]Constraint, 0, len(replicaAttrs.Attrs))
+	for _, attr := range replicaAttrs.Attrs {
+		if c, err := ParseConstraint(attr); err == nil {
+			constraints = append(constraints, c)
+		}
+	}
+	return constraints
+}
+
+// ConstraintsMatch returns whether the attributes of a store satisfy all the
+// constraints held by the attributes of a replica in the zone config.
+func ConstraintsMatch(replicaAttrs roachpb.Attributes, storeAttrs roachpb.Attributes) bool {
+	has := make(map[string]struct{}, len(storeAttrs.Attrs))
+	for _, attr := range storeAttrs.Attrs {
+		has[attr] = struct{}{}
+	}
+	for _, c := range ReplicaConstraints(replicaAttrs) {
+		if _, ok := has[c.Value]; ok != (c.Type == ConstraintRequired) {
+			return false
+		}
+	}
+	return true
+}
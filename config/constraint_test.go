@@ -0,0 +1,92 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package config_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestParseConstraint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		s        string
+		expected config.Constraint
+		str      string
+	}{
+		{"+ssd", config.Constraint{Type: config.ConstraintRequired, Value: "ssd"}, "+ssd"},
+		{"ssd", config.Constraint{Type: config.ConstraintRequired, Value: "ssd"}, "+ssd"},
+		{"-region=us-west", config.Constraint{Type: config.ConstraintProhibited, Value: "region=us-west"}, "-region=us-west"},
+	} {
+		c, err := config.ParseConstraint(tc.s)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.s, err)
+		}
+		if c != tc.expected {
+			t.Errorf("%s: expected %+v, got %+v", tc.s, tc.expected, c)
+		}
+		if s := c.String(); s != tc.str {
+			t.Errorf("%s: expected %s, got %s", tc.s, tc.str, s)
+		}
+	}
+
+	for _, s := range []string{"", "+", "-", "+-ssd", "--ssd"} {
+		if _, err := config.ParseConstraint(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestConstraintsMatch(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	constraints := config.MakeReplicaConstraints([]config.Constraint{
+		{Type: config.ConstraintRequired, Value: "ssd"},
+		{Type: config.ConstraintProhibited, Value: "region=us-west"},
+	})
+	if e := []string{"ssd", "-region=us-west"}; !reflect.DeepEqual(constraints.Attrs, e) {
+		t.Fatalf("expected %v, got %v", e, constraints.Attrs)
+	}
+
+	for _, tc := range []struct {
+		attrs    []string
+		expected bool
+	}{
+		{[]string{"ssd"}, true},
+		{[]string{"region=us-east", "ssd"}, true},
+		{[]string{"region=us-west", "ssd"}, false},
+		{[]string{"region=us-east", "hdd"}, false},
+		{nil, false},
+	} {
+		if m := config.ConstraintsMatch(constraints, roachpb.Attributes{Attrs: tc.attrs}); m != tc.expected {
+			t.Errorf("%v: expected %t, got %t", tc.attrs, tc.expected, m)
+		}
+	}
+
+	// The zone configs predating the prohibited constraints hold required
+	// attributes.
+	legacy := roachpb.Attributes{Attrs: []string{"us-east", "ssd"}}
+	if !config.ConstraintsMatch(legacy, roachpb.Attributes{Attrs: []string{"us-east", "ssd", "x"}}) {
+		t.Error("expected the store to match the legacy constraints")
+	}
+	if config.ConstraintsMatch(legacy, roachpb.Attributes{Attrs: []string{"us-east"}}) {
+		t.Error("expected the store not to match the legacy constraints")
+	}
+}
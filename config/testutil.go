@@ -89,3 +89,21 @@ func testingZoneConfigHook(_ SystemConfig, id uint32) (ZoneConfig, bool, error)
 	}
 	return ZoneConfig{}, false, nil
 }
+
+// TestingSetProtectedTimestamps is a testing-only function that replaces the
+// protected timestamps hook with one returning 'records' and returns a
+// function that reverts the change.
+func TestingSetProtectedTimestamps(records ...ProtectedTimestamp) func() {
+	testingLock.Lock()
+	oldHook := ProtectedTimestampsHook
+	ProtectedTimestampsHook = func(SystemConfig) ([]ProtectedTimestamp, error) {
+		return records, nil
+	}
+	testingLock.Unlock()
+
+	return func() {
+		testingLock.Lock()
+		ProtectedTimestampsHook = oldHook
+		testingLock.Unlock()
+	}
+}
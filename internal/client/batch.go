@@ -245,6 +245,8 @@ func (b *Batch) fillResults() error {
 			case *roachpb.AdminMergeRequest:
 			case *roachpb.AdminSplitRequest:
 			case *roachpb.AdminTransferLeaseRequest:
+			case *roachpb.AdminChangeReplicasRequest:
+			case *roachpb.AdminScatterRequest:
 			case *roachpb.HeartbeatTxnRequest:
 			case *roachpb.GCRequest:
 			case *roachpb.PushTxnRequest:
@@ -256,6 +258,7 @@ func (b *Batch) fillResults() error {
 			case *roachpb.RequestLeaseRequest:
 			case *roachpb.CheckConsistencyRequest:
 			case *roachpb.ChangeFrozenRequest:
+			case *roachpb.ClearRangeRequest:
 			}
 			// Fill up the resume span.
 			if result.Err == nil && reply != nil && reply.Header().ResumeSpan != nil {
@@ -548,6 +551,65 @@ func (b *Batch) DelRange(s, e interface{}, returnKeys bool) {
 	b.initResult(1, 0, notRaw, nil)
 }
 
+// DelRangeUsingTombstone deletes the rows between begin (inclusive) and end
+// (exclusive) by writing MVCC range tombstones, whose size doesn't depend on
+// the number of rows. It can't be used in a transaction, and isn't atomic
+// when the span covers several ranges.
+//
+// A new result will be appended to the batch which will contain 0 rows and
+// Result.Err will indicate success or failure.
+//
+// key can be either a byte slice or a string.
+func (b *Batch) DelRangeUsingTombstone(s, e interface{}) {
+	begin, err := marshalKey(s)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	end, err := marshalKey(e)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	b.appendReqs(&roachpb.DeleteRangeRequest{
+		Span: roachpb.Span{
+			Key:    begin,
+			EndKey: end,
+		},
+		UseRangeTombstone: true,
+	})
+	b.initResult(1, 0, notRaw, nil)
+}
+
+// ClearRange removes all the data between begin (inclusive) and end
+// (exclusive), including all the versions of the keys, directly from the
+// storage engines, bypassing MVCC. It can't be used in a transaction, and
+// must only be used on spans which are no longer read or written.
+//
+// A new result will be appended to the batch which will contain 0 rows and
+// Result.Err will indicate success or failure.
+//
+// key can be either a byte slice or a string.
+func (b *Batch) ClearRange(s, e interface{}) {
+	begin, err := marshalKey(s)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	end, err := marshalKey(e)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	b.appendReqs(&roachpb.ClearRangeRequest{
+		Span: roachpb.Span{
+			Key:    begin,
+			EndKey: end,
+		},
+	})
+	b.initResult(1, 0, notRaw, nil)
+}
+
 // adminMerge is only exported on DB. It is here for symmetry with the
 // other operations.
 func (b *Batch) adminMerge(key interface{}) {
@@ -600,3 +662,41 @@ func (b *Batch) adminTransferLease(key interface{}, target roachpb.StoreID) {
 	b.appendReqs(req)
 	b.initResult(1, 0, notRaw, nil)
 }
+
+// adminChangeReplicas is only exported on DB. It is here for symmetry with
+// the other operations.
+func (b *Batch) adminChangeReplicas(
+	key interface{}, changeType roachpb.ReplicaChangeType, targets []roachpb.ReplicaDescriptor,
+) {
+	k, err := marshalKey(key)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	req := &roachpb.AdminChangeReplicasRequest{
+		Span: roachpb.Span{
+			Key: k,
+		},
+		ChangeType: changeType,
+		Targets:    targets,
+	}
+	b.appendReqs(req)
+	b.initResult(1, 0, notRaw, nil)
+}
+
+// adminScatter is only exported on DB. It is here for symmetry with the
+// other operations.
+func (b *Batch) adminScatter(key interface{}) {
+	k, err := marshalKey(key)
+	if err != nil {
+		b.initResult(0, 0, notRaw, err)
+		return
+	}
+	req := &roachpb.AdminScatterRequest{
+		Span: roachpb.Span{
+			Key: k,
+		},
+	}
+	b.appendReqs(req)
+	b.initResult(1, 0, notRaw, nil)
+}
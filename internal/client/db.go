@@ -351,6 +351,29 @@ func (db *DB) DelRange(begin, end interface{}) error {
 	return err
 }
 
+// DelRangeUsingTombstone deletes the rows between begin (inclusive) and end
+// (exclusive) by writing MVCC range tombstones. See
+// Batch.DelRangeUsingTombstone.
+//
+// key can be either a byte slice or a string.
+func (db *DB) DelRangeUsingTombstone(begin, end interface{}) error {
+	b := &Batch{}
+	b.DelRangeUsingTombstone(begin, end)
+	_, err := runOneResult(db, b)
+	return err
+}
+
+// ClearRange removes all the data between begin (inclusive) and end
+// (exclusive), bypassing MVCC. See Batch.ClearRange.
+//
+// key can be either a byte slice or a string.
+func (db *DB) ClearRange(begin, end interface{}) error {
+	b := &Batch{}
+	b.ClearRange(begin, end)
+	_, err := runOneResult(db, b)
+	return err
+}
+
 // AdminMerge merges the range containing key and the subsequent
 // range. After the merge operation is complete, the range containing
 // key will contain all of the key/value pairs of the subsequent range
@@ -386,6 +409,31 @@ func (db *DB) AdminTransferLease(key interface{}, target roachpb.StoreID) error
 	return err
 }
 
+// AdminChangeReplicas adds or removes the replicas on the target stores of
+// the range containing key. Replicas are added or removed one at a time, and
+// an error is returned as soon as one of the changes fails.
+//
+// key can be either a byte slice or a string.
+func (db *DB) AdminChangeReplicas(
+	key interface{}, changeType roachpb.ReplicaChangeType, targets []roachpb.ReplicaDescriptor,
+) error {
+	b := &Batch{}
+	b.adminChangeReplicas(key, changeType, targets)
+	_, err := runOneResult(db, b)
+	return err
+}
+
+// AdminScatter moves the replicas and the lease of the range containing key
+// to randomly chosen stores which satisfy the zone config of the range.
+//
+// key can be either a byte slice or a string.
+func (db *DB) AdminScatter(key interface{}) error {
+	b := &Batch{}
+	b.adminScatter(key)
+	_, err := runOneResult(db, b)
+	return err
+}
+
 // CheckConsistency runs a consistency check on all the ranges containing
 // the key span. It logs a diff of all the keys that are inconsistent
 // when withDiff is set to true.
@@ -306,6 +306,55 @@ func TestTxn_Commit(t *testing.T) {
 	checkResults(t, expected, b.Results)
 }
 
+func TestTxn_RollbackToSavepoint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, db := setup(t)
+	defer s.Stopper().Stop()
+
+	if err := db.Put("aa", "1"); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Txn(func(txn *client.Txn) error {
+		if err := txn.Put("ab", "2"); err != nil {
+			return err
+		}
+		sp1 := txn.SetSavepoint()
+		if err := txn.Put("aa", "3"); err != nil {
+			return err
+		}
+		sp2 := txn.SetSavepoint()
+		if err := txn.Put("ac", "4"); err != nil {
+			return err
+		}
+		if err := txn.RollbackToSavepoint(sp2); err != nil {
+			return err
+		}
+		txn.ReleaseSavepoint()
+		if err := txn.DelRange("a", "b"); err != nil {
+			return err
+		}
+		if err := txn.RollbackToSavepoint(sp1); err != nil {
+			return err
+		}
+		txn.ReleaseSavepoint()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.Scan("a", "b", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]byte{
+		"aa": []byte("1"),
+		"ab": []byte("2"),
+	}
+	checkLen(t, len(expected), len(rows))
+	checkRows(t, expected, rows)
+}
+
 func TestDB_Put_insecure(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	s, _, db := serverutils.StartServer(t, base.TestServerArgs{Insecure: true})
@@ -364,6 +413,8 @@ func TestCommonMethods(t *testing.T) {
 		key{dbType, "AdminMerge"}:              {},
 		key{dbType, "AdminSplit"}:              {},
 		key{dbType, "AdminTransferLease"}:      {},
+		key{dbType, "AdminChangeReplicas"}:     {},
+		key{dbType, "AdminScatter"}:            {},
 		key{dbType, "CheckConsistency"}:        {},
 		key{dbType, "Run"}:                     {},
 		key{dbType, "Txn"}:                     {},
@@ -49,7 +49,13 @@ func (ts *txnSender) Send(ctx context.Context, ba roachpb.BatchRequest) (*roachp
 		ba.UserPriority = ts.UserPriority
 	}
 
-	br, pErr := ts.wrapped.Send(ts.Context, ba)
+	ctx = ts.Context
+	if _, ok := ba.GetArg(roachpb.EndTransaction); ok {
+		// Committing or aborting the txn is not interrupted by the
+		// cancellation of the statement doing it.
+		ctx = TxnContext(ctx)
+	}
+	br, pErr := ts.wrapped.Send(ctx, ba)
 	if br != nil && br.Error != nil {
 		panic(roachpb.ErrorUnexpectedlySet(ts.wrapped, br))
 	}
@@ -89,6 +95,29 @@ func (ts *txnSender) Send(ctx context.Context, ba roachpb.BatchRequest) (*roachp
 	return nil, pErr
 }
 
+// txnContextKey is the key under which the contexts returned by
+// WithStatementCancel hold the context of their txn.
+type txnContextKey struct{}
+
+// WithStatementCancel returns a copy of the context of a txn which can be
+// canceled to interrupt the requests of a single statement of the txn. Unlike
+// the cancellation of the txn's context, it neither makes the coordinator
+// abandon the txn nor prevents the txn from being committed or aborted.
+func WithStatementCancel(txnCtx context.Context) (context.Context, context.CancelFunc) {
+	txnCtx = TxnContext(txnCtx)
+	ctx, cancel := context.WithCancel(txnCtx)
+	return context.WithValue(ctx, txnContextKey{}, txnCtx), cancel
+}
+
+// TxnContext returns the context of the txn that ctx was derived from by
+// WithStatementCancel, or ctx itself.
+func TxnContext(ctx context.Context) context.Context {
+	if txnCtx, ok := ctx.Value(txnContextKey{}).(context.Context); ok {
+		return txnCtx
+	}
+	return ctx
+}
+
 // Txn is an in-progress distributed database transaction. A Txn is not safe for
 // concurrent use by multiple goroutines.
 type Txn struct {
@@ -106,8 +135,29 @@ type Txn struct {
 	deadline *hlc.Timestamp
 	// see IsFinalized()
 	finalized bool
+	// savepoints is the number of active savepoints. While there are any, the
+	// state of the keys written by the txn prior to each write is recorded in
+	// undoLog, so that the writes performed since a savepoint can be undone.
+	savepoints int
+	undoLog    []undoEntry
+	// batchCount is the number of batches sent to the KV layer by the txn.
+	batchCount int
+	// commitTriggers are run once the txn commits.
+	commitTriggers []func()
 }
 
+// undoEntry records the state of a key, or of a span of keys if endKey is
+// set, prior to a write.
+type undoEntry struct {
+	key, endKey roachpb.Key
+	// rows are the prior key/values. For a single key, rows contains one
+	// KeyValue whose Value is nil if the key was not present.
+	rows []KeyValue
+}
+
+// SavepointToken identifies a savepoint set on a Txn.
+type SavepointToken int
+
 // NewTxn returns a new txn.
 func NewTxn(ctx context.Context, db DB) *Txn {
 	txn := &Txn{
@@ -129,6 +179,12 @@ func (txn *Txn) IsFinalized() bool {
 	return txn.finalized
 }
 
+// BatchCount returns the number of batches the transaction has sent to the KV
+// layer. It is used to attribute the KV requests of a query to its parts.
+func (txn *Txn) BatchCount() int {
+	return txn.batchCount
+}
+
 // SetDebugName sets the debug name associated with the transaction which will
 // appear in log files and the web UI. Each transaction starts out with an
 // automatically assigned debug name composed of the file and line number where
@@ -203,6 +259,14 @@ func (txn *Txn) SystemConfigTrigger() bool {
 	return txn.systemConfigTrigger
 }
 
+// AddCommitTrigger adds a closure to be run once the transaction commits,
+// right after the commit is acknowledged and before the call which committed
+// the transaction returns. The closures registered by an attempt of the
+// transaction which restarts or aborts are discarded.
+func (txn *Txn) AddCommitTrigger(trigger func()) {
+	txn.commitTriggers = append(txn.commitTriggers, trigger)
+}
+
 // NewBatch creates and returns a new empty batch object for use with the Txn.
 func (txn *Txn) NewBatch() *Batch {
 	return &Batch{txn: txn}
@@ -442,11 +506,109 @@ func (txn *Txn) GetDeadline() *hlc.Timestamp {
 // The txn's status is set to ABORTED in case of error. txn is
 // considered finalized and cannot be used to send any more commands.
 func (txn *Txn) Rollback() error {
+	txn.commitTriggers = nil
 	err := txn.sendEndTxnReq(false /* commit */, nil)
 	txn.finalized = true
 	return err
 }
 
+// SetSavepoint sets a savepoint, which the writes performed by the txn
+// afterwards can be rolled back to. Until the savepoint is released, every
+// write is preceded by a read of the keys it overwrites.
+func (txn *Txn) SetSavepoint() SavepointToken {
+	txn.savepoints++
+	return SavepointToken(len(txn.undoLog))
+}
+
+// ReleaseSavepoint releases a savepoint. The writes performed since the
+// savepoint are kept, and can still be rolled back to an enclosing
+// savepoint.
+func (txn *Txn) ReleaseSavepoint() {
+	if txn.savepoints == 0 {
+		panic("no savepoint to release")
+	}
+	txn.savepoints--
+	if txn.savepoints == 0 {
+		txn.undoLog = nil
+	}
+}
+
+// ClearSavepoints releases all the savepoints of the txn.
+func (txn *Txn) ClearSavepoints() {
+	txn.savepoints = 0
+	txn.undoLog = nil
+}
+
+// RollbackToSavepoint undoes the writes performed since the savepoint
+// identified by token was set, by writing back the prior state of the keys
+// they modified. The savepoint remains set.
+func (txn *Txn) RollbackToSavepoint(token SavepointToken) error {
+	if txn.savepoints == 0 || int(token) > len(txn.undoLog) {
+		return errors.Errorf("invalid savepoint %d", token)
+	}
+	// Stop recording while the undo writes are performed. They are sent one
+	// entry at a time, in reverse order, so that the writes to the same key
+	// are undone in the right order.
+	savepoints := txn.savepoints
+	txn.savepoints = 0
+	defer func() { txn.savepoints = savepoints }()
+	for i := len(txn.undoLog) - 1; i >= int(token); i-- {
+		entry := txn.undoLog[i]
+		b := txn.NewBatch()
+		if entry.endKey != nil {
+			b.DelRange(entry.key, entry.endKey, false)
+		}
+		for _, kv := range entry.rows {
+			if kv.Value == nil {
+				b.Del(kv.Key)
+			} else {
+				b.Put(kv.Key, &roachpb.Value{RawBytes: kv.Value.RawBytes})
+			}
+		}
+		if err := txn.Run(b); err != nil {
+			return err
+		}
+		txn.undoLog = txn.undoLog[:i]
+	}
+	return nil
+}
+
+// recordUndo reads the state of the keys written by ba and appends it to the
+// undo log.
+func (txn *Txn) recordUndo(ba roachpb.BatchRequest) *roachpb.Error {
+	var entries []undoEntry
+	b := txn.NewBatch()
+	for _, ru := range ba.Requests {
+		args := ru.GetInner()
+		if !roachpb.IsTransactionWrite(args) {
+			continue
+		}
+		h := args.Header()
+		switch args.(type) {
+		case *roachpb.PutRequest, *roachpb.ConditionalPutRequest, *roachpb.InitPutRequest,
+			*roachpb.IncrementRequest, *roachpb.DeleteRequest:
+			b.Get(h.Key)
+			entries = append(entries, undoEntry{key: h.Key})
+		case *roachpb.DeleteRangeRequest:
+			b.Scan(h.Key, h.EndKey)
+			entries = append(entries, undoEntry{key: h.Key, endKey: h.EndKey})
+		default:
+			return roachpb.NewErrorf("%s is not supported while a savepoint is set", args.Method())
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := txn.Run(b); err != nil {
+		return roachpb.NewError(err)
+	}
+	for i := range entries {
+		entries[i].rows = b.Results[i].Rows
+	}
+	txn.undoLog = append(txn.undoLog, entries...)
+	return nil
+}
+
 func (txn *Txn) sendEndTxnReq(commit bool, deadline *hlc.Timestamp) error {
 	var ba roachpb.BatchRequest
 	ba.Add(endTxnReq(commit, deadline, txn.SystemConfigTrigger()))
@@ -619,6 +781,12 @@ func (txn *Txn) send(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.
 		return nil, nil
 	}
 
+	if txn.savepoints > 0 {
+		if pErr := txn.recordUndo(ba); pErr != nil {
+			return nil, pErr
+		}
+	}
+
 	// firstWriteIndex is set to the index of the first command which is
 	// a transactional write. If != -1, this indicates an intention to
 	// write. This is in contrast to txn.Proto.Writing, which is set by
@@ -647,7 +815,10 @@ func (txn *Txn) send(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.
 	elideEndTxn := haveEndTxn && !needEndTxn
 
 	// If we're not yet writing in this txn, but intend to, insert a
-	// begin transaction request before the first write command.
+	// begin transaction request before the first write command. The
+	// transaction record is anchored at the key of that write rather than
+	// at any key read before, which co-locates the record with the writes
+	// and lets transactions writing to a single range commit in one phase.
 	if needBeginTxn {
 		// If the transaction already has a key (we're in a restart), make
 		// sure we set the key in the begin transaction request to the original.
@@ -672,6 +843,9 @@ func (txn *Txn) send(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.
 		ba.Requests = ba.Requests[:lastIndex]
 	}
 
+	if len(ba.Requests) > 0 {
+		txn.batchCount++
+	}
 	br, pErr := txn.db.send(ba)
 	if elideEndTxn && pErr == nil {
 		// Check that read only transactions do not violate their deadline. This can NOT
@@ -711,5 +885,15 @@ func (txn *Txn) send(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.
 			}
 		}
 	}
+
+	if pErr == nil && txn.Proto.Status == roachpb.COMMITTED {
+		triggers := txn.commitTriggers
+		txn.commitTriggers = nil
+		for _, trigger := range triggers {
+			trigger()
+		}
+	} else if pErr != nil && pErr.TransactionRestart != roachpb.TransactionRestart_NONE {
+		txn.commitTriggers = nil
+	}
 	return br, pErr
 }
@@ -327,6 +327,34 @@ func TestTxnInsertBeginTransaction(t *testing.T) {
 	}
 }
 
+// TestTxnAnchorKeyIsFirstWrite verifies that the transaction record is
+// anchored at the key of the first write, and not at the first key read.
+func TestTxnAnchorKeyIsFirstWrite(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var anchor roachpb.Key
+	db := NewDB(newTestSender(func(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.Error) {
+		if bt, ok := ba.GetArg(roachpb.BeginTransaction); ok {
+			anchor = bt.Header().Key
+		}
+		return ba.CreateReply(), nil
+	}, nil))
+	if err := db.Txn(func(txn *Txn) error {
+		if _, err := txn.Get("a"); err != nil {
+			return err
+		}
+		b := txn.NewBatch()
+		b.Get("b")
+		b.Put("c", "value")
+		b.Put("d", "value")
+		return txn.Run(b)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !anchor.Equal(roachpb.Key("c")) {
+		t.Errorf("expected transaction to be anchored at %q, got %q", "c", anchor)
+	}
+}
+
 // TestBeginTransactionErrorIndex verifies that the error index is cleared
 // when a BeginTransaction command causes an error.
 func TestBeginTransactionErrorIndex(t *testing.T) {
@@ -374,6 +402,62 @@ func TestCommitTransactionOnce(t *testing.T) {
 	}
 }
 
+// TestCommitTriggers verifies that the commit triggers run once the
+// transaction commits, and that the triggers registered by an attempt which
+// restarts or aborts are discarded.
+func TestCommitTriggers(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	count := 0
+	dbCtx := DefaultDBContext()
+	dbCtx.TxnRetryOptions.InitialBackoff = 1 * time.Millisecond
+	db := NewDBWithContext(newTestSender(
+		func(ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.Error) {
+			if _, ok := ba.GetArg(roachpb.Put); ok {
+				count++
+				if count == 1 {
+					return nil, roachpb.NewErrorWithTxn(&roachpb.TransactionRetryError{}, ba.Txn)
+				}
+			}
+			return ba.CreateReply(), nil
+		}, nil), dbCtx)
+
+	var triggered []int
+	if err := db.Txn(func(txn *Txn) error {
+		attempt := count + 1
+		txn.AddCommitTrigger(func() {
+			triggered = append(triggered, attempt)
+		})
+		if err := txn.Put("a", "b"); err != nil {
+			return err
+		}
+		if len(triggered) != 0 {
+			t.Errorf("expected no trigger to run before the commit, got %v", triggered)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(triggered, []int{2}) {
+		t.Errorf("expected the trigger of the second attempt to run, got %v", triggered)
+	}
+
+	triggered = nil
+	if err := db.Txn(func(txn *Txn) error {
+		txn.AddCommitTrigger(func() {
+			triggered = append(triggered, 0)
+		})
+		if err := txn.Put("a", "b"); err != nil {
+			return err
+		}
+		return errors.New("foo")
+	}); !testutils.IsError(err, "foo") {
+		t.Fatalf("expected the transaction to abort, got %v", err)
+	}
+	if len(triggered) != 0 {
+		t.Errorf("expected no trigger to run on abort, got %v", triggered)
+	}
+}
+
 // TestAbortReadOnlyTransaction verifies that aborting a read-only
 // transaction does not prompt an EndTransaction call.
 func TestAbortReadOnlyTransaction(t *testing.T) {
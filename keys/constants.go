@@ -149,6 +149,13 @@ var (
 	// (storage/engine/rocksdb/db.cc).
 	localTransactionSuffix = roachpb.RKey("txn-")
 
+	// LocalRangeTombstonePrefix is the prefix for the MVCC range tombstones.
+	// The start key of the span covered by a tombstone is appended to this
+	// prefix, unencoded, and the key is addressed to it like a range-local
+	// key.
+	LocalRangeTombstonePrefix = roachpb.Key(makeKey(localPrefix, roachpb.RKey("t")))
+	LocalRangeTombstoneMax    = LocalRangeTombstonePrefix.PrefixEnd()
+
 	// Meta1Prefix is the first level of key addressing. It is selected such that
 	// all range addressing records sort before any system tables which they
 	// might describe. The value is a RangeDescriptor struct.
@@ -173,6 +180,14 @@ var (
 	SystemPrefix = roachpb.Key("\x04")
 	SystemMax    = roachpb.Key("\x05")
 
+	// NodeLivenessPrefix specifies the key prefix for the node liveness
+	// table. It sorts before the rest of the system keyspace in order to
+	// limit the number of ranges which must use expiration-based range
+	// leases instead of the epoch-based leases tied to node liveness.
+	NodeLivenessPrefix = roachpb.Key(makeKey(SystemPrefix, roachpb.RKey("\x00liveness-")))
+	// NodeLivenessKeyMax is the maximum value for any node liveness key.
+	NodeLivenessKeyMax = NodeLivenessPrefix.PrefixEnd()
+
 	// DescIDGenerator is the global descriptor ID generator sequence used for
 	// table and namespace IDs.
 	DescIDGenerator = roachpb.Key(makeKey(SystemPrefix, roachpb.RKey("desc-idgen")))
@@ -234,17 +249,22 @@ const (
 	// SystemDatabaseID and following are the database/table IDs for objects
 	// in the system span.
 	// NOTE: IDs must be <= MaxSystemConfigDescID.
-	SystemDatabaseID  = 1
-	NamespaceTableID  = 2
-	DescriptorTableID = 3
-	UsersTableID      = 4
-	ZonesTableID      = 5
+	SystemDatabaseID           = 1
+	NamespaceTableID           = 2
+	DescriptorTableID          = 3
+	UsersTableID               = 4
+	ZonesTableID               = 5
+	SettingsTableID            = 6
+	ProtectedTimestampsTableID = 7
 
 	// Reserved IDs for other system tables. If you're adding a new system table,
 	// it probably belongs here.
 	// NOTE: IDs must be <= MaxReservedDescID.
-	LeaseTableID      = 11
-	EventLogTableID   = 12
-	RangeEventTableID = 13
-	UITableID         = 14
+	LeaseTableID           = 11
+	EventLogTableID        = 12
+	RangeEventTableID      = 13
+	UITableID              = 14
+	TableStatisticsTableID = 15
+	JobsTableID            = 16
+	RoleMembersTableID     = 17
 )
@@ -61,6 +61,14 @@ func NodeStatusKey(nodeID int32) roachpb.Key {
 	return key
 }
 
+// NodeLivenessKey returns the key for the liveness record of the given node.
+func NodeLivenessKey(nodeID roachpb.NodeID) roachpb.Key {
+	key := make(roachpb.Key, 0, len(NodeLivenessPrefix)+9)
+	key = append(key, NodeLivenessPrefix...)
+	key = encoding.EncodeUvarintAscending(key, uint64(nodeID))
+	return key
+}
+
 // NodeLastUsageReportKey returns the key for accessing the node last update check
 // time (when version check or usage reporting was done).
 func NodeLastUsageReportKey(nodeID int32) roachpb.Key {
@@ -310,6 +318,21 @@ func RangeDescriptorKey(key roachpb.RKey) roachpb.Key {
 	return MakeRangeKey(key, LocalRangeDescriptorSuffix, nil)
 }
 
+// RangeTombstoneKey returns the key under which the MVCC range tombstones
+// covering a span starting at the specified key are stored.
+func RangeTombstoneKey(key roachpb.RKey) roachpb.Key {
+	return roachpb.Key(makeKey(LocalRangeTombstonePrefix, key))
+}
+
+// DecodeRangeTombstoneKey returns the start key of the span covered by the
+// MVCC range tombstones stored under the specified key.
+func DecodeRangeTombstoneKey(key roachpb.Key) (roachpb.RKey, error) {
+	if !bytes.HasPrefix(key, LocalRangeTombstonePrefix) {
+		return nil, errors.Errorf("key %q does not have %q prefix", key, LocalRangeTombstonePrefix)
+	}
+	return roachpb.RKey(key[len(LocalRangeTombstonePrefix):]), nil
+}
+
 // TransactionKey returns a transaction key based on the provided
 // transaction key and ID. The base key is encoded in order to
 // guarantee that all transaction records for a range sort together.
@@ -347,6 +370,9 @@ func Addr(k roachpb.Key) (roachpb.RKey, error) {
 		if bytes.HasPrefix(k, LocalRangeIDPrefix) {
 			return nil, errors.Errorf("local range ID key %q is not addressable", k)
 		}
+		if bytes.HasPrefix(k, LocalRangeTombstonePrefix) {
+			return roachpb.RKey(k[len(LocalRangeTombstonePrefix):]), nil
+		}
 		if !bytes.HasPrefix(k, LocalRangePrefix) {
 			return nil, errors.Errorf("local key %q malformed; should contain prefix %q",
 				k, LocalRangePrefix)
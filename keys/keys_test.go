@@ -80,6 +80,7 @@ func TestKeyAddress(t *testing.T) {
 		{RangeDescriptorKey(roachpb.RKey("foo")), roachpb.RKey("foo")},
 		{TransactionKey(roachpb.Key("baz"), uuid.NewV4()), roachpb.RKey("baz")},
 		{TransactionKey(roachpb.KeyMax, uuid.NewV4()), roachpb.RKeyMax},
+		{RangeTombstoneKey(roachpb.RKey("foo")), roachpb.RKey("foo")},
 		{RangeDescriptorKey(roachpb.RKey(TransactionKey(roachpb.Key("doubleBaz"), uuid.NewV4()))), roachpb.RKey("doubleBaz")},
 		{nil, nil},
 	}
@@ -72,6 +72,8 @@ var (
 				ppFunc: localRangeIDKeyPrint, psFunc: localRangeIDKeyParse},
 			{name: "/Range", prefix: LocalRangePrefix, ppFunc: localRangeKeyPrint,
 				psFunc: parseUnsupported},
+			{name: "/RangeTombstone", prefix: LocalRangeTombstonePrefix, ppFunc: print,
+				psFunc: parseUnsupported},
 		}},
 		{name: "/Meta1", start: Meta1Prefix, end: Meta1KeyMax, entries: []dictEntry{
 			{name: "", prefix: Meta1Prefix, ppFunc: print,
@@ -105,6 +107,10 @@ var (
 			}},
 		},
 		{name: "/System", start: SystemPrefix, end: SystemMax, entries: []dictEntry{
+			{name: "/NodeLiveness", prefix: NodeLivenessPrefix,
+				ppFunc: decodeKeyPrint,
+				psFunc: parseUnsupported,
+			},
 			{name: "/StatusNode", prefix: StatusNodePrefix,
 				ppFunc: decodeKeyPrint,
 				psFunc: parseUnsupported,
@@ -475,6 +481,7 @@ func prettyPrintInternal(key roachpb.Key) (string, bool) {
 //		/Range/...                                  "\x01k"+...
 //			/RangeDescriptor/[key]                    "\x01k"+[key]+"rdsc"
 //			/Transaction/addrKey:[key]/id:[id]				"\x01k"+[key]+"txn-"+[id]
+//		/RangeTombstone/[key]                       "\x01t"+[key]
 // /Local/Max                                     "\x02"
 //
 // /Meta1/[key]                                   "\x02"+[key]
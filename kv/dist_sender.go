@@ -34,6 +34,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/metric"
 	"github.com/cockroachdb/cockroach/util/retry"
 	"github.com/cockroachdb/cockroach/util/timeutil"
 	"github.com/cockroachdb/cockroach/util/tracing"
@@ -56,6 +57,9 @@ const (
 	defaultLeaseHolderCacheSize = 1 << 16
 	// The default size of the range descriptor cache.
 	defaultRangeDescriptorCacheSize = 1 << 20
+	// The time allowed for a closed timestamp to reach the followers, on top
+	// of the closed timestamp target duration, before reads are sent to them.
+	followerReadPropagationSlack = 5 * time.Second
 )
 
 // A firstRangeMissingError indicates that the first range has not yet
@@ -77,6 +81,39 @@ func (n noNodeAddrsAvailError) Error() string {
 	return "no replica node addresses available via gossip"
 }
 
+// DistSenderMetrics holds all metrics relating to a DistSender.
+type DistSenderMetrics struct {
+	// RangeCacheHits and RangeCacheMisses count range descriptor lookups
+	// served from the range descriptor cache and those which required a
+	// RangeLookup, respectively.
+	RangeCacheHits   *metric.Counter
+	RangeCacheMisses *metric.Counter
+	// RangeCacheEvictions counts stale descriptors evicted from the cache.
+	RangeCacheEvictions *metric.Counter
+	// MisroutedRPCs counts RPCs which reached a replica that no longer
+	// serves (all of) the addressed keys, i.e. which failed with a
+	// RangeKeyMismatchError or RangeNotFoundError.
+	MisroutedRPCs *metric.Counter
+}
+
+const (
+	rangeCacheHitsName      = "distsender.rangecache.hits"
+	rangeCacheMissesName    = "distsender.rangecache.misses"
+	rangeCacheEvictionsName = "distsender.rangecache.evictions"
+	misroutedRPCsName       = "distsender.rpc.misrouted"
+)
+
+// NewDistSenderMetrics returns a new instance of DistSenderMetrics that
+// contains metrics which have been registered with the provided Registry.
+func NewDistSenderMetrics(registry *metric.Registry) *DistSenderMetrics {
+	return &DistSenderMetrics{
+		RangeCacheHits:      registry.Counter(rangeCacheHitsName),
+		RangeCacheMisses:    registry.Counter(rangeCacheMissesName),
+		RangeCacheEvictions: registry.Counter(rangeCacheEvictionsName),
+		MisroutedRPCs:       registry.Counter(misroutedRPCsName),
+	}
+}
+
 // A DistSender provides methods to access Cockroach's monolithic,
 // distributed key value store. Each method invocation triggers a
 // lookup or lookups to find replica metadata for implicated key
@@ -101,10 +138,14 @@ type DistSender struct {
 	rangeLookupMaxRanges int32
 	// leaseHolderCache caches range lease holders by range ID.
 	leaseHolderCache *leaseHolderCache
+	// nodeLatencies tracks the latencies of RPCs to other nodes, by which
+	// replicas are ordered.
+	nodeLatencies    *nodeLatencies
 	transportFactory TransportFactory
 	rpcContext       *rpc.Context
 	rpcRetryOptions  retry.Options
 	sendNextTimeout  time.Duration
+	metrics          *DistSenderMetrics
 }
 
 var _ client.Sender = &DistSender{}
@@ -134,6 +175,9 @@ type DistSenderContext struct {
 	RangeDescriptorDB RangeDescriptorDB
 	Tracer            opentracing.Tracer
 	SendNextTimeout   time.Duration
+	// Metrics, if provided, records range cache and routing statistics.
+	// Defaults to metrics registered with a private registry.
+	Metrics *DistSenderMetrics
 }
 
 // NewDistSender returns a batch.Sender instance which connects to the
@@ -149,8 +193,12 @@ func NewDistSender(ctx *DistSenderContext, g *gossip.Gossip) *DistSender {
 		clock = hlc.NewClock(hlc.UnixNano)
 	}
 	ds := &DistSender{
-		clock:  clock,
-		gossip: g,
+		clock:   clock,
+		gossip:  g,
+		metrics: ctx.Metrics,
+	}
+	if ds.metrics == nil {
+		ds.metrics = NewDistSenderMetrics(metric.NewRegistry())
 	}
 	if ctx.nodeDescriptor != nil {
 		atomic.StorePointer(&ds.nodeDescriptor, unsafe.Pointer(ctx.nodeDescriptor))
@@ -163,12 +211,13 @@ func NewDistSender(ctx *DistSenderContext, g *gossip.Gossip) *DistSender {
 	if rdb == nil {
 		rdb = ds
 	}
-	ds.rangeCache = newRangeDescriptorCache(rdb, int(rcSize))
+	ds.rangeCache = newRangeDescriptorCache(rdb, int(rcSize), ds.metrics)
 	lcSize := ctx.LeaseHolderCacheSize
 	if lcSize <= 0 {
 		lcSize = defaultLeaseHolderCacheSize
 	}
 	ds.leaseHolderCache = newLeaseHolderCache(int(lcSize))
+	ds.nodeLatencies = newNodeLatencies()
 	if ctx.RangeLookupMaxRanges <= 0 {
 		ds.rangeLookupMaxRanges = defaultRangeLookupMaxRanges
 	}
@@ -279,21 +328,20 @@ func (ds *DistSender) FirstRange() (*roachpb.RangeDescriptor, error) {
 // randomly.
 // If the current node is a replica, then it'll be the first one.
 func (ds *DistSender) optimizeReplicaOrder(replicas ReplicaSlice) {
-	// TODO(spencer): going to need to also sort by affinity; closest
-	// ping time should win. Makes sense to have the rpc client/server
-	// heartbeat measure ping times. With a bit of seasoning, each
-	// node will be able to order the healthy replicas based on latency.
-
 	// Unless we know better, send the RPCs randomly.
 	nodeDesc := ds.getNodeDescriptor()
-	// If we don't know which node we're on, don't optimize anything.
+	// If we don't know which node we're on, order only by the latencies we
+	// have observed.
 	if nodeDesc == nil {
 		replicas.Shuffle()
+		replicas.SortByLatency(ds.nodeLatencies.Lookup)
 		return
 	}
 	// Sort replicas by attribute affinity (if any), which we treat as a stand-in
-	// for proximity (for now).
+	// for proximity, and then by the latencies we have observed, which take
+	// precedence where known. The transport puts unhealthy replicas last.
 	replicas.SortByCommonAttributePrefix(nodeDesc.Attrs.Attrs)
+	replicas.SortByLatency(ds.nodeLatencies.Lookup)
 
 	// If there is a replica in local node, move it to the front.
 	if i := replicas.FindReplicaByNodeID(nodeDesc.NodeID); i > 0 {
@@ -357,6 +405,7 @@ func (ds *DistSender) sendRPC(
 		Timeout:          base.NetworkTimeout,
 		Context:          ctx,
 		transportFactory: ds.transportFactory,
+		nodeLatencies:    ds.nodeLatencies,
 	}
 	tracing.AnnotateTrace()
 	defer tracing.AnnotateTrace()
@@ -442,6 +491,34 @@ func (ds *DistSender) getDescriptors(
 	return desc, needAnother, returnToken, nil
 }
 
+// prefetchNextDescriptor asynchronously looks up the descriptor of the
+// range adjacent to desc in the direction of the scan, unless it is already
+// cached. The lookup of that descriptor by the next iteration of a
+// multi-range request then either hits the cache or joins the inflight
+// lookup instead of waiting for a RangeLookup of its own.
+func (ds *DistSender) prefetchNextDescriptor(desc *roachpb.RangeDescriptor, useReverseScan bool) {
+	if ds.rpcContext == nil {
+		return
+	}
+	key := desc.EndKey
+	if useReverseScan {
+		key = desc.StartKey
+	}
+	if _, cached, err := ds.rangeCache.getCachedRangeDescriptor(key, useReverseScan); err != nil || cached != nil {
+		return
+	}
+	// The task is detached from the request's context: a prefetch which
+	// outlives the request is still useful to subsequent ones.
+	_ = ds.rpcContext.Stopper.RunAsyncTask(func() {
+		ctx := context.TODO()
+		if _, _, err := ds.rangeCache.LookupRangeDescriptor(ctx, key, nil, false, useReverseScan); err != nil {
+			if log.V(2) {
+				log.Infof(ctx, "prefetching range descriptor for %s failed: %s", key, err)
+			}
+		}
+	})
+}
+
 // sendSingleRange gathers and rearranges the replicas, and makes an RPC call.
 func (ds *DistSender) sendSingleRange(
 	ctx context.Context, ba roachpb.BatchRequest, desc *roachpb.RangeDescriptor,
@@ -467,8 +544,10 @@ func (ds *DistSender) sendSingleRange(
 	ds.optimizeReplicaOrder(replicas)
 
 	// If this request needs to go to a lease holder and we know who that is, move
-	// it to the front.
-	if !(ba.IsReadOnly() && ba.ReadConsistency == roachpb.INCONSISTENT) {
+	// it to the front. Reads far enough in the past are likely to be served by
+	// any replica from its closed timestamp, so they go to the closest one. A
+	// replica which can't serve them redirects to the lease holder.
+	if !(ba.IsReadOnly() && (ba.ReadConsistency == roachpb.INCONSISTENT || ds.canUseFollowerRead(ba))) {
 		if leaseHolder, ok := ds.leaseHolderCache.Lookup(desc.RangeID); ok {
 			if i := replicas.FindReplica(leaseHolder.StoreID); i >= 0 {
 				replicas.MoveToFront(i)
@@ -495,6 +574,27 @@ func (ds *DistSender) sendSingleRange(
 	return br, pErr
 }
 
+// canUseFollowerRead returns whether the read-only batch is far enough in the
+// past for followers to be likely to have closed its timestamp: the lease
+// holders close timestamps base.DefaultClosedTimestampTargetDuration behind
+// their clocks, which may be ahead of ours by up to the maximum clock offset,
+// and the closed timestamps take some time to reach the followers.
+func (ds *DistSender) canUseFollowerRead(ba roachpb.BatchRequest) bool {
+	if ba.ReadConsistency != roachpb.CONSISTENT {
+		return false
+	}
+	ts := ba.Timestamp
+	if ba.Txn != nil {
+		ts.Forward(ba.Txn.MaxTimestamp)
+	}
+	if ts == hlc.ZeroTimestamp {
+		return false
+	}
+	threshold := base.DefaultClosedTimestampTargetDuration + ds.clock.MaxOffset() +
+		followerReadPropagationSlack
+	return ts.WallTime < ds.clock.PhysicalNow()-int64(threshold)
+}
+
 // Send implements the batch.Sender interface. It subdivides
 // the Batch into batches admissible for sending (preventing certain
 // illegal mixtures of requests), executes each individual part
@@ -553,7 +653,7 @@ func (ds *DistSender) Send(ctx context.Context, ba roachpb.BatchRequest) (*roach
 		panic("empty batch")
 	}
 
-	if ba.MaxSpanRequestKeys != 0 {
+	if ba.MaxSpanRequestKeys != 0 || ba.TargetBytes != 0 {
 		// Verify that the batch contains only specific range requests or the
 		// Begin/EndTransactionRequest. Verify that a batch with a ReverseScan
 		// only contains ReverseScan range requests.
@@ -580,10 +680,10 @@ func (ds *DistSender) Send(ctx context.Context, ba roachpb.BatchRequest) (*roach
 
 	var rplChunks []*roachpb.BatchResponse
 	parts := ba.Split(false /* don't split ET */)
-	if len(parts) > 1 && ba.MaxSpanRequestKeys != 0 {
+	if len(parts) > 1 && (ba.MaxSpanRequestKeys != 0 || ba.TargetBytes != 0) {
 		// We already verified above that the batch contains only scan requests of the same type.
 		// Such a batch should never need splitting.
-		panic("batch with MaxSpanRequestKeys needs splitting")
+		panic("batch with MaxSpanRequestKeys or TargetBytes needs splitting")
 	}
 	for len(parts) > 0 {
 		part := parts[0]
@@ -645,6 +745,12 @@ func (ds *DistSender) sendChunk(ctx context.Context, ba roachpb.BatchRequest) (*
 
 	// Send the request to one range per iteration.
 	for {
+		// Don't bother with the remaining ranges if the client has given up
+		// on the request (e.g. because the SQL query was canceled).
+		if err := ctx.Err(); err != nil {
+			return nil, roachpb.NewError(err), false
+		}
+
 		// Increase the sequence counter only once before sending RPCs to
 		// the ranges involved in this chunk of the batch (as opposed to for
 		// each RPC individually). On RPC errors, there's no guarantee that
@@ -727,6 +833,12 @@ func (ds *DistSender) sendChunk(ctx context.Context, ba roachpb.BatchRequest) (*
 				continue
 			}
 
+			// Warm the cache with the descriptor of the range to visit next
+			// while this one is being queried.
+			if needAnother {
+				ds.prefetchNextDescriptor(desc, isReverse)
+			}
+
 			curReply, pErr = func() (*roachpb.BatchResponse, *roachpb.Error) {
 				// Truncate the request to our current key range.
 				intersected, iErr := rs.Intersect(desc)
@@ -772,6 +884,7 @@ func (ds *DistSender) sendChunk(ctx context.Context, ba roachpb.BatchRequest) (*
 				}
 				continue
 			case *roachpb.RangeKeyMismatchError:
+				ds.metrics.MisroutedRPCs.Inc(1)
 				// Range descriptor might be out of date - evict it. This is
 				// likely the result of a range split. If we have new range
 				// descriptors, insert them instead as long as they are different
@@ -868,6 +981,22 @@ func (ds *DistSender) sendChunk(ctx context.Context, ba roachpb.BatchRequest) (*
 			}
 		}
 
+		if ba.TargetBytes > 0 {
+			// Count how many bytes we received. Unlike the key limit, the
+			// byte limit may be overshot by the last row of each range.
+			var numBytes int64
+			for _, resp := range curReply.Responses {
+				numBytes += resp.GetInner().Header().NumBytes
+			}
+			ba.TargetBytes -= numBytes
+			if ba.TargetBytes <= 0 {
+				// prepare the batch response after meeting the byte target.
+				fillSkippedResponses(ba, br, rs)
+				// done, exit loop.
+				return br, nil, false
+			}
+		}
+
 		// If this was the last range accessed by this call, exit loop.
 		if !needAnother {
 			return br, nil, false
@@ -884,8 +1013,8 @@ func (ds *DistSender) sendChunk(ctx context.Context, ba roachpb.BatchRequest) (*
 	return br, nil, false
 }
 
-// fillSkippedResponses after meeting the batch key max limit for range
-// requests.
+// fillSkippedResponses after meeting the batch key max limit or byte target
+// for range requests.
 func fillSkippedResponses(
 	ba roachpb.BatchRequest, br *roachpb.BatchResponse, nextSpan roachpb.RSpan,
 ) {
@@ -1010,6 +1139,11 @@ func (ds *DistSender) sendToReplicas(opts SendOptions,
 				transport.SendNext(done)
 			}
 
+		case <-opts.Context.Done():
+			// The client has given up on the request; don't wait for the
+			// outstanding RPCs.
+			return nil, opts.Context.Err()
+
 		case call := <-done:
 			pending--
 			err := call.Err
@@ -1024,6 +1158,12 @@ func (ds *DistSender) sendToReplicas(opts SendOptions,
 					return call.Reply, nil
 				}
 
+				// If the replica knows the new lease holder, try it next
+				// instead of the remaining replicas in order.
+				if tErr, ok := call.Reply.Error.GetDetail().(*roachpb.NotLeaseHolderError); ok && tErr.LeaseHolder != nil {
+					transport.MoveToFront(*tErr.LeaseHolder)
+				}
+
 				// Extract the detail so it can be included in the error
 				// message if this is our last replica.
 				//
@@ -1059,6 +1199,7 @@ func (ds *DistSender) sendToReplicas(opts SendOptions,
 func (ds *DistSender) handlePerReplicaError(rangeID roachpb.RangeID, pErr *roachpb.Error) bool {
 	switch tErr := pErr.GetDetail().(type) {
 	case *roachpb.RangeNotFoundError:
+		ds.metrics.MisroutedRPCs.Inc(1)
 		return true
 	case *roachpb.NodeUnavailableError:
 		return true
@@ -1066,9 +1207,6 @@ func (ds *DistSender) handlePerReplicaError(rangeID roachpb.RangeID, pErr *roach
 		if tErr.LeaseHolder != nil {
 			// If the replica we contacted knows the new lease holder, update the cache.
 			ds.updateLeaseHolderCache(rangeID, *tErr.LeaseHolder)
-
-			// TODO(bdarnell): Move the new lease holder to the head of the queue
-			// for the next retry.
 		}
 		return true
 	}
@@ -17,6 +17,7 @@
 package kv_test
 
 import (
+	"reflect"
 	"sync/atomic"
 	"testing"
 
@@ -278,6 +279,68 @@ func TestMultiRangeBoundedBatchScan(t *testing.T) {
 	}
 }
 
+// TestMultiRangeScanWithTargetBytes verifies that a scan across many ranges
+// with a byte target returns its rows incrementally, and that following the
+// returned resume spans visits all rows.
+func TestMultiRangeScanWithTargetBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	db := setupMultipleRanges(t, s, "a", "b", "c", "d", "e", "f")
+	expKeys := []string{"a1", "a2", "a3", "b1", "b2", "c1", "c2", "d1", "f1", "f2", "f3"}
+	for _, key := range expKeys {
+		if err := db.Put(key, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// All rows have the same size.
+	rows, err := db.Scan("a1", "a2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rowSize := int64(len(rows[0].Key) + len(rows[0].Value.RawBytes))
+
+	for _, reverse := range []bool{false, true} {
+		for rowsPerBatch := 1; rowsPerBatch <= 4; rowsPerBatch++ {
+			var keys []string
+			span := roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("g")}
+			for batches := 0; span.Key != nil; batches++ {
+				if batches > len(expKeys) {
+					t.Fatalf("scan did not finish after %d batches", batches)
+				}
+				b := &client.Batch{}
+				b.Header.TargetBytes = int64(rowsPerBatch) * rowSize
+				if reverse {
+					b.ReverseScan(span.Key, span.EndKey)
+				} else {
+					b.Scan(span.Key, span.EndKey)
+				}
+				if err := db.Run(b); err != nil {
+					t.Fatal(err)
+				}
+				res := b.Results[0]
+				if res.ResumeSpan.Key != nil && len(res.Rows) != rowsPerBatch {
+					t.Errorf("reverse=%t: expected %d rows per batch, got %d", reverse, rowsPerBatch, len(res.Rows))
+				}
+				for _, row := range res.Rows {
+					keys = append(keys, string(row.Key))
+				}
+				span = res.ResumeSpan
+			}
+			exp := append([]string(nil), expKeys...)
+			if reverse {
+				for i, j := 0, len(exp)-1; i < j; i, j = i+1, j-1 {
+					exp[i], exp[j] = exp[j], exp[i]
+				}
+			}
+			if !reflect.DeepEqual(keys, exp) {
+				t.Errorf("reverse=%t, %d rows per batch: expected keys %s, got %s", reverse, rowsPerBatch, exp, keys)
+			}
+		}
+	}
+}
+
 // Tests multiple reverse scans across many ranges with multiple bounds.
 func TestMultiRangeBoundedBatchReverseScan(t *testing.T) {
 	defer leaktest.AfterTest(t)()
@@ -105,6 +105,9 @@ func (l *legacyTransportAdapter) SendNext(done chan BatchCall) {
 	}
 }
 
+func (*legacyTransportAdapter) MoveToFront(roachpb.ReplicaDescriptor) {
+}
+
 func (*legacyTransportAdapter) Close() {
 }
 
@@ -791,6 +794,12 @@ func TestRetryOnWrongReplicaErrorWithSuggestion(t *testing.T) {
 	if _, err := client.SendWrapped(ds, nil, scan); err != nil {
 		t.Errorf("scan encountered error: %s", err)
 	}
+	if c := ds.metrics.MisroutedRPCs.Count(); c != 1 {
+		t.Errorf("expected 1 misrouted RPC, got %d", c)
+	}
+	if c := ds.metrics.RangeCacheEvictions.Count(); c != 1 {
+		t.Errorf("expected 1 range cache eviction, got %d", c)
+	}
 }
 
 func TestGetFirstRangeDescriptor(t *testing.T) {
@@ -1722,6 +1731,9 @@ func (t *slowLeaseHolderTransport) SendNext(done chan BatchCall) {
 	t.count++
 }
 
+func (t *slowLeaseHolderTransport) MoveToFront(roachpb.ReplicaDescriptor) {
+}
+
 func (t *slowLeaseHolderTransport) Close() {
 }
 
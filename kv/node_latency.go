@@ -0,0 +1,57 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"time"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// nodeLatencyDecay is the weight given to a new observation in the moving
+// average of a node's latency.
+const nodeLatencyDecay = 0.2
+
+// A nodeLatencies tracks an exponentially weighted moving average of the
+// latencies observed for the RPCs sent to each node.
+type nodeLatencies struct {
+	mu        syncutil.Mutex
+	latencies map[roachpb.NodeID]time.Duration
+}
+
+// newNodeLatencies creates a new, empty nodeLatencies.
+func newNodeLatencies() *nodeLatencies {
+	return &nodeLatencies{latencies: make(map[roachpb.NodeID]time.Duration)}
+}
+
+// Record folds the given observed latency into the average of the node.
+func (nl *nodeLatencies) Record(nodeID roachpb.NodeID, latency time.Duration) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if avg, ok := nl.latencies[nodeID]; ok {
+		latency = avg + time.Duration(nodeLatencyDecay*float64(latency-avg))
+	}
+	nl.latencies[nodeID] = latency
+}
+
+// Lookup returns the average latency of the given node, if any RPC to it
+// has been observed.
+func (nl *nodeLatencies) Lookup(nodeID roachpb.NodeID) (time.Duration, bool) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	latency, ok := nl.latencies[nodeID]
+	return latency, ok
+}
@@ -0,0 +1,43 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package kv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestNodeLatencies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	nl := newNodeLatencies()
+	if _, ok := nl.Lookup(1); ok {
+		t.Fatal("expected no latency for node 1")
+	}
+
+	nl.Record(1, 100*time.Millisecond)
+	if latency, ok := nl.Lookup(1); !ok || latency != 100*time.Millisecond {
+		t.Errorf("expected latency 100ms, got %s (found: %t)", latency, ok)
+	}
+	// A new observation only moves the average by nodeLatencyDecay.
+	nl.Record(1, 200*time.Millisecond)
+	if latency, ok := nl.Lookup(1); !ok || latency != 120*time.Millisecond {
+		t.Errorf("expected latency 120ms, got %s (found: %t)", latency, ok)
+	}
+	if _, ok := nl.Lookup(2); ok {
+		t.Error("expected no latency for node 2")
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/cache"
 	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/metric"
 	"github.com/cockroachdb/cockroach/util/syncutil"
 )
 
@@ -112,6 +113,11 @@ type rangeDescriptorCache struct {
 		syncutil.Mutex
 		inflight map[lookupRequestKey]lookupRequest
 	}
+	// hits, misses and evictions count lookups served from the cache,
+	// lookups which had to consult the RangeDescriptorDB (including those
+	// coalesced onto an inflight request) and stale descriptors evicted
+	// from the cache, respectively.
+	hits, misses, evictions *metric.Counter
 }
 
 type lookupRequest struct {
@@ -181,9 +187,17 @@ func makeLookupRequestKey(key roachpb.RKey, evictToken *evictionToken, considerI
 
 // newRangeDescriptorCache returns a new RangeDescriptorCache which
 // uses the given RangeDescriptorDB as the underlying source of range
-// descriptors.
-func newRangeDescriptorCache(db RangeDescriptorDB, size int) *rangeDescriptorCache {
-	rdc := &rangeDescriptorCache{db: db}
+// descriptors. Cache hits, misses and evictions are recorded in the
+// given metrics.
+func newRangeDescriptorCache(
+	db RangeDescriptorDB, size int, metrics *DistSenderMetrics,
+) *rangeDescriptorCache {
+	rdc := &rangeDescriptorCache{
+		db:        db,
+		hits:      metrics.RangeCacheHits,
+		misses:    metrics.RangeCacheMisses,
+		evictions: metrics.RangeCacheEvictions,
+	}
 	rdc.rangeCache.cache = cache.NewOrderedCache(cache.Config{
 		Policy: cache.CacheLRU,
 		ShouldEvict: func(n int, _, _ interface{}) bool {
@@ -309,6 +323,7 @@ func (rdc *rangeDescriptorCache) lookupRangeDescriptorInternal(
 		return nil, nil, err
 	} else if desc != nil {
 		rdc.rangeCache.RUnlock()
+		rdc.hits.Inc(1)
 		returnToken := rdc.makeEvictionToken(desc, func() error {
 			return rdc.evictCachedRangeDescriptorLocked(key, desc, useReverseScan)
 		})
@@ -322,6 +337,7 @@ func (rdc *rangeDescriptorCache) lookupRangeDescriptorInternal(
 		log.Infof(ctx, "lookup range descriptor: key=%s", key)
 	}
 
+	rdc.misses.Inc(1)
 	var res lookupResult
 	requestKey := makeLookupRequestKey(key, evictToken, considerIntents, useReverseScan)
 	rdc.lookupRequests.Lock()
@@ -486,6 +502,9 @@ func (rdc *rangeDescriptorCache) evictCachedRangeDescriptorLocked(descKey roachp
 	if seenDesc != nil && seenDesc != cachedDesc {
 		return nil
 	}
+	if cachedDesc != nil {
+		rdc.evictions.Inc(1)
+	}
 
 	for {
 		if log.V(3) {
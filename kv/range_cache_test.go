@@ -31,6 +31,7 @@ import (
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/testutils"
 	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/metric"
 )
 
 type testDescriptorDB struct {
@@ -177,7 +178,7 @@ func initTestDescriptorDB(t *testing.T) *testDescriptorDB {
 			db.splitRange(t, mustMeta(roachpb.RKey(string(char))))
 		}
 	}
-	db.cache = newRangeDescriptorCache(db, 2<<10)
+	db.cache = newRangeDescriptorCache(db, 2<<10, NewDistSenderMetrics(metric.NewRegistry()))
 	return db
 }
 
@@ -342,6 +343,49 @@ func TestRangeCache(t *testing.T) {
 	db.assertLookupCountEq(t, 2, "cz")
 }
 
+// TestRangeCacheMetrics verifies that lookups served from the cache,
+// lookups which go to the RangeDescriptorDB and evictions are counted.
+func TestRangeCacheMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	db := initTestDescriptorDB(t)
+
+	assertMetrics := func(hits, misses, evictions int64) {
+		if a := db.cache.hits.Count(); a != hits {
+			t.Errorf("expected %d cache hits, got %d", hits, a)
+		}
+		if a := db.cache.misses.Count(); a != misses {
+			t.Errorf("expected %d cache misses, got %d", misses, a)
+		}
+		if a := db.cache.evictions.Count(); a != evictions {
+			t.Errorf("expected %d cache evictions, got %d", evictions, a)
+		}
+	}
+
+	// The first lookup misses on the range and on its meta2 range.
+	_, evictToken := doLookup(t, db.cache, "aa")
+	assertMetrics(0, 2, 0)
+	doLookup(t, db.cache, "ab")
+	assertMetrics(1, 2, 0)
+
+	// Evicting removes the descriptor along with its meta2 descriptor, so
+	// the next lookup misses twice again.
+	if err := evictToken.Evict(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(1, 2, 1)
+	doLookup(t, db.cache, "aa")
+	assertMetrics(1, 4, 1)
+
+	// Evicting with a stale descriptor is a no-op.
+	if err := evictToken.Evict(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.cache.EvictCachedRangeDescriptor(roachpb.RKey("aa"), &roachpb.RangeDescriptor{}, false); err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(1, 4, 1)
+}
+
 // TestRangeCacheCoalescedRequests verifies that concurrent lookups for
 // the same key will be coalesced onto the same database lookup.
 func TestRangeCacheCoalescedRequests(t *testing.T) {
@@ -636,7 +680,7 @@ func TestRangeCacheClearOverlapping(t *testing.T) {
 		EndKey:   roachpb.RKeyMax,
 	}
 
-	cache := newRangeDescriptorCache(nil, 2<<10)
+	cache := newRangeDescriptorCache(nil, 2<<10, NewDistSenderMetrics(metric.NewRegistry()))
 	cache.rangeCache.cache.Add(rangeCacheKey(keys.RangeMetaKey(roachpb.RKeyMax)), defDesc)
 
 	// Now, add a new, overlapping set of descriptors.
@@ -731,7 +775,7 @@ func TestRangeCacheClearOverlappingMeta(t *testing.T) {
 		EndKey:   roachpb.RKeyMax,
 	}
 
-	cache := newRangeDescriptorCache(nil, 2<<10)
+	cache := newRangeDescriptorCache(nil, 2<<10, NewDistSenderMetrics(metric.NewRegistry()))
 	cache.rangeCache.cache.Add(rangeCacheKey(keys.RangeMetaKey(firstDesc.EndKey)),
 		firstDesc)
 	cache.rangeCache.cache.Add(rangeCacheKey(keys.RangeMetaKey(restDesc.EndKey)),
@@ -765,7 +809,7 @@ func TestGetCachedRangeDescriptorInclusive(t *testing.T) {
 		{StartKey: roachpb.RKey("g"), EndKey: roachpb.RKey("z")},
 	}
 
-	cache := newRangeDescriptorCache(nil, 2<<10)
+	cache := newRangeDescriptorCache(nil, 2<<10, NewDistSenderMetrics(metric.NewRegistry()))
 	for _, rd := range testData {
 		cache.rangeCache.cache.Add(rangeCacheKey(keys.RangeMetaKey(rd.EndKey)), rd)
 	}
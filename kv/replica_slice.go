@@ -19,6 +19,8 @@ package kv
 
 import (
 	"math/rand"
+	"sort"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -126,6 +128,33 @@ func (rs ReplicaSlice) SortByCommonAttributePrefix(attrs []string) int {
 	return len(attrs)
 }
 
+// SortByLatency rearranges the ReplicaSlice so that replicas on nodes with a
+// lower latency, as reported by the given function, come first. Replicas on
+// nodes with unknown latency come last. The sort is stable, so replicas with
+// the same (or unknown) latency keep their relative order.
+func (rs ReplicaSlice) SortByLatency(latency func(roachpb.NodeID) (time.Duration, bool)) {
+	if len(rs) < 2 {
+		return
+	}
+	sort.Stable(byLatency{rs: rs, latency: latency})
+}
+
+type byLatency struct {
+	rs      ReplicaSlice
+	latency func(roachpb.NodeID) (time.Duration, bool)
+}
+
+func (l byLatency) Len() int      { return len(l.rs) }
+func (l byLatency) Swap(i, j int) { l.rs.Swap(i, j) }
+func (l byLatency) Less(i, j int) bool {
+	li, okI := l.latency(l.rs[i].NodeID)
+	lj, okJ := l.latency(l.rs[j].NodeID)
+	if !okI || !okJ {
+		return okI && !okJ
+	}
+	return li < lj
+}
+
 // MoveToFront moves the replica at the given index to the front
 // of the slice, keeping the order of the remaining elements stable.
 // The function will panic when invoked with an invalid index.
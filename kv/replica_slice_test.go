@@ -20,6 +20,7 @@ import (
 	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/leaktest"
@@ -133,3 +134,25 @@ func TestReplicaSetRandPerm(t *testing.T) {
 	verifyRandPermOrdering(1, 3, []roachpb.StoreID{1, 4, 2, 3, 5}, t)
 	verifyRandPermOrdering(0, 4, []roachpb.StoreID{3, 5, 2, 1, 4}, t)
 }
+
+func TestReplicaSetSortByLatency(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	rs := createReplicaSlice()
+	for i := range rs {
+		rs[i].NodeID = roachpb.NodeID(rs[i].StoreID)
+	}
+	latencies := map[roachpb.NodeID]time.Duration{
+		2: 30 * time.Millisecond,
+		3: 10 * time.Millisecond,
+		5: 20 * time.Millisecond,
+	}
+	rs.SortByLatency(func(nodeID roachpb.NodeID) (time.Duration, bool) {
+		latency, ok := latencies[nodeID]
+		return latency, ok
+	})
+	// Replicas on nodes with unknown latencies keep their order at the end.
+	exp := []roachpb.StoreID{3, 5, 2, 1, 4}
+	if stores := getStores(rs); !reflect.DeepEqual(stores, exp) {
+		t.Errorf("expected order %s, got %s", exp, stores)
+	}
+}
@@ -74,6 +74,10 @@ func (n Node) Reserve(_ context.Context, _ *roachpb.ReservationRequest) (*roachp
 	panic("unimplemented")
 }
 
+func (n Node) RangeFeed(_ *roachpb.RangeFeedRequest, _ roachpb.Internal_RangeFeedServer) error {
+	panic("unimplemented")
+}
+
 func TestInvalidAddrLength(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -175,6 +179,9 @@ func (c *channelSaveTransport) SendNext(done chan BatchCall) {
 	c.ch <- done
 }
 
+func (*channelSaveTransport) MoveToFront(roachpb.ReplicaDescriptor) {
+}
+
 func (*channelSaveTransport) Close() {
 }
 
@@ -534,6 +541,9 @@ func (f *firstNErrorTransport) SendNext(done chan BatchCall) {
 	done <- call
 }
 
+func (*firstNErrorTransport) MoveToFront(roachpb.ReplicaDescriptor) {
+}
+
 func (*firstNErrorTransport) Close() {
 }
 
@@ -670,6 +680,111 @@ func TestSplitHealthy(t *testing.T) {
 	}
 }
 
+// leaseHolderHintTransport is a mock transport on which all replicas but the
+// lease holder return a NotLeaseHolderError pointing at the lease holder.
+type leaseHolderHintTransport struct {
+	replicas    ReplicaSlice
+	leaseHolder roachpb.ReplicaDescriptor
+	sent        []roachpb.StoreID
+}
+
+func (l *leaseHolderHintTransport) IsExhausted() bool {
+	return len(l.replicas) == 0
+}
+
+func (l *leaseHolderHintTransport) SendNext(done chan BatchCall) {
+	replica := l.replicas[0]
+	l.replicas = l.replicas[1:]
+	l.sent = append(l.sent, replica.StoreID)
+	br := &roachpb.BatchResponse{}
+	if replica.StoreID != l.leaseHolder.StoreID {
+		br.Error = roachpb.NewError(&roachpb.NotLeaseHolderError{LeaseHolder: &l.leaseHolder})
+	}
+	done <- BatchCall{Reply: br}
+}
+
+func (l *leaseHolderHintTransport) MoveToFront(replica roachpb.ReplicaDescriptor) {
+	if i := l.replicas.FindReplica(replica.StoreID); i > 0 {
+		l.replicas.MoveToFront(i)
+	}
+}
+
+func (*leaseHolderHintTransport) Close() {
+}
+
+// TestSendToReplicasFollowsLeaseHolderHint verifies that a NotLeaseHolderError
+// naming the lease holder makes it the next replica tried, and updates the
+// lease holder cache.
+func TestSendToReplicasFollowsLeaseHolderHint(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var replicas ReplicaSlice
+	for i := 1; i <= 4; i++ {
+		replicas = append(replicas, ReplicaInfo{
+			ReplicaDescriptor: roachpb.ReplicaDescriptor{
+				NodeID:    roachpb.NodeID(i),
+				StoreID:   roachpb.StoreID(i),
+				ReplicaID: roachpb.ReplicaID(i),
+			},
+			NodeDesc: &roachpb.NodeDescriptor{NodeID: roachpb.NodeID(i)},
+		})
+	}
+	transport := &leaseHolderHintTransport{
+		replicas:    append(ReplicaSlice(nil), replicas...),
+		leaseHolder: replicas[3].ReplicaDescriptor,
+	}
+	opts := SendOptions{
+		Context:         context.Background(),
+		SendNextTimeout: time.Hour,
+		transportFactory: func(SendOptions, *rpc.Context, ReplicaSlice, roachpb.BatchRequest) (Transport, error) {
+			return transport, nil
+		},
+	}
+
+	ds := NewDistSender(nil, nil)
+	const rangeID = 5
+	if _, err := ds.sendToReplicas(opts, rangeID, replicas, roachpb.BatchRequest{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if exp := []roachpb.StoreID{1, 4}; !reflect.DeepEqual(transport.sent, exp) {
+		t.Errorf("expected replicas %v to be tried, got %v", exp, transport.sent)
+	}
+	if leaseHolder, ok := ds.leaseHolderCache.Lookup(rangeID); !ok || leaseHolder != transport.leaseHolder {
+		t.Errorf("expected lease holder %+v to be cached, got %+v", transport.leaseHolder, leaseHolder)
+	}
+}
+
+// TestGRPCTransportMoveToFront verifies that MoveToFront reorders the
+// replicas not tried yet, and ignores those already tried.
+func TestGRPCTransportMoveToFront(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var clients []batchClient
+	for i := 1; i <= 4; i++ {
+		var args roachpb.BatchRequest
+		args.Replica = roachpb.ReplicaDescriptor{StoreID: roachpb.StoreID(i)}
+		clients = append(clients, batchClient{args: args})
+	}
+	gt := grpcTransport{orderedClients: clients[1:]}
+	storeIDs := func() []roachpb.StoreID {
+		var ids []roachpb.StoreID
+		for _, c := range gt.orderedClients {
+			ids = append(ids, c.args.Replica.StoreID)
+		}
+		return ids
+	}
+
+	gt.MoveToFront(roachpb.ReplicaDescriptor{StoreID: 4})
+	if exp := []roachpb.StoreID{4, 2, 3}; !reflect.DeepEqual(storeIDs(), exp) {
+		t.Errorf("expected order %v, got %v", exp, storeIDs())
+	}
+	// Store 1 has already been tried.
+	gt.MoveToFront(roachpb.ReplicaDescriptor{StoreID: 1})
+	if exp := []roachpb.StoreID{4, 2, 3}; !reflect.DeepEqual(storeIDs(), exp) {
+		t.Errorf("expected order %v, got %v", exp, storeIDs())
+	}
+}
+
 func makeReplicas(addrs ...net.Addr) ReplicaSlice {
 	replicas := make(ReplicaSlice, len(addrs))
 	for i, addr := range addrs {
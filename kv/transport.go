@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/util/envutil"
 	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/timeutil"
 	"github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/rubyist/circuitbreaker"
@@ -51,6 +52,8 @@ type SendOptions struct {
 	Timeout time.Duration
 
 	transportFactory TransportFactory
+	// nodeLatencies, if set, records the latency of successful RPCs.
+	nodeLatencies *nodeLatencies
 }
 
 func (so SendOptions) contextWithTimeout() (context.Context, func()) {
@@ -104,6 +107,12 @@ type Transport interface {
 	// as needed.
 	SendNext(chan BatchCall)
 
+	// MoveToFront locates the specified replica among those not tried
+	// yet and moves it to the front of the ordering of replicas to try.
+	// If the replica has already been tried or can't be found, this is a
+	// noop.
+	MoveToFront(roachpb.ReplicaDescriptor)
+
 	// Close is called when the transport is no longer needed. It may
 	// cancel any pending RPCs without writing any response to the channel.
 	Close()
@@ -169,11 +178,19 @@ func (gt *grpcTransport) SendNext(done chan BatchCall) {
 		log.Infof(gt.opts.Context, "sending request to %s: %+v", addr, client.args)
 	}
 
+	start := timeutil.Now()
+	recordLatency := func(err error) {
+		if err == nil && gt.opts.nodeLatencies != nil {
+			gt.opts.nodeLatencies.Record(client.args.Replica.NodeID, timeutil.Since(start))
+		}
+	}
+
 	if localServer := gt.rpcContext.GetLocalInternalServerForAddr(addr); enableLocalCalls && localServer != nil {
 		ctx, cancel := gt.opts.contextWithTimeout()
 		defer cancel()
 
 		reply, err := localServer.Batch(ctx, &client.args)
+		recordLatency(err)
 		done <- BatchCall{Reply: reply, Err: err}
 		return
 	}
@@ -183,6 +200,7 @@ func (gt *grpcTransport) SendNext(done chan BatchCall) {
 		defer cancel()
 
 		reply, err := client.client.Batch(ctx, &client.args)
+		recordLatency(err)
 		if reply != nil {
 			for i := range reply.Responses {
 				if err := reply.Responses[i].GetInner().Verify(client.args.Requests[i].GetInner()); err != nil {
@@ -194,6 +212,17 @@ func (gt *grpcTransport) SendNext(done chan BatchCall) {
 	}()
 }
 
+func (gt *grpcTransport) MoveToFront(replica roachpb.ReplicaDescriptor) {
+	for i := range gt.orderedClients {
+		if gt.orderedClients[i].args.Replica.StoreID == replica.StoreID {
+			client := gt.orderedClients[i]
+			copy(gt.orderedClients[1:i+1], gt.orderedClients[:i])
+			gt.orderedClients[0] = client
+			return
+		}
+	}
+}
+
 func (*grpcTransport) Close() {
 	// TODO(bdarnell): Save the cancel functions of all pending RPCs and
 	// call them here. (it's fine to ignore them for now since they'll
@@ -269,5 +298,8 @@ func (s *senderTransport) SendNext(done chan BatchCall) {
 	done <- BatchCall{Reply: br}
 }
 
+func (s *senderTransport) MoveToFront(replica roachpb.ReplicaDescriptor) {
+}
+
 func (s *senderTransport) Close() {
 }
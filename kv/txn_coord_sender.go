@@ -77,9 +77,24 @@ type txnMetadata struct {
 	// current_timestamp > lastUpdateTS + timeoutDuration.
 	timeoutDuration time.Duration
 
-	// txnEnd is closed when the transaction is aborted or committed,
-	// terminating the associated heartbeat instance.
-	txnEnd chan struct{}
+	// registered is the wall time at which the coordinator started tracking
+	// the transaction. The transaction isn't heartbeat before it has been
+	// tracked for a heartbeat interval, which spares short-lived
+	// transactions from being heartbeat at all.
+	registered time.Time
+
+	// ctx is the context of the request which started tracking the
+	// transaction, or that of its txn if the request's context only belongs
+	// to a statement of the txn (see client.WithStatementCancel). If it is
+	// cancellable, the transaction is aborted once it is done, instead of
+	// when the client times out.
+	//
+	// TODO(dan): The semantics of this aren't good. Each context has its own
+	// associated lifetime and we're ignoring all but the first. It happens
+	// now that we pass the same one in every request, but it's brittle to
+	// rely on this forever.
+	// TODO(wiz): Update (*DBServer).Batch to not use context.TODO().
+	ctx context.Context
 }
 
 // setLastUpdate updates the wall time (in nanoseconds) since the most
@@ -140,8 +155,10 @@ func NewTxnMetrics(registry *metric.Registry) *TxnMetrics {
 // wraps a lower-level Sender (either a storage.Stores or a DistSender)
 // to which it sends commands. It acts as a man-in-the-middle,
 // coordinating transaction state for clients.  After a transaction is
-// started, the TxnCoordSender starts asynchronously sending heartbeat
-// messages to that transaction's txn record, to keep it live. It also
+// started, the TxnCoordSender periodically sends heartbeat messages to
+// that transaction's txn record, to keep it live; the heartbeats of all
+// the transactions it coordinates are sent together, from a single loop
+// running only while there are transactions to heartbeat. It also
 // keeps track of each written key or key range over the course of the
 // transaction. When the transaction is committed or aborted, it
 // clears accumulated write intents for the transaction.
@@ -152,6 +169,7 @@ type TxnCoordSender struct {
 	clientTimeout     time.Duration
 	syncutil.Mutex                               // protects txns and txnStats
 	txns              map[uuid.UUID]*txnMetadata // txn key to metadata
+	heartbeating      bool                       // heartbeat loop is running
 	linearizable      bool                       // enables linearizable behaviour
 	tracer            opentracing.Tracer
 	stopper           *stop.Stopper
@@ -514,24 +532,22 @@ func (tc *TxnCoordSender) maybeBeginTxn(ba *roachpb.BatchRequest) error {
 	return nil
 }
 
-// cleanupTxnLocked is called when a transaction ends. The transaction record
-// is updated and the heartbeat goroutine signaled to clean up the transaction
-// gracefully.
+// cleanupTxnLocked is called when a transaction ends. The transaction is
+// unregistered, which stops its heartbeats, and its stats are collected.
 func (tc *TxnCoordSender) cleanupTxnLocked(ctx context.Context, txn roachpb.Transaction) {
 	log.Trace(ctx, "coordinator stops")
 	txnMeta, ok := tc.txns[*txn.ID]
-	// The heartbeat might've already removed the record. Or we may have already
-	// closed txnEnd but we are racing with the heartbeat cleanup.
-	if !ok || txnMeta.txnEnd == nil {
+	// The heartbeat loop might've already removed the record, or the
+	// transaction may have already been cleaned up.
+	if !ok {
 		return
 	}
 
 	// The supplied txn may be newer than the one in txnMeta, which is relevant
 	// for stats.
 	txnMeta.txn = txn
-	// Trigger heartbeat shutdown.
-	close(txnMeta.txnEnd)
-	txnMeta.txnEnd = nil
+	duration, restarts, status := tc.unregisterTxnLocked(*txn.ID)
+	tc.updateStats(duration, restarts, status, false)
 }
 
 // unregisterTxn deletes a txnMetadata object from the sender
@@ -555,79 +571,121 @@ func (tc *TxnCoordSender) unregisterTxnLocked(txnID uuid.UUID) (
 	return duration, restarts, status
 }
 
-// heartbeatLoop periodically sends a HeartbeatTxn RPC to an extant transaction,
-// stopping in the event the transaction is aborted or committed after
-// attempting to resolve the intents. When the heartbeat stops, the transaction
-// is unregistered from the coordinator.
-//
-// TODO(dan): The Context we use for this is currently the one from the first
-// request in a Txn, but the semantics of this aren't good. Each context has its
-// own associated lifetime and we're ignoring all but the first. It happens now
-// that we pass the same one in every request, but it's brittle to rely on this
-// forever.
-// TODO(wiz): Update (*DBServer).Batch to not use context.TODO().
-func (tc *TxnCoordSender) heartbeatLoop(ctx context.Context, txnID uuid.UUID) {
-	var tickChan <-chan time.Time
-	{
-		ticker := time.NewTicker(tc.heartbeatInterval)
-		tickChan = ticker.C
-		defer ticker.Stop()
+// maybeStartHeartbeatLoopLocked starts the heartbeat loop unless it is
+// already running. It assumes the lock is held.
+func (tc *TxnCoordSender) maybeStartHeartbeatLoopLocked() error {
+	if tc.heartbeating {
+		return nil
 	}
-	defer func() {
-		tc.Lock()
-		duration, restarts, status := tc.unregisterTxnLocked(txnID)
-		tc.Unlock()
-		tc.updateStats(duration, restarts, status, false)
-	}()
+	interval := tc.heartbeatInterval
+	if err := tc.stopper.RunAsyncTask(func() {
+		tc.heartbeatLoop(interval)
+	}); err != nil {
+		return err
+	}
+	tc.heartbeating = true
+	return nil
+}
+
+// heartbeatLoop periodically heartbeats the transactions tracked by the
+// coordinator, and aborts the ones which were abandoned by their client.
+// The loop exits once there is no transaction left, to be restarted by the
+// next transaction which writes.
+func (tc *TxnCoordSender) heartbeatLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	var closer <-chan struct{}
-	// TODO(tschottdorf): this should join to the trace of the request
-	// which starts this goroutine.
 	sp := tc.tracer.StartSpan(opHeartbeatLoop)
 	defer sp.Finish()
-	ctx = opentracing.ContextWithSpan(ctx, sp)
+	ctx := opentracing.ContextWithSpan(context.Background(), sp)
 
-	{
-		tc.Lock()
-		txnMeta := tc.txns[txnID] // do not leak to outer scope
-		closer = txnMeta.txnEnd
-		tc.Unlock()
-	}
-	if closer == nil {
-		// Avoid race in which a Txn is cleaned up before the heartbeat
-		// goroutine gets a chance to start.
-		return
-	}
-	// Loop with ticker for periodic heartbeats.
 	for {
 		select {
-		case <-tickChan:
-			if !tc.heartbeat(ctx, txnID) {
+		case <-ticker.C:
+			if !tc.heartbeatTxns(ctx, interval) {
 				return
 			}
-		case <-closer:
-			// Transaction finished normally.
-			return
-		case <-ctx.Done():
-			// Note that if ctx is not cancellable, then ctx.Done() returns a nil
-			// channel, which blocks forever. In this case, the heartbeat loop is
-			// responsible for timing out transactions. If ctx.Done() is not nil, then
-			// then heartbeat loop ignores the timeout check and this case is
-			// responsible for client timeouts.
-			tc.tryAsyncAbort(txnID)
-			return
 		case <-tc.stopper.ShouldQuiesce():
 			return
 		}
 	}
 }
 
+// heartbeatTxns sends a single batch heartbeating all the pending
+// transactions which have been tracked for at least the heartbeat interval,
+// after aborting the ones which were abandoned. Returns false, marking the heartbeat loop as stopped, if there is no
+// transaction left to track.
+func (tc *TxnCoordSender) heartbeatTxns(ctx context.Context, interval time.Duration) bool {
+	var abandoned []uuid.UUID
+	var txns []roachpb.Transaction
+	{
+		tc.Lock()
+		nowNanos := tc.clock.PhysicalNow()
+		for txnID, txnMeta := range tc.txns {
+			// Determine whether this transaction should be considered
+			// abandoned. If its context is cancellable, we skip the timeout
+			// check and use the context's lifetime instead. Note that if the
+			// context is not cancellable, then Done() returns a nil channel.
+			if txnMeta.ctx.Err() != nil {
+				abandoned = append(abandoned, txnID)
+				continue
+			}
+			if txnMeta.txn.Status != roachpb.PENDING {
+				// The transaction has already been finalized, so we wait for
+				// the client to realize that and want to keep our state for
+				// the time being (to dish out the right error once it
+				// returns).
+				continue
+			}
+			if txnMeta.ctx.Done() == nil && txnMeta.hasClientAbandonedCoord(nowNanos) {
+				if log.V(1) {
+					log.Infof(ctx, "transaction %s abandoned; stopping heartbeat", txnMeta.txn)
+				}
+				abandoned = append(abandoned, txnID)
+				continue
+			}
+			if timeutil.Since(txnMeta.registered) < interval {
+				continue
+			}
+			txns = append(txns, txnMeta.txn.Clone())
+		}
+		tc.Unlock()
+	}
+
+	for _, txnID := range abandoned {
+		tc.tryAsyncAbort(txnID)
+		tc.Lock()
+		if _, ok := tc.txns[txnID]; ok {
+			duration, restarts, status := tc.unregisterTxnLocked(txnID)
+			tc.updateStats(duration, restarts, status, false)
+		}
+		tc.Unlock()
+	}
+
+	if len(txns) > 0 {
+		tc.heartbeatBatch(ctx, txns)
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+	if len(tc.txns) == 0 {
+		tc.heartbeating = false
+		return false
+	}
+	return true
+}
+
 // tryAsyncAbort (synchronously) grabs a copy of the txn proto and the intents
 // (which it then clears from txnMeta), and asynchronously tries to abort the
 // transaction.
 func (tc *TxnCoordSender) tryAsyncAbort(txnID uuid.UUID) {
 	tc.Lock()
-	txnMeta := tc.txns[txnID]
+	txnMeta, ok := tc.txns[txnID]
+	if !ok {
+		// The transaction ended in the meantime.
+		tc.Unlock()
+		return
+	}
 	// Clone the intents and the txn to avoid data races.
 	intentSpans, _ := roachpb.MergeSpans(append([]roachpb.Span(nil), txnMeta.keys...))
 	txnMeta.keys = nil
@@ -668,33 +726,44 @@ func (tc *TxnCoordSender) tryAsyncAbort(txnID uuid.UUID) {
 	}
 }
 
-func (tc *TxnCoordSender) heartbeat(ctx context.Context, txnID uuid.UUID) bool {
-	tc.Lock()
-	txnMeta := tc.txns[txnID]
-	txn := txnMeta.txn.Clone()
-	hasAbandoned := txnMeta.hasClientAbandonedCoord(tc.clock.PhysicalNow())
-	tc.Unlock()
+// heartbeatBatch heartbeats the given transactions with a single
+// non-transactional batch. If the batch fails, which a single missing
+// transaction record is enough for, each transaction is heartbeat on its
+// own instead.
+func (tc *TxnCoordSender) heartbeatBatch(ctx context.Context, txns []roachpb.Transaction) {
+	ba := roachpb.BatchRequest{}
+	now := tc.clock.Now()
+	for i := range txns {
+		hb := &roachpb.HeartbeatTxnRequest{
+			Now: now,
+			Txn: &txns[i].TxnMeta,
+		}
+		hb.Key = txns[i].Key
+		ba.Add(hb)
+	}
 
-	if txn.Status != roachpb.PENDING {
-		// A previous iteration has already determined that the transaction is
-		// already finalized, so we wait for the client to realize that and
-		// want to keep our state for the time being (to dish out the right
-		// error once it returns).
-		return true
-	}
-
-	// Before we send a heartbeat, determine whether this transaction should be
-	// considered abandoned. If so, exit heartbeat. If ctx.Done() is not nil, then
-	// it is a cancellable Context and we skip this check and use the ctx lifetime
-	// instead of a timeout.
-	if ctx.Done() == nil && hasAbandoned {
+	log.Tracef(ctx, "heartbeat %d transactions", len(txns))
+	br, pErr := tc.wrapped.Send(ctx, ba)
+	if pErr != nil {
 		if log.V(1) {
-			log.Infof(ctx, "transaction %s abandoned; stopping heartbeat", txnMeta.txn)
+			log.Infof(ctx, "batched heartbeat failed: %s", pErr)
 		}
-		tc.tryAsyncAbort(txnID)
-		return false
+		for _, txn := range txns {
+			tc.heartbeat(ctx, txn)
+		}
+		return
 	}
 
+	tc.Lock()
+	defer tc.Unlock()
+	for i, txn := range txns {
+		txn.Update(br.Responses[i].GetInner().(*roachpb.HeartbeatTxnResponse).Txn)
+		tc.updateHeartbeatedTxnLocked(txn)
+	}
+}
+
+// heartbeat sends a HeartbeatTxn RPC to a single transaction.
+func (tc *TxnCoordSender) heartbeat(ctx context.Context, txn roachpb.Transaction) {
 	ba := roachpb.BatchRequest{}
 	ba.Txn = &txn
 
@@ -722,15 +791,21 @@ func (tc *TxnCoordSender) heartbeat(ctx context.Context, txnID uuid.UUID) bool {
 		txn.Update(br.Responses[0].GetInner().(*roachpb.HeartbeatTxnResponse).Txn)
 	}
 
-	// Give the news to the txn in the txns map. This will update long-running
-	// transactions (which may find out that they have to restart in that way),
-	// but in particular makes sure that they notice when they've been aborted
-	// (in which case we'll give them an error on their next request).
 	tc.Lock()
-	tc.txns[txnID].txn.Update(&txn)
+	tc.updateHeartbeatedTxnLocked(txn)
 	tc.Unlock()
+}
 
-	return true
+// updateHeartbeatedTxnLocked gives the news from a heartbeat to the txn in the
+// txns map. This will update long-running transactions (which may find out
+// that they have to restart in that way), but in particular makes sure that
+// they notice when they've been aborted (in which case we'll give them an
+// error on their next request). It assumes the lock is held.
+func (tc *TxnCoordSender) updateHeartbeatedTxnLocked(txn roachpb.Transaction) {
+	// The transaction may have ended while it was being heartbeat.
+	if txnMeta, ok := tc.txns[*txn.ID]; ok {
+		txnMeta.txn.Update(&txn)
+	}
 }
 
 // updateState updates the transaction state in both the success and
@@ -864,13 +939,12 @@ func (tc *TxnCoordSender) updateState(
 					firstUpdateNanos: startNS,
 					lastUpdateNanos:  tc.clock.PhysicalNow(),
 					timeoutDuration:  tc.clientTimeout,
-					txnEnd:           make(chan struct{}),
+					registered:       timeutil.Now(),
+					ctx:              client.TxnContext(ctx),
 				}
 				tc.txns[txnID] = txnMeta
 
-				if err := tc.stopper.RunAsyncTask(func() {
-					tc.heartbeatLoop(ctx, txnID)
-				}); err != nil {
+				if err := tc.maybeStartHeartbeatLoopLocked(); err != nil {
 					// The system is already draining and we can't start the
 					// heartbeat. We refuse new transactions for now because
 					// they're likely not going to have all intents committed.
@@ -880,8 +954,7 @@ func (tc *TxnCoordSender) updateState(
 				}
 			} else {
 				// If this was a successful one phase commit, update stats
-				// directly as they won't otherwise be updated on heartbeat
-				// loop shutdown.
+				// directly as they won't otherwise be updated on cleanup.
 				etArgs, ok := br.Responses[len(br.Responses)-1].GetInner().(*roachpb.EndTransactionResponse)
 				tc.updateStats(tc.clock.PhysicalNow()-startNS, 0, newTxn.Status, ok && etArgs.OnePhaseCommit)
 			}
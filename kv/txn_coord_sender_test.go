@@ -51,19 +51,16 @@ func (f senderFn) Send(ctx context.Context, ba roachpb.BatchRequest) (*roachpb.B
 	return f(ctx, ba)
 }
 
-// teardownHeartbeats goes through the coordinator's active transactions and
-// has the associated heartbeat tasks quit. This is useful for tests which
-// don't finish transactions. This is safe to call multiple times.
+// teardownHeartbeats unregisters the coordinator's active transactions,
+// which has the heartbeat loop quit. This is useful for tests which don't
+// finish transactions. This is safe to call multiple times.
 func teardownHeartbeats(tc *TxnCoordSender) {
 	if r := recover(); r != nil {
 		panic(r)
 	}
 	tc.Lock()
-	for _, tm := range tc.txns {
-		if tm.txnEnd != nil {
-			close(tm.txnEnd)
-			tm.txnEnd = nil
-		}
+	for txnID := range tc.txns {
+		tc.unregisterTxnLocked(txnID)
 	}
 	defer tc.Unlock()
 }
@@ -355,6 +352,67 @@ func TestTxnCoordSenderHeartbeat(t *testing.T) {
 	assertTransactionAbortedError(t, err)
 }
 
+// TestTxnCoordSenderBatchedHeartbeats verifies that the heartbeats of
+// multiple transactions are sent in a single non-transactional batch, and
+// that the heartbeat loop stops once all the transactions have finished.
+func TestTxnCoordSenderBatchedHeartbeats(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, testSender := createTestDB(t)
+	defer s.Stop()
+
+	var batched int32
+	sender := NewTxnCoordSender(senderFn(func(ctx context.Context, ba roachpb.BatchRequest) (*roachpb.BatchResponse, *roachpb.Error) {
+		if _, ok := ba.GetArg(roachpb.HeartbeatTxn); ok && ba.Txn == nil && len(ba.Requests) == 2 {
+			atomic.AddInt32(&batched, 1)
+		}
+		return testSender.wrapped.Send(ctx, ba)
+	}), s.Clock, false, tracing.NewTracer(), s.Stopper, NewTxnMetrics(metric.NewRegistry()))
+	defer teardownHeartbeats(sender)
+	// Set heartbeat interval to 1ms for testing.
+	sender.heartbeatInterval = 1 * time.Millisecond
+
+	db := client.NewDB(sender)
+	txn1 := client.NewTxn(context.Background(), *db)
+	txn2 := client.NewTxn(context.Background(), *db)
+	if err := txn1.Put(roachpb.Key("a"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn2.Put(roachpb.Key("b"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	util.SucceedsSoon(t, func() error {
+		if atomic.LoadInt32(&batched) == 0 {
+			return errors.Errorf("expected batched heartbeat")
+		}
+		for _, txn := range []*client.Txn{txn1, txn2} {
+			hbTxn, pErr := getTxn(sender, &txn.Proto)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			if hbTxn.LastHeartbeat == nil {
+				return errors.Errorf("expected %s to be heartbeat", txn.Proto.ID)
+			}
+		}
+		return nil
+	})
+
+	if err := txn1.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn2.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	util.SucceedsSoon(t, func() error {
+		sender.Lock()
+		defer sender.Unlock()
+		if sender.heartbeating {
+			return errors.Errorf("expected heartbeat loop to stop")
+		}
+		return nil
+	})
+}
+
 // getTxn fetches the requested key and returns the transaction info.
 func getTxn(coord *TxnCoordSender, txn *roachpb.Transaction) (*roachpb.Transaction, *roachpb.Error) {
 	hb := &roachpb.HeartbeatTxnRequest{
@@ -855,8 +913,8 @@ func TestTxnCoordIdempotentCleanup(t *testing.T) {
 	sender.Unlock()
 
 	// For good measure, try to commit (which cleans up once more if it
-	// succeeds, which it may not if the previous cleanup has already
-	// terminated the heartbeat goroutine)
+	// succeeds, which it may not since the previous cleanup has already
+	// unregistered the transaction)
 	ba = txn.NewBatch()
 	ba.AddRawRequest(&roachpb.EndTransactionRequest{})
 	err := txn.Run(ba)
@@ -921,7 +979,7 @@ func TestTxnMultipleCoord(t *testing.T) {
 
 // TestTxnCoordSenderSingleRoundtripTxn checks that a batch which completely
 // holds the writing portion of a Txn (including EndTransaction) does not
-// launch a heartbeat goroutine at all.
+// launch the heartbeat loop at all.
 func TestTxnCoordSenderSingleRoundtripTxn(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	stopper := stop.NewStopper()
@@ -939,7 +997,7 @@ func TestTxnCoordSenderSingleRoundtripTxn(t *testing.T) {
 
 	// Stop the stopper manually, prior to trying the transaction. This has the
 	// effect of returning a NodeUnavailableError for any attempts at launching
-	// a heartbeat goroutine.
+	// the heartbeat loop.
 	stopper.Stop()
 
 	var ba roachpb.BatchRequest
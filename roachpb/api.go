@@ -155,6 +155,7 @@ func (rh *ResponseHeader) combine(otherRH ResponseHeader) error {
 	}
 	rh.ResumeSpan = otherRH.ResumeSpan
 	rh.NumKeys += otherRH.NumKeys
+	rh.NumBytes += otherRH.NumBytes
 	return nil
 }
 
@@ -248,6 +249,20 @@ func (af *ChangeFrozenResponse) combine(c combinable) error {
 
 var _ combinable = &ChangeFrozenResponse{}
 
+// combine implements the combinable interface.
+func (er *ExportResponse) combine(c combinable) error {
+	if er != nil {
+		otherER := c.(*ExportResponse)
+		if err := er.ResponseHeader.combine(otherER.Header()); err != nil {
+			return err
+		}
+		er.Files = append(er.Files, otherER.Files...)
+	}
+	return nil
+}
+
+var _ combinable = &ExportResponse{}
+
 // Header implements the Request interface.
 func (rh Span) Header() Span {
 	return rh
@@ -383,6 +398,18 @@ func (*CheckConsistencyRequest) Method() Method { return CheckConsistency }
 // Method implements the Request interface.
 func (*ChangeFrozenRequest) Method() Method { return ChangeFrozen }
 
+// Method implements the Request interface.
+func (*IngestRequest) Method() Method { return Ingest }
+
+// Method implements the Request interface.
+func (*ExportRequest) Method() Method { return Export }
+
+// Method implements the Request interface.
+func (*AddSSTableRequest) Method() Method { return AddSSTable }
+
+// Method implements the Request interface.
+func (*ClearRangeRequest) Method() Method { return ClearRange }
+
 // Method implements the Request interface.
 func (*BeginTransactionRequest) Method() Method { return BeginTransaction }
 
@@ -398,6 +425,12 @@ func (*AdminMergeRequest) Method() Method { return AdminMerge }
 // Method implements the Request interface.
 func (*AdminTransferLeaseRequest) Method() Method { return AdminTransferLease }
 
+// Method implements the Request interface.
+func (*AdminChangeReplicasRequest) Method() Method { return AdminChangeReplicas }
+
+// Method implements the Request interface.
+func (*AdminScatterRequest) Method() Method { return AdminScatter }
+
 // Method implements the Request interface.
 func (*HeartbeatTxnRequest) Method() Method { return HeartbeatTxn }
 
@@ -503,6 +536,30 @@ func (afr *ChangeFrozenRequest) ShallowCopy() Request {
 	return &shallowCopy
 }
 
+// ShallowCopy implements the Request interface.
+func (ir *IngestRequest) ShallowCopy() Request {
+	shallowCopy := *ir
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (er *ExportRequest) ShallowCopy() Request {
+	shallowCopy := *er
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (ar *AddSSTableRequest) ShallowCopy() Request {
+	shallowCopy := *ar
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (cr *ClearRangeRequest) ShallowCopy() Request {
+	shallowCopy := *cr
+	return &shallowCopy
+}
+
 // ShallowCopy implements the Request interface.
 func (btr *BeginTransactionRequest) ShallowCopy() Request {
 	shallowCopy := *btr
@@ -533,6 +590,18 @@ func (atlr *AdminTransferLeaseRequest) ShallowCopy() Request {
 	return &shallowCopy
 }
 
+// ShallowCopy implements the Request interface.
+func (acrr *AdminChangeReplicasRequest) ShallowCopy() Request {
+	shallowCopy := *acrr
+	return &shallowCopy
+}
+
+// ShallowCopy implements the Request interface.
+func (asr *AdminScatterRequest) ShallowCopy() Request {
+	shallowCopy := *asr
+	return &shallowCopy
+}
+
 // ShallowCopy implements the Request interface.
 func (htr *HeartbeatTxnRequest) ShallowCopy() Request {
 	shallowCopy := *htr
@@ -622,6 +691,10 @@ func (*ScanRequest) createReply() Response               { return &ScanResponse{
 func (*ReverseScanRequest) createReply() Response        { return &ReverseScanResponse{} }
 func (*CheckConsistencyRequest) createReply() Response   { return &CheckConsistencyResponse{} }
 func (*ChangeFrozenRequest) createReply() Response       { return &ChangeFrozenResponse{} }
+func (*IngestRequest) createReply() Response             { return &IngestResponse{} }
+func (*ExportRequest) createReply() Response             { return &ExportResponse{} }
+func (*AddSSTableRequest) createReply() Response         { return &AddSSTableResponse{} }
+func (*ClearRangeRequest) createReply() Response         { return &ClearRangeResponse{} }
 func (*BeginTransactionRequest) createReply() Response   { return &BeginTransactionResponse{} }
 func (*EndTransactionRequest) createReply() Response     { return &EndTransactionResponse{} }
 func (*AdminSplitRequest) createReply() Response         { return &AdminSplitResponse{} }
@@ -641,6 +714,11 @@ func (*TransferLeaseRequest) createReply() Response      { return &RequestLeaseR
 func (*ComputeChecksumRequest) createReply() Response    { return &ComputeChecksumResponse{} }
 func (*VerifyChecksumRequest) createReply() Response     { return &VerifyChecksumResponse{} }
 
+func (*AdminChangeReplicasRequest) createReply() Response {
+	return &AdminChangeReplicasResponse{}
+}
+func (*AdminScatterRequest) createReply() Response { return &AdminScatterResponse{} }
+
 // NewGet returns a Request initialized to get the value at key.
 func NewGet(key Key) Request {
 	return &GetRequest{
@@ -790,7 +868,6 @@ func (*ConditionalPutRequest) flags() int     { return isRead | isWrite | isTxn
 func (*InitPutRequest) flags() int            { return isRead | isWrite | isTxn | isTxnWrite }
 func (*IncrementRequest) flags() int          { return isRead | isWrite | isTxn | isTxnWrite }
 func (*DeleteRequest) flags() int             { return isWrite | isTxn | isTxnWrite }
-func (*DeleteRangeRequest) flags() int        { return isWrite | isTxn | isTxnWrite | isRange }
 func (*ScanRequest) flags() int               { return isRead | isRange | isTxn }
 func (*ReverseScanRequest) flags() int        { return isRead | isRange | isReverse | isTxn }
 func (*BeginTransactionRequest) flags() int   { return isWrite | isTxn }
@@ -798,7 +875,7 @@ func (*EndTransactionRequest) flags() int     { return isWrite | isTxn | isAlone
 func (*AdminSplitRequest) flags() int         { return isAdmin | isAlone }
 func (*AdminMergeRequest) flags() int         { return isAdmin | isAlone }
 func (*AdminTransferLeaseRequest) flags() int { return isAdmin | isAlone }
-func (*HeartbeatTxnRequest) flags() int       { return isWrite | isTxn }
+func (*HeartbeatTxnRequest) flags() int       { return isWrite }
 func (*GCRequest) flags() int                 { return isWrite | isRange }
 func (*PushTxnRequest) flags() int            { return isWrite }
 func (*RangeLookupRequest) flags() int        { return isRead | isTxn }
@@ -808,6 +885,15 @@ func (*NoopRequest) flags() int               { return isRead } // slightly spec
 func (*MergeRequest) flags() int              { return isWrite }
 func (*TruncateLogRequest) flags() int        { return isWrite }
 
+func (drr *DeleteRangeRequest) flags() int {
+	// Range tombstones are written outside of transactions, even when the
+	// span covers several ranges.
+	if drr.UseRangeTombstone {
+		return isWrite | isRange
+	}
+	return isWrite | isTxn | isTxnWrite | isRange
+}
+
 // TODO(tschottdorf): consider setting isAlone on RequestLeaseRequest and
 // LeaseTransferRequest.
 func (*RequestLeaseRequest) flags() int     { return isWrite }
@@ -816,3 +902,10 @@ func (*ComputeChecksumRequest) flags() int  { return isWrite }
 func (*VerifyChecksumRequest) flags() int   { return isWrite }
 func (*CheckConsistencyRequest) flags() int { return isAdmin | isRange }
 func (*ChangeFrozenRequest) flags() int     { return isWrite | isRange }
+func (*IngestRequest) flags() int           { return isWrite | isRange }
+func (*ExportRequest) flags() int           { return isRead | isRange }
+func (*AddSSTableRequest) flags() int       { return isWrite | isRange }
+func (*ClearRangeRequest) flags() int       { return isWrite | isRange | isAlone }
+
+func (*AdminChangeReplicasRequest) flags() int { return isAdmin | isAlone }
+func (*AdminScatterRequest) flags() int        { return isAdmin | isAlone }
@@ -241,6 +241,10 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 		checkConsistency   int
 		noop               int
 		changeFrozen       int
+		ingest             int
+		export             int
+		addSSTable         int
+		clearRange         int
 	}
 	for _, union := range ba.Requests {
 		switch union.GetInner().(type) {
@@ -300,6 +304,14 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 			counts.noop++
 		case *ChangeFrozenRequest:
 			counts.changeFrozen++
+		case *IngestRequest:
+			counts.ingest++
+		case *ExportRequest:
+			counts.export++
+		case *AddSSTableRequest:
+			counts.addSSTable++
+		case *ClearRangeRequest:
+			counts.clearRange++
 		default:
 			panic(fmt.Sprintf("unsupported type %T", union.GetInner()))
 		}
@@ -334,6 +346,10 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 		checkConsistency   []CheckConsistencyResponse
 		noop               []NoopResponse
 		changeFrozen       []ChangeFrozenResponse
+		ingest             []IngestResponse
+		export             []ExportResponse
+		addSSTable         []AddSSTableResponse
+		clearRange         []ClearRangeResponse
 	}
 	for i, union := range ba.Requests {
 		var reply Response
@@ -478,6 +494,26 @@ func (ba *BatchRequest) CreateReply() *BatchResponse {
 				bufs.changeFrozen = make([]ChangeFrozenResponse, counts.changeFrozen)
 			}
 			reply, bufs.changeFrozen = &bufs.changeFrozen[0], bufs.changeFrozen[1:]
+		case *IngestRequest:
+			if bufs.ingest == nil {
+				bufs.ingest = make([]IngestResponse, counts.ingest)
+			}
+			reply, bufs.ingest = &bufs.ingest[0], bufs.ingest[1:]
+		case *ExportRequest:
+			if bufs.export == nil {
+				bufs.export = make([]ExportResponse, counts.export)
+			}
+			reply, bufs.export = &bufs.export[0], bufs.export[1:]
+		case *AddSSTableRequest:
+			if bufs.addSSTable == nil {
+				bufs.addSSTable = make([]AddSSTableResponse, counts.addSSTable)
+			}
+			reply, bufs.addSSTable = &bufs.addSSTable[0], bufs.addSSTable[1:]
+		case *ClearRangeRequest:
+			if bufs.clearRange == nil {
+				bufs.clearRange = make([]ClearRangeResponse, counts.clearRange)
+			}
+			reply, bufs.clearRange = &bufs.clearRange[0], bufs.clearRange[1:]
 		default:
 			panic(fmt.Sprintf("unsupported type %T", union.GetInner()))
 		}
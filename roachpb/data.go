@@ -923,12 +923,18 @@ var _ fmt.Stringer = &Lease{}
 
 func (l Lease) String() string {
 	start := time.Unix(0, l.Start.WallTime).UTC()
+	if l.Epoch != nil {
+		return fmt.Sprintf("replica %s %s epoch %d", l.Replica, start, *l.Epoch)
+	}
 	expiration := time.Unix(0, l.Expiration.WallTime).UTC()
 	return fmt.Sprintf("replica %s %s %s", l.Replica, start, expiration.Sub(start))
 }
 
 // Covers returns true if the given timestamp can be served by the Lease.
 // This is the case if the timestamp precedes the Lease's stasis period.
+// Epoch-based leases have no stasis period and never cover a timestamp on
+// their own; their validity depends on the liveness of the lease holder's
+// node.
 // Note that the fact that a lease convers a timestamp is not enough for the
 // holder of the lease to be able to serve a read with that timestamp;
 // pendingLeaderLeaseRequest.TransferInProgress() should also be consulted to
@@ -942,6 +948,15 @@ func (l Lease) OwnedBy(storeID StoreID) bool {
 	return l.Replica.StoreID == storeID
 }
 
+// IsLive returns whether the node is considered live at the given
+// timestamp. The liveness of a node is only relied upon up to the maximum
+// clock offset before its expiration, which plays the role of the stasis
+// period of expiration-based leases for the epoch-based leases held by the
+// node.
+func (l *Liveness) IsLive(now hlc.Timestamp, maxOffset time.Duration) bool {
+	return now.Less(l.Expiration.Add(-maxOffset.Nanoseconds(), 0))
+}
+
 // AsIntents takes a slice of spans and returns it as a slice of intents for
 // the given transaction.
 func AsIntents(spans []Span, txn *Transaction) []Intent {
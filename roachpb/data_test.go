@@ -813,6 +813,31 @@ func TestLeaseCovers(t *testing.T) {
 	}
 }
 
+func TestLivenessIsLive(t *testing.T) {
+	const maxOffset = 10 * time.Nanosecond
+	l := Liveness{
+		NodeID:     1,
+		Epoch:      1,
+		Expiration: hlc.ZeroTimestamp.Add(100, 0),
+	}
+	for _, test := range []struct {
+		now  int64
+		live bool
+	}{
+		{0, true},
+		{89, true},
+		// The node is not considered live within the max offset of its
+		// expiration.
+		{90, false},
+		{100, false},
+		{101, false},
+	} {
+		if live := l.IsLive(hlc.ZeroTimestamp.Add(test.now, 0), maxOffset); live != test.live {
+			t.Errorf("at %d: expected live=%t, got %t", test.now, test.live, live)
+		}
+	}
+}
+
 func BenchmarkValueSetBytes(b *testing.B) {
 	v := Value{}
 	bytes := make([]byte, 16)
@@ -141,6 +141,18 @@ func (r RangeDescriptor) GetReplicaDescriptorByID(replicaID ReplicaID) (ReplicaD
 	return ReplicaDescriptor{}, false
 }
 
+// Voters returns the replicas of the range which are voters, i.e. which are
+// not learners.
+func (r RangeDescriptor) Voters() []ReplicaDescriptor {
+	voters := make([]ReplicaDescriptor, 0, len(r.Replicas))
+	for _, repDesc := range r.Replicas {
+		if !repDesc.IsLearner() {
+			voters = append(voters, repDesc)
+		}
+	}
+	return voters
+}
+
 // IsInitialized returns false if this descriptor represents an
 // uninitialized range.
 // TODO(bdarnell): unify this with Validate().
@@ -184,6 +196,20 @@ func (r ReplicaDescriptor) Validate() error {
 	return nil
 }
 
+// GetType returns the type of the replica, which is VOTER unless set.
+func (r ReplicaDescriptor) GetType() ReplicaType {
+	if r.Type == nil {
+		return VOTER
+	}
+	return *r.Type
+}
+
+// IsLearner returns whether the replica is a learner, which receives the Raft
+// log but doesn't vote.
+func (r ReplicaDescriptor) IsLearner() bool {
+	return r.GetType() == LEARNER
+}
+
 // FractionUsed computes the fraction of storage capacity that is in use.
 func (sc StoreCapacity) FractionUsed() float64 {
 	if sc.Capacity == 0 {
@@ -200,3 +226,36 @@ func (s StoreDescriptor) CombinedAttrs() *Attributes {
 	a = append(a, s.Attrs.Attrs...)
 	return &Attributes{Attrs: a}
 }
+
+// String returns the tier formatted as "key=value".
+func (t Tier) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// String returns the tiers of the locality formatted as a comma-separated
+// list of "key=value", as passed to the --locality flag.
+func (l Locality) String() string {
+	tiers := make([]string, len(l.Tiers))
+	for i, tier := range l.Tiers {
+		tiers[i] = tier.String()
+	}
+	return strings.Join(tiers, ",")
+}
+
+// DiversityScore compares two localities and returns a score between 0 and
+// 1: 1 if they differ in their first tier, e.g. the nodes are in different
+// regions, decreasing the more tiers they share, down to 0 if they share
+// all their tiers or don't have any in common to compare. Only the tiers
+// present in both localities are compared.
+func (l Locality) DiversityScore(other Locality) float64 {
+	length := len(l.Tiers)
+	if len(other.Tiers) < length {
+		length = len(other.Tiers)
+	}
+	for i := 0; i < length; i++ {
+		if l.Tiers[i] != other.Tiers[i] {
+			return float64(length-i) / float64(length)
+		}
+	}
+	return 0
+}
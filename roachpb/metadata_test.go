@@ -87,3 +87,58 @@ func TestRangeDescriptorMissingReplica(t *testing.T) {
 		t.Fatalf("unexpectedly got nontrivial return: %s", r)
 	}
 }
+
+func TestRangeDescriptorVoters(t *testing.T) {
+	desc := RangeDescriptor{
+		Replicas: []ReplicaDescriptor{
+			{NodeID: 1, StoreID: 1, ReplicaID: 1},
+			{NodeID: 2, StoreID: 2, ReplicaID: 2, Type: LEARNER.Enum()},
+			{NodeID: 3, StoreID: 3, ReplicaID: 3, Type: VOTER.Enum()},
+		},
+	}
+	if desc.Replicas[0].IsLearner() || !desc.Replicas[1].IsLearner() || desc.Replicas[2].IsLearner() {
+		t.Fatalf("unexpected replica types in %+v", desc.Replicas)
+	}
+	voters := desc.Voters()
+	if len(voters) != 2 || voters[0].ReplicaID != 1 || voters[1].ReplicaID != 3 {
+		t.Fatalf("expected replicas 1 and 3 to be voters, got %+v", voters)
+	}
+}
+
+func TestLocalityDiversityScore(t *testing.T) {
+	makeLocality := func(values ...string) Locality {
+		keys := []string{"region", "zone", "rack"}
+		var l Locality
+		for i, v := range values {
+			l.Tiers = append(l.Tiers, Tier{Key: keys[i], Value: v})
+		}
+		return l
+	}
+
+	testCases := []struct {
+		a, b     Locality
+		expected float64
+	}{
+		{makeLocality(), makeLocality(), 0},
+		{makeLocality("us"), makeLocality(), 0},
+		{makeLocality("us"), makeLocality("us"), 0},
+		{makeLocality("us"), makeLocality("eu"), 1},
+		{makeLocality("us", "a"), makeLocality("eu", "a"), 1},
+		{makeLocality("us", "a"), makeLocality("us", "b"), 0.5},
+		{makeLocality("us", "a", "1"), makeLocality("us", "a", "2"), 1.0 / 3},
+		{makeLocality("us", "a", "1"), makeLocality("us", "b"), 0.5},
+		{makeLocality("us", "a", "1"), makeLocality("us", "a"), 0},
+	}
+	for i, tc := range testCases {
+		if score := tc.a.DiversityScore(tc.b); score != tc.expected {
+			t.Errorf("%d: expected diversity score %.2f between %s and %s, got %.2f",
+				i, tc.expected, tc.a, tc.b, score)
+		}
+		if score := tc.b.DiversityScore(tc.a); score != tc.expected {
+			t.Errorf("%d: diversity score between %s and %s isn't symmetric", i, tc.a, tc.b)
+		}
+	}
+	if a, e := makeLocality("us", "a", "1").String(), "region=us,zone=a,rack=1"; a != e {
+		t.Errorf("expected %s, got %s", e, a)
+	}
+}
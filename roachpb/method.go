@@ -122,4 +122,25 @@ const (
 	// ChangeFrozen freezes or unfreezes all Ranges with StartKey in a given
 	// key span.
 	ChangeFrozen
+	// Ingest writes a batch of MVCC key/value pairs directly into a range,
+	// bypassing the transactional write path. It is used for bulk loading
+	// data, e.g. by IMPORT.
+	Ingest
+	// Export writes the data in a key span, as of a timestamp, to an SSTable
+	// in external storage. It is used by BACKUP.
+	Export
+	// AddSSTable links an externally built SSTable directly into the storage
+	// engine of a range's replicas. It is used for bulk loading data, e.g. by
+	// RESTORE.
+	AddSSTable
+	// ClearRange removes all the data in a key span directly from the storage
+	// engine of a range's replicas, bypassing MVCC. It is used to delete the
+	// data of dropped tables and indexes.
+	ClearRange
+	// AdminChangeReplicas adds or removes replicas of a range on the given
+	// stores.
+	AdminChangeReplicas
+	// AdminScatter moves the replicas and the lease of a range to randomly
+	// chosen stores.
+	AdminScatter
 )
@@ -30,6 +30,8 @@ const (
 	RootUser = "root"
 )
 
+var errPasswordMismatch = errors.Errorf("password authentication failed")
+
 // GetCertificateUser extract the username from a client certificate.
 func GetCertificateUser(tlsState *tls.ConnectionState) (string, error) {
 	if tlsState == nil {
@@ -114,3 +116,35 @@ func UserAuthHook(insecureMode bool, tlsState *tls.ConnectionState) (
 		return nil
 	}, nil
 }
+
+// UserAuthPasswordHook builds an authentication hook checking the password
+// given by a client against the hash of the password of the requested user,
+// if it exists and has one.
+func UserAuthPasswordHook(
+	insecureMode bool, password string, userExists bool, hashedPassword []byte,
+) func(string, bool) error {
+	return func(requestedUser string, public bool) error {
+		if len(requestedUser) == 0 {
+			return errors.Errorf("user is missing")
+		}
+
+		if !public {
+			return errors.Errorf("password authentication is only available for client connections")
+		}
+
+		// If running in insecure mode, we have nothing to verify it against.
+		if insecureMode {
+			return nil
+		}
+
+		if requestedUser == RootUser {
+			return errors.Errorf("user %s must use certificate authentication instead of password authentication", RootUser)
+		}
+		// The same error is returned for unknown users and wrong passwords,
+		// so that the existence of users cannot be probed.
+		if !userExists || len(hashedPassword) == 0 {
+			return errPasswordMismatch
+		}
+		return CompareHashAndPassword(hashedPassword, password)
+	}
+}
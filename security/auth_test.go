@@ -130,3 +130,47 @@ func TestAuthenticationHook(t *testing.T) {
 		}
 	}
 }
+
+func TestAuthenticationPasswordHook(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	hashedPassword, err := security.HashPassword([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		insecure           bool
+		user, password     string
+		userExists         bool
+		hashedPassword     []byte
+		publicHookSuccess  bool
+		privateHookSuccess bool
+	}{
+		// Insecure mode, any password.
+		{true, "foo", "wrong", true, hashedPassword, true, false},
+		// Insecure mode, no user.
+		{true, "", "secret", true, hashedPassword, false, false},
+		// Secure mode, good password.
+		{false, "foo", "secret", true, hashedPassword, true, false},
+		// Secure mode, wrong password.
+		{false, "foo", "wrong", true, hashedPassword, false, false},
+		// Secure mode, unknown user.
+		{false, "foo", "secret", false, nil, false, false},
+		// Secure mode, user without a password.
+		{false, "foo", "", true, nil, false, false},
+		// Secure mode, root user.
+		{false, security.RootUser, "secret", true, hashedPassword, false, false},
+	}
+
+	for tcNum, tc := range testCases {
+		hook := security.UserAuthPasswordHook(tc.insecure, tc.password, tc.userExists, tc.hashedPassword)
+		err := hook(tc.user, true /*public*/)
+		if (err == nil) != tc.publicHookSuccess {
+			t.Fatalf("#%d: expected success=%t, got err=%v", tcNum, tc.publicHookSuccess, err)
+		}
+		err = hook(tc.user, false /*not public*/)
+		if (err == nil) != tc.privateHookSuccess {
+			t.Fatalf("#%d: expected success=%t, got err=%v", tcNum, tc.privateHookSuccess, err)
+		}
+	}
+}
@@ -69,6 +69,16 @@ func HashPassword(raw []byte) ([]byte, error) {
 	return bcrypt.GenerateFromPassword(raw, bcryptCost)
 }
 
+// CompareHashAndPassword tests that the provided bytes are equivalent to the
+// hash of the supplied password. If they are not equivalent, returns an
+// error.
+func CompareHashAndPassword(hashedPassword []byte, password string) error {
+	if err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password)); err != nil {
+		return errPasswordMismatch
+	}
+	return nil
+}
+
 // PromptForPasswordAndHash prompts for a password on the stdin twice,
 // and if both match, returns a bcrypt hashed password.
 func PromptForPasswordAndHash() ([]byte, error) {
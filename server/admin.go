@@ -44,6 +44,7 @@ import (
 	"github.com/cockroachdb/cockroach/sql"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/cockroachdb/cockroach/util/retry"
@@ -452,12 +453,17 @@ func (s *adminServer) TableDetails(
 func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRequest) (
 	*serverpb.TableStatsResponse, error,
 ) {
-	// Get table span.
+	// Get table and index spans.
 	var tableSpan roachpb.Span
+	var indexSpans []sql.IndexSpan
 	var iexecutor sql.InternalExecutor
 	if err := s.server.db.Txn(func(txn *client.Txn) error {
 		var err error
 		tableSpan, err = iexecutor.GetTableSpan(s.getUser(req), txn, req.Database, req.Table)
+		if err != nil {
+			return err
+		}
+		indexSpans, err = iexecutor.GetTableIndexSpans(s.getUser(req), txn, req.Database, req.Table)
 		return err
 	}); err != nil {
 		return nil, s.serverError(err)
@@ -472,6 +478,16 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 		return nil, s.serverError(err)
 	}
 
+	indexKeySpans := make([]roachpb.RSpan, len(indexSpans))
+	for i, span := range indexSpans {
+		if indexKeySpans[i].Key, err = keys.Addr(span.Span.Key); err != nil {
+			return nil, s.serverError(err)
+		}
+		if indexKeySpans[i].EndKey, err = keys.Addr(span.Span.EndKey); err != nil {
+			return nil, s.serverError(err)
+		}
+	}
+
 	// Get current range descriptors for table. This is done by scanning over
 	// meta2 keys for the range.
 	rangeDescKVs, err := s.server.db.Scan(keys.RangeMetaKey(startKey), keys.RangeMetaKey(endKey), 0)
@@ -495,15 +511,22 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 	tableStatResponse := serverpb.TableStatsResponse{
 		NodeCount:  int64(len(nodeIDs)),
 		RangeCount: int64(len(rangeDescKVs)),
+		IndexStats: make([]serverpb.TableStatsResponse_IndexStats, len(indexSpans)),
+	}
+	for i, span := range indexSpans {
+		tableStatResponse.IndexStats[i].IndexName = span.Name
 	}
 	type nodeResponse struct {
-		nodeID roachpb.NodeID
-		resp   *serverpb.SpanStatsResponse
-		err    error
+		nodeID     roachpb.NodeID
+		resp       *serverpb.SpanStatsResponse
+		indexResps []*serverpb.SpanStatsResponse
+		err        error
 	}
 
-	// Send a SpanStats query to each node. Set a timeout on the context for
-	// these queries.
+	// Send a SpanStats query to each node, followed by a query for each index
+	// which only counts the ranges whose first replica is on the node, so that
+	// the per-index statistics count every range once. Set a timeout on the
+	// context for these queries.
 	responses := make(chan nodeResponse)
 	ctx, cancel := context.WithTimeout(ctx, base.NetworkTimeout)
 	defer cancel()
@@ -511,6 +534,7 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 		nodeID := nodeID
 		if err := s.server.stopper.RunAsyncTask(func() {
 			var spanResponse *serverpb.SpanStatsResponse
+			var indexResponses []*serverpb.SpanStatsResponse
 			client, err := s.server.status.dialNode(nodeID)
 			if err == nil {
 				req := serverpb.SpanStatsRequest{
@@ -520,11 +544,26 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 				}
 				spanResponse, err = client.SpanStats(ctx, &req)
 			}
+			for _, span := range indexKeySpans {
+				if err != nil {
+					break
+				}
+				req := serverpb.SpanStatsRequest{
+					StartKey:         span.Key,
+					EndKey:           span.EndKey,
+					NodeID:           nodeID.String(),
+					FirstReplicaOnly: true,
+				}
+				var indexResponse *serverpb.SpanStatsResponse
+				indexResponse, err = client.SpanStats(ctx, &req)
+				indexResponses = append(indexResponses, indexResponse)
+			}
 
 			response := nodeResponse{
-				nodeID: nodeID,
-				resp:   spanResponse,
-				err:    err,
+				nodeID:     nodeID,
+				resp:       spanResponse,
+				indexResps: indexResponses,
+				err:        err,
 			}
 			select {
 			case responses <- response:
@@ -552,6 +591,11 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 			} else {
 				tableStatResponse.Stats.Add(resp.resp.TotalStats)
 				tableStatResponse.ReplicaCount += int64(resp.resp.RangeCount)
+				for i, indexResp := range resp.indexResps {
+					indexStats := &tableStatResponse.IndexStats[i]
+					indexStats.Stats.Add(indexResp.TotalStats)
+					indexStats.RangeCount += int64(indexResp.RangeCount)
+				}
 			}
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -565,7 +609,7 @@ func (s *adminServer) TableStats(ctx context.Context, req *serverpb.TableStatsRe
 func (s *adminServer) Users(ctx context.Context, req *serverpb.UsersRequest) (*serverpb.UsersResponse, error) {
 	args := sql.SessionArgs{User: s.getUser(req)}
 	session := sql.NewSession(ctx, args, s.server.sqlExecutor, nil)
-	query := "SELECT username FROM system.users"
+	query := "SELECT username FROM system.users WHERE NOT isRole"
 	r := s.server.sqlExecutor.ExecuteStatements(session, query, nil)
 	if err := s.checkQueryResults(r.ResultList, 1); err != nil {
 		return nil, s.serverError(err)
@@ -779,6 +823,34 @@ func (s *adminServer) Health(ctx context.Context, req *serverpb.HealthRequest) (
 	return &serverpb.HealthResponse{}, nil
 }
 
+// Compact compacts the requested span of the node's stores, or of the
+// requested store only, reclaiming the space taken by deleted data.
+func (s *adminServer) Compact(ctx context.Context, req *serverpb.CompactRequest) (*serverpb.CompactResponse, error) {
+	if len(req.EndKey) > 0 && bytes.Compare(req.StartKey, req.EndKey) >= 0 {
+		return nil, grpc.Errorf(codes.InvalidArgument,
+			"start key %s must be less than end key %s", req.StartKey, req.EndKey)
+	}
+	compact := func(store *storage.Store) error {
+		log.Infof(ctx, "compacting store %d from %s to %s", store.Ident.StoreID, req.StartKey, req.EndKey)
+		return store.Engine().CompactRange(req.StartKey, req.EndKey)
+	}
+	var err error
+	if req.StoreID != 0 {
+		var store *storage.Store
+		store, err = s.server.node.stores.GetStore(req.StoreID)
+		if err != nil {
+			return nil, grpc.Errorf(codes.NotFound, err.Error())
+		}
+		err = compact(store)
+	} else {
+		err = s.server.node.stores.VisitStores(compact)
+	}
+	if err != nil {
+		return nil, s.serverError(err)
+	}
+	return &serverpb.CompactResponse{}, nil
+}
+
 func (s *adminServer) Drain(req *serverpb.DrainRequest, stream serverpb.Admin_DrainServer) error {
 	on := make([]serverpb.DrainMode, len(req.On))
 	for i := range req.On {
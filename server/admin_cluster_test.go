@@ -108,6 +108,14 @@ func TestAdminAPITableStats(t *testing.T) {
 	if len(tsResponse.MissingNodes) > 0 {
 		t.Fatalf("expected no missing nodes, found %v", tsResponse.MissingNodes)
 	}
+	// The statistics of the primary index count a single replica of its range.
+	if len(tsResponse.IndexStats) != 1 {
+		t.Fatalf("expected statistics for 1 index, found %v", tsResponse.IndexStats)
+	}
+	if indexStats := tsResponse.IndexStats[0]; indexStats.IndexName != "primary" ||
+		indexStats.RangeCount != 1 || indexStats.Stats.KeyCount != 10 {
+		t.Fatalf("unexpected primary index statistics %+v", indexStats)
+	}
 
 	// Kill a node, ensure it shows up in MissingNodes and that ReplicaCount is
 	// lower.
@@ -37,6 +37,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/base"
 	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/sql"
@@ -251,16 +252,18 @@ func TestAdminAPIDatabases(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// We should have three databases:
+	// We should have five databases:
 	// - system database
+	// - crdb_internal
 	// - information_schema
+	// - pg_catalog
 	// - newly created test database
-	if a, e := len(resp.Databases), 3; a != e {
+	if a, e := len(resp.Databases), 5; a != e {
 		t.Fatalf("length of result %d != expected %d", a, e)
 	}
 
 	sort.Strings(resp.Databases)
-	for i, e := range []string{"information_schema", "system", testdb} {
+	for i, e := range []string{"crdb_internal", "information_schema", "pg_catalog", "system", testdb} {
 		if a := resp.Databases[i]; a != e {
 			t.Fatalf("database name %s != expected %s", a, e)
 		}
@@ -799,6 +802,33 @@ func TestClusterAPI(t *testing.T) {
 	})
 }
 
+func TestAdminAPICompact(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	testCases := []struct {
+		req      serverpb.CompactRequest
+		expError string
+	}{
+		{serverpb.CompactRequest{}, ""},
+		{serverpb.CompactRequest{StoreID: 1, StartKey: roachpb.Key("a"), EndKey: roachpb.Key("b")}, ""},
+		{serverpb.CompactRequest{StoreID: 1, EndKey: roachpb.Key("b")}, ""},
+		{serverpb.CompactRequest{StoreID: 42}, "store 42 not found"},
+		{serverpb.CompactRequest{StartKey: roachpb.Key("b"), EndKey: roachpb.Key("a")}, "must be less than end key"},
+	}
+	for i, tc := range testCases {
+		err := apiPost(s, "compact", &tc.req, &serverpb.CompactResponse{})
+		if tc.expError == "" {
+			if err != nil {
+				t.Errorf("%d: unexpected error: %s", i, err)
+			}
+		} else if !testutils.IsError(err, tc.expError) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.expError, err)
+		}
+	}
+}
+
 func TestClusterFreeze(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
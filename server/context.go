@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -43,9 +45,10 @@ import (
 
 // Context defaults.
 const (
-	defaultCGroupMemPath = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
-	defaultMaxOffset     = 250 * time.Millisecond
-	defaultCacheSize     = 512 << 20 // 512 MB
+	defaultCGroupMemPath     = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	defaultMaxOffset         = 250 * time.Millisecond
+	defaultCacheSize         = 512 << 20 // 512 MB
+	defaultSQLMemoryPoolSize = 512 << 20 // 512 MB
 	// defaultMemtableBudget controls how much memory can be used for memory
 	// tables. The way we initialize RocksDB, 100% (32 MB) of this setting can be
 	// used for memory tables and each memory table will be 25% of the size (8
@@ -58,7 +61,12 @@ const (
 	defaultScanMaxIdleTime          = 5 * time.Second
 	defaultMetricsSampleInterval    = 10 * time.Second
 	defaultTimeUntilStoreDead       = 5 * time.Minute
+	defaultDrainWait                = 10 * time.Second
 	defaultStorePath                = "cockroach-data"
+	defaultTempStorageCacheSize     = 8 << 20 // 8 MB
+	tempStorageDirName              = "cockroach-temp"
+	defaultRaftLogCacheSize         = 8 << 20 // 8 MB
+	raftLogDirName                  = "raft-log"
 	defaultReservationsEnabled      = true
 
 	minimumNetworkFileDescriptors     = 256
@@ -83,6 +91,14 @@ type Context struct {
 	// in zone configs.
 	Attrs string
 
+	// Locality is a comma-separated list of key=value tiers describing the
+	// location of the node, from the most inclusive to the most specific. The
+	// tiers are gossiped with the node descriptor, where the allocator uses
+	// them to spread the replicas of ranges across localities, and are added
+	// to the node attributes, where the constraints of the zone configs match
+	// them.
+	Locality string
+
 	// JoinList is a list of node addresses that act as bootstrap hosts for
 	// connecting to the gossip network. Each item in the list can actually be
 	// multiple comma-separated addresses, kept for backward-compatibility.
@@ -92,6 +108,10 @@ type Context struct {
 	// The value is split evenly between the stores if there are more than one.
 	CacheSize int64
 
+	// SQLMemoryPoolSize is the amount of memory in bytes that can be used by
+	// SQL clients to store row data in server RAM.
+	SQLMemoryPoolSize int64
+
 	// MemtableBudget is the amount of memory, per store, in bytes to use for
 	// the memory table.
 	// This value is no longer settable by the end user.
@@ -102,9 +122,16 @@ type Context struct {
 	// Engines is the storage instances specified by Stores.
 	Engines []engine.Engine
 
+	// RaftEngines maps the engines of the stores which keep their raft logs
+	// separately to the engines holding the logs.
+	RaftEngines map[engine.Engine]engine.Engine
+
 	// NodeAttributes is the parsed representation of Attrs.
 	NodeAttributes roachpb.Attributes
 
+	// NodeLocality is the parsed representation of Locality.
+	NodeLocality roachpb.Locality
+
 	// GossipBootstrapResolvers is a list of gossip resolvers used
 	// to find bootstrap nodes for connecting to the gossip network.
 	GossipBootstrapResolvers []resolver.Resolver
@@ -149,11 +176,24 @@ type Context struct {
 	// replication consistency check failure.
 	ConsistencyCheckPanicOnFailure bool
 
+	// RaftDisablePreVote disables the pre-vote phase of Raft elections.
+	// Environment Variable: COCKROACH_RAFT_DISABLE_PREVOTE
+	RaftDisablePreVote bool
+
+	// RaftDisableCheckQuorum disables the Raft check-quorum mode.
+	// Environment Variable: COCKROACH_RAFT_DISABLE_CHECK_QUORUM
+	RaftDisableCheckQuorum bool
+
 	// TimeUntilStoreDead is the time after which if there is no new gossiped
 	// information about a store, it is considered dead.
 	// Environment Variable: COCKROACH_TIME_UNTIL_STORE_DEAD
 	TimeUntilStoreDead time.Duration
 
+	// DrainWait is the maximum time a drain of the clients waits for the
+	// distributed SQL flows running on the node to finish.
+	// Environment Variable: COCKROACH_DRAIN_WAIT
+	DrainWait time.Duration
+
 	// ReservationsEnabled is a switch used to enable the add replica
 	// reservation system.
 	ReservationsEnabled bool
@@ -325,12 +365,14 @@ func MakeContext() Context {
 		Context:                  new(base.Context),
 		MaxOffset:                defaultMaxOffset,
 		CacheSize:                defaultCacheSize,
+		SQLMemoryPoolSize:        defaultSQLMemoryPoolSize,
 		MemtableBudget:           defaultMemtableBudget,
 		ScanInterval:             defaultScanInterval,
 		ScanMaxIdleTime:          defaultScanMaxIdleTime,
 		ConsistencyCheckInterval: defaultConsistencyCheckInterval,
 		MetricsSampleInterval:    defaultMetricsSampleInterval,
 		TimeUntilStoreDead:       defaultTimeUntilStoreDead,
+		DrainWait:                defaultDrainWait,
 		ReservationsEnabled:      defaultReservationsEnabled,
 		Stores: StoreSpecList{
 			Specs: []StoreSpec{{Path: defaultStorePath}},
@@ -370,7 +412,11 @@ func (ctx *Context) InitStores(stopper *stop.Stopper) error {
 				return fmt.Errorf("%f%% of memory is only %s bytes, which is below the minimum requirement of %s",
 					spec.SizePercent, humanizeutil.IBytes(sizeInBytes), humanizeutil.IBytes(minimumStoreSize))
 			}
-			ctx.Engines = append(ctx.Engines, engine.NewInMem(spec.Attributes, sizeInBytes, stopper))
+			eng := engine.NewInMem(spec.Attributes, sizeInBytes, stopper)
+			ctx.Engines = append(ctx.Engines, eng)
+			if spec.SeparateRaftLog {
+				ctx.addRaftEngine(eng, engine.NewInMem(roachpb.Attributes{}, defaultRaftLogCacheSize, stopper))
+			}
 		} else {
 			if spec.SizePercent > 0 {
 				fileSystemUsage := gosigar.FileSystemUsage{}
@@ -383,18 +429,62 @@ func (ctx *Context) InitStores(stopper *stop.Stopper) error {
 				return fmt.Errorf("%f%% of %s's total free space is only %s bytes, which is below the minimum requirement of %s",
 					spec.SizePercent, spec.Path, humanizeutil.IBytes(sizeInBytes), humanizeutil.IBytes(minimumStoreSize))
 			}
-			ctx.Engines = append(
-				ctx.Engines,
-				engine.NewRocksDB(
-					spec.Attributes,
-					spec.Path,
-					cache,
+			encryption, err := spec.Encryption.Options()
+			if err != nil {
+				return err
+			}
+			maxOpenFiles := openFileLimitPerStore
+			if spec.Tuning.MaxOpenFiles > 0 {
+				if spec.Tuning.MaxOpenFiles > openFileLimitPerStore {
+					return fmt.Errorf("%s's max open files (%d) exceeds the %d files available to each store",
+						spec.Path, spec.Tuning.MaxOpenFiles, openFileLimitPerStore)
+				}
+				maxOpenFiles = spec.Tuning.MaxOpenFiles
+			}
+			raftLogDir := RaftLogDir(spec.Path)
+			if !spec.SeparateRaftLog {
+				// The raft logs of a store which keeps them separately can't be
+				// found anymore without the option.
+				if _, err := os.Stat(raftLogDir); err == nil {
+					return fmt.Errorf("%s keeps its raft logs separately, raft-log=separate must be specified",
+						spec.Path)
+				}
+			}
+			storeCache := cache
+			if spec.Tuning.CacheSize > 0 {
+				storeCache = engine.NewRocksDBCache(spec.Tuning.CacheSize)
+			}
+			eng := engine.NewRocksDB(
+				spec.Attributes,
+				spec.Path,
+				storeCache,
+				ctx.MemtableBudget,
+				sizeInBytes,
+				maxOpenFiles,
+				spec.Tuning.RocksDBTuning(),
+				encryption,
+				stopper,
+			)
+			ctx.Engines = append(ctx.Engines, eng)
+			if spec.SeparateRaftLog {
+				// Raft log entries are deleted shortly after being written, so
+				// they aren't worth compressing.
+				ctx.addRaftEngine(eng, engine.NewRocksDB(
+					roachpb.Attributes{},
+					raftLogDir,
+					storeCache,
 					ctx.MemtableBudget,
-					sizeInBytes,
-					openFileLimitPerStore,
+					0,
+					maxOpenFiles,
+					engine.RocksDBTuning{Compression: []engine.Compression{engine.CompressionNone}},
+					encryption,
 					stopper,
-				),
-			)
+				))
+			}
+			if spec.Tuning.CacheSize > 0 {
+				// The engine holds its own reference to its dedicated cache.
+				storeCache.Release()
+			}
 		}
 	}
 
@@ -406,6 +496,63 @@ func (ctx *Context) InitStores(stopper *stop.Stopper) error {
 	return nil
 }
 
+// RaftLogDir returns the directory holding the raft logs of the on-disk store
+// located in storeDir, if it keeps them separately.
+func RaftLogDir(storeDir string) string {
+	return filepath.Join(storeDir, raftLogDirName)
+}
+
+// addRaftEngine records raftEng as the engine holding the raft logs of the
+// store of eng.
+func (ctx *Context) addRaftEngine(eng, raftEng engine.Engine) {
+	if ctx.RaftEngines == nil {
+		ctx.RaftEngines = make(map[engine.Engine]engine.Engine)
+	}
+	ctx.RaftEngines[eng] = raftEng
+}
+
+// makeTempStorage creates the engine SQL sorts and aggregations spill their
+// rows to once they exceed their memory budget. It lives in a directory of
+// the first store, whose contents are discarded on startup, or in memory if
+// that store is in memory. It is encrypted with the active key of that store,
+// if any.
+func (ctx *Context) makeTempStorage(stopper *stop.Stopper) (engine.Engine, error) {
+	if len(ctx.Stores.Specs) == 0 || ctx.Stores.Specs[0].InMemory {
+		return engine.NewInMem(roachpb.Attributes{}, defaultTempStorageCacheSize, stopper), nil
+	}
+	dir := filepath.Join(ctx.Stores.Specs[0].Path, tempStorageDirName)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// The temporary storage is discarded on startup so only the active key
+	// is needed.
+	encryption, err := ctx.Stores.Specs[0].Encryption.Options()
+	if err != nil {
+		return nil, err
+	}
+	encryption.OldKeys = nil
+	cache := engine.NewRocksDBCache(defaultTempStorageCacheSize)
+	defer cache.Release()
+	e := engine.NewRocksDB(
+		roachpb.Attributes{},
+		dir,
+		cache,
+		ctx.MemtableBudget,
+		0, /* maxSize */
+		engine.MinimumMaxOpenFiles,
+		engine.RocksDBTuning{},
+		encryption,
+		stopper,
+	)
+	if err := e.Open(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
 // InitNode parses node attributes and initializes the gossip bootstrap
 // resolvers.
 func (ctx *Context) InitNode() error {
@@ -413,6 +560,14 @@ func (ctx *Context) InitNode() error {
 
 	// Initialize attributes.
 	ctx.NodeAttributes = parseAttributes(ctx.Attrs)
+	locality, err := parseLocality(ctx.Locality)
+	if err != nil {
+		return err
+	}
+	ctx.NodeLocality = locality
+	for _, tier := range locality.Tiers {
+		ctx.NodeAttributes.Attrs = append(ctx.NodeAttributes.Attrs, tier.String())
+	}
 
 	// Get the gossip bootstrap resolvers.
 	resolvers, err := ctx.parseGossipBootstrapResolvers()
@@ -433,11 +588,14 @@ func (ctx *Context) readEnvironmentVariables() {
 	// cockroach-linearizable
 	ctx.Linearizable = envutil.EnvOrDefaultBool("linearizable", ctx.Linearizable)
 	ctx.ConsistencyCheckPanicOnFailure = envutil.EnvOrDefaultBool("consistency_check_panic_on_failure", ctx.ConsistencyCheckPanicOnFailure)
+	ctx.RaftDisablePreVote = envutil.EnvOrDefaultBool("raft_disable_prevote", ctx.RaftDisablePreVote)
+	ctx.RaftDisableCheckQuorum = envutil.EnvOrDefaultBool("raft_disable_check_quorum", ctx.RaftDisableCheckQuorum)
 	ctx.MaxOffset = envutil.EnvOrDefaultDuration("max_offset", ctx.MaxOffset)
 	ctx.MetricsSampleInterval = envutil.EnvOrDefaultDuration("metrics_sample_interval", ctx.MetricsSampleInterval)
 	ctx.ScanInterval = envutil.EnvOrDefaultDuration("scan_interval", ctx.ScanInterval)
 	ctx.ScanMaxIdleTime = envutil.EnvOrDefaultDuration("scan_max_idle_time", ctx.ScanMaxIdleTime)
 	ctx.TimeUntilStoreDead = envutil.EnvOrDefaultDuration("time_until_store_dead", ctx.TimeUntilStoreDead)
+	ctx.DrainWait = envutil.EnvOrDefaultDuration("drain_wait", ctx.DrainWait)
 	ctx.ConsistencyCheckInterval = envutil.EnvOrDefaultDuration("consistency_check_interval", ctx.ConsistencyCheckInterval)
 	// TODO(bram): remove ReservationsEnabled once we've completed testing the
 	// feature.
@@ -476,3 +634,19 @@ func parseAttributes(attrsStr string) roachpb.Attributes {
 	}
 	return roachpb.Attributes{Attrs: filtered}
 }
+
+// parseLocality parses a comma-separated list of key=value tiers.
+func parseLocality(localityStr string) (roachpb.Locality, error) {
+	var locality roachpb.Locality
+	if localityStr == "" {
+		return locality, nil
+	}
+	for _, tier := range strings.Split(localityStr, ",") {
+		parts := strings.Split(tier, "=")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return roachpb.Locality{}, fmt.Errorf("invalid locality tier %q: expected key=value", tier)
+		}
+		locality.Tiers = append(locality.Tiers, roachpb.Tier{Key: parts[0], Value: parts[1]})
+	}
+	return locality, nil
+}
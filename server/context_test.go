@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/gossip/resolver"
+	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/envutil"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/stop"
@@ -47,6 +48,32 @@ func TestParseInitNodeAttributes(t *testing.T) {
 	}
 }
 
+func TestParseInitNodeLocality(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ctx := MakeContext()
+	ctx.Attrs = "ssd"
+	ctx.Locality = "region=us-east,datacenter=us-east-1"
+	if err := ctx.InitNode(); err != nil {
+		t.Fatalf("Failed to initialize node: %s", err)
+	}
+	if a, e := ctx.NodeAttributes.Attrs, []string{"ssd", "region=us-east", "datacenter=us-east-1"}; !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected attributes: %v, found: %v", e, a)
+	}
+	if a, e := ctx.NodeLocality, (roachpb.Locality{Tiers: []roachpb.Tier{
+		{Key: "region", Value: "us-east"},
+		{Key: "datacenter", Value: "us-east-1"},
+	}}); !reflect.DeepEqual(a, e) {
+		t.Fatalf("expected locality: %s, found: %s", e, a)
+	}
+
+	for _, locality := range []string{"region", "region=", "=us-east", "region=us-east,", "a=b=c"} {
+		ctx.Locality = locality
+		if err := ctx.InitNode(); err == nil {
+			t.Errorf("%q: expected error", locality)
+		}
+	}
+}
+
 // TestParseJoinUsingAddrs verifies that JoinList is parsed
 // correctly.
 func TestParseJoinUsingAddrs(t *testing.T) {
@@ -108,6 +135,12 @@ func TestReadEnvironmentVariables(t *testing.T) {
 		if err := os.Unsetenv("COCKROACH_CONSISTENCY_CHECK_PANIC_ON_FAILURE"); err != nil {
 			t.Fatal(err)
 		}
+		if err := os.Unsetenv("COCKROACH_RAFT_DISABLE_PREVOTE"); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Unsetenv("COCKROACH_RAFT_DISABLE_CHECK_QUORUM"); err != nil {
+			t.Fatal(err)
+		}
 		if err := os.Unsetenv("COCKROACH_TIME_UNTIL_STORE_DEAD"); err != nil {
 			t.Fatal(err)
 		}
@@ -160,6 +193,14 @@ func TestReadEnvironmentVariables(t *testing.T) {
 		t.Fatal(err)
 	}
 	ctxExpected.ConsistencyCheckPanicOnFailure = true
+	if err := os.Setenv("COCKROACH_RAFT_DISABLE_PREVOTE", "true"); err != nil {
+		t.Fatal(err)
+	}
+	ctxExpected.RaftDisablePreVote = true
+	if err := os.Setenv("COCKROACH_RAFT_DISABLE_CHECK_QUORUM", "true"); err != nil {
+		t.Fatal(err)
+	}
+	ctxExpected.RaftDisableCheckQuorum = true
 	if err := os.Setenv("COCKROACH_TIME_UNTIL_STORE_DEAD", "10ms"); err != nil {
 		t.Fatal(err)
 	}
@@ -205,6 +246,12 @@ func TestReadEnvironmentVariables(t *testing.T) {
 	if err := os.Setenv("COCKROACH_CONSISTENCY_CHECK_PANIC_ON_FAILURE", "abcd"); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.Setenv("COCKROACH_RAFT_DISABLE_PREVOTE", "abcd"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Setenv("COCKROACH_RAFT_DISABLE_CHECK_QUORUM", "abcd"); err != nil {
+		t.Fatal(err)
+	}
 	if err := os.Setenv("COCKROACH_TIME_UNTIL_STORE_DEAD", "abcd"); err != nil {
 		t.Fatal(err)
 	}
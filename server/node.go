@@ -127,6 +127,7 @@ type Node struct {
 	recorder    *status.MetricsRecorder
 	startedAt   int64
 	initialBoot bool // True if this is the first time this node has started.
+	newCluster  bool // True if this node bootstrapped the cluster.
 	txnMetrics  *kv.TxnMetrics
 
 	storage.InternalStoresServer
@@ -159,6 +160,9 @@ func GetBootstrapSchema() sqlbase.MetadataSchema {
 	schema := sqlbase.MakeMetadataSchema()
 	AddEventLogToMetadataSchema(&schema)
 	sql.AddEventLogToMetadataSchema(&schema)
+	sql.AddTableStatisticsToMetadataSchema(&schema)
+	sql.AddJobsToMetadataSchema(&schema)
+	sql.AddRoleMembersToMetadataSchema(&schema)
 	return schema
 }
 
@@ -271,10 +275,13 @@ func (n *Node) String() string {
 }
 
 // initDescriptor initializes the node descriptor with the server
-// address and the node attributes.
-func (n *Node) initDescriptor(addr net.Addr, attrs roachpb.Attributes) {
+// address, the node attributes and the node locality.
+func (n *Node) initDescriptor(
+	addr net.Addr, attrs roachpb.Attributes, locality roachpb.Locality,
+) {
 	n.Descriptor.Address = util.MakeUnresolvedAddr(addr.Network(), addr.String())
 	n.Descriptor.Attrs = attrs
+	n.Descriptor.Locality = locality
 }
 
 // initNodeID updates the internal NodeDescriptor with the given ID. If zero is
@@ -320,14 +327,19 @@ func (n *Node) initNodeID(id roachpb.NodeID) {
 // RPC service "Node" and initializing stores for each specified
 // engine. Launches periodic store gossiping in a goroutine.
 func (n *Node) start(
-	ctx context.Context, addr net.Addr, engines []engine.Engine, attrs roachpb.Attributes,
+	ctx context.Context,
+	addr net.Addr,
+	engines []engine.Engine,
+	attrs roachpb.Attributes,
+	locality roachpb.Locality,
 ) error {
-	n.initDescriptor(addr, attrs)
+	n.initDescriptor(addr, attrs, locality)
 
 	// Initialize stores, including bootstrapping new ones.
 	if err := n.initStores(ctx, engines, n.stopper); err != nil {
 		if err == errNeedsBootstrap {
 			n.initialBoot = true
+			n.newCluster = true
 			// This node has no initialized stores and no way to connect to
 			// an existing cluster, so we bootstrap it.
 			clusterID, err := bootstrapCluster(engines, n.txnMetrics)
@@ -353,7 +365,8 @@ func (n *Node) start(
 	// Record node started event.
 	n.recordJoinEvent()
 
-	log.Infof(ctx, "%s: started with %v engine(s) and attributes %v", n, engines, attrs.Attrs)
+	log.Infof(ctx, "%s: started with %v engine(s), attributes %v and locality %s",
+		n, engines, attrs.Attrs, locality)
 	return nil
 }
 
@@ -822,3 +835,33 @@ func (n *Node) Batch(
 	}
 	return br, nil
 }
+
+// RangeFeed implements the roachpb.InternalServer interface.
+func (n *Node) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) error {
+	if peer, ok := peer.FromContext(stream.Context()); ok {
+		if tlsInfo, ok := peer.AuthInfo.(credentials.TLSInfo); ok {
+			certUser, err := security.GetCertificateUser(&tlsInfo.State)
+			if err != nil {
+				return err
+			}
+			if certUser != security.NodeUser {
+				return errors.Errorf("user %s is not allowed", certUser)
+			}
+		}
+	}
+
+	var pErr *roachpb.Error
+	if err := n.stopper.RunTask(func() {
+		pErr = n.stores.RangeFeed(args, stream)
+	}); err != nil {
+		return err
+	}
+	if pErr != nil {
+		// Like Batch, errors from cockroach are returned within the stream so
+		// that their structure is preserved.
+		return stream.Send(&roachpb.RangeFeedEvent{Error: &roachpb.RangeFeedError{Error: *pErr}})
+	}
+	return nil
+}
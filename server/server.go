@@ -46,6 +46,8 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/server/status"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/settings/cluster"
 	"github.com/cockroachdb/cockroach/sql"
 	"github.com/cockroachdb/cockroach/sql/distsql"
 	"github.com/cockroachdb/cockroach/sql/pgwire"
@@ -74,31 +76,35 @@ var (
 
 // Server is the cockroach server node.
 type Server struct {
-	Tracer        opentracing.Tracer
-	ctx           Context
-	mux           *http.ServeMux
-	clock         *hlc.Clock
-	rpcContext    *rpc.Context
-	grpc          *grpc.Server
-	gossip        *gossip.Gossip
-	storePool     *storage.StorePool
-	distSender    *kv.DistSender
-	db            *client.DB
-	kvDB          *kv.DBServer
-	pgServer      *pgwire.Server
-	distSQLServer *distsql.ServerImpl
-	node          *Node
-	registry      *metric.Registry
-	recorder      *status.MetricsRecorder
-	runtime       status.RuntimeStatSampler
-	admin         adminServer
-	status        *statusServer
-	tsDB          *ts.DB
-	tsServer      ts.Server
-	raftTransport *storage.RaftTransport
-	stopper       *stop.Stopper
-	sqlExecutor   *sql.Executor
-	leaseMgr      *sql.LeaseManager
+	Tracer          opentracing.Tracer
+	ctx             Context
+	mux             *http.ServeMux
+	clock           *hlc.Clock
+	rpcContext      *rpc.Context
+	grpc            *grpc.Server
+	gossip          *gossip.Gossip
+	storePool       *storage.StorePool
+	nodeLiveness    *storage.NodeLiveness
+	distSender      *kv.DistSender
+	db              *client.DB
+	kvDB            *kv.DBServer
+	pgServer        *pgwire.Server
+	distSQLServer   *distsql.ServerImpl
+	node            *Node
+	registry        *metric.Registry
+	recorder        *status.MetricsRecorder
+	runtime         status.RuntimeStatSampler
+	admin           adminServer
+	status          *statusServer
+	tsDB            *ts.DB
+	tsServer        ts.Server
+	raftTransport   *storage.RaftTransport
+	stopper         *stop.Stopper
+	sqlExecutor     *sql.Executor
+	sessionRegistry *sql.SessionRegistry
+	leaseMgr        *sql.LeaseManager
+	statsRefresher  *sql.TableStatsRefresher
+	jobRegistry     *sql.JobRegistry
 }
 
 // NewServer creates a Server from a server.Context.
@@ -163,6 +169,7 @@ func NewServer(ctx Context, stopper *stop.Stopper) (*Server, error) {
 		Clock:           s.clock,
 		RPCContext:      s.rpcContext,
 		RPCRetryOptions: &retryOpts,
+		Metrics:         kv.NewDistSenderMetrics(s.registry),
 	}, s.gossip)
 	txnMetrics := kv.NewTxnMetrics(s.registry)
 	sender := kv.NewTxnCoordSender(s.distSender, s.clock, ctx.Linearizable, s.Tracer,
@@ -184,32 +191,14 @@ func NewServer(ctx Context, stopper *stop.Stopper) (*Server, error) {
 
 	// Set up the DistSQL server
 	distSQLCtx := distsql.ServerContext{
-		Context:    context.Background(),
-		DB:         s.db,
-		RPCContext: s.rpcContext,
+		Context:       context.Background(),
+		DB:            s.db,
+		RPCContext:    s.rpcContext,
+		FlowDrainWait: s.ctx.DrainWait,
 	}
 	s.distSQLServer = distsql.NewServer(distSQLCtx)
 	distsql.RegisterDistSQLServer(s.grpc, s.distSQLServer)
 
-	// Set up Executor
-	eCtx := sql.ExecutorContext{
-		Context:      context.Background(),
-		DB:           s.db,
-		Gossip:       s.gossip,
-		LeaseManager: s.leaseMgr,
-		Clock:        s.clock,
-		DistSQLSrv:   s.distSQLServer,
-	}
-	if ctx.TestingKnobs.SQLExecutor != nil {
-		eCtx.TestingKnobs = ctx.TestingKnobs.SQLExecutor.(*sql.ExecutorTestingKnobs)
-	} else {
-		eCtx.TestingKnobs = &sql.ExecutorTestingKnobs{}
-	}
-
-	s.sqlExecutor = sql.NewExecutor(eCtx, s.stopper, s.registry)
-
-	s.pgServer = pgwire.MakeServer(s.ctx.Context, s.sqlExecutor, s.registry)
-
 	// TODO(bdarnell): make StoreConfig configurable.
 	nCtx := storage.StoreContext{
 		Clock:                          s.clock,
@@ -217,6 +206,8 @@ func NewServer(ctx Context, stopper *stop.Stopper) (*Server, error) {
 		Gossip:                         s.gossip,
 		Transport:                      s.raftTransport,
 		RaftTickInterval:               s.ctx.RaftTickInterval,
+		RaftDisablePreVote:             s.ctx.RaftDisablePreVote,
+		RaftDisableCheckQuorum:         s.ctx.RaftDisableCheckQuorum,
 		ScanInterval:                   s.ctx.ScanInterval,
 		ScanMaxIdleTime:                s.ctx.ScanMaxIdleTime,
 		ConsistencyCheckInterval:       s.ctx.ConsistencyCheckInterval,
@@ -226,11 +217,19 @@ func NewServer(ctx Context, stopper *stop.Stopper) (*Server, error) {
 		SQLExecutor: sql.InternalExecutor{
 			LeaseManager: s.leaseMgr,
 		},
-		LogRangeEvents: true,
+		RaftEngines:                   s.ctx.RaftEngines,
+		LogRangeEvents:                true,
+		ClosedTimestampTargetDuration: base.DefaultClosedTimestampTargetDuration,
 		AllocatorOptions: storage.AllocatorOptions{
 			AllowRebalance: true,
 		},
 	}
+	// The node liveness records expire after the active duration of an
+	// expiration-based range lease, which the epoch-based leases replace.
+	livenessThreshold, renewalDuration := nCtx.RangeLeaseDurations()
+	s.nodeLiveness = storage.NewNodeLiveness(s.clock, s.db, s.gossip, livenessThreshold, renewalDuration)
+	nCtx.NodeLiveness = s.nodeLiveness
+	nCtx.EnableEpochRangeLeases = true
 	if ctx.TestingKnobs.Store != nil {
 		nCtx.TestingKnobs = *ctx.TestingKnobs.Store.(*storage.StoreTestingKnobs)
 	}
@@ -246,8 +245,50 @@ func NewServer(ctx Context, stopper *stop.Stopper) (*Server, error) {
 	s.tsDB = ts.NewDB(s.db)
 	s.tsServer = ts.MakeServer(s.tsDB)
 
+	s.sessionRegistry = sql.NewSessionRegistry()
+	s.status = newStatusServer(s.db, s.gossip, s.recorder, s.ctx.Context, s.rpcContext, s.node.stores, s.sessionRegistry)
+
+	tempStorage, err := ctx.makeTempStorage(s.stopper)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create temporary storage")
+	}
+
+	tableStatsCache := sql.NewTableStatisticsCache(s.leaseMgr)
+	s.statsRefresher = sql.NewTableStatsRefresher(*s.db, s.leaseMgr, tableStatsCache)
+	s.jobRegistry = sql.NewJobRegistry(*s.db, s.leaseMgr)
+
+	// Set up Executor
+	eCtx := sql.ExecutorContext{
+		Context:      context.Background(),
+		DB:           s.db,
+		Gossip:       s.gossip,
+		LeaseManager: s.leaseMgr,
+		Clock:        s.clock,
+		DistSQLSrv:   s.distSQLServer,
+
+		MemoryPoolSize:  ctx.SQLMemoryPoolSize,
+		TempStorage:     tempStorage,
+		SessionRegistry: s.sessionRegistry,
+		StatusServer:    s.status,
+		StatsRefresher:  s.statsRefresher,
+		TableStatsCache: tableStatsCache,
+		JobRegistry:     s.jobRegistry,
+		Stopper:         s.stopper,
+	}
+	if ctx.TestingKnobs.SQLExecutor != nil {
+		eCtx.TestingKnobs = ctx.TestingKnobs.SQLExecutor.(*sql.ExecutorTestingKnobs)
+	} else {
+		eCtx.TestingKnobs = &sql.ExecutorTestingKnobs{}
+	}
+
+	s.sqlExecutor = sql.NewExecutor(eCtx, s.stopper, s.registry)
+	// The status server and the executor depend on each other; the status
+	// server only uses the executor once requests are being served.
+	s.status.sqlExecutor = s.sqlExecutor
+
+	s.pgServer = pgwire.MakeServer(s.ctx.Context, s.sqlExecutor, s.registry, s.sessionRegistry)
+
 	s.admin = makeAdminServer(s)
-	s.status = newStatusServer(s.db, s.gossip, s.recorder, s.ctx.Context, s.rpcContext, s.node.stores)
 	for _, gw := range []grpcGatewayServer{&s.admin, s.status, &s.tsServer} {
 		gw.RegisterService(s.grpc)
 	}
@@ -266,6 +307,33 @@ type grpcGatewayServer interface {
 	) error
 }
 
+// initClusterVersion stores the version of this binary as the version of the
+// cluster it just bootstrapped: unlike the clusters created by older
+// binaries, which have to be upgraded by the operator, a new cluster can use
+// all the features of the binary right away. It waits until the version is
+// gossiped back to the node, so that they are in use once the server starts.
+func (s *Server) initClusterVersion() error {
+	ie := sql.InternalExecutor{LeaseManager: s.leaseMgr}
+	v := cluster.BinaryServerVersion
+	typ, _ := settings.Lookup(cluster.VersionSettingName)
+	if err := s.db.Txn(func(txn *client.Txn) error {
+		_, err := ie.ExecuteStatementInTransaction(txn,
+			`UPSERT INTO system.settings (name, value, lastUpdated, valueType) VALUES ($1, $2, NOW(), $3)`,
+			cluster.VersionSettingName, v.String(), typ.Typ())
+		return err
+	}); err != nil {
+		return errors.Wrap(err, "unable to store the cluster version")
+	}
+	for cluster.ActiveVersion().Less(v) {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-s.stopper.ShouldStop():
+			return errors.New("server stopped before the cluster version was applied")
+		}
+	}
+	return nil
+}
+
 // Start starts the server on the specified port, starts gossip and
 // initializes the node using the engines from the server's context.
 func (s *Server) Start() error {
@@ -399,10 +467,14 @@ func (s *Server) Start() error {
 	s.gossip.Start(unresolvedAddr)
 
 	ctx := context.Background()
-	if err := s.node.start(ctx, unresolvedAddr, s.ctx.Engines, s.ctx.NodeAttributes); err != nil {
+	if err := s.node.start(ctx, unresolvedAddr, s.ctx.Engines, s.ctx.NodeAttributes, s.ctx.NodeLocality); err != nil {
 		return err
 	}
 
+	// Begin heartbeating the liveness record of the node, now that its ID is
+	// known.
+	s.nodeLiveness.StartHeartbeat(ctx, s.stopper)
+
 	// We can now add the node registry.
 	s.recorder.AddNode(s.registry, s.node.Descriptor, s.node.startedAt)
 
@@ -424,7 +496,16 @@ func (s *Server) Start() error {
 	if s.ctx.TestingKnobs.SQLSchemaChangeManager != nil {
 		testingKnobs = s.ctx.TestingKnobs.SQLSchemaChangeManager.(*sql.SchemaChangeManagerTestingKnobs)
 	}
-	sql.NewSchemaChangeManager(testingKnobs, *s.db, s.gossip, s.leaseMgr).Start(s.stopper)
+	sql.NewSchemaChangeManager(testingKnobs, *s.db, s.gossip, s.leaseMgr, s.jobRegistry,
+		s.distSQLServer).Start(s.stopper)
+	s.sqlExecutor.StartTemporaryTableReaper(s.stopper)
+	s.statsRefresher.Start(s.stopper)
+
+	if s.node.newCluster {
+		if err := s.initClusterVersion(); err != nil {
+			return err
+		}
+	}
 
 	log.Infof(context.TODO(), "starting %s server at %s", s.ctx.HTTPRequestScheme(), unresolvedHTTPAddr)
 	log.Infof(context.TODO(), "starting grpc/postgres server at %s", unresolvedAddr)
@@ -510,6 +591,11 @@ func (s *Server) doDrain(modes []serverpb.DrainMode, setTo bool) ([]serverpb.Dra
 		switch {
 		case mode == serverpb.DrainMode_CLIENT:
 			err = s.pgServer.SetDraining(setTo)
+			if err == nil && setTo {
+				// Wait for the distributed flows running on this node, e.g.
+				// for the queries of the clients of other nodes.
+				s.distSQLServer.Drain()
+			}
 		case mode == serverpb.DrainMode_LEASES:
 			err = s.node.SetDraining(setTo)
 		default:
@@ -44,6 +44,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/server/status"
+	"github.com/cockroachdb/cockroach/sql"
 	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -66,6 +67,8 @@ const (
 	   /_status/nodes/:node_id          - a specific node's status
 	   /_status/metrics/:node_id        - a specific node's metrics
 	   /_status/ranges/:node_id         - a specific node's range metadata
+	   /_status/statements/:node_id     - a specific node's statement statistics
+	   /_status/stores/:node_id         - a specific node's store details
 	*/
 
 	// statusPrefix is the root of the cluster statistics and metrics API.
@@ -96,9 +99,16 @@ const (
 	// statusVars exposes prometheus metrics for monitoring consumption.
 	statusVars = statusPrefix + "vars"
 
+	// statusStatementsPattern exposes the statistics accumulated for the SQL
+	// statements executed by a node.
+	statusStatementsPattern = statusPrefix + "statements/:node_id"
+
 	// statusRangesPrefix exposes range information.
 	statusRangesPrefix = statusPrefix + "ranges/"
 
+	// statusStoresPrefix exposes store information.
+	statusStoresPrefix = statusPrefix + "stores/"
+
 	// statusRaftEndpoint exposes raft debug information.
 	statusRaftEndpoint = statusPrefix + "raft"
 
@@ -123,6 +133,8 @@ type statusServer struct {
 	router       *httprouter.Router
 	rpcCtx       *rpc.Context
 	stores       *storage.Stores
+	sqlExecutor  *sql.Executor
+	sessions     *sql.SessionRegistry
 }
 
 // newStatusServer allocates and returns a statusServer.
@@ -133,6 +145,7 @@ func newStatusServer(
 	ctx *base.Context,
 	rpcCtx *rpc.Context,
 	stores *storage.Stores,
+	sessions *sql.SessionRegistry,
 ) *statusServer {
 	server := &statusServer{
 		db:           db,
@@ -141,6 +154,7 @@ func newStatusServer(
 		router:       httprouter.New(),
 		rpcCtx:       rpcCtx,
 		stores:       stores,
+		sessions:     sessions,
 	}
 
 	server.router.GET(statusLogFilesListPattern, server.handleLogFilesList)
@@ -150,6 +164,7 @@ func newStatusServer(
 	// except that this one allows querying by NodeID.
 	server.router.GET(statusStacksPattern, server.handleStacks)
 	server.router.GET(statusMetricsPattern, server.handleMetrics)
+	server.router.GET(statusStatementsPattern, server.handleStatements)
 	server.router.GET(statusVars, server.handleVars)
 
 	return server
@@ -549,6 +564,104 @@ func (s *statusServer) handleMetrics(w http.ResponseWriter, r *http.Request, ps
 	writeJSONResponse(w, resp)
 }
 
+// Statements returns the statistics accumulated for the SQL statements
+// executed by the given node, grouped by statement fingerprint.
+func (s *statusServer) Statements(ctx context.Context, req *serverpb.StatementsRequest) (*serverpb.JSONResponse, error) {
+	nodeID, local, err := s.parseNodeID(req.NodeId)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.Statements(ctx, req)
+	}
+	return marshalJSONResponse(s.sqlExecutor.StatementStatistics())
+}
+
+func (s *statusServer) handleStatements(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	resp, err := s.Statements(context.TODO(), &serverpb.StatementsRequest{NodeId: ps.ByName("node_id")})
+	if err != nil {
+		log.Error(context.TODO(), err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, resp)
+}
+
+// ListLocalQueries returns the queries currently running on this node.
+func (s *statusServer) ListLocalQueries(
+	ctx context.Context, _ *serverpb.ListQueriesRequest,
+) (*serverpb.ListQueriesResponse, error) {
+	return &serverpb.ListQueriesResponse{
+		Queries: s.sessions.ActiveQueries(s.gossip.GetNodeID()),
+	}, nil
+}
+
+// ListQueries returns the queries currently running on all the nodes of the
+// cluster. Nodes that cannot be reached are reported in the response's
+// errors rather than failing the whole request.
+func (s *statusServer) ListQueries(
+	ctx context.Context, req *serverpb.ListQueriesRequest,
+) (*serverpb.ListQueriesResponse, error) {
+	nodes, err := s.Nodes(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp serverpb.ListQueriesResponse
+	for _, node := range nodes.Nodes {
+		nodeID := node.Desc.NodeID
+		var nodeResp *serverpb.ListQueriesResponse
+		if nodeID == s.gossip.GetNodeID() {
+			nodeResp, err = s.ListLocalQueries(ctx, req)
+		} else {
+			var status serverpb.StatusClient
+			if status, err = s.dialNode(nodeID); err == nil {
+				nodeResp, err = status.ListLocalQueries(ctx, req)
+			}
+		}
+		if err != nil {
+			resp.Errors = append(resp.Errors, serverpb.ListQueriesError{
+				NodeID:  nodeID,
+				Message: err.Error(),
+			})
+			continue
+		}
+		resp.Queries = append(resp.Queries, nodeResp.Queries...)
+	}
+	return &resp, nil
+}
+
+// CancelQuery cancels a query running on the given node.
+func (s *statusServer) CancelQuery(
+	ctx context.Context, req *serverpb.CancelQueryRequest,
+) (*serverpb.CancelQueryResponse, error) {
+	nodeID, local, err := s.parseNodeID(req.NodeId)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.CancelQuery(ctx, req)
+	}
+
+	var resp serverpb.CancelQueryResponse
+	if err := s.sessions.CancelQuery(req.QueryID, req.Username); err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Canceled = true
+	}
+	return &resp, nil
+}
+
 // RaftDebug returns raft debug information for all known nodes.
 func (s *statusServer) RaftDebug(ctx context.Context, _ *serverpb.RaftDebugRequest) (*serverpb.RaftDebugResponse, error) {
 	nodes, err := s.Nodes(ctx, nil)
@@ -668,8 +781,9 @@ func (s *statusServer) Ranges(ctx context.Context, req *serverpb.RangesRequest)
 						StartKey: desc.StartKey.String(),
 						EndKey:   desc.EndKey.String(),
 					},
-					RaftState: raftState,
-					State:     state,
+					RaftState:    raftState,
+					State:        state,
+					Inconsistent: rep.Inconsistent(),
 				})
 				return false, nil
 			})
@@ -681,6 +795,153 @@ func (s *statusServer) Ranges(ctx context.Context, req *serverpb.RangesRequest)
 	return &output, nil
 }
 
+// Stores returns the details of the stores of the server specified, such as
+// the encryption status of their files.
+func (s *statusServer) Stores(ctx context.Context, req *serverpb.StoresRequest) (*serverpb.StoresResponse, error) {
+	nodeID, local, err := s.parseNodeID(req.NodeId)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.Stores(ctx, req)
+	}
+
+	output := serverpb.StoresResponse{
+		Stores: make([]serverpb.StoreDetails, 0, s.stores.GetStoreCount()),
+	}
+	err = s.stores.VisitStores(func(store *storage.Store) error {
+		encStatus, err := store.Engine().EncryptionStatus()
+		if err != nil {
+			return err
+		}
+		details := serverpb.StoreDetails{
+			StoreID:               store.Ident.StoreID,
+			EncryptionActiveKeyID: encStatus.ActiveKeyID,
+		}
+		for _, k := range encStatus.Keys {
+			details.EncryptionKeys = append(details.EncryptionKeys, serverpb.EncryptionKeyUsage{
+				KeyID: k.KeyID,
+				Files: k.Files,
+				Bytes: k.Bytes,
+			})
+		}
+		output.Stores = append(output.Stores, details)
+		return nil
+	})
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, err.Error())
+	}
+	return &output, nil
+}
+
+// ContentionEvents returns the contention statistics of the stores of a
+// node, aggregated by index, and their most recent contention events.
+func (s *statusServer) ContentionEvents(
+	ctx context.Context, req *serverpb.ContentionEventsRequest,
+) (*serverpb.ContentionEventsResponse, error) {
+	nodeID, local, err := s.parseNodeID(req.NodeId)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.ContentionEvents(ctx, req)
+	}
+
+	var output serverpb.ContentionEventsResponse
+	indexes := make(map[[2]uint32]int)
+	err = s.stores.VisitStores(func(store *storage.Store) error {
+		storeIndexes, events := store.ContentionEvents()
+		for _, stats := range storeIndexes {
+			key := [2]uint32{stats.TableID, stats.IndexID}
+			i, ok := indexes[key]
+			if !ok {
+				i = len(output.Indexes)
+				indexes[key] = i
+				output.Indexes = append(output.Indexes, serverpb.IndexContention{
+					TableID: stats.TableID,
+					IndexID: stats.IndexID,
+				})
+			}
+			output.Indexes[i].NumContentionEvents += stats.NumEvents
+			output.Indexes[i].CumulativeContentionNanos += stats.CumulativeTime.Nanoseconds()
+		}
+		for _, event := range events {
+			var txnID string
+			if event.TxnID != nil {
+				txnID = event.TxnID.String()
+			}
+			var blockingTxnID string
+			if event.BlockingTxnID != nil {
+				blockingTxnID = event.BlockingTxnID.String()
+			}
+			output.Events = append(output.Events, serverpb.ContentionEvent{
+				Key:           event.Key.String(),
+				TxnID:         txnID,
+				BlockingTxnID: blockingTxnID,
+				TableID:       event.TableID,
+				IndexID:       event.IndexID,
+				DurationNanos: event.Duration.Nanoseconds(),
+				StoreID:       store.Ident.StoreID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, err.Error())
+	}
+	return &output, nil
+}
+
+// HotRanges returns the replicas of the stores of a node serving the most
+// requests, along with the rates of the requests they serve.
+func (s *statusServer) HotRanges(
+	ctx context.Context, req *serverpb.HotRangesRequest,
+) (*serverpb.HotRangesResponse, error) {
+	nodeID, local, err := s.parseNodeID(req.NodeId)
+	if err != nil {
+		return nil, grpc.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	if !local {
+		status, err := s.dialNode(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return status.HotRanges(ctx, req)
+	}
+
+	var output serverpb.HotRangesResponse
+	err = s.stores.VisitStores(func(store *storage.Store) error {
+		for _, info := range store.HotReplicas() {
+			output.Ranges = append(output.Ranges, serverpb.HotRange{
+				RangeID:          info.Desc.RangeID,
+				StoreID:          store.Ident.StoreID,
+				StartKey:         info.Desc.StartKey.String(),
+				EndKey:           info.Desc.EndKey.String(),
+				TableID:          info.TableID,
+				IndexID:          info.IndexID,
+				QueriesPerSecond: info.QueriesPerSecond,
+				WritesPerSecond:  info.WritesPerSecond,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, grpc.Errorf(codes.Internal, err.Error())
+	}
+	return &output, nil
+}
+
 // SpanStats requests the total statistics stored on a node for a given key
 // span, which may include multiple ranges.
 func (s *statusServer) SpanStats(ctx context.Context, req *serverpb.SpanStatsRequest) (
@@ -701,7 +962,11 @@ func (s *statusServer) SpanStats(ctx context.Context, req *serverpb.SpanStatsReq
 
 	output := &serverpb.SpanStatsResponse{}
 	err = s.stores.VisitStores(func(store *storage.Store) error {
-		stats, count := store.ComputeStatsForKeySpan(req.StartKey.Next(), req.EndKey)
+		stats, count, err := store.ComputeStatsForKeySpan(
+			req.StartKey, req.EndKey, req.FirstReplicaOnly)
+		if err != nil {
+			return err
+		}
 		output.TotalStats.Add(stats)
 		output.RangeCount += int32(count)
 		return nil
@@ -42,6 +42,7 @@ import (
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/server/status"
+	"github.com/cockroachdb/cockroach/sql"
 	"github.com/cockroachdb/cockroach/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/ts"
 	"github.com/cockroachdb/cockroach/util"
@@ -531,6 +532,29 @@ func TestRangesResponse(t *testing.T) {
 	}
 }
 
+func TestStoresResponse(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ts := startServer(t)
+	defer ts.Stopper().Stop()
+
+	var response serverpb.StoresResponse
+	if err := getRequestProto(t, ts, statusStoresPrefix+"local", &response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Stores) != 3 {
+		t.Fatalf("expected 3 stores, got %d", len(response.Stores))
+	}
+	for _, sd := range response.Stores {
+		if sd.StoreID == 0 {
+			t.Errorf("expected a store ID, got %+v", sd)
+		}
+		// The test server's stores are in memory and unencrypted.
+		if sd.EncryptionActiveKeyID != "" || len(sd.EncryptionKeys) != 0 {
+			t.Errorf("expected no encryption status, got %+v", sd)
+		}
+	}
+}
+
 func TestRaftDebug(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	s := startServer(t)
@@ -559,6 +583,35 @@ func TestStatusVars(t *testing.T) {
 	}
 }
 
+func TestStatusStatements(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, sqlDB, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	for i := 0; i < 3; i++ {
+		if _, err := sqlDB.Exec(`SELECT $1::INT + 1`, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stats []sql.StatementStatisticsEntry
+	if body, err := getText(s.AdminURL() + "/_status/statements/local"); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(body, &stats); err != nil {
+		t.Fatal(err)
+	}
+	const fingerprint = `SELECT CAST($1 AS INT) + _`
+	for _, e := range stats {
+		if e.Fingerprint == fingerprint {
+			if e.Stats.Count != 3 {
+				t.Errorf("expected %q to be executed 3 times, got %d", fingerprint, e.Stats.Count)
+			}
+			return
+		}
+	}
+	t.Errorf("expected statistics for %q, got %+v", fingerprint, stats)
+}
+
 func TestSpanStatsResponse(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	ts := startServer(t)
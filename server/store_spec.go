@@ -28,6 +28,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util/humanizeutil"
 )
 
@@ -36,6 +37,10 @@ import (
 
 var minimumStoreSize = 10 * config.DefaultZoneConfig().RangeMaxBytes
 
+// minimumWriteBufferSize is the smallest memtable a store may be configured
+// with.
+const minimumWriteBufferSize = 1 << 20 // 1 MiB
+
 // StoreSpec contains the details that can be specified in the cli pertaining
 // to the --store flag.
 type StoreSpec struct {
@@ -44,6 +49,71 @@ type StoreSpec struct {
 	SizePercent float64
 	InMemory    bool
 	Attributes  roachpb.Attributes
+	Encryption  StoreEncryptionSpec
+	Tuning      StoreTuningSpec
+	// SeparateRaftLog keeps the raft logs of the store in a RocksDB instance
+	// of their own, so that their frequent truncations don't interfere with
+	// the compactions of the rest of the data.
+	SeparateRaftLog bool
+}
+
+// plaintextKeyFile is the key file specified to write the new files of a
+// store in plaintext while still reading the files encrypted with old keys.
+const plaintextKeyFile = "plain"
+
+// StoreEncryptionSpec contains the files holding the keys of an encrypted
+// store. New files are encrypted with the key in KeyFile, or written in
+// plaintext if it's plaintextKeyFile. Files encrypted with the keys in
+// OldKeyFiles can still be read, which allows rotating the keys.
+type StoreEncryptionSpec struct {
+	KeyFile     string
+	OldKeyFiles []string
+}
+
+// Options reads the key files into the options of an encrypted engine.
+func (es StoreEncryptionSpec) Options() (engine.EncryptionOptions, error) {
+	var opts engine.EncryptionOptions
+	if es.KeyFile != "" && es.KeyFile != plaintextKeyFile {
+		k, err := engine.ReadEncryptionKeyFile(es.KeyFile)
+		if err != nil {
+			return engine.EncryptionOptions{}, err
+		}
+		opts.ActiveKey = k
+	}
+	for _, f := range es.OldKeyFiles {
+		k, err := engine.ReadEncryptionKeyFile(f)
+		if err != nil {
+			return engine.EncryptionOptions{}, err
+		}
+		opts.OldKeys = append(opts.OldKeys, k)
+	}
+	return opts, nil
+}
+
+// StoreTuningSpec contains the RocksDB options of a store which override the
+// defaults shared by all the stores of the node. Zero values keep the
+// defaults.
+type StoreTuningSpec struct {
+	// Compression holds the compression of each level, the last one applying
+	// to the remaining levels.
+	Compression     []engine.Compression
+	CacheSize       int64
+	WriteBufferSize int64
+	MaxOpenFiles    int
+}
+
+// isSet returns whether any option is overridden.
+func (ts StoreTuningSpec) isSet() bool {
+	return len(ts.Compression) > 0 || ts.CacheSize != 0 || ts.WriteBufferSize != 0 ||
+		ts.MaxOpenFiles != 0
+}
+
+// RocksDBTuning returns the options which are passed as is to the engine.
+func (ts StoreTuningSpec) RocksDBTuning() engine.RocksDBTuning {
+	return engine.RocksDBTuning{
+		Compression:     ts.Compression,
+		WriteBufferSize: ts.WriteBufferSize,
+	}
 }
 
 // String returns a fully parsable version of the store spec.
@@ -71,6 +141,34 @@ func (ss StoreSpec) String() string {
 		}
 		fmt.Fprintf(&buffer, ",")
 	}
+	if len(ss.Encryption.KeyFile) > 0 {
+		fmt.Fprintf(&buffer, "key=%s,", ss.Encryption.KeyFile)
+	}
+	if len(ss.Encryption.OldKeyFiles) > 0 {
+		fmt.Fprintf(&buffer, "old-key=%s,", strings.Join(ss.Encryption.OldKeyFiles, ":"))
+	}
+	if len(ss.Tuning.Compression) > 0 {
+		fmt.Fprint(&buffer, "compression=")
+		for i, c := range ss.Tuning.Compression {
+			if i != 0 {
+				fmt.Fprint(&buffer, ":")
+			}
+			fmt.Fprint(&buffer, c)
+		}
+		fmt.Fprint(&buffer, ",")
+	}
+	if ss.Tuning.CacheSize > 0 {
+		fmt.Fprintf(&buffer, "cache=%s,", humanizeutil.IBytes(ss.Tuning.CacheSize))
+	}
+	if ss.Tuning.WriteBufferSize > 0 {
+		fmt.Fprintf(&buffer, "write-buffer-size=%s,", humanizeutil.IBytes(ss.Tuning.WriteBufferSize))
+	}
+	if ss.Tuning.MaxOpenFiles > 0 {
+		fmt.Fprintf(&buffer, "max-open-files=%d,", ss.Tuning.MaxOpenFiles)
+	}
+	if ss.SeparateRaftLog {
+		fmt.Fprint(&buffer, "raft-log=separate,")
+	}
 	// Trim the extra comma from the end if it exists.
 	if l := buffer.Len(); l > 0 {
 		buffer.Truncate(l - 1)
@@ -80,7 +178,7 @@ func (ss StoreSpec) String() string {
 
 // newStoreSpec parses the string passed into a --store flag and returns a
 // StoreSpec if it is correctly parsed.
-// There are four possible fields that can be passed in, comma separated:
+// There are eleven possible fields that can be passed in, comma separated:
 // - path=xxx The directory in which to the rocks db instance should be
 //   located, required unless using a in memory storage.
 // - type=mem This specifies that the store is an in memory storage instead of
@@ -94,6 +192,19 @@ func (ss StoreSpec) String() string {
 //   - 20%             -> 20% of the available space
 //   - 0.2             -> 20% of the available space
 // - attrs=xxx:yyy:zzz A colon separated list of optional attributes.
+// - key=xxx The file holding the key the store's files are encrypted with, or
+//   "plain" to write them in plaintext.
+// - old-key=xxx:yyy A colon separated list of the files holding keys which
+//   files of the store may still be encrypted with, requires key.
+// - compression=xxx:yyy A colon separated list of the compression of each
+//   level, none or snappy, starting with L0. The last one applies to the
+//   remaining levels.
+// - cache=xxx The size of a block cache dedicated to the store, instead of the
+//   cache shared by the stores of the node.
+// - write-buffer-size=xxx The size of a memtable.
+// - max-open-files=xxx The maximum number of files the store keeps open.
+// - raft-log=separate This specifies that the raft logs are kept in a RocksDB
+//   instance of their own. Once set, it can't be removed.
 // Note that commas are forbidden within any field name or value.
 func newStoreSpec(value string) (StoreSpec, error) {
 	if len(value) == 0 {
@@ -187,6 +298,58 @@ func newStoreSpec(value string) (StoreSpec, error) {
 				ss.Attributes.Attrs = append(ss.Attributes.Attrs, attribute)
 			}
 			sort.Strings(ss.Attributes.Attrs)
+		case "key":
+			ss.Encryption.KeyFile = value
+		case "old-key":
+			for _, f := range strings.Split(value, ":") {
+				if f == plaintextKeyFile {
+					return StoreSpec{}, fmt.Errorf("%s is not a valid old key file", f)
+				}
+				ss.Encryption.OldKeyFiles = append(ss.Encryption.OldKeyFiles, f)
+			}
+		case "compression":
+			for _, name := range strings.Split(value, ":") {
+				c, err := engine.ParseCompression(name)
+				if err != nil {
+					return StoreSpec{}, err
+				}
+				ss.Tuning.Compression = append(ss.Tuning.Compression, c)
+			}
+		case "cache":
+			var err error
+			ss.Tuning.CacheSize, err = humanizeutil.ParseBytes(value)
+			if err != nil {
+				return StoreSpec{}, fmt.Errorf("could not parse cache size (%s) %s", value, err)
+			}
+			if ss.Tuning.CacheSize <= 0 {
+				return StoreSpec{}, fmt.Errorf("cache size (%s) must be positive", value)
+			}
+		case "write-buffer-size":
+			var err error
+			ss.Tuning.WriteBufferSize, err = humanizeutil.ParseBytes(value)
+			if err != nil {
+				return StoreSpec{}, fmt.Errorf("could not parse write buffer size (%s) %s", value, err)
+			}
+			if ss.Tuning.WriteBufferSize < minimumWriteBufferSize {
+				return StoreSpec{}, fmt.Errorf("write buffer size (%s) must be at least %s", value,
+					humanizeutil.IBytes(minimumWriteBufferSize))
+			}
+		case "max-open-files":
+			var err error
+			ss.Tuning.MaxOpenFiles, err = strconv.Atoi(value)
+			if err != nil {
+				return StoreSpec{}, fmt.Errorf("could not parse max open files (%s) %s", value, err)
+			}
+			if ss.Tuning.MaxOpenFiles < engine.MinimumMaxOpenFiles {
+				return StoreSpec{}, fmt.Errorf("max open files (%s) must be at least %d", value,
+					engine.MinimumMaxOpenFiles)
+			}
+		case "raft-log":
+			if value == "separate" {
+				ss.SeparateRaftLog = true
+			} else {
+				return StoreSpec{}, fmt.Errorf("%s is not a valid raft log storage", value)
+			}
 		case "type":
 			if value == "mem" {
 				ss.InMemory = true
@@ -205,9 +368,18 @@ func newStoreSpec(value string) (StoreSpec, error) {
 		if ss.SizePercent == 0 && ss.SizeInBytes == 0 {
 			return StoreSpec{}, fmt.Errorf("size must be specified for an in memory store")
 		}
+		if ss.Encryption.KeyFile != "" || len(ss.Encryption.OldKeyFiles) > 0 {
+			return StoreSpec{}, fmt.Errorf("encryption specified for in memory store")
+		}
+		if ss.Tuning.isSet() {
+			return StoreSpec{}, fmt.Errorf("rocksdb options specified for in memory store")
+		}
 	} else if ss.Path == "" {
 		return StoreSpec{}, fmt.Errorf("no path specified")
 	}
+	if len(ss.Encryption.OldKeyFiles) > 0 && ss.Encryption.KeyFile == "" {
+		return StoreSpec{}, fmt.Errorf("old-key requires key to be specified")
+	}
 	return ss, nil
 }
 
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 )
 
@@ -36,49 +37,49 @@ func TestNewStoreSpec(t *testing.T) {
 		expected    StoreSpec
 	}{
 		// path
-		{"path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}}},
-		{",path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}}},
-		{",,,path=/mnt/hda1,,,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}}},
-		{"/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}}},
+		{"path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{",path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{",,,path=/mnt/hda1,,,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"path=", "no value specified for path", StoreSpec{}},
 		{"path=/mnt/hda1,path=/mnt/hda2", "path field was used twice in store definition", StoreSpec{}},
 		{"/mnt/hda1,path=/mnt/hda2", "path field was used twice in store definition", StoreSpec{}},
 
 		// attributes
-		{"path=/mnt/hda1,attrs=ssd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"ssd"}}}},
-		{"path=/mnt/hda1,attrs=ssd:hdd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
-		{"path=/mnt/hda1,attrs=hdd:ssd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
-		{"attrs=ssd:hdd,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
-		{"attrs=hdd:ssd,path=/mnt/hda1,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
+		{"path=/mnt/hda1,attrs=ssd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,attrs=ssd:hdd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,attrs=hdd:ssd", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"attrs=ssd:hdd,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"attrs=hdd:ssd,path=/mnt/hda1,", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"attrs=hdd:ssd", "no path specified", StoreSpec{}},
 		{"path=/mnt/hda1,attrs=", "no value specified for attrs", StoreSpec{}},
 		{"path=/mnt/hda1,attrs=hdd:hdd", "duplicate attribute given for store: hdd", StoreSpec{}},
 		{"path=/mnt/hda1,attrs=hdd,attrs=ssd", "attrs field was used twice in store definition", StoreSpec{}},
 
 		// size
-		{"path=/mnt/hda1,size=671088640", "", StoreSpec{"/mnt/hda1", 671088640, 0, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=20GB", "", StoreSpec{"/mnt/hda1", 20000000000, 0, false, roachpb.Attributes{}}},
-		{"size=20GiB,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 21474836480, 0, false, roachpb.Attributes{}}},
-		{"size=0.1TiB,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 109951162777, 0, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=.1TiB", "", StoreSpec{"/mnt/hda1", 109951162777, 0, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=123TB", "", StoreSpec{"/mnt/hda1", 123000000000000, 0, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=123TiB", "", StoreSpec{"/mnt/hda1", 135239930216448, 0, false, roachpb.Attributes{}}},
+		{"path=/mnt/hda1,size=671088640", "", StoreSpec{"/mnt/hda1", 671088640, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=20GB", "", StoreSpec{"/mnt/hda1", 20000000000, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"size=20GiB,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 21474836480, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"size=0.1TiB,path=/mnt/hda1", "", StoreSpec{"/mnt/hda1", 109951162777, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=.1TiB", "", StoreSpec{"/mnt/hda1", 109951162777, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=123TB", "", StoreSpec{"/mnt/hda1", 123000000000000, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=123TiB", "", StoreSpec{"/mnt/hda1", 135239930216448, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		// %
-		{"path=/mnt/hda1,size=50.5%", "", StoreSpec{"/mnt/hda1", 0, 50.5, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=100%", "", StoreSpec{"/mnt/hda1", 0, 100, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=1%", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}}},
+		{"path=/mnt/hda1,size=50.5%", "", StoreSpec{"/mnt/hda1", 0, 50.5, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=100%", "", StoreSpec{"/mnt/hda1", 0, 100, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=1%", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"path=/mnt/hda1,size=0.999999%", "store size (0.999999%) must be between 1% and 100%", StoreSpec{}},
 		{"path=/mnt/hda1,size=100.0001%", "store size (100.0001%) must be between 1% and 100%", StoreSpec{}},
 		// 0.xxx
-		{"path=/mnt/hda1,size=0.99", "", StoreSpec{"/mnt/hda1", 0, 99, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=0.5000000", "", StoreSpec{"/mnt/hda1", 0, 50, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=0.01", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}}},
+		{"path=/mnt/hda1,size=0.99", "", StoreSpec{"/mnt/hda1", 0, 99, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=0.5000000", "", StoreSpec{"/mnt/hda1", 0, 50, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=0.01", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"path=/mnt/hda1,size=0.009999", "store size (0.009999) must be between 1% and 100%", StoreSpec{}},
 		// .xxx
-		{"path=/mnt/hda1,size=.999", "", StoreSpec{"/mnt/hda1", 0, 99.9, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=.5000000", "", StoreSpec{"/mnt/hda1", 0, 50, false, roachpb.Attributes{}}},
-		{"path=/mnt/hda1,size=.01", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}}},
+		{"path=/mnt/hda1,size=.999", "", StoreSpec{"/mnt/hda1", 0, 99.9, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=.5000000", "", StoreSpec{"/mnt/hda1", 0, 50, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,size=.01", "", StoreSpec{"/mnt/hda1", 0, 1, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"path=/mnt/hda1,size=.009999", "store size (.009999) must be between 1% and 100%", StoreSpec{}},
 		// errors
 		{"path=/mnt/hda1,size=0", "store size (0) must be larger than 640 MiB", StoreSpec{}},
@@ -88,10 +89,10 @@ func TestNewStoreSpec(t *testing.T) {
 		{"size=123TB", "no path specified", StoreSpec{}},
 
 		// type
-		{"type=mem,size=20GiB", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{}}},
-		{"size=20GiB,type=mem", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{}}},
-		{"size=20.5GiB,type=mem", "", StoreSpec{"", 22011707392, 0, true, roachpb.Attributes{}}},
-		{"size=20GiB,type=mem,attrs=mem", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{Attrs: []string{"mem"}}}},
+		{"type=mem,size=20GiB", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"size=20GiB,type=mem", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"size=20.5GiB,type=mem", "", StoreSpec{"", 22011707392, 0, true, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"size=20GiB,type=mem,attrs=mem", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{Attrs: []string{"mem"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
 		{"type=mem,size=20", "store size (20) must be larger than 640 MiB", StoreSpec{}},
 		{"type=mem,size=", "no value specified for size", StoreSpec{}},
 		{"type=mem,attrs=ssd", "size must be specified for an in memory store", StoreSpec{}},
@@ -100,8 +101,33 @@ func TestNewStoreSpec(t *testing.T) {
 		{"path=/mnt/hda1,type=mem,size=20GiB", "path specified for in memory store", StoreSpec{}},
 
 		// all together
-		{"path=/mnt/hda1,attrs=hdd:ssd,size=20GiB", "", StoreSpec{"/mnt/hda1", 21474836480, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
-		{"type=mem,attrs=hdd:ssd,size=20GiB", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}}},
+		{"path=/mnt/hda1,attrs=hdd:ssd,size=20GiB", "", StoreSpec{"/mnt/hda1", 21474836480, 0, false, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+		{"type=mem,attrs=hdd:ssd,size=20GiB", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{Attrs: []string{"hdd", "ssd"}}, StoreEncryptionSpec{}, StoreTuningSpec{}, false}},
+
+		// encryption
+		{"path=/mnt/hda1,key=/keys/a", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{"/keys/a", nil}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,key=/keys/b,old-key=/keys/a", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{"/keys/b", []string{"/keys/a"}}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,key=plain,old-key=/keys/a:/keys/b", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{"plain", []string{"/keys/a", "/keys/b"}}, StoreTuningSpec{}, false}},
+		{"path=/mnt/hda1,key=", "no value specified for key", StoreSpec{}},
+		{"path=/mnt/hda1,old-key=/keys/a", "old-key requires key to be specified", StoreSpec{}},
+		{"path=/mnt/hda1,key=/keys/b,old-key=plain", "plain is not a valid old key file", StoreSpec{}},
+		{"type=mem,size=20GiB,key=/keys/a", "encryption specified for in memory store", StoreSpec{}},
+
+		// rocksdb options
+		{"path=/mnt/hda1,compression=snappy", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{[]engine.Compression{engine.CompressionSnappy}, 0, 0, 0}, false}},
+		{"path=/mnt/hda1,compression=none:none:snappy", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{[]engine.Compression{engine.CompressionNone, engine.CompressionNone, engine.CompressionSnappy}, 0, 0, 0}, false}},
+		{"path=/mnt/hda1,cache=1GiB,write-buffer-size=64MiB,max-open-files=1000", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{nil, 1073741824, 67108864, 1000}, false}},
+		{"path=/mnt/hda1,compression=zlib", "unsupported compression \"zlib\", must be none or snappy", StoreSpec{}},
+		{"path=/mnt/hda1,cache=0", "cache size (0) must be positive", StoreSpec{}},
+		{"path=/mnt/hda1,write-buffer-size=1KiB", "write buffer size (1KiB) must be at least 1.0 MiB", StoreSpec{}},
+		{"path=/mnt/hda1,max-open-files=abc", "could not parse max open files (abc) strconv.ParseInt: parsing \"abc\": invalid syntax", StoreSpec{}},
+		{"path=/mnt/hda1,max-open-files=10", "max open files (10) must be at least 256", StoreSpec{}},
+		{"type=mem,size=20GiB,cache=1GiB", "rocksdb options specified for in memory store", StoreSpec{}},
+
+		// raft log
+		{"path=/mnt/hda1,raft-log=separate", "", StoreSpec{"/mnt/hda1", 0, 0, false, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, true}},
+		{"type=mem,size=20GiB,raft-log=separate", "", StoreSpec{"", 21474836480, 0, true, roachpb.Attributes{}, StoreEncryptionSpec{}, StoreTuningSpec{}, true}},
+		{"path=/mnt/hda1,raft-log=shared", "shared is not a valid raft log storage", StoreSpec{}},
 
 		// other error cases
 		{"", "no value specified", StoreSpec{}},
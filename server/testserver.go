@@ -116,6 +116,9 @@ func makeTestContextFromParams(params base.TestServerArgs) Context {
 	if params.SSLCertKey != "" {
 		ctx.SSLCertKey = params.SSLCertKey
 	}
+	if params.SQLMemoryPoolSize != 0 {
+		ctx.SQLMemoryPoolSize = params.SQLMemoryPoolSize
+	}
 	ctx.JoinList = []string{params.JoinAddr}
 	return ctx
 }
@@ -355,6 +358,23 @@ func (ts *TestServer) MustGetSQLNetworkCounter(name string) int64 {
 	return c
 }
 
+// MustGetSQLHistogramCount implements TestServerInterface.
+func (ts *TestServer) MustGetSQLHistogramCount(name string) int64 {
+	var c int64
+	var found bool
+
+	ts.sqlExecutor.Registry().Each(func(n string, v interface{}) {
+		if name == n {
+			c = v.(*metric.Histogram).Current().TotalCount()
+			found = true
+		}
+	})
+	if !found {
+		panic(fmt.Sprintf("couldn't find metric %s", name))
+	}
+	return c
+}
+
 // KVClient is part of TestServerInterface.
 func (ts *TestServer) KVClient() interface{} { return ts.db }
 
@@ -376,6 +396,11 @@ func (ts *TestServer) GetDistSender() *kv.DistSender {
 	return ts.distSender
 }
 
+// NodeLiveness exposes the Server's NodeLiveness.
+func (ts *TestServer) NodeLiveness() *storage.NodeLiveness {
+	return ts.nodeLiveness
+}
+
 // GetFirstStoreID is a utility function returning the StoreID of the first
 // store on this node.
 func (ts *TestServer) GetFirstStoreID() roachpb.StoreID {
@@ -0,0 +1,184 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package cluster holds the version of the cluster, which gates the features
+// that nodes running older binaries don't understand, e.g. new below-raft
+// commands or on-disk formats.
+//
+// During a rolling upgrade, nodes running the new binary keep the cluster
+// version of the old one, so they don't use any of the new features which
+// the old nodes would choke on. Once all the nodes run the new binary, the
+// operator finalizes the upgrade with
+//
+//   SET CLUSTER SETTING version = '1.0-1'
+//
+// and the features gated by the versions up to it become active. The cluster
+// version can't be downgraded, so a node running a binary older than the
+// cluster version must not join the cluster anymore.
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/settings"
+)
+
+// Version is a version of the cluster. Versions with a non-zero Unstable
+// component are the steps introducing features in between releases.
+type Version struct {
+	Major, Minor, Unstable int32
+}
+
+// Less returns whether v is older than o.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Unstable < o.Unstable
+}
+
+// String formats the version as "major.minor", followed by "-unstable" if
+// the unstable component is not zero.
+func (v Version) String() string {
+	if v.Unstable == 0 {
+		return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%d.%d-%d", v.Major, v.Minor, v.Unstable)
+}
+
+// ParseVersion parses a version formatted by Version.String.
+func ParseVersion(s string) (Version, error) {
+	var v Version
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) == 2 {
+		u, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil || u < 0 {
+			return Version{}, errors.Errorf("invalid version %q", s)
+		}
+		v.Unstable = int32(u)
+	}
+	nums := strings.Split(parts[0], ".")
+	if len(nums) != 2 {
+		return Version{}, errors.Errorf("invalid version %q", s)
+	}
+	for i, p := range []*int32{&v.Major, &v.Minor} {
+		n, err := strconv.ParseInt(nums[i], 10, 32)
+		if err != nil || n < 0 {
+			return Version{}, errors.Errorf("invalid version %q", s)
+		}
+		*p = int32(n)
+	}
+	return v, nil
+}
+
+// VersionKey identifies the version introducing a feature.
+type VersionKey int
+
+// The versions introducing the features which are gated. New keys are added
+// at the end, along with their version in versionsSingleton.
+const (
+	// VersionBase is the version of the clusters created before the cluster
+	// version was introduced.
+	VersionBase VersionKey = iota
+	// VersionAddSSTable introduces the AddSSTable command, which links
+	// SSTables directly into the storage engines of the replicas.
+	VersionAddSSTable
+	// VersionClearRange introduces the ClearRange command, which removes the
+	// data of a key span directly from the storage engines of the replicas.
+	VersionClearRange
+)
+
+// versionsSingleton holds the version of each key.
+var versionsSingleton = []Version{
+	VersionBase:       {Major: 1, Minor: 0},
+	VersionAddSSTable: {Major: 1, Minor: 0, Unstable: 1},
+	VersionClearRange: {Major: 1, Minor: 0, Unstable: 2},
+}
+
+// VersionByKey returns the version introducing the feature identified by key.
+func VersionByKey(key VersionKey) Version {
+	return versionsSingleton[key]
+}
+
+var (
+	// BinaryMinimumSupportedVersion is the oldest version of the cluster
+	// this binary can join.
+	BinaryMinimumSupportedVersion = VersionByKey(VersionBase)
+	// BinaryServerVersion is the newest version of the cluster this binary
+	// can run, i.e. the version of the clusters it bootstraps.
+	BinaryServerVersion = versionsSingleton[len(versionsSingleton)-1]
+)
+
+// VersionSettingName is the name of the cluster setting holding the version.
+const VersionSettingName = "version"
+
+// version is the version of the cluster. Its default applies to the clusters
+// which were created before the setting existed; new clusters store the
+// version of the binary which bootstrapped them. It is registered by init
+// because its validation reads it.
+var version *settings.StringSetting
+
+func init() {
+	version = settings.RegisterValidatedStringSetting(
+		VersionSettingName,
+		"the version of the cluster, which only moves forward",
+		BinaryMinimumSupportedVersion.String(),
+		validateVersion,
+	)
+}
+
+func validateVersion(s string) error {
+	v, err := ParseVersion(s)
+	if err != nil {
+		return err
+	}
+	if v.Less(BinaryMinimumSupportedVersion) {
+		return errors.Errorf("cannot use version %s: the minimum supported version is %s",
+			v, BinaryMinimumSupportedVersion)
+	}
+	if BinaryServerVersion.Less(v) {
+		return errors.Errorf("cannot use version %s: this node runs version %s",
+			v, BinaryServerVersion)
+	}
+	// The setting isn't registered yet when its default is validated.
+	if version != nil {
+		if active := ActiveVersion(); v.Less(active) {
+			return errors.Errorf("cannot downgrade from version %s to %s", active, v)
+		}
+	}
+	return nil
+}
+
+// ActiveVersion returns the current version of the cluster.
+func ActiveVersion() Version {
+	v, err := ParseVersion(version.Get())
+	if err != nil {
+		// The values of the setting are validated before being set.
+		panic(err)
+	}
+	return v
+}
+
+// IsActive returns whether the feature introduced by the version identified
+// by key can be used, i.e. whether all the nodes of the cluster support it.
+func IsActive(key VersionKey) bool {
+	return !ActiveVersion().Less(VersionByKey(key))
+}
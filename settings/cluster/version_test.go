@@ -0,0 +1,94 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/testutils"
+)
+
+func TestParseVersion(t *testing.T) {
+	for _, tc := range []struct {
+		s string
+		v Version
+	}{
+		{"1.0", Version{Major: 1}},
+		{"1.1", Version{Major: 1, Minor: 1}},
+		{"1.0-3", Version{Major: 1, Unstable: 3}},
+		{"12.34-56", Version{Major: 12, Minor: 34, Unstable: 56}},
+	} {
+		v, err := ParseVersion(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.v {
+			t.Errorf("%s: expected %+v, got %+v", tc.s, tc.v, v)
+		}
+		if s := v.String(); s != tc.s {
+			t.Errorf("expected %s, got %s", tc.s, s)
+		}
+	}
+	for _, s := range []string{"", "1", "1.0.0", "a.b", "1.0-", "1.0-x", "-1.0"} {
+		if _, err := ParseVersion(s); !testutils.IsError(err, "invalid version") {
+			t.Errorf("%q: expected an invalid version error, got %v", s, err)
+		}
+	}
+
+	if !(Version{Major: 1, Minor: 0, Unstable: 5}).Less(Version{Major: 1, Minor: 1}) {
+		t.Error("expected 1.0-5 < 1.1")
+	}
+	if (Version{Major: 2}).Less(Version{Major: 1, Minor: 9}) {
+		t.Error("expected 2.0 >= 1.9")
+	}
+}
+
+func TestVersionGates(t *testing.T) {
+	set := func(v string) error {
+		u := settings.MakeUpdater()
+		if err := u.Set(VersionSettingName, v, "s"); err != nil {
+			return err
+		}
+		u.Done()
+		return nil
+	}
+	defer settings.MakeUpdater().Done()
+
+	// Clusters which haven't been upgraded don't use the gated features.
+	settings.MakeUpdater().Done()
+	if ActiveVersion() != BinaryMinimumSupportedVersion || IsActive(VersionAddSSTable) {
+		t.Fatalf("unexpected default version %s", ActiveVersion())
+	}
+
+	if err := set(VersionByKey(VersionAddSSTable).String()); err != nil {
+		t.Fatal(err)
+	}
+	if !IsActive(VersionAddSSTable) {
+		t.Fatalf("expected AddSSTable to be active at %s", ActiveVersion())
+	}
+
+	for _, tc := range []struct {
+		v, expErr string
+	}{
+		{"0.9", "the minimum supported version is"},
+		{"99.0", "this node runs version"},
+		{"1.0", "cannot downgrade"},
+	} {
+		if err := settings.Validate(VersionSettingName, tc.v); !testutils.IsError(err, tc.expErr) {
+			t.Errorf("%s: expected %q, got %v", tc.v, tc.expErr, err)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package settings is the registry of the cluster settings: the knobs
+// operators change at runtime with SET CLUSTER SETTING. Each package registers
+// the settings it reads in a package-level variable, e.g.
+//
+//   var traceSampleRate = settings.RegisterFloatSetting(
+//     "trace.zipkin.sample_rate", "fraction of the traces sent to Zipkin", 0.01)
+//
+// and calls Get() on it wherever it needs the current value. The values set
+// by operators are stored in the system.settings table, which is gossiped as
+// part of the system config; every node applies them with an Updater as the
+// gossiped system config changes.
+package settings
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The types of the settings, as stored in the valueType column of the
+// system.settings table.
+const (
+	typeBool     = "b"
+	typeInt      = "i"
+	typeFloat    = "f"
+	typeDuration = "d"
+	typeEnum     = "e"
+	typeString   = "s"
+)
+
+// Setting is a cluster setting.
+type Setting interface {
+	// Typ returns the short name of the type of the setting, as stored in the
+	// system.settings table.
+	Typ() string
+	// Description returns the description of the setting.
+	Description() string
+	// String returns the current value of the setting, encoded like in the
+	// system.settings table.
+	String() string
+
+	// check returns an error if the encoded value isn't valid for the
+	// setting.
+	check(encoded string) error
+	// set sets the value of the setting from its encoding.
+	set(encoded string) error
+	// setToDefault resets the setting to its default value.
+	setToDefault()
+}
+
+// registry holds all the registered settings, by name. It is populated by the
+// Register functions, which are called during the initialization of the
+// packages, so it is read without synchronization.
+var registry = map[string]Setting{}
+
+func register(key string, s Setting) {
+	if _, ok := registry[key]; ok {
+		panic(fmt.Sprintf("setting %q already registered", key))
+	}
+	registry[key] = s
+}
+
+// Lookup returns the setting with the given name.
+func Lookup(name string) (Setting, bool) {
+	s, ok := registry[name]
+	return s, ok
+}
+
+// Keys returns the sorted names of all the registered settings.
+func Keys() []string {
+	keys := make([]string, 0, len(registry))
+	for k := range registry {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// BoolSetting is a boolean setting.
+type BoolSetting struct {
+	description  string
+	defaultValue bool
+	v            int32
+}
+
+var _ Setting = &BoolSetting{}
+
+// RegisterBoolSetting registers and returns a new boolean setting.
+func RegisterBoolSetting(key, desc string, defaultValue bool) *BoolSetting {
+	s := &BoolSetting{description: desc, defaultValue: defaultValue}
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *BoolSetting) Get() bool {
+	return atomic.LoadInt32(&s.v) != 0
+}
+
+// Typ implements the Setting interface.
+func (*BoolSetting) Typ() string { return typeBool }
+
+// Description implements the Setting interface.
+func (s *BoolSetting) Description() string { return s.description }
+
+// String implements the Setting interface.
+func (s *BoolSetting) String() string { return EncodeBool(s.Get()) }
+
+func (s *BoolSetting) check(encoded string) error {
+	_, err := strconv.ParseBool(encoded)
+	return err
+}
+
+func (s *BoolSetting) set(encoded string) error {
+	v, err := strconv.ParseBool(encoded)
+	if err != nil {
+		return err
+	}
+	s.setValue(v)
+	return nil
+}
+
+func (s *BoolSetting) setValue(v bool) {
+	if v {
+		atomic.StoreInt32(&s.v, 1)
+	} else {
+		atomic.StoreInt32(&s.v, 0)
+	}
+}
+
+func (s *BoolSetting) setToDefault() { s.setValue(s.defaultValue) }
+
+// IntSetting is an integer setting.
+type IntSetting struct {
+	description  string
+	defaultValue int64
+	validate     func(int64) error
+	v            int64
+}
+
+var _ Setting = &IntSetting{}
+
+// RegisterIntSetting registers and returns a new integer setting.
+func RegisterIntSetting(key, desc string, defaultValue int64) *IntSetting {
+	return RegisterValidatedIntSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedIntSetting registers and returns a new integer setting
+// whose values are checked by validate before being set.
+func RegisterValidatedIntSetting(
+	key, desc string, defaultValue int64, validate func(int64) error,
+) *IntSetting {
+	if validate != nil {
+		if err := validate(defaultValue); err != nil {
+			panic(errors.Wrapf(err, "invalid default value for %q", key))
+		}
+	}
+	s := &IntSetting{description: desc, defaultValue: defaultValue, validate: validate}
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *IntSetting) Get() int64 {
+	return atomic.LoadInt64(&s.v)
+}
+
+// Validate returns an error if the value can't be set.
+func (s *IntSetting) Validate(v int64) error {
+	if s.validate != nil {
+		return s.validate(v)
+	}
+	return nil
+}
+
+// Typ implements the Setting interface.
+func (*IntSetting) Typ() string { return typeInt }
+
+// Description implements the Setting interface.
+func (s *IntSetting) Description() string { return s.description }
+
+// String implements the Setting interface.
+func (s *IntSetting) String() string { return EncodeInt(s.Get()) }
+
+func (s *IntSetting) parse(encoded string) (int64, error) {
+	v, err := strconv.ParseInt(encoded, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Validate(v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+func (s *IntSetting) check(encoded string) error {
+	_, err := s.parse(encoded)
+	return err
+}
+
+func (s *IntSetting) set(encoded string) error {
+	v, err := s.parse(encoded)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.v, v)
+	return nil
+}
+
+func (s *IntSetting) setToDefault() { atomic.StoreInt64(&s.v, s.defaultValue) }
+
+// FloatSetting is a floating-point setting.
+type FloatSetting struct {
+	description  string
+	defaultValue float64
+	v            uint64
+}
+
+var _ Setting = &FloatSetting{}
+
+// RegisterFloatSetting registers and returns a new floating-point setting.
+func RegisterFloatSetting(key, desc string, defaultValue float64) *FloatSetting {
+	s := &FloatSetting{description: desc, defaultValue: defaultValue}
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *FloatSetting) Get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.v))
+}
+
+// Typ implements the Setting interface.
+func (*FloatSetting) Typ() string { return typeFloat }
+
+// Description implements the Setting interface.
+func (s *FloatSetting) Description() string { return s.description }
+
+// String implements the Setting interface.
+func (s *FloatSetting) String() string { return EncodeFloat(s.Get()) }
+
+func (s *FloatSetting) check(encoded string) error {
+	_, err := strconv.ParseFloat(encoded, 64)
+	return err
+}
+
+func (s *FloatSetting) set(encoded string) error {
+	v, err := strconv.ParseFloat(encoded, 64)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&s.v, math.Float64bits(v))
+	return nil
+}
+
+func (s *FloatSetting) setToDefault() {
+	atomic.StoreUint64(&s.v, math.Float64bits(s.defaultValue))
+}
+
+// DurationSetting is a duration setting.
+type DurationSetting struct {
+	description  string
+	defaultValue time.Duration
+	v            int64
+}
+
+var _ Setting = &DurationSetting{}
+
+// RegisterDurationSetting registers and returns a new duration setting.
+func RegisterDurationSetting(key, desc string, defaultValue time.Duration) *DurationSetting {
+	s := &DurationSetting{description: desc, defaultValue: defaultValue}
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *DurationSetting) Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.v))
+}
+
+// Typ implements the Setting interface.
+func (*DurationSetting) Typ() string { return typeDuration }
+
+// Description implements the Setting interface.
+func (s *DurationSetting) Description() string { return s.description }
+
+// String implements the Setting interface.
+func (s *DurationSetting) String() string { return EncodeDuration(s.Get()) }
+
+func (s *DurationSetting) parse(encoded string) (time.Duration, error) {
+	v, err := time.ParseDuration(encoded)
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, errors.Errorf("cannot set to a negative duration: %s", v)
+	}
+	return v, nil
+}
+
+func (s *DurationSetting) check(encoded string) error {
+	_, err := s.parse(encoded)
+	return err
+}
+
+func (s *DurationSetting) set(encoded string) error {
+	v, err := s.parse(encoded)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.v, int64(v))
+	return nil
+}
+
+func (s *DurationSetting) setToDefault() { atomic.StoreInt64(&s.v, int64(s.defaultValue)) }
+
+// EnumSetting is a setting taking one of a set of named values. Its value is
+// the integer of the name.
+type EnumSetting struct {
+	description  string
+	defaultValue int64
+	enumValues   map[int64]string
+	v            int64
+}
+
+var _ Setting = &EnumSetting{}
+
+// RegisterEnumSetting registers and returns a new enum setting. The default
+// value must be the name of one of the values.
+func RegisterEnumSetting(
+	key, desc string, defaultValue string, enumValues map[int64]string,
+) *EnumSetting {
+	s := &EnumSetting{description: desc, enumValues: enumValues}
+	v, ok := s.ParseEnum(defaultValue)
+	if !ok {
+		panic(fmt.Sprintf("invalid default value %q for %q", defaultValue, key))
+	}
+	s.defaultValue = v
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *EnumSetting) Get() int64 {
+	return atomic.LoadInt64(&s.v)
+}
+
+// ParseEnum returns the value of the given name, which is compared
+// case-insensitively, or of the given integer.
+func (s *EnumSetting) ParseEnum(raw string) (int64, bool) {
+	raw = strings.ToLower(raw)
+	for v, name := range s.enumValues {
+		if strings.ToLower(name) == raw {
+			return v, true
+		}
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	_, ok := s.enumValues[v]
+	return v, ok
+}
+
+// Typ implements the Setting interface.
+func (*EnumSetting) Typ() string { return typeEnum }
+
+// Description implements the Setting interface.
+func (s *EnumSetting) Description() string {
+	values := make([]string, 0, len(s.enumValues))
+	for v, name := range s.enumValues {
+		values = append(values, fmt.Sprintf("%s = %d", strings.ToLower(name), v))
+	}
+	sort.Strings(values)
+	return fmt.Sprintf("%s [%s]", s.description, strings.Join(values, ", "))
+}
+
+// String implements the Setting interface.
+func (s *EnumSetting) String() string { return s.enumValues[s.Get()] }
+
+func (s *EnumSetting) check(encoded string) error {
+	if _, ok := s.ParseEnum(encoded); !ok {
+		return errors.Errorf("invalid value %q", encoded)
+	}
+	return nil
+}
+
+func (s *EnumSetting) set(encoded string) error {
+	v, ok := s.ParseEnum(encoded)
+	if !ok {
+		return errors.Errorf("invalid value %q", encoded)
+	}
+	atomic.StoreInt64(&s.v, v)
+	return nil
+}
+
+func (s *EnumSetting) setToDefault() { atomic.StoreInt64(&s.v, s.defaultValue) }
+
+// StringSetting is a string setting.
+type StringSetting struct {
+	description  string
+	defaultValue string
+	validate     func(string) error
+	v            atomic.Value
+}
+
+var _ Setting = &StringSetting{}
+
+// RegisterStringSetting registers and returns a new string setting.
+func RegisterStringSetting(key, desc string, defaultValue string) *StringSetting {
+	return RegisterValidatedStringSetting(key, desc, defaultValue, nil)
+}
+
+// RegisterValidatedStringSetting registers and returns a new string setting
+// whose values are checked by validate before being set.
+func RegisterValidatedStringSetting(
+	key, desc string, defaultValue string, validate func(string) error,
+) *StringSetting {
+	if validate != nil {
+		if err := validate(defaultValue); err != nil {
+			panic(errors.Wrapf(err, "invalid default value for %q", key))
+		}
+	}
+	s := &StringSetting{description: desc, defaultValue: defaultValue, validate: validate}
+	s.setToDefault()
+	register(key, s)
+	return s
+}
+
+// Get returns the current value of the setting.
+func (s *StringSetting) Get() string {
+	return s.v.Load().(string)
+}
+
+// Validate returns an error if the value can't be set.
+func (s *StringSetting) Validate(v string) error {
+	if s.validate != nil {
+		return s.validate(v)
+	}
+	return nil
+}
+
+// Typ implements the Setting interface.
+func (*StringSetting) Typ() string { return typeString }
+
+// Description implements the Setting interface.
+func (s *StringSetting) Description() string { return s.description }
+
+// String implements the Setting interface.
+func (s *StringSetting) String() string { return s.Get() }
+
+func (s *StringSetting) check(encoded string) error {
+	return s.Validate(encoded)
+}
+
+func (s *StringSetting) set(encoded string) error {
+	if err := s.Validate(encoded); err != nil {
+		return err
+	}
+	s.v.Store(encoded)
+	return nil
+}
+
+func (s *StringSetting) setToDefault() { s.v.Store(s.defaultValue) }
+
+// EncodeBool encodes a value of a boolean setting.
+func EncodeBool(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+// EncodeInt encodes a value of an integer setting.
+func EncodeInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+// EncodeFloat encodes a value of a floating-point setting.
+func EncodeFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// EncodeDuration encodes a value of a duration setting.
+func EncodeDuration(d time.Duration) string {
+	return d.String()
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/testutils"
+)
+
+var (
+	boolSetting     = RegisterBoolSetting("test.bool", "desc", true)
+	intSetting      = RegisterIntSetting("test.int", "desc", 1)
+	posIntSetting   = RegisterValidatedIntSetting("test.int.positive", "desc", 2, checkPositive)
+	floatSetting    = RegisterFloatSetting("test.float", "desc", 5.4)
+	durationSetting = RegisterDurationSetting("test.duration", "desc", time.Second)
+	enumSetting     = RegisterEnumSetting("test.enum", "desc", "foo", map[int64]string{1: "foo", 2: "bar"})
+	strSetting      = RegisterValidatedStringSetting("test.str", "desc", "a", checkNotEmpty)
+)
+
+func checkPositive(v int64) error {
+	if v <= 0 {
+		return errors.Errorf("%d is not positive", v)
+	}
+	return nil
+}
+
+func checkNotEmpty(v string) error {
+	if v == "" {
+		return errors.New("empty string")
+	}
+	return nil
+}
+
+func TestSettings(t *testing.T) {
+	if !boolSetting.Get() || intSetting.Get() != 1 || posIntSetting.Get() != 2 ||
+		floatSetting.Get() != 5.4 || durationSetting.Get() != time.Second || enumSetting.Get() != 1 ||
+		strSetting.Get() != "a" {
+		t.Fatal("unexpected defaults")
+	}
+
+	u := MakeUpdater()
+	for _, tc := range []struct {
+		key, encoded, typ string
+	}{
+		{"test.bool", EncodeBool(false), "b"},
+		{"test.int", EncodeInt(5), "i"},
+		{"test.float", EncodeFloat(1.5), "f"},
+		{"test.duration", EncodeDuration(time.Minute), "d"},
+		{"test.enum", "BAR", "e"},
+		{"test.str", "b", "s"},
+		// Unknown settings are ignored.
+		{"test.unknown", "x", "i"},
+	} {
+		if err := u.Set(tc.key, tc.encoded, tc.typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u.Done()
+	if boolSetting.Get() || intSetting.Get() != 5 || posIntSetting.Get() != 2 ||
+		floatSetting.Get() != 1.5 || durationSetting.Get() != time.Minute || enumSetting.Get() != 2 ||
+		strSetting.Get() != "b" {
+		t.Fatal("unexpected values after update")
+	}
+	if s := enumSetting.String(); s != "bar" {
+		t.Fatalf("expected bar, got %s", s)
+	}
+
+	// Settings missing from the next update go back to their defaults.
+	u = MakeUpdater()
+	if err := u.Set("test.int", EncodeInt(7), "i"); err != nil {
+		t.Fatal(err)
+	}
+	u.Done()
+	if !boolSetting.Get() || intSetting.Get() != 7 || durationSetting.Get() != time.Second ||
+		enumSetting.Get() != 1 || strSetting.Get() != "a" {
+		t.Fatal("unexpected values after second update")
+	}
+
+	for _, tc := range []struct {
+		key, encoded, typ, expErr string
+	}{
+		{"test.int", "1", "b", "has type i, not b"},
+		{"test.int", "a", "i", "invalid syntax"},
+		{"test.int.positive", "-1", "i", "-1 is not positive"},
+		{"test.duration", "-1s", "d", "negative duration"},
+		{"test.enum", "baz", "e", "invalid value"},
+		{"test.str", "", "s", "empty string"},
+	} {
+		if err := u.Set(tc.key, tc.encoded, tc.typ); !testutils.IsError(err, tc.expErr) {
+			t.Errorf("%s = %s: expected %q, got %v", tc.key, tc.encoded, tc.expErr, err)
+		}
+	}
+	if err := Validate("test.unknown", "1"); !testutils.IsError(err, "unknown cluster setting") {
+		t.Errorf("expected unknown setting error, got %v", err)
+	}
+	if err := Validate("test.int.positive", "0"); !testutils.IsError(err, "not positive") {
+		t.Errorf("expected validation error, got %v", err)
+	}
+	if posIntSetting.Get() != 2 {
+		t.Errorf("validation changed the setting to %d", posIntSetting.Get())
+	}
+}
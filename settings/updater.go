@@ -0,0 +1,67 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package settings
+
+import "github.com/pkg/errors"
+
+// Updater applies the complete set of the values stored in the
+// system.settings table: the settings which were set are updated, and all the
+// others are reset to their defaults when Done is called.
+type Updater map[string]struct{}
+
+// MakeUpdater returns a new Updater.
+func MakeUpdater() Updater {
+	return Updater{}
+}
+
+// Set sets the setting with the given name from its encoded value and the
+// type stored along with it. Unknown settings are ignored: they were set by
+// nodes running a version registering them.
+func (u Updater) Set(key, encoded, typ string) error {
+	s, ok := registry[key]
+	if !ok {
+		return nil
+	}
+	if typ != s.Typ() {
+		return errors.Errorf("setting %q has type %s, not %s", key, s.Typ(), typ)
+	}
+	if err := s.set(encoded); err != nil {
+		return errors.Wrapf(err, "setting %q", key)
+	}
+	u[key] = struct{}{}
+	return nil
+}
+
+// Done resets the settings which weren't set to their defaults.
+func (u Updater) Done() {
+	for key, s := range registry {
+		if _, ok := u[key]; !ok {
+			s.setToDefault()
+		}
+	}
+}
+
+// Validate returns an error if the encoded value can't be stored for the
+// setting with the given name, without changing it.
+func Validate(key, encoded string) error {
+	s, ok := registry[key]
+	if !ok {
+		return errors.Errorf("unknown cluster setting %q", key)
+	}
+	if err := s.check(encoded); err != nil {
+		return errors.Wrapf(err, "invalid value for %q", key)
+	}
+	return nil
+}
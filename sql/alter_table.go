@@ -50,6 +50,9 @@ func (p *planner) AlterTable(n *parser.AlterTable) (planNode, error) {
 		}
 		return nil, sqlbase.NewUndefinedTableError(tn.String())
 	}
+	if !tableDesc.IsTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
 
 	if err := p.checkPrivilege(tableDesc, privilege.CREATE); err != nil {
 		return nil, err
@@ -69,6 +72,9 @@ func (n *alterTableNode) Start() error {
 	origNumMutations := len(n.tableDesc.Mutations)
 
 	for _, cmd := range n.n.Cmds {
+		if n.tableDesc.IsPrimaryKeyChanging() {
+			return primaryKeyChangingError(n.tableDesc)
+		}
 		switch t := cmd.(type) {
 		case *parser.AlterTableAddColumn:
 			d := t.ColumnDef
@@ -76,6 +82,10 @@ func (n *alterTableNode) Start() error {
 			if err != nil {
 				return err
 			}
+			if col.IsComputed() {
+				// Existing rows would need to be backfilled with the computed value.
+				return fmt.Errorf("computed column %q cannot be added to an existing table", col.Name)
+			}
 			normName := sqlbase.ReNormalizeName(col.Name)
 			status, i, err := n.tableDesc.FindColumnByNormalizedName(normName)
 			if err == nil {
@@ -115,6 +125,16 @@ func (n *alterTableNode) Start() error {
 				if err := idx.FillColumns(d.Columns); err != nil {
 					return err
 				}
+				if err := checkIndexColumnsNotBeingReplaced(n.tableDesc, &idx); err != nil {
+					return err
+				}
+				if d.Predicate != nil {
+					s := d.Predicate.String()
+					idx.Predicate = &s
+					if _, err := makeIndexPredicate(n.tableDesc, &idx); err != nil {
+						return err
+					}
+				}
 				status, i, err := n.tableDesc.FindIndexByName(d.Name)
 				if err == nil {
 					if status == sqlbase.DescriptorIncomplete &&
@@ -140,9 +160,31 @@ func (n *alterTableNode) Start() error {
 			switch status {
 			case sqlbase.DescriptorActive:
 				col := n.tableDesc.Columns[i]
+				if isColumnBeingReplaced(n.tableDesc, col.ID) {
+					return columnBeingReplacedError(col.Name)
+				}
+				// Views don't record which columns they use, so no column can be
+				// dropped while views depend on the table.
+				if len(n.tableDesc.DependedOnBy) > 0 {
+					return n.p.dependentViewError("drop", "column", col.Name, n.tableDesc.DependedOnBy[0])
+				}
 				if n.tableDesc.PrimaryIndex.ContainsColumnID(col.ID) {
 					return fmt.Errorf("column %q is referenced by the primary key", col.Name)
 				}
+				computed, err := computedColumnReferencing(n.tableDesc, col.Name)
+				if err != nil {
+					return err
+				}
+				if computed != "" {
+					return fmt.Errorf("column %q is referenced by computed column %q", col.Name, computed)
+				}
+				partial, err := partialIndexReferencing(n.tableDesc, col.ID)
+				if err != nil {
+					return err
+				}
+				if partial != "" {
+					return fmt.Errorf("column %q is referenced by the predicate of index %q", col.Name, partial)
+				}
 				for _, idx := range n.tableDesc.AllNonDropIndexes() {
 					if idx.ContainsColumnID(col.ID) {
 						return fmt.Errorf("column %q is referenced by existing index %q", col.Name, idx.Name)
@@ -185,6 +227,36 @@ func (n *alterTableNode) Start() error {
 				}
 			}
 
+		case *parser.AlterTableAlterPrimaryKey:
+			if err := n.alterPrimaryKey(t); err != nil {
+				return err
+			}
+
+		case *parser.AlterTableAlterColumnType:
+			status, i, err := n.tableDesc.FindColumnByName(t.Column)
+			if err != nil {
+				return err
+			}
+
+			switch status {
+			case sqlbase.DescriptorActive:
+				changed, err := n.alterColumnType(n.tableDesc.Columns[i], t)
+				if err != nil {
+					return err
+				}
+				if changed {
+					descriptorChanged = true
+				}
+
+			case sqlbase.DescriptorIncomplete:
+				switch n.tableDesc.Mutations[i].Direction {
+				case sqlbase.DescriptorMutation_ADD:
+					return fmt.Errorf("column %q in the middle of being added, try again later", t.Column)
+				case sqlbase.DescriptorMutation_DROP:
+					return fmt.Errorf("column %q in the middle of being dropped", t.Column)
+				}
+			}
+
 		case parser.ColumnMutationCmd:
 			// Column mutations
 			status, i, err := n.tableDesc.FindColumnByName(t.GetColumn())
@@ -194,6 +266,9 @@ func (n *alterTableNode) Start() error {
 
 			switch status {
 			case sqlbase.DescriptorActive:
+				if isColumnBeingReplaced(n.tableDesc, n.tableDesc.Columns[i].ID) {
+					return columnBeingReplacedError(n.tableDesc.Columns[i].Name)
+				}
 				if err := applyColumnMutation(&n.tableDesc.Columns[i], t); err != nil {
 					return err
 				}
@@ -280,6 +355,9 @@ func (n *alterTableNode) ExplainPlan(v bool) (string, string, []planNode) {
 func applyColumnMutation(col *sqlbase.ColumnDescriptor, mut parser.ColumnMutationCmd) error {
 	switch t := mut.(type) {
 	case *parser.AlterTableSetDefault:
+		if col.IsComputed() {
+			return fmt.Errorf("computed column %q cannot have a DEFAULT expression", col.Name)
+		}
 		if t.Default == nil {
 			col.DefaultExpr = nil
 		} else {
@@ -296,3 +374,323 @@ func applyColumnMutation(col *sqlbase.ColumnDescriptor, mut parser.ColumnMutatio
 	}
 	return nil
 }
+
+// alterColumnType changes the type of the column col. A conversion which
+// keeps the stored values valid for the new type, such as widening a STRING,
+// is applied to the descriptor directly, which is reported by the returned
+// bool. Otherwise a column of the new type is added, backfilled with the
+// converted values of col and then swapped in for col by the schema changer.
+func (n *alterTableNode) alterColumnType(
+	col sqlbase.ColumnDescriptor, t *parser.AlterTableAlterColumnType,
+) (bool, error) {
+	if col.IsComputed() {
+		return false, fmt.Errorf("cannot change the type of computed column %q", col.Name)
+	}
+	if isColumnBeingReplaced(n.tableDesc, col.ID) {
+		return false, columnBeingReplacedError(col.Name)
+	}
+	if typ, ok := t.ToType.(*parser.IntColType); ok && typ.IsSerial() {
+		return false, fmt.Errorf("cannot change the type of column %q to %s", col.Name, t.ToType)
+	}
+	typeDesc, _, err := sqlbase.MakeColumnDefDescs(&parser.ColumnTableDef{Name: t.Column, Type: t.ToType})
+	if err != nil {
+		return false, err
+	}
+	newType := typeDesc.Type
+
+	if col.DefaultExpr != nil {
+		defaultExpr, err := parser.ParseExprTraditional(*col.DefaultExpr)
+		if err != nil {
+			return false, err
+		}
+		if err := sqlbase.SanitizeVarFreeExpr(defaultExpr, newType.ToDatumType(), "DEFAULT"); err != nil {
+			return false, fmt.Errorf("default for column %q cannot be cast automatically to type %s",
+				col.Name, newType.SQLString())
+		}
+	}
+
+	if t.Using == nil && columnTypeConvertibleInPlace(col.Type, newType) {
+		if col.Type.SQLString() == newType.SQLString() {
+			return false, nil
+		}
+		for i := range n.tableDesc.Columns {
+			if n.tableDesc.Columns[i].ID == col.ID {
+				n.tableDesc.Columns[i].Type = newType
+			}
+		}
+		return true, nil
+	}
+
+	// The values of the column are rewritten, which isn't possible for columns
+	// used by other schema elements.
+	if len(n.tableDesc.DependedOnBy) > 0 {
+		return false, n.p.dependentViewError(
+			"change the type of", "column", col.Name, n.tableDesc.DependedOnBy[0])
+	}
+	if n.tableDesc.PrimaryIndex.ContainsColumnID(col.ID) {
+		return false, fmt.Errorf("column %q is referenced by the primary key", col.Name)
+	}
+	for _, idx := range n.tableDesc.AllNonDropIndexes() {
+		if idx.ContainsColumnID(col.ID) {
+			return false, fmt.Errorf("column %q is referenced by existing index %q", col.Name, idx.Name)
+		}
+	}
+	computed, err := computedColumnReferencing(n.tableDesc, col.Name)
+	if err != nil {
+		return false, err
+	}
+	if computed != "" {
+		return false, fmt.Errorf("column %q is referenced by computed column %q", col.Name, computed)
+	}
+	partial, err := partialIndexReferencing(n.tableDesc, col.ID)
+	if err != nil {
+		return false, err
+	}
+	if partial != "" {
+		return false, fmt.Errorf("column %q is referenced by the predicate of index %q", col.Name, partial)
+	}
+	check, err := checkReferencing(n.tableDesc, col.Name)
+	if err != nil {
+		return false, err
+	}
+	if check != "" {
+		return false, fmt.Errorf("column %q is referenced by CHECK constraint %q", col.Name, check)
+	}
+
+	// The new column computes its values from the row, which keeps it up to
+	// date with writes while it is being backfilled.
+	using := t.Using
+	if using == nil {
+		using = &parser.CastExpr{Expr: parser.UnresolvedName{parser.Name(col.Name)}, Type: t.ToType}
+	}
+	if err := validateRowExpr(
+		n.tableDesc, using, newType.ToDatumType(), "USING expression", "USING expression",
+	); err != nil {
+		return false, err
+	}
+	expr := using.String()
+
+	newCol := col
+	newCol.ID = 0
+	newCol.Name = makeReplacementColumnName(n.tableDesc, col.Name)
+	newCol.Type = newType
+	newCol.DefaultExpr = nil
+	newCol.DefaultExprConstraintName = ""
+	newCol.NullableConstraintName = ""
+	newCol.ComputedExpr = &expr
+	n.tableDesc.AddColumnReplacementMutation(newCol, col.ID)
+
+	// Store the new column in the family of the column it replaces.
+	for _, family := range n.tableDesc.Families {
+		for _, id := range family.ColumnIDs {
+			if id == col.ID {
+				return false, n.tableDesc.AddColumnToFamilyMaybeCreate(
+					newCol.Name, family.Name, false, false)
+			}
+		}
+	}
+	return false, nil
+}
+
+// columnTypeConvertibleInPlace returns true if the values stored for a
+// column of type from are valid values of type to.
+func columnTypeConvertibleInPlace(from, to sqlbase.ColumnType) bool {
+	if from.Kind != to.Kind {
+		return false
+	}
+	switch from.Kind {
+	case sqlbase.ColumnType_INT, sqlbase.ColumnType_STRING:
+		return to.Width == 0 || (from.Width > 0 && to.Width >= from.Width)
+
+	case sqlbase.ColumnType_COLLATEDSTRING:
+		if *from.Locale != *to.Locale {
+			return false
+		}
+		return to.Width == 0 || (from.Width > 0 && to.Width >= from.Width)
+
+	case sqlbase.ColumnType_DECIMAL:
+		// Values are rounded to the scale of the column, so the scale can't
+		// change, but more digits can be allowed left of the decimal point.
+		if to.Precision == 0 {
+			return true
+		}
+		return from.Precision > 0 && to.Width == from.Width && to.Precision >= from.Precision
+
+	case sqlbase.ColumnType_ARRAY:
+		return false
+	}
+	return true
+}
+
+// makeReplacementColumnName returns the name of the column replacing the
+// named column, which must not be used by any other column of the table.
+func makeReplacementColumnName(desc *sqlbase.TableDescriptor, name string) string {
+	candidate := name + "_new"
+	for i := 1; ; i++ {
+		if _, _, err := desc.FindColumnByName(parser.Name(candidate)); err != nil {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_new%d", name, i)
+	}
+}
+
+// isColumnBeingReplaced returns true if the type of the column with the
+// given ID is being changed.
+func isColumnBeingReplaced(desc *sqlbase.TableDescriptor, id sqlbase.ColumnID) bool {
+	for _, m := range desc.Mutations {
+		if m.Direction == sqlbase.DescriptorMutation_ADD && m.ReplacesColumnID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func columnBeingReplacedError(name string) error {
+	return fmt.Errorf("type of column %q is being changed, try again later", name)
+}
+
+// checkIndexColumnsNotBeingReplaced returns an error if the index contains a
+// column whose type is being changed. The index would otherwise keep
+// referencing the replaced column.
+func checkIndexColumnsNotBeingReplaced(
+	desc *sqlbase.TableDescriptor, idx *sqlbase.IndexDescriptor,
+) error {
+	names := append(append([]string(nil), idx.ColumnNames...), idx.StoreColumnNames...)
+	for _, name := range names {
+		col, err := desc.FindActiveColumnByName(parser.Name(name))
+		if err != nil {
+			// Reported when the index is added.
+			continue
+		}
+		if isColumnBeingReplaced(desc, col.ID) {
+			return columnBeingReplacedError(col.Name)
+		}
+	}
+	return nil
+}
+
+// alterPrimaryKey changes the primary key of the table to the given columns.
+// An index with the new primary key and the primary index encoding is added,
+// along with a copy of each secondary index encoded for the new primary key
+// and a unique index on the columns of the old primary key. Once they have
+// been backfilled, they are swapped in by the schema changer, which then
+// drops the indexes they replace.
+func (n *alterTableNode) alterPrimaryKey(t *parser.AlterTableAlterPrimaryKey) error {
+	desc := n.tableDesc
+	if len(desc.Mutations) > 0 {
+		return fmt.Errorf("table %q has schema changes in progress, try again later", desc.Name)
+	}
+	if desc.IsInterleaved() {
+		return fmt.Errorf("cannot change the primary key of interleaved table %q", desc.Name)
+	}
+	for _, idx := range desc.AllNonDropIndexes() {
+		if idx.ForeignKey.IsSet() || len(idx.ReferencedBy) > 0 {
+			return fmt.Errorf("cannot change the primary key of table %q with foreign keys", desc.Name)
+		}
+	}
+
+	newPrimary := sqlbase.IndexDescriptor{
+		Name:         makeReplacementIndexName(desc, desc.PrimaryIndex.Name),
+		Unique:       true,
+		EncodingType: sqlbase.PrimaryIndexEncoding,
+	}
+	if err := newPrimary.FillColumns(t.Columns); err != nil {
+		return err
+	}
+	seen := make(map[sqlbase.ColumnID]struct{}, len(t.Columns))
+	for _, elem := range t.Columns {
+		col, err := desc.FindActiveColumnByName(elem.Column)
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[col.ID]; ok {
+			return fmt.Errorf("column %q appears twice in primary key", col.Name)
+		}
+		seen[col.ID] = struct{}{}
+		if col.Nullable {
+			return fmt.Errorf("column %q must be NOT NULL to be part of the primary key", col.Name)
+		}
+		if isColumnBeingReplaced(desc, col.ID) {
+			return columnBeingReplacedError(col.Name)
+		}
+		inFirstFamily := false
+		for _, id := range desc.Families[0].ColumnIDs {
+			if id == col.ID {
+				inFirstFamily = true
+			}
+		}
+		if !inFirstFamily {
+			return fmt.Errorf("column %q must be in the first column family to be part of the primary key",
+				col.Name)
+		}
+	}
+	if indexColumnsEqual(newPrimary, desc.PrimaryIndex) {
+		// Noop.
+		return nil
+	}
+
+	desc.AddIndexReplacementMutation(newPrimary, desc.PrimaryIndex.ID)
+	for _, idx := range desc.Indexes {
+		newIdx := idx
+		newIdx.ID = 0
+		newIdx.Name = makeReplacementIndexName(desc, idx.Name)
+		newIdx.ImplicitColumnIDs = nil
+		desc.AddIndexReplacementMutation(newIdx, idx.ID)
+	}
+
+	// Keep the columns of the old primary key unique, unless they are the
+	// hidden row ID or another unique index covers them.
+	oldPrimary := desc.PrimaryIndex
+	if len(oldPrimary.ColumnIDs) == 1 {
+		col, err := desc.FindActiveColumnByID(oldPrimary.ColumnIDs[0])
+		if err != nil {
+			return err
+		}
+		if col.Hidden {
+			return nil
+		}
+	}
+	for _, idx := range desc.Indexes {
+		if idx.Unique && !idx.IsPartial() && indexColumnsEqual(idx, oldPrimary) {
+			return nil
+		}
+	}
+	unique := sqlbase.IndexDescriptor{
+		Unique:           true,
+		ColumnNames:      append([]string(nil), oldPrimary.ColumnNames...),
+		ColumnDirections: append([]sqlbase.IndexDescriptor_Direction(nil), oldPrimary.ColumnDirections...),
+	}
+	desc.AddIndexMutation(unique, sqlbase.DescriptorMutation_ADD)
+	return nil
+}
+
+// indexColumnsEqual returns true if the two indexes have the same columns in
+// the same directions.
+func indexColumnsEqual(a, b sqlbase.IndexDescriptor) bool {
+	if len(a.ColumnNames) != len(b.ColumnNames) {
+		return false
+	}
+	for i := range a.ColumnNames {
+		if sqlbase.ReNormalizeName(a.ColumnNames[i]) != sqlbase.ReNormalizeName(b.ColumnNames[i]) ||
+			a.ColumnDirections[i] != b.ColumnDirections[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// makeReplacementIndexName returns the name of the index replacing the named
+// index, which must not be used by any other index of the table.
+func makeReplacementIndexName(desc *sqlbase.TableDescriptor, name string) string {
+	candidate := name + "_new"
+	for i := 1; ; i++ {
+		if _, _, err := desc.FindIndexByName(parser.Name(candidate)); err != nil {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_new%d", name, i)
+	}
+}
+
+func primaryKeyChangingError(desc *sqlbase.TableDescriptor) error {
+	return fmt.Errorf("primary key of table %q is being changed, try again later", desc.Name)
+}
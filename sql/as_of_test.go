@@ -134,7 +134,7 @@ func TestAsOfTime(t *testing.T) {
 	// Old queries shouldn't work.
 	if err := db.QueryRow("SELECT a FROM d.t AS OF SYSTEM TIME '1969-12-31'").Scan(&i); err == nil {
 		t.Fatal("expected error")
-	} else if !testutils.IsError(err, "pq: batch timestamp -86400.000000000,0 must be after replica GC threshold 0.000000000,0") {
+	} else if !testutils.IsError(err, `pq: AS OF SYSTEM TIME: timestamp 1969-12-31 00:00:00 \+0000 UTC is older than the GC TTL of table "t" \(24h0m0s\)`) {
 		t.Fatal("unexpected error:", err)
 	}
 
@@ -23,6 +23,8 @@ import (
 
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/settings/cluster"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -155,6 +157,9 @@ func (sc *SchemaChanger) runBackfill(lease *sqlbase.TableDescriptor_SchemaChange
 	if err := sc.truncateIndexes(lease, droppedIndexDescs); err != nil {
 		return err
 	}
+	if err := sc.job.Progressed(context.TODO(), 1.0/3); err != nil {
+		return err
+	}
 
 	// Add and drop columns.
 	if err := sc.truncateAndBackfillColumns(
@@ -162,13 +167,12 @@ func (sc *SchemaChanger) runBackfill(lease *sqlbase.TableDescriptor_SchemaChange
 	); err != nil {
 		return err
 	}
-
-	// Add new indexes.
-	if err := sc.backfillIndexes(lease, addedIndexDescs); err != nil {
+	if err := sc.job.Progressed(context.TODO(), 2.0/3); err != nil {
 		return err
 	}
 
-	return nil
+	// Add new indexes.
+	return sc.backfillIndexes(lease, addedIndexDescs)
 }
 
 // getTableSpan returns a span containing the start and end key for a table.
@@ -211,17 +215,21 @@ func (sc *SchemaChanger) truncateAndBackfillColumns(
 		return err
 	}
 
-	// Note if there is a new non nullable column with no default value.
+	// Note if there is a new non nullable column with no default value, or a
+	// new column computed from the values of the other columns.
 	addingNonNullableColumn := false
+	addingComputedColumn := false
 	for _, columnDesc := range added {
 		if columnDesc.DefaultExpr == nil && !columnDesc.Nullable {
 			addingNonNullableColumn = true
-			break
+		}
+		if columnDesc.IsComputed() {
+			addingComputedColumn = true
 		}
 	}
 
 	// Add or Drop a column.
-	if len(dropped) > 0 || addingNonNullableColumn || len(defaultExprs) > 0 {
+	if len(dropped) > 0 || addingNonNullableColumn || addingComputedColumn || len(defaultExprs) > 0 {
 		// Initialize start and end to represent a span of keys.
 		sp, err := sc.getTableSpan()
 		if err != nil {
@@ -297,6 +305,40 @@ func (sc *SchemaChanger) truncateAndBackfillColumnsChunk(
 			panic("only column data should be modified, but the rowUpdater is configured otherwise")
 		}
 
+		// Columns replacing a column of another type are computed from the
+		// values of the replaced column. Errors are reported against the
+		// replaced column.
+		var computedCols []sqlbase.ColumnDescriptor
+		addedColIDtoRowIndex := colIDtoRowIndexFromCols(added)
+		replacedNames := make(map[sqlbase.ColumnID]string)
+		for _, col := range added {
+			if col.IsComputed() {
+				computedCols = append(computedCols, col)
+			}
+		}
+		for _, m := range tableDesc.Mutations {
+			if col := m.GetColumn(); col != nil && m.ReplacesColumnID != 0 {
+				replaced, err := tableDesc.FindActiveColumnByID(m.ReplacesColumnID)
+				if err != nil {
+					return err
+				}
+				replacedNames[col.ID] = replaced.Name
+			}
+		}
+		colName := func(col sqlbase.ColumnDescriptor) string {
+			if name, ok := replacedNames[col.ID]; ok {
+				return name
+			}
+			return col.Name
+		}
+		var ch computeHelper
+		tn := parser.TableName{TableName: parser.Name(tableDesc.Name)}
+		if err := ch.initCols(
+			makeInternalPlanner(txn, security.RootUser), &tn, tableDesc, computedCols,
+		); err != nil {
+			return err
+		}
+
 		// Run a scan across the table using the primary key. Running
 		// the scan and applying the changes in many transactions is
 		// fine because the schema change is in the correct state to
@@ -336,17 +378,32 @@ func (sc *SchemaChanger) truncateAndBackfillColumnsChunk(
 			curIndexKey, _, err = sqlbase.EncodeIndexKey(
 				tableDesc, &tableDesc.PrimaryIndex, colIDtoRowIndex, row, indexKeyPrefix)
 
+			ch.loadRow(colIDtoRowIndex, row, false)
+			for k, expr := range ch.exprs {
+				col := ch.computedCols[k]
+				j := addedColIDtoRowIndex[col.ID]
+				updateValues[j], err = expr.Eval(evalCtx)
+				if err == nil {
+					err = sqlbase.CheckValueWidth(col, updateValues[j])
+				}
+				if err != nil {
+					return sqlbase.NewColumnConversionError(colName(col), col.Type, err)
+				}
+			}
 			for j, col := range added {
-				if defaultExprs == nil || defaultExprs[j] == nil {
+				switch {
+				case col.IsComputed():
+					// Computed above.
+				case defaultExprs == nil || defaultExprs[j] == nil:
 					updateValues[j] = parser.DNull
-				} else {
+				default:
 					updateValues[j], err = defaultExprs[j].Eval(evalCtx)
 					if err != nil {
 						return err
 					}
 				}
 				if !col.Nullable && updateValues[j].Compare(parser.DNull) == 0 {
-					return sqlbase.NewNonNullViolationError(col.Name)
+					return sqlbase.NewNonNullViolationError(colName(col))
 				}
 			}
 			for j := range dropped {
@@ -384,6 +441,7 @@ func (sc *SchemaChanger) truncateIndexes(
 			return err
 		}
 		*lease = l
+		var clearSpan roachpb.Span
 		if err := sc.db.Txn(func(txn *client.Txn) error {
 			tableDesc, err := sqlbase.GetTableDescFromID(txn, sc.tableID)
 			if err != nil {
@@ -394,6 +452,16 @@ func (sc *SchemaChanger) truncateIndexes(
 				return nil
 			}
 
+			// The entries of an index which isn't interleaved occupy their own
+			// span, which is removed with ClearRange instead of one deletion
+			// per entry.
+			if cluster.IsActive(cluster.VersionClearRange) &&
+				len(desc.Interleave.Ancestors) == 0 && len(desc.InterleavedBy) == 0 {
+				clearSpan.Key = roachpb.Key(sqlbase.MakeIndexKeyPrefix(tableDesc, desc.ID))
+				clearSpan.EndKey = clearSpan.Key.PrefixEnd()
+				return nil
+			}
+
 			rd, err := makeRowDeleter(txn, tableDesc, nil, nil, false)
 			if err != nil {
 				return err
@@ -406,6 +474,11 @@ func (sc *SchemaChanger) truncateIndexes(
 		}); err != nil {
 			return err
 		}
+		if clearSpan.Key != nil {
+			if err := sc.db.ClearRange(clearSpan.Key, clearSpan.EndKey); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -425,12 +498,22 @@ func (sc *SchemaChanger) backfillIndexes(
 	if len(added) == 0 {
 		return nil
 	}
+	if sc.distSQLSrv != nil && canBulkBackfill(added) {
+		return sc.bulkBackfillIndexes(lease, added)
+	}
 
 	// Initialize start and end to represent a span of keys.
 	sp, err := sc.getTableSpan()
 	if err != nil {
 		return err
 	}
+	// Resume after the rows backfilled before the schema change was
+	// interrupted, if the job recorded where it got to.
+	if checkpoint := roachpb.Key(sc.job.Checkpoint()); checkpoint != nil &&
+		checkpoint.Compare(sp.Start) > 0 && checkpoint.Compare(sp.End) < 0 {
+		log.Infof(context.TODO(), "resuming backfill of table %d at %s", sc.tableID, checkpoint)
+		sp.Start = checkpoint
+	}
 
 	// Backfill the index entries for all the rows.
 	for done := false; !done; {
@@ -445,6 +528,18 @@ func (sc *SchemaChanger) backfillIndexes(
 		if err != nil {
 			return err
 		}
+		// Record the rows done so far, so that a retry of the schema change
+		// doesn't redo them.
+		if !done {
+			if err := sc.job.Checkpointed(context.TODO(), 2.0/3, []byte(sp.Start)); err != nil {
+				return err
+			}
+		}
+		if sc.backfillChunkNotification != nil {
+			if err := sc.backfillChunkNotification(); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -466,77 +561,107 @@ func (sc *SchemaChanger) backfillIndexesChunk(
 			return nil
 		}
 
-		// Get the next set of rows.
-		// TODO(tamird): Support partial indexes?
-		//
-		// Use a scanNode with SELECT to pass in a sqlbase.TableDescriptor
-		// to the SELECT without needing to go through table name
-		// resolution, because we want to run schema changes from a gossip
-		// feed of table IDs. Running the scan and applying the changes in
-		// many transactions is fine because the schema change is in the
-		// correct state to handle intermediate OLTP commands which delete
-		// and add values during the scan.
-		planner := makePlanner()
-		planner.setTxn(txn)
-		scan := planner.Scan()
-		scan.desc = *tableDesc
-		scan.spans = []sqlbase.Span{sp}
-		scan.initDescDefaults(publicAndNonPublicColumns)
-		rows, err := selectIndex(scan, nil, false)
-		if err != nil {
-			return err
-		}
-
-		if err := rows.Start(); err != nil {
-			return err
-		}
-
-		// Construct a map from column ID to the index the value appears at
-		// within a row.
-		colIDtoRowIndex, err := makeColIDtoRowIndex(rows, tableDesc)
+		var entries []sqlbase.IndexEntry
+		entries, nextKey, err = indexEntriesChunk(txn, tableDesc, added, sp, IndexBackfillChunkSize)
 		if err != nil {
 			return err
 		}
 		b := &client.Batch{}
-		numRows := 0
-		for ; numRows < IndexBackfillChunkSize; numRows++ {
-			if next, err := rows.Next(); !next {
-				if err != nil {
-					return err
-				}
-				break
-			}
-			rowVals := rows.Values()
-
-			for _, desc := range added {
-				secondaryIndexEntries := make([]sqlbase.IndexEntry, 1)
-				err := sqlbase.EncodeSecondaryIndexes(
-					tableDesc, []sqlbase.IndexDescriptor{desc}, colIDtoRowIndex,
-					rowVals, secondaryIndexEntries)
-				if err != nil {
-					return err
-				}
-				for _, secondaryIndexEntry := range secondaryIndexEntries {
-					if log.V(2) {
-						log.Infof(context.TODO(), "InitPut %s -> %v", secondaryIndexEntry.Key,
-							secondaryIndexEntry.Value)
-					}
-					b.InitPut(secondaryIndexEntry.Key, &secondaryIndexEntry.Value)
-				}
+		for _, entry := range entries {
+			if log.V(2) {
+				log.Infof(context.TODO(), "InitPut %s -> %v", entry.Key, entry.Value)
 			}
+			b.InitPut(entry.Key, &entry.Value)
 		}
 		// Write the new index values.
 		if err := txn.Run(b); err != nil {
 			return convertBackfillError(tableDesc, b)
 		}
 		// Have we processed all the table rows?
-		if numRows < IndexBackfillChunkSize {
-			done = true
-			return nil
-		}
-		// Keep track of the next key.
-		nextKey = scan.fetcher.Key()
+		done = nextKey == nil
 		return nil
 	})
 	return nextKey, done, err
 }
+
+// indexEntriesChunk reads up to chunkSize rows of the table in txn, starting
+// at sp.Start, and returns the entries of the added indexes for them, along
+// with the key of the next row to read, or nil if the span was exhausted.
+func indexEntriesChunk(
+	txn *client.Txn,
+	tableDesc *sqlbase.TableDescriptor,
+	added []sqlbase.IndexDescriptor,
+	sp sqlbase.Span,
+	chunkSize int,
+) ([]sqlbase.IndexEntry, roachpb.Key, error) {
+	// Get the next set of rows.
+	// TODO(tamird): Support partial indexes?
+	//
+	// Use a scanNode with SELECT to pass in a sqlbase.TableDescriptor
+	// to the SELECT without needing to go through table name
+	// resolution, because we want to run schema changes from a gossip
+	// feed of table IDs. Running the scan and applying the changes in
+	// many transactions is fine because the schema change is in the
+	// correct state to handle intermediate OLTP commands which delete
+	// and add values during the scan.
+	planner := makePlanner()
+	planner.setTxn(txn)
+	scan := planner.Scan()
+	scan.desc = *tableDesc
+	scan.spans = []sqlbase.Span{sp}
+	scan.initDescDefaults(publicAndNonPublicColumns)
+	rows, err := selectIndex(scan, nil, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := rows.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	// Construct a map from column ID to the index the value appears at
+	// within a row.
+	colIDtoRowIndex, err := makeColIDtoRowIndex(rows, tableDesc)
+	if err != nil {
+		return nil, nil, err
+	}
+	preds, err := makeIndexPredicates(tableDesc, added)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []sqlbase.IndexEntry
+	numRows := 0
+	for ; numRows < chunkSize; numRows++ {
+		if next, err := rows.Next(); !next {
+			if err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+		rowVals := rows.Values()
+
+		for i := range added {
+			if preds[i] != nil {
+				matches, err := preds[i].matches(&planner.evalCtx, colIDtoRowIndex, rowVals)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !matches {
+					// The row isn't contained in the partial index.
+					continue
+				}
+			}
+			secondaryIndexEntries, err := sqlbase.EncodeSecondaryIndexEntries(
+				tableDesc, &added[i], colIDtoRowIndex, rowVals)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries = append(entries, secondaryIndexEntries...)
+		}
+	}
+	if numRows < chunkSize {
+		return entries, nil, nil
+	}
+	// Keep track of the next key.
+	return entries, scan.fetcher.Key(), nil
+}
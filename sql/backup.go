@@ -0,0 +1,281 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/extstorage"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/protoutil"
+	"github.com/pkg/errors"
+)
+
+// BackupDescriptorName is the name of the manifest file written by BACKUP
+// next to the data files.
+const BackupDescriptorName = "BACKUP"
+
+var backupColumns = []ResultColumn{
+	{Name: "files", Typ: parser.TypeInt},
+	{Name: "data_size", Typ: parser.TypeInt},
+}
+
+type backupNode struct {
+	p *planner
+	n *parser.Backup
+
+	result parser.DTuple
+	done   bool
+}
+
+// Backup writes the contents of a set of tables, or of all the tables in a
+// set of databases, to the directory named by an external storage URI (see
+// package extstorage).
+//
+// The data is read at the timestamp of the statement's transaction, which is
+// also the timestamp the descriptors are read at, so the backup is a
+// consistent snapshot. Every range holding part of a table exports its data
+// as an SSTable written directly to the destination by the replica serving
+// the request; once all the ranges are done, a BackupDescriptor listing the
+// descriptors and files is written as the manifest. The backup is tracked by
+// a job, which can be paused or canceled between tables.
+// Privileges: security.RootUser user.
+func (p *planner) Backup(n *parser.Backup, autoCommit bool) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to run BACKUP", security.RootUser)
+	}
+	if !autoCommit {
+		return nil, errors.Errorf("BACKUP cannot be used inside a transaction")
+	}
+	if len(n.Options) > 0 {
+		return nil, errors.Errorf("unsupported BACKUP option %q", n.Options[0].Key)
+	}
+	return &backupNode{p: p, n: n}, nil
+}
+
+// backupTargets resolves the targets of a BACKUP statement to the descriptors
+// to back up: the databases named and all of their tables, or the tables
+// named and the databases holding them.
+func (p *planner) backupTargets(
+	targets parser.TargetList,
+) ([]*sqlbase.DatabaseDescriptor, []*sqlbase.TableDescriptor, error) {
+	var tableNames parser.TableNames
+	if targets.Databases != nil {
+		if len(targets.Databases) == 0 {
+			return nil, nil, errNoDatabase
+		}
+		for _, database := range targets.Databases {
+			dbDesc, err := p.mustGetDatabaseDesc(string(database))
+			if err != nil {
+				return nil, nil, err
+			}
+			names, err := p.getTableNames(dbDesc)
+			if err != nil {
+				return nil, nil, err
+			}
+			tableNames = append(tableNames, names...)
+		}
+	} else {
+		if len(targets.Tables) == 0 {
+			return nil, nil, errNoTable
+		}
+		for _, tableTarget := range targets.Tables {
+			tableGlob, err := tableTarget.NormalizeTablePattern()
+			if err != nil {
+				return nil, nil, err
+			}
+			names, err := p.expandTableGlob(tableGlob)
+			if err != nil {
+				return nil, nil, err
+			}
+			tableNames = append(tableNames, names...)
+		}
+	}
+
+	var dbs []*sqlbase.DatabaseDescriptor
+	var tables []*sqlbase.TableDescriptor
+	seenDBs := make(map[string]struct{})
+	seenTables := make(map[sqlbase.ID]struct{})
+	for i := range tableNames {
+		tn := &tableNames[i]
+		if _, ok := seenDBs[tn.Database()]; !ok {
+			dbDesc, err := p.mustGetDatabaseDesc(tn.Database())
+			if err != nil {
+				return nil, nil, err
+			}
+			if isVirtualDescriptor(dbDesc) {
+				return nil, nil, errors.Errorf("cannot back up virtual database %q", dbDesc.Name)
+			}
+			seenDBs[tn.Database()] = struct{}{}
+			dbs = append(dbs, dbDesc)
+		}
+		desc, err := p.mustGetTableDesc(tn)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := seenTables[desc.ID]; ok {
+			continue
+		}
+		if isVirtualDescriptor(desc) {
+			return nil, nil, errors.Errorf("cannot back up virtual table %q", desc.Name)
+		}
+		if desc.IsInterleaved() {
+			return nil, nil, errors.Errorf("BACKUP does not support interleaved table %q", desc.Name)
+		}
+		seenTables[desc.ID] = struct{}{}
+		tables = append(tables, desc)
+	}
+	// A database named explicitly is backed up even when it has no tables.
+	for _, database := range targets.Databases {
+		if _, ok := seenDBs[string(database)]; ok {
+			continue
+		}
+		dbDesc, err := p.mustGetDatabaseDesc(string(database))
+		if err != nil {
+			return nil, nil, err
+		}
+		seenDBs[string(database)] = struct{}{}
+		dbs = append(dbs, dbDesc)
+	}
+	return dbs, tables, nil
+}
+
+func (n *backupNode) expandPlan() error {
+	return nil
+}
+
+func (n *backupNode) Start() error {
+	dbs, tables, err := n.p.backupTargets(n.n.Targets)
+	if err != nil {
+		return err
+	}
+
+	ctx := n.p.ctx()
+	job, err := n.p.jobRegistry().newJob(ctx, jobTypeBackup, n.n.String(), n.p.session.User)
+	if err != nil {
+		return err
+	}
+	err = n.backup(ctx, job, dbs, tables)
+	job.Finished(ctx, err)
+	return err
+}
+
+// backup exports the data of the tables, one table at a time so that the
+// progress of the job can be reported, and writes the manifest.
+func (n *backupNode) backup(
+	ctx context.Context,
+	job *Job,
+	dbs []*sqlbase.DatabaseDescriptor,
+	tables []*sqlbase.TableDescriptor,
+) error {
+
+	desc := sqlbase.BackupDescriptor{EndTime: n.p.txn.Proto.OrigTimestamp}
+	for _, db := range dbs {
+		desc.Descriptors = append(desc.Descriptors, *sqlbase.WrapDescriptor(db))
+	}
+	for _, table := range tables {
+		desc.Descriptors = append(desc.Descriptors, *sqlbase.WrapDescriptor(table))
+	}
+
+	// Protect the data of the tables from garbage collection until it is
+	// exported, however long that takes.
+	spans := make([]roachpb.Span, len(tables))
+	for i, table := range tables {
+		prefix := roachpb.Key(keys.MakeTablePrefix(uint32(table.ID)))
+		spans[i] = roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()}
+	}
+	protectedID, err := n.p.protectTimestamp(desc.EndTime, spans, n.n.String())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := n.p.releaseTimestamp(protectedID); err != nil {
+			log.Warningf(ctx, "unable to release protected timestamp %d: %s", protectedID, err)
+		}
+	}()
+
+	for i, span := range spans {
+		// The export is not part of the statement's transaction: it only reads
+		// at the transaction's timestamp, and may span any number of ranges.
+		b := &client.Batch{}
+		b.Header.Timestamp = desc.EndTime
+		b.AddRawRequest(&roachpb.ExportRequest{
+			Span:    span,
+			Storage: n.n.To,
+		})
+		if err := n.p.execCtx.DB.Run(b); err != nil {
+			return err
+		}
+		for _, file := range b.RawResponse().Responses[0].GetInner().(*roachpb.ExportResponse).Files {
+			desc.Files = append(desc.Files, sqlbase.BackupDescriptor_File{
+				StartKey: file.Span.Key,
+				EndKey:   file.Span.EndKey,
+				Path:     file.Path,
+				DataSize: file.DataSize,
+			})
+		}
+		if err := job.Progressed(ctx, float32(i+1)/float32(len(tables))); err != nil {
+			return err
+		}
+	}
+
+	buf, err := protoutil.Marshal(&desc)
+	if err != nil {
+		return err
+	}
+	uri, err := extstorage.Join(n.n.To, BackupDescriptorName)
+	if err != nil {
+		return err
+	}
+	if err := extstorage.Write(ctx, uri, buf); err != nil {
+		return errors.Wrap(err, uri)
+	}
+
+	var dataSize int64
+	for _, file := range desc.Files {
+		dataSize += file.DataSize
+	}
+	n.result = parser.DTuple{
+		parser.NewDInt(parser.DInt(len(desc.Files))),
+		parser.NewDInt(parser.DInt(dataSize)),
+	}
+	return nil
+}
+
+func (n *backupNode) Next() (bool, error) {
+	if n.done {
+		return false, nil
+	}
+	n.done = true
+	return true, nil
+}
+
+func (n *backupNode) Values() parser.DTuple { return n.result }
+
+func (n *backupNode) Columns() []ResultColumn             { return backupColumns }
+func (n *backupNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *backupNode) DebugValues() debugValues            { return debugValues{} }
+func (n *backupNode) ExplainTypes(_ func(string, string)) {}
+func (n *backupNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *backupNode) MarkDebug(mode explainMode)          {}
+func (n *backupNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "backup", n.n.To, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/gogo/protobuf/proto"
+)
+
+func TestBackup(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.Exec(`CREATE TABLE d.t (a INT PRIMARY KEY, b STRING)`)
+	r.Exec(`INSERT INTO d.t VALUES (1, 'x'), (2, 'y'), (3, 'z')`)
+	r.Exec(`CREATE TABLE d.empty (a INT PRIMARY KEY)`)
+
+	readDescriptor := func(name string) sqlbase.BackupDescriptor {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, name, sql.BackupDescriptorName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var desc sqlbase.BackupDescriptor
+		if err := proto.Unmarshal(buf, &desc); err != nil {
+			t.Fatal(err)
+		}
+		return desc
+	}
+	descNames := func(desc sqlbase.BackupDescriptor) string {
+		var names []string
+		for _, d := range desc.Descriptors {
+			if db := d.GetDatabase(); db != nil {
+				names = append(names, "database "+db.Name)
+			} else {
+				names = append(names, "table "+d.GetTable().Name)
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, ", ")
+	}
+
+	var files, dataSize int
+	if err := sqlDB.QueryRow(fmt.Sprintf(`BACKUP DATABASE d TO 'nodelocal://%s/db'`, dir)).Scan(&files, &dataSize); err != nil {
+		t.Fatal(err)
+	}
+	desc := readDescriptor("db")
+	if expected := "database d, table empty, table t"; descNames(desc) != expected {
+		t.Errorf("expected descriptors %s, got %s", expected, descNames(desc))
+	}
+	if desc.EndTime.WallTime == 0 {
+		t.Errorf("expected a backup timestamp, got %s", desc.EndTime)
+	}
+	if files != len(desc.Files) || files == 0 {
+		t.Fatalf("expected %d files, got %d", len(desc.Files), files)
+	}
+	var totalSize int64
+	for _, f := range desc.Files {
+		info, err := os.Stat(filepath.Join(dir, "db", f.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("expected %s to be non-empty", f.Path)
+		}
+		totalSize += f.DataSize
+	}
+	if int64(dataSize) != totalSize || dataSize == 0 {
+		t.Errorf("expected a data size of %d, got %d", totalSize, dataSize)
+	}
+
+	if err := sqlDB.QueryRow(fmt.Sprintf(`BACKUP TABLE d.empty TO 'nodelocal://%s/empty'`, dir)).Scan(&files, &dataSize); err != nil {
+		t.Fatal(err)
+	}
+	if files != 0 || dataSize != 0 {
+		t.Errorf("expected no data for an empty table, got %d files of %d bytes", files, dataSize)
+	}
+	if expected := "database d, table empty"; descNames(readDescriptor("empty")) != expected {
+		t.Errorf("expected descriptors %s, got %s", expected, descNames(readDescriptor("empty")))
+	}
+
+	for _, tc := range []struct {
+		stmt, expected string
+	}{
+		{`BACKUP TABLE d.t TO 'nodelocal://%s/err' WITH foo = 'bar'`,
+			`unsupported BACKUP option "foo"`},
+		{`BACKUP TABLE d.missing TO 'nodelocal://%s/err'`,
+			`table "d.missing" does not exist`},
+		{`BACKUP DATABASE information_schema TO 'nodelocal://%s/err'`,
+			`cannot back up virtual database "information_schema"`},
+		{`BACKUP TABLE d.t TO 'foo://%s/err'`,
+			`unsupported storage scheme "foo"`},
+	} {
+		if _, err := sqlDB.Exec(fmt.Sprintf(tc.stmt, dir)); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.stmt, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/server/serverpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/pkg/errors"
+)
+
+// CancelQuery cancels a query running on any node of the cluster. The
+// canceled query's session receives a query_canceled error.
+// Privileges: None.
+//   Notes: users other than root can only cancel their own queries.
+//          postgres uses pg_cancel_backend() instead.
+func (p *planner) CancelQuery(n *parser.CancelQuery) (planNode, error) {
+	typedID, err := p.analyzeExpr(n.ID, nil, nil, parser.TypeString, true, "CANCEL QUERY")
+	if err != nil {
+		return nil, err
+	}
+	d, err := typedID.Eval(&p.evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := d.(*parser.DString)
+	if !ok {
+		return nil, errors.Errorf("CANCEL QUERY requires a query ID, got %s", d)
+	}
+	queryID := string(*id)
+	nodeID, err := nodeIDFromQueryID(queryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if nodeID == p.evalCtx.NodeID {
+		if p.execCtx.SessionRegistry == nil {
+			return nil, errors.Errorf("query ID %s not found", queryID)
+		}
+		if err := p.execCtx.SessionRegistry.CancelQuery(queryID, p.session.User); err != nil {
+			return nil, err
+		}
+		return &emptyNode{}, nil
+	}
+
+	if p.execCtx.StatusServer == nil {
+		return nil, errors.New("cannot cancel queries running on other nodes")
+	}
+	resp, err := p.execCtx.StatusServer.CancelQuery(p.ctx(), &serverpb.CancelQueryRequest{
+		NodeId:   nodeID.String(),
+		QueryID:  queryID,
+		Username: p.session.User,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Canceled {
+		return nil, errors.New(resp.Error)
+	}
+	return &emptyNode{}, nil
+}
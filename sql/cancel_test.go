@@ -0,0 +1,287 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"bytes"
+	gosql "database/sql"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/storagebase"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/pq"
+)
+
+func TestCancelQuery(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, cmdFilters := createTestServerParams()
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY);
+INSERT INTO d.t VALUES (1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "d", "t")
+	tablePrefix := keys.MakeTablePrefix(uint32(tableDesc.ID))
+
+	// Block scans of the table until the query performing them is canceled.
+	blocked := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	defer cmdFilters.AppendFilter(func(args storagebase.FilterArgs) *roachpb.Error {
+		if req, ok := args.Req.(*roachpb.ScanRequest); ok && bytes.HasPrefix(req.Key, tablePrefix) {
+			select {
+			case blocked <- struct{}{}:
+			default:
+			}
+			<-unblock
+		}
+		return nil
+	}, true)()
+
+	const query = `SELECT * FROM d.t`
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := sqlDB.Exec(query)
+		errCh <- err
+	}()
+	<-blocked
+
+	queryID := findQueryID(t, sqlDB, query)
+	if _, err := sqlDB.Exec(`CANCEL QUERY $1`, queryID); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+
+	err := <-errCh
+	if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != pgerror.CodeQueryCanceledError {
+		t.Fatalf("expected a query canceled error, got %v", err)
+	}
+
+	// The query is gone and can no longer be canceled.
+	if _, err := sqlDB.Exec(`CANCEL QUERY $1`, queryID); !testutils.IsError(err, "not found") {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+// findQueryID returns the ID of the running query whose SQL is query.
+func findQueryID(t *testing.T, sqlDB *gosql.DB, query string) string {
+	rows, err := sqlDB.Query(`SHOW QUERIES`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var queryID string
+	for rows.Next() {
+		var id, user, sql, client, phase string
+		var node int
+		var start time.Time
+		if err := rows.Scan(&id, &node, &user, &start, &sql, &client, &phase); err != nil {
+			t.Fatal(err)
+		}
+		if sql == query {
+			queryID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if queryID == "" {
+		t.Fatalf("query %q not found in SHOW QUERIES", query)
+	}
+	return queryID
+}
+
+// TestCancelQueryInTransaction verifies that canceling a statement of a
+// transaction only interrupts that statement: the transaction can go on
+// after rolling back to a savepoint, and commit.
+func TestCancelQueryInTransaction(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, cmdFilters := createTestServerParams()
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY);
+INSERT INTO d.t VALUES (1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "d", "t")
+	tablePrefix := keys.MakeTablePrefix(uint32(tableDesc.ID))
+
+	// Block scans of the table until the query performing them is canceled.
+	blocked := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	defer cmdFilters.AppendFilter(func(args storagebase.FilterArgs) *roachpb.Error {
+		if req, ok := args.Req.(*roachpb.ScanRequest); ok && bytes.HasPrefix(req.Key, tablePrefix) {
+			select {
+			case blocked <- struct{}{}:
+			default:
+			}
+			<-unblock
+		}
+		return nil
+	}, true)()
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`INSERT INTO d.t VALUES (2); SAVEPOINT s`); err != nil {
+		t.Fatal(err)
+	}
+
+	const query = `SELECT * FROM d.t`
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := tx.Exec(query)
+		errCh <- err
+	}()
+	<-blocked
+
+	queryID := findQueryID(t, sqlDB, query)
+	if _, err := sqlDB.Exec(`CANCEL QUERY $1`, queryID); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+
+	err = <-errCh
+	if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != pgerror.CodeQueryCanceledError {
+		t.Fatalf("expected a query canceled error, got %v", err)
+	}
+
+	// The statements following the canceled one run in the same transaction.
+	if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT s; INSERT INTO d.t VALUES (3)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM d.t`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 rows, got %d", count)
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, cmdFilters := createTestServerParams()
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+	// The timeout is a session variable, so all statements must use the same
+	// connection.
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY);
+INSERT INTO d.t VALUES (1);
+`); err != nil {
+		t.Fatal(err)
+	}
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "d", "t")
+	tablePrefix := keys.MakeTablePrefix(uint32(tableDesc.ID))
+
+	// Block scans of the table until the end of the test.
+	unblock := make(chan struct{})
+	defer cmdFilters.AppendFilter(func(args storagebase.FilterArgs) *roachpb.Error {
+		if req, ok := args.Req.(*roachpb.ScanRequest); ok && bytes.HasPrefix(req.Key, tablePrefix) {
+			<-unblock
+		}
+		return nil
+	}, true)()
+	defer close(unblock)
+
+	if _, err := sqlDB.Exec(`SET STATEMENT_TIMEOUT = 100`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := sqlDB.Exec(`SELECT * FROM d.t`)
+	if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != pgerror.CodeQueryCanceledError {
+		t.Fatalf("expected a query canceled error, got %v", err)
+	}
+	if !testutils.IsError(err, "statement timeout") {
+		t.Fatalf("expected a statement timeout error, got %v", err)
+	}
+
+	// The session is still usable.
+	if _, err := sqlDB.Exec(`SET STATEMENT_TIMEOUT = 0`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdleInTransactionSessionTimeout(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+	// The timeout is a session variable, so all statements must use the same
+	// connection.
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (a INT PRIMARY KEY);
+SET IDLE_IN_TRANSACTION_SESSION_TIMEOUT = 100;
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`INSERT INTO d.t VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	_, err = tx.Exec(`INSERT INTO d.t VALUES (2)`)
+	if pqErr, ok := err.(*pq.Error); !ok ||
+		pqErr.Code != pgerror.CodeIdleInTransactionSessionTimeoutError {
+		t.Fatalf("expected an idle in transaction timeout error, got %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The transaction's writes were rolled back.
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM d.t`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no rows, got %d", count)
+	}
+}
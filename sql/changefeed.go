@@ -0,0 +1,503 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/jsonb"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/protoutil"
+	"github.com/pkg/errors"
+)
+
+// The options accepted by CREATE CHANGEFEED in its WITH clause.
+const (
+	changefeedOptionResolved = "resolved"
+)
+
+// changefeedDefaultResolved is how often a changefeed emits resolved
+// timestamps unless the resolved option is given.
+const changefeedDefaultResolved = 10 * time.Second
+
+// ChangefeedPollInterval is how often the changefeeds read the changes made
+// to their tables. It is a variable for testing.
+var ChangefeedPollInterval = time.Second
+
+var changefeedColumns = []ResultColumn{
+	{Name: "job_id", Typ: parser.TypeInt},
+}
+
+type createChangefeedNode struct {
+	p        *planner
+	n        *parser.CreateChangefeed
+	resolved time.Duration
+
+	result parser.DTuple
+	done   bool
+}
+
+// CreateChangefeed starts a changefeed, which emits a message to the sink
+// named by the statement for every change made to a set of tables, and
+// returns the ID of the job tracking it.
+//
+// The changefeed first emits the rows of the tables as of the timestamp of
+// the statement's transaction, and then polls the tables for the changes
+// made since the last poll, which the ranges export as SSTables holding every
+// revision written in the interval. The changes of a poll are emitted in
+// timestamp order and the sink is flushed before the next poll starts; every
+// so often, a resolved timestamp is emitted to tell the consumers that no
+// change at or before it remains to be emitted. The changefeed runs until its
+// job is canceled, one of its tables undergoes a schema change, or the node
+// stops.
+//
+// The key of the message for a row is the JSON array of its primary key
+// values; the value is a JSON object holding the row ("after", null for a
+// deletion) and the timestamp of the change ("updated").
+// Privileges: security.RootUser user.
+func (p *planner) CreateChangefeed(n *parser.CreateChangefeed, autoCommit bool) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to run CREATE CHANGEFEED", security.RootUser)
+	}
+	if !autoCommit {
+		return nil, errors.Errorf("CREATE CHANGEFEED cannot be used inside a transaction")
+	}
+	node := &createChangefeedNode{p: p, n: n, resolved: changefeedDefaultResolved}
+	for _, opt := range n.Options {
+		switch opt.Key {
+		case changefeedOptionResolved:
+			resolved, err := time.ParseDuration(opt.Value)
+			if err != nil || resolved <= 0 {
+				return nil, errors.Errorf("CREATE CHANGEFEED option %s must be a positive duration", opt.Key)
+			}
+			node.resolved = resolved
+		default:
+			return nil, errors.Errorf("unsupported CREATE CHANGEFEED option %q", opt.Key)
+		}
+	}
+	return node, nil
+}
+
+// changefeedTargets resolves the targets of a CREATE CHANGEFEED statement to
+// the descriptors of the tables watched, and checks that the changefeed can
+// decode their changes.
+func (p *planner) changefeedTargets(
+	targets parser.TargetList,
+) ([]*sqlbase.TableDescriptor, error) {
+	if len(targets.Tables) == 0 {
+		return nil, errNoTable
+	}
+	var tables []*sqlbase.TableDescriptor
+	seen := make(map[sqlbase.ID]struct{})
+	for _, tableTarget := range targets.Tables {
+		tableGlob, err := tableTarget.NormalizeTablePattern()
+		if err != nil {
+			return nil, err
+		}
+		tableNames, err := p.expandTableGlob(tableGlob)
+		if err != nil {
+			return nil, err
+		}
+		for i := range tableNames {
+			desc, err := p.mustGetTableDesc(&tableNames[i])
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := seen[desc.ID]; ok {
+				continue
+			}
+			if isVirtualDescriptor(desc) {
+				return nil, errors.Errorf("cannot create a changefeed for virtual table %q", desc.Name)
+			}
+			if desc.IsInterleaved() {
+				return nil, errors.Errorf("CREATE CHANGEFEED does not support interleaved table %q", desc.Name)
+			}
+			if len(desc.Families) != 1 {
+				return nil, errors.Errorf(
+					"CREATE CHANGEFEED does not support table %q, which has more than one column family", desc.Name)
+			}
+			if len(desc.Mutations) > 0 {
+				return nil, errors.Errorf(
+					"cannot create a changefeed for table %q while it undergoes a schema change", desc.Name)
+			}
+			seen[desc.ID] = struct{}{}
+			tables = append(tables, desc)
+		}
+	}
+	return tables, nil
+}
+
+func (n *createChangefeedNode) expandPlan() error {
+	return nil
+}
+
+func (n *createChangefeedNode) Start() error {
+	tables, err := n.p.changefeedTargets(n.n.Targets)
+	if err != nil {
+		return err
+	}
+	if n.p.jobRegistry() == nil || n.p.execCtx.Stopper == nil {
+		return errors.Errorf("CREATE CHANGEFEED is not supported by this server")
+	}
+
+	cf, err := makeChangefeed(n.p.execCtx, tables, n.resolved, n.p.txn.Proto.OrigTimestamp)
+	if err != nil {
+		return err
+	}
+	var tableNames []string
+	for _, table := range tables {
+		tableNames = append(tableNames, table.Name)
+	}
+	cf.sink, err = makeChangefeedSink(n.n.SinkURI, tableNames, n.p.execCtx.TestingKnobs)
+	if err != nil {
+		return err
+	}
+
+	ctx := n.p.ctx()
+	cf.job, err = n.p.jobRegistry().newJob(ctx, jobTypeChangefeed, n.n.String(), n.p.session.User)
+	if err != nil {
+		_ = cf.sink.Close()
+		return err
+	}
+
+	// The changefeed outlives the statement: it runs until it fails or is
+	// canceled, or until the node stops.
+	stopper := n.p.execCtx.Stopper
+	if err := stopper.RunAsyncTask(func() {
+		ctx := stopper.WithCancel(context.Background())
+		err := cf.run(ctx)
+		if log.V(2) {
+			log.Infof(ctx, "changefeed job %d stopped: %v", cf.job.id, err)
+		}
+		cf.job.Finished(ctx, err)
+		if err := cf.sink.Close(); err != nil {
+			log.Warningf(ctx, "unable to close the sink of changefeed job %d: %s", cf.job.id, err)
+		}
+	}); err != nil {
+		cf.job.Finished(ctx, err)
+		_ = cf.sink.Close()
+		return err
+	}
+
+	n.result = parser.DTuple{parser.NewDInt(parser.DInt(cf.job.id))}
+	return nil
+}
+
+// changefeed emits the changes made to a set of tables to a sink.
+type changefeed struct {
+	db       *client.DB
+	clock    *hlc.Clock
+	tables   []*changefeedTable
+	resolved time.Duration
+	sink     ChangefeedSink
+	job      *Job
+
+	// highwater is the timestamp up to which all the changes were emitted.
+	highwater hlc.Timestamp
+	// lastResolved is when the last resolved timestamp was emitted.
+	lastResolved time.Time
+}
+
+// changefeedTable decodes the changes made to a table of a changefeed.
+type changefeedTable struct {
+	desc *sqlbase.TableDescriptor
+
+	keyTypes []parser.Datum
+	keyVals  []parser.Datum
+	keyDirs  []encoding.Direction
+	fetcher  sqlbase.RowFetcher
+	alloc    sqlbase.DatumAlloc
+}
+
+// changefeedChange is a revision of a row, read from an exported SSTable.
+type changefeedChange struct {
+	table *changefeedTable
+	kv    client.KeyValue
+}
+
+func makeChangefeed(
+	execCtx *ExecutorContext,
+	descs []*sqlbase.TableDescriptor,
+	resolved time.Duration,
+	highwater hlc.Timestamp,
+) (*changefeed, error) {
+	cf := &changefeed{
+		db:        execCtx.DB,
+		clock:     execCtx.Clock,
+		resolved:  resolved,
+		highwater: highwater,
+	}
+	for _, desc := range descs {
+		t := &changefeedTable{desc: desc}
+		index := &desc.PrimaryIndex
+		var err error
+		if t.keyTypes, err = sqlbase.MakeKeyVals(desc, index.ColumnIDs); err != nil {
+			return nil, err
+		}
+		t.keyVals = make([]parser.Datum, len(t.keyTypes))
+		t.keyDirs = make([]encoding.Direction, len(index.ColumnDirections))
+		for i, dir := range index.ColumnDirections {
+			if t.keyDirs[i], err = dir.ToEncodingDirection(); err != nil {
+				return nil, err
+			}
+		}
+
+		colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+		valNeededForCol := make([]bool, len(desc.Columns))
+		for i, col := range desc.Columns {
+			colIdxMap[col.ID] = i
+			valNeededForCol[i] = true
+		}
+		if err := t.fetcher.Init(
+			desc, colIdxMap, index, false /* reverse */, false /* isSecondaryIndex */, desc.Columns,
+			valNeededForCol,
+		); err != nil {
+			return nil, err
+		}
+		cf.tables = append(cf.tables, t)
+	}
+	return cf, nil
+}
+
+// run emits the rows of the tables as of the highwater, then the changes
+// made after it, until ctx is canceled or an error occurs.
+func (cf *changefeed) run(ctx context.Context) error {
+	if err := cf.poll(ctx, hlc.ZeroTimestamp, cf.highwater); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-time.After(ChangefeedPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := cf.poll(ctx, cf.highwater, cf.clock.Now()); err != nil {
+			return err
+		}
+	}
+}
+
+// poll emits the changes made to the tables in (start, end], or their rows
+// as of end if start is zero, and moves the highwater to end.
+func (cf *changefeed) poll(ctx context.Context, start, end hlc.Timestamp) error {
+	if err := cf.checkDescriptors(end); err != nil {
+		return err
+	}
+
+	var changes []changefeedChange
+	for _, t := range cf.tables {
+		// The export reads at end, which keeps later writes from happening at
+		// or before it: the next poll can start from there.
+		b := &client.Batch{}
+		b.Header.Timestamp = end
+		prefix := roachpb.Key(keys.MakeTablePrefix(uint32(t.desc.ID)))
+		b.AddRawRequest(&roachpb.ExportRequest{
+			Span:      roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()},
+			StartTime: start,
+			ReturnSST: true,
+		})
+		if err := cf.db.Run(b); err != nil {
+			return err
+		}
+		for _, file := range b.RawResponse().Responses[0].GetInner().(*roachpb.ExportResponse).Files {
+			if err := readChangefeedChanges(file.SST, func(kv engine.MVCCKeyValue) {
+				changes = append(changes, changefeedChange{
+					table: t,
+					kv: client.KeyValue{
+						Key: kv.Key.Key,
+						Value: &roachpb.Value{
+							RawBytes:  kv.Value,
+							Timestamp: kv.Key.Timestamp,
+						},
+					},
+				})
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The revisions of each table are sorted by key, newest first; the changes
+	// to the same row must be emitted oldest first.
+	sort.Stable(changefeedChangesByTimestamp(changes))
+	for _, c := range changes {
+		key, value, err := c.table.encode(c.kv)
+		if err != nil {
+			return err
+		}
+		if err := cf.sink.EmitRow(ctx, c.table.desc.Name, key, value); err != nil {
+			return err
+		}
+	}
+	if err := cf.sink.Flush(ctx); err != nil {
+		return err
+	}
+
+	cf.highwater = end
+	checkpoint, err := protoutil.Marshal(&cf.highwater)
+	if err != nil {
+		return err
+	}
+	if err := cf.job.Checkpointed(ctx, 0, checkpoint); err != nil {
+		return err
+	}
+
+	if now := cf.clock.PhysicalTime(); now.Sub(cf.lastResolved) >= cf.resolved {
+		value := jsonb.String(map[string]interface{}{"resolved": changefeedTimestamp(cf.highwater)})
+		if err := cf.sink.EmitResolved(ctx, []byte(value)); err != nil {
+			return err
+		}
+		cf.lastResolved = now
+	}
+	return nil
+}
+
+// checkDescriptors returns an error if one of the tables was changed since
+// the changefeed started: the changes made after a schema change would not be
+// decoded correctly.
+func (cf *changefeed) checkDescriptors(ts hlc.Timestamp) error {
+	return cf.db.Txn(func(txn *client.Txn) error {
+		setTxnTimestamps(txn, ts)
+		for _, t := range cf.tables {
+			desc, err := sqlbase.GetTableDescFromID(txn, t.desc.ID)
+			if err != nil {
+				return err
+			}
+			if desc.Version != t.desc.Version {
+				return errors.Errorf(
+					"table %q was changed, which changefeeds do not support", t.desc.Name)
+			}
+		}
+		return nil
+	})
+}
+
+// readChangefeedChanges calls f with every revision in an exported SSTable.
+func readChangefeedChanges(sst []byte, f func(engine.MVCCKeyValue)) error {
+	fr, err := engine.MakeRocksDBSstFileReader()
+	if err != nil {
+		return err
+	}
+	defer fr.Close()
+	if err := fr.IngestExternalFile(sst); err != nil {
+		return err
+	}
+	start, end := engine.MakeMVCCMetadataKey(keys.MinKey), engine.MakeMVCCMetadataKey(keys.MaxKey)
+	return fr.Iterate(start, end,
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			f(engine.MVCCKeyValue{
+				Key: engine.MVCCKey{
+					Key:       append(roachpb.Key(nil), kv.Key.Key...),
+					Timestamp: kv.Key.Timestamp,
+				},
+				Value: append([]byte(nil), kv.Value...),
+			})
+			return false, nil
+		})
+}
+
+// encode returns the key and value of the message for a change to a row of
+// the table.
+func (t *changefeedTable) encode(kv client.KeyValue) ([]byte, []byte, error) {
+	if _, ok, err := sqlbase.DecodeIndexKey(
+		&t.alloc, t.desc, t.desc.PrimaryIndex.ID, t.keyTypes, t.keyVals, t.keyDirs, kv.Key,
+	); err != nil {
+		return nil, nil, err
+	} else if !ok {
+		return nil, nil, errors.Errorf("unexpected key %s in table %q", kv.Key, t.desc.Name)
+	}
+	key := make([]interface{}, len(t.keyVals))
+	for i, d := range t.keyVals {
+		var err error
+		if key[i], err = parser.DatumToJSON(d); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// A deletion has no value.
+	var after interface{}
+	if len(kv.Value.RawBytes) > 0 {
+		if err := t.fetcher.StartScanFrom([]client.KeyValue{kv}); err != nil {
+			return nil, nil, err
+		}
+		row, err := t.fetcher.NextRow()
+		if err != nil {
+			return nil, nil, err
+		}
+		cols := make(map[string]interface{}, len(row))
+		for i, d := range row {
+			if cols[t.desc.Columns[i].Name], err = parser.DatumToJSON(d); err != nil {
+				return nil, nil, err
+			}
+		}
+		after = cols
+	}
+
+	value := map[string]interface{}{
+		"after":   after,
+		"updated": changefeedTimestamp(kv.Value.Timestamp),
+	}
+	return []byte(jsonb.String(key)), []byte(jsonb.String(value)), nil
+}
+
+// changefeedTimestamp formats a timestamp for the messages of a changefeed,
+// as a decimal whose integer part is the wall time in nanoseconds and whose
+// fractional part is the logical time: like cluster_logical_timestamp().
+func changefeedTimestamp(ts hlc.Timestamp) string {
+	return fmt.Sprintf("%d.%010d", ts.WallTime, ts.Logical)
+}
+
+// changefeedChangesByTimestamp sorts changes by timestamp, keeping the order
+// of the changes made at the same timestamp.
+type changefeedChangesByTimestamp []changefeedChange
+
+func (c changefeedChangesByTimestamp) Len() int      { return len(c) }
+func (c changefeedChangesByTimestamp) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c changefeedChangesByTimestamp) Less(i, j int) bool {
+	return c[i].kv.Value.Timestamp.Less(c[j].kv.Value.Timestamp)
+}
+
+func (n *createChangefeedNode) Next() (bool, error) {
+	if n.done {
+		return false, nil
+	}
+	n.done = true
+	return true, nil
+}
+
+func (n *createChangefeedNode) Values() parser.DTuple { return n.result }
+
+func (n *createChangefeedNode) Columns() []ResultColumn             { return changefeedColumns }
+func (n *createChangefeedNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *createChangefeedNode) DebugValues() debugValues            { return debugValues{} }
+func (n *createChangefeedNode) ExplainTypes(_ func(string, string)) {}
+func (n *createChangefeedNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *createChangefeedNode) MarkDebug(mode explainMode)          {}
+func (n *createChangefeedNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "create changefeed", n.n.SinkURI, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"net/url"
+
+	"golang.org/x/net/context"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+)
+
+// changefeedSinkParamTopicPrefix is the parameter of a sink URI prepended to
+// the names of the tables to make the names of their topics.
+const changefeedSinkParamTopicPrefix = "topic_prefix"
+
+// ChangefeedSink is the destination of the messages emitted by a changefeed.
+// Every table of the changefeed has its own topic, named after the table.
+type ChangefeedSink interface {
+	// EmitRow emits the message for a change to a row of a table, in the
+	// topic of the table. The messages with the same key must be delivered in
+	// the order they are emitted.
+	EmitRow(ctx context.Context, table string, key, value []byte) error
+	// EmitResolved emits a resolved timestamp message to all the topics,
+	// after all the messages emitted before it. The message must reach every
+	// consumer of the topics, e.g. every partition of a Kafka topic.
+	EmitResolved(ctx context.Context, value []byte) error
+	// Flush returns once all the messages emitted so far are delivered.
+	Flush(ctx context.Context) error
+	// Close releases the resources of the sink.
+	Close() error
+}
+
+// makeChangefeedSink returns the sink named by sinkURI, for a changefeed of
+// the given tables.
+func makeChangefeedSink(
+	sinkURI string, tables []string, knobs *ExecutorTestingKnobs,
+) (ChangefeedSink, error) {
+	if knobs != nil && knobs.ChangefeedSink != nil {
+		return knobs.ChangefeedSink(sinkURI, tables)
+	}
+	u, err := url.Parse(sinkURI)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "kafka":
+		return makeKafkaSink(u.Host, u.Query().Get(changefeedSinkParamTopicPrefix), tables)
+	default:
+		return nil, errors.Errorf("unsupported changefeed sink: %s", sinkURI)
+	}
+}
+
+// kafkaSink emits the messages of a changefeed to a Kafka cluster. The
+// messages for rows are sent to the partition of their key, so that all the
+// changes to a row are ordered; resolved timestamps are sent to every
+// partition.
+type kafkaSink struct {
+	client   sarama.Client
+	producer sarama.SyncProducer
+	prefix   string
+	topics   []string
+
+	// The messages emitted since the last flush.
+	msgs []*sarama.ProducerMessage
+}
+
+func makeKafkaSink(addr, prefix string, tables []string) (*kafkaSink, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = newChangefeedPartitioner
+
+	client, err := sarama.NewClient([]string{addr}, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to kafka: %s", addr)
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, errors.Wrapf(err, "connecting to kafka: %s", addr)
+	}
+	s := &kafkaSink{client: client, producer: producer, prefix: prefix}
+	for _, table := range tables {
+		s.topics = append(s.topics, prefix+table)
+	}
+	return s, nil
+}
+
+// EmitRow implements the ChangefeedSink interface.
+func (s *kafkaSink) EmitRow(_ context.Context, table string, key, value []byte) error {
+	s.msgs = append(s.msgs, &sarama.ProducerMessage{
+		Topic: s.prefix + table,
+		Key:   sarama.ByteEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	})
+	return nil
+}
+
+// EmitResolved implements the ChangefeedSink interface.
+func (s *kafkaSink) EmitResolved(ctx context.Context, value []byte) error {
+	for _, topic := range s.topics {
+		partitions, err := s.client.Partitions(topic)
+		if err != nil {
+			return errors.Wrapf(err, "listing the partitions of %s", topic)
+		}
+		for _, partition := range partitions {
+			s.msgs = append(s.msgs, &sarama.ProducerMessage{
+				Topic:     topic,
+				Partition: partition,
+				Value:     sarama.ByteEncoder(value),
+			})
+		}
+	}
+	return s.Flush(ctx)
+}
+
+// Flush implements the ChangefeedSink interface.
+func (s *kafkaSink) Flush(_ context.Context) error {
+	if len(s.msgs) == 0 {
+		return nil
+	}
+	err := s.producer.SendMessages(s.msgs)
+	s.msgs = s.msgs[:0]
+	return err
+}
+
+// Close implements the ChangefeedSink interface.
+func (s *kafkaSink) Close() error {
+	if err := s.producer.Close(); err != nil {
+		_ = s.client.Close()
+		return err
+	}
+	return s.client.Close()
+}
+
+// changefeedPartitioner sends the messages with a key to the partition of the
+// hash of the key, and the others (the resolved timestamps) to the partition
+// they name.
+type changefeedPartitioner struct {
+	hash sarama.Partitioner
+}
+
+var _ sarama.Partitioner = &changefeedPartitioner{}
+
+func newChangefeedPartitioner(topic string) sarama.Partitioner {
+	return &changefeedPartitioner{hash: sarama.NewHashPartitioner(topic)}
+}
+
+// Partition implements the sarama.Partitioner interface.
+func (p *changefeedPartitioner) Partition(
+	msg *sarama.ProducerMessage, numPartitions int32,
+) (int32, error) {
+	if msg.Key == nil {
+		return msg.Partition, nil
+	}
+	return p.hash.Partition(msg, numPartitions)
+}
+
+// RequiresConsistency implements the sarama.Partitioner interface.
+func (p *changefeedPartitioner) RequiresConsistency() bool {
+	return true
+}
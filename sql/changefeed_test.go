@@ -0,0 +1,230 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/base"
+	csql "github.com/cockroachdb/cockroach/sql"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/pkg/errors"
+)
+
+// testChangefeedSink records the messages emitted by a changefeed for the
+// rows as "<table> <key> <after>", and counts the resolved timestamps.
+type testChangefeedSink struct {
+	mu struct {
+		syncutil.Mutex
+		msgs     []string
+		resolved int
+		closed   bool
+	}
+}
+
+var _ csql.ChangefeedSink = &testChangefeedSink{}
+
+func (s *testChangefeedSink) EmitRow(_ context.Context, table string, key, value []byte) error {
+	var v struct {
+		After   json.RawMessage `json:"after"`
+		Updated string          `json:"updated"`
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	if v.Updated == "" {
+		return errors.Errorf("no timestamp in %s", value)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.msgs = append(s.mu.msgs, fmt.Sprintf("%s %s %s", table, key, v.After))
+	return nil
+}
+
+func (s *testChangefeedSink) EmitResolved(_ context.Context, value []byte) error {
+	var v struct {
+		Resolved string `json:"resolved"`
+	}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	if v.Resolved == "" {
+		return errors.Errorf("no timestamp in %s", value)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.resolved++
+	return nil
+}
+
+func (s *testChangefeedSink) Flush(_ context.Context) error { return nil }
+
+func (s *testChangefeedSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.closed = true
+	return nil
+}
+
+// next returns the row messages emitted since the last call.
+func (s *testChangefeedSink) next() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := s.mu.msgs
+	s.mu.msgs = nil
+	return msgs
+}
+
+func TestChangefeed(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	defer func(interval time.Duration) { csql.ChangefeedPollInterval = interval }(csql.ChangefeedPollInterval)
+	csql.ChangefeedPollInterval = 10 * time.Millisecond
+
+	var mu syncutil.Mutex
+	var sinks []*testChangefeedSink
+	lastSink := func() *testChangefeedSink {
+		mu.Lock()
+		defer mu.Unlock()
+		return sinks[len(sinks)-1]
+	}
+	params, _ := createTestServerParams()
+	params.Knobs = base.TestingKnobs{
+		SQLExecutor: &csql.ExecutorTestingKnobs{
+			ChangefeedSink: func(sinkURI string, tables []string) (csql.ChangefeedSink, error) {
+				if sinkURI != "test://" {
+					return nil, errors.Errorf("unsupported changefeed sink: %s", sinkURI)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				s := &testChangefeedSink{}
+				sinks = append(sinks, s)
+				return s, nil
+			},
+		},
+	}
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.Exec(`CREATE TABLE d.t (a INT PRIMARY KEY, b STRING)`)
+	r.Exec(`INSERT INTO d.t VALUES (1, 'x'), (2, 'y')`)
+
+	expectMsgs := func(sink *testChangefeedSink, expected ...string) {
+		var msgs []string
+		util.SucceedsSoon(t, func() error {
+			msgs = append(msgs, sink.next()...)
+			if len(msgs) < len(expected) {
+				return errors.Errorf("expected %d messages, got %d", len(expected), len(msgs))
+			}
+			return nil
+		})
+		if !reflect.DeepEqual(expected, msgs) {
+			t.Errorf("expected messages\n%q\ngot\n%q", expected, msgs)
+		}
+	}
+	jobStatus := func(id int64) string {
+		var status string
+		r.QueryRow(`SELECT status FROM system.jobs WHERE id = $1`, id).Scan(&status)
+		return status
+	}
+
+	var jobID int64
+	r.QueryRow(`CREATE CHANGEFEED FOR TABLE d.t INTO 'test://' WITH resolved = '1ms'`).Scan(&jobID)
+	sink := lastSink()
+
+	// The rows of the table are emitted first, then the changes made to them.
+	expectMsgs(sink, `t [1] {"a": 1, "b": "x"}`, `t [2] {"a": 2, "b": "y"}`)
+	r.Exec(`UPDATE d.t SET b = 'z' WHERE a = 1`)
+	r.Exec(`DELETE FROM d.t WHERE a = 2`)
+	r.Exec(`INSERT INTO d.t VALUES (3, NULL)`)
+	expectMsgs(sink, `t [1] {"a": 1, "b": "z"}`, `t [2] null`, `t [3] {"a": 3, "b": null}`)
+	util.SucceedsSoon(t, func() error {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		if sink.mu.resolved == 0 {
+			return errors.New("no resolved timestamp emitted")
+		}
+		return nil
+	})
+	if status := jobStatus(jobID); status != "running" {
+		t.Fatalf("expected job %d to be running, got %s", jobID, status)
+	}
+
+	// Canceling the job stops the changefeed.
+	r.Exec(`CANCEL JOB $1`, jobID)
+	util.SucceedsSoon(t, func() error {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		if !sink.mu.closed {
+			return errors.New("the changefeed is still running")
+		}
+		return nil
+	})
+	if status := jobStatus(jobID); status != "canceled" {
+		t.Fatalf("expected job %d to be canceled, got %s", jobID, status)
+	}
+
+	// Schema changes make the changefeed fail.
+	r.QueryRow(`CREATE CHANGEFEED FOR d.t INTO 'test://'`).Scan(&jobID)
+	expectMsgs(lastSink(), `t [1] {"a": 1, "b": "z"}`, `t [3] {"a": 3, "b": null}`)
+	r.Exec(`ALTER TABLE d.t ADD COLUMN c INT`)
+	util.SucceedsSoon(t, func() error {
+		if status := jobStatus(jobID); status != "failed" {
+			return errors.Errorf("expected job %d to fail, got %s", jobID, status)
+		}
+		return nil
+	})
+
+	r.Exec(`CREATE TABLE d.families (a INT PRIMARY KEY, b INT, FAMILY (a), FAMILY (b))`)
+	for i, tc := range []struct {
+		stmt     string
+		expected string
+	}{
+		{`CREATE CHANGEFEED FOR d.t INTO 'kafka://localhost:9092'`, "unsupported changefeed sink"},
+		{`CREATE CHANGEFEED FOR d.t INTO 'test://' WITH foo = 'bar'`, `unsupported CREATE CHANGEFEED option "foo"`},
+		{`CREATE CHANGEFEED FOR d.t INTO 'test://' WITH resolved = 'x'`, "must be a positive duration"},
+		{`CREATE CHANGEFEED FOR d.families INTO 'test://'`, "more than one column family"},
+		{`CREATE CHANGEFEED FOR d.missing INTO 'test://'`, `table "d.missing" does not exist`},
+	} {
+		if _, err := sqlDB.Exec(tc.stmt); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.expected, err)
+		}
+	}
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`CREATE CHANGEFEED FOR d.t INTO 'test://'`); !testutils.IsError(
+		err, "cannot be used inside a transaction",
+	) {
+		t.Errorf("expected an error inside a transaction, got %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package colexec implements a vectorized execution engine: its operators
+// process batches of rows stored column by column in typed slices, rather
+// than one row of interface values at a time.
+package colexec
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"gopkg.in/inf.v0"
+)
+
+// BatchSize is the maximum number of rows of a batch.
+const BatchSize = 1024
+
+// ColType is the physical type of the values of a column.
+type ColType int
+
+const (
+	// ColUnhandled is the type of the columns whose values the engine
+	// can't represent. Such columns are left empty.
+	ColUnhandled ColType = iota
+	// ColInt holds INT values in Vec.Ints.
+	ColInt
+	// ColFloat holds FLOAT values in Vec.Floats.
+	ColFloat
+	// ColDecimal holds DECIMAL values in Vec.Decimals.
+	ColDecimal
+	// ColString holds STRING values in Vec.Strings.
+	ColString
+	// ColBytes holds BYTES values in Vec.Strings.
+	ColBytes
+	// ColBool holds BOOL values in Vec.Bools.
+	ColBool
+)
+
+var colTypeNames = [...]string{
+	ColUnhandled: "unhandled",
+	ColInt:       "int",
+	ColFloat:     "float",
+	ColDecimal:   "decimal",
+	ColString:    "string",
+	ColBytes:     "bytes",
+	ColBool:      "bool",
+}
+
+func (t ColType) String() string {
+	return colTypeNames[t]
+}
+
+// ColTypeFromDatum returns the type of the columns holding values of the
+// same type as the given datum.
+func ColTypeFromDatum(typ parser.Datum) ColType {
+	switch typ.(type) {
+	case *parser.DInt:
+		return ColInt
+	case *parser.DFloat:
+		return ColFloat
+	case *parser.DDecimal:
+		return ColDecimal
+	case *parser.DString:
+		return ColString
+	case *parser.DBytes:
+		return ColBytes
+	case *parser.DBool:
+		return ColBool
+	default:
+		return ColUnhandled
+	}
+}
+
+// Vec is a column of a batch. Only the slice matching its type is used.
+type Vec struct {
+	Type     ColType
+	Ints     []int64
+	Floats   []float64
+	Decimals []inf.Dec
+	Strings  []string
+	Bools    []bool
+	// Nulls indicates which values are NULL.
+	Nulls []bool
+}
+
+// reset prepares the vector to hold n values of the given type, reusing its
+// memory if possible. The values are all NULL.
+func (v *Vec) reset(typ ColType, n int) {
+	v.Type = typ
+	switch typ {
+	case ColInt:
+		v.Ints = resizeInts(v.Ints, n)
+	case ColFloat:
+		v.Floats = resizeFloats(v.Floats, n)
+	case ColDecimal:
+		if cap(v.Decimals) < n {
+			v.Decimals = make([]inf.Dec, n)
+		}
+		v.Decimals = v.Decimals[:n]
+	case ColString, ColBytes:
+		if cap(v.Strings) < n {
+			v.Strings = make([]string, n)
+		}
+		v.Strings = v.Strings[:n]
+	case ColBool:
+		v.Bools = resizeBools(v.Bools, n)
+	}
+	v.Nulls = resizeBools(v.Nulls, n)
+	for i := range v.Nulls {
+		v.Nulls[i] = true
+	}
+}
+
+func resizeInts(s []int64, n int) []int64 {
+	if cap(s) < n {
+		return make([]int64, n)
+	}
+	return s[:n]
+}
+
+func resizeFloats(s []float64, n int) []float64 {
+	if cap(s) < n {
+		return make([]float64, n)
+	}
+	return s[:n]
+}
+
+func resizeBools(s []bool, n int) []bool {
+	if cap(s) < n {
+		return make([]bool, n)
+	}
+	return s[:n]
+}
+
+// SetDatum sets the i-th value of the vector.
+func (v *Vec) SetDatum(i int, d parser.Datum) error {
+	if d == parser.DNull {
+		v.Nulls[i] = true
+		return nil
+	}
+	v.Nulls[i] = false
+	switch t := d.(type) {
+	case *parser.DInt:
+		v.Ints[i] = int64(*t)
+	case *parser.DFloat:
+		v.Floats[i] = float64(*t)
+	case *parser.DDecimal:
+		v.Decimals[i].Set(&t.Dec)
+	case *parser.DString:
+		v.Strings[i] = string(*t)
+	case *parser.DBytes:
+		v.Strings[i] = string(*t)
+	case *parser.DBool:
+		v.Bools[i] = bool(*t)
+	default:
+		return fmt.Errorf("unsupported value %s for a column of type %s", d, v.Type)
+	}
+	return nil
+}
+
+// Datum returns the i-th value of the vector.
+func (v *Vec) Datum(i int) parser.Datum {
+	if v.Nulls[i] {
+		return parser.DNull
+	}
+	switch v.Type {
+	case ColInt:
+		return parser.NewDInt(parser.DInt(v.Ints[i]))
+	case ColFloat:
+		return parser.NewDFloat(parser.DFloat(v.Floats[i]))
+	case ColDecimal:
+		d := &parser.DDecimal{}
+		d.Set(&v.Decimals[i])
+		return d
+	case ColString:
+		return parser.NewDString(v.Strings[i])
+	case ColBytes:
+		return parser.NewDBytes(parser.DBytes(v.Strings[i]))
+	case ColBool:
+		return parser.MakeDBool(parser.DBool(v.Bools[i]))
+	default:
+		panic(fmt.Sprintf("no values in a column of type %s", v.Type))
+	}
+}
+
+// Batch is a set of rows stored column by column.
+type Batch struct {
+	// Length is the number of rows stored in the columns.
+	Length int
+	// Sel, if set, holds the indexes of the rows which are part of the
+	// batch, in increasing order. The others have been filtered out.
+	Sel  []int
+	Cols []Vec
+}
+
+// NumRows returns the number of rows which are part of the batch.
+func (b *Batch) NumRows() int {
+	if b.Sel != nil {
+		return len(b.Sel)
+	}
+	return b.Length
+}
+
+// Row returns the index in the columns of the i-th row of the batch.
+func (b *Batch) Row(i int) int {
+	if b.Sel != nil {
+		return b.Sel[i]
+	}
+	return i
+}
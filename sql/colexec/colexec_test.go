@@ -0,0 +1,374 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package colexec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// testRows returns n rows of an INT, a FLOAT and a STRING column, in which
+// some values are NULL.
+func testRows(n int) []parser.DTuple {
+	rows := make([]parser.DTuple, n)
+	for i := range rows {
+		rows[i] = parser.DTuple{
+			parser.NewDInt(parser.DInt(i)),
+			parser.NewDFloat(parser.DFloat(float64(i) / 2)),
+			parser.NewDString(fmt.Sprintf("s%d", i%3)),
+		}
+		if i%7 < len(rows[i]) {
+			rows[i][i%7] = parser.DNull
+		}
+	}
+	return rows
+}
+
+var testTypes = []parser.Datum{parser.TypeInt, parser.TypeFloat, parser.TypeString}
+
+func testColTypes() []ColType {
+	types := make([]ColType, len(testTypes))
+	for i, t := range testTypes {
+		types[i] = ColTypeFromDatum(t)
+	}
+	return types
+}
+
+// sliceSource returns a RowSource returning the given rows.
+func sliceSource(rows []parser.DTuple) RowSource {
+	return func() (parser.DTuple, error) {
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		row := rows[0]
+		rows = rows[1:]
+		return row, nil
+	}
+}
+
+// materialize returns the formatted rows of the operator.
+func materialize(t *testing.T, op Operator, numCols int) []string {
+	m := NewMaterializer(op, numCols)
+	var res []string
+	for {
+		row, err := m.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row == nil {
+			return res
+		}
+		res = append(res, row.String())
+	}
+}
+
+// testVars is an IndexedVarContainer evaluating the variables using the
+// values of the current row.
+type testVars struct {
+	row parser.DTuple
+}
+
+func (v *testVars) IndexedVarEval(idx int, ctx *parser.EvalContext) (parser.Datum, error) {
+	return v.row[idx], nil
+}
+
+func (v *testVars) IndexedVarReturnType(idx int) parser.Datum {
+	return testTypes[idx]
+}
+
+func (v *testVars) IndexedVarString(idx int) string {
+	return fmt.Sprintf("$%d", idx)
+}
+
+// placeholdersToVars converts the placeholders ($0, $1, etc.) to IndexedVars.
+type placeholdersToVars struct {
+	h   *parser.IndexedVarHelper
+	err error
+}
+
+func (v *placeholdersToVars) VisitPre(expr parser.Expr) (recurse bool, newExpr parser.Expr) {
+	if val, ok := expr.(parser.Placeholder); ok {
+		idx, err := strconv.Atoi(val.Name)
+		if err != nil || idx < 0 || idx >= v.h.NumVars() {
+			v.err = errors.New("invalid variable index " + val.Name)
+			return false, expr
+		}
+		return false, v.h.IndexedVar(idx)
+	}
+	return true, expr
+}
+
+func (*placeholdersToVars) VisitPost(expr parser.Expr) parser.Expr { return expr }
+
+func parseTestExpr(t *testing.T, h *parser.IndexedVarHelper, sql string) parser.TypedExpr {
+	expr, err := parser.ParseExprTraditional(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := placeholdersToVars{h: h}
+	expr, _ = parser.WalkExpr(&v, expr)
+	if v.err != nil {
+		t.Fatal(v.err)
+	}
+	typedExpr, err := parser.TypeCheck(expr, nil, parser.NoTypePreference)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return typedExpr
+}
+
+// TestFilterProject checks that filters and projections return the same
+// results as evaluating their expressions one row at a time.
+func TestFilterProject(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		filter string
+		exprs  []string
+	}{
+		{``, []string{`$0`, `$1`, `$2`}},
+		{`$0 > 5 AND $2 = 's1'`, []string{`$0`, `$1 * 2.0`, `$0 + 1`}},
+		{`$0 IS NULL OR $1 < 10.5`, []string{`$2`, `NOT ($0 >= 100)`}},
+		{`NOT ($0 != 3 OR $2 <= 's0') OR $0 IS NOT NULL AND $0 < 2`, []string{`$0 - $0 * 3`}},
+		{`$0 < 0`, []string{`$0`}},
+		{`$1 = $1 AND ($2 > 's1') = true`, []string{`$1 - 0.5`, `$2 < 's2'`}},
+	}
+	ctx := &parser.EvalContext{}
+	rows := testRows(3*BatchSize + 10)
+	for _, tc := range testCases {
+		vars := &testVars{}
+		h := parser.MakeIndexedVarHelper(vars, len(testTypes))
+		var filter parser.TypedExpr
+		if tc.filter != "" {
+			filter = parseTestExpr(t, &h, tc.filter)
+		}
+		exprs := make([]parser.TypedExpr, len(tc.exprs))
+		for i, e := range tc.exprs {
+			exprs[i] = parseTestExpr(t, &h, e)
+		}
+
+		var expected []string
+		for _, row := range rows {
+			vars.row = row
+			if filter != nil {
+				d, err := filter.Eval(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d != parser.DBoolTrue {
+					continue
+				}
+			}
+			res := make(parser.DTuple, len(exprs))
+			for i, e := range exprs {
+				var err error
+				if res[i], err = e.Eval(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected = append(expected, res.String())
+		}
+
+		op := NewColumnarizer(testColTypes(), BatchSize, sliceSource(rows))
+		if filter != nil {
+			var err error
+			if op, err = NewFilter(op, testColTypes(), filter); err != nil {
+				t.Fatal(err)
+			}
+		}
+		op, _, err := NewProjection(op, testColTypes(), exprs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := materialize(t, op, len(exprs)); !reflect.DeepEqual(res, expected) {
+			t.Errorf("%s %s: expected\n%s\ngot\n%s", tc.filter, tc.exprs, expected, res)
+		}
+	}
+}
+
+func TestUnsupportedExpr(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	h := parser.MakeIndexedVarHelper(&testVars{}, len(testTypes))
+	for _, sql := range []string{
+		`$0 / 2`,
+		`$2 LIKE 's%'`,
+		`length($2)`,
+		`$0::float < $1`,
+	} {
+		if _, err := compileExpr(parseTestExpr(t, &h, sql), testColTypes()); err == nil {
+			t.Errorf("%s: expected an error", sql)
+		}
+	}
+}
+
+// rowAggregate returns the row aggregate function of the given name for
+// values of the given type.
+func rowAggregate(t *testing.T, name string, typ parser.Datum) parser.AggregateFunc {
+	if name == "any" {
+		return parser.NewIdentAggregate()
+	}
+	for _, b := range parser.Builtins[name] {
+		if types, ok := b.Types.(parser.ArgTypes); ok && types[0].TypeEqual(typ) {
+			return b.AggregateFunc()
+		}
+	}
+	t.Fatalf("no %s aggregate for %s", name, typ.Type())
+	return nil
+}
+
+// TestHashAggregator checks that the hash aggregator returns the same results
+// as the row aggregate functions.
+func TestHashAggregator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	baseAggs := []AggSpec{
+		{Func: AggCountRows},
+		{Func: AggCount, Col: 0},
+		{Func: AggSum, Col: 0},
+		{Func: AggSum, Col: 1},
+		{Func: AggMin, Col: 1},
+		{Func: AggMax, Col: 0},
+		{Func: AggMin, Col: 2},
+	}
+
+	testCases := []struct {
+		numRows   int
+		groupCols []int
+	}{
+		{0, nil},
+		{0, []int{2}},
+		{1, nil},
+		{2*BatchSize + 1, nil},
+		{2*BatchSize + 1, []int{2}},
+		{100, []int{0, 2}},
+		// More groups than fit in a batch.
+		{2*BatchSize + 1, []int{0}},
+	}
+	for _, tc := range testCases {
+		rows := testRows(tc.numRows)
+		// The values of the grouping columns are returned first.
+		var aggs []AggSpec
+		for _, c := range tc.groupCols {
+			aggs = append(aggs, AggSpec{Func: AggAny, Col: c})
+		}
+		aggs = append(aggs, baseAggs...)
+
+		// Compute the expected results one row at a time.
+		type group struct {
+			key   string
+			funcs []parser.AggregateFunc
+		}
+		groups := make(map[string]*group)
+		newGroup := func(key string) *group {
+			g := &group{key: key, funcs: make([]parser.AggregateFunc, len(aggs))}
+			for i, a := range aggs {
+				if a.Func == AggCountRows {
+					g.funcs[i] = rowAggregate(t, "count", parser.TypeInt)
+				} else {
+					g.funcs[i] = rowAggregate(t, a.Func.String(), testTypes[a.Col])
+				}
+			}
+			groups[key] = g
+			return g
+		}
+		if tc.groupCols == nil {
+			newGroup("")
+		}
+		for _, row := range rows {
+			var key parser.DTuple
+			for _, c := range tc.groupCols {
+				key = append(key, row[c])
+			}
+			g, ok := groups[key.String()]
+			if !ok {
+				g = newGroup(key.String())
+			}
+			for i, a := range aggs {
+				d := parser.Datum(parser.DBoolTrue)
+				if a.Func != AggCountRows {
+					d = row[a.Col]
+				}
+				if err := g.funcs[i].Add(d); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		var expected []string
+		for _, g := range groups {
+			res := make(parser.DTuple, len(aggs))
+			for i, f := range g.funcs {
+				var err error
+				if res[i], err = f.Result(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected = append(expected, res.String())
+		}
+		sort.Strings(expected)
+
+		var used int64
+		op, outTypes, err := NewHashAggregator(
+			NewColumnarizer(testColTypes(), BatchSize, sliceSource(rows)),
+			testColTypes(), tc.groupCols, aggs,
+			func(n int64) error { used += n; return nil },
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(outTypes) != len(aggs) {
+			t.Fatalf("expected %d output columns, got %d", len(aggs), len(outTypes))
+		}
+		res := materialize(t, op, len(aggs))
+		sort.Strings(res)
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf("%d rows grouped by %v: expected\n%s\ngot\n%s", tc.numRows, tc.groupCols, expected, res)
+		}
+		if len(groups) > 0 && used == 0 {
+			t.Errorf("%d rows grouped by %v: no memory accounted for", tc.numRows, tc.groupCols)
+		}
+	}
+}
+
+func TestHashAggregatorMemoryLimit(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	limitErr := errors.New("memory limit exceeded")
+	var used int64
+	op, _, err := NewHashAggregator(
+		NewColumnarizer(testColTypes(), BatchSize, sliceSource(testRows(1000))),
+		testColTypes(), []int{0}, []AggSpec{{Func: AggCountRows}},
+		func(n int64) error {
+			if used+n > 1000 {
+				return limitErr
+			}
+			used += n
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := op.Next(); err != limitErr {
+		t.Fatalf("expected %v, got %v", limitErr, err)
+	}
+}
@@ -0,0 +1,486 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package colexec
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+)
+
+// vecExpr is an expression evaluated for all the rows of a batch at once.
+type vecExpr interface {
+	// eval evaluates the expression for the rows of the batch. Only the
+	// values of the rows which are part of the batch are set in the result,
+	// which is only valid until the next call.
+	eval(b *Batch) (*Vec, error)
+	typ() ColType
+}
+
+// compileExpr turns an expression, whose variables are IndexedVars
+// referring to columns of the given types, into a vecExpr. An error is
+// returned if the expression is not supported by the engine.
+func compileExpr(expr parser.TypedExpr, types []ColType) (vecExpr, error) {
+	switch t := expr.(type) {
+	case *parser.IndexedVar:
+		if t.Idx >= len(types) || types[t.Idx] == ColUnhandled {
+			return nil, fmt.Errorf("column %s of unsupported type", t)
+		}
+		return colRefExpr{idx: t.Idx, colType: types[t.Idx]}, nil
+
+	case *parser.ParenExpr:
+		return compileExpr(t.TypedInnerExpr(), types)
+
+	case *parser.AndExpr:
+		return compileLogic(t.TypedLeft(), t.TypedRight(), types, false /* or */)
+
+	case *parser.OrExpr:
+		return compileLogic(t.TypedLeft(), t.TypedRight(), types, true /* or */)
+
+	case *parser.NotExpr:
+		input, err := compileExpr(t.TypedInnerExpr(), types)
+		if err != nil {
+			return nil, err
+		}
+		if input.typ() != ColBool {
+			return nil, fmt.Errorf("unsupported expression %s", t)
+		}
+		return &notExpr{input: input}, nil
+
+	case *parser.ComparisonExpr:
+		return compileComparison(t, types)
+
+	case *parser.BinaryExpr:
+		switch t.Operator {
+		case parser.Plus, parser.Minus, parser.Mult:
+		default:
+			return nil, fmt.Errorf("unsupported operator %s", t.Operator)
+		}
+		left, err := compileExpr(t.TypedLeft(), types)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compileExpr(t.TypedRight(), types)
+		if err != nil {
+			return nil, err
+		}
+		if left.typ() != right.typ() || (left.typ() != ColInt && left.typ() != ColFloat) {
+			return nil, fmt.Errorf("unsupported expression %s", t)
+		}
+		return &arithExpr{op: t.Operator, left: left, right: right}, nil
+
+	case parser.Datum:
+		typ := ColTypeFromDatum(t)
+		if typ == ColUnhandled {
+			return nil, fmt.Errorf("constant %s of unsupported type", t)
+		}
+		c := &constExpr{}
+		c.vec.reset(typ, BatchSize)
+		for i := 0; i < BatchSize; i++ {
+			if err := c.vec.SetDatum(i, t); err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported expression %s", expr)
+	}
+}
+
+func compileLogic(
+	leftExpr, rightExpr parser.TypedExpr, types []ColType, or bool,
+) (vecExpr, error) {
+	left, err := compileExpr(leftExpr, types)
+	if err != nil {
+		return nil, err
+	}
+	right, err := compileExpr(rightExpr, types)
+	if err != nil {
+		return nil, err
+	}
+	if left.typ() != ColBool || right.typ() != ColBool {
+		return nil, fmt.Errorf("unsupported operands %s and %s", leftExpr, rightExpr)
+	}
+	return &logicExpr{or: or, left: left, right: right}, nil
+}
+
+func compileComparison(expr *parser.ComparisonExpr, types []ColType) (vecExpr, error) {
+	switch expr.Operator {
+	case parser.Is, parser.IsNot:
+		if expr.Right != parser.DNull {
+			return nil, fmt.Errorf("unsupported expression %s", expr)
+		}
+		input, err := compileExpr(expr.TypedLeft(), types)
+		if err != nil {
+			return nil, err
+		}
+		return &isNullExpr{negate: expr.Operator == parser.IsNot, input: input}, nil
+	case parser.EQ, parser.NE, parser.LT, parser.LE, parser.GT, parser.GE:
+	default:
+		return nil, fmt.Errorf("unsupported operator %s", expr.Operator)
+	}
+	left, err := compileExpr(expr.TypedLeft(), types)
+	if err != nil {
+		return nil, err
+	}
+	right, err := compileExpr(expr.TypedRight(), types)
+	if err != nil {
+		return nil, err
+	}
+	if left.typ() != right.typ() || left.typ() == ColDecimal {
+		return nil, fmt.Errorf("unsupported expression %s", expr)
+	}
+	return &cmpExpr{op: expr.Operator, left: left, right: right}, nil
+}
+
+type colRefExpr struct {
+	idx     int
+	colType ColType
+}
+
+func (e colRefExpr) eval(b *Batch) (*Vec, error) {
+	return &b.Cols[e.idx], nil
+}
+
+func (e colRefExpr) typ() ColType { return e.colType }
+
+// constExpr is a constant, whose values are set once and for all.
+type constExpr struct {
+	vec Vec
+}
+
+func (e *constExpr) eval(b *Batch) (*Vec, error) {
+	return &e.vec, nil
+}
+
+func (e *constExpr) typ() ColType { return e.vec.Type }
+
+type notExpr struct {
+	input vecExpr
+	out   Vec
+}
+
+func (e *notExpr) eval(b *Batch) (*Vec, error) {
+	in, err := e.input.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	e.out.reset(ColBool, b.Length)
+	for k, n := 0, b.NumRows(); k < n; k++ {
+		i := b.Row(k)
+		if !in.Nulls[i] {
+			e.out.Nulls[i] = false
+			e.out.Bools[i] = !in.Bools[i]
+		}
+	}
+	return &e.out, nil
+}
+
+func (e *notExpr) typ() ColType { return ColBool }
+
+type isNullExpr struct {
+	negate bool
+	input  vecExpr
+	out    Vec
+}
+
+func (e *isNullExpr) eval(b *Batch) (*Vec, error) {
+	in, err := e.input.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	e.out.reset(ColBool, b.Length)
+	for k, n := 0, b.NumRows(); k < n; k++ {
+		i := b.Row(k)
+		e.out.Nulls[i] = false
+		e.out.Bools[i] = in.Nulls[i] != e.negate
+	}
+	return &e.out, nil
+}
+
+func (e *isNullExpr) typ() ColType { return ColBool }
+
+// logicExpr is an AND or an OR, following the three-valued logic of SQL.
+type logicExpr struct {
+	or          bool
+	left, right vecExpr
+	out         Vec
+}
+
+func (e *logicExpr) eval(b *Batch) (*Vec, error) {
+	l, err := e.left.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	e.out.reset(ColBool, b.Length)
+	for k, n := 0, b.NumRows(); k < n; k++ {
+		i := b.Row(k)
+		// A non-NULL operand equal to e.or (false for AND, true for OR)
+		// decides the result on its own.
+		switch {
+		case !l.Nulls[i] && l.Bools[i] == e.or, !r.Nulls[i] && r.Bools[i] == e.or:
+			e.out.Nulls[i] = false
+			e.out.Bools[i] = e.or
+		case !l.Nulls[i] && !r.Nulls[i]:
+			e.out.Nulls[i] = false
+			e.out.Bools[i] = !e.or
+		}
+	}
+	return &e.out, nil
+}
+
+func (e *logicExpr) typ() ColType { return ColBool }
+
+type cmpExpr struct {
+	op          parser.ComparisonOperator
+	left, right vecExpr
+	out         Vec
+}
+
+func (e *cmpExpr) eval(b *Batch) (*Vec, error) {
+	l, err := e.left.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	e.out.reset(ColBool, b.Length)
+	n := b.NumRows()
+	switch l.Type {
+	case ColInt:
+		a, c := l.Ints, r.Ints
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				e.out.Bools[i] = e.test(a[i] < c[i], a[i] == c[i], c[i] < a[i])
+			}
+		}
+	case ColFloat:
+		a, c := l.Floats, r.Floats
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				e.out.Bools[i] = e.test(a[i] < c[i], a[i] == c[i], c[i] < a[i])
+			}
+		}
+	case ColString, ColBytes:
+		a, c := l.Strings, r.Strings
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				e.out.Bools[i] = e.test(a[i] < c[i], a[i] == c[i], c[i] < a[i])
+			}
+		}
+	case ColBool:
+		a, c := l.Bools, r.Bools
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				e.out.Bools[i] = e.test(!a[i] && c[i], a[i] == c[i], a[i] && !c[i])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported comparison of %s values", l.Type)
+	}
+	return &e.out, nil
+}
+
+// test returns the result of the comparison given whether the left operand
+// is less than, equal to or greater than the right one. All three are false
+// when comparing NaN floats, which are then only different from any value.
+func (e *cmpExpr) test(lt, eq, gt bool) bool {
+	switch e.op {
+	case parser.EQ:
+		return eq
+	case parser.NE:
+		return !eq
+	case parser.LT:
+		return lt
+	case parser.LE:
+		return lt || eq
+	case parser.GT:
+		return gt
+	default:
+		return gt || eq
+	}
+}
+
+func (e *cmpExpr) typ() ColType { return ColBool }
+
+// arithExpr is an addition, subtraction or multiplication of ints or
+// floats. Like for rows, the int operations wrap around on overflow.
+type arithExpr struct {
+	op          parser.BinaryOperator
+	left, right vecExpr
+	out         Vec
+}
+
+func (e *arithExpr) eval(b *Batch) (*Vec, error) {
+	l, err := e.left.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.right.eval(b)
+	if err != nil {
+		return nil, err
+	}
+	e.out.reset(l.Type, b.Length)
+	n := b.NumRows()
+	switch l.Type {
+	case ColInt:
+		a, c, out := l.Ints, r.Ints, e.out.Ints
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				switch e.op {
+				case parser.Plus:
+					out[i] = a[i] + c[i]
+				case parser.Minus:
+					out[i] = a[i] - c[i]
+				default:
+					out[i] = a[i] * c[i]
+				}
+			}
+		}
+	case ColFloat:
+		a, c, out := l.Floats, r.Floats, e.out.Floats
+		for k := 0; k < n; k++ {
+			if i := b.Row(k); !l.Nulls[i] && !r.Nulls[i] {
+				e.out.Nulls[i] = false
+				switch e.op {
+				case parser.Plus:
+					out[i] = a[i] + c[i]
+				case parser.Minus:
+					out[i] = a[i] - c[i]
+				default:
+					out[i] = a[i] * c[i]
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported arithmetic on %s values", l.Type)
+	}
+	return &e.out, nil
+}
+
+func (e *arithExpr) typ() ColType { return e.left.typ() }
+
+// filterOp only keeps the rows of its input for which a boolean expression
+// is true.
+type filterOp struct {
+	input  Operator
+	filter vecExpr
+	sel    []int
+}
+
+// NewFilter returns an Operator only keeping the rows of the input for which
+// the filter is true. The variables of the filter are IndexedVars referring
+// to the columns of the input, which are of the given types.
+func NewFilter(input Operator, types []ColType, filter parser.TypedExpr) (Operator, error) {
+	e, err := compileExpr(filter, types)
+	if err != nil {
+		return nil, err
+	}
+	if e.typ() != ColBool {
+		return nil, fmt.Errorf("filter %s is not a boolean expression", filter)
+	}
+	return &filterOp{input: input, filter: e}, nil
+}
+
+func (f *filterOp) Next() (*Batch, error) {
+	for {
+		b, err := f.input.Next()
+		if err != nil {
+			return nil, err
+		}
+		if b.NumRows() == 0 {
+			return b, nil
+		}
+		v, err := f.filter.eval(b)
+		if err != nil {
+			return nil, err
+		}
+		f.sel = f.sel[:0]
+		for k, n := 0, b.NumRows(); k < n; k++ {
+			if i := b.Row(k); !v.Nulls[i] && v.Bools[i] {
+				f.sel = append(f.sel, i)
+			}
+		}
+		// Batches whose rows have all been filtered out are skipped, as an
+		// empty batch signals the end of the results.
+		if len(f.sel) > 0 {
+			b.Sel = f.sel
+			return b, nil
+		}
+	}
+}
+
+// projectOp outputs the results of expressions over the rows of its input.
+type projectOp struct {
+	input Operator
+	exprs []vecExpr
+	out   Batch
+}
+
+// NewProjection returns an Operator whose columns are the results of the
+// given expressions over the rows of the input. The variables of the
+// expressions are IndexedVars referring to the columns of the input, which
+// are of the given types. The types of the columns of the operator are
+// returned along with it.
+func NewProjection(
+	input Operator, types []ColType, exprs []parser.TypedExpr,
+) (Operator, []ColType, error) {
+	p := &projectOp{
+		input: input,
+		exprs: make([]vecExpr, len(exprs)),
+		out:   Batch{Cols: make([]Vec, len(exprs))},
+	}
+	outTypes := make([]ColType, len(exprs))
+	for i, expr := range exprs {
+		e, err := compileExpr(expr, types)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.exprs[i] = e
+		outTypes[i] = e.typ()
+	}
+	return p, outTypes, nil
+}
+
+func (p *projectOp) Next() (*Batch, error) {
+	b, err := p.input.Next()
+	if err != nil {
+		return nil, err
+	}
+	p.out.Length, p.out.Sel = b.Length, b.Sel
+	if b.NumRows() == 0 {
+		return &p.out, nil
+	}
+	for i, e := range p.exprs {
+		v, err := e.eval(b)
+		if err != nil {
+			return nil, err
+		}
+		p.out.Cols[i] = *v
+	}
+	return &p.out, nil
+}
@@ -0,0 +1,476 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package colexec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"gopkg.in/inf.v0"
+)
+
+// AggFunc is an aggregate function supported by the hash aggregator.
+type AggFunc int
+
+const (
+	// AggAny returns a value of the column for the group; it is used for
+	// the grouping columns, whose value is the same for all the rows of a
+	// group.
+	AggAny AggFunc = iota
+	// AggCountRows is COUNT(*). Its column is ignored.
+	AggCountRows
+	// AggCount is COUNT.
+	AggCount
+	// AggSum is SUM.
+	AggSum
+	// AggMin is MIN.
+	AggMin
+	// AggMax is MAX.
+	AggMax
+)
+
+var aggFuncNames = [...]string{
+	AggAny:       "any",
+	AggCountRows: "count_rows",
+	AggCount:     "count",
+	AggSum:       "sum",
+	AggMin:       "min",
+	AggMax:       "max",
+}
+
+func (f AggFunc) String() string {
+	return aggFuncNames[f]
+}
+
+// AggSpec is an aggregation over a column of the input of an aggregator.
+type AggSpec struct {
+	Func AggFunc
+	Col  int
+}
+
+// aggOutputType returns the type of the results of the aggregation of
+// values of the given type, or ColUnhandled if the aggregation is not
+// supported.
+func aggOutputType(f AggFunc, in ColType) ColType {
+	switch f {
+	case AggAny:
+		return in
+	case AggCountRows, AggCount:
+		return ColInt
+	case AggSum:
+		switch in {
+		case ColInt, ColDecimal:
+			// Like for rows, the sum of ints is a decimal, which doesn't
+			// overflow.
+			return ColDecimal
+		case ColFloat:
+			return ColFloat
+		}
+	case AggMin, AggMax:
+		return in
+	}
+	return ColUnhandled
+}
+
+// aggBucketSize is an estimate of the memory used by each group, besides the
+// grouping key and the aggregated values.
+const aggBucketSize = 64
+
+// aggState holds the values aggregated for all the groups by an AggSpec.
+type aggState struct {
+	AggSpec
+	inType ColType
+	// seen indicates for each group whether a non-NULL value was aggregated,
+	// or for AggAny whether any value was.
+	seen   []bool
+	counts []int64
+	ints   []int64
+	floats []float64
+	// decs holds the sums of decimals, as well as the part of the sums of
+	// ints which overflowed ints.
+	decs    []inf.Dec
+	strings []string
+	bools   []bool
+	// nulls is used by AggAny to record NULL values.
+	nulls []bool
+}
+
+func (s *aggState) addGroup() {
+	s.seen = append(s.seen, false)
+	switch s.Func {
+	case AggCountRows, AggCount:
+		s.counts = append(s.counts, 0)
+		return
+	case AggAny:
+		s.nulls = append(s.nulls, false)
+	}
+	switch s.inType {
+	case ColInt:
+		s.ints = append(s.ints, 0)
+		if s.Func == AggSum {
+			s.decs = append(s.decs, inf.Dec{})
+		}
+	case ColFloat:
+		s.floats = append(s.floats, 0)
+	case ColDecimal:
+		s.decs = append(s.decs, inf.Dec{})
+	case ColString, ColBytes:
+		s.strings = append(s.strings, "")
+	case ColBool:
+		s.bools = append(s.bools, false)
+	}
+}
+
+// add aggregates the values of the rows of the batch; groups[k] is the
+// group of the k-th row of the batch.
+func (s *aggState) add(b *Batch, groups []int) {
+	if s.Func == AggCountRows {
+		for _, g := range groups {
+			s.counts[g]++
+		}
+		return
+	}
+	v := &b.Cols[s.Col]
+	for k, g := range groups {
+		i := b.Row(k)
+		if s.Func == AggAny {
+			if !s.seen[g] {
+				s.seen[g] = true
+				s.nulls[g] = v.Nulls[i]
+				if !v.Nulls[i] {
+					s.set(g, v, i)
+				}
+			}
+			continue
+		}
+		if v.Nulls[i] {
+			continue
+		}
+		first := !s.seen[g]
+		s.seen[g] = true
+		switch s.Func {
+		case AggCount:
+			s.counts[g]++
+		case AggSum:
+			s.sum(g, v, i)
+		case AggMin, AggMax:
+			if first {
+				s.set(g, v, i)
+			} else if c := s.compare(g, v, i); (s.Func == AggMin && c > 0) || (s.Func == AggMax && c < 0) {
+				s.set(g, v, i)
+			}
+		}
+	}
+}
+
+// set sets the value of the group to the i-th value of the vector.
+func (s *aggState) set(g int, v *Vec, i int) {
+	switch s.inType {
+	case ColInt:
+		s.ints[g] = v.Ints[i]
+	case ColFloat:
+		s.floats[g] = v.Floats[i]
+	case ColDecimal:
+		s.decs[g].Set(&v.Decimals[i])
+	case ColString, ColBytes:
+		s.strings[g] = v.Strings[i]
+	case ColBool:
+		s.bools[g] = v.Bools[i]
+	}
+}
+
+// compare compares the value of the group to the i-th value of the vector,
+// like Datum.Compare does.
+func (s *aggState) compare(g int, v *Vec, i int) int {
+	switch s.inType {
+	case ColInt:
+		return compareBools(s.ints[g] < v.Ints[i], s.ints[g] > v.Ints[i])
+	case ColFloat:
+		return compareBools(s.floats[g] < v.Floats[i], s.floats[g] > v.Floats[i])
+	case ColDecimal:
+		return s.decs[g].Cmp(&v.Decimals[i])
+	case ColString, ColBytes:
+		return compareBools(s.strings[g] < v.Strings[i], s.strings[g] > v.Strings[i])
+	case ColBool:
+		return compareBools(!s.bools[g] && v.Bools[i], s.bools[g] && !v.Bools[i])
+	}
+	panic(fmt.Sprintf("unsupported comparison of %s values", s.inType))
+}
+
+func compareBools(lt, gt bool) int {
+	switch {
+	case lt:
+		return -1
+	case gt:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// sum adds the i-th value of the vector to the sum of the group. Ints are
+// summed as ints until that overflows, at which point the sum so far is
+// moved to the decimal sum.
+func (s *aggState) sum(g int, v *Vec, i int) {
+	switch s.inType {
+	case ColInt:
+		a, x := s.ints[g], v.Ints[i]
+		r := a + x
+		if (x > 0 && r < a) || (x < 0 && r > a) {
+			var tmp inf.Dec
+			tmp.SetUnscaled(a)
+			s.decs[g].Add(&s.decs[g], &tmp)
+			r = x
+		}
+		s.ints[g] = r
+	case ColFloat:
+		s.floats[g] += v.Floats[i]
+	case ColDecimal:
+		s.decs[g].Add(&s.decs[g], &v.Decimals[i])
+	}
+}
+
+// result sets the i-th value of the vector to the result of the
+// aggregation for the group.
+func (s *aggState) result(g int, v *Vec, i int) {
+	switch s.Func {
+	case AggCountRows, AggCount:
+		v.Nulls[i] = false
+		v.Ints[i] = s.counts[g]
+		return
+	case AggAny:
+		if !s.seen[g] || s.nulls[g] {
+			return
+		}
+	default:
+		if !s.seen[g] {
+			return
+		}
+	}
+	v.Nulls[i] = false
+	if s.Func == AggSum && s.inType == ColInt {
+		var tmp inf.Dec
+		tmp.SetUnscaled(s.ints[g])
+		v.Decimals[i].Add(&s.decs[g], &tmp)
+		return
+	}
+	switch s.inType {
+	case ColInt:
+		v.Ints[i] = s.ints[g]
+	case ColFloat:
+		v.Floats[i] = s.floats[g]
+	case ColDecimal:
+		v.Decimals[i].Set(&s.decs[g])
+	case ColString, ColBytes:
+		v.Strings[i] = s.strings[g]
+	case ColBool:
+		v.Bools[i] = s.bools[g]
+	}
+}
+
+// hashAggOp groups the rows of its input using a hash table, and aggregates
+// the values of each group. It consumes all its input before producing any
+// result.
+type hashAggOp struct {
+	input     Operator
+	groupCols []int
+	aggs      []aggState
+	outTypes  []ColType
+	// grow is called to account for the memory used by new groups.
+	grow func(int64) error
+
+	// groupIdx maps the encoded grouping keys to the index of their group.
+	groupIdx  map[string]int
+	numGroups int
+	// batchGroups holds the groups of the rows of the current batch.
+	batchGroups []int
+	keyBuf      []byte
+
+	done bool
+	// emitted is the number of groups whose results have been returned.
+	emitted int
+	out     Batch
+}
+
+// NewHashAggregator returns an Operator grouping the rows of the input, whose
+// columns are of the given types, by the given columns, and computing the
+// given aggregations over each group. The results have one column per
+// aggregation, of the returned types. If no grouping column is given, there
+// is a single group, even for an empty input. The memory used by the groups
+// is accounted for using grow, which can refuse it by returning an error.
+func NewHashAggregator(
+	input Operator,
+	inputTypes []ColType,
+	groupCols []int,
+	aggs []AggSpec,
+	grow func(int64) error,
+) (Operator, []ColType, error) {
+	h := &hashAggOp{
+		input:     input,
+		groupCols: groupCols,
+		aggs:      make([]aggState, len(aggs)),
+		outTypes:  make([]ColType, len(aggs)),
+		grow:      grow,
+		groupIdx:  make(map[string]int),
+		out:       Batch{Cols: make([]Vec, len(aggs))},
+	}
+	for _, c := range groupCols {
+		switch inputTypes[c] {
+		case ColInt, ColFloat, ColString, ColBytes, ColBool:
+		default:
+			return nil, nil, fmt.Errorf("unsupported grouping by a column of type %s", inputTypes[c])
+		}
+	}
+	for i, a := range aggs {
+		inType := ColInt
+		if a.Func != AggCountRows {
+			inType = inputTypes[a.Col]
+		}
+		outType := aggOutputType(a.Func, inType)
+		if inType == ColUnhandled || outType == ColUnhandled {
+			return nil, nil, fmt.Errorf("unsupported %s over a column of type %s", a.Func, inType)
+		}
+		h.aggs[i] = aggState{AggSpec: a, inType: inType}
+		h.outTypes[i] = outType
+	}
+	return h, h.outTypes, nil
+}
+
+func (h *hashAggOp) Next() (*Batch, error) {
+	if !h.done {
+		if err := h.consume(); err != nil {
+			return nil, err
+		}
+		h.done = true
+	}
+	n := h.numGroups - h.emitted
+	if n > BatchSize {
+		n = BatchSize
+	}
+	for j := range h.aggs {
+		v := &h.out.Cols[j]
+		v.reset(h.outTypes[j], n)
+		for i := 0; i < n; i++ {
+			h.aggs[j].result(h.emitted+i, v, i)
+		}
+	}
+	h.out.Length = n
+	h.emitted += n
+	return &h.out, nil
+}
+
+// consume aggregates all the rows of the input.
+func (h *hashAggOp) consume() error {
+	for {
+		b, err := h.input.Next()
+		if err != nil {
+			return err
+		}
+		n := b.NumRows()
+		if n == 0 {
+			break
+		}
+		h.batchGroups = h.batchGroups[:0]
+		for k := 0; k < n; k++ {
+			g, err := h.findGroup(b, b.Row(k))
+			if err != nil {
+				return err
+			}
+			h.batchGroups = append(h.batchGroups, g)
+		}
+		for j := range h.aggs {
+			h.aggs[j].add(b, h.batchGroups)
+		}
+	}
+	if len(h.groupCols) == 0 && h.numGroups == 0 {
+		// Without grouping columns, the results are computed over all the
+		// rows, even if there is none.
+		return h.newGroup("")
+	}
+	return nil
+}
+
+// findGroup returns the group of the i-th row of the batch, creating it if
+// needed.
+func (h *hashAggOp) findGroup(b *Batch, i int) (int, error) {
+	h.keyBuf = h.keyBuf[:0]
+	for _, c := range h.groupCols {
+		h.keyBuf = encodeKeyValue(h.keyBuf, &b.Cols[c], i)
+	}
+	if g, ok := h.groupIdx[string(h.keyBuf)]; ok {
+		return g, nil
+	}
+	g := h.numGroups
+	if err := h.newGroup(string(h.keyBuf)); err != nil {
+		return 0, err
+	}
+	return g, nil
+}
+
+func (h *hashAggOp) newGroup(key string) error {
+	if err := h.grow(int64(len(key) + aggBucketSize + 16*len(h.aggs))); err != nil {
+		return err
+	}
+	h.groupIdx[key] = h.numGroups
+	h.numGroups++
+	for j := range h.aggs {
+		h.aggs[j].addGroup()
+	}
+	return nil
+}
+
+// encodeKeyValue appends to the buffer an encoding of the i-th value of the
+// vector, such that values are considered equal for grouping if and only if
+// their encodings are equal.
+func encodeKeyValue(buf []byte, v *Vec, i int) []byte {
+	if v.Nulls[i] {
+		return append(buf, 0)
+	}
+	buf = append(buf, 1)
+	var tmp [binary.MaxVarintLen64]byte
+	switch v.Type {
+	case ColInt:
+		binary.BigEndian.PutUint64(tmp[:8], uint64(v.Ints[i]))
+		buf = append(buf, tmp[:8]...)
+	case ColFloat:
+		f := v.Floats[i]
+		var bits uint64
+		switch {
+		case math.IsNaN(f):
+			// All the NaNs form a single group.
+			bits = math.Float64bits(math.NaN())
+		case f == 0:
+			// So do 0 and -0.
+		default:
+			bits = math.Float64bits(f)
+		}
+		binary.BigEndian.PutUint64(tmp[:8], bits)
+		buf = append(buf, tmp[:8]...)
+	case ColString, ColBytes:
+		n := binary.PutUvarint(tmp[:], uint64(len(v.Strings[i])))
+		buf = append(buf, tmp[:n]...)
+		buf = append(buf, v.Strings[i]...)
+	case ColBool:
+		if v.Bools[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported grouping by a column of type %s", v.Type))
+	}
+	return buf
+}
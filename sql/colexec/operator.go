@@ -0,0 +1,127 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package colexec
+
+import "github.com/cockroachdb/cockroach/sql/parser"
+
+// Operator is a vectorized operator, which produces its results a batch at a
+// time.
+type Operator interface {
+	// Next returns the next batch of results. A batch without any row
+	// signals the end of the results. The batch is only valid until the next
+	// call to Next.
+	Next() (*Batch, error)
+}
+
+// RowSource returns rows one at a time. A nil row signals the end of the
+// rows.
+type RowSource func() (parser.DTuple, error)
+
+// columnarizer is an Operator which turns the rows of a RowSource into
+// batches.
+type columnarizer struct {
+	types     []ColType
+	source    RowSource
+	batchSize int
+	batch     Batch
+	done      bool
+}
+
+// NewColumnarizer returns an Operator producing batches of the given size
+// out of the rows of the source, which have columns of the given types. The
+// columns of type ColUnhandled are left empty.
+func NewColumnarizer(types []ColType, batchSize int, source RowSource) Operator {
+	if batchSize <= 0 || batchSize > BatchSize {
+		batchSize = BatchSize
+	}
+	return &columnarizer{
+		types:     types,
+		source:    source,
+		batchSize: batchSize,
+		batch:     Batch{Cols: make([]Vec, len(types))},
+	}
+}
+
+func (c *columnarizer) Next() (*Batch, error) {
+	c.batch.Sel = nil
+	c.batch.Length = 0
+	if c.done {
+		return &c.batch, nil
+	}
+	for i := range c.batch.Cols {
+		c.batch.Cols[i].reset(c.types[i], c.batchSize)
+	}
+	n := 0
+	for n < c.batchSize {
+		row, err := c.source()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			c.done = true
+			break
+		}
+		for i, typ := range c.types {
+			if typ == ColUnhandled {
+				continue
+			}
+			if err := c.batch.Cols[i].SetDatum(n, row[i]); err != nil {
+				return nil, err
+			}
+		}
+		n++
+	}
+	c.batch.Length = n
+	return &c.batch, nil
+}
+
+// Materializer returns the rows of the batches of an Operator one at a time.
+type Materializer struct {
+	input Operator
+	batch *Batch
+	// idx is the index in the batch of the next row.
+	idx int
+	row parser.DTuple
+}
+
+// NewMaterializer returns a Materializer for the results of the operator,
+// which have the given number of columns.
+func NewMaterializer(input Operator, numCols int) *Materializer {
+	return &Materializer{input: input, row: make(parser.DTuple, numCols)}
+}
+
+// Next returns the next row, or nil once all the rows have been returned.
+// The row is only valid until the next call.
+func (m *Materializer) Next() (parser.DTuple, error) {
+	for m.batch == nil || m.idx >= m.batch.NumRows() {
+		if m.batch != nil && m.batch.Length == 0 {
+			return nil, nil
+		}
+		var err error
+		if m.batch, err = m.input.Next(); err != nil {
+			return nil, err
+		}
+		m.idx = 0
+		if m.batch.NumRows() == 0 {
+			return nil, nil
+		}
+	}
+	i := m.batch.Row(m.idx)
+	m.idx++
+	for j := range m.row {
+		m.row[j] = m.batch.Cols[j].Datum(i)
+	}
+	return m.row, nil
+}
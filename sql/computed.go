@@ -0,0 +1,236 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// computeHelper evaluates the expressions of a table's computed columns
+// against the other values of a row being written.
+type computeHelper struct {
+	exprs []parser.TypedExpr
+	qvals qvalMap
+	cols  []sqlbase.ColumnDescriptor
+	// computedCols[i] is the column whose value is produced by exprs[i].
+	computedCols []sqlbase.ColumnDescriptor
+}
+
+func (c *computeHelper) init(p *planner, tn *parser.TableName, tableDesc *sqlbase.TableDescriptor) error {
+	return c.initCols(p, tn, tableDesc, tableDesc.WritableComputedColumns())
+}
+
+// initCols is like init, but only evaluates the expressions of the given
+// computed columns.
+func (c *computeHelper) initCols(
+	p *planner,
+	tn *parser.TableName,
+	tableDesc *sqlbase.TableDescriptor,
+	computedCols []sqlbase.ColumnDescriptor,
+) error {
+	if len(computedCols) == 0 {
+		return nil
+	}
+	c.computedCols = computedCols
+	exprStrings := make([]string, len(computedCols))
+	for i, col := range computedCols {
+		exprStrings[i] = *col.ComputedExpr
+	}
+
+	c.qvals = make(qvalMap)
+	c.cols = tableDesc.Columns
+	sourceInfo := newSourceInfoForSingleTable(*tn, makeResultColumns(tableDesc.Columns))
+
+	exprs, err := parser.ParseExprsTraditional(exprStrings)
+	if err != nil {
+		return err
+	}
+
+	c.exprs = make([]parser.TypedExpr, len(exprs))
+	for i, raw := range exprs {
+		typedExpr, err := p.analyzeExpr(raw, multiSourceInfo{sourceInfo}, c.qvals,
+			c.computedCols[i].Type.ToDatumType(), true, "computed column")
+		if err != nil {
+			return err
+		}
+		c.exprs[i] = typedExpr
+	}
+	return nil
+}
+
+// Set values in the qvalues used by the computed column exprs.
+// Any value not passed is set to NULL, unless `merge` is true, in which
+// case it is left unchanged (allowing updating a subset of a row's values).
+func (c *computeHelper) loadRow(colIdx map[sqlbase.ColumnID]int, row parser.DTuple, merge bool) {
+	if len(c.exprs) == 0 {
+		return
+	}
+
+	for ref, qval := range c.qvals {
+		ri, has := colIdx[c.cols[ref.colIdx].ID]
+		if has {
+			qval.datum = row[ri]
+		} else if !merge {
+			qval.datum = parser.DNull
+		}
+	}
+}
+
+// compute evaluates the computed columns using the values last passed to
+// loadRow and stores the results in row. colIdx must contain every computed
+// column.
+func (c *computeHelper) compute(
+	ctx *parser.EvalContext, colIdx map[sqlbase.ColumnID]int, row parser.DTuple,
+) error {
+	for i, expr := range c.exprs {
+		d, err := expr.Eval(ctx)
+		if err != nil {
+			return err
+		}
+		row[colIdx[c.computedCols[i].ID]] = d
+	}
+	return nil
+}
+
+// validateComputedColumn checks that the expression of a computed column
+// only references non-computed columns of the table and has the type of the
+// column.
+func validateComputedColumn(desc *sqlbase.TableDescriptor, col *sqlbase.ColumnDescriptor) error {
+	raw, err := parser.ParseExprTraditional(*col.ComputedExpr)
+	if err != nil {
+		return err
+	}
+	return validateRowExpr(desc, raw, col.Type.ToDatumType(),
+		fmt.Sprintf("computed column %q", col.Name), "computed column")
+}
+
+// validateRowExpr checks that an expression computed from the values of a
+// row only references non-computed columns of the table and has the given
+// type. exprDesc describes the expression in errors and typeContext in type
+// errors.
+func validateRowExpr(
+	desc *sqlbase.TableDescriptor, raw parser.Expr, typ parser.Datum, exprDesc, typeContext string,
+) error {
+	preFn := func(expr parser.Expr) (err error, recurse bool, newExpr parser.Expr) {
+		vBase, ok := expr.(parser.VarName)
+		if !ok {
+			// Not a VarName, don't do anything to this node.
+			return nil, true, expr
+		}
+
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return err, false, nil
+		}
+
+		c, ok := v.(*parser.ColumnItem)
+		if !ok {
+			return nil, true, expr
+		}
+
+		ref, err := desc.FindActiveColumnByName(c.ColumnName)
+		if err != nil {
+			return fmt.Errorf("column %q not found for %s", c.ColumnName, exprDesc), false, nil
+		}
+		if ref.IsComputed() {
+			return fmt.Errorf("%s cannot reference computed column %q", exprDesc, ref.Name), false, nil
+		}
+		// Convert to a dummy datum of the correct type.
+		return nil, false, ref.Type.ToDatumType()
+	}
+
+	expr, err := parser.SimpleVisit(raw, preFn)
+	if err != nil {
+		return err
+	}
+
+	var p parser.Parser
+	if p.AggregateInExpr(expr) {
+		return fmt.Errorf("aggregate functions are not allowed in %s", exprDesc)
+	}
+
+	return sqlbase.SanitizeVarFreeExpr(expr, typ, typeContext)
+}
+
+// computedColumnReferencing returns the name of a computed column of the
+// table whose expression references the named column, or the empty string if
+// there is none.
+func computedColumnReferencing(desc *sqlbase.TableDescriptor, name string) (string, error) {
+	normName := sqlbase.ReNormalizeName(name)
+	for _, col := range desc.Columns {
+		if !col.IsComputed() {
+			continue
+		}
+		found, err := exprReferencesColumn(*col.ComputedExpr, normName)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return col.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// checkReferencing returns the name of a CHECK constraint of the table whose
+// expression references the named column, or its expression if it is
+// unnamed. The empty string is returned if there is no such constraint.
+func checkReferencing(desc *sqlbase.TableDescriptor, name string) (string, error) {
+	normName := sqlbase.ReNormalizeName(name)
+	for _, check := range desc.Checks {
+		found, err := exprReferencesColumn(check.Expr, normName)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			if check.Name == "" {
+				return check.Expr, nil
+			}
+			return check.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// exprReferencesColumn returns true if the expression references the column
+// with the given normalized name.
+func exprReferencesColumn(exprString string, normName string) (bool, error) {
+	raw, err := parser.ParseExprTraditional(exprString)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	preFn := func(expr parser.Expr) (err error, recurse bool, newExpr parser.Expr) {
+		vBase, ok := expr.(parser.VarName)
+		if !ok {
+			return nil, true, expr
+		}
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return err, false, nil
+		}
+		if c, ok := v.(*parser.ColumnItem); ok && sqlbase.NormalizeName(c.ColumnName) == normName {
+			found = true
+		}
+		return nil, false, expr
+	}
+	if _, err := parser.SimpleVisit(raw, preFn); err != nil {
+		return false, err
+	}
+	return found, nil
+}
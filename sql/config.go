@@ -26,6 +26,7 @@ func init() {
 	// TODO(marc): we use a hook to avoid a dependency on the sql package. We
 	// should probably move keys/protos elsewhere.
 	config.ZoneConfigHook = GetZoneConfig
+	config.ProtectedTimestampsHook = GetProtectedTimestamps
 }
 
 // GetZoneConfig returns the zone config for the object with 'id'.
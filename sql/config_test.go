@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/protoutil"
 	"github.com/gogo/protobuf/proto"
@@ -243,3 +244,53 @@ func TestGetZoneConfig(t *testing.T) {
 		}
 	}
 }
+
+// TestGetProtectedTimestamp exercises config.GetProtectedTimestamp and the
+// sql hook for it.
+func TestGetProtectedTimestamp(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	srv, sqlDB, _ := serverutils.StartServer(t, params)
+	defer srv.Stopper().Stop()
+	s := srv.(*server.TestServer)
+
+	span := func(start, end string) roachpb.Span {
+		return roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)}
+	}
+	ts1 := hlc.Timestamp{WallTime: 1}
+	ts2 := hlc.Timestamp{WallTime: 2}
+	for i, record := range []config.ProtectedTimestamp{
+		{Timestamp: ts2, Spans: []roachpb.Span{span("a", "c")}},
+		{Timestamp: ts1, Spans: []roachpb.Span{span("x", "y"), span("b", "d")}},
+	} {
+		buf, err := protoutil.Marshal(&record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sqlDB.Exec(
+			`INSERT INTO system.protected_ts (id, record) VALUES ($1, $2)`, i, buf,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := forceNewConfig(t, s)
+
+	testCases := []struct {
+		span     roachpb.Span
+		expected hlc.Timestamp
+	}{
+		{span("a", "b"), ts2},
+		{span("a", "z"), ts1},
+		{span("c", "d"), ts1},
+		{span("d", "x"), hlc.ZeroTimestamp},
+	}
+	for i, tc := range testCases {
+		ts, err := cfg.GetProtectedTimestamp(tc.span)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ts != tc.expected {
+			t.Errorf("%d: expected %s to be protected at %s, got %s", i, tc.span, tc.expected, ts)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+)
+
+// copyBatchRowSize is the number of rows buffered by a COPY before they are
+// inserted.
+const copyBatchRowSize = 100
+
+// copyNode is the planNode of a COPY FROM STDIN statement. It doesn't insert
+// anything itself: it puts the session in copy mode, in which the data sent
+// by the client is handed to the session's copyMachine.
+type copyNode struct {
+	p             *planner
+	machine       *copyMachine
+	resultColumns []ResultColumn
+}
+
+var _ planNode = &copyNode{}
+
+// CopyFrom starts a COPY FROM statement.
+// Privileges: INSERT on table.
+func (p *planner) CopyFrom(n *parser.CopyFrom) (planNode, error) {
+	if !n.Stdin {
+		return nil, fmt.Errorf("COPY FROM is only supported from STDIN")
+	}
+	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+	tableDesc, err := p.getTableLease(tn)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivilege(tableDesc, privilege.INSERT); err != nil {
+		return nil, err
+	}
+	cols, err := p.processColumns(tableDesc, n.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &copyMachine{
+		table:    tn,
+		columns:  make(parser.UnresolvedNames, len(cols)),
+		colTypes: make([]parser.ColumnType, len(cols)),
+	}
+	resultColumns := make([]ResultColumn, len(cols))
+	for i, col := range cols {
+		m.columns[i] = parser.UnresolvedName{parser.Name(col.Name)}
+		typ := col.Type.ToDatumType()
+		if m.colTypes[i], err = parser.DatumTypeToColumnType(typ); err != nil {
+			return nil, err
+		}
+		resultColumns[i] = ResultColumn{Name: col.Name, Typ: typ}
+	}
+	return &copyNode{p: p, machine: m, resultColumns: resultColumns}, nil
+}
+
+func (n *copyNode) Start() error {
+	n.p.session.copyFrom = n.machine
+	return nil
+}
+
+func (n *copyNode) Next() (bool, error)                 { return false, nil }
+func (n *copyNode) Columns() []ResultColumn             { return n.resultColumns }
+func (n *copyNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *copyNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *copyNode) DebugValues() debugValues            { return debugValues{} }
+func (n *copyNode) ExplainTypes(_ func(string, string)) {}
+func (n *copyNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *copyNode) MarkDebug(mode explainMode)          {}
+func (n *copyNode) expandPlan() error                   { return nil }
+func (n *copyNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "copy", n.machine.table.String(), nil
+}
+
+// CopyFormat describes how the rows exported by a COPY TO statement are
+// formatted.
+type CopyFormat struct {
+	// CSV selects the CSV format instead of the text format of Postgres.
+	CSV bool
+	// Header, with CSV, requests a first line holding the column names.
+	Header bool
+}
+
+func makeCopyFormat(opts parser.CopyOptions) (CopyFormat, error) {
+	var f CopyFormat
+	switch strings.ToLower(opts.DataFormat) {
+	case "", "text":
+	case "csv":
+		f.CSV = true
+	default:
+		return f, fmt.Errorf("COPY format %q not recognized", opts.DataFormat)
+	}
+	if opts.Header && !f.CSV {
+		return f, fmt.Errorf("COPY HEADER is only available in CSV mode")
+	}
+	f.Header = opts.Header
+	return f, nil
+}
+
+// CopyTo plans a COPY TO statement. Its results are those of the query, or
+// of the table, it exports; they are sent to the client using the COPY
+// sub-protocol.
+// Privileges: SELECT on table.
+func (p *planner) CopyTo(n *parser.CopyTo, autoCommit bool) (planNode, error) {
+	if !n.Stdout {
+		return nil, fmt.Errorf("COPY TO is only supported to STDOUT")
+	}
+	if _, err := makeCopyFormat(n.Options); err != nil {
+		return nil, err
+	}
+	sel := n.Stmt
+	if sel == nil {
+		exprs := parser.SelectExprs{{Expr: parser.StarExpr()}}
+		if len(n.Columns) > 0 {
+			exprs = make(parser.SelectExprs, len(n.Columns))
+			for i, c := range n.Columns {
+				exprs[i].Expr = c
+			}
+		}
+		sel = &parser.Select{Select: &parser.SelectClause{
+			Exprs: exprs,
+			From:  &parser.From{Tables: parser.TableExprs{&n.Table}},
+		}}
+	}
+	return p.Select(sel, nil, autoCommit)
+}
+
+// copyMachine decodes the data sent by the client during a COPY FROM STDIN,
+// in the text format of Postgres: one row per line, with the values
+// separated by tabs. The decoded rows are buffered and turned into INSERT
+// statements, run by the executor like any other statement so that they
+// can be retried.
+type copyMachine struct {
+	table    *parser.TableName
+	columns  parser.UnresolvedNames
+	colTypes []parser.ColumnType
+
+	// buf holds the data received which doesn't form a complete line yet.
+	buf  bytes.Buffer
+	rows []*parser.Tuple
+	// done is set once the end-of-data marker is received.
+	done bool
+}
+
+// addData decodes the lines completed by the given data.
+func (m *copyMachine) addData(data []byte) error {
+	m.buf.Write(data)
+	for !m.done {
+		i := bytes.IndexByte(m.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		if err := m.addLine(m.buf.Bytes()[:i]); err != nil {
+			return err
+		}
+		m.buf.Next(i + 1)
+	}
+	return nil
+}
+
+// finish decodes the last line of the data, if it isn't terminated by a
+// newline.
+func (m *copyMachine) finish() error {
+	if m.done || m.buf.Len() == 0 {
+		return nil
+	}
+	err := m.addLine(m.buf.Bytes())
+	m.buf.Reset()
+	return err
+}
+
+func (m *copyMachine) addLine(line []byte) error {
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	if string(line) == `\.` {
+		m.done = true
+		return nil
+	}
+	parts := bytes.Split(line, []byte{'\t'})
+	if len(parts) != len(m.columns) {
+		return fmt.Errorf("expected %d values, got %d", len(m.columns), len(parts))
+	}
+	exprs := make(parser.Exprs, len(parts))
+	for i, part := range parts {
+		if string(part) == `\N` {
+			exprs[i] = parser.DNull
+			continue
+		}
+		s, err := decodeCopy(part)
+		if err != nil {
+			return err
+		}
+		exprs[i] = &parser.CastExpr{Expr: parser.NewDString(s), Type: m.colTypes[i]}
+	}
+	m.rows = append(m.rows, &parser.Tuple{Exprs: exprs})
+	return nil
+}
+
+// insertStmt returns the statement inserting the rows buffered so far, and
+// clears them.
+func (m *copyMachine) insertStmt() *parser.Insert {
+	rows := m.rows
+	m.rows = nil
+	return &parser.Insert{
+		Table:   &parser.NormalizableTableName{TableNameReference: m.table},
+		Columns: m.columns,
+		Rows:    &parser.Select{Select: &parser.ValuesClause{Tuples: rows}},
+	}
+}
+
+// decodeCopy unescapes a value of the text format of COPY. See
+// https://www.postgresql.org/docs/current/static/sql-copy.html.
+func decodeCopy(in []byte) (string, error) {
+	if bytes.IndexByte(in, '\\') < 0 {
+		return string(in), nil
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(in); i++ {
+		ch := in[i]
+		if ch != '\\' {
+			buf.WriteByte(ch)
+			continue
+		}
+		i++
+		if i == len(in) {
+			return "", fmt.Errorf("unterminated escape sequence in %q", in)
+		}
+		switch ch = in[i]; ch {
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 't':
+			buf.WriteByte('\t')
+		case 'v':
+			buf.WriteByte('\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			// Up to three octal digits.
+			j := i + 1
+			for j < len(in) && j < i+3 && in[j] >= '0' && in[j] <= '7' {
+				j++
+			}
+			v, err := strconv.ParseUint(string(in[i:j]), 8, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence in %q", in)
+			}
+			buf.WriteByte(byte(v))
+			i = j - 1
+		case 'x':
+			// Up to two hexadecimal digits.
+			j := i + 1
+			for j < len(in) && j < i+3 && isHexDigit(in[j]) {
+				j++
+			}
+			if j == i+1 {
+				// Not followed by a digit: a plain x.
+				buf.WriteByte(ch)
+				continue
+			}
+			v, err := strconv.ParseUint(string(in[i+1:j]), 16, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape sequence in %q", in)
+			}
+			buf.WriteByte(byte(v))
+			i = j - 1
+		default:
+			// Any other character, including a backslash, stands for itself.
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String(), nil
+}
+
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// CopyData hands data sent by the client during a COPY FROM STDIN to the
+// session's copyMachine, and inserts the rows it decodes once enough of them
+// are buffered.
+func (e *Executor) CopyData(session *Session, data []byte) StatementResults {
+	m := session.copyFrom
+	if m == nil {
+		return copyErrorResults(fmt.Errorf("no COPY in progress"))
+	}
+	if err := m.addData(data); err != nil {
+		return e.abortCopy(session, err)
+	}
+	if len(m.rows) < copyBatchRowSize {
+		return StatementResults{}
+	}
+	return e.execCopyBatch(session)
+}
+
+// CopyDone inserts the remaining rows of the COPY in progress in the
+// session, once the client has sent all its data.
+func (e *Executor) CopyDone(session *Session) StatementResults {
+	m := session.copyFrom
+	if m == nil {
+		return copyErrorResults(fmt.Errorf("no COPY in progress"))
+	}
+	if err := m.finish(); err != nil {
+		return e.abortCopy(session, err)
+	}
+	if len(m.rows) == 0 {
+		return StatementResults{}
+	}
+	return e.execCopyBatch(session)
+}
+
+func (e *Executor) execCopyBatch(session *Session) StatementResults {
+	session.planner.resetForBatch(e)
+	return e.execParsed(session, parser.StatementList{session.copyFrom.insertStmt()})
+}
+
+// abortCopy reports an error in the data of a COPY, which aborts the
+// transaction it is run in, if any.
+func (e *Executor) abortCopy(session *Session, err error) StatementResults {
+	if session.TxnState.txn != nil {
+		session.TxnState.updateStateAndCleanupOnErr(err, e)
+	}
+	return copyErrorResults(err)
+}
+
+func copyErrorResults(err error) StatementResults {
+	return StatementResults{ResultList: ResultList{{Err: err}}}
+}
+
+// CopyEnd ends the COPY in progress in the session, if any.
+func (s *Session) CopyEnd() {
+	s.copyFrom = nil
+	s.startIdleTxnTimer()
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestDecodeCopy(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		in, expected string
+	}{
+		{`abc`, "abc"},
+		{`a\tb\nc\\`, "a\tb\nc\\"},
+		{`\b\f\r\v`, "\b\f\r\v"},
+		{`\101\0618`, "A18"},
+		{`\x41\x4a\x4`, "AJ\x04"},
+		{`\xz\q`, "xzq"},
+	}
+	for _, tc := range testCases {
+		out, err := decodeCopy([]byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+		} else if out != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.in, tc.expected, out)
+		}
+	}
+	if _, err := decodeCopy([]byte(`abc\`)); err == nil {
+		t.Error("expected an error for an unterminated escape sequence")
+	}
+}
+
+func TestCopyMachine(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := &copyMachine{
+		table:    &parser.TableName{DatabaseName: "d", TableName: "t"},
+		columns:  parser.UnresolvedNames{{parser.Name("a")}, {parser.Name("b")}},
+		colTypes: []parser.ColumnType{&parser.IntColType{Name: "INT"}, &parser.StringColType{Name: "STRING"}},
+	}
+	// Lines may be split across messages.
+	for _, data := range []string{"1\tx\n2\t", "\\N\n", "3\ta\\tb\r\n4\tz"} {
+		if err := m.addData([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(m.rows))
+	}
+	if err := m.finish(); err != nil {
+		t.Fatal(err)
+	}
+	var values [][]string
+	for _, row := range m.rows {
+		var v []string
+		for _, e := range row.Exprs {
+			if c, ok := e.(*parser.CastExpr); ok {
+				v = append(v, string(*c.Expr.(*parser.DString)))
+			} else {
+				v = append(v, e.String())
+			}
+		}
+		values = append(values, v)
+	}
+	expected := [][]string{{"1", "x"}, {"2", "NULL"}, {"3", "a\tb"}, {"4", "z"}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %q, got %q", expected, values)
+	}
+	ins := m.insertStmt()
+	if rows := ins.Rows.Select.(*parser.ValuesClause).Tuples; len(rows) != 4 {
+		t.Errorf("expected 4 rows to insert, got %d", len(rows))
+	}
+	if len(m.rows) != 0 {
+		t.Errorf("expected the rows to be cleared, got %d", len(m.rows))
+	}
+
+	// The data after the end marker is ignored.
+	if err := m.addData([]byte("5\ty\n\\.\n6\tw\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.finish(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.rows) != 1 {
+		t.Errorf("expected 1 row, got %d", len(m.rows))
+	}
+
+	m = &copyMachine{columns: m.columns, colTypes: m.colTypes}
+	if err := m.addData([]byte("1\tx\ty\n")); !testutils.IsError(err, "expected 2 values, got 3") {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
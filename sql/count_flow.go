@@ -0,0 +1,113 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"io"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/distsql"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/pkg/errors"
+)
+
+// The bulk operations (IMPORT, index backfills) run on several nodes as flows
+// made of a single processor which does all the work and outputs a single row
+// holding a count, such as the number of rows it processed.
+
+// makeCountFlowRequest returns the request for a flow made of a single
+// processor with the given core, whose output is sent back in the response.
+func makeCountFlowRequest(
+	txn roachpb.Transaction, core distsql.ProcessorCoreUnion,
+) *distsql.SetupFlowRequest {
+	return &distsql.SetupFlowRequest{
+		Txn: txn,
+		Flow: distsql.FlowSpec{
+			Processors: []distsql.ProcessorSpec{{
+				Core: core,
+				Output: []distsql.OutputRouterSpec{{
+					Type: distsql.OutputRouterSpec_MIRROR,
+					Streams: []distsql.StreamEndpointSpec{{
+						Mailbox: &distsql.MailboxSpec{SimpleResponse: true},
+					}},
+				}},
+			}},
+		},
+	}
+}
+
+// runLocalCountFlow runs a count flow on this node and returns its count.
+func runLocalCountFlow(srv *distsql.ServerImpl, req *distsql.SetupFlowRequest) (int64, error) {
+	rb := new(distsql.RowBuffer)
+	flow, err := srv.SetupSimpleFlow(context.Background(), req, rb)
+	if err != nil {
+		return 0, err
+	}
+	flow.RunSync()
+	return decodeFlowCount(rb.NextRow())
+}
+
+// runRemoteCountFlow runs a count flow on the node at addr and returns its
+// count.
+func runRemoteCountFlow(
+	srv *distsql.ServerImpl, addr string, req *distsql.SetupFlowRequest,
+) (int64, error) {
+	conn, err := srv.RPCContext.GRPCDial(addr)
+	if err != nil {
+		return 0, err
+	}
+	stream, err := distsql.NewDistSQLClient(conn).RunSimpleFlow(context.Background(), req)
+	if err != nil {
+		return 0, err
+	}
+	var decoder distsql.StreamDecoder
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := decoder.AddMessage(msg); err != nil {
+			return 0, err
+		}
+	}
+	if done, err := decoder.IsDone(); !done {
+		return 0, errors.Errorf("flow on %s ended early", addr)
+	} else if err != nil {
+		return 0, err
+	}
+	return decodeFlowCount(decoder.GetRow(nil))
+}
+
+// decodeFlowCount decodes the single row, holding a count, produced by the
+// processor of a count flow.
+func decodeFlowCount(row sqlbase.EncDatumRow, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	if len(row) != 1 {
+		return 0, errors.Errorf("unexpected flow result %s", row)
+	}
+	var alloc sqlbase.DatumAlloc
+	if err := row[0].Decode(&alloc); err != nil {
+		return 0, err
+	}
+	return int64(*row[0].Datum.(*parser.DInt)), nil
+}
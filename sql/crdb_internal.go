@@ -0,0 +1,543 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/build"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/server/serverpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// crdbInternal is a virtual schema exposing CockroachDB-specific
+// introspection data that has no equivalent in information_schema.
+var crdbInternal = virtualSchema{
+	name: "crdb_internal",
+	tables: []virtualSchemaTable{
+		crdbInternalBuildInfoTable,
+		crdbInternalGossipInfosTable,
+		crdbInternalHotRangesTable,
+		crdbInternalIndexContentionTable,
+		crdbInternalLeasesTable,
+		crdbInternalRuntimeInfoTable,
+		crdbInternalSchemaChangesTable,
+		crdbInternalStmtStatsTable,
+		crdbInternalTableSpanStatsTable,
+	},
+}
+
+// requireRoot returns an error unless the session's user is root. It guards
+// the tables exposing node internals that are not scoped by privileges.
+func requireRoot(p *planner, table string) error {
+	if p.session.User != security.RootUser {
+		return errors.Errorf("only %s is allowed to read crdb_internal.%s", security.RootUser, table)
+	}
+	return nil
+}
+
+func durationToSeconds(d time.Duration) parser.Datum {
+	return parser.NewDFloat(parser.DFloat(d.Seconds()))
+}
+
+// crdbInternalStmtStatsTable exposes the statement statistics accumulated by
+// the executor of the node serving the query.
+var crdbInternalStmtStatsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_statement_statistics (
+  node_id         INT NOT NULL,
+  key             STRING NOT NULL,
+  count           INT NOT NULL,
+  retry_count     INT NOT NULL,
+  error_count     INT NOT NULL,
+  rows_affected   INT NOT NULL,
+  service_lat_avg FLOAT NOT NULL,
+  service_lat_max FLOAT NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if p.sqlStats == nil {
+			return nil
+		}
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		for _, e := range p.sqlStats.entries() {
+			addRow(
+				nodeID,                           // node_id
+				parser.NewDString(e.Fingerprint), // key
+				parser.NewDInt(parser.DInt(e.Stats.Count)),        // count
+				parser.NewDInt(parser.DInt(e.Stats.RetryCount)),   // retry_count
+				parser.NewDInt(parser.DInt(e.Stats.ErrorCount)),   // error_count
+				parser.NewDInt(parser.DInt(e.Stats.RowsAffected)), // rows_affected
+				durationToSeconds(e.Stats.MeanLatency()),          // service_lat_avg
+				durationToSeconds(e.Stats.MaxLatency),             // service_lat_max
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalBuildInfoTable exposes the build information of the node
+// serving the query.
+var crdbInternalBuildInfoTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_build_info (
+  node_id INT NOT NULL,
+  field   STRING NOT NULL,
+  value   STRING NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		info := build.GetInfo()
+		for _, kv := range [][2]string{
+			{"GoVersion", info.GoVersion},
+			{"Tag", info.Tag},
+			{"Time", info.Time},
+			{"Dependencies", info.Dependencies},
+			{"CgoCompiler", info.CgoCompiler},
+			{"Platform", info.Platform},
+		} {
+			addRow(
+				nodeID,                   // node_id
+				parser.NewDString(kv[0]), // field
+				parser.NewDString(kv[1]), // value
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalRuntimeInfoTable exposes runtime statistics of the process of
+// the node serving the query.
+var crdbInternalRuntimeInfoTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_runtime_info (
+  node_id INT NOT NULL,
+  field   STRING NOT NULL,
+  value   INT NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		for _, kv := range []struct {
+			field string
+			value int64
+		}{
+			{"goroutines", int64(runtime.NumGoroutine())},
+			{"gomaxprocs", int64(runtime.GOMAXPROCS(0))},
+			{"cpus", int64(runtime.NumCPU())},
+			{"cgo_calls", runtime.NumCgoCall()},
+			{"heap_alloc_bytes", int64(ms.HeapAlloc)},
+			{"heap_objects", int64(ms.HeapObjects)},
+			{"total_alloc_bytes", int64(ms.TotalAlloc)},
+			{"sys_bytes", int64(ms.Sys)},
+			{"gc_count", int64(ms.NumGC)},
+			{"gc_pause_total_ns", int64(ms.PauseTotalNs)},
+		} {
+			addRow(
+				nodeID,                                // node_id
+				parser.NewDString(kv.field),           // field
+				parser.NewDInt(parser.DInt(kv.value)), // value
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalIndexContentionTable exposes the contention statistics of the
+// stores of the node serving the query, aggregated by index. Only the indexes
+// of the tables visible to the user are listed.
+var crdbInternalIndexContentionTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_index_contention (
+  node_id                    INT NOT NULL,
+  table_id                   INT NOT NULL,
+  index_id                   INT NOT NULL,
+  database_name              STRING NOT NULL,
+  table_name                 STRING NOT NULL,
+  index_name                 STRING,
+  num_contention_events      INT NOT NULL,
+  cumulative_contention_time FLOAT NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if p.execCtx == nil || p.execCtx.StatusServer == nil {
+			return nil
+		}
+		resp, err := p.execCtx.StatusServer.ContentionEvents(p.ctx(), &serverpb.ContentionEventsRequest{
+			NodeId: "local",
+		})
+		if err != nil {
+			return err
+		}
+		type tableEntry struct {
+			dbName string
+			desc   *sqlbase.TableDescriptor
+		}
+		tables := make(map[sqlbase.ID]tableEntry)
+		if err := forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				tables[table.ID] = tableEntry{dbName: db.Name, desc: table}
+			},
+		); err != nil {
+			return err
+		}
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		for _, stats := range resp.Indexes {
+			table, ok := tables[sqlbase.ID(stats.TableID)]
+			if !ok {
+				continue
+			}
+			indexName := parser.DNull
+			if index, err := table.desc.FindIndexByID(sqlbase.IndexID(stats.IndexID)); err == nil {
+				indexName = parser.NewDString(index.Name)
+			}
+			contentionTime := time.Duration(stats.CumulativeContentionNanos)
+			addRow(
+				nodeID, // node_id
+				parser.NewDInt(parser.DInt(stats.TableID)),             // table_id
+				parser.NewDInt(parser.DInt(stats.IndexID)),             // index_id
+				parser.NewDString(table.dbName),                        // database_name
+				parser.NewDString(table.desc.Name),                     // table_name
+				indexName,                                              // index_name
+				parser.NewDInt(parser.DInt(stats.NumContentionEvents)), // num_contention_events
+				durationToSeconds(contentionTime),                      // cumulative_contention_time
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalHotRangesTable exposes the replicas of the stores of the node
+// serving the query which serve the most requests, along with the table and
+// index each of their ranges starts in, when the table is visible to the user.
+var crdbInternalHotRangesTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.node_hot_ranges (
+  node_id            INT NOT NULL,
+  store_id           INT NOT NULL,
+  range_id           INT NOT NULL,
+  start_key          STRING NOT NULL,
+  end_key            STRING NOT NULL,
+  database_name      STRING,
+  table_name         STRING,
+  index_name         STRING,
+  queries_per_second FLOAT NOT NULL,
+  writes_per_second  FLOAT NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if err := requireRoot(p, "node_hot_ranges"); err != nil {
+			return err
+		}
+		if p.execCtx == nil || p.execCtx.StatusServer == nil {
+			return nil
+		}
+		resp, err := p.execCtx.StatusServer.HotRanges(p.ctx(), &serverpb.HotRangesRequest{
+			NodeId: "local",
+		})
+		if err != nil {
+			return err
+		}
+		type tableEntry struct {
+			dbName string
+			desc   *sqlbase.TableDescriptor
+		}
+		tables := make(map[sqlbase.ID]tableEntry)
+		if err := forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				tables[table.ID] = tableEntry{dbName: db.Name, desc: table}
+			},
+		); err != nil {
+			return err
+		}
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		for _, hot := range resp.Ranges {
+			dbName, tableName, indexName := parser.DNull, parser.DNull, parser.DNull
+			if table, ok := tables[sqlbase.ID(hot.TableID)]; ok {
+				dbName = parser.NewDString(table.dbName)
+				tableName = parser.NewDString(table.desc.Name)
+				if index, err := table.desc.FindIndexByID(sqlbase.IndexID(hot.IndexID)); err == nil {
+					indexName = parser.NewDString(index.Name)
+				}
+			}
+			addRow(
+				nodeID,                                   // node_id
+				parser.NewDInt(parser.DInt(hot.StoreID)), // store_id
+				parser.NewDInt(parser.DInt(hot.RangeID)), // range_id
+				parser.NewDString(hot.StartKey),          // start_key
+				parser.NewDString(hot.EndKey),            // end_key
+				dbName,                                   // database_name
+				tableName,                                // table_name
+				indexName,                                // index_name
+				parser.NewDFloat(parser.DFloat(hot.QueriesPerSecond)), // queries_per_second
+				parser.NewDFloat(parser.DFloat(hot.WritesPerSecond)),  // writes_per_second
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalGossipInfosTable exposes the contents of the gossip network as
+// seen by the node serving the query.
+var crdbInternalGossipInfosTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.gossip_infos (
+  node_id        INT NOT NULL,
+  key            STRING NOT NULL,
+  value_type     STRING NOT NULL,
+  origin_node_id INT NOT NULL,
+  peer_node_id   INT NOT NULL,
+  hops           INT NOT NULL,
+  orig_stamp     TIMESTAMP NOT NULL,
+  ttl_stamp      TIMESTAMP
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if err := requireRoot(p, "gossip_infos"); err != nil {
+			return err
+		}
+		if p.execCtx == nil || p.execCtx.Gossip == nil {
+			return nil
+		}
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		infos := p.execCtx.Gossip.GetInfoStatus().Infos
+		infoKeys := make([]string, 0, len(infos))
+		for key := range infos {
+			infoKeys = append(infoKeys, key)
+		}
+		sort.Strings(infoKeys)
+		for _, key := range infoKeys {
+			info := infos[key]
+			ttl := parser.DNull
+			if info.TTLStamp != 0 {
+				ttl = parser.MakeDTimestamp(time.Unix(0, info.TTLStamp).UTC(), time.Microsecond)
+			}
+			addRow(
+				nodeID,                 // node_id
+				parser.NewDString(key), // key
+				parser.NewDString(info.Value.GetTag().String()),                             // value_type
+				parser.NewDInt(parser.DInt(info.NodeID)),                                    // origin_node_id
+				parser.NewDInt(parser.DInt(info.PeerID)),                                    // peer_node_id
+				parser.NewDInt(parser.DInt(info.Hops)),                                      // hops
+				parser.MakeDTimestamp(time.Unix(0, info.OrigStamp).UTC(), time.Microsecond), // orig_stamp
+				ttl, // ttl_stamp
+			)
+		}
+		return nil
+	},
+}
+
+// crdbInternalLeasesTable exposes the table descriptor leases held by the
+// node serving the query.
+var crdbInternalLeasesTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.leases (
+  node_id    INT NOT NULL,
+  table_id   INT NOT NULL,
+  name       STRING NOT NULL,
+  parent_id  INT NOT NULL,
+  version    INT NOT NULL,
+  expiration TIMESTAMP NOT NULL,
+  refcount   INT NOT NULL,
+  released   BOOL NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if p.leaseMgr == nil {
+			return nil
+		}
+		nodeID := parser.NewDInt(parser.DInt(p.evalCtx.NodeID))
+		p.leaseMgr.visitLeases(func(lease *LeaseState, refcount int, released bool) {
+			if !userCanSeeTable(&lease.TableDescriptor, p.session.User) {
+				return
+			}
+			expiration := lease.expiration
+			addRow(
+				nodeID,                                      // node_id
+				parser.NewDInt(parser.DInt(lease.ID)),       // table_id
+				parser.NewDString(lease.Name),               // name
+				parser.NewDInt(parser.DInt(lease.ParentID)), // parent_id
+				parser.NewDInt(parser.DInt(lease.Version)),  // version
+				&expiration,                                 // expiration
+				parser.NewDInt(parser.DInt(refcount)),       // refcount
+				parser.MakeDBool(parser.DBool(released)),    // released
+			)
+		})
+		return nil
+	},
+}
+
+// crdbInternalSchemaChangesTable exposes the schema changes which are in
+// progress, which are the only long-running jobs performed by the cluster.
+var crdbInternalSchemaChangesTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.schema_changes (
+  table_id      INT NOT NULL,
+  database_name STRING NOT NULL,
+  table_name    STRING NOT NULL,
+  mutation_id   INT NOT NULL,
+  type          STRING NOT NULL,
+  target_name   STRING NOT NULL,
+  direction     STRING NOT NULL,
+  state         STRING NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				for _, m := range table.Mutations {
+					var typ, target string
+					if col := m.GetColumn(); col != nil {
+						typ, target = "COLUMN", col.Name
+					} else if idx := m.GetIndex(); idx != nil {
+						typ, target = "INDEX", idx.Name
+					}
+					addRow(
+						parser.NewDInt(parser.DInt(table.ID)),     // table_id
+						parser.NewDString(db.Name),                // database_name
+						parser.NewDString(table.Name),             // table_name
+						parser.NewDInt(parser.DInt(m.MutationID)), // mutation_id
+						parser.NewDString(typ),                    // type
+						parser.NewDString(target),                 // target_name
+						parser.NewDString(m.Direction.String()),   // direction
+						parser.NewDString(m.State.String()),       // state
+					)
+				}
+			},
+		)
+	},
+}
+
+// crdbInternalTableSpanStatsTable exposes the key span of every table along
+// with the ranges it is split into.
+var crdbInternalTableSpanStatsTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE crdb_internal.table_span_stats (
+  table_id      INT NOT NULL,
+  database_name STRING NOT NULL,
+  table_name    STRING NOT NULL,
+  start_key     STRING NOT NULL,
+  end_key       STRING NOT NULL,
+  range_count   INT NOT NULL,
+  replica_count INT NOT NULL,
+  node_count    INT NOT NULL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		if p.execCtx == nil || p.execCtx.DB == nil {
+			return nil
+		}
+		var tables []*sqlbase.TableDescriptor
+		var dbNames []string
+		if err := forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if isVirtualDescriptor(table) || table.IsView() {
+					return
+				}
+				tables = append(tables, table)
+				dbNames = append(dbNames, db.Name)
+			},
+		); err != nil {
+			return err
+		}
+		for i, table := range tables {
+			start := roachpb.Key(keys.MakeTablePrefix(uint32(table.ID)))
+			end := start.PrefixEnd()
+			ranges, err := lookupRangeDescriptors(p.execCtx.DB, start, end)
+			if err != nil {
+				return err
+			}
+			replicas := 0
+			nodeIDs := make(map[roachpb.NodeID]struct{})
+			for _, rng := range ranges {
+				replicas += len(rng.Replicas)
+				for _, repl := range rng.Replicas {
+					nodeIDs[repl.NodeID] = struct{}{}
+				}
+			}
+			addRow(
+				parser.NewDInt(parser.DInt(table.ID)),     // table_id
+				parser.NewDString(dbNames[i]),             // database_name
+				parser.NewDString(table.Name),             // table_name
+				parser.NewDString(start.String()),         // start_key
+				parser.NewDString(end.String()),           // end_key
+				parser.NewDInt(parser.DInt(len(ranges))),  // range_count
+				parser.NewDInt(parser.DInt(replicas)),     // replica_count
+				parser.NewDInt(parser.DInt(len(nodeIDs))), // node_count
+			)
+		}
+		return nil
+	},
+}
+
+// lookupRangeDescriptors returns the descriptors of the ranges overlapping the
+// span [start, end), by scanning the meta2 records addressing it.
+func lookupRangeDescriptors(
+	db *client.DB, start, end roachpb.Key,
+) ([]roachpb.RangeDescriptor, error) {
+	startKey, err := keys.Addr(start)
+	if err != nil {
+		return nil, err
+	}
+	endKey, err := keys.Addr(end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Range descriptors are addressed by their end key, so the ranges
+	// overlapping the span are those with an end key in (start, end], plus
+	// the first one past it if the last range doesn't end at end.
+	kvs, err := db.Scan(keys.RangeMetaKey(startKey).Next(), keys.RangeMetaKey(endKey).Next(), 0)
+	if err != nil {
+		return nil, err
+	}
+	ranges := make([]roachpb.RangeDescriptor, len(kvs))
+	for i, kv := range kvs {
+		if err := kv.Value.GetProto(&ranges[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(ranges) > 0 && bytes.Equal(ranges[len(ranges)-1].EndKey, endKey) {
+		return ranges, nil
+	}
+	kvs, err = db.Scan(keys.RangeMetaKey(endKey).Next(), keys.Meta2KeyMax, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		var rng roachpb.RangeDescriptor
+		if err := kv.Value.GetProto(&rng); err != nil {
+			return nil, err
+		}
+		if bytes.Compare(rng.StartKey, endKey) >= 0 {
+			break
+		}
+		ranges = append(ranges, rng)
+	}
+	return ranges, nil
+}
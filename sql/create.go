@@ -19,6 +19,8 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 
 	"golang.org/x/net/context"
@@ -129,6 +131,9 @@ func (p *planner) CreateIndex(n *parser.CreateIndex) (planNode, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !tableDesc.IsTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
 
 	if err := p.checkPrivilege(tableDesc, privilege.CREATE); err != nil {
 		return nil, err
@@ -142,6 +147,9 @@ func (n *createIndexNode) expandPlan() error {
 }
 
 func (n *createIndexNode) Start() error {
+	if n.tableDesc.IsPrimaryKeyChanging() {
+		return primaryKeyChangingError(n.tableDesc)
+	}
 	status, i, err := n.tableDesc.FindIndexByName(n.n.Name)
 	if err == nil {
 		if status == sqlbase.DescriptorIncomplete {
@@ -163,9 +171,32 @@ func (n *createIndexNode) Start() error {
 		Unique:           n.n.Unique,
 		StoreColumnNames: n.n.Storing.ToStrings(),
 	}
+	if n.n.Inverted {
+		indexDesc.Type = sqlbase.IndexDescriptor_INVERTED
+	}
 	if err := indexDesc.FillColumns(n.n.Columns); err != nil {
 		return err
 	}
+	if err := checkIndexColumnsNotBeingReplaced(n.tableDesc, &indexDesc); err != nil {
+		return err
+	}
+	if n.n.Predicate != nil {
+		s := n.n.Predicate.String()
+		indexDesc.Predicate = &s
+		pred, err := makeIndexPredicate(n.tableDesc, &indexDesc)
+		if err != nil {
+			return err
+		}
+		for _, id := range pred.columnIDs() {
+			if isColumnBeingReplaced(n.tableDesc, id) {
+				col, err := n.tableDesc.FindActiveColumnByID(id)
+				if err != nil {
+					return err
+				}
+				return columnBeingReplacedError(col.Name)
+			}
+		}
+	}
 
 	mutationIdx := len(n.tableDesc.Mutations)
 	n.tableDesc.AddIndexMutation(indexDesc, sqlbase.DescriptorMutation_ADD)
@@ -187,6 +218,16 @@ func (n *createIndexNode) Start() error {
 		}
 	}
 
+	if n.n.PartitionBy != nil {
+		index := n.tableDesc.Mutations[mutationIdx].GetIndex()
+		if err := n.p.addPartitioning(n.tableDesc, index, n.n.PartitionBy); err != nil {
+			return err
+		}
+		if err := n.tableDesc.ValidateTable(); err != nil {
+			return err
+		}
+	}
+
 	if err := n.p.txn.Put(
 		sqlbase.MakeDescMetadataKey(n.tableDesc.GetID()),
 		sqlbase.WrapDescriptor(n.tableDesc)); err != nil {
@@ -309,6 +350,12 @@ func (n *createTableNode) Start() error {
 	// Inherit permissions from the database descriptor.
 	desc.Privileges = n.dbDesc.GetPrivileges()
 
+	if n.n.Temporary {
+		if desc.Temporary, err = n.p.makeTemporaryInfo(); err != nil {
+			return err
+		}
+	}
+
 	if len(desc.PrimaryIndex.ColumnNames) == 0 {
 		// Ensure a Primary Key exists.
 		s := "unique_rowid()"
@@ -348,6 +395,12 @@ func (n *createTableNode) Start() error {
 		}
 	}
 
+	if n.n.PartitionBy != nil {
+		if err := n.p.addPartitioning(&desc, &desc.PrimaryIndex, n.n.PartitionBy); err != nil {
+			return err
+		}
+	}
+
 	// FKs are resolved after the descriptor is otherwise complete and IDs have
 	// been allocated since the FKs will reference those IDs. Resolution also
 	// accumulated updates to other tables (adding backreferences) in the passed
@@ -361,14 +414,14 @@ func (n *createTableNode) Start() error {
 				if d.References.Col != "" {
 					targetCol = append(targetCol, d.References.Col)
 				}
-				err := n.resolveFK(&desc, parser.NameList{d.Name},
-					d.References.Table.TableName(), targetCol, d.References.ConstraintName, affected)
+				err := n.resolveFK(&desc, parser.NameList{d.Name}, d.References.Table.TableName(),
+					targetCol, d.References.ConstraintName, d.References.Actions, affected)
 				if err != nil {
 					return err
 				}
 			}
 		case *parser.ForeignKeyConstraintTableDef:
-			err := n.resolveFK(&desc, d.FromCols, d.Table.TableName(), d.ToCols, d.Name, affected)
+			err := n.resolveFK(&desc, d.FromCols, d.Table.TableName(), d.ToCols, d.Name, d.Actions, affected)
 			if err != nil {
 				return err
 			}
@@ -408,6 +461,9 @@ func (n *createTableNode) Start() error {
 				return err
 			}
 		}
+		if desc.IsTemporary() {
+			n.p.session.tempTableIDs = append(n.p.session.tempTableIDs, desc.ID)
+		}
 		if desc.Adding() {
 			n.p.notifySchemaChange(desc.ID, sqlbase.InvalidMutationID)
 		}
@@ -455,6 +511,274 @@ func (n *createTableNode) ExplainPlan(v bool) (string, string, []planNode) {
 	return "create table", "", nil
 }
 
+type createViewNode struct {
+	p           *planner
+	n           *parser.CreateView
+	dbDesc      *sqlbase.DatabaseDescriptor
+	columns     []ResultColumn
+	sourceQuery string
+	planDeps    map[sqlbase.ID]struct{}
+}
+
+// CreateView creates a view.
+// Privileges: CREATE on database plus SELECT on all the selected tables.
+//   Notes: postgres requires CREATE on database plus SELECT on all the
+//          selected columns.
+//          mysql requires CREATE VIEW plus SELECT on all the selected columns.
+func (p *planner) CreateView(n *parser.CreateView) (planNode, error) {
+	tn, err := n.Name.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	dbDesc, err := p.mustGetDatabaseDesc(tn.Database())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.checkPrivilege(dbDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+
+	// Plan the query to validate it and to find the tables and views it
+	// depends on. Planning also qualifies the table names in the query with
+	// their database, so that the stored query does not depend on the
+	// session's database.
+	planDeps := make(map[sqlbase.ID]struct{})
+	p.planDeps, p.hasStar = planDeps, false
+	sourcePlan, err := p.Select(n.AsSource, nil, false)
+	hasStar := p.hasStar
+	p.planDeps, p.hasStar = nil, false
+	if err != nil {
+		return nil, err
+	}
+	if hasStar {
+		return nil, errors.Errorf("views do not support * expressions")
+	}
+
+	columns := sourcePlan.Columns()
+	if numColNames := len(n.ColumnNames); numColNames > 0 && numColNames != len(columns) {
+		return nil, errors.Errorf("CREATE VIEW specifies %d column name%s, but data source has %d column%s",
+			numColNames, util.Pluralize(int64(numColNames)),
+			len(columns), util.Pluralize(int64(len(columns))))
+	}
+
+	return &createViewNode{
+		p:           p,
+		n:           n,
+		dbDesc:      dbDesc,
+		columns:     columns,
+		sourceQuery: parser.AsStringWithFlags(n.AsSource, parser.FmtQualifyTableNames),
+		planDeps:    planDeps,
+	}, nil
+}
+
+func (n *createViewNode) expandPlan() error {
+	return nil
+}
+
+func (n *createViewNode) Start() error {
+	desc, err := makeViewTableDesc(n.n, n.columns, n.dbDesc.ID)
+	if err != nil {
+		return err
+	}
+	desc.ViewQuery = n.sourceQuery
+
+	tableKey := tableKey{parentID: n.dbDesc.ID, name: n.n.Name.TableName().Table()}
+	key := tableKey.Key()
+	if exists, err := n.p.descExists(key); err == nil && exists {
+		return descriptorAlreadyExistsErr{&desc, tableKey.Name()}
+	} else if err != nil {
+		return err
+	}
+
+	// Inherit permissions from the database descriptor.
+	desc.Privileges = n.dbDesc.GetPrivileges()
+	for id := range n.planDeps {
+		desc.DependsOn = append(desc.DependsOn, id)
+	}
+	sort.Sort(sqlbase.IDs(desc.DependsOn))
+
+	id, err := n.p.generateUniqueDescID()
+	if err != nil {
+		return err
+	}
+	desc.SetID(id)
+
+	if err := desc.AllocateIDs(); err != nil {
+		return err
+	}
+	if err := desc.ValidateTable(); err != nil {
+		return err
+	}
+
+	created, err := n.p.createDescriptorWithID(key, id, &desc)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		// Add back-references to the new view from the tables and views it
+		// depends on, so that they cannot be dropped from under it.
+		for _, depID := range desc.DependsOn {
+			dependency, err := sqlbase.GetTableDescFromID(n.p.txn, depID)
+			if err != nil {
+				return err
+			}
+			dependency.DependedOnBy = append(dependency.DependedOnBy, desc.ID)
+			if err := n.p.saveNonmutationAndNotify(dependency); err != nil {
+				return err
+			}
+		}
+
+		if err := desc.Validate(n.p.txn); err != nil {
+			return err
+		}
+
+		// Log Create View event. This is an auditable log event and is
+		// recorded in the same transaction as the table descriptor update.
+		if err := MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+			EventLogCreateView,
+			int32(desc.ID),
+			int32(n.p.evalCtx.NodeID),
+			struct {
+				ViewName  string
+				Statement string
+				User      string
+			}{n.n.Name.String(), n.n.String(), n.p.session.User},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *createViewNode) Next() (bool, error)                 { return false, nil }
+func (n *createViewNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *createViewNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *createViewNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *createViewNode) DebugValues() debugValues            { return debugValues{} }
+func (n *createViewNode) ExplainTypes(_ func(string, string)) {}
+func (n *createViewNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *createViewNode) MarkDebug(mode explainMode)          {}
+func (n *createViewNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "create view", "", nil
+}
+
+type createSequenceNode struct {
+	p      *planner
+	n      *parser.CreateSequence
+	dbDesc *sqlbase.DatabaseDescriptor
+}
+
+// CreateSequence creates a sequence.
+// Privileges: CREATE on database.
+//   Notes: postgres requires CREATE on database.
+func (p *planner) CreateSequence(n *parser.CreateSequence) (planNode, error) {
+	tn, err := n.Name.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	dbDesc, err := p.mustGetDatabaseDesc(tn.Database())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.checkPrivilege(dbDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+
+	return &createSequenceNode{p: p, n: n, dbDesc: dbDesc}, nil
+}
+
+func (n *createSequenceNode) expandPlan() error {
+	return nil
+}
+
+func (n *createSequenceNode) Start() error {
+	desc, err := makeSequenceTableDesc(n.n, n.dbDesc.ID)
+	if err != nil {
+		return err
+	}
+
+	tableKey := tableKey{parentID: n.dbDesc.ID, name: n.n.Name.TableName().Table()}
+	key := tableKey.Key()
+	if exists, err := n.p.descExists(key); err == nil && exists {
+		if n.n.IfNotExists {
+			return nil
+		}
+		return descriptorAlreadyExistsErr{&desc, tableKey.Name()}
+	} else if err != nil {
+		return err
+	}
+
+	// Inherit permissions from the database descriptor.
+	desc.Privileges = n.dbDesc.GetPrivileges()
+
+	id, err := n.p.generateUniqueDescID()
+	if err != nil {
+		return err
+	}
+	desc.SetID(id)
+
+	if err := desc.AllocateIDs(); err != nil {
+		return err
+	}
+	if err := desc.ValidateTable(); err != nil {
+		return err
+	}
+
+	created, err := n.p.createDescriptorWithID(key, id, &desc)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		// Initialize the value of the sequence so that the first call to
+		// nextval() returns the start value.
+		opts := desc.SequenceOpts
+		if err := n.p.txn.Put(
+			sqlbase.MakeSequenceKey(desc.ID), opts.Start-opts.Increment); err != nil {
+			return err
+		}
+
+		if err := desc.Validate(n.p.txn); err != nil {
+			return err
+		}
+
+		// Log Create Sequence event. This is an auditable log event and is
+		// recorded in the same transaction as the table descriptor update.
+		if err := MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+			EventLogCreateSequence,
+			int32(desc.ID),
+			int32(n.p.evalCtx.NodeID),
+			struct {
+				SequenceName string
+				Statement    string
+				User         string
+			}{n.n.Name.String(), n.n.String(), n.p.session.User},
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *createSequenceNode) Next() (bool, error)                 { return false, nil }
+func (n *createSequenceNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *createSequenceNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *createSequenceNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *createSequenceNode) DebugValues() debugValues            { return debugValues{} }
+func (n *createSequenceNode) ExplainTypes(_ func(string, string)) {}
+func (n *createSequenceNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *createSequenceNode) MarkDebug(mode explainMode)          {}
+func (n *createSequenceNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "create sequence", "", nil
+}
+
 // resolveFK looks up the tables and columns mentioned in a `REFERENCES`
 // constraint and adds metadata representing that constraint to the descriptor.
 // It may, in doing so, add to or alter descriptors in the passed in `backrefs`
@@ -465,6 +789,7 @@ func (n *createTableNode) resolveFK(
 	targetTable *parser.TableName,
 	targetColNames parser.NameList,
 	constraintName parser.Name,
+	actions parser.ReferenceActions,
 	backrefs map[sqlbase.ID]*sqlbase.TableDescriptor,
 ) error {
 	target, err := n.p.getTableDesc(targetTable)
@@ -480,6 +805,12 @@ func (n *createTableNode) resolveFK(
 		} else {
 			return fmt.Errorf("referenced table %q not found", targetTable.String())
 		}
+	} else if !target.IsTable() {
+		return sqlbase.NewWrongObjectTypeError(targetTable.String(), "table")
+	} else if tbl.IsTemporary() && !target.IsTemporary() {
+		return errors.New("constraints on temporary tables may reference only temporary tables")
+	} else if !tbl.IsTemporary() && target.IsTemporary() {
+		return errors.New("constraints on permanent tables may reference only permanent tables")
 	} else {
 		// Since this FK is referencing another table, this table must be created in
 		// a non-public "ADD" state and made public only after all leases on the
@@ -528,6 +859,15 @@ func (n *createTableNode) resolveFK(
 		}
 	}
 
+	if actions.Delete == parser.SetNull || actions.Update == parser.SetNull {
+		for _, s := range srcCols {
+			if !s.Nullable {
+				return fmt.Errorf("cannot add a SET NULL action on column %q which has a NOT NULL constraint",
+					s.Name)
+			}
+		}
+	}
+
 	type indexMatch bool
 	const (
 		matchExact  indexMatch = true
@@ -543,6 +883,12 @@ func (n *createTableNode) resolveFK(
 		if len(cols) > len(idx.ColumnIDs) || (exact && len(cols) != len(idx.ColumnIDs)) {
 			return false
 		}
+		// Partial indexes don't contain every row, so they can neither enforce
+		// uniqueness of referenced cols nor be used to look up referencing rows.
+		// Neither can inverted indexes, which don't index the column values.
+		if idx.IsPartial() || idx.IsInverted() {
+			return false
+		}
 
 		for i := range cols {
 			if cols[i].ID != idx.ColumnIDs[i] {
@@ -574,7 +920,13 @@ func (n *createTableNode) resolveFK(
 		}
 	}
 
-	ref := sqlbase.ForeignKeyReference{Table: target.ID, Index: targetIdx.ID, Name: string(constraintName)}
+	ref := sqlbase.ForeignKeyReference{
+		Table:    target.ID,
+		Index:    targetIdx.ID,
+		Name:     string(constraintName),
+		OnDelete: fkActionFromAST[actions.Delete],
+		OnUpdate: fkActionFromAST[actions.Update],
+	}
 	backref := sqlbase.ForeignKeyReference{Table: tbl.ID}
 
 	if matchesIndex(srcCols, tbl.PrimaryIndex, matchPrefix) {
@@ -757,6 +1109,105 @@ func CreateTableDescriptor(
 	return desc
 }
 
+// makeViewTableDesc creates a table descriptor for a view from a CreateView
+// statement and the result columns of the view's query.
+func makeViewTableDesc(
+	p *parser.CreateView, columns []ResultColumn, parentID sqlbase.ID,
+) (sqlbase.TableDescriptor, error) {
+	desc := sqlbase.TableDescriptor{}
+	t, err := p.Name.Normalize()
+	if err != nil {
+		return desc, err
+	}
+	desc.Name = string(t.TableName)
+	desc.ParentID = parentID
+	desc.FormatVersion = sqlbase.FamilyFormatVersion
+	// We don't use version 0.
+	desc.Version = 1
+
+	for i, column := range columns {
+		colType, err := parser.DatumTypeToColumnType(column.Typ)
+		if err != nil {
+			return desc, errors.Errorf("view column %q has unsupported type %s",
+				column.Name, column.Typ.Type())
+		}
+		columnTableDef := parser.ColumnTableDef{Name: parser.Name(column.Name), Type: colType}
+		if len(p.ColumnNames) > 0 {
+			columnTableDef.Name = p.ColumnNames[i]
+		}
+		col, _, err := sqlbase.MakeColumnDefDescs(&columnTableDef)
+		if err != nil {
+			return desc, err
+		}
+		desc.AddColumn(*col)
+	}
+	return desc, nil
+}
+
+// makeSequenceTableDesc creates a sequence descriptor from a CreateSequence
+// statement. As in postgres, an ascending sequence defaults to the range
+// [1, MaxInt64] and a descending one to [MinInt64, -1], and a sequence starts
+// at the bound it moves away from.
+func makeSequenceTableDesc(
+	p *parser.CreateSequence, parentID sqlbase.ID,
+) (sqlbase.TableDescriptor, error) {
+	desc := sqlbase.TableDescriptor{}
+	t, err := p.Name.Normalize()
+	if err != nil {
+		return desc, err
+	}
+	desc.Name = string(t.TableName)
+	desc.ParentID = parentID
+	desc.FormatVersion = sqlbase.FamilyFormatVersion
+	// We don't use version 0.
+	desc.Version = 1
+
+	opts := &sqlbase.TableDescriptor_SequenceOpts{Increment: 1}
+	var minValue, maxValue, start *int64
+	seen := make(map[string]struct{}, len(p.Options))
+	for _, opt := range p.Options {
+		if _, ok := seen[opt.Name]; ok {
+			return desc, errors.Errorf("conflicting or redundant options")
+		}
+		seen[opt.Name] = struct{}{}
+		switch opt.Name {
+		case parser.SeqOptIncrement:
+			opts.Increment = *opt.IntVal
+		case parser.SeqOptMinValue:
+			minValue = opt.IntVal
+		case parser.SeqOptMaxValue:
+			maxValue = opt.IntVal
+		case parser.SeqOptStart:
+			start = opt.IntVal
+		}
+	}
+	if opts.Increment == 0 {
+		return desc, errors.Errorf("INCREMENT must not be zero")
+	}
+
+	if opts.Increment > 0 {
+		opts.MinValue, opts.MaxValue = 1, math.MaxInt64
+	} else {
+		opts.MinValue, opts.MaxValue = math.MinInt64, -1
+	}
+	if minValue != nil {
+		opts.MinValue = *minValue
+	}
+	if maxValue != nil {
+		opts.MaxValue = *maxValue
+	}
+	if opts.Increment > 0 {
+		opts.Start = opts.MinValue
+	} else {
+		opts.Start = opts.MaxValue
+	}
+	if start != nil {
+		opts.Start = *start
+	}
+	desc.SequenceOpts = opts
+	return desc, nil
+}
+
 // MakeTableDesc creates a table descriptor from a CreateTable statement.
 func MakeTableDesc(p *parser.CreateTable, parentID sqlbase.ID) (sqlbase.TableDescriptor, error) {
 	desc := sqlbase.TableDescriptor{}
@@ -799,9 +1250,16 @@ func MakeTableDesc(p *parser.CreateTable, parentID sqlbase.ID) (sqlbase.TableDes
 				Name:             string(d.Name),
 				StoreColumnNames: d.Storing.ToStrings(),
 			}
+			if d.Inverted {
+				idx.Type = sqlbase.IndexDescriptor_INVERTED
+			}
 			if err := idx.FillColumns(d.Columns); err != nil {
 				return desc, err
 			}
+			if d.Predicate != nil {
+				s := d.Predicate.String()
+				idx.Predicate = &s
+			}
 			if err := desc.AddIndex(idx, false); err != nil {
 				return desc, err
 			}
@@ -817,6 +1275,10 @@ func MakeTableDesc(p *parser.CreateTable, parentID sqlbase.ID) (sqlbase.TableDes
 			if err := idx.FillColumns(d.Columns); err != nil {
 				return desc, err
 			}
+			if d.Predicate != nil {
+				s := d.Predicate.String()
+				idx.Predicate = &s
+			}
 			if err := desc.AddIndex(idx, d.PrimaryKey); err != nil {
 				return desc, err
 			}
@@ -909,5 +1371,22 @@ func MakeTableDesc(p *parser.CreateTable, parentID sqlbase.ID) (sqlbase.TableDes
 		}
 	}
 
+	// Computed columns and index predicates may reference columns defined
+	// after them, so they are validated once all of the columns are known.
+	for i := range desc.Columns {
+		if desc.Columns[i].IsComputed() {
+			if err := validateComputedColumn(&desc, &desc.Columns[i]); err != nil {
+				return desc, err
+			}
+		}
+	}
+	for i := range desc.Indexes {
+		if desc.Indexes[i].IsPartial() {
+			if _, err := makeIndexPredicate(&desc, &desc.Indexes[i]); err != nil {
+				return desc, err
+			}
+		}
+	}
+
 	return desc, nil
 }
@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/pkg/errors"
@@ -214,6 +215,54 @@ func (p *planner) getVirtualDataSource(tn *parser.TableName) (planDataSource, bo
 	return planDataSource{}, false, nil
 }
 
+// getViewPlan builds a planDataSource for a view by planning the query the
+// view is defined by.
+func (p *planner) getViewPlan(
+	tn *parser.TableName, desc *sqlbase.TableDescriptor,
+) (planDataSource, error) {
+	if err := p.checkPrivilege(desc, privilege.SELECT); err != nil {
+		return planDataSource{}, err
+	}
+
+	stmt, err := parser.ParseOneTraditional(desc.ViewQuery)
+	if err != nil {
+		return planDataSource{}, errors.Wrapf(err, "failed to parse query of view %q", tn)
+	}
+	sel, ok := stmt.(*parser.Select)
+	if !ok {
+		return planDataSource{}, errors.Errorf("query of view %q is not a SELECT", tn)
+	}
+
+	// Only the SELECT privilege on the view itself is required: a view can be
+	// used to expose part of a table to users who cannot read the table. The
+	// dependencies of the view itself are not dependencies of the query being
+	// planned.
+	// The query of the view cannot see the common table expressions of the
+	// statement using it either.
+	defer func(skip bool, deps map[sqlbase.ID]struct{}, ctes *cteScope) {
+		p.skipSelectPrivilegeChecks, p.planDeps, p.ctes = skip, deps, ctes
+	}(p.skipSelectPrivilegeChecks, p.planDeps, p.ctes)
+	p.skipSelectPrivilegeChecks, p.planDeps, p.ctes = true, nil, nil
+	plan, err := p.newPlan(sel, nil, false)
+	if err != nil {
+		return planDataSource{}, err
+	}
+
+	planColumns := plan.Columns()
+	if len(planColumns) != len(desc.Columns) {
+		return planDataSource{}, errors.Errorf(
+			"view %q has %d columns but its query returns %d", tn, len(desc.Columns), len(planColumns))
+	}
+	columns := make([]ResultColumn, len(planColumns))
+	for i, col := range planColumns {
+		columns[i] = ResultColumn{Name: desc.Columns[i].Name, Typ: col.Typ}
+	}
+	return planDataSource{
+		info: newSourceInfoForSingleTable(*tn, columns),
+		plan: plan,
+	}, nil
+}
+
 // getDataSource builds a planDataSource from a single data source clause
 // (TableExpr) in a SelectClause.
 func (p *planner) getDataSource(
@@ -224,11 +273,27 @@ func (p *planner) getDataSource(
 	switch t := src.(type) {
 	case *parser.NormalizableTableName:
 		// Usual case: a table.
-		tn, err := t.NormalizeWithDatabaseName(p.session.Database)
+		tn, err := t.Normalize()
 		if err != nil {
 			return planDataSource{}, err
 		}
 
+		// An unqualified name may refer to a common table expression. CTE
+		// references are left unqualified, so that they are still recognized
+		// when the statement is planned again.
+		if tn.DatabaseName == "" {
+			if cte := p.findCTE(tn); cte != nil {
+				if hints != nil {
+					return planDataSource{}, errors.Errorf(
+						"index hints cannot be used with WITH query %q", cte.name)
+				}
+				return p.getCTEDataSource(cte, scanVisibility)
+			}
+		}
+		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+			return planDataSource{}, err
+		}
+
 		// Is this perhaps a name for a virtual table?
 		ds, foundVirtual, err := p.getVirtualDataSource(tn)
 		if err != nil {
@@ -238,9 +303,37 @@ func (p *planner) getDataSource(
 			return ds, nil
 		}
 
-		// This name designates a real table.
+		// This name designates a real table or a view.
+		descFunc := p.getTableLease
+		if p.asOf {
+			// AS OF SYSTEM TIME queries need to fetch the table descriptor at the
+			// specified time, and never lease anything. The proto transaction already
+			// has its timestamps set correctly so mustGetTableDesc will fetch with the
+			// correct timestamp.
+			descFunc = p.mustGetTableDesc
+		}
+		desc, err := descFunc(tn)
+		if err != nil {
+			return planDataSource{}, err
+		}
+		if p.asOf {
+			if err := p.checkAsOfGCTTL(desc); err != nil {
+				return planDataSource{}, err
+			}
+		}
+		if p.planDeps != nil {
+			p.planDeps[desc.ID] = struct{}{}
+		}
+
+		if desc.IsView() {
+			return p.getViewPlan(tn, desc)
+		}
+		if desc.IsSequence() {
+			return planDataSource{}, sqlbase.NewWrongObjectTypeError(tn.String(), "table or view")
+		}
+
 		scan := p.Scan()
-		if err := scan.initTable(p, tn, hints, scanVisibility); err != nil {
+		if err := scan.initTable(p, desc, hints, scanVisibility); err != nil {
 			return planDataSource{}, err
 		}
 
@@ -260,6 +353,17 @@ func (p *planner) getDataSource(
 			plan: plan,
 		}, nil
 
+	case *parser.FuncExpr:
+		// A set-generating function.
+		plan, err := p.makeGenerator(t)
+		if err != nil {
+			return planDataSource{}, err
+		}
+		return planDataSource{
+			info: newSourceInfoForSingleTable(parser.TableName{}, plan.Columns()),
+			plan: plan,
+		}, nil
+
 	case *parser.JoinTableExpr:
 		// Joins: two sources.
 		left, err := p.getDataSource(t.Left, nil, scanVisibility)
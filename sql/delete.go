@@ -47,6 +47,11 @@ type deleteNode struct {
 //   Notes: postgres requires DELETE. Also requires SELECT for "USING" and "WHERE" with tables.
 //          mysql requires DELETE. Also requires SELECT if a table is used in the "WHERE" clause.
 func (p *planner) Delete(n *parser.Delete, desiredTypes []parser.Datum, autoCommit bool) (planNode, error) {
+	defer func(ctes *cteScope) { p.ctes = ctes }(p.ctes)
+	if err := p.pushWith(n.With); err != nil {
+		return nil, err
+	}
+
 	tn, err := p.getAliasedTableName(n.Table)
 	if err != nil {
 		return nil, err
@@ -83,7 +88,7 @@ func (p *planner) Delete(n *parser.Delete, desiredTypes []parser.Datum, autoComm
 		Exprs: sqlbase.ColumnsSelectors(rd.fetchCols),
 		From:  &parser.From{Tables: []parser.TableExpr{n.Table}},
 		Where: n.Where,
-	}, nil, nil, nil, publicAndNonPublicColumns)
+	}, nil, nil, parser.LockNone, nil, publicAndNonPublicColumns)
 	if err != nil {
 		return nil, err
 	}
@@ -146,7 +151,9 @@ func (d *deleteNode) Next() (bool, error) {
 	if !next {
 		if err == nil {
 			// We're done. Finish the batch.
-			err = d.tw.finalize(ctx)
+			if err = d.tw.finalize(ctx); err == nil {
+				d.notifyMutation(d.run.rowsWritten)
+			}
 		}
 		return false, err
 	}
@@ -161,6 +168,7 @@ func (d *deleteNode) Next() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	d.run.rowsWritten++
 
 	resultRow, err := d.rh.cookResultRow(rowVals)
 	if err != nil {
@@ -210,6 +218,7 @@ func (d *deleteNode) fastDelete(scan *scanNode) error {
 		return err
 	}
 	d.rh.rowCount += rowCount
+	d.notifyMutation(rowCount)
 	return nil
 }
 
@@ -70,19 +70,41 @@ type DescriptorAccessor interface {
 var _ DescriptorAccessor = &planner{}
 
 // checkPrivilege implements the DescriptorAccessor interface.
+// The privileges of the roles the user is a member of are inherited.
 func (p *planner) checkPrivilege(descriptor sqlbase.DescriptorProto, privilege privilege.Kind) error {
-	if descriptor.GetPrivileges().CheckPrivilege(p.session.User, privilege) {
+	privs := descriptor.GetPrivileges()
+	if privs.CheckPrivilege(p.session.User, privilege) {
 		return nil
 	}
+	roles, err := p.memberOf()
+	if err != nil {
+		return err
+	}
+	for role := range roles {
+		if privs.CheckPrivilege(role, privilege) {
+			return nil
+		}
+	}
 	return fmt.Errorf("user %s does not have %s privilege on %s %s",
 		p.session.User, privilege, descriptor.TypeName(), descriptor.GetName())
 }
 
 // anyPrivilege implements the DescriptorAccessor interface.
+// The privileges of the roles the user is a member of are inherited.
 func (p *planner) anyPrivilege(descriptor sqlbase.DescriptorProto) error {
-	if descriptor.GetPrivileges().AnyPrivilege(p.session.User) || isVirtualDescriptor(descriptor) {
+	privs := descriptor.GetPrivileges()
+	if privs.AnyPrivilege(p.session.User) || isVirtualDescriptor(descriptor) {
 		return nil
 	}
+	roles, err := p.memberOf()
+	if err != nil {
+		return err
+	}
+	for role := range roles {
+		if privs.AnyPrivilege(role) {
+			return nil
+		}
+	}
 	return fmt.Errorf("user %s has no privileges on %s %s",
 		p.session.User, descriptor.TypeName(), descriptor.GetName())
 }
@@ -0,0 +1,221 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/pkg/errors"
+)
+
+// diskRowContainerIDs is used to allocate the key prefixes of
+// diskRowContainers, so that containers sharing a temporary storage engine
+// never see each other's rows.
+var diskRowContainerIDs uint64
+
+// diskRowContainerBatchSize is the number of bytes buffered in a write batch
+// before it is committed to the temporary storage engine.
+const diskRowContainerBatchSize = 1 << 20
+
+// diskRowContainer stores rows in a temporary storage engine. Every row is
+// added with a key and iterating over the container returns the rows ordered
+// by that key; rows added with equal keys are returned in the order they were
+// added. It is used by the plan nodes that buffer rows (sorts and
+// aggregations) once they run out of memory budget.
+type diskRowContainer struct {
+	engine engine.Engine
+	// prefix is unique to this container; all its keys start with it.
+	prefix roachpb.Key
+	// types are the types of the columns of the stored rows.
+	types []parser.Datum
+
+	batch     engine.Batch
+	batchSize int
+	numRows   uint64
+	// iter is the open iterator over the container, if any.
+	iter *diskRowIterator
+
+	scratchKey []byte
+	scratchVal []byte
+}
+
+func makeDiskRowContainer(e engine.Engine, types []parser.Datum) diskRowContainer {
+	return diskRowContainer{
+		engine: e,
+		prefix: encoding.EncodeUvarintAscending(nil, atomic.AddUint64(&diskRowContainerIDs, 1)),
+		types:  types,
+	}
+}
+
+// addRow stores a copy of row under the given key.
+func (d *diskRowContainer) addRow(key []byte, row parser.DTuple) error {
+	if len(row) != len(d.types) {
+		return errors.Errorf("expected %d columns to spill to disk, got %d", len(d.types), len(row))
+	}
+	k := append(d.scratchKey[:0], d.prefix...)
+	k = append(k, key...)
+	// The sequence number makes the keys unique and keeps rows with equal keys
+	// in insertion order.
+	k = encoding.EncodeUint64Ascending(k, d.numRows)
+
+	v := d.scratchVal[:0]
+	for _, datum := range row {
+		var err error
+		v, err = sqlbase.EncodeTableValue(v, sqlbase.ColumnID(encoding.NoColumnID), datum)
+		if err != nil {
+			return errors.Wrap(err, "unable to spill row to disk")
+		}
+	}
+
+	if d.batch == nil {
+		d.batch = d.engine.NewBatch()
+	}
+	if err := d.batch.Put(engine.MakeMVCCMetadataKey(k), v); err != nil {
+		return err
+	}
+	d.scratchKey, d.scratchVal = k, v
+	d.numRows++
+	d.batchSize += len(k) + len(v)
+	if d.batchSize >= diskRowContainerBatchSize {
+		return d.flush()
+	}
+	return nil
+}
+
+// flush commits the buffered rows to the temporary storage engine.
+func (d *diskRowContainer) flush() error {
+	if d.batch == nil {
+		return nil
+	}
+	err := d.batch.Commit()
+	d.batch.Close()
+	d.batch = nil
+	d.batchSize = 0
+	return err
+}
+
+// newIterator returns an iterator over the rows added so far. Only one
+// iterator can be open at a time; it is closed along with the container.
+func (d *diskRowContainer) newIterator() (*diskRowIterator, error) {
+	if err := d.flush(); err != nil {
+		return nil, err
+	}
+	it := &diskRowIterator{
+		container: d,
+		iter:      d.engine.NewIterator(false /* prefix */),
+	}
+	it.rewind()
+	d.iter = it
+	return it, nil
+}
+
+// close removes the rows of the container from the temporary storage engine.
+func (d *diskRowContainer) close() error {
+	if d.iter != nil {
+		d.iter.close()
+	}
+	if d.batch != nil {
+		d.batch.Close()
+		d.batch = nil
+	}
+	if d.numRows == 0 {
+		return nil
+	}
+	b := d.engine.NewBatch()
+	defer b.Close()
+	if _, err := engine.ClearRange(
+		b, engine.MakeMVCCMetadataKey(d.prefix), engine.MakeMVCCMetadataKey(d.prefix.PrefixEnd()),
+	); err != nil {
+		return err
+	}
+	d.numRows = 0
+	return b.Commit()
+}
+
+// diskRowIterator iterates over the rows of a diskRowContainer in key order.
+type diskRowIterator struct {
+	container *diskRowContainer
+	iter      engine.Iterator
+	alloc     sqlbase.DatumAlloc
+	scratch   []byte
+}
+
+// valid returns whether the iterator is positioned at a row.
+func (it *diskRowIterator) valid() (bool, error) {
+	if !it.iter.Valid() {
+		return false, it.iter.Error()
+	}
+	return bytes.HasPrefix(it.iter.Key().Key, it.container.prefix), nil
+}
+
+// rewind positions the iterator at the first row.
+func (it *diskRowIterator) rewind() {
+	it.iter.Seek(engine.MakeMVCCMetadataKey(it.container.prefix))
+}
+
+// seek positions the iterator at the first row added with a key greater
+// than or equal to key.
+func (it *diskRowIterator) seek(key []byte) {
+	it.scratch = append(append(it.scratch[:0], it.container.prefix...), key...)
+	it.iter.Seek(engine.MakeMVCCMetadataKey(it.scratch))
+}
+
+// next advances the iterator to the next row.
+func (it *diskRowIterator) next() {
+	it.iter.Next()
+}
+
+// key returns the key the current row was added with.
+func (it *diskRowIterator) key() []byte {
+	k := it.iter.Key().Key
+	return k[len(it.container.prefix) : len(k)-8]
+}
+
+// seq returns the sequence number of the current row, i.e. the number of
+// rows added to the container before it.
+func (it *diskRowIterator) seq() uint64 {
+	k := it.iter.Key().Key
+	_, seq, err := encoding.DecodeUint64Ascending(k[len(k)-8:])
+	if err != nil {
+		panic(err)
+	}
+	return seq
+}
+
+// row decodes the current row. The returned row is not reused by subsequent
+// calls.
+func (it *diskRowIterator) row() (parser.DTuple, error) {
+	b := it.iter.Value()
+	row := make(parser.DTuple, len(it.container.types))
+	for i, typ := range it.container.types {
+		var err error
+		row[i], b, err = sqlbase.DecodeTableValue(&it.alloc, typ, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+func (it *diskRowIterator) close() {
+	it.iter.Close()
+	it.container.iter = nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/stop"
+)
+
+func TestDiskRowContainer(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	e := engine.NewInMem(roachpb.Attributes{}, 1<<20, stopper)
+
+	types := []parser.Datum{parser.TypeInt, parser.TypeString}
+	c1 := makeDiskRowContainer(e, types)
+	c2 := makeDiskRowContainer(e, types)
+
+	rows := []parser.DTuple{
+		{parser.NewDInt(3), parser.NewDString("c")},
+		{parser.NewDInt(1), parser.NewDString("a")},
+		{parser.NewDInt(2), parser.DNull},
+		{parser.NewDInt(1), parser.NewDString("b")},
+	}
+	for _, row := range rows {
+		key, err := sqlbase.EncodeTableKey(nil, row[0], encoding.Descending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c1.addRow(key, row); err != nil {
+			t.Fatal(err)
+		}
+		// The rows of the second container must not be seen by the first.
+		if err := c2.addRow(key, row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The rows come back ordered by key, and in insertion order for equal
+	// keys.
+	expected := []string{`(3, 'c')`, `(2, NULL)`, `(1, 'a')`, `(1, 'b')`}
+	var actual []string
+	it, err := c1.newIterator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ; ; it.next() {
+		if ok, err := it.valid(); err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			break
+		}
+		row, err := it.row()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual = append(actual, row.String())
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, actual)
+		}
+	}
+
+	// Closing a container removes its rows, and only its rows.
+	for _, c := range []*diskRowContainer{&c1, &c2} {
+		prefixEnd := c.prefix.PrefixEnd()
+		kvs, err := engine.Scan(
+			e, engine.MakeMVCCMetadataKey(c.prefix), engine.MakeMVCCMetadataKey(prefixEnd), 0,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != len(rows) {
+			t.Fatalf("expected %d rows before closing, got %d", len(rows), len(kvs))
+		}
+		if err := c.close(); err != nil {
+			t.Fatal(err)
+		}
+		kvs, err = engine.Scan(
+			e, engine.MakeMVCCMetadataKey(c.prefix), engine.MakeMVCCMetadataKey(prefixEnd), 0,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(kvs) != 0 {
+			t.Fatalf("expected no rows after closing, got %d", len(kvs))
+		}
+	}
+}
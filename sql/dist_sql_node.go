@@ -17,11 +17,13 @@
 package sql
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/sql/distsql"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
@@ -196,14 +198,11 @@ func scanNodeToTableReaderSpec(n *scanNode) *distsql.TableReaderSpec {
 	return s
 }
 
-// scanNodeToDistSQL creates a flow and distSQLNode that correspond to a
-// scanNode.
-// If syncMode is true, the plan does not instantiate any goroutines
-// internally.
-func scanNodeToDistSQL(n *scanNode, syncMode bool) (*distSQLNode, error) {
-	req := distsql.SetupFlowRequest{Txn: n.p.txn.Proto}
+// scanNodeToFlowSpec generates a FlowSpec that corresponds to a scanNode: a
+// TableReader returning its rows directly to the gateway.
+func scanNodeToFlowSpec(n *scanNode) (distsql.FlowSpec, *distsql.TableReaderSpec) {
 	tr := scanNodeToTableReaderSpec(n)
-	req.Flow = distsql.FlowSpec{
+	flow := distsql.FlowSpec{
 		Processors: []distsql.ProcessorSpec{{
 			Core: distsql.ProcessorCoreUnion{TableReader: tr},
 			Output: []distsql.OutputRouterSpec{{
@@ -214,6 +213,17 @@ func scanNodeToDistSQL(n *scanNode, syncMode bool) (*distSQLNode, error) {
 			}},
 		}},
 	}
+	return flow, tr
+}
+
+// scanNodeToDistSQL creates a flow and distSQLNode that correspond to a
+// scanNode.
+// If syncMode is true, the plan does not instantiate any goroutines
+// internally.
+func scanNodeToDistSQL(n *scanNode, syncMode bool) (*distSQLNode, error) {
+	req := distsql.SetupFlowRequest{Txn: n.p.txn.Proto}
+	var tr *distsql.TableReaderSpec
+	req.Flow, tr = scanNodeToFlowSpec(n)
 
 	return newDistSQLNode(
 		n.resultColumns, tr.OutputColumns, n.ordering, n.p.execCtx.DistSQLSrv, &req, syncMode)
@@ -245,3 +255,77 @@ func hackPlanToUseDistSQL(plan planNode, syncMode bool) error {
 	}
 	return nil
 }
+
+// planToFlowSpecs returns the flows which hackPlanToUseDistSQL sets up for
+// the plan, in the order in which it sets them up.
+func planToFlowSpecs(plan planNode, flows []distsql.FlowSpec) []distsql.FlowSpec {
+	if sel, ok := plan.(*selectNode); ok {
+		if scan, ok := sel.source.plan.(*scanNode); ok {
+			flow, _ := scanNodeToFlowSpec(scan)
+			flows = append(flows, flow)
+		}
+	}
+
+	_, _, children := plan.ExplainPlan(true)
+	for _, c := range children {
+		flows = planToFlowSpecs(c, flows)
+	}
+	return flows
+}
+
+var explainDistSQLColumns = []ResultColumn{
+	{Name: "URL", Typ: parser.TypeString},
+	{Name: "JSON", Typ: parser.TypeString},
+}
+
+// explainDistSQLNode is a planNode that returns the diagrams of the physical
+// plans run by distsql for the wrapped plan, one row per flow. The URL
+// renders the diagram in the admin UI; the JSON is the diagram itself, see
+// distsql.GeneratePlanDiagram. It is used as the top-level node for
+// EXPLAIN (DISTSQL) statements.
+type explainDistSQLNode struct {
+	p       *planner
+	plan    planNode
+	results *valuesNode
+}
+
+func (e *explainDistSQLNode) ExplainTypes(fn func(string, string)) {}
+func (e *explainDistSQLNode) Next() (bool, error)                  { return e.results.Next() }
+func (e *explainDistSQLNode) Columns() []ResultColumn              { return explainDistSQLColumns }
+func (e *explainDistSQLNode) Ordering() orderingInfo               { return orderingInfo{} }
+func (e *explainDistSQLNode) Values() parser.DTuple                { return e.results.Values() }
+func (e *explainDistSQLNode) DebugValues() debugValues             { return debugValues{} }
+func (e *explainDistSQLNode) SetLimitHint(n int64, s bool)         { e.results.SetLimitHint(n, s) }
+func (e *explainDistSQLNode) MarkDebug(mode explainMode)           {}
+
+func (e *explainDistSQLNode) expandPlan() error {
+	e.results = &valuesNode{columns: explainDistSQLColumns}
+	if err := e.plan.expandPlan(); err != nil {
+		return err
+	}
+	// Trigger limit hint propagation, as hackPlanToUseDistSQL does; the
+	// limits are pushed down into the TableReaders.
+	e.plan.SetLimitHint(math.MaxInt64, true)
+	return nil
+}
+
+func (e *explainDistSQLNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "explain", "distsql", []planNode{e.plan}
+}
+
+func (e *explainDistSQLNode) Start() error {
+	gateway := e.p.evalCtx.NodeID
+	for _, flow := range planToFlowSpecs(e.plan, nil) {
+		flows := map[roachpb.NodeID]distsql.FlowSpec{gateway: flow}
+		var buf bytes.Buffer
+		if err := distsql.GeneratePlanDiagram(gateway, flows, &buf); err != nil {
+			return err
+		}
+		diagram := bytes.TrimSpace(buf.Bytes())
+		e.results.rows = append(e.results.rows, parser.DTuple{
+			parser.NewDString(distsql.PlanDiagramURL(diagram)),
+			parser.NewDString(string(diagram)),
+		})
+	}
+	return nil
+}
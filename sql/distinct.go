@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 )
@@ -37,16 +38,18 @@ type distinctNode struct {
 	// Encoding of the non-columnInOrder columns for rows sharing the same
 	// prefixSeen value.
 	suffixSeen map[string]struct{}
-	explain    explainMode
-	debugVals  debugValues
+	// suffixMemAcc accounts for the entries of suffixSeen.
+	suffixMemAcc mon.MemoryAccount
+	explain      explainMode
+	debugVals    debugValues
 }
 
 // distinct constructs a distinctNode.
-func (*planner) Distinct(n *parser.SelectClause) *distinctNode {
+func (p *planner) Distinct(n *parser.SelectClause) *distinctNode {
 	if !n.Distinct {
 		return nil
 	}
-	return &distinctNode{}
+	return &distinctNode{suffixMemAcc: p.makeMemoryAccount()}
 }
 
 // wrap connects the distinctNode to its source planNode.
@@ -153,9 +156,13 @@ func (n *distinctNode) Next() (bool, error) {
 			// reset our seen set.
 			if len(n.suffixSeen) > 0 {
 				n.suffixSeen = make(map[string]struct{})
+				n.suffixMemAcc.Clear()
 			}
 			n.prefixSeen = prefix
 			if suffix != nil {
+				if err := n.suffixMemAcc.Grow(sizeOfBucket + int64(len(suffix))); err != nil {
+					return false, err
+				}
 				n.suffixSeen[string(suffix)] = struct{}{}
 			}
 			return true, nil
@@ -166,6 +173,9 @@ func (n *distinctNode) Next() (bool, error) {
 		if suffix != nil {
 			sKey := string(suffix)
 			if _, ok := n.suffixSeen[sKey]; !ok {
+				if err := n.suffixMemAcc.Grow(sizeOfBucket + int64(len(sKey))); err != nil {
+					return false, err
+				}
 				n.suffixSeen[sKey] = struct{}{}
 				return true, nil
 			}
@@ -0,0 +1,85 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsql
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/pkg/errors"
+)
+
+// BackfillFunc reads the rows in the spans of spec and ingests the entries of
+// the indexes being added by spec.MutationID through db, returning the number
+// of index entries ingested. Like ReadCSVFunc, it is injected by package sql,
+// which knows how to encode index entries.
+var BackfillFunc func(ctx context.Context, db *client.DB, spec *BackfillerSpec) (int64, error)
+
+// backfiller is a processor that backfills new secondary indexes of a table
+// from a part of its primary index. It outputs a single row with the number of
+// index entries it ingested.
+type backfiller struct {
+	flowCtx *FlowCtx
+	ctx     context.Context
+	spec    BackfillerSpec
+	output  RowReceiver
+}
+
+var _ processor = &backfiller{}
+
+func newBackfiller(
+	flowCtx *FlowCtx, spec *BackfillerSpec, output RowReceiver,
+) (*backfiller, error) {
+	if flowCtx.clientDB == nil {
+		return nil, errors.Errorf("backfiller requires a client.DB")
+	}
+	return &backfiller{
+		flowCtx: flowCtx,
+		ctx:     log.WithLogTagInt(flowCtx.Context, "Backfiller", int(spec.Table.ID)),
+		spec:    *spec,
+		output:  output,
+	}, nil
+}
+
+// Run is part of the processor interface.
+func (b *backfiller) Run(wg *sync.WaitGroup) {
+	if wg != nil {
+		defer wg.Done()
+	}
+
+	if log.V(2) {
+		log.Infof(b.ctx, "starting (spans: %v)", b.spec.Spans)
+		defer log.Infof(b.ctx, "exiting")
+	}
+
+	if BackfillFunc == nil {
+		b.output.Close(errors.Errorf("index backfills are not available"))
+		return
+	}
+	count, err := BackfillFunc(b.ctx, b.flowCtx.clientDB, &b.spec)
+	if err != nil {
+		b.output.Close(err)
+		return
+	}
+	row := make(sqlbase.EncDatumRow, 1)
+	row[0].SetDatum(sqlbase.ColumnType_INT, parser.NewDInt(parser.DInt(count)))
+	b.output.PushRow(row)
+	b.output.Close(nil)
+}
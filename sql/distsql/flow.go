@@ -50,6 +50,8 @@ type FlowCtx struct {
 	evalCtx *parser.EvalContext
 	rpcCtx  *rpc.Context
 	txn     *client.Txn
+	// clientDB is used by processors that write outside of txn.
+	clientDB *client.DB
 }
 
 type flowStatus int
@@ -195,6 +197,18 @@ func (f *Flow) makeProcessor(ps *ProcessorSpec, inputs []RowSource) (processor,
 		}
 		return newJoinReader(&f.FlowCtx, ps.Core.JoinReader, inputs[0], outputs[0])
 	}
+	if ps.Core.ReadCSV != nil {
+		if err := checkNumInOut(inputs, outputs, 0, 1); err != nil {
+			return nil, err
+		}
+		return newReadCSV(&f.FlowCtx, ps.Core.ReadCSV, outputs[0])
+	}
+	if ps.Core.Backfiller != nil {
+		if err := checkNumInOut(inputs, outputs, 0, 1); err != nil {
+			return nil, err
+		}
+		return newBackfiller(&f.FlowCtx, ps.Core.Backfiller, outputs[0])
+	}
 	return nil, errors.Errorf("unsupported processor %s", ps)
 }
 
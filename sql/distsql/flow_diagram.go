@@ -0,0 +1,289 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// PlanDiagramURLPrefix is the path of the admin UI page rendering the
+// diagrams of physical plans, to which the encoded diagram is appended.
+const PlanDiagramURLPrefix = "#/distsql-plan?plan="
+
+// diagramCell is a box of the diagram, with a title and a few lines of
+// details.
+type diagramCell struct {
+	Title   string   `json:"title"`
+	Details []string `json:"details,omitempty"`
+}
+
+// diagramProcessor is a processor of the diagram: its core, along with its
+// input synchronizers and output routers. The trivial ones (with a single
+// stream) are omitted.
+type diagramProcessor struct {
+	NodeIdx int           `json:"nodeIdx"`
+	Inputs  []diagramCell `json:"inputs,omitempty"`
+	Core    diagramCell   `json:"core"`
+	Outputs []diagramCell `json:"outputs,omitempty"`
+}
+
+// diagramEdge is a stream between two processors. SourceOutput and DestInput
+// are 1-based indexes into the outputs of the source and the inputs of the
+// destination; 0 refers to the core itself when there is no router or
+// synchronizer.
+type diagramEdge struct {
+	SourceProc   int `json:"sourceProc"`
+	SourceOutput int `json:"sourceOutput"`
+	DestProc     int `json:"destProc"`
+	DestInput    int `json:"destInput"`
+}
+
+// diagramData is the diagram of a physical plan: the processors, grouped
+// by the node hosting them, and the streams between them.
+type diagramData struct {
+	NodeNames  []string           `json:"nodeNames"`
+	Processors []diagramProcessor `json:"processors"`
+	Edges      []diagramEdge      `json:"edges"`
+}
+
+// GeneratePlanDiagram writes the JSON diagram of the physical plan made of
+// the flows run on each node. The results of the plan are returned to the
+// gateway node.
+func GeneratePlanDiagram(
+	gateway roachpb.NodeID, flows map[roachpb.NodeID]FlowSpec, w io.Writer,
+) error {
+	d, err := makeDiagramData(gateway, flows)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(d)
+}
+
+// PlanDiagramURL returns the URL of the admin UI page rendering a diagram
+// written by GeneratePlanDiagram. The URL is relative to the address of the
+// admin UI.
+func PlanDiagramURL(diagram []byte) string {
+	return PlanDiagramURLPrefix + base64.RawURLEncoding.EncodeToString(diagram)
+}
+
+type localStreamKey struct {
+	nodeIdx int
+	id      LocalStreamID
+}
+
+type streamDest struct {
+	proc, input int
+}
+
+func makeDiagramData(gateway roachpb.NodeID, flows map[roachpb.NodeID]FlowSpec) (*diagramData, error) {
+	nodeIDs := make([]int, 0, len(flows)+1)
+	for nodeID := range flows {
+		nodeIDs = append(nodeIDs, int(nodeID))
+	}
+	if _, ok := flows[gateway]; !ok {
+		nodeIDs = append(nodeIDs, int(gateway))
+	}
+	sort.Ints(nodeIDs)
+
+	d := &diagramData{
+		NodeNames:  make([]string, len(nodeIDs)),
+		Processors: []diagramProcessor{},
+		Edges:      []diagramEdge{},
+	}
+	gatewayIdx := 0
+	for i, nodeID := range nodeIDs {
+		d.NodeNames[i] = fmt.Sprintf("Node %d", nodeID)
+		if roachpb.NodeID(nodeID) == gateway {
+			gatewayIdx = i
+		}
+	}
+
+	// Lay out the processors and record the destination of each stream.
+	localDests := make(map[localStreamKey]streamDest)
+	remoteDests := make(map[StreamID]streamDest)
+	var procs []*ProcessorSpec
+	for nodeIdx, nodeID := range nodeIDs {
+		flow := flows[roachpb.NodeID(nodeID)]
+		for i := range flow.Processors {
+			p := &flow.Processors[i]
+			procIdx := len(d.Processors)
+			dp := diagramProcessor{NodeIdx: nodeIdx, Core: coreCell(&p.Core)}
+			for j, input := range p.Input {
+				inputIdx := 0
+				if len(input.Streams) > 1 || len(p.Input) > 1 {
+					dp.Inputs = append(dp.Inputs, inputSyncCell(&input))
+					inputIdx = j + 1
+				}
+				for _, s := range input.Streams {
+					dest := streamDest{proc: procIdx, input: inputIdx}
+					if s.Mailbox != nil {
+						remoteDests[s.Mailbox.StreamID] = dest
+					} else {
+						localDests[localStreamKey{nodeIdx, s.LocalStreamID}] = dest
+					}
+				}
+			}
+			d.Processors = append(d.Processors, dp)
+			procs = append(procs, p)
+		}
+	}
+
+	// The rows returned directly by the flows are received by the gateway.
+	responseIdx := -1
+	responseDest := func() streamDest {
+		if responseIdx == -1 {
+			responseIdx = len(d.Processors)
+			d.Processors = append(d.Processors, diagramProcessor{
+				NodeIdx: gatewayIdx,
+				Core:    diagramCell{Title: "Response"},
+			})
+		}
+		return streamDest{proc: responseIdx}
+	}
+
+	for procIdx, p := range procs {
+		nodeIdx := d.Processors[procIdx].NodeIdx
+		for j, output := range p.Output {
+			outputIdx := 0
+			if len(output.Streams) > 1 || len(p.Output) > 1 {
+				d.Processors[procIdx].Outputs = append(d.Processors[procIdx].Outputs, outputRouterCell(&output))
+				outputIdx = j + 1
+			}
+			for _, s := range output.Streams {
+				var dest streamDest
+				var ok bool
+				switch {
+				case s.Mailbox != nil && s.Mailbox.SimpleResponse:
+					dest, ok = responseDest(), true
+				case s.Mailbox != nil:
+					dest, ok = remoteDests[s.Mailbox.StreamID]
+				default:
+					dest, ok = localDests[localStreamKey{nodeIdx, s.LocalStreamID}]
+				}
+				if !ok {
+					return nil, fmt.Errorf("no destination for the output stream of processor %d", procIdx)
+				}
+				d.Edges = append(d.Edges, diagramEdge{
+					SourceProc:   procIdx,
+					SourceOutput: outputIdx,
+					DestProc:     dest.proc,
+					DestInput:    dest.input,
+				})
+			}
+		}
+	}
+	return d, nil
+}
+
+func coreCell(core *ProcessorCoreUnion) diagramCell {
+	switch {
+	case core.TableReader != nil:
+		tr := core.TableReader
+		c := diagramCell{Title: "TableReader"}
+		c.Details = append(c.Details, indexName(&tr.Table, tr.IndexIdx))
+		if tr.Reverse {
+			c.Details = append(c.Details, "Reverse")
+		}
+		c.Details = append(c.Details, fmt.Sprintf("Spans: %d", len(tr.Spans)))
+		if tr.Filter.Expr != "" {
+			c.Details = append(c.Details, "Filter: "+tr.Filter.Expr)
+		}
+		c.Details = append(c.Details, "Out: "+formatColumnIndexes(tr.OutputColumns))
+		if tr.HardLimit != 0 {
+			c.Details = append(c.Details, fmt.Sprintf("Limit: %d", tr.HardLimit))
+		} else if tr.SoftLimit != 0 {
+			c.Details = append(c.Details, fmt.Sprintf("Limit hint: %d", tr.SoftLimit))
+		}
+		return c
+
+	case core.JoinReader != nil:
+		jr := core.JoinReader
+		c := diagramCell{Title: "JoinReader"}
+		c.Details = append(c.Details, indexName(&jr.Table, jr.IndexIdx))
+		if jr.Filter.Expr != "" {
+			c.Details = append(c.Details, "Filter: "+jr.Filter.Expr)
+		}
+		c.Details = append(c.Details, "Out: "+formatColumnIndexes(jr.OutputColumns))
+		return c
+
+	default:
+		return diagramCell{Title: "unknown"}
+	}
+}
+
+func inputSyncCell(input *InputSyncSpec) diagramCell {
+	switch input.Type {
+	case InputSyncSpec_ORDERED:
+		var buf bytes.Buffer
+		for i, col := range input.Ordering.Columns {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "$%d", col.ColIdx)
+			if col.Direction == Ordering_Column_DESC {
+				buf.WriteByte('-')
+			} else {
+				buf.WriteByte('+')
+			}
+		}
+		return diagramCell{Title: "ordered", Details: []string{buf.String()}}
+	default:
+		return diagramCell{Title: "unordered"}
+	}
+}
+
+func outputRouterCell(output *OutputRouterSpec) diagramCell {
+	switch output.Type {
+	case OutputRouterSpec_BY_HASH:
+		return diagramCell{
+			Title:   "by hash",
+			Details: []string{formatColumnIndexes(output.HashColumns)},
+		}
+	case OutputRouterSpec_BY_RANGE:
+		return diagramCell{Title: "by range"}
+	default:
+		return diagramCell{Title: "mirror"}
+	}
+}
+
+// indexName returns the name of the index of the table, using the index
+// numbering of the processor specs: 0 is the primary index.
+func indexName(desc *sqlbase.TableDescriptor, indexIdx uint32) string {
+	if indexIdx == 0 {
+		return desc.Name + "@" + desc.PrimaryIndex.Name
+	}
+	return desc.Name + "@" + desc.Indexes[indexIdx-1].Name
+}
+
+// formatColumnIndexes formats the column indexes using the $0, $1 notation
+// of the expressions of the processors.
+func formatColumnIndexes(cols []uint32) string {
+	var buf bytes.Buffer
+	for i, c := range cols {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, "$%d", c)
+	}
+	return buf.String()
+}
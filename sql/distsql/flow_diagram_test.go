@@ -0,0 +1,125 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package distsql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestPlanDiagram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	desc := sqlbase.TableDescriptor{
+		Name:         "t",
+		PrimaryIndex: sqlbase.IndexDescriptor{Name: "primary"},
+		Indexes:      []sqlbase.IndexDescriptor{{Name: "idx"}},
+	}
+
+	// Node 1 reads the primary index and sends the rows to node 2, which
+	// merges them with the rows of its own index and returns the results of
+	// the join.
+	flows := map[roachpb.NodeID]FlowSpec{
+		1: {Processors: []ProcessorSpec{{
+			Core: ProcessorCoreUnion{TableReader: &TableReaderSpec{
+				Table: desc, OutputColumns: []uint32{0, 1},
+			}},
+			Output: []OutputRouterSpec{{
+				Streams: []StreamEndpointSpec{{
+					Mailbox: &MailboxSpec{StreamID: 1, TargetAddr: "node2"},
+				}},
+			}},
+		}}},
+		2: {Processors: []ProcessorSpec{
+			{
+				Core: ProcessorCoreUnion{TableReader: &TableReaderSpec{
+					Table:         desc,
+					IndexIdx:      1,
+					Spans:         []TableReaderSpan{{}},
+					OutputColumns: []uint32{1},
+					HardLimit:     10,
+				}},
+				Output: []OutputRouterSpec{{
+					Streams: []StreamEndpointSpec{{LocalStreamID: 0}},
+				}},
+			},
+			{
+				Input: []InputSyncSpec{{
+					Type: InputSyncSpec_ORDERED,
+					Ordering: Ordering{Columns: []Ordering_Column{
+						{ColIdx: 1, Direction: Ordering_Column_DESC},
+					}},
+					Streams: []StreamEndpointSpec{
+						{LocalStreamID: 0},
+						{Mailbox: &MailboxSpec{StreamID: 1}},
+					},
+				}},
+				Core: ProcessorCoreUnion{JoinReader: &JoinReaderSpec{
+					Table:         desc,
+					Filter:        Expression{Expr: "$2 = 1"},
+					OutputColumns: []uint32{0, 1, 2},
+				}},
+				Output: []OutputRouterSpec{{
+					Streams: []StreamEndpointSpec{{
+						Mailbox: &MailboxSpec{SimpleResponse: true},
+					}},
+				}},
+			},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := GeneratePlanDiagram(2, flows, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"nodeNames":["Node 1","Node 2"],"processors":[` +
+		`{"nodeIdx":0,"core":{"title":"TableReader","details":["t@primary","Spans: 0","Out: $0,$1"]}},` +
+		`{"nodeIdx":1,"core":{"title":"TableReader","details":["t@idx","Spans: 1","Out: $1","Limit: 10"]}},` +
+		`{"nodeIdx":1,"inputs":[{"title":"ordered","details":["$1-"]}],` +
+		`"core":{"title":"JoinReader","details":["t@primary","Filter: $2 = 1","Out: $0,$1,$2"]}},` +
+		`{"nodeIdx":1,"core":{"title":"Response"}}],"edges":[` +
+		`{"sourceProc":0,"sourceOutput":0,"destProc":2,"destInput":1},` +
+		`{"sourceProc":1,"sourceOutput":0,"destProc":2,"destInput":1},` +
+		`{"sourceProc":2,"sourceOutput":0,"destProc":3,"destInput":0}]}`
+	diagram := strings.TrimSpace(buf.String())
+	if diagram != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, diagram)
+	}
+
+	url := PlanDiagramURL([]byte(diagram))
+	if !strings.HasPrefix(url, PlanDiagramURLPrefix) {
+		t.Fatalf("unexpected URL %s", url)
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(url, PlanDiagramURLPrefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != diagram {
+		t.Errorf("expected the URL to encode the diagram, got %s", decoded)
+	}
+
+	// A stream without a destination is an error.
+	delete(flows, 2)
+	if err := GeneratePlanDiagram(2, flows, &buf); !testutils.IsError(err, "no destination") {
+		t.Errorf("expected an error about the missing stream, got %v", err)
+	}
+}
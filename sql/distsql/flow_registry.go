@@ -107,6 +107,19 @@ func (fr *flowRegistry) UnregisterFlow(id FlowID) {
 	fr.mu.Unlock()
 }
 
+// numFlows returns the number of flows registered and not unregistered yet.
+func (fr *flowRegistry) numFlows() int {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	n := 0
+	for _, entry := range fr.flows {
+		if entry.flow != nil {
+			n++
+		}
+	}
+	return n
+}
+
 // LookupFlow returns the registered flow with the given ID. If no such flow is
 // registered, waits until it gets registered - up to the given timeout. If the
 // timeout elapses, returns nil.
@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/rpc"
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/pkg/errors"
 )
@@ -36,6 +37,9 @@ type ServerContext struct {
 	Context    context.Context
 	DB         *client.DB
 	RPCContext *rpc.Context
+	// FlowDrainWait is the maximum time Drain waits for the running flows to
+	// finish.
+	FlowDrainWait time.Duration
 }
 
 // ServerImpl implements the server for the distributed SQL APIs.
@@ -68,6 +72,22 @@ func (ds *ServerImpl) SetNodeID(nodeID roachpb.NodeID) {
 	ds.ServerContext.Context = log.WithLogTagInt(ds.ServerContext.Context, "node", int(nodeID))
 }
 
+// Drain waits up to FlowDrainWait for the flows running on the node to
+// finish. The node keeps accepting new flows while it drains: the gateways of
+// the other nodes plan them on the ranges whose leases it still holds. Flows
+// still running after the wait are only logged, so that the rest of the drain
+// (e.g. the transfer of the leases) is not held up by them.
+func (ds *ServerImpl) Drain() {
+	if err := util.RetryForDuration(ds.FlowDrainWait, func() error {
+		if n := ds.flowRegistry.numFlows(); n != 0 {
+			return errors.Errorf("timed out waiting for %d running flows to finish", n)
+		}
+		return nil
+	}); err != nil {
+		log.Warningf(ds.Context, "drain: %s", err)
+	}
+}
+
 func (ds *ServerImpl) setupTxn(
 	ctx context.Context,
 	txnProto *roachpb.Transaction,
@@ -85,11 +105,12 @@ func (ds *ServerImpl) SetupSimpleFlow(
 ) (*Flow, error) {
 	txn := ds.setupTxn(ctx, &req.Txn)
 	flowCtx := FlowCtx{
-		Context: ds.ServerContext.Context,
-		id:      req.Flow.FlowID,
-		evalCtx: &ds.evalCtx,
-		rpcCtx:  ds.RPCContext,
-		txn:     txn,
+		Context:  ds.ServerContext.Context,
+		id:       req.Flow.FlowID,
+		evalCtx:  &ds.evalCtx,
+		rpcCtx:   ds.RPCContext,
+		txn:      txn,
+		clientDB: ds.DB,
 	}
 
 	f := newFlow(flowCtx, ds.flowRegistry, output)
@@ -134,11 +155,12 @@ func (ds *ServerImpl) SetupFlow(ctx context.Context, req *SetupFlowRequest) (
 
 	txn := ds.setupTxn(ds.ServerContext.Context, &req.Txn)
 	flowCtx := FlowCtx{
-		Context: ds.ServerContext.Context,
-		id:      req.Flow.FlowID,
-		evalCtx: &ds.evalCtx,
-		rpcCtx:  ds.RPCContext,
-		txn:     txn,
+		Context:  ds.ServerContext.Context,
+		id:       req.Flow.FlowID,
+		evalCtx:  &ds.evalCtx,
+		rpcCtx:   ds.RPCContext,
+		txn:      txn,
+		clientDB: ds.DB,
 	}
 	f := newFlow(flowCtx, ds.flowRegistry, nil)
 	err := f.setupFlow(&req.Flow)
@@ -19,6 +19,7 @@ package distsql
 import (
 	"io"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -28,6 +29,8 @@ import (
 	"github.com/cockroachdb/cockroach/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/testutils/sqlutils"
 	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+	"github.com/cockroachdb/cockroach/util/uuid"
 )
 
 func TestServer(t *testing.T) {
@@ -102,3 +105,40 @@ func TestServer(t *testing.T) {
 		t.Errorf("invalid results: %s, expected %s'", str, expected)
 	}
 }
+
+func TestServerDrain(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ds := NewServer(ServerContext{Context: context.Background()})
+	id := FlowID{uuid.MakeV4()}
+	ds.flowRegistry.RegisterFlow(id, &Flow{})
+
+	// Drain waits for the registered flow to finish.
+	ds.FlowDrainWait = time.Minute
+	done := make(chan struct{})
+	go func() {
+		ds.Drain()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("drain returned while a flow was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	ds.flowRegistry.UnregisterFlow(id)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("drain did not return after the flow finished")
+	}
+
+	// Drain gives up on flows still running after FlowDrainWait.
+	ds.flowRegistry.RegisterFlow(id, &Flow{})
+	ds.FlowDrainWait = 10 * time.Millisecond
+	start := timeutil.Now()
+	ds.Drain()
+	if elapsed := timeutil.Since(start); elapsed < ds.FlowDrainWait {
+		t.Fatalf("drain returned after %s, expected to wait %s", elapsed, ds.FlowDrainWait)
+	}
+	ds.flowRegistry.UnregisterFlow(id)
+}
@@ -21,7 +21,9 @@ import (
 
 	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings/cluster"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/privilege"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
@@ -86,6 +88,21 @@ func (p *planner) DropDatabase(n *parser.DropDatabase) (planNode, error) {
 		td[i] = tbDesc
 	}
 
+	// Views in other databases cannot be dropped along with this database.
+	tbIDs := make(map[sqlbase.ID]struct{}, len(td))
+	for _, tbDesc := range td {
+		tbIDs[tbDesc.ID] = struct{}{}
+	}
+	for _, tbDesc := range td {
+		for _, id := range tbDesc.DependedOnBy {
+			if _, ok := tbIDs[id]; !ok {
+				if err := p.canRemoveDependentView(tbDesc, id, parser.DropRestrict); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	return &dropDatabaseNode{n: n, p: p, dbDesc: dbDesc, td: td}, nil
 }
 
@@ -213,6 +230,9 @@ func (n *dropIndexNode) Start() error {
 			// Index does not exist, but we want it to: error out.
 			return err
 		}
+		if tableDesc.IsPrimaryKeyChanging() {
+			return primaryKeyChangingError(tableDesc)
+		}
 		// Queue the mutation.
 		switch status {
 		case sqlbase.DescriptorActive:
@@ -335,6 +355,9 @@ func (p *planner) DropTable(n *parser.DropTable) (planNode, error) {
 			// Table does not exist, but we want it to: error out.
 			return nil, sqlbase.NewUndefinedTableError(name.String())
 		}
+		if !droppedDesc.IsTable() {
+			return nil, sqlbase.NewWrongObjectTypeError(droppedDesc.Name, "table")
+		}
 
 		for _, idx := range droppedDesc.AllNonDropIndexes() {
 			for _, ref := range idx.ReferencedBy {
@@ -348,6 +371,11 @@ func (p *planner) DropTable(n *parser.DropTable) (planNode, error) {
 				}
 			}
 		}
+		for _, id := range droppedDesc.DependedOnBy {
+			if err := p.canRemoveDependentView(droppedDesc, id, n.DropBehavior); err != nil {
+				return nil, err
+			}
+		}
 		td = append(td, droppedDesc)
 	}
 
@@ -400,6 +428,67 @@ func (p *planner) canRemoveInterleave(
 	return nil
 }
 
+// canRemoveDependentView checks whether the view with the given ID, which
+// depends on the table or view being dropped, can be dropped along with it.
+func (p *planner) canRemoveDependentView(
+	from *sqlbase.TableDescriptor, viewID sqlbase.ID, behavior parser.DropBehavior,
+) error {
+	if behavior != parser.DropCascade {
+		return p.dependentViewError("drop", from.TypeName(), from.Name, viewID)
+	}
+	viewDesc, err := sqlbase.GetTableDescFromID(p.txn, viewID)
+	if err != nil {
+		return err
+	}
+	if err := p.checkPrivilege(viewDesc, privilege.DROP); err != nil {
+		return err
+	}
+	for _, id := range viewDesc.DependedOnBy {
+		if err := p.canRemoveDependentView(viewDesc, id, behavior); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependentViewError returns an error reporting that an action cannot be
+// performed on a table, view or column because a view depends on it.
+func (p *planner) dependentViewError(action, typeName, objName string, viewID sqlbase.ID) error {
+	viewDesc, err := sqlbase.GetTableDescFromID(p.txn, viewID)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("cannot %s %s %q because view %q depends on it",
+		action, typeName, objName, viewDesc.Name)
+}
+
+// removeDependentViews drops the views that depend on the given table or
+// view, recursively. It returns the names of the dropped views.
+func (p *planner) removeDependentViews(desc *sqlbase.TableDescriptor) ([]string, error) {
+	var droppedViews []string
+	for _, id := range desc.DependedOnBy {
+		viewDesc, err := sqlbase.GetTableDescFromID(p.txn, id)
+		if err != nil {
+			return nil, err
+		}
+		if viewDesc.Deleted() {
+			// The view was already dropped through another dependency.
+			continue
+		}
+		cascaded, err := p.removeDependentViews(viewDesc)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.dropTableImpl(viewDesc); err != nil {
+			return nil, err
+		}
+		droppedViews = append(droppedViews, cascaded...)
+		droppedViews = append(droppedViews, viewDesc.Name)
+	}
+	desc.DependedOnBy = nil
+	return droppedViews, nil
+}
+
 func (p *planner) removeFK(ref sqlbase.ForeignKeyReference, table *sqlbase.TableDescriptor) error {
 	if table == nil {
 		var err error
@@ -434,6 +523,10 @@ func (n *dropTableNode) Start() error {
 		if droppedDesc == nil {
 			continue
 		}
+		cascadeDroppedViews, err := n.p.removeDependentViews(droppedDesc)
+		if err != nil {
+			return err
+		}
 		if err := n.p.dropTableImpl(droppedDesc); err != nil {
 			return err
 		}
@@ -445,10 +538,11 @@ func (n *dropTableNode) Start() error {
 			int32(droppedDesc.ID),
 			int32(n.p.evalCtx.NodeID),
 			struct {
-				TableName string
-				Statement string
-				User      string
-			}{droppedDesc.Name, n.n.String(), n.p.session.User},
+				TableName           string
+				Statement           string
+				User                string
+				CascadeDroppedViews []string
+			}{droppedDesc.Name, n.n.String(), n.p.session.User, cascadeDroppedViews},
 		); err != nil {
 			return err
 		}
@@ -468,6 +562,184 @@ func (n *dropTableNode) ExplainPlan(v bool) (string, string, []planNode) {
 	return "drop table", "", nil
 }
 
+type dropViewNode struct {
+	p  *planner
+	n  *parser.DropView
+	td []*sqlbase.TableDescriptor
+}
+
+// DropView drops a view.
+// Privileges: DROP on view.
+//   Notes: postgres allows only the view owner to DROP a view.
+//          mysql requires the DROP privilege on the view.
+func (p *planner) DropView(n *parser.DropView) (planNode, error) {
+	td := make([]*sqlbase.TableDescriptor, 0, len(n.Names))
+	for _, name := range n.Names {
+		tn, err := name.NormalizeTableName()
+		if err != nil {
+			return nil, err
+		}
+		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+			return nil, err
+		}
+
+		droppedDesc, err := p.dropTablePrepare(tn)
+		if err != nil {
+			return nil, err
+		}
+		if droppedDesc == nil {
+			if n.IfExists {
+				continue
+			}
+			// View does not exist, but we want it to: error out.
+			return nil, sqlbase.NewUndefinedTableError(name.String())
+		}
+		if !droppedDesc.IsView() {
+			return nil, sqlbase.NewWrongObjectTypeError(droppedDesc.Name, "view")
+		}
+
+		for _, id := range droppedDesc.DependedOnBy {
+			if err := p.canRemoveDependentView(droppedDesc, id, n.DropBehavior); err != nil {
+				return nil, err
+			}
+		}
+		td = append(td, droppedDesc)
+	}
+
+	if len(td) == 0 {
+		return &emptyNode{}, nil
+	}
+	return &dropViewNode{p: p, n: n, td: td}, nil
+}
+
+func (n *dropViewNode) expandPlan() error {
+	return nil
+}
+
+func (n *dropViewNode) Start() error {
+	for _, droppedDesc := range n.td {
+		cascadeDroppedViews, err := n.p.removeDependentViews(droppedDesc)
+		if err != nil {
+			return err
+		}
+		if err := n.p.dropTableImpl(droppedDesc); err != nil {
+			return err
+		}
+		// Log a Drop View event for this view. This is an auditable log event
+		// and is recorded in the same transaction as the table descriptor
+		// update.
+		if err := MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+			EventLogDropView,
+			int32(droppedDesc.ID),
+			int32(n.p.evalCtx.NodeID),
+			struct {
+				ViewName            string
+				Statement           string
+				User                string
+				CascadeDroppedViews []string
+			}{droppedDesc.Name, n.n.String(), n.p.session.User, cascadeDroppedViews},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *dropViewNode) Next() (bool, error)                 { return false, nil }
+func (n *dropViewNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *dropViewNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *dropViewNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *dropViewNode) ExplainTypes(_ func(string, string)) {}
+func (n *dropViewNode) DebugValues() debugValues            { return debugValues{} }
+func (n *dropViewNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *dropViewNode) MarkDebug(mode explainMode)          {}
+func (n *dropViewNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "drop view", "", nil
+}
+
+type dropSequenceNode struct {
+	p  *planner
+	n  *parser.DropSequence
+	td []*sqlbase.TableDescriptor
+}
+
+// DropSequence drops a sequence.
+// Privileges: DROP on sequence.
+//   Notes: postgres allows only the sequence owner to DROP a sequence.
+func (p *planner) DropSequence(n *parser.DropSequence) (planNode, error) {
+	td := make([]*sqlbase.TableDescriptor, 0, len(n.Names))
+	for _, name := range n.Names {
+		tn, err := name.NormalizeTableName()
+		if err != nil {
+			return nil, err
+		}
+		if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+			return nil, err
+		}
+
+		droppedDesc, err := p.dropTablePrepare(tn)
+		if err != nil {
+			return nil, err
+		}
+		if droppedDesc == nil {
+			if n.IfExists {
+				continue
+			}
+			// Sequence does not exist, but we want it to: error out.
+			return nil, sqlbase.NewUndefinedTableError(name.String())
+		}
+		if !droppedDesc.IsSequence() {
+			return nil, sqlbase.NewWrongObjectTypeError(droppedDesc.Name, "sequence")
+		}
+		td = append(td, droppedDesc)
+	}
+
+	if len(td) == 0 {
+		return &emptyNode{}, nil
+	}
+	return &dropSequenceNode{p: p, n: n, td: td}, nil
+}
+
+func (n *dropSequenceNode) expandPlan() error {
+	return nil
+}
+
+func (n *dropSequenceNode) Start() error {
+	for _, droppedDesc := range n.td {
+		if err := n.p.dropTableImpl(droppedDesc); err != nil {
+			return err
+		}
+		// Log a Drop Sequence event for this sequence. This is an auditable log
+		// event and is recorded in the same transaction as the table descriptor
+		// update.
+		if err := MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+			EventLogDropSequence,
+			int32(droppedDesc.ID),
+			int32(n.p.evalCtx.NodeID),
+			struct {
+				SequenceName string
+				Statement    string
+				User         string
+			}{droppedDesc.Name, n.n.String(), n.p.session.User},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *dropSequenceNode) Next() (bool, error)                 { return false, nil }
+func (n *dropSequenceNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *dropSequenceNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *dropSequenceNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *dropSequenceNode) ExplainTypes(_ func(string, string)) {}
+func (n *dropSequenceNode) DebugValues() debugValues            { return debugValues{} }
+func (n *dropSequenceNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *dropSequenceNode) MarkDebug(mode explainMode)          {}
+func (n *dropSequenceNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "drop sequence", "", nil
+}
+
 // dropTablePrepare/dropTableImpl is used to drop a single table by
 // name, which can result from either a DROP TABLE or DROP DATABASE
 // statement. This method returns the dropped table descriptor, to be
@@ -531,6 +803,13 @@ func (p *planner) dropTableImpl(tableDesc *sqlbase.TableDescriptor) error {
 		}
 	}
 
+	// Remove the back-references from the tables and views a view depends on.
+	for _, depID := range tableDesc.DependsOn {
+		if err := p.removeViewBackReference(tableDesc, depID); err != nil {
+			return err
+		}
+	}
+
 	verifyMetadataCallback := func(systemConfig config.SystemConfig, tableID sqlbase.ID) error {
 		desc, err := GetTableDesc(systemConfig, tableID)
 		if err != nil {
@@ -593,19 +872,65 @@ func (p *planner) removeInterleaveBackReference(
 	return p.saveNonmutationAndNotify(t)
 }
 
+func (p *planner) removeViewBackReference(
+	viewDesc *sqlbase.TableDescriptor, dependencyID sqlbase.ID,
+) error {
+	t, err := sqlbase.GetTableDescFromID(p.txn, dependencyID)
+	if err != nil {
+		return errors.Errorf("error resolving dependency ID %d: %v", dependencyID, err)
+	}
+	for k, id := range t.DependedOnBy {
+		if id == viewDesc.ID {
+			t.DependedOnBy = append(t.DependedOnBy[:k], t.DependedOnBy[k+1:]...)
+			break
+		}
+	}
+	return p.saveNonmutationAndNotify(t)
+}
+
 // truncateAndDropTable batches all the commands required for truncating and deleting the
 // table descriptor.
 // It is called from a mutation, async wrt the DROP statement.
 func truncateAndDropTable(tableDesc *sqlbase.TableDescriptor, db *client.DB) error {
-	return db.Txn(func(txn *client.Txn) error {
-		if err := truncateTable(tableDesc, txn); err != nil {
+	// The data of a table which isn't interleaved occupies its own span, and
+	// is removed with ClearRange, or with range tombstones until all the nodes
+	// support it, instead of one deletion per row. The table is no longer in
+	// use, so the deletion doesn't need to be atomic.
+	clearTableSpan := !tableDesc.IsSequence() && !tableDesc.IsView() && !tableDesc.IsInterleaved()
+	if clearTableSpan {
+		tablePrefix := roachpb.Key(keys.MakeTablePrefix(uint32(tableDesc.ID)))
+		var err error
+		if cluster.IsActive(cluster.VersionClearRange) {
+			err = db.ClearRange(tablePrefix, tablePrefix.PrefixEnd())
+		} else {
+			err = db.DelRangeUsingTombstone(tablePrefix, tablePrefix.PrefixEnd())
+		}
+		if err != nil {
 			return err
 		}
+	}
+	return db.Txn(func(txn *client.Txn) error {
+		// Views don't store any data, and sequences only store their value.
+		if tableDesc.IsSequence() {
+			if err := txn.Del(sqlbase.MakeSequenceKey(tableDesc.ID)); err != nil {
+				return err
+			}
+		} else if !tableDesc.IsView() && !clearTableSpan {
+			if err := truncateTable(tableDesc, txn); err != nil {
+				return err
+			}
+		}
 		zoneKey, nameKey, descKey := getKeysForTableDescriptor(tableDesc)
 		// Delete table descriptor
 		b := &client.Batch{}
 		b.Del(descKey)
-		b.Del(nameKey)
+		// Keep the name if it has been taken over by the replacement of the
+		// table by TRUNCATE.
+		if gr, err := txn.Get(nameKey); err != nil {
+			return err
+		} else if gr.Exists() && sqlbase.ID(gr.ValueInt()) == tableDesc.ID {
+			b.Del(nameKey)
+		}
 		// Delete the zone config entry for this table.
 		b.Del(zoneKey)
 		txn.SetSystemConfigTrigger()
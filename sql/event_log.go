@@ -41,6 +41,14 @@ const (
 	EventLogCreateTable EventLogType = "create_table"
 	// EventLogDropTable is recorded when a table is dropped.
 	EventLogDropTable EventLogType = "drop_table"
+	// EventLogCreateView is recorded when a view is created.
+	EventLogCreateView EventLogType = "create_view"
+	// EventLogDropView is recorded when a view is dropped.
+	EventLogDropView EventLogType = "drop_view"
+	// EventLogCreateSequence is recorded when a sequence is created.
+	EventLogCreateSequence EventLogType = "create_sequence"
+	// EventLogDropSequence is recorded when a sequence is dropped.
+	EventLogDropSequence EventLogType = "drop_sequence"
 
 	// EventLogAlterTable is recorded when a table is altered.
 	EventLogAlterTable EventLogType = "alter_table"
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sync"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/context"
 	"gopkg.in/inf.v0"
@@ -30,10 +31,14 @@ import (
 	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/sql/distsql"
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/duration"
 	"github.com/cockroachdb/cockroach/util/hlc"
@@ -41,6 +46,7 @@ import (
 	"github.com/cockroachdb/cockroach/util/metric"
 	"github.com/cockroachdb/cockroach/util/stop"
 	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/cockroachdb/cockroach/util/timeutil"
 	"github.com/pkg/errors"
 )
 
@@ -66,6 +72,21 @@ const (
 	MetricDdlName         = "sql.ddl.count"
 	MetricMiscName        = "sql.misc.count"
 	MetricQueryName       = "sql.query.count"
+
+	// Per-statement-type latency metrics. Each of the prefixes below is
+	// combined with one of the phase suffixes to form the name of a set of
+	// latency histograms, e.g. "sql.select.exec.latency".
+	MetricSelectLatencyPrefix = "sql.select"
+	MetricUpdateLatencyPrefix = "sql.update"
+	MetricInsertLatencyPrefix = "sql.insert"
+	MetricDeleteLatencyPrefix = "sql.delete"
+	MetricDdlLatencyPrefix    = "sql.ddl"
+	MetricMiscLatencyPrefix   = "sql.misc"
+
+	MetricParseLatencySuffix   = ".parse.latency"
+	MetricPlanLatencySuffix    = ".plan.latency"
+	MetricExecLatencySuffix    = ".exec.latency"
+	MetricServiceLatencySuffix = ".service.latency"
 )
 
 // TODO(radu): experimental code for testing distSQL flows.
@@ -116,6 +137,9 @@ type Result struct {
 	// the result set of the result.
 	// TODO(nvanbenschoten): Can this be streamed from the planNode?
 	Rows []ResultRow
+	// CopyFormat will be populated if the statement type is "CopyOut". It
+	// describes how the Rows are to be sent to the client.
+	CopyFormat CopyFormat
 }
 
 // ResultColumn contains the name and type of a SQL "cell".
@@ -157,6 +181,25 @@ type Executor struct {
 	miscCount        *metric.Counter
 	queryCount       *metric.Counter
 
+	// Latency histograms broken down by statement type.
+	selectLatency phaseLatencies
+	updateLatency phaseLatencies
+	insertLatency phaseLatencies
+	deleteLatency phaseLatencies
+	ddlLatency    phaseLatencies
+	miscLatency   phaseLatencies
+
+	// sqlStats tracks per-fingerprint statistics for the statements executed
+	// by this executor.
+	sqlStats sqlStats
+
+	// tempSessions tracks the sessions that own temporary tables.
+	tempSessions temporarySessions
+
+	// memMonitor is the root monitor of the memory used by the sessions,
+	// bounded by ExecutorContext.MemoryPoolSize.
+	memMonitor mon.MemoryMonitor
+
 	// System Config and mutex.
 	systemConfig   config.SystemConfig
 	databaseCache  *databaseCache
@@ -167,6 +210,31 @@ type Executor struct {
 	systemConfigCond *sync.Cond
 }
 
+// phaseLatencies holds the latency histograms tracking the different phases
+// of execution of a single type of statement.
+type phaseLatencies struct {
+	// parse is the time spent parsing. Since statements are parsed in
+	// batches, each statement is attributed an equal share of its batch's
+	// parse time.
+	parse metric.Histograms
+	// plan is the time spent building the plan for the statement.
+	plan metric.Histograms
+	// exec is the time spent running the plan and consuming its results.
+	exec metric.Histograms
+	// service is the total time spent executing the statement, including
+	// txn handling, planning and execution.
+	service metric.Histograms
+}
+
+func makePhaseLatencies(registry *metric.Registry, prefix string) phaseLatencies {
+	return phaseLatencies{
+		parse:   registry.Latency(prefix + MetricParseLatencySuffix),
+		plan:    registry.Latency(prefix + MetricPlanLatencySuffix),
+		exec:    registry.Latency(prefix + MetricExecLatencySuffix),
+		service: registry.Latency(prefix + MetricServiceLatencySuffix),
+	}
+}
+
 // An ExecutorContext encompasses the auxiliary objects and configuration
 // required to create an executor.
 // All fields holding a pointer or an interface are required to create
@@ -179,6 +247,32 @@ type ExecutorContext struct {
 	Clock        *hlc.Clock
 	DistSQLSrv   *distsql.ServerImpl
 
+	// MemoryPoolSize is the maximum number of bytes the SQL sessions of this
+	// node can use for buffering rows (e.g. for sorts, aggregations and
+	// results). Zero means no limit.
+	MemoryPoolSize int64
+	// TempStorage, if set, is the engine sorts and aggregations spill their
+	// rows to once they exceed their memory budget.
+	TempStorage engine.Engine
+
+	// SessionRegistry tracks the client sessions open on this node.
+	SessionRegistry *SessionRegistry
+	// StatusServer is used to gather information from other nodes, e.g. for
+	// SHOW CLUSTER QUERIES.
+	StatusServer serverpb.StatusServer
+	// StatsRefresher, if set, refreshes the statistics of the tables modified
+	// on this node.
+	StatsRefresher *TableStatsRefresher
+	// TableStatsCache, if set, provides the table statistics used to
+	// estimate the cost of the plans.
+	TableStatsCache *TableStatisticsCache
+	// JobRegistry, if set, tracks the long-running operations (e.g. BACKUP)
+	// coordinated by this node as jobs.
+	JobRegistry *JobRegistry
+	// Stopper runs the operations outliving the statements starting them,
+	// e.g. changefeeds.
+	Stopper *stop.Stopper
+
 	TestingKnobs *ExecutorTestingKnobs
 }
 
@@ -235,11 +329,20 @@ type ExecutorTestingKnobs struct {
 	// backfill and return an error to the caller of the SchemaChanger.exec().
 	SchemaChangersStartBackfillNotification func() error
 
+	// SchemaChangersBackfillChunkNotification is called after every chunk of
+	// an index backfill is written and checkpointed. It returns error to stop
+	// the backfill and return an error to the caller of the SchemaChanger.exec().
+	SchemaChangersBackfillChunkNotification func() error
+
 	//SyncSchemaChangersRenameOldNameNotInUseNotification is called during a rename
 	//schema change, after all leases on the version of the descriptor with the
 	//old name are gone, and just before the mapping of the old name to the
 	//descriptor id is about to be deleted.
 	SyncSchemaChangersRenameOldNameNotInUseNotification func()
+
+	// ChangefeedSink, if set, creates the sinks of the changefeeds instead of
+	// the sinks named by their URIs.
+	ChangefeedSink func(sinkURI string, tables []string) (ChangefeedSink, error)
 }
 
 // NewExecutor creates an Executor and registers a callback on the
@@ -262,8 +365,16 @@ func NewExecutor(ctx ExecutorContext, stopper *stop.Stopper, registry *metric.Re
 		ddlCount:         registry.Counter(MetricDdlName),
 		miscCount:        registry.Counter(MetricMiscName),
 		queryCount:       registry.Counter(MetricQueryName),
+
+		selectLatency: makePhaseLatencies(registry, MetricSelectLatencyPrefix),
+		updateLatency: makePhaseLatencies(registry, MetricUpdateLatencyPrefix),
+		insertLatency: makePhaseLatencies(registry, MetricInsertLatencyPrefix),
+		deleteLatency: makePhaseLatencies(registry, MetricDeleteLatencyPrefix),
+		ddlLatency:    makePhaseLatencies(registry, MetricDdlLatencyPrefix),
+		miscLatency:   makePhaseLatencies(registry, MetricMiscLatencyPrefix),
 	}
 	exec.systemConfigCond = sync.NewCond(exec.systemConfigMu.RLocker())
+	exec.memMonitor = mon.MakeMonitor("sql", ctx.MemoryPoolSize)
 
 	gossipUpdateC := ctx.Gossip.RegisterSystemConfigChannel()
 	stopper.RunWorker(func() {
@@ -303,6 +414,7 @@ func (e *Executor) updateSystemConfig(cfg config.SystemConfig) {
 	e.systemConfigMu.Lock()
 	defer e.systemConfigMu.Unlock()
 	e.systemConfig = cfg
+	refreshSettings(e.Ctx(), cfg)
 	// The database cache gets reset whenever the system config changes.
 	e.databaseCache = &databaseCache{
 		databases: map[string]sqlbase.ID{},
@@ -329,7 +441,7 @@ func (e *Executor) Prepare(
 ) ([]ResultColumn, error) {
 	if log.V(2) {
 		log.Infof(session.Ctx(), "preparing: %s", query)
-	} else if traceSQL {
+	} else if traceSQL.Get() {
 		log.Tracef(session.Ctx(), "preparing: %s", query)
 	}
 	stmt, err := parser.ParseOne(query, parser.Syntax(session.Syntax))
@@ -429,6 +541,20 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 	var res StatementResults
 	txnState := &session.TxnState
 	planMaker := &session.planner
+	// The results of the previous request have been sent to the client by now.
+	session.resultsMemAcc.Clear()
+	if session.stopIdleTxnTimer() {
+		// The transaction was rolled back while the session was idle in it.
+		// Report it and move the SQL txn to the Aborted state, which requires
+		// a ROLLBACK from the client.
+		e.txnAbortCount.Inc(1)
+		txnState.clearSavepoints()
+		txnState.resetStateAndTxn(Aborted)
+		res.ResultList = append(res.ResultList, Result{Err: sqlbase.NewIdleInTxnSessionTimeoutError()})
+		return res
+	}
+	defer session.startIdleTxnTimer()
+	parseStart := timeutil.Now()
 	stmts, err := planMaker.parser.Parse(sql, parser.Syntax(session.Syntax))
 	if err != nil {
 		// A parse error occurred: we can't determine if there were multiple
@@ -444,6 +570,30 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 		res.Empty = true
 		return res
 	}
+	e.recordParseLatency(stmts, timeutil.Since(parseStart))
+	if len(stmts) > 1 {
+		for _, stmt := range stmts {
+			if _, ok := stmt.(*parser.CopyFrom); ok {
+				// The data of the COPY follows the statement, so the statements
+				// after it can't be run before it is received.
+				err := errors.New("COPY must be the only statement in the query")
+				if txnState.txn != nil {
+					txnState.updateStateAndCleanupOnErr(err, e)
+				}
+				res.ResultList = append(res.ResultList, Result{Err: err})
+				return res
+			}
+		}
+	}
+	return e.execParsed(session, stmts)
+}
+
+// execParsed executes the parsed statements of a request, as described by
+// execRequest.
+func (e *Executor) execParsed(session *Session, stmts parser.StatementList) StatementResults {
+	var res StatementResults
+	txnState := &session.TxnState
+	planMaker := &session.planner
 
 	// If the planMaker wants config updates to be blocked, then block them.
 	defer planMaker.blockConfigUpdatesMaybe(e)()
@@ -471,6 +621,7 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 				stmtsToExec = stmtsToExec[:1]
 				// Check for AS OF SYSTEM TIME. If it is present but not detected here,
 				// it will raise an error later on.
+				var err error
 				protoTS, err = isAsOf(planMaker, stmtsToExec[0], execOpt.MinInitialTimestamp)
 				if err != nil {
 					res.ResultList = append(res.ResultList, Result{Err: err})
@@ -504,12 +655,19 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 		var results []Result
 		origState := txnState.State
 
+		// attempt counts the number of times txnClosure has been called.
+		attempt := 0
+		txnState.autoRetryCounter = 0
 		txnClosure := func(txn *client.Txn, opt *client.TxnExecOptions) error {
 			if txnState.State == Open && txnState.txn != txn {
 				panic(fmt.Sprintf("closure wasn't called in the txn we set up for it."+
 					"\ntxnState.txn:%+v\ntxn:%+v\ntxnState:%+v", txnState.txn, txn, txnState))
 			}
 			txnState.txn = txn
+			if attempt > 0 {
+				txnState.autoRetryCounter++
+			}
+			attempt++
 
 			if protoTS != nil {
 				setTxnTimestamps(txnState.txn, *protoTS)
@@ -564,9 +722,10 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 		}
 		// If the txn is in any state but Open, exec the schema changes. They'll
 		// short-circuit themselves if the mutation that queued them has been
-		// rolled back from the table descriptor.
+		// rolled back from the table descriptor. A txn that can still be rolled
+		// back to a savepoint is treated as open.
 		stmtsExecuted := stmts[:len(stmtsToExec)-len(remainingStmts)]
-		if txnState.State != Open {
+		if txnState.State != Open && !txnState.canRollbackToSavepoint() {
 			planMaker.checkTestingVerifyMetadataInitialOrDie(e, stmts)
 			planMaker.checkTestingVerifyMetadataOrDie(e, stmtsExecuted)
 			// Exec the schema changers (if the txn rolled back, the schema changers
@@ -597,7 +756,7 @@ func (e *Executor) execRequest(session *Session, sql string) StatementResults {
 
 // If the plan has a fast path we attempt to query that,
 // otherwise we fall back to counting via plan.Next().
-func countRowsAffected(p planNode) (int, error) {
+func countRowsAffected(ctx context.Context, p planNode) (int, error) {
 	if a, ok := p.(planNodeFastPath); ok {
 		if count, res := a.FastPathResults(); res {
 			return count, nil
@@ -606,6 +765,10 @@ func countRowsAffected(p planNode) (int, error) {
 	count := 0
 	next, err := p.Next()
 	for ; next; next, err = p.Next() {
+		// Stop early if the statement was canceled.
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
 		count++
 	}
 	return count, err
@@ -690,10 +853,28 @@ func (e *Executor) execStmtsInCurrentTxn(
 		ctx := planMaker.session.Ctx()
 		if log.V(2) {
 			log.Infof(ctx, "executing %d/%d: %s", i+1, len(stmts), stmt)
-		} else if traceSQL {
+		} else if traceSQL.Get() {
 			log.Tracef(ctx, "executing %d/%d: %s", i+1, len(stmts), stmt)
 		}
 		txnState.schemaChangers.curStatementIdx = i
+		stmtStart := timeutil.Now()
+		queryID := makeQueryID(e.ctx.Clock.Now(), e.nodeID)
+		// The statement runs under its own context, derived from the txn's,
+		// so that canceling it interrupts the statement but not the txn.
+		txn := txnState.txn
+		var txnCtx context.Context
+		var stmtCancel context.CancelFunc
+		if txn != nil {
+			txnCtx = txn.Context
+			txn.Context, stmtCancel = client.WithStatementCancel(txnCtx)
+		}
+		planMaker.activeQuery = planMaker.session.addActiveQuery(queryID, stmt, stmtStart, stmtCancel)
+		var stmtTimer *time.Timer
+		if timeout := planMaker.session.StatementTimeout; timeout > 0 {
+			stmtTimer = time.AfterFunc(timeout, func() {
+				planMaker.session.timeoutQuery(queryID)
+			})
+		}
 
 		var stmtStrBefore string
 		// TODO(nvanbenschoten) Constant literals can change their representation (1.0000 -> 1) when type checking,
@@ -715,12 +896,35 @@ func (e *Executor) execStmtsInCurrentTxn(
 		default:
 			panic(fmt.Sprintf("unexpected txn state: %s", txnState.State))
 		}
+		if stmtTimer != nil {
+			stmtTimer.Stop()
+		}
+		canceled, timedOut := planMaker.session.removeActiveQuery(queryID)
+		planMaker.activeQuery = nil
+		if stmtCancel != nil {
+			stmtCancel()
+			txn.Context = txnCtx
+		}
+		if canceled && err != nil {
+			// Report the cancellation rather than the error it caused deeper
+			// down (e.g. a context cancellation in the KV layer).
+			if timedOut {
+				err = sqlbase.NewStatementTimeoutError()
+			} else {
+				err = sqlbase.NewQueryCanceledError()
+			}
+			res.Err = err
+		}
 		if e.ctx.TestingKnobs.CheckStmtStringChange && false {
 			if after := stmt.String(); after != stmtStrBefore {
 				panic(fmt.Sprintf("statement changed after exec; before:\n    %s\nafter:\n    %s",
 					stmtStrBefore, after))
 			}
 		}
+		serviceLat := timeutil.Since(stmtStart)
+		e.recordServiceLatency(stmt, serviceLat)
+		e.sqlStats.recordStatement(
+			stmt, &res, serviceLat, txnState.retrying || txnState.autoRetryCounter > 0)
 		res.Err = convertToErrWithPGCode(res.Err)
 		results = append(results, res)
 		if err != nil {
@@ -742,8 +946,10 @@ func (e *Executor) execStmtsInCurrentTxn(
 // execStmtInAbortedTxn executes a statement in a txn that's in state
 // Aborted or RestartWait. All statements cause errors except:
 // - COMMIT / ROLLBACK: aborts the current transaction.
-// - ROLLBACK TO SAVEPOINT / SAVEPOINT: reopens the current transaction,
-//   allowing it to be retried.
+// - ROLLBACK TO SAVEPOINT / SAVEPOINT cockroach_restart: reopens the current
+//   transaction, allowing it to be retried.
+// - ROLLBACK TO SAVEPOINT <name>: reopens the current transaction, undoing the
+//   work performed since the savepoint, if it was set before the error.
 func (e *Executor) execStmtInAbortedTxn(
 	stmt parser.Statement, txnState *txnState, planMaker *planner,
 ) (Result, error) {
@@ -754,7 +960,7 @@ func (e *Executor) execStmtInAbortedTxn(
 	// TODO(andrei/cuongdo): Figure out what statements to count here.
 	switch s := stmt.(type) {
 	case *parser.CommitTransaction, *parser.RollbackTransaction:
-		if txnState.State == RestartWait {
+		if txnState.State == RestartWait || txnState.canRollbackToSavepoint() {
 			return rollbackSQLTransaction(txnState, planMaker), nil
 		}
 		// Reset the state to allow new transactions to start.
@@ -776,7 +982,17 @@ func (e *Executor) execStmtInAbortedTxn(
 		default:
 			panic("unreachable")
 		}
-		if err := parser.ValidateRestartCheckpoint(spName); err != nil {
+		if !parser.IsRestartSavepoint(spName) {
+			// Rolling back to a regular savepoint reopens the txn, provided the
+			// error that aborted it didn't also finalize the KV txn.
+			if _, ok := s.(*parser.RollbackToSavepoint); ok && txnState.canRollbackToSavepoint() {
+				if err := txnState.rollbackToSavepoint(spName); err != nil {
+					return Result{Err: err}, err
+				}
+				txnState.State = Open
+				return Result{}, nil
+			}
+			err := sqlbase.NewTransactionAbortedError("")
 			return Result{Err: err}, err
 		}
 		if txnState.State == RestartWait {
@@ -877,8 +1093,12 @@ func (e *Executor) execStmtInOpenTxn(
 		if implicitTxn {
 			return e.noTransactionHelper(txnState)
 		}
-		if err := parser.ValidateRestartCheckpoint(s.Savepoint); err != nil {
-			return Result{Err: err}, err
+		if !parser.IsRestartSavepoint(s.Savepoint) {
+			if err := txnState.releaseSavepoint(s.Savepoint); err != nil {
+				txnState.updateStateAndCleanupOnErr(err, e)
+				return Result{Err: err}, err
+			}
+			return Result{}, nil
 		}
 		// ReleaseSavepoint is executed fully here; there's no planNode for it
 		// and the planner is not involved at all.
@@ -900,8 +1120,10 @@ func (e *Executor) execStmtInOpenTxn(
 		if implicitTxn {
 			return e.noTransactionHelper(txnState)
 		}
-		if err := parser.ValidateRestartCheckpoint(s.Name); err != nil {
-			return Result{Err: err}, err
+		if !parser.IsRestartSavepoint(s.Name) {
+			// Note that Savepoint doesn't have a corresponding plan node.
+			txnState.setSavepoint(s.Name)
+			return Result{}, nil
 		}
 		// We want to disallow SAVEPOINTs to be issued after a transaction has
 		// started running, but such enforcement is problematic in the
@@ -925,11 +1147,13 @@ func (e *Executor) execStmtInOpenTxn(
 		txnState.retryIntent = true
 		return Result{}, nil
 	case *parser.RollbackToSavepoint:
-		err := parser.ValidateRestartCheckpoint(s.Savepoint)
-		if err == nil {
+		var err error
+		if parser.IsRestartSavepoint(s.Savepoint) {
 			// Can't restart if we didn't get an error first, which would've put the
 			// txn in a different state.
 			err = errNotRetriable
+		} else if err = txnState.rollbackToSavepoint(s.Savepoint); err == nil {
+			return Result{}, nil
 		}
 		txnState.updateStateAndCleanupOnErr(err, e)
 		return Result{Err: err}, err
@@ -960,9 +1184,13 @@ func (e *Executor) execStmtInOpenTxn(
 		txnState.tr.LazyLog(stmt, true /* sensitive */)
 	}
 
+	if txnState.sp != nil {
+		log.Tracef(txnState.txn.Context, "executing: %s", stmt)
+	}
+
 	result, err := e.execStmt(stmt, planMaker, implicitTxn /* autoCommit */)
 	if err != nil {
-		if traceSQL {
+		if txnState.sp != nil {
 			log.Tracef(txnState.txn.Context, "ERROR: %v", err)
 		}
 		if txnState.tr != nil {
@@ -975,11 +1203,11 @@ func (e *Executor) execStmtInOpenTxn(
 		switch result.Type {
 		case parser.RowsAffected:
 			tResult.count = result.RowsAffected
-		case parser.Rows:
+		case parser.Rows, parser.CopyOut:
 			tResult.count = len(result.Rows)
 		}
 		txnState.tr.LazyLog(tResult, false)
-		if traceSQL {
+		if txnState.sp != nil {
 			log.Tracef(txnState.txn.Context, "%s done", tResult)
 		}
 	}
@@ -999,7 +1227,8 @@ func rollbackSQLTransaction(txnState *txnState, p *planner) Result {
 	if p.txn != txnState.txn {
 		panic("rollbackSQLTransaction called on a different txn than the planner's")
 	}
-	if txnState.State != Open && txnState.State != RestartWait {
+	if txnState.State != Open && txnState.State != RestartWait &&
+		!txnState.canRollbackToSavepoint() {
 		panic(fmt.Sprintf("rollbackSQLTransaction called on txn in wrong state: %s (txn: %s)",
 			txnState.State, txnState.txn.Proto))
 	}
@@ -1037,6 +1266,9 @@ func commitSQLTransaction(
 	if commitType == commit {
 		txnState.commitSeen = true
 	}
+	// Committing releases all the savepoints; an error can't be recovered
+	// from by rolling back to one of them.
+	txnState.clearSavepoints()
 	err := txnState.txn.Commit()
 	result := Result{PGTag: (*parser.CommitTransaction)(nil).StatementTag()}
 	if err != nil {
@@ -1069,10 +1301,31 @@ func (e *Executor) execStmt(
 	stmt parser.Statement, planMaker *planner, autoCommit bool,
 ) (Result, error) {
 	var result Result
+	// The memory used by the plan is accounted for by a monitor which
+	// releases it all once the statement is done. So are the rows the plan
+	// spilled to temporary storage.
+	stmtMonitor := mon.MakeMonitorWithPool("statement", &planMaker.session.memMonitor)
+	planMaker.memMonitor = &stmtMonitor
+	defer func() {
+		planMaker.closeDiskRowContainers()
+		planMaker.memMonitor = nil
+		stmtMonitor.Stop(planMaker.ctx())
+	}()
+
+	latencies := e.latenciesForStmt(stmt)
+	planStart := timeutil.Now()
 	plan, err := planMaker.makePlan(stmt, autoCommit)
+	latencies.plan.RecordValue(timeutil.Since(planStart).Nanoseconds())
 	if err != nil {
 		return result, err
 	}
+	if planMaker.activeQuery != nil {
+		planMaker.session.setQueryPhase(planMaker.activeQuery, serverpb.ActiveQuery_EXECUTING)
+	}
+	execStart := timeutil.Now()
+	defer func() {
+		latencies.exec.RecordValue(timeutil.Since(execStart).Nanoseconds())
+	}()
 
 	if testDistSQL != 0 {
 		if err := hackPlanToUseDistSQL(plan, testDistSQL == 1); err != nil {
@@ -1089,13 +1342,23 @@ func (e *Executor) execStmt(
 
 	switch result.Type {
 	case parser.RowsAffected:
-		count, err := countRowsAffected(plan)
+		count, err := countRowsAffected(planMaker.ctx(), plan)
 		if err != nil {
 			return result, err
 		}
 		result.RowsAffected += count
 
-	case parser.Rows:
+	case parser.CopyIn:
+		// The data is sent by the client afterwards; the columns tell it how
+		// many values each row holds.
+		result.Columns = plan.Columns()
+
+	case parser.Rows, parser.CopyOut:
+		if copyTo, ok := stmt.(*parser.CopyTo); ok {
+			if result.CopyFormat, err = makeCopyFormat(copyTo.Options); err != nil {
+				return result, err
+			}
+		}
 		result.Columns = plan.Columns()
 		for _, c := range result.Columns {
 			if err := checkResultDatum(c.Typ); err != nil {
@@ -1111,6 +1374,10 @@ func (e *Executor) execStmt(
 
 		next, err := plan.Next()
 		for ; next; next, err = plan.Next() {
+			// Stop early if the statement was canceled.
+			if err := planMaker.ctx().Err(); err != nil {
+				return result, err
+			}
 			// The plan.Values DTuple needs to be copied on each iteration.
 			values := plan.Values()
 
@@ -1124,11 +1391,18 @@ func (e *Executor) execStmt(
 			row := ResultRow{Values: valuesAlloc[:0:n]}
 			valuesAlloc = valuesAlloc[n:]
 
+			rowSize := int64(unsafe.Sizeof(row))
 			for _, val := range values {
 				if err := checkResultDatum(val); err != nil {
 					return result, err
 				}
 				row.Values = append(row.Values, val)
+				rowSize += int64(val.Size())
+			}
+			// The results are kept until they have been sent to the client,
+			// i.e. until the session's next request.
+			if err := planMaker.session.resultsMemAcc.Grow(rowSize); err != nil {
+				return result, err
 			}
 			result.Rows = append(result.Rows, row)
 		}
@@ -1167,6 +1441,48 @@ func (e *Executor) updateStmtCounts(stmt parser.Statement) {
 	}
 }
 
+// latenciesForStmt returns the latency histograms tracking statements of the
+// same type as the given statement. The classification matches the one used
+// by updateStmtCounts, with txn control statements counted as misc.
+func (e *Executor) latenciesForStmt(stmt parser.Statement) *phaseLatencies {
+	switch stmt.(type) {
+	case *parser.Select:
+		return &e.selectLatency
+	case *parser.Update:
+		return &e.updateLatency
+	case *parser.Insert:
+		return &e.insertLatency
+	case *parser.Delete:
+		return &e.deleteLatency
+	}
+	if stmt.StatementType() == parser.DDL {
+		return &e.ddlLatency
+	}
+	return &e.miscLatency
+}
+
+// recordParseLatency attributes an equal share of the time spent parsing a
+// batch of statements to each of the statements in the batch.
+func (e *Executor) recordParseLatency(stmts parser.StatementList, d time.Duration) {
+	share := d.Nanoseconds() / int64(len(stmts))
+	for _, stmt := range stmts {
+		e.latenciesForStmt(stmt).parse.RecordValue(share)
+	}
+}
+
+// recordServiceLatency records the total time spent executing a statement,
+// both in the per-type histograms and in the aggregate sql.latency ones.
+func (e *Executor) recordServiceLatency(stmt parser.Statement, d time.Duration) {
+	e.latenciesForStmt(stmt).service.RecordValue(d.Nanoseconds())
+	e.latency.RecordValue(d.Nanoseconds())
+}
+
+// StatementStatistics returns the statistics accumulated for the statements
+// executed by this executor, sorted by statement fingerprint.
+func (e *Executor) StatementStatistics() []StatementStatisticsEntry {
+	return e.sqlStats.entries()
+}
+
 // Registry returns a registry with the metrics tracked by this executor, which can be used to
 // access its stats or be added to another registry.
 func (e *Executor) Registry() *metric.Registry {
@@ -1252,6 +1568,10 @@ func checkResultDatum(datum parser.Datum) error {
 	case *parser.DTimestamp:
 	case *parser.DTimestampTZ:
 	case *parser.DInterval:
+	case *parser.DArray:
+	case *parser.DJSON:
+	case *parser.DIPAddr:
+	case *parser.DCollatedString:
 	case *parser.DPlaceholder:
 		return fmt.Errorf("could not determine data type of %s %s", datum.Type(), datum)
 	default:
@@ -1319,6 +1639,36 @@ func isAsOf(planMaker *planner, stmt parser.Statement, max hlc.Timestamp) (*hlc.
 	return &ts, nil
 }
 
+// checkAsOfGCTTL verifies that the AS OF SYSTEM TIME timestamp of the
+// current transaction is not older than the GC TTL of the table, as data
+// that old may already have been garbage collected, unless the data of the
+// table is protected from garbage collection at or below that timestamp.
+func (p *planner) checkAsOfGCTTL(desc *sqlbase.TableDescriptor) error {
+	if p.execCtx == nil {
+		return nil
+	}
+	zone, found, err := GetZoneConfig(p.systemConfig, uint32(desc.ID))
+	if err != nil || !found {
+		return err
+	}
+	ttl := time.Duration(zone.GC.TTLSeconds) * time.Second
+	ts := p.txn.Proto.OrigTimestamp
+	if ts.GoTime().Add(ttl).Before(p.execCtx.Clock.PhysicalTime()) {
+		prefix := roachpb.Key(keys.MakeTablePrefix(uint32(desc.ID)))
+		protected, err := p.systemConfig.GetProtectedTimestamp(
+			roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()})
+		if err != nil {
+			return err
+		}
+		if protected != hlc.ZeroTimestamp && !ts.Less(protected) {
+			return nil
+		}
+		return fmt.Errorf("AS OF SYSTEM TIME: timestamp %s is older than the GC TTL of table %q (%s)",
+			ts.GoTime().UTC(), desc.Name, ttl)
+	}
+	return nil
+}
+
 // setTxnTimestamps sets the transaction's proto timestamps and deadline
 // to ts. This is for use with AS OF queries, and should be called in the
 // retry block (except in the case of prepare which doesn't use retry). The
@@ -36,9 +36,11 @@ const (
 	explainPlan
 	explainTrace
 	explainTypes
+	explainAnalyze
+	explainDistSQL
 )
 
-var explainStrings = []string{"", "debug", "plan", "trace", "types"}
+var explainStrings = []string{"", "debug", "plan", "trace", "types", "analyze", "distsql"}
 
 // Explain executes the explain statement, providing debugging and analysis
 // info about the wrapped statement.
@@ -59,6 +61,10 @@ func (p *planner) Explain(n *parser.Explain, autoCommit bool) (planNode, error)
 			newMode = explainPlan
 		} else if strings.EqualFold(opt, "TYPES") {
 			newMode = explainTypes
+		} else if strings.EqualFold(opt, "ANALYZE") || strings.EqualFold(opt, "ANALYSE") {
+			newMode = explainAnalyze
+		} else if strings.EqualFold(opt, "DISTSQL") {
+			newMode = explainDistSQL
 		} else if strings.EqualFold(opt, "VERBOSE") {
 			verbose = true
 		} else if strings.EqualFold(opt, "NOEXPAND") {
@@ -126,6 +132,17 @@ func (p *planner) Explain(n *parser.Explain, autoCommit bool) (planNode, error)
 	case explainTrace:
 		return makeTraceNode(plan, p.txn), nil
 
+	case explainAnalyze:
+		node := &explainAnalyzeNode{
+			p:       p,
+			verbose: verbose,
+			plan:    plan,
+		}
+		return node, nil
+
+	case explainDistSQL:
+		return &explainDistSQLNode{p: p, plan: plan}, nil
+
 	default:
 		return nil, fmt.Errorf("unsupported EXPLAIN mode: %d", mode)
 	}
@@ -0,0 +1,266 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+)
+
+// analyzeColumns are the columns holding the runtime statistics of the plan
+// nodes in the results of EXPLAIN (ANALYZE).
+var analyzeColumns = []ResultColumn{
+	{Name: "Rows", Typ: parser.TypeInt},
+	{Name: "Time", Typ: parser.TypeString},
+	{Name: "KV Batches", Typ: parser.TypeInt},
+	{Name: "Max Memory", Typ: parser.TypeInt},
+}
+
+// explainAnalyzeNode is a planNode that runs the wrapped plan to completion
+// and annotates each of its nodes with the number of rows it produced, the
+// time spent in it, the KV batches it sent and the memory it used. It is used
+// as the top-level node for EXPLAIN (ANALYZE) statements. Like the statement
+// being explained, it performs all its side effects.
+//
+// The statistics of a node include those of the nodes below it. The nodes
+// which are not run through an interface, like the scans of an index join, or
+// which are run on behalf of another node, like sub-queries, are not
+// instrumented: their statistics are accounted for in the node using them.
+type explainAnalyzeNode struct {
+	p       *planner
+	verbose bool
+	plan    planNode
+	results *valuesNode
+}
+
+func (e *explainAnalyzeNode) ExplainTypes(fn func(string, string)) {}
+func (e *explainAnalyzeNode) Next() (bool, error)                  { return e.results.Next() }
+func (e *explainAnalyzeNode) Columns() []ResultColumn              { return e.results.Columns() }
+func (e *explainAnalyzeNode) Ordering() orderingInfo               { return e.results.Ordering() }
+func (e *explainAnalyzeNode) Values() parser.DTuple                { return e.results.Values() }
+func (e *explainAnalyzeNode) DebugValues() debugValues             { return debugValues{} }
+func (e *explainAnalyzeNode) SetLimitHint(n int64, s bool)         { e.results.SetLimitHint(n, s) }
+func (e *explainAnalyzeNode) MarkDebug(mode explainMode)           {}
+
+func (e *explainAnalyzeNode) expandPlan() error {
+	columns := []ResultColumn{
+		{Name: "Level", Typ: parser.TypeInt},
+		{Name: "Type", Typ: parser.TypeString},
+		{Name: "Description", Typ: parser.TypeString},
+	}
+	if e.verbose {
+		columns = append(columns, ResultColumn{Name: "Columns", Typ: parser.TypeString})
+		columns = append(columns, ResultColumn{Name: "Ordering", Typ: parser.TypeString})
+	}
+	columns = append(columns, analyzeColumns...)
+	e.results = &valuesNode{columns: columns}
+
+	if err := e.plan.expandPlan(); err != nil {
+		return err
+	}
+	// The plan is instrumented once it has reached its final form, so that
+	// the nodes introduced by expandPlan are instrumented too.
+	e.plan = e.instrument(e.plan)
+	return nil
+}
+
+func (e *explainAnalyzeNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "explain", "analyze", []planNode{e.plan}
+}
+
+func (e *explainAnalyzeNode) Start() error {
+	if err := e.plan.Start(); err != nil {
+		return err
+	}
+	for {
+		// Stop early if the statement was canceled.
+		if err := e.p.ctx().Err(); err != nil {
+			return err
+		}
+		next, err := e.plan.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+	}
+	e.populate(e.plan, 0)
+	return nil
+}
+
+// instrument wraps the plan and the plans it runs through the planNode
+// interface so as to collect their statistics.
+func (e *explainAnalyzeNode) instrument(plan planNode) planNode {
+	switch n := plan.(type) {
+	case *selectTopNode:
+		// The node only forwards the calls to the plan it connects, which
+		// is instrumented instead. DELETE and UPDATE also expect to find
+		// it as their source.
+		n.plan = e.instrument(n.plan)
+		return n
+
+	case *selectNode:
+		n.source.plan = e.instrument(n.source.plan)
+	case *groupNode:
+		n.plan = e.instrument(n.plan)
+	case *windowNode:
+		n.plan = e.instrument(n.plan)
+	case *sortNode:
+		// A values node is sorted in place rather than through its
+		// interface; see sortNode.Next.
+		if _, ok := n.plan.(*valuesNode); !ok {
+			n.plan = e.instrument(n.plan)
+		}
+	case *distinctNode:
+		n.plan = e.instrument(n.plan)
+	case *limitNode:
+		n.plan = e.instrument(n.plan)
+	case *joinNode:
+		n.left = e.instrument(n.left)
+		n.right = e.instrument(n.right)
+	case *unionNode:
+		n.left = e.instrument(n.left)
+		n.right = e.instrument(n.right)
+
+	case *insertNode:
+		n.run.rows = e.instrument(n.run.rows)
+	case *updateNode:
+		n.run.rows = e.instrument(n.run.rows)
+	case *deleteNode:
+		// DELETE removes the rows of a plain table scan without reading
+		// them when it can; see deleteNode.Start. The scan is left as is
+		// so as not to disable this fast path.
+		sel := n.run.rows.(*selectTopNode).source.(*selectNode)
+		scan := sel.source.plan
+		n.run.rows = e.instrument(n.run.rows)
+		if _, ok := scan.(*scanNode); ok {
+			sel.source.plan = scan
+		}
+	}
+	return &instrumentedNode{planNode: plan, p: e.p}
+}
+
+func (e *explainAnalyzeNode) populate(plan planNode, level int) {
+	if !e.verbose {
+		// A selectTopNode explains itself as the plan it connects, which
+		// holds the statistics.
+		for {
+			top, ok := plan.(*selectTopNode)
+			if !ok {
+				break
+			}
+			plan = top.plan
+		}
+	}
+	name, description, children := plan.ExplainPlan(e.verbose)
+
+	row := parser.DTuple{
+		parser.NewDInt(parser.DInt(level)),
+		parser.NewDString(name),
+		parser.NewDString(description),
+	}
+	if e.verbose {
+		row = append(row, parser.NewDString(formatColumns(plan.Columns(), false)))
+		row = append(row, parser.NewDString(plan.Ordering().AsString(plan.Columns())))
+	}
+	if n, ok := plan.(*instrumentedNode); ok {
+		row = append(row,
+			parser.NewDInt(parser.DInt(n.rowCount())),
+			parser.NewDString(fmt.Sprintf("%.3fms", n.elapsed.Seconds()*1000)),
+			parser.NewDInt(parser.DInt(n.batches)),
+			parser.NewDInt(parser.DInt(n.maxMem)),
+		)
+	} else {
+		for range analyzeColumns {
+			row = append(row, parser.DNull)
+		}
+	}
+	e.results.rows = append(e.results.rows, row)
+
+	for _, child := range children {
+		e.populate(child, level+1)
+	}
+}
+
+// instrumentedNode is a planNode that forwards all the calls to the node it
+// wraps, measuring its Start and Next calls for EXPLAIN (ANALYZE).
+type instrumentedNode struct {
+	planNode
+	p *planner
+
+	rows    int64
+	elapsed time.Duration
+	batches int
+	// memBase is the memory allocated for the statement when the node was
+	// started. maxMem is the most memory allocated on top of it, as sampled
+	// at the end of each call into the node.
+	memBase int64
+	maxMem  int64
+}
+
+func (n *instrumentedNode) Start() error {
+	n.memBase = n.memAllocated()
+	start, batches := timeutil.Now(), n.batchCount()
+	err := n.planNode.Start()
+	n.record(start, batches)
+	return err
+}
+
+func (n *instrumentedNode) Next() (bool, error) {
+	start, batches := timeutil.Now(), n.batchCount()
+	next, err := n.planNode.Next()
+	n.record(start, batches)
+	if next {
+		n.rows++
+	}
+	return next, err
+}
+
+func (n *instrumentedNode) record(start time.Time, batches int) {
+	n.elapsed += timeutil.Since(start)
+	n.batches += n.batchCount() - batches
+	if mem := n.memAllocated() - n.memBase; mem > n.maxMem {
+		n.maxMem = mem
+	}
+}
+
+// rowCount returns the number of rows produced by the node, or affected by
+// it if it did all its work in Start.
+func (n *instrumentedNode) rowCount() int64 {
+	if fp, ok := n.planNode.(planNodeFastPath); ok {
+		if count, ok := fp.FastPathResults(); ok {
+			return int64(count)
+		}
+	}
+	return n.rows
+}
+
+func (n *instrumentedNode) batchCount() int {
+	if n.p.txn == nil {
+		return 0
+	}
+	return n.p.txn.BatchCount()
+}
+
+func (n *instrumentedNode) memAllocated() int64 {
+	if n.p.memMonitor == nil {
+		return 0
+	}
+	return n.p.memMonitor.CurrentlyAllocated()
+}
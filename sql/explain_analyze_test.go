@@ -0,0 +1,111 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestExplainAnalyze(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE test;
+CREATE TABLE test.t (k INT PRIMARY KEY, v INT);
+INSERT INTO test.t VALUES (1, 10), (2, 9), (3, 8), (4, 7), (5, 6), (6, 5), (7, 4), (8, 3), (9, 2), (10, 1);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := sqlDB.Query(`EXPLAIN ANALYZE SELECT k FROM test.t WHERE v > 5 ORDER BY v LIMIT 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pretty := rowsToStrings(rows)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	expCols := []string{"Level", "Type", "Description", "Rows", "Time", "KV Batches", "Max Memory"}
+	if !reflect.DeepEqual(pretty[0], expCols) {
+		t.Fatalf("expected columns %v, got %v", expCols, pretty[0])
+	}
+
+	expected := []struct {
+		typ  string
+		rows int
+	}{
+		{"limit", 3},
+		{"sort", 3},
+		{"scan", 5},
+	}
+	if len(pretty)-1 != len(expected) {
+		t.Fatalf("expected %d nodes, got:\n%s", len(expected), prettyPrint(pretty))
+	}
+	prevBatches := -1
+	for i, exp := range expected {
+		row := pretty[i+1]
+		if row[1] != exp.typ || row[3] != strconv.Itoa(exp.rows) {
+			t.Errorf("expected %s producing %d rows, got:\n%s", exp.typ, exp.rows, prettyPrint(pretty))
+		}
+		if !strings.HasSuffix(row[4], "ms") {
+			t.Errorf("unexpected time %q", row[4])
+		}
+		// The statistics of a node include those of the nodes below it.
+		batches, err := strconv.Atoi(row[5])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prevBatches >= 0 && batches > prevBatches {
+			t.Errorf("%s sent more KV batches than its parent:\n%s", exp.typ, prettyPrint(pretty))
+		}
+		prevBatches = batches
+	}
+	if prevBatches < 1 {
+		t.Errorf("expected the scan to send KV batches:\n%s", prettyPrint(pretty))
+	}
+	if mem, err := strconv.Atoi(pretty[2][6]); err != nil || mem <= 0 {
+		t.Errorf("expected the sort to use memory:\n%s", prettyPrint(pretty))
+	}
+
+	// The statement explained is run, including its side effects.
+	rows, err = sqlDB.Query(`EXPLAIN ANALYZE DELETE FROM test.t WHERE k > 8`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pretty = rowsToStrings(rows)
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pretty) < 2 || pretty[1][1] != "delete" || pretty[1][3] != "2" {
+		t.Errorf("expected delete affecting 2 rows, got:\n%s", prettyPrint(pretty))
+	}
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM test.t`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 8 {
+		t.Errorf("expected 8 rows left, got %d", count)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/extstorage"
+	"github.com/cockroachdb/cockroach/util/jsonb"
+	"github.com/pkg/errors"
+)
+
+// The options accepted by EXPORT in its WITH clause.
+const (
+	exportOptionDelimiter   = "delimiter"
+	exportOptionCompression = "compression"
+	exportOptionChunkRows   = "chunk_rows"
+)
+
+// exportDefaultChunkRows is the number of rows written to each file by EXPORT
+// unless the chunk_rows option is given.
+const exportDefaultChunkRows = 100000
+
+var exportColumns = []ResultColumn{
+	{Name: "filename", Typ: parser.TypeString},
+	{Name: "rows", Typ: parser.TypeInt},
+	{Name: "bytes", Typ: parser.TypeInt},
+}
+
+type exportNode struct {
+	p      *planner
+	n      *parser.Export
+	source planNode
+
+	comma     rune
+	gzip      bool
+	chunkRows int
+
+	results []parser.DTuple
+	curRow  int
+}
+
+// Export writes the results of a query as CSV files in the directory named by
+// an external storage URI (see package extstorage). The rows are split into
+// files of at most chunk_rows rows, named n<node ID>.<index>.csv (with a .gz
+// suffix when compressed). The query itself is planned and run like any
+// other, so it is distributed whenever its plan is; the files are written by
+// the gateway node. One row is returned for each file written.
+// Privileges: security.RootUser user.
+func (p *planner) Export(n *parser.Export, autoCommit bool) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to run EXPORT", security.RootUser)
+	}
+	if n.FileFormat != "CSV" {
+		return nil, errors.Errorf("unsupported export format %q", n.FileFormat)
+	}
+
+	node := &exportNode{p: p, n: n, comma: ',', chunkRows: exportDefaultChunkRows}
+	for _, opt := range n.Options {
+		switch opt.Key {
+		case exportOptionDelimiter:
+			r, size := utf8.DecodeRuneInString(opt.Value)
+			if size == 0 || size != len(opt.Value) || r == utf8.RuneError {
+				return nil, errors.Errorf("EXPORT option %s must be a single character", opt.Key)
+			}
+			node.comma = r
+		case exportOptionCompression:
+			switch opt.Value {
+			case "gzip":
+				node.gzip = true
+			case "none":
+				node.gzip = false
+			default:
+				return nil, errors.Errorf("unsupported EXPORT compression %q", opt.Value)
+			}
+		case exportOptionChunkRows:
+			chunkRows, err := strconv.Atoi(opt.Value)
+			if err != nil || chunkRows <= 0 {
+				return nil, errors.Errorf("EXPORT option %s must be a positive integer", opt.Key)
+			}
+			node.chunkRows = chunkRows
+		default:
+			return nil, errors.Errorf("unsupported EXPORT option %q", opt.Key)
+		}
+	}
+
+	source, err := p.Select(n.Query, nil, autoCommit)
+	if err != nil {
+		return nil, err
+	}
+	node.source = source
+	return node, nil
+}
+
+func (n *exportNode) expandPlan() error {
+	return n.source.expandPlan()
+}
+
+func (n *exportNode) Start() error {
+	if err := n.source.Start(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var w *csv.Writer
+	var zw *gzip.Writer
+	var rows int
+	reset := func() {
+		buf.Reset()
+		var out io.Writer = &buf
+		if n.gzip {
+			zw = gzip.NewWriter(&buf)
+			out = zw
+		}
+		w = csv.NewWriter(out)
+		w.Comma = n.comma
+		rows = 0
+	}
+	flush := func() error {
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("n%d.%d.csv", n.p.evalCtx.NodeID, len(n.results))
+		if n.gzip {
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			name += ".gz"
+		}
+		uri, err := extstorage.Join(n.n.File, name)
+		if err != nil {
+			return err
+		}
+		if err := extstorage.Write(n.p.ctx(), uri, buf.Bytes()); err != nil {
+			return errors.Wrap(err, uri)
+		}
+		n.results = append(n.results, parser.DTuple{
+			parser.NewDString(name),
+			parser.NewDInt(parser.DInt(rows)),
+			parser.NewDInt(parser.DInt(buf.Len())),
+		})
+		return nil
+	}
+
+	reset()
+	record := make([]string, len(n.source.Columns()))
+	for {
+		next, err := n.source.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+		for i, d := range n.source.Values() {
+			record[i] = exportValue(d)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		rows++
+		if rows == n.chunkRows {
+			if err := flush(); err != nil {
+				return err
+			}
+			reset()
+		}
+	}
+	// Write the last, partial chunk; an empty result still produces a file.
+	if rows > 0 || len(n.results) == 0 {
+		return flush()
+	}
+	return nil
+}
+
+// exportValue returns the CSV field for d: NULL is an empty field and the
+// other values are written in a form that IMPORT (and casts from STRING)
+// accept.
+func exportValue(d parser.Datum) string {
+	if d == parser.DNull {
+		return ""
+	}
+	switch t := d.(type) {
+	case *parser.DString:
+		return string(*t)
+	case *parser.DBytes:
+		return string(*t)
+	case *parser.DJSON:
+		return jsonb.String(t.JSON)
+	case *parser.DIPAddr:
+		return t.IPAddr.String()
+	}
+	return d.String()
+}
+
+func (n *exportNode) Next() (bool, error) {
+	if n.curRow >= len(n.results) {
+		return false, nil
+	}
+	n.curRow++
+	return true, nil
+}
+
+func (n *exportNode) Values() parser.DTuple { return n.results[n.curRow-1] }
+
+func (n *exportNode) Columns() []ResultColumn             { return exportColumns }
+func (n *exportNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *exportNode) DebugValues() debugValues            { return debugValues{} }
+func (n *exportNode) ExplainTypes(_ func(string, string)) {}
+func (n *exportNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *exportNode) MarkDebug(mode explainMode)          {}
+func (n *exportNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "export", n.n.File, []planNode{n.source}
+}
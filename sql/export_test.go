@@ -0,0 +1,131 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestExportCSV(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.Exec(`CREATE TABLE d.t (a INT PRIMARY KEY, b STRING)`)
+	r.Exec(`INSERT INTO d.t VALUES (1, 'x'), (2, NULL), (3, 'y,z')`)
+
+	readFile := func(name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	checkFiles := func(stmt string, expected []string) {
+		rows := r.Query(stmt)
+		var files []string
+		for rows.Next() {
+			var name string
+			var numRows, numBytes int
+			if err := rows.Scan(&name, &numRows, &numBytes); err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, fmt.Sprintf("%s %d", name, numRows))
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(files) != fmt.Sprint(expected) {
+			t.Fatalf("%s: expected files %s, got %s", stmt, expected, files)
+		}
+	}
+
+	checkFiles(fmt.Sprintf(`EXPORT INTO CSV 'nodelocal://%s/plain' FROM SELECT * FROM d.t ORDER BY a`, dir),
+		[]string{"n1.0.csv 3"})
+	if content, expected := readFile("plain/n1.0.csv"), "1,x\n2,\n3,\"y,z\"\n"; content != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+
+	checkFiles(fmt.Sprintf(`EXPORT INTO CSV 'nodelocal://%s/chunked' WITH delimiter = '|', chunk_rows = '2'
+		FROM SELECT * FROM d.t ORDER BY a`, dir),
+		[]string{"n1.0.csv 2", "n1.1.csv 1"})
+	if content, expected := readFile("chunked/n1.0.csv")+readFile("chunked/n1.1.csv"), "1|x\n2|\n3|y,z\n"; content != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+
+	checkFiles(fmt.Sprintf(`EXPORT INTO CSV 'nodelocal://%s/gzip' WITH compression = 'gzip'
+		FROM SELECT a FROM d.t WHERE a > 1 ORDER BY a`, dir),
+		[]string{"n1.0.csv.gz 2"})
+	f, err := os.Open(filepath.Join(dir, "gzip", "n1.0.csv.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "2\n3\n"; string(content) != expected {
+		t.Errorf("expected %q, got %q", expected, content)
+	}
+
+	// The exported files can be imported back.
+	r.ExecRowsAffected(3, fmt.Sprintf(`IMPORT TABLE d.u (a INT PRIMARY KEY, b STRING)
+		CSV DATA ('nodelocal://%s/plain/n1.0.csv') WITH nullif = ''`, dir))
+
+	for _, tc := range []struct {
+		stmt, expected string
+	}{
+		{`EXPORT INTO CSV 'nodelocal://%s/err' WITH foo = 'bar' FROM SELECT * FROM d.t`,
+			`unsupported EXPORT option "foo"`},
+		{`EXPORT INTO CSV 'nodelocal://%s/err' WITH compression = 'lz4' FROM SELECT * FROM d.t`,
+			`unsupported EXPORT compression "lz4"`},
+		{`EXPORT INTO CSV 'nodelocal://%s/err' WITH chunk_rows = '0' FROM SELECT * FROM d.t`,
+			`EXPORT option chunk_rows must be a positive integer`},
+		{`EXPORT INTO CSV 'foo://%s/err' FROM SELECT * FROM d.t`,
+			`unsupported storage scheme "foo"`},
+	} {
+		if _, err := sqlDB.Exec(fmt.Sprintf(tc.stmt, dir)); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.stmt, tc.expected, err)
+		}
+	}
+}
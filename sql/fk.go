@@ -16,11 +16,15 @@ package sql
 
 import (
 	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/pkg/errors"
 )
 
@@ -74,6 +78,46 @@ func tablesNeededForFKs(table sqlbase.TableDescriptor, usage FKCheck) tableLooku
 	return ret
 }
 
+// hasFKCascade returns whether any of the foreign keys of `table` has a
+// cascading (CASCADE or SET NULL) action, i.e. whether rows of `table` can be
+// deleted or updated when the rows they reference are.
+func hasFKCascade(table sqlbase.TableDescriptor) bool {
+	for _, idx := range table.AllNonDropIndexes() {
+		if isCascadingFKAction(idx.ForeignKey.OnDelete) || isCascadingFKAction(idx.ForeignKey.OnUpdate) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCascadingFKAction(action sqlbase.ForeignKeyReference_Action) bool {
+	return action == sqlbase.ForeignKeyReference_CASCADE || action == sqlbase.ForeignKeyReference_SET_NULL
+}
+
+// fkActionFromAST maps the referential actions of the AST to the ones stored
+// in ForeignKeyReferences.
+var fkActionFromAST = [...]sqlbase.ForeignKeyReference_Action{
+	parser.NoAction: sqlbase.ForeignKeyReference_NO_ACTION,
+	parser.Restrict: sqlbase.ForeignKeyReference_RESTRICT,
+	parser.SetNull:  sqlbase.ForeignKeyReference_SET_NULL,
+	parser.Cascade:  sqlbase.ForeignKeyReference_CASCADE,
+}
+
+// fkActionsToAST returns the referential actions of a ForeignKeyReference in
+// their AST form.
+func fkActionsToAST(ref sqlbase.ForeignKeyReference) parser.ReferenceActions {
+	var ret parser.ReferenceActions
+	for astAction, action := range fkActionFromAST {
+		if ref.OnDelete == action {
+			ret.Delete = parser.ReferenceAction(astAction)
+		}
+		if ref.OnUpdate == action {
+			ret.Update = parser.ReferenceAction(astAction)
+		}
+	}
+	return ret
+}
+
 type fkInsertHelper map[sqlbase.IndexID][]baseFKHelper
 
 var errSkipUnsedFK = errors.New("no columns involved in FK included in writer")
@@ -140,8 +184,16 @@ func (fks fkInsertHelper) checkIdx(idx sqlbase.IndexID, row parser.DTuple) error
 
 type fkDeleteHelper map[sqlbase.IndexID][]baseFKHelper
 
+// makeFKDeleteHelper creates a helper checking that the rows of `table` being
+// deleted (usage CheckDeletes) or updated (CheckUpdates) are not referenced,
+// or performing the ON DELETE or ON UPDATE action of the referencing foreign
+// keys.
 func makeFKDeleteHelper(
-	txn *client.Txn, table sqlbase.TableDescriptor, otherTables tableLookupsByID, colMap map[sqlbase.ColumnID]int,
+	txn *client.Txn,
+	table sqlbase.TableDescriptor,
+	otherTables tableLookupsByID,
+	colMap map[sqlbase.ColumnID]int,
+	usage FKCheck,
 ) (fkDeleteHelper, error) {
 	var fks fkDeleteHelper
 	for _, idx := range table.AllNonDropIndexes() {
@@ -158,31 +210,51 @@ func makeFKDeleteHelper(
 			if err != nil {
 				return fks, err
 			}
+			if fk.cascader, err = makeFKCascader(txn, otherTables, fk, usage); err != nil {
+				return fks, err
+			}
 			if fks == nil {
 				fks = make(fkDeleteHelper)
 			}
 			fks[idx.ID] = append(fks[idx.ID], fk)
 		}
 	}
+	if fks != nil {
+		fks.shareCascaded(makeCascadedRows())
+	}
 	return fks, nil
 }
 
-func (fks fkDeleteHelper) checkAll(row parser.DTuple) error {
+// checkAll checks the references to a row being deleted, cascading the delete
+// to the referencing rows as needed. A nil row checks the references to any
+// row of the table.
+func (fks fkDeleteHelper) checkAll(ctx context.Context, b *client.Batch, row parser.DTuple) error {
 	for idx := range fks {
-		if err := fks.checkIdx(idx, row); err != nil {
+		if err := fks.checkIdx(ctx, b, idx, row, nil); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (fks fkDeleteHelper) checkIdx(idx sqlbase.IndexID, row parser.DTuple) error {
+// checkIdx checks the references to the values of index idx in a row being
+// deleted (newRow is nil) or updated to newRow, cascading the delete or update
+// to the referencing rows as needed.
+func (fks fkDeleteHelper) checkIdx(
+	ctx context.Context, b *client.Batch, idx sqlbase.IndexID, row, newRow parser.DTuple,
+) error {
 	for _, fk := range fks[idx] {
 		found, err := fk.check(row)
 		if err != nil {
 			return err
 		}
 		if found != nil {
+			if fk.cascader != nil && row != nil {
+				if err := fk.cascader.cascade(ctx, b, row, newRow); err != nil {
+					return err
+				}
+				continue
+			}
 			if row == nil {
 				return fmt.Errorf("foreign key violation: non-empty columns %s referenced in table %q",
 					fk.writeIdx.ColumnNames[:fk.prefixLen], fk.searchTable.Name)
@@ -209,15 +281,17 @@ func makeFKUpdateHelper(
 ) (fkUpdateHelper, error) {
 	ret := fkUpdateHelper{}
 	var err error
-	if ret.inbound, err = makeFKDeleteHelper(txn, table, otherTables, colMap); err != nil {
+	if ret.inbound, err = makeFKDeleteHelper(txn, table, otherTables, colMap, CheckUpdates); err != nil {
 		return ret, err
 	}
 	ret.outbound, err = makeFKInsertHelper(txn, table, otherTables, colMap)
 	return ret, err
 }
 
-func (fks fkUpdateHelper) checkIdx(idx sqlbase.IndexID, oldValues, newValues parser.DTuple) error {
-	if err := fks.inbound.checkIdx(idx, oldValues); err != nil {
+func (fks fkUpdateHelper) checkIdx(
+	ctx context.Context, b *client.Batch, idx sqlbase.IndexID, oldValues, newValues parser.DTuple,
+) error {
+	if err := fks.inbound.checkIdx(ctx, b, idx, oldValues, newValues); err != nil {
 		return err
 	}
 	return fks.outbound.checkIdx(idx, newValues)
@@ -232,6 +306,7 @@ type baseFKHelper struct {
 	writeIdx     sqlbase.IndexDescriptor  // the index we want to modify
 	searchPrefix []byte                   // prefix of keys in searchIdx
 	ids          map[sqlbase.ColumnID]int // col IDs
+	cascader     *fkCascader              // set for inbound FKs with a cascading action
 }
 
 func makeBaseFKHelper(
@@ -282,20 +357,328 @@ func makeBaseFKHelper(
 
 // TODO(dt): Batch checks of many rows.
 func (f baseFKHelper) check(values parser.DTuple) (parser.DTuple, error) {
+	span, err := f.spanForValues(values)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.rf.StartScan(f.txn, sqlbase.Spans{span}, 1); err != nil {
+		return nil, err
+	}
+	return f.rf.NextRow()
+}
+
+// spanForValues returns the span of searchIdx containing the entries matching
+// values, or the entire index if values is nil.
+func (f baseFKHelper) spanForValues(values parser.DTuple) (sqlbase.Span, error) {
 	var key roachpb.Key
 	if values != nil {
 		keyBytes, _, err := sqlbase.EncodeIndexKey(
 			f.searchTable, f.searchIdx, f.ids, values, f.searchPrefix)
 		if err != nil {
-			return nil, err
+			return sqlbase.Span{}, err
 		}
 		key = roachpb.Key(keyBytes)
 	} else {
 		key = roachpb.Key(f.searchPrefix)
 	}
-	spans := sqlbase.Spans{sqlbase.Span{Start: key, End: key.PrefixEnd()}}
-	if err := f.rf.StartScan(f.txn, spans, 1); err != nil {
+	return sqlbase.Span{Start: key, End: key.PrefixEnd()}, nil
+}
+
+// fkCascader performs the ON DELETE or ON UPDATE action of a foreign key on the
+// rows referencing a row being deleted or updated: CASCADE deletes them or
+// updates their referencing columns to the new referenced values, SET NULL
+// sets their referencing columns to NULL.
+type fkCascader struct {
+	fk          baseFKHelper
+	otherTables tableLookupsByID
+	action      sqlbase.ForeignKeyReference_Action
+
+	// updateCols are the referencing columns, which are updated unless the
+	// referencing rows are deleted (ON DELETE CASCADE), in which case it is nil.
+	updateCols []sqlbase.ColumnDescriptor
+
+	// pkFetcher fetches the primary key of the referencing rows from the
+	// referencing index, then rowFetcher fetches the entire rows.
+	colMap     map[sqlbase.ColumnID]int
+	pkPrefix   []byte
+	pkFetcher  sqlbase.RowFetcher
+	rowFetcher sqlbase.RowFetcher
+
+	// rd and ru are only initialized when first needed, since the foreign keys
+	// of the referencing table can themselves cascade back to this table.
+	rd *rowDeleter
+	ru *rowUpdater
+
+	// cascaded holds the rows already deleted or updated by a cascade. It is
+	// shared by all the cascaders of an fkDeleteHelper and, transitively, of rd
+	// and ru.
+	cascaded *cascadedRows
+}
+
+// cascadeKey identifies a row, by its primary key, whose referencing columns
+// in the index with the given ID were updated by a cascade.
+type cascadeKey struct {
+	index sqlbase.IndexID
+	pk    string
+}
+
+// cascadedRows tracks the rows deleted or updated by the cascades of a
+// statement. A row referencing the same row through several foreign keys gets
+// the action of each of them: the updates are applied on top of each other,
+// and the row is skipped once deleted.
+type cascadedRows struct {
+	// deleted holds the primary keys of the deleted rows.
+	deleted map[string]struct{}
+	// updated holds the latest values of the updated rows, by primary key. The
+	// writes of a cascade are batched and not visible to the reads of the
+	// following ones, which use these values instead.
+	updated map[string]parser.DTuple
+	// visited holds the rows whose referencing columns were already updated
+	// through each index, so that cycles of foreign keys don't cascade forever.
+	visited map[cascadeKey]struct{}
+}
+
+func makeCascadedRows() *cascadedRows {
+	return &cascadedRows{
+		deleted: make(map[string]struct{}),
+		updated: make(map[string]parser.DTuple),
+		visited: make(map[cascadeKey]struct{}),
+	}
+}
+
+// makeFKCascader sets up the action of the foreign key checked by fk on the
+// rows referencing a row being deleted (usage CheckDeletes) or updated
+// (CheckUpdates). It returns nil if the action isn't a cascading one.
+func makeFKCascader(
+	txn *client.Txn, otherTables tableLookupsByID, fk baseFKHelper, usage FKCheck,
+) (*fkCascader, error) {
+	action := fk.searchIdx.ForeignKey.OnDelete
+	if usage == CheckUpdates {
+		action = fk.searchIdx.ForeignKey.OnUpdate
+	}
+	if !isCascadingFKAction(action) {
+		return nil, nil
+	}
+
+	table := fk.searchTable
+	pkPrefix := sqlbase.MakeIndexKeyPrefix(table, table.PrimaryIndex.ID)
+	c := &fkCascader{
+		fk:          fk,
+		otherTables: otherTables,
+		action:      action,
+		colMap:      colIDtoRowIndexFromCols(table.Columns),
+		pkPrefix:    pkPrefix[:len(pkPrefix):len(pkPrefix)],
+	}
+
+	if usage == CheckUpdates || action == sqlbase.ForeignKeyReference_SET_NULL {
+		c.updateCols = make([]sqlbase.ColumnDescriptor, fk.prefixLen)
+		for i, colID := range fk.searchIdx.ColumnIDs[:fk.prefixLen] {
+			col, err := table.FindColumnByID(colID)
+			if err != nil {
+				return nil, err
+			}
+			c.updateCols[i] = *col
+		}
+	}
+
+	pkNeeded := make([]bool, len(table.Columns))
+	for _, colID := range table.PrimaryIndex.ColumnIDs {
+		pkNeeded[c.colMap[colID]] = true
+	}
+	isSecondary := table.PrimaryIndex.ID != fk.searchIdx.ID
+	if err := c.pkFetcher.Init(
+		table, c.colMap, fk.searchIdx, false, isSecondary, table.Columns, pkNeeded,
+	); err != nil {
 		return nil, err
 	}
-	return f.rf.NextRow()
+	rowNeeded := make([]bool, len(table.Columns))
+	for i := range rowNeeded {
+		rowNeeded[i] = true
+	}
+	if err := c.rowFetcher.Init(
+		table, c.colMap, &table.PrimaryIndex, false, false, table.Columns, rowNeeded,
+	); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cascade performs the action of the foreign key on the rows referencing row,
+// which is being deleted (newRow is nil) or updated to newRow.
+func (c *fkCascader) cascade(ctx context.Context, b *client.Batch, row, newRow parser.DTuple) error {
+	rows, err := c.fetchReferencingRows(row)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+
+	if c.updateCols == nil {
+		rd, err := c.rowDeleter()
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err := rd.deleteRow(ctx, b, r.values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	updateValues := make(parser.DTuple, len(c.updateCols))
+	for i, col := range c.updateCols {
+		updateValues[i] = parser.DNull
+		if c.action == sqlbase.ForeignKeyReference_CASCADE {
+			idx, ok := c.fk.ids[col.ID]
+			if !ok {
+				return errors.Errorf("missing value referenced by column %q", col.Name)
+			}
+			updateValues[i] = newRow[idx]
+		}
+		if updateValues[i] == parser.DNull && !col.Nullable {
+			return sqlbase.NewNonNullViolationError(col.Name)
+		}
+	}
+	ru, err := c.rowUpdater()
+	if err != nil {
+		return err
+	}
+	for _, r := range rows {
+		// Record the new values before updating the row, since the update can
+		// cascade back to it.
+		newValues := append(parser.DTuple(nil), r.values...)
+		for i, col := range c.updateCols {
+			newValues[c.colMap[col.ID]] = updateValues[i]
+		}
+		c.cascaded.updated[r.pk] = newValues
+		if _, err := ru.updateRow(ctx, b, r.values, updateValues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cascadedRow is a row referencing a row being deleted or updated, along with
+// its encoded primary key.
+type cascadedRow struct {
+	pk     string
+	values parser.DTuple
+}
+
+// fetchReferencingRows returns the rows referencing the given row, with the
+// latest values of the ones already updated by a cascade. It skips the rows
+// already deleted by a cascade, and the ones already updated through this
+// foreign key.
+func (c *fkCascader) fetchReferencingRows(row parser.DTuple) ([]cascadedRow, error) {
+	span, err := c.fk.spanForValues(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.pkFetcher.StartScan(c.fk.txn, sqlbase.Spans{span}, 0); err != nil {
+		return nil, err
+	}
+	var rows []cascadedRow
+	var spans sqlbase.Spans
+	for {
+		pkRow, err := c.pkFetcher.NextRow()
+		if err != nil {
+			return nil, err
+		}
+		if pkRow == nil {
+			break
+		}
+		key, _, err := sqlbase.EncodeIndexKey(
+			c.fk.searchTable, &c.fk.searchTable.PrimaryIndex, c.colMap, pkRow, c.pkPrefix)
+		if err != nil {
+			return nil, err
+		}
+		pk := string(key)
+		if _, ok := c.cascaded.deleted[pk]; ok {
+			continue
+		}
+		if c.updateCols == nil {
+			c.cascaded.deleted[pk] = struct{}{}
+		} else {
+			visited := cascadeKey{index: c.fk.searchIdx.ID, pk: pk}
+			if _, ok := c.cascaded.visited[visited]; ok {
+				continue
+			}
+			c.cascaded.visited[visited] = struct{}{}
+		}
+		if values, ok := c.cascaded.updated[pk]; ok {
+			rows = append(rows, cascadedRow{pk: pk, values: values})
+			continue
+		}
+		spans = append(spans, sqlbase.Span{
+			Start: roachpb.Key(key),
+			End:   roachpb.Key(encoding.EncodeNotNullDescending(key)),
+		})
+	}
+	if len(spans) == 0 {
+		return rows, nil
+	}
+
+	sort.Sort(spans)
+	if err := c.rowFetcher.StartScan(c.fk.txn, spans, 0); err != nil {
+		return nil, err
+	}
+	for {
+		r, err := c.rowFetcher.NextRow()
+		if err != nil {
+			return nil, err
+		}
+		if r == nil {
+			break
+		}
+		key, _, err := sqlbase.EncodeIndexKey(
+			c.fk.searchTable, &c.fk.searchTable.PrimaryIndex, c.colMap, r, c.pkPrefix)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, cascadedRow{pk: string(key), values: append(parser.DTuple(nil), r...)})
+	}
+	return rows, nil
+}
+
+func (c *fkCascader) rowDeleter() (*rowDeleter, error) {
+	if c.rd == nil {
+		rd, err := makeRowDeleter(
+			c.fk.txn, c.fk.searchTable, c.otherTables, c.fk.searchTable.Columns, checkFKs)
+		if err != nil {
+			return nil, err
+		}
+		rd.fks.shareCascaded(c.cascaded)
+		c.rd = &rd
+	}
+	return c.rd, nil
+}
+
+func (c *fkCascader) rowUpdater() (*rowUpdater, error) {
+	if c.ru == nil {
+		ru, err := makeRowUpdater(
+			c.fk.txn, c.fk.searchTable, c.otherTables, c.updateCols, c.fk.searchTable.Columns,
+			rowUpdaterDefault,
+		)
+		if err != nil {
+			return nil, err
+		}
+		// The new values of the referencing columns are either NULL or those of
+		// the referenced row being written in the same batch, which the check of
+		// this foreign key could not see.
+		delete(ru.fks.outbound, c.fk.searchIdx.ID)
+		ru.fks.inbound.shareCascaded(c.cascaded)
+		c.ru = &ru
+	}
+	return c.ru, nil
+}
+
+// shareCascaded makes the cascaders of fks use the given rows already deleted
+// or updated by a cascade.
+func (fks fkDeleteHelper) shareCascaded(cascaded *cascadedRows) {
+	for _, idxFKs := range fks {
+		for _, fk := range idxFKs {
+			if fk.cascader != nil {
+				fk.cascader.cascaded = cascaded
+			}
+		}
+	}
 }
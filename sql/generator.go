@@ -0,0 +1,128 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/pkg/errors"
+)
+
+// valueGenerator represents a node that produces rows
+// computationally, by means of a "generator function" (called
+// "set-generating function" in PostgreSQL).
+type valueGenerator struct {
+	p *planner
+
+	// expr holds the function call that needs to be performed,
+	// including its arguments that need evaluation, to obtain the
+	// generator object.
+	expr parser.TypedExpr
+
+	// gen is a reference to the generator object that produces the
+	// values for this node.
+	gen parser.ValueGenerator
+
+	// columns is the signature of this generator.
+	columns []ResultColumn
+
+	// rowCount is used for DebugValues.
+	rowCount int
+}
+
+// makeGenerator creates a valueGenerator instance that wraps a call to a
+// generator function.
+func (p *planner) makeGenerator(t *parser.FuncExpr) (planNode, error) {
+	normalized, err := p.analyzeExpr(t, nil, nil, parser.NoTypePreference, false, "FROM")
+	if err != nil {
+		return nil, err
+	}
+
+	fn, ok := normalized.(*parser.FuncExpr)
+	if !ok || !fn.IsGeneratorApplication() {
+		return nil, errors.Errorf("FROM expression is not a generator: %s", t)
+	}
+
+	origName, err := t.Name.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	tType := normalized.ReturnType().(*parser.DTuple)
+	columns := make([]ResultColumn, len(*tType))
+	for i, typ := range *tType {
+		columns[i] = ResultColumn{Name: origName.Function(), Typ: typ}
+	}
+
+	return &valueGenerator{
+		p:       p,
+		expr:    normalized,
+		columns: columns,
+	}, nil
+}
+
+func (n *valueGenerator) expandPlan() error {
+	return n.p.expandSubqueryPlans(n.expr)
+}
+
+func (n *valueGenerator) Start() error {
+	if err := n.p.startSubqueryPlans(n.expr); err != nil {
+		return err
+	}
+
+	gen, err := n.expr.(*parser.FuncExpr).EvalArgsAndGetGenerator(&n.p.evalCtx)
+	if err != nil {
+		return err
+	}
+	if err := gen.Start(); err != nil {
+		return err
+	}
+	n.gen = gen
+	return nil
+}
+
+func (n *valueGenerator) Next() (bool, error) {
+	next, err := n.gen.Next()
+	if err != nil || !next {
+		n.gen.Close()
+		return false, err
+	}
+	n.rowCount++
+	return true, nil
+}
+
+func (n *valueGenerator) Values() parser.DTuple        { return n.gen.Values() }
+func (n *valueGenerator) Columns() []ResultColumn      { return n.columns }
+func (n *valueGenerator) Ordering() orderingInfo       { return orderingInfo{} }
+func (n *valueGenerator) MarkDebug(_ explainMode)      {}
+func (n *valueGenerator) SetLimitHint(_ int64, _ bool) {}
+
+func (n *valueGenerator) DebugValues() debugValues {
+	values := n.gen.Values()
+	return debugValues{
+		rowIdx: n.rowCount - 1,
+		key:    fmt.Sprintf("%d", n.rowCount-1),
+		value:  values.String(),
+		output: debugValueRow,
+	}
+}
+
+func (n *valueGenerator) ExplainPlan(_ bool) (name, description string, children []planNode) {
+	return "generator", parser.AsString(n.expr), nil
+}
+
+func (n *valueGenerator) ExplainTypes(regTypes func(string, string)) {
+	regTypes("generator", parser.AsStringWithFlags(n.expr, parser.FmtShowTypes))
+}
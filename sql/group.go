@@ -20,7 +20,9 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unsafe"
 
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util/encoding"
@@ -48,6 +50,9 @@ func (p *planner) groupBy(n *parser.SelectClause, s *selectNode) (*groupNode, er
 		if p.parser.AggregateInExpr(groupBy[i]) {
 			return nil, fmt.Errorf("aggregate functions are not allowed in GROUP BY")
 		}
+		if p.parser.WindowFuncInExpr(groupBy[i]) {
+			return nil, fmt.Errorf("window functions are not allowed in GROUP BY")
+		}
 
 		// We do not need to fully analyze the GROUP BY expression here
 		// (as per analyzeExpr) because this is taken care of by addRender
@@ -74,6 +79,9 @@ func (p *planner) groupBy(n *parser.SelectClause, s *selectNode) (*groupNode, er
 	var typedHaving parser.TypedExpr
 	var err error
 	if n.Having != nil {
+		if p.parser.WindowFuncInExpr(n.Having.Expr) {
+			return nil, fmt.Errorf("window functions are not allowed in HAVING")
+		}
 		typedHaving, err = p.analyzeExpr(n.Having.Expr, s.sourceInfo, s.qvals,
 			parser.TypeBool, true, "HAVING")
 		if err != nil {
@@ -86,6 +94,7 @@ func (p *planner) groupBy(n *parser.SelectClause, s *selectNode) (*groupNode, er
 		planner: p,
 		values:  valuesNode{columns: s.columns},
 		render:  s.render,
+		memAcc:  p.makeMemoryAccount(),
 	}
 
 	visitor := extractAggregatesVisitor{
@@ -187,9 +196,28 @@ type groupNode struct {
 	needOnlyOneRow  bool
 	gotOneRow       bool
 
+	// memAcc accounts for the buckets, the state of the aggregate functions
+	// and the rendered rows.
+	memAcc mon.MemoryAccount
+	// bucketsMemUsage is the memory accounted for by the buckets and the
+	// state of their aggregate functions.
+	bucketsMemUsage int64
+	// spilled holds, once the buckets no longer fit in the memory budget, the
+	// rows of the groups that had no bucket yet. These groups are aggregated
+	// one at a time after the in-memory ones.
+	spilled *diskRowContainer
+
 	explain explainMode
 }
 
+// sizeOfBucket is a rough estimate of the memory used by a map entry keyed
+// by a bucket, without the bucket's contents.
+const sizeOfBucket = int64(unsafe.Sizeof("")) + 32
+
+// sizeOfAggregateFunc is a rough estimate of the memory used by an
+// AggregateFunc.
+const sizeOfAggregateFunc = 64
+
 func (n *groupNode) Columns() []ResultColumn {
 	return n.values.Columns()
 }
@@ -299,14 +327,22 @@ func (n *groupNode) Next() (bool, error) {
 			return false, err
 		}
 
-		n.buckets[string(encoded)] = struct{}{}
-
-		// Feed the aggregateFuncHolders for this bucket the non-grouped values.
-		for i, value := range aggregatedValues {
-			if err := n.funcs[i].add(encoded, value); err != nil {
+		spilled := false
+		if _, ok := n.buckets[string(encoded)]; !ok {
+			if spilled, err = n.addBucket(encoded, aggregatedValues); err != nil {
 				return false, err
 			}
 		}
+
+		// Feed the aggregateFuncHolders for this bucket the non-grouped values,
+		// unless the row was spilled to disk.
+		if !spilled {
+			for i, value := range aggregatedValues {
+				if err := n.funcs[i].add(encoded, value); err != nil {
+					return false, err
+				}
+			}
+		}
 		scratch = encoded[:0]
 
 		n.gotOneRow = true
@@ -320,8 +356,35 @@ func (n *groupNode) Next() (bool, error) {
 	return n.values.Next()
 }
 
+// addBucket creates the bucket of a new group. Once the buckets no longer
+// fit in the memory budget, the rows of new groups are spilled to disk
+// instead, in which case true is returned.
+func (n *groupNode) addBucket(bucket []byte, aggregatedValues parser.DTuple) (bool, error) {
+	if n.spilled == nil {
+		// Account for the bucket and for the state of each aggregate function
+		// in it.
+		size := sizeOfBucket + int64(len(bucket))
+		size += int64(len(n.funcs)) * (sizeOfBucket + int64(len(bucket)) + sizeOfAggregateFunc)
+		err := n.memAcc.Grow(size)
+		if err == nil {
+			n.bucketsMemUsage += size
+			n.buckets[string(bucket)] = struct{}{}
+			return false, nil
+		}
+		if !isMemoryBudgetError(err) || !n.planner.canSpillToDisk() {
+			return false, err
+		}
+		types := make([]parser.Datum, len(n.funcs))
+		for i, f := range n.funcs {
+			types[i] = f.arg.ReturnType()
+		}
+		n.spilled = n.planner.newDiskRowContainer(types)
+	}
+	return true, n.spilled.addRow(bucket, aggregatedValues)
+}
+
 func (n *groupNode) computeAggregates() error {
-	if len(n.buckets) < 1 && n.addNullBucketIfEmpty {
+	if len(n.buckets) < 1 && n.spilled == nil && n.addNullBucketIfEmpty {
 		n.buckets[""] = struct{}{}
 	}
 
@@ -331,34 +394,109 @@ func (n *groupNode) computeAggregates() error {
 	// Render the results.
 	n.values.rows = make([]parser.DTuple, 0, len(n.buckets))
 	for k := range n.buckets {
-		n.currentBucket = k
+		if err := n.renderBucket(k); err != nil {
+			return err
+		}
+	}
+	if n.spilled != nil {
+		return n.computeSpilledAggregates()
+	}
+	return nil
+}
 
-		if n.having != nil {
-			res, err := n.having.Eval(&n.planner.evalCtx)
-			if err != nil {
-				return err
-			}
-			if val, err := parser.GetBool(res); err != nil {
+// computeSpilledAggregates aggregates and renders the groups spilled to
+// disk. Their rows are read back ordered by bucket, so the groups are
+// aggregated one at a time and only the state of the current one is kept in
+// memory.
+func (n *groupNode) computeSpilledAggregates() error {
+	// The in-memory groups have been rendered, release their state.
+	n.resetFuncs()
+	n.buckets = make(map[string]struct{})
+	n.memAcc.Shrink(n.bucketsMemUsage)
+	n.bucketsMemUsage = 0
+
+	it, err := n.spilled.newIterator()
+	if err != nil {
+		return err
+	}
+	defer it.close()
+
+	var bucket []byte
+	for ; ; it.next() {
+		if ok, err := it.valid(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		key := it.key()
+		if bucket != nil && !bytes.Equal(key, bucket) {
+			if err := n.renderBucket(string(bucket)); err != nil {
 				return err
-			} else if !val {
-				continue
 			}
+			n.resetFuncs()
 		}
+		bucket = key
 
-		row := make(parser.DTuple, 0, len(n.render))
-		for _, r := range n.render {
-			res, err := r.Eval(&n.planner.evalCtx)
-			if err != nil {
+		aggregatedValues, err := it.row()
+		if err != nil {
+			return err
+		}
+		for i, value := range aggregatedValues {
+			if err := n.funcs[i].add(bucket, value); err != nil {
 				return err
 			}
-			row = append(row, res)
 		}
+	}
+	if bucket != nil {
+		return n.renderBucket(string(bucket))
+	}
+	return nil
+}
+
+// renderBucket renders the result row of a group, unless it is filtered out
+// by the HAVING clause.
+func (n *groupNode) renderBucket(bucket string) error {
+	n.currentBucket = bucket
+
+	if n.having != nil {
+		res, err := n.having.Eval(&n.planner.evalCtx)
+		if err != nil {
+			return err
+		}
+		if val, err := parser.GetBool(res); err != nil {
+			return err
+		} else if !val {
+			return nil
+		}
+	}
+
+	row := make(parser.DTuple, 0, len(n.render))
+	for _, r := range n.render {
+		res, err := r.Eval(&n.planner.evalCtx)
+		if err != nil {
+			return err
+		}
+		row = append(row, res)
+	}
 
-		n.values.rows = append(n.values.rows, row)
+	if err := n.memAcc.Grow(int64(row.Size())); err != nil {
+		return err
 	}
+	n.values.rows = append(n.values.rows, row)
 	return nil
 }
 
+// resetFuncs discards the state the aggregate functions keep for each
+// bucket.
+func (n *groupNode) resetFuncs() {
+	for _, f := range n.funcs {
+		f.buckets = make(map[string]parser.AggregateFunc)
+		if f.seen != nil {
+			f.seen = make(map[string]struct{})
+		}
+	}
+}
+
 func (n *groupNode) ExplainPlan(_ bool) (name, description string, children []planNode) {
 	name = "group"
 	var buf bytes.Buffer
@@ -493,7 +631,7 @@ func (v *extractAggregatesVisitor) VisitPre(expr parser.Expr) (recurse bool, new
 			return false, expr
 		}
 
-		if impl, ok := parser.Aggregates[strings.ToLower(fn.Function())]; ok {
+		if _, ok := parser.Aggregates[strings.ToLower(fn.Function())]; ok {
 			if len(t.Exprs) != 1 {
 				// Type checking has already run on these expressions thus
 				// if an aggregate function of the wrong arity gets here,
@@ -511,11 +649,11 @@ func (v *extractAggregatesVisitor) VisitPre(expr parser.Expr) (recurse bool, new
 			f := &aggregateFuncHolder{
 				expr:    t,
 				arg:     t.Exprs[0].(parser.TypedExpr),
-				create:  impl[0].AggregateFunc,
+				create:  t.GetAggregateConstructor(),
 				group:   v.n,
 				buckets: make(map[string]parser.AggregateFunc),
 			}
-			if t.Type == parser.Distinct {
+			if t.Type == parser.DistinctFuncType {
 				f.seen = make(map[string]struct{})
 			}
 			v.n.funcs = append(v.n.funcs, f)
@@ -594,11 +732,15 @@ func (a *aggregateFuncHolder) add(bucket []byte, d parser.Datum) error {
 			// skip
 			return nil
 		}
+		if err := a.group.memAcc.Grow(sizeOfBucket + int64(len(encoded))); err != nil {
+			return err
+		}
 		a.seen[string(encoded)] = struct{}{}
 	}
 
 	impl, ok := a.buckets[string(bucket)]
 	if !ok {
+		// The memory used by impl is accounted for by groupNode.addBucket.
 		impl = a.create()
 		a.buckets[string(bucket)] = impl
 	}
@@ -0,0 +1,497 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/distsql"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/envutil"
+	"github.com/cockroachdb/cockroach/util/extstorage"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/pkg/errors"
+)
+
+// importBatchSize is the approximate number of bytes of KVs sent in a single
+// IngestRequest by IMPORT.
+var importBatchSize = envutil.EnvOrDefaultBytes("import_batch_size", 4<<20)
+
+// The options accepted by IMPORT in its WITH clause.
+const (
+	importOptionDelimiter = "delimiter"
+	importOptionComment   = "comment"
+	importOptionNullIf    = "nullif"
+)
+
+func init() {
+	distsql.ReadCSVFunc = readCSV
+}
+
+type importNode struct {
+	p      *planner
+	n      *parser.Import
+	dbDesc *sqlbase.DatabaseDescriptor
+	spec   distsql.ReadCSVSpec
+
+	rowCount int
+}
+
+// Import creates a table and fills it with the rows of CSV files. The rows are
+// converted to KVs on the nodes of the cluster and ingested directly into the
+// KV layer, bypassing the transactional write path; the table descriptor is
+// only written, making the table visible, once all the data is in place.
+//
+// Because of this, IMPORT does not detect duplicate primary or unique index
+// keys (the last row wins), and data ingested by a statement that fails later
+// on is left behind under a table ID that is never used. The import is
+// tracked by a job.
+// Privileges: security.RootUser user.
+func (p *planner) Import(n *parser.Import, autoCommit bool) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to run IMPORT", security.RootUser)
+	}
+	if !autoCommit {
+		return nil, errors.Errorf("IMPORT cannot be used inside a transaction")
+	}
+	if n.FileFormat != "CSV" {
+		return nil, errors.Errorf("unsupported import format %q", n.FileFormat)
+	}
+	if len(n.Files) == 0 {
+		return nil, errors.Errorf("IMPORT requires at least one file")
+	}
+
+	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+	dbDesc, err := p.mustGetDatabaseDesc(tn.Database())
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivilege(dbDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+
+	hasPrimaryKey := false
+	for _, def := range n.Defs {
+		switch t := def.(type) {
+		case *parser.ColumnTableDef:
+			if t.References.Table.TableNameReference != nil {
+				return nil, errors.Errorf("IMPORT does not support foreign keys")
+			}
+			if t.CheckExpr.Expr != nil {
+				return nil, errors.Errorf("IMPORT does not support CHECK constraints")
+			}
+			if t.Computed.Computed {
+				return nil, errors.Errorf("IMPORT does not support computed columns")
+			}
+			hasPrimaryKey = hasPrimaryKey || t.PrimaryKey
+		case *parser.UniqueConstraintTableDef:
+			hasPrimaryKey = hasPrimaryKey || t.PrimaryKey
+		case *parser.ForeignKeyConstraintTableDef:
+			return nil, errors.Errorf("IMPORT does not support foreign keys")
+		case *parser.CheckConstraintTableDef:
+			return nil, errors.Errorf("IMPORT does not support CHECK constraints")
+		}
+	}
+	if !hasPrimaryKey {
+		return nil, errors.Errorf("IMPORT requires an explicit PRIMARY KEY")
+	}
+
+	spec := distsql.ReadCSVSpec{Uri: n.Files, Comma: ','}
+	for _, opt := range n.Options {
+		switch opt.Key {
+		case importOptionDelimiter:
+			if spec.Comma, err = importOptionRune(opt); err != nil {
+				return nil, err
+			}
+		case importOptionComment:
+			if spec.Comment, err = importOptionRune(opt); err != nil {
+				return nil, err
+			}
+		case importOptionNullIf:
+			nullif := opt.Value
+			spec.Nullif = &nullif
+		default:
+			return nil, errors.Errorf("unsupported IMPORT option %q", opt.Key)
+		}
+	}
+
+	// IMPORT isn't a DDL statement (it reports the number of rows imported), so
+	// newPlan doesn't set the trigger for the descriptor it writes.
+	p.txn.SetSystemConfigTrigger()
+
+	return &importNode{p: p, n: n, dbDesc: dbDesc, spec: spec}, nil
+}
+
+func importOptionRune(opt parser.KVOption) (int32, error) {
+	r, size := utf8.DecodeRuneInString(opt.Value)
+	if size == 0 || size != len(opt.Value) || r == utf8.RuneError {
+		return 0, errors.Errorf("IMPORT option %s must be a single character", opt.Key)
+	}
+	return r, nil
+}
+
+func (n *importNode) expandPlan() error {
+	return nil
+}
+
+func (n *importNode) Start() error {
+	desc, err := MakeTableDesc(&parser.CreateTable{Table: n.n.Table, Defs: n.n.Defs}, n.dbDesc.ID)
+	if err != nil {
+		return err
+	}
+
+	tableKey := tableKey{parentID: n.dbDesc.ID, name: n.n.Table.TableName().Table()}
+	key := tableKey.Key()
+	if exists, err := n.p.descExists(key); err == nil && exists {
+		return descriptorAlreadyExistsErr{&desc, tableKey.Name()}
+	} else if err != nil {
+		return err
+	}
+
+	// Inherit permissions from the database descriptor.
+	desc.Privileges = n.dbDesc.GetPrivileges()
+
+	id, err := n.p.generateUniqueDescID()
+	if err != nil {
+		return err
+	}
+	desc.SetID(id)
+	if err := desc.AllocateIDs(); err != nil {
+		return err
+	}
+	if err := desc.ValidateTable(); err != nil {
+		return err
+	}
+
+	// The data is written at (or below) the timestamp of the transaction that
+	// creates the descriptor, so it is visible as soon as the table is.
+	n.spec.TableDesc = desc
+	n.spec.Walltime = n.p.txn.Proto.OrigTimestamp.WallTime
+	ctx := n.p.ctx()
+	job, err := n.p.jobRegistry().newJob(ctx, jobTypeImport, n.n.String(), n.p.session.User)
+	if err != nil {
+		return err
+	}
+	count, err := n.distribute(ctx, job)
+	job.Finished(ctx, err)
+	if err != nil {
+		return err
+	}
+	n.rowCount = int(count)
+
+	if _, err := n.p.createDescriptorWithID(key, id, &desc); err != nil {
+		return err
+	}
+	if err := desc.Validate(n.p.txn); err != nil {
+		return err
+	}
+
+	// Log Create Table event. This is an auditable log event and is
+	// recorded in the same transaction as the table descriptor update.
+	return MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+		EventLogCreateTable,
+		int32(desc.ID),
+		int32(n.p.evalCtx.NodeID),
+		struct {
+			TableName string
+			Statement string
+			User      string
+		}{n.n.Table.String(), n.n.String(), n.p.session.User},
+	)
+}
+
+// distribute splits the files among the nodes of the cluster and runs a
+// ReadCSV flow on each of them, returning the total number of rows imported.
+// The files are read locally when the other nodes cannot be reached through
+// DistSQL (e.g. in tests without gossip). The progress of the job is reported
+// as the flows complete; the flows can't be interrupted, so a canceled job
+// only fails once they are all done.
+func (n *importNode) distribute(ctx context.Context, job *Job) (int64, error) {
+	execCtx := n.p.execCtx
+	if execCtx.DistSQLSrv == nil {
+		return readCSV(ctx, execCtx.DB, &n.spec)
+	}
+	addrs := importNodeAddrs(execCtx.Gossip)
+	if len(addrs) == 0 {
+		return runLocalCountFlow(execCtx.DistSQLSrv, n.makeFlowRequest(&n.spec))
+	}
+	if len(addrs) > len(n.spec.Uri) {
+		addrs = addrs[:len(n.spec.Uri)]
+	}
+
+	specs := make([]distsql.ReadCSVSpec, len(addrs))
+	for i := range specs {
+		specs[i] = n.spec
+		specs[i].Uri = nil
+	}
+	for i, uri := range n.spec.Uri {
+		specs[i%len(specs)].Uri = append(specs[i%len(specs)].Uri, uri)
+	}
+
+	counts := make([]int64, len(addrs))
+	errs := make([]error, len(addrs))
+	doneC := make(chan struct{}, len(addrs))
+	for i := range addrs {
+		go func(i int) {
+			counts[i], errs[i] = runRemoteCountFlow(execCtx.DistSQLSrv, addrs[i], n.makeFlowRequest(&specs[i]))
+			doneC <- struct{}{}
+		}(i)
+	}
+	var progressErr error
+	for done := 1; done <= len(addrs); done++ {
+		<-doneC
+		if progressErr == nil {
+			progressErr = job.Progressed(ctx, float32(done)/float32(len(addrs)))
+		}
+	}
+
+	var total int64
+	for i := range addrs {
+		if errs[i] != nil {
+			return 0, errs[i]
+		}
+		total += counts[i]
+	}
+	return total, progressErr
+}
+
+func (n *importNode) makeFlowRequest(spec *distsql.ReadCSVSpec) *distsql.SetupFlowRequest {
+	return makeCountFlowRequest(n.p.txn.Proto, distsql.ProcessorCoreUnion{ReadCSV: spec})
+}
+
+// importNodeAddrs returns the addresses of the nodes known through gossip,
+// sorted for determinism.
+func importNodeAddrs(g *gossip.Gossip) []string {
+	if g == nil {
+		return nil
+	}
+	prefix := gossip.MakeKey(gossip.KeyNodeIDPrefix, "")
+	var addrs []string
+	for key, info := range g.GetInfoStatus().Infos {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var desc roachpb.NodeDescriptor
+		if err := info.Value.GetProto(&desc); err != nil {
+			continue
+		}
+		addrs = append(addrs, desc.Address.String())
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func (n *importNode) FastPathResults() (int, bool)        { return n.rowCount, true }
+func (n *importNode) Next() (bool, error)                 { return false, nil }
+func (n *importNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *importNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *importNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *importNode) DebugValues() debugValues            { return debugValues{} }
+func (n *importNode) ExplainTypes(_ func(string, string)) {}
+func (n *importNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *importNode) MarkDebug(mode explainMode)          {}
+func (n *importNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "import", "", nil
+}
+
+// readCSV converts the rows of the CSV files in spec to the KVs of
+// spec.TableDesc and ingests them through db. It returns the number of rows
+// converted. It is run by the ReadCSV processor.
+func readCSV(ctx context.Context, db *client.DB, spec *distsql.ReadCSVSpec) (int64, error) {
+	conv, err := makeCSVConverter(&spec.TableDesc, spec.Nullif)
+	if err != nil {
+		return 0, err
+	}
+	ing := kvIngester{db: db, ts: hlc.Timestamp{WallTime: spec.Walltime}}
+	var count int64
+	for _, uri := range spec.Uri {
+		n, err := conv.readFile(ctx, uri, spec.Comma, spec.Comment, &ing)
+		count += n
+		if err != nil {
+			return count, errors.Wrap(err, uri)
+		}
+	}
+	return count, ing.flush(ctx)
+}
+
+// csvConverter converts CSV records to the KVs of a table.
+type csvConverter struct {
+	desc    *sqlbase.TableDescriptor
+	ri      rowInserter
+	types   []parser.ColumnType
+	nullif  *string
+	evalCtx parser.EvalContext
+	row     parser.DTuple
+}
+
+func makeCSVConverter(desc *sqlbase.TableDescriptor, nullif *string) (*csvConverter, error) {
+	ri, err := makeRowInserter(nil, desc, nil, desc.Columns, skipFKs)
+	if err != nil {
+		return nil, err
+	}
+	c := &csvConverter{
+		desc:   desc,
+		ri:     ri,
+		types:  make([]parser.ColumnType, len(desc.Columns)),
+		nullif: nullif,
+		row:    make(parser.DTuple, len(desc.Columns)),
+	}
+	for i, col := range desc.Columns {
+		if c.types[i], err = parser.DatumTypeToColumnType(col.Type.ToDatumType()); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// readFile converts the records of the CSV file at uri and hands their KVs to
+// ing, returning the number of records converted.
+func (c *csvConverter) readFile(
+	ctx context.Context, uri string, comma, comment int32, ing *kvIngester,
+) (int64, error) {
+	f, err := extstorage.Open(ctx, uri)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comma = comma
+	r.Comment = comment
+	r.FieldsPerRecord = len(c.desc.Columns)
+	var count int64
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := c.convertRecord(ctx, record, ing); err != nil {
+			return count, errors.Wrapf(err, "row %d", count+1)
+		}
+		count++
+		if ing.size() >= importBatchSize {
+			if err := ing.flush(ctx); err != nil {
+				return count, err
+			}
+		}
+	}
+}
+
+func (c *csvConverter) convertRecord(ctx context.Context, record []string, ing *kvIngester) error {
+	for i, field := range record {
+		col := &c.desc.Columns[i]
+		if c.nullif != nil && field == *c.nullif {
+			if !col.Nullable {
+				return sqlbase.NewNonNullViolationError(col.Name)
+			}
+			c.row[i] = parser.DNull
+			continue
+		}
+		typedExpr, err := (&parser.CastExpr{Expr: parser.NewDString(field), Type: c.types[i]}).TypeCheck(nil, nil)
+		if err != nil {
+			return err
+		}
+		if c.row[i], err = typedExpr.Eval(&c.evalCtx); err != nil {
+			return errors.Wrapf(err, "column %q", col.Name)
+		}
+		if err := sqlbase.CheckValueWidth(*col, c.row[i]); err != nil {
+			return err
+		}
+	}
+	return c.ri.insertRow(ctx, ing, c.row, true)
+}
+
+// kvIngester buffers the KVs generated for imported rows and sends them to
+// the KV layer in IngestRequests. It implements putter.
+type kvIngester struct {
+	db    *client.DB
+	ts    hlc.Timestamp
+	kvs   []roachpb.KeyValue
+	bytes int64
+}
+
+var _ putter = &kvIngester{}
+
+// Put is part of the putter interface.
+func (ing *kvIngester) Put(key, value interface{}) {
+	ing.add(*key.(*roachpb.Key), value.(*roachpb.Value))
+}
+
+// CPut is part of the putter interface. The expected value is ignored: the
+// imported table is empty and duplicates are not detected.
+func (ing *kvIngester) CPut(key, value, _ interface{}) {
+	ing.add(*key.(*roachpb.Key), value.(*roachpb.Value))
+}
+
+func (ing *kvIngester) add(key roachpb.Key, value *roachpb.Value) {
+	kv := roachpb.KeyValue{Key: append(roachpb.Key(nil), key...)}
+	kv.Value.RawBytes = append([]byte(nil), value.RawBytes...)
+	kv.Value.InitChecksum(kv.Key)
+	ing.kvs = append(ing.kvs, kv)
+	ing.bytes += int64(len(kv.Key) + len(kv.Value.RawBytes))
+}
+
+func (ing *kvIngester) size() int64 {
+	return ing.bytes
+}
+
+// flush sends the buffered KVs, sorted, in a single IngestRequest spanning
+// them. The DistSender splits it up along range boundaries.
+func (ing *kvIngester) flush(ctx context.Context) error {
+	if len(ing.kvs) == 0 {
+		return nil
+	}
+	sort.Sort(roachpb.KeyValueByKey(ing.kvs))
+	var builder engine.RocksDBBatchBuilder
+	for _, kv := range ing.kvs {
+		builder.Put(engine.MVCCKey{Key: kv.Key, Timestamp: ing.ts}, kv.Value.RawBytes)
+	}
+	if log.V(2) {
+		log.Infof(ctx, "ingesting %d KVs (%d bytes)", len(ing.kvs), ing.bytes)
+	}
+	b := &client.Batch{}
+	b.AddRawRequest(&roachpb.IngestRequest{
+		Span: roachpb.Span{
+			Key:    ing.kvs[0].Key,
+			EndKey: ing.kvs[len(ing.kvs)-1].Key.Next(),
+		},
+		Data: builder.Finish(),
+	})
+	if err := ing.db.Run(b); err != nil {
+		return err
+	}
+	ing.kvs, ing.bytes = ing.kvs[:0], 0
+	return nil
+}
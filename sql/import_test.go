@@ -0,0 +1,107 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestImportCSV(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	local := filepath.Join(dir, "a.csv")
+	if err := ioutil.WriteFile(local, []byte("1,x,10\n# skipped\n2,,20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/b.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "3,\"y,z\",30\n4,w,40\n")
+	}))
+	defer srv.Close()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.ExecRowsAffected(4, fmt.Sprintf(`IMPORT TABLE d.t (a INT PRIMARY KEY, b STRING, c INT, INDEX (c))
+		CSV DATA ('nodelocal://%s', '%s/b.csv') WITH comment = '#', nullif = ''`, local, srv.URL))
+
+	rows := r.Query(`SELECT a, b, c FROM d.t@t_c_idx ORDER BY c`)
+	var results []string
+	for rows.Next() {
+		var a, c int
+		var b *string
+		if err := rows.Scan(&a, &b, &c); err != nil {
+			t.Fatal(err)
+		}
+		bs := "NULL"
+		if b != nil {
+			bs = *b
+		}
+		results = append(results, fmt.Sprintf("%d %s %d", a, bs, c))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[1 x 10 2 NULL 20 3 y,z 30 4 w 40]"; fmt.Sprint(results) != expected {
+		t.Fatalf("expected %s, got %s", expected, results)
+	}
+
+	for _, tc := range []struct {
+		stmt, expected string
+	}{
+		{`IMPORT TABLE d.t (a INT PRIMARY KEY) CSV DATA ('nodelocal://%s')`,
+			`table "t" already exists`},
+		{`IMPORT TABLE d.u (a INT) CSV DATA ('nodelocal://%s')`,
+			`IMPORT requires an explicit PRIMARY KEY`},
+		{`IMPORT TABLE d.u (a INT PRIMARY KEY REFERENCES d.t) CSV DATA ('nodelocal://%s')`,
+			`IMPORT does not support foreign keys`},
+		{`IMPORT TABLE d.u (a INT PRIMARY KEY) CSV DATA ('nodelocal://%s') WITH foo = 'bar'`,
+			`unsupported IMPORT option "foo"`},
+		{`IMPORT TABLE d.u (a INT PRIMARY KEY, b STRING, c INT) CSV DATA ('nodelocal://%s') WITH delimiter = '|'`,
+			`wrong number of fields`},
+	} {
+		if _, err := sqlDB.Exec(fmt.Sprintf(tc.stmt, local)); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.stmt, tc.expected, err)
+		}
+	}
+	if _, err := sqlDB.Exec(`SELECT * FROM d.u`); !testutils.IsError(err, `table "d.u" does not exist`) {
+		t.Fatalf("expected the failed imports not to create a table, got %v", err)
+	}
+}
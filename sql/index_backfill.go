@@ -0,0 +1,315 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings/cluster"
+	"github.com/cockroachdb/cockroach/sql/distsql"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+func init() {
+	distsql.BackfillFunc = backfillIndexSpans
+}
+
+// indexBulkBackfillChunkSize is the maximum number of rows read per
+// transaction by the Backfiller processors.
+const indexBulkBackfillChunkSize = 1000
+
+// canBulkBackfill returns whether the entries of the added indexes can be
+// ingested as SSTables. The entries of unique indexes are written with
+// transactional conditional puts instead, which detect duplicate values, and
+// so are all the entries until the cluster version supports AddSSTable.
+func canBulkBackfill(added []sqlbase.IndexDescriptor) bool {
+	if !cluster.IsActive(cluster.VersionAddSSTable) {
+		return false
+	}
+	for _, index := range added {
+		if index.Unique {
+			return false
+		}
+	}
+	return true
+}
+
+// bulkBackfillIndexes backfills the added indexes by running a Backfiller
+// processor on each node holding ranges of the table. Every processor reads
+// its part of the primary index at a fixed timestamp and ingests the index
+// entries of the rows as SSTables written at that timestamp. This is correct
+// because the indexes are already write-only on all the nodes at that
+// timestamp: rows written or deleted later maintain their index entries
+// themselves, which shadow the ingested ones.
+//
+// The backfill is not checkpointed: if it fails, it is retried as a whole,
+// and the entries ingested again are identical to the ones already there.
+func (sc *SchemaChanger) bulkBackfillIndexes(
+	lease *sqlbase.TableDescriptor_SchemaChangeLease, added []sqlbase.IndexDescriptor,
+) error {
+	l, err := sc.ExtendLease(*lease)
+	if err != nil {
+		return err
+	}
+	*lease = l
+
+	var tableDesc *sqlbase.TableDescriptor
+	var readAt hlc.Timestamp
+	if err := sc.db.Txn(func(txn *client.Txn) error {
+		var err error
+		tableDesc, err = sqlbase.GetTableDescFromID(txn, sc.tableID)
+		readAt = txn.Proto.OrigTimestamp
+		return err
+	}); err != nil {
+		return err
+	}
+	// Short circuit the backfill if the table has been deleted.
+	if tableDesc.Deleted() {
+		return nil
+	}
+
+	prefix := roachpb.Key(sqlbase.MakeIndexKeyPrefix(tableDesc, tableDesc.PrimaryIndex.ID))
+	spans, err := sc.partitionSpans(roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()})
+	if err != nil {
+		return err
+	}
+	addrs := make([]string, 0, len(spans))
+	for addr := range spans {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	ctx := context.TODO()
+	counts := make([]int64, len(addrs))
+	errs := make([]error, len(addrs))
+	doneC := make(chan struct{}, len(addrs))
+	for i := range addrs {
+		spec := &distsql.BackfillerSpec{
+			Table:      *tableDesc,
+			Spans:      spans[addrs[i]],
+			MutationID: uint32(sc.mutationID),
+			Walltime:   readAt.WallTime,
+		}
+		req := makeCountFlowRequest(roachpb.Transaction{}, distsql.ProcessorCoreUnion{Backfiller: spec})
+		go func(i int) {
+			if addrs[i] == "" {
+				counts[i], errs[i] = runLocalCountFlow(sc.distSQLSrv, req)
+			} else {
+				counts[i], errs[i] = runRemoteCountFlow(sc.distSQLSrv, addrs[i], req)
+			}
+			doneC <- struct{}{}
+		}(i)
+	}
+
+	// Keep the schema change lease while the flows run, and report the
+	// progress of the job as they complete.
+	ticker := time.NewTicker(MinSchemaChangeLeaseDuration / 2)
+	defer ticker.Stop()
+	var progressErr error
+	for done := 0; done < len(addrs); {
+		select {
+		case <-doneC:
+			done++
+			if progressErr == nil {
+				progressErr = sc.job.Progressed(ctx, 2.0/3+float32(done)/float32(len(addrs))/3)
+			}
+		case <-ticker.C:
+			if progressErr == nil {
+				var l sqlbase.TableDescriptor_SchemaChangeLease
+				if l, progressErr = sc.ExtendLease(*lease); progressErr == nil {
+					*lease = l
+				}
+			}
+		}
+	}
+
+	var total int64
+	for i := range addrs {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		total += counts[i]
+	}
+	if log.V(2) {
+		log.Infof(ctx, "backfilled %d index entries of table %d", total, sc.tableID)
+	}
+	return progressErr
+}
+
+// partitionSpans splits span along the boundaries of the ranges holding it,
+// and groups the resulting spans by the address of the node to read them
+// on, that of a replica of their range. Spans are grouped under the empty
+// address, meaning this node, when the nodes can't be located.
+func (sc *SchemaChanger) partitionSpans(span roachpb.Span) (map[string][]distsql.TableReaderSpan, error) {
+	if sc.gossip == nil {
+		return map[string][]distsql.TableReaderSpan{"": {{Span: span}}}, nil
+	}
+	ranges, err := lookupRangeDescriptors(&sc.db, span.Key, span.EndKey)
+	if err != nil {
+		return nil, err
+	}
+	spans := make(map[string][]distsql.TableReaderSpan)
+	for _, rng := range ranges {
+		sp := span
+		if start := roachpb.Key(rng.StartKey); bytes.Compare(start, sp.Key) > 0 {
+			sp.Key = start
+		}
+		if end := roachpb.Key(rng.EndKey); bytes.Compare(end, sp.EndKey) < 0 {
+			sp.EndKey = end
+		}
+		if bytes.Compare(sp.Key, sp.EndKey) >= 0 {
+			continue
+		}
+		var addr string
+		if len(rng.Replicas) > 0 {
+			if desc, err := sc.gossip.GetNodeDescriptor(rng.Replicas[0].NodeID); err == nil {
+				addr = desc.Address.String()
+			}
+		}
+		spans[addr] = append(spans[addr], distsql.TableReaderSpan{Span: sp})
+	}
+	if len(spans) == 0 {
+		spans[""] = []distsql.TableReaderSpan{{Span: span}}
+	}
+	return spans, nil
+}
+
+// backfillIndexSpans reads the rows in the spans of spec at spec.Walltime and
+// ingests the entries of the indexes added by spec.MutationID, written at the
+// same timestamp, through db. It returns the number of index entries
+// ingested. It is run by the Backfiller processor.
+func backfillIndexSpans(
+	ctx context.Context, db *client.DB, spec *distsql.BackfillerSpec,
+) (int64, error) {
+	desc := &spec.Table
+	var added []sqlbase.IndexDescriptor
+	for _, m := range desc.Mutations {
+		if m.MutationID != sqlbase.MutationID(spec.MutationID) ||
+			m.Direction != sqlbase.DescriptorMutation_ADD {
+			continue
+		}
+		if index := m.GetIndex(); index != nil {
+			added = append(added, *index)
+		}
+	}
+
+	ts := hlc.Timestamp{WallTime: spec.Walltime}
+	ing := sstIngester{db: db, ts: ts}
+	var count int64
+	for _, span := range spec.Spans {
+		sp := sqlbase.Span{Start: span.Span.Key, End: span.Span.EndKey}
+		for sp.Start != nil {
+			var entries []sqlbase.IndexEntry
+			var nextKey roachpb.Key
+			if err := db.Txn(func(txn *client.Txn) error {
+				setTxnTimestamps(txn, ts)
+				var err error
+				entries, nextKey, err = indexEntriesChunk(txn, desc, added, sp, indexBulkBackfillChunkSize)
+				return err
+			}); err != nil {
+				return count, err
+			}
+			for _, entry := range entries {
+				ing.add(entry.Key, entry.Value)
+			}
+			count += int64(len(entries))
+			sp.Start = nextKey
+			if ing.size() >= importBatchSize {
+				if err := ing.flush(ctx); err != nil {
+					return count, err
+				}
+			}
+		}
+	}
+	return count, ing.flush(ctx)
+}
+
+// sstIngester buffers KVs and sends them to the KV layer as SSTables in
+// AddSSTableRequests, all written at the same timestamp.
+type sstIngester struct {
+	db    *client.DB
+	ts    hlc.Timestamp
+	kvs   []roachpb.KeyValue
+	bytes int64
+}
+
+func (ing *sstIngester) add(key roachpb.Key, value roachpb.Value) {
+	kv := roachpb.KeyValue{Key: append(roachpb.Key(nil), key...)}
+	kv.Value.RawBytes = append([]byte(nil), value.RawBytes...)
+	kv.Value.ClearChecksum()
+	kv.Value.InitChecksum(kv.Key)
+	ing.kvs = append(ing.kvs, kv)
+	ing.bytes += int64(len(kv.Key) + len(kv.Value.RawBytes))
+}
+
+func (ing *sstIngester) size() int64 {
+	return ing.bytes
+}
+
+// flush sends the buffered KVs as a single SSTable spanning them. The
+// DistSender splits the request up along range boundaries.
+func (ing *sstIngester) flush(ctx context.Context) error {
+	if len(ing.kvs) == 0 {
+		return nil
+	}
+	sort.Sort(roachpb.KeyValueByKey(ing.kvs))
+	sst, err := engine.MakeRocksDBSstFileWriter()
+	if err != nil {
+		return err
+	}
+	defer sst.Close()
+	for i, kv := range ing.kvs {
+		// The same entry can be generated twice, e.g. for the elements of
+		// an inverted index.
+		if i > 0 && kv.Key.Equal(ing.kvs[i-1].Key) {
+			continue
+		}
+		if err := sst.Add(engine.MVCCKeyValue{
+			Key:   engine.MVCCKey{Key: kv.Key, Timestamp: ing.ts},
+			Value: kv.Value.RawBytes,
+		}); err != nil {
+			return err
+		}
+	}
+	if log.V(2) {
+		log.Infof(ctx, "adding an sstable of %d KVs (%d bytes)", len(ing.kvs), ing.bytes)
+	}
+	data, err := sst.Finish()
+	if err != nil {
+		return err
+	}
+	b := &client.Batch{}
+	b.AddRawRequest(&roachpb.AddSSTableRequest{
+		Span: roachpb.Span{
+			Key:    ing.kvs[0].Key,
+			EndKey: ing.kvs[len(ing.kvs)-1].Key.Next(),
+		},
+		Data: data,
+	})
+	if err := ing.db.Run(b); err != nil {
+		return err
+	}
+	ing.kvs, ing.bytes = ing.kvs[:0], 0
+	return nil
+}
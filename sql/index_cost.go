@@ -0,0 +1,265 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+)
+
+// When the statistics of a table are available, the cost of scanning an
+// index is estimated from the number of rows the scan reads. The costs are
+// relative to that of reading a key sequentially.
+const (
+	// columnReadCost is the cost of decoding a column of a row.
+	columnReadCost = 0.1
+	// indexJoinLookupCost is the cost of looking up a key of the primary
+	// index for a row found in a non-covering index.
+	indexJoinLookupCost = 4
+	// sortRowCost is the cost per comparison of sorting the rows scanned
+	// when the index doesn't provide the desired ordering.
+	sortRowCost = 0.1
+	// orderMatchingLimitRows is the estimated number of rows read by a scan
+	// providing the desired ordering when the query has a small limit; see
+	// preferOrderMatching in selectIndex.
+	orderMatchingLimitRows = 1000
+
+	// The selectivities used for the columns without statistics.
+	defaultEqSelectivity    = 0.01
+	defaultRangeSelectivity = 1.0 / 3
+	// invertedSelectivity is the fraction of the rows estimated to match
+	// the containment condition restricting an inverted index.
+	invertedSelectivity = 0.01
+)
+
+// estimateCost estimates the cost of scanning the index using the
+// statistics of the table, replacing the heuristic cost. It must be called
+// once the constraints and the ordering of the index have been analyzed.
+func (v *indexInfo) estimateCost(stats *tableStatistics, preferOrderMatching bool) {
+	v.estimatedRows = v.estimateRows(stats)
+
+	v.cost = v.estimatedRows * v.rowCost()
+	if !v.covering {
+		pkKeys := float64(len(v.desc.Families))
+		if pkKeys == 0 {
+			pkKeys = 1
+		}
+		v.cost += v.estimatedRows * pkKeys * indexJoinLookupCost
+	}
+
+	if v.orderingCols > 0 {
+		if v.orderingMatch < v.orderingCols {
+			v.cost += v.estimatedRows * math.Log2(v.estimatedRows+1) * sortRowCost
+		} else if preferOrderMatching && v.estimatedRows > orderMatchingLimitRows {
+			// The scan stops once enough rows are found.
+			v.cost *= orderMatchingLimitRows / v.estimatedRows
+		}
+	}
+}
+
+// rowCost returns the cost of reading a row of the index.
+func (v *indexInfo) rowCost() float64 {
+	if v.index == &v.desc.PrimaryIndex {
+		keys := len(v.desc.Families)
+		if keys == 0 {
+			keys = 1
+		}
+		return float64(keys) + columnReadCost*float64(len(v.desc.Columns))
+	}
+	numCols := len(v.index.ColumnIDs) + len(v.index.ImplicitColumnIDs)
+	return 1 + columnReadCost*float64(numCols)
+}
+
+// estimateRows estimates the number of rows read by the scan of the index.
+func (v *indexInfo) estimateRows(stats *tableStatistics) float64 {
+	rowCount := float64(stats.rowCount)
+	var sel float64
+	switch {
+	case v.invertedKey != nil:
+		sel = invertedSelectivity
+	case len(v.constraints) == 0:
+		sel = 1
+	default:
+		// The rows matching the disjunctions may overlap; adding up their
+		// selectivities overestimates the rows read.
+		for _, c := range v.constraints {
+			sel += v.constraintsSelectivity(c, stats)
+		}
+	}
+	rows := rowCount * math.Min(sel, 1)
+	if rows < 1 {
+		// The statistics may be stale; never assume that a scan reads no rows.
+		rows = 1
+	}
+	return rows
+}
+
+// constraintsSelectivity estimates the fraction of the rows of the table read
+// by a scan restricted by the constraints. Only the constraints used to
+// compute the spans of the scan are considered: those on the columns of the
+// exact prefix and the first one restricting a range of values.
+func (v *indexInfo) constraintsSelectivity(
+	constraints indexConstraints, stats *tableStatistics,
+) float64 {
+	sel := 1.0
+	colIdx := 0
+	for _, c := range constraints {
+		if c.tupleMap != nil {
+			// Tuple comparisons are estimated using the distinct counts of
+			// their columns only.
+			if c.start != c.end || (c.start.Operator != parser.EQ && c.start.Operator != parser.In) {
+				return sel * defaultRangeSelectivity
+			}
+			tupleSel := 1.0
+			for range c.tupleMap {
+				tupleSel *= stats.columns[v.index.ColumnIDs[colIdx]].eqSelectivity(nil)
+				colIdx++
+			}
+			if t, ok := c.start.Right.(*parser.DTuple); ok && c.start.Operator == parser.In {
+				tupleSel *= float64(len(*t))
+			}
+			sel *= math.Min(tupleSel, 1)
+			continue
+		}
+
+		stat := stats.columns[v.index.ColumnIDs[colIdx]]
+		colIdx++
+		if c.start == c.end {
+			switch c.start.Operator {
+			case parser.EQ:
+				sel *= stat.eqSelectivity(c.start.Right.(parser.Datum))
+				continue
+			case parser.In:
+				inSel := 0.0
+				if t, ok := c.start.Right.(*parser.DTuple); ok {
+					for _, d := range *t {
+						inSel += stat.eqSelectivity(d)
+					}
+				}
+				sel *= math.Min(inSel, 1)
+				continue
+			}
+		}
+		return sel * stat.rangeSelectivity(c.start, c.end)
+	}
+	return sel
+}
+
+// eqSelectivity estimates the fraction of the rows of the table in which the
+// column is equal to the datum. A nil datum stands for an unknown value.
+func (s *tableStatistic) eqSelectivity(d parser.Datum) float64 {
+	if s == nil || s.rowCount == 0 {
+		return defaultEqSelectivity
+	}
+	if d == parser.DNull {
+		return 0
+	}
+	numValues := float64(s.rowCount - s.nullCount)
+	if s.distinctCount == 0 {
+		return 1 / float64(s.rowCount)
+	}
+	// The average number of rows per distinct value.
+	rows := numValues / float64(s.distinctCount)
+	if d != nil && len(s.histogram) > 0 && s.histogram[0].upperBound.TypeEqual(d) {
+		// Find the bucket the value falls into.
+		i := 0
+		for i < len(s.histogram) && s.histogram[i].upperBound.Compare(d) < 0 {
+			i++
+		}
+		switch {
+		case i == len(s.histogram):
+			// The value is beyond the last bucket.
+			rows = 1
+		case s.histogram[i].upperBound.Compare(d) == 0:
+			rows = float64(s.histogram[i].NumEq)
+		default:
+			rows = math.Min(rows, float64(s.histogram[i].NumRange))
+		}
+	}
+	return math.Max(rows, 1) / float64(s.rowCount)
+}
+
+// rangeSelectivity estimates the fraction of the rows of the table in which
+// the column satisfies the start and end constraints on it.
+func (s *tableStatistic) rangeSelectivity(start, end *parser.ComparisonExpr) float64 {
+	if s == nil || s.rowCount == 0 {
+		return defaultRangeSelectivity
+	}
+	nullFrac := float64(s.nullCount) / float64(s.rowCount)
+
+	// Find the bounds of the range. Depending on the direction of the
+	// column, the lower bound may be in the end constraint and vice versa.
+	var lo, hi parser.Datum
+	var loInclusive, hiInclusive bool
+	for _, c := range []*parser.ComparisonExpr{start, end} {
+		if c == nil {
+			continue
+		}
+		switch c.Operator {
+		case parser.Is:
+			return nullFrac
+		case parser.GE, parser.GT:
+			lo, loInclusive = c.Right.(parser.Datum), c.Operator == parser.GE
+		case parser.LE, parser.LT:
+			hi, hiInclusive = c.Right.(parser.Datum), c.Operator == parser.LE
+		}
+	}
+	if lo == nil && hi == nil {
+		// IS NOT NULL.
+		return 1 - nullFrac
+	}
+	if len(s.histogram) == 0 || (lo != nil && !s.histogram[0].upperBound.TypeEqual(lo)) ||
+		(hi != nil && !s.histogram[0].upperBound.TypeEqual(hi)) {
+		return (1 - nullFrac) * defaultRangeSelectivity
+	}
+
+	numValues := 0.0
+	for _, b := range s.histogram {
+		numValues += float64(b.NumEq + b.NumRange)
+	}
+	rows := numValues
+	if hi != nil {
+		rows = s.rowsBelow(hi, hiInclusive)
+	}
+	if lo != nil {
+		rows -= s.rowsBelow(lo, !loInclusive)
+	}
+	return math.Max(rows, 1) / float64(s.rowCount)
+}
+
+// rowsBelow estimates the number of rows of the histogram in which the column
+// is less than the datum, or less than or equal to it if inclusive is set.
+// The values within a bucket are assumed to be uniformly distributed.
+func (s *tableStatistic) rowsBelow(d parser.Datum, inclusive bool) float64 {
+	rows := 0.0
+	for _, b := range s.histogram {
+		cmp := b.upperBound.Compare(d)
+		if cmp < 0 {
+			rows += float64(b.NumRange + b.NumEq)
+			continue
+		}
+		if cmp == 0 {
+			rows += float64(b.NumRange)
+			if inclusive {
+				rows += float64(b.NumEq)
+			}
+		} else {
+			rows += float64(b.NumRange) / 2
+		}
+		break
+	}
+	return rows
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func makeTestHistogram(buckets ...[3]int64) []histogramBucket {
+	h := make([]histogramBucket, len(buckets))
+	for i, b := range buckets {
+		h[i] = histogramBucket{
+			NumEq:      b[0],
+			NumRange:   b[1],
+			upperBound: parser.NewDInt(parser.DInt(b[2])),
+		}
+	}
+	return h
+}
+
+func TestEstimateRows(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Column a (ID 1) has 100 distinct values, uniformly distributed between
+	// 1 and 100. Column b (ID 2) has 5 distinct values and 20 NULLs; most of
+	// its values are 1. There are no statistics on the other columns.
+	stats := &tableStatistics{
+		rowCount: 100,
+		columns: map[sqlbase.ColumnID]*tableStatistic{
+			1: {
+				columnID: 1, rowCount: 100, distinctCount: 100,
+				histogram: makeTestHistogram([3]int64{1, 24, 25}, [3]int64{1, 24, 50},
+					[3]int64{1, 24, 75}, [3]int64{1, 24, 100}),
+			},
+			2: {
+				columnID: 2, rowCount: 100, distinctCount: 5, nullCount: 20,
+				histogram: makeTestHistogram([3]int64{60, 0, 1}, [3]int64{5, 0, 2},
+					[3]int64{5, 0, 3}, [3]int64{5, 0, 4}, [3]int64{5, 0, 5}),
+			},
+		},
+	}
+
+	testData := []struct {
+		expr     string
+		columns  string
+		expected float64
+	}{
+		{`c`, `a`, 100},
+		{`a = 30`, `a`, 1},
+		{`a = 50`, `a`, 1},
+		{`a = 30 OR a = 80`, `a`, 2},
+		{`a > 50`, `a`, 38},
+		{`a <= 50`, `a`, 50},
+		{`a >= 25 AND a < 75`, `a`, 38},
+		{`a > 200`, `a`, 1},
+		{`b = 1`, `b`, 60},
+		{`b = 3`, `b`, 5},
+		{`b IN (1, 2)`, `b`, 65},
+		{`b IS NULL`, `b`, 20},
+		{`b != 1`, `b`, 80},
+		{`b = 1 AND a > 50`, `b,a`, 60 * 0.38},
+		{`j = 1`, `j`, 1},
+		{`j > 1`, `j`, 100.0 / 3},
+	}
+	for _, d := range testData {
+		desc, index := makeTestIndexFromStr(t, d.columns)
+		constraints, _ := makeConstraints(t, d.expr, desc, index)
+		v := &indexInfo{desc: desc, index: index, constraints: constraints}
+		if rows := v.estimateRows(stats); math.Abs(rows-d.expected) > 1e-6 {
+			t.Errorf("%s on (%s): expected %v rows, got %v (constraints %s)",
+				d.expr, d.columns, d.expected, rows, constraints)
+		}
+	}
+}
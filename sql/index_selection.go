@@ -74,6 +74,13 @@ type analyzeOrderingFn func(indexOrdering orderingInfo) (matchingCols, totalCols
 // these constraints and the best index is selected. The constraints are then
 // transformed into a set of spans to scan within the index.
 //
+// If statistics were collected on the table, the candidates are ranked by
+// the estimated cost of the scan, which accounts for the number of rows read
+// (from the histograms and distinct counts of the constrained columns), the
+// lookups of the index join for non-covering indexes and the sort needed if
+// the index doesn't provide the desired ordering. Otherwise heuristic costs
+// are used.
+//
 // The analyzeOrdering function is used to determine how useful the ordering of
 // an index is. If no particular ordering is desired, it can be nil.
 //
@@ -113,10 +120,12 @@ func selectIndex(
 		c.init(s)
 	}
 
+	var exprs []parser.TypedExprs
 	if s.filter != nil {
 		// Analyze the filter expression, simplifying it and splitting it up into
 		// possibly overlapping ranges.
-		exprs, equivalent := analyzeExpr(s.filter)
+		var equivalent bool
+		exprs, equivalent = analyzeExpr(s.filter)
 		if log.V(2) {
 			log.Infof(s.p.ctx(), "analyzeExpr: %s -> %s [equivalent=%v]", s.filter, exprs, equivalent)
 		}
@@ -156,6 +165,49 @@ func selectIndex(
 		}
 	}
 
+	// Eliminate the partial indexes that don't contain all of the rows
+	// satisfying the filter.
+	for i := 0; i < len(candidates); {
+		if index := candidates[i].index; index.IsPartial() {
+			usable, err := s.partialIndexUsable(index, exprs)
+			if err != nil {
+				return nil, err
+			}
+			if !usable {
+				if s.specifiedIndex != nil {
+					return nil, fmt.Errorf("index \"%s\" is a partial index that does not contain all the rows needed to execute this query",
+						index.Name)
+				}
+				candidates[i] = candidates[len(candidates)-1]
+				candidates = candidates[:len(candidates)-1]
+				continue
+			}
+		}
+		i++
+	}
+
+	// Eliminate the inverted indexes that can't be restricted by a containment
+	// condition of the filter.
+	for i := 0; i < len(candidates); {
+		if c := candidates[i]; c.index.IsInverted() {
+			invertedKey, err := s.invertedIndexKey(c.index)
+			if err != nil {
+				return nil, err
+			}
+			if invertedKey == nil {
+				if s.specifiedIndex != nil {
+					return nil, fmt.Errorf("index \"%s\" is inverted and cannot be used for this query",
+						c.index.Name)
+				}
+				candidates[i] = candidates[len(candidates)-1]
+				candidates = candidates[:len(candidates)-1]
+				continue
+			}
+			c.invertedKey = invertedKey
+		}
+		i++
+	}
+
 	if s.noIndexJoin {
 		// Eliminate non-covering indexes. We do this after the check above for
 		// constant false filter.
@@ -184,12 +236,20 @@ func selectIndex(
 		}
 	}
 
+	// If statistics were collected on the table, replace the heuristic costs
+	// by estimates based on the number of rows read.
+	if stats := s.p.getTableStatistics(&s.desc); stats != nil {
+		for _, c := range candidates {
+			c.estimateCost(stats, preferOrderMatching)
+		}
+	}
+
 	indexInfoByCost(candidates).Sort()
 
 	if log.V(2) {
 		for i, c := range candidates {
-			log.Infof(s.p.ctx(), "%d: selectIndex(%s): cost=%v constraints=%s reverse=%t",
-				i, c.index.Name, c.cost, c.constraints, c.reverse)
+			log.Infof(s.p.ctx(), "%d: selectIndex(%s): cost=%v rows=%v constraints=%s reverse=%t",
+				i, c.index.Name, c.cost, c.estimatedRows, c.constraints, c.reverse)
 		}
 	}
 
@@ -198,7 +258,11 @@ func selectIndex(
 	c := candidates[0]
 	s.index = c.index
 	s.isSecondaryIndex = (c.index != &s.desc.PrimaryIndex)
-	s.spans = makeSpans(c.constraints, c.desc, c.index)
+	if c.invertedKey != nil {
+		s.spans = makeInvertedIndexSpans(c.desc, c.index, c.invertedKey)
+	} else {
+		s.spans = makeSpans(c.constraints, c.desc, c.index)
+	}
 	if len(s.spans) == 0 {
 		// There are no spans to scan.
 		return &emptyNode{}, nil
@@ -306,6 +370,16 @@ type indexInfo struct {
 	covering    bool // Does the index cover the required qvalues?
 	reverse     bool
 	exactPrefix int
+	// The key of the entries to scan in an inverted index.
+	invertedKey []byte
+
+	// orderingMatch is the number of columns of the desired ordering, of
+	// which there are orderingCols, provided by the index.
+	orderingMatch int
+	orderingCols  int
+	// estimatedRows is the number of rows the scan of the index is
+	// estimated to read, if the statistics of the table are available.
+	estimatedRows float64
 }
 
 func (v *indexInfo) init(s *scanNode) {
@@ -330,6 +404,11 @@ func (v *indexInfo) init(s *scanNode) {
 // analyzeExprs examines the range map to determine the cost of using the
 // index.
 func (v *indexInfo) analyzeExprs(exprs []parser.TypedExprs) {
+	if v.index.IsInverted() {
+		// Inverted indexes are only restricted by containment conditions, which
+		// are analyzed separately.
+		return
+	}
 	if err := v.makeOrConstraints(exprs); err != nil {
 		panic(err)
 	}
@@ -393,6 +472,7 @@ func (v *indexInfo) analyzeOrdering(scan *scanNode, analyzeOrdering analyzeOrder
 		match = revMatch
 		v.reverse = true
 	}
+	v.orderingMatch, v.orderingCols = match, orderCols
 	weight := float64(orderCols+1) / float64(match+1)
 	v.cost *= weight
 
@@ -755,6 +835,14 @@ func (v *indexInfo) isCoveringIndex(scan *scanNode) bool {
 		// The primary key index always covers all of the columns.
 		return true
 	}
+	if v.index.IsInverted() {
+		// The values of the column of an inverted index aren't stored in it.
+		return false
+	}
+	if scan.lockRows {
+		// Rows are locked in the primary index.
+		return false
+	}
 
 	for i, needed := range scan.valNeededForCol {
 		if needed {
@@ -28,6 +28,9 @@ var informationSchema = virtualSchema{
 	name: "information_schema",
 	tables: []virtualSchemaTable{
 		informationSchemaColumnsTable,
+		informationSchemaKeyColumnUsageTable,
+		informationSchemaSchemataTable,
+		informationSchemaTableConstraintTable,
 		informationSchemaTablesTable,
 	},
 }
@@ -136,9 +139,141 @@ func datetimePrecision(colType sqlbase.ColumnType) parser.Datum {
 	return parser.DNull
 }
 
+var informationSchemaKeyColumnUsageTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE information_schema.key_column_usage (
+  CONSTRAINT_CATALOG STRING NOT NULL DEFAULT '',
+  CONSTRAINT_SCHEMA STRING NOT NULL DEFAULT '',
+  CONSTRAINT_NAME STRING NOT NULL DEFAULT '',
+  TABLE_CATALOG STRING NOT NULL DEFAULT '',
+  TABLE_SCHEMA STRING NOT NULL DEFAULT '',
+  TABLE_NAME STRING NOT NULL DEFAULT '',
+  COLUMN_NAME STRING NOT NULL DEFAULT '',
+  ORDINAL_POSITION INT NOT NULL DEFAULT 0,
+  POSITION_IN_UNIQUE_CONSTRAINT INT
+);`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if isVirtualDescriptor(table) || table.IsView() {
+					return
+				}
+				dbNameStr := parser.NewDString(db.Name)
+				tbNameStr := parser.NewDString(table.Name)
+				appendKeys := func(name string, columns []string, fk bool) {
+					constraintNameStr := parser.NewDString(name)
+					for i, col := range columns {
+						pos := parser.NewDInt(parser.DInt(i + 1))
+						uniquePos := parser.DNull
+						if fk {
+							// Foreign keys reference a unique index whose columns
+							// are in the same order as the referencing columns.
+							uniquePos = pos
+						}
+						addRow(
+							defString,              // constraint_catalog
+							dbNameStr,              // constraint_schema
+							constraintNameStr,      // constraint_name
+							defString,              // table_catalog
+							dbNameStr,              // table_schema
+							tbNameStr,              // table_name
+							parser.NewDString(col), // column_name
+							pos,                    // ordinal_position, 1-indexed
+							uniquePos,              // position_in_unique_constraint
+						)
+					}
+				}
+				for _, index := range table.AllNonDropIndexes() {
+					if index.ID == table.PrimaryIndex.ID || (index.Unique && !index.IsPartial()) {
+						appendKeys(index.Name, index.ColumnNames, false /* fk */)
+					}
+					if index.ForeignKey.IsSet() {
+						appendKeys(index.ForeignKey.Name, index.ColumnNames, true /* fk */)
+					}
+				}
+			},
+		)
+	},
+}
+
+var informationSchemaSchemataTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE information_schema.schemata (
+  CATALOG_NAME STRING NOT NULL DEFAULT '',
+  SCHEMA_NAME STRING NOT NULL DEFAULT '',
+  DEFAULT_CHARACTER_SET_NAME STRING NOT NULL DEFAULT '',
+  SQL_PATH STRING
+);`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		return forEachDatabaseDesc(p, func(db *sqlbase.DatabaseDescriptor) {
+			addRow(
+				defString,                  // catalog_name
+				parser.NewDString(db.Name), // schema_name
+				parser.NewDString(""),      // default_character_set_name
+				parser.DNull,               // sql_path
+			)
+		})
+	},
+}
+
+var (
+	constraintTypePrimaryKey = parser.NewDString("PRIMARY KEY")
+	constraintTypeUnique     = parser.NewDString("UNIQUE")
+	constraintTypeForeignKey = parser.NewDString("FOREIGN KEY")
+	constraintTypeCheck      = parser.NewDString("CHECK")
+)
+
+var informationSchemaTableConstraintTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE information_schema.table_constraints (
+  CONSTRAINT_CATALOG STRING NOT NULL DEFAULT '',
+  CONSTRAINT_SCHEMA STRING NOT NULL DEFAULT '',
+  CONSTRAINT_NAME STRING NOT NULL DEFAULT '',
+  TABLE_SCHEMA STRING NOT NULL DEFAULT '',
+  TABLE_NAME STRING NOT NULL DEFAULT '',
+  CONSTRAINT_TYPE STRING NOT NULL DEFAULT ''
+);`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if isVirtualDescriptor(table) || table.IsView() {
+					return
+				}
+				dbNameStr := parser.NewDString(db.Name)
+				tbNameStr := parser.NewDString(table.Name)
+				appendConstraint := func(name string, typ parser.Datum) {
+					addRow(
+						defString,               // constraint_catalog
+						dbNameStr,               // constraint_schema
+						parser.NewDString(name), // constraint_name
+						dbNameStr,               // table_schema
+						tbNameStr,               // table_name
+						typ,                     // constraint_type
+					)
+				}
+				for _, index := range table.AllNonDropIndexes() {
+					if index.ID == table.PrimaryIndex.ID {
+						appendConstraint(index.Name, constraintTypePrimaryKey)
+					} else if index.Unique && !index.IsPartial() {
+						appendConstraint(index.Name, constraintTypeUnique)
+					}
+					if index.ForeignKey.IsSet() {
+						appendConstraint(index.ForeignKey.Name, constraintTypeForeignKey)
+					}
+				}
+				for _, check := range table.Checks {
+					appendConstraint(check.Name, constraintTypeCheck)
+				}
+			},
+		)
+	},
+}
+
 var (
 	tableTypeSystemView = parser.NewDString("SYSTEM VIEW")
 	tableTypeBaseTable  = parser.NewDString("BASE TABLE")
+	tableTypeView       = parser.NewDString("VIEW")
+	tableTypeTemporary  = parser.NewDString("LOCAL TEMPORARY")
 )
 
 var informationSchemaTablesTable = virtualSchemaTable{
@@ -156,6 +291,10 @@ CREATE TABLE information_schema.tables (
 				tableType := tableTypeBaseTable
 				if isVirtualDescriptor(table) {
 					tableType = tableTypeSystemView
+				} else if table.IsView() {
+					tableType = tableTypeView
+				} else if table.IsTemporary() {
+					tableType = tableTypeTemporary
 				}
 				addRow(
 					defString,                     // table_catalog
@@ -216,6 +355,9 @@ func forEachTableDesc(
 	// to database name to add descriptors to a dbDescTables' tables map.
 	for _, desc := range descs {
 		if table, ok := desc.(*sqlbase.TableDescriptor); ok {
+			if p.isOtherSessionTemporaryTable(table) {
+				continue
+			}
 			dbName, ok := dbIDsToName[table.GetParentID()]
 			if !ok {
 				return errors.Errorf("no database with ID %d found", table.GetParentID())
@@ -251,6 +393,47 @@ func forEachTableDesc(
 	return nil
 }
 
+// forEachDatabaseDesc retrieves all database descriptors, including those of
+// virtual schemas, and iterates through them in lexicographical order with
+// respect to their name. Databases the user has no privileges on are skipped.
+func forEachDatabaseDesc(p *planner, fn func(*sqlbase.DatabaseDescriptor)) error {
+	databases := make(map[string]*sqlbase.DatabaseDescriptor)
+
+	// Handle virtual schemas.
+	for dbName, schema := range virtualSchemaMap {
+		databases[dbName] = schema.desc
+	}
+
+	// Handle real schemas.
+	descs, err := p.getAllDescriptors()
+	if err != nil {
+		return err
+	}
+	for _, desc := range descs {
+		if db, ok := desc.(*sqlbase.DatabaseDescriptor); ok {
+			databases[db.GetName()] = db
+		}
+	}
+
+	dbNames := make([]string, 0, len(databases))
+	for dbName := range databases {
+		dbNames = append(dbNames, dbName)
+	}
+	sort.Strings(dbNames)
+	for _, dbName := range dbNames {
+		db := databases[dbName]
+		if !userCanSeeDatabase(db, p.session.User) {
+			continue
+		}
+		fn(db)
+	}
+	return nil
+}
+
+func userCanSeeDatabase(db *sqlbase.DatabaseDescriptor, user string) bool {
+	return db.Privileges.AnyPrivilege(user) || isVirtualDescriptor(db)
+}
+
 func userCanSeeTable(table *sqlbase.TableDescriptor, user string) bool {
 	return table.State == sqlbase.TableDescriptor_PUBLIC &&
 		(table.Privileges.AnyPrivilege(user) || isVirtualDescriptor(table))
@@ -31,10 +31,11 @@ import (
 type insertNode struct {
 	// The following fields are populated during makePlan.
 	editNodeBase
-	defaultExprs []parser.TypedExpr
-	n            *parser.Insert
-	insertRows   parser.SelectStatement
-	checkHelper  checkHelper
+	defaultExprs  []parser.TypedExpr
+	n             *parser.Insert
+	insertRows    parser.SelectStatement
+	computeHelper computeHelper
+	checkHelper   checkHelper
 
 	insertCols            []sqlbase.ColumnDescriptor
 	insertColIDtoRowIndex map[sqlbase.ColumnID]int
@@ -56,6 +57,18 @@ type insertNode struct {
 func (p *planner) Insert(
 	n *parser.Insert, desiredTypes []parser.Datum, autoCommit bool,
 ) (planNode, error) {
+	defer func(ctes *cteScope) { p.ctes = ctes }(p.ctes)
+	if err := p.pushWith(n.With); err != nil {
+		return nil, err
+	}
+	// The source rows may carry their own WITH clause, which is lost when
+	// fillDefaults unwraps the select below.
+	if n.Rows != nil {
+		if err := p.pushWith(n.Rows.With); err != nil {
+			return nil, err
+		}
+	}
+
 	tn, err := p.getAliasedTableName(n.Table)
 	if err != nil {
 		return nil, err
@@ -77,6 +90,12 @@ func (p *planner) Insert(
 		}
 	}
 
+	computedCols := en.tableDesc.WritableComputedColumns()
+	hasComputed := len(computedCols) > 0
+	if hasComputed && n.OnConflict != nil && !n.OnConflict.DoNothing {
+		return nil, fmt.Errorf("UPSERT is not supported on tables with computed columns")
+	}
+
 	var cols []sqlbase.ColumnDescriptor
 	// Determine which columns we're inserting into.
 	if n.DefaultValues() {
@@ -87,6 +106,21 @@ func (p *planner) Insert(
 			return nil, err
 		}
 	}
+	if hasComputed {
+		// Computed columns never receive an input value. They are only
+		// written to implicitly, when no column list is given.
+		inputCols := make([]sqlbase.ColumnDescriptor, 0, len(cols))
+		for _, col := range cols {
+			if col.IsComputed() {
+				if n.Columns != nil {
+					return nil, fmt.Errorf("cannot write directly to computed column %q", col.Name)
+				}
+				continue
+			}
+			inputCols = append(inputCols, col)
+		}
+		cols = inputCols
+	}
 	// Number of columns expecting an input. This doesn't include the
 	// columns receiving a default value.
 	numInputColumns := len(cols)
@@ -132,6 +166,15 @@ func (p *planner) Insert(
 		return nil, err
 	}
 
+	// Add the computed columns last; their values are computed in Next() from
+	// the values of the other columns, including any defaults.
+	for _, col := range computedCols {
+		cols = append(cols, col)
+		if defaultExprs != nil {
+			defaultExprs = append(defaultExprs, parser.DNull)
+		}
+	}
+
 	// Analyze the expressions for column information and typing.
 	desiredTypesFromSelect := make([]parser.Datum, len(cols))
 	for i, col := range cols {
@@ -169,10 +212,11 @@ func (p *planner) Insert(
 		}
 
 		if n.OnConflict.DoNothing {
-			// TODO(dan): Postgres allows ON CONFLICT DO NOTHING without specifying a
-			// conflict index, which means do nothing on any conflict. Support this if
-			// someone needs it.
-			tw = &tableUpserter{ri: ri, conflictIndex: *conflictIndex}
+			tw = &tableUpserter{
+				ri:            ri,
+				conflictIndex: *conflictIndex,
+				anyConflict:   len(n.OnConflict.Columns) == 0,
+			}
 		} else {
 			names, err := p.namesForExprs(updateExprs)
 			if err != nil {
@@ -206,6 +250,9 @@ func (p *planner) Insert(
 		tw: tw,
 	}
 
+	if err := in.computeHelper.init(p, tn, en.tableDesc); err != nil {
+		return nil, err
+	}
 	if err := in.checkHelper.init(p, tn, en.tableDesc); err != nil {
 		return nil, err
 	}
@@ -238,7 +285,12 @@ func (n *insertNode) expandPlan() error {
 
 		n.run.rowIdxToRetIdx = make([]int, len(n.insertCols))
 		for i, col := range n.insertCols {
-			n.run.rowIdxToRetIdx[i] = colIDToRetIndex[col.ID]
+			if retIdx, ok := colIDToRetIndex[col.ID]; ok {
+				n.run.rowIdxToRetIdx[i] = retIdx
+			} else {
+				// Columns being added aren't returned.
+				n.run.rowIdxToRetIdx[i] = -1
+			}
 		}
 	}
 
@@ -262,7 +314,9 @@ func (n *insertNode) Next() (bool, error) {
 	if next, err := n.run.rows.Next(); !next {
 		if err == nil {
 			// We're done. Finish the batch.
-			err = n.tw.finalize(ctx)
+			if err = n.tw.finalize(ctx); err == nil {
+				n.notifyMutation(n.run.rowsWritten)
+			}
 		}
 		return false, err
 	}
@@ -288,6 +342,11 @@ func (n *insertNode) Next() (bool, error) {
 		rowVals = append(rowVals, d)
 	}
 
+	n.computeHelper.loadRow(n.insertColIDtoRowIndex, rowVals, false)
+	if err := n.computeHelper.compute(&n.p.evalCtx, n.insertColIDtoRowIndex, rowVals); err != nil {
+		return false, err
+	}
+
 	// Check to see if NULL is being inserted into any non-nullable column.
 	for _, col := range n.tableDesc.Columns {
 		if !col.Nullable {
@@ -313,9 +372,10 @@ func (n *insertNode) Next() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	n.run.rowsWritten++
 
 	for i, val := range rowVals {
-		if n.run.rowTemplate != nil {
+		if n.run.rowTemplate != nil && n.run.rowIdxToRetIdx[i] >= 0 {
 			n.run.rowTemplate[n.run.rowIdxToRetIdx[i]] = val
 		}
 	}
@@ -0,0 +1,173 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// interleavedJoinState is the state of a joinNode performing an interleaved
+// join. The left data source scans a table and the right one a table whose
+// primary index is interleaved in the primary index of the left table. The
+// rows of both tables are read with a single scan of the left table, in
+// which every left row is directly followed by the right rows sharing its
+// primary key; the right rows are compared with the left row preceding
+// them.
+type interleavedJoinState struct {
+	parent  *scanNode
+	fetcher sqlbase.InterleavedRowFetcher
+	started bool
+	done    bool
+}
+
+// initInterleavedJoin sets up an interleaved join if one of the data
+// sources scans a table interleaved in the table scanned by the other one
+// and the join predicate requires the primary key of the parent table to be
+// equal to the interleaved prefix of the one of the child table. The data
+// sources of an inner join are swapped if needed for the parent table to be
+// on the left. It returns false if no interleaved join is possible.
+func (n *joinNode) initInterleavedJoin() bool {
+	left, ok := n.left.(*scanNode)
+	if !ok {
+		return false
+	}
+	right, ok := n.right.(*scanNode)
+	if !ok {
+		return false
+	}
+	swap := false
+	switch {
+	case n.joinType == joinTypeOuterFull:
+		// The right rows without a parent row would have to be emitted too.
+		return false
+	case isInterleavedScan(right, left):
+	case n.joinType == joinTypeInner && isInterleavedScan(left, right):
+		// The child table is on the left: the data sources are swapped like
+		// for RIGHT JOIN, the rows being matched in the order of the parent
+		// table.
+		swap = true
+		left, right = right, left
+	default:
+		return false
+	}
+	leftEqCols, rightEqCols := n.leftEqCols, n.rightEqCols
+	if swap {
+		leftEqCols, rightEqCols = rightEqCols, leftEqCols
+	}
+
+	// Every column of the primary key of the parent table must be equal to
+	// the column of the child table at the same position.
+	for i, colID := range left.index.ColumnIDs {
+		leftCol := left.colIdxMap[colID]
+		rightCol := right.colIdxMap[right.index.ColumnIDs[i]]
+		found := false
+		for j := range leftEqCols {
+			if leftEqCols[j] == leftCol && rightEqCols[j] == rightCol {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if swap {
+		n.left, n.right = n.right, n.left
+		n.leftEqCols, n.rightEqCols = leftEqCols, rightEqCols
+		n.swapped = !n.swapped
+	}
+	n.interleaved = interleavedJoinState{parent: left}
+	n.interleaved.fetcher.Init(&left.fetcher, &right.fetcher)
+	return true
+}
+
+// isInterleavedScan returns whether a scan reads the primary index of a
+// table interleaved in the primary index of the table read by another scan,
+// in a way which allows both to be read at once: the scans must be forward
+// scans of the primary indexes without filters, and the one of the child
+// table must not be restricted to some spans.
+func isInterleavedScan(child, parent *scanNode) bool {
+	for _, scan := range []*scanNode{child, parent} {
+		if scan.index != &scan.desc.PrimaryIndex || scan.reverse || scan.filter != nil ||
+			scan.lockRows {
+			return false
+		}
+	}
+	if len(child.spans) > 0 {
+		return false
+	}
+	ancestors := child.index.Interleave.Ancestors
+	if len(ancestors) == 0 {
+		return false
+	}
+	ancestor := ancestors[len(ancestors)-1]
+	return ancestor.TableID == parent.desc.ID && ancestor.IndexID == parent.index.ID
+}
+
+// interleavedNext implements Next for interleaved joins.
+func (n *joinNode) interleavedNext() (bool, error) {
+	il := &n.interleaved
+	if !il.started {
+		if err := il.fetcher.StartScan(n.p.txn, il.parent.spans); err != nil {
+			return false, err
+		}
+		il.started = true
+	}
+	for !il.done {
+		row, isChild, err := il.fetcher.NextRow()
+		if err != nil {
+			return false, err
+		}
+
+		if isChild {
+			if n.leftRow == nil {
+				// The right row has no parent row.
+				continue
+			}
+			leftRow, rightRow := n.orient(n.leftRow, row)
+			passesFilter, err := n.pred.eval(leftRow, rightRow)
+			if err != nil {
+				return false, err
+			}
+			if passesFilter {
+				n.leftMatched = true
+				n.pred.prepareRow(n.output, leftRow, rightRow)
+				return true, nil
+			}
+			continue
+		}
+
+		// The right rows of the current left row, if any, are exhausted.
+		leftRow, leftMatched := n.leftRow, n.leftMatched
+		if row == nil {
+			il.done = true
+			n.leftRow = nil
+		} else {
+			// The row is only valid until the next one is read.
+			n.leftRow = make(parser.DTuple, len(row))
+			copy(n.leftRow, row)
+			n.leftMatched = false
+		}
+		if leftRow != nil && !leftMatched && n.joinType != joinTypeInner {
+			// If the left row didn't match, insert a tuple of NULLs on the
+			// right.
+			n.emit(leftRow, n.emptyRight)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -65,6 +65,44 @@ func (ie InternalExecutor) GetTableSpan(user string, txn *client.Txn, dbName, ta
 	return roachpb.Span{Key: tableStartKey, EndKey: tableEndKey}, nil
 }
 
+// IndexSpan is the key span of an index of a SQL table.
+type IndexSpan struct {
+	Name string
+	Span roachpb.Span
+}
+
+// GetTableIndexSpans gets the key spans of the indexes of a SQL table, in
+// the order of the indexes of its descriptor.
+func (ie InternalExecutor) GetTableIndexSpans(
+	user string, txn *client.Txn, dbName, tableName string,
+) ([]IndexSpan, error) {
+	p := makeInternalPlanner(txn, user)
+	p.leaseMgr = ie.LeaseManager
+
+	tn := parser.TableName{DatabaseName: parser.Name(dbName), TableName: parser.Name(tableName)}
+	desc, err := p.mustGetTableDesc(&tn)
+	if err != nil {
+		return nil, err
+	}
+	return tableIndexSpans(desc), nil
+}
+
+// tableIndexSpans returns the key spans of the non-dropped indexes of the
+// table. The span of an interleaved index is the span of the index of its
+// interleave root, which holds its data.
+func tableIndexSpans(desc *sqlbase.TableDescriptor) []IndexSpan {
+	indexes := desc.AllNonDropIndexes()
+	spans := make([]IndexSpan, len(indexes))
+	for i, index := range indexes {
+		prefix := roachpb.Key(sqlbase.MakeIndexKeyPrefix(desc, index.ID))
+		spans[i] = IndexSpan{
+			Name: index.Name,
+			Span: roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()},
+		}
+	}
+	return spans
+}
+
 // getTableID retrieves the table ID for the specified table.
 func getTableID(p *planner, tn *parser.TableName) (sqlbase.ID, error) {
 	if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
@@ -0,0 +1,70 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/jsonb"
+)
+
+// invertedIndexKey returns the key, without the index prefix, of inverted
+// index entries that every row satisfying the filter of the scan has. It is
+// derived from a conjunct of the filter of the form `col @> constant`, where
+// col is the column of the inverted index. It returns nil if there is no such
+// conjunct, in which case the index can't be used.
+func (n *scanNode) invertedIndexKey(index *sqlbase.IndexDescriptor) ([]byte, error) {
+	if n.filter == nil {
+		return nil, nil
+	}
+	for _, e := range splitAndExpr(n.filter, nil) {
+		c, ok := e.(*parser.ComparisonExpr)
+		if !ok || c.Operator != parser.Contains {
+			continue
+		}
+		ok, colIdx := getQValColIdx(c.Left)
+		if !ok || n.cols[colIdx].ID != index.ColumnIDs[0] {
+			continue
+		}
+		var invertedKeys [][]byte
+		switch t := c.Right.(type) {
+		case *parser.DJSON:
+			invertedKeys = jsonb.InvertedIndexContainsKeys(t.JSON)
+		case *parser.DArray:
+			var err error
+			if invertedKeys, err = sqlbase.EncodeInvertedIndexKeys(t); err != nil {
+				return nil, err
+			}
+		}
+		if len(invertedKeys) > 0 {
+			// Any of the keys restricts the scan to a superset of the rows
+			// satisfying the filter, which is still applied to them.
+			return invertedKeys[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// makeInvertedIndexSpans returns the spans of the entries of an inverted index
+// with the given key.
+func makeInvertedIndexSpans(
+	tableDesc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor, invertedKey []byte,
+) sqlbase.Spans {
+	start := roachpb.Key(encoding.EncodeBytesAscending(
+		sqlbase.MakeIndexKeyPrefix(tableDesc, index.ID), invertedKey))
+	return sqlbase.Spans{{Start: start, End: start.PrefixEnd()}}
+}
@@ -0,0 +1,452 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+	"github.com/pkg/errors"
+)
+
+// jobsTableSchema describes the schema of the jobs table. Every row tracks a
+// long-running operation: a schema change, BACKUP, RESTORE, IMPORT or a
+// changefeed. The checkpoint is opaque to the registry: it is written by
+// operations that can resume where they left off, and handed back to the
+// coordinator adopting the job when the previous one went away.
+const jobsTableSchema = `
+CREATE TABLE system.jobs (
+  id                INT        DEFAULT unique_rowid() PRIMARY KEY,
+  jobType           STRING     NOT NULL,
+  description       STRING     NOT NULL,
+  username          STRING     NOT NULL,
+  status            STRING     NOT NULL,
+  created           TIMESTAMP  NOT NULL,
+  started           TIMESTAMP,
+  finished          TIMESTAMP,
+  modified          TIMESTAMP  NOT NULL,
+  fractionCompleted FLOAT      NOT NULL,
+  error             STRING,
+  coordinatorID     INT        NOT NULL,
+  checkpoint        BYTES
+);`
+
+// AddJobsToMetadataSchema adds the jobs table to the supplied MetadataSchema.
+func AddJobsToMetadataSchema(schema *sqlbase.MetadataSchema) {
+	desc := CreateTableDescriptor(
+		keys.JobsTableID,
+		keys.SystemDatabaseID,
+		jobsTableSchema,
+		sqlbase.NewDefaultPrivilegeDescriptor(),
+	)
+	schema.AddDescriptor(keys.SystemDatabaseID, &desc)
+}
+
+// The types of the jobs.
+const (
+	jobTypeSchemaChange = "SCHEMA CHANGE"
+	jobTypeBackup       = "BACKUP"
+	jobTypeRestore      = "RESTORE"
+	jobTypeImport       = "IMPORT"
+	jobTypeChangefeed   = "CHANGEFEED"
+)
+
+// The statuses of the jobs. A job is running from its creation until it
+// succeeds, fails or is canceled; it can be paused and resumed while running.
+const (
+	jobStatusRunning   = "running"
+	jobStatusPaused    = "paused"
+	jobStatusSucceeded = "succeeded"
+	jobStatusFailed    = "failed"
+	jobStatusCanceled  = "canceled"
+)
+
+// jobPausedPollInterval is how often a paused job checks whether it was
+// resumed or canceled.
+var jobPausedPollInterval = time.Second
+
+// JobRegistry creates the jobs tracking the long-running operations
+// coordinated by this node. The state of the jobs is kept in the jobs table,
+// which is also how the operations learn that they were paused or canceled:
+// they check their status whenever they report progress.
+type JobRegistry struct {
+	db       client.DB
+	leaseMgr *LeaseManager
+}
+
+// NewJobRegistry returns a new JobRegistry.
+func NewJobRegistry(db client.DB, leaseMgr *LeaseManager) *JobRegistry {
+	return &JobRegistry{db: db, leaseMgr: leaseMgr}
+}
+
+// Job is a handle on a row of the jobs table, used by the operation it tracks
+// to report its progress and completion. The methods of a nil *Job do nothing,
+// so operations run without a JobRegistry (e.g. in tests) needn't check.
+type Job struct {
+	registry   *JobRegistry
+	id         int64
+	nodeID     roachpb.NodeID
+	checkpoint []byte
+}
+
+// newJob records a new running job, in its own transaction so that the job
+// is visible while the operation runs. It returns a nil *Job if r is nil.
+func (r *JobRegistry) newJob(
+	ctx context.Context, jobType, description, username string,
+) (*Job, error) {
+	if r == nil {
+		return nil, nil
+	}
+	const insertStmt = `
+INSERT INTO system.jobs (
+  jobType, description, username, status, created, started, modified, fractionCompleted, coordinatorID
+)
+VALUES (
+  $1, $2, $3, $4, $5, $5, $5, 0, $6
+)
+RETURNING id
+`
+	nodeID := roachpb.NodeID(r.leaseMgr.nodeID)
+	var id int64
+	if err := r.db.Txn(func(txn *client.Txn) error {
+		p := r.planner(txn)
+		p.evalCtx.NodeID = nodeID
+		row, err := p.queryRow(insertStmt,
+			jobType, description, username, jobStatusRunning, timeutil.Now(), int(nodeID))
+		if err != nil {
+			return err
+		}
+		id = int64(*row[0].(*parser.DInt))
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "creating job")
+	}
+	if log.V(1) {
+		log.Infof(ctx, "created %s job %d: %s", jobType, id, description)
+	}
+	return &Job{registry: r, id: id, nodeID: nodeID}, nil
+}
+
+// adoptOrNewJob takes over the running job of the given type and description
+// if there is one, making this node its coordinator, and otherwise records a
+// new job like newJob. It is used by operations that are retried until they
+// complete, possibly on another node, so that the retry continues the job
+// (and can resume from its checkpoint) instead of starting a new one.
+func (r *JobRegistry) adoptOrNewJob(
+	ctx context.Context, jobType, description, username string,
+) (*Job, error) {
+	if r == nil {
+		return nil, nil
+	}
+	const selectStmt = `
+SELECT id, checkpoint FROM system.jobs
+WHERE jobType = $1 AND description = $2 AND status = $3
+ORDER BY created DESC LIMIT 1
+`
+	const adoptStmt = `
+UPDATE system.jobs SET coordinatorID = $2, modified = $3 WHERE id = $1
+`
+	job := &Job{registry: r, nodeID: roachpb.NodeID(r.leaseMgr.nodeID)}
+	var found bool
+	if err := r.db.Txn(func(txn *client.Txn) error {
+		p := r.planner(txn)
+		row, err := p.queryRow(selectStmt, jobType, description, jobStatusRunning)
+		found = row != nil
+		if err != nil || !found {
+			return err
+		}
+		job.id = int64(*row[0].(*parser.DInt))
+		job.checkpoint = nil
+		if row[1] != parser.DNull {
+			job.checkpoint = []byte(*row[1].(*parser.DBytes))
+		}
+		_, err = p.exec(adoptStmt, job.id, int(job.nodeID), timeutil.Now())
+		return err
+	}); err != nil {
+		return nil, errors.Wrap(err, "adopting job")
+	}
+	if !found {
+		return r.newJob(ctx, jobType, description, username)
+	}
+	if log.V(1) {
+		log.Infof(ctx, "adopted %s job %d: %s", jobType, job.id, description)
+	}
+	return job, nil
+}
+
+func (r *JobRegistry) planner(txn *client.Txn) *planner {
+	p := makeInternalPlanner(txn, security.RootUser)
+	p.leaseMgr = r.leaseMgr
+	return p
+}
+
+// Progressed records the fraction of the operation completed, between 0 and
+// 1. If the job was paused, Progressed blocks until it is resumed; if the job
+// was canceled, Progressed returns an error, which the operation should
+// return.
+func (j *Job) Progressed(ctx context.Context, fraction float32) error {
+	return j.progressed(ctx, fraction, nil)
+}
+
+// Checkpointed is like Progressed, but also records a checkpoint from which
+// the operation can resume if it is retried.
+func (j *Job) Checkpointed(ctx context.Context, fraction float32, checkpoint []byte) error {
+	if err := j.progressed(ctx, fraction, checkpoint); err != nil {
+		return err
+	}
+	if j != nil {
+		j.checkpoint = checkpoint
+	}
+	return nil
+}
+
+// Checkpoint returns the last checkpoint recorded for the job, which is the
+// one left by the previous coordinator when the job was adopted, or nil.
+func (j *Job) Checkpoint() []byte {
+	if j == nil {
+		return nil
+	}
+	return j.checkpoint
+}
+
+// progressed implements Progressed and Checkpointed; the checkpoint is left
+// unchanged when it is nil.
+func (j *Job) progressed(ctx context.Context, fraction float32, checkpoint []byte) error {
+	if j == nil {
+		return nil
+	}
+	const updateStmt = `
+UPDATE system.jobs SET fractionCompleted = $2, modified = $3 WHERE id = $1
+`
+	const checkpointStmt = `
+UPDATE system.jobs SET fractionCompleted = $2, modified = $3, checkpoint = $4 WHERE id = $1
+`
+	for {
+		var status string
+		if err := j.registry.db.Txn(func(txn *client.Txn) error {
+			p := j.registry.planner(txn)
+			row, err := p.queryRow(`SELECT status, coordinatorID FROM system.jobs WHERE id = $1`, j.id)
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				return errors.Errorf("job %d not found", j.id)
+			}
+			if coordinatorID := roachpb.NodeID(*row[1].(*parser.DInt)); coordinatorID != j.nodeID {
+				return errors.Errorf("job %d was adopted by node %d", j.id, coordinatorID)
+			}
+			status = string(*row[0].(*parser.DString))
+			if status != jobStatusRunning {
+				return nil
+			}
+			if checkpoint == nil {
+				_, err = p.exec(updateStmt, j.id, float64(fraction), timeutil.Now())
+			} else {
+				_, err = p.exec(checkpointStmt, j.id, float64(fraction), timeutil.Now(), checkpoint)
+			}
+			return err
+		}); err != nil {
+			return errors.Wrapf(err, "updating job %d", j.id)
+		}
+
+		switch status {
+		case jobStatusRunning:
+			return nil
+		case jobStatusPaused:
+			select {
+			case <-time.After(jobPausedPollInterval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case jobStatusCanceled:
+			return errors.Errorf("job %d was canceled", j.id)
+		default:
+			return errors.Errorf("job %d is unexpectedly %s", j.id, status)
+		}
+	}
+}
+
+// Finished records that the operation tracked by the job ended, successfully
+// if err is nil. A canceled job remains canceled, and a job adopted by another
+// node is left to it. Failures to update the job are only logged, so as not to
+// hide the outcome of the operation.
+func (j *Job) Finished(ctx context.Context, err error) {
+	if j == nil {
+		return
+	}
+	const succeededStmt = `
+UPDATE system.jobs SET status = 'succeeded', fractionCompleted = 1, finished = $3, modified = $3
+WHERE id = $1 AND coordinatorID = $2 AND status IN ('running', 'paused')
+`
+	const failedStmt = `
+UPDATE system.jobs SET status = 'failed', error = $4, finished = $3, modified = $3
+WHERE id = $1 AND coordinatorID = $2 AND status IN ('running', 'paused')
+`
+	stmt, args := succeededStmt, []interface{}{j.id, int(j.nodeID), timeutil.Now()}
+	if err != nil {
+		stmt, args = failedStmt, append(args, err.Error())
+	}
+	if err := j.registry.db.Txn(func(txn *client.Txn) error {
+		_, err := j.registry.planner(txn).exec(stmt, args...)
+		return err
+	}); err != nil {
+		log.Warningf(ctx, "unable to record the end of job %d: %s", j.id, err)
+	}
+}
+
+// jobRegistry returns the job registry of the node, if any.
+func (p *planner) jobRegistry() *JobRegistry {
+	if p.execCtx == nil {
+		return nil
+	}
+	return p.execCtx.JobRegistry
+}
+
+// ShowJobs returns the jobs, most recently created first.
+// Privileges: None.
+//   Notes: users other than root only see their own jobs.
+func (p *planner) ShowJobs(n *parser.ShowJobs) (planNode, error) {
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	plan, err := ip.query(`
+SELECT id, jobType, description, username, status, created, started, finished, modified,
+       fractionCompleted, error, coordinatorID
+FROM system.jobs ORDER BY created DESC, id
+`)
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.Start(); err != nil {
+		return nil, err
+	}
+
+	v := &valuesNode{
+		columns: []ResultColumn{
+			{Name: "ID", Typ: parser.TypeInt},
+			{Name: "Type", Typ: parser.TypeString},
+			{Name: "Description", Typ: parser.TypeString},
+			{Name: "User", Typ: parser.TypeString},
+			{Name: "Status", Typ: parser.TypeString},
+			{Name: "Created", Typ: parser.TypeTimestamp},
+			{Name: "Started", Typ: parser.TypeTimestamp},
+			{Name: "Finished", Typ: parser.TypeTimestamp},
+			{Name: "Modified", Typ: parser.TypeTimestamp},
+			{Name: "FractionCompleted", Typ: parser.TypeFloat},
+			{Name: "Error", Typ: parser.TypeString},
+			{Name: "Coordinator", Typ: parser.TypeInt},
+		},
+	}
+	for {
+		next, err := plan.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !next {
+			break
+		}
+		row := plan.Values()
+		if p.session.User != security.RootUser && string(*row[3].(*parser.DString)) != p.session.User {
+			continue
+		}
+		v.rows = append(v.rows, append(parser.DTuple(nil), row...))
+	}
+	return v, nil
+}
+
+// PauseJob pauses a running job. The operation tracked by the job stops the
+// next time it reports progress, until the job is resumed.
+// Privileges: None.
+//   Notes: users other than root can only pause their own jobs.
+func (p *planner) PauseJob(n *parser.PauseJob) (planNode, error) {
+	return p.setJobStatus(n.ID, "PAUSE JOB", jobStatusPaused, jobStatusRunning)
+}
+
+// ResumeJob resumes a paused job.
+// Privileges: None.
+//   Notes: users other than root can only resume their own jobs.
+func (p *planner) ResumeJob(n *parser.ResumeJob) (planNode, error) {
+	return p.setJobStatus(n.ID, "RESUME JOB", jobStatusRunning, jobStatusPaused)
+}
+
+// CancelJob cancels a running or paused job. The operation tracked by the
+// job fails the next time it reports progress.
+// Privileges: None.
+//   Notes: users other than root can only cancel their own jobs.
+func (p *planner) CancelJob(n *parser.CancelJob) (planNode, error) {
+	return p.setJobStatus(n.ID, "CANCEL JOB", jobStatusCanceled, jobStatusRunning, jobStatusPaused)
+}
+
+// setJobStatus moves the job identified by idExpr to status newStatus, as
+// part of the statement's transaction, provided it is currently in one of
+// the given statuses.
+func (p *planner) setJobStatus(
+	idExpr parser.Expr, stmtName string, newStatus string, fromStatuses ...string,
+) (planNode, error) {
+	typedID, err := p.analyzeExpr(idExpr, nil, nil, parser.TypeInt, true, stmtName)
+	if err != nil {
+		return nil, err
+	}
+	d, err := typedID.Eval(&p.evalCtx)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := d.(*parser.DInt)
+	if !ok {
+		return nil, errors.Errorf("%s requires a job ID, got %s", stmtName, d)
+	}
+
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	row, err := ip.queryRow(`SELECT jobType, username, status FROM system.jobs WHERE id = $1`, int64(*id))
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, errors.Errorf("job %d not found", *id)
+	}
+	jobType := string(*row[0].(*parser.DString))
+	username := string(*row[1].(*parser.DString))
+	status := string(*row[2].(*parser.DString))
+	if p.session.User != security.RootUser && username != p.session.User {
+		return nil, errors.Errorf("job %d not found", *id)
+	}
+	if jobType == jobTypeSchemaChange {
+		// Schema changes are retried until they complete, so they can't
+		// be stopped.
+		return nil, errors.Errorf("%s is not supported for %s jobs", stmtName, jobType)
+	}
+	allowed := false
+	for _, s := range fromStatuses {
+		allowed = allowed || status == s
+	}
+	if !allowed {
+		return nil, errors.Errorf("job %d is %s", *id, status)
+	}
+
+	if _, err := ip.exec(`UPDATE system.jobs SET status = $2, modified = $3 WHERE id = $1`,
+		int64(*id), newStatus, timeutil.Now()); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestJobs(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.Exec(`CREATE TABLE d.t (a INT PRIMARY KEY, b STRING)`)
+	r.Exec(`INSERT INTO d.t VALUES (1, 'x'), (2, 'y')`)
+	r.Exec(fmt.Sprintf(`BACKUP DATABASE d TO 'nodelocal://%s'`, dir))
+
+	// The backup is done, so its job has succeeded.
+	var backupID int64
+	var status, user string
+	var fraction float64
+	if err := sqlDB.QueryRow(
+		`SELECT id, status, username, fractionCompleted FROM system.jobs WHERE jobType = 'BACKUP'`,
+	).Scan(&backupID, &status, &user, &fraction); err != nil {
+		t.Fatal(err)
+	}
+	if status != "succeeded" || user != "root" || fraction != 1 {
+		t.Errorf("expected a succeeded backup job run by root, got %s job run by %s at %f",
+			status, user, fraction)
+	}
+
+	// Schema changes with a backfill are tracked as well.
+	r.Exec(`CREATE INDEX b_idx ON d.t (b)`)
+	var schemaChangeID int64
+	if err := sqlDB.QueryRow(
+		`SELECT id, status FROM system.jobs WHERE jobType = 'SCHEMA CHANGE'`,
+	).Scan(&schemaChangeID, &status); err != nil {
+		t.Fatal(err)
+	}
+	if status != "succeeded" {
+		t.Errorf("expected a succeeded schema change job, got %s", status)
+	}
+
+	rows := r.Query(`SHOW JOBS`)
+	var count int
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 jobs, got %d", count)
+	}
+
+	// A job that is still running can be paused, resumed and canceled.
+	var runningID int64
+	if err := sqlDB.QueryRow(`
+INSERT INTO system.jobs (jobType, description, username, status, created, modified,
+                         fractionCompleted, coordinatorID)
+VALUES ('IMPORT', 'test', 'root', 'running', now(), now(), 0, 1) RETURNING id
+`).Scan(&runningID); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		stmt, expected string
+	}{
+		{`PAUSE JOB $1`, "paused"},
+		{`RESUME JOB $1`, "running"},
+		{`PAUSE JOB $1`, "paused"},
+		{`CANCEL JOB $1`, "canceled"},
+	} {
+		r.Exec(tc.stmt, runningID)
+		if err := sqlDB.QueryRow(
+			`SELECT status FROM system.jobs WHERE id = $1`, runningID,
+		).Scan(&status); err != nil {
+			t.Fatal(err)
+		}
+		if status != tc.expected {
+			t.Errorf("%s: expected status %s, got %s", tc.stmt, tc.expected, status)
+		}
+	}
+
+	for _, tc := range []struct {
+		stmt     string
+		id       int64
+		expected string
+	}{
+		{`PAUSE JOB $1`, backupID, fmt.Sprintf("job %d is succeeded", backupID)},
+		{`RESUME JOB $1`, runningID, fmt.Sprintf("job %d is canceled", runningID)},
+		{`CANCEL JOB $1`, schemaChangeID, "CANCEL JOB is not supported for SCHEMA CHANGE jobs"},
+		{`CANCEL JOB $1`, 1, "job 1 not found"},
+	} {
+		if _, err := sqlDB.Exec(tc.stmt, tc.id); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.stmt, tc.expected, err)
+		}
+	}
+}
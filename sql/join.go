@@ -57,6 +57,9 @@ func (p *planner) makeIndexJoin(origScan *scanNode, exactPrefix int) (resultPlan
 	table.desc = origScan.desc
 	table.initDescDefaults(publicColumns)
 	table.initOrdering(0)
+	// The rows are locked in the table rather than in the index.
+	table.lockRows = origScan.lockRows
+	indexScan.lockRows = false
 
 	colIDtoRowIndex := map[sqlbase.ColumnID]int{}
 	for _, colID := range table.desc.PrimaryIndex.ColumnIDs {
@@ -66,7 +69,12 @@ func (p *planner) makeIndexJoin(origScan *scanNode, exactPrefix int) (resultPlan
 		}
 		colIDtoRowIndex[colID] = idx
 	}
-	for _, colID := range indexScan.index.ColumnIDs {
+	for i, colID := range indexScan.index.ColumnIDs {
+		if i == 0 && indexScan.index.IsInverted() {
+			// The values of the column of an inverted index can't be decoded
+			// from its entries; the table node provides them.
+			continue
+		}
 		idx, ok := indexScan.colIdxMap[colID]
 		if !ok {
 			panic(fmt.Sprintf("Unknown column %d in index!", colID))
@@ -1121,6 +1121,30 @@ func (m *LeaseManager) Release(lease *LeaseState) error {
 	return t.release(lease, m.LeaseStore)
 }
 
+// invalidateTable updates the leases held by the node on a table once a
+// transaction writing a new version of its descriptor committed, without
+// waiting for the descriptor to be gossiped: the leases on a descriptor
+// with another name stop resolving that name, and the unused leases on a
+// dropped table are released.
+func (m *LeaseManager) invalidateTable(desc sqlbase.TableDescriptor) {
+	t := m.findTableState(desc.ID, false /* create */)
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, lease := range t.active.data {
+		if lease.ParentID != desc.ParentID || lease.Name != desc.Name {
+			t.tableNameCache.remove(lease)
+		}
+	}
+	if desc.Deleted() {
+		t.deleted = true
+		toRelease := append([]*LeaseState(nil), t.active.data...)
+		t.releaseLeasesIfNotActive(toRelease, m.LeaseStore)
+	}
+}
+
 // If create is set, cache and stopper need to be set as well.
 func (m *LeaseManager) findTableState(tableID sqlbase.ID, create bool) *tableState {
 	m.mu.Lock()
@@ -1133,6 +1157,35 @@ func (m *LeaseManager) findTableState(tableID sqlbase.ID, create bool) *tableSta
 	return t
 }
 
+// visitLeases calls fn on every lease held by the node, ordered by table ID
+// and then by version and expiration. The lease must not be retained.
+func (m *LeaseManager) visitLeases(fn func(lease *LeaseState, refcount int, released bool)) {
+	m.mu.Lock()
+	tables := make([]*tableState, 0, len(m.tables))
+	for _, t := range m.tables {
+		tables = append(tables, t)
+	}
+	m.mu.Unlock()
+	sort.Sort(tableStatesByID(tables))
+
+	for _, t := range tables {
+		t.mu.Lock()
+		for _, lease := range t.active.data {
+			lease.mu.Lock()
+			refcount, released := lease.refcount, lease.released
+			lease.mu.Unlock()
+			fn(lease, refcount, released)
+		}
+		t.mu.Unlock()
+	}
+}
+
+type tableStatesByID []*tableState
+
+func (t tableStatesByID) Len() int           { return len(t) }
+func (t tableStatesByID) Less(i, j int) bool { return t[i].id < t[j].id }
+func (t tableStatesByID) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
 // RefreshLeases starts a goroutine that refreshes the lease manager
 // leases for tables received in the latest system configuration via gossip.
 func (m *LeaseManager) RefreshLeases(s *stop.Stopper, db *client.DB, gossip *gossip.Gossip) {
@@ -0,0 +1,213 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+// lookupJoinState is the state of a joinNode performing a lookup join. The
+// left rows are read in batches of joinBatchSize; the values of their
+// equality columns are used to construct spans of an index of the right
+// table, and the right rows found in these spans are put in a hash table
+// against which the left rows of the batch are matched.
+type lookupJoinState struct {
+	// scan is the scan of the index in which the right rows are looked up.
+	// It is either the right data source or, if the index isn't covering,
+	// the index side of the right data source.
+	scan      *scanNode
+	keyPrefix roachpb.Key
+	// indexCols are the leading columns of the index which are looked up,
+	// and colMap maps them to the left columns holding their values.
+	indexCols []sqlbase.ColumnID
+	dirs      []sqlbase.IndexDescriptor_Direction
+	colMap    map[sqlbase.ColumnID]int
+
+	// batch holds the current batch of left rows, and batchIdx is the next
+	// one to join.
+	batch         []parser.DTuple
+	batchIdx      int
+	leftInputDone bool
+	// keys is used to look up every key only once per batch.
+	keys map[string]struct{}
+}
+
+// initLookupJoin sets up a lookup join if the right data source is a table
+// scan and the equality columns include the leading columns of one of its
+// indexes, using the index with the most such columns. It returns false if
+// no lookup join is possible.
+func (n *joinNode) initLookupJoin() bool {
+	scan, ok := n.right.(*scanNode)
+	if !ok || n.joinType == joinTypeOuterFull || len(scan.spans) > 0 || scan.filter != nil {
+		// The unmatched right rows of a full outer join can't be looked up.
+		return false
+	}
+
+	// Map the columns of the right table to the left columns they must be
+	// equal to.
+	eqCols := make(map[sqlbase.ColumnID]int, len(n.rightEqCols))
+	for i, col := range n.rightEqCols {
+		eqCols[scan.cols[col].ID] = n.leftEqCols[i]
+	}
+
+	var best *sqlbase.IndexDescriptor
+	var bestCovering bool
+	bestLen := 0
+	consider := func(index *sqlbase.IndexDescriptor) {
+		if index.IsInverted() || index.IsPartial() || len(index.Interleave.Ancestors) > 0 {
+			return
+		}
+		info := indexInfo{desc: &scan.desc, index: index}
+		covering := info.isCoveringIndex(scan)
+		if !covering && scan.noIndexJoin {
+			return
+		}
+		numCols := 0
+		for _, colID := range index.ColumnIDs {
+			if _, ok := eqCols[colID]; !ok {
+				break
+			}
+			numCols++
+		}
+		// Prefer covering indexes when looking up as many columns.
+		if numCols > bestLen || (numCols == bestLen && numCols > 0 && covering && !bestCovering) {
+			best, bestLen, bestCovering = index, numCols, covering
+		}
+	}
+	if scan.specifiedIndex != nil {
+		consider(scan.specifiedIndex)
+	} else {
+		consider(&scan.desc.PrimaryIndex)
+		for i := range scan.desc.Indexes {
+			consider(&scan.desc.Indexes[i])
+		}
+	}
+	if best == nil {
+		return false
+	}
+
+	scan.index = best
+	scan.isSecondaryIndex = (best != &scan.desc.PrimaryIndex)
+	if bestCovering {
+		scan.initOrdering(0)
+	} else {
+		// Note: makeIndexJoin destroys scan and returns a new index scan
+		// node.
+		n.right, scan = n.p.makeIndexJoin(scan, 0)
+	}
+
+	l := lookupJoinState{
+		scan:      scan,
+		keyPrefix: roachpb.Key(sqlbase.MakeIndexKeyPrefix(&scan.desc, best.ID)),
+		indexCols: best.ColumnIDs[:bestLen],
+		dirs:      best.ColumnDirections[:bestLen],
+		colMap:    make(map[sqlbase.ColumnID]int, bestLen),
+	}
+	for _, colID := range l.indexCols {
+		l.colMap[colID] = eqCols[colID]
+	}
+	n.lookup = l
+	return true
+}
+
+// lookupNextLeft returns the next left row of a lookup join, or nil if the
+// left data source is exhausted. When the current batch of left rows is
+// exhausted, the next one is read and the matching right rows are looked
+// up.
+func (n *joinNode) lookupNextLeft() (parser.DTuple, error) {
+	l := &n.lookup
+	for l.batchIdx >= len(l.batch) {
+		if l.leftInputDone {
+			return nil, nil
+		}
+		if err := n.lookupBatch(); err != nil {
+			return nil, err
+		}
+	}
+	row := l.batch[l.batchIdx]
+	l.batchIdx++
+	return row, nil
+}
+
+// lookupBatch reads the next batch of left rows and loads the right rows
+// matching them in the hash table.
+func (n *joinNode) lookupBatch() error {
+	l := &n.lookup
+	l.batch = l.batch[:0]
+	l.batchIdx = 0
+	l.scan.spans = l.scan.spans[:0]
+	l.keys = make(map[string]struct{}, joinBatchSize)
+
+	for len(l.batch) < joinBatchSize {
+		hasRow, err := n.left.Next()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			l.leftInputDone = true
+			break
+		}
+		values := n.left.Values()
+		row := make(parser.DTuple, len(values))
+		copy(row, values)
+		l.batch = append(l.batch, row)
+
+		key, containsNull, err := sqlbase.EncodeColumns(l.indexCols, l.dirs, l.colMap, row, l.keyPrefix)
+		if err != nil {
+			return err
+		}
+		if containsNull {
+			// The row can't match any right row.
+			continue
+		}
+		if _, ok := l.keys[string(key)]; ok {
+			continue
+		}
+		l.keys[string(key)] = struct{}{}
+		k := roachpb.Key(key)
+		l.scan.spans = append(l.scan.spans, sqlbase.Span{Start: k, End: k.PrefixEnd()})
+	}
+
+	n.rightRows = n.rightRows[:0]
+	n.buckets = make(map[string][]int)
+	n.rightMemAcc.Clear()
+	if len(l.scan.spans) == 0 {
+		return nil
+	}
+	l.scan.spans = mergeAndSortSpans(l.scan.spans)
+	if log.V(3) {
+		log.Infof(n.p.ctx(), "lookup join: %s", sqlbase.PrettySpans(l.scan.spans, 0))
+	}
+
+	// Look up the right rows. The scan is restarted with the new spans; for
+	// an index join, the table side restarts on its own once it runs out of
+	// rows.
+	l.scan.scanInitialized = false
+	for {
+		hasRow, err := n.right.Next()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		if err := n.bufferRight(n.right.Values()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql/pgwire/pgerror"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/pq"
+)
+
+func TestSQLMemoryBudget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	params.SQLMemoryPoolSize = 256 * 1024
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (k INT PRIMARY KEY, v STRING);
+`); err != nil {
+		t.Fatal(err)
+	}
+	// Insert about 1MB of data.
+	v := strings.Repeat("x", 1024)
+	for i := 0; i < 1000; i++ {
+		if _, err := sqlDB.Exec(`INSERT INTO d.t VALUES ($1, $2)`, i, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Statements whose results hold all the rows exceed the budget, even
+	// though sorts and aggregations can spill their rows to disk.
+	for _, query := range []string{
+		`SELECT * FROM d.t ORDER BY v, k`,
+		`SELECT v, COUNT(*) FROM d.t GROUP BY v, k`,
+		`SELECT DISTINCT k, v FROM d.t`,
+		`SELECT * FROM d.t`,
+	} {
+		_, err := sqlDB.Exec(query)
+		if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != pgerror.CodeOutOfMemoryError {
+			t.Fatalf("%s: expected an out of memory error, got %v", query, err)
+		}
+	}
+
+	// The memory is released once the statements fail, and statements which
+	// don't buffer rows still succeed.
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM d.t`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1000 {
+		t.Fatalf("expected 1000 rows, got %d", count)
+	}
+	if _, err := sqlDB.Exec(`SELECT * FROM d.t ORDER BY k LIMIT 10`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLSpillToDisk(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	params.SQLMemoryPoolSize = 256 * 1024
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (k INT PRIMARY KEY, v STRING);
+`); err != nil {
+		t.Fatal(err)
+	}
+	// Insert about 1MB of data.
+	for i := 0; i < 1000; i++ {
+		v := fmt.Sprintf("%04d%s", i%100, strings.Repeat("x", 1024))
+		if _, err := sqlDB.Exec(`INSERT INTO d.t VALUES ($1, $2)`, i, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A sort which doesn't fit in the budget is performed on disk.
+	rows, err := sqlDB.Query(`SELECT k FROM d.t ORDER BY v DESC, k`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prev int
+	n := 0
+	for ; rows.Next(); n++ {
+		var k int
+		if err := rows.Scan(&k); err != nil {
+			t.Fatal(err)
+		}
+		// v sorts by k%100 descending, and then by k.
+		if n > 0 && (k%100 > prev%100 || (k%100 == prev%100 && k < prev)) {
+			t.Fatalf("row %d: %d is out of order after %d", n, k, prev)
+		}
+		prev = k
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1000 {
+		t.Fatalf("expected 1000 rows, got %d", n)
+	}
+
+	// So are the groups of an aggregation which don't fit in the budget.
+	rows, err = sqlDB.Query(`SELECT COUNT(*), SUM(k) FROM d.t GROUP BY v`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, sum := 0, 0
+	for ; rows.Next(); groups++ {
+		var c, total int
+		if err := rows.Scan(&c, &total); err != nil {
+			t.Fatal(err)
+		}
+		if c != 10 {
+			t.Fatalf("expected groups of 10 rows, got %d", c)
+		}
+		sum += total
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if groups != 100 || sum != 999*1000/2 {
+		t.Fatalf("expected 100 groups summing to %d, got %d summing to %d", 999*1000/2, groups, sum)
+	}
+
+	// So are the right rows of a hash join.
+	for _, tc := range []struct {
+		query    string
+		expected int
+	}{
+		{`SELECT COUNT(*) FROM d.t AS a JOIN d.t AS b ON a.v = b.v`, 100 * 10 * 10},
+		// Every group has 45 matching pairs, and its largest and smallest k are
+		// respectively left and right rows without a match.
+		{`SELECT COUNT(*) FROM d.t AS a FULL OUTER JOIN d.t AS b ON a.v = b.v AND a.k < b.k`, 100 * (45 + 2)},
+	} {
+		var c int
+		if err := sqlDB.QueryRow(tc.query).Scan(&c); err != nil {
+			t.Fatalf("%s: %s", tc.query, err)
+		}
+		if c != tc.expected {
+			t.Fatalf("%s: expected %d rows, got %d", tc.query, tc.expected, c)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/encoding"
+)
+
+// mergeJoinState is the state of a joinNode performing a merge join. Both
+// data sources are ordered on the merge columns; the right rows are read one
+// group at a time, a group being the rows with the same values on the merge
+// columns, and each left row is only compared with the rows of the group
+// matching it.
+type mergeJoinState struct {
+	// leftCols and rightCols are the pairs of merge columns, in the order of
+	// the orderings of the data sources, and dirs the direction of each.
+	leftCols  []int
+	rightCols []int
+	dirs      []encoding.Direction
+
+	// leftKey is the encoding of the merge columns of the current left row;
+	// leftKeyOK is false if one of them is NULL.
+	leftKey   []byte
+	leftKeyOK bool
+
+	// group holds the current group of right rows, if groupLoaded is set.
+	group        []parser.DTuple
+	groupMatched []bool
+	groupKey     []byte
+	groupKeyOK   bool
+	groupLoaded  bool
+
+	// next is the right row following the current group, if any.
+	next      parser.DTuple
+	nextKey   []byte
+	nextKeyOK bool
+	// rightInputDone is set once the right data source is exhausted, and
+	// rightDone once all its groups have been loaded.
+	rightInputDone bool
+	rightDone      bool
+
+	// unmatched are the rows of a finished group which matched no left row,
+	// waiting to be emitted by a full outer join.
+	unmatched []parser.DTuple
+}
+
+// mergeJoinColumns determines whether the left and right data sources of a
+// join are both ordered on some of the equality columns. It returns the
+// equality columns matching the longest common prefix of the orderings,
+// along with their directions.
+func mergeJoinColumns(
+	left, right orderingInfo, leftEqCols, rightEqCols []int,
+) (leftCols, rightCols []int, dirs []encoding.Direction) {
+	used := make([]bool, len(leftEqCols))
+	for i := 0; i < len(left.ordering) && i < len(right.ordering); i++ {
+		lo, ro := left.ordering[i], right.ordering[i]
+		if lo.Direction != ro.Direction {
+			break
+		}
+		found := false
+		for j := range leftEqCols {
+			if !used[j] && leftEqCols[j] == lo.ColIdx && rightEqCols[j] == ro.ColIdx {
+				used[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		leftCols = append(leftCols, lo.ColIdx)
+		rightCols = append(rightCols, ro.ColIdx)
+		dirs = append(dirs, lo.Direction)
+	}
+	return leftCols, rightCols, dirs
+}
+
+// mergeNext implements Next for merge joins.
+func (n *joinNode) mergeNext() (bool, error) {
+	m := &n.merge
+	for {
+		if len(m.unmatched) > 0 {
+			row := m.unmatched[0]
+			m.unmatched = m.unmatched[1:]
+			n.emit(n.emptyLeft, row)
+			return true, nil
+		}
+
+		if n.leftRow == nil && !n.leftDone {
+			leftHasRow, err := n.left.Next()
+			if err != nil {
+				return false, err
+			}
+			if leftHasRow {
+				n.leftRow = n.left.Values()
+				n.leftMatched = false
+				n.candIdx = 0
+				m.leftKey, m.leftKeyOK, err = encodeJoinKey(m.leftKey[:0], n.leftRow, m.leftCols, m.dirs)
+				if err != nil {
+					return false, err
+				}
+			} else {
+				n.leftDone = true
+			}
+		}
+		if n.leftDone && n.joinType != joinTypeOuterFull {
+			// The remaining right rows can't be part of the results.
+			return false, nil
+		}
+		if !m.groupLoaded && !m.rightDone {
+			if err := n.loadMergeGroup(); err != nil {
+				return false, err
+			}
+		}
+
+		if n.leftDone {
+			if !m.groupLoaded {
+				// Both left and right are exhausted; done.
+				return false, nil
+			}
+			n.finishMergeGroup()
+			continue
+		}
+
+		// Compare the left row with the group. Rows with NULL merge columns
+		// never match.
+		cmp := -1
+		if m.groupLoaded && m.leftKeyOK {
+			if !m.groupKeyOK {
+				cmp = 1
+			} else {
+				cmp = bytes.Compare(m.leftKey, m.groupKey)
+			}
+		}
+		if cmp > 0 {
+			// No more left rows can match the group.
+			n.finishMergeGroup()
+			continue
+		}
+		if cmp == 0 {
+			for n.candIdx < len(m.group) {
+				idx := n.candIdx
+				n.candIdx++
+				leftRow, rightRow := n.orient(n.leftRow, m.group[idx])
+				passesFilter, err := n.pred.eval(leftRow, rightRow)
+				if err != nil {
+					return false, err
+				}
+				if passesFilter {
+					n.leftMatched = true
+					m.groupMatched[idx] = true
+					n.pred.prepareRow(n.output, leftRow, rightRow)
+					return true, nil
+				}
+			}
+		}
+
+		// The current left row has been compared with all its candidates.
+		leftRow := n.leftRow
+		n.leftRow = nil
+		if !n.leftMatched && n.joinType != joinTypeInner {
+			// If the left row didn't match, insert a tuple of NULLs on the
+			// right.
+			n.emit(leftRow, n.emptyRight)
+			return true, nil
+		}
+	}
+}
+
+// loadMergeGroup reads the next group of right rows.
+func (n *joinNode) loadMergeGroup() error {
+	m := &n.merge
+	if m.next == nil {
+		if m.rightInputDone {
+			m.rightDone = true
+			return nil
+		}
+		if err := n.readMergeRight(); err != nil {
+			return err
+		}
+		if m.next == nil {
+			m.rightDone = true
+			return nil
+		}
+	}
+
+	n.rightMemAcc.Clear()
+	m.group = m.group[:0]
+	m.groupKey, m.nextKey = m.nextKey, m.groupKey
+	m.groupKeyOK = m.nextKeyOK
+	for {
+		if err := n.rightMemAcc.Grow(int64(m.next.Size())); err != nil {
+			return err
+		}
+		m.group = append(m.group, m.next)
+		if err := n.readMergeRight(); err != nil {
+			return err
+		}
+		if m.next == nil || !m.groupKeyOK || !m.nextKeyOK || !bytes.Equal(m.nextKey, m.groupKey) {
+			break
+		}
+	}
+
+	if cap(m.groupMatched) >= len(m.group) {
+		m.groupMatched = m.groupMatched[:len(m.group)]
+		for i := range m.groupMatched {
+			m.groupMatched[i] = false
+		}
+	} else {
+		m.groupMatched = make([]bool, len(m.group))
+	}
+	m.groupLoaded = true
+	return nil
+}
+
+// readMergeRight reads the next right row into m.next, leaving it nil if the
+// right data source is exhausted.
+func (n *joinNode) readMergeRight() error {
+	m := &n.merge
+	m.next = nil
+	hasRow, err := n.right.Next()
+	if err != nil {
+		return err
+	}
+	if !hasRow {
+		m.rightInputDone = true
+		return nil
+	}
+	row := n.right.Values()
+	m.next = make(parser.DTuple, len(row))
+	copy(m.next, row)
+	m.nextKey, m.nextKeyOK, err = encodeJoinKey(m.nextKey[:0], m.next, m.rightCols, m.dirs)
+	return err
+}
+
+// finishMergeGroup discards the current group of right rows, queuing those
+// which matched no left row for full outer joins.
+func (n *joinNode) finishMergeGroup() {
+	m := &n.merge
+	if n.joinType == joinTypeOuterFull {
+		for i, row := range m.group {
+			if !m.groupMatched[i] {
+				m.unmatched = append(m.unmatched, row)
+			}
+		}
+	}
+	m.groupLoaded = false
+}
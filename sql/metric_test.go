@@ -92,6 +92,46 @@ func TestQueryCounts(t *testing.T) {
 	}
 }
 
+func TestStatementLatencies(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	for _, q := range []string{
+		"CREATE DATABASE mt",
+		"CREATE TABLE mt.n (num INTEGER)",
+		"INSERT INTO mt.n VALUES (3)",
+		"SELECT * FROM mt.n",
+	} {
+		if _, err := sqlDB.Exec(q); err != nil {
+			t.Fatalf("unexpected error executing '%s': %s'", q, err)
+		}
+	}
+
+	const window = "-1m"
+	for _, prefix := range []string{
+		sql.MetricDdlLatencyPrefix, sql.MetricInsertLatencyPrefix, sql.MetricSelectLatencyPrefix,
+	} {
+		for _, suffix := range []string{
+			sql.MetricParseLatencySuffix, sql.MetricPlanLatencySuffix,
+			sql.MetricExecLatencySuffix, sql.MetricServiceLatencySuffix,
+		} {
+			name := prefix + suffix + window
+			if c := s.MustGetSQLHistogramCount(name); c == 0 {
+				t.Errorf("expected values to be recorded in %s", name)
+			}
+		}
+	}
+	if c := s.MustGetSQLHistogramCount(sql.MetricDeleteLatencyPrefix +
+		sql.MetricServiceLatencySuffix + window); c != 0 {
+		t.Errorf("expected no DELETE latencies to be recorded, got %d", c)
+	}
+	if c := s.MustGetSQLHistogramCount(sql.MetricLatencyName + window); c < 4 {
+		t.Errorf("expected at least 4 values in %s, got %d", sql.MetricLatencyName, c)
+	}
+}
+
 func TestAbortCountConflictingWrites(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
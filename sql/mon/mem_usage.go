@@ -0,0 +1,215 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package mon implements the accounting of the memory used by SQL sessions
+// against a per-node budget.
+package mon
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/humanizeutil"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// MemoryMonitor tracks the memory allocated by SQL sessions, through the
+// MemoryAccounts opened on it.
+//
+// Monitors form a hierarchy: a monitor created with a pool reserves the
+// memory it hands out from that pool, in chunks of poolAllocationSize to
+// avoid contending on the pool for every allocation. The root monitor of a
+// node has no pool and is bounded by the node's SQL memory budget; a
+// monitor is typically created for each session with the root monitor as
+// its pool.
+//
+// Allocations which would make a monitor (or its pool) exceed its limit
+// fail with a memory budget exceeded error, letting the statement fail
+// cleanly instead of the node running out of memory.
+type MemoryMonitor struct {
+	mu struct {
+		syncutil.Mutex
+
+		// curAllocated is the number of bytes currently allocated through the
+		// monitor's accounts.
+		curAllocated int64
+		// maxAllocated is the high water mark of curAllocated.
+		maxAllocated int64
+		// reserved is the number of bytes reserved from the pool, if any.
+		reserved int64
+	}
+
+	// name identifies the monitor in logging messages.
+	name string
+	// limit is the maximum number of bytes the monitor can hand out, or zero
+	// if it is not limited.
+	limit int64
+	// pool is the monitor the memory is reserved from, if any.
+	pool *MemoryMonitor
+}
+
+// poolAllocationSize is the granularity at which monitors reserve memory
+// from their pool.
+const poolAllocationSize = 10 * 1024
+
+// MakeMonitor creates a monitor bounded by the given limit. A limit of zero
+// means no limit.
+func MakeMonitor(name string, limit int64) MemoryMonitor {
+	return MemoryMonitor{name: name, limit: limit}
+}
+
+// MakeMonitorWithPool creates a monitor which reserves the memory it hands out
+// from the given pool. It is only bounded by the pool's limit.
+func MakeMonitorWithPool(name string, pool *MemoryMonitor) MemoryMonitor {
+	return MemoryMonitor{name: name, pool: pool}
+}
+
+// Stop releases all the memory allocated through the monitor, including by
+// the accounts which were not closed, and returns the memory reserved from
+// its pool.
+func (mm *MemoryMonitor) Stop(ctx context.Context) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if log.V(1) {
+		log.Infof(ctx, "%s: memory usage peaked at %s",
+			mm.name, humanizeutil.IBytes(mm.mu.maxAllocated))
+	}
+	if mm.pool != nil && mm.mu.reserved > 0 {
+		mm.pool.release(mm.mu.reserved)
+	}
+	mm.mu.curAllocated = 0
+	mm.mu.reserved = 0
+}
+
+// CurrentlyAllocated returns the number of bytes currently allocated through
+// the monitor.
+func (mm *MemoryMonitor) CurrentlyAllocated() int64 {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.mu.curAllocated
+}
+
+// MaximumAllocated returns the high water mark of the bytes allocated
+// through the monitor.
+func (mm *MemoryMonitor) MaximumAllocated() int64 {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return mm.mu.maxAllocated
+}
+
+// reserve accounts for the allocation of n bytes, reserving more memory from
+// the pool if needed.
+func (mm *MemoryMonitor) reserve(n int64) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if mm.limit > 0 && mm.mu.curAllocated > mm.limit-n {
+		return sqlbase.NewMemoryBudgetExceededError(n, mm.limit)
+	}
+	if mm.pool != nil && mm.mu.curAllocated+n > mm.mu.reserved {
+		needed := mm.mu.curAllocated + n - mm.mu.reserved
+		// Round the reservation up to the allocation granularity.
+		needed = ((needed + poolAllocationSize - 1) / poolAllocationSize) * poolAllocationSize
+		if err := mm.pool.reserve(needed); err != nil {
+			return err
+		}
+		mm.mu.reserved += needed
+	}
+	mm.mu.curAllocated += n
+	if mm.mu.curAllocated > mm.mu.maxAllocated {
+		mm.mu.maxAllocated = mm.mu.curAllocated
+	}
+	return nil
+}
+
+// release accounts for the deallocation of n bytes. Memory reserved from the
+// pool is kept until the monitor is stopped, except for what exceeds a few
+// chunks, so that a session which allocated a lot of memory once doesn't hold
+// on to it.
+func (mm *MemoryMonitor) release(n int64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	if n > mm.mu.curAllocated {
+		panic("no memory to release")
+	}
+	mm.mu.curAllocated -= n
+	if mm.pool != nil {
+		if excess := mm.mu.reserved - mm.mu.curAllocated - 4*poolAllocationSize; excess > 0 {
+			excess = (excess / poolAllocationSize) * poolAllocationSize
+			mm.pool.release(excess)
+			mm.mu.reserved -= excess
+		}
+	}
+}
+
+// MemoryAccount tracks the memory allocated on behalf of a single client
+// of a monitor, e.g. the rows buffered by a sort. The memory is returned to
+// the monitor when the account is closed.
+type MemoryAccount struct {
+	curAllocated int64
+	mon          *MemoryMonitor
+}
+
+// MakeAccount creates an account allocating from the monitor. A nil monitor
+// makes an account which doesn't track anything, for the code paths which
+// don't run on behalf of a session.
+func (mm *MemoryMonitor) MakeAccount() MemoryAccount {
+	return MemoryAccount{mon: mm}
+}
+
+// Grow accounts for the allocation of extra bytes. It returns an error if the
+// monitor's budget would be exceeded, in which case nothing is allocated.
+func (acc *MemoryAccount) Grow(extra int64) error {
+	if acc.mon == nil {
+		return nil
+	}
+	if err := acc.mon.reserve(extra); err != nil {
+		return err
+	}
+	acc.curAllocated += extra
+	return nil
+}
+
+// Shrink accounts for the deallocation of delta bytes.
+func (acc *MemoryAccount) Shrink(delta int64) {
+	if acc.mon == nil {
+		return
+	}
+	if delta > acc.curAllocated {
+		panic("no memory in account to release")
+	}
+	acc.mon.release(delta)
+	acc.curAllocated -= delta
+}
+
+// ResizeItem accounts for an item changing size from oldSize to newSize.
+func (acc *MemoryAccount) ResizeItem(oldSize, newSize int64) error {
+	if newSize > oldSize {
+		return acc.Grow(newSize - oldSize)
+	}
+	acc.Shrink(oldSize - newSize)
+	return nil
+}
+
+// Clear releases all the memory allocated through the account, which can
+// still be used afterwards.
+func (acc *MemoryAccount) Clear() {
+	acc.Shrink(acc.curAllocated)
+}
+
+// Close releases all the memory allocated through the account. It is
+// equivalent to Clear; the account should not be used afterwards.
+func (acc *MemoryAccount) Close() {
+	acc.Clear()
+}
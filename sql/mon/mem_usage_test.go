@@ -0,0 +1,108 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package mon
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestMemoryAccount(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	m := MakeMonitor("test", 100)
+	a1 := m.MakeAccount()
+	a2 := m.MakeAccount()
+
+	if err := a1.Grow(60); err != nil {
+		t.Fatal(err)
+	}
+	if err := a2.Grow(50); !testutils.IsError(err, "memory budget exceeded") {
+		t.Fatalf("expected a memory budget exceeded error, got %v", err)
+	}
+	if err := a2.Grow(40); err != nil {
+		t.Fatal(err)
+	}
+	if cur := m.CurrentlyAllocated(); cur != 100 {
+		t.Fatalf("expected 100 bytes allocated, got %d", cur)
+	}
+
+	a1.Shrink(10)
+	if err := a2.ResizeItem(20, 30); err != nil {
+		t.Fatal(err)
+	}
+	a1.Close()
+	if cur := m.CurrentlyAllocated(); cur != 50 {
+		t.Fatalf("expected 50 bytes allocated, got %d", cur)
+	}
+	if max := m.MaximumAllocated(); max != 100 {
+		t.Fatalf("expected a high water mark of 100 bytes, got %d", max)
+	}
+
+	m.Stop(context.Background())
+	if cur := m.CurrentlyAllocated(); cur != 0 {
+		t.Fatalf("expected no bytes allocated after Stop, got %d", cur)
+	}
+}
+
+func TestMemoryMonitorPool(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	pool := MakeMonitor("pool", 3*poolAllocationSize)
+	m1 := MakeMonitorWithPool("m1", &pool)
+	m2 := MakeMonitorWithPool("m2", &pool)
+	a1 := m1.MakeAccount()
+	a2 := m2.MakeAccount()
+
+	// Small allocations reserve a whole chunk from the pool.
+	if err := a1.Grow(1); err != nil {
+		t.Fatal(err)
+	}
+	if cur := pool.CurrentlyAllocated(); cur != poolAllocationSize {
+		t.Fatalf("expected %d bytes reserved from the pool, got %d", poolAllocationSize, cur)
+	}
+	if err := a2.Grow(poolAllocationSize + 1); err != nil {
+		t.Fatal(err)
+	}
+	// The pool is exhausted.
+	if err := a1.Grow(poolAllocationSize); !testutils.IsError(err, "memory budget exceeded") {
+		t.Fatalf("expected a memory budget exceeded error, got %v", err)
+	}
+
+	// Stopping a monitor returns its reservation to the pool.
+	m2.Stop(context.Background())
+	if err := a1.Grow(poolAllocationSize); err != nil {
+		t.Fatal(err)
+	}
+	m1.Stop(context.Background())
+	if cur := pool.CurrentlyAllocated(); cur != 0 {
+		t.Fatalf("expected no bytes reserved from the pool, got %d", cur)
+	}
+}
+
+func TestNilMemoryMonitor(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var m *MemoryMonitor
+	a := m.MakeAccount()
+	if err := a.Grow(1 << 40); err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+}
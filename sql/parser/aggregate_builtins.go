@@ -50,6 +50,11 @@ type IsAggregateVisitor struct {
 func (v *IsAggregateVisitor) VisitPre(expr Expr) (recurse bool, newExpr Expr) {
 	switch t := expr.(type) {
 	case *FuncExpr:
+		if t.IsWindowFunctionApplication() {
+			// A window function application of an aggregate builtin is not an
+			// aggregate function, but any of its arguments could be.
+			return true, expr
+		}
 		fn, err := t.Name.Normalize()
 		if err != nil {
 			return false, expr
@@ -112,6 +117,8 @@ func (p *Parser) IsAggregate(n *SelectClause) bool {
 // table, so their evaluation must always be delayed until query
 // execution.
 var Aggregates = map[string][]Builtin{
+	"array_agg": makeArrayAggBuiltins(arrayParamTypes...),
+
 	"avg": {
 		makeAggBuiltin(TypeInt, TypeDecimal, newAvgAggregate),
 		makeAggBuiltin(TypeFloat, TypeFloat, newAvgAggregate),
@@ -168,8 +175,24 @@ func makeAggBuiltins(f func() AggregateFunc, types ...Datum) []Builtin {
 	return ret
 }
 
+// makeArrayAggBuiltins creates the array_agg overloads, each of which
+// aggregates values of one of the given types into an array of that type.
+func makeArrayAggBuiltins(types ...Datum) []Builtin {
+	ret := make([]Builtin, len(types))
+	for i := range types {
+		paramTyp := types[i]
+		ret[i] = makeAggBuiltin(paramTyp, NewDArray(paramTyp), func() AggregateFunc {
+			return newArrayAggregate(paramTyp)
+		})
+	}
+	return ret
+}
+
 func countImpls() []Builtin {
 	types := ArgTypes{TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes, TypeDate, TypeTimestamp, TypeInterval, TypeTuple}
+	for _, t := range arrayParamTypes {
+		types = append(types, NewDArray(t))
+	}
 	r := make([]Builtin, len(types))
 	for i := range types {
 		r[i] = makeAggBuiltin(types[i], TypeInt, newCountAggregate)
@@ -177,6 +200,7 @@ func countImpls() []Builtin {
 	return r
 }
 
+var _ AggregateFunc = &arrayAggregate{}
 var _ AggregateFunc = &avgAggregate{}
 var _ AggregateFunc = &countAggregate{}
 var _ AggregateFunc = &MaxAggregate{}
@@ -214,6 +238,32 @@ func (a *identAggregate) Result() (Datum, error) {
 	return a.val, nil
 }
 
+type arrayAggregate struct {
+	arr *DArray
+}
+
+func newArrayAggregate(paramTyp Datum) AggregateFunc {
+	return &arrayAggregate{arr: NewDArray(paramTyp)}
+}
+
+// Add accumulates the passed datum into the array.
+func (a *arrayAggregate) Add(datum Datum) error {
+	return a.arr.Append(datum)
+}
+
+// Result returns an array of all datums passed to Add, or NULL if there were
+// none.
+func (a *arrayAggregate) Result() (Datum, error) {
+	if len(a.arr.Array) == 0 {
+		return DNull, nil
+	}
+	// Copy the array, as further values may still be added to it when it is
+	// used as a window function.
+	res := NewDArray(a.arr.ParamTyp)
+	res.Array = append(DTuple(nil), a.arr.Array...)
+	return res, nil
+}
+
 type avgAggregate struct {
 	sumAggregate
 	count int
@@ -60,12 +60,14 @@ type AlterTableCmd interface {
 	alterTableCmd()
 }
 
-func (*AlterTableAddColumn) alterTableCmd()      {}
-func (*AlterTableAddConstraint) alterTableCmd()  {}
-func (*AlterTableDropColumn) alterTableCmd()     {}
-func (*AlterTableDropConstraint) alterTableCmd() {}
-func (*AlterTableSetDefault) alterTableCmd()     {}
-func (*AlterTableDropNotNull) alterTableCmd()    {}
+func (*AlterTableAddColumn) alterTableCmd()       {}
+func (*AlterTableAddConstraint) alterTableCmd()   {}
+func (*AlterTableDropColumn) alterTableCmd()      {}
+func (*AlterTableDropConstraint) alterTableCmd()  {}
+func (*AlterTableSetDefault) alterTableCmd()      {}
+func (*AlterTableDropNotNull) alterTableCmd()     {}
+func (*AlterTableAlterColumnType) alterTableCmd() {}
+func (*AlterTableAlterPrimaryKey) alterTableCmd() {}
 
 // ColumnMutationCmd is the subset of AlterTableCmds that modify an
 // existing column.
@@ -195,3 +197,40 @@ func (node *AlterTableDropNotNull) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, node.Column)
 	buf.WriteString(" DROP NOT NULL")
 }
+
+// AlterTableAlterColumnType represents an ALTER COLUMN TYPE command.
+type AlterTableAlterColumnType struct {
+	columnKeyword bool
+	Column        Name
+	ToType        ColumnType
+	// Using is the expression computing the new values of the column from
+	// the old row. If nil, the old values are cast to the new type.
+	Using Expr
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterTableAlterColumnType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	if node.columnKeyword {
+		buf.WriteString("COLUMN ")
+	}
+	FormatNode(buf, f, node.Column)
+	buf.WriteString(" TYPE ")
+	FormatNode(buf, f, node.ToType)
+	if node.Using != nil {
+		buf.WriteString(" USING ")
+		FormatNode(buf, f, node.Using)
+	}
+}
+
+// AlterTableAlterPrimaryKey represents an ALTER PRIMARY KEY command.
+type AlterTableAlterPrimaryKey struct {
+	Columns IndexElemList
+}
+
+// Format implements the NodeFormatter interface.
+func (node *AlterTableAlterPrimaryKey) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER PRIMARY KEY USING COLUMNS (")
+	FormatNode(buf, f, node.Columns)
+	buf.WriteByte(')')
+}
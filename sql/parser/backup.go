@@ -0,0 +1,65 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// Backup represents a BACKUP statement, which writes the descriptors and the
+// data of a set of tables or databases to external storage.
+type Backup struct {
+	Targets TargetList
+	// To is the destination the backup is written to.
+	To      string
+	Options KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Backup) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("BACKUP ")
+	if node.Targets.Databases == nil {
+		buf.WriteString("TABLE ")
+	}
+	FormatNode(buf, f, node.Targets)
+	buf.WriteString(" TO ")
+	encodeSQLString(buf, node.To)
+	if len(node.Options) > 0 {
+		buf.WriteString(" WITH ")
+		FormatNode(buf, f, node.Options)
+	}
+}
+
+// Restore represents a RESTORE statement, which recreates tables from a
+// backup written by BACKUP.
+type Restore struct {
+	Targets TargetList
+	// From is the location of the backup to restore.
+	From    string
+	Options KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Restore) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("RESTORE ")
+	if node.Targets.Databases == nil {
+		buf.WriteString("TABLE ")
+	}
+	FormatNode(buf, f, node.Targets)
+	buf.WriteString(" FROM ")
+	encodeSQLString(buf, node.From)
+	if len(node.Options) > 0 {
+		buf.WriteString(" WITH ")
+		FormatNode(buf, f, node.Options)
+	}
+}
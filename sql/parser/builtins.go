@@ -40,18 +40,21 @@ import (
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/decimal"
 	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/jsonb"
 	"github.com/cockroachdb/cockroach/util/syncutil"
 	"github.com/cockroachdb/cockroach/util/timeutil"
 	"github.com/cockroachdb/cockroach/util/uuid"
 )
 
 var (
-	errEmptyInputString  = errors.New("the input string must not be empty")
-	errAbsOfMinInt64     = errors.New("abs of min integer value (-9223372036854775808) not defined")
-	errRoundNumberDigits = errors.New("number of digits must be greater than 0")
-	errSqrtOfNegNumber   = errors.New("cannot take square root of a negative number")
-	errLogOfNegNumber    = errors.New("cannot take logarithm of a negative number")
-	errLogOfZero         = errors.New("cannot take logarithm of zero")
+	errEmptyInputString     = errors.New("the input string must not be empty")
+	errAbsOfMinInt64        = errors.New("abs of min integer value (-9223372036854775808) not defined")
+	errRoundNumberDigits    = errors.New("number of digits must be greater than 0")
+	errSqrtOfNegNumber      = errors.New("cannot take square root of a negative number")
+	errLogOfNegNumber       = errors.New("cannot take logarithm of a negative number")
+	errLogOfZero            = errors.New("cannot take logarithm of zero")
+	errOddBuildObject       = errors.New("argument list must have even number of elements")
+	errSequencesUnavailable = errors.New("sequences cannot be accessed in this context")
 )
 
 const (
@@ -61,6 +64,10 @@ const (
 	categoryString       = "String and Byte"
 	categoryMath         = "Math and Numeric"
 	categoryComparison   = "Comparison"
+	categoryArray        = "Array"
+	categoryJSON         = "JSON"
+	categoryIPAddr       = "IP Address"
+	categorySequences    = "Sequence"
 )
 
 // Builtin is a built-in function.
@@ -75,6 +82,8 @@ type Builtin struct {
 	// in separate statements, and should not be marked as impure.
 	impure        bool
 	AggregateFunc func() AggregateFunc
+	WindowFunc    func() WindowFunc
+	Generator     func(*EvalContext, DTuple) (ValueGenerator, error)
 	fn            func(*EvalContext, DTuple) (Datum, error)
 }
 
@@ -516,6 +525,84 @@ var Builtins = map[string][]Builtin{
 		},
 	},
 
+	// Sequence functions.
+
+	"nextval": {
+		Builtin{
+			Types:      ArgTypes{TypeString},
+			ReturnType: TypeInt,
+			category:   categorySequences,
+			impure:     true,
+			fn: func(ctx *EvalContext, args DTuple) (Datum, error) {
+				if ctx.Sequence == nil {
+					return nil, errSequencesUnavailable
+				}
+				res, err := ctx.Sequence.IncrementSequence(string(*args[0].(*DString)))
+				if err != nil {
+					return nil, err
+				}
+				return NewDInt(DInt(res)), nil
+			},
+		},
+	},
+
+	"currval": {
+		Builtin{
+			Types:      ArgTypes{TypeString},
+			ReturnType: TypeInt,
+			category:   categorySequences,
+			impure:     true,
+			fn: func(ctx *EvalContext, args DTuple) (Datum, error) {
+				if ctx.Sequence == nil {
+					return nil, errSequencesUnavailable
+				}
+				res, err := ctx.Sequence.GetLatestValueInSessionForSequence(string(*args[0].(*DString)))
+				if err != nil {
+					return nil, err
+				}
+				return NewDInt(DInt(res)), nil
+			},
+		},
+	},
+
+	"setval": {
+		Builtin{
+			Types:      ArgTypes{TypeString, TypeInt},
+			ReturnType: TypeInt,
+			category:   categorySequences,
+			impure:     true,
+			fn: func(ctx *EvalContext, args DTuple) (Datum, error) {
+				if ctx.Sequence == nil {
+					return nil, errSequencesUnavailable
+				}
+				newVal := *args[1].(*DInt)
+				if err := ctx.Sequence.SetSequenceValue(
+					string(*args[0].(*DString)), int64(newVal), true); err != nil {
+					return nil, err
+				}
+				return args[1], nil
+			},
+		},
+		Builtin{
+			Types:      ArgTypes{TypeString, TypeInt, TypeBool},
+			ReturnType: TypeInt,
+			category:   categorySequences,
+			impure:     true,
+			fn: func(ctx *EvalContext, args DTuple) (Datum, error) {
+				if ctx.Sequence == nil {
+					return nil, errSequencesUnavailable
+				}
+				newVal := *args[1].(*DInt)
+				isCalled := bool(*args[2].(*DBool))
+				if err := ctx.Sequence.SetSequenceValue(
+					string(*args[0].(*DString)), int64(newVal), isCalled); err != nil {
+					return nil, err
+				}
+				return args[1], nil
+			},
+		},
+	},
+
 	"experimental_uuid_v4": {uuidV4Impl},
 	"uuid_v4":              {uuidV4Impl},
 
@@ -997,6 +1084,91 @@ var Builtins = map[string][]Builtin{
 			},
 		},
 	},
+
+	// Array functions.
+
+	"array_length": arrayBuiltins(func(arr *DArray, dim int64) Datum {
+		return arrayLength(arr, dim)
+	}),
+	"array_lower": arrayBuiltins(func(arr *DArray, dim int64) Datum {
+		return arrayLower(arr, dim)
+	}),
+	"array_upper": arrayBuiltins(func(arr *DArray, dim int64) Datum {
+		return arrayLength(arr, dim)
+	}),
+
+	// JSON functions. As JSON values are stored as JSONB, the json_ and jsonb_
+	// variants of each function are identical.
+
+	"json_build_array":  {jsonBuildArrayImpl},
+	"jsonb_build_array": {jsonBuildArrayImpl},
+
+	"json_build_object":  {jsonBuildObjectImpl},
+	"jsonb_build_object": {jsonBuildObjectImpl},
+
+	"json_array_length":  {jsonArrayLengthImpl},
+	"jsonb_array_length": {jsonArrayLengthImpl},
+
+	"json_typeof":  {jsonTypeOfImpl},
+	"jsonb_typeof": {jsonTypeOfImpl},
+
+	"jsonb_set": {
+		Builtin{
+			Types:      ArgTypes{TypeJSON, NewDArray(TypeString), TypeJSON},
+			ReturnType: TypeJSON,
+			category:   categoryJSON,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				return jsonSet(args[0].(*DJSON), args[1].(*DArray), args[2].(*DJSON), true)
+			},
+		},
+		Builtin{
+			Types:      ArgTypes{TypeJSON, NewDArray(TypeString), TypeJSON, TypeBool},
+			ReturnType: TypeJSON,
+			category:   categoryJSON,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				return jsonSet(args[0].(*DJSON), args[1].(*DArray), args[2].(*DJSON),
+					bool(*args[3].(*DBool)))
+			},
+		},
+	},
+
+	"to_json":  {toJSONImpl},
+	"to_jsonb": {toJSONImpl},
+
+	// IP address functions.
+
+	"family": {
+		Builtin{
+			Types:      ArgTypes{TypeINet},
+			ReturnType: TypeInt,
+			category:   categoryIPAddr,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				return NewDInt(DInt(args[0].(*DIPAddr).Family)), nil
+			},
+		},
+	},
+
+	"host": {
+		Builtin{
+			Types:      ArgTypes{TypeINet},
+			ReturnType: TypeString,
+			category:   categoryIPAddr,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				return NewDString(args[0].(*DIPAddr).Host()), nil
+			},
+		},
+	},
+
+	"masklen": {
+		Builtin{
+			Types:      ArgTypes{TypeINet},
+			ReturnType: TypeInt,
+			category:   categoryIPAddr,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				return NewDInt(DInt(args[0].(*DIPAddr).Mask)), nil
+			},
+		},
+	},
 }
 
 func init() {
@@ -1005,6 +1177,197 @@ func init() {
 	}
 }
 
+// arrayBuiltins creates overloads of a function taking an array of each of
+// the supported element types and a dimension, and returning an int.
+func arrayBuiltins(impl func(*DArray, int64) Datum) []Builtin {
+	result := make([]Builtin, 0, len(arrayParamTypes))
+	for _, typ := range arrayParamTypes {
+		result = append(result, Builtin{
+			Types:      ArgTypes{NewDArray(typ), TypeInt},
+			ReturnType: TypeInt,
+			category:   categoryArray,
+			fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+				arr := args[0].(*DArray)
+				dimen := int64(*args[1].(*DInt))
+				return impl(arr, dimen), nil
+			},
+		})
+	}
+	return result
+}
+
+// arrayLength returns the length of the given dimension of the array, or
+// NULL if the array has no such dimension. Arrays are one-dimensional, and
+// empty arrays have no dimensions.
+func arrayLength(arr *DArray, dim int64) Datum {
+	if arr.Len() == 0 || dim != 1 {
+		return DNull
+	}
+	return NewDInt(DInt(arr.Len()))
+}
+
+var intOne = NewDInt(DInt(1))
+
+// arrayLower returns the lower bound of the given dimension of the array, or
+// NULL if the array has no such dimension. Arrays are indexed from 1.
+func arrayLower(arr *DArray, dim int64) Datum {
+	if arr.Len() == 0 || dim != 1 {
+		return DNull
+	}
+	return intOne
+}
+
+var jsonBuildArrayImpl = Builtin{
+	Types:      HeterogeneousType{},
+	ReturnType: TypeJSON,
+	category:   categoryJSON,
+	fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+		arr := make([]interface{}, len(args))
+		for i, d := range args {
+			var err error
+			if arr[i], err = DatumToJSON(d); err != nil {
+				return nil, err
+			}
+		}
+		return NewDJSON(arr), nil
+	},
+}
+
+var jsonBuildObjectImpl = Builtin{
+	Types:      HeterogeneousType{},
+	ReturnType: TypeJSON,
+	category:   categoryJSON,
+	fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+		if len(args)%2 != 0 {
+			return nil, errOddBuildObject
+		}
+		obj := make(map[string]interface{}, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			if args[i] == DNull {
+				return nil, fmt.Errorf("argument %d: key must not be null", i+1)
+			}
+			key, err := DatumToJSON(args[i])
+			if err != nil {
+				return nil, err
+			}
+			var keyStr string
+			switch t := key.(type) {
+			case string:
+				keyStr = t
+			case []interface{}, map[string]interface{}:
+				return nil, fmt.Errorf("argument %d: key must be a scalar, not %s",
+					i+1, args[i].Type())
+			default:
+				keyStr = jsonb.String(t)
+			}
+			if obj[keyStr], err = DatumToJSON(args[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		return NewDJSON(obj), nil
+	},
+}
+
+var jsonArrayLengthImpl = Builtin{
+	Types:      ArgTypes{TypeJSON},
+	ReturnType: TypeInt,
+	category:   categoryJSON,
+	fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+		switch t := args[0].(*DJSON).JSON.(type) {
+		case []interface{}:
+			return NewDInt(DInt(len(t))), nil
+		case map[string]interface{}:
+			return nil, errors.New("cannot get array length of a non-array")
+		}
+		return nil, errors.New("cannot get array length of a scalar")
+	},
+}
+
+var jsonTypeOfImpl = Builtin{
+	Types:      ArgTypes{TypeJSON},
+	ReturnType: TypeString,
+	category:   categoryJSON,
+	fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+		return NewDString(jsonb.TypeOf(args[0].(*DJSON).JSON)), nil
+	},
+}
+
+var toJSONImpl = Builtin{
+	Types:      HeterogeneousType{NumArgs: 1},
+	ReturnType: TypeJSON,
+	category:   categoryJSON,
+	fn: func(_ *EvalContext, args DTuple) (Datum, error) {
+		if args[0] == DNull {
+			return DNull, nil
+		}
+		j, err := DatumToJSON(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return NewDJSON(j), nil
+	},
+}
+
+// jsonSet implements jsonb_set, replacing the value at the given path in the
+// target document by the new value.
+func jsonSet(target *DJSON, path *DArray, newVal *DJSON, createMissing bool) (Datum, error) {
+	keys := make([]string, len(path.Array))
+	for i, d := range path.Array {
+		if d == DNull {
+			return nil, fmt.Errorf("path element at position %d is null", i+1)
+		}
+		keys[i] = string(*d.(*DString))
+	}
+	j, err := jsonb.Set(target.JSON, keys, newVal.JSON, createMissing)
+	if err != nil {
+		return nil, err
+	}
+	return NewDJSON(j), nil
+}
+
+// DatumToJSON converts a Datum to the JSON value with the same meaning, as
+// done by the to_jsonb function: numbers and booleans become their JSON
+// counterparts, arrays become JSON arrays and other values are converted to
+// JSON strings holding their text representation.
+func DatumToJSON(d Datum) (interface{}, error) {
+	switch t := d.(type) {
+	case dNull:
+		return nil, nil
+	case *DBool:
+		return bool(*t), nil
+	case *DInt:
+		return jsonb.FromNumber(strconv.FormatInt(int64(*t), 10))
+	case *DFloat:
+		f := float64(*t)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		}
+		return jsonb.FromNumber(strconv.FormatFloat(f, 'g', -1, 64))
+	case *DDecimal:
+		return jsonb.FromNumber(t.Dec.String())
+	case *DString:
+		return string(*t), nil
+	case *DBytes:
+		return fmt.Sprintf("\\x%x", string(*t)), nil
+	case *DDate, *DTimestamp, *DTimestampTZ, *DInterval:
+		return AsString(t), nil
+	case *DArray:
+		arr := make([]interface{}, len(t.Array))
+		for i, e := range t.Array {
+			var err error
+			if arr[i], err = DatumToJSON(e); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	case *DJSON:
+		return t.JSON, nil
+	case *DIPAddr:
+		return t.IPAddr.String(), nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to JSON", d.Type())
+}
+
 var substringImpls = []Builtin{
 	{
 		Types:      ArgTypes{TypeString, TypeInt},
@@ -0,0 +1,38 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// CreateChangefeed represents a CREATE CHANGEFEED statement, which starts a
+// job emitting the changes made to a set of tables to a sink.
+type CreateChangefeed struct {
+	Targets TargetList
+	// SinkURI is the sink the changes are emitted to, e.g. a Kafka cluster.
+	SinkURI string
+	Options KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateChangefeed) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE CHANGEFEED FOR TABLE ")
+	FormatNode(buf, f, node.Targets.Tables)
+	buf.WriteString(" INTO ")
+	encodeSQLString(buf, node.SinkURI)
+	if len(node.Options) > 0 {
+		buf.WriteString(" WITH ")
+		FormatNode(buf, f, node.Options)
+	}
+}
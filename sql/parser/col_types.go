@@ -30,16 +30,20 @@ type ColumnType interface {
 	columnType()
 }
 
-func (*BoolColType) columnType()        {}
-func (*IntColType) columnType()         {}
-func (*FloatColType) columnType()       {}
-func (*DecimalColType) columnType()     {}
-func (*DateColType) columnType()        {}
-func (*TimestampColType) columnType()   {}
-func (*TimestampTZColType) columnType() {}
-func (*IntervalColType) columnType()    {}
-func (*StringColType) columnType()      {}
-func (*BytesColType) columnType()       {}
+func (*BoolColType) columnType()           {}
+func (*IntColType) columnType()            {}
+func (*FloatColType) columnType()          {}
+func (*DecimalColType) columnType()        {}
+func (*DateColType) columnType()           {}
+func (*TimestampColType) columnType()      {}
+func (*TimestampTZColType) columnType()    {}
+func (*IntervalColType) columnType()       {}
+func (*StringColType) columnType()         {}
+func (*BytesColType) columnType()          {}
+func (*ArrayColType) columnType()          {}
+func (*JSONColType) columnType()           {}
+func (*INetColType) columnType()           {}
+func (*CollatedStringColType) columnType() {}
 
 // Pre-allocated immutable boolean column types.
 var (
@@ -225,6 +229,24 @@ func (node *StringColType) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// CollatedStringColType represents a STRING, CHAR or VARCHAR type with a
+// locale.
+type CollatedStringColType struct {
+	Name   string
+	N      int
+	Locale string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CollatedStringColType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(node.Name)
+	if node.N > 0 {
+		fmt.Fprintf(buf, "(%d)", node.N)
+	}
+	buf.WriteString(" COLLATE ")
+	FormatNode(buf, f, Name(node.Locale))
+}
+
 // Pre-allocated immutable bytes column types.
 var (
 	bytesColTypeBlob  = &BytesColType{Name: "BLOB"}
@@ -242,22 +264,80 @@ func (node *BytesColType) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(node.Name)
 }
 
-func (node *BoolColType) String() string        { return AsString(node) }
-func (node *IntColType) String() string         { return AsString(node) }
-func (node *FloatColType) String() string       { return AsString(node) }
-func (node *DecimalColType) String() string     { return AsString(node) }
-func (node *DateColType) String() string        { return AsString(node) }
-func (node *TimestampColType) String() string   { return AsString(node) }
-func (node *TimestampTZColType) String() string { return AsString(node) }
-func (node *IntervalColType) String() string    { return AsString(node) }
-func (node *StringColType) String() string      { return AsString(node) }
-func (node *BytesColType) String() string       { return AsString(node) }
+// ArrayColType represents an ARRAY column type.
+type ArrayColType struct {
+	Name string
+	// ParamType is the type of the elements in this array.
+	ParamType ColumnType
+	// Bounds are the declared sizes of the dimensions of this array, or -1
+	// for dimensions declared without a size. Like Postgres, we accept but
+	// do not enforce them.
+	Bounds []int32
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ArrayColType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(node.Name)
+}
+
+// Pre-allocated immutable JSON column types.
+var (
+	jsonColTypeJSON  = &JSONColType{Name: "JSON"}
+	jsonColTypeJSONB = &JSONColType{Name: "JSONB"}
+)
+
+// JSONColType represents a JSON or JSONB type. Both are stored as JSONB: the
+// documents are kept in a decomposed binary format rather than as text.
+type JSONColType struct {
+	Name string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *JSONColType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(node.Name)
+}
+
+// Pre-allocated immutable INET column type.
+var inetColTypeINet = &INetColType{}
+
+// INetColType represents an INET type.
+type INetColType struct {
+}
+
+// Format implements the NodeFormatter interface.
+func (node *INetColType) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("INET")
+}
+
+func arrayOf(colType ColumnType, bounds []int32) (ColumnType, error) {
+	if _, ok := colType.(*ArrayColType); ok {
+		return nil, errNestedArraysNotSupported
+	}
+	return &ArrayColType{Name: colType.String() + "[]", ParamType: colType, Bounds: bounds}, nil
+}
+
+func (node *BoolColType) String() string           { return AsString(node) }
+func (node *IntColType) String() string            { return AsString(node) }
+func (node *FloatColType) String() string          { return AsString(node) }
+func (node *DecimalColType) String() string        { return AsString(node) }
+func (node *DateColType) String() string           { return AsString(node) }
+func (node *TimestampColType) String() string      { return AsString(node) }
+func (node *TimestampTZColType) String() string    { return AsString(node) }
+func (node *IntervalColType) String() string       { return AsString(node) }
+func (node *StringColType) String() string         { return AsString(node) }
+func (node *BytesColType) String() string          { return AsString(node) }
+func (node *ArrayColType) String() string          { return AsString(node) }
+func (node *JSONColType) String() string           { return AsString(node) }
+func (node *INetColType) String() string           { return AsString(node) }
+func (node *CollatedStringColType) String() string { return AsString(node) }
 
 // DatumTypeToColumnType produces a SQL column type equivalent to the
 // given Datum type. Used to generate CastExpr nodes during
 // normalization.
 func DatumTypeToColumnType(d Datum) (ColumnType, error) {
-	switch d.(type) {
+	switch t := d.(type) {
+	case *DBool:
+		return boolColTypeBool, nil
 	case *DInt:
 		return intColTypeInt, nil
 	case *DFloat:
@@ -276,6 +356,18 @@ func DatumTypeToColumnType(d Datum) (ColumnType, error) {
 		return stringColTypeString, nil
 	case *DBytes:
 		return bytesColTypeBytes, nil
+	case *DArray:
+		elemTyp, err := DatumTypeToColumnType(t.ParamTyp)
+		if err != nil {
+			return nil, err
+		}
+		return arrayOf(elemTyp, []int32{-1})
+	case *DJSON:
+		return jsonColTypeJSONB, nil
+	case *DIPAddr:
+		return inetColTypeINet, nil
+	case *DCollatedString:
+		return &CollatedStringColType{Name: "STRING", Locale: t.Locale}, nil
 	}
 	return nil, errors.Errorf("internal error: unknown Datum type %T", d)
 }
@@ -306,7 +306,18 @@ var strValAvailAllParsable = []Datum{
 	TypeTimestamp,
 	TypeTimestampTZ,
 	TypeInterval,
+	TypeJSON,
+	TypeINet,
 }
+
+// String constants can also be parsed as arrays of any type that arrays can
+// hold, as in '{1,2,3}'.
+func init() {
+	for _, typ := range arrayParamTypes {
+		strValAvailAllParsable = append(strValAvailAllParsable, NewDArray(typ))
+	}
+}
+
 var strValAvailBytesString = []Datum{TypeBytes, TypeString}
 var strValAvailBytes = []Datum{TypeBytes}
 
@@ -338,7 +349,22 @@ func (expr *StrVal) ResolveAsType(ctx *SemaContext, typ Datum) (Datum, error) {
 		return ParseDTimestampTZ(expr.s, ctx.getLocation(), time.Microsecond)
 	case TypeInterval:
 		return ParseDInterval(expr.s)
+	case TypeJSON:
+		return ParseDJSON(expr.s)
+	case TypeINet:
+		return ParseDIPAddr(expr.s)
 	default:
+		if arr, ok := typ.(*DArray); ok {
+			paramTyp, err := DatumTypeToColumnType(arr.ParamTyp)
+			if err != nil {
+				return nil, err
+			}
+			evalCtx := &EvalContext{}
+			if ctx != nil {
+				evalCtx.Location = ctx.Location
+			}
+			return ParseDArrayFromString(evalCtx, expr.s, paramTyp)
+		}
 		return nil, fmt.Errorf("could not resolve %T %v into a %T", expr, expr, typ)
 	}
 }
@@ -0,0 +1,95 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// CopyFrom represents a COPY FROM statement.
+type CopyFrom struct {
+	Table   NormalizableTableName
+	Columns UnresolvedNames
+	Stdin   bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyFrom) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("COPY ")
+	FormatNode(buf, f, node.Table)
+	if len(node.Columns) > 0 {
+		buf.WriteString(" (")
+		FormatNode(buf, f, node.Columns)
+		buf.WriteString(")")
+	}
+	buf.WriteString(" FROM ")
+	if node.Stdin {
+		buf.WriteString("STDIN")
+	}
+}
+
+// CopyTo represents a COPY TO statement, exporting either the rows of a
+// table or the results of a query.
+type CopyTo struct {
+	Table   NormalizableTableName
+	Columns UnresolvedNames
+	Stmt    *Select
+	Stdout  bool
+	Options CopyOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyTo) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("COPY ")
+	if node.Stmt != nil {
+		FormatNode(buf, f, node.Stmt)
+	} else {
+		FormatNode(buf, f, node.Table)
+		if len(node.Columns) > 0 {
+			buf.WriteString(" (")
+			FormatNode(buf, f, node.Columns)
+			buf.WriteString(")")
+		}
+	}
+	buf.WriteString(" TO ")
+	if node.Stdout {
+		buf.WriteString("STDOUT")
+	}
+	FormatNode(buf, f, &node.Options)
+}
+
+// CopyOptions represents the options of a COPY statement.
+type CopyOptions struct {
+	// DataFormat is the name of the data format, text by default.
+	DataFormat string
+	Header     bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CopyOptions) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.DataFormat == "" && !node.Header {
+		return
+	}
+	buf.WriteString(" WITH (")
+	if node.DataFormat != "" {
+		buf.WriteString("FORMAT ")
+		buf.WriteString(node.DataFormat)
+		if node.Header {
+			buf.WriteString(", ")
+		}
+	}
+	if node.Header {
+		buf.WriteString("HEADER")
+	}
+	buf.WriteByte(')')
+}
@@ -25,6 +25,8 @@ package parser
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/pkg/errors"
 )
 
 // CreateDatabase represents a CREATE DATABASE statement.
@@ -47,6 +49,41 @@ func (node *CreateDatabase) Format(buf *bytes.Buffer, f FmtFlags) {
 	}
 }
 
+// CreateRole represents a CREATE ROLE statement.
+type CreateRole struct {
+	Name        Name
+	IfNotExists bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateRole) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE ROLE ")
+	if node.IfNotExists {
+		buf.WriteString("IF NOT EXISTS ")
+	}
+	FormatNode(buf, f, node.Name)
+}
+
+// CreateUser represents a CREATE USER statement.
+type CreateUser struct {
+	Name        Name
+	Password    *StrVal
+	IfNotExists bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateUser) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE USER ")
+	if node.IfNotExists {
+		buf.WriteString("IF NOT EXISTS ")
+	}
+	FormatNode(buf, f, node.Name)
+	if node.Password != nil {
+		buf.WriteString(" WITH PASSWORD ")
+		node.Password.Format(buf, f)
+	}
+}
+
 // IndexElem represents a column with a direction in a CREATE INDEX statement.
 type IndexElem struct {
 	Column    Name
@@ -81,12 +118,17 @@ type CreateIndex struct {
 	Name        Name
 	Table       NormalizableTableName
 	Unique      bool
+	Inverted    bool
 	IfNotExists bool
 	Columns     IndexElemList
 	// Extra columns to be stored together with the indexed ones as an optimization
 	// for improved reading performance.
-	Storing    NameList
-	Interleave *InterleaveDef
+	Storing     NameList
+	Interleave  *InterleaveDef
+	PartitionBy *PartitionBy
+	// Predicate restricts the index to the rows that satisfy it. It is nil
+	// for indexes over all of the rows of the table.
+	Predicate Expr
 }
 
 // Format implements the NodeFormatter interface.
@@ -95,6 +137,9 @@ func (node *CreateIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Unique {
 		buf.WriteString("UNIQUE ")
 	}
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.IfNotExists {
 		buf.WriteString("IF NOT EXISTS ")
@@ -113,6 +158,13 @@ func (node *CreateIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.PartitionBy != nil {
+		FormatNode(buf, f, node.PartitionBy)
+	}
+	if node.Predicate != nil {
+		buf.WriteString(" WHERE ")
+		FormatNode(buf, f, node.Predicate)
+	}
 }
 
 // TableDef represents a column, index or constraint definition within a CREATE
@@ -173,10 +225,15 @@ type ColumnTableDef struct {
 		Expr           Expr
 		ConstraintName Name
 	}
+	Computed struct {
+		Computed bool
+		Expr     Expr
+	}
 	References struct {
 		Table          NormalizableTableName
 		Col            Name
 		ConstraintName Name
+		Actions        ReferenceActions
 	}
 	Family struct {
 		Name        Name
@@ -187,7 +244,7 @@ type ColumnTableDef struct {
 
 func newColumnTableDef(
 	name Name, typ ColumnType, qualifications []NamedColumnQualification,
-) *ColumnTableDef {
+) (*ColumnTableDef, error) {
 	d := &ColumnTableDef{
 		Name: name,
 		Type: typ,
@@ -228,6 +285,7 @@ func newColumnTableDef(
 		case *ColumnFKConstraint:
 			d.References.Table = t.Table
 			d.References.Col = t.Col
+			d.References.Actions = t.Actions
 			if c.Name != "" {
 				d.References.ConstraintName = c.Name
 			}
@@ -235,11 +293,24 @@ func newColumnTableDef(
 			d.Family.Name = t.Family
 			d.Family.Create = t.Create
 			d.Family.IfNotExists = t.IfNotExists
+		case *ColumnComputedDef:
+			d.Computed.Computed = true
+			d.Computed.Expr = t.Expr
+		case ColumnCollation:
+			locale := string(t)
+			switch st := d.Type.(type) {
+			case *StringColType:
+				d.Type = &CollatedStringColType{Name: st.Name, N: st.N, Locale: locale}
+			case *CollatedStringColType:
+				return nil, errors.Errorf("multiple COLLATE declarations for column %q", name)
+			default:
+				return nil, errors.Errorf("COLLATE declaration for non-string-typed column %q", name)
+			}
 		default:
 			panic(fmt.Sprintf("unexpected column qualification: %T", c))
 		}
 	}
-	return d
+	return d, nil
 }
 
 func (node *ColumnTableDef) setName(name Name) {
@@ -279,6 +350,11 @@ func (node *ColumnTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 		FormatNode(buf, f, node.CheckExpr.Expr)
 		buf.WriteByte(')')
 	}
+	if node.Computed.Computed {
+		buf.WriteString(" AS (")
+		FormatNode(buf, f, node.Computed.Expr)
+		buf.WriteString(") STORED")
+	}
 	if node.References.Table.TableNameReference != nil {
 		if node.References.ConstraintName != "" {
 			fmt.Fprintf(buf, " CONSTRAINT %s", node.References.ConstraintName)
@@ -290,6 +366,7 @@ func (node *ColumnTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 			FormatNode(buf, f, node.References.Col)
 			buf.WriteByte(')')
 		}
+		FormatNode(buf, f, node.References.Actions)
 	}
 	if node.Family.Name != "" || node.Family.Create {
 		if node.Family.Create {
@@ -325,6 +402,11 @@ func (UniqueConstraint) columnQualification()        {}
 func (*ColumnCheckConstraint) columnQualification()  {}
 func (*ColumnFKConstraint) columnQualification()     {}
 func (*ColumnFamilyConstraint) columnQualification() {}
+func (*ColumnComputedDef) columnQualification()      {}
+func (ColumnCollation) columnQualification()         {}
+
+// ColumnCollation represents a COLLATE clause for a column.
+type ColumnCollation string
 
 // ColumnDefault represents a DEFAULT clause for a column.
 type ColumnDefault struct {
@@ -350,8 +432,14 @@ type ColumnCheckConstraint struct {
 
 // ColumnFKConstraint represents a FK-constaint on a column.
 type ColumnFKConstraint struct {
-	Table NormalizableTableName
-	Col   Name // empty-string means use PK
+	Table   NormalizableTableName
+	Col     Name // empty-string means use PK
+	Actions ReferenceActions
+}
+
+// ColumnComputedDef represents the description of a computed column.
+type ColumnComputedDef struct {
+	Expr Expr
 }
 
 // ColumnFamilyConstraint represents FAMILY on a column.
@@ -378,6 +466,8 @@ type IndexTableDef struct {
 	Columns    IndexElemList
 	Storing    NameList
 	Interleave *InterleaveDef
+	Predicate  Expr
+	Inverted   bool
 }
 
 func (node *IndexTableDef) setName(name Name) {
@@ -386,6 +476,9 @@ func (node *IndexTableDef) setName(name Name) {
 
 // Format implements the NodeFormatter interface.
 func (node *IndexTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Inverted {
+		buf.WriteString("INVERTED ")
+	}
 	buf.WriteString("INDEX ")
 	if node.Name != "" {
 		FormatNode(buf, f, node.Name)
@@ -402,6 +495,10 @@ func (node *IndexTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.Predicate != nil {
+		buf.WriteString(" WHERE ")
+		FormatNode(buf, f, node.Predicate)
+	}
 }
 
 // ConstraintTableDef represents a constraint definition within a CREATE TABLE
@@ -443,6 +540,10 @@ func (node *UniqueConstraintTableDef) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.Predicate != nil {
+		buf.WriteString(" WHERE ")
+		FormatNode(buf, f, node.Predicate)
+	}
 }
 
 // ForeignKeyConstraintTableDef represents a FOREIGN KEY constraint in the AST.
@@ -451,6 +552,7 @@ type ForeignKeyConstraintTableDef struct {
 	Table    NormalizableTableName
 	FromCols NameList
 	ToCols   NameList
+	Actions  ReferenceActions
 }
 
 // Format implements the NodeFormatter interface.
@@ -469,6 +571,7 @@ func (node *ForeignKeyConstraintTableDef) Format(buf *bytes.Buffer, f FmtFlags)
 		FormatNode(buf, f, node.ToCols)
 		buf.WriteByte(')')
 	}
+	FormatNode(buf, f, node.Actions)
 }
 
 func (node *ForeignKeyConstraintTableDef) setName(name Name) {
@@ -478,6 +581,45 @@ func (node *ForeignKeyConstraintTableDef) setName(name Name) {
 func (*ForeignKeyConstraintTableDef) tableDef()           {}
 func (*ForeignKeyConstraintTableDef) constraintTableDef() {}
 
+// ReferenceAction is the action taken on the referencing rows of a foreign
+// key when the rows they reference are deleted or updated.
+type ReferenceAction int
+
+// ReferenceAction values.
+const (
+	NoAction ReferenceAction = iota
+	Restrict
+	SetNull
+	Cascade
+)
+
+var referenceActionName = [...]string{
+	NoAction: "NO ACTION",
+	Restrict: "RESTRICT",
+	SetNull:  "SET NULL",
+	Cascade:  "CASCADE",
+}
+
+func (ra ReferenceAction) String() string {
+	return referenceActionName[ra]
+}
+
+// ReferenceActions are the ON DELETE and ON UPDATE actions of a foreign key.
+type ReferenceActions struct {
+	Delete ReferenceAction
+	Update ReferenceAction
+}
+
+// Format implements the NodeFormatter interface.
+func (node ReferenceActions) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Delete != NoAction {
+		fmt.Fprintf(buf, " ON DELETE %s", node.Delete)
+	}
+	if node.Update != NoAction {
+		fmt.Fprintf(buf, " ON UPDATE %s", node.Update)
+	}
+}
+
 func (*CheckConstraintTableDef) tableDef()           {}
 func (*CheckConstraintTableDef) constraintTableDef() {}
 
@@ -554,14 +696,21 @@ func (node *InterleaveDef) Format(buf *bytes.Buffer, f FmtFlags) {
 // CreateTable represents a CREATE TABLE statement.
 type CreateTable struct {
 	IfNotExists bool
+	Temporary   bool
 	Table       NormalizableTableName
 	Interleave  *InterleaveDef
+	// PartitionBy partitions the primary index of the table.
+	PartitionBy *PartitionBy
 	Defs        TableDefs
 }
 
 // Format implements the NodeFormatter interface.
 func (node *CreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
-	buf.WriteString("CREATE TABLE ")
+	buf.WriteString("CREATE ")
+	if node.Temporary {
+		buf.WriteString("TEMPORARY ")
+	}
+	buf.WriteString("TABLE ")
 	if node.IfNotExists {
 		buf.WriteString("IF NOT EXISTS ")
 	}
@@ -572,4 +721,101 @@ func (node *CreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
 	if node.Interleave != nil {
 		FormatNode(buf, f, node.Interleave)
 	}
+	if node.PartitionBy != nil {
+		FormatNode(buf, f, node.PartitionBy)
+	}
+}
+
+// CreateView represents a CREATE VIEW statement.
+type CreateView struct {
+	Name        NormalizableTableName
+	ColumnNames NameList
+	AsSource    *Select
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateView) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE VIEW ")
+	FormatNode(buf, f, node.Name)
+	if len(node.ColumnNames) > 0 {
+		buf.WriteString(" (")
+		FormatNode(buf, f, node.ColumnNames)
+		buf.WriteByte(')')
+	}
+	buf.WriteString(" AS ")
+	FormatNode(buf, f, node.AsSource)
+}
+
+// CreateSequence represents a CREATE SEQUENCE statement.
+type CreateSequence struct {
+	IfNotExists bool
+	Name        NormalizableTableName
+	Options     SequenceOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateSequence) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE SEQUENCE ")
+	if node.IfNotExists {
+		buf.WriteString("IF NOT EXISTS ")
+	}
+	FormatNode(buf, f, node.Name)
+	FormatNode(buf, f, node.Options)
+}
+
+// Names of the options of a CREATE SEQUENCE statement.
+const (
+	SeqOptIncrement = "INCREMENT"
+	SeqOptMinValue  = "MINVALUE"
+	SeqOptMaxValue  = "MAXVALUE"
+	SeqOptStart     = "START"
+)
+
+// SequenceOption represents an option of a CREATE SEQUENCE statement.
+type SequenceOption struct {
+	Name string
+	// IntVal is nil for NO MINVALUE and NO MAXVALUE.
+	IntVal *int64
+}
+
+// SequenceOptions represents a list of sequence options.
+type SequenceOptions []SequenceOption
+
+// Format implements the NodeFormatter interface.
+func (node SequenceOptions) Format(buf *bytes.Buffer, f FmtFlags) {
+	for _, opt := range node {
+		buf.WriteByte(' ')
+		if opt.IntVal == nil {
+			buf.WriteString("NO ")
+			buf.WriteString(opt.Name)
+			continue
+		}
+		buf.WriteString(opt.Name)
+		switch opt.Name {
+		case SeqOptIncrement:
+			buf.WriteString(" BY")
+		case SeqOptStart:
+			buf.WriteString(" WITH")
+		}
+		fmt.Fprintf(buf, " %d", *opt.IntVal)
+	}
+}
+
+// CreateStats represents a CREATE STATISTICS statement.
+type CreateStats struct {
+	Name        Name
+	ColumnNames NameList
+	Table       NormalizableTableName
+}
+
+// Format implements the NodeFormatter interface.
+func (node *CreateStats) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("CREATE STATISTICS ")
+	FormatNode(buf, f, node.Name)
+	if len(node.ColumnNames) > 0 {
+		buf.WriteString(" ON ")
+		FormatNode(buf, f, node.ColumnNames)
+	}
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, node.Table)
 }
@@ -20,15 +20,22 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"math/big"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unsafe"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
 
 	"gopkg.in/inf.v0"
 
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/duration"
+	"github.com/cockroachdb/cockroach/util/ipaddr"
+	"github.com/cockroachdb/cockroach/util/jsonb"
 )
 
 var (
@@ -82,6 +89,10 @@ type Datum interface {
 	// IsMin returns true if the datum is equal to the minimum value the datum
 	// type can hold.
 	IsMin() bool
+	// Size returns a lower bound on the total size of the receiver in bytes,
+	// including memory that is pointed at (even if shared between Datum
+	// instances) but excluding allocation overhead.
+	Size() uintptr
 }
 
 // DBool is the boolean Datum.
@@ -194,6 +205,11 @@ func (d *DBool) IsMin() bool {
 	return !bool(*d)
 }
 
+// Size implements the Datum interface.
+func (d *DBool) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DBool) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(strconv.FormatBool(bool(*d)))
@@ -286,6 +302,11 @@ func (d *DInt) IsMin() bool {
 	return *d == math.MinInt64
 }
 
+// Size implements the Datum interface.
+func (d *DInt) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DInt) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(strconv.FormatInt(int64(*d), 10))
@@ -381,6 +402,11 @@ func (d *DFloat) IsMin() bool {
 	return *d <= -math.MaxFloat64
 }
 
+// Size implements the Datum interface.
+func (d *DFloat) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DFloat) Format(buf *bytes.Buffer, f FmtFlags) {
 	fl := float64(*d)
@@ -470,6 +496,12 @@ func (*DDecimal) IsMin() bool {
 	return false
 }
 
+// Size implements the Datum interface.
+func (d *DDecimal) Size() uintptr {
+	intVal := d.UnscaledBig()
+	return unsafe.Sizeof(*d) + uintptr(cap(intVal.Bits()))*unsafe.Sizeof(big.Word(0))
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DDecimal) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(d.Dec.String())
@@ -550,6 +582,11 @@ func (d *DString) IsMin() bool {
 	return len(*d) == 0
 }
 
+// Size implements the Datum interface.
+func (d *DString) Size() uintptr {
+	return unsafe.Sizeof(*d) + uintptr(len(*d))
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DString) Format(buf *bytes.Buffer, f FmtFlags) {
 	encodeSQLString(buf, string(*d))
@@ -630,6 +667,11 @@ func (d *DBytes) IsMin() bool {
 	return len(*d) == 0
 }
 
+// Size implements the Datum interface.
+func (d *DBytes) Size() uintptr {
+	return unsafe.Sizeof(*d) + uintptr(len(*d))
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DBytes) Format(buf *bytes.Buffer, f FmtFlags) {
 	encodeSQLBytes(buf, string(*d))
@@ -751,6 +793,11 @@ func (d *DDate) IsMin() bool {
 	return *d == math.MinInt64
 }
 
+// Size implements the Datum interface.
+func (d *DDate) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DDate) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(time.Unix(int64(*d)*secondsInDay, 0).UTC().Format(dateFormat))
@@ -872,6 +919,11 @@ func (d *DTimestamp) IsMin() bool {
 	return d.Before(d.Add(-1))
 }
 
+// Size implements the Datum interface.
+func (d *DTimestamp) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DTimestamp) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(d.UTC().Format(timestampNodeFormat))
@@ -966,6 +1018,11 @@ func (d *DTimestampTZ) IsMin() bool {
 	return d.Before(d.Add(-1))
 }
 
+// Size implements the Datum interface.
+func (d *DTimestampTZ) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DTimestampTZ) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(d.UTC().Format(timestampNodeFormat))
@@ -1075,6 +1132,11 @@ func (d *DInterval) IsMin() bool {
 	return d.Months == math.MinInt64 && d.Days == math.MinInt64 && d.Nanos == math.MinInt64
 }
 
+// Size implements the Datum interface.
+func (d *DInterval) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DInterval) Format(buf *bytes.Buffer, f FmtFlags) {
 	if d.Months != 0 || d.Days != 0 {
@@ -1205,6 +1267,15 @@ func (*DTuple) IsMin() bool {
 	return false
 }
 
+// Size implements the Datum interface.
+func (d *DTuple) Size() uintptr {
+	sz := unsafe.Sizeof(*d)
+	for _, e := range *d {
+		sz += e.Size()
+	}
+	return sz
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DTuple) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteByte('(')
@@ -1270,6 +1341,430 @@ func (d *DTuple) SortedDifference(other *DTuple) *DTuple {
 	return &r
 }
 
+// DArray is the array Datum. Any Datum inserted into a DArray must be of
+// type ParamTyp, or be NULL.
+type DArray struct {
+	ParamTyp Datum
+	Array    DTuple
+}
+
+// NewDArray returns a DArray containing elements of the specified type.
+func NewDArray(paramTyp Datum) *DArray {
+	return &DArray{ParamTyp: paramTyp}
+}
+
+// ReturnType implements the TypedExpr interface.
+func (d *DArray) ReturnType() Datum {
+	return d
+}
+
+// Type implements the Datum interface.
+func (d *DArray) Type() string {
+	return d.ParamTyp.Type() + "[]"
+}
+
+// TypeEqual implements the Datum interface.
+func (d *DArray) TypeEqual(other Datum) bool {
+	t, ok := other.(*DArray)
+	if !ok {
+		return false
+	}
+	return d.ParamTyp.TypeEqual(t.ParamTyp)
+}
+
+// Compare implements the Datum interface. Arrays are compared element by
+// element, NULL elements sorting before all other values, and a shorter array
+// sorting before any array of which it is a prefix.
+func (d *DArray) Compare(other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := other.(*DArray)
+	if !ok {
+		panic(fmt.Sprintf("unsupported comparison: %s to %s", d.Type(), other.Type()))
+	}
+	return d.Array.Compare(&v.Array)
+}
+
+// HasPrev implements the Datum interface.
+func (*DArray) HasPrev() bool {
+	return false
+}
+
+// Prev implements the Datum interface.
+func (d *DArray) Prev() Datum {
+	panic(d.Type() + ".Prev not supported")
+}
+
+// HasNext implements the Datum interface.
+func (*DArray) HasNext() bool {
+	return true
+}
+
+// Next implements the Datum interface. The next array is the current array
+// with a NULL element appended, as NULL sorts before all other values.
+func (d *DArray) Next() Datum {
+	a := &DArray{ParamTyp: d.ParamTyp, Array: make(DTuple, len(d.Array), len(d.Array)+1)}
+	copy(a.Array, d.Array)
+	a.Array = append(a.Array, DNull)
+	return a
+}
+
+// IsMax implements the Datum interface.
+func (*DArray) IsMax() bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DArray) IsMin() bool {
+	return len(d.Array) == 0
+}
+
+// Size implements the Datum interface.
+func (d *DArray) Size() uintptr {
+	return unsafe.Sizeof(*d) + d.Array.Size()
+}
+
+// Format implements the NodeFormatter interface.
+func (d *DArray) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ARRAY[")
+	for i, v := range d.Array {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, v)
+	}
+	buf.WriteByte(']')
+}
+
+// Len returns the length of the array.
+func (d *DArray) Len() int {
+	return len(d.Array)
+}
+
+// Append appends a Datum to the array, whose type must be either NULL or the
+// parameterized type of the array.
+func (d *DArray) Append(v Datum) error {
+	if v != DNull && !d.ParamTyp.TypeEqual(v) {
+		return fmt.Errorf("cannot append %s to array containing %s", v.Type(),
+			d.ParamTyp.Type())
+	}
+	d.Array = append(d.Array, v)
+	return nil
+}
+
+// DJSON is the JSON Datum, stored in JSONB columns. JSON holds the document
+// in the representation used by the jsonb package.
+type DJSON struct {
+	JSON interface{}
+}
+
+// NewDJSON is a helper routine to create a *DJSON initialized from its
+// argument.
+func NewDJSON(j interface{}) *DJSON {
+	return &DJSON{JSON: j}
+}
+
+// ParseDJSON parses and returns the *DJSON Datum value represented by the
+// provided string, or an error if parsing is unsuccessful.
+func ParseDJSON(s string) (*DJSON, error) {
+	j, err := jsonb.Parse(s)
+	if err != nil {
+		return nil, makeParseError(s, TypeJSON.Type(), err)
+	}
+	return NewDJSON(j), nil
+}
+
+// ReturnType implements the TypedExpr interface.
+func (*DJSON) ReturnType() Datum {
+	return TypeJSON
+}
+
+// Type implements the Datum interface.
+func (*DJSON) Type() string {
+	return "jsonb"
+}
+
+// TypeEqual implements the Datum interface.
+func (*DJSON) TypeEqual(other Datum) bool {
+	_, ok := other.(*DJSON)
+	return ok
+}
+
+// Compare implements the Datum interface.
+func (d *DJSON) Compare(other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := other.(*DJSON)
+	if !ok {
+		panic(fmt.Sprintf("unsupported comparison: %s to %s", d.Type(), other.Type()))
+	}
+	return jsonb.Compare(d.JSON, v.JSON)
+}
+
+// HasPrev implements the Datum interface.
+func (*DJSON) HasPrev() bool {
+	return false
+}
+
+// Prev implements the Datum interface.
+func (d *DJSON) Prev() Datum {
+	panic(d.Type() + ".Prev not supported")
+}
+
+// HasNext implements the Datum interface.
+func (*DJSON) HasNext() bool {
+	return false
+}
+
+// Next implements the Datum interface.
+func (d *DJSON) Next() Datum {
+	panic(d.Type() + ".Next not supported")
+}
+
+// IsMax implements the Datum interface.
+func (*DJSON) IsMax() bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DJSON) IsMin() bool {
+	return d.JSON == nil
+}
+
+// Size implements the Datum interface.
+func (d *DJSON) Size() uintptr {
+	// The size of the in-memory representation of a JSON value is
+	// approximated by the size of its text representation.
+	return unsafe.Sizeof(*d) + uintptr(len(d.String()))
+}
+
+// Format implements the NodeFormatter interface. The document is formatted
+// as a string literal.
+func (d *DJSON) Format(buf *bytes.Buffer, f FmtFlags) {
+	encodeSQLString(buf, jsonb.String(d.JSON))
+}
+
+// DIPAddr is the IP address Datum, stored in INET columns.
+type DIPAddr struct {
+	ipaddr.IPAddr
+}
+
+// NewDIPAddr is a helper routine to create a *DIPAddr initialized from its
+// argument.
+func NewDIPAddr(ip ipaddr.IPAddr) *DIPAddr {
+	return &DIPAddr{IPAddr: ip}
+}
+
+// ParseDIPAddr parses and returns the *DIPAddr Datum value represented by the
+// provided string, or an error if parsing is unsuccessful.
+func ParseDIPAddr(s string) (*DIPAddr, error) {
+	ip, err := ipaddr.Parse(s)
+	if err != nil {
+		return nil, makeParseError(s, TypeINet.Type(), err)
+	}
+	return NewDIPAddr(ip), nil
+}
+
+// ReturnType implements the TypedExpr interface.
+func (*DIPAddr) ReturnType() Datum {
+	return TypeINet
+}
+
+// Type implements the Datum interface.
+func (*DIPAddr) Type() string {
+	return "inet"
+}
+
+// TypeEqual implements the Datum interface.
+func (*DIPAddr) TypeEqual(other Datum) bool {
+	_, ok := other.(*DIPAddr)
+	return ok
+}
+
+// Compare implements the Datum interface.
+func (d *DIPAddr) Compare(other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := other.(*DIPAddr)
+	if !ok {
+		panic(fmt.Sprintf("unsupported comparison: %s to %s", d.Type(), other.Type()))
+	}
+	return d.IPAddr.Compare(v.IPAddr)
+}
+
+// HasPrev implements the Datum interface.
+func (*DIPAddr) HasPrev() bool {
+	return false
+}
+
+// Prev implements the Datum interface.
+func (d *DIPAddr) Prev() Datum {
+	panic(d.Type() + ".Prev not supported")
+}
+
+// HasNext implements the Datum interface.
+func (*DIPAddr) HasNext() bool {
+	return false
+}
+
+// Next implements the Datum interface.
+func (d *DIPAddr) Next() Datum {
+	panic(d.Type() + ".Next not supported")
+}
+
+// IsMax implements the Datum interface.
+func (*DIPAddr) IsMax() bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DIPAddr) IsMin() bool {
+	return d.Family == ipaddr.IPv4Family && d.Mask == 0 && d.Addr.IsUnspecified()
+}
+
+// Size implements the Datum interface.
+func (d *DIPAddr) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
+// Format implements the NodeFormatter interface. The address is formatted
+// as a string literal.
+func (d *DIPAddr) Format(buf *bytes.Buffer, f FmtFlags) {
+	encodeSQLString(buf, d.IPAddr.String())
+}
+
+// DCollatedString is the Datum for strings with a locale. Collated strings
+// are ordered by the collation key of their contents, as defined by the
+// locale, and then by their contents.
+type DCollatedString struct {
+	Contents string
+	// Locale is empty only for TypeCollatedString, where it stands for any
+	// locale.
+	Locale string
+	// Key is the collation key of Contents.
+	Key []byte
+}
+
+// CollationEnvironment stores the state needed by NewDCollatedString to
+// construct collation keys efficiently. The zero value is ready to use.
+type CollationEnvironment struct {
+	collators map[string]*collate.Collator
+	buffer    *collate.Buffer
+}
+
+func (env *CollationEnvironment) getCollator(locale string) *collate.Collator {
+	if env.collators == nil {
+		env.collators = make(map[string]*collate.Collator)
+		env.buffer = &collate.Buffer{}
+	}
+	c, ok := env.collators[locale]
+	if !ok {
+		c = collate.New(language.MustParse(locale))
+		env.collators[locale] = c
+	}
+	return c
+}
+
+// ValidateLocale returns an error if the given locale is not one that
+// collated strings can use.
+func ValidateLocale(locale string) error {
+	if _, err := language.Parse(locale); err != nil {
+		return fmt.Errorf("invalid locale %s: %v", locale, err)
+	}
+	return nil
+}
+
+// NewDCollatedString is a helper routine to create a *DCollatedString. The
+// locale must have been checked with ValidateLocale.
+func NewDCollatedString(contents, locale string, env *CollationEnvironment) *DCollatedString {
+	key := env.getCollator(locale).KeyFromString(env.buffer, contents)
+	d := &DCollatedString{Contents: contents, Locale: locale, Key: append([]byte(nil), key...)}
+	env.buffer.Reset()
+	return d
+}
+
+// ReturnType implements the TypedExpr interface.
+func (d *DCollatedString) ReturnType() Datum {
+	return &DCollatedString{Locale: d.Locale}
+}
+
+// Type implements the Datum interface.
+func (d *DCollatedString) Type() string {
+	return "collatedstring{" + d.Locale + "}"
+}
+
+// TypeEqual implements the Datum interface. Collated strings only have the
+// same type if they have the same locale, unless one of them is
+// TypeCollatedString.
+func (d *DCollatedString) TypeEqual(other Datum) bool {
+	o, ok := other.(*DCollatedString)
+	return ok && (d.Locale == o.Locale || d.Locale == "" || o.Locale == "")
+}
+
+// Compare implements the Datum interface.
+func (d *DCollatedString) Compare(other Datum) int {
+	if other == DNull {
+		// NULL is less than any non-NULL value.
+		return 1
+	}
+	v, ok := other.(*DCollatedString)
+	if !ok || d.Locale != v.Locale {
+		panic(fmt.Sprintf("unsupported comparison: %s to %s", d.Type(), other.Type()))
+	}
+	if c := bytes.Compare(d.Key, v.Key); c != 0 {
+		return c
+	}
+	return strings.Compare(d.Contents, v.Contents)
+}
+
+// HasPrev implements the Datum interface.
+func (*DCollatedString) HasPrev() bool {
+	return false
+}
+
+// Prev implements the Datum interface.
+func (d *DCollatedString) Prev() Datum {
+	panic(d.Type() + ".Prev not supported")
+}
+
+// HasNext implements the Datum interface.
+func (*DCollatedString) HasNext() bool {
+	return false
+}
+
+// Next implements the Datum interface.
+func (d *DCollatedString) Next() Datum {
+	panic(d.Type() + ".Next not supported")
+}
+
+// IsMax implements the Datum interface.
+func (*DCollatedString) IsMax() bool {
+	return false
+}
+
+// IsMin implements the Datum interface.
+func (d *DCollatedString) IsMin() bool {
+	return d.Contents == ""
+}
+
+// Size implements the Datum interface.
+func (d *DCollatedString) Size() uintptr {
+	return unsafe.Sizeof(*d) + uintptr(len(d.Contents)) + uintptr(len(d.Locale)) + uintptr(len(d.Key))
+}
+
+// Format implements the NodeFormatter interface.
+func (d *DCollatedString) Format(buf *bytes.Buffer, f FmtFlags) {
+	encodeSQLString(buf, d.Contents)
+	buf.WriteString(" COLLATE ")
+	FormatNode(buf, f, Name(d.Locale))
+}
+
 type dNull struct{}
 
 // ReturnType implements the TypedExpr interface.
@@ -1326,6 +1821,11 @@ func (dNull) IsMin() bool {
 	return true
 }
 
+// Size implements the Datum interface.
+func (d dNull) Size() uintptr {
+	return unsafe.Sizeof(d)
+}
+
 // Format implements the NodeFormatter interface.
 func (dNull) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("NULL")
@@ -1401,6 +1901,11 @@ func (*DPlaceholder) IsMin() bool {
 	return true
 }
 
+// Size implements the Datum interface.
+func (d *DPlaceholder) Size() uintptr {
+	return unsafe.Sizeof(*d)
+}
+
 // Format implements the NodeFormatter interface.
 func (d *DPlaceholder) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteByte('$')
@@ -34,6 +34,7 @@ import (
 	"github.com/cockroachdb/cockroach/util/decimal"
 	"github.com/cockroachdb/cockroach/util/duration"
 	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/jsonb"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/pkg/errors"
 )
@@ -782,6 +783,16 @@ var BinOps = map[BinaryOperator]binOpOverload{
 				return NewDInt(*left.(*DInt) << uint(*right.(*DInt))), nil
 			},
 		},
+		// For addresses, << tests whether the left address is strictly
+		// contained within the network of the right one.
+		BinOp{
+			LeftType:   TypeINet,
+			RightType:  TypeINet,
+			ReturnType: TypeBool,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				return MakeDBool(DBool(left.(*DIPAddr).ContainedBy(right.(*DIPAddr).IPAddr))), nil
+			},
+		},
 	},
 
 	RShift: {
@@ -793,9 +804,86 @@ var BinOps = map[BinaryOperator]binOpOverload{
 				return NewDInt(*left.(*DInt) >> uint(*right.(*DInt))), nil
 			},
 		},
+		// For addresses, >> tests whether the left network strictly contains
+		// the right address.
+		BinOp{
+			LeftType:   TypeINet,
+			RightType:  TypeINet,
+			ReturnType: TypeBool,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				return MakeDBool(DBool(left.(*DIPAddr).Contains(right.(*DIPAddr).IPAddr))), nil
+			},
+		},
+	},
+
+	JSONFetchVal: {
+		BinOp{
+			LeftType:   TypeJSON,
+			RightType:  TypeString,
+			ReturnType: TypeJSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j, ok := jsonb.FetchKey(left.(*DJSON).JSON, string(*right.(*DString)))
+				if !ok {
+					return DNull, nil
+				}
+				return NewDJSON(j), nil
+			},
+		},
+		BinOp{
+			LeftType:   TypeJSON,
+			RightType:  TypeInt,
+			ReturnType: TypeJSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j, ok := jsonb.FetchIndex(left.(*DJSON).JSON, int(*right.(*DInt)))
+				if !ok {
+					return DNull, nil
+				}
+				return NewDJSON(j), nil
+			},
+		},
+	},
+
+	JSONFetchText: {
+		BinOp{
+			LeftType:   TypeJSON,
+			RightType:  TypeString,
+			ReturnType: TypeString,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j, ok := jsonb.FetchKey(left.(*DJSON).JSON, string(*right.(*DString)))
+				if !ok {
+					return DNull, nil
+				}
+				return jsonAsText(j), nil
+			},
+		},
+		BinOp{
+			LeftType:   TypeJSON,
+			RightType:  TypeInt,
+			ReturnType: TypeString,
+			fn: func(_ *EvalContext, left Datum, right Datum) (Datum, error) {
+				j, ok := jsonb.FetchIndex(left.(*DJSON).JSON, int(*right.(*DInt)))
+				if !ok {
+					return DNull, nil
+				}
+				return jsonAsText(j), nil
+			},
+		},
 	},
 }
 
+// jsonAsText returns the text value of a JSON value as returned by the ->>
+// operator: strings are returned without quotes and the JSON null is returned
+// as NULL.
+func jsonAsText(j interface{}) Datum {
+	switch t := j.(type) {
+	case nil:
+		return DNull
+	case string:
+		return NewDString(t)
+	}
+	return NewDString(jsonb.String(j))
+}
+
 var timestampMinusBinOp BinOp
 
 func init() {
@@ -810,6 +898,26 @@ type CmpOp struct {
 	types     typeList
 }
 
+// arrayContains returns whether every element of the right array is equal to
+// some element of the left array. As in PostgreSQL, NULL elements are never
+// contained.
+func arrayContains(_ *EvalContext, left Datum, right Datum) (DBool, error) {
+	l, r := left.(*DArray), right.(*DArray)
+outer:
+	for _, re := range r.Array {
+		if re == DNull {
+			return false, nil
+		}
+		for _, le := range l.Array {
+			if le != DNull && le.Compare(re) == 0 {
+				continue outer
+			}
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
 func (op CmpOp) params() typeList {
 	return op.types
 }
@@ -823,6 +931,27 @@ func (op CmpOp) returnType() Datum {
 }
 
 func init() {
+	// Arrays are comparable with arrays of the same element type, and contain
+	// the arrays of the same element type whose elements they each contain.
+	for _, t := range arrayParamTypes {
+		arr := NewDArray(t)
+		for _, op := range []ComparisonOperator{EQ, LT, LE} {
+			CmpOps[op] = append(CmpOps[op], makeCompareCmpOp(op, arr))
+		}
+		CmpOps[Contains] = append(CmpOps[Contains], CmpOp{
+			LeftType:  arr,
+			RightType: arr,
+			fn:        arrayContains,
+		})
+	}
+	// JSON documents, IP addresses and collated strings are comparable with
+	// each other. Collated strings must also have the same locale, which is
+	// checked during type checking.
+	for _, op := range []ComparisonOperator{EQ, LT, LE} {
+		CmpOps[op] = append(CmpOps[op], makeCompareCmpOp(op, TypeJSON))
+		CmpOps[op] = append(CmpOps[op], makeCompareCmpOp(op, TypeINet))
+		CmpOps[op] = append(CmpOps[op], makeCompareCmpOp(op, TypeCollatedString))
+	}
 	for op, overload := range CmpOps {
 		for i, impl := range overload {
 			impl.types = ArgTypes{impl.LeftType, impl.RightType}
@@ -1311,6 +1440,26 @@ var CmpOps = map[ComparisonOperator]cmpOpOverload{
 			},
 		},
 	},
+
+	Contains: {
+		CmpOp{
+			LeftType:  TypeJSON,
+			RightType: TypeJSON,
+			fn: func(_ *EvalContext, left Datum, right Datum) (DBool, error) {
+				return DBool(jsonb.Contains(left.(*DJSON).JSON, right.(*DJSON).JSON)), nil
+			},
+		},
+	},
+
+	JSONExists: {
+		CmpOp{
+			LeftType:  TypeJSON,
+			RightType: TypeString,
+			fn: func(_ *EvalContext, left Datum, right Datum) (DBool, error) {
+				return DBool(jsonb.Exists(left.(*DJSON).JSON, string(*right.(*DString)))), nil
+			},
+		},
+	},
 }
 
 var errCmpNull = errors.New("NULL comparison")
@@ -1331,6 +1480,26 @@ func cmpTuple(ldatum, rdatum Datum) (int, error) {
 	return 0, nil
 }
 
+// makeCompareCmpOp returns an EQ, LT or LE CmpOp for values of the given type
+// implemented using Datum.Compare.
+func makeCompareCmpOp(op ComparisonOperator, typ Datum) CmpOp {
+	return CmpOp{
+		LeftType:  typ,
+		RightType: typ,
+		fn: func(_ *EvalContext, left Datum, right Datum) (DBool, error) {
+			c := left.Compare(right)
+			switch op {
+			case EQ:
+				return DBool(c == 0), nil
+			case LT:
+				return DBool(c < 0), nil
+			default:
+				return DBool(c <= 0), nil
+			}
+		},
+	}
+}
+
 func makeEvalTupleIn(d Datum) CmpOp {
 	return CmpOp{
 		LeftType:  d,
@@ -1398,6 +1567,26 @@ type EvalContext struct {
 	// (false) or not (true).  It is set to true conditionally by
 	// EXPLAIN(TYPES[, NORMALIZE]).
 	SkipNormalize bool
+
+	collationEnv CollationEnvironment
+
+	// Sequence gives the sequence builtins access to the sequences of the
+	// database. It is nil when sequences cannot be accessed.
+	Sequence SequenceOperators
+}
+
+// SequenceOperators is the interface through which the nextval(), currval()
+// and setval() builtins operate on sequences, which are identified by name.
+type SequenceOperators interface {
+	// IncrementSequence increments the given sequence and returns its new
+	// value.
+	IncrementSequence(seqName string) (int64, error)
+	// GetLatestValueInSessionForSequence returns the value most recently
+	// obtained from the given sequence in the current session.
+	GetLatestValueInSessionForSequence(seqName string) (int64, error)
+	// SetSequenceValue sets the value of the given sequence. If isCalled is
+	// false, the next call to IncrementSequence returns newVal itself.
+	SetSequenceValue(seqName string, newVal int64, isCalled bool) error
 }
 
 // GetStmtTimestamp retrieves the current statement timestamp as per
@@ -1581,7 +1770,7 @@ func (expr *CastExpr) Eval(ctx *EvalContext) (Datum, error) {
 		return d, nil
 	}
 
-	switch expr.Type.(type) {
+	switch typ := expr.Type.(type) {
 	case *BoolColType:
 		switch v := d.(type) {
 		case *DBool:
@@ -1678,6 +1867,12 @@ func (expr *CastExpr) Eval(ctx *EvalContext) (Datum, error) {
 				return nil, fmt.Errorf("invalid utf8: %q", string(*t))
 			}
 			s = DString(*t)
+		case *DJSON:
+			s = DString(jsonb.String(t.JSON))
+		case *DIPAddr:
+			s = DString(t.IPAddr.String())
+		case *DCollatedString:
+			s = DString(t.Contents)
 		}
 		if c, ok := expr.Type.(*StringColType); ok {
 			// If the CHAR type specifies a limit we truncate to that limit:
@@ -1742,6 +1937,30 @@ func (expr *CastExpr) Eval(ctx *EvalContext) (Datum, error) {
 		case *DInterval:
 			return d, nil
 		}
+
+	case *ArrayColType:
+		switch v := d.(type) {
+		case *DString:
+			return ParseDArrayFromString(ctx, string(*v), typ.ParamType)
+		case *DArray:
+			return d, nil
+		}
+
+	case *JSONColType:
+		switch v := d.(type) {
+		case *DString:
+			return ParseDJSON(string(*v))
+		case *DJSON:
+			return d, nil
+		}
+
+	case *INetColType:
+		switch v := d.(type) {
+		case *DString:
+			return ParseDIPAddr(string(*v))
+		case *DIPAddr:
+			return d, nil
+		}
 	}
 
 	return nil, fmt.Errorf("invalid cast: %s -> %s", d.Type(), expr.Type)
@@ -1752,6 +1971,22 @@ func (expr *AnnotateTypeExpr) Eval(ctx *EvalContext) (Datum, error) {
 	return expr.Expr.(TypedExpr).Eval(ctx)
 }
 
+// Eval implements the TypedExpr interface.
+func (expr *CollateExpr) Eval(ctx *EvalContext) (Datum, error) {
+	d, err := expr.Expr.(TypedExpr).Eval(ctx)
+	if err != nil {
+		return DNull, err
+	}
+	switch t := d.(type) {
+	case *DString:
+		return NewDCollatedString(string(*t), expr.Locale, &ctx.collationEnv), nil
+	case *DCollatedString:
+		return NewDCollatedString(t.Contents, expr.Locale, &ctx.collationEnv), nil
+	default:
+		return DNull, nil
+	}
+}
+
 // Eval implements the TypedExpr interface.
 func (expr *CoalesceExpr) Eval(ctx *EvalContext) (Datum, error) {
 	for _, e := range expr.Exprs {
@@ -1777,6 +2012,10 @@ func (expr *ComparisonExpr) Eval(ctx *EvalContext) (Datum, error) {
 		return DNull, err
 	}
 
+	if expr.Operator.hasSubOperator() {
+		return evalComparisonExprWithSubOperator(ctx, expr, left, right)
+	}
+
 	if left == DNull || right == DNull {
 		switch expr.Operator {
 		case IsDistinctFrom:
@@ -1804,6 +2043,78 @@ func (expr *ComparisonExpr) Eval(ctx *EvalContext) (Datum, error) {
 	return MakeDBool(d), err
 }
 
+func evalComparisonExprWithSubOperator(
+	ctx *EvalContext, expr *ComparisonExpr, left, right Datum,
+) (Datum, error) {
+	var datums DTuple
+	switch d := right.(type) {
+	case *DArray:
+		datums = d.Array
+	case *DTuple:
+		datums = *d
+	default:
+		if right == DNull {
+			return DNull, nil
+		}
+		return nil, errors.Errorf("unhandled right expression %s", right)
+	}
+	return evalDatumsCmp(ctx, expr.Operator, expr.SubOperator, expr.fn, left, datums)
+}
+
+// evalDatumsCmp evaluates the comparison between the left Datum and each of
+// the right Datums using the provided sub-operator and its CmpOp, and returns
+// the result of the ANY/SOME/ALL predicate.
+//
+// A NULL result is returned if there exists a NULL element and:
+//   ANY/SOME: no comparisons evaluate to true
+//   ALL: no comparisons evaluate to false
+//
+// For example, given 1 < ANY (SELECT * FROM generate_series(1,3))
+// (right is a DTuple), evalDatumsCmp would be called with:
+//   evalDatumsCmp(ctx, Any, LT, ltFn, 1, DTuple{1, 2, 3})
+func evalDatumsCmp(
+	ctx *EvalContext, op, subOp ComparisonOperator, fn CmpOp, left Datum, right DTuple,
+) (Datum, error) {
+	all := op == All
+	any := !all
+	sawNull := false
+	for _, elem := range right {
+		if left == DNull || elem == DNull {
+			sawNull = true
+			continue
+		}
+
+		_, newLeft, newRight, _, not := foldComparisonExpr(subOp, left, elem)
+		d, err := fn.fn(ctx, newLeft.(Datum), newRight.(Datum))
+		if err == errCmpNull {
+			sawNull = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res := d != DBool(not)
+		if any && res {
+			return DBoolTrue, nil
+		} else if all && !res {
+			return DBoolFalse, nil
+		}
+	}
+
+	if sawNull {
+		// If the right-hand array contains any null elements and no [false,true]
+		// comparison result is obtained, the result of [ALL,ANY] will be null.
+		return DNull, nil
+	}
+
+	if all {
+		// ALL are true && !sawNull
+		return DBoolTrue, nil
+	}
+	// ANY is false && !sawNull
+	return DBoolFalse, nil
+}
+
 // Eval implements the TypedExpr interface.
 func (t *ExistsExpr) Eval(ctx *EvalContext) (Datum, error) {
 	// Exists expressions are handled during subquery expansion.
@@ -1812,6 +2123,13 @@ func (t *ExistsExpr) Eval(ctx *EvalContext) (Datum, error) {
 
 // Eval implements the TypedExpr interface.
 func (expr *FuncExpr) Eval(ctx *EvalContext) (Datum, error) {
+	if expr.fn.AggregateFunc != nil || expr.fn.WindowFunc != nil || expr.fn.Generator != nil {
+		// Aggregate and window functions are computed by their respective
+		// plan nodes, and generators by the data sources they are used as, so
+		// none of them can be evaluated on their own.
+		return nil, fmt.Errorf("%s: cannot be evaluated in this context", expr.Name)
+	}
+
 	args := make(DTuple, 0, len(expr.Exprs))
 	for _, e := range expr.Exprs {
 		arg, err := e.(TypedExpr).Eval(ctx)
@@ -2068,6 +2386,56 @@ func (expr *ColumnItem) Eval(ctx *EvalContext) (Datum, error) {
 	return nil, errors.Errorf("unhandled type %T", expr)
 }
 
+// Eval implements the TypedExpr interface.
+func (t *Array) Eval(ctx *EvalContext) (Datum, error) {
+	array := NewDArray(t.ReturnType().(*DArray).ParamTyp)
+	for _, v := range t.Exprs {
+		d, err := v.(TypedExpr).Eval(ctx)
+		if err != nil {
+			return DNull, err
+		}
+		if err := array.Append(d); err != nil {
+			return DNull, err
+		}
+	}
+	return array, nil
+}
+
+// Eval implements the TypedExpr interface.
+func (expr *IndirectionExpr) Eval(ctx *EvalContext) (Datum, error) {
+	var subscriptIdx int
+	for i, t := range expr.Indirection {
+		if t.End != nil || i > 0 {
+			return nil, errors.Errorf("unsupported feature should have been rejected during planning")
+		}
+
+		d, err := t.Begin.(TypedExpr).Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if d == DNull {
+			return d, nil
+		}
+		subscriptIdx = int(*d.(*DInt))
+	}
+
+	d, err := expr.Expr.(TypedExpr).Eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if d == DNull {
+		return d, nil
+	}
+
+	// Index into the DArray, using 1-indexing. Out of range subscripts
+	// evaluate to NULL.
+	arr := d.(*DArray)
+	if subscriptIdx < 1 || subscriptIdx > arr.Len() {
+		return DNull, nil
+	}
+	return arr.Array[subscriptIdx-1], nil
+}
+
 // Eval implements the TypedExpr interface.
 func (t *Tuple) Eval(ctx *EvalContext) (Datum, error) {
 	tuple := make(DTuple, 0, len(t.Exprs))
@@ -2141,6 +2509,26 @@ func (t *DTuple) Eval(_ *EvalContext) (Datum, error) {
 	return t, nil
 }
 
+// Eval implements the TypedExpr interface.
+func (t *DArray) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
+// Eval implements the TypedExpr interface.
+func (t *DJSON) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
+// Eval implements the TypedExpr interface.
+func (t *DIPAddr) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
+// Eval implements the TypedExpr interface.
+func (t *DCollatedString) Eval(_ *EvalContext) (Datum, error) {
+	return t, nil
+}
+
 // Eval implements the TypedExpr interface.
 func (t *DPlaceholder) Eval(_ *EvalContext) (Datum, error) {
 	return t, fmt.Errorf("no value provided for placeholder: $%s", t.name)
@@ -539,6 +539,64 @@ func TestEval(t *testing.T) {
 		{`'NaN'::float(4)`, `NaN`},
 		{`'NaN'::real`, `NaN`},
 		{`'NaN'::double precision`, `NaN`},
+		// Arrays
+		{`ARRAY[1, 2, 3]`, `ARRAY[1, 2, 3]`},
+		{`ARRAY['a', NULL]`, `ARRAY['a', NULL]`},
+		{`'{1, 2, NULL}'::int[]`, `ARRAY[1, 2, NULL]`},
+		{`'{"a b", c}'::string[]`, `ARRAY['a b', 'c']`},
+		{`'{}'::int[]`, `ARRAY[]`},
+		{`(ARRAY[1, 2, 3])[1]`, `1`},
+		{`(ARRAY[1, 2, 3])[3]`, `3`},
+		{`(ARRAY[1, 2, 3])[4]`, `NULL`},
+		{`(ARRAY[1, 2, 3])[NULL]`, `NULL`},
+		{`ARRAY[1, 2] < ARRAY[1, 3]`, `true`},
+		{`ARRAY[1, 2, 3] @> ARRAY[3, 1, 3]`, `true`},
+		{`ARRAY[1, 2] @> ARRAY[1, 4]`, `false`},
+		{`ARRAY[1, NULL] @> ARRAY[1, NULL]`, `false`},
+		{`ARRAY['a'] @> '{}'::string[]`, `true`},
+		{`ARRAY[1, 2] = ARRAY[1, 2]`, `true`},
+		{`1 = ANY (ARRAY[1, 2])`, `true`},
+		{`3 = ANY (ARRAY[1, 2])`, `false`},
+		{`3 = ANY (ARRAY[1, NULL])`, `NULL`},
+		{`1 < ALL (ARRAY[2, 3])`, `true`},
+		{`2 < ALL (ARRAY[2, 3])`, `false`},
+		{`1 < ALL (ARRAY[2, NULL])`, `NULL`},
+		{`NULL = ANY (ARRAY[1, 2])`, `NULL`},
+		{`'foo' LIKE ANY (ARRAY['f%', 'g%'])`, `true`},
+		{`'foo' NOT LIKE ALL (ARRAY['f%', 'g%'])`, `false`},
+		{`array_length(ARRAY[1, 2, 3], 1)`, `3`},
+		{`array_length(ARRAY[1, 2, 3], 2)`, `NULL`},
+		{`array_lower(ARRAY[1, 2, 3], 1)`, `1`},
+		{`array_upper(ARRAY[1, 2, 3], 1)`, `3`},
+		// JSON operators and functions.
+		{`'{"b": 1, "a": [2]}'::jsonb`, `'{"a": [2], "b": 1}'`},
+		{`'{"a": [2]}'::jsonb->'a'`, `'[2]'`},
+		{`'{"a": "x"}'::jsonb->>'a'`, `'x'`},
+		{`'{"a": null}'::jsonb->>'a'`, `NULL`},
+		{`'[1, 2]'::jsonb->-1`, `'2'`},
+		{`'[1, 2]'::jsonb->2`, `NULL`},
+		{`'{"a": [1, 2]}'::jsonb @> '{"a": [2]}'`, `true`},
+		{`'{"a": 1}'::jsonb ? 'b'`, `false`},
+		{`'1'::jsonb < '"a"'::jsonb`, `false`},
+		{`jsonb_typeof('[1]')`, `'array'`},
+		{`jsonb_build_object('a', 1, 'b', 'c')`, `'{"a": 1, "b": "c"}'`},
+		{`to_jsonb(1.5)`, `'1.5'`},
+		// IP address operators and functions.
+		{`'10.0.0.1/32'::inet`, `'10.0.0.1'`},
+		{`'2001:DB8::1/64'::inet`, `'2001:db8::1/64'`},
+		{`'10.1.2.3'::inet << '10.0.0.0/8'::inet`, `true`},
+		{`'10.0.0.0/8'::inet >> '10.0.0.0/8'::inet`, `false`},
+		{`'10.0.0.0/8'::inet < '10.0.0.0'::inet`, `true`},
+		{`'255.255.255.255'::inet < '::'::inet`, `true`},
+		{`family('::1'::inet)`, `6`},
+		{`host('192.168.0.1/24'::inet)`, `'192.168.0.1'`},
+		{`masklen('192.168.0.1/24'::inet)`, `24`},
+		// Collated strings.
+		{`'a' COLLATE de`, `'a' COLLATE de`},
+		{`('a' COLLATE en) < ('B' COLLATE en)`, `true`},
+		{`('ä' COLLATE de) < ('z' COLLATE de)`, `true`},
+		{`('ä' COLLATE sv) < ('z' COLLATE sv)`, `false`},
+		{`('x' COLLATE de)::string`, `'x'`},
 	}
 	for _, d := range testData {
 		expr, err := ParseExprTraditional(d.expr)
@@ -593,6 +651,14 @@ func TestEvalError(t *testing.T) {
 		{`ANNOTATE_TYPE(ANNOTATE_TYPE(1, int), decimal)`,
 			`incompatible type assertion for ANNOTATE_TYPE(1, INT) as decimal, found type: int`},
 		{`b'\xff\xfe\xfd'::string`, `invalid utf8: "\xff\xfe\xfd"`},
+		{`'1, 2'::int[]`,
+			`could not parse '1, 2' as type int[]: array must be enclosed in { and }`},
+		{`'{1, 2'::int[]`,
+			`could not parse '{1, 2' as type int[]: array must be enclosed in { and }`},
+		{`'{1, 2} 3'::int[]`,
+			`could not parse '{1, 2} 3' as type int[]: extra text after closing right brace`},
+		{`'{a}'::int[]`,
+			`could not parse '{a}' as type int[]: could not parse 'a' as type int`},
 		{`'' LIKE ` + string([]byte{0x27, 0xc2, 0x30, 0x7a, 0xd5, 0x25, 0x30, 0x27}),
 			`LIKE regexp compilation failed: error parsing regexp: invalid UTF-8: .*`},
 		// TODO(pmattis): Check for overflow.
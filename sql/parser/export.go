@@ -0,0 +1,42 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// Export represents an EXPORT statement, which writes the results of a query
+// to a set of files.
+type Export struct {
+	Query *Select
+	// FileFormat is the format of the files, e.g. CSV.
+	FileFormat string
+	// File is the destination the files are written to.
+	File    string
+	Options KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Export) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("EXPORT INTO ")
+	buf.WriteString(node.FileFormat)
+	buf.WriteByte(' ')
+	encodeSQLString(buf, node.File)
+	if len(node.Options) > 0 {
+		buf.WriteString(" WITH ")
+		FormatNode(buf, f, node.Options)
+	}
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, node.Query)
+}
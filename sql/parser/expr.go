@@ -271,6 +271,25 @@ const (
 	IsNotDistinctFrom
 	Is
 	IsNot
+	Contains
+	JSONExists
+
+	// The following operators will always be used with an associated SubOperator.
+	// If Go had algebraic data types they would be defined in a self-contained
+	// manner like:
+	//
+	// Any(ComparisonOperator)
+	// Some(ComparisonOperator)
+	// ...
+	//
+	// where the internal ComparisonOperator qualifies the behavior of the primary
+	// operator. Instead, a secondary ComparisonOperator is optionally included in
+	// ComparisonExpr for the cases where these operators are the primary op.
+	//
+	// ComparisonOperator.hasSubOperator returns true for ops in this group.
+	Any
+	Some
+	All
 )
 
 var comparisonOpName = [...]string{
@@ -296,6 +315,11 @@ var comparisonOpName = [...]string{
 	IsNotDistinctFrom: "IS NOT DISTINCT FROM",
 	Is:                "IS",
 	IsNot:             "IS NOT",
+	Contains:          "@>",
+	JSONExists:        "?",
+	Any:               "ANY",
+	Some:              "SOME",
+	All:               "ALL",
 }
 
 func (i ComparisonOperator) String() string {
@@ -305,9 +329,22 @@ func (i ComparisonOperator) String() string {
 	return comparisonOpName[i]
 }
 
+// hasSubOperator returns if the ComparisonOperator is used with a sub-operator.
+func (i ComparisonOperator) hasSubOperator() bool {
+	switch i {
+	case Any:
+	case Some:
+	case All:
+	default:
+		return false
+	}
+	return true
+}
+
 // ComparisonExpr represents a two-value comparison expression.
 type ComparisonExpr struct {
 	Operator    ComparisonOperator
+	SubOperator ComparisonOperator // used for array operators (when Operator is Any, Some, or All)
 	Left, Right Expr
 
 	typeAnnotation
@@ -318,7 +355,35 @@ func (*ComparisonExpr) operatorExpr() {}
 
 // Format implements the NodeFormatter interface.
 func (node *ComparisonExpr) Format(buf *bytes.Buffer, f FmtFlags) {
-	binExprFmtWithParen(buf, f, node.Left, node.Operator.String(), node.Right)
+	if !node.Operator.hasSubOperator() {
+		binExprFmtWithParen(buf, f, node.Left, node.Operator.String(), node.Right)
+		return
+	}
+	exprFmtWithParen(buf, f, node.Left)
+	buf.WriteByte(' ')
+	buf.WriteString(node.SubOperator.String())
+	buf.WriteByte(' ')
+	buf.WriteString(node.Operator.String())
+	buf.WriteByte(' ')
+	switch node.Right.(type) {
+	case *Subquery, *DTuple:
+		// Subqueries and their results are already parenthesized.
+		FormatNode(buf, f, node.Right)
+	default:
+		buf.WriteByte('(')
+		FormatNode(buf, f, node.Right)
+		buf.WriteByte(')')
+	}
+}
+
+// NewTypedComparisonExprWithSubOp returns a new ComparisonExpr that is verified to be well-typed.
+func NewTypedComparisonExprWithSubOp(
+	op, subOp ComparisonOperator, left, right TypedExpr,
+) *ComparisonExpr {
+	node := &ComparisonExpr{Operator: op, SubOperator: subOp, Left: left, Right: right}
+	node.typ = TypeBool
+	node.memoizeFn()
+	return node
 }
 
 // NewTypedComparisonExpr returns a new ComparisonExpr that is verified to be well-typed.
@@ -336,6 +401,16 @@ func (node *ComparisonExpr) memoizeFn() {
 	}
 	fOp, fLeft, fRight, _, _ := foldComparisonExpr(node.Operator, node.Left, node.Right)
 	leftRet, rightRet := fLeft.(TypedExpr).ReturnType(), fRight.(TypedExpr).ReturnType()
+	if node.Operator.hasSubOperator() {
+		// Array operators memoize the SubOperator's CmpOp, which compares the
+		// left operand to each element of the right operand.
+		var switched bool
+		fOp, _, _, switched, _ = foldComparisonExpr(node.SubOperator, nil, nil)
+		rightRet, _ = subOperandElemType(rightRet)
+		if switched {
+			leftRet, rightRet = rightRet, leftRet
+		}
+	}
 	fn, ok := CmpOps[fOp].lookupImpl(leftRet, rightRet)
 	if !ok {
 		panic(fmt.Sprintf("lookup for ComparisonExpr %s's CmpOp failed",
@@ -366,6 +441,13 @@ func (node *ComparisonExpr) IsMixedTypeComparison() bool {
 			}
 		}
 		return false
+	case Any, Some, All:
+		leftType := node.TypedLeft().ReturnType()
+		elemType, _ := subOperandElemType(node.TypedRight().ReturnType())
+		if leftType == DNull || elemType == DNull {
+			return false
+		}
+		return !leftType.TypeEqual(elemType)
 	default:
 		return !sameTypeExprs(node.TypedLeft(), node.TypedRight())
 	}
@@ -587,6 +669,8 @@ func (node *Tuple) ReturnType() Datum {
 // Array represents an array constructor.
 type Array struct {
 	Exprs Exprs
+
+	typeAnnotation
 }
 
 // Format implements the NodeFormatter interface.
@@ -596,6 +680,37 @@ func (node *Array) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteByte(']')
 }
 
+// ArraySubscripts represents a sequence of one or more array subscripts.
+type ArraySubscripts []*ArraySubscript
+
+// Format implements the NodeFormatter interface.
+func (a ArraySubscripts) Format(buf *bytes.Buffer, f FmtFlags) {
+	for _, s := range a {
+		FormatNode(buf, f, s)
+	}
+}
+
+// IndirectionExpr represents a subscript expression.
+type IndirectionExpr struct {
+	Expr        Expr
+	Indirection ArraySubscripts
+
+	typeAnnotation
+}
+
+// Format implements the NodeFormatter interface.
+func (node *IndirectionExpr) Format(buf *bytes.Buffer, f FmtFlags) {
+	switch node.Expr.(type) {
+	case *ParenExpr, UnresolvedName, *ColumnItem, *IndexedVar:
+		FormatNode(buf, f, node.Expr)
+	default:
+		buf.WriteByte('(')
+		FormatNode(buf, f, node.Expr)
+		buf.WriteByte(')')
+	}
+	FormatNode(buf, f, node.Indirection)
+}
+
 // Exprs represents a list of value expressions. It's not a valid expression
 // because it's not parenthesized.
 type Exprs []Expr
@@ -659,6 +774,8 @@ const (
 	Concat
 	LShift
 	RShift
+	JSONFetchVal
+	JSONFetchText
 )
 
 var binaryOpName = [...]string{
@@ -674,6 +791,9 @@ var binaryOpName = [...]string{
 	Concat:   "||",
 	LShift:   "<<",
 	RShift:   ">>",
+
+	JSONFetchVal:  "->",
+	JSONFetchText: "->>",
 }
 
 func (i BinaryOperator) String() string {
@@ -789,6 +909,9 @@ type FuncExpr struct {
 	Name  NormalizableFunctionName
 	Type  funcType
 	Exprs Exprs
+	// WindowDef is set when the function is applied as a window function,
+	// i.e. with an OVER clause.
+	WindowDef *WindowDef
 
 	typeAnnotation
 	fn Builtin
@@ -799,13 +922,13 @@ type funcType int
 // FuncExpr.Type
 const (
 	_ funcType = iota
-	Distinct
-	All
+	DistinctFuncType
+	AllFuncType
 )
 
 var funcTypeName = [...]string{
-	Distinct: "DISTINCT",
-	All:      "ALL",
+	DistinctFuncType: "DISTINCT",
+	AllFuncType:      "ALL",
 }
 
 // Format implements the NodeFormatter interface.
@@ -819,6 +942,40 @@ func (node *FuncExpr) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(typ)
 	FormatNode(buf, f, node.Exprs)
 	buf.WriteByte(')')
+	if window := node.WindowDef; window != nil {
+		buf.WriteString(" OVER ")
+		if window.Name != "" {
+			FormatNode(buf, f, window.Name)
+		} else {
+			FormatNode(buf, f, window)
+		}
+	}
+}
+
+// IsWindowFunctionApplication returns whether the function is applied as a
+// window function.
+func (node *FuncExpr) IsWindowFunctionApplication() bool {
+	return node.WindowDef != nil
+}
+
+// GetAggregateConstructor returns the constructor of the aggregate function
+// overload selected for the FuncExpr. The FuncExpr must have been type
+// checked.
+func (node *FuncExpr) GetAggregateConstructor() func() AggregateFunc {
+	return node.fn.AggregateFunc
+}
+
+// GetWindowConstructor returns a window function constructor if the
+// FuncExpr is a built-in window function, or a built-in aggregate function
+// applied as a window function. The FuncExpr must have been type checked.
+func (node *FuncExpr) GetWindowConstructor() func() WindowFunc {
+	if node.fn.AggregateFunc != nil {
+		aggregateFunc := node.fn.AggregateFunc
+		return func() WindowFunc {
+			return NewAggregateWindowFunc(aggregateFunc())
+		}
+	}
+	return node.fn.WindowFunc
 }
 
 // OverlayExpr represents an overlay function call.
@@ -906,15 +1063,17 @@ var (
 	intCastTypes       = []Datum{DNull, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString}
 	floatCastTypes     = []Datum{DNull, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString}
 	decimalCastTypes   = []Datum{DNull, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString}
-	stringCastTypes    = []Datum{DNull, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes, TypeTimestamp, TypeTimestampTZ}
+	stringCastTypes    = []Datum{DNull, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes, TypeTimestamp, TypeTimestampTZ, TypeJSON, TypeINet, TypeCollatedString}
 	bytesCastTypes     = []Datum{DNull, TypeString, TypeBytes}
 	dateCastTypes      = []Datum{DNull, TypeString, TypeDate, TypeTimestamp}
 	timestampCastTypes = []Datum{DNull, TypeString, TypeDate, TypeTimestamp, TypeTimestampTZ}
 	intervalCastTypes  = []Datum{DNull, TypeString, TypeInt, TypeInterval}
+	jsonCastTypes      = []Datum{DNull, TypeString, TypeJSON}
+	inetCastTypes      = []Datum{DNull, TypeString, TypeINet}
 )
 
 func colTypeToTypeAndValidArgTypes(t ColumnType) (Datum, []Datum) {
-	switch t.(type) {
+	switch ct := t.(type) {
 	case *BoolColType:
 		return TypeBool, boolCastTypes
 	case *IntColType:
@@ -935,6 +1094,17 @@ func colTypeToTypeAndValidArgTypes(t ColumnType) (Datum, []Datum) {
 		return TypeTimestampTZ, timestampCastTypes
 	case *IntervalColType:
 		return TypeInterval, intervalCastTypes
+	case *JSONColType:
+		return TypeJSON, jsonCastTypes
+	case *INetColType:
+		return TypeINet, inetCastTypes
+	case *ArrayColType:
+		paramTyp, _ := colTypeToTypeAndValidArgTypes(ct.ParamType)
+		if paramTyp == nil {
+			return nil, nil
+		}
+		typ := NewDArray(paramTyp)
+		return typ, []Datum{DNull, TypeString, typ}
 	}
 	return nil, nil
 }
@@ -970,6 +1140,23 @@ func (node *AnnotateTypeExpr) annotationType() Datum {
 	return typ
 }
 
+// CollateExpr represents an (expr COLLATE locale) expression.
+type CollateExpr struct {
+	Expr   Expr
+	Locale string
+
+	typeAnnotation
+}
+
+func (*CollateExpr) operatorExpr() {}
+
+// Format implements the NodeFormatter interface.
+func (node *CollateExpr) Format(buf *bytes.Buffer, f FmtFlags) {
+	exprFmtWithParen(buf, f, node.Expr)
+	buf.WriteString(" COLLATE ")
+	FormatNode(buf, f, Name(node.Locale))
+}
+
 func (node *AliasedTableExpr) String() string { return AsString(node) }
 func (node *ParenTableExpr) String() string   { return AsString(node) }
 func (node *JoinTableExpr) String() string    { return AsString(node) }
@@ -979,6 +1166,7 @@ func (node *BinaryExpr) String() string       { return AsString(node) }
 func (node *CaseExpr) String() string         { return AsString(node) }
 func (node *CastExpr) String() string         { return AsString(node) }
 func (node *CoalesceExpr) String() string     { return AsString(node) }
+func (node *CollateExpr) String() string      { return AsString(node) }
 func (node *ComparisonExpr) String() string   { return AsString(node) }
 func (node *DBool) String() string            { return AsString(node) }
 func (node *DBytes) String() string           { return AsString(node) }
@@ -991,12 +1179,17 @@ func (node *DString) String() string          { return AsString(node) }
 func (node *DTimestamp) String() string       { return AsString(node) }
 func (node *DTimestampTZ) String() string     { return AsString(node) }
 func (node *DTuple) String() string           { return AsString(node) }
+func (node *DArray) String() string           { return AsString(node) }
+func (node *DJSON) String() string            { return AsString(node) }
+func (node *DIPAddr) String() string          { return AsString(node) }
+func (node *DCollatedString) String() string  { return AsString(node) }
 func (node *DPlaceholder) String() string     { return AsString(node) }
 func (node *ExistsExpr) String() string       { return AsString(node) }
 func (node Exprs) String() string             { return AsString(node) }
 func (node *FuncExpr) String() string         { return AsString(node) }
 func (node *IfExpr) String() string           { return AsString(node) }
 func (node *IndexedVar) String() string       { return AsString(node) }
+func (node *IndirectionExpr) String() string  { return AsString(node) }
 func (node *IsOfTypeExpr) String() string     { return AsString(node) }
 func (node Name) String() string              { return AsString(node) }
 func (node *NotExpr) String() string          { return AsString(node) }
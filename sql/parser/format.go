@@ -22,8 +22,10 @@ import (
 )
 
 type fmtFlags struct {
-	showTypes        bool
-	showTableAliases bool
+	showTypes         bool
+	showTableAliases  bool
+	hideConstants     bool
+	qualifyTableNames bool
 }
 
 // FmtFlags enables conditional formatting in the pretty-printer.
@@ -42,6 +44,17 @@ var FmtQualify FmtFlags = &fmtFlags{showTableAliases: true}
 // annotate expressions with their resolved types.
 var FmtShowTypes FmtFlags = &fmtFlags{showTypes: true}
 
+// FmtHideConstants instructs the pretty-printer to produce a
+// representation that does not disclose query-specific data: literal
+// constants are replaced by underscores. It is used to compute statement
+// fingerprints.
+var FmtHideConstants FmtFlags = &fmtFlags{hideConstants: true}
+
+// FmtQualifyTableNames instructs the pretty-printer to prefix table
+// names with their database, even when the database was omitted in the
+// original query. It is used to store the queries views are defined by.
+var FmtQualifyTableNames FmtFlags = &fmtFlags{qualifyTableNames: true}
+
 // NodeFormatter is implemented by nodes that can be pretty-printed.
 type NodeFormatter interface {
 	// Format performs pretty-printing towards a bytes buffer. The
@@ -52,6 +65,10 @@ type NodeFormatter interface {
 // FormatNode recurses into a node for pretty-printing.
 // Flag-driven special cases can hook into this.
 func FormatNode(buf *bytes.Buffer, f FmtFlags, n NodeFormatter) {
+	if f.hideConstants && isLiteral(n) {
+		buf.WriteByte('_')
+		return
+	}
 	if f.showTypes {
 		if te, ok := n.(TypedExpr); ok {
 			buf.WriteByte('(')
@@ -73,6 +90,20 @@ func FormatNode(buf *bytes.Buffer, f FmtFlags, n NodeFormatter) {
 	n.Format(buf, f)
 }
 
+// isLiteral returns true if the node is a literal constant (other than
+// NULL, which is kept as-is as it usually carries meaning beyond its value).
+func isLiteral(n NodeFormatter) bool {
+	switch t := n.(type) {
+	case Constant:
+		return true
+	case *DPlaceholder:
+		return false
+	case Datum:
+		return t != DNull
+	}
+	return false
+}
+
 // AsStringWithFlags pretty prints a node to a string given specific flags.
 func AsStringWithFlags(n NodeFormatter, f FmtFlags) string {
 	var buf bytes.Buffer
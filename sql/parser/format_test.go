@@ -0,0 +1,42 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "testing"
+
+func TestFormatHideConstants(t *testing.T) {
+	testData := []struct {
+		stmt     string
+		expected string
+	}{
+		{`SELECT 1`, `SELECT _`},
+		{`SELECT a FROM t WHERE b = 'foo' AND c > 1.5`, `SELECT a FROM t WHERE (b = _) AND (c > _)`},
+		{`SELECT a FROM t WHERE b IS NULL`, `SELECT a FROM t WHERE b IS NULL`},
+		{`SELECT a FROM t WHERE b = $1`, `SELECT a FROM t WHERE b = $1`},
+		{`SELECT a FROM t WHERE b = true`, `SELECT a FROM t WHERE b = _`},
+		{`INSERT INTO t VALUES (1, 'a'), (2, 'b')`, `INSERT INTO t VALUES (_, _), (_, _)`},
+		{`UPDATE t SET a = a + 1 WHERE b IN (1, 2)`, `UPDATE t SET a = a + _ WHERE b IN (_, _)`},
+		{`DELETE FROM t WHERE a = 3`, `DELETE FROM t WHERE a = _`},
+	}
+	for _, d := range testData {
+		stmt, err := ParseOneTraditional(d.stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", d.stmt, err)
+		}
+		if s := AsStringWithFlags(stmt, FmtHideConstants); s != d.expected {
+			t.Errorf("%s: expected %s, but found %s", d.stmt, d.expected, s)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	for k, v := range Generators {
+		for i := range v {
+			v[i].impure = true
+		}
+		Generators[strings.ToUpper(k)] = v
+	}
+}
+
+// ValueGenerator is the interface provided by the object returned by a
+// generator builtin, which produces the rows of a set-returning function.
+type ValueGenerator interface {
+	// ColumnTypes returns the types of the columns of the rows produced by
+	// this generator.
+	ColumnTypes() DTuple
+
+	// Start initializes the generator. It must be called once before Next
+	// and Values.
+	Start() error
+
+	// Next determines whether there is a row of data available.
+	Next() (bool, error)
+
+	// Values retrieves the current row of data.
+	Values() DTuple
+
+	// Close must be called after Start before disposing of the generator.
+	Close()
+}
+
+// Generators are a special class of builtin functions that produce a set of
+// rows rather than a single value. They can only be used as data sources in
+// the FROM clause.
+// See `valueGenerator` in the sql package.
+var Generators = map[string][]Builtin{
+	"unnest": makeUnnestBuiltins(arrayParamTypes...),
+}
+
+func makeGeneratorBuiltin(
+	in ArgTypes, ret DTuple, g func(*EvalContext, DTuple) (ValueGenerator, error),
+) Builtin {
+	return Builtin{
+		impure:     true,
+		Types:      in,
+		ReturnType: &ret,
+		Generator:  g,
+	}
+}
+
+// makeUnnestBuiltins creates the unnest(array) overloads for arrays of each of
+// the given types.
+func makeUnnestBuiltins(types ...Datum) []Builtin {
+	ret := make([]Builtin, len(types))
+	for i := range types {
+		paramTyp := types[i]
+		ret[i] = makeGeneratorBuiltin(ArgTypes{NewDArray(paramTyp)}, DTuple{paramTyp},
+			func(_ *EvalContext, args DTuple) (ValueGenerator, error) {
+				return newArrayValueGenerator(paramTyp, args[0]), nil
+			})
+	}
+	return ret
+}
+
+// IsGeneratorApplication returns true iff the FuncExpr calls a generator
+// builtin. The FuncExpr must have been type checked.
+func (node *FuncExpr) IsGeneratorApplication() bool {
+	return node.fn.Generator != nil
+}
+
+// EvalArgsAndGetGenerator evaluates the arguments of the FuncExpr and returns
+// the ValueGenerator of the generator builtin it calls. The FuncExpr must have
+// been type checked.
+func (node *FuncExpr) EvalArgsAndGetGenerator(ctx *EvalContext) (ValueGenerator, error) {
+	if node.fn.Generator == nil {
+		return nil, errors.Errorf("function %s is not a generator", node.Name)
+	}
+	args := make(DTuple, 0, len(node.Exprs))
+	for _, e := range node.Exprs {
+		arg, err := e.(TypedExpr).Eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return node.fn.Generator(ctx, args)
+}
+
+var _ ValueGenerator = &arrayValueGenerator{}
+
+// arrayValueGenerator produces one row for each element of an array, in
+// order. A NULL array produces no rows.
+type arrayValueGenerator struct {
+	paramTyp Datum
+	array    Datum
+	nextIdx  int
+	row      DTuple
+}
+
+func newArrayValueGenerator(paramTyp Datum, array Datum) ValueGenerator {
+	return &arrayValueGenerator{paramTyp: paramTyp, array: array}
+}
+
+// ColumnTypes implements the ValueGenerator interface.
+func (g *arrayValueGenerator) ColumnTypes() DTuple { return DTuple{g.paramTyp} }
+
+// Start implements the ValueGenerator interface.
+func (g *arrayValueGenerator) Start() error {
+	g.nextIdx = 0
+	g.row = make(DTuple, 1)
+	return nil
+}
+
+// Next implements the ValueGenerator interface.
+func (g *arrayValueGenerator) Next() (bool, error) {
+	if g.array == DNull {
+		return false, nil
+	}
+	arr := g.array.(*DArray)
+	if g.nextIdx >= len(arr.Array) {
+		return false, nil
+	}
+	g.row[0] = arr.Array[g.nextIdx]
+	g.nextIdx++
+	return true, nil
+}
+
+// Values implements the ValueGenerator interface.
+func (g *arrayValueGenerator) Values() DTuple { return g.row }
+
+// Close implements the ValueGenerator interface.
+func (g *arrayValueGenerator) Close() {}
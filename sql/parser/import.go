@@ -0,0 +1,81 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// Import represents an IMPORT statement, which creates a table and bulk loads
+// it from a set of files.
+type Import struct {
+	Table NormalizableTableName
+	Defs  TableDefs
+	// FileFormat is the format of the files, e.g. CSV.
+	FileFormat string
+	Files      []string
+	Options    KVOptions
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Import) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("IMPORT TABLE ")
+	FormatNode(buf, f, node.Table)
+	buf.WriteString(" (")
+	FormatNode(buf, f, node.Defs)
+	buf.WriteString(") ")
+	buf.WriteString(node.FileFormat)
+	buf.WriteString(" DATA (")
+	for i, file := range node.Files {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		encodeSQLString(buf, file)
+	}
+	buf.WriteByte(')')
+	if len(node.Options) > 0 {
+		buf.WriteString(" WITH ")
+		FormatNode(buf, f, node.Options)
+	}
+}
+
+// KVOption is a key-value option of a statement, e.g. delimiter = '|'.
+type KVOption struct {
+	Key   string
+	Value string
+}
+
+// KVOptions represents a list of key-value options.
+type KVOptions []KVOption
+
+// Format implements the NodeFormatter interface.
+func (node KVOptions) Format(buf *bytes.Buffer, f FmtFlags) {
+	for i, opt := range node {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, Name(opt.Key))
+		buf.WriteString(" = ")
+		encodeSQLString(buf, opt.Value)
+	}
+}
+
+// Get returns the value of the option with the given key, if present.
+func (node KVOptions) Get(key string) (string, bool) {
+	for _, opt := range node {
+		if opt.Key == key {
+			return opt.Value, true
+		}
+	}
+	return "", false
+}
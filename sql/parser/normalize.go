@@ -412,7 +412,8 @@ func (expr *ComparisonExpr) normalize(v *normalizeVisitor) TypedExpr {
 		ILike, NotILike,
 		SimilarTo, NotSimilarTo,
 		RegMatch, NotRegMatch,
-		RegIMatch, NotRegIMatch:
+		RegIMatch, NotRegIMatch,
+		Contains, JSONExists:
 		if expr.TypedLeft() == DNull || expr.TypedRight() == DNull {
 			return DNull
 		}
@@ -42,6 +42,7 @@ var _ typeList = ArgTypes{}
 var _ typeList = AnyType{}
 var _ typeList = VariadicType{}
 var _ typeList = SingleType{}
+var _ typeList = HeterogeneousType{}
 
 // ArgTypes is a typeList implementation that accepts a specific number of
 // argument types.
@@ -96,6 +97,30 @@ func (AnyType) getAt(i int) Datum {
 	panic("getAt called on AnyType")
 }
 
+// HeterogeneousType is a typeList implementation that accepts arguments of
+// any types. Unlike with AnyType, the arguments are not required to have the
+// same type. If NumArgs is positive, exactly that many arguments are
+// accepted, otherwise any number of arguments are.
+type HeterogeneousType struct {
+	NumArgs int
+}
+
+func (h HeterogeneousType) match(types ArgTypes) bool {
+	return h.matchLen(len(types))
+}
+
+func (HeterogeneousType) matchAt(typ Datum, i int) bool {
+	return true
+}
+
+func (h HeterogeneousType) matchLen(l int) bool {
+	return h.NumArgs <= 0 || l == h.NumArgs
+}
+
+func (HeterogeneousType) getAt(i int) Datum {
+	panic("getAt called on HeterogeneousType")
+}
+
 // VariadicType is a typeList implementation which accepts any number of
 // arguments and matches when each argument is either NULL or of the type
 // typ.
@@ -179,6 +204,30 @@ func typeCheckOverloadedExprs(
 		}
 	}
 
+	// Special-case the HeterogeneousType overload, whose parameters are each
+	// type checked without a preference.
+	for _, overload := range overloads {
+		// Only one overload can be provided if it has parameters with
+		// HeterogeneousType.
+		if h, ok := overload.params().(HeterogeneousType); ok {
+			if len(overloads) > 1 {
+				return nil, nil, fmt.Errorf("only one overload can have parameters with HeterogeneousType")
+			}
+			typedExprs := make([]TypedExpr, len(exprs))
+			for i, expr := range exprs {
+				typ, err := expr.TypeCheck(ctx, NoTypePreference)
+				if err != nil {
+					return nil, nil, err
+				}
+				typedExprs[i] = typ
+			}
+			if !h.matchLen(len(exprs)) {
+				return typedExprs, nil, nil
+			}
+			return typedExprs, overload, nil
+		}
+	}
+
 	// Hold the resolved type expressions of the provided exprs, in order.
 	typedExprs := make([]TypedExpr, len(exprs))
 
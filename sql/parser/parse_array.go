@@ -0,0 +1,190 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/pkg/errors"
+)
+
+var (
+	errArrayNotEnclosed         = errors.New("array must be enclosed in { and }")
+	errArrayExtraText           = errors.New("extra text after closing right brace")
+	errNestedArraysNotSupported = util.UnimplementedWithIssueErrorf(2115,
+		"nested arrays are not supported")
+)
+
+type parseState struct {
+	s       string
+	ctx     *EvalContext
+	result  *DArray
+	t       ColumnType
+	elemBuf bytes.Buffer
+}
+
+func (p *parseState) advance() {
+	p.s = p.s[1:]
+}
+
+func (p *parseState) eatWhitespace() {
+	for len(p.s) > 0 && unicode.IsSpace(rune(p.s[0])) {
+		p.advance()
+	}
+}
+
+func (p *parseState) gobbleString(isTerminatingChar func(ch byte) bool) (out string, err error) {
+	p.elemBuf.Reset()
+	for {
+		if len(p.s) == 0 {
+			return "", errArrayNotEnclosed
+		}
+		if isTerminatingChar(p.s[0]) {
+			break
+		}
+		// Backslashes escape the character that follows them.
+		if p.s[0] == '\\' {
+			p.advance()
+			if len(p.s) == 0 {
+				return "", errArrayNotEnclosed
+			}
+		}
+		p.elemBuf.WriteByte(p.s[0])
+		p.advance()
+	}
+	return p.elemBuf.String(), nil
+}
+
+func isQuoteChar(ch byte) bool {
+	return ch == '"'
+}
+
+func isControlChar(ch byte) bool {
+	return ch == '{' || ch == '}' || ch == ',' || ch == '"'
+}
+
+func (p *parseState) parseQuotedString() (string, error) {
+	return p.gobbleString(isQuoteChar)
+}
+
+func (p *parseState) parseUnquotedString() (string, error) {
+	out, err := p.gobbleString(isControlChar)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (p *parseState) parseElement() error {
+	var next string
+	var err error
+	if len(p.s) == 0 {
+		return errArrayNotEnclosed
+	}
+	switch p.s[0] {
+	case '{':
+		return errNestedArraysNotSupported
+	case '"':
+		p.advance()
+		next, err = p.parseQuotedString()
+		if err != nil {
+			return err
+		}
+		p.advance()
+	default:
+		next, err = p.parseUnquotedString()
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(next, "null") {
+			return p.result.Append(DNull)
+		}
+	}
+
+	d, err := (&CastExpr{Expr: NewDString(next), Type: p.t}).Eval(p.ctx)
+	if err != nil {
+		return err
+	}
+	return p.result.Append(d)
+}
+
+// ParseDArrayFromString parses the string-form of constructing arrays, handling
+// cases such as `'{1,2,3}'::INT[]`, whose elements are parsed as values of the
+// given column type.
+func ParseDArrayFromString(ctx *EvalContext, s string, t ColumnType) (*DArray, error) {
+	paramTyp, _ := colTypeToTypeAndValidArgTypes(t)
+	if paramTyp == nil {
+		return nil, errors.Errorf("cannot parse array of %s", t)
+	}
+	ret, err := doParseDArrayFromString(ctx, s, t, paramTyp)
+	if err != nil {
+		return nil, makeParseError(s, NewDArray(paramTyp).Type(), err)
+	}
+	return ret, nil
+}
+
+func doParseDArrayFromString(
+	ctx *EvalContext, s string, t ColumnType, paramTyp Datum,
+) (*DArray, error) {
+	parser := parseState{
+		s:      s,
+		ctx:    ctx,
+		result: NewDArray(paramTyp),
+		t:      t,
+	}
+
+	parser.eatWhitespace()
+	if len(parser.s) == 0 || parser.s[0] != '{' {
+		return nil, errArrayNotEnclosed
+	}
+	parser.advance()
+	parser.eatWhitespace()
+	if len(parser.s) == 0 {
+		return nil, errArrayNotEnclosed
+	}
+
+	if parser.s[0] == '}' {
+		parser.advance()
+	} else {
+		for {
+			if err := parser.parseElement(); err != nil {
+				return nil, err
+			}
+			parser.eatWhitespace()
+			if len(parser.s) == 0 {
+				return nil, errArrayNotEnclosed
+			}
+			if parser.s[0] == '}' {
+				parser.advance()
+				break
+			}
+			if parser.s[0] != ',' {
+				return nil, errArrayNotEnclosed
+			}
+			parser.advance()
+			parser.eatWhitespace()
+		}
+	}
+
+	parser.eatWhitespace()
+	if parser.s != "" {
+		return nil, errArrayExtraText
+	}
+
+	return parser.result, nil
+}
@@ -47,11 +47,30 @@ func TestParse(t *testing.T) {
 		{`ROLLBACK TRANSACTION`},
 		{"SAVEPOINT foo"},
 
+		{`CANCEL QUERY 'abc'`},
+		{`CANCEL QUERY $1`},
+		{`CANCEL JOB 123`},
+		{`CANCEL JOB $1`},
+
+		{`COPY t FROM STDIN`},
+		{`COPY t (a, b, c) FROM STDIN`},
+		{`COPY d.t FROM STDIN`},
+		{`COPY t TO STDOUT`},
+		{`COPY t (a, b) TO STDOUT WITH (FORMAT csv, HEADER)`},
+		{`COPY (SELECT a FROM t WHERE b > 1) TO STDOUT WITH (FORMAT text)`},
+		{`COPY d.t TO STDOUT WITH (HEADER)`},
+
+		{`CREATE CHANGEFEED FOR TABLE foo INTO 'kafka://localhost:9092'`},
+		{`CREATE CHANGEFEED FOR TABLE foo, db.bar INTO 'kafka://localhost:9092?topic_prefix=crdb_' WITH resolved = '10s'`},
 		{`CREATE DATABASE a`},
 		{`CREATE DATABASE a ENCODING='UTF8'`},
 		{`CREATE DATABASE IF NOT EXISTS a`},
 		{`CREATE DATABASE IF NOT EXISTS a ENCODING='UTF8'`},
 		{`CREATE DATABASE IF NOT EXISTS a ENCODING='INVALID'`},
+		{`CREATE ROLE a`},
+		{`CREATE ROLE IF NOT EXISTS a`},
+		{`CREATE USER a`},
+		{`CREATE USER IF NOT EXISTS a WITH PASSWORD 'secret'`},
 
 		{`CREATE INDEX a ON b (c)`},
 		{`CREATE INDEX a ON b.c (d)`},
@@ -62,7 +81,12 @@ func TestParse(t *testing.T) {
 		{`CREATE UNIQUE INDEX a ON b (c)`},
 		{`CREATE UNIQUE INDEX a ON b (c) STORING (d)`},
 		{`CREATE UNIQUE INDEX a ON b (c) INTERLEAVE IN PARENT d (e, f)`},
+		{`CREATE INDEX a ON b (c, d) PARTITION BY LIST (c) (PARTITION p1 VALUES IN (1, 2), PARTITION p2 VALUES IN (3))`},
 		{`CREATE UNIQUE INDEX a ON b.c (d)`},
+		{`CREATE INDEX a ON b (c) WHERE c > 0`},
+		{`CREATE UNIQUE INDEX a ON b (c) STORING (d) WHERE d IS NOT NULL`},
+		{`CREATE INVERTED INDEX a ON b (c)`},
+		{`CREATE INVERTED INDEX IF NOT EXISTS a ON b (c) WHERE d`},
 
 		{`CREATE TABLE a ()`},
 		{`CREATE TABLE a (b INT)`},
@@ -87,6 +111,13 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT DEFAULT 1)`},
 		{`CREATE TABLE a (b INT CONSTRAINT one DEFAULT 1)`},
 		{`CREATE TABLE a (b INT DEFAULT now())`},
+		{`CREATE TABLE a (b INT[])`},
+		{`CREATE TABLE a (b STRING[], c DECIMAL(3,2)[])`},
+		{`CREATE TABLE a (b JSON, c JSONB)`},
+		{`CREATE TABLE a (b INET)`},
+		{`CREATE TABLE a (b STRING COLLATE de, c STRING(3) COLLATE "en-US" NOT NULL)`},
+		{`CREATE TABLE a (b INT, c INT AS (b + 1) STORED, INDEX (c))`},
+		{`CREATE TABLE a (b INT, c INT NOT NULL AS (b * 2) STORED)`},
 		{`CREATE TABLE a (a INT CHECK (a > 0))`},
 		{`CREATE TABLE a (a INT CONSTRAINT positive CHECK (a > 0))`},
 		{`CREATE TABLE a (a INT DEFAULT 1 CHECK (a > 0))`},
@@ -99,6 +130,8 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT, c TEXT, FOREIGN KEY (b, c) REFERENCES other)`},
 		{`CREATE TABLE a (b INT, c TEXT, FOREIGN KEY (b, c) REFERENCES other (x, y))`},
 		{`CREATE TABLE a (b INT, c TEXT, CONSTRAINT s FOREIGN KEY (b, c) REFERENCES other (x, y))`},
+		{`CREATE TABLE a (b INT, FOREIGN KEY (b) REFERENCES other ON DELETE CASCADE)`},
+		{`CREATE TABLE a (b INT, FOREIGN KEY (b) REFERENCES other ON DELETE SET NULL ON UPDATE RESTRICT)`},
 		{`CREATE TABLE a (b INT, c TEXT, INDEX (b, c))`},
 		{`CREATE TABLE a (b INT, c TEXT, INDEX d (b, c))`},
 		{`CREATE TABLE a (b INT, c TEXT, CONSTRAINT d UNIQUE (b, c))`},
@@ -109,15 +142,37 @@ func TestParse(t *testing.T) {
 		{`CREATE TABLE a (b INT, c INT REFERENCES foo)`},
 		{`CREATE TABLE a (b INT, c INT CONSTRAINT ref REFERENCES foo)`},
 		{`CREATE TABLE a (b INT, c INT REFERENCES foo (bar))`},
+		{`CREATE TABLE a (b INT, c INT REFERENCES foo (bar) ON UPDATE CASCADE)`},
 		{`CREATE TABLE a (b INT, INDEX (b) STORING (c))`},
 		{`CREATE TABLE a (b INT, c TEXT, INDEX (b ASC, c DESC) STORING (c))`},
 		{`CREATE TABLE a (b INT, INDEX (b) INTERLEAVE IN PARENT c (d, e))`},
+		{`CREATE TABLE a (b INT, c BOOL, INDEX (b) WHERE c, UNIQUE INDEX (b) WHERE b > 0)`},
+		{`CREATE TABLE a (b INT, c JSONB, INVERTED INDEX (c))`},
+		{`CREATE TABLE a (b INT, c INT[], INVERTED INDEX d (c))`},
 		{`CREATE TABLE a (b INT, FAMILY (b))`},
 		{`CREATE TABLE a (b INT, c STRING, FAMILY foo (b), FAMILY (c))`},
 		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (c, d)`},
 		{`CREATE TABLE a (b INT) INTERLEAVE IN PARENT foo (c) CASCADE`},
+		{`CREATE TABLE a (b INT PRIMARY KEY, c STRING) PARTITION BY LIST (b) (PARTITION p1 VALUES IN (1, 2), PARTITION p2 VALUES IN (3))`},
+		{`CREATE TABLE a (b INT, c STRING, PRIMARY KEY (b, c)) PARTITION BY LIST (b, c) (PARTITION p1 VALUES IN ((1, 'x'), (2, 'y')))`},
+		{`CREATE TABLE a (b INT PRIMARY KEY) PARTITION BY RANGE (b) (PARTITION p1 VALUES < (10), PARTITION p2 VALUES < MAXVALUE)`},
+		{`CREATE TABLE IF NOT EXISTS a (b INT, c INT, PRIMARY KEY (b, c)) PARTITION BY RANGE (b, c) (PARTITION p1 VALUES < (10, 20))`},
 		{`CREATE TABLE a.b (b INT)`},
 		{`CREATE TABLE IF NOT EXISTS a (b INT)`},
+		{`CREATE TEMPORARY TABLE a (b INT)`},
+		{`CREATE TEMPORARY TABLE IF NOT EXISTS a.b (c INT PRIMARY KEY)`},
+		{`CREATE VIEW a AS SELECT * FROM b`},
+		{`CREATE VIEW a AS SELECT b.* FROM b LIMIT 5`},
+		{`CREATE VIEW a AS (SELECT c, d FROM b WHERE c > 0 ORDER BY c)`},
+		{`CREATE VIEW a (x, y) AS SELECT c, d FROM b`},
+		{`CREATE VIEW a.b AS VALUES (1, 2)`},
+		{`CREATE SEQUENCE a`},
+		{`CREATE SEQUENCE IF NOT EXISTS a.b`},
+		{`CREATE SEQUENCE a INCREMENT BY 5 START WITH 10`},
+		{`CREATE SEQUENCE a INCREMENT BY -1 MINVALUE -100 MAXVALUE -1`},
+		{`CREATE SEQUENCE a NO MINVALUE NO MAXVALUE`},
+		{`CREATE STATISTICS a FROM b`},
+		{`CREATE STATISTICS a ON col1, col2 FROM d.b`},
 
 		{`DELETE FROM a`},
 		{`DELETE FROM a.b`},
@@ -128,6 +183,8 @@ func TestParse(t *testing.T) {
 
 		{`DROP DATABASE a`},
 		{`DROP DATABASE IF EXISTS a`},
+		{`DROP ROLE a`},
+		{`DROP ROLE IF EXISTS a, b`},
 		{`DROP TABLE a`},
 		{`DROP TABLE a.b`},
 		{`DROP TABLE a, b`},
@@ -140,6 +197,14 @@ func TestParse(t *testing.T) {
 		{`DROP TABLE a.b CASCADE`},
 		{`DROP TABLE a, b CASCADE`},
 		{`DROP TABLE IF EXISTS a CASCADE`},
+		{`DROP VIEW a`},
+		{`DROP VIEW a.b`},
+		{`DROP VIEW a, b`},
+		{`DROP VIEW IF EXISTS a`},
+		{`DROP VIEW a RESTRICT`},
+		{`DROP VIEW IF EXISTS a, b CASCADE`},
+		{`DROP SEQUENCE a`},
+		{`DROP SEQUENCE IF EXISTS a.b, c RESTRICT`},
 		{`DROP INDEX a.b@c`},
 		{`DROP INDEX IF EXISTS a.b@c`},
 		{`DROP INDEX a.b@c, d@f`},
@@ -149,22 +214,45 @@ func TestParse(t *testing.T) {
 
 		{`EXPLAIN SELECT 1`},
 		{`EXPLAIN EXPLAIN SELECT 1`},
+
+		{`EXPORT INTO CSV 'nodelocal:///out' FROM SELECT * FROM t`},
+		{`EXPORT INTO CSV 's3://bucket/dir?AWS_ACCESS_KEY_ID=x' WITH delimiter = '|', compression = 'gzip', chunk_rows = '1000' FROM SELECT a, b FROM t WHERE c > 1`},
+		{`EXPORT INTO CSV 'gs://bucket/dir' FROM SELECT 1 UNION SELECT 2`},
 		{`EXPLAIN (DEBUG) SELECT 1`},
 		{`EXPLAIN (A, B, C) SELECT 1`},
+		{`EXPLAIN (ANALYZE) SELECT 1`},
+		{`EXPLAIN (ANALYZE, VERBOSE) DELETE FROM a`},
 
 		{`SHOW BARFOO`},
 		{`SHOW DATABASE`},
 		{`SHOW SYNTAX`},
 
 		{`SHOW DATABASES`},
+		{`SHOW JOBS`},
+		{`SHOW QUERIES`},
+		{`SHOW CLUSTER QUERIES`},
+		{`SHOW RANGES FROM TABLE a.b`},
+		{`SHOW RANGES FROM INDEX a.b@c`},
+		{`SHOW CLUSTER SETTING a.b`},
+		{`SHOW ALL CLUSTER SETTINGS`},
+		{`SHOW ZONE CONFIGURATION FOR DATABASE d`},
+		{`SHOW ZONE CONFIGURATION FOR TABLE a.b`},
+		{`SHOW ZONE CONFIGURATION FOR PARTITION p OF TABLE a.b`},
 		{`SHOW TABLES`},
 		{`SHOW TABLES FROM a`},
+		{`SHOW TRACE FOR SESSION`},
+		{`SHOW TRACE FOR SELECT 1`},
+		{`SHOW TRACE FOR INSERT INTO a VALUES (1)`},
 		{`SHOW COLUMNS FROM a`},
 		{`SHOW COLUMNS FROM a.b.c`},
 		{`SHOW INDEXES FROM a`},
 		{`SHOW INDEXES FROM a.b.c`},
 		{`SHOW CONSTRAINTS FROM a`},
 		{`SHOW CONSTRAINTS FROM a.b.c`},
+		{`SHOW CREATE TABLE a`},
+		{`SHOW CREATE TABLE a.b`},
+		{`SHOW CREATE VIEW a`},
+		{`SHOW CREATE VIEW a.b`},
 		{`SHOW TABLES FROM a; SHOW COLUMNS FROM b`},
 
 		// Tables are the default, but can also be specified with
@@ -203,6 +291,8 @@ func TestParse(t *testing.T) {
 		{`GRANT SELECT, INSERT ON DATABASE bar TO foo, bar, baz`},
 		{`GRANT SELECT, INSERT ON DATABASE db1, db2 TO foo, bar, baz`},
 		{`GRANT SELECT, INSERT ON DATABASE db1, db2 TO "test-user"`},
+		{`GRANT foo TO bar`},
+		{`GRANT foo, bar TO baz, qux WITH ADMIN OPTION`},
 
 		// Tables are the default, but can also be specified with
 		// REVOKE x ON TABLE y. However, the stringer does not output TABLE.
@@ -212,6 +302,24 @@ func TestParse(t *testing.T) {
 		{`REVOKE ALL ON DATABASE foo FROM root, test`},
 		{`REVOKE SELECT, INSERT ON DATABASE bar FROM foo, bar, baz`},
 		{`REVOKE SELECT, INSERT ON DATABASE db1, db2 FROM foo, bar, baz`},
+		{`REVOKE foo FROM bar`},
+		{`REVOKE ADMIN OPTION FOR foo, bar FROM baz, qux`},
+
+		{`IMPORT TABLE foo (id INT PRIMARY KEY, email STRING, age INT) CSV DATA ('path/to/some/file')`},
+
+		{`BACKUP TABLE foo TO 'nodelocal:///bak'`},
+		{`BACKUP TABLE foo, d.bar, baz.* TO 's3://bucket/bak?AWS_ACCESS_KEY_ID=x'`},
+		{`BACKUP DATABASE foo, bar TO 'gs://bucket/bak' WITH foo = 'bar'`},
+		{`RESTORE TABLE foo FROM 'nodelocal:///bak'`},
+		{`RESTORE TABLE foo, d.bar, baz.* FROM 's3://bucket/bak'`},
+		{`RESTORE DATABASE foo, bar FROM 'gs://bucket/bak' WITH into_db = 'baz'`},
+
+		{`PAUSE JOB 123`},
+		{`PAUSE JOB $1`},
+		{`RESUME JOB 123`},
+		{`RESUME JOB $1`},
+		{`IMPORT TABLE d.foo (id INT, email STRING, INDEX (email)) CSV DATA ('nodelocal:///a.csv', 'http://host/b.csv') WITH delimiter = '|', comment = '#'`},
+		{`IMPORT TABLE foo (id INT) CSV DATA ('s3://bucket/path?AWS_ACCESS_KEY_ID=x') WITH nullif = ''`},
 
 		{`INSERT INTO a VALUES (1)`},
 		{`INSERT INTO a.b VALUES (1)`},
@@ -287,6 +395,20 @@ func TestParse(t *testing.T) {
 		{`SELECT a.b.* FROM t`},
 		{`SELECT a.b[1] FROM t`},
 		{`SELECT a.b[1 + 1:4][3] FROM t`},
+		{`SELECT (ARRAY[1, 2])[1]`},
+		{`SELECT (a + b)[1] FROM t`},
+		{`SELECT a = ANY (ARRAY[1, 2]) FROM t`},
+		{`SELECT a < ALL (SELECT b FROM u) FROM t`},
+		{`SELECT a LIKE SOME (ARRAY['a%']) FROM t`},
+		{`SELECT * FROM unnest(ARRAY[1, 2])`},
+		{`SELECT a -> 'b', a ->> 'b', a -> 1 FROM t`},
+		{`SELECT a @> b, a ? 'c' FROM t`},
+		{`SELECT CAST(a AS JSONB)`},
+		{`SELECT host(a), masklen(a) FROM t`},
+		{`SELECT CAST(a AS INET)`},
+		{`SELECT a COLLATE de FROM t ORDER BY b COLLATE "en-US"`},
+		{`SELECT ('a' COLLATE de) < ('b' COLLATE de)`},
+		{`SELECT * FROM unnest(ARRAY[1, 2]) AS u (x)`},
 		{`SELECT 'a' FROM t`},
 		{`SELECT 'a' FROM t@bar`},
 		{`SELECT 'a' FROM t@{NO_INDEX_JOIN}`},
@@ -388,6 +510,22 @@ func TestParse(t *testing.T) {
 
 		{`SELECT a FROM t HAVING a = b`},
 
+		{`SELECT a FROM t WINDOW w AS ()`},
+		{`SELECT a FROM t WINDOW w AS (w2)`},
+		{`SELECT a FROM t WINDOW w AS (PARTITION BY b)`},
+		{`SELECT a FROM t WINDOW w AS (PARTITION BY b, 1 + 2)`},
+		{`SELECT a FROM t WINDOW w AS (ORDER BY c)`},
+		{`SELECT a FROM t WINDOW w AS (ORDER BY c, 1 + 2)`},
+		{`SELECT a FROM t WINDOW w AS (w2 PARTITION BY b ORDER BY c DESC)`},
+		{`SELECT a FROM t WINDOW w AS (), w2 AS (w), w3 AS (w2 PARTITION BY b)`},
+		{`SELECT avg(1) OVER w FROM t`},
+		{`SELECT avg(1) OVER () FROM t`},
+		{`SELECT avg(1) OVER (w) FROM t`},
+		{`SELECT avg(1) OVER (PARTITION BY b) FROM t`},
+		{`SELECT avg(1) OVER (ORDER BY c) FROM t`},
+		{`SELECT avg(1) OVER (w PARTITION BY b ORDER BY c) FROM t`},
+		{`SELECT row_number() OVER (PARTITION BY b ORDER BY c DESC), lag(a, 2, 0) OVER w FROM t WINDOW w AS (ORDER BY b)`},
+
 		{`SELECT a FROM t UNION SELECT 1 FROM t`},
 		{`SELECT a FROM t UNION SELECT 1 FROM t UNION SELECT 1 FROM t`},
 		{`SELECT a FROM t UNION ALL SELECT 1 FROM t`},
@@ -412,6 +550,10 @@ func TestParse(t *testing.T) {
 		{`SELECT a FROM t LIMIT a`},
 		{`SELECT a FROM t OFFSET b`},
 		{`SELECT a FROM t LIMIT a OFFSET b`},
+		{`SELECT a FROM t FOR UPDATE`},
+		{`SELECT a FROM t FOR SHARE`},
+		{`SELECT a FROM t WHERE a = 1 ORDER BY a LIMIT 1 FOR UPDATE`},
+		{`WITH a AS (SELECT 1) SELECT * FROM a FOR UPDATE`},
 		{`SELECT DISTINCT * FROM t`},
 		{`SELECT DISTINCT a, b FROM t`},
 		{`SET a = 3`},
@@ -433,6 +575,9 @@ func TestParse(t *testing.T) {
 		{`SET TIME ZONE -7.3`},
 		{`SET TIME ZONE DEFAULT`},
 		{`SET TIME ZONE LOCAL`},
+		{`SET CLUSTER SETTING a = 3`},
+		{`SET CLUSTER SETTING a.b = 'x'`},
+		{`SET CLUSTER SETTING a = DEFAULT`},
 
 		{`SELECT OVERLAY('w333333rce' PLACING 'resou' FROM 3)`},
 		{`SELECT OVERLAY('w333333rce' PLACING 'resou' FROM 3 FOR 5)`},
@@ -459,14 +604,39 @@ func TestParse(t *testing.T) {
 		{`UPDATE a SET b = 3 WHERE a = b RETURNING 1, 2`},
 		{`UPDATE a SET b = 3 WHERE a = b RETURNING a, a + b`},
 
+		{`WITH a AS (SELECT 1) SELECT * FROM a`},
+		{`WITH a (x, y) AS (SELECT 1, 2) SELECT x FROM a`},
+		{`WITH a AS (SELECT 1), b AS (SELECT * FROM a) SELECT * FROM a, b`},
+		{`WITH a AS (VALUES (1)) SELECT * FROM a ORDER BY 1 LIMIT 1`},
+		{`SELECT * FROM (WITH a AS (SELECT 1) SELECT * FROM a) AS b`},
+		{`WITH a AS (SELECT 1) INSERT INTO b SELECT * FROM a`},
+		{`WITH a AS (SELECT 1) UPSERT INTO b SELECT * FROM a`},
+		{`INSERT INTO b WITH a AS (SELECT 1) SELECT * FROM a`},
+		{`WITH a AS (SELECT 1) UPDATE b SET c = 1 WHERE d IN (SELECT * FROM a)`},
+		{`WITH a AS (SELECT 1) DELETE FROM b WHERE c IN (SELECT * FROM a) RETURNING c`},
+
 		{`UPDATE T AS "0" SET K = ''`},                 // "0" lost its quotes
 		{`SELECT * FROM "0" JOIN "0" USING (id, "0")`}, // last "0" lost its quotes.
 
 		{`ALTER DATABASE a RENAME TO b`},
 		{`ALTER TABLE a RENAME TO b`},
+		{`ALTER TABLE a CONFIGURE ZONE USING num_replicas = 5, constraints = '[+ssd]'`},
+		{`ALTER TABLE a.b CONFIGURE ZONE DISCARD`},
+		{`ALTER DATABASE d CONFIGURE ZONE USING range_max_bytes = 1000`},
+		{`ALTER DATABASE d CONFIGURE ZONE DISCARD`},
+		{`ALTER PARTITION p OF TABLE a.b CONFIGURE ZONE USING constraints = '[+region=us-east]'`},
+		{`ALTER PARTITION p OF TABLE a CONFIGURE ZONE DISCARD`},
 		{`ALTER TABLE IF EXISTS a RENAME TO b`},
 		{`ALTER INDEX a@b RENAME TO b`},
 		{`ALTER INDEX IF EXISTS a@b RENAME TO b`},
+		{`ALTER TABLE a SPLIT AT SELECT * FROM b`},
+		{`ALTER TABLE a SPLIT AT VALUES (1), (2)`},
+		{`ALTER INDEX a@b SPLIT AT VALUES (1, 'x')`},
+		{`ALTER TABLE a SCATTER`},
+		{`ALTER INDEX d.a@b SCATTER`},
+		{`ALTER TABLE a EXPERIMENTAL_RELOCATE VALUES (ARRAY[1, 2, 3], 1)`},
+		{`ALTER INDEX a@b EXPERIMENTAL_RELOCATE SELECT ARRAY[1], 'x'`},
+		{`EXPLAIN ALTER TABLE a SPLIT AT VALUES (1)`},
 		{`ALTER TABLE a RENAME COLUMN c1 TO c2`},
 		{`ALTER TABLE IF EXISTS a RENAME COLUMN c1 TO c2`},
 
@@ -501,6 +671,11 @@ func TestParse(t *testing.T) {
 		{`ALTER TABLE a ALTER COLUMN b DROP DEFAULT`},
 		{`ALTER TABLE a ALTER COLUMN b DROP NOT NULL`},
 		{`ALTER TABLE a ALTER b DROP NOT NULL`},
+		{`ALTER TABLE a ALTER COLUMN b TYPE STRING`},
+		{`ALTER TABLE a ALTER b TYPE DECIMAL(10, 2)`},
+		{`ALTER TABLE a ALTER COLUMN b TYPE INT USING length(b)`},
+		{`ALTER TABLE a ALTER PRIMARY KEY USING COLUMNS (b)`},
+		{`ALTER TABLE a ALTER PRIMARY KEY USING COLUMNS (b, c DESC)`},
 	}
 	for _, d := range testData {
 		stmts, err := parseTraditional(d.sql)
@@ -522,12 +697,35 @@ func TestParse2(t *testing.T) {
 		sql      string
 		expected string
 	}{
+		{`CREATE TEMP TABLE a (b INT)`, `CREATE TEMPORARY TABLE a (b INT)`},
+		{`COPY t TO STDOUT CSV`, `COPY t TO STDOUT WITH (FORMAT csv)`},
+		{`IMPORT TABLE foo (id INT) CSV DATA ('a') WITH DELIMITER = '|'`,
+			`IMPORT TABLE foo (id INT) CSV DATA ('a') WITH delimiter = '|'`},
+		{`EXPORT INTO CSV 'a' WITH COMPRESSION = 'gzip' FROM VALUES (1)`,
+			`EXPORT INTO CSV 'a' WITH compression = 'gzip' FROM VALUES (1)`},
+		{`BACKUP foo TO 'a'`, `BACKUP TABLE foo TO 'a'`},
+		{`RESTORE foo FROM 'a'`, `RESTORE TABLE foo FROM 'a'`},
+		{`CREATE CHANGEFEED FOR foo INTO 'a'`, `CREATE CHANGEFEED FOR TABLE foo INTO 'a'`},
+		{`COPY t TO STDOUT WITH CSV HEADER`, `COPY t TO STDOUT WITH (FORMAT csv, HEADER)`},
+		{`COPY t TO STDOUT (HEADER, FORMAT csv)`, `COPY t TO STDOUT WITH (FORMAT csv, HEADER)`},
+		{`CREATE LOCAL TEMPORARY TABLE a (b INT)`, `CREATE TEMPORARY TABLE a (b INT)`},
+		{`ALTER TABLE a ALTER COLUMN b SET DATA TYPE STRING`, `ALTER TABLE a ALTER COLUMN b TYPE STRING`},
+		{`ALTER TABLE a ALTER b SET DATA TYPE INT USING b::INT + 1`,
+			`ALTER TABLE a ALTER b TYPE INT USING CAST(b AS INT) + 1`},
 		{`CREATE TABLE a (b INT, UNIQUE INDEX foo (b))`,
 			`CREATE TABLE a (b INT, CONSTRAINT foo UNIQUE (b))`},
 		{`CREATE TABLE a (b INT, UNIQUE INDEX foo (b) INTERLEAVE IN PARENT c (d))`,
 			`CREATE TABLE a (b INT, CONSTRAINT foo UNIQUE (b) INTERLEAVE IN PARENT c (d))`},
 		{`CREATE INDEX ON a (b) COVERING (c)`, `CREATE INDEX ON a (b) STORING (c)`},
+		{`CREATE SEQUENCE a INCREMENT 2 START 3`,
+			`CREATE SEQUENCE a INCREMENT BY 2 START WITH 3`},
+		{`CREATE TABLE a (b INT REFERENCES c ON UPDATE CASCADE ON DELETE NO ACTION)`,
+			`CREATE TABLE a (b INT REFERENCES c ON UPDATE CASCADE)`},
+
+		{`EXPLAIN ANALYZE SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
+		{`EXPLAIN ANALYSE SELECT 1`, `EXPLAIN (ANALYZE) SELECT 1`},
 
+		{`SELECT a FROM t FOR UPDATE LIMIT 1`, `SELECT a FROM t LIMIT 1 FOR UPDATE`},
 		{`SELECT BOOL 'foo'`, `SELECT CAST('foo' AS BOOL)`},
 		{`SELECT INT 'foo'`, `SELECT CAST('foo' AS INT)`},
 		{`SELECT REAL 'foo'`, `SELECT CAST('foo' AS REAL)`},
@@ -562,6 +760,13 @@ func TestParse2(t *testing.T) {
 		{`SELECT a FROM t WHERE a = b / c`, `SELECT a FROM t WHERE a = (b / c)`},
 		{`SELECT a FROM t WHERE a = b % c`, `SELECT a FROM t WHERE a = (b % c)`},
 		{`SELECT a FROM t WHERE a = b || c`, `SELECT a FROM t WHERE a = (b || c)`},
+		{`SELECT a->'b'->>'c' FROM t`, `SELECT (a -> 'b') ->> 'c' FROM t`},
+		// FAMILY is a reserved keyword, so the family() builtin is quoted.
+		{`SELECT family(a) FROM t`, `SELECT "family"(a) FROM t`},
+		{`SELECT a FROM t WHERE a @> b->'c'`, `SELECT a FROM t WHERE a @> (b -> 'c')`},
+		{`SELECT a || b COLLATE de FROM t`, `SELECT a || (b COLLATE de) FROM t`},
+		{`CREATE TABLE a (b STRING NOT NULL COLLATE de)`, `CREATE TABLE a (b STRING COLLATE de NOT NULL)`},
+		{`CREATE TABLE a (b INT, c INT AS (b + 1) STORED NOT NULL)`, `CREATE TABLE a (b INT, c INT NOT NULL AS (b + 1) STORED)`},
 		{`SELECT a FROM t WHERE a = + b`, `SELECT a FROM t WHERE a = (+ b)`},
 		{`SELECT a FROM t WHERE a = - b`, `SELECT a FROM t WHERE a = (- b)`},
 		{`SELECT a FROM t WHERE a = ~ b`, `SELECT a FROM t WHERE a = (~ b)`},
@@ -642,6 +847,8 @@ func TestParse2(t *testing.T) {
 		{`SELECT -0.-/*test*/-1`,
 			`SELECT (- 0.) - (- 1)`,
 		},
+		{`CREATE TABLE a (b INT ARRAY, c INT ARRAY[3], d INT[4])`,
+			`CREATE TABLE a (b INT[], c INT[], d INT[])`},
 		// See #1948.
 		{`SELECT~~+~++~bd(*)`,
 			`SELECT ~ (~ (+ (~ (+ (+ (~ bd(*)))))))`},
@@ -662,6 +869,8 @@ func TestParse2(t *testing.T) {
 			`SET TIME ZONE 'Europe/Rome'`},
 		{`SET TIME ZONE INTERVAL '-7h'`,
 			`SET TIME ZONE INTERVAL '-7h0m0s'`},
+		{`SET CLUSTER SETTING a TO true`,
+			`SET CLUSTER SETTING a = true`},
 		// Special substring syntax
 		{`SELECT SUBSTRING('RoacH' from 2 for 3)`,
 			`SELECT SUBSTRING('RoacH', 2, 3)`},
@@ -831,6 +1040,11 @@ CREATE DATABASE a b
 			`syntax error at or near "b"
 CREATE DATABASE a b c
                   ^
+`},
+		{`GRANT SELECT, foo ON t TO root`,
+			`not a valid privilege: "foo" at or near "ON"
+GRANT SELECT, foo ON t TO root
+                  ^
 `},
 		{`CREATE INDEX ON a (b) STORING ()`,
 			`syntax error at or near ")"
@@ -0,0 +1,94 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// PartitionBy represents a PARTITION BY LIST or PARTITION BY RANGE clause,
+// which divides the rows of an index into named partitions by the values of
+// a prefix of its columns. Exactly one of List and Range is set.
+type PartitionBy struct {
+	Fields NameList
+	List   []ListPartition
+	Range  []RangePartition
+}
+
+// Format implements the NodeFormatter interface.
+func (node *PartitionBy) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString(" PARTITION BY ")
+	if node.List != nil {
+		buf.WriteString("LIST")
+	} else {
+		buf.WriteString("RANGE")
+	}
+	buf.WriteString(" (")
+	FormatNode(buf, f, node.Fields)
+	buf.WriteString(") (")
+	for i := range node.List {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, &node.List[i])
+	}
+	for i := range node.Range {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, &node.Range[i])
+	}
+	buf.WriteByte(')')
+}
+
+// ListPartition represents a partition of a PARTITION BY LIST clause, holding
+// the rows whose partitioning columns equal one of Exprs. Each expression is
+// a tuple with a value per partitioning column, or a single value if there is
+// only one.
+type ListPartition struct {
+	Name  Name
+	Exprs Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ListPartition) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("PARTITION ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" VALUES IN (")
+	FormatNode(buf, f, node.Exprs)
+	buf.WriteByte(')')
+}
+
+// RangePartition represents a partition of a PARTITION BY RANGE clause,
+// holding the rows whose partitioning columns are below the upper bound given
+// by Exprs, with a value per partitioning column, and not in a previous
+// partition. Nil Exprs mean MAXVALUE: the partition holds all the remaining
+// rows.
+type RangePartition struct {
+	Name  Name
+	Exprs Exprs
+}
+
+// Format implements the NodeFormatter interface.
+func (node *RangePartition) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("PARTITION ")
+	FormatNode(buf, f, node.Name)
+	buf.WriteString(" VALUES < ")
+	if node.Exprs == nil {
+		buf.WriteString("MAXVALUE")
+		return
+	}
+	buf.WriteByte('(')
+	FormatNode(buf, f, node.Exprs)
+	buf.WriteByte(')')
+}
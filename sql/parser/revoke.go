@@ -45,3 +45,21 @@ func (node *Revoke) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString(" FROM ")
 	FormatNode(buf, f, node.Grantees)
 }
+
+// RevokeRole represents a REVOKE <role> statement.
+type RevokeRole struct {
+	Roles       NameList
+	Members     NameList
+	AdminOption bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *RevokeRole) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("REVOKE ")
+	if node.AdminOption {
+		buf.WriteString("ADMIN OPTION FOR ")
+	}
+	FormatNode(buf, f, node.Roles)
+	buf.WriteString(" FROM ")
+	FormatNode(buf, f, node.Members)
+}
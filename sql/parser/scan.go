@@ -360,6 +360,30 @@ func (s *Scanner) scan(lval *sqlSymType) {
 		}
 		return
 
+	case '-':
+		switch s.peek() {
+		case '>': // ->
+			s.pos++
+			switch s.peek() {
+			case '>': // ->>
+				s.pos++
+				lval.id = FETCHTEXT
+				return
+			}
+			lval.id = FETCHVAL
+			return
+		}
+		return
+
+	case '@':
+		switch s.peek() {
+		case '>': // @>
+			s.pos++
+			lval.id = CONTAINS
+			return
+		}
+		return
+
 	case ':':
 		switch s.peek() {
 		case ':': // ::
@@ -38,18 +38,23 @@ func (*SelectClause) selectStatement() {}
 func (*UnionClause) selectStatement()  {}
 func (*ValuesClause) selectStatement() {}
 
-// Select represents a SelectStatement with an ORDER and/or LIMIT.
+// Select represents a SelectStatement with an ORDER, a LIMIT and/or a locking
+// clause.
 type Select struct {
+	With    *With
 	Select  SelectStatement
 	OrderBy OrderBy
 	Limit   *Limit
+	Locking LockingStrength
 }
 
 // Format implements the NodeFormatter interface.
 func (node *Select) Format(buf *bytes.Buffer, f FmtFlags) {
+	FormatNode(buf, f, node.With)
 	FormatNode(buf, f, node.Select)
 	FormatNode(buf, f, node.OrderBy)
 	FormatNode(buf, f, node.Limit)
+	FormatNode(buf, f, node.Locking)
 }
 
 // ParenSelect represents a parenthesized SELECT/UNION/VALUES statement.
@@ -72,6 +77,7 @@ type SelectClause struct {
 	Where       *Where
 	GroupBy     GroupBy
 	Having      *Where
+	Window      Window
 	Lock        string
 	tableSelect bool
 }
@@ -91,6 +97,7 @@ func (node *SelectClause) Format(buf *bytes.Buffer, f FmtFlags) {
 		FormatNode(buf, f, node.Where)
 		FormatNode(buf, f, node.GroupBy)
 		FormatNode(buf, f, node.Having)
+		FormatNode(buf, f, node.Window)
 		buf.WriteString(node.Lock)
 	}
 }
@@ -261,6 +268,7 @@ func (node *AliasedTableExpr) Format(buf *bytes.Buffer, f FmtFlags) {
 }
 
 func (*Subquery) tableExpr() {}
+func (*FuncExpr) tableExpr() {}
 
 // ParenTableExpr represents a parenthesized TableExpr.
 type ParenTableExpr struct {
@@ -468,3 +476,86 @@ func (node *Limit) Format(buf *bytes.Buffer, f FmtFlags) {
 		}
 	}
 }
+
+// LockingStrength represents the row locking clause of a SELECT.
+type LockingStrength int
+
+// LockingStrength values.
+const (
+	LockNone LockingStrength = iota
+	LockForShare
+	LockForUpdate
+)
+
+var lockingStrengthName = [...]string{
+	LockNone:      "",
+	LockForShare:  "FOR SHARE",
+	LockForUpdate: "FOR UPDATE",
+}
+
+func (l LockingStrength) String() string {
+	return lockingStrengthName[l]
+}
+
+// Format implements the NodeFormatter interface.
+func (l LockingStrength) Format(buf *bytes.Buffer, f FmtFlags) {
+	if l != LockNone {
+		buf.WriteByte(' ')
+		buf.WriteString(l.String())
+	}
+}
+
+// Window represents a WINDOW clause.
+type Window []*WindowDef
+
+// Format implements the NodeFormatter interface.
+func (node Window) Format(buf *bytes.Buffer, f FmtFlags) {
+	prefix := " WINDOW "
+	for _, n := range node {
+		buf.WriteString(prefix)
+		FormatNode(buf, f, n.Name)
+		buf.WriteString(" AS ")
+		FormatNode(buf, f, n)
+		prefix = ", "
+	}
+}
+
+// WindowDef represents a single window definition expression, either in
+// a WINDOW clause or in the OVER clause of a window function application.
+type WindowDef struct {
+	Name       Name
+	RefName    Name
+	Partitions Exprs
+	OrderBy    OrderBy
+}
+
+// Format implements the NodeFormatter interface. It formats the window
+// specification, without the name of the window.
+func (node *WindowDef) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteByte('(')
+	needSpace := false
+	if node.RefName != "" {
+		FormatNode(buf, f, node.RefName)
+		needSpace = true
+	}
+	if len(node.Partitions) > 0 {
+		if needSpace {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString("PARTITION BY ")
+		FormatNode(buf, f, node.Partitions)
+		needSpace = true
+	}
+	if len(node.OrderBy) > 0 {
+		prefix := "ORDER BY "
+		if needSpace {
+			prefix = " ORDER BY "
+		}
+		for _, n := range node.OrderBy {
+			buf.WriteString(prefix)
+			FormatNode(buf, f, n)
+			prefix = ", "
+		}
+	}
+	buf.WriteByte(')')
+}
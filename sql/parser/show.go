@@ -66,6 +66,78 @@ func (node *ShowIndex) Format(buf *bytes.Buffer, f FmtFlags) {
 	FormatNode(buf, f, node.Table)
 }
 
+// ShowJobs represents a SHOW JOBS statement.
+type ShowJobs struct{}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowJobs) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW JOBS")
+}
+
+// ShowQueries represents a SHOW QUERIES statement.
+type ShowQueries struct {
+	Cluster bool
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowQueries) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW ")
+	if node.Cluster {
+		buf.WriteString("CLUSTER ")
+	}
+	buf.WriteString("QUERIES")
+}
+
+// ShowRanges represents a SHOW RANGES FROM TABLE or SHOW RANGES FROM INDEX
+// statement. Only one of Table and Index can be set.
+type ShowRanges struct {
+	Table *NormalizableTableName
+	Index *TableNameWithIndex
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowRanges) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Index != nil {
+		buf.WriteString("SHOW RANGES FROM INDEX ")
+		FormatNode(buf, f, node.Index)
+	} else {
+		buf.WriteString("SHOW RANGES FROM TABLE ")
+		FormatNode(buf, f, node.Table)
+	}
+}
+
+// ShowClusterSetting represents a SHOW CLUSTER SETTING statement. The name
+// "all" stands for SHOW ALL CLUSTER SETTINGS.
+type ShowClusterSetting struct {
+	Name string
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowClusterSetting) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Name == "all" {
+		buf.WriteString("SHOW ALL CLUSTER SETTINGS")
+		return
+	}
+	buf.WriteString("SHOW CLUSTER SETTING ")
+	buf.WriteString(node.Name)
+}
+
+// ShowTrace represents a SHOW TRACE FOR statement. A nil Statement
+// stands for SHOW TRACE FOR SESSION.
+type ShowTrace struct {
+	Statement Statement
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowTrace) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW TRACE FOR ")
+	if node.Statement == nil {
+		buf.WriteString("SESSION")
+	} else {
+		FormatNode(buf, f, node.Statement)
+	}
+}
+
 // ShowTables represents a SHOW TABLES statement.
 type ShowTables struct {
 	Database Name
@@ -124,3 +196,14 @@ func (node *ShowCreateTable) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("SHOW CREATE TABLE ")
 	FormatNode(buf, f, node.Table)
 }
+
+// ShowCreateView represents a SHOW CREATE VIEW statement.
+type ShowCreateView struct {
+	View NormalizableTableName
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowCreateView) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW CREATE VIEW ")
+	FormatNode(buf, f, node.View)
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// formatTableOrIndex formats the table or the index targeted by an ALTER
+// TABLE or ALTER INDEX statement which operates on the ranges of an index.
+func formatTableOrIndex(
+	buf *bytes.Buffer, f FmtFlags, table *NormalizableTableName, index *TableNameWithIndex,
+) {
+	if index != nil {
+		buf.WriteString("ALTER INDEX ")
+		FormatNode(buf, f, index)
+	} else {
+		buf.WriteString("ALTER TABLE ")
+		FormatNode(buf, f, table)
+	}
+}
+
+// Split represents an ALTER TABLE/INDEX ... SPLIT AT statement, which splits
+// the ranges of an index at the keys of the rows returned by Rows. Only one
+// of Table and Index can be set; the primary index is used for Table.
+type Split struct {
+	Table *NormalizableTableName
+	Index *TableNameWithIndex
+	// Rows is a query returning values for a prefix of the index columns.
+	Rows *Select
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Split) Format(buf *bytes.Buffer, f FmtFlags) {
+	formatTableOrIndex(buf, f, node.Table, node.Index)
+	buf.WriteString(" SPLIT AT ")
+	FormatNode(buf, f, node.Rows)
+}
+
+// Scatter represents an ALTER TABLE/INDEX ... SCATTER statement, which moves
+// the replicas and leases of the ranges of an index to randomly chosen
+// stores. Only one of Table and Index can be set.
+type Scatter struct {
+	Table *NormalizableTableName
+	Index *TableNameWithIndex
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Scatter) Format(buf *bytes.Buffer, f FmtFlags) {
+	formatTableOrIndex(buf, f, node.Table, node.Index)
+	buf.WriteString(" SCATTER")
+}
+
+// Relocate represents an ALTER TABLE/INDEX ... EXPERIMENTAL_RELOCATE
+// statement, which moves the replicas of ranges of an index to the given
+// stores. Only one of Table and Index can be set.
+type Relocate struct {
+	Table *NormalizableTableName
+	Index *TableNameWithIndex
+	// Rows is a query returning, for each range to relocate, an array of the
+	// IDs of the target stores followed by values for a prefix of the index
+	// columns identifying a key within the range. The lease is moved to the
+	// first store of the array.
+	Rows *Select
+}
+
+// Format implements the NodeFormatter interface.
+func (node *Relocate) Format(buf *bytes.Buffer, f FmtFlags) {
+	formatTableOrIndex(buf, f, node.Table, node.Index)
+	buf.WriteString(" EXPERIMENTAL_RELOCATE ")
+	FormatNode(buf, f, node.Rows)
+}
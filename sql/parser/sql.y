@@ -246,21 +246,24 @@ func (u *sqlSymUnion) updateExprs() UpdateExprs {
 func (u *sqlSymUnion) limit() *Limit {
     return u.val.(*Limit)
 }
+func (u *sqlSymUnion) lockingStrength() LockingStrength {
+    return u.val.(LockingStrength)
+}
 func (u *sqlSymUnion) targetList() TargetList {
     return u.val.(TargetList)
 }
 func (u *sqlSymUnion) targetListPtr() *TargetList {
     return u.val.(*TargetList)
 }
-func (u *sqlSymUnion) privilegeType() privilege.Kind {
-    return u.val.(privilege.Kind)
-}
 func (u *sqlSymUnion) privilegeList() privilege.List {
     return u.val.(privilege.List)
 }
 func (u *sqlSymUnion) onConflict() *OnConflict {
     return u.val.(*OnConflict)
 }
+func (u *sqlSymUnion) copyOptions() *CopyOptions {
+    return u.val.(*CopyOptions)
+}
 func (u *sqlSymUnion) orderBy() OrderBy {
     return u.val.(OrderBy)
 }
@@ -300,9 +303,66 @@ func (u *sqlSymUnion) idxElems() IndexElemList {
 func (u *sqlSymUnion) dropBehavior() DropBehavior {
     return u.val.(DropBehavior)
 }
+func (u *sqlSymUnion) referenceAction() ReferenceAction {
+    return u.val.(ReferenceAction)
+}
+func (u *sqlSymUnion) referenceActions() ReferenceActions {
+    return u.val.(ReferenceActions)
+}
+func (u *sqlSymUnion) window() Window {
+    return u.val.(Window)
+}
+func (u *sqlSymUnion) windowDef() *WindowDef {
+    return u.val.(*WindowDef)
+}
+func (u *sqlSymUnion) with() *With {
+    return u.val.(*With)
+}
+func (u *sqlSymUnion) cte() *CTE {
+    return u.val.(*CTE)
+}
+func (u *sqlSymUnion) ctes() []*CTE {
+    return u.val.([]*CTE)
+}
+func (u *sqlSymUnion) arraySubscript() *ArraySubscript {
+    return u.val.(*ArraySubscript)
+}
+func (u *sqlSymUnion) arraySubscripts() ArraySubscripts {
+    if as, ok := u.val.(ArraySubscripts); ok {
+        return as
+    }
+    return nil
+}
+func (u *sqlSymUnion) int32s() []int32 {
+    return u.val.([]int32)
+}
+func (u *sqlSymUnion) cmpOp() ComparisonOperator {
+    return u.val.(ComparisonOperator)
+}
 func (u *sqlSymUnion) interleave() *InterleaveDef {
     return u.val.(*InterleaveDef)
 }
+func (u *sqlSymUnion) partitionBy() *PartitionBy {
+    return u.val.(*PartitionBy)
+}
+func (u *sqlSymUnion) listPartitions() []ListPartition {
+    return u.val.([]ListPartition)
+}
+func (u *sqlSymUnion) rangePartitions() []RangePartition {
+    return u.val.([]RangePartition)
+}
+func (u *sqlSymUnion) seqOpt() SequenceOption {
+    return u.val.(SequenceOption)
+}
+func (u *sqlSymUnion) kvOptions() KVOptions {
+    return u.val.(KVOptions)
+}
+func (u *sqlSymUnion) zoneSettings() ZoneSettings {
+    return u.val.(ZoneSettings)
+}
+func (u *sqlSymUnion) seqOpts() SequenceOptions {
+    return u.val.(SequenceOptions)
+}
 
 %}
 
@@ -319,10 +379,22 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <Statement> stmt
 
 %type <Statement> alter_table_stmt
+%type <Statement> alter_index_stmt
+%type <Statement> backup_stmt
+%type <Statement> cancel_stmt
+%type <Statement> configure_zone_stmt
+%type <Statement> copy_from_stmt
+%type <Statement> copy_to_stmt
 %type <Statement> create_stmt
+%type <Statement> create_changefeed_stmt
 %type <Statement> create_database_stmt
 %type <Statement> create_index_stmt
+%type <Statement> create_role_stmt
+%type <Statement> create_sequence_stmt
+%type <Statement> create_stats_stmt
 %type <Statement> create_table_stmt
+%type <Statement> create_user_stmt
+%type <Statement> create_view_stmt
 %type <Statement> delete_stmt
 %type <Statement> drop_stmt
 %type <Statement> explain_stmt
@@ -332,8 +404,13 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <Statement> execute_stmt
 %type <Statement> deallocate_stmt
 %type <Statement> grant_stmt
+%type <Statement> import_stmt
+%type <Statement> pause_stmt
+%type <Statement> export_stmt
 %type <Statement> insert_stmt
 %type <Statement> release_stmt
+%type <Statement> restore_stmt
+%type <Statement> resume_stmt
 %type <Statement> rename_stmt
 %type <Statement> revoke_stmt
 %type <*Select> select_stmt
@@ -347,8 +424,8 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <*Select> select_no_parens
 %type <SelectStatement> select_clause select_with_parens simple_select values_clause
 
-%type <empty> alter_using
 %type <Expr> alter_column_default
+%type <Expr> alter_using
 %type <Direction> opt_asc_desc
 
 %type <AlterTableCmd> alter_table_cmd
@@ -359,6 +436,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <DropBehavior> opt_drop_behavior
 
 %type <*StrVal> opt_encoding_clause
+%type <*StrVal> opt_password
 
 %type <IsolationLevel> transaction_iso_level
 %type <UserPriority>  transaction_user_priority
@@ -366,7 +444,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <str>   name opt_name opt_name_parens opt_to_savepoint
 %type <str>   savepoint_name
 
-// %type <empty> subquery_op
+%type <ComparisonOperator> subquery_op
 %type <FunctionName> func_name
 %type <empty> opt_collate
 
@@ -377,7 +455,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <*TableNameWithIndex> table_name_with_index
 %type <TableNameWithIndexList> table_name_with_index_list
 
-// %type <empty> math_op
+%type <ComparisonOperator> math_op
 
 %type <IsolationLevel> iso_level
 %type <UserPriority> user_priority
@@ -385,14 +463,18 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 
 %type <TableDefs> opt_table_elem_list table_elem_list
 %type <*InterleaveDef> opt_interleave
+%type <*PartitionBy> opt_partition_by
+%type <[]ListPartition> list_partitions list_partition
+%type <[]RangePartition> range_partitions range_partition
 %type <empty> opt_all_clause
+%type <*CopyOptions> opt_copy_options copy_csv_options copy_option_list copy_option
 %type <bool> distinct_clause
 %type <NameList> opt_column_list
 %type <OrderBy> sort_clause opt_sort_clause
 %type <[]*Order> sortby_list
 %type <IndexElemList> index_params
 %type <NameList> name_list opt_name_list
-%type <empty> opt_array_bounds
+%type <[]int32> opt_array_bounds array_bounds
 %type <*From> from_clause update_from_clause
 %type <TableExprs> from_list
 %type <UnresolvedNames> qualified_name_list
@@ -405,9 +487,12 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <UpdateExprs> set_clause_list
 %type <*UpdateExpr> set_clause multiple_set_clause
 %type <UnresolvedName> indirection
+%type <*ArraySubscript> array_subscript
+%type <ArraySubscripts> array_subscripts
 %type <Exprs> ctext_expr_list ctext_row
 %type <GroupBy> group_clause
-%type <*Limit> select_limit
+%type <*Limit> select_limit opt_select_limit
+%type <LockingStrength> for_locking_clause
 %type <TableNameReferences> relation_expr_list
 %type <ReturningExprs> returning_clause
 
@@ -425,7 +510,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <empty> opt_interval interval_second
 %type <Expr> overlay_placing
 
-%type <bool> opt_unique opt_column
+%type <bool> opt_unique opt_column opt_temp
 
 %type <empty> opt_set_data
 
@@ -442,6 +527,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <Statement>  generic_set set_rest set_rest_more transaction_mode_list opt_transaction_mode_list set_exprs_internal
 
 %type <NameList> opt_storing
+%type <NameList> opt_stats_columns
 %type <*ColumnTableDef> column_def
 %type <TableDef> table_elem
 %type <Expr>  where_clause
@@ -449,6 +535,8 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <NamePart> name_indirection
 %type <NamePart> indirection_elem
 %type <*IndexHints> opt_index_hints
+%type <SequenceOptions> opt_sequence_option_list sequence_option_list
+%type <SequenceOption> sequence_option_elem
 %type <*IndexHints> index_hints_param
 %type <*IndexHints> index_hints_param_list
 %type <Expr>  a_expr b_expr c_expr a_expr_const
@@ -462,7 +550,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <Expr>  case_expr case_arg case_default
 %type <*When>  when_clause
 %type <[]*When> when_clause_list
-// %type <empty> sub_type
+%type <ComparisonOperator> sub_type
 %type <Expr> ctext_expr
 %type <Expr> numeric_only
 %type <AliasClause> alias_clause opt_alias_clause
@@ -477,7 +565,11 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <AsOfClause> opt_as_of_clause
 
 %type <str> explain_option_name
+%type <str> analyze_kw
 %type <[]string> explain_option_list
+%type <[]string> string_list
+%type <KVOptions> opt_with_options kv_option_list kv_option
+%type <ZoneSettings> zone_setting_list zone_setting
 
 %type <ColumnType> typename simple_typename const_typename
 %type <ColumnType> numeric opt_numeric_modifiers
@@ -509,26 +601,31 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %type <[]NamedColumnQualification> col_qual_list
 %type <NamedColumnQualification> col_qualification
 %type <ColumnQualification> col_qualification_elem
-%type <empty> key_actions key_delete key_match key_update key_action
+%type <empty> key_match
+%type <ReferenceActions> key_actions
+%type <ReferenceAction> key_action key_delete key_update
 
 %type <Expr>  func_application func_expr_common_subexpr
 %type <Expr>  func_expr func_expr_windowless
-%type <empty> common_table_expr
-%type <empty> with_clause opt_with_clause
-%type <empty> cte_list
+%type <*CTE> common_table_expr
+%type <*With> with_clause opt_with_clause
+%type <[]*CTE> cte_list
 
 %type <empty> within_group_clause
 %type <empty> filter_clause
-%type <empty> window_clause window_definition_list opt_partition_clause
-%type <empty> window_definition over_clause window_specification
+%type <Window> window_clause window_definition_list
+%type <*WindowDef> window_definition over_clause window_specification
+%type <str> opt_existing_window_name
+%type <Exprs> opt_partition_clause
 %type <empty> opt_frame_clause frame_extent frame_bound
-%type <empty> opt_existing_window_name
 
+%type <TargetList>    changefeed_targets
 %type <TargetList>    privilege_target
 %type <*TargetList> on_privilege_target_clause
 %type <NameList>       grantee_list for_grantee_clause
-%type <privilege.List> privileges privilege_list
-%type <privilege.Kind> privilege
+%type <privilege.List> privileges
+%type <NameList>       privilege_list
+%type <str>            privilege
 
 // Non-keyword token types. These are hard-wired into the "flex" lexer. They
 // must be listed first so that their numeric codes do not depend on the set of
@@ -543,6 +640,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %token <str>   TYPECAST DOT_DOT
 %token <str>   LESS_EQUALS GREATER_EQUALS NOT_EQUALS
 %token <str>   NOT_REGMATCH REGIMATCH NOT_REGIMATCH
+%token <str>   FETCHVAL FETCHTEXT CONTAINS
 %token <str>   ERROR
 
 // If you want to make any keyword changes, update the keyword table in
@@ -551,80 +649,82 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 // "Keyword category lists".
 
 // Ordinary key words in alphabetical order.
-%token <str>   ACTION ADD
+%token <str>   ACTION ADD ADMIN
 %token <str>   ALL ALTER ANALYSE ANALYZE AND ANY ANNOTATE_TYPE ARRAY AS ASC
 %token <str>   ASYMMETRIC AT
 
-%token <str>   BEGIN BETWEEN BIGINT BIGSERIAL BIT
+%token <str>   BACKUP BEGIN BETWEEN BIGINT BIGSERIAL BIT
 %token <str>   BLOB BOOL BOOLEAN BOTH BY BYTEA BYTES
 
-%token <str>   CASCADE CASE CAST CHAR
-%token <str>   CHARACTER CHARACTERISTICS CHECK
+%token <str>   CANCEL CASCADE CASE CAST CHANGEFEED CHAR
+%token <str>   CHARACTER CHARACTERISTICS CHECK CLUSTER
 %token <str>   COALESCE COLLATE COLLATION COLUMN COLUMNS COMMIT
-%token <str>   COMMITTED CONCAT CONFLICT CONSTRAINT CONSTRAINTS
-%token <str>   COVERING CREATE
-%token <str>   CROSS CUBE CURRENT CURRENT_CATALOG CURRENT_DATE
+%token <str>   COMMITTED CONCAT CONFIGURATION CONFIGURE CONFLICT CONSTRAINT CONSTRAINTS
+%token <str>   COPY COVERING CREATE
+%token <str>   CROSS CSV CUBE CURRENT CURRENT_CATALOG CURRENT_DATE
 %token <str>   CURRENT_ROLE CURRENT_TIME CURRENT_TIMESTAMP
 %token <str>   CURRENT_USER CYCLE
 
 %token <str>   DATA DATABASE DATABASES DATE DAY DEC DECIMAL DEFAULT
 %token <str>   DEALLOCATE DEFERRABLE DELETE DESC
-%token <str>   DISTINCT DO DOUBLE DROP
+%token <str>   DISCARD DISTINCT DO DOUBLE DROP
 
 %token <str>   ELSE ENCODING END ESCAPE EXCEPT
-%token <str>   EXISTS EXECUTE EXPLAIN EXTRACT
+%token <str>   EXISTS EXECUTE EXPERIMENTAL_RELOCATE EXPLAIN EXPORT EXTRACT
 
 %token <str>   FALSE FAMILY FETCH FILTER FIRST FLOAT FLOORDIV FOLLOWING FOR
-%token <str>   FORCE_INDEX FOREIGN FROM FULL
+%token <str>   FORCE_INDEX FOREIGN FORMAT FROM FULL
 
 %token <str>   GRANT GRANTS GREATEST GROUP GROUPING
 
-%token <str>   HAVING HIGH HOUR
+%token <str>   HAVING HEADER HIGH HOUR
 
-%token <str>   IF IFNULL ILIKE IN INTERLEAVE
-%token <str>   INDEX INDEXES INITIALLY
-%token <str>   INNER INSERT INT INT64 INTEGER
-%token <str>   INTERSECT INTERVAL INTO IS ISOLATION
+%token <str>   IF IFNULL ILIKE IMPORT IN INTERLEAVE
+%token <str>   INCREMENT INDEX INDEXES INITIALLY
+%token <str>   INET INNER INSERT INT INT64 INTEGER
+%token <str>   INTERSECT INTERVAL INTO INVERTED IS ISOLATION
 
-%token <str>   JOIN
+%token <str>   JOB JOBS JOIN JSON JSONB
 
 %token <str>   KEY KEYS
 
 %token <str>   LATERAL
-%token <str>   LEADING LEAST LEFT LEVEL LIKE LIMIT LOCAL
+%token <str>   LEADING LEAST LEFT LEVEL LIKE LIMIT LIST LOCAL
 %token <str>   LOCALTIME LOCALTIMESTAMP LOW LSHIFT
 
-%token <str>   MATCH MINUTE MONTH
+%token <str>   MATCH MAXVALUE MINUTE MINVALUE MONTH
 
 %token <str>   NAME NAMES NATURAL NEXT NO NO_INDEX_JOIN NORMAL
 %token <str>   NOT NOTHING NULL NULLIF
 %token <str>   NULLS NUMERIC
 
-%token <str>   OF OFF OFFSET ON ONLY OR
+%token <str>   OF OFF OFFSET ON ONLY OPTION OR
 %token <str>   ORDER ORDINALITY OUT OUTER OVER OVERLAPS OVERLAY
 
-%token <str>   PARENT PARTIAL PARTITION PLACING POSITION
+%token <str>   PARENT PARTIAL PARTITION PASSWORD PAUSE PLACING POSITION
 %token <str>   PRECEDING PRECISION PREPARE PRIMARY PRIORITY
 
-%token <str>   RANGE READ REAL RECURSIVE REF REFERENCES
+%token <str>   QUERIES QUERY
+
+%token <str>   RANGE RANGES READ REAL RECURSIVE REF REFERENCES
 %token <str>   RENAME REPEATABLE
-%token <str>   RELEASE RESTRICT RETURNING REVOKE RIGHT ROLLBACK ROLLUP
+%token <str>   RELEASE RESTORE RESTRICT RESUME RETURNING REVOKE RIGHT ROLE ROLLBACK ROLLUP
 %token <str>   ROW ROWS RSHIFT
 
-%token <str>   SAVEPOINT SEARCH SECOND SELECT
-%token <str>   SERIAL SERIALIZABLE SESSION SESSION_USER SET SHOW
-%token <str>   SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SQL
-%token <str>   START STRICT STRING STORING SUBSTRING
+%token <str>   SAVEPOINT SCATTER SEARCH SECOND SELECT SEQUENCE
+%token <str>   SERIAL SERIALIZABLE SESSION SESSION_USER SET SETTING SETTINGS SHARE SHOW
+%token <str>   SIMILAR SIMPLE SMALLINT SMALLSERIAL SNAPSHOT SOME SPLIT SQL
+%token <str>   START STATISTICS STDIN STDOUT STRICT STRING STORED STORING SUBSTRING
 %token <str>   SYMMETRIC SYSTEM
 
-%token <str>   TABLE TABLES TEXT THEN
-%token <str>   TIME TIMESTAMP TIMESTAMPTZ TO TRAILING TRANSACTION TREAT TRIM TRUE
+%token <str>   TABLE TABLES TEMP TEMPORARY TEXT THEN
+%token <str>   TIME TIMESTAMP TIMESTAMPTZ TO TRACE TRAILING TRANSACTION TREAT TRIM TRUE
 %token <str>   TRUNCATE TYPE
 
 %token <str>   UNBOUNDED UNCOMMITTED UNION UNIQUE UNKNOWN
 %token <str>   UPDATE UPSERT USER USING
 
-%token <str>   VALID VALIDATE VALUE VALUES VARCHAR VARIADIC VARYING
+%token <str>   VALID VALIDATE VALUE VALUES VARCHAR VARIADIC VARYING VIEW
 
 %token <str>   WHEN WHERE WINDOW WITH WITHIN WITHOUT
 
@@ -650,7 +750,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 %left      AND
 %right     NOT
 %nonassoc  IS                  // IS sets precedence for IS NULL, etc
-%nonassoc  '<' '>' '=' LESS_EQUALS GREATER_EQUALS NOT_EQUALS
+%nonassoc  '<' '>' '=' LESS_EQUALS GREATER_EQUALS NOT_EQUALS CONTAINS '?'
 %nonassoc  BETWEEN IN LIKE ILIKE SIMILAR NOT_REGMATCH REGIMATCH, NOT_REGIMATCH NOT_LA
 %nonassoc  ESCAPE              // ESCAPE must be just above LIKE/ILIKE/SIMILAR
 %nonassoc  OVERLAPS
@@ -680,7 +780,7 @@ func (u *sqlSymUnion) interleave() *InterleaveDef {
 // funny behavior of UNBOUNDED on the SQL standard, though.
 %nonassoc  UNBOUNDED         // ideally should have same precedence as IDENT
 %nonassoc  IDENT NULL PARTITION RANGE ROWS PRECEDING FOLLOWING CUBE ROLLUP
-%left      CONCAT       // multi-character ops
+%left      CONCAT FETCHVAL FETCHTEXT // multi-character ops
 %left      '|'
 %left      '^' '#'
 %left      '&'
@@ -731,15 +831,24 @@ stmt_list:
 
 stmt:
   alter_table_stmt
+| alter_index_stmt
+| backup_stmt
+| cancel_stmt
+| configure_zone_stmt
+| copy_from_stmt
+| copy_to_stmt
 | create_stmt
 | delete_stmt
 | drop_stmt
 | explain_stmt
+| export_stmt
 | prepare_stmt
 | execute_stmt
 | deallocate_stmt
 | grant_stmt
+| import_stmt
 | insert_stmt
+| pause_stmt
 | rename_stmt
 | revoke_stmt
 | savepoint_stmt
@@ -751,6 +860,8 @@ stmt:
 | show_stmt
 | transaction_stmt
 | release_stmt
+| restore_stmt
+| resume_stmt
 | truncate_stmt
 | update_stmt
 | /* EMPTY */
@@ -767,6 +878,32 @@ alter_table_stmt:
   {
     $$.val = &AlterTable{Table: $5.normalizableTableName(), IfExists: true, Cmds: $6.alterTableCmds()}
   }
+| ALTER TABLE qualified_name SPLIT AT select_stmt
+  {
+    $$.val = &Split{Table: $3.newNormalizableTableName(), Rows: $6.slct()}
+  }
+| ALTER TABLE qualified_name SCATTER
+  {
+    $$.val = &Scatter{Table: $3.newNormalizableTableName()}
+  }
+| ALTER TABLE qualified_name EXPERIMENTAL_RELOCATE select_stmt
+  {
+    $$.val = &Relocate{Table: $3.newNormalizableTableName(), Rows: $5.slct()}
+  }
+
+alter_index_stmt:
+  ALTER INDEX table_name_with_index SPLIT AT select_stmt
+  {
+    $$.val = &Split{Index: $3.tableWithIdx(), Rows: $6.slct()}
+  }
+| ALTER INDEX table_name_with_index SCATTER
+  {
+    $$.val = &Scatter{Index: $3.tableWithIdx()}
+  }
+| ALTER INDEX table_name_with_index EXPERIMENTAL_RELOCATE select_stmt
+  {
+    $$.val = &Relocate{Index: $3.tableWithIdx(), Rows: $5.slct()}
+  }
 
 alter_table_cmds:
   alter_table_cmd
@@ -828,7 +965,20 @@ alter_table_cmd:
   }
   // ALTER TABLE <name> ALTER [COLUMN] <colname> [SET DATA] TYPE <typename>
   //     [ USING <expression> ]
-| ALTER opt_column name opt_set_data TYPE typename opt_collate_clause alter_using {}
+| ALTER opt_column name opt_set_data TYPE typename opt_collate_clause alter_using
+  {
+    $$.val = &AlterTableAlterColumnType{
+      columnKeyword: $2.bool(),
+      Column: Name($3),
+      ToType: $6.colType(),
+      Using: $8.expr(),
+    }
+  }
+  // ALTER TABLE <name> ALTER PRIMARY KEY USING COLUMNS ( <colnames...> )
+| ALTER PRIMARY KEY USING COLUMNS '(' index_params ')'
+  {
+    $$.val = &AlterTableAlterPrimaryKey{Columns: $7.idxElems()}
+  }
   // ALTER TABLE <name> ADD CONSTRAINT ...
 | ADD table_constraint
   {
@@ -886,20 +1036,244 @@ opt_collate_clause:
 | /* EMPTY */ {}
 
 alter_using:
-  USING a_expr { unimplemented() }
-| /* EMPTY */ {}
+  USING a_expr
+  {
+    $$.val = $2.expr()
+  }
+| /* EMPTY */
+  {
+    $$.val = Expr(nil)
+  }
+
+// CANCEL QUERY <query_id>
+// CANCEL JOB <job_id>
+cancel_stmt:
+  CANCEL QUERY a_expr
+  {
+    $$.val = &CancelQuery{ID: $3.expr()}
+  }
+| CANCEL JOB a_expr
+  {
+    $$.val = &CancelJob{ID: $3.expr()}
+  }
+
+// COPY table [(column [, ...])] FROM STDIN
+copy_from_stmt:
+  COPY qualified_name FROM STDIN
+  {
+    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Stdin: true}
+  }
+| COPY qualified_name '(' qualified_name_list ')' FROM STDIN
+  {
+    $$.val = &CopyFrom{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdin: true}
+  }
+
+// COPY table [(column [, ...])] TO STDOUT [[WITH] (option [, ...])]
+// COPY (select) TO STDOUT [[WITH] (option [, ...])]
+//
+// The options are FORMAT text|csv and HEADER. The older WITH CSV [HEADER]
+// syntax is accepted as well.
+copy_to_stmt:
+  COPY qualified_name TO STDOUT opt_copy_options
+  {
+    $$.val = &CopyTo{Table: $2.normalizableTableName(), Stdout: true, Options: *$5.copyOptions()}
+  }
+| COPY qualified_name '(' qualified_name_list ')' TO STDOUT opt_copy_options
+  {
+    $$.val = &CopyTo{Table: $2.normalizableTableName(), Columns: $4.unresolvedNames(), Stdout: true, Options: *$8.copyOptions()}
+  }
+| COPY select_with_parens TO STDOUT opt_copy_options
+  {
+    $$.val = &CopyTo{Stmt: &Select{Select: $2.selectStmt()}, Stdout: true, Options: *$5.copyOptions()}
+  }
 
-// CREATE [DATABASE|INDEX|TABLE|TABLE AS]
+opt_copy_options:
+  WITH '(' copy_option_list ')'
+  {
+    $$.val = $3.copyOptions()
+  }
+| '(' copy_option_list ')'
+  {
+    $$.val = $2.copyOptions()
+  }
+| WITH copy_csv_options
+  {
+    $$.val = $2.copyOptions()
+  }
+| copy_csv_options
+| /* EMPTY */
+  {
+    $$.val = &CopyOptions{}
+  }
+
+copy_csv_options:
+  CSV
+  {
+    $$.val = &CopyOptions{DataFormat: "csv"}
+  }
+| CSV HEADER
+  {
+    $$.val = &CopyOptions{DataFormat: "csv", Header: true}
+  }
+
+copy_option_list:
+  copy_option
+| copy_option_list ',' copy_option
+  {
+    opts, opt := $1.copyOptions(), $3.copyOptions()
+    if opt.DataFormat != "" {
+      opts.DataFormat = opt.DataFormat
+    }
+    opts.Header = opts.Header || opt.Header
+    $$.val = opts
+  }
+
+copy_option:
+  FORMAT name
+  {
+    $$.val = &CopyOptions{DataFormat: $2}
+  }
+| HEADER
+  {
+    $$.val = &CopyOptions{Header: true}
+  }
+
+// BACKUP [TABLE] name [, ...] TO 'destination' [WITH option = 'value' [, ...]]
+// BACKUP DATABASE name [, ...] TO 'destination' [WITH option = 'value' [, ...]]
+backup_stmt:
+  BACKUP privilege_target TO SCONST opt_with_options
+  {
+    $$.val = &Backup{Targets: $2.targetList(), To: $4, Options: $5.kvOptions()}
+  }
+
+// RESTORE [TABLE] name [, ...] FROM 'source' [WITH option = 'value' [, ...]]
+// RESTORE DATABASE name [, ...] FROM 'source' [WITH option = 'value' [, ...]]
+restore_stmt:
+  RESTORE privilege_target FROM SCONST opt_with_options
+  {
+    $$.val = &Restore{Targets: $2.targetList(), From: $4, Options: $5.kvOptions()}
+  }
+
+// PAUSE JOB <job_id>
+pause_stmt:
+  PAUSE JOB a_expr
+  {
+    $$.val = &PauseJob{ID: $3.expr()}
+  }
+
+// RESUME JOB <job_id>
+resume_stmt:
+  RESUME JOB a_expr
+  {
+    $$.val = &ResumeJob{ID: $3.expr()}
+  }
+
+// IMPORT TABLE name (table_elem [, ...]) CSV DATA ('file' [, ...])
+//     [WITH option = 'value' [, ...]]
+import_stmt:
+  IMPORT TABLE any_name '(' table_elem_list ')' CSV DATA '(' string_list ')' opt_with_options
+  {
+    $$.val = &Import{Table: $3.normalizableTableName(), Defs: $5.tblDefs(), FileFormat: "CSV", Files: $10.strs(), Options: $12.kvOptions()}
+  }
+
+// EXPORT INTO CSV 'destination' [WITH option = 'value' [, ...]] FROM query
+export_stmt:
+  EXPORT INTO CSV SCONST opt_with_options FROM select_stmt
+  {
+    $$.val = &Export{Query: $7.slct(), FileFormat: "CSV", File: $4, Options: $5.kvOptions()}
+  }
+
+string_list:
+  SCONST
+  {
+    $$.val = []string{$1}
+  }
+| string_list ',' SCONST
+  {
+    $$.val = append($1.strs(), $3)
+  }
+
+opt_with_options:
+  WITH kv_option_list
+  {
+    $$.val = $2.kvOptions()
+  }
+| /* EMPTY */
+  {
+    $$.val = KVOptions(nil)
+  }
+
+kv_option_list:
+  kv_option
+| kv_option_list ',' kv_option
+  {
+    $$.val = append($1.kvOptions(), $3.kvOptions()...)
+  }
+
+kv_option:
+  name '=' SCONST
+  {
+    $$.val = KVOptions{{Key: $1, Value: $3}}
+  }
+
+// ALTER [DATABASE|TABLE] name CONFIGURE ZONE [USING settings|DISCARD]
+// ALTER PARTITION name OF TABLE name CONFIGURE ZONE [USING settings|DISCARD]
+configure_zone_stmt:
+  ALTER DATABASE name CONFIGURE ZONE USING zone_setting_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Database: Name($3)}, Settings: $7.zoneSettings()}
+  }
+| ALTER TABLE relation_expr CONFIGURE ZONE USING zone_setting_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $3.normalizableTableName()}, Settings: $7.zoneSettings()}
+  }
+| ALTER DATABASE name CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Database: Name($3)}}
+  }
+| ALTER TABLE relation_expr CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $3.normalizableTableName()}}
+  }
+| ALTER PARTITION name OF TABLE relation_expr CONFIGURE ZONE USING zone_setting_list
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $6.normalizableTableName(), Partition: Name($3)}, Settings: $10.zoneSettings()}
+  }
+| ALTER PARTITION name OF TABLE relation_expr CONFIGURE ZONE DISCARD
+  {
+    $$.val = &SetZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $6.normalizableTableName(), Partition: Name($3)}}
+  }
+
+zone_setting_list:
+  zone_setting
+| zone_setting_list ',' zone_setting
+  {
+    $$.val = append($1.zoneSettings(), $3.zoneSettings()...)
+  }
+
+zone_setting:
+  name '=' a_expr
+  {
+    $$.val = ZoneSettings{{Key: Name($1), Value: $3.expr()}}
+  }
+
+// CREATE [DATABASE|INDEX|ROLE|SEQUENCE|STATISTICS|TABLE|TABLE AS|USER|VIEW]
 create_stmt:
-  create_database_stmt
+  create_changefeed_stmt
+| create_database_stmt
 | create_index_stmt
+| create_role_stmt
+| create_sequence_stmt
+| create_stats_stmt
 | create_table_stmt
+| create_user_stmt
+| create_view_stmt
 
 // DELETE FROM query
 delete_stmt:
   opt_with_clause DELETE FROM relation_expr_opt_alias where_clause returning_clause
   {
-    $$.val = &Delete{Table: $4.tblExpr(), Where: newWhere(astWhere, $5.expr()), Returning: $6.retExprs()}
+    $$.val = &Delete{With: $1.with(), Table: $4.tblExpr(), Where: newWhere(astWhere, $5.expr()), Returning: $6.retExprs()}
   }
 
 // DROP itemtype [ IF EXISTS ] itemname [, itemname ...] [ RESTRICT | CASCADE ]
@@ -936,6 +1310,30 @@ drop_stmt:
   {
     $$.val = &DropTable{Names: $5.tableNameReferences(), IfExists: true, DropBehavior: $6.dropBehavior()}
   }
+| DROP VIEW table_name_list opt_drop_behavior
+  {
+    $$.val = &DropView{Names: $3.tableNameReferences(), IfExists: false, DropBehavior: $4.dropBehavior()}
+  }
+| DROP VIEW IF EXISTS table_name_list opt_drop_behavior
+  {
+    $$.val = &DropView{Names: $5.tableNameReferences(), IfExists: true, DropBehavior: $6.dropBehavior()}
+  }
+| DROP SEQUENCE table_name_list opt_drop_behavior
+  {
+    $$.val = &DropSequence{Names: $3.tableNameReferences(), IfExists: false, DropBehavior: $4.dropBehavior()}
+  }
+| DROP SEQUENCE IF EXISTS table_name_list opt_drop_behavior
+  {
+    $$.val = &DropSequence{Names: $5.tableNameReferences(), IfExists: true, DropBehavior: $6.dropBehavior()}
+  }
+| DROP ROLE name_list
+  {
+    $$.val = &DropRole{Names: $3.nameList(), IfExists: false}
+  }
+| DROP ROLE IF EXISTS name_list
+  {
+    $$.val = &DropRole{Names: $5.nameList(), IfExists: true}
+  }
 
 table_name_list:
   any_name
@@ -973,11 +1371,19 @@ explain_stmt:
   {
     $$.val = &Explain{Statement: $2.stmt()}
   }
+| EXPLAIN analyze_kw explainable_stmt
+  {
+    $$.val = &Explain{Options: []string{"ANALYZE"}, Statement: $3.stmt()}
+  }
 | EXPLAIN '(' explain_option_list ')' explainable_stmt
   {
     $$.val = &Explain{Options: $3.strs(), Statement: $5.stmt()}
   }
 
+analyze_kw:
+  ANALYZE
+| ANALYSE
+
 explainable_stmt:
   select_stmt
   {
@@ -986,6 +1392,7 @@ explainable_stmt:
 | create_stmt
 | drop_stmt
 | alter_table_stmt
+| alter_index_stmt
 | insert_stmt
 | update_stmt
 | delete_stmt
@@ -1003,6 +1410,7 @@ explain_option_list:
 
 explain_option_name:
   non_reserved_word
+| analyze_kw
 
 // PREPARE <plan_name> [(args, ...)] AS <query>
 prepare_stmt:
@@ -1080,18 +1488,36 @@ deallocate_stmt:
   }
 
 // GRANT privileges ON privilege_target TO grantee_list
+// GRANT role_list TO grantee_list [WITH ADMIN OPTION]
 grant_stmt:
   GRANT privileges ON privilege_target TO grantee_list
   {
     $$.val = &Grant{Privileges: $2.privilegeList(), Grantees: $6.nameList(), Targets: $4.targetList()}
   }
+| GRANT privilege_list TO grantee_list
+  {
+    $$.val = &GrantRole{Roles: $2.nameList(), Members: $4.nameList(), AdminOption: false}
+  }
+| GRANT privilege_list TO grantee_list WITH ADMIN OPTION
+  {
+    $$.val = &GrantRole{Roles: $2.nameList(), Members: $4.nameList(), AdminOption: true}
+  }
 
 // REVOKE privileges ON privilege_target FROM grantee_list
+// REVOKE [ADMIN OPTION FOR] role_list FROM grantee_list
 revoke_stmt:
   REVOKE privileges ON privilege_target FROM grantee_list
   {
     $$.val = &Revoke{Privileges: $2.privilegeList(), Grantees: $6.nameList(), Targets: $4.targetList()}
   }
+| REVOKE privilege_list FROM grantee_list
+  {
+    $$.val = &RevokeRole{Roles: $2.nameList(), Members: $4.nameList(), AdminOption: false}
+  }
+| REVOKE ADMIN OPTION FOR privilege_list FROM grantee_list
+  {
+    $$.val = &RevokeRole{Roles: $5.nameList(), Members: $7.nameList(), AdminOption: true}
+  }
 
 
 privilege_target:
@@ -1114,48 +1540,35 @@ privileges:
   {
     $$.val = privilege.List{privilege.ALL}
   }
-  | privilege_list { }
+| privilege_list
+  {
+    privList, err := privilege.ListFromStrings($1.nameList().ToStrings())
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = privList
+  }
 
+// A privilege_list may also be a list of roles, in GRANT and REVOKE
+// statements without an ON clause.
 privilege_list:
   privilege
   {
-    $$.val = privilege.List{$1.privilegeType()}
+    $$.val = NameList{Name($1)}
   }
-  | privilege_list ',' privilege
+| privilege_list ',' privilege
   {
-    $$.val = append($1.privilegeList(), $3.privilegeType())
+    $$.val = append($1.nameList(), Name($3))
   }
 
-// This list must match the list of privileges in sql/privilege/privilege.go.
+// Privileges which are not reserved keywords are parsed as names. This list
+// must match the list of privileges in sql/privilege/privilege.go.
 privilege:
-  CREATE
-  {
-    $$.val = privilege.CREATE
-  }
-| DROP
-  {
-    $$.val = privilege.DROP
-  }
+  name
+| CREATE
 | GRANT
-  {
-    $$.val = privilege.GRANT
-  }
 | SELECT
-  {
-    $$.val = privilege.SELECT
-  }
-| INSERT
-  {
-    $$.val = privilege.INSERT
-  }
-| DELETE
-  {
-    $$.val = privilege.DELETE
-  }
-| UPDATE
-  {
-    $$.val = privilege.UPDATE
-  }
 
 // TODO(marc): this should not be 'name', but should instead be a
 // type just for usernames.
@@ -1171,11 +1584,28 @@ grantee_list:
 
 // SET name TO 'var_value'
 // SET TIME ZONE 'var_value'
+// SET CLUSTER SETTING name TO 'var_value'
 set_stmt:
   SET set_rest
   {
     $$.val = $2.stmt()
   }
+| SET CLUSTER SETTING var_name TO var_value
+  {
+    $$.val = &SetClusterSetting{Name: $4.unresolvedName().String(), Value: $6.expr()}
+  }
+| SET CLUSTER SETTING var_name '=' var_value
+  {
+    $$.val = &SetClusterSetting{Name: $4.unresolvedName().String(), Value: $6.expr()}
+  }
+| SET CLUSTER SETTING var_name TO DEFAULT
+  {
+    $$.val = &SetClusterSetting{Name: $4.unresolvedName().String()}
+  }
+| SET CLUSTER SETTING var_name '=' DEFAULT
+  {
+    $$.val = &SetClusterSetting{Name: $4.unresolvedName().String()}
+  }
 | SET LOCAL set_rest
   {
     $$.val = $3.stmt()
@@ -1423,6 +1853,46 @@ show_stmt:
   {
     $$.val = &ShowIndex{Table: $4.normalizableTableName()}
   }
+| SHOW JOBS
+  {
+    $$.val = &ShowJobs{}
+  }
+| SHOW QUERIES
+  {
+    $$.val = &ShowQueries{}
+  }
+| SHOW CLUSTER QUERIES
+  {
+    $$.val = &ShowQueries{Cluster: true}
+  }
+| SHOW RANGES FROM TABLE var_name
+  {
+    $$.val = &ShowRanges{Table: $5.newNormalizableTableName()}
+  }
+| SHOW RANGES FROM INDEX table_name_with_index
+  {
+    $$.val = &ShowRanges{Index: $5.tableWithIdx()}
+  }
+| SHOW CLUSTER SETTING var_name
+  {
+    $$.val = &ShowClusterSetting{Name: $4.unresolvedName().String()}
+  }
+| SHOW ALL CLUSTER SETTINGS
+  {
+    $$.val = &ShowClusterSetting{Name: "all"}
+  }
+| SHOW ZONE CONFIGURATION FOR DATABASE name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier: ZoneSpecifier{Database: Name($6)}}
+  }
+| SHOW ZONE CONFIGURATION FOR TABLE var_name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $6.normalizableTableName()}}
+  }
+| SHOW ZONE CONFIGURATION FOR PARTITION name OF TABLE var_name
+  {
+    $$.val = &ShowZoneConfig{ZoneSpecifier: ZoneSpecifier{Table: $9.normalizableTableName(), Partition: Name($6)}}
+  }
 | SHOW TABLES FROM name
   {
     $$.val = &ShowTables{Database: Name($4)}
@@ -1431,6 +1901,14 @@ show_stmt:
   {
     $$.val = &ShowTables{}
   }
+| SHOW TRACE FOR SESSION
+  {
+    $$.val = &ShowTrace{}
+  }
+| SHOW TRACE FOR explainable_stmt
+  {
+    $$.val = &ShowTrace{Statement: $4.stmt()}
+  }
 | SHOW TIME ZONE
   {
     $$.val = &Show{Name: "TIME ZONE"}
@@ -1451,6 +1929,10 @@ show_stmt:
   {
     $$.val = &ShowCreateTable{Table: $4.normalizableTableName()}
   }
+| SHOW CREATE VIEW var_name
+  {
+    $$.val = &ShowCreateView{View: $4.normalizableTableName()}
+  }
 
 on_privilege_target_clause:
   ON privilege_target
@@ -1475,13 +1957,188 @@ for_grantee_clause:
 
 // CREATE TABLE relname
 create_table_stmt:
-  CREATE TABLE any_name '(' opt_table_elem_list ')' opt_interleave
+  CREATE opt_temp TABLE any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
+  {
+    $$.val = &CreateTable{Table: $4.normalizableTableName(), IfNotExists: false, Temporary: $2.bool(), Interleave: $8.interleave(), PartitionBy: $9.partitionBy(), Defs: $6.tblDefs()}
+  }
+| CREATE opt_temp TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave opt_partition_by
+  {
+    $$.val = &CreateTable{Table: $7.normalizableTableName(), IfNotExists: true, Temporary: $2.bool(), Interleave: $11.interleave(), PartitionBy: $12.partitionBy(), Defs: $9.tblDefs()}
+  }
+
+// Temporary tables are dropped at the end of the session that created them.
+// As in postgres, GLOBAL is not supported and LOCAL is ignored.
+opt_temp:
+  TEMPORARY
+  {
+    $$.val = true
+  }
+| TEMP
+  {
+    $$.val = true
+  }
+| LOCAL TEMPORARY
+  {
+    $$.val = true
+  }
+| LOCAL TEMP
   {
-    $$.val = &CreateTable{Table: $3.normalizableTableName(), IfNotExists: false, Interleave: $7.interleave(), Defs: $5.tblDefs()}
+    $$.val = true
+  }
+| /* EMPTY */
+  {
+    $$.val = false
+  }
+
+// CREATE VIEW relname
+create_view_stmt:
+  CREATE VIEW any_name opt_column_list AS select_stmt
+  {
+    $$.val = &CreateView{
+      Name: $3.normalizableTableName(),
+      ColumnNames: $4.nameList(),
+      AsSource: $6.slct(),
+    }
+  }
+
+// CREATE SEQUENCE [IF NOT EXISTS] name
+//     [INCREMENT [BY] n] [MINVALUE n | NO MINVALUE] [MAXVALUE n | NO MAXVALUE]
+//     [START [WITH] n]
+create_sequence_stmt:
+  CREATE SEQUENCE any_name opt_sequence_option_list
+  {
+    $$.val = &CreateSequence{
+      Name: $3.normalizableTableName(),
+      Options: $4.seqOpts(),
+    }
+  }
+| CREATE SEQUENCE IF NOT EXISTS any_name opt_sequence_option_list
+  {
+    $$.val = &CreateSequence{
+      IfNotExists: true,
+      Name: $6.normalizableTableName(),
+      Options: $7.seqOpts(),
+    }
+  }
+
+// CREATE CHANGEFEED FOR [TABLE] name [, ...] INTO 'sink'
+//     [WITH option = 'value' [, ...]]
+create_changefeed_stmt:
+  CREATE CHANGEFEED FOR changefeed_targets INTO SCONST opt_with_options
+  {
+    $$.val = &CreateChangefeed{Targets: $4.targetList(), SinkURI: $6, Options: $7.kvOptions()}
+  }
+
+changefeed_targets:
+  table_pattern_list
+  {
+    $$.val = TargetList{Tables: $1.tablePatterns()}
+  }
+| TABLE table_pattern_list
+  {
+    $$.val = TargetList{Tables: $2.tablePatterns()}
+  }
+
+// CREATE STATISTICS name [ON column [, ...]] FROM table
+create_stats_stmt:
+  CREATE STATISTICS name opt_stats_columns FROM qualified_name
+  {
+    $$.val = &CreateStats{
+      Name: Name($3),
+      ColumnNames: $4.nameList(),
+      Table: $6.normalizableTableName(),
+    }
+  }
+
+opt_stats_columns:
+  ON name_list
+  {
+    $$.val = $2.nameList()
+  }
+| /* EMPTY */
+  {
+    $$.val = NameList(nil)
+  }
+
+opt_sequence_option_list:
+  sequence_option_list
+| /* EMPTY */
+  {
+    $$.val = SequenceOptions(nil)
+  }
+
+sequence_option_list:
+  sequence_option_elem
+  {
+    $$.val = SequenceOptions{$1.seqOpt()}
+  }
+| sequence_option_list sequence_option_elem
+  {
+    $$.val = append($1.seqOpts(), $2.seqOpt())
+  }
+
+sequence_option_elem:
+  INCREMENT signed_iconst
+  {
+    x, err := $2.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptIncrement, IntVal: &x}
+  }
+| INCREMENT BY signed_iconst
+  {
+    x, err := $3.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptIncrement, IntVal: &x}
+  }
+| MINVALUE signed_iconst
+  {
+    x, err := $2.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptMinValue, IntVal: &x}
+  }
+| NO MINVALUE
+  {
+    $$.val = SequenceOption{Name: SeqOptMinValue}
+  }
+| MAXVALUE signed_iconst
+  {
+    x, err := $2.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptMaxValue, IntVal: &x}
+  }
+| NO MAXVALUE
+  {
+    $$.val = SequenceOption{Name: SeqOptMaxValue}
+  }
+| START signed_iconst
+  {
+    x, err := $2.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptStart, IntVal: &x}
   }
-| CREATE TABLE IF NOT EXISTS any_name '(' opt_table_elem_list ')' opt_interleave
+| START WITH signed_iconst
   {
-    $$.val = &CreateTable{Table: $6.normalizableTableName(), IfNotExists: true, Interleave: $10.interleave(), Defs: $8.tblDefs()}
+    x, err := $3.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = SequenceOption{Name: SeqOptStart, IntVal: &x}
   }
 
 opt_table_elem_list:
@@ -1528,10 +2185,59 @@ opt_interleave:
     $$.val = (*InterleaveDef)(nil)
   }
 
+opt_partition_by:
+  PARTITION BY LIST '(' name_list ')' '(' list_partitions ')'
+  {
+    $$.val = &PartitionBy{Fields: $5.nameList(), List: $8.listPartitions()}
+  }
+| PARTITION BY RANGE '(' name_list ')' '(' range_partitions ')'
+  {
+    $$.val = &PartitionBy{Fields: $5.nameList(), Range: $8.rangePartitions()}
+  }
+| /* EMPTY */
+  {
+    $$.val = (*PartitionBy)(nil)
+  }
+
+list_partitions:
+  list_partition
+| list_partitions ',' list_partition
+  {
+    $$.val = append($1.listPartitions(), $3.listPartitions()...)
+  }
+
+list_partition:
+  PARTITION name VALUES IN '(' expr_list ')'
+  {
+    $$.val = []ListPartition{{Name: Name($2), Exprs: $6.exprs()}}
+  }
+
+range_partitions:
+  range_partition
+| range_partitions ',' range_partition
+  {
+    $$.val = append($1.rangePartitions(), $3.rangePartitions()...)
+  }
+
+range_partition:
+  PARTITION name VALUES '<' '(' expr_list ')'
+  {
+    $$.val = []RangePartition{{Name: Name($2), Exprs: $6.exprs()}}
+  }
+| PARTITION name VALUES '<' MAXVALUE
+  {
+    $$.val = []RangePartition{{Name: Name($2)}}
+  }
+
 column_def:
   name typename col_qual_list
   {
-    $$.val = newColumnTableDef(Name($1), $2.colType(), $3.colQuals())
+    tableDef, err := newColumnTableDef(Name($1), $2.colType(), $3.colQuals())
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = tableDef
   }
 
 col_qual_list:
@@ -1553,7 +2259,10 @@ col_qualification:
   {
     $$.val = NamedColumnQualification{Qualification: $1.colQualElem()}
   }
-| COLLATE any_name { unimplemented() }
+| COLLATE name
+  {
+    $$.val = NamedColumnQualification{Qualification: ColumnCollation($2)}
+  }
 | FAMILY name
   {
     $$.val = NamedColumnQualification{Qualification: &ColumnFamilyConstraint{Family: Name($2)}}
@@ -1609,20 +2318,26 @@ col_qualification_elem:
     $$.val = &ColumnFKConstraint{
       Table: $2.normalizableTableName(),
       Col: Name($3),
+      Actions: $5.referenceActions(),
     }
  }
+| AS '(' a_expr ')' STORED
+  {
+    $$.val = &ColumnComputedDef{Expr: $3.expr()}
+  }
 
 index_def:
-  INDEX opt_name '(' index_params ')' opt_storing opt_interleave
+  INDEX opt_name '(' index_params ')' opt_storing opt_interleave where_clause
   {
     $$.val = &IndexTableDef{
       Name:    Name($2),
       Columns: $4.idxElems(),
       Storing: $6.nameList(),
       Interleave: $7.interleave(),
+      Predicate: $8.expr(),
     }
   }
-| UNIQUE INDEX opt_name '(' index_params ')' opt_storing opt_interleave
+| UNIQUE INDEX opt_name '(' index_params ')' opt_storing opt_interleave where_clause
   {
     $$.val = &UniqueConstraintTableDef{
       IndexTableDef: IndexTableDef {
@@ -1630,9 +2345,19 @@ index_def:
         Columns: $5.idxElems(),
         Storing: $7.nameList(),
         Interleave: $8.interleave(),
+        Predicate: $9.expr(),
       },
     }
   }
+| INVERTED INDEX opt_name '(' index_params ')' where_clause
+  {
+    $$.val = &IndexTableDef{
+      Name:    Name($3),
+      Columns: $5.idxElems(),
+      Predicate: $7.expr(),
+      Inverted: true,
+    }
+  }
 
 family_def:
   FAMILY opt_name '(' name_list ')'
@@ -1664,13 +2389,14 @@ constraint_elem:
       Expr: $3.expr(),
     }
   }
-| UNIQUE '(' name_list ')' opt_storing opt_interleave
+| UNIQUE '(' name_list ')' opt_storing opt_interleave where_clause
   {
     $$.val = &UniqueConstraintTableDef{
       IndexTableDef: IndexTableDef{
         Columns: NameListToIndexElems($3.nameList()),
         Storing: $5.nameList(),
         Interleave: $6.interleave(),
+        Predicate: $7.expr(),
       },
     }
   }
@@ -1690,6 +2416,7 @@ constraint_elem:
       Table: $7.normalizableTableName(),
       FromCols: $4.nameList(),
       ToCols: $8.nameList(),
+      Actions: $10.referenceActions(),
     }
   }
 
@@ -1732,28 +2459,58 @@ key_match:
 | MATCH SIMPLE { unimplemented() }
 | /* EMPTY */ {}
 
-// We combine the update and delete actions into one value temporarily for
-// simplicity of parsing, and then break them down again in the calling
-// production. update is in the left 8 bits, delete in the right. Note that
-// NOACTION is the default.
+// Note that NO ACTION is the default.
 key_actions:
-  key_update { unimplemented() }
-| key_delete { unimplemented() }
-| key_update key_delete { unimplemented() }
-| key_delete key_update { unimplemented() }
-| /* EMPTY */ {}
+  key_update
+  {
+    $$.val = ReferenceActions{Update: $1.referenceAction()}
+  }
+| key_delete
+  {
+    $$.val = ReferenceActions{Delete: $1.referenceAction()}
+  }
+| key_update key_delete
+  {
+    $$.val = ReferenceActions{Update: $1.referenceAction(), Delete: $2.referenceAction()}
+  }
+| key_delete key_update
+  {
+    $$.val = ReferenceActions{Delete: $1.referenceAction(), Update: $2.referenceAction()}
+  }
+| /* EMPTY */
+  {
+    $$.val = ReferenceActions{}
+  }
 
 key_update:
-  ON UPDATE key_action { unimplemented() }
+  ON UPDATE key_action
+  {
+    $$.val = $3.referenceAction()
+  }
 
 key_delete:
-  ON DELETE key_action { unimplemented() }
+  ON DELETE key_action
+  {
+    $$.val = $3.referenceAction()
+  }
 
 key_action:
-  NO ACTION { unimplemented() }
-| RESTRICT { unimplemented() }
-| CASCADE { unimplemented() }
-| SET NULL { unimplemented() }
+  NO ACTION
+  {
+    $$.val = NoAction
+  }
+| RESTRICT
+  {
+    $$.val = Restrict
+  }
+| CASCADE
+  {
+    $$.val = Cascade
+  }
+| SET NULL
+  {
+    $$.val = SetNull
+  }
 | SET DEFAULT { unimplemented() }
 
 numeric_only:
@@ -1779,7 +2536,7 @@ truncate_stmt:
 
 // CREATE INDEX
 create_index_stmt:
-  CREATE opt_unique INDEX opt_name ON qualified_name '(' index_params ')' opt_storing opt_interleave
+  CREATE opt_unique INDEX opt_name ON qualified_name '(' index_params ')' opt_storing opt_interleave opt_partition_by where_clause
   {
     $$.val = &CreateIndex{
       Name:    Name($4),
@@ -1788,9 +2545,11 @@ create_index_stmt:
       Columns: $8.idxElems(),
       Storing: $10.nameList(),
       Interleave: $11.interleave(),
+      PartitionBy: $12.partitionBy(),
+      Predicate: $13.expr(),
     }
   }
-| CREATE opt_unique INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')' opt_storing opt_interleave
+| CREATE opt_unique INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')' opt_storing opt_interleave opt_partition_by where_clause
   {
     $$.val = &CreateIndex{
       Name:        Name($7),
@@ -1800,6 +2559,29 @@ create_index_stmt:
       Columns:     $11.idxElems(),
       Storing:     $13.nameList(),
       Interleave: $14.interleave(),
+      PartitionBy: $15.partitionBy(),
+      Predicate:   $16.expr(),
+    }
+  }
+| CREATE INVERTED INDEX opt_name ON qualified_name '(' index_params ')' where_clause
+  {
+    $$.val = &CreateIndex{
+      Name:      Name($4),
+      Table:     $6.normalizableTableName(),
+      Inverted:  true,
+      Columns:   $8.idxElems(),
+      Predicate: $10.expr(),
+    }
+  }
+| CREATE INVERTED INDEX IF NOT EXISTS name ON qualified_name '(' index_params ')' where_clause
+  {
+    $$.val = &CreateIndex{
+      Name:        Name($7),
+      Table:       $9.normalizableTableName(),
+      Inverted:    true,
+      IfNotExists: true,
+      Columns:     $11.idxElems(),
+      Predicate:   $13.expr(),
     }
   }
 
@@ -2004,6 +2786,37 @@ transaction_iso_level:
     $$.val = $3.isoLevel()
   }
 
+// CREATE ROLE [IF NOT EXISTS] name
+create_role_stmt:
+  CREATE ROLE name
+  {
+    $$.val = &CreateRole{Name: Name($3), IfNotExists: false}
+  }
+| CREATE ROLE IF NOT EXISTS name
+  {
+    $$.val = &CreateRole{Name: Name($6), IfNotExists: true}
+  }
+
+// CREATE USER [IF NOT EXISTS] name [WITH PASSWORD 'password']
+create_user_stmt:
+  CREATE USER name opt_password
+  {
+    $$.val = &CreateUser{Name: Name($3), Password: $4.strVal(), IfNotExists: false}
+  }
+| CREATE USER IF NOT EXISTS name opt_password
+  {
+    $$.val = &CreateUser{Name: Name($6), Password: $7.strVal(), IfNotExists: true}
+  }
+
+opt_password:
+  WITH PASSWORD SCONST
+  {
+    $$.val = &StrVal{s: $3}
+  }
+| /* EMPTY */ {
+    $$.val = (*StrVal)(nil)
+  }
+
 create_database_stmt:
   CREATE DATABASE name opt_encoding_clause
   {
@@ -2031,12 +2844,14 @@ insert_stmt:
   opt_with_clause INSERT INTO insert_target insert_rest returning_clause
   {
     $$.val = $5.stmt()
+    $$.val.(*Insert).With = $1.with()
     $$.val.(*Insert).Table = $4.tblExpr()
     $$.val.(*Insert).Returning = $6.retExprs()
   }
 | opt_with_clause INSERT INTO insert_target insert_rest on_conflict
   {
     $$.val = $5.stmt()
+    $$.val.(*Insert).With = $1.with()
     $$.val.(*Insert).Table = $4.tblExpr()
     $$.val.(*Insert).OnConflict = $6.onConflict()
   }
@@ -2044,6 +2859,7 @@ insert_stmt:
 | opt_with_clause UPSERT INTO insert_target insert_rest
   {
     $$.val = $5.stmt()
+    $$.val.(*Insert).With = $1.with()
     $$.val.(*Insert).Table = $4.tblExpr()
     $$.val.(*Insert).OnConflict = &OnConflict{}
   }
@@ -2113,7 +2929,7 @@ update_stmt:
   opt_with_clause UPDATE relation_expr_opt_alias
     SET set_clause_list update_from_clause where_clause returning_clause
   {
-    $$.val = &Update{Table: $3.tblExpr(), Exprs: $5.updateExprs(), Where: newWhere(astWhere, $7.expr()), Returning: $8.retExprs()}
+    $$.val = &Update{With: $1.with(), Table: $3.tblExpr(), Exprs: $5.updateExprs(), Where: newWhere(astWhere, $7.expr()), Returning: $8.retExprs()}
   }
 
 // Mark this as unimplemented until the normal from_clause is supported here.
@@ -2235,15 +3051,43 @@ select_no_parens:
   }
 | with_clause select_clause
   {
-    $$.val = &Select{Select: $2.selectStmt()}
+    $$.val = &Select{With: $1.with(), Select: $2.selectStmt()}
   }
 | with_clause select_clause sort_clause
   {
-    $$.val = &Select{Select: $2.selectStmt(), OrderBy: $3.orderBy()}
+    $$.val = &Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy()}
   }
 | with_clause select_clause opt_sort_clause select_limit
   {
-    $$.val = &Select{Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit()}
+    $$.val = &Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit()}
+  }
+| select_clause opt_sort_clause for_locking_clause opt_select_limit
+  {
+    $$.val = &Select{Select: $1.selectStmt(), OrderBy: $2.orderBy(), Limit: $4.limit(), Locking: $3.lockingStrength()}
+  }
+| select_clause opt_sort_clause select_limit for_locking_clause
+  {
+    $$.val = &Select{Select: $1.selectStmt(), OrderBy: $2.orderBy(), Limit: $3.limit(), Locking: $4.lockingStrength()}
+  }
+| with_clause select_clause opt_sort_clause for_locking_clause opt_select_limit
+  {
+    $$.val = &Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $5.limit(), Locking: $4.lockingStrength()}
+  }
+| with_clause select_clause opt_sort_clause select_limit for_locking_clause
+  {
+    $$.val = &Select{With: $1.with(), Select: $2.selectStmt(), OrderBy: $3.orderBy(), Limit: $4.limit(), Locking: $5.lockingStrength()}
+  }
+
+// We only support the strongest and weakest of the postgres row locking
+// strengths; FOR SHARE locks rows as strongly as FOR UPDATE.
+for_locking_clause:
+  FOR UPDATE
+  {
+    $$.val = LockForUpdate
+  }
+| FOR SHARE
+  {
+    $$.val = LockForShare
   }
 
 select_clause:
@@ -2283,6 +3127,7 @@ simple_select:
       Where:   newWhere(astWhere, $5.expr()),
       GroupBy: $6.groupBy(),
       Having:  newWhere(astHaving, $7.expr()),
+      Window:  $8.window(),
     }
   }
 | SELECT distinct_clause target_list
@@ -2296,6 +3141,7 @@ simple_select:
       Where:    newWhere(astWhere, $5.expr()),
       GroupBy:  $6.groupBy(),
       Having:   newWhere(astHaving, $7.expr()),
+      Window:   $8.window(),
     }
   }
 | values_clause
@@ -2344,20 +3190,44 @@ simple_select:
 //
 // Recognizing WITH_LA here allows a CTE to be named TIME or ORDINALITY.
 with_clause:
-WITH cte_list { unimplemented() }
-| WITH_LA cte_list { unimplemented() }
+  WITH cte_list
+  {
+    $$.val = &With{CTEList: $2.ctes()}
+  }
+| WITH_LA cte_list
+  {
+    $$.val = &With{CTEList: $2.ctes()}
+  }
 | WITH RECURSIVE cte_list { unimplemented() }
 
 cte_list:
-  common_table_expr { unimplemented() }
-| cte_list ',' common_table_expr { unimplemented() }
+  common_table_expr
+  {
+    $$.val = []*CTE{$1.cte()}
+  }
+| cte_list ',' common_table_expr
+  {
+    $$.val = append($1.ctes(), $3.cte())
+  }
 
 common_table_expr:
-  name opt_name_list AS '(' preparable_stmt ')' { unimplemented() }
+  name opt_name_list AS '(' preparable_stmt ')'
+  {
+    $$.val = &CTE{
+      Name: AliasClause{Alias: Name($1), Cols: $2.nameList()},
+      Stmt: $5.stmt(),
+    }
+  }
 
 opt_with_clause:
-  with_clause { unimplemented() }
-| /* EMPTY */ {}
+  with_clause
+  {
+    $$.val = $1.with()
+  }
+| /* EMPTY */
+  {
+    $$.val = (*With)(nil)
+  }
 
 opt_table:
   TABLE {}
@@ -2441,6 +3311,13 @@ select_limit:
 | limit_clause
 | offset_clause
 
+opt_select_limit:
+  select_limit
+| /* EMPTY */
+  {
+    $$.val = (*Limit)(nil)
+  }
+
 limit_clause:
   LIMIT select_limit_value
   {
@@ -2625,6 +3502,10 @@ table_ref:
   {
     $$.val = &AliasedTableExpr{Expr: &Subquery{Select: $1.selectStmt()}, As: $2.aliasClause()}
   }
+| func_application opt_alias_clause
+  {
+    $$.val = &AliasedTableExpr{Expr: $1.expr().(*FuncExpr), As: $2.aliasClause()}
+  }
 | joined_table
   {
     $$.val = $1.tblExpr()
@@ -2817,16 +3698,59 @@ where_clause:
 typename:
   simple_typename opt_array_bounds
   {
-    $$.val = $1.colType()
+    if bounds := $2.int32s(); bounds != nil {
+      var err error
+      $$.val, err = arrayOf($1.colType(), bounds)
+      if err != nil {
+        sqllex.Error(err.Error())
+        return 1
+      }
+    } else {
+      $$.val = $1.colType()
+    }
   }
   // SQL standard syntax, currently only one-dimensional
-| simple_typename ARRAY '[' ICONST ']' { unimplementedWithIssue(2115) }
-| simple_typename ARRAY { unimplementedWithIssue(2115) }
+| simple_typename ARRAY '[' ICONST ']'
+  {
+    bound, err := $4.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val, err = arrayOf($1.colType(), []int32{int32(bound)})
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+  }
+| simple_typename ARRAY
+  {
+    var err error
+    $$.val, err = arrayOf($1.colType(), []int32{-1})
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+  }
 
 opt_array_bounds:
-  opt_array_bounds '[' ']' { unimplementedWithIssue(2115) }
-| opt_array_bounds '[' ICONST ']' { unimplementedWithIssue(2115) }
-| /* EMPTY */ {}
+  array_bounds
+| /* EMPTY */ { $$.val = []int32(nil) }
+
+// Note that, as in Postgres, the sizes of array bounds are not enforced.
+array_bounds:
+  '[' ']' { $$.val = []int32{-1} }
+| '[' ICONST ']'
+  {
+    bound, err := $2.numVal().asInt64()
+    if err != nil {
+      sqllex.Error(err.Error())
+      return 1
+    }
+    $$.val = []int32{int32(bound)}
+  }
+| array_bounds '[' ']' { unimplementedWithIssue(2115) }
+| array_bounds '[' ICONST ']' { unimplementedWithIssue(2115) }
 
 simple_typename:
   numeric
@@ -2847,6 +3771,18 @@ simple_typename:
   {
     $$.val = bytesColTypeBytea
   }
+| JSON
+  {
+    $$.val = jsonColTypeJSON
+  }
+| JSONB
+  {
+    $$.val = jsonColTypeJSONB
+  }
+| INET
+  {
+    $$.val = inetColTypeINet
+  }
 | TEXT
   {
     $$.val = stringColTypeText
@@ -3159,7 +4095,10 @@ a_expr:
 //  {
 //    $$.val = &AnnotateTypeExpr{Expr: $1.expr(), Type: $3.colType()}
 //  }
-| a_expr COLLATE any_name { unimplemented() }
+| a_expr COLLATE name
+  {
+    $$.val = &CollateExpr{Expr: $1.expr(), Locale: $3}
+  }
 | a_expr AT TIME ZONE a_expr %prec AT { unimplemented() }
   // These operators must be called out explicitly in order to make use of
   // bison's automatic operator-precedence handling. All other operator names
@@ -3236,6 +4175,14 @@ a_expr:
   {
     $$.val = &BinaryExpr{Operator: Concat, Left: $1.expr(), Right: $3.expr()}
   }
+| a_expr FETCHVAL a_expr
+  {
+    $$.val = &BinaryExpr{Operator: JSONFetchVal, Left: $1.expr(), Right: $3.expr()}
+  }
+| a_expr FETCHTEXT a_expr
+  {
+    $$.val = &BinaryExpr{Operator: JSONFetchText, Left: $1.expr(), Right: $3.expr()}
+  }
 | a_expr LSHIFT a_expr
   {
     $$.val = &BinaryExpr{Operator: LShift, Left: $1.expr(), Right: $3.expr()}
@@ -3256,6 +4203,14 @@ a_expr:
   {
     $$.val = &ComparisonExpr{Operator: NE, Left: $1.expr(), Right: $3.expr()}
   }
+| a_expr CONTAINS a_expr
+  {
+    $$.val = &ComparisonExpr{Operator: Contains, Left: $1.expr(), Right: $3.expr()}
+  }
+| a_expr '?' a_expr
+  {
+    $$.val = &ComparisonExpr{Operator: JSONExists, Left: $1.expr(), Right: $3.expr()}
+  }
 | a_expr AND a_expr
   {
     $$.val = &AndExpr{Left: $1.expr(), Right: $3.expr()}
@@ -3385,8 +4340,14 @@ a_expr:
   {
     $$.val = &ComparisonExpr{Operator: NotIn, Left: $1.expr(), Right: $4.expr()}
   }
-// | a_expr subquery_op sub_type select_with_parens %prec CONCAT { unimplemented() }
-// | a_expr subquery_op sub_type '(' a_expr ')' %prec CONCAT { unimplemented() }
+| a_expr subquery_op sub_type select_with_parens %prec CONCAT
+  {
+    $$.val = &ComparisonExpr{Operator: $3.cmpOp(), SubOperator: $2.cmpOp(), Left: $1.expr(), Right: &Subquery{Select: $4.selectStmt()}}
+  }
+| a_expr subquery_op sub_type '(' a_expr ')' %prec CONCAT
+  {
+    $$.val = &ComparisonExpr{Operator: $3.cmpOp(), SubOperator: $2.cmpOp(), Left: $1.expr(), Right: $5.expr()}
+  }
 // | UNIQUE select_with_parens { unimplemented() }
 
 // Restricted expressions
@@ -3475,6 +4436,14 @@ b_expr:
   {
     $$.val = &BinaryExpr{Operator: Concat, Left: $1.expr(), Right: $3.expr()}
   }
+| b_expr FETCHVAL b_expr
+  {
+    $$.val = &BinaryExpr{Operator: JSONFetchVal, Left: $1.expr(), Right: $3.expr()}
+  }
+| b_expr FETCHTEXT b_expr
+  {
+    $$.val = &BinaryExpr{Operator: JSONFetchText, Left: $1.expr(), Right: $3.expr()}
+  }
 | b_expr LSHIFT b_expr
   {
     $$.val = &BinaryExpr{Operator: LShift, Left: $1.expr(), Right: $3.expr()}
@@ -3495,6 +4464,14 @@ b_expr:
   {
     $$.val = &ComparisonExpr{Operator: NE, Left: $1.expr(), Right: $3.expr()}
   }
+| b_expr CONTAINS b_expr
+  {
+    $$.val = &ComparisonExpr{Operator: Contains, Left: $1.expr(), Right: $3.expr()}
+  }
+| b_expr '?' b_expr
+  {
+    $$.val = &ComparisonExpr{Operator: JSONExists, Left: $1.expr(), Right: $3.expr()}
+  }
 | b_expr IS DISTINCT FROM b_expr %prec IS
   {
     $$.val = &ComparisonExpr{Operator: IsDistinctFrom, Left: $1.expr(), Right: $5.expr()}
@@ -3532,6 +4509,10 @@ c_expr:
   {
     $$.val = &ParenExpr{Expr: $2.expr()}
   }
+| '(' a_expr ')' array_subscripts
+  {
+    $$.val = &IndirectionExpr{Expr: &ParenExpr{Expr: $2.expr()}, Indirection: $4.arraySubscripts()}
+  }
 | case_expr
 | func_expr
 | select_with_parens %prec UMINUS
@@ -3576,11 +4557,11 @@ func_application:
 | func_name '(' expr_list ',' VARIADIC a_expr opt_sort_clause ')' { unimplemented() }
 | func_name '(' ALL expr_list opt_sort_clause ')'
   {
-    $$.val = &FuncExpr{Name: $1.normalizableFunctionName(), Type: All, Exprs: $4.exprs()}
+    $$.val = &FuncExpr{Name: $1.normalizableFunctionName(), Type: AllFuncType, Exprs: $4.exprs()}
   }
 | func_name '(' DISTINCT expr_list opt_sort_clause ')'
   {
-    $$.val = &FuncExpr{Name: $1.normalizableFunctionName(), Type: Distinct, Exprs: $4.exprs()}
+    $$.val = &FuncExpr{Name: $1.normalizableFunctionName(), Type: DistinctFuncType, Exprs: $4.exprs()}
   }
 | func_name '(' '*' ')'
   {
@@ -3597,7 +4578,9 @@ func_application:
 func_expr:
   func_application within_group_clause filter_clause over_clause
   {
-    $$.val = $1.expr()
+    f := $1.expr().(*FuncExpr)
+    f.WindowDef = $4.windowDef()
+    $$.val = f
   }
 | func_expr_common_subexpr
   {
@@ -3643,6 +4626,10 @@ func_expr_common_subexpr:
   {
     $$.val = &AnnotateTypeExpr{Expr: $3.expr(), Type: $5.colType()}
   }
+| FAMILY '(' a_expr ')'
+  {
+    $$.val = &FuncExpr{Name: WrapQualifiedFunctionName($1), Exprs: Exprs{$3.expr()}}
+  }
 | EXTRACT '(' extract_list ')'
   {
     $$.val = &FuncExpr{Name: WrapQualifiedFunctionName($1), Exprs: $3.exprs()}
@@ -3712,24 +4699,57 @@ filter_clause:
 
 // Window Definitions
 window_clause:
-  WINDOW window_definition_list { unimplemented() }
-| /* EMPTY */ {}
+  WINDOW window_definition_list
+  {
+    $$.val = $2.window()
+  }
+| /* EMPTY */
+  {
+    $$.val = Window(nil)
+  }
 
 window_definition_list:
-  window_definition { unimplemented() }
-| window_definition_list ',' window_definition { unimplemented() }
+  window_definition
+  {
+    $$.val = Window{$1.windowDef()}
+  }
+| window_definition_list ',' window_definition
+  {
+    $$.val = append($1.window(), $3.windowDef())
+  }
 
 window_definition:
-  name AS window_specification { unimplemented() }
+  name AS window_specification
+  {
+    n := $3.windowDef()
+    n.Name = Name($1)
+    $$.val = n
+  }
 
 over_clause:
-  OVER window_specification { unimplemented() }
-| OVER name { unimplemented() }
-| /* EMPTY */ {}
+  OVER window_specification
+  {
+    $$.val = $2.windowDef()
+  }
+| OVER name
+  {
+    $$.val = &WindowDef{Name: Name($2)}
+  }
+| /* EMPTY */
+  {
+    $$.val = (*WindowDef)(nil)
+  }
 
 window_specification:
   '(' opt_existing_window_name opt_partition_clause
-    opt_sort_clause opt_frame_clause ')' { unimplemented() }
+    opt_sort_clause opt_frame_clause ')'
+  {
+    $$.val = &WindowDef{
+      RefName: Name($2),
+      Partitions: $3.exprs(),
+      OrderBy: $4.orderBy(),
+    }
+  }
 
 // If we see PARTITION, RANGE, or ROWS as the first token after the '(' of a
 // window_specification, we want the assumption to be that there is no
@@ -3740,12 +4760,21 @@ window_specification:
 // keywords are thus precluded from being an existing_window_name but are not
 // reserved for any other purpose.
 opt_existing_window_name:
-  name { unimplemented() }
-| /* EMPTY */ %prec CONCAT {}
+  name
+| /* EMPTY */ %prec CONCAT
+  {
+    $$ = ""
+  }
 
 opt_partition_clause:
-  PARTITION BY expr_list { unimplemented() }
-| /* EMPTY */ {}
+  PARTITION BY expr_list
+  {
+    $$.val = $3.exprs()
+  }
+| /* EMPTY */
+  {
+    $$.val = Exprs(nil)
+  }
 
 // For frame clauses, we return a WindowDef, but only some fields are used:
 // frameOptions, startOffset, and endOffset.
@@ -3808,35 +4837,44 @@ implicit_row:
     $$.val = &Tuple{Exprs: append($2.exprs(), $4.expr())}
   }
 
-// sub_type:
-//   ANY { unimplemented() }
-// | SOME { unimplemented() }
-// | ALL { unimplemented() }
-
-// math_op:
-//   '+' { unimplemented() }
-// | '-' { unimplemented() }
-// | '*' { unimplemented() }
-// | '/' { unimplemented() }
-// | '%' { unimplemented() }
-// | '&' { unimplemented() }
-// | '|' { unimplemented() }
-// | '^' { unimplemented() }
-// | '#' { unimplemented() }
-// | '<' { unimplemented() }
-// | '>' { unimplemented() }
-// | '=' { unimplemented() }
-// | CONCAT { unimplemented() }
-// | LESS_EQUALS { unimplemented() }
-// | GREATER_EQUALS { unimplemented() }
-// | NOT_EQUALS { unimplemented() }
-
-// subquery_op:
-//   math_op { unimplemented() }
-// | LIKE { unimplemented() }
-// | NOT_LA LIKE { unimplemented() }
-// | ILIKE { unimplemented() }
-// | NOT_LA ILIKE { unimplemented() }
+sub_type:
+  ANY
+  {
+    $$.val = Any
+  }
+| SOME
+  {
+    $$.val = Some
+  }
+| ALL
+  {
+    $$.val = All
+  }
+
+math_op:
+  '+' { unimplemented() }
+| '-' { unimplemented() }
+| '*' { unimplemented() }
+| '/' { unimplemented() }
+| '%' { unimplemented() }
+| '&' { unimplemented() }
+| '|' { unimplemented() }
+| '^' { unimplemented() }
+| '#' { unimplemented() }
+| '<'  { $$.val = LT }
+| '>'  { $$.val = GT }
+| '='  { $$.val = EQ }
+| CONCAT { unimplemented() }
+| LESS_EQUALS    { $$.val = LE }
+| GREATER_EQUALS { $$.val = GE }
+| NOT_EQUALS     { $$.val = NE }
+
+subquery_op:
+  math_op
+| LIKE         { $$.val = Like }
+| NOT_LA LIKE  { $$.val = NotLike }
+| ILIKE        { $$.val = ILike }
+| NOT_LA ILIKE { $$.val = NotILike }
   // cannot put SIMILAR TO here, because SIMILAR TO is a hack.
   // the regular expression is preprocessed by a function (similar_escape),
   // and the ~ operator for posix regular expressions is used.
@@ -3868,15 +4906,15 @@ type_list:
 array_expr:
   '[' expr_list ']'
   {
-    $$.val = &Array{$2.exprs()}
+    $$.val = &Array{Exprs: $2.exprs()}
   }
 | '[' array_expr_list ']'
   {
-    $$.val = &Array{$2.exprs()}
+    $$.val = &Array{Exprs: $2.exprs()}
   }
 | '[' ']'
   {
-    $$.val = &Array{nil}
+    $$.val = &Array{Exprs: nil}
   }
 
 array_expr_list:
@@ -4065,13 +5103,9 @@ indirection_elem:
   {
     $$.val = $1.namePart()
   }
-| '[' a_expr ']'
-  {
-    $$.val = &ArraySubscript{Begin: $2.expr()}
-  }
-| '[' a_expr ':' a_expr ']'
+| array_subscript
   {
-    $$.val = &ArraySubscript{Begin: $2.expr(), End: $4.expr()}
+    $$.val = $1.arraySubscript()
   }
 
 name_indirection:
@@ -4086,6 +5120,26 @@ glob_indirection:
     $$.val = UnqualifiedStar{}
   }
 
+array_subscript:
+  '[' a_expr ']'
+  {
+    $$.val = &ArraySubscript{Begin: $2.expr()}
+  }
+| '[' a_expr ':' a_expr ']'
+  {
+    $$.val = &ArraySubscript{Begin: $2.expr(), End: $4.expr()}
+  }
+
+array_subscripts:
+  array_subscript
+  {
+    $$.val = ArraySubscripts{$1.arraySubscript()}
+  }
+| array_subscripts array_subscript
+  {
+    $$.val = append($1.arraySubscripts(), $2.arraySubscript())
+  }
+
 indirection:
   indirection_elem
   {
@@ -4254,7 +5308,10 @@ opt_name_list:
   {
     $$.val = $2.nameList()
   }
-| /* EMPTY */ {}
+| /* EMPTY */
+  {
+    $$.val = NameList(nil)
+  }
 
 // The production for a qualified func_name has to exactly match the production
 // for a qualified name, because we cannot tell which we are parsing until
@@ -4396,18 +5453,27 @@ col_label:
 unreserved_keyword:
   ACTION
 | ADD
+| ADMIN
 | ALTER
 | AT
+| BACKUP
 | BEGIN
 | BLOB
 | BY
+| CANCEL
 | CASCADE
+| CHANGEFEED
+| CLUSTER
 | COLUMNS
 | COMMIT
 | COMMITTED
+| CONFIGURATION
+| CONFIGURE
 | CONFLICT
 | CONSTRAINTS
+| COPY
 | COVERING
+| CSV
 | CUBE
 | CURRENT
 | CYCLE
@@ -4417,29 +5483,45 @@ unreserved_keyword:
 | DAY
 | DEALLOCATE
 | DELETE
+| DISCARD
 | DOUBLE
 | DROP
 | ENCODING
 | EXECUTE
+| EXPERIMENTAL_RELOCATE
 | EXPLAIN
+| EXPORT
 | FILTER
 | FIRST
 | FOLLOWING
 | FORCE_INDEX
+| FORMAT
 | GRANTS
+| HEADER
 | HIGH
 | HOUR
+| IMPORT
+| INCREMENT
 | INDEXES
+| INET
 | INSERT
 | INTERLEAVE
+| INVERTED
 | ISOLATION
+| JOB
+| JOBS
+| JSON
+| JSONB
 | KEY
 | KEYS
 | LEVEL
+| LIST
 | LOCAL
 | LOW
 | MATCH
+| MAXVALUE
 | MINUTE
+| MINVALUE
 | MONTH
 | NAME
 | NAMES
@@ -4451,42 +5533,64 @@ unreserved_keyword:
 | NULLS
 | OF
 | OFF
+| OPTION
 | ORDINALITY
 | OVER
 | PARENT
 | PARTIAL
 | PARTITION
+| PASSWORD
+| PAUSE
 | PRECEDING
 | PREPARE
 | PRIORITY
+| QUERIES
+| QUERY
 | RANGE
+| RANGES
 | READ
 | RECURSIVE
 | REF
 | RELEASE
 | RENAME
 | REPEATABLE
+| RESTORE
 | RESTRICT
+| RESUME
 | REVOKE
+| ROLE
 | ROLLBACK
 | ROLLUP
 | ROWS
 | SAVEPOINT
+| SCATTER
 | SEARCH
 | SECOND
+| SEQUENCE
 | SERIALIZABLE
 | SESSION
 | SET
+| SETTING
+| SETTINGS
+| SHARE
 | SHOW
 | SIMPLE
 | SNAPSHOT
+| SPLIT
 | SQL
 | START
+| STATISTICS
+| STDIN
+| STDOUT
+| STORED
 | STORING
 | STRICT
 | SYSTEM
 | TABLES
+| TEMP
+| TEMPORARY
 | TEXT
+| TRACE
 | TRANSACTION
 | TRUNCATE
 | TYPE
@@ -4499,6 +5603,7 @@ unreserved_keyword:
 | VALIDATE
 | VALUE
 | VARYING
+| VIEW
 | WITHIN
 | WITHOUT
 | YEAR
@@ -105,7 +105,7 @@ type TableName struct {
 
 // Format implements the NodeFormatter interface.
 func (t *TableName) Format(buf *bytes.Buffer, f FmtFlags) {
-	if !t.dbNameOriginallyOmitted {
+	if !t.dbNameOriginallyOmitted || (f.qualifyTableNames && t.DatabaseName != "") {
 		FormatNode(buf, f, t.DatabaseName)
 		buf.WriteByte('.')
 	}
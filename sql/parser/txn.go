@@ -106,19 +106,16 @@ func (node *RollbackTransaction) Format(buf *bytes.Buffer, f FmtFlags) {
 	buf.WriteString("ROLLBACK TRANSACTION")
 }
 
-// RestartSavepointName is the only savepoint name that we accept, modulo
-// capitalization.
+// RestartSavepointName is the savepoint name, modulo capitalization, that
+// clients use to declare their intent to retry the transaction.
 const RestartSavepointName string = "COCKROACH_RESTART"
 
-// ValidateRestartCheckpoint checks that a checkpoint name is our magic restart
+// IsRestartSavepoint returns true if a savepoint name is our magic restart
 // value.
 // We accept everything with the desired prefix because at least the C++ libpqxx
 // appends sequence numbers to the savepoint name specified by the user.
-func ValidateRestartCheckpoint(savepoint string) error {
-	if !strings.HasPrefix(strings.ToUpper(savepoint), RestartSavepointName) {
-		return fmt.Errorf("SAVEPOINT not supported except for %s", RestartSavepointName)
-	}
-	return nil
+func IsRestartSavepoint(savepoint string) bool {
+	return strings.HasPrefix(strings.ToUpper(savepoint), RestartSavepointName)
 }
 
 // Savepoint represents a SAVEPOINT <name> statement.
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/pkg/errors"
 )
 
@@ -45,10 +46,22 @@ var (
 	TypeTimestampTZ Datum = &DTimestampTZ{}
 	// TypeInterval is the type of a DInterval.
 	TypeInterval Datum = &DInterval{}
+	// TypeJSON is the type of a DJSON.
+	TypeJSON Datum = &DJSON{}
+	// TypeINet is the type of a DIPAddr.
+	TypeINet Datum = &DIPAddr{}
+	// TypeCollatedString is the type of a DCollatedString with any locale.
+	TypeCollatedString Datum = &DCollatedString{}
 	// TypeTuple is the type of a DTuple.
 	TypeTuple Datum = &DTuple{}
 )
 
+// arrayParamTypes are the element types of the supported ARRAY types.
+var arrayParamTypes = []Datum{
+	TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes,
+	TypeDate, TypeTimestamp, TypeTimestampTZ, TypeInterval,
+}
+
 // SemaContext defines the context in which to perform semantic analysis on an
 // expression syntax tree.
 type SemaContext struct {
@@ -222,6 +235,10 @@ func (expr *CastExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, err
 			// which will result in the CastExpr becoming an identity cast.
 			desired = returnDatum
 		}
+	case isArrayConstructor(expr.Expr):
+		// An array constructor subject to a cast to an array type takes its
+		// element type from the cast, which allows empty arrays to be typed.
+		desired = returnDatum
 	case ctx.isUnresolvedPlaceholder(expr.Expr):
 		// This case will be triggered if ProcessPlaceholderAnnotations found
 		// the same placeholder in another location where it was either not
@@ -260,6 +277,24 @@ func (expr *AnnotateTypeExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedE
 	return expr, nil
 }
 
+// TypeCheck implements the Expr interface.
+func (expr *CollateExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, error) {
+	if err := ValidateLocale(expr.Locale); err != nil {
+		return nil, err
+	}
+	subExpr, err := expr.Expr.TypeCheck(ctx, TypeString)
+	if err != nil {
+		return nil, err
+	}
+	if t := subExpr.ReturnType(); t != DNull && !t.TypeEqual(TypeString) &&
+		!t.TypeEqual(TypeCollatedString) {
+		return nil, fmt.Errorf("incompatible type for COLLATE: %s", t.Type())
+	}
+	expr.Expr = subExpr
+	expr.typ = &DCollatedString{Locale: expr.Locale}
+	return expr, nil
+}
+
 // TypeCheck implements the Expr interface.
 func (expr *CoalesceExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, error) {
 	typedSubExprs, retType, err := typeCheckSameTypedExprs(ctx, desired, expr.Exprs...)
@@ -276,7 +311,15 @@ func (expr *CoalesceExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr,
 
 // TypeCheck implements the Expr interface.
 func (expr *ComparisonExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, error) {
-	leftTyped, rightTyped, fn, err := typeCheckComparisonOp(ctx, expr.Operator, expr.Left, expr.Right)
+	var leftTyped, rightTyped TypedExpr
+	var fn CmpOp
+	var err error
+	if expr.Operator.hasSubOperator() {
+		leftTyped, rightTyped, fn, err = typeCheckComparisonOpWithSubOperator(ctx,
+			expr.Operator, expr.SubOperator, expr.Left, expr.Right)
+	} else {
+		leftTyped, rightTyped, fn, err = typeCheckComparisonOp(ctx, expr.Operator, expr.Left, expr.Right)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -317,12 +360,24 @@ func (expr *FuncExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, err
 	if !ok {
 		candidates, ok = Aggregates[name]
 	}
+	if !ok {
+		candidates, ok = Windows[name]
+	}
+	if !ok {
+		candidates, ok = Generators[name]
+	}
 	if !ok {
 		lowerName := strings.ToLower(name)
 		candidates, ok = Builtins[lowerName]
 		if !ok {
 			candidates, ok = Aggregates[lowerName]
 		}
+		if !ok {
+			candidates, ok = Windows[lowerName]
+		}
+		if !ok {
+			candidates, ok = Generators[lowerName]
+		}
 	}
 	if !ok {
 		return nil, fmt.Errorf("unknown function: %s", name)
@@ -348,10 +403,26 @@ func (expr *FuncExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, err
 			expr.Name, expr.Name, strings.Join(typeNames, ", "), desStr)
 	}
 
+	builtin := fn.(Builtin)
+	if expr.IsWindowFunctionApplication() {
+		// Make sure the window function application is of either a built-in
+		// window function or of a builtin aggregate function.
+		if builtin.WindowFunc == nil && builtin.AggregateFunc == nil {
+			return nil, fmt.Errorf("OVER specified, but %s is not a window function nor an aggregate function",
+				expr.Name)
+		}
+		if expr.Type == DistinctFuncType {
+			return nil, fmt.Errorf("DISTINCT is not implemented for window functions")
+		}
+	} else if builtin.WindowFunc != nil {
+		// Make sure that window functions are always applied as such.
+		return nil, fmt.Errorf("window function %s() requires an OVER clause", expr.Name)
+	}
+
 	for i, subExpr := range typedSubExprs {
 		expr.Exprs[i] = subExpr
 	}
-	expr.fn = fn.(Builtin)
+	expr.fn = builtin
 	returnType := fn.returnType()
 	if _, ok = expr.fn.params().(AnyType); ok {
 		if len(typedSubExprs) > 0 {
@@ -534,8 +605,82 @@ func (expr *UnaryExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, er
 }
 
 // TypeCheck implements the Expr interface.
-func (expr *Array) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) {
-	return nil, errors.Errorf("unhandled type %T", expr)
+func (expr *Array) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, error) {
+	desiredParam := NoTypePreference
+	if arr, ok := desired.(*DArray); ok {
+		desiredParam = arr.ParamTyp
+	}
+
+	if len(expr.Exprs) == 0 {
+		if desiredParam == NoTypePreference {
+			return nil, errors.Errorf("cannot determine type of empty array. "+
+				"Consider annotating with the desired type, for example ANNOTATE_TYPE(ARRAY[], INT[])")
+		}
+		expr.typ = NewDArray(desiredParam)
+		return expr, nil
+	}
+
+	typedSubExprs, typ, err := typeCheckSameTypedExprs(ctx, desiredParam, expr.Exprs...)
+	if err != nil {
+		return nil, err
+	}
+	switch typ.(type) {
+	case *DArray:
+		return nil, errNestedArraysNotSupported
+	case *DTuple:
+		return nil, errors.Errorf("arrays of tuples are not supported")
+	}
+	if typ == DNull {
+		// An array of only NULLs has no element type to speak of; like
+		// Postgres, we make it an array of strings.
+		typ = TypeString
+	}
+
+	for i := range typedSubExprs {
+		expr.Exprs[i] = typedSubExprs[i]
+	}
+	expr.typ = NewDArray(typ)
+	return expr, nil
+}
+
+func isArrayConstructor(expr Expr) bool {
+	_, ok := StripParens(expr).(*Array)
+	return ok
+}
+
+// TypeCheck implements the Expr interface.
+func (expr *IndirectionExpr) TypeCheck(ctx *SemaContext, desired Datum) (TypedExpr, error) {
+	for i, t := range expr.Indirection {
+		if t.End != nil {
+			return nil, util.UnimplementedWithIssueErrorf(2115, "ARRAY slicing in %s", expr)
+		}
+		if i > 0 {
+			return nil, util.UnimplementedWithIssueErrorf(2115, "multidimensional ARRAY %s", expr)
+		}
+
+		beginExpr, err := typeCheckAndRequire(ctx, t.Begin, TypeInt, "ARRAY subscript")
+		if err != nil {
+			return nil, err
+		}
+		t.Begin = beginExpr
+	}
+
+	var desiredArray Datum
+	if desired != NoTypePreference {
+		desiredArray = NewDArray(desired)
+	}
+	subExpr, err := expr.Expr.TypeCheck(ctx, desiredArray)
+	if err != nil {
+		return nil, err
+	}
+	typ := subExpr.ReturnType()
+	arr, ok := typ.(*DArray)
+	if !ok {
+		return nil, errors.Errorf("cannot subscript type %s because it is not an array", typ.Type())
+	}
+	expr.Expr = subExpr
+	expr.typ = arr.ParamTyp
+	return expr, nil
 }
 
 // TypeCheck implements the Expr interface.
@@ -640,6 +785,24 @@ func (d *DTuple) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { r
 // identity function for Datum.
 func (d dNull) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { return d, nil }
 
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DArray) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { return d, nil }
+
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DJSON) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { return d, nil }
+
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DIPAddr) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { return d, nil }
+
+// TypeCheck implements the Expr interface. It is implemented as an idempotent
+// identity function for Datum.
+func (d *DCollatedString) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) {
+	return d, nil
+}
+
 // TypeCheck implements the Expr interface. It is implemented as an idempotent
 // identity function for Datum.
 func (d *DPlaceholder) TypeCheck(_ *SemaContext, desired Datum) (TypedExpr, error) { return d, nil }
@@ -744,6 +907,10 @@ func typeCheckComparisonOp(
 		}
 	}
 
+	// Collated strings are only comparable if they have the same locale.
+	if _, ok := leftReturn.(*DCollatedString); ok && !leftReturn.TypeEqual(rightReturn) {
+		fn = nil
+	}
 	if fn == nil {
 		return nil, nil, CmpOp{}, fmt.Errorf(unsupportedCompErrFmtWithTypes, leftReturn.Type(),
 			op, rightReturn.Type())
@@ -751,6 +918,86 @@ func typeCheckComparisonOp(
 	return leftExpr, rightExpr, fn.(CmpOp), nil
 }
 
+// subOperandElemType returns the type of the values that the left operand of
+// an ANY, SOME or ALL comparison is compared against, given the type of its
+// right operand: either an array, or the tuple of column types of a subquery.
+func subOperandElemType(typ Datum) (Datum, bool) {
+	switch t := typ.(type) {
+	case *DArray:
+		return t.ParamTyp, true
+	case *DTuple:
+		if len(*t) == 1 {
+			return (*t)[0], true
+		}
+		return t, true
+	}
+	return nil, false
+}
+
+func typeCheckComparisonOpWithSubOperator(
+	ctx *SemaContext, op, subOp ComparisonOperator, left, right Expr,
+) (TypedExpr, TypedExpr, CmpOp, error) {
+	var leftTyped, rightTyped TypedExpr
+	var elemType Datum
+	if array, isConstructor := StripParens(right).(*Array); isConstructor {
+		// If the right expression is an array constructor, we perform type
+		// inference on the array elements and the left expression together.
+		sameTypeExprs := make([]Expr, 0, len(array.Exprs)+1)
+		sameTypeExprs = append(sameTypeExprs, left)
+		sameTypeExprs = append(sameTypeExprs, array.Exprs...)
+
+		typedSubExprs, retType, err := typeCheckSameTypedExprs(ctx, nil, sameTypeExprs...)
+		if err != nil {
+			return nil, nil, CmpOp{}, fmt.Errorf(unsupportedCompErrFmtWithExprs,
+				left, fmt.Sprintf("%s %s", subOp, op), right, err)
+		}
+		if retType == DNull {
+			retType = TypeString
+		}
+
+		leftTyped = typedSubExprs[0]
+		for i, typedExpr := range typedSubExprs[1:] {
+			array.Exprs[i] = typedExpr
+		}
+		array.typ = NewDArray(retType)
+		rightTyped = array
+		elemType = retType
+	} else {
+		var err error
+		rightTyped, err = right.TypeCheck(ctx, NoTypePreference)
+		if err != nil {
+			return nil, nil, CmpOp{}, err
+		}
+		rightReturn := rightTyped.ReturnType()
+		if rightReturn == DNull {
+			return nil, nil, CmpOp{}, errors.Errorf("op %s %s (array) requires array on right side",
+				subOp, op)
+		}
+		var ok bool
+		if elemType, ok = subOperandElemType(rightReturn); !ok {
+			return nil, nil, CmpOp{}, errors.Errorf("op %s %s (array) requires array on right side",
+				subOp, op)
+		}
+		leftTyped, err = left.TypeCheck(ctx, elemType)
+		if err != nil {
+			return nil, nil, CmpOp{}, err
+		}
+	}
+
+	leftReturn := leftTyped.ReturnType()
+	if leftReturn == DNull || elemType == DNull {
+		return leftTyped, rightTyped, CmpOp{}, nil
+	}
+
+	foldedOp, foldedLeft, foldedRight, _, _ := foldComparisonExpr(subOp, leftReturn, elemType)
+	fn, ok := CmpOps[foldedOp].lookupImpl(foldedLeft.(Datum), foldedRight.(Datum))
+	if !ok {
+		return nil, nil, CmpOp{}, fmt.Errorf(unsupportedCompErrFmtWithTypes,
+			leftReturn.Type(), fmt.Sprintf("%s %s", subOp, op), rightTyped.ReturnType().Type())
+	}
+	return leftTyped, rightTyped, fn, nil
+}
+
 type indexedExpr struct {
 	e Expr
 	i int
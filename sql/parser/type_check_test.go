@@ -71,6 +71,14 @@ func TestTypeCheck(t *testing.T) {
 		`CASE 1 WHEN 1 THEN (1, 2) ELSE (1, 3) END`,
 		`1 BETWEEN 2 AND 3`,
 		`COUNT(3)`,
+		`ARRAY[1, 2, 3]`,
+		`ARRAY[NULL, 1]`,
+		`ARRAY['a', 'b']::string[]`,
+		`(ARRAY[1, 2])[1]`,
+		`1 = ANY (ARRAY[1, 2])`,
+		`1.5 < ALL (ARRAY[2, 3])`,
+		`'a' LIKE SOME (ARRAY['a', 'b'])`,
+		`ARRAY_AGG(1)`,
 	}
 	for _, d := range testData {
 		expr, err := ParseExprTraditional(d)
@@ -104,6 +112,9 @@ func TestTypeCheckError(t *testing.T) {
 		{`lower()`, `unknown signature for lower: lower()`},
 		{`lower(1, 2)`, `unknown signature for lower: lower(int, int)`},
 		{`lower(1)`, `unknown signature for lower: lower(int)`},
+		{`lower('FOO') OVER ()`, `OVER specified, but lower is not a window function nor an aggregate function`},
+		{`rank()`, `window function rank() requires an OVER clause`},
+		{`count(DISTINCT 1) OVER ()`, `DISTINCT is not implemented for window functions`},
 		{`1::date`, `invalid cast: int -> DATE`},
 		{`1::timestamp`, `invalid cast: int -> TIMESTAMP`},
 		{`CASE 'one' WHEN 1 THEN 1 WHEN 'two' THEN 2 END`, `incompatible condition type`},
@@ -119,6 +130,17 @@ func TestTypeCheckError(t *testing.T) {
 		{`IFNULL(1, '5')`, `incompatible IFNULL expressions: expected 1 to be of type string, found type int`},
 		{`NULLIF(1, '5')`, `incompatible NULLIF expressions: expected 1 to be of type string, found type int`},
 		{`COALESCE(1, 2, 3, 4, '5')`, `incompatible COALESCE expressions: expected 1 to be of type string, found type int`},
+		{`ARRAY[]`, `cannot determine type of empty array`},
+		{`ARRAY[1, 'a']`, `expected 1 to be of type string, found type int`},
+		{`ARRAY[ARRAY[1]]`, `nested arrays are not supported`},
+		{`ARRAY[(1, 2)]`, `arrays of tuples are not supported`},
+		{`(1)[1]`, `cannot subscript type int because it is not an array`},
+		{`(ARRAY[1])['a']`, `incompatible ARRAY subscript type: string`},
+		{`1 = ANY (1)`, `op = ANY (array) requires array on right side`},
+		{`1 = ANY (ARRAY['a'])`, `unsupported comparison operator: 1 = ANY ARRAY['a']: expected 1 to be of type string, found type int`},
+		{`'a' COLLATE de = ('a' COLLATE en)`, `unsupported comparison operator: <collatedstring{de}> = <collatedstring{en}>`},
+		{`1 COLLATE de`, `incompatible type for COLLATE: int`},
+		{`'a' COLLATE xx_yy_zz`, `invalid locale xx_yy_zz`},
 	}
 	for _, d := range testData {
 		expr, err := ParseExprTraditional(d.expr)
@@ -132,6 +132,17 @@ func (expr *AnnotateTypeExpr) Walk(v Visitor) Expr {
 	return expr
 }
 
+// Walk implements the Expr interface.
+func (expr *CollateExpr) Walk(v Visitor) Expr {
+	e, changed := WalkExpr(v, expr.Expr)
+	if changed {
+		exprCopy := *expr
+		exprCopy.Expr = e
+		return &exprCopy
+	}
+	return expr
+}
+
 // CopyNode makes a copy of this Expr without recursing in any child Exprs.
 func (expr *CoalesceExpr) CopyNode() *CoalesceExpr {
 	exprCopy := *expr
@@ -328,7 +339,38 @@ func walkExprSlice(v Visitor, slice []Expr) ([]Expr, bool) {
 func (expr *Array) Walk(v Visitor) Expr {
 	exprs, changed := walkExprSlice(v, expr.Exprs)
 	if changed {
-		return &Array{exprs}
+		exprCopy := *expr
+		exprCopy.Exprs = exprs
+		return &exprCopy
+	}
+	return expr
+}
+
+// Walk implements the Expr interface.
+func (expr *IndirectionExpr) Walk(v Visitor) Expr {
+	e, changed := WalkExpr(v, expr.Expr)
+	var indirection ArraySubscripts
+	for i, t := range expr.Indirection {
+		begin, beginChanged := WalkExpr(v, t.Begin)
+		var end Expr
+		var endChanged bool
+		if t.End != nil {
+			end, endChanged = WalkExpr(v, t.End)
+		}
+		if beginChanged || endChanged {
+			if indirection == nil {
+				indirection = append(ArraySubscripts(nil), expr.Indirection...)
+			}
+			indirection[i] = &ArraySubscript{Begin: begin, End: end}
+		}
+	}
+	if changed || indirection != nil {
+		exprCopy := *expr
+		exprCopy.Expr = e
+		if indirection != nil {
+			exprCopy.Indirection = indirection
+		}
+		return &exprCopy
 	}
 	return expr
 }
@@ -408,6 +450,18 @@ func (expr *DTimestampTZ) Walk(_ Visitor) Expr { return expr }
 // Walk implements the Expr interface.
 func (expr *DTuple) Walk(_ Visitor) Expr { return expr }
 
+// Walk implements the Expr interface.
+func (expr *DArray) Walk(_ Visitor) Expr { return expr }
+
+// Walk implements the Expr interface.
+func (expr *DJSON) Walk(_ Visitor) Expr { return expr }
+
+// Walk implements the Expr interface.
+func (expr *DIPAddr) Walk(_ Visitor) Expr { return expr }
+
+// Walk implements the Expr interface.
+func (expr *DCollatedString) Walk(_ Visitor) Expr { return expr }
+
 // Walk implements the Expr interface.
 func (expr *DPlaceholder) Walk(_ Visitor) Expr { return expr }
 
@@ -440,6 +494,25 @@ type WalkableStmt interface {
 	WalkStmt(Visitor) Statement
 }
 
+// CopyNode makes a copy of this Expr without recursing in any child Exprs.
+func (stmt *CopyTo) CopyNode() *CopyTo {
+	stmtCopy := *stmt
+	return &stmtCopy
+}
+
+// WalkStmt is part of the WalkableStmt interface.
+func (stmt *CopyTo) WalkStmt(v Visitor) Statement {
+	if stmt.Stmt == nil {
+		return stmt
+	}
+	s, changed := WalkStmt(v, stmt.Stmt)
+	if changed {
+		stmt = stmt.CopyNode()
+		stmt.Stmt = s.(*Select)
+	}
+	return stmt
+}
+
 // CopyNode makes a copy of this Expr without recursing in any child Exprs.
 func (stmt *Delete) CopyNode() *Delete {
 	stmtCopy := *stmt
@@ -454,10 +527,16 @@ func (stmt *Delete) CopyNode() *Delete {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Delete) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	if stmt.Where != nil {
 		e, changed := WalkExpr(v, stmt.Where.Expr)
 		if changed {
-			ret = stmt.CopyNode()
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
 			ret.Where.Expr = e
 		}
 	}
@@ -500,10 +579,16 @@ func (stmt *Insert) CopyNode() *Insert {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Insert) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	if stmt.Rows != nil {
 		rows, changed := WalkStmt(v, stmt.Rows)
 		if changed {
-			ret = stmt.CopyNode()
+			if ret == stmt {
+				ret = stmt.CopyNode()
+			}
 			ret.Rows = rows.(*Select)
 		}
 	}
@@ -548,9 +633,15 @@ func (stmt *Select) CopyNode() *Select {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Select) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	sel, changed := WalkStmt(v, stmt.Select)
 	if changed {
-		ret = stmt.CopyNode()
+		if ret == stmt {
+			ret = stmt.CopyNode()
+		}
 		ret.Select = sel.(SelectStatement)
 	}
 	for i, expr := range stmt.OrderBy {
@@ -683,6 +774,25 @@ func (stmt *Set) WalkStmt(v Visitor) Statement {
 	return ret
 }
 
+// CopyNode makes a copy of this Expr without recursing in any child Exprs.
+func (stmt *ShowTrace) CopyNode() *ShowTrace {
+	stmtCopy := *stmt
+	return &stmtCopy
+}
+
+// WalkStmt is part of the WalkableStmt interface.
+func (stmt *ShowTrace) WalkStmt(v Visitor) Statement {
+	if stmt.Statement == nil {
+		return stmt
+	}
+	s, changed := WalkStmt(v, stmt.Statement)
+	if changed {
+		stmt = stmt.CopyNode()
+		stmt.Statement = s
+	}
+	return stmt
+}
+
 // CopyNode makes a copy of this Expr without recursing in any child Exprs.
 func (stmt *Update) CopyNode() *Update {
 	stmtCopy := *stmt
@@ -702,6 +812,10 @@ func (stmt *Update) CopyNode() *Update {
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *Update) WalkStmt(v Visitor) Statement {
 	ret := stmt
+	if with, changed := walkWith(v, stmt.With); changed {
+		ret = stmt.CopyNode()
+		ret.With = with
+	}
 	for i, expr := range stmt.Exprs {
 		e, changed := WalkExpr(v, expr.Expr)
 		if changed {
@@ -734,6 +848,25 @@ func (stmt *Update) WalkStmt(v Visitor) Statement {
 	return ret
 }
 
+// walkWith walks the statements of the common table expressions in a WITH
+// clause. A copy of the clause is returned if any of them changed.
+func walkWith(v Visitor, with *With) (*With, bool) {
+	if with == nil {
+		return nil, false
+	}
+	ret := with
+	for i, cte := range with.CTEList {
+		s, changed := WalkStmt(v, cte.Stmt)
+		if changed {
+			if ret == with {
+				ret = &With{CTEList: append([]*CTE(nil), with.CTEList...)}
+			}
+			ret.CTEList[i] = &CTE{Name: cte.Name, Stmt: s}
+		}
+	}
+	return ret, ret != with
+}
+
 // WalkStmt is part of the WalkableStmt interface.
 func (stmt *ValuesClause) WalkStmt(v Visitor) Statement {
 	ret := stmt
@@ -749,6 +882,7 @@ func (stmt *ValuesClause) WalkStmt(v Visitor) Statement {
 	return ret
 }
 
+var _ WalkableStmt = &CopyTo{}
 var _ WalkableStmt = &Delete{}
 var _ WalkableStmt = &Explain{}
 var _ WalkableStmt = &Insert{}
@@ -756,6 +890,7 @@ var _ WalkableStmt = &ParenSelect{}
 var _ WalkableStmt = &Select{}
 var _ WalkableStmt = &SelectClause{}
 var _ WalkableStmt = &Set{}
+var _ WalkableStmt = &ShowTrace{}
 var _ WalkableStmt = &Update{}
 var _ WalkableStmt = &ValuesClause{}
 
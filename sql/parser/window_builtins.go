@@ -0,0 +1,298 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "strings"
+
+func init() {
+	for k, v := range Windows {
+		for i := range v {
+			v[i].impure = true
+		}
+		Windows[strings.ToUpper(k)] = v
+	}
+}
+
+// IndexedRow is a row with a corresponding index.
+type IndexedRow struct {
+	// Idx is the position of the row in the set of rows being windowed.
+	Idx int
+	// Row holds the arguments of the window function for the row.
+	Row DTuple
+}
+
+// WindowFrame is a view into a subset of data over which calculations are made.
+type WindowFrame struct {
+	// Rows are the rows of the current partition, in the order of the
+	// window's ORDER BY clause. They are constant for all calls to
+	// WindowFunc.Compute for the partition.
+	Rows []IndexedRow
+
+	// RowIdx is the index of the current row in Rows. It changes on each
+	// call to WindowFunc.Compute.
+	RowIdx int
+
+	// FirstPeerIdx is the index in Rows of the first row in the current
+	// row's peer group, and PeerRowCount the number of rows in that group.
+	// Two rows are peers when they are not distinguished by the window's
+	// ORDER BY clause.
+	FirstPeerIdx int
+	PeerRowCount int
+}
+
+func (wf WindowFrame) rowCount() int {
+	return len(wf.Rows)
+}
+
+// args returns the arguments of the window function for the current row.
+func (wf WindowFrame) args() DTuple {
+	return wf.argsWithRowOffset(0)
+}
+
+// argsWithRowOffset returns the arguments of the window function for the row
+// at the given offset from the current row.
+func (wf WindowFrame) argsWithRowOffset(offset int) DTuple {
+	return wf.Rows[wf.RowIdx+offset].Row
+}
+
+func (wf WindowFrame) firstInPeerGroup() bool {
+	return wf.RowIdx == wf.FirstPeerIdx
+}
+
+// WindowFunc performs a computation on each row using data from a provided
+// WindowFrame.
+type WindowFunc interface {
+	// Compute computes the window function for the provided window frame. It
+	// must be called on every row of a partition in turn, in the order of the
+	// window's ORDER BY clause, because the function can carry state from each
+	// row to the next (much like an AggregateFunc).
+	Compute(WindowFrame) (Datum, error)
+}
+
+var _ Visitor = &ContainsWindowVisitor{}
+
+// ContainsWindowVisitor checks if walked expressions contain window functions.
+type ContainsWindowVisitor struct {
+	sawWindow bool
+}
+
+// VisitPre satisfies the Visitor interface.
+func (v *ContainsWindowVisitor) VisitPre(expr Expr) (recurse bool, newExpr Expr) {
+	switch t := expr.(type) {
+	case *FuncExpr:
+		if t.IsWindowFunctionApplication() {
+			v.sawWindow = true
+			return false, expr
+		}
+	case *Subquery:
+		return false, expr
+	}
+	return true, expr
+}
+
+// VisitPost satisfies the Visitor interface.
+func (*ContainsWindowVisitor) VisitPost(expr Expr) Expr { return expr }
+
+// WindowFuncInExpr determines if an Expr contains a window function.
+func (p *Parser) WindowFuncInExpr(expr Expr) bool {
+	if expr == nil {
+		return false
+	}
+	defer func() { p.containsWindowVisitor.sawWindow = false }()
+	WalkExprConst(&p.containsWindowVisitor, expr)
+	return p.containsWindowVisitor.sawWindow
+}
+
+// WindowFuncInExprs determines if any of the provided TypedExpr contains a
+// window function.
+func (p *Parser) WindowFuncInExprs(exprs []TypedExpr) bool {
+	for _, expr := range exprs {
+		if p.WindowFuncInExpr(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Windows are a special class of builtin functions that can only be applied
+// as window functions using an OVER clause.
+// See `windowFuncHolder` in the sql package.
+var Windows = map[string][]Builtin{
+	"row_number": {
+		makeWindowBuiltin(ArgTypes{}, TypeInt, newRowNumberWindow),
+	},
+	"rank": {
+		makeWindowBuiltin(ArgTypes{}, TypeInt, newRankWindow),
+	},
+	"dense_rank": {
+		makeWindowBuiltin(ArgTypes{}, TypeInt, newDenseRankWindow),
+	},
+	"lag":  makeLeadLagBuiltins(false, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes, TypeDate, TypeTimestamp, TypeInterval),
+	"lead": makeLeadLagBuiltins(true, TypeBool, TypeInt, TypeFloat, TypeDecimal, TypeString, TypeBytes, TypeDate, TypeTimestamp, TypeInterval),
+}
+
+func makeWindowBuiltin(in ArgTypes, ret Datum, f func() WindowFunc) Builtin {
+	return Builtin{
+		impure:     true,
+		Types:      in,
+		ReturnType: ret,
+		WindowFunc: f,
+	}
+}
+
+// makeLeadLagBuiltins creates the lead(val [, offset [, default]]) or
+// lag(val [, offset [, default]]) overloads for each of the given types.
+func makeLeadLagBuiltins(forward bool, types ...Datum) []Builtin {
+	ret := make([]Builtin, 0, 3*len(types))
+	for _, t := range types {
+		ret = append(ret,
+			makeWindowBuiltin(ArgTypes{t}, t, func() WindowFunc {
+				return newLeadLagWindow(forward, false, false)
+			}),
+			makeWindowBuiltin(ArgTypes{t, TypeInt}, t, func() WindowFunc {
+				return newLeadLagWindow(forward, true, false)
+			}),
+			makeWindowBuiltin(ArgTypes{t, TypeInt, t}, t, func() WindowFunc {
+				return newLeadLagWindow(forward, true, true)
+			}),
+		)
+	}
+	return ret
+}
+
+var _ WindowFunc = &aggregateWindowFunc{}
+var _ WindowFunc = &rowNumberWindow{}
+var _ WindowFunc = &rankWindow{}
+var _ WindowFunc = &denseRankWindow{}
+var _ WindowFunc = &leadLagWindow{}
+
+// aggregateWindowFunc aggregates over the current row's window frame, using
+// the internal AggregateFunc to perform the aggregation. Without an ORDER BY
+// clause the frame is the whole partition; with one, it runs from the start
+// of the partition to the last peer of the current row.
+type aggregateWindowFunc struct {
+	agg     AggregateFunc
+	peerRes Datum
+}
+
+// NewAggregateWindowFunc creates a WindowFunc that applies the given
+// AggregateFunc over window frames.
+func NewAggregateWindowFunc(agg AggregateFunc) WindowFunc {
+	return &aggregateWindowFunc{agg: agg}
+}
+
+func (w *aggregateWindowFunc) Compute(wf WindowFrame) (Datum, error) {
+	if !wf.firstInPeerGroup() {
+		return w.peerRes, nil
+	}
+
+	// Accumulate all values in the peer group at the same time, as these
+	// must return the same value.
+	for i := 0; i < wf.PeerRowCount; i++ {
+		if err := w.agg.Add(wf.argsWithRowOffset(i)[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Retrieve the value for the entire peer group, save it, and return it.
+	peerRes, err := w.agg.Result()
+	if err != nil {
+		return nil, err
+	}
+	w.peerRes = peerRes
+	return w.peerRes, nil
+}
+
+// rowNumberWindow computes the number of the current row within its
+// partition, counting from 1.
+type rowNumberWindow struct{}
+
+func newRowNumberWindow() WindowFunc {
+	return &rowNumberWindow{}
+}
+
+func (rowNumberWindow) Compute(wf WindowFrame) (Datum, error) {
+	return NewDInt(DInt(wf.RowIdx + 1)), nil
+}
+
+// rankWindow computes the rank of the current row with gaps.
+type rankWindow struct{}
+
+func newRankWindow() WindowFunc {
+	return &rankWindow{}
+}
+
+func (rankWindow) Compute(wf WindowFrame) (Datum, error) {
+	return NewDInt(DInt(wf.FirstPeerIdx + 1)), nil
+}
+
+// denseRankWindow computes the rank of the current row without gaps (it
+// counts peer groups).
+type denseRankWindow struct {
+	denseRank int
+}
+
+func newDenseRankWindow() WindowFunc {
+	return &denseRankWindow{}
+}
+
+func (w *denseRankWindow) Compute(wf WindowFrame) (Datum, error) {
+	if wf.firstInPeerGroup() {
+		w.denseRank++
+	}
+	return NewDInt(DInt(w.denseRank)), nil
+}
+
+// leadLagWindow returns the value evaluated at the row offset rows after
+// (lead) or before (lag) the current row within its partition. If there is
+// no such row, the default value (or NULL if none is given) is returned.
+type leadLagWindow struct {
+	forward     bool
+	withOffset  bool
+	withDefault bool
+}
+
+func newLeadLagWindow(forward, withOffset, withDefault bool) WindowFunc {
+	return &leadLagWindow{
+		forward:     forward,
+		withOffset:  withOffset,
+		withDefault: withDefault,
+	}
+}
+
+func (w *leadLagWindow) Compute(wf WindowFrame) (Datum, error) {
+	offset := 1
+	if w.withOffset {
+		offsetArg := wf.args()[1]
+		if offsetArg == DNull {
+			return DNull, nil
+		}
+		offset = int(*offsetArg.(*DInt))
+	}
+	if !w.forward {
+		offset = -offset
+	}
+
+	if targetRow := wf.RowIdx + offset; targetRow < 0 || targetRow >= wf.rowCount() {
+		// The target row is out of the partition; supply the default value if
+		// one was provided, otherwise return NULL.
+		if w.withDefault {
+			return wf.args()[2], nil
+		}
+		return DNull, nil
+	}
+
+	return wf.argsWithRowOffset(offset)[0], nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package parser
+
+import "bytes"
+
+// ZoneSpecifier identifies the database, the table or the partition of a
+// table whose zone config is configured or shown.
+type ZoneSpecifier struct {
+	Database Name
+	Table    NormalizableTableName
+	// Partition is set along with Table for a partition of one of its
+	// indexes.
+	Partition Name
+}
+
+// Format implements the NodeFormatter interface.
+func (node ZoneSpecifier) Format(buf *bytes.Buffer, f FmtFlags) {
+	if node.Partition != "" {
+		buf.WriteString("PARTITION ")
+		FormatNode(buf, f, node.Partition)
+		buf.WriteString(" OF ")
+	}
+	if node.Database != "" {
+		buf.WriteString("DATABASE ")
+		FormatNode(buf, f, node.Database)
+	} else {
+		buf.WriteString("TABLE ")
+		FormatNode(buf, f, node.Table)
+	}
+}
+
+// ZoneSetting is a field of a zone config set by CONFIGURE ZONE, e.g.
+// num_replicas = 5.
+type ZoneSetting struct {
+	Key   Name
+	Value Expr
+}
+
+// ZoneSettings represents a list of zone config fields.
+type ZoneSettings []ZoneSetting
+
+// Format implements the NodeFormatter interface.
+func (node ZoneSettings) Format(buf *bytes.Buffer, f FmtFlags) {
+	for i, s := range node {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		FormatNode(buf, f, s.Key)
+		buf.WriteString(" = ")
+		FormatNode(buf, f, s.Value)
+	}
+}
+
+// SetZoneConfig represents an ALTER DATABASE/TABLE ... CONFIGURE ZONE
+// statement. Empty Settings mean DISCARD: the zone config of the database or
+// table is removed, so that it inherits the one of its parent.
+type SetZoneConfig struct {
+	ZoneSpecifier
+	Settings ZoneSettings
+}
+
+// Format implements the NodeFormatter interface.
+func (node *SetZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("ALTER ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+	buf.WriteString(" CONFIGURE ZONE ")
+	if len(node.Settings) == 0 {
+		buf.WriteString("DISCARD")
+	} else {
+		buf.WriteString("USING ")
+		FormatNode(buf, f, node.Settings)
+	}
+}
+
+// ShowZoneConfig represents a SHOW ZONE CONFIGURATION statement.
+type ShowZoneConfig struct {
+	ZoneSpecifier
+}
+
+// Format implements the NodeFormatter interface.
+func (node *ShowZoneConfig) Format(buf *bytes.Buffer, f FmtFlags) {
+	buf.WriteString("SHOW ZONE CONFIGURATION FOR ")
+	FormatNode(buf, f, node.ZoneSpecifier)
+}
@@ -0,0 +1,348 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// replaceColumnVars replaces the column references in expr with IndexedVars
+// for the matching columns in cols.
+func replaceColumnVars(
+	expr parser.Expr, cols []sqlbase.ColumnDescriptor, vars *parser.IndexedVarHelper,
+) (parser.Expr, error) {
+	preFn := func(expr parser.Expr) (err error, recurse bool, newExpr parser.Expr) {
+		vBase, ok := expr.(parser.VarName)
+		if !ok {
+			// Not a VarName, don't do anything to this node.
+			return nil, true, expr
+		}
+
+		v, err := vBase.NormalizeVarName()
+		if err != nil {
+			return err, false, nil
+		}
+
+		c, ok := v.(*parser.ColumnItem)
+		if !ok {
+			return nil, true, expr
+		}
+
+		name := sqlbase.NormalizeName(c.ColumnName)
+		for i := range cols {
+			if sqlbase.ReNormalizeName(cols[i].Name) == name {
+				return nil, false, vars.IndexedVar(i)
+			}
+		}
+		return fmt.Errorf("column %q not found for index predicate", c.ColumnName), false, nil
+	}
+	return parser.SimpleVisit(expr, preFn)
+}
+
+// indexPredicate evaluates the predicate of a partial index against the
+// values of rows being written to the table.
+type indexPredicate struct {
+	expr parser.TypedExpr
+	vars parser.IndexedVarHelper
+	// The IndexedVars in expr refer to the columns in cols.
+	cols []sqlbase.ColumnDescriptor
+
+	// The row being evaluated, set by matches.
+	colIDtoRowIndex map[sqlbase.ColumnID]int
+	values          []parser.Datum
+}
+
+var _ parser.IndexedVarContainer = &indexPredicate{}
+
+// IndexedVarEval is part of the parser.IndexedVarContainer interface.
+func (ip *indexPredicate) IndexedVarEval(idx int, ctx *parser.EvalContext) (parser.Datum, error) {
+	i, ok := ip.colIDtoRowIndex[ip.cols[idx].ID]
+	if !ok {
+		// The column isn't being written, so it is NULL.
+		return parser.DNull, nil
+	}
+	return ip.values[i].Eval(ctx)
+}
+
+// IndexedVarReturnType is part of the parser.IndexedVarContainer interface.
+func (ip *indexPredicate) IndexedVarReturnType(idx int) parser.Datum {
+	return ip.cols[idx].Type.ToDatumType()
+}
+
+// IndexedVarString is part of the parser.IndexedVarContainer interface.
+func (ip *indexPredicate) IndexedVarString(idx int) string {
+	return ip.cols[idx].Name
+}
+
+// makeIndexPredicate parses and type checks the predicate of a partial index
+// of the table.
+func makeIndexPredicate(
+	tableDesc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor,
+) (*indexPredicate, error) {
+	raw, err := parser.ParseExprTraditional(*index.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	var p parser.Parser
+	if p.AggregateInExpr(raw) {
+		return nil, fmt.Errorf("aggregate functions are not allowed in index predicates")
+	}
+
+	ip := &indexPredicate{cols: tableDesc.Columns}
+	ip.vars = parser.MakeIndexedVarHelper(ip, len(ip.cols))
+	expr, err := replaceColumnVars(raw, ip.cols, &ip.vars)
+	if err != nil {
+		return nil, err
+	}
+	if ip.expr, err = parser.TypeCheckAndRequire(expr, nil, parser.TypeBool, "index predicate"); err != nil {
+		return nil, err
+	}
+	return ip, nil
+}
+
+// columnIDs returns the IDs of the columns referenced by the predicate.
+func (ip *indexPredicate) columnIDs() []sqlbase.ColumnID {
+	var ids []sqlbase.ColumnID
+	for i := range ip.cols {
+		if ip.vars.IndexedVarUsed(i) {
+			ids = append(ids, ip.cols[i].ID)
+		}
+	}
+	return ids
+}
+
+// matches returns true if the row satisfies the predicate, i.e. if the row
+// has an entry in the partial index.
+func (ip *indexPredicate) matches(
+	ctx *parser.EvalContext, colIDtoRowIndex map[sqlbase.ColumnID]int, values []parser.Datum,
+) (bool, error) {
+	ip.colIDtoRowIndex, ip.values = colIDtoRowIndex, values
+	return sqlbase.RunFilter(ip.expr, ctx)
+}
+
+// makeIndexPredicates returns the predicates of the indexes. The predicate
+// of an index which is not partial is nil.
+func makeIndexPredicates(
+	tableDesc *sqlbase.TableDescriptor, indexes []sqlbase.IndexDescriptor,
+) ([]*indexPredicate, error) {
+	preds := make([]*indexPredicate, len(indexes))
+	for i := range indexes {
+		if indexes[i].IsPartial() {
+			var err error
+			if preds[i], err = makeIndexPredicate(tableDesc, &indexes[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return preds, nil
+}
+
+// partialIndexReferencing returns the name of a partial index of the table
+// whose predicate references the column, or the empty string if there is
+// none.
+func partialIndexReferencing(
+	tableDesc *sqlbase.TableDescriptor, colID sqlbase.ColumnID,
+) (string, error) {
+	for _, index := range tableDesc.AllNonDropIndexes() {
+		if !index.IsPartial() {
+			continue
+		}
+		pred, err := makeIndexPredicate(tableDesc, &index)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range pred.columnIDs() {
+			if id == colID {
+				return index.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// scanIndexPredicate returns the predicate of a partial index as a normalized
+// expression over the columns of the scan, comparable to its filter.
+func (n *scanNode) scanIndexPredicate(index *sqlbase.IndexDescriptor) (parser.TypedExpr, error) {
+	raw, err := parser.ParseExprTraditional(*index.Predicate)
+	if err != nil {
+		return nil, err
+	}
+	vars := parser.MakeIndexedVarHelper(n, len(n.cols))
+	expr, err := replaceColumnVars(raw, n.cols, &vars)
+	if err != nil {
+		return nil, err
+	}
+	typedExpr, err := parser.TypeCheck(expr, nil, parser.TypeBool)
+	if err != nil {
+		return nil, err
+	}
+	return n.p.parser.NormalizeExpr(&n.p.evalCtx, typedExpr)
+}
+
+// partialIndexUsable returns true if every row satisfying the filter of the
+// scan has an entry in the partial index, i.e. if the filter implies the
+// predicate of the index. exprs is the filter as analyzed by analyzeExpr.
+func (n *scanNode) partialIndexUsable(
+	index *sqlbase.IndexDescriptor, exprs []parser.TypedExprs,
+) (bool, error) {
+	if n.filter == nil {
+		return false, nil
+	}
+	pred, err := n.scanIndexPredicate(index)
+	if err != nil {
+		return false, err
+	}
+	if conjunctsImply(splitAndExpr(n.filter, nil), pred) {
+		return true, nil
+	}
+	// The simplified filter may be a disjunction, in which case each of the
+	// disjuncts has to imply the predicate.
+	for _, andExprs := range exprs {
+		if !conjunctsImply(andExprs, pred) {
+			return false, nil
+		}
+	}
+	return len(exprs) > 0, nil
+}
+
+// conjunctsImply returns true if the conjunction of exprs implies pred. The
+// analysis is conservative: each conjunct of pred has to be implied by one of
+// exprs on its own.
+func conjunctsImply(exprs parser.TypedExprs, pred parser.TypedExpr) bool {
+	for _, p := range splitAndExpr(pred, nil) {
+		implied := false
+		for _, e := range exprs {
+			if exprImplies(e, p) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+// exprImplies returns true if every row satisfying e satisfies p. Besides
+// identical expressions, it handles comparisons of the same variable against
+// constants, e.g. "a = 3" and "a > 5" both imply "a > 0".
+func exprImplies(e, p parser.TypedExpr) bool {
+	if parser.AsString(e) == parser.AsString(p) {
+		return true
+	}
+	ec, ok := e.(*parser.ComparisonExpr)
+	if !ok {
+		return false
+	}
+	pc, ok := p.(*parser.ComparisonExpr)
+	if !ok {
+		return false
+	}
+	if _, ok := ec.Left.(parser.VariableExpr); !ok {
+		return false
+	}
+	if parser.AsString(ec.Left) != parser.AsString(pc.Left) {
+		return false
+	}
+	eVal, ok := ec.Right.(parser.Datum)
+	if !ok || eVal == parser.DNull {
+		return false
+	}
+	pVal, ok := pc.Right.(parser.Datum)
+	if !ok {
+		return false
+	}
+
+	if pc.Operator == parser.IsNot && pVal == parser.DNull {
+		// Any comparison against a non-NULL constant is false or NULL for NULL
+		// values.
+		switch ec.Operator {
+		case parser.EQ, parser.NE, parser.LT, parser.LE, parser.GT, parser.GE, parser.In:
+			return true
+		}
+		return false
+	}
+
+	if ec.Operator == parser.In {
+		tuple, ok := eVal.(*parser.DTuple)
+		if !ok || len(*tuple) == 0 {
+			return false
+		}
+		for _, d := range *tuple {
+			if !comparisonImplies(parser.EQ, d, pc.Operator, pVal) {
+				return false
+			}
+		}
+		return true
+	}
+	return comparisonImplies(ec.Operator, eVal, pc.Operator, pVal)
+}
+
+// comparisonImplies returns true if every value v satisfying "v eOp eVal"
+// also satisfies "v pOp pVal".
+func comparisonImplies(
+	eOp parser.ComparisonOperator, eVal parser.Datum, pOp parser.ComparisonOperator, pVal parser.Datum,
+) bool {
+	if eVal == parser.DNull || pVal == parser.DNull || !eVal.TypeEqual(pVal) {
+		return false
+	}
+	c := eVal.Compare(pVal)
+	switch pOp {
+	case parser.EQ:
+		return eOp == parser.EQ && c == 0
+	case parser.NE:
+		switch eOp {
+		case parser.EQ:
+			return c != 0
+		case parser.LT:
+			return c <= 0
+		case parser.LE:
+			return c < 0
+		case parser.GT:
+			return c >= 0
+		case parser.GE:
+			return c > 0
+		}
+	case parser.LT:
+		switch eOp {
+		case parser.EQ, parser.LE:
+			return c < 0
+		case parser.LT:
+			return c <= 0
+		}
+	case parser.LE:
+		switch eOp {
+		case parser.EQ, parser.LT, parser.LE:
+			return c <= 0
+		}
+	case parser.GT:
+		switch eOp {
+		case parser.EQ, parser.GE:
+			return c > 0
+		case parser.GT:
+			return c >= 0
+		}
+	case parser.GE:
+		switch eOp {
+		case parser.EQ, parser.GT, parser.GE:
+			return c >= 0
+		}
+	}
+	return false
+}
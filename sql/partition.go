@@ -0,0 +1,206 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+)
+
+// addPartitioning partitions an index according to the given definition. The
+// values of the partitions are evaluated as the types of the partitioning
+// columns and stored with the key encoding of the index, so that the
+// partitions map to spans of its keys.
+func (p *planner) addPartitioning(
+	desc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor, partBy *parser.PartitionBy,
+) error {
+	if len(index.Interleave.Ancestors) > 0 {
+		return fmt.Errorf("interleaved index %q cannot be partitioned", index.Name)
+	}
+	if len(partBy.Fields) > len(index.ColumnIDs) {
+		return fmt.Errorf("declared partition columns must match index being partitioned")
+	}
+	for i, field := range partBy.Fields {
+		if sqlbase.NormalizeName(field) != sqlbase.ReNormalizeName(index.ColumnNames[i]) {
+			return fmt.Errorf("declared partition columns must match index being partitioned")
+		}
+	}
+
+	part := sqlbase.PartitioningDescriptor{NumColumns: uint32(len(partBy.Fields))}
+	for _, l := range partBy.List {
+		partition := sqlbase.PartitioningDescriptor_List{Name: string(l.Name)}
+		for _, expr := range l.Exprs {
+			exprs := parser.Exprs{expr}
+			if t, ok := expr.(*parser.Tuple); ok && len(partBy.Fields) > 1 {
+				exprs = t.Exprs
+			}
+			value, err := p.encodePartitionValues(desc, index, len(partBy.Fields), exprs)
+			if err != nil {
+				return err
+			}
+			partition.Values = append(partition.Values, value)
+		}
+		part.List = append(part.List, partition)
+	}
+	for _, r := range partBy.Range {
+		partition := sqlbase.PartitioningDescriptor_Range{Name: string(r.Name)}
+		if r.Exprs != nil {
+			var err error
+			partition.UpperBound, err = p.encodePartitionValues(desc, index, len(partBy.Fields), r.Exprs)
+			if err != nil {
+				return err
+			}
+		}
+		part.Range = append(part.Range, partition)
+	}
+
+	names := make(map[string]struct{})
+	for _, name := range part.PartitionNames() {
+		normName := sqlbase.ReNormalizeName(name)
+		if _, ok := names[normName]; ok {
+			return fmt.Errorf("duplicate partition name: %q", name)
+		}
+		names[normName] = struct{}{}
+		if _, err := desc.FindIndexByPartitionName(name); err == nil {
+			return fmt.Errorf("partition %q already exists", name)
+		}
+	}
+	index.Partitioning = part
+	return nil
+}
+
+// encodePartitionValues returns the key encoding, in the index, of the values
+// of a partition for the first numColumns columns of the index.
+func (p *planner) encodePartitionValues(
+	desc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor, numColumns int, exprs parser.Exprs,
+) ([]byte, error) {
+	if len(exprs) != numColumns {
+		return nil, fmt.Errorf("partition values (%s) must have a value for each of the %d partition columns",
+			exprs, numColumns)
+	}
+	var key []byte
+	for i, expr := range exprs {
+		col, err := desc.FindColumnByID(index.ColumnIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		typ := col.Type.ToDatumType()
+		typedExpr, err := parser.TypeCheck(expr, nil, typ)
+		if err != nil {
+			return nil, err
+		}
+		d, err := typedExpr.Eval(&p.evalCtx)
+		if err != nil {
+			return nil, err
+		}
+		if d != parser.DNull && !d.TypeEqual(typ) {
+			return nil, fmt.Errorf("partition value %s for column %q must be of type %s, not type %s",
+				expr, col.Name, typ.Type(), d.Type())
+		}
+		dir, err := index.ColumnDirections[i].ToEncodingDirection()
+		if err != nil {
+			return nil, err
+		}
+		if key, err = sqlbase.EncodeTableKey(key, d, dir); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// decodePartitionValues returns the values of a partition, formatted as SQL
+// literals, from their key encoding in the index.
+func decodePartitionValues(
+	desc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor, key []byte,
+) ([]string, error) {
+	var a sqlbase.DatumAlloc
+	values := make([]string, index.Partitioning.NumColumns)
+	for i := range values {
+		col, err := desc.FindColumnByID(index.ColumnIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		dir, err := index.ColumnDirections[i].ToEncodingDirection()
+		if err != nil {
+			return nil, err
+		}
+		var d parser.Datum
+		d, key, err = sqlbase.DecodeTableKey(&a, col.Type.ToDatumType(), key, dir)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = d.String()
+	}
+	return values, nil
+}
+
+// showCreatePartitioning returns a PARTITION BY clause for the specified
+// index, if it is partitioned.
+func showCreatePartitioning(
+	desc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor,
+) (string, error) {
+	if !index.IsPartitioned() {
+		return "", nil
+	}
+	part := &index.Partitioning
+	var buf bytes.Buffer
+	kind := "LIST"
+	if len(part.Range) > 0 {
+		kind = "RANGE"
+	}
+	fmt.Fprintf(&buf, " PARTITION BY %s (%s) (", kind, quoteNames(index.ColumnNames[:part.NumColumns]...))
+	for i, l := range part.List {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "PARTITION %s VALUES IN (", quoteNames(l.Name))
+		for j, v := range l.Values {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			values, err := decodePartitionValues(desc, index, v)
+			if err != nil {
+				return "", err
+			}
+			if len(values) == 1 {
+				buf.WriteString(values[0])
+			} else {
+				fmt.Fprintf(&buf, "(%s)", strings.Join(values, ", "))
+			}
+		}
+		buf.WriteByte(')')
+	}
+	for i, r := range part.Range {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "PARTITION %s VALUES < ", quoteNames(r.Name))
+		if len(r.UpperBound) == 0 {
+			buf.WriteString("MAXVALUE")
+			continue
+		}
+		values, err := decodePartitionValues(desc, index, r.UpperBound)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "(%s)", strings.Join(values, ", "))
+	}
+	buf.WriteByte(')')
+	return buf.String(), nil
+}
@@ -0,0 +1,986 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/pq/oid"
+)
+
+// pgCatalog is a virtual schema exposing a subset of PostgreSQL's system
+// catalogs, which clients such as psql and ORMs use to introspect the
+// database. Object identifiers (OIDs) are not stored anywhere; they are
+// derived by hashing the identity of each object, so they are stable as long
+// as the objects are not renamed.
+var pgCatalog = virtualSchema{
+	name: pgCatalogName,
+	tables: []virtualSchemaTable{
+		pgCatalogAttrDefTable,
+		pgCatalogAttributeTable,
+		pgCatalogClassTable,
+		pgCatalogConstraintTable,
+		pgCatalogDatabaseTable,
+		pgCatalogIndexTable,
+		pgCatalogNamespaceTable,
+		pgCatalogTablesTable,
+		pgCatalogTypeTable,
+	},
+}
+
+const pgCatalogName = "pg_catalog"
+
+var (
+	// pgCatalogEncoding is the value of pg_database.encoding for UTF8.
+	pgCatalogEncoding = parser.NewDInt(6)
+	pgCatalogCollate  = parser.NewDString("en_US.utf8")
+
+	zeroOid      = parser.NewDInt(0)
+	persistenceP = parser.NewDString("p")
+	persistenceT = parser.NewDString("t")
+
+	relKindTable = parser.NewDString("r")
+	relKindIndex = parser.NewDString("i")
+	relKindView  = parser.NewDString("v")
+
+	conTypeCheck      = parser.NewDString("c")
+	conTypeForeignKey = parser.NewDString("f")
+	conTypePrimaryKey = parser.NewDString("p")
+	conTypeUnique     = parser.NewDString("u")
+
+	fkActionNoAction = parser.NewDString("a")
+	fkActionRestrict = parser.NewDString("r")
+	fkActionSetNull  = parser.NewDString("n")
+	fkActionCascade  = parser.NewDString("c")
+	fkMatchSimple    = parser.NewDString("s")
+)
+
+var pgCatalogAttrDefTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_attrdef (
+  oid INT,
+  adrelid INT,
+  adnum INT,
+  adbin STRING,
+  adsrc STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				for _, column := range table.Columns {
+					if column.DefaultExpr == nil {
+						continue
+					}
+					defSrc := parser.NewDString(*column.DefaultExpr)
+					addRow(
+						h.ColumnDefaultOid(db, table, &column), // oid
+						h.TableOid(db, table),                  // adrelid
+						parser.NewDInt(parser.DInt(column.ID)), // adnum
+						defSrc,                                 // adbin
+						defSrc,                                 // adsrc
+					)
+				}
+			},
+		)
+	},
+}
+
+var pgCatalogAttributeTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_attribute (
+  attrelid INT,
+  attname STRING,
+  atttypid INT,
+  attstattarget INT,
+  attlen INT,
+  attnum INT,
+  attndims INT,
+  attcacheoff INT,
+  atttypmod INT,
+  attbyval BOOL,
+  attstorage STRING,
+  attalign STRING,
+  attnotnull BOOL,
+  atthasdef BOOL,
+  attisdropped BOOL,
+  attislocal BOOL,
+  attinhcount INT,
+  attacl STRING,
+  attoptions STRING,
+  attfdwoptions STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				addColumn := func(relOid parser.Datum, column *sqlbase.ColumnDescriptor, attNum int) {
+					typ := pgTypeForColumnType(column.Type)
+					addRow(
+						relOid,                                  // attrelid
+						parser.NewDString(column.Name),          // attname
+						parser.NewDInt(parser.DInt(typ.oid)),    // atttypid
+						zeroOid,                                 // attstattarget
+						parser.NewDInt(parser.DInt(typ.typLen)), // attlen
+						parser.NewDInt(parser.DInt(attNum)),     // attnum
+						zeroOid,                                 // attndims
+						parser.NewDInt(-1),                      // attcacheoff
+						parser.NewDInt(-1),                      // atttypmod
+						parser.DNull,                            // attbyval
+						parser.DNull,                            // attstorage
+						parser.DNull,                            // attalign
+						parser.MakeDBool(parser.DBool(!column.Nullable)),          // attnotnull
+						parser.MakeDBool(parser.DBool(column.DefaultExpr != nil)), // atthasdef
+						parser.DBoolFalse, // attisdropped
+						parser.DBoolTrue,  // attislocal
+						zeroOid,           // attinhcount
+						parser.DNull,      // attacl
+						parser.DNull,      // attoptions
+						parser.DNull,      // attfdwoptions
+					)
+				}
+
+				// Columns of the table or view.
+				tableOid := h.TableOid(db, table)
+				for i := range table.Columns {
+					column := &table.Columns[i]
+					if column.Hidden {
+						continue
+					}
+					addColumn(tableOid, column, int(column.ID))
+				}
+
+				// Columns of its indexes.
+				if table.IsView() || isVirtualDescriptor(table) {
+					return
+				}
+				for _, index := range table.AllNonDropIndexes() {
+					indexOid := h.IndexOid(db, table, &index)
+					for i, colID := range index.ColumnIDs {
+						column, err := table.FindColumnByID(colID)
+						if err != nil {
+							continue
+						}
+						addColumn(indexOid, column, i+1)
+					}
+				}
+			},
+		)
+	},
+}
+
+var pgCatalogClassTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_class (
+  oid INT,
+  relname STRING NOT NULL DEFAULT '',
+  relnamespace INT,
+  reltype INT,
+  relowner INT,
+  relam INT,
+  relfilenode INT,
+  reltablespace INT,
+  relpages INT,
+  reltuples FLOAT,
+  relallvisible INT,
+  reltoastrelid INT,
+  relhasindex BOOL,
+  relisshared BOOL,
+  relpersistence STRING,
+  relistemp BOOL,
+  relkind STRING,
+  relnatts INT,
+  relchecks INT,
+  relhasoids BOOL,
+  relhaspkey BOOL,
+  relhasrules BOOL,
+  relhastriggers BOOL,
+  relhassubclass BOOL,
+  relfrozenxid INT,
+  relacl STRING,
+  reloptions STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				persistence, isTemp := persistenceP, parser.DBoolFalse
+				if table.IsTemporary() {
+					persistence, isTemp = persistenceT, parser.DBoolTrue
+				}
+				// Virtual tables are presented as views, matching
+				// information_schema.tables.
+				hasIndexes := !table.IsView() && !isVirtualDescriptor(table)
+				relKind := relKindTable
+				if !hasIndexes {
+					relKind = relKindView
+				}
+				visibleColumns := 0
+				for _, column := range table.Columns {
+					if !column.Hidden {
+						visibleColumns++
+					}
+				}
+				namespaceOid := h.NamespaceOid(db)
+				addRow(
+					h.TableOid(db, table),         // oid
+					parser.NewDString(table.Name), // relname
+					namespaceOid,                  // relnamespace
+					zeroOid,                       // reltype
+					parser.DNull,                  // relowner
+					parser.DNull,                  // relam
+					zeroOid,                       // relfilenode
+					zeroOid,                       // reltablespace
+					parser.DNull,                  // relpages
+					parser.DNull,                  // reltuples
+					zeroOid,                       // relallvisible
+					zeroOid,                       // reltoastrelid
+					parser.MakeDBool(parser.DBool(hasIndexes)), // relhasindex
+					parser.DBoolFalse,                          // relisshared
+					persistence,                                // relpersistence
+					isTemp,                                     // relistemp
+					relKind,                                    // relkind
+					parser.NewDInt(parser.DInt(visibleColumns)),    // relnatts
+					parser.NewDInt(parser.DInt(len(table.Checks))), // relchecks
+					parser.DBoolFalse,                          // relhasoids
+					parser.MakeDBool(parser.DBool(hasIndexes)), // relhaspkey
+					parser.DBoolFalse,                          // relhasrules
+					parser.DBoolFalse,                          // relhastriggers
+					parser.DBoolFalse,                          // relhassubclass
+					zeroOid,                                    // relfrozenxid
+					parser.DNull,                               // relacl
+					parser.DNull,                               // reloptions
+				)
+
+				if !hasIndexes {
+					return
+				}
+				for _, index := range table.AllNonDropIndexes() {
+					addRow(
+						h.IndexOid(db, table, &index), // oid
+						parser.NewDString(index.Name), // relname
+						namespaceOid,                  // relnamespace
+						zeroOid,                       // reltype
+						parser.DNull,                  // relowner
+						parser.DNull,                  // relam
+						zeroOid,                       // relfilenode
+						zeroOid,                       // reltablespace
+						parser.DNull,                  // relpages
+						parser.DNull,                  // reltuples
+						zeroOid,                       // relallvisible
+						zeroOid,                       // reltoastrelid
+						parser.DBoolFalse,             // relhasindex
+						parser.DBoolFalse,             // relisshared
+						persistence,                   // relpersistence
+						isTemp,                        // relistemp
+						relKindIndex,                  // relkind
+						parser.NewDInt(parser.DInt(len(index.ColumnIDs))), // relnatts
+						zeroOid,           // relchecks
+						parser.DBoolFalse, // relhasoids
+						parser.DBoolFalse, // relhaspkey
+						parser.DBoolFalse, // relhasrules
+						parser.DBoolFalse, // relhastriggers
+						parser.DBoolFalse, // relhassubclass
+						zeroOid,           // relfrozenxid
+						parser.DNull,      // relacl
+						parser.DNull,      // reloptions
+					)
+				}
+			},
+		)
+	},
+}
+
+var pgCatalogConstraintTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_constraint (
+  oid INT,
+  conname STRING,
+  connamespace INT,
+  contype STRING,
+  condeferrable BOOL,
+  condeferred BOOL,
+  convalidated BOOL,
+  conrelid INT,
+  contypid INT,
+  conindid INT,
+  confrelid INT,
+  confupdtype STRING,
+  confdeltype STRING,
+  confmatchtype STRING,
+  conislocal BOOL,
+  coninhcount INT,
+  connoinherit BOOL,
+  conkey STRING,
+  confkey STRING,
+  conpfeqop STRING,
+  conppeqop STRING,
+  conffeqop STRING,
+  conexclop STRING,
+  conbin STRING,
+  consrc STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+
+		// Foreign keys refer to other tables by ID, so gather the tables the
+		// user can see first.
+		type tableWithDB struct {
+			db    *sqlbase.DatabaseDescriptor
+			table *sqlbase.TableDescriptor
+		}
+		tablesByID := make(map[sqlbase.ID]tableWithDB)
+		if err := forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if !isVirtualDescriptor(table) {
+					tablesByID[table.ID] = tableWithDB{db: db, table: table}
+				}
+			},
+		); err != nil {
+			return err
+		}
+
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if isVirtualDescriptor(table) || table.IsView() {
+					return
+				}
+				namespaceOid := h.NamespaceOid(db)
+				tableOid := h.TableOid(db, table)
+				addConstraint := func(
+					conOid parser.Datum,
+					name string,
+					conType parser.Datum,
+					indexOid parser.Datum,
+					fkTableOid parser.Datum,
+					fkUpdate, fkDelete, fkMatch parser.Datum,
+					conKey, confKey parser.Datum,
+					src parser.Datum,
+				) {
+					addRow(
+						conOid,                  // oid
+						parser.NewDString(name), // conname
+						namespaceOid,            // connamespace
+						conType,                 // contype
+						parser.DBoolFalse,       // condeferrable
+						parser.DBoolFalse,       // condeferred
+						parser.DBoolTrue,        // convalidated
+						tableOid,                // conrelid
+						zeroOid,                 // contypid
+						indexOid,                // conindid
+						fkTableOid,              // confrelid
+						fkUpdate,                // confupdtype
+						fkDelete,                // confdeltype
+						fkMatch,                 // confmatchtype
+						parser.DBoolTrue,        // conislocal
+						zeroOid,                 // coninhcount
+						parser.DBoolTrue,        // connoinherit
+						conKey,                  // conkey
+						confKey,                 // confkey
+						parser.DNull,            // conpfeqop
+						parser.DNull,            // conppeqop
+						parser.DNull,            // conffeqop
+						parser.DNull,            // conexclop
+						src,                     // conbin
+						src,                     // consrc
+					)
+				}
+
+				for _, index := range table.AllNonDropIndexes() {
+					indexOid := h.IndexOid(db, table, &index)
+					conKey := columnIDArray(index.ColumnIDs)
+					if index.ID == table.PrimaryIndex.ID {
+						addConstraint(h.PrimaryKeyConstraintOid(db, table, &index),
+							index.Name, conTypePrimaryKey, indexOid,
+							zeroOid, parser.DNull, parser.DNull, parser.DNull,
+							conKey, parser.DNull, parser.DNull)
+					} else if index.Unique && !index.IsPartial() {
+						addConstraint(h.UniqueConstraintOid(db, table, &index),
+							index.Name, conTypeUnique, indexOid,
+							zeroOid, parser.DNull, parser.DNull, parser.DNull,
+							conKey, parser.DNull, parser.DNull)
+					}
+
+					fk := index.ForeignKey
+					if !fk.IsSet() {
+						continue
+					}
+					fkTableOid, confKey := parser.Datum(parser.DNull), parser.Datum(parser.DNull)
+					if ref, ok := tablesByID[fk.Table]; ok {
+						fkTableOid = h.TableOid(ref.db, ref.table)
+						if refIndex, err := ref.table.FindIndexByID(fk.Index); err == nil {
+							confKey = columnIDArray(refIndex.ColumnIDs)
+						}
+					}
+					addConstraint(h.ForeignKeyConstraintOid(db, table, &fk),
+						fk.Name, conTypeForeignKey, indexOid,
+						fkTableOid, fkActionDatum(fk.OnUpdate), fkActionDatum(fk.OnDelete),
+						fkMatchSimple, conKey, confKey, parser.DNull)
+				}
+
+				for _, check := range table.Checks {
+					addConstraint(h.CheckConstraintOid(db, table, check),
+						check.Name, conTypeCheck, zeroOid,
+						zeroOid, parser.DNull, parser.DNull, parser.DNull,
+						parser.DNull, parser.DNull, parser.NewDString(check.Expr))
+				}
+			},
+		)
+	},
+}
+
+// columnIDArray formats a list of column IDs the way PostgreSQL formats the
+// int2[] columns of pg_constraint.
+func columnIDArray(ids []sqlbase.ColumnID) parser.Datum {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, id := range ids {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Itoa(int(id)))
+	}
+	buf.WriteByte('}')
+	return parser.NewDString(buf.String())
+}
+
+func fkActionDatum(action sqlbase.ForeignKeyReference_Action) parser.Datum {
+	switch action {
+	case sqlbase.ForeignKeyReference_RESTRICT:
+		return fkActionRestrict
+	case sqlbase.ForeignKeyReference_SET_NULL:
+		return fkActionSetNull
+	case sqlbase.ForeignKeyReference_CASCADE:
+		return fkActionCascade
+	default:
+		return fkActionNoAction
+	}
+}
+
+var pgCatalogDatabaseTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_database (
+  oid INT,
+  datname STRING,
+  datdba INT,
+  encoding INT,
+  datcollate STRING,
+  datctype STRING,
+  datistemplate BOOL,
+  datallowconn BOOL,
+  datconnlimit INT,
+  datlastsysoid INT,
+  datfrozenxid INT,
+  datminmxid INT,
+  dattablespace INT,
+  datacl STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachDatabaseDesc(p, func(db *sqlbase.DatabaseDescriptor) {
+			addRow(
+				h.DBOid(db),                // oid
+				parser.NewDString(db.Name), // datname
+				parser.DNull,               // datdba
+				pgCatalogEncoding,          // encoding
+				pgCatalogCollate,           // datcollate
+				pgCatalogCollate,           // datctype
+				parser.DBoolFalse,          // datistemplate
+				parser.DBoolTrue,           // datallowconn
+				parser.NewDInt(-1),         // datconnlimit
+				parser.DNull,               // datlastsysoid
+				parser.DNull,               // datfrozenxid
+				parser.DNull,               // datminmxid
+				parser.DNull,               // dattablespace
+				parser.DNull,               // datacl
+			)
+		})
+	},
+}
+
+var pgCatalogIndexTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_index (
+  indexrelid INT,
+  indrelid INT,
+  indnatts INT,
+  indisunique BOOL,
+  indisprimary BOOL,
+  indisexclusion BOOL,
+  indimmediate BOOL,
+  indisclustered BOOL,
+  indisvalid BOOL,
+  indcheckxmin BOOL,
+  indisready BOOL,
+  indislive BOOL,
+  indisreplident BOOL,
+  indkey STRING,
+  indcollation STRING,
+  indclass STRING,
+  indoption STRING,
+  indexprs STRING,
+  indpred STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if table.IsView() || isVirtualDescriptor(table) {
+					return
+				}
+				tableOid := h.TableOid(db, table)
+				for _, index := range table.AllNonDropIndexes() {
+					var keys, options, zeros bytes.Buffer
+					for i, colID := range index.ColumnIDs {
+						if i > 0 {
+							keys.WriteByte(' ')
+							options.WriteByte(' ')
+							zeros.WriteByte(' ')
+						}
+						keys.WriteString(strconv.Itoa(int(colID)))
+						// Bit 0 of indoption is set for descending columns.
+						if index.ColumnDirections[i] == sqlbase.IndexDescriptor_DESC {
+							options.WriteByte('1')
+						} else {
+							options.WriteByte('0')
+						}
+						zeros.WriteByte('0')
+					}
+					isPrimary := index.ID == table.PrimaryIndex.ID
+					addRow(
+						h.IndexOid(db, table, &index), // indexrelid
+						tableOid,                      // indrelid
+						parser.NewDInt(parser.DInt(len(index.ColumnIDs))), // indnatts
+						parser.MakeDBool(parser.DBool(index.Unique)),      // indisunique
+						parser.MakeDBool(parser.DBool(isPrimary)),         // indisprimary
+						parser.DBoolFalse, // indisexclusion
+						parser.MakeDBool(parser.DBool(index.Unique)), // indimmediate
+						parser.MakeDBool(parser.DBool(isPrimary)),    // indisclustered
+						parser.DBoolTrue,                    // indisvalid
+						parser.DBoolFalse,                   // indcheckxmin
+						parser.DBoolTrue,                    // indisready
+						parser.DBoolTrue,                    // indislive
+						parser.DBoolFalse,                   // indisreplident
+						parser.NewDString(keys.String()),    // indkey
+						parser.NewDString(zeros.String()),   // indcollation
+						parser.NewDString(zeros.String()),   // indclass
+						parser.NewDString(options.String()), // indoption
+						parser.DNull,                        // indexprs
+						dStringOrNull(index.Predicate),      // indpred
+					)
+				}
+			},
+		)
+	},
+}
+
+var pgCatalogNamespaceTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_namespace (
+  oid INT,
+  nspname STRING NOT NULL DEFAULT '',
+  nspowner INT,
+  nspacl STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		return forEachDatabaseDesc(p, func(db *sqlbase.DatabaseDescriptor) {
+			addRow(
+				h.NamespaceOid(db),         // oid
+				parser.NewDString(db.Name), // nspname
+				parser.DNull,               // nspowner
+				parser.DNull,               // nspacl
+			)
+		})
+	},
+}
+
+var pgCatalogTablesTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_tables (
+  schemaname STRING,
+  tablename STRING,
+  tableowner STRING,
+  tablespace STRING,
+  hasindexes BOOL,
+  hasrules BOOL,
+  hastriggers BOOL,
+  rowsecurity BOOL
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		return forEachTableDesc(p,
+			func(db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor) {
+				if table.IsView() || isVirtualDescriptor(table) {
+					return
+				}
+				addRow(
+					parser.NewDString(db.Name),    // schemaname
+					parser.NewDString(table.Name), // tablename
+					parser.DNull,                  // tableowner
+					parser.DNull,                  // tablespace
+					parser.DBoolTrue,              // hasindexes
+					parser.DBoolFalse,             // hasrules
+					parser.DBoolFalse,             // hastriggers
+					parser.DBoolFalse,             // rowsecurity
+				)
+			},
+		)
+	},
+}
+
+var (
+	typTypeBase       = parser.NewDString("b")
+	typDelim          = parser.NewDString(",")
+	typCategoryArray  = parser.NewDString("A")
+	typCategoryBool   = parser.NewDString("B")
+	typCategoryDate   = parser.NewDString("D")
+	typCategoryNet    = parser.NewDString("I")
+	typCategoryNumber = parser.NewDString("N")
+	typCategoryString = parser.NewDString("S")
+	typCategorySpan   = parser.NewDString("T")
+	typCategoryUser   = parser.NewDString("U")
+)
+
+// pgType describes a type exposed through pg_type.
+type pgType struct {
+	oid      oid.Oid
+	name     string
+	typLen   int
+	category parser.Datum
+	elem     oid.Oid
+}
+
+// pgTypes lists the types exposed through pg_type, which are the types that
+// column values are sent to clients as.
+var pgTypes = []pgType{
+	{oid.T_bool, "bool", 1, typCategoryBool, 0},
+	{oid.T_bytea, "bytea", -1, typCategoryUser, 0},
+	{oid.T_int8, "int8", 8, typCategoryNumber, 0},
+	{oid.T_float8, "float8", 8, typCategoryNumber, 0},
+	{oid.T_numeric, "numeric", -1, typCategoryNumber, 0},
+	{oid.T_text, "text", -1, typCategoryString, 0},
+	{oid.T_date, "date", 4, typCategoryDate, 0},
+	{oid.T_timestamp, "timestamp", 8, typCategoryDate, 0},
+	{oid.T_timestamptz, "timestamptz", 8, typCategoryDate, 0},
+	{oid.T_interval, "interval", 16, typCategorySpan, 0},
+	{oid.T_inet, "inet", -1, typCategoryNet, 0},
+	{pgTypeJSONBOid, "jsonb", -1, typCategoryUser, 0},
+	{oid.T__int8, "_int8", -1, typCategoryArray, oid.T_int8},
+	{oid.T__text, "_text", -1, typCategoryArray, oid.T_text},
+}
+
+// pgTypeJSONBOid is the OID of the jsonb type, which github.com/cockroachdb/pq/oid
+// does not define.
+const pgTypeJSONBOid oid.Oid = 3802
+
+var pgTypesByOid = func() map[oid.Oid]pgType {
+	m := make(map[oid.Oid]pgType, len(pgTypes))
+	for _, typ := range pgTypes {
+		m[typ.oid] = typ
+	}
+	return m
+}()
+
+// pgTypeForColumnType returns the pg_type entry of the type values of the
+// given column type are sent to clients as.
+func pgTypeForColumnType(colType sqlbase.ColumnType) pgType {
+	var typOid oid.Oid
+	switch colType.Kind {
+	case sqlbase.ColumnType_BOOL:
+		typOid = oid.T_bool
+	case sqlbase.ColumnType_INT:
+		typOid = oid.T_int8
+	case sqlbase.ColumnType_FLOAT:
+		typOid = oid.T_float8
+	case sqlbase.ColumnType_DECIMAL:
+		typOid = oid.T_numeric
+	case sqlbase.ColumnType_DATE:
+		typOid = oid.T_date
+	case sqlbase.ColumnType_TIMESTAMP:
+		typOid = oid.T_timestamp
+	case sqlbase.ColumnType_TIMESTAMPTZ:
+		typOid = oid.T_timestamptz
+	case sqlbase.ColumnType_INTERVAL:
+		typOid = oid.T_interval
+	case sqlbase.ColumnType_STRING, sqlbase.ColumnType_COLLATEDSTRING:
+		typOid = oid.T_text
+	case sqlbase.ColumnType_BYTES:
+		typOid = oid.T_bytea
+	case sqlbase.ColumnType_INET:
+		typOid = oid.T_inet
+	case sqlbase.ColumnType_JSON:
+		typOid = pgTypeJSONBOid
+	case sqlbase.ColumnType_ARRAY:
+		if colType.ArrayContents != nil && *colType.ArrayContents == sqlbase.ColumnType_INT {
+			typOid = oid.T__int8
+		} else {
+			typOid = oid.T__text
+		}
+	default:
+		panic(fmt.Sprintf("unsupported column type: %s", colType.Kind))
+	}
+	return pgTypesByOid[typOid]
+}
+
+var pgCatalogTypeTable = virtualSchemaTable{
+	schema: `
+CREATE TABLE pg_catalog.pg_type (
+  oid INT,
+  typname STRING NOT NULL DEFAULT '',
+  typnamespace INT,
+  typowner INT,
+  typlen INT,
+  typbyval BOOL,
+  typtype STRING,
+  typcategory STRING,
+  typispreferred BOOL,
+  typisdefined BOOL,
+  typdelim STRING,
+  typrelid INT,
+  typelem INT,
+  typarray INT,
+  typinput STRING,
+  typoutput STRING,
+  typreceive STRING,
+  typsend STRING,
+  typmodin STRING,
+  typmodout STRING,
+  typanalyze STRING,
+  typalign STRING,
+  typstorage STRING,
+  typnotnull BOOL,
+  typbasetype INT,
+  typtypmod INT,
+  typndims INT,
+  typcollation INT,
+  typdefaultbin STRING,
+  typdefault STRING,
+  typacl STRING
+);
+`,
+	populate: func(p *planner, addRow func(...parser.Datum)) error {
+		h := makeOidHasher()
+		namespaceOid := h.NamespaceOid(virtualSchemaMap[pgCatalogName].desc)
+		for _, typ := range pgTypes {
+			typByVal := typ.typLen > 0 && typ.typLen <= 8
+			addRow(
+				parser.NewDInt(parser.DInt(typ.oid)),     // oid
+				parser.NewDString(typ.name),              // typname
+				namespaceOid,                             // typnamespace
+				parser.DNull,                             // typowner
+				parser.NewDInt(parser.DInt(typ.typLen)),  // typlen
+				parser.MakeDBool(parser.DBool(typByVal)), // typbyval
+				typTypeBase,                              // typtype
+				typ.category,                             // typcategory
+				parser.DBoolFalse,                        // typispreferred
+				parser.DBoolTrue,                         // typisdefined
+				typDelim,                                 // typdelim
+				zeroOid,                                  // typrelid
+				parser.NewDInt(parser.DInt(typ.elem)),    // typelem
+				zeroOid,                                  // typarray
+				parser.DNull,                             // typinput
+				parser.DNull,                             // typoutput
+				parser.DNull,                             // typreceive
+				parser.DNull,                             // typsend
+				parser.DNull,                             // typmodin
+				parser.DNull,                             // typmodout
+				parser.DNull,                             // typanalyze
+				parser.DNull,                             // typalign
+				parser.DNull,                             // typstorage
+				parser.DBoolFalse,                        // typnotnull
+				zeroOid,                                  // typbasetype
+				parser.NewDInt(-1),                       // typtypmod
+				zeroOid,                                  // typndims
+				zeroOid,                                  // typcollation
+				parser.DNull,                             // typdefaultbin
+				parser.DNull,                             // typdefault
+				parser.DNull,                             // typacl
+			)
+		}
+		return nil
+	},
+}
+
+// oidHasher provides a consistent hashing mechanism for object identifiers in
+// pg_catalog tables, allowing for reliable joins across tables.
+//
+// In Postgres, oids are physical properties of database objects which are
+// sequentially generated and naturally unique across all objects. See:
+// https://www.postgresql.org/docs/9.6/static/datatype-oid.html.
+// Because Cockroach does not have an equivalent concept, we generate arbitrary
+// fingerprints for database objects with the only requirements being that they
+// are unique across all objects of the same kind and that they are stable
+// across accesses.
+type oidHasher struct {
+	h hash.Hash32
+}
+
+func makeOidHasher() oidHasher {
+	return oidHasher{h: fnv.New32()}
+}
+
+func (h oidHasher) writeStr(s string) {
+	if _, err := h.h.Write([]byte(s)); err != nil {
+		panic(err)
+	}
+}
+
+func (h oidHasher) writeUInt32(i uint32) {
+	if err := binary.Write(h.h, binary.BigEndian, i); err != nil {
+		panic(err)
+	}
+}
+
+// oidTypeTag distinguishes the kinds of objects hashed into an oid, so that
+// different kinds of objects with the same identity get different oids.
+type oidTypeTag uint8
+
+const (
+	_ oidTypeTag = iota
+	databaseTypeTag
+	namespaceTypeTag
+	tableTypeTag
+	indexTypeTag
+	columnTypeTag
+	checkConstraintTypeTag
+	pKeyConstraintTypeTag
+	uniqueConstraintTypeTag
+	fkConstraintTypeTag
+)
+
+func (h oidHasher) writeTypeTag(tag oidTypeTag) {
+	h.writeUInt32(uint32(tag))
+}
+
+func (h oidHasher) getOid() *parser.DInt {
+	i := h.h.Sum32()
+	h.h.Reset()
+	return parser.NewDInt(parser.DInt(i))
+}
+
+func (h oidHasher) writeDB(db *sqlbase.DatabaseDescriptor) {
+	h.writeUInt32(uint32(db.ID))
+	h.writeStr(db.Name)
+}
+
+// writeTable hashes the identity of a table. Virtual tables all share the
+// same descriptor ID, so their names are hashed as well.
+func (h oidHasher) writeTable(table *sqlbase.TableDescriptor) {
+	h.writeUInt32(uint32(table.ID))
+	h.writeStr(table.Name)
+}
+
+func (h oidHasher) writeIndex(index *sqlbase.IndexDescriptor) {
+	h.writeUInt32(uint32(index.ID))
+}
+
+func (h oidHasher) DBOid(db *sqlbase.DatabaseDescriptor) *parser.DInt {
+	h.writeTypeTag(databaseTypeTag)
+	h.writeDB(db)
+	return h.getOid()
+}
+
+func (h oidHasher) NamespaceOid(db *sqlbase.DatabaseDescriptor) *parser.DInt {
+	h.writeTypeTag(namespaceTypeTag)
+	h.writeDB(db)
+	return h.getOid()
+}
+
+func (h oidHasher) TableOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor,
+) *parser.DInt {
+	h.writeTypeTag(tableTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	return h.getOid()
+}
+
+func (h oidHasher) IndexOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor,
+) *parser.DInt {
+	h.writeTypeTag(indexTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeIndex(index)
+	return h.getOid()
+}
+
+func (h oidHasher) ColumnDefaultOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor, column *sqlbase.ColumnDescriptor,
+) *parser.DInt {
+	h.writeTypeTag(columnTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeUInt32(uint32(column.ID))
+	return h.getOid()
+}
+
+func (h oidHasher) CheckConstraintOid(
+	db *sqlbase.DatabaseDescriptor,
+	table *sqlbase.TableDescriptor,
+	check *sqlbase.TableDescriptor_CheckConstraint,
+) *parser.DInt {
+	h.writeTypeTag(checkConstraintTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeStr(check.Name)
+	h.writeStr(check.Expr)
+	return h.getOid()
+}
+
+func (h oidHasher) PrimaryKeyConstraintOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor, pkey *sqlbase.IndexDescriptor,
+) *parser.DInt {
+	h.writeTypeTag(pKeyConstraintTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeIndex(pkey)
+	return h.getOid()
+}
+
+func (h oidHasher) UniqueConstraintOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor,
+) *parser.DInt {
+	h.writeTypeTag(uniqueConstraintTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeIndex(index)
+	return h.getOid()
+}
+
+func (h oidHasher) ForeignKeyConstraintOid(
+	db *sqlbase.DatabaseDescriptor, table *sqlbase.TableDescriptor, fk *sqlbase.ForeignKeyReference,
+) *parser.DInt {
+	h.writeTypeTag(fkConstraintTypeTag)
+	h.writeDB(db)
+	h.writeTable(table)
+	h.writeStr(fk.Name)
+	return h.getOid()
+}
@@ -0,0 +1,237 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/pkg/errors"
+)
+
+// hbaConf holds the host-based authentication configuration, in a format
+// modeled after PostgreSQL's pg_hba.conf. Every line holds a rule:
+//
+//	host <users> <address> <method>
+//
+// where <users> is "all" or a comma-separated list of user names, <address>
+// is "all", an IP address or a CIDR block, and <method> is one of the
+// hbaMethods. Text following a '#' is a comment. The method of the first
+// rule matching the user and the address of a client is used to
+// authenticate it; clients matching no rule are rejected. When the
+// configuration is empty, clients authenticate with their certificate if
+// they present one, and with their password otherwise.
+var hbaConf = settings.RegisterValidatedStringSetting(
+	"server.host_based_authentication.configuration",
+	"host-based authentication configuration to use during connection authentication",
+	"",
+	func(s string) error {
+		_, err := parseHBAConf(s)
+		return err
+	},
+)
+
+// The authentication methods of the host-based authentication rules.
+const (
+	// hbaMethodCert requires a client certificate.
+	hbaMethodCert = "cert"
+	// hbaMethodPassword requires the password of the user, even if the
+	// client presents a certificate.
+	hbaMethodPassword = "password"
+	// hbaMethodCertPassword uses the client certificate if there is one,
+	// and the password of the user otherwise.
+	hbaMethodCertPassword = "cert-password"
+	// hbaMethodTrust accepts the client without authenticating it.
+	hbaMethodTrust = "trust"
+	// hbaMethodReject rejects the client.
+	hbaMethodReject = "reject"
+)
+
+var hbaMethods = map[string]struct{}{
+	hbaMethodCert:         {},
+	hbaMethodPassword:     {},
+	hbaMethodCertPassword: {},
+	hbaMethodTrust:        {},
+	hbaMethodReject:       {},
+}
+
+// hbaRule is a rule of the host-based authentication configuration.
+type hbaRule struct {
+	// users is nil if the rule matches all the users.
+	users []string
+	// network is nil if the rule matches all the addresses.
+	network *net.IPNet
+	method  string
+}
+
+// matches returns whether the rule applies to a user connecting from ip.
+func (r hbaRule) matches(user string, ip net.IP) bool {
+	if r.network != nil && (ip == nil || !r.network.Contains(ip)) {
+		return false
+	}
+	if r.users == nil {
+		return true
+	}
+	for _, u := range r.users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHBAConf parses a host-based authentication configuration.
+func parseHBAConf(conf string) ([]hbaRule, error) {
+	var rules []hbaRule
+	for i, line := range strings.Split(conf, "\n") {
+		if idx := strings.IndexByte(line, '#'); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rule, err := parseHBARule(fields)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseHBARule(fields []string) (hbaRule, error) {
+	var rule hbaRule
+	if len(fields) != 4 {
+		return rule, errors.Errorf("expected 4 fields, found %d", len(fields))
+	}
+	if fields[0] != "host" {
+		return rule, errors.Errorf("unsupported connection type %q", fields[0])
+	}
+
+	if users := fields[1]; users != "all" {
+		for _, u := range strings.Split(users, ",") {
+			if u == "" {
+				return rule, errors.Errorf("invalid user list %q", users)
+			}
+			rule.users = append(rule.users, u)
+		}
+	}
+
+	if addr := fields[2]; addr != "all" {
+		if strings.IndexByte(addr, '/') < 0 {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return rule, errors.Errorf("invalid address %q", addr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			rule.network = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		} else {
+			_, network, err := net.ParseCIDR(addr)
+			if err != nil {
+				return rule, errors.Errorf("invalid address %q", addr)
+			}
+			rule.network = network
+		}
+	}
+
+	rule.method = fields[3]
+	if _, ok := hbaMethods[rule.method]; !ok {
+		return rule, errors.Errorf("unknown authentication method %q", rule.method)
+	}
+	return rule, nil
+}
+
+// hbaMethod returns the authentication method of a user connecting from
+// addr according to the current host-based authentication configuration.
+func hbaMethod(user string, addr net.Addr) (string, error) {
+	rules, err := parseHBAConf(hbaConf.Get())
+	if err != nil {
+		return "", err
+	}
+	if rules == nil {
+		return hbaMethodCertPassword, nil
+	}
+	var ip net.IP
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		ip = net.ParseIP(host)
+	}
+	for _, rule := range rules {
+		if rule.matches(user, ip) {
+			return rule.method, nil
+		}
+	}
+	return hbaMethodReject, nil
+}
+
+// authenticationHook returns the hook authenticating the user of a
+// connection over TLS, following the host-based authentication
+// configuration. The root user presenting a certificate is always
+// authenticated with it, so that the configuration cannot lock it out.
+// Roles are rejected, as they cannot log in.
+func (s *Server) authenticationHook(
+	c *v3Conn, user string, addr net.Addr, tlsState *tls.ConnectionState,
+) (func(string, bool) error, error) {
+	method := hbaMethodCert
+	if user != security.RootUser || len(tlsState.PeerCertificates) == 0 {
+		var err error
+		if method, err = hbaMethod(user, addr); err != nil {
+			return nil, err
+		}
+	}
+	if method == hbaMethodCertPassword {
+		method = hbaMethodCert
+		if len(tlsState.PeerCertificates) == 0 {
+			method = hbaMethodPassword
+		}
+	}
+
+	// Roles cannot log in, whatever the authentication method.
+	if method != hbaMethodReject && user != security.RootUser {
+		isRole, err := s.executor.IsRole(user)
+		if err != nil {
+			return nil, err
+		}
+		if isRole {
+			return nil, errors.Errorf("%s is a role, not a user: roles cannot log in", user)
+		}
+	}
+
+	switch method {
+	case hbaMethodCert:
+		return security.UserAuthHook(s.context.Insecure, tlsState)
+	case hbaMethodPassword:
+		password, err := c.sendAuthPasswordRequest()
+		if err != nil {
+			return nil, err
+		}
+		exists, hashedPassword, err := s.executor.GetUserHashedPassword(user)
+		if err != nil {
+			return nil, err
+		}
+		return security.UserAuthPasswordHook(s.context.Insecure, password, exists, hashedPassword), nil
+	case hbaMethodTrust:
+		// There is nothing to verify, as in insecure mode.
+		return security.UserAuthHook(true /* insecureMode */, tlsState)
+	default:
+		return nil, errors.Errorf("authentication rejected by configuration for user %s", user)
+	}
+}
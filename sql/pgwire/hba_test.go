@@ -0,0 +1,83 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package pgwire
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestParseHBAConf(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	errTests := []struct {
+		conf        string
+		expectedErr string
+	}{
+		{`host all all`, `line 1: expected 4 fields, found 3`},
+		{`local all all trust`, `line 1: unsupported connection type "local"`},
+		{"\nhostssl all all trust", `line 2: unsupported connection type "hostssl"`},
+		{`host alice,,bob all trust`, `line 1: invalid user list "alice,,bob"`},
+		{`host all 10.0.0.0/33 trust`, `line 1: invalid address "10.0.0.0/33"`},
+		{`host all localhost trust`, `line 1: invalid address "localhost"`},
+		{`host all all md5`, `line 1: unknown authentication method "md5"`},
+	}
+	for _, tc := range errTests {
+		if _, err := parseHBAConf(tc.conf); !testutils.IsError(err, tc.expectedErr) {
+			t.Errorf("%q: expected error %q, got %v", tc.conf, tc.expectedErr, err)
+		}
+	}
+
+	rules, err := parseHBAConf(`
+# TYPE USERS       ADDRESS        METHOD
+host   root        all            cert
+host   alice,bob   10.0.0.0/8     password   # internal network
+host   all         192.168.1.1    trust
+host   all         2001:db8::/32  cert-password
+host   all         all            reject
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matchTests := []struct {
+		user, ip string
+		method   string
+	}{
+		{"root", "192.168.1.1", hbaMethodCert},
+		{"alice", "10.1.2.3", hbaMethodPassword},
+		{"bob", "10.1.2.3", hbaMethodPassword},
+		{"carl", "10.1.2.3", hbaMethodReject},
+		{"carl", "192.168.1.1", hbaMethodTrust},
+		{"carl", "192.168.1.2", hbaMethodReject},
+		{"carl", "2001:db8::1", hbaMethodCertPassword},
+		{"alice", "2001:db9::1", hbaMethodReject},
+	}
+	for _, tc := range matchTests {
+		ip := net.ParseIP(tc.ip)
+		method := ""
+		for _, rule := range rules {
+			if rule.matches(tc.user, ip) {
+				method = rule.method
+				break
+			}
+		}
+		if method != tc.method {
+			t.Errorf("%s@%s: expected method %q, got %q", tc.user, tc.ip, tc.method, method)
+		}
+	}
+}
@@ -24,7 +24,6 @@ import (
 	"time"
 
 	"github.com/cockroachdb/cockroach/base"
-	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/sql"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/metric"
@@ -65,6 +64,7 @@ var (
 type Server struct {
 	context  *base.Context
 	executor *sql.Executor
+	sessions *sql.SessionRegistry
 
 	registry *metric.Registry
 	metrics  *serverMetrics
@@ -90,10 +90,17 @@ func newServerMetrics(reg *metric.Registry) *serverMetrics {
 }
 
 // MakeServer creates a Server, adding network stats to the given Registry.
-func MakeServer(context *base.Context, executor *sql.Executor, reg *metric.Registry) *Server {
+// The sessions of the connections it serves are tracked in sessions.
+func MakeServer(
+	context *base.Context,
+	executor *sql.Executor,
+	reg *metric.Registry,
+	sessions *sql.SessionRegistry,
+) *Server {
 	return &Server{
 		context:  context,
 		executor: executor,
+		sessions: sessions,
 		registry: reg,
 		metrics:  newServerMetrics(reg),
 	}
@@ -215,6 +222,8 @@ func (s *Server) ServeConn(conn net.Conn) error {
 		// error.
 		v3conn := makeV3Conn(conn, s.executor, s.metrics, sessionArgs)
 		defer v3conn.finish()
+		s.sessions.Register(v3conn.session)
+		defer s.sessions.Deregister(v3conn.session)
 		if argsErr != nil {
 			return v3conn.sendInternalError(argsErr.Error())
 		}
@@ -229,7 +238,8 @@ func (s *Server) ServeConn(conn net.Conn) error {
 
 		if tlsConn, ok := conn.(*tls.Conn); ok {
 			tlsState := tlsConn.ConnectionState()
-			authenticationHook, err := security.UserAuthHook(s.context.Insecure, &tlsState)
+			authenticationHook, err := s.authenticationHook(
+				&v3conn, sessionArgs.User, conn.RemoteAddr(), &tlsState)
 			if err != nil {
 				return v3conn.sendInternalError(err.Error())
 			}
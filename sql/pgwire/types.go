@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"net"
 	"reflect"
 	"strconv"
 	"time"
@@ -32,6 +33,8 @@ import (
 	"gopkg.in/inf.v0"
 
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/ipaddr"
+	"github.com/cockroachdb/cockroach/util/jsonb"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/cockroachdb/pq"
 	"github.com/cockroachdb/pq/oid"
@@ -73,6 +76,20 @@ const (
 // The number of decimal digits per int16 Postgres "digit".
 const pgDecDigits = 4
 
+// oidJSONB is the Oid of the jsonb type, which github.com/cockroachdb/pq/oid
+// does not define.
+const oidJSONB oid.Oid = 3802
+
+// The address families used in the binary format of inet values.
+const (
+	pgBinaryIPv4family byte = 2
+	pgBinaryIPv6family byte = 3
+)
+
+// jsonbBinaryVersion is the version byte that prefixes the binary format of
+// jsonb values. Version 1 is followed by the text format.
+const jsonbBinaryVersion = 1
+
 type pgNumeric struct {
 	ndigits, weight, dscale int16
 	sign                    pgNumericSign
@@ -82,7 +99,7 @@ func typeForDatum(d parser.Datum) pgType {
 	if d == parser.DNull {
 		return pgType{}
 	}
-	switch d.(type) {
+	switch t := d.(type) {
 	case *parser.DBool:
 		return pgType{oid.T_bool, 1}
 
@@ -98,7 +115,7 @@ func typeForDatum(d parser.Datum) pgType {
 	case *parser.DDecimal:
 		return pgType{oid.T_numeric, -1}
 
-	case *parser.DString:
+	case *parser.DString, *parser.DCollatedString:
 		return pgType{oid.T_text, -1}
 
 	case *parser.DDate:
@@ -113,11 +130,29 @@ func typeForDatum(d parser.Datum) pgType {
 	case *parser.DInterval:
 		return pgType{oid.T_interval, 8}
 
+	case *parser.DArray:
+		return pgType{arrayOidForParamType(t.ParamTyp), -1}
+
+	case *parser.DJSON:
+		return pgType{oidJSONB, -1}
+
+	case *parser.DIPAddr:
+		return pgType{oid.T_inet, -1}
+
 	default:
 		panic(fmt.Sprintf("unsupported type %T", d))
 	}
 }
 
+// arrayOidForParamType returns the Oid of arrays of the given element type.
+func arrayOidForParamType(paramTyp parser.Datum) oid.Oid {
+	id, ok := datumToArrayOid[reflect.TypeOf(paramTyp)]
+	if !ok {
+		panic(fmt.Sprintf("unsupported array element type %T", paramTyp))
+	}
+	return id
+}
+
 const secondsInDay = 24 * 60 * 60
 
 func (b *writeBuffer) writeTextDatum(d parser.Datum, sessionLoc *time.Location) {
@@ -167,6 +202,9 @@ func (b *writeBuffer) writeTextDatum(d parser.Datum, sessionLoc *time.Location)
 	case *parser.DString:
 		b.writeLengthPrefixedString(string(*v))
 
+	case *parser.DCollatedString:
+		b.writeLengthPrefixedString(v.Contents)
+
 	case *parser.DDate:
 		t := time.Unix(int64(*v)*secondsInDay, 0)
 		s := formatTs(t, nil)
@@ -186,11 +224,112 @@ func (b *writeBuffer) writeTextDatum(d parser.Datum, sessionLoc *time.Location)
 	case *parser.DInterval:
 		b.writeLengthPrefixedString(v.String())
 
+	case *parser.DArray:
+		// Arrays are written as {a,b,"c d",NULL}. Each element is written in
+		// its own text format, which we obtain from a scratch writeBuffer by
+		// stripping the length prefix.
+		var arr bytes.Buffer
+		var elemBuf writeBuffer
+		arr.WriteByte('{')
+		for i, elem := range v.Array {
+			if i > 0 {
+				arr.WriteByte(',')
+			}
+			if elem == parser.DNull {
+				arr.WriteString("NULL")
+				continue
+			}
+			elemBuf.reset()
+			elemBuf.writeTextDatum(elem, sessionLoc)
+			if elemBuf.err != nil {
+				b.setError(elemBuf.err)
+				return
+			}
+			writeArrayTextElement(&arr, elemBuf.wrapped.Bytes()[4:])
+		}
+		arr.WriteByte('}')
+		b.putInt32(int32(arr.Len()))
+		b.write(arr.Bytes())
+
+	case *parser.DJSON:
+		b.writeLengthPrefixedString(jsonb.String(v.JSON))
+
+	case *parser.DIPAddr:
+		b.writeLengthPrefixedString(v.IPAddr.String())
+
 	default:
 		b.setError(errors.Errorf("unsupported type %T", d))
 	}
 }
 
+// writeArrayTextElement writes the text format of an array element, quoting
+// and escaping it if it would otherwise be ambiguous.
+func writeArrayTextElement(buf *bytes.Buffer, elem []byte) {
+	needsQuotes := len(elem) == 0 || bytes.EqualFold(elem, []byte("NULL"))
+	for _, c := range elem {
+		switch c {
+		case '{', '}', ',', '"', '\\', ' ', '\t', '\n', '\r', '\v', '\f':
+			needsQuotes = true
+		}
+	}
+	if !needsQuotes {
+		buf.Write(elem)
+		return
+	}
+	buf.WriteByte('"')
+	for _, c := range elem {
+		if c == '"' || c == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteByte('"')
+}
+
+// writeCopyTextValue writes a value in the text format of COPY, escaping
+// the characters which would otherwise be taken as delimiters.
+func writeCopyTextValue(buf *bytes.Buffer, val []byte) {
+	for _, c := range val {
+		switch c {
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+// writeCopyCSVValue writes a value in the CSV format of COPY, quoting it if
+// it holds special characters. Empty strings are quoted too, to tell them
+// apart from NULL.
+func writeCopyCSVValue(buf *bytes.Buffer, val []byte) {
+	needsQuotes := len(val) == 0
+	for _, c := range val {
+		switch c {
+		case ',', '"', '\n', '\r':
+			needsQuotes = true
+		}
+	}
+	if !needsQuotes {
+		buf.Write(val)
+		return
+	}
+	buf.WriteByte('"')
+	for _, c := range val {
+		if c == '"' {
+			buf.WriteByte('"')
+		}
+		buf.WriteByte(c)
+	}
+	buf.WriteByte('"')
+}
+
 func (b *writeBuffer) writeBinaryDatum(d parser.Datum) {
 	if log.V(2) {
 		log.Infof(context.TODO(), "pgwire writing BINARY datum of type: %T, %#v", d, d)
@@ -303,6 +442,63 @@ func (b *writeBuffer) writeBinaryDatum(d parser.Datum) {
 	case *parser.DString:
 		b.writeLengthPrefixedString(string(*v))
 
+	case *parser.DCollatedString:
+		b.writeLengthPrefixedString(v.Contents)
+
+	case *parser.DArray:
+		// The binary format of a one-dimensional array is a header holding
+		// the number of dimensions, a flag for the presence of NULLs, and the
+		// element Oid, followed by the size and lower bound of the dimension
+		// and the length-prefixed elements.
+		var arr writeBuffer
+		hasNulls := int32(0)
+		for _, elem := range v.Array {
+			if elem == parser.DNull {
+				hasNulls = 1
+				break
+			}
+		}
+		ndims := int32(1)
+		if len(v.Array) == 0 {
+			ndims = 0
+		}
+		arr.putInt32(ndims)
+		arr.putInt32(hasNulls)
+		arr.putInt32(int32(typeForDatum(v.ParamTyp).oid))
+		if ndims > 0 {
+			arr.putInt32(int32(len(v.Array)))
+			arr.putInt32(1)
+		}
+		for _, elem := range v.Array {
+			arr.writeBinaryDatum(elem)
+		}
+		if arr.err != nil {
+			b.setError(arr.err)
+			return
+		}
+		b.putInt32(int32(arr.wrapped.Len()))
+		b.write(arr.wrapped.Bytes())
+
+	case *parser.DJSON:
+		s := jsonb.String(v.JSON)
+		b.putInt32(int32(len(s) + 1))
+		b.writeByte(jsonbBinaryVersion)
+		b.writeString(s)
+
+	case *parser.DIPAddr:
+		// The binary format is the family, the mask length, a flag that is
+		// set for cidr values, the address length and the address itself.
+		family := pgBinaryIPv4family
+		if v.Family == ipaddr.IPv6Family {
+			family = pgBinaryIPv6family
+		}
+		b.putInt32(int32(4 + len(v.Addr)))
+		b.writeByte(family)
+		b.writeByte(v.Mask)
+		b.writeByte(0)
+		b.writeByte(byte(len(v.Addr)))
+		b.write(v.Addr)
+
 	default:
 		b.setError(errors.Errorf("unsupported type %T", d))
 	}
@@ -367,10 +563,13 @@ var (
 		oid.T_date:        parser.TypeDate,
 		oid.T_float4:      parser.TypeFloat,
 		oid.T_float8:      parser.TypeFloat,
+		oid.T_inet:        parser.TypeINet,
 		oid.T_int2:        parser.TypeInt,
 		oid.T_int4:        parser.TypeInt,
 		oid.T_int8:        parser.TypeInt,
 		oid.T_interval:    parser.TypeInterval,
+		oid.T_json:        parser.TypeJSON,
+		oidJSONB:          parser.TypeJSON,
 		oid.T_numeric:     parser.TypeDecimal,
 		oid.T_text:        parser.TypeString,
 		oid.T_timestamp:   parser.TypeTimestamp,
@@ -385,11 +584,27 @@ var (
 		reflect.TypeOf(parser.TypeFloat):       oid.T_float8,
 		reflect.TypeOf(parser.TypeInt):         oid.T_int8,
 		reflect.TypeOf(parser.TypeInterval):    oid.T_interval,
+		reflect.TypeOf(parser.TypeJSON):        oidJSONB,
+		reflect.TypeOf(parser.TypeINet):        oid.T_inet,
 		reflect.TypeOf(parser.TypeDecimal):     oid.T_numeric,
 		reflect.TypeOf(parser.TypeString):      oid.T_text,
 		reflect.TypeOf(parser.TypeTimestamp):   oid.T_timestamp,
 		reflect.TypeOf(parser.TypeTimestampTZ): oid.T_timestamptz,
 	}
+	// datumToArrayOid maps the element types of arrays to the Oids of the
+	// corresponding array types.
+	datumToArrayOid = map[reflect.Type]oid.Oid{
+		reflect.TypeOf(parser.TypeBool):        oid.T__bool,
+		reflect.TypeOf(parser.TypeBytes):       oid.T__bytea,
+		reflect.TypeOf(parser.TypeDate):        oid.T__date,
+		reflect.TypeOf(parser.TypeFloat):       oid.T__float8,
+		reflect.TypeOf(parser.TypeInt):         oid.T__int8,
+		reflect.TypeOf(parser.TypeInterval):    oid.T__interval,
+		reflect.TypeOf(parser.TypeDecimal):     oid.T__numeric,
+		reflect.TypeOf(parser.TypeString):      oid.T__text,
+		reflect.TypeOf(parser.TypeTimestamp):   oid.T__timestamp,
+		reflect.TypeOf(parser.TypeTimestampTZ): oid.T__timestamptz,
+	}
 )
 
 // decodeOidDatum decodes bytes with specified Oid and format code into
@@ -650,6 +865,54 @@ func decodeOidDatum(id oid.Oid, code formatCode, b []byte) (parser.Datum, error)
 		default:
 			return d, errors.Errorf("unsupported interval format code: %s", code)
 		}
+	case oid.T_json, oidJSONB:
+		switch code {
+		case formatText:
+		case formatBinary:
+			// The binary format of json is the same as its text format, while
+			// that of jsonb is prefixed with a version byte.
+			if id == oidJSONB {
+				if len(b) == 0 || b[0] != jsonbBinaryVersion {
+					return d, errors.Errorf("unsupported jsonb binary format version")
+				}
+				b = b[1:]
+			}
+		default:
+			return d, errors.Errorf("unsupported json format code: %s", code)
+		}
+		j, err := parser.ParseDJSON(string(b))
+		if err != nil {
+			return d, err
+		}
+		return j, nil
+	case oid.T_inet:
+		switch code {
+		case formatText:
+			ip, err := parser.ParseDIPAddr(string(b))
+			if err != nil {
+				return d, err
+			}
+			return ip, nil
+		case formatBinary:
+			if len(b) < 4 || len(b) != 4+int(b[3]) {
+				return d, errors.Errorf("inet requires 4 bytes plus the address length")
+			}
+			ip := ipaddr.IPAddr{Mask: b[1], Addr: append(net.IP(nil), b[4:]...)}
+			switch {
+			case b[0] == pgBinaryIPv4family && len(ip.Addr) == net.IPv4len:
+				ip.Family = ipaddr.IPv4Family
+			case b[0] == pgBinaryIPv6family && len(ip.Addr) == net.IPv6len:
+				ip.Family = ipaddr.IPv6Family
+			default:
+				return d, errors.Errorf("unsupported inet address family: %d", b[0])
+			}
+			if int(ip.Mask) > 8*len(ip.Addr) {
+				return d, errors.Errorf("invalid inet mask length: %d", ip.Mask)
+			}
+			return parser.NewDIPAddr(ip), nil
+		default:
+			return d, errors.Errorf("unsupported inet format code: %s", code)
+		}
 	default:
 		return d, errors.Errorf("unsupported OID: %v", id)
 	}
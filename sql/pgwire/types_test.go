@@ -17,12 +17,14 @@
 package pgwire
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/pq/oid"
 
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/testutils"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/metric"
 )
@@ -82,6 +84,154 @@ func TestTimestampRoundtrip(t *testing.T) {
 	}
 }
 
+func TestWriteArrayDatum(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	arr := parser.NewDArray(parser.TypeString)
+	for _, elem := range []parser.Datum{
+		parser.NewDString("a"), parser.DNull, parser.NewDString(`b "c"`), parser.NewDString(""),
+	} {
+		if err := arr.Append(elem); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if typ := typeForDatum(arr); typ.oid != oid.T__text {
+		t.Errorf("expected oid %d, but found %d", oid.T__text, typ.oid)
+	}
+
+	buf := writeBuffer{bytecount: metric.NewCounter()}
+	buf.writeTextDatum(arr, time.UTC)
+	if buf.err != nil {
+		t.Fatal(buf.err)
+	}
+	expectedText := `{a,NULL,"b \"c\"",""}`
+	if text := string(buf.wrapped.Bytes()[4:]); text != expectedText {
+		t.Errorf("expected %s, but found %s", expectedText, text)
+	}
+
+	ints := parser.NewDArray(parser.TypeInt)
+	for _, elem := range []parser.Datum{parser.NewDInt(1), parser.DNull} {
+		if err := ints.Append(elem); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf.reset()
+	buf.writeBinaryDatum(ints)
+	if buf.err != nil {
+		t.Fatal(buf.err)
+	}
+	expectedBinary := []byte{
+		0, 0, 0, 36, // length
+		0, 0, 0, 1, // number of dimensions
+		0, 0, 0, 1, // has NULLs
+		0, 0, 0, 20, // element Oid (int8)
+		0, 0, 0, 2, // dimension size
+		0, 0, 0, 1, // dimension lower bound
+		0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 1, // 1
+		0xff, 0xff, 0xff, 0xff, // NULL
+	}
+	if !bytes.Equal(buf.wrapped.Bytes(), expectedBinary) {
+		t.Errorf("expected %v, but found %v", expectedBinary, buf.wrapped.Bytes())
+	}
+}
+
+func TestWriteCopyValue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		val, text, csv string
+	}{
+		{`abc`, `abc`, `abc`},
+		{``, ``, `""`},
+		{"a\tb\nc\\d", `a\tb\nc\\d`, "\"a\tb\nc\\d\""},
+		{`a,"b"`, `a,"b"`, `"a,""b"""`},
+		{"a\r", `a\r`, "\"a\r\""},
+	}
+	for _, tc := range testCases {
+		var text, csv bytes.Buffer
+		writeCopyTextValue(&text, []byte(tc.val))
+		writeCopyCSVValue(&csv, []byte(tc.val))
+		if text.String() != tc.text {
+			t.Errorf("%q: expected text %q, got %q", tc.val, tc.text, text.String())
+		}
+		if csv.String() != tc.csv {
+			t.Errorf("%q: expected CSV %q, got %q", tc.val, tc.csv, csv.String())
+		}
+	}
+}
+
+func TestJSONDatumRoundtrip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	j, err := parser.ParseDJSON(`{"b": [1, "x"], "a": null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := typeForDatum(j); typ.oid != oidJSONB {
+		t.Errorf("expected oid %d, but found %d", oidJSONB, typ.oid)
+	}
+
+	const expected = `{"a": null, "b": [1, "x"]}`
+	buf := writeBuffer{bytecount: metric.NewCounter()}
+	for _, code := range []formatCode{formatText, formatBinary} {
+		buf.reset()
+		if code == formatText {
+			buf.writeTextDatum(j, time.UTC)
+		} else {
+			buf.writeBinaryDatum(j)
+		}
+		if buf.err != nil {
+			t.Fatal(buf.err)
+		}
+		d, err := decodeOidDatum(oidJSONB, code, buf.wrapped.Bytes()[4:])
+		if err != nil {
+			t.Fatalf("%s: %v", code, err)
+		}
+		if s := d.(*parser.DJSON).String(); s != parser.NewDString(expected).String() {
+			t.Errorf("%s: expected %s, but found %s", code, expected, s)
+		}
+	}
+
+	if _, err := decodeOidDatum(oidJSONB, formatBinary, []byte("{}")); !testutils.IsError(err,
+		"unsupported jsonb binary format version") {
+		t.Errorf("expected version error, but found %v", err)
+	}
+}
+
+func TestIPAddrDatumRoundtrip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	buf := writeBuffer{bytecount: metric.NewCounter()}
+	for _, s := range []string{`10.0.0.1`, `192.168.0.1/24`, `::1`, `2001:db8::1/32`} {
+		ip, err := parser.ParseDIPAddr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ := typeForDatum(ip); typ.oid != oid.T_inet {
+			t.Errorf("expected oid %d, but found %d", oid.T_inet, typ.oid)
+		}
+		for _, code := range []formatCode{formatText, formatBinary} {
+			buf.reset()
+			if code == formatText {
+				buf.writeTextDatum(ip, time.UTC)
+			} else {
+				buf.writeBinaryDatum(ip)
+			}
+			if buf.err != nil {
+				t.Fatal(buf.err)
+			}
+			d, err := decodeOidDatum(oid.T_inet, code, buf.wrapped.Bytes()[4:])
+			if err != nil {
+				t.Fatalf("%s %s: %v", s, code, err)
+			}
+			if d.Compare(ip) != 0 {
+				t.Errorf("%s: expected %s, but found %s", code, ip, d)
+			}
+		}
+	}
+}
+
 func BenchmarkWriteBinaryDecimal(b *testing.B) {
 	buf := writeBuffer{bytecount: metric.NewCounter()}
 
@@ -19,6 +19,7 @@ package pgwire
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"net"
 	"reflect"
@@ -46,10 +47,14 @@ type serverMessageType byte
 const (
 	clientMsgBind        clientMessageType = 'B'
 	clientMsgClose       clientMessageType = 'C'
+	clientMsgCopyData    clientMessageType = 'd'
+	clientMsgCopyDone    clientMessageType = 'c'
+	clientMsgCopyFail    clientMessageType = 'f'
 	clientMsgDescribe    clientMessageType = 'D'
 	clientMsgExecute     clientMessageType = 'E'
 	clientMsgFlush       clientMessageType = 'H'
 	clientMsgParse       clientMessageType = 'P'
+	clientMsgPassword    clientMessageType = 'p'
 	clientMsgSimpleQuery clientMessageType = 'Q'
 	clientMsgSync        clientMessageType = 'S'
 	clientMsgTerminate   clientMessageType = 'X'
@@ -58,6 +63,10 @@ const (
 	serverMsgBindComplete         serverMessageType = '2'
 	serverMsgCommandComplete      serverMessageType = 'C'
 	serverMsgCloseComplete        serverMessageType = '3'
+	serverMsgCopyData             serverMessageType = 'd'
+	serverMsgCopyDone             serverMessageType = 'c'
+	serverMsgCopyInResponse       serverMessageType = 'G'
+	serverMsgCopyOutResponse      serverMessageType = 'H'
 	serverMsgDataRow              serverMessageType = 'D'
 	serverMsgEmptyQuery           serverMessageType = 'I'
 	serverMsgErrorResponse        serverMessageType = 'E'
@@ -91,7 +100,8 @@ const (
 )
 
 const (
-	authOK int32 = 0
+	authOK                int32 = 0
+	authCleartextPassword int32 = 3
 )
 
 // preparedStatementMeta is pgwire-specific metadata which is attached to each
@@ -189,6 +199,31 @@ var statusReportParams = map[string]string{
 	"server_version": "9.5.0",
 }
 
+// sendAuthPasswordRequest asks the client for its password and returns it.
+// The password is sent in cleartext, which is only done over TLS
+// connections: the MD5 exchange cannot be used, as only bcrypt hashes of the
+// passwords are stored.
+func (c *v3Conn) sendAuthPasswordRequest() (string, error) {
+	c.writeBuf.initMsg(serverMsgAuth)
+	c.writeBuf.putInt32(authCleartextPassword)
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return "", err
+	}
+	if err := c.wr.Flush(); err != nil {
+		return "", err
+	}
+
+	typ, n, err := c.readBuf.readTypedMsg(c.rd)
+	c.metrics.bytesInCount.Inc(int64(n))
+	if err != nil {
+		return "", err
+	}
+	if typ != clientMsgPassword {
+		return "", errors.Errorf("invalid response to authentication request: %s", typ)
+	}
+	return c.readBuf.getString()
+}
+
 func (c *v3Conn) serve(authenticationHook func(string, bool) error) error {
 	ctx := c.session.Ctx()
 
@@ -786,6 +821,16 @@ func (c *v3Conn) sendResponse(results sql.ResultList, formatCodes []formatCode,
 				return err
 			}
 
+		case parser.CopyIn:
+			if err := c.copyIn(result.Columns); err != nil {
+				return err
+			}
+
+		case parser.CopyOut:
+			if err := c.copyOut(result); err != nil {
+				return err
+			}
+
 		default:
 			panic(fmt.Sprintf("unexpected result type %v", result.Type))
 		}
@@ -794,6 +839,167 @@ func (c *v3Conn) sendResponse(results sql.ResultList, formatCodes []formatCode,
 	return nil
 }
 
+// copyIn runs the COPY-in sub-protocol once a COPY FROM STDIN statement was
+// executed: the client sends the data in CopyData messages, ended by a
+// CopyDone or CopyFail message. See
+// https://www.postgresql.org/docs/current/static/protocol-flow.html#PROTOCOL-COPY.
+func (c *v3Conn) copyIn(columns []sql.ResultColumn) error {
+	defer c.session.CopyEnd()
+
+	c.writeBuf.initMsg(serverMsgCopyInResponse)
+	c.writeBuf.writeByte(byte(formatText))
+	c.writeBuf.putInt16(int16(len(columns)))
+	for range columns {
+		c.writeBuf.putInt16(int16(formatText))
+	}
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
+	if err := c.wr.Flush(); err != nil {
+		return err
+	}
+
+	// Once an error was reported, the remaining data is ignored until the
+	// client ends the copy.
+	failed := false
+	rowsAffected := 0
+	for {
+		typ, n, err := c.readBuf.readTypedMsg(c.rd)
+		c.metrics.bytesInCount.Inc(int64(n))
+		if err != nil {
+			return err
+		}
+		var results sql.StatementResults
+		switch typ {
+		case clientMsgCopyData:
+			if failed {
+				continue
+			}
+			results = c.executor.CopyData(c.session, c.readBuf.msg)
+
+		case clientMsgCopyDone:
+			if failed {
+				return nil
+			}
+			results = c.executor.CopyDone(c.session)
+
+		case clientMsgCopyFail:
+			if failed {
+				return nil
+			}
+			msg, err := c.readBuf.getString()
+			if err != nil {
+				return err
+			}
+			return c.sendErrorWithCode(pgerror.CodeQueryCanceledError, sqlbase.MakeSrcCtx(0),
+				fmt.Sprintf("COPY from stdin failed: %s", msg))
+
+		case clientMsgFlush, clientMsgSync:
+			// These are allowed, and ignored, during a copy.
+			continue
+
+		default:
+			return c.sendErrorWithCode(pgerror.CodeProtocolViolationError, sqlbase.MakeSrcCtx(0),
+				fmt.Sprintf("unexpected message type %s during COPY from stdin", typ))
+		}
+
+		for _, result := range results.ResultList {
+			if result.Err != nil {
+				if err := c.sendError(result.Err); err != nil {
+					return err
+				}
+				failed = true
+				break
+			}
+			rowsAffected += result.RowsAffected
+		}
+		if typ == clientMsgCopyDone {
+			if failed {
+				return nil
+			}
+			tag := append(c.tagBuf[:0], "COPY "...)
+			tag = strconv.AppendInt(tag, int64(rowsAffected), 10)
+			return c.sendCommandComplete(tag)
+		}
+	}
+}
+
+// copyOut sends the results of a COPY TO STDOUT statement using the
+// COPY-out sub-protocol: each row is sent in its own CopyData message.
+func (c *v3Conn) copyOut(result sql.Result) error {
+	c.writeBuf.initMsg(serverMsgCopyOutResponse)
+	c.writeBuf.writeByte(byte(formatText))
+	c.writeBuf.putInt16(int16(len(result.Columns)))
+	for range result.Columns {
+		c.writeBuf.putInt16(int16(formatText))
+	}
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
+
+	var line bytes.Buffer
+	var valBuf writeBuffer
+	sendLine := func() error {
+		line.WriteByte('\n')
+		c.writeBuf.initMsg(serverMsgCopyData)
+		c.writeBuf.write(line.Bytes())
+		line.Reset()
+		return c.writeBuf.finishMsg(c.wr)
+	}
+	if result.CopyFormat.Header {
+		for i, col := range result.Columns {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			writeCopyCSVValue(&line, []byte(col.Name))
+		}
+		if err := sendLine(); err != nil {
+			return err
+		}
+	}
+	for _, row := range result.Rows {
+		for i, val := range row.Values {
+			if i > 0 {
+				if result.CopyFormat.CSV {
+					line.WriteByte(',')
+				} else {
+					line.WriteByte('\t')
+				}
+			}
+			if val == parser.DNull {
+				// NULL is an unquoted empty value in CSV.
+				if !result.CopyFormat.CSV {
+					line.WriteString(`\N`)
+				}
+				continue
+			}
+			// The text format of the value is obtained from a scratch
+			// writeBuffer by stripping the length prefix.
+			valBuf.reset()
+			valBuf.writeTextDatum(val, c.session.Location)
+			if valBuf.err != nil {
+				return valBuf.err
+			}
+			if result.CopyFormat.CSV {
+				writeCopyCSVValue(&line, valBuf.wrapped.Bytes()[4:])
+			} else {
+				writeCopyTextValue(&line, valBuf.wrapped.Bytes()[4:])
+			}
+		}
+		if err := sendLine(); err != nil {
+			return err
+		}
+	}
+
+	c.writeBuf.initMsg(serverMsgCopyDone)
+	if err := c.writeBuf.finishMsg(c.wr); err != nil {
+		return err
+	}
+	tag := append(c.tagBuf[:0], "COPY "...)
+	tag = strconv.AppendInt(tag, int64(len(result.Rows)), 10)
+	return c.sendCommandComplete(tag)
+}
+
 func (c *v3Conn) sendRowDescription(columns []sql.ResultColumn, formatCodes []formatCode) error {
 	if len(columns) == 0 {
 		c.writeBuf.initMsg(serverMsgNoData)
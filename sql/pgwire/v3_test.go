@@ -17,12 +17,15 @@
 package pgwire
 
 import (
+	"bufio"
 	"io"
 	"io/ioutil"
 	"net"
+	"reflect"
 	"testing"
 
 	"github.com/cockroachdb/cockroach/sql"
+	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/metric"
 )
@@ -89,3 +92,73 @@ func testMaliciousInput(t *testing.T, data []byte) {
 	v3Conn := makeTestV3Conn(r)
 	_ = v3Conn.serve(nil)
 }
+
+// TestCopyOut checks the messages sent for the results of a COPY TO STDOUT.
+func TestCopyOut(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	result := sql.Result{
+		Type:  parser.CopyOut,
+		PGTag: "COPY",
+		Columns: []sql.ResultColumn{
+			{Name: "k", Typ: parser.TypeInt},
+			{Name: "s", Typ: parser.TypeString},
+		},
+		Rows: []sql.ResultRow{
+			{Values: parser.DTuple{parser.NewDInt(1), parser.NewDString("a\tb")}},
+			{Values: parser.DTuple{parser.NewDInt(2), parser.DNull}},
+			{Values: parser.DTuple{parser.NewDInt(3), parser.NewDString("")}},
+		},
+	}
+	testCases := []struct {
+		format   sql.CopyFormat
+		expected []string
+	}{
+		{sql.CopyFormat{}, []string{"1\ta\\tb\n", "2\t\\N\n", "3\t\n"}},
+		{sql.CopyFormat{CSV: true, Header: true}, []string{"k,s\n", "1,a\tb\n", "2,\n", "3,\"\"\n"}},
+	}
+	for _, tc := range testCases {
+		result.CopyFormat = tc.format
+		w, r := net.Pipe()
+
+		errCh := make(chan error, 1)
+		go func() {
+			c := makeTestV3Conn(r)
+			defer c.finish()
+			err := c.copyOut(result)
+			if err == nil {
+				err = c.wr.Flush()
+			}
+			errCh <- err
+		}()
+
+		var data []string
+		rd := bufio.NewReader(w)
+		var buf readBuffer
+		for done := false; !done; {
+			typ, _, err := buf.readTypedMsg(rd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch serverMessageType(typ) {
+			case serverMsgCopyOutResponse, serverMsgCopyDone:
+			case serverMsgCopyData:
+				data = append(data, string(buf.msg))
+			case serverMsgCommandComplete:
+				if tag := string(buf.msg); tag != "COPY 3\x00" {
+					t.Errorf("unexpected tag %q", tag)
+				}
+				done = true
+			default:
+				t.Fatalf("unexpected message %s", serverMessageType(typ))
+			}
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		_ = w.Close()
+		if !reflect.DeepEqual(data, tc.expected) {
+			t.Errorf("%+v: expected %q, got %q", tc.format, tc.expected, data)
+		}
+	}
+}
@@ -102,7 +102,7 @@ func TestPGWire(t *testing.T) {
 					t.Error(err)
 				}
 			} else {
-				if !testutils.IsError(err, "no client certificates in request") {
+				if !testutils.IsError(err, "password authentication failed") {
 					t.Error(err)
 				}
 			}
@@ -132,7 +132,7 @@ func TestPGWire(t *testing.T) {
 					t.Error(err)
 				}
 			} else {
-				if !testutils.IsError(err, "no client certificates in request") {
+				if !testutils.IsError(err, "password authentication failed") {
 					t.Error(err)
 				}
 			}
@@ -169,6 +169,153 @@ func TestPGWire(t *testing.T) {
 	}
 }
 
+// TestPGWirePassword checks that clients connecting over TLS without a
+// certificate are authenticated with the password of their user.
+func TestPGWirePassword(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`CREATE USER alice WITH PASSWORD 'secret'`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user, password string
+		expectedErr    string
+	}{
+		{"alice", "secret", ""},
+		{"alice", "wrong", "password authentication failed"},
+		{"bob", "secret", "password authentication failed"},
+		{security.RootUser, "secret", "must use certificate authentication"},
+	} {
+		pgURL := url.URL{
+			Scheme:   "postgres",
+			Host:     s.ServingAddr(),
+			User:     url.UserPassword(tc.user, tc.password),
+			RawQuery: "sslmode=require",
+		}
+		err := trivialQuery(pgURL)
+		if tc.expectedErr == "" {
+			if err != nil {
+				t.Errorf("%s: %s", tc.user, err)
+			}
+		} else if !testutils.IsError(err, tc.expectedErr) {
+			t.Errorf("%s: expected error %q, got %v", tc.user, tc.expectedErr, err)
+		}
+	}
+}
+
+// TestPGWireHBA checks that clients are authenticated according to the
+// host-based authentication configuration.
+func TestPGWireHBA(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	for _, user := range []string{"alice", server.TestUser} {
+		if _, err := sqlDB.Exec(`CREATE USER ` + user + ` WITH PASSWORD 'secret'`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sqlDB.Exec(`SET CLUSTER SETTING server.host_based_authentication.configuration = '
+# TYPE USERS          ADDRESS       METHOD
+host   alice          127.0.0.0/8   password
+host   testuser       all           cert
+host   all            ::1           password
+'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(
+		`SET CLUSTER SETTING server.host_based_authentication.configuration = 'host all all all foo'`,
+	); !testutils.IsError(err, `line 1: unknown authentication method "foo"`) {
+		t.Fatalf("expected an invalid configuration error, got %v", err)
+	}
+
+	passwordURL := func(user string) url.URL {
+		return url.URL{
+			Scheme:   "postgres",
+			Host:     s.ServingAddr(),
+			User:     url.UserPassword(user, "secret"),
+			RawQuery: "sslmode=require",
+		}
+	}
+	// The configuration is applied asynchronously.
+	util.SucceedsSoon(t, func() error {
+		err := trivialQuery(passwordURL("bob"))
+		if !testutils.IsError(err, "authentication rejected by configuration for user bob") {
+			return errors.Errorf("expected bob to be rejected, got %v", err)
+		}
+		return nil
+	})
+
+	testUserCertURL, cleanupTestUser := sqlutils.PGUrl(t, s.ServingAddr(), server.TestUser, "TestPGWireHBA")
+	defer cleanupTestUser()
+	rootCertURL, cleanupRoot := sqlutils.PGUrl(t, s.ServingAddr(), security.RootUser, "TestPGWireHBA")
+	defer cleanupRoot()
+
+	for i, tc := range []struct {
+		pgURL       url.URL
+		expectedErr string
+	}{
+		{passwordURL("alice"), ""},
+		{passwordURL(server.TestUser), "no client certificates in request"},
+		{testUserCertURL, ""},
+		// The root user can always authenticate with its certificate.
+		{rootCertURL, ""},
+	} {
+		err := trivialQuery(tc.pgURL)
+		if tc.expectedErr == "" {
+			if err != nil {
+				t.Errorf("%d: %s", i, err)
+			}
+		} else if !testutils.IsError(err, tc.expectedErr) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.expectedErr, err)
+		}
+	}
+}
+
+// TestPGWireRoleLogin checks that roles cannot log in, whether with a
+// certificate or with a password.
+func TestPGWireRoleLogin(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	for _, role := range []string{"readers", server.TestUser} {
+		if _, err := sqlDB.Exec(`CREATE ROLE ` + role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	certURL, cleanup := sqlutils.PGUrl(t, s.ServingAddr(), server.TestUser, "TestPGWireRoleLogin")
+	defer cleanup()
+	passwordURL := url.URL{
+		Scheme:   "postgres",
+		Host:     s.ServingAddr(),
+		User:     url.UserPassword("readers", "secret"),
+		RawQuery: "sslmode=require",
+	}
+
+	for i, tc := range []struct {
+		pgURL       url.URL
+		expectedErr string
+	}{
+		{certURL, "testuser is a role, not a user"},
+		{passwordURL, "readers is a role, not a user"},
+	} {
+		if err := trivialQuery(tc.pgURL); !testutils.IsError(err, tc.expectedErr) {
+			t.Errorf("%d: expected error %q, got %v", i, tc.expectedErr, err)
+		}
+	}
+}
+
 // TestPGWireDrainClient makes sure the server refuses new connections when
 // it's in draining mode.
 func TestPGWireDrainClient(t *testing.T) {
@@ -401,10 +548,11 @@ func TestPGPreparedQuery(t *testing.T) {
 		"SHOW COLUMNS FROM system.users": {
 			baseTest.
 				Results("username", "STRING", false, gosql.NullBool{}).
-				Results("hashedPassword", "BYTES", true, gosql.NullBool{}),
+				Results("hashedPassword", "BYTES", true, gosql.NullBool{}).
+				Results("isRole", "BOOL", false, "false"),
 		},
 		"SHOW DATABASES": {
-			baseTest.Results("information_schema").Results("d").Results("system"),
+			baseTest.Results("crdb_internal").Results("information_schema").Results("d").Results("pg_catalog").Results("system"),
 		},
 		"SHOW GRANTS ON system.users": {
 			baseTest.Results("users", security.RootUser, "DELETE,GRANT,INSERT,SELECT,UPDATE"),
@@ -495,6 +643,9 @@ func TestPGPreparedQuery(t *testing.T) {
 		"SELECT a FROM d.T WHERE a = $1 AND (SELECT a >= $2 FROM d.T WHERE a = $1)": {
 			baseTest.SetArgs(10, 5).Results(10),
 		},
+		"WITH t AS (SELECT a FROM d.T WHERE a = $1) SELECT a + $2 FROM t": {
+			baseTest.SetArgs(10, 1).Results(11),
+		},
 		"SELECT * FROM (VALUES (1), (2), (3), (4)) AS foo (a) LIMIT $1 OFFSET $2": {
 			baseTest.SetArgs(1, 0).Results(1),
 			baseTest.SetArgs(1, 1).Results(2),
@@ -1042,6 +1193,106 @@ func TestPGCommandTags(t *testing.T) {
 	}
 }
 
+func TestPGCopyIn(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	pgURL, cleanupFn := sqlutils.PGUrl(t, s.ServingAddr(), security.RootUser, "TestPGCopyIn")
+	defer cleanupFn()
+
+	db, err := gosql.Open("postgres", pgURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+CREATE DATABASE d;
+CREATE TABLE d.t (k INT PRIMARY KEY, s STRING, f FLOAT, b BOOL);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	// More rows than are inserted at once.
+	const numRows = 250
+	txn, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := txn.Prepare(pq.CopyIn("d.t", "k", "s", "f", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < numRows; i++ {
+		var s interface{}
+		if i%10 != 0 {
+			s = fmt.Sprintf("a\tb\n%d\\", i)
+		}
+		if _, err := stmt.Exec(i, s, float64(i)/2, i%2 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var count, nulls int
+	if err := db.QueryRow(
+		`SELECT COUNT(*), COUNT(*) - COUNT(s) FROM d.t`,
+	).Scan(&count, &nulls); err != nil {
+		t.Fatal(err)
+	}
+	if count != numRows || nulls != numRows/10 {
+		t.Fatalf("expected %d rows of which %d NULL, got %d and %d", numRows, numRows/10, count, nulls)
+	}
+	var str string
+	var f float64
+	var b bool
+	if err := db.QueryRow(`SELECT s, f, b FROM d.t WHERE k = 7`).Scan(&str, &f, &b); err != nil {
+		t.Fatal(err)
+	}
+	if e := "a\tb\n7\\"; str != e || f != 3.5 || b {
+		t.Fatalf("expected (%q, 3.5, false), got (%q, %v, %v)", e, str, f, b)
+	}
+
+	// Bad data is reported, and leaves the table as it was.
+	txn, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err = txn.Prepare(pq.CopyIn("d.t", "k", "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(numRows, "not a float"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(); !testutils.IsError(err, "could not parse") {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+	_ = stmt.Close()
+	_ = txn.Rollback()
+	if err := db.QueryRow(`SELECT COUNT(*) FROM d.t`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != numRows {
+		t.Fatalf("expected %d rows, got %d", numRows, count)
+	}
+
+	if _, err := db.Exec(`COPY d.t FROM STDIN; SELECT 1`); !testutils.IsError(
+		err, "COPY must be the only statement in the query",
+	) {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
 // checkSQLNetworkMetrics returns the server's pgwire bytesIn/bytesOut and an
 // error if the bytesIn/bytesOut don't satisfy the given minimums and maximums.
 func checkSQLNetworkMetrics(
@@ -236,30 +236,68 @@ func (p *planner) newPlan(stmt parser.Statement, desiredTypes []parser.Datum, au
 	switch n := stmt.(type) {
 	case *parser.AlterTable:
 		return p.AlterTable(n)
+	case *parser.Backup:
+		return p.Backup(n, autoCommit)
 	case *parser.BeginTransaction:
 		return p.BeginTransaction(n)
+	case *parser.CancelJob:
+		return p.CancelJob(n)
+	case *parser.CancelQuery:
+		return p.CancelQuery(n)
+	case *parser.CopyFrom:
+		return p.CopyFrom(n)
+	case *parser.CopyTo:
+		return p.CopyTo(n, autoCommit)
+	case *parser.CreateChangefeed:
+		return p.CreateChangefeed(n, autoCommit)
 	case *parser.CreateDatabase:
 		return p.CreateDatabase(n)
 	case *parser.CreateIndex:
 		return p.CreateIndex(n)
+	case *parser.CreateRole:
+		return p.CreateRole(n)
+	case *parser.CreateSequence:
+		return p.CreateSequence(n)
+	case *parser.CreateStats:
+		return p.CreateStatistics(n)
 	case *parser.CreateTable:
 		return p.CreateTable(n)
+	case *parser.CreateUser:
+		return p.CreateUser(n)
+	case *parser.CreateView:
+		return p.CreateView(n)
 	case *parser.Delete:
 		return p.Delete(n, desiredTypes, autoCommit)
 	case *parser.DropDatabase:
 		return p.DropDatabase(n)
 	case *parser.DropIndex:
 		return p.DropIndex(n)
+	case *parser.DropRole:
+		return p.DropRole(n)
+	case *parser.DropSequence:
+		return p.DropSequence(n)
 	case *parser.DropTable:
 		return p.DropTable(n)
+	case *parser.DropView:
+		return p.DropView(n)
 	case *parser.Explain:
 		return p.Explain(n, autoCommit)
+	case *parser.Export:
+		return p.Export(n, autoCommit)
 	case *parser.Grant:
 		return p.Grant(n)
+	case *parser.GrantRole:
+		return p.GrantRole(n)
+	case *parser.Import:
+		return p.Import(n, autoCommit)
 	case *parser.Insert:
 		return p.Insert(n, desiredTypes, autoCommit)
 	case *parser.ParenSelect:
 		return p.newPlan(n.Select, desiredTypes, autoCommit)
+	case *parser.PauseJob:
+		return p.PauseJob(n)
+	case *parser.Relocate:
+		return p.Relocate(n)
 	case *parser.RenameColumn:
 		return p.RenameColumn(n)
 	case *parser.RenameDatabase:
@@ -268,24 +306,40 @@ func (p *planner) newPlan(stmt parser.Statement, desiredTypes []parser.Datum, au
 		return p.RenameIndex(n)
 	case *parser.RenameTable:
 		return p.RenameTable(n)
+	case *parser.Restore:
+		return p.Restore(n, autoCommit)
+	case *parser.ResumeJob:
+		return p.ResumeJob(n)
 	case *parser.Revoke:
 		return p.Revoke(n)
+	case *parser.RevokeRole:
+		return p.RevokeRole(n)
+	case *parser.Scatter:
+		return p.Scatter(n)
 	case *parser.Select:
 		return p.Select(n, desiredTypes, autoCommit)
 	case *parser.SelectClause:
-		return p.SelectClause(n, nil, nil, desiredTypes, publicColumns)
+		return p.SelectClause(n, nil, nil, parser.LockNone, desiredTypes, publicColumns)
 	case *parser.Set:
 		return p.Set(n)
+	case *parser.SetClusterSetting:
+		return p.SetClusterSetting(n)
 	case *parser.SetTimeZone:
 		return p.SetTimeZone(n)
 	case *parser.SetTransaction:
 		return p.SetTransaction(n)
+	case *parser.SetZoneConfig:
+		return p.SetZoneConfig(n)
 	case *parser.SetDefaultIsolation:
 		return p.SetDefaultIsolation(n)
 	case *parser.Show:
 		return p.Show(n)
+	case *parser.ShowClusterSetting:
+		return p.ShowClusterSetting(n)
 	case *parser.ShowCreateTable:
 		return p.ShowCreateTable(n)
+	case *parser.ShowCreateView:
+		return p.ShowCreateView(n)
 	case *parser.ShowColumns:
 		return p.ShowColumns(n)
 	case *parser.ShowDatabases:
@@ -294,10 +348,22 @@ func (p *planner) newPlan(stmt parser.Statement, desiredTypes []parser.Datum, au
 		return p.ShowGrants(n)
 	case *parser.ShowIndex:
 		return p.ShowIndex(n)
+	case *parser.ShowJobs:
+		return p.ShowJobs(n)
+	case *parser.ShowQueries:
+		return p.ShowQueries(n)
+	case *parser.ShowRanges:
+		return p.ShowRanges(n)
 	case *parser.ShowConstraints:
 		return p.ShowConstraints(n)
 	case *parser.ShowTables:
 		return p.ShowTables(n)
+	case *parser.ShowTrace:
+		return p.ShowTrace(n, autoCommit)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(n)
+	case *parser.Split:
+		return p.Split(n)
 	case *parser.Truncate:
 		return p.Truncate(n)
 	case *parser.UnionClause:
@@ -313,6 +379,20 @@ func (p *planner) newPlan(stmt parser.Statement, desiredTypes []parser.Datum, au
 
 func (p *planner) prepare(stmt parser.Statement) (planNode, error) {
 	switch n := stmt.(type) {
+	case *parser.CancelJob:
+		// Only type the job ID, so that placeholders can be used for it.
+		_, err := p.analyzeExpr(n.ID, nil, nil, parser.TypeInt, true, "CANCEL JOB")
+		return nil, err
+	case *parser.CancelQuery:
+		// Only type the query ID, so that placeholders can be used for it.
+		_, err := p.analyzeExpr(n.ID, nil, nil, parser.TypeString, true, "CANCEL QUERY")
+		return nil, err
+	case *parser.PauseJob:
+		_, err := p.analyzeExpr(n.ID, nil, nil, parser.TypeInt, true, "PAUSE JOB")
+		return nil, err
+	case *parser.ResumeJob:
+		_, err := p.analyzeExpr(n.ID, nil, nil, parser.TypeInt, true, "RESUME JOB")
+		return nil, err
 	case *parser.Delete:
 		return p.Delete(n, nil, false)
 	case *parser.Insert:
@@ -320,11 +400,15 @@ func (p *planner) prepare(stmt parser.Statement) (planNode, error) {
 	case *parser.Select:
 		return p.Select(n, nil, false)
 	case *parser.SelectClause:
-		return p.SelectClause(n, nil, nil, nil, publicColumns)
+		return p.SelectClause(n, nil, nil, parser.LockNone, nil, publicColumns)
 	case *parser.Show:
 		return p.Show(n)
+	case *parser.ShowClusterSetting:
+		return p.ShowClusterSetting(n)
 	case *parser.ShowCreateTable:
 		return p.ShowCreateTable(n)
+	case *parser.ShowCreateView:
+		return p.ShowCreateView(n)
 	case *parser.ShowColumns:
 		return p.ShowColumns(n)
 	case *parser.ShowDatabases:
@@ -333,10 +417,18 @@ func (p *planner) prepare(stmt parser.Statement) (planNode, error) {
 		return p.ShowGrants(n)
 	case *parser.ShowIndex:
 		return p.ShowIndex(n)
+	case *parser.ShowJobs:
+		return p.ShowJobs(n)
+	case *parser.ShowQueries:
+		return p.ShowQueries(n)
+	case *parser.ShowRanges:
+		return p.ShowRanges(n)
 	case *parser.ShowConstraints:
 		return p.ShowConstraints(n)
 	case *parser.ShowTables:
 		return p.ShowTables(n)
+	case *parser.ShowZoneConfig:
+		return p.ShowZoneConfig(n)
 	case *parser.Update:
 		return p.Update(n, nil, false)
 	default:
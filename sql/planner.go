@@ -24,8 +24,11 @@ import (
 
 	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/pkg/errors"
 )
 
@@ -59,6 +62,24 @@ type planner struct {
 	// table descriptor is not leased, only fetched at the correct time.
 	asOf bool
 
+	// If set, the tables and views used by the query being planned are
+	// recorded in this set. It is used by CREATE VIEW to track the
+	// dependencies of the view.
+	planDeps map[sqlbase.ID]struct{}
+	// If set, star expressions were expanded while planning the query. Views
+	// cannot use them, as their meaning changes when columns are added.
+	hasStar bool
+	// If set, the SELECT privilege is not checked on the tables being
+	// scanned. It is used when expanding views, whose users only need the
+	// SELECT privilege on the view itself.
+	skipSelectPrivilegeChecks bool
+	// The roles the user of the session is a member of, which it inherits
+	// the privileges of.
+	roles roleCache
+	// The common table expressions (WITH clauses) in scope for the table
+	// references being planned.
+	ctes *cteScope
+
 	// Avoid allocations by embedding commonly used visitors.
 	subqueryVisitor             subqueryVisitor
 	subqueryPlanVisitor         subqueryPlanVisitor
@@ -66,6 +87,19 @@ type planner struct {
 	nameResolutionVisitor       nameResolutionVisitor
 
 	execCtx *ExecutorContext
+	// sqlStats holds the statement statistics of the Executor the planner
+	// belongs to. It is nil for internal planners.
+	sqlStats *sqlStats
+	// activeQuery is the query currently being executed by the planner, as
+	// registered with the session. It is nil outside of statement execution.
+	activeQuery *queryMeta
+	// memMonitor accounts for the memory used by the statement currently
+	// being executed. It is nil outside of statement execution, in which
+	// case the memory is not accounted for.
+	memMonitor *mon.MemoryMonitor
+	// diskRowContainers holds the rows spilled to temporary storage by the
+	// statement currently being executed. They are removed once it is done.
+	diskRowContainers []*diskRowContainer
 }
 
 // makePlanner creates a new planner instances, referencing a dummy Session.
@@ -76,6 +110,45 @@ func makePlanner() *planner {
 	return &planner{session: &Session{Location: time.UTC, context: context.Background()}}
 }
 
+// makeMemoryAccount creates an account for the memory used by a plan node of
+// the statement being executed.
+func (p *planner) makeMemoryAccount() mon.MemoryAccount {
+	return p.memMonitor.MakeAccount()
+}
+
+// canSpillToDisk returns whether plan nodes can spill their rows to
+// temporary storage once they exceed their memory budget.
+func (p *planner) canSpillToDisk() bool {
+	return p.memMonitor != nil && p.execCtx != nil && p.execCtx.TempStorage != nil
+}
+
+// newDiskRowContainer creates a container for rows of the given types in the
+// temporary storage engine. The container is closed when the statement
+// currently being executed is done.
+func (p *planner) newDiskRowContainer(types []parser.Datum) *diskRowContainer {
+	c := makeDiskRowContainer(p.execCtx.TempStorage, types)
+	p.diskRowContainers = append(p.diskRowContainers, &c)
+	return &c
+}
+
+// closeDiskRowContainers removes the rows spilled to temporary storage by
+// the statement currently being executed.
+func (p *planner) closeDiskRowContainers() {
+	for _, c := range p.diskRowContainers {
+		if err := c.close(); err != nil {
+			log.Warningf(p.ctx(), "unable to remove rows from temporary storage: %s", err)
+		}
+	}
+	p.diskRowContainers = nil
+}
+
+// isMemoryBudgetError returns whether err signals that the memory budget of
+// a plan node was exceeded.
+func isMemoryBudgetError(err error) bool {
+	_, ok := err.(*sqlbase.ErrMemoryBudgetExceeded)
+	return ok
+}
+
 // queryRunner abstracts the services provided by a planner object
 // to the other SQL front-end components.
 type queryRunner interface {
@@ -179,6 +252,7 @@ func (p *planner) resetContexts() {
 
 	p.evalCtx = parser.EvalContext{
 		Location: &p.session.Location,
+		Sequence: p,
 	}
 }
 
@@ -247,7 +321,7 @@ func (p *planner) exec(sql string, args ...interface{}) (int, error) {
 	if err := plan.Start(); err != nil {
 		return 0, err
 	}
-	return countRowsAffected(plan)
+	return countRowsAffected(p.ctx(), plan)
 }
 
 // setTestingVerifyMetadata implements the queryRunner interface.
@@ -300,8 +374,17 @@ func (p *planner) checkTestingVerifyMetadataOrDie(
 	p.testingVerifyMetadataFn = nil
 }
 
+// fillFKTableMap acquires leases on the tables of m. Rows of the tables having
+// cascading foreign keys can be deleted or updated by the statement, so the
+// tables needed to check their own foreign keys are added to m as well.
 func (p *planner) fillFKTableMap(m tableLookupsByID) error {
+	pending := make([]sqlbase.ID, 0, len(m))
 	for tableID := range m {
+		pending = append(pending, tableID)
+	}
+	for len(pending) > 0 {
+		tableID := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
 		table, err := p.getTableLeaseByID(tableID)
 		if err == errTableAdding {
 			m[tableID] = tableLookup{isAdding: true}
@@ -311,6 +394,14 @@ func (p *planner) fillFKTableMap(m tableLookupsByID) error {
 			return err
 		}
 		m[tableID] = tableLookup{table: table}
+		if hasFKCascade(*table) {
+			for id := range tablesNeededForFKs(*table, CheckUpdates) {
+				if _, ok := m[id]; !ok {
+					m[id] = tableLookup{}
+					pending = append(pending, id)
+				}
+			}
+		}
 	}
 	return nil
 }
@@ -18,6 +18,7 @@ package privilege
 
 import (
 	"bytes"
+	"fmt"
 	"sort"
 	"strings"
 )
@@ -48,6 +49,18 @@ var (
 	ReadWriteData = List{GRANT, SELECT, INSERT, DELETE, UPDATE}
 )
 
+// ByName is a map of string -> kind value.
+var ByName = map[string]Kind{
+	"ALL":    ALL,
+	"CREATE": CREATE,
+	"DROP":   DROP,
+	"GRANT":  GRANT,
+	"SELECT": SELECT,
+	"INSERT": INSERT,
+	"DELETE": DELETE,
+	"UPDATE": UPDATE,
+}
+
 // Mask returns the bitmask for a given privilege.
 func (k Kind) Mask() uint32 {
 	return 1 << k
@@ -131,3 +144,18 @@ func ListFromBitField(m uint32) List {
 	}
 	return ret
 }
+
+// ListFromStrings takes a list of privilege names and returns the list of
+// privileges, or an error if one of the names is not a valid privilege.
+// Names are case-insensitive.
+func ListFromStrings(strs []string) (List, error) {
+	ret := make(List, len(strs))
+	for i, s := range strs {
+		k, ok := ByName[strings.ToUpper(s)]
+		if !ok {
+			return nil, fmt.Errorf("not a valid privilege: %q", s)
+		}
+		ret[i] = k
+	}
+	return ret, nil
+}
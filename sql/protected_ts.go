@@ -0,0 +1,128 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/protoutil"
+)
+
+// GetProtectedTimestamps returns the protected timestamp records stored in
+// the system.protected_ts table of the system config.
+func GetProtectedTimestamps(cfg config.SystemConfig) ([]config.ProtectedTimestamp, error) {
+	desc := &sqlbase.ProtectedTimestampsTable
+	colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+	valNeededForCol := make([]bool, len(desc.Columns))
+	for i, col := range desc.Columns {
+		colIdxMap[col.ID] = i
+		valNeededForCol[i] = true
+	}
+	var fetcher sqlbase.RowFetcher
+	if err := fetcher.Init(
+		desc, colIdxMap, &desc.PrimaryIndex, false /* reverse */, false, /* isSecondaryIndex */
+		desc.Columns, valNeededForCol,
+	); err != nil {
+		return nil, err
+	}
+
+	prefix := sqlbase.MakeIndexKeyPrefix(desc, desc.PrimaryIndex.ID)
+	var kvs []client.KeyValue
+	for i := range cfg.Values {
+		if bytes.HasPrefix(cfg.Values[i].Key, prefix) {
+			kvs = append(kvs, client.KeyValue{Key: cfg.Values[i].Key, Value: &cfg.Values[i].Value})
+		}
+	}
+	if err := fetcher.StartScanFrom(kvs); err != nil {
+		return nil, err
+	}
+
+	var records []config.ProtectedTimestamp
+	for {
+		row, err := fetcher.NextRow()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		b, ok := row[1].(*parser.DBytes)
+		if !ok {
+			continue
+		}
+		var record config.ProtectedTimestamp
+		if err := protoutil.Unmarshal([]byte(*b), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// protectTimestamp protects the MVCC versions of the spans needed to read
+// them at the timestamp from garbage collection, until the returned record
+// is released by releaseTimestamp. The record is written in its own
+// transaction so that it reaches the GC queues through the system config
+// gossip while the caller reads the spans.
+//
+// A record left behind by a node which died before releasing it keeps
+// protecting the spans until its row is deleted from system.protected_ts.
+func (p *planner) protectTimestamp(
+	ts hlc.Timestamp, spans []roachpb.Span, description string,
+) (int64, error) {
+	buf, err := protoutil.Marshal(&config.ProtectedTimestamp{
+		Timestamp:   ts,
+		Spans:       spans,
+		Description: description,
+	})
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = p.execCtx.DB.Txn(func(txn *client.Txn) error {
+		row, err := p.internalPlanner(txn).queryRow(
+			`INSERT INTO system.protected_ts (id, record) VALUES (unique_rowid(), $1) RETURNING id`, buf,
+		)
+		if err != nil {
+			return err
+		}
+		id = int64(*row[0].(*parser.DInt))
+		return nil
+	})
+	return id, err
+}
+
+// releaseTimestamp deletes the protected timestamp record with the given ID.
+func (p *planner) releaseTimestamp(id int64) error {
+	return p.execCtx.DB.Txn(func(txn *client.Txn) error {
+		_, err := p.internalPlanner(txn).exec(`DELETE FROM system.protected_ts WHERE id = $1`, id)
+		return err
+	})
+}
+
+// internalPlanner returns a root planner running in txn, which shares the
+// lease manager of p.
+func (p *planner) internalPlanner(txn *client.Txn) *planner {
+	ip := makeInternalPlanner(txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	return ip
+}
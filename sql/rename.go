@@ -108,6 +108,12 @@ func (p *planner) RenameTable(n *parser.RenameTable) (planNode, error) {
 		return nil, err
 	}
 
+	// The queries of the views that depend on the table refer to it by name.
+	if len(tableDesc.DependedOnBy) > 0 {
+		return nil, p.dependentViewError(
+			"rename", tableDesc.TypeName(), oldTn.String(), tableDesc.DependedOnBy[0])
+	}
+
 	// Check if target database exists.
 	targetDbDesc, err := p.mustGetDatabaseDesc(newTn.Database())
 	if err != nil {
@@ -190,6 +196,9 @@ func (p *planner) RenameIndex(n *parser.RenameIndex) (planNode, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !tableDesc.IsTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
 
 	normIdxName := sqlbase.NormalizeName(n.Index.Index)
 	status, i, err := tableDesc.FindIndexByNormalizedName(normIdxName)
@@ -262,6 +271,9 @@ func (p *planner) RenameColumn(n *parser.RenameColumn) (planNode, error) {
 		// Key does not exist, but we want it to: error out.
 		return nil, fmt.Errorf("table %q does not exist", tn.Table())
 	}
+	if !tableDesc.IsTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
 
 	if err := p.checkPrivilege(tableDesc, privilege.CREATE); err != nil {
 		return nil, err
@@ -270,6 +282,13 @@ func (p *planner) RenameColumn(n *parser.RenameColumn) (planNode, error) {
 	if n.NewName == "" {
 		return nil, errEmptyColumnName
 	}
+
+	// The queries of the views that depend on the table refer to its columns
+	// by name.
+	if len(tableDesc.DependedOnBy) > 0 {
+		return nil, p.dependentViewError(
+			"rename", "column", string(n.Name), tableDesc.DependedOnBy[0])
+	}
 	normNewColName := sqlbase.NormalizeName(n.NewName)
 	normColName := sqlbase.NormalizeName(n.Name)
 
@@ -281,6 +300,9 @@ func (p *planner) RenameColumn(n *parser.RenameColumn) (planNode, error) {
 	var column *sqlbase.ColumnDescriptor
 	if status == sqlbase.DescriptorActive {
 		column = &tableDesc.Columns[i]
+		if isColumnBeingReplaced(tableDesc, column.ID) {
+			return nil, columnBeingReplacedError(column.Name)
+		}
 	} else {
 		column = tableDesc.Mutations[i].GetColumn()
 	}
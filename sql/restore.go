@@ -0,0 +1,602 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"io/ioutil"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/settings/cluster"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/envutil"
+	"github.com/cockroachdb/cockroach/util/extstorage"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// restoreConcurrency is the number of backup files read and ingested
+// concurrently by RESTORE. The env var only sets its default.
+var restoreConcurrency = settings.RegisterValidatedIntSetting(
+	"sql.restore.concurrency",
+	"number of backup files read and ingested concurrently by RESTORE",
+	int64(envutil.EnvOrDefaultInt("restore_concurrency", 4)),
+	func(v int64) error {
+		if v <= 0 {
+			return errors.Errorf("concurrency must be positive, got %d", v)
+		}
+		return nil
+	},
+)
+
+// The options accepted by RESTORE in its WITH clause.
+const (
+	restoreOptionIntoDB = "into_db"
+)
+
+var restoreColumns = []ResultColumn{
+	{Name: "tables", Typ: parser.TypeInt},
+	{Name: "files", Typ: parser.TypeInt},
+	{Name: "data_size", Typ: parser.TypeInt},
+}
+
+type restoreNode struct {
+	p      *planner
+	n      *parser.Restore
+	intoDB string
+
+	result parser.DTuple
+	done   bool
+}
+
+// Restore recreates tables from a backup written by BACKUP to the directory
+// named by an external storage URI (see package extstorage). The tables are
+// restored into the database they were backed up from, or into the database
+// named by the into_db option, which must exist and must not already contain
+// tables of the same names.
+//
+// Each restored table is given a new ID. The keys of the backed up data are
+// rewritten to the new table prefixes and the results are linked into the
+// ranges as SSTables with AddSSTableRequests, after splitting the new key
+// spans along the range boundaries recorded in the backup so that the
+// ingestion is spread over many ranges. The descriptors are only written,
+// making the tables visible, once all the data is in place. The restore is
+// tracked by a job, which can be paused or canceled between files.
+// Privileges: security.RootUser user.
+func (p *planner) Restore(n *parser.Restore, autoCommit bool) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to run RESTORE", security.RootUser)
+	}
+	if !autoCommit {
+		return nil, errors.Errorf("RESTORE cannot be used inside a transaction")
+	}
+	if !cluster.IsActive(cluster.VersionAddSSTable) {
+		return nil, errors.Errorf("RESTORE requires the cluster version to be at least %s",
+			cluster.VersionByKey(cluster.VersionAddSSTable))
+	}
+
+	node := &restoreNode{p: p, n: n}
+	for _, opt := range n.Options {
+		switch opt.Key {
+		case restoreOptionIntoDB:
+			node.intoDB = opt.Value
+		default:
+			return nil, errors.Errorf("unsupported RESTORE option %q", opt.Key)
+		}
+	}
+
+	// RESTORE isn't a DDL statement, so newPlan doesn't set the trigger for the
+	// descriptors it writes.
+	p.txn.SetSystemConfigTrigger()
+
+	return node, nil
+}
+
+// readBackupDescriptor reads and decodes the manifest of the backup in the
+// directory named by uri.
+func readBackupDescriptor(ctx context.Context, uri string) (sqlbase.BackupDescriptor, error) {
+	var desc sqlbase.BackupDescriptor
+	descURI, err := extstorage.Join(uri, BackupDescriptorName)
+	if err != nil {
+		return desc, err
+	}
+	r, err := extstorage.Open(ctx, descURI)
+	if err != nil {
+		return desc, errors.Wrap(err, descURI)
+	}
+	defer r.Close()
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return desc, errors.Wrap(err, descURI)
+	}
+	if err := proto.Unmarshal(buf, &desc); err != nil {
+		return desc, errors.Wrapf(err, "%s: invalid backup descriptor", descURI)
+	}
+	return desc, nil
+}
+
+// restoreTargets resolves the targets of a RESTORE statement against the
+// descriptors of a backup, returning the tables to restore along with the
+// names of the databases they were backed up from.
+func (p *planner) restoreTargets(
+	targets parser.TargetList, backup sqlbase.BackupDescriptor,
+) ([]*sqlbase.TableDescriptor, map[sqlbase.ID]string, error) {
+	dbNames := make(map[sqlbase.ID]string)
+	dbIDs := make(map[string]sqlbase.ID)
+	var tables []*sqlbase.TableDescriptor
+	for _, desc := range backup.Descriptors {
+		if db := desc.GetDatabase(); db != nil {
+			dbNames[db.ID] = db.Name
+			dbIDs[db.Name] = db.ID
+		} else if table := desc.GetTable(); table != nil {
+			tables = append(tables, table)
+		}
+	}
+	lookupDB := func(name string) (sqlbase.ID, error) {
+		id, ok := dbIDs[name]
+		if !ok {
+			return 0, errors.Errorf("database %q not found in backup", name)
+		}
+		return id, nil
+	}
+
+	var matched []*sqlbase.TableDescriptor
+	seen := make(map[sqlbase.ID]struct{})
+	add := func(table *sqlbase.TableDescriptor) {
+		if _, ok := seen[table.ID]; !ok {
+			seen[table.ID] = struct{}{}
+			matched = append(matched, table)
+		}
+	}
+	if targets.Databases != nil {
+		if len(targets.Databases) == 0 {
+			return nil, nil, errNoDatabase
+		}
+		for _, database := range targets.Databases {
+			dbID, err := lookupDB(string(database))
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, table := range tables {
+				if table.ParentID == dbID {
+					add(table)
+				}
+			}
+		}
+	} else {
+		if len(targets.Tables) == 0 {
+			return nil, nil, errNoTable
+		}
+		for _, tableTarget := range targets.Tables {
+			pattern, err := tableTarget.NormalizeTablePattern()
+			if err != nil {
+				return nil, nil, err
+			}
+			switch t := pattern.(type) {
+			case *parser.TableName:
+				if err := t.QualifyWithDatabase(p.session.Database); err != nil {
+					return nil, nil, err
+				}
+				dbID, err := lookupDB(t.Database())
+				if err != nil {
+					return nil, nil, err
+				}
+				found := false
+				for _, table := range tables {
+					if table.ParentID == dbID && table.Name == t.Table() {
+						add(table)
+						found = true
+					}
+				}
+				if !found {
+					return nil, nil, errors.Errorf("table %q not found in backup", t)
+				}
+			case *parser.AllTablesSelector:
+				if err := t.QualifyWithDatabase(p.session.Database); err != nil {
+					return nil, nil, err
+				}
+				dbID, err := lookupDB(string(t.Database))
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, table := range tables {
+					if table.ParentID == dbID {
+						add(table)
+					}
+				}
+			}
+		}
+	}
+
+	for _, table := range matched {
+		if table.IsView() {
+			return nil, nil, errors.Errorf("RESTORE does not support view %q", table.Name)
+		}
+	}
+	return matched, dbNames, nil
+}
+
+// rewriteTableDescs gives the tables to restore the IDs in newIDs, moves them
+// into the databases in parentDBs and rewrites the references between them.
+// Back-references from tables which are not restored are dropped; foreign keys
+// to tables which are not restored are an error.
+func rewriteTableDescs(
+	tables []*sqlbase.TableDescriptor,
+	newIDs map[sqlbase.ID]sqlbase.ID,
+	parentDBs map[sqlbase.ID]*sqlbase.DatabaseDescriptor,
+) error {
+	for _, table := range tables {
+		indexes := []*sqlbase.IndexDescriptor{&table.PrimaryIndex}
+		for i := range table.Indexes {
+			indexes = append(indexes, &table.Indexes[i])
+		}
+		for _, index := range indexes {
+			if index.ForeignKey.IsSet() {
+				newID, ok := newIDs[index.ForeignKey.Table]
+				if !ok {
+					return errors.Errorf("cannot restore table %q without the table referenced by %q",
+						table.Name, index.ForeignKey.Name)
+				}
+				index.ForeignKey.Table = newID
+			}
+			referencedBy := index.ReferencedBy[:0]
+			for _, ref := range index.ReferencedBy {
+				if newID, ok := newIDs[ref.Table]; ok {
+					ref.Table = newID
+					referencedBy = append(referencedBy, ref)
+				}
+			}
+			index.ReferencedBy = referencedBy
+		}
+		// Views are not restored.
+		table.DependedOnBy = nil
+
+		parent := parentDBs[table.ID]
+		table.ID = newIDs[table.ID]
+		table.ParentID = parent.ID
+		// Inherit permissions from the database descriptor.
+		table.Privileges = parent.GetPrivileges()
+		if err := table.ValidateTable(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyRewriter rewrites keys of backed up tables to the prefixes of the IDs
+// the tables are restored under.
+type keyRewriter map[sqlbase.ID]sqlbase.ID
+
+// rewriteKey returns key with its table prefix replaced, or false if key
+// does not belong to one of the restored tables.
+func (kr keyRewriter) rewriteKey(key roachpb.Key) (roachpb.Key, bool) {
+	rest, oldID, err := keys.DecodeTablePrefix(key)
+	if err != nil {
+		return nil, false
+	}
+	newID, ok := kr[sqlbase.ID(oldID)]
+	if !ok {
+		return nil, false
+	}
+	newKey := roachpb.Key(keys.MakeTablePrefix(uint32(newID)))
+	return append(newKey, rest...), true
+}
+
+func (n *restoreNode) expandPlan() error {
+	return nil
+}
+
+func (n *restoreNode) Start() error {
+	ctx := n.p.ctx()
+	job, err := n.p.jobRegistry().newJob(ctx, jobTypeRestore, n.n.String(), n.p.session.User)
+	if err != nil {
+		return err
+	}
+	err = n.restore(ctx, job)
+	job.Finished(ctx, err)
+	return err
+}
+
+func (n *restoreNode) restore(ctx context.Context, job *Job) error {
+	backup, err := readBackupDescriptor(ctx, n.n.From)
+	if err != nil {
+		return err
+	}
+	tables, dbNames, err := n.p.restoreTargets(n.n.Targets, backup)
+	if err != nil {
+		return err
+	}
+
+	// Check the destinations and allocate the new IDs before writing anything.
+	newIDs := make(keyRewriter, len(tables))
+	parentDBs := make(map[sqlbase.ID]*sqlbase.DatabaseDescriptor, len(tables))
+	for _, table := range tables {
+		dbName := n.intoDB
+		if dbName == "" {
+			dbName = dbNames[table.ParentID]
+		}
+		dbDesc, err := n.p.mustGetDatabaseDesc(dbName)
+		if err != nil {
+			return err
+		}
+		if err := n.p.checkPrivilege(dbDesc, privilege.CREATE); err != nil {
+			return err
+		}
+		key := tableKey{parentID: dbDesc.ID, name: table.Name}
+		if exists, err := n.p.descExists(key.Key()); err == nil && exists {
+			return descriptorAlreadyExistsErr{table, key.Name()}
+		} else if err != nil {
+			return err
+		}
+		newID, err := n.p.generateUniqueDescID()
+		if err != nil {
+			return err
+		}
+		newIDs[table.ID] = newID
+		parentDBs[table.ID] = dbDesc
+	}
+
+	// Find the files holding the data of the restored tables.
+	var files []sqlbase.BackupDescriptor_File
+	for _, file := range backup.Files {
+		if _, ok := newIDs.rewriteKey(file.StartKey); ok {
+			files = append(files, file)
+		}
+	}
+
+	if err := rewriteTableDescs(tables, newIDs, parentDBs); err != nil {
+		return err
+	}
+
+	db := n.p.execCtx.DB
+	n.splitRanges(db, tables, files, newIDs)
+	dataSize, err := n.ingestFiles(ctx, job, db, files, newIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		key := tableKey{parentID: table.ParentID, name: table.Name}.Key()
+		if _, err := n.p.createDescriptorWithID(key, table.ID, table); err != nil {
+			return err
+		}
+		if err := table.Validate(n.p.txn); err != nil {
+			return err
+		}
+		if err := MakeEventLogger(n.p.leaseMgr).InsertEventRecord(n.p.txn,
+			EventLogCreateTable,
+			int32(table.ID),
+			int32(n.p.evalCtx.NodeID),
+			struct {
+				TableName string
+				Statement string
+				User      string
+			}{table.Name, n.n.String(), n.p.session.User},
+		); err != nil {
+			return err
+		}
+	}
+
+	n.result = parser.DTuple{
+		parser.NewDInt(parser.DInt(len(tables))),
+		parser.NewDInt(parser.DInt(len(files))),
+		parser.NewDInt(parser.DInt(dataSize)),
+	}
+	return nil
+}
+
+// splitRanges splits the key spans of the restored tables at their new
+// prefixes and at the rewritten start keys of the backup's files, which are
+// the range boundaries at the time of the backup, so that the ingestion is
+// spread over ranges which can be rebalanced independently. Splitting is an
+// optimization, so failures are only logged.
+func (n *restoreNode) splitRanges(
+	db *client.DB,
+	tables []*sqlbase.TableDescriptor,
+	files []sqlbase.BackupDescriptor_File,
+	kr keyRewriter,
+) {
+	var splitKeys []roachpb.Key
+	for _, table := range tables {
+		splitKeys = append(splitKeys, roachpb.Key(keys.MakeTablePrefix(uint32(table.ID))))
+	}
+	for _, file := range files {
+		if key, ok := kr.rewriteKey(file.StartKey); ok {
+			splitKeys = append(splitKeys, key)
+		}
+	}
+	for _, key := range splitKeys {
+		if err := db.AdminSplit(key); err != nil {
+			log.Warningf(n.p.ctx(), "RESTORE: unable to split at %s: %s", key, err)
+		}
+	}
+}
+
+// ingestFiles reads the given backup files, restoreConcurrency at a time, and
+// writes their rewritten contents, reporting the progress of the job after
+// each file. It returns the number of key and value bytes ingested.
+func (n *restoreNode) ingestFiles(
+	ctx context.Context,
+	job *Job,
+	db *client.DB,
+	files []sqlbase.BackupDescriptor_File,
+	kr keyRewriter,
+) (int64, error) {
+	var wg sync.WaitGroup
+	var mu struct {
+		sync.Mutex
+		dataSize int64
+		done     int
+		err      error
+	}
+	sem := make(chan struct{}, restoreConcurrency.Get())
+	for _, file := range files {
+		sem <- struct{}{}
+		mu.Lock()
+		err := mu.err
+		mu.Unlock()
+		if err != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(file sqlbase.BackupDescriptor_File) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			size, err := restoreFile(ctx, db, n.n.From, file, kr)
+			mu.Lock()
+			defer mu.Unlock()
+			mu.dataSize += size
+			if err == nil {
+				// Progressed blocks while the job is paused, which holds up the
+				// other files as well.
+				mu.done++
+				err = job.Progressed(ctx, float32(mu.done)/float32(len(files)))
+			}
+			if err != nil && mu.err == nil {
+				mu.err = err
+			}
+		}(file)
+	}
+	wg.Wait()
+	return mu.dataSize, mu.err
+}
+
+// restoreFile reads a backup file and sends its contents, with the keys
+// rewritten by kr, to the KV layer as SSTables of about importBatchSize bytes
+// each, which are linked into the ranges by AddSSTableRequests.
+func restoreFile(
+	ctx context.Context, db *client.DB, from string, file sqlbase.BackupDescriptor_File, kr keyRewriter,
+) (int64, error) {
+	uri, err := extstorage.Join(from, file.Path)
+	if err != nil {
+		return 0, err
+	}
+	r, err := extstorage.Open(ctx, uri)
+	if err != nil {
+		return 0, errors.Wrap(err, uri)
+	}
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return 0, errors.Wrap(err, uri)
+	}
+
+	fr, err := engine.MakeRocksDBSstFileReader()
+	if err != nil {
+		return 0, err
+	}
+	defer fr.Close()
+	if err := fr.IngestExternalFile(data); err != nil {
+		return 0, errors.Wrap(err, uri)
+	}
+
+	var sst *engine.RocksDBSstFileWriter
+	defer func() {
+		if sst != nil {
+			sst.Close()
+		}
+	}()
+	var startKey, lastKey roachpb.Key
+	var dataSize int64
+	flush := func() error {
+		if sst == nil {
+			return nil
+		}
+		if log.V(2) {
+			log.Infof(ctx, "adding an sstable of %d bytes from %s", sst.DataSize, uri)
+		}
+		data, err := sst.Finish()
+		if err != nil {
+			return err
+		}
+		sst.Close()
+		sst = nil
+		b := &client.Batch{}
+		b.AddRawRequest(&roachpb.AddSSTableRequest{
+			Span: roachpb.Span{Key: startKey, EndKey: lastKey.Next()},
+			Data: data,
+		})
+		return db.Run(b)
+	}
+
+	start := engine.MakeMVCCMetadataKey(file.StartKey)
+	end := engine.MakeMVCCMetadataKey(file.EndKey)
+	if err := fr.Iterate(start, end, func(kv engine.MVCCKeyValue) (bool, error) {
+		key, ok := kr.rewriteKey(kv.Key.Key)
+		if !ok {
+			return true, errors.Errorf("%s: unexpected key %s", uri, kv.Key)
+		}
+		// The checksum of a value covers its key.
+		value := roachpb.Value{RawBytes: append([]byte(nil), kv.Value...)}
+		value.ClearChecksum()
+		value.InitChecksum(key)
+		if sst == nil {
+			w, err := engine.MakeRocksDBSstFileWriter()
+			if err != nil {
+				return true, err
+			}
+			sst = &w
+			startKey = key
+		}
+		if err := sst.Add(engine.MVCCKeyValue{
+			Key:   engine.MVCCKey{Key: key, Timestamp: kv.Key.Timestamp},
+			Value: value.RawBytes,
+		}); err != nil {
+			return true, err
+		}
+		lastKey = key
+		dataSize += int64(len(key) + len(value.RawBytes))
+		if sst.DataSize >= importBatchSize {
+			if err := flush(); err != nil {
+				return true, err
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return dataSize, err
+	}
+	return dataSize, flush()
+}
+
+func (n *restoreNode) Next() (bool, error) {
+	if n.done {
+		return false, nil
+	}
+	n.done = true
+	return true, nil
+}
+
+func (n *restoreNode) Values() parser.DTuple { return n.result }
+
+func (n *restoreNode) Columns() []ResultColumn             { return restoreColumns }
+func (n *restoreNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *restoreNode) DebugValues() debugValues            { return debugValues{} }
+func (n *restoreNode) ExplainTypes(_ func(string, string)) {}
+func (n *restoreNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *restoreNode) MarkDebug(mode explainMode)          {}
+func (n *restoreNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "restore", n.n.From, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestRestore(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	dir, err := ioutil.TempDir("", "restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	r.Exec(`CREATE DATABASE d`)
+	r.Exec(`CREATE TABLE d.t (a INT PRIMARY KEY, b STRING, INDEX (b))`)
+	r.Exec(`INSERT INTO d.t VALUES (1, 'x'), (2, 'y'), (3, 'z')`)
+	r.Exec(`CREATE TABLE d.child (c INT PRIMARY KEY, a INT REFERENCES d.t, INDEX (a))`)
+	r.Exec(`INSERT INTO d.child VALUES (10, 1), (20, 3)`)
+	r.Exec(fmt.Sprintf(`BACKUP DATABASE d TO 'nodelocal://%s'`, dir))
+	// Changes made after the backup are not restored.
+	r.Exec(`INSERT INTO d.t VALUES (4, 'w')`)
+
+	queryStrings := func(query string) string {
+		rows := r.Query(query)
+		var results []string
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, s)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Sprint(results)
+	}
+
+	var tables, files, dataSize int
+	r.Exec(`CREATE DATABASE e`)
+	if err := sqlDB.QueryRow(fmt.Sprintf(`RESTORE TABLE d.t FROM 'nodelocal://%s' WITH into_db = 'e'`, dir)).Scan(
+		&tables, &files, &dataSize); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 1 || files == 0 || dataSize == 0 {
+		t.Errorf("expected 1 table and some data, got %d tables, %d files of %d bytes", tables, files, dataSize)
+	}
+	if results, expected := queryStrings(`SELECT b FROM e.t ORDER BY a`), "[x y z]"; results != expected {
+		t.Errorf("expected %s, got %s", expected, results)
+	}
+	if results, expected := queryStrings(`SELECT b FROM e.t@t_b_idx WHERE b > 'x'`), "[y z]"; results != expected {
+		t.Errorf("expected %s, got %s", expected, results)
+	}
+
+	// Restoring a whole database restores the references between its tables.
+	r.Exec(`CREATE DATABASE f`)
+	r.Exec(fmt.Sprintf(`RESTORE DATABASE d FROM 'nodelocal://%s' WITH into_db = 'f'`, dir))
+	if results, expected := queryStrings(`SELECT b FROM f.t WHERE a IN (SELECT a FROM f.child)`), "[x z]"; results != expected {
+		t.Errorf("expected %s, got %s", expected, results)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO f.child VALUES (30, 4)`); !testutils.IsError(err, "foreign key violation") {
+		t.Errorf("expected a foreign key violation, got %v", err)
+	}
+	r.Exec(`INSERT INTO f.child VALUES (30, 2)`)
+	// The original tables are unchanged.
+	if results, expected := queryStrings(`SELECT b FROM d.t ORDER BY a`), "[x y z w]"; results != expected {
+		t.Errorf("expected %s, got %s", expected, results)
+	}
+
+	for _, tc := range []struct {
+		stmt, expected string
+	}{
+		{`RESTORE TABLE d.t FROM 'nodelocal://%s'`,
+			`table "t" already exists`},
+		{`RESTORE TABLE d.missing FROM 'nodelocal://%s' WITH into_db = 'e'`,
+			`table "d.missing" not found in backup`},
+		{`RESTORE DATABASE missing FROM 'nodelocal://%s'`,
+			`database "missing" not found in backup`},
+		{`RESTORE TABLE d.child FROM 'nodelocal://%s' WITH into_db = 'e'`,
+			`cannot restore table "child" without the table referenced by`},
+		{`RESTORE TABLE d.t FROM 'nodelocal://%s' WITH into_db = 'missing'`,
+			`database "missing" does not exist`},
+		{`RESTORE TABLE d.t FROM 'nodelocal://%s' WITH foo = 'bar'`,
+			`unsupported RESTORE option "foo"`},
+		{`RESTORE TABLE d.t FROM 'nodelocal://%s/missing'`,
+			`no such file or directory`},
+	} {
+		if _, err := sqlDB.Exec(fmt.Sprintf(tc.stmt, dir)); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.stmt, tc.expected, err)
+		}
+	}
+}
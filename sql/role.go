@@ -0,0 +1,317 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/pkg/errors"
+)
+
+// roleMembersTableSchema describes the schema of the role_members table.
+// Every row records that a user or a role is a member of a role, and
+// whether it may grant the role to others and revoke it from them. The
+// roles themselves are stored in system.users.
+const roleMembersTableSchema = `
+CREATE TABLE system.role_members (
+  role    STRING NOT NULL,
+  member  STRING NOT NULL,
+  isAdmin BOOL   NOT NULL,
+  PRIMARY KEY (role, member),
+  INDEX (member)
+);`
+
+// AddRoleMembersToMetadataSchema adds the role_members table to the supplied
+// MetadataSchema.
+func AddRoleMembersToMetadataSchema(schema *sqlbase.MetadataSchema) {
+	desc := CreateTableDescriptor(
+		keys.RoleMembersTableID,
+		keys.SystemDatabaseID,
+		roleMembersTableSchema,
+		sqlbase.NewDefaultPrivilegeDescriptor(),
+	)
+	schema.AddDescriptor(keys.SystemDatabaseID, &desc)
+}
+
+// roleCache holds the roles the user of a session is a member of, as seen
+// by a transaction.
+type roleCache struct {
+	txn *client.Txn
+	// memberOf maps the roles to whether the user holds the admin option on
+	// them.
+	memberOf map[string]bool
+}
+
+// memberOf returns the roles the user of the session is a member of,
+// directly or through other roles, mapped to whether it may administer
+// them. The roles are looked up once per transaction.
+func (p *planner) memberOf() (map[string]bool, error) {
+	if p.txn == nil || p.session.User == security.RootUser {
+		return nil, nil
+	}
+	if p.roles.txn != p.txn {
+		roles, err := p.resolveMemberships(p.session.User)
+		if err != nil {
+			return nil, err
+		}
+		p.roles = roleCache{txn: p.txn, memberOf: roles}
+	}
+	return p.roles.memberOf, nil
+}
+
+// resolveMemberships returns the roles a user or role is a member of,
+// following the memberships of the roles themselves. A role is mapped to
+// true if the admin option on it is held by the member or by one of the
+// roles the member belongs to. The admin option on a role doesn't extend to
+// the roles that role is a member of.
+func (p *planner) resolveMemberships(member string) (map[string]bool, error) {
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+
+	roles := make(map[string]bool)
+	visited := map[string]struct{}{member: {}}
+	for toVisit := []string{member}; len(toVisit) > 0; toVisit = toVisit[1:] {
+		plan, err := ip.query(`SELECT role, isAdmin FROM system.role_members WHERE member = $1`, toVisit[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := plan.Start(); err != nil {
+			return nil, err
+		}
+		for {
+			next, err := plan.Next()
+			if err != nil {
+				return nil, err
+			}
+			if !next {
+				break
+			}
+			values := plan.Values()
+			role := string(*values[0].(*parser.DString))
+			roles[role] = roles[role] || bool(*values[1].(*parser.DBool))
+			if _, ok := visited[role]; !ok {
+				visited[role] = struct{}{}
+				toVisit = append(toVisit, role)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// checkRoleManagement checks that the user of the session may manage the
+// roles: create, drop, grant and revoke them. Only the users allowed to
+// modify system.users can.
+func (p *planner) checkRoleManagement() error {
+	return p.checkPrivilege(&sqlbase.UsersTable, privilege.INSERT)
+}
+
+// checkRoleAdmin checks that the user of the session may grant a role and
+// revoke it: it must either be allowed to manage the roles, or hold the
+// admin option on the role.
+func (p *planner) checkRoleAdmin(role string) error {
+	if p.checkRoleManagement() == nil {
+		return nil
+	}
+	roles, err := p.memberOf()
+	if err != nil {
+		return err
+	}
+	if !roles[role] {
+		return errors.Errorf("user %s must have admin option on role %s", p.session.User, role)
+	}
+	return nil
+}
+
+// lookupUser returns whether a user or role exists with the given name,
+// and whether it is a role.
+func lookupUser(ip *planner, name string) (exists bool, isRole bool, err error) {
+	row, err := ip.queryRow(`SELECT isRole FROM system.users WHERE username = $1`, name)
+	if err != nil || row == nil {
+		return false, false, err
+	}
+	return true, bool(*row[0].(*parser.DBool)), nil
+}
+
+// CreateRole creates a role.
+// Privileges: INSERT on system.users.
+func (p *planner) CreateRole(n *parser.CreateRole) (planNode, error) {
+	if err := p.checkRoleManagement(); err != nil {
+		return nil, err
+	}
+	name := string(n.Name)
+	if name == security.RootUser {
+		return nil, errors.Errorf("a user or role named %s already exists", name)
+	}
+
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+	exists, isRole, err := lookupUser(ip, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if n.IfNotExists && isRole {
+			return &emptyNode{}, nil
+		}
+		return nil, errors.Errorf("a user or role named %s already exists", name)
+	}
+	if _, err := ip.exec(`INSERT INTO system.users (username, isRole) VALUES ($1, true)`, name); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// DropRole drops roles, along with their memberships. A role still holding
+// privileges on a database or table cannot be dropped.
+// Privileges: INSERT on system.users.
+func (p *planner) DropRole(n *parser.DropRole) (planNode, error) {
+	if err := p.checkRoleManagement(); err != nil {
+		return nil, err
+	}
+
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+	var descs []sqlbase.DescriptorProto
+	for _, roleName := range n.Names {
+		name := string(roleName)
+		exists, isRole, err := lookupUser(ip, name)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			if n.IfExists {
+				continue
+			}
+			return nil, errors.Errorf("role %s does not exist", name)
+		}
+		if !isRole {
+			return nil, errors.Errorf("%s is a user, not a role", name)
+		}
+
+		if descs == nil {
+			if descs, err = p.getAllDescriptors(); err != nil {
+				return nil, err
+			}
+		}
+		for _, desc := range descs {
+			if desc.GetPrivileges().AnyPrivilege(name) {
+				return nil, errors.Errorf("cannot drop role %s: it has privileges on %s %s",
+					name, desc.TypeName(), desc.GetName())
+			}
+		}
+
+		if _, err := ip.exec(`DELETE FROM system.users WHERE username = $1`, name); err != nil {
+			return nil, err
+		}
+		if _, err := ip.exec(
+			`DELETE FROM system.role_members WHERE role = $1 OR member = $1`, name,
+		); err != nil {
+			return nil, err
+		}
+	}
+	p.roles = roleCache{}
+	return &emptyNode{}, nil
+}
+
+// GrantRole makes users or roles members of roles. A role cannot be made a
+// member of itself, directly or through other roles.
+// Privileges: INSERT on system.users, or the admin option on the roles.
+func (p *planner) GrantRole(n *parser.GrantRole) (planNode, error) {
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+	for _, roleName := range n.Roles {
+		role := string(roleName)
+		if err := p.checkRoleAdmin(role); err != nil {
+			return nil, err
+		}
+		exists, isRole, err := lookupUser(ip, role)
+		if err != nil {
+			return nil, err
+		}
+		if !exists || !isRole {
+			return nil, errors.Errorf("role %s does not exist", role)
+		}
+		for _, memberName := range n.Members {
+			member := string(memberName)
+			if exists, _, err := lookupUser(ip, member); err != nil {
+				return nil, err
+			} else if !exists {
+				return nil, errors.Errorf("user or role %s does not exist", member)
+			}
+			if member == role {
+				return nil, errors.Errorf("role %s cannot be a member of itself", role)
+			}
+			memberOf, err := p.resolveMemberships(role)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := memberOf[member]; ok {
+				return nil, errors.Errorf("making %s a member of %s would create a cycle", member, role)
+			}
+
+			stmt := `INSERT INTO system.role_members (role, member, isAdmin) VALUES ($1, $2, false)
+ON CONFLICT (role, member) DO NOTHING`
+			if n.AdminOption {
+				stmt = `UPSERT INTO system.role_members (role, member, isAdmin) VALUES ($1, $2, true)`
+			}
+			if _, err := ip.exec(stmt, role, member); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.roles = roleCache{}
+	return &emptyNode{}, nil
+}
+
+// RevokeRole removes users or roles from roles, or only revokes their admin
+// option on them.
+// Privileges: INSERT on system.users, or the admin option on the roles.
+func (p *planner) RevokeRole(n *parser.RevokeRole) (planNode, error) {
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+	for _, roleName := range n.Roles {
+		role := string(roleName)
+		if err := p.checkRoleAdmin(role); err != nil {
+			return nil, err
+		}
+		exists, isRole, err := lookupUser(ip, role)
+		if err != nil {
+			return nil, err
+		}
+		if !exists || !isRole {
+			return nil, errors.Errorf("role %s does not exist", role)
+		}
+		for _, memberName := range n.Members {
+			stmt := `DELETE FROM system.role_members WHERE role = $1 AND member = $2`
+			if n.AdminOption {
+				stmt = `UPDATE system.role_members SET isAdmin = false WHERE role = $1 AND member = $2`
+			}
+			if _, err := ip.exec(stmt, role, string(memberName)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.roles = roleCache{}
+	return &emptyNode{}, nil
+}
@@ -44,22 +44,25 @@ const (
 type rowHelper struct {
 	tableDesc    *sqlbase.TableDescriptor
 	indexes      []sqlbase.IndexDescriptor
-	indexEntries []sqlbase.IndexEntry
+	indexEntries [][]sqlbase.IndexEntry
 
 	// Computed and cached.
 	primaryIndexKeyPrefix []byte
 	primaryIndexCols      map[sqlbase.ColumnID]struct{}
 	sortedColumnFamilies  map[sqlbase.FamilyID][]sqlbase.ColumnID
+	// predicates[i] is the predicate of indexes[i] if it is a partial index.
+	predicates []*indexPredicate
+	evalCtx    parser.EvalContext
 }
 
-// encodeIndexes encodes the primary and secondary index keys. The
-// secondaryIndexEntries are only valid until the next call to encodeIndexes or
-// encodeSecondaryIndexes.
+// encodeIndexes encodes the primary and secondary index keys.
+// secondaryIndexEntries[i] holds the entries of rh.indexes[i] and is only valid
+// until the next call to encodeIndexes or encodeSecondaryIndexes.
 func (rh *rowHelper) encodeIndexes(
 	colIDtoRowIndex map[sqlbase.ColumnID]int, values []parser.Datum,
 ) (
 	primaryIndexKey []byte,
-	secondaryIndexEntries []sqlbase.IndexEntry,
+	secondaryIndexEntries [][]sqlbase.IndexEntry,
 	err error,
 ) {
 	if rh.primaryIndexKeyPrefix == nil {
@@ -78,23 +81,41 @@ func (rh *rowHelper) encodeIndexes(
 	return primaryIndexKey, secondaryIndexEntries, nil
 }
 
-// encodeSecondaryIndexes encodes the secondary index keys. The
-// secondaryIndexEntries are only valid until the next call to encodeIndexes or
-// encodeSecondaryIndexes.
+// encodeSecondaryIndexes encodes the secondary index keys.
+// secondaryIndexEntries[i] holds the entries of rh.indexes[i] and is only valid
+// until the next call to encodeIndexes or encodeSecondaryIndexes. Partial
+// indexes that don't contain the row have no entries.
 func (rh *rowHelper) encodeSecondaryIndexes(
 	colIDtoRowIndex map[sqlbase.ColumnID]int, values []parser.Datum,
 ) (
-	secondaryIndexEntries []sqlbase.IndexEntry,
+	secondaryIndexEntries [][]sqlbase.IndexEntry,
 	err error,
 ) {
 	if len(rh.indexEntries) != len(rh.indexes) {
-		rh.indexEntries = make([]sqlbase.IndexEntry, len(rh.indexes))
+		rh.indexEntries = make([][]sqlbase.IndexEntry, len(rh.indexes))
+	}
+	if rh.predicates == nil {
+		if rh.predicates, err = makeIndexPredicates(rh.tableDesc, rh.indexes); err != nil {
+			return nil, err
+		}
 	}
 	err = sqlbase.EncodeSecondaryIndexes(
 		rh.tableDesc, rh.indexes, colIDtoRowIndex, values, rh.indexEntries)
 	if err != nil {
 		return nil, err
 	}
+	for i, pred := range rh.predicates {
+		if pred == nil {
+			continue
+		}
+		matches, err := pred.matches(&rh.evalCtx, colIDtoRowIndex, values)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			rh.indexEntries[i] = nil
+		}
+	}
 	return rh.indexEntries, nil
 }
 
@@ -186,9 +207,16 @@ func makeRowInserter(
 	return ri, nil
 }
 
+// putter is the subset of client.Batch used by insertRow. It allows the
+// generated KVs to be collected somewhere other than a batch (see IMPORT).
+type putter interface {
+	CPut(key, value, expValue interface{})
+	Put(key, value interface{})
+}
+
 // insertCPutFn is used by insertRow when conflicts should be respected.
 // logValue is used for pretty printing.
-func insertCPutFn(ctx context.Context, b *client.Batch, key *roachpb.Key, value *roachpb.Value) {
+func insertCPutFn(ctx context.Context, b putter, key *roachpb.Key, value *roachpb.Value) {
 	// TODO(dan): We want do this V(2) log everywhere in sql. Consider making a
 	// client.Batch wrapper instead of inlining it everywhere.
 	if log.V(2) {
@@ -199,7 +227,7 @@ func insertCPutFn(ctx context.Context, b *client.Batch, key *roachpb.Key, value
 
 // insertPutFn is used by insertRow when conflicts should be ignored.
 // logValue is used for pretty printing.
-func insertPutFn(ctx context.Context, b *client.Batch, key *roachpb.Key, value *roachpb.Value) {
+func insertPutFn(ctx context.Context, b putter, key *roachpb.Key, value *roachpb.Value) {
 	if log.V(2) {
 		log.InfofDepth(ctx, 1, "Put %s -> %s", *key, value.PrettyPrint())
 	}
@@ -209,7 +237,7 @@ func insertPutFn(ctx context.Context, b *client.Batch, key *roachpb.Key, value *
 // insertRow adds to the batch the kv operations necessary to insert a table row
 // with the given values.
 func (ri *rowInserter) insertRow(
-	ctx context.Context, b *client.Batch, values []parser.Datum, ignoreConflicts bool,
+	ctx context.Context, b putter, values []parser.Datum, ignoreConflicts bool,
 ) error {
 	if len(values) != len(ri.insertCols) {
 		return errors.Errorf("got %d values but expected %d", len(values), len(ri.insertCols))
@@ -322,9 +350,11 @@ func (ri *rowInserter) insertRow(
 		ri.key = nil
 	}
 
-	for i := range secondaryIndexEntries {
-		e := &secondaryIndexEntries[i]
-		putFn(ctx, b, &e.Key, &e.Value)
+	for _, entries := range secondaryIndexEntries {
+		for i := range entries {
+			e := &entries[i]
+			putFn(ctx, b, &e.Key, &e.Value)
+		}
 	}
 
 	return nil
@@ -349,7 +379,7 @@ type rowUpdater struct {
 	marshalled      []roachpb.Value
 	newValues       []parser.Datum
 	key             roachpb.Key
-	indexEntriesBuf []sqlbase.IndexEntry
+	indexEntriesBuf [][]sqlbase.IndexEntry
 	valueBuf        []byte
 	value           roachpb.Value
 }
@@ -393,26 +423,34 @@ func makeRowUpdater(
 	}
 
 	// Secondary indexes needing updating.
-	needsUpdate := func(index sqlbase.IndexDescriptor) bool {
+	needsUpdate := func(index sqlbase.IndexDescriptor) (bool, error) {
 		if updateType == rowUpdaterOnlyColumns {
 			// Only update columns.
-			return false
+			return false, nil
 		}
 		// If the primary key changed, we need to update all of them.
 		if primaryKeyColChange {
-			return true
+			return true, nil
 		}
-		for _, id := range index.ColumnIDs {
+		colIDs, err := indexWriteColumnIDs(tableDesc, index)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range colIDs {
 			if _, ok := updateColIDtoRowIndex[id]; ok {
-				return true
+				return true, nil
 			}
 		}
-		return false
+		return false, nil
 	}
 
 	indexes := make([]sqlbase.IndexDescriptor, 0, len(tableDesc.Indexes)+len(tableDesc.Mutations))
 	for _, index := range tableDesc.Indexes {
-		if needsUpdate(index) {
+		update, err := needsUpdate(index)
+		if err != nil {
+			return rowUpdater{}, err
+		}
+		if update {
 			indexes = append(indexes, index)
 		}
 	}
@@ -420,7 +458,11 @@ func makeRowUpdater(
 	var deleteOnlyIndex map[int]struct{}
 	for _, m := range tableDesc.Mutations {
 		if index := m.GetIndex(); index != nil {
-			if needsUpdate(*index) {
+			update, err := needsUpdate(*index)
+			if err != nil {
+				return rowUpdater{}, err
+			}
+			if update {
 				indexes = append(indexes, *index)
 
 				switch m.State {
@@ -497,7 +539,11 @@ func makeRowUpdater(
 			}
 		}
 		for _, index := range indexes {
-			for _, colID := range index.ColumnIDs {
+			colIDs, err := indexWriteColumnIDs(tableDesc, index)
+			if err != nil {
+				return rowUpdater{}, err
+			}
+			for _, colID := range colIDs {
 				if err := maybeAddCol(colID); err != nil {
 					return rowUpdater{}, err
 				}
@@ -559,7 +605,7 @@ func (ru *rowUpdater) updateRow(
 	}
 
 	rowPrimaryKeyChanged := false
-	var newSecondaryIndexEntries []sqlbase.IndexEntry
+	var newSecondaryIndexEntries [][]sqlbase.IndexEntry
 	if ru.primaryKeyColChange {
 		var newPrimaryIndexKey []byte
 		newPrimaryIndexKey, newSecondaryIndexEntries, err =
@@ -577,12 +623,14 @@ func (ru *rowUpdater) updateRow(
 	}
 
 	if rowPrimaryKeyChanged {
-		if err := ru.fks.checkIdx(ru.helper.tableDesc.PrimaryIndex.ID, oldValues, ru.newValues); err != nil {
+		if err := ru.fks.checkIdx(
+			ctx, b, ru.helper.tableDesc.PrimaryIndex.ID, oldValues, ru.newValues,
+		); err != nil {
 			return nil, err
 		}
 		for i := range newSecondaryIndexEntries {
-			if !bytes.Equal(newSecondaryIndexEntries[i].Key, secondaryIndexEntries[i].Key) {
-				if err := ru.fks.checkIdx(ru.helper.indexes[i].ID, oldValues, ru.newValues); err != nil {
+			if !indexEntriesEqual(newSecondaryIndexEntries[i], secondaryIndexEntries[i]) {
+				if err := ru.fks.checkIdx(ctx, b, ru.helper.indexes[i].ID, oldValues, ru.newValues); err != nil {
 					return nil, err
 				}
 			}
@@ -698,25 +746,46 @@ func (ru *rowUpdater) updateRow(
 	}
 
 	// Update secondary indexes.
-	for i, newSecondaryIndexEntry := range newSecondaryIndexEntries {
-		secondaryIndexEntry := secondaryIndexEntries[i]
-		secondaryKeyChanged := !bytes.Equal(newSecondaryIndexEntry.Key, secondaryIndexEntry.Key)
-		if secondaryKeyChanged {
-			if err := ru.fks.checkIdx(ru.helper.indexes[i].ID, oldValues, ru.newValues); err != nil {
-				return nil, err
-			}
+	for i, newEntries := range newSecondaryIndexEntries {
+		entries := secondaryIndexEntries[i]
+		if indexEntriesEqual(entries, newEntries) {
+			continue
+		}
+		if err := ru.fks.checkIdx(ctx, b, ru.helper.indexes[i].ID, oldValues, ru.newValues); err != nil {
+			return nil, err
+		}
 
+		// Do not update Indexes in the DELETE_ONLY state.
+		_, deleteOnly := ru.deleteOnlyIndex[i]
+		for _, secondaryIndexEntry := range entries {
+			if !deleteOnly && indexEntriesContainKey(newEntries, secondaryIndexEntry.Key) {
+				// Overwritten below.
+				continue
+			}
 			if log.V(2) {
 				log.Infof(ctx, "Del %s", secondaryIndexEntry.Key)
 			}
 			b.Del(secondaryIndexEntry.Key)
-			// Do not update Indexes in the DELETE_ONLY state.
-			if _, ok := ru.deleteOnlyIndex[i]; !ok {
+		}
+		if deleteOnly {
+			continue
+		}
+		for j := range newEntries {
+			newSecondaryIndexEntry := &newEntries[j]
+			if indexEntriesContainKey(entries, newSecondaryIndexEntry.Key) {
+				// Only the value of the entry changed, which happens with the
+				// entries of the column families of an index with the primary
+				// index encoding.
 				if log.V(2) {
-					log.Infof(ctx, "CPut %s -> %v", newSecondaryIndexEntry.Key, newSecondaryIndexEntry.Value.PrettyPrint())
+					log.Infof(ctx, "Put %s -> %v", newSecondaryIndexEntry.Key, newSecondaryIndexEntry.Value.PrettyPrint())
 				}
-				b.CPut(newSecondaryIndexEntry.Key, &newSecondaryIndexEntry.Value, nil)
+				b.Put(newSecondaryIndexEntry.Key, &newSecondaryIndexEntry.Value)
+				continue
 			}
+			if log.V(2) {
+				log.Infof(ctx, "CPut %s -> %v", newSecondaryIndexEntry.Key, newSecondaryIndexEntry.Value.PrettyPrint())
+			}
+			b.CPut(newSecondaryIndexEntry.Key, &newSecondaryIndexEntry.Value, nil)
 		}
 	}
 
@@ -785,7 +854,11 @@ func makeRowDeleter(
 		}
 	}
 	for _, index := range indexes {
-		for _, colID := range index.ColumnIDs {
+		colIDs, err := indexWriteColumnIDs(tableDesc, index)
+		if err != nil {
+			return rowDeleter{}, err
+		}
+		for _, colID := range colIDs {
 			if err := maybeAddCol(colID); err != nil {
 				return rowDeleter{}, err
 			}
@@ -799,7 +872,9 @@ func makeRowDeleter(
 	}
 	if checkFKs {
 		var err error
-		if rd.fks, err = makeFKDeleteHelper(txn, *tableDesc, fkTables, fetchColIDtoRowIndex); err != nil {
+		if rd.fks, err = makeFKDeleteHelper(
+			txn, *tableDesc, fkTables, fetchColIDtoRowIndex, CheckDeletes,
+		); err != nil {
 			return rowDeleter{}, err
 		}
 	}
@@ -810,7 +885,7 @@ func makeRowDeleter(
 // deleteRow adds to the batch the kv operations necessary to delete a table row
 // with the given values.
 func (rd *rowDeleter) deleteRow(ctx context.Context, b *client.Batch, values []parser.Datum) error {
-	if err := rd.fks.checkAll(values); err != nil {
+	if err := rd.fks.checkAll(ctx, b, values); err != nil {
 		return err
 	}
 
@@ -819,11 +894,13 @@ func (rd *rowDeleter) deleteRow(ctx context.Context, b *client.Batch, values []p
 		return err
 	}
 
-	for _, secondaryIndexEntry := range secondaryIndexEntries {
-		if log.V(2) {
-			log.Infof(ctx, "Del %s", secondaryIndexEntry.Key)
+	for _, entries := range secondaryIndexEntries {
+		for _, secondaryIndexEntry := range entries {
+			if log.V(2) {
+				log.Infof(ctx, "Del %s", secondaryIndexEntry.Key)
+			}
+			b.Del(secondaryIndexEntry.Key)
 		}
-		b.Del(secondaryIndexEntry.Key)
 	}
 
 	// Delete the row.
@@ -843,21 +920,71 @@ func (rd *rowDeleter) deleteRow(ctx context.Context, b *client.Batch, values []p
 func (rd *rowDeleter) deleteIndexRow(
 	ctx context.Context, b *client.Batch, idx *sqlbase.IndexDescriptor, values []parser.Datum,
 ) error {
-	if err := rd.fks.checkAll(values); err != nil {
+	if err := rd.fks.checkAll(ctx, b, values); err != nil {
 		return err
 	}
-	secondaryIndexEntry, err := sqlbase.EncodeSecondaryIndex(
+	secondaryIndexEntries, err := sqlbase.EncodeSecondaryIndexEntries(
 		rd.helper.tableDesc, idx, rd.fetchColIDtoRowIndex, values)
 	if err != nil {
 		return err
 	}
-	if log.V(2) {
-		log.Infof(ctx, "Del %s", secondaryIndexEntry.Key)
+	for _, secondaryIndexEntry := range secondaryIndexEntries {
+		if log.V(2) {
+			log.Infof(ctx, "Del %s", secondaryIndexEntry.Key)
+		}
+		b.Del(secondaryIndexEntry.Key)
 	}
-	b.Del(secondaryIndexEntry.Key)
 	return nil
 }
 
+// indexEntriesEqual returns true if the two lists of entries of an index have
+// the same keys and values.
+func indexEntriesEqual(a, b []sqlbase.IndexEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Key, b[i].Key) || !bytes.Equal(a[i].Value.RawBytes, b[i].Value.RawBytes) {
+			return false
+		}
+	}
+	return true
+}
+
+// indexEntriesContainKey returns true if one of the entries has the key.
+func indexEntriesContainKey(entries []sqlbase.IndexEntry, key roachpb.Key) bool {
+	for i := range entries {
+		if bytes.Equal(entries[i].Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexWriteColumnIDs returns the IDs of the columns needed to write the
+// entry of a row in the index: the indexed columns and, for partial indexes,
+// the columns referenced by the predicate. The entries of an index with the
+// primary index encoding need all the columns.
+func indexWriteColumnIDs(
+	tableDesc *sqlbase.TableDescriptor, index sqlbase.IndexDescriptor,
+) ([]sqlbase.ColumnID, error) {
+	if index.EncodingType == sqlbase.PrimaryIndexEncoding {
+		colIDs := make([]sqlbase.ColumnID, len(tableDesc.Columns))
+		for i, col := range tableDesc.Columns {
+			colIDs[i] = col.ID
+		}
+		return colIDs, nil
+	}
+	if !index.IsPartial() {
+		return index.ColumnIDs, nil
+	}
+	pred, err := makeIndexPredicate(tableDesc, &index)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]sqlbase.ColumnID(nil), index.ColumnIDs...), pred.columnIDs()...), nil
+}
+
 func colIDtoRowIndexFromCols(cols []sqlbase.ColumnDescriptor) map[sqlbase.ColumnID]int {
 	colIDtoRowIndex := make(map[sqlbase.ColumnID]int, len(cols))
 	for i, col := range cols {
@@ -39,6 +39,9 @@ type scanNode struct {
 	specifiedIndex *sqlbase.IndexDescriptor
 	// Set if the NO_INDEX_JOIN hint was given.
 	noIndexJoin bool
+	// Set if the rows returned must be locked, for SELECT ... FOR UPDATE.
+	// Only set on scans of the primary index.
+	lockRows bool
 
 	// The table columns, possibly including ones currently in schema changes.
 	cols []sqlbase.ColumnDescriptor
@@ -126,6 +129,9 @@ func (n *scanNode) Start() error {
 	if err != nil {
 		return err
 	}
+	if n.lockRows {
+		n.fetcher.KeepRowKVs()
+	}
 
 	return n.p.startSubqueryPlans(n.filter)
 }
@@ -204,11 +210,39 @@ func (n *scanNode) Next() (bool, error) {
 			return false, err
 		}
 		if passesFilter {
+			if n.lockRows {
+				if err := n.lockRow(); err != nil {
+					return false, err
+				}
+			}
 			return true, nil
 		}
 	}
 }
 
+// nextUnfilteredRow returns the next row of the scan, without applying the
+// filter, or nil once all the rows have been read. It is used by
+// vectorizedNode, which applies the filter itself.
+func (n *scanNode) nextUnfilteredRow() (parser.DTuple, error) {
+	if !n.scanInitialized {
+		if err := n.initScan(); err != nil {
+			return nil, err
+		}
+	}
+	return n.fetcher.NextRow()
+}
+
+// lockRow locks the current row by writing back the key/values it's made of,
+// leaving write intents on them. Concurrent transactions trying to modify the
+// row then wait for the current one instead of forcing it to restart.
+func (n *scanNode) lockRow() error {
+	b := n.p.txn.NewBatch()
+	for _, kv := range n.fetcher.RowKVs() {
+		b.Put(kv.Key, &roachpb.Value{RawBytes: kv.Value.RawBytes})
+	}
+	return n.p.txn.Run(b)
+}
+
 func (n *scanNode) ExplainPlan(_ bool) (name, description string, children []planNode) {
 	if n.reverse {
 		name = "revscan"
@@ -244,26 +278,16 @@ func (n *scanNode) ExplainTypes(regTypes func(string, string)) {
 // fully-qualified columns if an alias is not specified.
 func (n *scanNode) initTable(
 	p *planner,
-	tableName *parser.TableName,
+	desc *sqlbase.TableDescriptor,
 	indexHints *parser.IndexHints,
 	scanVisibility scanVisibility,
 ) error {
-	descFunc := p.getTableLease
-	if p.asOf {
-		// AS OF SYSTEM TIME queries need to fetch the table descriptor at the
-		// specified time, and never lease anything. The proto transaction already
-		// has its timestamps set correctly so mustGetTableDesc will fetch with the
-		// correct timestamp.
-		descFunc = p.mustGetTableDesc
-	}
-	desc, err := descFunc(tableName)
-	if err != nil {
-		return err
-	}
 	n.desc = *desc
 
-	if err := p.checkPrivilege(&n.desc, privilege.SELECT); err != nil {
-		return err
+	if !p.skipSelectPrivilegeChecks {
+		if err := p.checkPrivilege(&n.desc, privilege.SELECT); err != nil {
+			return err
+		}
 	}
 
 	if indexHints != nil && indexHints.Index != "" {
@@ -345,6 +369,11 @@ func (n *scanNode) computeOrdering(
 	columnIDs, dirs := index.FullColumnIDs()
 
 	for i, colID := range columnIDs {
+		if i == 0 && index.IsInverted() {
+			// A scan of an inverted index is restricted to the entries for a
+			// single path or element, which are ordered by the implicit columns.
+			continue
+		}
 		idx, ok := n.colIdxMap[colID]
 		if !ok {
 			panic(fmt.Sprintf("index refers to unknown column id %d", colID))
@@ -182,6 +182,17 @@ func TestScanBatches(t *testing.T) {
 		}
 	}
 
+	// Small byte targets cut the batches short regardless of their size.
+	restoreTargetBytes := sqlbase.SetKVBatchTargetBytes(1)
+	defer restoreTargetBytes()
+	for _, targetBytes := range []int64{1, 20, 100} {
+		sqlbase.SetKVBatchTargetBytes(targetBytes)
+		for _, numSpans := range numSpanValues {
+			testScanBatchQuery(t, db, numSpans, numAs, numBs, false)
+			testScanBatchQuery(t, db, numSpans, numAs, numBs, true)
+		}
+	}
+
 	if _, err := db.Exec(`DROP TABLE test.scan`); err != nil {
 		t.Fatal(err)
 	}
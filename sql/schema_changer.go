@@ -28,6 +28,8 @@ import (
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/distsql"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -54,6 +56,19 @@ type SchemaChanger struct {
 	db         client.DB
 	leaseMgr   *LeaseManager
 	evalCtx    parser.EvalContext
+	// jobRegistry, if set, is used to track the application of the
+	// mutations as a job.
+	jobRegistry *JobRegistry
+	// job is the job tracking the current application of the mutations, if
+	// any.
+	job *Job
+	// backfillChunkNotification, if set, is called after every chunk of an
+	// index backfill.
+	backfillChunkNotification func() error
+	// distSQLSrv, if set, is used to backfill indexes by ingesting SSTables
+	// built by Backfiller processors, run on the nodes located through gossip.
+	distSQLSrv *distsql.ServerImpl
+	gossip     *gossip.Gossip
 	// The SchemaChangeManager can attempt to execute this schema
 	// changer after this time.
 	execAfter time.Time
@@ -193,12 +208,18 @@ func isSchemaChangeRetryError(err error) bool {
 	}
 }
 
+// schemaChangeJobDescription returns the description of the job tracking a
+// mutation, by which the job is found again when the schema change is retried.
+func schemaChangeJobDescription(tableID sqlbase.ID, mutationID sqlbase.MutationID) string {
+	return fmt.Sprintf("mutation %d of table %d", mutationID, tableID)
+}
+
 // Execute the entire schema change in steps. startBackfillNotification is
 // called before the backfill starts; it can be nil.
 func (sc SchemaChanger) exec(
 	startBackfillNotification func() error,
 	oldNameNotInUseNotification func(),
-) error {
+) (err error) {
 	// Acquire lease.
 	lease, err := sc.AcquireLease()
 	if err != nil {
@@ -320,6 +341,21 @@ func (sc SchemaChanger) exec(
 	// Another transaction might set the up_version bit again,
 	// but we're no longer responsible for taking care of that.
 
+	// The job of a schema change that failed with a transient error, here or
+	// on a node that died, is taken over so that the backfill resumes from its
+	// last checkpoint. The job is only finished once the schema change is
+	// done or won't be retried; it remains running in between.
+	sc.job, err = sc.jobRegistry.adoptOrNewJob(context.TODO(), jobTypeSchemaChange,
+		schemaChangeJobDescription(sc.tableID, sc.mutationID), security.NodeUser)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil || !isSchemaChangeRetryError(err) {
+			sc.job.Finished(context.TODO(), err)
+		}
+	}()
+
 	// Run through mutation state machine and backfill.
 	err = sc.runStateMachineAndBackfill(&lease, startBackfillNotification)
 
@@ -444,9 +480,13 @@ func (sc *SchemaChanger) waitToUpdateLeases(tableID sqlbase.ID) error {
 // done finalizes the mutations (adds new cols/indexes to the table).
 // It ensures that all nodes are on the current (pre-update) version of the
 // schema.
-// Returns the updated of the descriptor.
-func (sc *SchemaChanger) done() (*sqlbase.Descriptor, error) {
-	return sc.leaseMgr.Publish(sc.tableID, func(desc *sqlbase.TableDescriptor) error {
+// Returns the ID of the mutations queued by the completion of the
+// mutations, if any: completing the replacement of a column or an index
+// queues the drop of the replaced one.
+func (sc *SchemaChanger) done() (sqlbase.MutationID, error) {
+	followUpID := sqlbase.InvalidMutationID
+	_, err := sc.leaseMgr.Publish(sc.tableID, func(desc *sqlbase.TableDescriptor) error {
+		followUpID = sqlbase.InvalidMutationID
 		i := 0
 		for _, mutation := range desc.Mutations {
 			if mutation.MutationID != sc.mutationID {
@@ -464,6 +504,17 @@ func (sc *SchemaChanger) done() (*sqlbase.Descriptor, error) {
 		}
 		// Trim the executed mutations from the descriptor.
 		desc.Mutations = desc.Mutations[i:]
+		// The mutations queued by the completed ones share a new mutation ID.
+		// They are run right away if no other mutations precede them.
+		for j, mutation := range desc.Mutations {
+			if mutation.MutationID == desc.NextMutationID {
+				if j == 0 {
+					followUpID = desc.NextMutationID
+				}
+				desc.NextMutationID++
+				break
+			}
+		}
 		return nil
 	}, func(txn *client.Txn) error {
 		// Log "Finish Schema Change" event. Only the table ID and mutation ID
@@ -478,6 +529,7 @@ func (sc *SchemaChanger) done() (*sqlbase.Descriptor, error) {
 			}{uint32(sc.mutationID)},
 		)
 	})
+	return followUpID, err
 }
 
 // runStateMachineAndBackfill runs the schema change state machine followed by
@@ -502,8 +554,15 @@ func (sc *SchemaChanger) runStateMachineAndBackfill(
 	}
 
 	// Mark the mutations as completed.
-	_, err := sc.done()
-	return err
+	followUpID, err := sc.done()
+	if err != nil || followUpID == sqlbase.InvalidMutationID {
+		return err
+	}
+
+	// Run the mutations queued by the completed ones right away rather than
+	// leaving them to the SchemaChangeManager.
+	sc.mutationID = followUpID
+	return sc.runStateMachineAndBackfill(lease, nil)
 }
 
 // reverseMutations reverses the direction of all the mutations with the
@@ -650,6 +709,8 @@ type SchemaChangeManager struct {
 	db           client.DB
 	gossip       *gossip.Gossip
 	leaseMgr     *LeaseManager
+	jobRegistry  *JobRegistry
+	distSQLSrv   *distsql.ServerImpl
 	testingKnobs *SchemaChangeManagerTestingKnobs
 	// Create a schema changer for every outstanding schema change seen.
 	schemaChangers map[sqlbase.ID]SchemaChanger
@@ -661,11 +722,15 @@ func NewSchemaChangeManager(
 	db client.DB,
 	gossip *gossip.Gossip,
 	leaseMgr *LeaseManager,
+	jobRegistry *JobRegistry,
+	distSQLSrv *distsql.ServerImpl,
 ) *SchemaChangeManager {
 	return &SchemaChangeManager{
 		db:             db,
 		gossip:         gossip,
 		leaseMgr:       leaseMgr,
+		jobRegistry:    jobRegistry,
+		distSQLSrv:     distSQLSrv,
 		testingKnobs:   testingKnobs,
 		schemaChangers: make(map[sqlbase.ID]SchemaChanger),
 	}
@@ -709,9 +774,12 @@ func (s *SchemaChangeManager) Start(stopper *stop.Stopper) {
 					log.Info(context.TODO(), "received a new config")
 				}
 				schemaChanger := SchemaChanger{
-					nodeID:   roachpb.NodeID(s.leaseMgr.nodeID),
-					db:       s.db,
-					leaseMgr: s.leaseMgr,
+					nodeID:      roachpb.NodeID(s.leaseMgr.nodeID),
+					db:          s.db,
+					leaseMgr:    s.leaseMgr,
+					jobRegistry: s.jobRegistry,
+					distSQLSrv:  s.distSQLSrv,
+					gossip:      s.gossip,
 				}
 				// Keep track of existing schema changers.
 				oldSchemaChangers := make(map[sqlbase.ID]struct{}, len(s.schemaChangers))
@@ -34,6 +34,7 @@ import (
 	"github.com/cockroachdb/cockroach/testutils"
 	"github.com/cockroachdb/cockroach/testutils/serverutils"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/protoutil"
@@ -784,6 +785,183 @@ CREATE TABLE t.test (k INT PRIMARY KEY, v INT);
 	}
 }
 
+// Test that a retried index backfill resumes from the checkpoint recorded in
+// its job instead of starting over.
+func TestSchemaChangeResumesBackfill(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	chunks := 0
+	params.Knobs = base.TestingKnobs{
+		SQLExecutor: &csql.ExecutorTestingKnobs{
+			SchemaChangersBackfillChunkNotification: func() error {
+				chunks++
+				// Interrupt the backfill once, after two chunks.
+				if chunks == 2 {
+					return errors.New("context deadline exceeded")
+				}
+				return nil
+			},
+		},
+		SQLSchemaChangeManager: &csql.SchemaChangeManagerTestingKnobs{
+			AsyncSchemaChangerExecNotification: schemaChangeManagerDisabled,
+		},
+	}
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.test (k INT PRIMARY KEY, v INT);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert enough rows for four chunks, the last one partial.
+	maxValue := 3 * csql.IndexBackfillChunkSize
+	insert := fmt.Sprintf(`INSERT INTO t.test VALUES (%d, %d)`, 0, maxValue)
+	for i := 1; i <= maxValue; i++ {
+		insert += fmt.Sprintf(` ,(%d, %d)`, i, maxValue-i)
+	}
+	if _, err := sqlDB.Exec(insert); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sqlDB.Exec("CREATE UNIQUE INDEX foo ON t.test (v)"); err != nil {
+		t.Fatal(err)
+	}
+	// The retry only backfilled the last two chunks.
+	if e := 4; chunks != e {
+		t.Fatalf("backfilled %d chunks instead of %d", chunks, e)
+	}
+
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM t.test@foo`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if eCount := maxValue + 1; eCount != count {
+		t.Fatalf("read the wrong number of rows: e = %d, v = %d", eCount, count)
+	}
+
+	// The retry continued the job of the first attempt.
+	var jobs int
+	var status string
+	if err := sqlDB.QueryRow(
+		`SELECT COUNT(*), MAX(status) FROM system.jobs WHERE jobType = 'SCHEMA CHANGE'`,
+	).Scan(&jobs, &status); err != nil {
+		t.Fatal(err)
+	}
+	if jobs != 1 || status != "succeeded" {
+		t.Fatalf("expected 1 succeeded schema change job, got %d with status %s", jobs, status)
+	}
+}
+
+// Test that non-unique indexes are backfilled by ingesting SSTables, across
+// the ranges of the table.
+func TestSchemaChangeBulkBackfill(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	chunks := 0
+	params.Knobs = base.TestingKnobs{
+		SQLExecutor: &csql.ExecutorTestingKnobs{
+			SchemaChangersBackfillChunkNotification: func() error {
+				chunks++
+				return nil
+			},
+		},
+		SQLSchemaChangeManager: &csql.SchemaChangeManagerTestingKnobs{
+			AsyncSchemaChangerExecNotification: schemaChangeManagerDisabled,
+		},
+	}
+	s, sqlDB, kvDB := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+CREATE DATABASE t;
+CREATE TABLE t.test (k INT PRIMARY KEY, v INT);
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	maxValue := 2000
+	insert := fmt.Sprintf(`INSERT INTO t.test VALUES (%d, %d)`, 0, maxValue)
+	for i := 1; i <= maxValue; i++ {
+		insert += fmt.Sprintf(` ,(%d, %d)`, i, maxValue-i)
+	}
+	if _, err := sqlDB.Exec(insert); err != nil {
+		t.Fatal(err)
+	}
+
+	// Split the table into a few ranges.
+	tableDesc := sqlbase.GetTableDescriptor(kvDB, "t", "test")
+	for _, k := range []int64{500, 1000, 1500} {
+		key := sqlbase.MakeIndexKeyPrefix(tableDesc, tableDesc.PrimaryIndex.ID)
+		key = encoding.EncodeVarintAscending(key, k)
+		if err := kvDB.AdminSplit(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := sqlDB.Exec("CREATE INDEX foo ON t.test (v)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec("CREATE INDEX bar ON t.test (v) WHERE k % 2 = 0"); err != nil {
+		t.Fatal(err)
+	}
+	// Neither index was backfilled transactionally.
+	if chunks != 0 {
+		t.Fatalf("expected a bulk backfill, got %d transactional chunks", chunks)
+	}
+
+	rows, err := sqlDB.Query(`SELECT v FROM t.test@foo`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for ; rows.Next(); count++ {
+		var val int
+		if err := rows.Scan(&val); err != nil {
+			t.Fatal(err)
+		}
+		if count != val {
+			t.Fatalf("e = %d, v = %d", count, val)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if eCount := maxValue + 1; eCount != count {
+		t.Fatalf("read the wrong number of rows: e = %d, v = %d", eCount, count)
+	}
+
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM t.test@bar WHERE k % 2 = 0`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if eCount := maxValue/2 + 1; eCount != count {
+		t.Fatalf("read the wrong number of rows: e = %d, v = %d", eCount, count)
+	}
+
+	// The index is maintained by the writes following the backfill.
+	if _, err := sqlDB.Exec(`DELETE FROM t.test WHERE k < 100`); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM t.test@foo`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if eCount := maxValue + 1 - 100; eCount != count {
+		t.Fatalf("read the wrong number of rows: e = %d, v = %d", eCount, count)
+	}
+
+	// Unique indexes are still backfilled transactionally.
+	if _, err := sqlDB.Exec("CREATE UNIQUE INDEX baz ON t.test (v)"); err != nil {
+		t.Fatal(err)
+	}
+	if chunks == 0 {
+		t.Fatal("expected a transactional backfill of the unique index")
+	}
+}
+
 // Test schema change purge failure doesn't leave DB in a bad state.
 func TestSchemaChangePurgeFailure(t *testing.T) {
 	defer leaktest.AfterTest(t)()
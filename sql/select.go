@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 )
 
@@ -220,9 +221,18 @@ func (p *planner) Select(n *parser.Select, desiredTypes []parser.Datum, autoComm
 	wrapped := n.Select
 	limit := n.Limit
 	orderBy := n.OrderBy
+	locking := n.Locking
+
+	defer func(ctes *cteScope) { p.ctes = ctes }(p.ctes)
+	if err := p.pushWith(n.With); err != nil {
+		return nil, err
+	}
 
 	for s, ok := wrapped.(*parser.ParenSelect); ok; s, ok = wrapped.(*parser.ParenSelect) {
 		wrapped = s.Select.Select
+		if err := p.pushWith(s.Select.With); err != nil {
+			return nil, err
+		}
 		if s.Select.OrderBy != nil {
 			if orderBy != nil {
 				return nil, fmt.Errorf("multiple ORDER BY clauses not allowed")
@@ -235,13 +245,16 @@ func (p *planner) Select(n *parser.Select, desiredTypes []parser.Datum, autoComm
 			}
 			limit = s.Select.Limit
 		}
+		if s.Select.Locking > locking {
+			locking = s.Select.Locking
+		}
 	}
 
 	switch s := wrapped.(type) {
 	case *parser.SelectClause:
 		// Select can potentially optimize index selection if it's being ordered,
 		// so we allow it to do its own sorting.
-		return p.SelectClause(s, orderBy, limit, desiredTypes, publicColumns)
+		return p.SelectClause(s, orderBy, limit, locking, desiredTypes, publicColumns)
 
 	// TODO(dan): Union can also do optimizations when it has an ORDER BY, but
 	// currently expects the ordering to be done externally, so we let it fall
@@ -249,6 +262,14 @@ func (p *planner) Select(n *parser.Select, desiredTypes []parser.Datum, autoComm
 	// investigating a general mechanism for passing some context down during
 	// plan node construction.
 	default:
+		if locking != parser.LockNone {
+			switch s.(type) {
+			case *parser.UnionClause:
+				return nil, fmt.Errorf("%s is not allowed with UNION/INTERSECT/EXCEPT", locking)
+			case *parser.ValuesClause:
+				return nil, fmt.Errorf("%s cannot be applied to VALUES", locking)
+			}
+		}
 		plan, err := p.newPlan(s, desiredTypes, autoCommit)
 		if err != nil {
 			return nil, err
@@ -278,13 +299,14 @@ func (p *planner) Select(n *parser.Select, desiredTypes []parser.Datum, autoComm
 // LIMIT, or parenthesis in the parsed SELECT. See `sql/parser.Select` and
 // `sql/parser.SelectStatement`.
 //
-// Privileges: SELECT on table
+// Privileges: SELECT on table. Also UPDATE on "FOR UPDATE" and "FOR SHARE".
 //   Notes: postgres requires SELECT. Also requires UPDATE on "FOR UPDATE".
 //          mysql requires SELECT.
 func (p *planner) SelectClause(
 	parsed *parser.SelectClause,
 	orderBy parser.OrderBy,
 	limit *parser.Limit,
+	locking parser.LockingStrength,
 	desiredTypes []parser.Datum,
 	scanVisibility scanVisibility,
 ) (planNode, error) {
@@ -304,11 +326,16 @@ func (p *planner) SelectClause(
 		return nil, err
 	}
 
-	// NB: both orderBy and groupBy are passed and can modify the selectNode but orderBy must do so first.
+	// NB: orderBy, window, and groupBy are passed and can modify the selectNode,
+	// but must do so in that order.
 	sort, err := p.orderBy(orderBy, s)
 	if err != nil {
 		return nil, err
 	}
+	window, err := p.window(parsed, s)
+	if err != nil {
+		return nil, err
+	}
 	group, err := p.groupBy(parsed, s)
 	if err != nil {
 		return nil, err
@@ -319,6 +346,12 @@ func (p *planner) SelectClause(
 		s.filter = group.isNotNullFilter(s.filter)
 	}
 
+	if locking != parser.LockNone {
+		if err := s.initLocking(parsed, locking, group, window); err != nil {
+			return nil, err
+		}
+	}
+
 	limitPlan, err := p.Limit(limit)
 	if err != nil {
 		return nil, err
@@ -328,6 +361,7 @@ func (p *planner) SelectClause(
 	result := &selectTopNode{
 		source:   s,
 		group:    group,
+		window:   window,
 		sort:     sort,
 		distinct: distinctPlan,
 		limit:    limitPlan,
@@ -444,6 +478,37 @@ func (s *selectNode) initFrom(parsed *parser.SelectClause, scanVisibility scanVi
 	return nil
 }
 
+// initLocking arranges for the rows returned by the select to be locked, for
+// SELECT ... FOR UPDATE/SHARE. Only the rows of a single table can be locked.
+func (s *selectNode) initLocking(
+	parsed *parser.SelectClause,
+	locking parser.LockingStrength,
+	group *groupNode,
+	window *windowNode,
+) error {
+	switch {
+	case parsed.Distinct:
+		return fmt.Errorf("%s is not allowed with DISTINCT clause", locking)
+	case len(parsed.GroupBy) > 0:
+		return fmt.Errorf("%s is not allowed with GROUP BY clause", locking)
+	case group != nil:
+		return fmt.Errorf("%s is not allowed with aggregate functions", locking)
+	case window != nil:
+		return fmt.Errorf("%s is not allowed with window functions", locking)
+	case s.planner.asOf:
+		return fmt.Errorf("%s is not allowed with AS OF SYSTEM TIME", locking)
+	}
+	scan, ok := s.source.plan.(*scanNode)
+	if !ok {
+		return fmt.Errorf("%s is only supported on queries over a single table", locking)
+	}
+	if err := s.planner.checkPrivilege(&scan.desc, privilege.UPDATE); err != nil {
+		return err
+	}
+	scan.lockRows = true
+	return nil
+}
+
 func (s *selectNode) initTargets(targets parser.SelectExprs, desiredTypes []parser.Datum) error {
 	// Loop over the select expressions and expand them into the expressions
 	// we're going to use to generate the returned column set and the names for
@@ -485,6 +550,11 @@ func (s *selectNode) initWhere(where *parser.Where) error {
 		return fmt.Errorf("aggregate functions are not allowed in WHERE")
 	}
 
+	// Same for window functions.
+	if s.planner.parser.WindowFuncInExpr(s.filter) {
+		return fmt.Errorf("window functions are not allowed in WHERE")
+	}
+
 	return nil
 }
 
@@ -543,6 +613,7 @@ func (s *selectNode) addRender(target parser.SelectExpr, desiredType parser.Datu
 	if isStar, cols, typedExprs, err := checkRenderStar(target, s.source.info, s.qvals); err != nil {
 		return err
 	} else if isStar {
+		s.planner.hasStar = true
 		s.columns = append(s.columns, cols...)
 		s.render = append(s.render, typedExprs...)
 		return nil
@@ -563,6 +634,13 @@ func (s *selectNode) addRender(target parser.SelectExpr, desiredType parser.Datu
 	return nil
 }
 
+// addTypedRender adds an expression which has already been analyzed as a
+// render target.
+func (s *selectNode) addTypedRender(expr parser.TypedExpr, name string) {
+	s.render = append(s.render, expr)
+	s.columns = append(s.columns, ResultColumn{Name: name, Typ: expr.ReturnType()})
+}
+
 // renderRow renders the row by evaluating the render expressions. Assumes the qvals have been
 // populated with the current row.
 func (s *selectNode) renderRow() error {
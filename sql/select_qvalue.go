@@ -154,6 +154,14 @@ func (v *nameResolutionVisitor) VisitPre(expr parser.Expr) (recurse bool, newNod
 		return v.VisitPre(vn)
 
 	case *parser.ColumnItem:
+		if subscripts, ok := arraySubscripts(t.Selector); ok {
+			// The column is followed by array subscripts, as in `a[1]`: index into
+			// the column once it is resolved.
+			col := *t
+			col.Selector = nil
+			return true, &parser.IndirectionExpr{Expr: &col, Indirection: subscripts}
+		}
+
 		var colRef columnRef
 
 		colRef.source, colRef.colIdx, v.err = v.qt.sources.findColumn(t)
@@ -220,6 +228,23 @@ func (v *nameResolutionVisitor) VisitPre(expr parser.Expr) (recurse bool, newNod
 
 func (*nameResolutionVisitor) VisitPost(expr parser.Expr) parser.Expr { return expr }
 
+// arraySubscripts returns the given selector as array subscripts, if it only
+// consists of array subscripts.
+func arraySubscripts(selector parser.NameParts) (parser.ArraySubscripts, bool) {
+	if len(selector) == 0 {
+		return nil, false
+	}
+	subscripts := make(parser.ArraySubscripts, len(selector))
+	for i, p := range selector {
+		subscript, ok := p.(*parser.ArraySubscript)
+		if !ok {
+			return nil, false
+		}
+		subscripts[i] = subscript
+	}
+	return subscripts, true
+}
+
 func (s *selectNode) resolveNames(expr parser.Expr) (parser.Expr, error) {
 	var v *nameResolutionVisitor
 	if s.planner != nil {
@@ -0,0 +1,126 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/pkg/errors"
+)
+
+var _ parser.SequenceOperators = &planner{}
+
+// getSequenceDesc looks up the sequence with the given name, which is
+// qualified with the session database if needed, and checks that the user
+// has the given privilege on it.
+func (p *planner) getSequenceDesc(
+	seqName string, priv privilege.Kind,
+) (*sqlbase.TableDescriptor, error) {
+	tn, err := parser.ParseTableNameTraditional(seqName)
+	if err != nil {
+		return nil, err
+	}
+	if err := tn.QualifyWithDatabase(p.session.Database); err != nil {
+		return nil, err
+	}
+	desc, err := p.getTableLease(tn)
+	if err != nil {
+		return nil, err
+	}
+	if !desc.IsSequence() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "sequence")
+	}
+	if err := p.checkPrivilege(desc, priv); err != nil {
+		return nil, err
+	}
+	return desc, nil
+}
+
+// IncrementSequence implements the parser.SequenceOperators interface.
+// The increment is not transactional: as in postgres, the values obtained
+// by a transaction are not given back if it aborts, so that concurrent
+// transactions using the sequence never wait on each other.
+// Privileges: UPDATE on sequence.
+func (p *planner) IncrementSequence(seqName string) (int64, error) {
+	desc, err := p.getSequenceDesc(seqName, privilege.UPDATE)
+	if err != nil {
+		return 0, err
+	}
+	opts := desc.SequenceOpts
+	kv, err := p.leaseMgr.db.Inc(sqlbase.MakeSequenceKey(desc.ID), opts.Increment)
+	if err != nil {
+		return 0, err
+	}
+	val := kv.ValueInt()
+	if val > opts.MaxValue {
+		return 0, errors.Errorf("reached maximum value of sequence %q (%d)", desc.Name, opts.MaxValue)
+	}
+	if val < opts.MinValue {
+		return 0, errors.Errorf("reached minimum value of sequence %q (%d)", desc.Name, opts.MinValue)
+	}
+	p.session.setSequenceValue(desc.ID, val)
+	return val, nil
+}
+
+// GetLatestValueInSessionForSequence implements the parser.SequenceOperators
+// interface.
+// Privileges: SELECT on sequence.
+func (p *planner) GetLatestValueInSessionForSequence(seqName string) (int64, error) {
+	desc, err := p.getSequenceDesc(seqName, privilege.SELECT)
+	if err != nil {
+		return 0, err
+	}
+	val, ok := p.session.sequenceValues[desc.ID]
+	if !ok {
+		return 0, errors.Errorf("currval of sequence %q is not yet defined in this session", desc.Name)
+	}
+	return val, nil
+}
+
+// SetSequenceValue implements the parser.SequenceOperators interface. Like
+// IncrementSequence, it is not transactional.
+// Privileges: UPDATE on sequence.
+func (p *planner) SetSequenceValue(seqName string, newVal int64, isCalled bool) error {
+	desc, err := p.getSequenceDesc(seqName, privilege.UPDATE)
+	if err != nil {
+		return err
+	}
+	opts := desc.SequenceOpts
+	if newVal < opts.MinValue || newVal > opts.MaxValue {
+		return errors.Errorf("value %d is out of bounds for sequence %q (%d..%d)",
+			newVal, desc.Name, opts.MinValue, opts.MaxValue)
+	}
+	storedVal := newVal
+	if !isCalled {
+		storedVal -= opts.Increment
+	}
+	if err := p.leaseMgr.db.Put(sqlbase.MakeSequenceKey(desc.ID), storedVal); err != nil {
+		return err
+	}
+	if isCalled {
+		p.session.setSequenceValue(desc.ID, newVal)
+	}
+	return nil
+}
+
+// setSequenceValue records the value most recently obtained from a sequence
+// in the session, for currval().
+func (s *Session) setSequenceValue(id sqlbase.ID, val int64) {
+	if s.sequenceValues == nil {
+		s.sequenceValues = make(map[sqlbase.ID]int64)
+	}
+	s.sequenceValues[id] = val
+}
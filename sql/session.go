@@ -28,16 +28,26 @@ import (
 	"github.com/cockroachdb/cockroach/base"
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/sql/mon"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/util/envutil"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/cockroachdb/cockroach/util/retry"
+	"github.com/cockroachdb/cockroach/util/syncutil"
 	"github.com/cockroachdb/cockroach/util/tracing"
 	basictracer "github.com/opentracing/basictracer-go"
 	opentracing "github.com/opentracing/opentracing-go"
 )
 
-var traceSQL = envutil.EnvOrDefaultBool("trace_sql", false)
+// traceSQL causes all the SQL statements to be traced. The env var only
+// sets its default.
+var traceSQL = settings.RegisterBoolSetting(
+	"sql.trace.log_statements",
+	"trace all the SQL statements to the log",
+	envutil.EnvOrDefaultBool("trace_sql", false),
+)
 
 // Session contains the state of a SQL client connection.
 // Create instances using NewSession().
@@ -58,6 +68,64 @@ type Session struct {
 	Trace                 trace.Trace
 	context               context.Context
 	cancel                context.CancelFunc
+
+	// StatementTimeout is the duration after which a statement is canceled.
+	// Zero means no timeout.
+	StatementTimeout time.Duration
+	// IdleInTxnSessionTimeout is the duration after which a transaction left
+	// open and idle by the client is aborted. Zero means no timeout.
+	IdleInTxnSessionTimeout time.Duration
+
+	// Tracing is set by SET tracing = on. The trace spans of the
+	// transactions started while it is set are accumulated in traceSpans,
+	// which are returned by SHOW TRACE FOR SESSION.
+	Tracing    bool
+	traceSpans []basictracer.RawSpan
+
+	// Vectorize is set by SET vectorize = on. The queries which the
+	// vectorized engine supports are then run by it; see maybeVectorize.
+	Vectorize bool
+
+	// copyFrom is set while a COPY FROM STDIN is in progress. The data sent
+	// by the client is then handed to it instead of being run as statements.
+	copyFrom *copyMachine
+
+	// memMonitor accounts for the memory used by the session, reserved from
+	// the executor's pool.
+	memMonitor mon.MemoryMonitor
+	// resultsMemAcc accounts for the result rows buffered until they are
+	// sent to the client.
+	resultsMemAcc mon.MemoryAccount
+
+	// ClientAddr is the address of the client that opened the session, if
+	// any.
+	ClientAddr string
+
+	// sequenceValues holds, for each sequence used in the session, the value
+	// most recently obtained from it. It is used by currval().
+	sequenceValues map[sqlbase.ID]int64
+
+	// tempSessionID identifies the session as the owner of the temporary
+	// tables it creates. It is set when the first one is created.
+	tempSessionID string
+	// tempTableIDs are the IDs of the temporary tables created by the
+	// session, dropped when the session finishes.
+	tempTableIDs []sqlbase.ID
+	tempSessions *temporarySessions
+
+	mu struct {
+		syncutil.Mutex
+		// ActiveQueries contains the queries currently being executed in the
+		// session, keyed by query ID.
+		ActiveQueries map[string]*queryMeta
+
+		// idleTxnTimer is armed while the session is idle in a transaction,
+		// if IdleInTxnSessionTimeout is set.
+		idleTxnTimer *time.Timer
+		// idleTxnTimedOut is set when idleTxnTimer fires; the KV transaction
+		// has then been rolled back.
+		idleTxnTimedOut bool
+	}
 }
 
 // SessionArgs contains arguments for creating a new Session with NewSession().
@@ -82,13 +150,19 @@ func NewSession(ctx context.Context, args SessionArgs, e *Executor, remote net.A
 		databaseCache: cache,
 		session:       s,
 		execCtx:       &e.ctx,
+		sqlStats:      &e.sqlStats,
 	}
+	s.tempSessions = &e.tempSessions
+	s.memMonitor = mon.MakeMonitorWithPool("session", &e.memMonitor)
+	s.resultsMemAcc = s.memMonitor.MakeAccount()
 	s.PreparedStatements = makePreparedStatements(s)
 	s.PreparedPortals = makePreparedPortals(s)
 	remoteStr := ""
 	if remote != nil {
 		remoteStr = remote.String()
 	}
+	s.ClientAddr = remoteStr
+	s.mu.ActiveQueries = make(map[string]*queryMeta)
 	s.Trace = trace.New("sql."+args.User, remoteStr)
 	s.Trace.SetMaxEvents(100)
 	s.context, s.cancel = context.WithCancel(ctx)
@@ -101,13 +175,60 @@ func (s *Session) Finish() {
 	// session abruptly in the middle of a transaction, or, until #7648 is
 	// addressed, there might be leases accumulated by preparing statements.
 	s.planner.releaseLeases()
+	s.finishTemporaryTables()
 	if s.Trace != nil {
 		s.Trace.Finish()
 		s.Trace = nil
 	}
+	s.stopIdleTxnTimer()
+	s.memMonitor.Stop(s.context)
 	s.cancel()
 }
 
+// startIdleTxnTimer arms the idle-in-transaction timer if the session is
+// left in a transaction at the end of a request and
+// IdleInTxnSessionTimeout is set. When it fires, the KV transaction is rolled
+// back so that it stops holding on to its intents; the SQL transaction is
+// cleaned up by the next request, through stopIdleTxnTimer.
+func (s *Session) startIdleTxnTimer() {
+	// The session isn't idle while the client sends the data of a COPY; the
+	// timer is armed once it ends.
+	if s.IdleInTxnSessionTimeout == 0 || s.TxnState.State == NoTxn || s.copyFrom != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var timer *time.Timer
+	timer = time.AfterFunc(s.IdleInTxnSessionTimeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.mu.idleTxnTimer != timer {
+			// The timer was stopped concurrently.
+			return
+		}
+		s.mu.idleTxnTimedOut = true
+		if txn := s.TxnState.txn; txn != nil && !txn.IsFinalized() {
+			txn.CleanupOnError(sqlbase.NewIdleInTxnSessionTimeoutError())
+		}
+	})
+	s.mu.idleTxnTimer = timer
+}
+
+// stopIdleTxnTimer disarms the timer armed by startIdleTxnTimer. It returns
+// whether the timer had fired, in which case the transaction was aborted.
+func (s *Session) stopIdleTxnTimer() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.idleTxnTimer == nil {
+		return false
+	}
+	s.mu.idleTxnTimer.Stop()
+	s.mu.idleTxnTimer = nil
+	timedOut := s.mu.idleTxnTimedOut
+	s.mu.idleTxnTimedOut = false
+	return timedOut
+}
+
 // Ctx returns the current context for the session. If there is an active
 // transaction it returns the transaction context, otherwise it returns the
 // session context.
@@ -161,6 +282,10 @@ type txnState struct {
 	// except it's reset in between client round trips.
 	autoRetry bool
 
+	// autoRetryCounter keeps track of the number of automatic retries of the
+	// current batch of statements.
+	autoRetryCounter int
+
 	// A COMMIT statement has been processed. Useful for allowing the txn to
 	// survive retriable errors if it will be auto-retried (BEGIN; ... COMMIT; in
 	// the same batch), but not if the error needs to be reported to the user.
@@ -168,10 +293,23 @@ type txnState struct {
 
 	// The schema change closures to run when this txn is done.
 	schemaChangers schemaChangerCollection
+
+	// savepoints are the savepoints set in the txn, other than the restart
+	// savepoint, innermost last. While there are any, a non-retriable error
+	// doesn't clean up the KV txn, so that it can be rolled back to one of
+	// them.
+	savepoints []savepoint
+
+	// tableReplacements are the tables replaced by TRUNCATE in the txn.
+	tableReplacements []tableReplacement
+
 	// TODO(andrei): this is the same as Session.Trace. Consider removing this and
 	// passing the Session along everywhere the trace is needed.
 	tr trace.Trace
 	sp opentracing.Span
+	// sessionSpans, if set, is where the trace spans of the txn are
+	// accumulated for SHOW TRACE FOR SESSION when the txn is done.
+	sessionSpans *[]basictracer.RawSpan
 
 	// The timestamp to report for current_timestamp(), now() etc.
 	// This must be constant for the lifetime of a SQL transaction.
@@ -188,7 +326,10 @@ func (ts *txnState) reset(ctx context.Context, e *Executor, s *Session) {
 	// Discard the old schemaChangers, if any.
 	ts.schemaChangers = schemaChangerCollection{}
 
-	if traceSQL {
+	if s.Tracing {
+		ts.sessionSpans = &s.traceSpans
+	}
+	if traceSQL.Get() || s.Tracing {
 		sp, err := tracing.JoinOrNewSnowball("coordinator", nil, func(sp basictracer.RawSpan) {
 			ts.txn.CollectedSpans = append(ts.txn.CollectedSpans, sp)
 		})
@@ -201,6 +342,101 @@ func (ts *txnState) reset(ctx context.Context, e *Executor, s *Session) {
 	}
 }
 
+// savepoint is a savepoint set by a SAVEPOINT statement.
+type savepoint struct {
+	name  string
+	token client.SavepointToken
+	// numSchemaChangers is the number of schema changers that had been queued
+	// when the savepoint was set.
+	numSchemaChangers int
+	// numTableReplacements is the number of tables that had been replaced
+	// when the savepoint was set.
+	numTableReplacements int
+}
+
+// tableReplacement records that the table oldID has been replaced by the
+// empty table newID in the given epoch of the txn.
+type tableReplacement struct {
+	epoch        uint32
+	oldID, newID sqlbase.ID
+}
+
+func (ts *txnState) setSavepoint(name string) {
+	ts.savepoints = append(ts.savepoints, savepoint{
+		name:                 sqlbase.ReNormalizeName(name),
+		token:                ts.txn.SetSavepoint(),
+		numSchemaChangers:    len(ts.schemaChangers.schemaChangers),
+		numTableReplacements: len(ts.tableReplacements),
+	})
+}
+
+// findSavepoint returns the index of the innermost savepoint with the given
+// name.
+func (ts *txnState) findSavepoint(name string) (int, error) {
+	normName := sqlbase.ReNormalizeName(name)
+	for i := len(ts.savepoints) - 1; i >= 0; i-- {
+		if ts.savepoints[i].name == normName {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("savepoint %s does not exist", name)
+}
+
+// truncateSavepoints releases the savepoints set after the i-th one.
+func (ts *txnState) truncateSavepoints(i int) {
+	for j := len(ts.savepoints) - 1; j > i; j-- {
+		ts.txn.ReleaseSavepoint()
+	}
+	ts.savepoints = ts.savepoints[:i+1]
+}
+
+// releaseSavepoint releases the named savepoint and the ones set after it.
+// The writes performed since are kept.
+func (ts *txnState) releaseSavepoint(name string) error {
+	i, err := ts.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	ts.truncateSavepoints(i)
+	ts.txn.ReleaseSavepoint()
+	ts.savepoints = ts.savepoints[:i]
+	return nil
+}
+
+// rollbackToSavepoint undoes the writes performed since the named savepoint
+// was set and releases the savepoints set after it. The savepoint itself
+// remains set.
+func (ts *txnState) rollbackToSavepoint(name string) error {
+	i, err := ts.findSavepoint(name)
+	if err != nil {
+		return err
+	}
+	ts.truncateSavepoints(i)
+	sp := ts.savepoints[i]
+	if err := ts.txn.RollbackToSavepoint(sp.token); err != nil {
+		return err
+	}
+	// The schema changes queued since the savepoint were undone along with
+	// the descriptor writes.
+	ts.schemaChangers.schemaChangers = ts.schemaChangers.schemaChangers[:sp.numSchemaChangers]
+	ts.tableReplacements = ts.tableReplacements[:sp.numTableReplacements]
+	return nil
+}
+
+// clearSavepoints releases all the savepoints.
+func (ts *txnState) clearSavepoints() {
+	if ts.txn != nil {
+		ts.txn.ClearSavepoints()
+	}
+	ts.savepoints = nil
+}
+
+// canRollbackToSavepoint returns true if the txn is Aborted but its KV txn
+// has been kept open so that it can be rolled back to a savepoint.
+func (ts *txnState) canRollbackToSavepoint() bool {
+	return ts.State == Aborted && ts.txn != nil
+}
+
 func (ts *txnState) willBeRetried() bool {
 	return ts.autoRetry || ts.retryIntent
 }
@@ -221,14 +457,20 @@ func (ts *txnState) resetStateAndTxn(state TxnStateEnum) {
 }
 
 func (ts *txnState) dumpTrace() {
-	if traceSQL && ts.txn != nil {
+	if ts.sp != nil && ts.txn != nil {
 		ts.sp.Finish()
-		dump := tracing.FormatRawSpans(ts.txn.CollectedSpans)
-		if len(dump) > 0 {
-			log.Infof(context.Background(), "%s\n%s", ts.txn.Proto.ID, dump)
+		if ts.sessionSpans != nil {
+			*ts.sessionSpans = append(*ts.sessionSpans, ts.txn.CollectedSpans...)
+		}
+		if traceSQL.Get() {
+			dump := tracing.FormatRawSpans(ts.txn.CollectedSpans)
+			if len(dump) > 0 {
+				log.Infof(context.Background(), "%s\n%s", ts.txn.Proto.ID, dump)
+			}
 		}
 	}
 	ts.sp = nil
+	ts.sessionSpans = nil
 }
 
 // updateStateAndCleanupOnErr updates txnState based on the type of error that we
@@ -239,7 +481,14 @@ func (ts *txnState) updateStateAndCleanupOnErr(err error, e *Executor) {
 	if err == nil {
 		panic("updateStateAndCleanupOnErr called with no error")
 	}
-	if _, ok := err.(*roachpb.RetryableTxnError); !ok || !ts.willBeRetried() {
+	_, retryable := err.(*roachpb.RetryableTxnError)
+	if !retryable && len(ts.savepoints) > 0 {
+		// The txn can still be rolled back to a savepoint, so don't clean it up.
+		ts.State = Aborted
+		return
+	}
+	ts.clearSavepoints()
+	if !retryable || !ts.willBeRetried() {
 		// We can't or don't want to retry this txn, so the txn is over.
 		e.txnAbortCount.Inc(1)
 		ts.txn.CleanupOnError(err)
@@ -309,6 +558,10 @@ func (scc *schemaChangerCollection) execSchemaChanges(
 	for _, scEntry := range scc.schemaChangers {
 		sc := &scEntry.sc
 		sc.db = *e.ctx.DB
+		sc.jobRegistry = e.ctx.JobRegistry
+		sc.backfillChunkNotification = e.ctx.TestingKnobs.SchemaChangersBackfillChunkNotification
+		sc.distSQLSrv = e.ctx.DistSQLSrv
+		sc.gossip = e.ctx.Gossip
 		for r := retry.Start(base.DefaultRetryOptions()); r.Next(); {
 			if done, err := sc.IsDone(); err != nil {
 				log.Warning(e.ctx.Context, err)
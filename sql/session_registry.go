@@ -0,0 +1,220 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/server/serverpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/pkg/errors"
+)
+
+// SessionRegistry keeps track of the client sessions open on a node, so that
+// the queries they are running can be inspected (e.g. by SHOW QUERIES).
+type SessionRegistry struct {
+	syncutil.Mutex
+	store map[*Session]struct{}
+}
+
+// NewSessionRegistry creates a new, empty SessionRegistry.
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{store: make(map[*Session]struct{})}
+}
+
+// Register adds a session to the registry.
+func (r *SessionRegistry) Register(s *Session) {
+	r.Lock()
+	r.store[s] = struct{}{}
+	r.Unlock()
+}
+
+// Deregister removes a session from the registry.
+func (r *SessionRegistry) Deregister(s *Session) {
+	r.Lock()
+	delete(r.store, s)
+	r.Unlock()
+}
+
+// ActiveQueries returns the queries currently running in all the registered
+// sessions, ordered by start time.
+func (r *SessionRegistry) ActiveQueries(nodeID roachpb.NodeID) []serverpb.ActiveQuery {
+	r.Lock()
+	defer r.Unlock()
+	var queries []serverpb.ActiveQuery
+	for s := range r.store {
+		queries = append(queries, s.activeQueries(nodeID)...)
+	}
+	sort.Sort(activeQueriesByStart(queries))
+	return queries
+}
+
+// CancelQuery cancels the query with the given ID, if it is running in one
+// of the registered sessions. Users other than root can only cancel their own
+// queries.
+func (r *SessionRegistry) CancelQuery(queryID string, username string) error {
+	r.Lock()
+	defer r.Unlock()
+	for s := range r.store {
+		if found, err := s.cancelQuery(queryID, username); found {
+			return err
+		}
+	}
+	return errors.Errorf("query ID %s not found", queryID)
+}
+
+type activeQueriesByStart []serverpb.ActiveQuery
+
+func (q activeQueriesByStart) Len() int           { return len(q) }
+func (q activeQueriesByStart) Less(i, j int) bool { return q[i].Start < q[j].Start }
+func (q activeQueriesByStart) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+// queryMeta holds the information about a query running in a session that is
+// exposed by SHOW QUERIES.
+type queryMeta struct {
+	start time.Time
+	stmt  parser.Statement
+	// cancel cancels the context the query runs under.
+	cancel context.CancelFunc
+
+	// phase, canceled and timedOut are protected by the owning Session's mu.
+	phase    serverpb.ActiveQuery_Phase
+	canceled bool
+	// timedOut is set if the query was canceled because it exceeded the
+	// session's statement timeout.
+	timedOut bool
+}
+
+// makeQueryID generates a cluster-wide unique ID for a query, out of an HLC
+// timestamp taken on the gateway node and that node's ID. The node ID is
+// encoded in the last 8 hex digits so that it can be recovered from the ID.
+func makeQueryID(ts hlc.Timestamp, nodeID roachpb.NodeID) string {
+	return fmt.Sprintf("%016x%08x%08x", uint64(ts.WallTime), uint32(ts.Logical), uint32(nodeID))
+}
+
+// nodeIDFromQueryID extracts the ID of the node a query runs on out of a
+// query ID generated by makeQueryID.
+func nodeIDFromQueryID(queryID string) (roachpb.NodeID, error) {
+	if len(queryID) != 32 {
+		return 0, errors.Errorf("invalid query ID %q", queryID)
+	}
+	nodeID, err := strconv.ParseUint(queryID[24:], 16, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid query ID %q", queryID)
+	}
+	return roachpb.NodeID(nodeID), nil
+}
+
+// addActiveQuery registers a query as running in the session. cancel, if
+// set, cancels the context of the query.
+func (s *Session) addActiveQuery(
+	id string, stmt parser.Statement, start time.Time, cancel context.CancelFunc,
+) *queryMeta {
+	q := &queryMeta{
+		start:  start,
+		stmt:   stmt,
+		cancel: cancel,
+		phase:  serverpb.ActiveQuery_PREPARING,
+	}
+	s.mu.Lock()
+	s.mu.ActiveQueries[id] = q
+	s.mu.Unlock()
+	return q
+}
+
+// removeActiveQuery deregisters a query previously added with
+// addActiveQuery. It returns whether the query was canceled, and if so
+// whether that was because of the statement timeout.
+func (s *Session) removeActiveQuery(id string) (canceled bool, timedOut bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.mu.ActiveQueries[id]
+	delete(s.mu.ActiveQueries, id)
+	if q == nil {
+		return false, false
+	}
+	return q.canceled, q.timedOut
+}
+
+// cancelQuery cancels the query with the given ID if it is running in the
+// session. It returns whether the query was found.
+func (s *Session) cancelQuery(id string, username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.mu.ActiveQueries[id]
+	if !ok {
+		return false, nil
+	}
+	if username != security.RootUser && username != s.User {
+		return true, errors.Errorf("only %s or %s can cancel query %s",
+			security.RootUser, s.User, id)
+	}
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.canceled = true
+	return true, nil
+}
+
+// timeoutQuery cancels the query with the given ID, if it is still running in
+// the session, because it exceeded the statement timeout.
+func (s *Session) timeoutQuery(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.mu.ActiveQueries[id]
+	if !ok {
+		return
+	}
+	if q.cancel != nil {
+		q.cancel()
+	}
+	q.canceled = true
+	q.timedOut = true
+}
+
+// setQueryPhase updates the phase of a query running in the session.
+func (s *Session) setQueryPhase(q *queryMeta, phase serverpb.ActiveQuery_Phase) {
+	s.mu.Lock()
+	q.phase = phase
+	s.mu.Unlock()
+}
+
+// activeQueries returns the queries currently running in the session.
+func (s *Session) activeQueries(nodeID roachpb.NodeID) []serverpb.ActiveQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queries := make([]serverpb.ActiveQuery, 0, len(s.mu.ActiveQueries))
+	for id, q := range s.mu.ActiveQueries {
+		queries = append(queries, serverpb.ActiveQuery{
+			ID:            id,
+			NodeID:        nodeID,
+			Username:      s.User,
+			ClientAddress: s.ClientAddr,
+			SQL:           q.stmt.String(),
+			Start:         q.start.UnixNano(),
+			Phase:         q.phase,
+		})
+	}
+	return queries
+}
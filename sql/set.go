@@ -19,6 +19,7 @@ package sql
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -78,6 +79,39 @@ func (p *planner) Set(n *parser.Set) (planNode, error) {
 			return nil, fmt.Errorf("%s: \"%s\" is not in (%q, %q)", name, s, parser.Modern, parser.Traditional)
 		}
 
+	case `STATEMENT_TIMEOUT`:
+		timeout, err := p.getTimeoutVal(name, typedValues)
+		if err != nil {
+			return nil, err
+		}
+		p.session.StatementTimeout = timeout
+
+	case `IDLE_IN_TRANSACTION_SESSION_TIMEOUT`:
+		timeout, err := p.getTimeoutVal(name, typedValues)
+		if err != nil {
+			return nil, err
+		}
+		p.session.IdleInTxnSessionTimeout = timeout
+
+	case `TRACING`:
+		on, err := p.getOnOffVal(name, typedValues)
+		if err != nil {
+			return nil, err
+		}
+		if on && !p.session.Tracing {
+			// Start a fresh trace. It takes effect with the next
+			// transaction.
+			p.session.traceSpans = nil
+		}
+		p.session.Tracing = on
+
+	case `VECTORIZE`:
+		on, err := p.getOnOffVal(name, typedValues)
+		if err != nil {
+			return nil, err
+		}
+		p.session.Vectorize = on
+
 	case `EXTRA_FLOAT_DIGITS`:
 		// These settings are sent by the JDBC driver but we silently ignore them.
 
@@ -103,6 +137,65 @@ func (p *planner) getStringVal(name string, values []parser.TypedExpr) (string,
 	return string(*s), nil
 }
 
+// getOnOffVal evaluates the value of a boolean session variable, given as
+// on/off or as a boolean.
+func (p *planner) getOnOffVal(name string, values []parser.TypedExpr) (bool, error) {
+	if len(values) != 1 {
+		return false, fmt.Errorf("%s: requires a single value", name)
+	}
+	val, err := values[0].Eval(&p.evalCtx)
+	if err != nil {
+		return false, err
+	}
+	switch t := val.(type) {
+	case *parser.DBool:
+		return bool(*t), nil
+	case *parser.DString:
+		switch strings.ToUpper(string(*t)) {
+		case "ON", "TRUE":
+			return true, nil
+		case "OFF", "FALSE":
+			return false, nil
+		}
+		return false, fmt.Errorf("%s: \"%s\" is not in (\"on\", \"off\")", name, string(*t))
+	default:
+		return false, fmt.Errorf("%s: requires a boolean value: %s is a %s",
+			name, values[0], val.Type())
+	}
+}
+
+// getTimeoutVal evaluates the value of a timeout session variable. As in
+// postgres, it is either an integer number of milliseconds or a string
+// holding a duration with a unit (e.g. '5s'). Zero disables the timeout.
+func (p *planner) getTimeoutVal(name string, values []parser.TypedExpr) (time.Duration, error) {
+	if len(values) != 1 {
+		return 0, fmt.Errorf("%s: requires a single value", name)
+	}
+	val, err := values[0].Eval(&p.evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	var timeout time.Duration
+	switch t := val.(type) {
+	case *parser.DInt:
+		timeout = time.Duration(*t) * time.Millisecond
+	case *parser.DString:
+		s := string(*t)
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+			timeout = time.Duration(ms) * time.Millisecond
+		} else if timeout, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("%s: invalid timeout %q", name, s)
+		}
+	default:
+		return 0, fmt.Errorf("%s: requires an integer or string value: %s is a %s",
+			name, values[0], val.Type())
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("%s: timeout cannot be negative", name)
+	}
+	return timeout, nil
+}
+
 func (p *planner) SetDefaultIsolation(n *parser.SetDefaultIsolation) (planNode, error) {
 	switch n.Isolation {
 	case parser.SerializableIsolation:
@@ -0,0 +1,244 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/settings/cluster"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/pkg/errors"
+)
+
+// SetClusterSetting sets the value of a cluster setting, or resets it to its
+// default. The new value is stored in the system.settings table and reaches
+// all the nodes through the system config gossip.
+// Privileges: root user.
+func (p *planner) SetClusterSetting(n *parser.SetClusterSetting) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to SET CLUSTER SETTING", security.RootUser)
+	}
+	name := strings.ToLower(n.Name)
+	s, ok := settings.Lookup(name)
+	if !ok {
+		return nil, errors.Errorf("unknown cluster setting %q", name)
+	}
+
+	if n.Value == nil {
+		if name == cluster.VersionSettingName {
+			return nil, errors.New("the cluster version cannot be reset")
+		}
+		if _, err := p.exec("DELETE FROM system.settings WHERE name = $1", name); err != nil {
+			return nil, err
+		}
+		return &emptyNode{}, nil
+	}
+
+	encoded, err := p.encodeClusterSetting(name, s, n.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := settings.Validate(name, encoded); err != nil {
+		return nil, err
+	}
+	if _, err := p.exec(
+		"UPSERT INTO system.settings (name, value, lastUpdated, valueType) VALUES ($1, $2, NOW(), $3)",
+		name, encoded, s.Typ(),
+	); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// encodeClusterSetting evaluates the value given to a setting and returns it
+// encoded as stored in the system.settings table.
+func (p *planner) encodeClusterSetting(
+	name string, s settings.Setting, expr parser.Expr,
+) (string, error) {
+	var desired parser.Datum
+	switch s.(type) {
+	case *settings.BoolSetting:
+		desired = parser.TypeBool
+	case *settings.IntSetting:
+		desired = parser.TypeInt
+	case *settings.FloatSetting:
+		desired = parser.TypeFloat
+	case *settings.DurationSetting:
+		desired = parser.TypeInterval
+	default:
+		desired = parser.TypeString
+	}
+	typedValue, err := parser.TypeCheck(expr, nil, desired)
+	if err != nil {
+		return "", err
+	}
+	d, err := typedValue.Eval(&p.evalCtx)
+	if err != nil {
+		return "", err
+	}
+
+	switch s.(type) {
+	case *settings.BoolSetting:
+		b, err := p.getOnOffVal(name, []parser.TypedExpr{d})
+		if err != nil {
+			return "", err
+		}
+		return settings.EncodeBool(b), nil
+	case *settings.IntSetting:
+		if i, ok := d.(*parser.DInt); ok {
+			return settings.EncodeInt(int64(*i)), nil
+		}
+	case *settings.FloatSetting:
+		switch t := d.(type) {
+		case *parser.DFloat:
+			return settings.EncodeFloat(float64(*t)), nil
+		case *parser.DInt:
+			return settings.EncodeFloat(float64(*t)), nil
+		case *parser.DDecimal:
+			f, err := strconv.ParseFloat(t.Dec.String(), 64)
+			if err != nil {
+				return "", err
+			}
+			return settings.EncodeFloat(f), nil
+		}
+	case *settings.DurationSetting:
+		switch t := d.(type) {
+		case *parser.DInterval:
+			if t.Months != 0 {
+				return "", errors.Errorf("%s: cannot use an interval with months", name)
+			}
+			return settings.EncodeDuration(
+				time.Duration(t.Days)*24*time.Hour + time.Duration(t.Nanos)), nil
+		case *parser.DString:
+			dur, err := time.ParseDuration(string(*t))
+			if err != nil {
+				return "", errors.Wrapf(err, "%s", name)
+			}
+			return settings.EncodeDuration(dur), nil
+		}
+	case *settings.EnumSetting:
+		switch t := d.(type) {
+		case *parser.DString:
+			return string(*t), nil
+		case *parser.DInt:
+			return settings.EncodeInt(int64(*t)), nil
+		}
+	}
+	if s, ok := d.(*parser.DString); ok {
+		// The setting parses the string when validating it.
+		return string(*s), nil
+	}
+	return "", errors.Errorf("%s: cannot use %s of type %s", name, expr, d.Type())
+}
+
+// ShowClusterSetting shows the current value of a cluster setting on this
+// node, or of all of them.
+// Privileges: None.
+func (p *planner) ShowClusterSetting(n *parser.ShowClusterSetting) (planNode, error) {
+	name := strings.ToLower(n.Name)
+	if name == "all" {
+		v := &valuesNode{
+			columns: []ResultColumn{
+				{Name: "Name", Typ: parser.TypeString},
+				{Name: "Value", Typ: parser.TypeString},
+				{Name: "Type", Typ: parser.TypeString},
+				{Name: "Description", Typ: parser.TypeString},
+			},
+		}
+		for _, k := range settings.Keys() {
+			s, _ := settings.Lookup(k)
+			v.rows = append(v.rows, []parser.Datum{
+				parser.NewDString(k),
+				parser.NewDString(s.String()),
+				parser.NewDString(s.Typ()),
+				parser.NewDString(s.Description()),
+			})
+		}
+		return v, nil
+	}
+
+	s, ok := settings.Lookup(name)
+	if !ok {
+		return nil, errors.Errorf("unknown cluster setting %q", name)
+	}
+	v := &valuesNode{columns: []ResultColumn{{Name: name, Typ: parser.TypeString}}}
+	v.rows = append(v.rows, []parser.Datum{parser.NewDString(s.String())})
+	return v, nil
+}
+
+// refreshSettings updates the cluster settings from the rows of the
+// system.settings table in the system config.
+func refreshSettings(ctx context.Context, cfg config.SystemConfig) {
+	desc := &sqlbase.SettingsTable
+	colIdxMap := make(map[sqlbase.ColumnID]int, len(desc.Columns))
+	valNeededForCol := make([]bool, len(desc.Columns))
+	for i, col := range desc.Columns {
+		colIdxMap[col.ID] = i
+		valNeededForCol[i] = true
+	}
+	var fetcher sqlbase.RowFetcher
+	if err := fetcher.Init(
+		desc, colIdxMap, &desc.PrimaryIndex, false /* reverse */, false, /* isSecondaryIndex */
+		desc.Columns, valNeededForCol,
+	); err != nil {
+		log.Errorf(ctx, "unable to refresh cluster settings: %s", err)
+		return
+	}
+
+	prefix := sqlbase.MakeIndexKeyPrefix(desc, desc.PrimaryIndex.ID)
+	var kvs []client.KeyValue
+	for i := range cfg.Values {
+		if bytes.HasPrefix(cfg.Values[i].Key, prefix) {
+			kvs = append(kvs, client.KeyValue{Key: cfg.Values[i].Key, Value: &cfg.Values[i].Value})
+		}
+	}
+	if err := fetcher.StartScanFrom(kvs); err != nil {
+		log.Errorf(ctx, "unable to refresh cluster settings: %s", err)
+		return
+	}
+
+	u := settings.MakeUpdater()
+	for {
+		row, err := fetcher.NextRow()
+		if err != nil {
+			log.Errorf(ctx, "unable to refresh cluster settings: %s", err)
+			return
+		}
+		if row == nil {
+			break
+		}
+		name := string(*row[0].(*parser.DString))
+		value := string(*row[1].(*parser.DString))
+		var typ string
+		if t, ok := row[3].(*parser.DString); ok {
+			typ = string(*t)
+		}
+		if err := u.Set(name, value, typ); err != nil {
+			log.Warningf(ctx, "unable to apply cluster setting %q: %s", name, err)
+		}
+	}
+	u.Done()
+}
@@ -0,0 +1,60 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/sqlutils"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/pkg/errors"
+)
+
+var testIntSetting = settings.RegisterIntSetting("sql.test.int", "for testing", 3)
+
+// TestClusterSettingGossip verifies that the values stored by SET CLUSTER
+// SETTING reach the settings of the nodes through the system config gossip.
+func TestClusterSettingGossip(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	r := sqlutils.MakeSQLRunner(t, sqlDB)
+	waitFor := func(expected int64) {
+		util.SucceedsSoon(t, func() error {
+			if v := testIntSetting.Get(); v != expected {
+				return errors.Errorf("expected %d, got %d", expected, v)
+			}
+			return nil
+		})
+		var shown string
+		if err := sqlDB.QueryRow(`SHOW CLUSTER SETTING sql.test.int`).Scan(&shown); err != nil {
+			t.Fatal(err)
+		}
+		if shown != settings.EncodeInt(expected) {
+			t.Errorf("expected %d to be shown, got %s", expected, shown)
+		}
+	}
+
+	r.Exec(`SET CLUSTER SETTING sql.test.int = 5`)
+	waitFor(5)
+	r.Exec(`SET CLUSTER SETTING sql.test.int = DEFAULT`)
+	waitFor(3)
+}
@@ -20,10 +20,15 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/server/serverpb"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/pkg/errors"
 )
@@ -44,6 +49,22 @@ func (p *planner) Show(n *parser.Show) (planNode, error) {
 	case `DEFAULT_TRANSACTION_ISOLATION`:
 		level := p.session.DefaultIsolationLevel.String()
 		v.rows = append(v.rows, []parser.Datum{parser.NewDString(level)})
+	case `STATEMENT_TIMEOUT`:
+		v.rows = append(v.rows, []parser.Datum{parser.NewDString(p.session.StatementTimeout.String())})
+	case `IDLE_IN_TRANSACTION_SESSION_TIMEOUT`:
+		v.rows = append(v.rows, []parser.Datum{parser.NewDString(p.session.IdleInTxnSessionTimeout.String())})
+	case `TRACING`:
+		tracing := "off"
+		if p.session.Tracing {
+			tracing = "on"
+		}
+		v.rows = append(v.rows, []parser.Datum{parser.NewDString(tracing)})
+	case `VECTORIZE`:
+		vectorize := "off"
+		if p.session.Vectorize {
+			vectorize = "on"
+		}
+		v.rows = append(v.rows, []parser.Datum{parser.NewDString(vectorize)})
 	case `TRANSACTION ISOLATION LEVEL`:
 		v.rows = append(v.rows, []parser.Datum{parser.NewDString(p.txn.Proto.Isolation.String())})
 	case `TRANSACTION PRIORITY`:
@@ -142,7 +163,29 @@ func (p *planner) ShowCreateTable(n *parser.ShowCreateTable) (planNode, error) {
 	}
 
 	var buf bytes.Buffer
-	fmt.Fprintf(&buf, "CREATE TABLE %s (", quoteNames(n.Table.String()))
+	if desc.IsView() {
+		v.rows = append(v.rows, []parser.Datum{
+			parser.NewDString(n.Table.String()),
+			parser.NewDString(showCreateView(n.Table.String(), desc)),
+		})
+		return v, nil
+	}
+	if desc.IsSequence() {
+		opts := desc.SequenceOpts
+		fmt.Fprintf(&buf, "CREATE SEQUENCE %s INCREMENT BY %d MINVALUE %d MAXVALUE %d START WITH %d",
+			quoteNames(n.Table.String()), opts.Increment, opts.MinValue, opts.MaxValue, opts.Start)
+		v.rows = append(v.rows, []parser.Datum{
+			parser.NewDString(n.Table.String()),
+			parser.NewDString(buf.String()),
+		})
+		return v, nil
+	}
+
+	buf.WriteString("CREATE ")
+	if desc.IsTemporary() {
+		buf.WriteString("TEMPORARY ")
+	}
+	fmt.Fprintf(&buf, "TABLE %s (", quoteNames(n.Table.String()))
 	var primary string
 	for i, col := range desc.VisibleColumns() {
 		if i != 0 {
@@ -164,11 +207,14 @@ func (p *planner) ShowCreateTable(n *parser.ShowCreateTable) (planNode, error) {
 			}
 			fmt.Fprintf(&buf, " DEFAULT %s", *col.DefaultExpr)
 		}
+		if col.IsComputed() {
+			fmt.Fprintf(&buf, " AS (%s) STORED", *col.ComputedExpr)
+		}
 		if len(desc.PrimaryIndex.ColumnIDs) > 0 && desc.PrimaryIndex.ColumnIDs[0] == col.ID {
 			// Only set primary if the primary key is on a visible column (not rowid).
 			primary = fmt.Sprintf(",\n\tCONSTRAINT %s PRIMARY KEY (%s)",
 				quoteNames(desc.PrimaryIndex.Name),
-				quoteNames(desc.PrimaryIndex.ColumnNames...),
+				showCreateIndexColumns(&desc.PrimaryIndex),
 			)
 		}
 	}
@@ -182,14 +228,30 @@ func (p *planner) ShowCreateTable(n *parser.ShowCreateTable) (planNode, error) {
 		if err != nil {
 			return nil, err
 		}
-		fmt.Fprintf(&buf, ",\n\t%sINDEX %s (%s)%s%s",
-			isUnique[idx.Unique],
+		var predicate string
+		if idx.IsPartial() {
+			predicate = fmt.Sprintf(" WHERE %s", *idx.Predicate)
+		}
+		kind := isUnique[idx.Unique]
+		if idx.IsInverted() {
+			kind = "INVERTED "
+		}
+		fmt.Fprintf(&buf, ",\n\t%sINDEX %s (%s)%s%s%s",
+			kind,
 			quoteNames(idx.Name),
-			quoteNames(idx.ColumnNames...),
+			showCreateIndexColumns(&idx),
 			storing,
 			interleave,
+			predicate,
 		)
 	}
+	for _, idx := range desc.AllNonDropIndexes() {
+		fk, err := p.showCreateForeignKey(desc, &idx)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fk)
+	}
 	for _, fam := range desc.Families {
 		activeColumnNames := make([]string, 0, len(fam.ColumnNames))
 		for i, colID := range fam.ColumnIDs {
@@ -217,6 +279,11 @@ func (p *planner) ShowCreateTable(n *parser.ShowCreateTable) (planNode, error) {
 		return nil, err
 	}
 	buf.WriteString(interleave)
+	partitioning, err := showCreatePartitioning(desc, &desc.PrimaryIndex)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteString(partitioning)
 
 	v.rows = append(v.rows, []parser.Datum{
 		parser.NewDString(n.Table.String()),
@@ -225,6 +292,112 @@ func (p *planner) ShowCreateTable(n *parser.ShowCreateTable) (planNode, error) {
 	return v, nil
 }
 
+// ShowCreateView returns a CREATE VIEW statement for the specified view in
+// Traditional syntax.
+// Privileges: Any privilege on view.
+func (p *planner) ShowCreateView(n *parser.ShowCreateView) (planNode, error) {
+	tn, err := n.View.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := p.mustGetTableDesc(tn)
+	if err != nil {
+		return nil, err
+	}
+	if !desc.IsView() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "view")
+	}
+	if err := p.anyPrivilege(desc); err != nil {
+		return nil, err
+	}
+
+	v := &valuesNode{
+		columns: []ResultColumn{
+			{Name: "View", Typ: parser.TypeString},
+			{Name: "CreateView", Typ: parser.TypeString},
+		},
+	}
+	v.rows = append(v.rows, []parser.Datum{
+		parser.NewDString(n.View.String()),
+		parser.NewDString(showCreateView(n.View.String(), desc)),
+	})
+	return v, nil
+}
+
+// showCreateView returns the CREATE VIEW statement for the view desc, named
+// name.
+func showCreateView(name string, desc *sqlbase.TableDescriptor) string {
+	columnNames := make([]string, len(desc.Columns))
+	for i, col := range desc.Columns {
+		columnNames[i] = col.Name
+	}
+	return fmt.Sprintf("CREATE VIEW %s (%s) AS %s",
+		quoteNames(name), quoteNames(columnNames...), desc.ViewQuery)
+}
+
+// showCreateIndexColumns returns the columns of the specified index, along
+// with their direction if it is not the default.
+func showCreateIndexColumns(idx *sqlbase.IndexDescriptor) string {
+	var buf bytes.Buffer
+	for i, name := range idx.ColumnNames {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(quoteNames(name))
+		if idx.ColumnDirections[i] == sqlbase.IndexDescriptor_DESC {
+			buf.WriteString(" DESC")
+		}
+	}
+	return buf.String()
+}
+
+// showCreateForeignKey returns a FOREIGN KEY constraint clause for the
+// specified index of desc, if applicable.
+func (p *planner) showCreateForeignKey(
+	desc *sqlbase.TableDescriptor, idx *sqlbase.IndexDescriptor,
+) (string, error) {
+	fk := idx.ForeignKey
+	if !fk.IsSet() {
+		return "", nil
+	}
+	refTable, err := sqlbase.GetTableDescFromID(p.txn, fk.Table)
+	if err != nil {
+		return "", err
+	}
+	refIdx, err := refTable.FindIndexByID(fk.Index)
+	if err != nil {
+		return "", err
+	}
+	refName := quoteNames(refTable.Name)
+	if refTable.ParentID != desc.ParentID {
+		// Tables in other databases must be qualified by their database name.
+		dbDesc := &sqlbase.Descriptor{}
+		if err := p.txn.GetProto(sqlbase.MakeDescMetadataKey(refTable.ParentID), dbDesc); err != nil {
+			return "", err
+		}
+		db := dbDesc.GetDatabase()
+		if db == nil {
+			return "", errors.Errorf("no database with ID %d found", refTable.ParentID)
+		}
+		tn := parser.TableName{
+			DatabaseName: parser.Name(db.Name),
+			TableName:    parser.Name(refTable.Name),
+		}
+		refName = parser.AsString(&tn)
+	}
+	// Only the prefix of the index matching the referenced columns makes up
+	// the foreign key.
+	numCols := len(refIdx.ColumnNames)
+	return fmt.Sprintf(",\n\tCONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)%s",
+		quoteNames(fk.Name),
+		quoteNames(idx.ColumnNames[:numCols]...),
+		refName,
+		quoteNames(refIdx.ColumnNames...),
+		parser.AsString(fkActionsToAST(fk)),
+	), nil
+}
+
 var isUnique = map[bool]string{true: "UNIQUE "}
 
 // quoteName quotes based on Traditional syntax and adds commas between names.
@@ -435,7 +608,8 @@ func (p *planner) ShowConstraints(n *parser.ShowConstraints) (planNode, error) {
 					index.ForeignKey.Index, other.Name)
 			}
 			appendRow(index.ForeignKey.Name, "FOREIGN KEY", fmt.Sprintf("%v", index.ColumnNames),
-				fmt.Sprintf("%s.%v", other.Name, otherIdx.ColumnNames))
+				fmt.Sprintf("%s.%v%s", other.Name, otherIdx.ColumnNames,
+					parser.AsString(fkActionsToAST(index.ForeignKey))))
 		}
 	}
 	for _, c := range desc.Checks {
@@ -467,6 +641,162 @@ func (p *planner) ShowConstraints(n *parser.ShowConstraints) (planNode, error) {
 	return &selectTopNode{source: v, sort: sort}, nil
 }
 
+// ShowQueries returns the queries currently running on this node, or on all
+// the nodes of the cluster for SHOW CLUSTER QUERIES.
+// Nodes that cannot be reached are listed in the error phase.
+// Privileges: None.
+//   Notes: users other than root only see their own queries.
+//          postgres and mysql have no SHOW QUERIES statement; the closest
+//          equivalents are pg_stat_activity and SHOW PROCESSLIST.
+func (p *planner) ShowQueries(n *parser.ShowQueries) (planNode, error) {
+	var queries []serverpb.ActiveQuery
+	var nodeErrors []serverpb.ListQueriesError
+	if n.Cluster {
+		if p.execCtx.StatusServer == nil {
+			return nil, errors.New("SHOW CLUSTER QUERIES is not supported on this node")
+		}
+		resp, err := p.execCtx.StatusServer.ListQueries(p.ctx(), &serverpb.ListQueriesRequest{})
+		if err != nil {
+			return nil, err
+		}
+		queries = resp.Queries
+		nodeErrors = resp.Errors
+	} else if p.execCtx.SessionRegistry != nil {
+		queries = p.execCtx.SessionRegistry.ActiveQueries(p.evalCtx.NodeID)
+	}
+
+	v := &valuesNode{
+		columns: []ResultColumn{
+			{Name: "ID", Typ: parser.TypeString},
+			{Name: "Node", Typ: parser.TypeInt},
+			{Name: "User", Typ: parser.TypeString},
+			{Name: "Start", Typ: parser.TypeTimestamp},
+			{Name: "Query", Typ: parser.TypeString},
+			{Name: "Client", Typ: parser.TypeString},
+			{Name: "Phase", Typ: parser.TypeString},
+		},
+	}
+	for _, q := range queries {
+		if p.session.User != security.RootUser && q.Username != p.session.User {
+			continue
+		}
+		v.rows = append(v.rows, []parser.Datum{
+			parser.NewDString(q.ID),
+			parser.NewDInt(parser.DInt(q.NodeID)),
+			parser.NewDString(q.Username),
+			parser.MakeDTimestamp(time.Unix(0, q.Start), time.Microsecond),
+			parser.NewDString(q.SQL),
+			parser.NewDString(q.ClientAddress),
+			parser.NewDString(strings.ToLower(q.Phase.String())),
+		})
+	}
+	// The nodes whose queries could not be listed are reported in rows of
+	// their own, in the error phase, so that the queries of the other nodes
+	// can still be inspected.
+	for _, e := range nodeErrors {
+		v.rows = append(v.rows, []parser.Datum{
+			parser.DNull,
+			parser.NewDInt(parser.DInt(e.NodeID)),
+			parser.DNull,
+			parser.DNull,
+			parser.NewDString(fmt.Sprintf("could not list queries: %s", e.Message)),
+			parser.DNull,
+			parser.NewDString("error"),
+		})
+	}
+	return v, nil
+}
+
+// ShowRanges returns the ranges holding the data of the indexes of a table,
+// or of a single index, with the stores of their replicas and the disk usage
+// of the index data they hold. The start and end keys of the ranges are
+// limited to the span of the index.
+// Privileges: Any privilege on table.
+//   Notes: postgres and mysql have no SHOW RANGES statement.
+func (p *planner) ShowRanges(n *parser.ShowRanges) (planNode, error) {
+	if p.execCtx.StatusServer == nil {
+		return nil, errors.New("SHOW RANGES is not supported on this node")
+	}
+
+	desc, index, err := p.getTableAndIndex(n.Table, n.Index)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.anyPrivilege(desc); err != nil {
+		return nil, err
+	}
+	spans := tableIndexSpans(desc)
+	if n.Index != nil {
+		for _, span := range spans {
+			if span.Name == index.Name {
+				spans = []IndexSpan{span}
+				break
+			}
+		}
+	}
+
+	v := &valuesNode{
+		columns: []ResultColumn{
+			{Name: "Index", Typ: parser.TypeString},
+			{Name: "Start Key", Typ: parser.TypeString},
+			{Name: "End Key", Typ: parser.TypeString},
+			{Name: "Range ID", Typ: parser.TypeInt},
+			{Name: "Replicas", Typ: parser.NewDArray(parser.TypeInt)},
+			{Name: "Keys", Typ: parser.TypeInt},
+			{Name: "Live Bytes", Typ: parser.TypeInt},
+			{Name: "Total Bytes", Typ: parser.TypeInt},
+		},
+	}
+	for _, span := range spans {
+		ranges, err := lookupRangeDescriptors(p.execCtx.DB, span.Span.Key, span.Span.EndKey)
+		if err != nil {
+			return nil, err
+		}
+		for _, rng := range ranges {
+			startKey, endKey := rng.StartKey, rng.EndKey
+			if spanStart := roachpb.RKey(span.Span.Key); startKey.Less(spanStart) {
+				startKey = spanStart
+			}
+			if spanEnd := roachpb.RKey(span.Span.EndKey); spanEnd.Less(endKey) {
+				endKey = spanEnd
+			}
+			replicas := parser.NewDArray(parser.TypeInt)
+			for _, repl := range rng.Replicas {
+				if err := replicas.Append(parser.NewDInt(parser.DInt(repl.StoreID))); err != nil {
+					return nil, err
+				}
+			}
+			// The statistics are computed by the node of the first replica of
+			// the range, so that they are counted once.
+			var stats enginepb.MVCCStats
+			if len(rng.Replicas) > 0 {
+				nodeID := rng.Replicas[0].NodeID
+				resp, err := p.execCtx.StatusServer.SpanStats(p.ctx(), &serverpb.SpanStatsRequest{
+					NodeID:           nodeID.String(),
+					StartKey:         startKey,
+					EndKey:           endKey,
+					FirstReplicaOnly: true,
+				})
+				if err != nil {
+					return nil, errors.Wrapf(err, "could not compute statistics on node %d", nodeID)
+				}
+				stats = resp.TotalStats
+			}
+			v.rows = append(v.rows, []parser.Datum{
+				parser.NewDString(span.Name),
+				parser.NewDString(startKey.AsRawKey().String()),
+				parser.NewDString(endKey.AsRawKey().String()),
+				parser.NewDInt(parser.DInt(rng.RangeID)),
+				replicas,
+				parser.NewDInt(parser.DInt(stats.KeyCount)),
+				parser.NewDInt(parser.DInt(stats.LiveBytes)),
+				parser.NewDInt(parser.DInt(stats.Total())),
+			})
+		}
+	}
+	return v, nil
+}
+
 // ShowTables returns all the tables.
 // Privileges: None.
 //   Notes: postgres does not have a SHOW TABLES statement.
@@ -17,10 +17,19 @@
 package sql_test
 
 import (
+	gosql "database/sql"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/base"
+	"github.com/cockroachdb/cockroach/security"
 	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/testutils/testcluster"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 )
 
@@ -34,6 +43,7 @@ func TestShowCreateTable(t *testing.T) {
 	if _, err := sqlDB.Exec(`
 		CREATE DATABASE d;
 		SET DATABASE = d;
+		CREATE TABLE items (a INT, b INT, c INT UNIQUE, PRIMARY KEY (a, b));
 	`); err != nil {
 		t.Fatal(err)
 	}
@@ -120,6 +130,51 @@ func TestShowCreateTable(t *testing.T) {
 		},
 		{
 			stmt: `CREATE TABLE %s (
+	i INT,
+	f FLOAT,
+	CONSTRAINT "primary" PRIMARY KEY (i DESC),
+	INDEX idx_f (f DESC, i)
+)`,
+			expect: `CREATE TABLE %s (
+	i INT NOT NULL,
+	f FLOAT NULL,
+	CONSTRAINT "primary" PRIMARY KEY (i DESC),
+	INDEX idx_f (f DESC, i),
+	FAMILY "primary" (i, f)
+)`,
+		},
+		{
+			stmt: `CREATE TABLE %s (
+	i INT,
+	j INT,
+	k INT,
+	CONSTRAINT fk_ij FOREIGN KEY (i, j) REFERENCES items (a, b) ON DELETE CASCADE,
+	CONSTRAINT fk_k FOREIGN KEY (k) REFERENCES items (c),
+	INDEX idx_ij (i, j),
+	INDEX idx_k (k)
+)`,
+			expect: `CREATE TABLE %s (
+	i INT NULL,
+	j INT NULL,
+	k INT NULL,
+	INDEX idx_ij (i, j),
+	INDEX idx_k (k),
+	CONSTRAINT fk_ij FOREIGN KEY (i, j) REFERENCES items (a, b) ON DELETE CASCADE,
+	CONSTRAINT fk_k FOREIGN KEY (k) REFERENCES items (c),
+	FAMILY "primary" (i, j, k, rowid)
+)`,
+		},
+		{
+			stmt: `CREATE TABLE %s (
+	a INT NOT NULL,
+	b INT NOT NULL,
+	x INT NULL,
+	CONSTRAINT "primary" PRIMARY KEY (a, b),
+	FAMILY "primary" (a, b, x)
+) INTERLEAVE IN PARENT items (a, b)`,
+		},
+		{
+			stmt: `CREATE TABLE %s (
 	"te""st" INT NOT NULL,
 	CONSTRAINT "pri""mary" PRIMARY KEY ("te""st"),
 	FAMILY "primary" ("te""st")
@@ -171,3 +226,178 @@ func TestShowCreateTable(t *testing.T) {
 		}
 	}
 }
+
+func TestShowQueries(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	for _, stmt := range []string{`SHOW QUERIES`, `SHOW CLUSTER QUERIES`} {
+		rows, err := sqlDB.Query(stmt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int
+		for rows.Next() {
+			count++
+			var id, user, query, client, phase string
+			var node int
+			var start time.Time
+			if err := rows.Scan(&id, &node, &user, &start, &query, &client, &phase); err != nil {
+				t.Fatal(err)
+			}
+			if query != stmt {
+				t.Errorf("%s: expected query %q, got %q", stmt, stmt, query)
+			}
+			// SHOW QUERIES collects the active queries while it is being planned,
+			// so it lists itself in the preparing phase.
+			if node != 1 || user != security.RootUser || phase != "preparing" {
+				t.Errorf("%s: unexpected node %d, user %q or phase %q", stmt, node, user, phase)
+			}
+			if client == "" {
+				t.Errorf("%s: expected a client address", stmt)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("%s: expected 1 query, got %d", stmt, count)
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestShowClusterQueriesUnavailableNode checks that SHOW CLUSTER QUERIES
+// lists the queries of the nodes which answer and reports the others.
+func TestShowClusterQueriesUnavailableNode(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	tc := testcluster.StartTestCluster(t, 3, base.TestClusterArgs{})
+	defer tc.Stopper().Stop()
+	// Make sure all the nodes are known to the status server before
+	// stopping one of them.
+	for _, s := range tc.Servers {
+		if err := s.WriteSummaries(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stoppedNodeID := int(tc.Servers[2].GetNode().Descriptor.NodeID)
+	tc.StopServer(2)
+
+	util.SucceedsSoon(t, func() error {
+		rows, err := tc.ServerConn(0).Query(`SHOW CLUSTER QUERIES`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var queries, errorNodes []int
+		for rows.Next() {
+			var id, user, client gosql.NullString
+			var start gosql.NullString
+			var query, phase string
+			var node int
+			if err := rows.Scan(&id, &node, &user, &start, &query, &client, &phase); err != nil {
+				t.Fatal(err)
+			}
+			if phase == "error" {
+				if !strings.HasPrefix(query, "could not list queries: ") {
+					t.Errorf("unexpected error %q for node %d", query, node)
+				}
+				errorNodes = append(errorNodes, node)
+			} else {
+				queries = append(queries, node)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(queries) != 1 || queries[0] != 1 {
+			t.Fatalf("expected only the query on node 1, got queries on nodes %v", queries)
+		}
+		if len(errorNodes) != 1 || errorNodes[0] != stoppedNodeID {
+			return errors.Errorf("expected an error for node %d, got errors for nodes %v",
+				stoppedNodeID, errorNodes)
+		}
+		return nil
+	})
+}
+
+func TestShowRanges(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	if _, err := sqlDB.Exec(`
+		CREATE DATABASE d;
+		CREATE TABLE d.t (k INT PRIMARY KEY, v INT, INDEX (v));
+	`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := sqlDB.Exec(`INSERT INTO d.t VALUES ($1, $2)`, i, i*10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// showRanges returns the index and key count of each row returned by a
+	// SHOW RANGES statement.
+	showRanges := func(query string) []string {
+		rows, err := sqlDB.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var results []string
+		for rows.Next() {
+			var index, startKey, endKey, replicas string
+			var rangeID, keyCount, liveBytes, totalBytes int64
+			if err := rows.Scan(
+				&index, &startKey, &endKey, &rangeID, &replicas, &keyCount, &liveBytes, &totalBytes,
+			); err != nil {
+				t.Fatal(err)
+			}
+			if replicas != "{1}" {
+				t.Errorf("%s: expected replicas {1}, got %s", index, replicas)
+			}
+			if liveBytes <= 0 || totalBytes < liveBytes {
+				t.Errorf("%s: unexpected live bytes %d and total bytes %d", index, liveBytes, totalBytes)
+			}
+			results = append(results, fmt.Sprintf("%s:%d", index, keyCount))
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+
+	// Each index holds a key per row, all of them within a single range.
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{`SHOW RANGES FROM TABLE d.t`, []string{"primary:10", "t_v_idx:10"}},
+		{`SHOW RANGES FROM INDEX d.t@t_v_idx`, []string{"t_v_idx:10"}},
+	}
+	for _, tc := range testCases {
+		if results := showRanges(tc.query); fmt.Sprint(results) != fmt.Sprint(tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.query, tc.expected, results)
+		}
+	}
+
+	// Splitting the primary index in the middle of its rows divides its keys
+	// between two ranges.
+	if _, err := sqlDB.Exec(`ALTER TABLE d.t SPLIT AT VALUES (5)`); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"primary:5", "primary:5"}
+	if results := showRanges(`SHOW RANGES FROM INDEX d.t@primary`); fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/duration"
+	"github.com/cockroachdb/cockroach/util/tracing"
+	basictracer "github.com/opentracing/basictracer-go"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+// showTraceColumns are the columns of the results of SHOW TRACE.
+var showTraceColumns = []ResultColumn{
+	{Name: "Timestamp", Typ: parser.TypeTimestamp},
+	{Name: "Age", Typ: parser.TypeInterval},
+	{Name: "Message", Typ: parser.TypeString},
+	{Name: "Operation", Typ: parser.TypeString},
+	{Name: "Span", Typ: parser.TypeInt},
+}
+
+// ShowTrace runs a statement and returns the trace of its execution: the
+// events logged by the SQL layer, the DistSender and the replicas evaluating
+// its KV requests. SHOW TRACE FOR SESSION returns instead the trace collected
+// for the transactions run since SET tracing = on.
+// Privileges: None.
+//   Notes: postgres and mysql have no equivalent statement.
+func (p *planner) ShowTrace(n *parser.ShowTrace, autoCommit bool) (planNode, error) {
+	if n.Statement == nil {
+		return &valuesNode{
+			columns: showTraceColumns,
+			rows:    traceRows(p.session.traceSpans),
+		}, nil
+	}
+
+	// The statement may be traced already as part of the session; it is
+	// traced on its own in a new trace, whose spans are collected along with
+	// the others by the txn.
+	prevCtx, firstSpan := p.txn.Context, len(p.txn.CollectedSpans)
+	sp, err := tracing.JoinOrNewSnowball("coordinator", nil, func(sp basictracer.RawSpan) {
+		p.txn.CollectedSpans = append(p.txn.CollectedSpans, sp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.txn.Context = opentracing.ContextWithSpan(p.txn.Context, sp)
+	sp.LogEvent(fmt.Sprintf("executing: %s", n.Statement))
+
+	node := &showTraceNode{txn: p.txn, sp: sp, prevCtx: prevCtx, firstSpan: firstSpan}
+	plan, err := p.newPlan(n.Statement, nil, autoCommit)
+	if err != nil {
+		node.finish()
+		return nil, err
+	}
+	node.plan = plan
+	return node, nil
+}
+
+// showTraceNode is a planNode that runs the wrapped plan to completion under
+// a new trace and returns the events of the trace. It is used as the
+// top-level node for SHOW TRACE FOR statements. Like the statement being
+// traced, it performs all its side effects.
+type showTraceNode struct {
+	plan planNode
+	txn  *client.Txn
+	sp   opentracing.Span
+	// prevCtx is the context of the txn before the trace was started,
+	// restored once the statement has run.
+	prevCtx context.Context
+	// firstSpan is the index of the first span of the trace in the spans
+	// collected by the txn.
+	firstSpan int
+	results   *valuesNode
+}
+
+func (n *showTraceNode) ExplainTypes(fn func(string, string)) {}
+func (n *showTraceNode) Next() (bool, error)                  { return n.results.Next() }
+func (n *showTraceNode) Columns() []ResultColumn              { return showTraceColumns }
+func (n *showTraceNode) Ordering() orderingInfo               { return orderingInfo{} }
+func (n *showTraceNode) Values() parser.DTuple                { return n.results.Values() }
+func (n *showTraceNode) DebugValues() debugValues             { return debugValues{} }
+func (n *showTraceNode) SetLimitHint(_ int64, _ bool)         {}
+func (n *showTraceNode) MarkDebug(mode explainMode)           {}
+
+func (n *showTraceNode) expandPlan() error {
+	n.results = &valuesNode{columns: showTraceColumns}
+	if err := n.plan.expandPlan(); err != nil {
+		n.finish()
+		return err
+	}
+	return nil
+}
+
+func (n *showTraceNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "show trace", "", []planNode{n.plan}
+}
+
+func (n *showTraceNode) Start() error {
+	err := n.run()
+	n.finish()
+	if err != nil {
+		return err
+	}
+	spans := n.txn.CollectedSpans
+	if n.firstSpan <= len(spans) {
+		spans = spans[n.firstSpan:]
+	}
+	n.results.rows = traceRows(spans)
+	return nil
+}
+
+func (n *showTraceNode) run() error {
+	if err := n.plan.Start(); err != nil {
+		return err
+	}
+	for {
+		next, err := n.plan.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+}
+
+// finish finishes the trace and restores the context of the txn.
+func (n *showTraceNode) finish() {
+	if n.sp == nil {
+		return
+	}
+	n.sp.Finish()
+	n.sp = nil
+	n.txn.Context = n.prevCtx
+}
+
+type traceEvent struct {
+	timestamp time.Time
+	message   string
+	operation string
+	span      int
+}
+
+type traceEvents []traceEvent
+
+func (e traceEvents) Len() int           { return len(e) }
+func (e traceEvents) Less(i, j int) bool { return e[i].timestamp.Before(e[j].timestamp) }
+func (e traceEvents) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+
+// traceRows returns the rows of SHOW TRACE for the spans: one for the start
+// of each span and one for each event logged in it, in chronological order.
+// The Span column holds the index of the span in the list.
+func traceRows(spans []basictracer.RawSpan) []parser.DTuple {
+	var events traceEvents
+	for i, sp := range spans {
+		events = append(events, traceEvent{
+			timestamp: sp.Start,
+			message:   fmt.Sprintf("=== span start: %s ===", sp.Operation),
+			operation: sp.Operation,
+			span:      i,
+		})
+		for _, l := range sp.Logs {
+			events = append(events, traceEvent{
+				timestamp: l.Timestamp,
+				message:   l.Event,
+				operation: sp.Operation,
+				span:      i,
+			})
+		}
+	}
+	sort.Stable(events)
+
+	rows := make([]parser.DTuple, len(events))
+	for i, e := range events {
+		age := e.timestamp.Sub(events[0].timestamp)
+		rows[i] = parser.DTuple{
+			parser.MakeDTimestamp(e.timestamp, time.Microsecond),
+			&parser.DInterval{Duration: duration.Duration{Nanos: age.Nanoseconds()}},
+			parser.NewDString(e.message),
+			parser.NewDString(e.operation),
+			parser.NewDInt(parser.DInt(e.span)),
+		}
+	}
+	return rows
+}
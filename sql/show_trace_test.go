@@ -0,0 +1,152 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql_test
+
+import (
+	gosql "database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+type traceRow struct {
+	timestamp time.Time
+	message   string
+	operation string
+}
+
+func queryTrace(t *testing.T, sqlDB *gosql.DB, query string) []traceRow {
+	rows, err := sqlDB.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expCols := []string{"Timestamp", "Age", "Message", "Operation", "Span"}
+	if !reflect.DeepEqual(cols, expCols) {
+		t.Fatalf("expected columns %v, got %v", expCols, cols)
+	}
+	var trace []traceRow
+	for rows.Next() {
+		var r traceRow
+		var age string
+		var span int
+		if err := rows.Scan(&r.timestamp, &age, &r.message, &r.operation, &span); err != nil {
+			t.Fatal(err)
+		}
+		trace = append(trace, r)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(trace); i++ {
+		if trace[i].timestamp.Before(trace[i-1].timestamp) {
+			t.Errorf("%s: events out of order: %v", query, trace)
+		}
+	}
+	return trace
+}
+
+func checkTrace(t *testing.T, trace []traceRow, expMessages []string, expOps []string) {
+	messages := make(map[string]bool)
+	ops := make(map[string]bool)
+	for _, r := range trace {
+		messages[r.message] = true
+		ops[r.operation] = true
+	}
+	for _, m := range expMessages {
+		if !messages[m] {
+			t.Errorf("expected message %q in trace %v", m, trace)
+		}
+	}
+	for _, op := range expOps {
+		if !ops[op] {
+			t.Errorf("expected operation %q in trace %v", op, trace)
+		}
+	}
+}
+
+func TestShowTrace(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	params, _ := createTestServerParams()
+	s, sqlDB, _ := serverutils.StartServer(t, params)
+	defer s.Stopper().Stop()
+
+	// Session tracing is specific to the connection.
+	sqlDB.SetMaxOpenConns(1)
+
+	if _, err := sqlDB.Exec(`CREATE DATABASE test; CREATE TABLE test.foo (id INT PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+
+	// The statement traced is run, including its side effects, and its trace
+	// spans the SQL layer and the KV requests it sent.
+	trace := queryTrace(t, sqlDB, `SHOW TRACE FOR INSERT INTO test.foo VALUES (1)`)
+	checkTrace(t, trace,
+		[]string{"executing: INSERT INTO test.foo VALUES (1)", "=== span start: node 1 ==="},
+		[]string{"coordinator", "node 1"})
+	var count int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM test.foo`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 row, got %d", count)
+	}
+
+	// Nothing is collected for the session until tracing is turned on.
+	if trace := queryTrace(t, sqlDB, `SHOW TRACE FOR SESSION`); len(trace) != 0 {
+		t.Fatalf("expected an empty trace, got %v", trace)
+	}
+
+	if _, err := sqlDB.Exec(`SET tracing = on`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO test.foo VALUES (2)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`SELECT * FROM test.foo`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`SET tracing = off`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO test.foo VALUES (3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	trace = queryTrace(t, sqlDB, `SHOW TRACE FOR SESSION`)
+	checkTrace(t, trace,
+		[]string{"executing: INSERT INTO test.foo VALUES (2)", "executing: SELECT * FROM test.foo"},
+		[]string{"coordinator", "node 1"})
+	for _, r := range trace {
+		if r.message == "executing: INSERT INTO test.foo VALUES (3)" {
+			t.Errorf("unexpected statement traced after tracing was turned off: %v", trace)
+		}
+	}
+
+	// Turning tracing on again starts a fresh trace.
+	if _, err := sqlDB.Exec(`SET tracing = on`); err != nil {
+		t.Fatal(err)
+	}
+	if trace := queryTrace(t, sqlDB, `SHOW TRACE FOR SESSION`); len(trace) != 0 {
+		t.Fatalf("expected an empty trace, got %v", trace)
+	}
+}
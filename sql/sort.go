@@ -25,6 +25,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util/encoding"
@@ -36,6 +37,7 @@ import (
 // sub-node.
 type sortNode struct {
 	ctx      context.Context
+	p        *planner
 	plan     planNode
 	columns  []ResultColumn
 	ordering sqlbase.ColumnOrdering
@@ -43,6 +45,8 @@ type sortNode struct {
 	needSort     bool
 	sortStrategy sortingStrategy
 	valueIter    valueIterator
+	// rowsMemAcc accounts for the rows buffered by the sortStrategy.
+	rowsMemAcc mon.MemoryAccount
 
 	explain   explainMode
 	debugVals debugValues
@@ -163,7 +167,13 @@ func (p *planner) orderBy(orderBy parser.OrderBy, n planNode) (*sortNode, error)
 		ordering = append(ordering, sqlbase.ColumnOrderInfo{ColIdx: index, Direction: direction})
 	}
 
-	return &sortNode{ctx: p.ctx(), columns: columns, ordering: ordering}, nil
+	return &sortNode{
+		ctx:        p.ctx(),
+		p:          p,
+		columns:    columns,
+		ordering:   ordering,
+		rowsMemAcc: p.makeMemoryAccount(),
+	}, nil
 }
 
 // colIndex takes an expression that refers to a column using an integer, verifies it refers to a
@@ -261,9 +271,9 @@ func (n *sortNode) SetLimitHint(numRows int64, soft bool) {
 		if numRows != math.MaxInt64 {
 			v := &valuesNode{ordering: n.ordering}
 			if soft {
-				n.sortStrategy = newIterativeSortStrategy(v)
+				n.sortStrategy = newIterativeSortStrategy(v, &n.rowsMemAcc)
 			} else {
-				n.sortStrategy = newSortTopKStrategy(v, numRows)
+				n.sortStrategy = newSortTopKStrategy(v, numRows, &n.rowsMemAcc)
 			}
 		}
 	}
@@ -317,13 +327,13 @@ func (n *sortNode) Next() (bool, error) {
 		if v, ok := n.plan.(*valuesNode); ok {
 			// The plan we wrap is already a values node. Just sort it.
 			v.ordering = n.ordering
-			n.sortStrategy = newSortAllStrategy(v)
+			n.sortStrategy = newSortAllStrategy(v, &n.rowsMemAcc)
 			n.sortStrategy.Finish()
 			n.needSort = false
 			break
 		} else if n.sortStrategy == nil {
 			v := &valuesNode{ordering: n.ordering}
-			n.sortStrategy = newSortAllStrategy(v)
+			n.sortStrategy = newSortAllStrategy(v, &n.rowsMemAcc)
 		}
 
 		// TODO(andrei): If we're scanning an index with a prefix matching an
@@ -349,7 +359,20 @@ func (n *sortNode) Next() (bool, error) {
 		}
 
 		values := n.plan.Values()
-		n.sortStrategy.Add(values)
+		if err := n.sortStrategy.Add(values); err != nil {
+			if !isMemoryBudgetError(err) {
+				return false, err
+			}
+			// The values don't fit in memory; sort them on disk instead.
+			if spilled, spillErr := n.spillToDisk(); spillErr != nil {
+				return false, spillErr
+			} else if !spilled {
+				return false, err
+			}
+			if err := n.sortStrategy.Add(values); err != nil {
+				return false, err
+			}
+		}
 
 		if n.explain == explainDebug {
 			// Emit a "buffered" row.
@@ -371,6 +394,41 @@ func (n *sortNode) Next() (bool, error) {
 	return true, nil
 }
 
+// spillToDisk replaces the sorting strategy with a diskSortStrategy, moving
+// over the values buffered so far. It returns false if the values can't be
+// spilled, either because there is no temporary storage or because the
+// strategy only keeps the top K values, whose memory use is bounded.
+func (n *sortNode) spillToDisk() (bool, error) {
+	if n.p == nil || !n.p.canSpillToDisk() {
+		return false, nil
+	}
+	var vNode *valuesNode
+	switch ss := n.sortStrategy.(type) {
+	case *sortAllStrategy:
+		vNode = ss.vNode
+	case *iterativeSortStrategy:
+		vNode = ss.vNode
+	default:
+		return false, nil
+	}
+
+	columns := n.plan.Columns()
+	types := make([]parser.Datum, len(columns))
+	for i, col := range columns {
+		types[i] = col.Typ
+	}
+	ss := newDiskSortStrategy(n.ordering, n.p.newDiskRowContainer(types))
+	for _, values := range vNode.rows {
+		if err := ss.Add(values); err != nil {
+			return false, err
+		}
+	}
+	vNode.rows = nil
+	n.rowsMemAcc.Clear()
+	n.sortStrategy = ss
+	return true, nil
+}
+
 // valueIterator provides iterative access to a value source's values and
 // debug values. It is a subset of the planNode interface, so all methods
 // should conform to the comments expressed in the planNode definition.
@@ -384,8 +442,9 @@ type sortingStrategy interface {
 	valueIterator
 	// Add adds a single value to the sortingStrategy. It guarantees that
 	// if it decided to store the provided value, that it will make a deep
-	// copy of it.
-	Add(parser.DTuple)
+	// copy of it. It returns an error if the memory budget doesn't allow
+	// storing the value.
+	Add(parser.DTuple) error
 	// Finish terminates the sorting strategy, allowing for postprocessing
 	// after all values have been provided to the strategy. The method should
 	// not be called more than once, and should only be called after all Add
@@ -399,19 +458,25 @@ type sortingStrategy interface {
 //
 // The strategy is intended to be used when all values need to be sorted.
 type sortAllStrategy struct {
-	vNode *valuesNode
+	vNode  *valuesNode
+	memAcc *mon.MemoryAccount
 }
 
-func newSortAllStrategy(vNode *valuesNode) sortingStrategy {
+func newSortAllStrategy(vNode *valuesNode, memAcc *mon.MemoryAccount) sortingStrategy {
 	return &sortAllStrategy{
-		vNode: vNode,
+		vNode:  vNode,
+		memAcc: memAcc,
 	}
 }
 
-func (ss *sortAllStrategy) Add(values parser.DTuple) {
+func (ss *sortAllStrategy) Add(values parser.DTuple) error {
 	valuesCopy := make(parser.DTuple, len(values))
 	copy(valuesCopy, values)
+	if err := ss.memAcc.Grow(int64(valuesCopy.Size())); err != nil {
+		return err
+	}
 	ss.vNode.rows = append(ss.vNode.rows, valuesCopy)
+	return nil
 }
 
 func (ss *sortAllStrategy) Finish() {
@@ -442,20 +507,26 @@ func (ss *sortAllStrategy) DebugValues() debugValues {
 // need to be sorted, but that most likely not all values need to be sorted.
 type iterativeSortStrategy struct {
 	vNode      *valuesNode
+	memAcc     *mon.MemoryAccount
 	lastVal    parser.DTuple
 	nextRowIdx int
 }
 
-func newIterativeSortStrategy(vNode *valuesNode) sortingStrategy {
+func newIterativeSortStrategy(vNode *valuesNode, memAcc *mon.MemoryAccount) sortingStrategy {
 	return &iterativeSortStrategy{
-		vNode: vNode,
+		vNode:  vNode,
+		memAcc: memAcc,
 	}
 }
 
-func (ss *iterativeSortStrategy) Add(values parser.DTuple) {
+func (ss *iterativeSortStrategy) Add(values parser.DTuple) error {
 	valuesCopy := make(parser.DTuple, len(values))
 	copy(valuesCopy, values)
+	if err := ss.memAcc.Grow(int64(valuesCopy.Size())); err != nil {
+		return err
+	}
 	ss.vNode.rows = append(ss.vNode.rows, valuesCopy)
+	return nil
 }
 
 func (ss *iterativeSortStrategy) Finish() {
@@ -501,25 +572,32 @@ func (ss *iterativeSortStrategy) DebugValues() debugValues {
 // a worst-case space complexity of O(k). For instance, the top k can be found
 // in linear time, and then this can be sorted in linearithmic time.
 type sortTopKStrategy struct {
-	vNode *valuesNode
-	topK  int64
+	vNode  *valuesNode
+	memAcc *mon.MemoryAccount
+	topK   int64
 }
 
-func newSortTopKStrategy(vNode *valuesNode, topK int64) sortingStrategy {
+func newSortTopKStrategy(
+	vNode *valuesNode, topK int64, memAcc *mon.MemoryAccount,
+) sortingStrategy {
 	ss := &sortTopKStrategy{
-		vNode: vNode,
-		topK:  topK,
+		vNode:  vNode,
+		memAcc: memAcc,
+		topK:   topK,
 	}
 	ss.vNode.InitMaxHeap()
 	return ss
 }
 
-func (ss *sortTopKStrategy) Add(values parser.DTuple) {
+func (ss *sortTopKStrategy) Add(values parser.DTuple) error {
 	switch {
 	case int64(ss.vNode.Len()) < ss.topK:
 		// The first k values all go into the max-heap.
 		valuesCopy := make(parser.DTuple, len(values))
 		copy(valuesCopy, values)
+		if err := ss.memAcc.Grow(int64(valuesCopy.Size())); err != nil {
+			return err
+		}
 
 		ss.vNode.PushValues(valuesCopy)
 	case ss.vNode.ValuesLess(values, ss.vNode.rows[0]):
@@ -528,10 +606,16 @@ func (ss *sortTopKStrategy) Add(values parser.DTuple) {
 		// replace and fix the heap.
 		valuesCopy := make(parser.DTuple, len(values))
 		copy(valuesCopy, values)
+		if err := ss.memAcc.ResizeItem(
+			int64(ss.vNode.rows[0].Size()), int64(valuesCopy.Size()),
+		); err != nil {
+			return err
+		}
 
 		ss.vNode.rows[0] = valuesCopy
 		heap.Fix(ss.vNode, 0)
 	}
+	return nil
 }
 
 func (ss *sortTopKStrategy) Finish() {
@@ -557,9 +641,79 @@ func (ss *sortTopKStrategy) DebugValues() debugValues {
 	return ss.vNode.DebugValues()
 }
 
-// TODO(pmattis): If the result set is large, we might need to perform the
-// sort on disk. There is no point in doing this while we're buffering the
-// entire result set in memory. If/when we start streaming results back to
-// the client we should revisit.
-//
-// type onDiskSortStrategy struct{}
+// diskSortStrategy stores the values in a diskRowContainer, keyed by the
+// encoding of their ordering columns, so that iterating over the container
+// returns them sorted. It is used in place of the in-memory strategies once
+// the values no longer fit in the memory budget of the sortNode.
+type diskSortStrategy struct {
+	ordering sqlbase.ColumnOrdering
+	rows     *diskRowContainer
+	iter     *diskRowIterator
+	err      error
+
+	started bool
+	rowIdx  int
+	values  parser.DTuple
+	scratch []byte
+}
+
+func newDiskSortStrategy(
+	ordering sqlbase.ColumnOrdering, rows *diskRowContainer,
+) sortingStrategy {
+	return &diskSortStrategy{
+		ordering: ordering,
+		rows:     rows,
+	}
+}
+
+func (ss *diskSortStrategy) Add(values parser.DTuple) error {
+	key := ss.scratch[:0]
+	for _, o := range ss.ordering {
+		var err error
+		key, err = sqlbase.EncodeTableKey(key, values[o.ColIdx], o.Direction)
+		if err != nil {
+			return errors.Wrap(err, "unable to spill row to disk")
+		}
+	}
+	ss.scratch = key
+	return ss.rows.addRow(key, values)
+}
+
+func (ss *diskSortStrategy) Finish() {
+	ss.iter, ss.err = ss.rows.newIterator()
+}
+
+func (ss *diskSortStrategy) Next() (bool, error) {
+	if ss.iter == nil {
+		return false, ss.err
+	}
+	if ss.started {
+		ss.iter.next()
+	}
+	ss.started = true
+	if ok, err := ss.iter.valid(); !ok || err != nil {
+		ss.iter.close()
+		ss.iter, ss.err = nil, err
+		return false, err
+	}
+	values, err := ss.iter.row()
+	if err != nil {
+		return false, err
+	}
+	ss.values = values
+	ss.rowIdx++
+	return true, nil
+}
+
+func (ss *diskSortStrategy) Values() parser.DTuple {
+	return ss.values
+}
+
+func (ss *diskSortStrategy) DebugValues() debugValues {
+	return debugValues{
+		rowIdx: ss.rowIdx - 1,
+		key:    fmt.Sprintf("%d", ss.rowIdx-1),
+		value:  ss.values.String(),
+		output: debugValueRow,
+	}
+}
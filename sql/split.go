@@ -0,0 +1,404 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/storage"
+)
+
+// rangeOpColumns are the columns returned by the statements which operate on
+// the ranges of an index: the key at which each range was split, relocated or
+// scattered, in its raw and pretty-printed forms.
+var rangeOpColumns = []ResultColumn{
+	{Name: "key", Typ: parser.TypeBytes},
+	{Name: "pretty", Typ: parser.TypeString},
+}
+
+// getTableAndIndex returns the descriptors of a table and of one of its
+// indexes: the primary index if table is set, or the index named by
+// tableWithIndex otherwise. The caller is responsible for checking the
+// privileges of the user on the table.
+func (p *planner) getTableAndIndex(
+	table *parser.NormalizableTableName, tableWithIndex *parser.TableNameWithIndex,
+) (*sqlbase.TableDescriptor, *sqlbase.IndexDescriptor, error) {
+	var tn *parser.TableName
+	var err error
+	if tableWithIndex == nil {
+		tn, err = table.NormalizeWithDatabaseName(p.session.Database)
+	} else {
+		tn, err = tableWithIndex.Table.NormalizeWithDatabaseName(p.session.Database)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tableDesc, err := p.mustGetTableDesc(tn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !tableDesc.IsTable() {
+		return nil, nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
+
+	if tableWithIndex == nil {
+		return tableDesc, &tableDesc.PrimaryIndex, nil
+	}
+	normIdxName := sqlbase.NormalizeName(tableWithIndex.Index)
+	if normIdxName == sqlbase.ReNormalizeName(tableDesc.PrimaryIndex.Name) {
+		return tableDesc, &tableDesc.PrimaryIndex, nil
+	}
+	status, i, err := tableDesc.FindIndexByNormalizedName(normIdxName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != sqlbase.DescriptorActive {
+		return nil, nil, errors.Errorf("index %q is not yet available", tableWithIndex.Index)
+	}
+	return tableDesc, &tableDesc.Indexes[i], nil
+}
+
+// newIndexKeysPlan plans a query returning, after columns of the given
+// leading types, values for a prefix of the columns of the index.
+func (p *planner) newIndexKeysPlan(
+	stmtName string,
+	tableDesc *sqlbase.TableDescriptor,
+	index *sqlbase.IndexDescriptor,
+	rows *parser.Select,
+	leading []parser.Datum,
+) (planNode, error) {
+	desiredTypes := append([]parser.Datum(nil), leading...)
+	for _, colID := range index.ColumnIDs {
+		col, err := tableDesc.FindColumnByID(colID)
+		if err != nil {
+			return nil, err
+		}
+		desiredTypes = append(desiredTypes, col.Type.ToDatumType())
+	}
+
+	plan, err := p.newPlan(rows, desiredTypes, false)
+	if err != nil {
+		return nil, err
+	}
+	cols := plan.Columns()
+	if len(cols) <= len(leading) {
+		return nil, errors.Errorf("no columns in %s data", stmtName)
+	}
+	if len(cols) > len(desiredTypes) {
+		return nil, errors.Errorf("too many columns in %s data", stmtName)
+	}
+	for i, col := range cols {
+		if col.Typ != parser.DNull && !col.Typ.TypeEqual(desiredTypes[i]) {
+			return nil, errors.Errorf("%s data column %d (%s) must be of type %s, not type %s",
+				stmtName, i+1, col.Name, desiredTypes[i].Type(), col.Typ.Type())
+		}
+	}
+	return plan, nil
+}
+
+// encodeIndexKeyPrefix returns the key prefixing the index entries with the
+// given values for the first columns of the index.
+func encodeIndexKeyPrefix(
+	tableDesc *sqlbase.TableDescriptor, index *sqlbase.IndexDescriptor, values parser.DTuple,
+) (roachpb.Key, error) {
+	colMap := make(map[sqlbase.ColumnID]int, len(values))
+	for i := range values {
+		colMap[index.ColumnIDs[i]] = i
+	}
+	prefix := sqlbase.MakeIndexKeyPrefix(tableDesc, index.ID)
+	key, _, err := sqlbase.EncodePartialIndexKey(tableDesc, index, len(values), colMap, values, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return roachpb.Key(key), nil
+}
+
+func rangeOpRow(key roachpb.Key) parser.DTuple {
+	return parser.DTuple{
+		parser.NewDBytes(parser.DBytes(key)),
+		parser.NewDString(key.String()),
+	}
+}
+
+type splitNode struct {
+	p         *planner
+	tableDesc *sqlbase.TableDescriptor
+	index     *sqlbase.IndexDescriptor
+	rows      planNode
+
+	results []parser.DTuple
+	curRow  int
+}
+
+// Split splits the ranges of an index at the keys given by the rows of a
+// query, each of which holds values for a prefix of the index columns. One
+// row is returned for each split key.
+// Privileges: INSERT on table.
+func (p *planner) Split(n *parser.Split) (planNode, error) {
+	tableDesc, index, err := p.getTableAndIndex(n.Table, n.Index)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivilege(tableDesc, privilege.INSERT); err != nil {
+		return nil, err
+	}
+	rows, err := p.newIndexKeysPlan("SPLIT AT", tableDesc, index, n.Rows, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &splitNode{p: p, tableDesc: tableDesc, index: index, rows: rows}, nil
+}
+
+func (n *splitNode) expandPlan() error {
+	return n.rows.expandPlan()
+}
+
+func (n *splitNode) Start() error {
+	if err := n.rows.Start(); err != nil {
+		return err
+	}
+	for {
+		next, err := n.rows.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+		key, err := encodeIndexKeyPrefix(n.tableDesc, n.index, n.rows.Values())
+		if err != nil {
+			return err
+		}
+		if err := n.p.execCtx.DB.AdminSplit(key); err != nil {
+			return err
+		}
+		n.results = append(n.results, rangeOpRow(key))
+	}
+}
+
+func (n *splitNode) Next() (bool, error) {
+	if n.curRow >= len(n.results) {
+		return false, nil
+	}
+	n.curRow++
+	return true, nil
+}
+
+func (n *splitNode) Values() parser.DTuple { return n.results[n.curRow-1] }
+
+func (n *splitNode) Columns() []ResultColumn             { return rangeOpColumns }
+func (n *splitNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *splitNode) DebugValues() debugValues            { return debugValues{} }
+func (n *splitNode) ExplainTypes(_ func(string, string)) {}
+func (n *splitNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *splitNode) MarkDebug(mode explainMode)          {}
+func (n *splitNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "split", n.index.Name, []planNode{n.rows}
+}
+
+type relocateNode struct {
+	p         *planner
+	tableDesc *sqlbase.TableDescriptor
+	index     *sqlbase.IndexDescriptor
+	rows      planNode
+
+	results []parser.DTuple
+	curRow  int
+}
+
+// Relocate moves the replicas of the ranges of an index to the given stores.
+// Each row of the query holds an array of the IDs of the target stores,
+// followed by values for a prefix of the index columns which identify a key
+// within the range to relocate; its lease is moved to the first target store.
+// One row is returned for each relocated range, with the key of the row.
+// Privileges: INSERT on table.
+func (p *planner) Relocate(n *parser.Relocate) (planNode, error) {
+	tableDesc, index, err := p.getTableAndIndex(n.Table, n.Index)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivilege(tableDesc, privilege.INSERT); err != nil {
+		return nil, err
+	}
+	rows, err := p.newIndexKeysPlan("EXPERIMENTAL_RELOCATE", tableDesc, index, n.Rows,
+		[]parser.Datum{parser.NewDArray(parser.TypeInt)})
+	if err != nil {
+		return nil, err
+	}
+	return &relocateNode{p: p, tableDesc: tableDesc, index: index, rows: rows}, nil
+}
+
+func (n *relocateNode) expandPlan() error {
+	return n.rows.expandPlan()
+}
+
+func (n *relocateNode) Start() error {
+	if err := n.rows.Start(); err != nil {
+		return err
+	}
+	for {
+		next, err := n.rows.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+		values := n.rows.Values()
+		storeIDs, ok := values[0].(*parser.DArray)
+		if !ok {
+			return errors.Errorf("EXPERIMENTAL_RELOCATE data column 1 must be an array of store IDs, not %s",
+				values[0])
+		}
+		targets, err := n.lookupTargets(storeIDs)
+		if err != nil {
+			return err
+		}
+
+		key, err := encodeIndexKeyPrefix(n.tableDesc, n.index, values[1:])
+		if err != nil {
+			return err
+		}
+		ranges, err := lookupRangeDescriptors(n.p.execCtx.DB, key, key.Next())
+		if err != nil {
+			return err
+		}
+		if len(ranges) == 0 {
+			return errors.Errorf("no range found for key %s", key)
+		}
+		if err := storage.RelocateRange(n.p.ctx(), n.p.execCtx.DB, ranges[0], targets); err != nil {
+			return err
+		}
+		n.results = append(n.results, rangeOpRow(key))
+	}
+}
+
+// lookupTargets returns the replica descriptors of the stores with the given
+// IDs, whose nodes are found in their gossiped store descriptors.
+func (n *relocateNode) lookupTargets(storeIDs *parser.DArray) ([]roachpb.ReplicaDescriptor, error) {
+	if n.p.execCtx.Gossip == nil {
+		return nil, errors.New("EXPERIMENTAL_RELOCATE is not supported on this node")
+	}
+	if storeIDs.Len() == 0 {
+		return nil, errors.New("EXPERIMENTAL_RELOCATE requires at least one target store")
+	}
+	targets := make([]roachpb.ReplicaDescriptor, storeIDs.Len())
+	for i, d := range storeIDs.Array {
+		id, ok := d.(*parser.DInt)
+		if !ok {
+			return nil, errors.Errorf("invalid target store ID %s", d)
+		}
+		storeID := roachpb.StoreID(*id)
+		var storeDesc roachpb.StoreDescriptor
+		if err := n.p.execCtx.Gossip.GetInfoProto(gossip.MakeStoreKey(storeID), &storeDesc); err != nil {
+			return nil, errors.Wrapf(err, "unable to look up store %d", storeID)
+		}
+		targets[i] = roachpb.ReplicaDescriptor{NodeID: storeDesc.Node.NodeID, StoreID: storeID}
+	}
+	return targets, nil
+}
+
+func (n *relocateNode) Next() (bool, error) {
+	if n.curRow >= len(n.results) {
+		return false, nil
+	}
+	n.curRow++
+	return true, nil
+}
+
+func (n *relocateNode) Values() parser.DTuple { return n.results[n.curRow-1] }
+
+func (n *relocateNode) Columns() []ResultColumn             { return rangeOpColumns }
+func (n *relocateNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *relocateNode) DebugValues() debugValues            { return debugValues{} }
+func (n *relocateNode) ExplainTypes(_ func(string, string)) {}
+func (n *relocateNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *relocateNode) MarkDebug(mode explainMode)          {}
+func (n *relocateNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "relocate", n.index.Name, []planNode{n.rows}
+}
+
+type scatterNode struct {
+	p    *planner
+	span roachpb.Span
+
+	results []parser.DTuple
+	curRow  int
+}
+
+// Scatter moves the replicas and the leases of the ranges of an index to
+// randomly chosen stores, e.g. to spread a freshly split table over the
+// cluster. One row is returned for each range, with its start key or the
+// start of the index for the first one.
+// Privileges: INSERT on table.
+func (p *planner) Scatter(n *parser.Scatter) (planNode, error) {
+	tableDesc, index, err := p.getTableAndIndex(n.Table, n.Index)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkPrivilege(tableDesc, privilege.INSERT); err != nil {
+		return nil, err
+	}
+	prefix := roachpb.Key(sqlbase.MakeIndexKeyPrefix(tableDesc, index.ID))
+	return &scatterNode{p: p, span: roachpb.Span{Key: prefix, EndKey: prefix.PrefixEnd()}}, nil
+}
+
+func (n *scatterNode) expandPlan() error {
+	return nil
+}
+
+func (n *scatterNode) Start() error {
+	ranges, err := lookupRangeDescriptors(n.p.execCtx.DB, n.span.Key, n.span.EndKey)
+	if err != nil {
+		return err
+	}
+	for _, rng := range ranges {
+		key := rng.StartKey.AsRawKey()
+		if key.Compare(n.span.Key) < 0 {
+			key = n.span.Key
+		}
+		if err := n.p.execCtx.DB.AdminScatter(key); err != nil {
+			return err
+		}
+		n.results = append(n.results, rangeOpRow(key))
+	}
+	return nil
+}
+
+func (n *scatterNode) Next() (bool, error) {
+	if n.curRow >= len(n.results) {
+		return false, nil
+	}
+	n.curRow++
+	return true, nil
+}
+
+func (n *scatterNode) Values() parser.DTuple { return n.results[n.curRow-1] }
+
+func (n *scatterNode) Columns() []ResultColumn             { return rangeOpColumns }
+func (n *scatterNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *scatterNode) DebugValues() debugValues            { return debugValues{} }
+func (n *scatterNode) ExplainTypes(_ func(string, string)) {}
+func (n *scatterNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *scatterNode) MarkDebug(mode explainMode)          {}
+func (n *scatterNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "scatter", n.span.String(), nil
+}
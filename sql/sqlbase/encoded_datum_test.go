@@ -137,6 +137,15 @@ func TestEncDatumCompare(t *testing.T) {
 	rng, _ := randutil.NewPseudoRand()
 
 	for typ := ColumnType_Kind(0); int(typ) < len(ColumnType_Kind_value); typ++ {
+		if typ == ColumnType_ARRAY || typ == ColumnType_COLLATEDSTRING {
+			// EncDatums don't carry the element type needed for arrays, nor
+			// the locale needed for collated strings.
+			continue
+		}
+		if typ == ColumnType_JSON {
+			// JSON documents have no key encoding.
+			continue
+		}
 		// Generate two datums d1 < d2
 		var d1, d2 parser.Datum
 		for {
@@ -51,18 +51,26 @@ type ErrorWithPGCode interface {
 
 var _ ErrorWithPGCode = &ErrNonNullViolation{}
 var _ ErrorWithPGCode = &ErrUniquenessConstraintViolation{}
+var _ ErrorWithPGCode = &ErrColumnConversion{}
 var _ ErrorWithPGCode = &ErrTransactionAborted{}
 var _ ErrorWithPGCode = &ErrTransactionCommitted{}
 var _ ErrorWithPGCode = &ErrUndefinedDatabase{}
 var _ ErrorWithPGCode = &ErrUndefinedTable{}
 var _ ErrorWithPGCode = &ErrRetry{}
+var _ ErrorWithPGCode = &ErrQueryCanceled{}
+var _ ErrorWithPGCode = &ErrIdleInTxnSessionTimeout{}
+var _ ErrorWithPGCode = &ErrMemoryBudgetExceeded{}
 
 const (
 	txnAbortedMsg = "current transaction is aborted, commands ignored " +
 		"until end of transaction block"
 	txnCommittedMsg = "current transaction is committed, commands ignored " +
 		"until end of transaction block"
-	txnRetryMsgPrefix = "restart transaction:"
+	txnRetryMsgPrefix   = "restart transaction:"
+	queryCanceledMsg    = "canceling statement due to user request"
+	stmtTimeoutMsg      = "canceling statement due to statement timeout"
+	idleInTxnTimeoutMsg = "current transaction was aborted due to " +
+		"idle-in-transaction timeout"
 )
 
 // NewRetryError creates a ErrRetry.
@@ -92,6 +100,92 @@ func (e *ErrRetry) SrcContext() SrcCtx {
 	return e.ctx
 }
 
+// NewQueryCanceledError creates a new ErrQueryCanceled.
+func NewQueryCanceledError() error {
+	return &ErrQueryCanceled{ctx: MakeSrcCtx(1), msg: queryCanceledMsg}
+}
+
+// NewStatementTimeoutError creates a new ErrQueryCanceled for a statement
+// which exceeded the session's statement_timeout.
+func NewStatementTimeoutError() error {
+	return &ErrQueryCanceled{ctx: MakeSrcCtx(1), msg: stmtTimeoutMsg}
+}
+
+// ErrQueryCanceled signals that the statement was interrupted by a CANCEL
+// QUERY statement or because it ran for longer than statement_timeout.
+type ErrQueryCanceled struct {
+	ctx SrcCtx
+	msg string
+}
+
+func (e *ErrQueryCanceled) Error() string {
+	return e.msg
+}
+
+// Code implements the ErrorWithPGCode interface.
+func (*ErrQueryCanceled) Code() string {
+	return pgerror.CodeQueryCanceledError
+}
+
+// SrcContext implements the ErrorWithPGCode interface.
+func (e *ErrQueryCanceled) SrcContext() SrcCtx {
+	return e.ctx
+}
+
+// NewIdleInTxnSessionTimeoutError creates a new ErrIdleInTxnSessionTimeout.
+func NewIdleInTxnSessionTimeoutError() error {
+	return &ErrIdleInTxnSessionTimeout{ctx: MakeSrcCtx(1)}
+}
+
+// ErrIdleInTxnSessionTimeout signals that the session's transaction was
+// aborted because it stayed idle for longer than
+// idle_in_transaction_session_timeout.
+type ErrIdleInTxnSessionTimeout struct {
+	ctx SrcCtx
+}
+
+func (*ErrIdleInTxnSessionTimeout) Error() string {
+	return idleInTxnTimeoutMsg
+}
+
+// Code implements the ErrorWithPGCode interface.
+func (*ErrIdleInTxnSessionTimeout) Code() string {
+	return pgerror.CodeIdleInTransactionSessionTimeoutError
+}
+
+// SrcContext implements the ErrorWithPGCode interface.
+func (e *ErrIdleInTxnSessionTimeout) SrcContext() SrcCtx {
+	return e.ctx
+}
+
+// NewMemoryBudgetExceededError creates a new ErrMemoryBudgetExceeded.
+func NewMemoryBudgetExceededError(requested, budget int64) error {
+	return &ErrMemoryBudgetExceeded{ctx: MakeSrcCtx(1), requested: requested, budget: budget}
+}
+
+// ErrMemoryBudgetExceeded signals that a statement needed more memory than
+// the SQL memory budget of the node allows.
+type ErrMemoryBudgetExceeded struct {
+	ctx       SrcCtx
+	requested int64
+	budget    int64
+}
+
+func (e *ErrMemoryBudgetExceeded) Error() string {
+	return fmt.Sprintf("out of memory: memory budget exceeded: %d bytes requested, %d bytes in budget",
+		e.requested, e.budget)
+}
+
+// Code implements the ErrorWithPGCode interface.
+func (*ErrMemoryBudgetExceeded) Code() string {
+	return pgerror.CodeOutOfMemoryError
+}
+
+// SrcContext implements the ErrorWithPGCode interface.
+func (e *ErrMemoryBudgetExceeded) SrcContext() SrcCtx {
+	return e.ctx
+}
+
 // NewTransactionAbortedError creates a new ErrTransactionAborted.
 func NewTransactionAbortedError(customMsg string) error {
 	return &ErrTransactionAborted{ctx: MakeSrcCtx(1), CustomMsg: customMsg}
@@ -213,6 +307,35 @@ func (e *ErrUniquenessConstraintViolation) SrcContext() SrcCtx {
 	return e.ctx
 }
 
+// NewColumnConversionError creates a new ErrColumnConversion.
+func NewColumnConversionError(columnName string, typ ColumnType, cause error) error {
+	return &ErrColumnConversion{ctx: MakeSrcCtx(1), columnName: columnName, typ: typ, cause: cause}
+}
+
+// ErrColumnConversion represents a value of a column that cannot be
+// converted to the new type of the column.
+type ErrColumnConversion struct {
+	ctx        SrcCtx
+	columnName string
+	typ        ColumnType
+	cause      error
+}
+
+func (e *ErrColumnConversion) Error() string {
+	return fmt.Sprintf("column %q cannot be converted to %s: %v",
+		e.columnName, e.typ.SQLString(), e.cause)
+}
+
+// Code implements the ErrorWithPGCode interface.
+func (*ErrColumnConversion) Code() string {
+	return pgerror.CodeDatatypeMismatchError
+}
+
+// SrcContext implements the ErrorWithPGCode interface.
+func (e *ErrColumnConversion) SrcContext() SrcCtx {
+	return e.ctx
+}
+
 // NewUndefinedTableError creates a new ErrUndefinedTable.
 func NewUndefinedTableError(name string) error {
 	return &ErrUndefinedTable{ctx: MakeSrcCtx(1), name: name}
@@ -238,6 +361,33 @@ func (e *ErrUndefinedTable) SrcContext() SrcCtx {
 	return e.ctx
 }
 
+// NewWrongObjectTypeError creates a new ErrWrongObjectType.
+func NewWrongObjectTypeError(name, desiredObjType string) error {
+	return &ErrWrongObjectType{ctx: MakeSrcCtx(1), name: name, desiredObjType: desiredObjType}
+}
+
+// ErrWrongObjectType represents a table or view used where the other kind
+// of object is expected.
+type ErrWrongObjectType struct {
+	ctx            SrcCtx
+	name           string
+	desiredObjType string
+}
+
+func (e *ErrWrongObjectType) Error() string {
+	return fmt.Sprintf("%q is not a %s", e.name, e.desiredObjType)
+}
+
+// Code implements the ErrorWithPGCode interface.
+func (*ErrWrongObjectType) Code() string {
+	return pgerror.CodeWrongObjectTypeError
+}
+
+// SrcContext implements the ErrorWithPGCode interface.
+func (e *ErrWrongObjectType) SrcContext() SrcCtx {
+	return e.ctx
+}
+
 // NewUndefinedDatabaseError creates a new ErrUndefinedDatabase.
 func NewUndefinedDatabaseError(name string) error {
 	return &ErrUndefinedDatabase{ctx: MakeSrcCtx(1), name: name}
@@ -272,7 +422,7 @@ func (e *ErrUndefinedDatabase) SrcContext() SrcCtx {
 // constraint violation.
 func IsIntegrityConstraintError(err error) bool {
 	switch err.(type) {
-	case *ErrNonNullViolation, *ErrUniquenessConstraintViolation:
+	case *ErrNonNullViolation, *ErrUniquenessConstraintViolation, *ErrColumnConversion:
 		return true
 	default:
 		return false
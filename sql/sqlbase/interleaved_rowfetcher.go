@@ -0,0 +1,121 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"bytes"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/sql/parser"
+)
+
+// InterleavedRowFetcher fetches the rows of a table and of a table whose
+// primary index is interleaved in its primary index with a single scan of
+// the keyspace of the parent table. The rows are returned in key order:
+// each row of the parent table is followed by the rows of the child table
+// which share its primary key.
+type InterleavedRowFetcher struct {
+	// fetchers holds the RowFetchers decoding the rows of the parent table
+	// and of the child table, in that order.
+	fetchers [2]*RowFetcher
+
+	kvFetcher kvFetcher
+	// The current key/value, unless kvEnd is true, and the index in fetchers
+	// of the fetcher of its table.
+	kv    client.KeyValue
+	kvEnd bool
+	cur   int
+}
+
+// Init sets up an InterleavedRowFetcher from the RowFetchers of the parent
+// and child tables, which must have been initialized for their primary
+// indexes.
+func (f *InterleavedRowFetcher) Init(parent, child *RowFetcher) {
+	f.fetchers = [2]*RowFetcher{parent, child}
+}
+
+// StartScan initializes and starts the key-value scan of the given spans of
+// the primary index of the parent table, which also hold the rows of the
+// child table interleaved in them.
+func (f *InterleavedRowFetcher) StartScan(txn *client.Txn, spans Spans) error {
+	parent := f.fetchers[0]
+	if len(spans) == 0 {
+		start := roachpb.Key(MakeIndexKeyPrefix(parent.desc, parent.index.ID))
+		spans = Spans{{Start: start, End: start.PrefixEnd()}}
+	}
+	for _, rf := range f.fetchers {
+		rf.indexKey = nil
+	}
+	f.kvFetcher = makeKVFetcher(txn, spans, false /* reverse */, 0 /* no batch limit */)
+
+	// Retrieve the first key.
+	return f.nextKey()
+}
+
+// nextKey retrieves the next key/value of one of the two tables and decodes
+// its index key with the fetcher of its table, skipping the keys of the
+// other tables and indexes interleaved in the parent table.
+func (f *InterleavedRowFetcher) nextKey() error {
+	for {
+		ok, kv, err := f.kvFetcher.nextKV()
+		if err != nil {
+			return err
+		}
+		f.kv = kv
+		f.kvEnd = !ok
+		if f.kvEnd {
+			return nil
+		}
+		for i, rf := range f.fetchers {
+			rf.keyRemainingBytes, ok, err = rf.ReadIndexKey(kv.Key)
+			if err != nil {
+				return err
+			}
+			if ok {
+				f.cur = i
+				return nil
+			}
+		}
+	}
+}
+
+// NextRow processes keys until one row is complete, which is returned along
+// with whether it belongs to the child table. Like for RowFetcher.NextRow,
+// the DTuple should not be modified and is only valid until the next call.
+// When there are no more rows, the DTuple is nil.
+func (f *InterleavedRowFetcher) NextRow() (row parser.DTuple, isChild bool, err error) {
+	if f.kvEnd {
+		return nil, false, nil
+	}
+	idx := f.cur
+	rf := f.fetchers[idx]
+	rf.indexKey = nil
+
+	// The key/values of a row are contiguous: the rows of the child table
+	// sharing its primary key follow all of them.
+	for {
+		if _, _, err := rf.ProcessKV(f.kv, false); err != nil {
+			return nil, false, err
+		}
+		if err := f.nextKey(); err != nil {
+			return nil, false, err
+		}
+		if f.kvEnd || f.cur != idx || !bytes.HasPrefix(f.kv.Key, rf.indexKey) {
+			rf.finalizeRow()
+			return rf.row, idx == 1, nil
+		}
+	}
+}
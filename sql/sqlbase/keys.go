@@ -129,3 +129,13 @@ func MakeZoneKey(id ID) roachpb.Key {
 	k = encoding.EncodeUvarintAscending(k, uint64(id))
 	return keys.MakeFamilyKey(k, uint32(ZonesTable.Columns[1].ID))
 }
+
+// MakeSequenceKey returns the key holding the current value of the sequence
+// with the given ID. It is laid out like the single row of a table with a
+// primary index on a constant.
+func MakeSequenceKey(id ID) roachpb.Key {
+	k := keys.MakeTablePrefix(uint32(id))
+	k = encoding.EncodeUvarintAscending(k, 1)
+	k = encoding.EncodeUvarintAscending(k, 0)
+	return keys.MakeFamilyKey(k, keys.SentinelFamilyID)
+}
@@ -93,14 +93,31 @@ func SetKVBatchSize(val int64) func() {
 	return func() { kvBatchSize = oldVal }
 }
 
+// kvBatchTargetBytes bounds the size of the key/values we request at a time, so
+// that scans over large rows are returned incrementally instead of buffering
+// kvBatchSize of them at once. A batch may overshoot it by one key/value.
+var kvBatchTargetBytes int64 = 10 << 20
+
+// SetKVBatchTargetBytes changes the kvFetcher batch byte target, and returns a
+// function that restores it.
+func SetKVBatchTargetBytes(val int64) func() {
+	oldVal := kvBatchTargetBytes
+	kvBatchTargetBytes = val
+	return func() { kvBatchTargetBytes = oldVal }
+}
+
 // kvFetcher handles retrieval of key/values.
 type kvFetcher struct {
 	// "Constant" fields, provided by the caller.
 	txn             *client.Txn
-	spans           Spans
 	reverse         bool
 	firstBatchLimit int64
 
+	// spans are the spans which remain to be fetched. They start out as the
+	// spans provided by the caller and are replaced by the resume spans of
+	// each batch.
+	spans Spans
+
 	batchIdx     int
 	fetchEnd     bool
 	kvs          []client.KeyValue
@@ -162,58 +179,22 @@ func (f *kvFetcher) fetch() error {
 
 	b := &client.Batch{}
 	b.Header.MaxSpanRequestKeys = batchSize
+	b.Header.TargetBytes = kvBatchTargetBytes
 
-	var resumeKey roachpb.Key
-	if len(f.kvs) > 0 {
-		resumeKey = f.kvs[len(f.kvs)-1].Key
-		// To resume forward scans we will set the (inclusive) scan start to the Next of the last
-		// received key. To resume reverse scans we will set the (exclusive) scan end to the last
-		// received key.
-		if !f.reverse {
-			resumeKey = resumeKey.Next()
-		}
-	}
-
-	atEnd := true
+	// Each batch scans exactly what the previous one left off at: the resume
+	// spans returned for a bounded batch are the parts of its spans which
+	// were not scanned yet.
 	if !f.reverse {
-		for i := 0; i < len(f.spans); i++ {
-			start := f.spans[i].Start
-			if resumeKey != nil {
-				if resumeKey.Compare(f.spans[i].End) >= 0 {
-					// We are resuming from a key after this span.
-					continue
-				}
-				if resumeKey.Compare(start) > 0 {
-					// We are resuming from a key inside this span.
-					// In this case we should technically reduce the max count for the span; but
-					// since this count is only an optimization it's not incorrect to retrieve more
-					// keys for the span.
-					start = resumeKey
-				}
-			}
-			atEnd = false
-			b.Scan(start, f.spans[i].End)
+		for _, span := range f.spans {
+			b.Scan(span.Start, span.End)
 		}
 	} else {
 		for i := len(f.spans) - 1; i >= 0; i-- {
-			end := f.spans[i].End
-			if resumeKey != nil {
-				if resumeKey.Compare(f.spans[i].Start) <= 0 {
-					// We are resuming from a key before this span.
-					continue
-				}
-				if resumeKey.Compare(end) < 0 {
-					// We resume from a key inside this span.
-					end = resumeKey
-				}
-			}
-			atEnd = false
-			b.ReverseScan(f.spans[i].Start, end)
+			b.ReverseScan(f.spans[i].Start, f.spans[i].End)
 		}
 	}
 
-	if atEnd {
-		// The last scan happened to finish just at the end of the last span.
+	if len(f.spans) == 0 {
 		f.kvs = nil
 		f.fetchEnd = true
 		return nil
@@ -233,18 +214,30 @@ func (f *kvFetcher) fetch() error {
 		f.kvs = f.kvs[:0]
 	}
 
+	// Collect the resume spans for the next batch. They are kept in ascending
+	// order, like the spans provided by the caller (which we must not modify).
+	var resumeSpans Spans
 	for _, result := range b.Results {
 		f.kvs = append(f.kvs, result.Rows...)
+		if result.ResumeSpan.Key != nil {
+			resumeSpans = append(resumeSpans, Span{
+				Start: result.ResumeSpan.Key,
+				End:   result.ResumeSpan.EndKey,
+			})
+		}
+	}
+	if f.reverse {
+		for i, j := 0, len(resumeSpans)-1; i < j; i, j = i+1, j-1 {
+			resumeSpans[i], resumeSpans[j] = resumeSpans[j], resumeSpans[i]
+		}
 	}
+	f.spans = resumeSpans
+	f.fetchEnd = len(resumeSpans) == 0
 
 	f.batchIdx++
 	f.totalFetched += int64(len(f.kvs))
 	f.kvIndex = 0
 
-	if int64(len(f.kvs)) < batchSize {
-		f.fetchEnd = true
-	}
-
 	// TODO(radu): We should fetch the next chunk in the background instead of waiting for the next
 	// call to fetch(). We can use a pool of workers to issue the KV ops which will also limit the
 	// total number of fetches that happen in parallel (and thus the amount of resources we use).
@@ -0,0 +1,108 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/base"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestKVFetcherResumeSpans verifies that the kvFetcher returns every key of
+// its spans exactly once and in order, whether the batches run out in the
+// middle of a span or exactly at its end.
+func TestKVFetcherResumeSpans(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s, _, kvDB := serverutils.StartServer(t, base.TestServerArgs{})
+	defer s.Stopper().Stop()
+
+	key := func(i int) roachpb.Key {
+		return roachpb.Key(fmt.Sprintf("kvfetcher-%02d", i))
+	}
+	for i := 0; i < 20; i++ {
+		if err := kvDB.Put(key(i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two adjacent spans of 5 keys, followed by a span of 5 keys after a gap.
+	// Batches of 5 keys end exactly at the end of the first span scanned
+	// (the last one when scanning in reverse).
+	spans := Spans{
+		{Start: key(0), End: key(5)},
+		{Start: key(5), End: key(10)},
+		{Start: key(12), End: key(17)},
+	}
+	var expected []roachpb.Key
+	for _, span := range spans {
+		for i := 0; i < 20; i++ {
+			if k := key(i); k.Compare(span.Start) >= 0 && k.Compare(span.End) < 0 {
+				expected = append(expected, k)
+			}
+		}
+	}
+
+	fetchAll := func(reverse bool, firstBatchLimit int64) ([]roachpb.Key, error) {
+		txn := client.NewTxn(context.Background(), *kvDB)
+		f := makeKVFetcher(txn, spans, reverse, firstBatchLimit)
+		var fetched []roachpb.Key
+		for {
+			ok, kv, err := f.nextKV()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return fetched, nil
+			}
+			fetched = append(fetched, kv.Key)
+		}
+	}
+
+	for _, batchSize := range []int64{1, 3, 5, 7, 15, 100} {
+		for _, firstBatchLimit := range []int64{0, 2} {
+			for _, reverse := range []bool{false, true} {
+				restore := SetKVBatchSize(batchSize)
+				fetched, err := fetchAll(reverse, firstBatchLimit)
+				restore()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				prefix := fmt.Sprintf("batch=%d first=%d reverse=%t", batchSize, firstBatchLimit, reverse)
+				if len(fetched) != len(expected) {
+					t.Errorf("%s: expected %d keys, got %d: %s", prefix, len(expected), len(fetched), fetched)
+					continue
+				}
+				for i := range expected {
+					exp := expected[i]
+					if reverse {
+						exp = expected[len(expected)-1-i]
+					}
+					if !fetched[i].Equal(exp) {
+						t.Errorf("%s: expected key %d to be %s, got %s", prefix, i, exp, fetched[i])
+						break
+					}
+				}
+			}
+		}
+	}
+}
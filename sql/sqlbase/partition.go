@@ -0,0 +1,160 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sqlbase
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+)
+
+// IsPartitioned returns whether the index is partitioned.
+func (desc *IndexDescriptor) IsPartitioned() bool {
+	return desc.Partitioning.NumColumns > 0
+}
+
+// PartitionNames returns the names of the partitions, in order.
+func (p *PartitioningDescriptor) PartitionNames() []string {
+	names := make([]string, 0, len(p.List)+len(p.Range))
+	for _, l := range p.List {
+		names = append(names, l.Name)
+	}
+	for _, r := range p.Range {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// FindIndexByPartitionName finds the index (active or inactive) with a
+// partition of the specified name. Partition names are unique in a table.
+func (desc *TableDescriptor) FindIndexByPartitionName(name string) (*IndexDescriptor, error) {
+	normName := ReNormalizeName(name)
+	hasPartition := func(index *IndexDescriptor) bool {
+		for _, n := range index.Partitioning.PartitionNames() {
+			if ReNormalizeName(n) == normName {
+				return true
+			}
+		}
+		return false
+	}
+	if hasPartition(&desc.PrimaryIndex) {
+		return &desc.PrimaryIndex, nil
+	}
+	for i := range desc.Indexes {
+		if hasPartition(&desc.Indexes[i]) {
+			return &desc.Indexes[i], nil
+		}
+	}
+	for _, m := range desc.Mutations {
+		if idx := m.GetIndex(); idx != nil && hasPartition(idx) {
+			return idx, nil
+		}
+	}
+	return nil, fmt.Errorf("partition %q does not exist", name)
+}
+
+// PartitionSpans returns the key spans holding the entries of the partition
+// of the index with the specified name: one per tuple of values of a LIST
+// partition, or the span from the upper bound of the previous partition to
+// the one of the partition for a RANGE partition.
+func (desc *TableDescriptor) PartitionSpans(
+	index *IndexDescriptor, name string,
+) ([]roachpb.Span, error) {
+	normName := ReNormalizeName(name)
+	prefix := roachpb.Key(MakeIndexKeyPrefix(desc, index.ID))
+	for _, l := range index.Partitioning.List {
+		if ReNormalizeName(l.Name) != normName {
+			continue
+		}
+		spans := make([]roachpb.Span, len(l.Values))
+		for i, v := range l.Values {
+			key := append(prefix[:len(prefix):len(prefix)], v...)
+			spans[i] = roachpb.Span{Key: key, EndKey: key.PrefixEnd()}
+		}
+		return spans, nil
+	}
+	start := prefix
+	for _, r := range index.Partitioning.Range {
+		end := prefix.PrefixEnd()
+		if len(r.UpperBound) > 0 {
+			end = append(prefix[:len(prefix):len(prefix)], r.UpperBound...)
+		}
+		if ReNormalizeName(r.Name) == normName {
+			return []roachpb.Span{{Key: start, EndKey: end}}, nil
+		}
+		start = end
+	}
+	return nil, fmt.Errorf("partition %q does not exist in index %q", name, index.Name)
+}
+
+// validatePartitioning validates the partitioning of the indexes of the
+// table: the partitions must have names unique in the table, the values of
+// a LIST partitioning must not repeat, and the upper bounds of a RANGE
+// partitioning must be increasing, with MAXVALUE only for the last one.
+func (desc *TableDescriptor) validatePartitioning() error {
+	names := make(map[string]struct{})
+	for _, index := range desc.AllNonDropIndexes() {
+		part := &index.Partitioning
+		if !index.IsPartitioned() {
+			if len(part.List) > 0 || len(part.Range) > 0 {
+				return fmt.Errorf("index %q has partitions but no partitioning columns", index.Name)
+			}
+			continue
+		}
+		if int(part.NumColumns) > len(index.ColumnIDs) {
+			return fmt.Errorf("index %q is partitioned by %d columns but only has %d",
+				index.Name, part.NumColumns, len(index.ColumnIDs))
+		}
+		if (len(part.List) > 0) == (len(part.Range) > 0) {
+			return fmt.Errorf("index %q must have either LIST or RANGE partitions", index.Name)
+		}
+		for _, name := range part.PartitionNames() {
+			if name == "" {
+				return fmt.Errorf("index %q has a partition without a name", index.Name)
+			}
+			normName := ReNormalizeName(name)
+			if _, ok := names[normName]; ok {
+				return fmt.Errorf("duplicate partition name: %q", name)
+			}
+			names[normName] = struct{}{}
+		}
+		values := make(map[string]string)
+		for _, l := range part.List {
+			if len(l.Values) == 0 {
+				return fmt.Errorf("partition %q has no values", l.Name)
+			}
+			for _, v := range l.Values {
+				if other, ok := values[string(v)]; ok {
+					return fmt.Errorf("partitions %q and %q overlap", other, l.Name)
+				}
+				values[string(v)] = l.Name
+			}
+		}
+		for i, r := range part.Range {
+			if len(r.UpperBound) == 0 {
+				if i != len(part.Range)-1 {
+					return fmt.Errorf("partition %q with MAXVALUE must be the last one", r.Name)
+				}
+				continue
+			}
+			if i > 0 && bytes.Compare(r.UpperBound, part.Range[i-1].UpperBound) <= 0 {
+				return fmt.Errorf("partition %q must have an upper bound greater than the one of %q",
+					r.Name, part.Range[i-1].Name)
+			}
+		}
+	}
+	return nil
+}
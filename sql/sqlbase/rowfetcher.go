@@ -69,7 +69,8 @@ type RowFetcher struct {
 	colIdxMap map[ColumnID]int
 
 	// One value per column that is part of the key; each value is a column
-	// index (into cols).
+	// index (into cols), or -1 for the column of an inverted index, whose
+	// value can't be decoded from the key.
 	indexColIdx []int
 
 	// -- Fields updated during a scan --
@@ -88,6 +89,11 @@ type RowFetcher struct {
 	keyRemainingBytes []byte
 	kvEnd             bool
 
+	// If keepRowKVs is set, rowKVs holds the key/values making up the current
+	// row.
+	keepRowKVs bool
+	rowKVs     []client.KeyValue
+
 	// Buffered allocation of decoded datums.
 	alloc DatumAlloc
 }
@@ -125,6 +131,10 @@ func (rf *RowFetcher) Init(
 			if needed && !index.ContainsColumnID(rf.cols[i].ID) {
 				return errors.Errorf("requested column %s not in index", rf.cols[i].Name)
 			}
+			if needed && index.IsInverted() && rf.cols[i].ID == index.ColumnIDs[0] {
+				return errors.Errorf("requested column %s is inverted in index %s",
+					rf.cols[i].Name, index.Name)
+			}
 		}
 	}
 
@@ -134,6 +144,12 @@ func (rf *RowFetcher) Init(
 	if err != nil {
 		return err
 	}
+	if index.IsInverted() {
+		// The key of an inverted index entry holds one of the paths or
+		// elements of the column value, encoded as bytes.
+		rf.keyValTypes[0] = parser.TypeBytes
+		rf.indexColIdx[0] = -1
+	}
 	rf.keyVals = make([]parser.Datum, len(rf.keyValTypes))
 
 	if isSecondaryIndex && index.Unique {
@@ -184,6 +200,18 @@ func (rf *RowFetcher) StartScan(txn *client.Txn, spans Spans, limitHint int64) e
 	return err
 }
 
+// StartScanFrom is like StartScan, but decodes the rows from the given
+// key/values, which must be ordered like a scan of the index, instead of
+// reading them from the KV layer.
+func (rf *RowFetcher) StartScanFrom(kvs []client.KeyValue) error {
+	rf.indexKey = nil
+	rf.kvFetcher = kvFetcher{kvs: kvs, fetchEnd: true}
+
+	// Retrieve the first key.
+	_, err := rf.NextKey()
+	return err
+}
+
 // NextKey retrieves the next key/value and sets kv/kvEnd. Returns whether a row
 // has been completed.
 // TODO(andrei): change to return error
@@ -268,7 +296,9 @@ func (rf *RowFetcher) ProcessKV(kv client.KeyValue, debugStrings bool) (
 
 		// Fill in the column values that are part of the index key.
 		for i, v := range rf.keyVals {
-			rf.row[rf.indexColIdx[i]] = v
+			if rf.indexColIdx[i] >= 0 {
+				rf.row[rf.indexColIdx[i]] = v
+			}
 		}
 	}
 
@@ -351,10 +381,10 @@ func (rf *RowFetcher) processValueSingle(
 		if debugStrings {
 			prettyKey = fmt.Sprintf("%s/%s", prettyKey, rf.desc.Columns[idx].Name)
 		}
-		kind := rf.cols[idx].Type.Kind
+		typ := rf.cols[idx].Type
 		// TODO(dan): Once we decide if we're changing the tuple encoding, see if we
 		// can get rid of UnmarshalColumnValue in favor of DecodeTableValue.
-		value, err := UnmarshalColumnValue(&rf.alloc, kind, kv.Value)
+		value, err := UnmarshalColumnValue(&rf.alloc, typ, kv.Value)
 		if err != nil {
 			return "", "", err
 		}
@@ -426,8 +456,8 @@ func (rf *RowFetcher) processValueTuple(
 			prettyKey = fmt.Sprintf("%s/%s", prettyKey, rf.desc.Columns[idx].Name)
 		}
 
-		kind := rf.cols[idx].Type.Kind.ToDatumType()
-		value, tupleBytes, err = DecodeTableValue(&rf.alloc, kind, tupleBytes)
+		typ := rf.cols[idx].Type.ToDatumType()
+		value, tupleBytes, err = DecodeTableValue(&rf.alloc, typ, tupleBytes)
 		if err != nil {
 			return "", "", err
 		}
@@ -459,6 +489,7 @@ func (rf *RowFetcher) NextRow() (parser.DTuple, error) {
 	if rf.kvEnd {
 		return nil, nil
 	}
+	rf.rowKVs = rf.rowKVs[:0]
 
 	// All of the columns for a particular row will be grouped together. We loop
 	// over the key/value pairs and decode the key to extract the columns encoded
@@ -467,6 +498,9 @@ func (rf *RowFetcher) NextRow() (parser.DTuple, error) {
 	// column name. When the index key changes we output a row containing the
 	// current values.
 	for {
+		if rf.keepRowKVs {
+			rf.rowKVs = append(rf.rowKVs, rf.kv)
+		}
 		_, _, err := rf.ProcessKV(rf.kv, false)
 		if err != nil {
 			return nil, err
@@ -518,6 +552,19 @@ func (rf *RowFetcher) finalizeRow() {
 	}
 }
 
+// KeepRowKVs instructs the RowFetcher to keep the key/values making up each
+// row returned by NextRow, so that they can be retrieved with RowKVs.
+func (rf *RowFetcher) KeepRowKVs() {
+	rf.keepRowKVs = true
+}
+
+// RowKVs returns the key/values making up the row last returned by NextRow.
+// KeepRowKVs must have been called before the scan. The slice is only valid
+// until the next call to NextRow.
+func (rf *RowFetcher) RowKVs() []client.KeyValue {
+	return rf.rowKVs
+}
+
 // Key returns the next key (the key that follows the last returned row).
 func (rf *RowFetcher) Key() roachpb.Key {
 	return rf.kv.Key
@@ -36,6 +36,13 @@ import (
 // ID is a custom type for {Database,Table}Descriptor IDs.
 type ID uint32
 
+// IDs is a sortable list of IDs.
+type IDs []ID
+
+func (ids IDs) Len() int           { return len(ids) }
+func (ids IDs) Less(i, j int) bool { return ids[i] < ids[j] }
+func (ids IDs) Swap(i, j int)      { ids[i], ids[j] = ids[j], ids[i] }
+
 // ColumnID is a custom type for ColumnDescriptor IDs.
 type ColumnID uint32
 
@@ -45,6 +52,19 @@ type FamilyID uint32
 // IndexID is a custom type for IndexDescriptor IDs.
 type IndexID uint32
 
+// IndexDescriptorEncodingType is a custom type for the encoding of the
+// entries of an IndexDescriptor.
+type IndexDescriptorEncodingType uint32
+
+const (
+	// SecondaryIndexEncoding maps the values of the indexed columns to the
+	// primary key of the row.
+	SecondaryIndexEncoding IndexDescriptorEncodingType = iota
+	// PrimaryIndexEncoding maps the values of the indexed columns to the
+	// values of all the columns of the row, stored in column families.
+	PrimaryIndexEncoding
+)
+
 // DescriptorVersion is a custom type for TableDescriptor Versions.
 type DescriptorVersion uint32
 
@@ -201,6 +221,18 @@ func (desc *IndexDescriptor) ContainsColumnID(colID ColumnID) bool {
 	return false
 }
 
+// IsPartial returns true if the index only contains entries for the rows
+// satisfying its predicate.
+func (desc *IndexDescriptor) IsPartial() bool {
+	return desc.Predicate != nil
+}
+
+// IsInverted returns true if the index is an inverted index, containing an
+// entry for each path or element of its single JSONB or ARRAY column.
+func (desc *IndexDescriptor) IsInverted() bool {
+	return desc.Type == IndexDescriptor_INVERTED
+}
+
 // FullColumnIDs returns the index column IDs including any implicit column IDs
 // for non-unique indexes. It also returns the direction with which each column
 // was encoded.
@@ -234,6 +266,12 @@ func (desc *TableDescriptor) SetID(id ID) {
 
 // TypeName returns the plain type of this descriptor.
 func (desc *TableDescriptor) TypeName() string {
+	if desc.IsView() {
+		return "view"
+	}
+	if desc.IsSequence() {
+		return "sequence"
+	}
 	return "table"
 }
 
@@ -341,7 +379,7 @@ func (desc *TableDescriptor) AllocateIDs() error {
 		desc.NextColumnID = 1
 	}
 	if desc.NextFamilyID == 0 {
-		if len(desc.Families) == 0 {
+		if len(desc.Families) == 0 && desc.IsTable() {
 			desc.Families = []ColumnFamilyDescriptor{
 				{ID: 0, Name: "primary"},
 			}
@@ -359,6 +397,7 @@ func (desc *TableDescriptor) AllocateIDs() error {
 	}
 
 	columnNames := map[string]ColumnID{}
+	columnKinds := map[ColumnID]ColumnType_Kind{}
 	fillColumnID := func(c *ColumnDescriptor) {
 		columnID := c.ID
 		if columnID == 0 {
@@ -366,6 +405,7 @@ func (desc *TableDescriptor) AllocateIDs() error {
 			desc.NextColumnID++
 		}
 		columnNames[ReNormalizeName(c.Name)] = columnID
+		columnKinds[columnID] = c.Type.Kind
 		c.ID = columnID
 	}
 	for i := range desc.Columns {
@@ -377,6 +417,12 @@ func (desc *TableDescriptor) AllocateIDs() error {
 		}
 	}
 
+	// Views don't store any data and sequences only store their value, so they
+	// have no indexes or column families.
+	if !desc.IsTable() {
+		return nil
+	}
+
 	// Keep track of unnamed indexes.
 	anonymousIndexes := make([]*IndexDescriptor, 0, len(desc.Indexes)+len(desc.Mutations))
 
@@ -402,6 +448,23 @@ func (desc *TableDescriptor) AllocateIDs() error {
 		index.allocateName(desc)
 	}
 
+	// The implicit columns of the indexes being added along with a new
+	// primary index are those of the new primary key. Indexes being dropped
+	// keep the implicit columns their entries were written with.
+	newPrimaryIndex, newPrimaryIndexMutationID := desc.newPrimaryIndex()
+	fixedImplicitColumns := make(map[*IndexDescriptor]struct{})
+	newPrimaryKeyIndexes := make(map[*IndexDescriptor]struct{})
+	for _, m := range desc.Mutations {
+		index := m.GetIndex()
+		switch {
+		case index == nil:
+		case m.Direction == DescriptorMutation_DROP || index.EncodingType == PrimaryIndexEncoding:
+			fixedImplicitColumns[index] = struct{}{}
+		case newPrimaryIndex != nil && m.MutationID == newPrimaryIndexMutationID:
+			newPrimaryKeyIndexes[index] = struct{}{}
+		}
+	}
+
 	// Populate IDs.
 	for _, index := range indexes {
 		if index.ID == 0 {
@@ -415,13 +478,47 @@ func (desc *TableDescriptor) AllocateIDs() error {
 			if index.ColumnIDs[j] == 0 {
 				index.ColumnIDs[j] = columnNames[ReNormalizeName(colName)]
 			}
+			// JSON documents have no key encoding, they can only be indexed by
+			// an inverted index.
+			kind, ok := columnKinds[index.ColumnIDs[j]]
+			if ok && kind == ColumnType_JSON && !index.IsInverted() {
+				return fmt.Errorf("index \"%s\" cannot contain column \"%s\" of type JSONB",
+					index.Name, colName)
+			}
+		}
+		if index.IsInverted() {
+			if len(index.ColumnIDs) != 1 {
+				return fmt.Errorf("inverted index \"%s\" must contain exactly one column",
+					index.Name)
+			}
+			if kind := columnKinds[index.ColumnIDs[0]]; kind != ColumnType_JSON && kind != ColumnType_ARRAY {
+				return fmt.Errorf("inverted index \"%s\" cannot contain column \"%s\": "+
+					"only JSONB and ARRAY columns can be inverted indexed",
+					index.Name, index.ColumnNames[0])
+			}
+			if index.Unique {
+				return fmt.Errorf("inverted index \"%s\" cannot be unique", index.Name)
+			}
+			if len(index.ColumnDirections) > 0 && index.ColumnDirections[0] != IndexDescriptor_ASC {
+				return fmt.Errorf("inverted index \"%s\" cannot be descending", index.Name)
+			}
+			if len(index.StoreColumnNames) > 0 {
+				return fmt.Errorf("inverted index \"%s\" cannot store columns", index.Name)
+			}
+		}
+		if _, ok := fixedImplicitColumns[index]; ok {
+			continue
 		}
 		if index != &desc.PrimaryIndex {
+			primaryIndex := &desc.PrimaryIndex
+			if _, ok := newPrimaryKeyIndexes[index]; ok {
+				primaryIndex = newPrimaryIndex
+			}
 			// Need to clear ImplicitColumnIDs because it is used by
 			// ContainsColumnID.
 			index.ImplicitColumnIDs = nil
 			var implicitColumnIDs []ColumnID
-			for _, primaryColID := range desc.PrimaryIndex.ColumnIDs {
+			for _, primaryColID := range primaryIndex.ColumnIDs {
 				if !index.ContainsColumnID(primaryColID) {
 					implicitColumnIDs = append(implicitColumnIDs, primaryColID)
 				}
@@ -439,7 +536,7 @@ func (desc *TableDescriptor) AllocateIDs() error {
 				} else {
 					col = desc.Mutations[i].GetColumn()
 				}
-				if desc.PrimaryIndex.ContainsColumnID(col.ID) {
+				if primaryIndex.ContainsColumnID(col.ID) {
 					continue
 				}
 				if index.ContainsColumnID(col.ID) {
@@ -467,7 +564,14 @@ func (desc *TableDescriptor) AllocateIDs() error {
 				family.ColumnIDs = append(family.ColumnIDs, 0)
 			}
 			if family.ColumnIDs[j] == 0 {
-				family.ColumnIDs[j] = columnNames[ReNormalizeName(colName)]
+				colID, ok := columnNames[ReNormalizeName(colName)]
+				if !ok {
+					return fmt.Errorf("column %q does not exist", colName)
+				}
+				family.ColumnIDs[j] = colID
+			}
+			if _, ok := columnsInFamilies[family.ColumnIDs[j]]; ok {
+				return fmt.Errorf("column %q cannot be part of more than one family", colName)
 			}
 			columnsInFamilies[family.ColumnIDs[j]] = struct{}{}
 		}
@@ -692,6 +796,42 @@ func (desc *TableDescriptor) validateCrossReferences(txn *client.Txn) error {
 		}
 	}
 	// TODO(dan): Also validate SharedPrefixLen in the interleaves.
+
+	// Check view dependencies.
+	for _, id := range desc.DependsOn {
+		dependency, err := getTable(id)
+		if err != nil {
+			return errors.Wrapf(err, "invalid view dependency table=%d", id)
+		}
+		found := false
+		for _, backref := range dependency.DependedOnBy {
+			if backref == desc.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("missing view dependency back reference to %s from %s",
+				desc.Name, dependency.Name)
+		}
+	}
+	for _, id := range desc.DependedOnBy {
+		dependent, err := getTable(id)
+		if err != nil {
+			return errors.Wrapf(err, "invalid view dependency backreference table=%d", id)
+		}
+		found := false
+		for _, ref := range dependent.DependsOn {
+			if ref == desc.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("broken view dependency backward reference from %s to %s",
+				desc.Name, dependent.Name)
+		}
+	}
 	return nil
 }
 
@@ -730,6 +870,26 @@ func (desc *TableDescriptor) ValidateTable() error {
 			desc.Name, desc.GetFormatVersion(), FamilyFormatVersion, InterleavedFormatVersion)
 	}
 
+	if desc.IsSequence() {
+		opts := desc.SequenceOpts
+		if opts.Increment == 0 {
+			return fmt.Errorf("sequence %q cannot have an increment of 0", desc.Name)
+		}
+		if opts.MinValue > opts.MaxValue {
+			return fmt.Errorf("sequence %q has MINVALUE (%d) greater than MAXVALUE (%d)",
+				desc.Name, opts.MinValue, opts.MaxValue)
+		}
+		if opts.Start < opts.MinValue || opts.Start > opts.MaxValue {
+			return fmt.Errorf("sequence %q has START value (%d) out of bounds [%d, %d]",
+				desc.Name, opts.Start, opts.MinValue, opts.MaxValue)
+		}
+		if len(desc.Columns) > 0 || len(desc.Families) > 0 || len(desc.PrimaryIndex.ColumnIDs) > 0 ||
+			len(desc.Indexes) > 0 {
+			return fmt.Errorf("sequence %q cannot have columns, column families or indexes", desc.Name)
+		}
+		return desc.Privileges.Validate(desc.GetID())
+	}
+
 	if len(desc.Columns) == 0 {
 		return ErrMissingColumns
 	}
@@ -807,6 +967,13 @@ func (desc *TableDescriptor) ValidateTable() error {
 
 	// TODO(dt): Validate each column only appears at-most-once in any FKs.
 
+	if desc.IsView() {
+		if len(desc.Families) > 0 || len(desc.PrimaryIndex.ColumnIDs) > 0 || len(desc.Indexes) > 0 {
+			return fmt.Errorf("view %q cannot have column families or indexes", desc.Name)
+		}
+		return desc.Privileges.Validate(desc.GetID())
+	}
+
 	if len(desc.Families) < 1 {
 		return fmt.Errorf("at least 1 column family must be specified")
 	}
@@ -939,13 +1106,22 @@ func (desc *TableDescriptor) ValidateTable() error {
 		}
 	}
 
-	for _, colID := range desc.PrimaryIndex.ColumnIDs {
+	primaryKeyColumnIDs := desc.PrimaryIndex.ColumnIDs
+	if newPrimaryIndex, _ := desc.newPrimaryIndex(); newPrimaryIndex != nil {
+		primaryKeyColumnIDs = append(primaryKeyColumnIDs[:len(primaryKeyColumnIDs):len(primaryKeyColumnIDs)],
+			newPrimaryIndex.ColumnIDs...)
+	}
+	for _, colID := range primaryKeyColumnIDs {
 		famID, ok := colIDToFamilyID[colID]
 		if !ok || famID != FamilyID(0) {
 			return fmt.Errorf("primary key column %d is not in column family 0", colID)
 		}
 	}
 
+	if err := desc.validatePartitioning(); err != nil {
+		return err
+	}
+
 	// Validate the privilege descriptor.
 	return desc.Privileges.Validate(desc.GetID())
 }
@@ -968,10 +1144,12 @@ func upperBoundColumnValueEncodedSize(col ColumnDescriptor) (int, bool) {
 		typ = encoding.Float
 	case ColumnType_INTERVAL:
 		typ = encoding.Duration
-	case ColumnType_STRING, ColumnType_BYTES:
+	case ColumnType_STRING, ColumnType_BYTES, ColumnType_COLLATEDSTRING:
 		// STRINGs are counted as runes, so this isn't totally correct, but this
 		// seems better than always assuming the maximum rune width.
 		typ, size = encoding.Bytes, int(col.Type.Width)
+	case ColumnType_ARRAY, ColumnType_JSON, ColumnType_INET:
+		typ = encoding.Bytes
 	case ColumnType_DECIMAL:
 		typ, size = encoding.Decimal, int(col.Type.Precision)
 	default:
@@ -1288,6 +1466,30 @@ func (desc *TableDescriptor) FindIndexByID(id IndexID) (*IndexDescriptor, error)
 	return nil, fmt.Errorf("index-id \"%d\" does not exist", id)
 }
 
+// IsView returns true if the TableDescriptor actually describes a view rather
+// than a table.
+func (desc *TableDescriptor) IsView() bool {
+	return desc.ViewQuery != ""
+}
+
+// IsSequence returns true if the TableDescriptor actually describes a
+// sequence rather than a table.
+func (desc *TableDescriptor) IsSequence() bool {
+	return desc.SequenceOpts != nil
+}
+
+// IsTemporary returns true if the TableDescriptor describes a temporary
+// table, which is private to the session that created it.
+func (desc *TableDescriptor) IsTemporary() bool {
+	return desc.Temporary != nil
+}
+
+// IsTable returns true if the TableDescriptor describes a table, as opposed
+// to a view or a sequence.
+func (desc *TableDescriptor) IsTable() bool {
+	return !desc.IsView() && !desc.IsSequence()
+}
+
 // IsInterleaved returns true if any part of this this table is interleaved with
 // another table's data.
 func (desc *TableDescriptor) IsInterleaved() bool {
@@ -1308,10 +1510,16 @@ func (desc *TableDescriptor) MakeMutationComplete(m DescriptorMutation) {
 	case DescriptorMutation_ADD:
 		switch t := m.Descriptor_.(type) {
 		case *DescriptorMutation_Column:
-			desc.AddColumn(*t.Column)
+			if m.ReplacesColumnID != 0 {
+				desc.replaceColumn(*t.Column, m.ReplacesColumnID)
+			} else {
+				desc.AddColumn(*t.Column)
+			}
 
 		case *DescriptorMutation_Index:
-			if err := desc.AddIndex(*t.Index, false); err != nil {
+			if m.ReplacesIndexID != 0 {
+				desc.replaceIndex(*t.Index, m.ReplacesIndexID)
+			} else if err := desc.AddIndex(*t.Index, false); err != nil {
 				panic(err)
 			}
 		}
@@ -1332,6 +1540,109 @@ func (desc *TableDescriptor) AddColumnMutation(c ColumnDescriptor, direction Des
 	desc.addMutation(m)
 }
 
+// AddColumnReplacementMutation adds a mutation adding the column c, which
+// takes the place of the column with the ID replaces once it is backfilled.
+func (desc *TableDescriptor) AddColumnReplacementMutation(c ColumnDescriptor, replaces ColumnID) {
+	m := DescriptorMutation{
+		Descriptor_:      &DescriptorMutation_Column{Column: &c},
+		Direction:        DescriptorMutation_ADD,
+		ReplacesColumnID: replaces,
+	}
+	desc.addMutation(m)
+}
+
+// replaceColumn puts the backfilled column col in the place of the column
+// with the ID replaces, taking over its name, DEFAULT expression and
+// constraint names. The replaced column is renamed to the former name of col
+// and queued to be dropped under a new mutation ID.
+func (desc *TableDescriptor) replaceColumn(col ColumnDescriptor, replaces ColumnID) {
+	for i := range desc.Columns {
+		if desc.Columns[i].ID != replaces {
+			continue
+		}
+		old := desc.Columns[i]
+		// The values of the column are no longer computed from the replaced
+		// column.
+		col.ComputedExpr = nil
+		col.DefaultExpr = old.DefaultExpr
+		col.DefaultExprConstraintName = old.DefaultExprConstraintName
+		col.NullableConstraintName = old.NullableConstraintName
+		old.Name, col.Name = col.Name, old.Name
+		old.DefaultExpr = nil
+		old.DefaultExprConstraintName = ""
+		old.NullableConstraintName = ""
+		desc.RenameColumnNormalized(col.ID, col.Name)
+		desc.RenameColumnNormalized(old.ID, old.Name)
+		desc.Columns[i] = col
+
+		desc.AddColumnMutation(old, DescriptorMutation_DROP)
+		return
+	}
+	panic(fmt.Sprintf("column-id \"%d\" replaced by column %q does not exist", replaces, col.Name))
+}
+
+// AddIndexReplacementMutation adds a mutation adding the index idx, which
+// takes the place of the index with the ID replaces once it is backfilled.
+func (desc *TableDescriptor) AddIndexReplacementMutation(idx IndexDescriptor, replaces IndexID) {
+	m := DescriptorMutation{
+		Descriptor_:     &DescriptorMutation_Index{Index: &idx},
+		Direction:       DescriptorMutation_ADD,
+		ReplacesIndexID: replaces,
+	}
+	desc.addMutation(m)
+}
+
+// replaceIndex puts the backfilled index idx in the place of the index with
+// the ID replaces, taking over its name. An index with the primary index
+// encoding replaces the primary index. The replaced index is renamed to the
+// former name of idx and queued to be dropped under a new mutation ID.
+func (desc *TableDescriptor) replaceIndex(idx IndexDescriptor, replaces IndexID) {
+	old, err := desc.FindIndexByID(replaces)
+	if err != nil || (old != &desc.PrimaryIndex && !desc.isActiveIndex(old)) {
+		panic(fmt.Sprintf("index-id \"%d\" replaced by index %q does not exist", replaces, idx.Name))
+	}
+	dropped := *old
+	dropped.Name, idx.Name = idx.Name, dropped.Name
+	if old == &desc.PrimaryIndex {
+		// The entries of the former primary index are deleted like those of
+		// any index with the primary index encoding, while the encoding of
+		// the primary index is implied.
+		dropped.EncodingType = PrimaryIndexEncoding
+		idx.EncodingType = SecondaryIndexEncoding
+	}
+	*old = idx
+	desc.AddIndexMutation(dropped, DescriptorMutation_DROP)
+}
+
+// isActiveIndex returns true if idx points into desc.Indexes.
+func (desc *TableDescriptor) isActiveIndex(idx *IndexDescriptor) bool {
+	for i := range desc.Indexes {
+		if &desc.Indexes[i] == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// newPrimaryIndex returns the index being added to replace the primary index
+// of the table, and the ID of the mutation adding it, if there is one.
+func (desc *TableDescriptor) newPrimaryIndex() (*IndexDescriptor, MutationID) {
+	for _, m := range desc.Mutations {
+		if index := m.GetIndex(); index != nil && m.Direction == DescriptorMutation_ADD &&
+			index.EncodingType == PrimaryIndexEncoding {
+			return index, m.MutationID
+		}
+	}
+	return nil, InvalidMutationID
+}
+
+// IsPrimaryKeyChanging returns true if the primary key of the table is being
+// changed.
+func (desc *TableDescriptor) IsPrimaryKeyChanging() bool {
+	index, _ := desc.newPrimaryIndex()
+	return index != nil
+}
+
 // AddIndexMutation adds an index mutation to desc.Mutations.
 func (desc *TableDescriptor) AddIndexMutation(idx IndexDescriptor, direction DescriptorMutation_Direction) {
 	m := DescriptorMutation{Descriptor_: &DescriptorMutation_Index{Index: &idx}, Direction: direction}
@@ -1418,6 +1729,33 @@ func (desc *TableDescriptor) IsEmpty() bool {
 	return desc.ID == 0
 }
 
+// WritableComputedColumns returns the computed columns whose values are
+// written along with the rows of the table: the active computed columns,
+// followed by the computed columns being added in the WRITE_ONLY state.
+func (desc *TableDescriptor) WritableComputedColumns() []ColumnDescriptor {
+	var cols []ColumnDescriptor
+	for _, col := range desc.Columns {
+		if col.IsComputed() {
+			cols = append(cols, col)
+		}
+	}
+	for _, m := range desc.Mutations {
+		if m.Direction != DescriptorMutation_ADD || m.State != DescriptorMutation_WRITE_ONLY {
+			continue
+		}
+		if col := m.GetColumn(); col != nil && col.IsComputed() {
+			cols = append(cols, *col)
+		}
+	}
+	return cols
+}
+
+// IsComputed returns true if the column's value is computed from an
+// expression over the other columns of its row.
+func (desc *ColumnDescriptor) IsComputed() bool {
+	return desc.ComputedExpr != nil
+}
+
 // SQLString returns the SQL string corresponding to the type.
 func (c *ColumnType) SQLString() string {
 	switch c.Kind {
@@ -1429,6 +1767,9 @@ func (c *ColumnType) SQLString() string {
 		if c.Precision > 0 {
 			return fmt.Sprintf("%s(%d)", c.Kind.String(), c.Precision)
 		}
+	case ColumnType_COLLATEDSTRING:
+		stringTyp := ColumnType{Kind: ColumnType_STRING, Width: c.Width}
+		return fmt.Sprintf("%s COLLATE %s", stringTyp.SQLString(), *c.Locale)
 	case ColumnType_DECIMAL:
 		if c.Precision > 0 {
 			if c.Width > 0 {
@@ -1438,6 +1779,11 @@ func (c *ColumnType) SQLString() string {
 		}
 	case ColumnType_TIMESTAMPTZ:
 		return "TIMESTAMP WITH TIME ZONE"
+	case ColumnType_ARRAY:
+		elemTyp := ColumnType{Kind: *c.ArrayContents}
+		return elemTyp.SQLString() + "[]"
+	case ColumnType_JSON:
+		return "JSONB"
 	}
 	return c.Kind.String()
 }
@@ -1447,7 +1793,7 @@ func (c *ColumnType) SQLString() string {
 // type is not a character or bit string, or if the string's length is not bounded.
 func (c *ColumnType) MaxCharacterLength() (int32, bool) {
 	switch c.Kind {
-	case ColumnType_INT, ColumnType_STRING:
+	case ColumnType_INT, ColumnType_STRING, ColumnType_COLLATEDSTRING:
 		if c.Width > 0 {
 			return c.Width, true
 		}
@@ -1460,7 +1806,7 @@ func (c *ColumnType) MaxCharacterLength() (int32, bool) {
 // is not a character string, or if the string's length is not bounded.
 func (c *ColumnType) MaxOctetLength() (int32, bool) {
 	switch c.Kind {
-	case ColumnType_STRING:
+	case ColumnType_STRING, ColumnType_COLLATEDSTRING:
 		if c.Width > 0 {
 			return c.Width * utf8.UTFMax, true
 		}
@@ -1527,6 +1873,10 @@ func (k ColumnType_Kind) ToDatumType() parser.Datum {
 		return parser.TypeTimestampTZ
 	case ColumnType_INTERVAL:
 		return parser.TypeInterval
+	case ColumnType_JSON:
+		return parser.TypeJSON
+	case ColumnType_INET:
+		return parser.TypeINet
 	}
 	return nil
 }
@@ -1534,6 +1884,22 @@ func (k ColumnType_Kind) ToDatumType() parser.Datum {
 // ToDatumType converts the ColumnType to the correct type Datum, or
 // nil if there is no correspondence.
 func (c *ColumnType) ToDatumType() parser.Datum {
+	if c.Kind == ColumnType_ARRAY {
+		if c.ArrayContents == nil {
+			return nil
+		}
+		paramTyp := c.ArrayContents.ToDatumType()
+		if paramTyp == nil {
+			return nil
+		}
+		return parser.NewDArray(paramTyp)
+	}
+	if c.Kind == ColumnType_COLLATEDSTRING {
+		if c.Locale == nil {
+			return nil
+		}
+		return &parser.DCollatedString{Locale: *c.Locale}
+	}
 	return c.Kind.ToDatumType()
 }
 
@@ -822,6 +822,9 @@ func TestValidateCrossTableReferences(t *testing.T) {
 func TestColumnTypeSQLString(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
+	intKind := ColumnType_INT
+	stringKind := ColumnType_STRING
+	deLocale := "de"
 	testData := []struct {
 		colType     ColumnType
 		expectedSQL string
@@ -839,6 +842,12 @@ func TestColumnTypeSQLString(t *testing.T) {
 		{ColumnType{Kind: ColumnType_STRING}, "STRING"},
 		{ColumnType{Kind: ColumnType_STRING, Width: 10}, "STRING(10)"},
 		{ColumnType{Kind: ColumnType_BYTES}, "BYTES"},
+		{ColumnType{Kind: ColumnType_ARRAY, ArrayContents: &intKind}, "INT[]"},
+		{ColumnType{Kind: ColumnType_ARRAY, ArrayContents: &stringKind}, "STRING[]"},
+		{ColumnType{Kind: ColumnType_JSON}, "JSONB"},
+		{ColumnType{Kind: ColumnType_INET}, "INET"},
+		{ColumnType{Kind: ColumnType_COLLATEDSTRING, Locale: &deLocale}, "STRING COLLATE de"},
+		{ColumnType{Kind: ColumnType_COLLATEDSTRING, Width: 10, Locale: &deLocale}, "STRING(10) COLLATE de"},
 	}
 	for i, d := range testData {
 		sql := d.colType.SQLString()
@@ -49,10 +49,14 @@ CREATE TABLE system.descriptor (
 );`
 
 	// UsersTableSchema is checked in TestSystemTables.
+	// The users and roles: roles cannot log in and have no password, they
+	// only hold privileges for their members, which are listed in
+	// system.role_members.
 	UsersTableSchema = `
 CREATE TABLE system.users (
   username       STRING PRIMARY KEY,
-  hashedPassword BYTES
+  hashedPassword BYTES,
+  isRole         BOOL NOT NULL DEFAULT false
 );`
 
 	// ZonesTableSchema is checked in TestSystemTables.
@@ -62,6 +66,26 @@ CREATE TABLE system.zones (
   id     INT PRIMARY KEY,
   config BYTES
 );`
+
+	// SettingsTableSchema is checked in TestSystemTables.
+	// The cluster settings set by SET CLUSTER SETTING, gossiped to all the
+	// nodes.
+	SettingsTableSchema = `
+CREATE TABLE system.settings (
+  name        STRING PRIMARY KEY,
+  value       STRING NOT NULL,
+  lastUpdated TIMESTAMP NOT NULL,
+  valueType   STRING
+);`
+
+	// ProtectedTimestampsTableSchema is checked in TestSystemTables.
+	// The records protecting spans from garbage collection, gossiped to all
+	// the nodes.
+	ProtectedTimestampsTableSchema = `
+CREATE TABLE system.protected_ts (
+  id     INT PRIMARY KEY,
+  record BYTES
+);`
 )
 
 // These system tables are not part of the system config.
@@ -102,8 +126,11 @@ var (
 	colTypeInt    = ColumnType{Kind: ColumnType_INT}
 	colTypeString = ColumnType{Kind: ColumnType_STRING}
 	colTypeBytes  = ColumnType{Kind: ColumnType_BYTES}
+	colTypeBool   = ColumnType{Kind: ColumnType_BOOL}
 	singleASC     = []IndexDescriptor_Direction{IndexDescriptor_ASC}
 	singleID1     = []ColumnID{1}
+
+	falseBoolString = "false"
 )
 
 // These system config TableDescriptor literals should match the descriptor
@@ -183,14 +210,16 @@ var (
 		Columns: []ColumnDescriptor{
 			{Name: "username", ID: 1, Type: colTypeString},
 			{Name: "hashedPassword", ID: 2, Type: colTypeBytes, Nullable: true},
+			{Name: "isRole", ID: 3, Type: colTypeBool, DefaultExpr: &falseBoolString},
 		},
-		NextColumnID: 3,
+		NextColumnID: 4,
 		Families: []ColumnFamilyDescriptor{
 			{Name: "primary", ID: 0, ColumnNames: []string{"username"}, ColumnIDs: singleID1},
 			{Name: "fam_2_hashedPassword", ID: 2, ColumnNames: []string{"hashedPassword"}, ColumnIDs: []ColumnID{2}, DefaultColumnID: 2},
+			{Name: "fam_3_isRole", ID: 3, ColumnNames: []string{"isRole"}, ColumnIDs: []ColumnID{3}, DefaultColumnID: 3},
 		},
 		PrimaryIndex:   pk("username"),
-		NextFamilyID:   3,
+		NextFamilyID:   4,
 		NextIndexID:    2,
 		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemConfigAllowedPrivileges[4]),
 		FormatVersion:  FamilyFormatVersion,
@@ -220,16 +249,68 @@ var (
 		NextMutationID: 1,
 	}
 
+	// SettingsTable is the descriptor for the settings table.
+	SettingsTable = TableDescriptor{
+		Name:     "settings",
+		ID:       keys.SettingsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "name", ID: 1, Type: colTypeString},
+			{Name: "value", ID: 2, Type: colTypeString},
+			{Name: "lastUpdated", ID: 3, Type: ColumnType{Kind: ColumnType_TIMESTAMP}},
+			{Name: "valueType", ID: 4, Type: colTypeString, Nullable: true},
+		},
+		NextColumnID: 5,
+		Families: []ColumnFamilyDescriptor{
+			{Name: "primary", ID: 0, ColumnNames: []string{"name"}, ColumnIDs: singleID1},
+			{Name: "fam_2_value", ID: 2, ColumnNames: []string{"value"}, ColumnIDs: []ColumnID{2}, DefaultColumnID: 2},
+			{Name: "fam_3_lastUpdated", ID: 3, ColumnNames: []string{"lastUpdated"}, ColumnIDs: []ColumnID{3}, DefaultColumnID: 3},
+			{Name: "fam_4_valueType", ID: 4, ColumnNames: []string{"valueType"}, ColumnIDs: []ColumnID{4}, DefaultColumnID: 4},
+		},
+		NextFamilyID:   5,
+		PrimaryIndex:   pk("name"),
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemConfigAllowedPrivileges[keys.SettingsTableID]),
+		FormatVersion:  FamilyFormatVersion,
+		NextMutationID: 1,
+	}
+
+	// ProtectedTimestampsTable is the descriptor for the protected_ts table.
+	ProtectedTimestampsTable = TableDescriptor{
+		Name:     "protected_ts",
+		ID:       keys.ProtectedTimestampsTableID,
+		ParentID: 1,
+		Version:  1,
+		Columns: []ColumnDescriptor{
+			{Name: "id", ID: 1, Type: colTypeInt},
+			{Name: "record", ID: 2, Type: colTypeBytes, Nullable: true},
+		},
+		NextColumnID: 3,
+		Families: []ColumnFamilyDescriptor{
+			{Name: "primary", ID: 0, ColumnNames: []string{"id"}, ColumnIDs: singleID1},
+			{Name: "fam_2_record", ID: 2, ColumnNames: []string{"record"}, ColumnIDs: []ColumnID{2}, DefaultColumnID: 2},
+		},
+		PrimaryIndex:   pk("id"),
+		NextFamilyID:   3,
+		NextIndexID:    2,
+		Privileges:     NewPrivilegeDescriptor(security.RootUser, SystemConfigAllowedPrivileges[keys.ProtectedTimestampsTableID]),
+		FormatVersion:  FamilyFormatVersion,
+		NextMutationID: 1,
+	}
+
 	// SystemConfigAllowedPrivileges describes the privileges allowed for each
 	// system config object. No user may have more than those privileges, and
 	// the root user must have exactly those privileges. CREATE|DROP|ALL
 	// should always be denied.
 	SystemConfigAllowedPrivileges = map[ID]privilege.List{
-		keys.SystemDatabaseID:  privilege.ReadData,
-		keys.NamespaceTableID:  privilege.ReadData,
-		keys.DescriptorTableID: privilege.ReadData,
-		keys.UsersTableID:      privilege.ReadWriteData,
-		keys.ZonesTableID:      privilege.ReadWriteData,
+		keys.SystemDatabaseID:           privilege.ReadData,
+		keys.NamespaceTableID:           privilege.ReadData,
+		keys.DescriptorTableID:          privilege.ReadData,
+		keys.UsersTableID:               privilege.ReadWriteData,
+		keys.ZonesTableID:               privilege.ReadWriteData,
+		keys.SettingsTableID:            privilege.ReadWriteData,
+		keys.ProtectedTimestampsTableID: privilege.ReadWriteData,
 	}
 )
 
@@ -324,6 +405,8 @@ func addSystemDatabaseToSchema(target *MetadataSchema) {
 	target.AddConfigDescriptor(keys.SystemDatabaseID, &DescriptorTable)
 	target.AddConfigDescriptor(keys.SystemDatabaseID, &UsersTable)
 	target.AddConfigDescriptor(keys.SystemDatabaseID, &ZonesTable)
+	target.AddConfigDescriptor(keys.SystemDatabaseID, &SettingsTable)
+	target.AddConfigDescriptor(keys.SystemDatabaseID, &ProtectedTimestampsTable)
 
 	// Add other system tables.
 	target.AddDescriptor(keys.SystemDatabaseID, &LeaseTable)
@@ -17,7 +17,9 @@
 package sqlbase
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
 	"time"
 	"unicode/utf8"
 
@@ -30,6 +32,8 @@ import (
 	"github.com/cockroachdb/cockroach/util/decimal"
 	"github.com/cockroachdb/cockroach/util/duration"
 	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/ipaddr"
+	"github.com/cockroachdb/cockroach/util/jsonb"
 
 	"github.com/pkg/errors"
 )
@@ -115,6 +119,28 @@ func MakeColumnDefDescs(d *parser.ColumnTableDef) (*ColumnDescriptor, *IndexDesc
 	case *parser.BytesColType:
 		col.Type.Kind = ColumnType_BYTES
 		colDatumType = parser.TypeBytes
+	case *parser.ArrayColType:
+		elemTyp, _, err := MakeColumnDefDescs(&parser.ColumnTableDef{Name: d.Name, Type: t.ParamType})
+		if err != nil {
+			return nil, nil, err
+		}
+		col.Type.Kind = ColumnType_ARRAY
+		col.Type.ArrayContents = &elemTyp.Type.Kind
+		colDatumType = col.Type.ToDatumType()
+	case *parser.JSONColType:
+		col.Type.Kind = ColumnType_JSON
+		colDatumType = parser.TypeJSON
+	case *parser.INetColType:
+		col.Type.Kind = ColumnType_INET
+		colDatumType = parser.TypeINet
+	case *parser.CollatedStringColType:
+		if err := parser.ValidateLocale(t.Locale); err != nil {
+			return nil, nil, err
+		}
+		col.Type.Kind = ColumnType_COLLATEDSTRING
+		col.Type.Width = int32(t.N)
+		col.Type.Locale = &t.Locale
+		colDatumType = col.Type.ToDatumType()
 	default:
 		return nil, nil, errors.Errorf("unexpected type %T", t)
 	}
@@ -130,6 +156,16 @@ func MakeColumnDefDescs(d *parser.ColumnTableDef) (*ColumnDescriptor, *IndexDesc
 		}
 	}
 
+	if d.Computed.Computed {
+		if d.DefaultExpr.Expr != nil {
+			return nil, nil, fmt.Errorf("computed column %q cannot have a DEFAULT expression", d.Name)
+		}
+		// The expression references other columns, so it is validated against
+		// the table descriptor once all of its columns are known.
+		s := d.Computed.Expr.String()
+		col.ComputedExpr = &s
+	}
+
 	if d.DefaultExpr.Expr != nil {
 		// Verify the default expression type is compatible with the column type.
 		if err := SanitizeVarFreeExpr(d.DefaultExpr.Expr, colDatumType, "DEFAULT"); err != nil {
@@ -192,10 +228,29 @@ func EncodeIndexKey(
 	colMap map[ColumnID]int,
 	values []parser.Datum,
 	keyPrefix []byte,
+) (key []byte, containsNull bool, err error) {
+	return EncodePartialIndexKey(
+		tableDesc, index, len(index.ColumnIDs), colMap, values, keyPrefix)
+}
+
+// EncodePartialIndexKey is a version of EncodeIndexKey which only encodes the
+// first numCols columns of the index. The resulting key is a prefix of the
+// keys of the index entries with the same values for these columns, e.g. a
+// split point in front of them.
+func EncodePartialIndexKey(
+	tableDesc *TableDescriptor,
+	index *IndexDescriptor,
+	numCols int,
+	colMap map[ColumnID]int,
+	values []parser.Datum,
+	keyPrefix []byte,
 ) (key []byte, containsNull bool, err error) {
 	key = keyPrefix
-	colIDs := index.ColumnIDs
+	colIDs := index.ColumnIDs[:numCols]
 	dirs := directions(index.ColumnDirections)
+	if len(dirs) > numCols {
+		dirs = dirs[:numCols]
+	}
 
 	if len(index.Interleave.Ancestors) > 0 {
 		for i, ancestor := range index.Interleave.Ancestors {
@@ -206,6 +261,10 @@ func EncodeIndexKey(
 			}
 
 			length := int(ancestor.SharedPrefixLen)
+			partial := length > len(colIDs)
+			if partial {
+				length = len(colIDs)
+			}
 			var n bool
 			key, n, err = EncodeColumns(colIDs[:length], dirs[:length], colMap, values, key)
 			if err != nil {
@@ -213,6 +272,10 @@ func EncodeIndexKey(
 			}
 			colIDs, dirs = colIDs[length:], dirs[length:]
 			containsNull = containsNull || n
+			if partial {
+				// The key ends within the columns shared with this ancestor.
+				return key, containsNull, nil
+			}
 
 			// We reuse NotNullDescending (0xfe) as the interleave sentinel.
 			key = encoding.EncodeNotNullDescending(key)
@@ -406,6 +469,37 @@ func EncodeTableKey(b []byte, val parser.Datum, dir encoding.Direction) ([]byte,
 			}
 		}
 		return b, nil
+	case *parser.DArray:
+		// The elements are encoded in ascending order and the result is
+		// wrapped as bytes in the requested direction, which both delimits
+		// the array and preserves its ordering.
+		var elems []byte
+		for _, datum := range t.Array {
+			var err error
+			elems, err = EncodeTableKey(elems, datum, encoding.Ascending)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if dir == encoding.Ascending {
+			return encoding.EncodeBytesAscending(b, elems), nil
+		}
+		return encoding.EncodeBytesDescending(b, elems), nil
+	case *parser.DIPAddr:
+		// The encoding of addresses sorts bytewise in address order.
+		if dir == encoding.Ascending {
+			return encoding.EncodeBytesAscending(b, t.Encode(nil)), nil
+		}
+		return encoding.EncodeBytesDescending(b, t.Encode(nil)), nil
+	case *parser.DCollatedString:
+		// The collation key determines the order; the contents follow it
+		// because the key cannot be decoded and need not be unique.
+		if dir == encoding.Ascending {
+			b = encoding.EncodeBytesAscending(b, t.Key)
+			return encoding.EncodeStringAscending(b, t.Contents), nil
+		}
+		b = encoding.EncodeBytesDescending(b, t.Key)
+		return encoding.EncodeStringDescending(b, t.Contents), nil
 	}
 	return nil, errors.Errorf("unable to encode table key: %T", val)
 }
@@ -437,10 +531,78 @@ func EncodeTableValue(appendTo []byte, colID ColumnID, val parser.Datum) ([]byte
 		return encoding.EncodeTimeValue(appendTo, uint32(colID), t.Time), nil
 	case *parser.DInterval:
 		return encoding.EncodeDurationValue(appendTo, uint32(colID), t.Duration), nil
+	case *parser.DArray:
+		elems, err := encodeArrayValue(t)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), elems), nil
+	case *parser.DJSON:
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), jsonb.Encode(nil, t.JSON)), nil
+	case *parser.DIPAddr:
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), t.Encode(nil)), nil
+	case *parser.DCollatedString:
+		return encoding.EncodeBytesValue(appendTo, uint32(colID), []byte(t.Contents)), nil
 	}
 	return nil, errors.Errorf("unable to encode table value: %T", val)
 }
 
+// encodeArrayValue encodes the elements of an array as a sequence of values
+// without column IDs.
+func encodeArrayValue(d *parser.DArray) ([]byte, error) {
+	var b []byte
+	for _, datum := range d.Array {
+		var err error
+		b, err = EncodeTableValue(b, ColumnID(encoding.NoColumnID), datum)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// decodeArrayValue decodes the elements of an array encoded by
+// encodeArrayValue.
+func decodeArrayValue(a *DatumAlloc, paramTyp parser.Datum, b []byte) (*parser.DArray, error) {
+	arr := parser.NewDArray(paramTyp)
+	for len(b) > 0 {
+		var datum parser.Datum
+		var err error
+		datum, b, err = DecodeTableValue(a, paramTyp, b)
+		if err != nil {
+			return nil, err
+		}
+		if err := arr.Append(datum); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// decodeJSONValue decodes a JSON document encoded by jsonb.Encode.
+func decodeJSONValue(b []byte) (*parser.DJSON, error) {
+	j, rem, err := jsonb.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rem) != 0 {
+		return nil, errors.Errorf("%d trailing bytes in encoded JSON value", len(rem))
+	}
+	return parser.NewDJSON(j), nil
+}
+
+// decodeIPAddrValue decodes an IP address encoded by ipaddr.IPAddr.Encode.
+func decodeIPAddrValue(b []byte) (*parser.DIPAddr, error) {
+	ip, rem, err := ipaddr.Decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rem) != 0 {
+		return nil, errors.Errorf("%d trailing bytes in encoded IP address", len(rem))
+	}
+	return parser.NewDIPAddr(ip), nil
+}
+
 // MakeKeyVals returns a slice of Datums with the correct types for the given
 // columns.
 func MakeKeyVals(
@@ -738,6 +900,8 @@ type DatumAlloc struct {
 	dtimestampAlloc   []parser.DTimestamp
 	dtimestampTzAlloc []parser.DTimestampTZ
 	dintervalAlloc    []parser.DInterval
+
+	env parser.CollationEnvironment
 }
 
 // NewDInt allocates a DInt.
@@ -776,6 +940,11 @@ func (a *DatumAlloc) NewDString(v parser.DString) *parser.DString {
 	return r
 }
 
+// NewDCollatedString allocates a DCollatedString.
+func (a *DatumAlloc) NewDCollatedString(contents, locale string) *parser.DCollatedString {
+	return parser.NewDCollatedString(contents, locale, &a.env)
+}
+
 // NewDBytes allocates a DBytes.
 func (a *DatumAlloc) NewDBytes(v parser.DBytes) *parser.DBytes {
 	buf := &a.dbytesAlloc
@@ -946,6 +1115,58 @@ func DecodeTableKey(
 			rkey, d, err = encoding.DecodeDurationDescending(key)
 		}
 		return a.NewDInterval(parser.DInterval{Duration: d}), rkey, err
+	case *parser.DArray:
+		var elems []byte
+		if dir == encoding.Ascending {
+			rkey, elems, err = encoding.DecodeBytesAscending(key, nil)
+		} else {
+			rkey, elems, err = encoding.DecodeBytesDescending(key, nil)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		paramTyp := valType.(*parser.DArray).ParamTyp
+		arr := parser.NewDArray(paramTyp)
+		for len(elems) > 0 {
+			var datum parser.Datum
+			datum, elems, err = DecodeTableKey(a, paramTyp, elems, encoding.Ascending)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := arr.Append(datum); err != nil {
+				return nil, nil, err
+			}
+		}
+		return arr, rkey, nil
+	case *parser.DIPAddr:
+		var b []byte
+		if dir == encoding.Ascending {
+			rkey, b, err = encoding.DecodeBytesAscending(key, nil)
+		} else {
+			rkey, b, err = encoding.DecodeBytesDescending(key, nil)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ip, err := decodeIPAddrValue(b)
+		return ip, rkey, err
+	case *parser.DCollatedString:
+		var contents string
+		if dir == encoding.Ascending {
+			if rkey, _, err = encoding.DecodeBytesAscending(key, nil); err != nil {
+				return nil, nil, err
+			}
+			rkey, contents, err = encoding.DecodeUnsafeStringAscending(rkey, nil)
+		} else {
+			if rkey, _, err = encoding.DecodeBytesDescending(key, nil); err != nil {
+				return nil, nil, err
+			}
+			rkey, contents, err = encoding.DecodeUnsafeStringDescending(rkey, nil)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return a.NewDCollatedString(contents, valType.(*parser.DCollatedString).Locale), rkey, nil
 	default:
 		return nil, nil, errors.Errorf("TODO(pmattis): decoded index key: %s", valType.Type())
 	}
@@ -1005,6 +1226,37 @@ func DecodeTableValue(a *DatumAlloc, valType parser.Datum, b []byte) (parser.Dat
 		var d duration.Duration
 		b, d, err = encoding.DecodeDurationValue(b)
 		return a.NewDInterval(parser.DInterval{Duration: d}), b, err
+	case *parser.DArray:
+		var data []byte
+		b, data, err = encoding.DecodeBytesValue(b)
+		if err != nil {
+			return nil, b, err
+		}
+		arr, err := decodeArrayValue(a, valType.(*parser.DArray).ParamTyp, data)
+		return arr, b, err
+	case *parser.DJSON:
+		var data []byte
+		b, data, err = encoding.DecodeBytesValue(b)
+		if err != nil {
+			return nil, b, err
+		}
+		j, err := decodeJSONValue(data)
+		return j, b, err
+	case *parser.DIPAddr:
+		var data []byte
+		b, data, err = encoding.DecodeBytesValue(b)
+		if err != nil {
+			return nil, b, err
+		}
+		ip, err := decodeIPAddrValue(data)
+		return ip, b, err
+	case *parser.DCollatedString:
+		var data []byte
+		b, data, err = encoding.DecodeBytesValue(b)
+		if err != nil {
+			return nil, b, err
+		}
+		return a.NewDCollatedString(string(data), valType.(*parser.DCollatedString).Locale), b, nil
 	default:
 		return nil, nil, errors.Errorf("TODO(pmattis): decoded index value: %s", valType.Type())
 	}
@@ -1016,8 +1268,8 @@ type IndexEntry struct {
 	Value roachpb.Value
 }
 
-// EncodeSecondaryIndex encodes key/values for a secondary index. colMap maps
-// ColumnIDs to indices in `values`.
+// EncodeSecondaryIndex encodes key/values for a secondary forward index.
+// colMap maps ColumnIDs to indices in `values`.
 func EncodeSecondaryIndex(
 	tableDesc *TableDescriptor,
 	secondaryIndex *IndexDescriptor,
@@ -1067,20 +1319,192 @@ func EncodeSecondaryIndex(
 	return entry, nil
 }
 
+// EncodeSecondaryIndexEntries encodes the key/values of the entries of a
+// secondary index for a row. A forward index has one entry per row, while an
+// inverted index has an entry for each path or element of the value of its
+// column. colMap maps ColumnIDs to indices in `values`.
+func EncodeSecondaryIndexEntries(
+	tableDesc *TableDescriptor,
+	secondaryIndex *IndexDescriptor,
+	colMap map[ColumnID]int,
+	values []parser.Datum,
+) ([]IndexEntry, error) {
+	if secondaryIndex.EncodingType == PrimaryIndexEncoding {
+		return EncodePrimaryIndex(tableDesc, secondaryIndex, colMap, values)
+	}
+	if !secondaryIndex.IsInverted() {
+		entry, err := EncodeSecondaryIndex(tableDesc, secondaryIndex, colMap, values)
+		if err != nil {
+			return nil, err
+		}
+		return []IndexEntry{entry}, nil
+	}
+
+	var val parser.Datum = parser.DNull
+	if i, ok := colMap[secondaryIndex.ColumnIDs[0]]; ok {
+		val = values[i]
+	}
+	invertedKeys, err := EncodeInvertedIndexKeys(val)
+	if err != nil {
+		return nil, err
+	}
+	if len(invertedKeys) == 0 {
+		return nil, nil
+	}
+
+	// The implicit columns are appended to each key to make it unique.
+	extraKey, _, err := EncodeColumns(secondaryIndex.ImplicitColumnIDs, nil,
+		colMap, values, nil)
+	if err != nil {
+		return nil, err
+	}
+	secondaryIndexKeyPrefix := MakeIndexKeyPrefix(tableDesc, secondaryIndex.ID)
+	entries := make([]IndexEntry, len(invertedKeys))
+	for i, invertedKey := range invertedKeys {
+		key := encoding.EncodeBytesAscending(append([]byte(nil), secondaryIndexKeyPrefix...), invertedKey)
+		key = append(key, extraKey...)
+		entries[i].Key = keys.MakeRowSentinelKey(key)
+		// The zero value for an index-key is a 0-length bytes value.
+		entries[i].Value.SetBytes([]byte{})
+	}
+	return entries, nil
+}
+
+// EncodePrimaryIndex encodes the key/values of the entries of a row in an
+// index with the primary index encoding: an entry for each column family
+// holding the values of its columns which aren't part of the key. The entry
+// of the first family acts as a sentinel and is always present, while those
+// of the other families are omitted if all of their values are NULL. colMap
+// maps ColumnIDs to indices in `values`.
+func EncodePrimaryIndex(
+	tableDesc *TableDescriptor,
+	index *IndexDescriptor,
+	colMap map[ColumnID]int,
+	values []parser.Datum,
+) ([]IndexEntry, error) {
+	keyPrefix := MakeIndexKeyPrefix(tableDesc, index.ID)
+	indexKey, _, err := EncodeIndexKey(tableDesc, index, colMap, values, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	indexKey = indexKey[:len(indexKey):len(indexKey)]
+
+	var entries []IndexEntry
+	for _, family := range tableDesc.Families {
+		familyKey := keys.MakeFamilyKey(indexKey, uint32(family.ID))
+
+		if len(family.ColumnIDs) == 1 && family.ColumnIDs[0] == family.DefaultColumnID &&
+			!index.ContainsColumnID(family.DefaultColumnID) {
+			// Storage optimization to store DefaultColumnID directly as a value.
+			idx, ok := colMap[family.DefaultColumnID]
+			if !ok || values[idx] == parser.DNull {
+				continue
+			}
+			col, err := tableDesc.FindColumnByID(family.DefaultColumnID)
+			if err != nil {
+				return nil, err
+			}
+			value, err := MarshalColumnValue(*col, values[idx])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, IndexEntry{Key: familyKey, Value: value})
+			continue
+		}
+
+		colIDs := append([]ColumnID(nil), family.ColumnIDs...)
+		sort.Sort(byColumnID(colIDs))
+		var valueBuf []byte
+		var lastColID ColumnID
+		for _, colID := range colIDs {
+			if index.ContainsColumnID(colID) {
+				// The values of the indexed columns are encoded in the key.
+				continue
+			}
+			idx, ok := colMap[colID]
+			if !ok || values[idx] == parser.DNull {
+				continue
+			}
+			valueBuf, err = EncodeTableValue(valueBuf, colID-lastColID, values[idx])
+			if err != nil {
+				return nil, err
+			}
+			lastColID = colID
+		}
+		if family.ID != 0 && len(valueBuf) == 0 {
+			continue
+		}
+		entry := IndexEntry{Key: familyKey}
+		entry.Value.SetTuple(valueBuf)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// EncodeInvertedIndexKeys returns the keys, without the index prefix, of the
+// inverted index entries for the value of a JSONB or ARRAY column, in sorted
+// order and without duplicates. A JSONB value has a key for each path to one
+// of its scalars or empty containers, and an ARRAY value has a key for each of
+// its non-NULL elements. NULL values have no keys.
+func EncodeInvertedIndexKeys(val parser.Datum) ([][]byte, error) {
+	switch t := val.(type) {
+	case *parser.DJSON:
+		return jsonb.EncodeInvertedIndexKeys(t.JSON), nil
+	case *parser.DArray:
+		var invertedKeys [][]byte
+		for _, elem := range t.Array {
+			if elem == parser.DNull {
+				continue
+			}
+			key, err := EncodeTableKey(nil, elem, encoding.Ascending)
+			if err != nil {
+				return nil, err
+			}
+			invertedKeys = append(invertedKeys, key)
+		}
+		sort.Sort(byteSlices(invertedKeys))
+		res := invertedKeys[:0]
+		for i, key := range invertedKeys {
+			if i == 0 || !bytes.Equal(key, invertedKeys[i-1]) {
+				res = append(res, key)
+			}
+		}
+		return res, nil
+	}
+	if val == parser.DNull {
+		return nil, nil
+	}
+	return nil, errors.Errorf("value of type %s cannot be inverted indexed", val.Type())
+}
+
+type byteSlices [][]byte
+
+func (b byteSlices) Len() int           { return len(b) }
+func (b byteSlices) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byteSlices) Less(i, j int) bool { return bytes.Compare(b[i], b[j]) < 0 }
+
+type byColumnID []ColumnID
+
+func (c byColumnID) Len() int           { return len(c) }
+func (c byColumnID) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byColumnID) Less(i, j int) bool { return c[i] < c[j] }
+
 // EncodeSecondaryIndexes encodes key/values for the secondary indexes. colMap
 // maps ColumnIDs to indices in `values`. secondaryIndexEntries is the return
 // value (passed as a parameter so the caller can reuse between rows) and is
-// expected to be the same length as indexes.
+// expected to be the same length as indexes; secondaryIndexEntries[i] is set
+// to the entries of indexes[i].
 func EncodeSecondaryIndexes(
 	tableDesc *TableDescriptor,
 	indexes []IndexDescriptor,
 	colMap map[ColumnID]int,
 	values []parser.Datum,
-	secondaryIndexEntries []IndexEntry,
+	secondaryIndexEntries [][]IndexEntry,
 ) error {
 	for i := range indexes {
 		var err error
-		secondaryIndexEntries[i], err = EncodeSecondaryIndex(tableDesc, &indexes[i], colMap, values)
+		secondaryIndexEntries[i], err = EncodeSecondaryIndexEntries(
+			tableDesc, &indexes[i], colMap, values)
 		if err != nil {
 			return err
 		}
@@ -1132,6 +1556,18 @@ func CheckColumnType(col ColumnDescriptor, val parser.Datum, pmap *parser.Placeh
 	case ColumnType_INTERVAL:
 		_, ok = val.(*parser.DInterval)
 		set = parser.TypeInterval
+	case ColumnType_ARRAY:
+		set = col.Type.ToDatumType()
+		ok = val.TypeEqual(set)
+	case ColumnType_JSON:
+		_, ok = val.(*parser.DJSON)
+		set = parser.TypeJSON
+	case ColumnType_INET:
+		_, ok = val.(*parser.DIPAddr)
+		set = parser.TypeINet
+	case ColumnType_COLLATEDSTRING:
+		set = col.Type.ToDatumType()
+		ok = val.TypeEqual(set)
 	default:
 		return errors.Errorf("unsupported column type: %s", col.Type.Kind)
 	}
@@ -1218,6 +1654,30 @@ func MarshalColumnValue(col ColumnDescriptor, val parser.Datum) (roachpb.Value,
 			err := r.SetDuration(v.Duration)
 			return r, err
 		}
+	case ColumnType_ARRAY:
+		if v, ok := val.(*parser.DArray); ok && v.TypeEqual(col.Type.ToDatumType()) {
+			b, err := encodeArrayValue(v)
+			if err != nil {
+				return r, err
+			}
+			r.SetBytes(b)
+			return r, nil
+		}
+	case ColumnType_JSON:
+		if v, ok := val.(*parser.DJSON); ok {
+			r.SetBytes(jsonb.Encode(nil, v.JSON))
+			return r, nil
+		}
+	case ColumnType_INET:
+		if v, ok := val.(*parser.DIPAddr); ok {
+			r.SetBytes(v.Encode(nil))
+			return r, nil
+		}
+	case ColumnType_COLLATEDSTRING:
+		if v, ok := val.(*parser.DCollatedString); ok && v.Locale == *col.Type.Locale {
+			r.SetString(v.Contents)
+			return r, nil
+		}
 	default:
 		return r, errors.Errorf("unsupported column type: %s", col.Type.Kind)
 	}
@@ -1229,13 +1689,13 @@ func MarshalColumnValue(col ColumnDescriptor, val parser.Datum) (roachpb.Value,
 // expected by the column. An error is returned if the value's type does not
 // match the column's type.
 func UnmarshalColumnValue(
-	a *DatumAlloc, kind ColumnType_Kind, value *roachpb.Value,
+	a *DatumAlloc, typ ColumnType, value *roachpb.Value,
 ) (parser.Datum, error) {
 	if value == nil {
 		return parser.DNull, nil
 	}
 
-	switch kind {
+	switch typ.Kind {
 	case ColumnType_BOOL:
 		v, err := value.GetBool()
 		if err != nil {
@@ -1298,8 +1758,32 @@ func UnmarshalColumnValue(
 			return nil, err
 		}
 		return a.NewDInterval(parser.DInterval{Duration: d}), nil
+	case ColumnType_ARRAY:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return decodeArrayValue(a, typ.ArrayContents.ToDatumType(), v)
+	case ColumnType_JSON:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return decodeJSONValue(v)
+	case ColumnType_INET:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return decodeIPAddrValue(v)
+	case ColumnType_COLLATEDSTRING:
+		v, err := value.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		return a.NewDCollatedString(string(v), *typ.Locale), nil
 	default:
-		return nil, errors.Errorf("unsupported column type: %s", kind)
+		return nil, errors.Errorf("unsupported column type: %s", typ.Kind)
 	}
 }
 
@@ -1315,6 +1799,13 @@ func CheckValueWidth(col ColumnDescriptor, val parser.Datum) error {
 					col.Type.SQLString(), col.Name)
 			}
 		}
+	case ColumnType_COLLATEDSTRING:
+		if v, ok := val.(*parser.DCollatedString); ok {
+			if col.Type.Width > 0 && utf8.RuneCountInString(v.Contents) > int(col.Type.Width) {
+				return fmt.Errorf("value too long for type %s (column %q)",
+					col.Type.SQLString(), col.Name)
+			}
+		}
 	case ColumnType_INT:
 		if v, ok := val.(*parser.DInt); ok {
 			if col.Type.Width > 0 {
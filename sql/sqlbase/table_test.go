@@ -25,6 +25,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/testutils"
 	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/cockroachdb/cockroach/util/randutil"
 )
@@ -230,3 +231,232 @@ func TestIndexKey(t *testing.T) {
 		checkEntry(&tableDesc.Indexes[0], secondaryIndexKV)
 	}
 }
+
+func TestArrayEncoding(t *testing.T) {
+	var a DatumAlloc
+
+	makeArray := func(paramTyp parser.Datum, elems ...parser.Datum) *parser.DArray {
+		arr := parser.NewDArray(paramTyp)
+		for _, elem := range elems {
+			if err := arr.Append(elem); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return arr
+	}
+
+	// The arrays are listed in ascending order.
+	intArrays := []*parser.DArray{
+		makeArray(parser.TypeInt),
+		makeArray(parser.TypeInt, parser.DNull),
+		makeArray(parser.TypeInt, parser.NewDInt(1)),
+		makeArray(parser.TypeInt, parser.NewDInt(1), parser.DNull),
+		makeArray(parser.TypeInt, parser.NewDInt(1), parser.NewDInt(2)),
+		makeArray(parser.TypeInt, parser.NewDInt(2)),
+	}
+	stringArrays := []*parser.DArray{
+		makeArray(parser.TypeString, parser.NewDString("")),
+		makeArray(parser.TypeString, parser.NewDString("a"), parser.NewDString("b\x00c")),
+	}
+
+	for _, dir := range []encoding.Direction{encoding.Ascending, encoding.Descending} {
+		var prev []byte
+		for i, arr := range intArrays {
+			key, err := EncodeTableKey(nil, arr, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prev != nil {
+				c := bytes.Compare(prev, key)
+				if (dir == encoding.Ascending && c >= 0) || (dir == encoding.Descending && c <= 0) {
+					t.Errorf("%d: %s key encoding is not ordered after %s", i, arr, intArrays[i-1])
+				}
+			}
+			prev = key
+		}
+
+		for _, arr := range append(intArrays, stringArrays...) {
+			key, err := EncodeTableKey(nil, arr, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, rest, err := DecodeTableKey(&a, arr, key, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 0 {
+				t.Errorf("%s: %d bytes left after decoding key", arr, len(rest))
+			}
+			if decoded.Compare(arr) != 0 {
+				t.Errorf("expected %s, but found %s", arr, decoded)
+			}
+		}
+	}
+
+	for _, arr := range append(intArrays, stringArrays...) {
+		b, err := EncodeTableValue(nil, ColumnID(encoding.NoColumnID), arr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, rest, err := DecodeTableValue(&a, arr, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rest) != 0 {
+			t.Errorf("%s: %d bytes left after decoding value", arr, len(rest))
+		}
+		if decoded.Compare(arr) != 0 {
+			t.Errorf("expected %s, but found %s", arr, decoded)
+		}
+
+		paramKind := ColumnType_INT
+		if arr.ParamTyp.TypeEqual(parser.TypeString) {
+			paramKind = ColumnType_STRING
+		}
+		col := ColumnDescriptor{
+			Name: "a",
+			Type: ColumnType{Kind: ColumnType_ARRAY, ArrayContents: &paramKind},
+		}
+		value, err := MarshalColumnValue(col, arr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err = UnmarshalColumnValue(&a, col.Type, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Compare(arr) != 0 {
+			t.Errorf("expected %s, but found %s", arr, decoded)
+		}
+	}
+
+	intKind := ColumnType_INT
+	col := ColumnDescriptor{
+		Name: "a",
+		Type: ColumnType{Kind: ColumnType_ARRAY, ArrayContents: &intKind},
+	}
+	if _, err := MarshalColumnValue(col, stringArrays[0]); !testutils.IsError(err,
+		`value type string\[\] doesn't match type ARRAY of column "a"`) {
+		t.Errorf("expected type mismatch error, but found %v", err)
+	}
+}
+
+func TestJSONEncoding(t *testing.T) {
+	var a DatumAlloc
+
+	col := ColumnDescriptor{Name: "j", Type: ColumnType{Kind: ColumnType_JSON}}
+	for _, s := range []string{
+		`null`,
+		`"a"`,
+		`1.50`,
+		`[true, false, [], {}]`,
+		`{"a": {"b": [1, "c"]}, "bb": null}`,
+	} {
+		j, err := parser.ParseDJSON(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := EncodeTableValue(nil, ColumnID(encoding.NoColumnID), j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, rest, err := DecodeTableValue(&a, parser.TypeJSON, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rest) != 0 {
+			t.Errorf("%s: %d bytes left after decoding value", j, len(rest))
+		}
+		if decoded.Compare(j) != 0 {
+			t.Errorf("expected %s, but found %s", j, decoded)
+		}
+
+		value, err := MarshalColumnValue(col, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err = UnmarshalColumnValue(&a, col.Type, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Compare(j) != 0 {
+			t.Errorf("expected %s, but found %s", j, decoded)
+		}
+
+		if _, err := EncodeTableKey(nil, j, encoding.Ascending); !testutils.IsError(err,
+			"unable to encode table key") {
+			t.Errorf("expected key encoding error, but found %v", err)
+		}
+	}
+
+	if _, err := MarshalColumnValue(col, parser.NewDString("{}")); !testutils.IsError(err,
+		`value type string doesn't match type JSON of column "j"`) {
+		t.Errorf("expected type mismatch error, but found %v", err)
+	}
+}
+
+func TestCollatedStringEncoding(t *testing.T) {
+	var a DatumAlloc
+	var env parser.CollationEnvironment
+
+	// The strings are listed in ascending order for the locale, which differs
+	// from the order of their bytes.
+	locale := "sv"
+	var strs []*parser.DCollatedString
+	for _, s := range []string{"", "a", "b", "B", "z", "ä"} {
+		strs = append(strs, parser.NewDCollatedString(s, locale, &env))
+	}
+
+	for _, dir := range []encoding.Direction{encoding.Ascending, encoding.Descending} {
+		var prev []byte
+		for i, d := range strs {
+			key, err := EncodeTableKey(nil, d, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prev != nil {
+				c := bytes.Compare(prev, key)
+				if (dir == encoding.Ascending && c >= 0) || (dir == encoding.Descending && c <= 0) {
+					t.Errorf("%d: %s key encoding is not ordered after %s", i, d, strs[i-1])
+				}
+			}
+			prev = key
+
+			decoded, rest, err := DecodeTableKey(&a, d, key, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 0 {
+				t.Errorf("%s: %d bytes left after decoding key", d, len(rest))
+			}
+			if decoded.Compare(d) != 0 {
+				t.Errorf("expected %s, but found %s", d, decoded)
+			}
+		}
+	}
+
+	col := ColumnDescriptor{
+		Name: "s",
+		Type: ColumnType{Kind: ColumnType_COLLATEDSTRING, Locale: &locale},
+	}
+	for _, d := range strs {
+		value, err := MarshalColumnValue(col, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := UnmarshalColumnValue(&a, col.Type, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Compare(d) != 0 {
+			t.Errorf("expected %s, but found %s", d, decoded)
+		}
+	}
+
+	other := parser.NewDCollatedString("a", "de", &env)
+	if _, err := MarshalColumnValue(col, other); !testutils.IsError(err,
+		`value type collatedstring{de} doesn't match type COLLATEDSTRING of column "s"`) {
+		t.Errorf("expected type mismatch error, but found %v", err)
+	}
+}
@@ -20,12 +20,14 @@ package sqlbase
 import (
 	"fmt"
 	"math/rand"
+	"net"
 	"time"
 
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/util/duration"
+	"github.com/cockroachdb/cockroach/util/ipaddr"
 	"gopkg.in/inf.v0"
 )
 
@@ -100,14 +102,29 @@ func RandDatum(rng *rand.Rand, typ ColumnType_Kind, null bool) parser.Datum {
 		return parser.NewDBytes(parser.DBytes(p))
 	case ColumnType_TIMESTAMPTZ:
 		return &parser.DTimestampTZ{Time: time.Unix(rng.Int63n(1000000), rng.Int63n(1000000))}
+	case ColumnType_INET:
+		ip := ipaddr.IPAddr{Family: ipaddr.IPv4Family, Addr: make(net.IP, net.IPv4len)}
+		if rng.Intn(2) == 1 {
+			ip = ipaddr.IPAddr{Family: ipaddr.IPv6Family, Addr: make(net.IP, net.IPv6len)}
+		}
+		_, _ = rng.Read(ip.Addr)
+		ip.Mask = byte(rng.Intn(8*len(ip.Addr) + 1))
+		return parser.NewDIPAddr(ip)
 	default:
 		panic(fmt.Sprintf("invalid type %s", typ))
 	}
 }
 
-// RandColumnType returns a random ColumnType_Kind value.
+// RandColumnType returns a random ColumnType_Kind value. ARRAY and
+// COLLATEDSTRING are never returned, as they require an element type and a
+// locale, and neither is JSON, which has no key encoding.
 func RandColumnType(rng *rand.Rand) ColumnType_Kind {
-	return ColumnType_Kind(rng.Intn(len(ColumnType_Kind_value)))
+	for {
+		typ := ColumnType_Kind(rng.Intn(len(ColumnType_Kind_value)))
+		if typ != ColumnType_ARRAY && typ != ColumnType_COLLATEDSTRING && typ != ColumnType_JSON {
+			return typ
+		}
+	}
 }
 
 // RandDatumEncoding returns a random DatumEncoding value.
@@ -0,0 +1,153 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// maxStmtStatsEntries bounds the number of distinct statement fingerprints
+// for which statistics are accumulated, so that a workload generating an
+// unbounded number of distinct statements cannot exhaust memory. Statements
+// with new fingerprints are not tracked once the limit is reached.
+const maxStmtStatsEntries = 10000
+
+// StatementStatistics holds the statistics accumulated for all the
+// executions of statements sharing the same fingerprint.
+type StatementStatistics struct {
+	// Count is the number of times the statement was executed.
+	Count int64
+	// RetryCount is the number of executions that were retries of a previous
+	// execution of the statement, automatic or client-directed.
+	RetryCount int64
+	// ErrorCount is the number of executions that resulted in an error.
+	ErrorCount int64
+	// RowsAffected is the total number of rows affected or returned by all
+	// executions.
+	RowsAffected int64
+	// TotalLatency is the sum of the service latencies of all executions.
+	TotalLatency time.Duration
+	// MaxLatency is the largest service latency observed.
+	MaxLatency time.Duration
+}
+
+// MeanLatency returns the mean service latency of the statement.
+func (s StatementStatistics) MeanLatency() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Count)
+}
+
+// StatementStatisticsEntry associates a statement fingerprint with the
+// statistics accumulated for it.
+type StatementStatisticsEntry struct {
+	// Fingerprint is the text of the statement with literal constants
+	// replaced by underscores.
+	Fingerprint string
+	Stats       StatementStatistics
+}
+
+type stmtStats struct {
+	syncutil.Mutex
+	data StatementStatistics
+}
+
+// sqlStats accumulates per-fingerprint statistics for the statements
+// executed by an Executor.
+type sqlStats struct {
+	syncutil.Mutex
+	stmts map[string]*stmtStats
+}
+
+// stmtFingerprint returns the fingerprint of a statement, which is used to
+// group together statements that only differ in their constants.
+func stmtFingerprint(stmt parser.Statement) string {
+	return parser.AsStringWithFlags(stmt, parser.FmtHideConstants)
+}
+
+// getStatsForStmt returns the stats object for the given fingerprint,
+// creating it if needed. It returns nil if the fingerprint is not yet
+// tracked and the maximum number of tracked fingerprints has been reached.
+func (s *sqlStats) getStatsForStmt(fingerprint string) *stmtStats {
+	s.Lock()
+	defer s.Unlock()
+	if s.stmts == nil {
+		s.stmts = make(map[string]*stmtStats)
+	}
+	ss, ok := s.stmts[fingerprint]
+	if !ok {
+		if len(s.stmts) >= maxStmtStatsEntries {
+			return nil
+		}
+		ss = &stmtStats{}
+		s.stmts[fingerprint] = ss
+	}
+	return ss
+}
+
+// recordStatement records the execution of a statement.
+func (s *sqlStats) recordStatement(
+	stmt parser.Statement, res *Result, serviceLat time.Duration, retry bool,
+) {
+	ss := s.getStatsForStmt(stmtFingerprint(stmt))
+	if ss == nil {
+		return
+	}
+	ss.Lock()
+	defer ss.Unlock()
+	ss.data.Count++
+	if retry {
+		ss.data.RetryCount++
+	}
+	if res.Err != nil {
+		ss.data.ErrorCount++
+	}
+	switch res.Type {
+	case parser.RowsAffected:
+		ss.data.RowsAffected += int64(res.RowsAffected)
+	case parser.Rows, parser.CopyOut:
+		ss.data.RowsAffected += int64(len(res.Rows))
+	}
+	ss.data.TotalLatency += serviceLat
+	if serviceLat > ss.data.MaxLatency {
+		ss.data.MaxLatency = serviceLat
+	}
+}
+
+// entries returns a copy of the statistics accumulated so far, sorted by
+// fingerprint.
+func (s *sqlStats) entries() []StatementStatisticsEntry {
+	s.Lock()
+	res := make([]StatementStatisticsEntry, 0, len(s.stmts))
+	for fingerprint, ss := range s.stmts {
+		ss.Lock()
+		res = append(res, StatementStatisticsEntry{Fingerprint: fingerprint, Stats: ss.data})
+		ss.Unlock()
+	}
+	s.Unlock()
+	sort.Sort(stmtStatsByFingerprint(res))
+	return res
+}
+
+type stmtStatsByFingerprint []StatementStatisticsEntry
+
+func (s stmtStatsByFingerprint) Len() int           { return len(s) }
+func (s stmtStatsByFingerprint) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s stmtStatsByFingerprint) Less(i, j int) bool { return s[i].Fingerprint < s[j].Fingerprint }
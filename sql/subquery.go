@@ -329,7 +329,7 @@ func (v *subqueryVisitor) VisitPre(expr parser.Expr) (recurse bool, newExpr pars
 		result.execMode = execModeExists
 		result.typ = parser.TypeBool
 	} else {
-		wantedNumColumns, ctxIsInExpr := v.getSubqueryContext()
+		wantedNumColumns, ctxIsInExpr, ctxIsArrayExpr := v.getSubqueryContext()
 
 		// First check that the number of columns match.
 		cols := plan.Columns()
@@ -346,14 +346,19 @@ func (v *subqueryVisitor) VisitPre(expr parser.Expr) (recurse bool, newExpr pars
 		}
 
 		// Decide how the sub-query will be evaluated.
-		if ctxIsInExpr {
+		switch {
+		case ctxIsInExpr:
 			result.execMode = execModeAllRows
 			result.wantNormalized = true
-		} else {
+		case ctxIsArrayExpr:
+			// ANY, SOME and ALL need to see every row, including NULLs, which
+			// normalization would remove.
+			result.execMode = execModeAllRows
+		default:
 			result.execMode = execModeOneRow
 		}
 
-		if wantedNumColumns == 1 && !ctxIsInExpr {
+		if wantedNumColumns == 1 && !ctxIsInExpr && !ctxIsArrayExpr {
 			// This seems hokey, but if we don't do this then the subquery expands
 			// to a tuple of tuples instead of a tuple of values and an expression
 			// like "k IN (SELECT foo FROM bar)" will fail because we're comparing
@@ -387,8 +392,11 @@ func (p *planner) replaceSubqueries(expr parser.Expr, columns int) (parser.Expr,
 
 // getSubqueryContext returns:
 // - the desired number of columns;
-// - whether the sub-query is operand of a IN/NOT IN expression.
-func (v *subqueryVisitor) getSubqueryContext() (columns int, ctxIsInExpr bool) {
+// - whether the sub-query is operand of a IN/NOT IN expression;
+// - whether the sub-query is operand of an ANY/SOME/ALL expression.
+func (v *subqueryVisitor) getSubqueryContext() (
+	columns int, ctxIsInExpr bool, ctxIsArrayExpr bool,
+) {
 	for i := len(v.path) - 1; i >= 0; i-- {
 		switch e := v.path[i].(type) {
 		case *parser.ExistsExpr:
@@ -413,23 +421,24 @@ func (v *subqueryVisitor) getSubqueryContext() (columns int, ctxIsInExpr bool) {
 				columns = len(*t)
 			}
 
-			ctxIsInExpr = false
 			switch e.Operator {
 			case parser.In, parser.NotIn:
 				ctxIsInExpr = true
+			case parser.Any, parser.Some, parser.All:
+				ctxIsArrayExpr = true
 			}
 
-			return columns, ctxIsInExpr
+			return columns, ctxIsInExpr, ctxIsArrayExpr
 
 		default:
 			// Any other expr that has this sub-query as operand
 			// is expecting a single value.
-			return 1, false
+			return 1, false, false
 		}
 	}
 
 	// We have not encountered any non-paren, non-IN expression so far,
 	// so the outer context is informing us of the desired number of
 	// columns.
-	return v.columns, false
+	return v.columns, false, false
 }
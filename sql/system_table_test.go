@@ -100,6 +100,8 @@ func TestSystemTableLiterals(t *testing.T) {
 		{keys.DescriptorTableID, sqlbase.DescriptorTableSchema, sqlbase.DescriptorTable},
 		{keys.UsersTableID, sqlbase.UsersTableSchema, sqlbase.UsersTable},
 		{keys.ZonesTableID, sqlbase.ZonesTableSchema, sqlbase.ZonesTable},
+		{keys.SettingsTableID, sqlbase.SettingsTableSchema, sqlbase.SettingsTable},
+		{keys.ProtectedTimestampsTableID, sqlbase.ProtectedTimestampsTableSchema, sqlbase.ProtectedTimestampsTable},
 	} {
 		gen := sql.CreateTableDescriptor(test.id, keys.SystemDatabaseID, test.schema,
 			sqlbase.NewPrivilegeDescriptor(security.RootUser, sqlbase.SystemConfigAllowedPrivileges[test.id]))
@@ -124,7 +124,7 @@ func (p *planner) getTableDesc(tn *parser.TableName) (*sqlbase.TableDescriptor,
 	if err != nil {
 		return nil, err
 	}
-	if !found {
+	if !found || p.isOtherSessionTemporaryTable(&desc) {
 		return nil, nil
 	}
 	return &desc, nil
@@ -225,9 +225,31 @@ func (p *planner) getTableLease(tn *parser.TableName) (*sqlbase.TableDescriptor,
 		// the deadline.
 		p.txn.UpdateDeadlineMaybe(hlc.Timestamp{WallTime: lease.Expiration().UnixNano()})
 	}
+	if p.isOtherSessionTemporaryTable(&lease.TableDescriptor) {
+		return nil, sqlbase.NewUndefinedTableError(tn.String())
+	}
+	if newID, ok := p.replacementTableID(lease.ID); ok {
+		// The table was truncated earlier in the txn.
+		return p.getTableLeaseByID(newID)
+	}
 	return &lease.TableDescriptor, nil
 }
 
+// replacementTableID returns the ID of the table which replaced the given
+// table when it was truncated in the current txn, if any.
+func (p *planner) replacementTableID(id sqlbase.ID) (sqlbase.ID, bool) {
+	replaced := false
+	// Replacements are recorded in order, so a table replaced repeatedly is
+	// followed to its latest replacement.
+	for _, r := range p.session.TxnState.tableReplacements {
+		if r.oldID == id && r.epoch == p.txn.Proto.Epoch {
+			id = r.newID
+			replaced = true
+		}
+	}
+	return id, replaced
+}
+
 // getTableLeaseByID is a by-ID variant of getTableLease (i.e. uses same cache).
 func (p *planner) getTableLeaseByID(tableID sqlbase.ID) (*sqlbase.TableDescriptor, error) {
 	if log.V(2) {
@@ -383,6 +405,16 @@ func (p *planner) writeTableDesc(tableDesc *sqlbase.TableDescriptor) error {
 	if isVirtualDescriptor(tableDesc) {
 		panic(fmt.Sprintf("Virtual Descriptors cannot be stored, found: %v", tableDesc))
 	}
+	// Release the leases of the planner and update the ones of the node as
+	// part of the commit, so that the statements running after it never use
+	// the descriptors it replaced.
+	desc := *tableDesc
+	p.txn.AddCommitTrigger(func() {
+		p.releaseLeases()
+		if p.leaseMgr != nil {
+			p.leaseMgr.invalidateTable(desc)
+		}
+	})
 	return p.txn.Put(sqlbase.MakeDescMetadataKey(tableDesc.GetID()),
 		sqlbase.WrapDescriptor(tableDesc))
 }
@@ -19,10 +19,13 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"unsafe"
 
+	"github.com/cockroachdb/cockroach/sql/mon"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
 	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/encoding"
 	"github.com/pkg/errors"
 )
 
@@ -34,10 +37,40 @@ const (
 	joinTypeOuterFull
 )
 
+// joinAlgorithm is the algorithm a joinNode uses to find the pairs of rows
+// which satisfy the join predicate.
+type joinAlgorithm int
+
+const (
+	// nestedLoopJoin buffers the right rows and compares every left row with
+	// every one of them.
+	nestedLoopJoin joinAlgorithm = iota
+	// hashJoin buffers the right rows in a hash table keyed by their
+	// equality columns, and only compares each left row with the right rows
+	// in its bucket. The hash table is moved to temporary storage if it
+	// exceeds the memory budget.
+	hashJoin
+	// mergeJoin streams both inputs, which are ordered on some of the
+	// equality columns, only buffering the right rows which have the same
+	// values on these columns.
+	mergeJoin
+	// lookupJoin reads the left rows in batches and looks up the right rows
+	// matching each batch in an index of the right table, hashing them like
+	// a hash join. It avoids reading the whole right table when there are
+	// few left rows.
+	lookupJoin
+	// interleavedJoin reads the rows of a table and of a table interleaved
+	// in it with a single scan, comparing each left row with the right rows
+	// which follow it in the interleaved keyspace.
+	interleavedJoin
+)
+
 // joinNode is a planNode whose rows are the result of an inner or
 // left/right outer join.
 type joinNode struct {
+	p        *planner
 	joinType joinType
+	algo     joinAlgorithm
 
 	// The data sources.
 	left    planNode
@@ -47,6 +80,12 @@ type joinNode struct {
 	// pred represents the join predicate.
 	pred joinPredicate
 
+	// leftEqCols and rightEqCols are the pairs of columns of the left and
+	// right data sources (after swapping) which the predicate requires to be
+	// equal.
+	leftEqCols  []int
+	rightEqCols []int
+
 	// columns contains the metadata for the results of this node.
 	columns []ResultColumn
 
@@ -54,20 +93,48 @@ type joinNode struct {
 	output parser.DTuple
 
 	// rightRows contains a copy of all rows from the data source on the
-	// right of the join.
-	rightRows *valuesNode
+	// right of the join, for nested loop and hash joins, or of the rows looked
+	// up for the current batch of left rows, for lookup joins.
+	rightRows []parser.DTuple
+	// buckets maps the encoding of the equality columns of the right rows
+	// to their indices in rightRows, for hash and lookup joins.
+	buckets map[string][]int
+	// rightMemAcc accounts for the buffered right rows.
+	rightMemAcc mon.MemoryAccount
+	// spilled holds the right rows of a hash join, keyed by the encoding of
+	// their equality columns, once they no longer fit in the memory budget.
+	spilled     *diskRowContainer
+	spilledIter *diskRowIterator
 	// rightMatched remembers which of the right rows have matched in a
 	// full outer join.
 	rightMatched []bool
 
-	// rightIdx indicates the current right row.
-	// In a full outer join, the value becomes negative during
-	// the last pass searching for unmatched right rows.
-	rightIdx int
+	// merge is the state of a merge join.
+	merge mergeJoinState
+	// lookup is the state of a lookup join.
+	lookup lookupJoinState
+	// interleaved is the state of an interleaved join.
+	interleaved interleavedJoinState
 
-	// passedFilter turns to true when the current left row has matched
+	// leftRow is the current left row, or nil if the next one must be read.
+	leftRow parser.DTuple
+	// leftMatched turns to true when the current left row has matched
 	// at least one right row.
-	passedFilter bool
+	leftMatched bool
+	leftDone    bool
+	// candidates are the indices of the right rows in the bucket of the
+	// current left row for in-memory hash joins, and candIdx is the next one
+	// to compare. For nested loop joins candIdx is the index of the next
+	// right row; for spilled hash joins it counts the rows read from disk.
+	candidates []int
+	candIdx    int
+	// probing is set while the spilled right rows are read for the current
+	// left row.
+	probing bool
+	// unmatchedIdx is the next right row to check during the last pass of a
+	// full outer join searching for unmatched right rows.
+	unmatchedIdx int
+	keyScratch   []byte
 
 	// emptyRight contain tuples of NULL values to use on the
 	// right for outer joins when the filter fails.
@@ -95,6 +162,11 @@ type joinPredicate interface {
 	// input data sources.
 	prepareRow(result parser.DTuple, leftRow parser.DTuple, rightRow parser.DTuple)
 
+	// equalityColumns returns the pairs of columns of the left and right
+	// input rows which must be equal for the predicate to pass, restricted
+	// to the columns whose values can be matched by their key encoding.
+	equalityColumns() (leftCols, rightCols []int)
+
 	// expand and start propagate to embedded sub-queries.
 	expand() error
 	start() error
@@ -131,6 +203,20 @@ func (p *crossPredicate) start() error                        { return nil }
 func (p *crossPredicate) expand() error                       { return nil }
 func (p *crossPredicate) format(_ *bytes.Buffer)              {}
 func (p *crossPredicate) explainTypes(_ func(string, string)) {}
+func (p *crossPredicate) equalityColumns() ([]int, []int)     { return nil, nil }
+
+// isJoinKeyType returns whether two values of the given type are equal if
+// and only if their key encodings are, which allows joining on columns of
+// this type by hashing or merging the encodings. This excludes e.g. floats
+// and decimals, whose encodings distinguish 0 from -0 and 1.0 from 1.00.
+func isJoinKeyType(typ parser.Datum) bool {
+	switch typ.(type) {
+	case *parser.DBool, *parser.DInt, *parser.DString, *parser.DBytes,
+		*parser.DDate, *parser.DTimestamp, *parser.DTimestampTZ:
+		return true
+	}
+	return false
+}
 
 // onPredicate implements the predicate logic for joins with an ON clause.
 type onPredicate struct {
@@ -146,6 +232,11 @@ type onPredicate struct {
 	// using IndexedVar (like scanNode) instead of qvalues.
 	qvals  qvalMap
 	filter parser.TypedExpr
+
+	// leftEqCols/rightEqCols are the pairs of columns compared by
+	// equalities in the filter's conjuncts.
+	leftEqCols  []int
+	rightEqCols []int
 }
 
 // eval for onPredicate uses an arbitrary SQL expression to determine
@@ -160,6 +251,10 @@ func (p *onPredicate) prepareRow(result parser.DTuple, leftRow parser.DTuple, ri
 	prepareRowConcat(result, leftRow, rightRow)
 }
 
+func (p *onPredicate) equalityColumns() ([]int, []int) {
+	return p.leftEqCols, p.rightEqCols
+}
+
 func (p *onPredicate) expand() error {
 	return p.p.expandSubqueryPlans(p.filter)
 }
@@ -198,13 +293,45 @@ func (p *planner) makeOnPredicate(
 		return nil, nil, err
 	}
 
-	return &onPredicate{
+	pred := &onPredicate{
 		p:         p,
 		leftInfo:  left,
 		rightInfo: right,
 		qvals:     qvals,
 		filter:    filter,
-	}, info, nil
+	}
+	pred.extractEqualityColumns()
+	return pred, info, nil
+}
+
+// extractEqualityColumns finds the conjuncts of the filter which compare a
+// column of the left data source with a column of the right one for
+// equality.
+func (p *onPredicate) extractEqualityColumns() {
+	for _, e := range splitAndExpr(p.filter, nil) {
+		c, ok := parser.StripParens(e).(*parser.ComparisonExpr)
+		if !ok || c.Operator != parser.EQ {
+			continue
+		}
+		l, lok := parser.StripParens(c.Left).(*qvalue)
+		r, rok := parser.StripParens(c.Right).(*qvalue)
+		if !lok || !rok {
+			continue
+		}
+		if l.colRef.source == p.rightInfo && r.colRef.source == p.leftInfo {
+			l, r = r, l
+		}
+		if l.colRef.source != p.leftInfo || r.colRef.source != p.rightInfo {
+			continue
+		}
+		leftTyp := p.leftInfo.sourceColumns[l.colRef.colIdx].Typ
+		rightTyp := p.rightInfo.sourceColumns[r.colRef.colIdx].Typ
+		if !leftTyp.TypeEqual(rightTyp) || !isJoinKeyType(leftTyp) {
+			continue
+		}
+		p.leftEqCols = append(p.leftEqCols, l.colRef.colIdx)
+		p.rightEqCols = append(p.rightEqCols, r.colRef.colIdx)
+	}
 }
 
 // usingPredicate implements the predicate logic for joins with a USING clause.
@@ -228,6 +355,11 @@ type usingPredicate struct {
 	// the left and right input row arrays, respectively.
 	leftRestIndices  []int
 	rightRestIndices []int
+
+	// left/rightEqCols are the USING columns whose values can be matched by
+	// their key encoding.
+	leftEqCols  []int
+	rightEqCols []int
 }
 
 func (p *usingPredicate) format(buf *bytes.Buffer) {
@@ -238,6 +370,9 @@ func (p *usingPredicate) format(buf *bytes.Buffer) {
 func (p *usingPredicate) start() error                        { return nil }
 func (p *usingPredicate) expand() error                       { return nil }
 func (p *usingPredicate) explainTypes(_ func(string, string)) {}
+func (p *usingPredicate) equalityColumns() ([]int, []int) {
+	return p.leftEqCols, p.rightEqCols
+}
 
 // eval for usingPredicate compares the USING columns, returning true
 // if and only if all USING columns are equal on both sides.
@@ -314,6 +449,7 @@ func (p *planner) makeUsingPredicate(
 	cmpOps := make([]func(*parser.EvalContext, parser.Datum, parser.Datum) (parser.DBool, error), len(colNames))
 	leftUsingIndices := make([]int, len(colNames))
 	rightUsingIndices := make([]int, len(colNames))
+	var leftEqCols, rightEqCols []int
 	usedLeft := make([]int, len(left.sourceColumns))
 	for i := range usedLeft {
 		usedLeft[i] = invalidColIdx
@@ -359,6 +495,10 @@ func (p *planner) makeUsingPredicate(
 			return nil, nil, fmt.Errorf("JOIN/USING types %s and %s for column %s cannot be matched", leftType.Type(), rightType.Type(), colName)
 		}
 		cmpOps[i] = fn
+		if leftType.TypeEqual(rightType) && isJoinKeyType(leftType) {
+			leftEqCols = append(leftEqCols, leftIdx)
+			rightEqCols = append(rightEqCols, rightIdx)
+		}
 
 		// Prepare the output column for USING.
 		columns = append(columns, left.sourceColumns[leftIdx])
@@ -413,6 +553,8 @@ func (p *planner) makeUsingPredicate(
 		rightUsingIndices: rightUsingIndices,
 		leftRestIndices:   leftRestIndices,
 		rightRestIndices:  rightRestIndices,
+		leftEqCols:        leftEqCols,
+		rightEqCols:       rightEqCols,
 	}, info, nil
 }
 
@@ -495,15 +637,24 @@ func (p *planner) makeJoin(
 		return planDataSource{}, err
 	}
 
+	leftEqCols, rightEqCols := pred.equalityColumns()
+	if swapped {
+		leftEqCols, rightEqCols = rightEqCols, leftEqCols
+	}
+
 	return planDataSource{
 		info: info,
 		plan: &joinNode{
-			joinType: typ,
-			left:     left.plan,
-			right:    right.plan,
-			pred:     pred,
-			columns:  info.sourceColumns,
-			swapped:  swapped,
+			p:           p,
+			joinType:    typ,
+			left:        left.plan,
+			right:       right.plan,
+			pred:        pred,
+			leftEqCols:  leftEqCols,
+			rightEqCols: rightEqCols,
+			columns:     info.sourceColumns,
+			swapped:     swapped,
+			rightMemAcc: p.makeMemoryAccount(),
 		},
 	}, nil
 }
@@ -524,7 +675,47 @@ func (n *joinNode) expandPlan() error {
 	if err := n.left.expandPlan(); err != nil {
 		return err
 	}
-	return n.right.expandPlan()
+	if err := n.right.expandPlan(); err != nil {
+		return err
+	}
+	// Tables scanned by the join don't go through index selection; they are
+	// scanned in the order of their primary index.
+	for _, plan := range []planNode{n.left, n.right} {
+		if scan, ok := plan.(*scanNode); ok && len(scan.ordering.ordering) == 0 {
+			scan.initOrdering(0)
+		}
+	}
+	n.chooseAlgorithm()
+	return nil
+}
+
+// chooseAlgorithm picks the join algorithm once the orderings of the
+// inputs are known: an interleaved join if one of the data sources is a
+// table interleaved in the other one and the equality columns include the
+// interleaved prefix, otherwise a merge join if both are ordered on some of
+// the equality columns, otherwise a lookup join if the right data source is a
+// table with an index on some of them, otherwise a hash join if there are
+// equality columns, otherwise a nested loop join.
+func (n *joinNode) chooseAlgorithm() {
+	n.algo = nestedLoopJoin
+	if len(n.leftEqCols) == 0 {
+		return
+	}
+	if n.initInterleavedJoin() {
+		n.algo = interleavedJoin
+		return
+	}
+	n.algo = hashJoin
+	leftCols, rightCols, dirs := mergeJoinColumns(
+		n.left.Ordering(), n.right.Ordering(), n.leftEqCols, n.rightEqCols)
+	if len(dirs) > 0 {
+		n.algo = mergeJoin
+		n.merge = mergeJoinState{leftCols: leftCols, rightCols: rightCols, dirs: dirs}
+		return
+	}
+	if n.initLookupJoin() {
+		n.algo = lookupJoin
+	}
 }
 
 // ExplainPlan implements the planNode interface.
@@ -548,6 +739,16 @@ func (n *joinNode) ExplainPlan(v bool) (name, description string, children []pla
 	}
 
 	n.pred.format(&buf)
+	switch n.algo {
+	case hashJoin:
+		buf.WriteString(" (hash)")
+	case mergeJoin:
+		buf.WriteString(" (merge)")
+	case lookupJoin:
+		buf.WriteString(" (lookup)")
+	case interleavedJoin:
+		buf.WriteString(" (interleaved)")
+	}
 
 	subplans := []planNode{n.left, n.right}
 	if n.swapped {
@@ -564,7 +765,56 @@ func (n *joinNode) ExplainPlan(v bool) (name, description string, children []pla
 func (n *joinNode) Columns() []ResultColumn { return n.columns }
 
 // Ordering implements the planNode interface.
-func (n *joinNode) Ordering() orderingInfo { return n.left.Ordering() }
+func (n *joinNode) Ordering() orderingInfo {
+	if n.joinType == joinTypeOuterFull {
+		// The unmatched right rows are interspersed with the left ones.
+		return orderingInfo{}
+	}
+	// The rows are produced in the order of the left rows, but a left row
+	// can be repeated. The left columns must be mapped to the output ones.
+	leftOrd := n.left.Ordering()
+	var ord orderingInfo
+	for col := range leftOrd.exactMatchCols {
+		if ord.exactMatchCols == nil {
+			ord.exactMatchCols = make(map[int]struct{})
+		}
+		ord.exactMatchCols[n.leftColumnToOutput(col)] = struct{}{}
+	}
+	for _, o := range leftOrd.ordering {
+		ord.ordering = append(ord.ordering, sqlbase.ColumnOrderInfo{
+			ColIdx:    n.leftColumnToOutput(o.ColIdx),
+			Direction: o.Direction,
+		})
+	}
+	return ord
+}
+
+// leftColumnToOutput returns the index of the output column holding the
+// values of the given column of the left data source (after swapping).
+func (n *joinNode) leftColumnToOutput(col int) int {
+	if u, ok := n.pred.(*usingPredicate); ok {
+		usingIndices, restIndices, offset := u.leftUsingIndices, u.leftRestIndices, len(u.colNames)
+		if n.swapped {
+			usingIndices, restIndices = u.rightUsingIndices, u.rightRestIndices
+			offset += len(u.leftRestIndices)
+		}
+		for i, idx := range usingIndices {
+			if idx == col {
+				return i
+			}
+		}
+		for i, idx := range restIndices {
+			if idx == col {
+				return offset + i
+			}
+		}
+		panic(fmt.Sprintf("column %d of the join input not found in its output", col))
+	}
+	if n.swapped {
+		return len(n.right.Columns()) + col
+	}
+	return col
+}
 
 // MarkDebug implements the planNode interface.
 func (n *joinNode) MarkDebug(mode explainMode) {
@@ -589,24 +839,11 @@ func (n *joinNode) Start() error {
 		return err
 	}
 
-	if n.explain != explainDebug {
-		// Load all the rows from the right side in memory.
-		v := &valuesNode{}
-		for {
-			hasRow, err := n.right.Next()
-			if err != nil {
-				return err
-			}
-			if !hasRow {
-				break
-			}
-			row := n.right.Values()
-			newRow := make([]parser.Datum, len(row))
-			copy(newRow, row)
-			v.rows = append(v.rows, newRow)
-		}
-		if len(v.rows) > 0 {
-			n.rightRows = v
+	if n.explain != explainDebug && n.algo != mergeJoin && n.algo != lookupJoin &&
+		n.algo != interleavedJoin {
+		// Load all the rows from the right side.
+		if err := n.loadRight(); err != nil {
+			return err
 		}
 	}
 
@@ -621,10 +858,9 @@ func (n *joinNode) Start() error {
 			n.emptyRight[i] = parser.DNull
 		}
 	}
-	// If needed, allocate an array of booleans to remember which
-	// right rows have matched.
-	if n.joinType == joinTypeOuterFull && n.rightRows != nil {
-		n.rightMatched = make([]bool, len(n.rightRows.rows))
+	// If needed, pre-allocate a right row of NULL tuples for the right rows
+	// which don't match.
+	if n.joinType == joinTypeOuterFull {
 		n.emptyLeft = make(parser.DTuple, len(n.left.Columns()))
 		for i := range n.emptyLeft {
 			n.emptyLeft[i] = parser.DNull
@@ -634,6 +870,151 @@ func (n *joinNode) Start() error {
 	return nil
 }
 
+// loadRight buffers the rows of the right data source, in a hash table
+// keyed by their equality columns for hash joins. If the rows of a hash join
+// exceed the memory budget, they are moved to temporary storage.
+func (n *joinNode) loadRight() error {
+	if n.algo == hashJoin {
+		n.buckets = make(map[string][]int)
+	}
+	for {
+		hasRow, err := n.right.Next()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			break
+		}
+		row := n.right.Values()
+		if n.spilled == nil {
+			err := n.bufferRight(row)
+			if err == nil {
+				continue
+			}
+			if !isMemoryBudgetError(err) || n.algo != hashJoin || !n.p.canSpillToDisk() {
+				return err
+			}
+			if err := n.spillRight(); err != nil {
+				return err
+			}
+		}
+		if err := n.addSpilledRight(row); err != nil {
+			return err
+		}
+	}
+
+	numRightRows := len(n.rightRows)
+	if n.spilled != nil {
+		var err error
+		if n.spilledIter, err = n.spilled.newIterator(); err != nil {
+			return err
+		}
+		numRightRows = int(n.spilled.numRows)
+	}
+	// If needed, allocate an array of booleans to remember which
+	// right rows have matched.
+	if n.joinType == joinTypeOuterFull {
+		n.rightMatched = make([]bool, numRightRows)
+	}
+	return nil
+}
+
+// bufferRight adds a copy of a right row to rightRows and, for hash joins,
+// to its bucket.
+func (n *joinNode) bufferRight(row parser.DTuple) error {
+	size := int64(row.Size())
+	var key []byte
+	hashed := false
+	if n.algo == hashJoin || n.algo == lookupJoin {
+		var err error
+		key, hashed, err = encodeJoinKey(n.keyScratch[:0], row, n.rightEqCols, nil)
+		if err != nil {
+			return err
+		}
+		n.keyScratch = key
+		if !hashed && n.joinType != joinTypeOuterFull {
+			// A row with NULL equality columns never matches, and is only
+			// needed if it must be emitted on its own.
+			return nil
+		}
+		if hashed {
+			size += sizeOfBucket + int64(len(key)) + int64(unsafe.Sizeof(int(0)))
+		}
+	}
+	if err := n.rightMemAcc.Grow(size); err != nil {
+		return err
+	}
+	newRow := make(parser.DTuple, len(row))
+	copy(newRow, row)
+	n.rightRows = append(n.rightRows, newRow)
+	if hashed {
+		n.buckets[string(key)] = append(n.buckets[string(key)], len(n.rightRows)-1)
+	}
+	return nil
+}
+
+// spillRight moves the right rows buffered so far to temporary storage.
+func (n *joinNode) spillRight() error {
+	columns := n.right.Columns()
+	types := make([]parser.Datum, len(columns))
+	for i, col := range columns {
+		types[i] = col.Typ
+	}
+	n.spilled = n.p.newDiskRowContainer(types)
+	for _, row := range n.rightRows {
+		if err := n.addSpilledRight(row); err != nil {
+			return err
+		}
+	}
+	n.rightRows = nil
+	n.buckets = nil
+	n.rightMemAcc.Clear()
+	return nil
+}
+
+// addSpilledRight adds a right row to temporary storage, keyed by its
+// equality columns.
+func (n *joinNode) addSpilledRight(row parser.DTuple) error {
+	key := n.keyScratch[:0]
+	for _, col := range n.rightEqCols {
+		if row[col] == parser.DNull && n.joinType != joinTypeOuterFull {
+			// A row with NULL equality columns never matches, and is only
+			// needed if it must be emitted on its own.
+			return nil
+		}
+		// NULL values are encoded too: the resulting keys never match those
+		// of the left rows, which are only looked up without NULL values.
+		var err error
+		if key, err = sqlbase.EncodeTableKey(key, row[col], encoding.Ascending); err != nil {
+			return err
+		}
+	}
+	n.keyScratch = key
+	return n.spilled.addRow(key, row)
+}
+
+// encodeJoinKey appends the key encoding of the given columns of a row to
+// b, in the given directions (ascending if nil). It returns false if any of
+// the values is NULL, in which case the row can't satisfy the equalities.
+func encodeJoinKey(
+	b []byte, row parser.DTuple, cols []int, dirs []encoding.Direction,
+) ([]byte, bool, error) {
+	for i, col := range cols {
+		if row[col] == parser.DNull {
+			return b, false, nil
+		}
+		dir := encoding.Ascending
+		if dirs != nil {
+			dir = dirs[i]
+		}
+		var err error
+		if b, err = sqlbase.EncodeTableKey(b, row[col], dir); err != nil {
+			return b, false, err
+		}
+	}
+	return b, true, nil
+}
+
 func (n *joinNode) debugNext() (bool, error) {
 	if !n.doneReadingRight {
 		hasRightRow, err := n.right.Next()
@@ -654,106 +1035,207 @@ func (n *joinNode) Next() (bool, error) {
 	if n.explain == explainDebug {
 		return n.debugNext()
 	}
-
-	var leftRow, rightRow parser.DTuple
-	var nRightRows int
-
-	if n.rightRows == nil {
-		if n.joinType != joinTypeOuterLeft && n.joinType != joinTypeOuterFull {
-			// No rows on right; don't even try.
-			return false, nil
-		}
-		nRightRows = 0
-	} else {
-		nRightRows = len(n.rightRows.rows)
+	switch n.algo {
+	case mergeJoin:
+		return n.mergeNext()
+	case interleavedJoin:
+		return n.interleavedNext()
 	}
 
 	// We fetch one row at a time until we find one that passes the filter.
 	for {
-		curRightIdx := n.rightIdx
-
-		if curRightIdx < 0 {
-			// Going through the remaining right row of a full outer join.
-			curRightIdx = (-curRightIdx) - 1
-			n.rightIdx--
-			if curRightIdx < nRightRows {
-				if n.rightMatched[curRightIdx] {
-					continue
-				}
-				leftRow = n.emptyLeft
-				rightRow = n.rightRows.rows[curRightIdx]
-				break
-			} else {
-				// Both right and left exhausted.
-				return false, nil
-			}
+		if n.leftDone {
+			return n.nextUnmatchedRight()
 		}
 
-		if curRightIdx == 0 {
-			leftHasRow, err := n.left.Next()
+		if n.leftRow == nil {
+			if n.joinType == joinTypeInner && n.algo != lookupJoin &&
+				len(n.rightRows) == 0 && n.spilled == nil {
+				// No rows on right; don't even try.
+				return false, nil
+			}
+			leftRow, err := n.readLeft()
 			if err != nil {
 				return false, err
 			}
-
-			if !leftHasRow && n.rightMatched != nil {
-				// Go through the remaining right rows.
-				n.rightIdx = -1
+			if leftRow == nil {
+				n.leftDone = true
 				continue
 			}
-
-			if !leftHasRow {
-				// Both left and right are exhausted; done.
-				return false, nil
+			n.leftRow = leftRow
+			n.leftMatched = false
+			if err := n.findCandidates(); err != nil {
+				return false, err
 			}
 		}
 
-		leftRow = n.left.Values()
-
-		if curRightIdx >= nRightRows {
-			n.rightIdx = 0
-			if (n.joinType == joinTypeOuterLeft || n.joinType == joinTypeOuterFull) && !n.passedFilter {
-				// If nothing was emitted in the previous batch of right rows,
-				// insert a tuple of NULLs on the right.
-				rightRow = n.emptyRight
-				if n.swapped {
-					leftRow, rightRow = rightRow, leftRow
+		rightRow, rightIdx, ok, err := n.nextCandidate()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			leftRow, rightRow := n.orient(n.leftRow, rightRow)
+			passesFilter, err := n.pred.eval(leftRow, rightRow)
+			if err != nil {
+				return false, err
+			}
+			if passesFilter {
+				n.leftMatched = true
+				if n.rightMatched != nil {
+					// FULL OUTER JOIN, mark the rows as matched.
+					n.rightMatched[rightIdx] = true
 				}
-				break
+				n.pred.prepareRow(n.output, leftRow, rightRow)
+				return true, nil
 			}
-			n.passedFilter = false
 			continue
 		}
 
-		emptyRight := false
-		if nRightRows > 0 {
-			rightRow = n.rightRows.rows[curRightIdx]
-			n.rightIdx = curRightIdx + 1
-		} else {
-			emptyRight = true
-			rightRow = n.emptyRight
+		// The current left row has been compared with all its candidates.
+		leftRow := n.leftRow
+		n.leftRow = nil
+		if !n.leftMatched && n.joinType != joinTypeInner {
+			// If the left row didn't match, insert a tuple of NULLs on the
+			// right.
+			n.emit(leftRow, n.emptyRight)
+			return true, nil
 		}
+	}
+}
 
-		if n.swapped {
-			leftRow, rightRow = rightRow, leftRow
+// readLeft returns the next left row, or nil if the left data source is
+// exhausted.
+func (n *joinNode) readLeft() (parser.DTuple, error) {
+	if n.algo == lookupJoin {
+		return n.lookupNextLeft()
+	}
+	hasRow, err := n.left.Next()
+	if err != nil || !hasRow {
+		return nil, err
+	}
+	return n.left.Values(), nil
+}
+
+// findCandidates prepares the iteration over the right rows which may match
+// the current left row.
+func (n *joinNode) findCandidates() error {
+	n.candIdx = 0
+	if n.algo == nestedLoopJoin {
+		return nil
+	}
+	key, ok, err := encodeJoinKey(n.keyScratch[:0], n.leftRow, n.leftEqCols, nil)
+	if err != nil {
+		return err
+	}
+	n.keyScratch = key
+	if n.spilled != nil {
+		n.probing = ok
+		if ok {
+			n.spilledIter.seek(key)
+		}
+		return nil
+	}
+	n.candidates = nil
+	if ok {
+		n.candidates = n.buckets[string(key)]
+	}
+	return nil
+}
+
+// nextCandidate returns the next right row which may match the current left
+// row, along with its index.
+func (n *joinNode) nextCandidate() (parser.DTuple, int, bool, error) {
+	switch {
+	case n.algo == nestedLoopJoin:
+		if n.candIdx >= len(n.rightRows) {
+			return nil, 0, false, nil
+		}
+		n.candIdx++
+		return n.rightRows[n.candIdx-1], n.candIdx - 1, true, nil
+
+	case n.spilled != nil:
+		if !n.probing {
+			return nil, 0, false, nil
+		}
+		if n.candIdx > 0 {
+			n.spilledIter.next()
+		}
+		n.candIdx++
+		if ok, err := n.spilledIter.valid(); err != nil || !ok ||
+			!bytes.Equal(n.spilledIter.key(), n.keyScratch) {
+			n.probing = false
+			return nil, 0, false, err
 		}
-		passesFilter, err := n.pred.eval(leftRow, rightRow)
+		row, err := n.spilledIter.row()
 		if err != nil {
-			return false, err
+			return nil, 0, false, err
+		}
+		return row, int(n.spilledIter.seq()), true, nil
+
+	default:
+		if n.candIdx >= len(n.candidates) {
+			return nil, 0, false, nil
 		}
+		idx := n.candidates[n.candIdx]
+		n.candIdx++
+		return n.rightRows[idx], idx, true, nil
+	}
+}
 
-		if passesFilter {
-			n.passedFilter = true
-			if n.rightMatched != nil && !emptyRight {
-				// FULL OUTER JOIN, mark the rows as matched.
-				n.rightMatched[curRightIdx] = true
+// nextUnmatchedRight emits the right rows which haven't matched any left
+// row, once the left rows are exhausted, for full outer joins.
+func (n *joinNode) nextUnmatchedRight() (bool, error) {
+	if n.rightMatched == nil {
+		// Both left and right are exhausted; done.
+		return false, nil
+	}
+	if n.spilled != nil {
+		if n.unmatchedIdx == 0 {
+			n.spilledIter.rewind()
+		} else {
+			n.spilledIter.next()
+		}
+		n.unmatchedIdx++
+		for {
+			if ok, err := n.spilledIter.valid(); err != nil || !ok {
+				return false, err
 			}
-			break
+			if !n.rightMatched[n.spilledIter.seq()] {
+				row, err := n.spilledIter.row()
+				if err != nil {
+					return false, err
+				}
+				n.emit(n.emptyLeft, row)
+				return true, nil
+			}
+			n.spilledIter.next()
+		}
+	}
+	for n.unmatchedIdx < len(n.rightRows) {
+		idx := n.unmatchedIdx
+		n.unmatchedIdx++
+		if !n.rightMatched[idx] {
+			n.emit(n.emptyLeft, n.rightRows[idx])
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	// Got a row from both right and left; prepareRow the result.
+// orient returns a row of the left data source and a row of the right one
+// in the order expected by the join predicate.
+func (n *joinNode) orient(leftRow, rightRow parser.DTuple) (parser.DTuple, parser.DTuple) {
+	if n.swapped {
+		return rightRow, leftRow
+	}
+	return leftRow, rightRow
+}
+
+// emit prepares the output row for a row of the left data source and a row
+// of the right one.
+func (n *joinNode) emit(leftRow, rightRow parser.DTuple) {
+	leftRow, rightRow = n.orient(leftRow, rightRow)
 	n.pred.prepareRow(n.output, leftRow, rightRow)
-	return true, nil
 }
 
 // Values implements the planNode interface.
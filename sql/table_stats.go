@@ -0,0 +1,785 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/mon"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/privilege"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/stop"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+)
+
+// tableStatisticsTableSchema describes the schema of the table statistics
+// table. Every row holds the statistics collected on a column of a table.
+const tableStatisticsTableSchema = `
+CREATE TABLE system.table_statistics (
+  tableID       INT,
+  statisticID   INT        DEFAULT unique_rowid(),
+  name          STRING     NOT NULL,
+  columnID      INT        NOT NULL,
+  createdAt     TIMESTAMP  NOT NULL,
+  rowCount      INT        NOT NULL,
+  distinctCount INT        NOT NULL,
+  nullCount     INT        NOT NULL,
+  histogram     STRING,
+  PRIMARY KEY (tableID, statisticID)
+);`
+
+// AddTableStatisticsToMetadataSchema adds the table statistics table to the
+// supplied MetadataSchema.
+func AddTableStatisticsToMetadataSchema(schema *sqlbase.MetadataSchema) {
+	desc := CreateTableDescriptor(
+		keys.TableStatisticsTableID,
+		keys.SystemDatabaseID,
+		tableStatisticsTableSchema,
+		sqlbase.NewDefaultPrivilegeDescriptor(),
+	)
+	schema.AddDescriptor(keys.SystemDatabaseID, &desc)
+}
+
+const (
+	// statsSampleSize is the number of rows sampled to estimate the distinct
+	// counts and histograms of the columns of a table.
+	statsSampleSize = 10000
+	// statsMaxHistogramBuckets is the maximum number of buckets of the
+	// histograms.
+	statsMaxHistogramBuckets = 200
+
+	// autoStatsName is the name of the statistics collected automatically.
+	autoStatsName = "__auto__"
+	// The statistics of a table are refreshed automatically once the number
+	// of rows modified since they were collected exceeds autoStatsMinStaleRows
+	// plus autoStatsFractionStaleRows times the row count of the table.
+	autoStatsMinStaleRows      = 500
+	autoStatsFractionStaleRows = 0.2
+	// autoStatsRefreshInterval is how often the tables are checked for
+	// stale statistics.
+	autoStatsRefreshInterval = time.Minute
+
+	// tableStatsCacheTTL is how long the statistics of a table are cached
+	// before being loaded again, in case they were collected by another node.
+	tableStatsCacheTTL = time.Minute
+)
+
+// tableStatistic holds the statistics collected on a column of a table.
+type tableStatistic struct {
+	columnID      sqlbase.ColumnID
+	rowCount      int64
+	distinctCount int64
+	nullCount     int64
+	histogram     []histogramBucket
+}
+
+// histogramBucket is a bucket of an equi-depth histogram of the non-NULL
+// values of a column. The buckets are ordered by their upper bound.
+type histogramBucket struct {
+	// NumEq is the estimated number of rows equal to the upper bound.
+	NumEq int64 `json:"numEq"`
+	// NumRange is the estimated number of rows between the upper bound of
+	// the previous bucket and the upper bound of this one, both excluded.
+	NumRange int64 `json:"numRange"`
+	// UpperBound is the upper bound of the bucket, formatted as a SQL
+	// literal.
+	UpperBound string `json:"upperBound"`
+
+	// upperBound is UpperBound parsed as a value of the column, when the
+	// histogram is loaded by a TableStatisticsCache.
+	upperBound parser.Datum
+}
+
+// tableSampler counts the rows and NULL values of some columns of a table,
+// and keeps a uniform random sample of their values using reservoir
+// sampling.
+type tableSampler struct {
+	// cols are the indices of the sampled columns in the scanned rows.
+	cols       []int
+	rowCount   int64
+	nullCounts []int64
+	// sample holds the values of the sampled columns of up to
+	// statsSampleSize rows.
+	sample []parser.DTuple
+	rng    *rand.Rand
+	memAcc mon.MemoryAccount
+}
+
+func makeTableSampler(cols []int, memAcc mon.MemoryAccount) tableSampler {
+	return tableSampler{
+		cols:       cols,
+		nullCounts: make([]int64, len(cols)),
+		rng:        rand.New(rand.NewSource(timeutil.Now().UnixNano())),
+		memAcc:     memAcc,
+	}
+}
+
+// addRow accounts for a row of the table.
+func (s *tableSampler) addRow(row parser.DTuple) error {
+	s.rowCount++
+	for i, col := range s.cols {
+		if row[col] == parser.DNull {
+			s.nullCounts[i]++
+		}
+	}
+
+	idx := len(s.sample)
+	if idx >= statsSampleSize {
+		// Replace a random row of the sample with probability
+		// statsSampleSize/rowCount, so that every row is equally likely to be
+		// in the sample.
+		if idx = int(s.rng.Int63n(s.rowCount)); idx >= statsSampleSize {
+			return nil
+		}
+	}
+	values := make(parser.DTuple, len(s.cols))
+	for i, col := range s.cols {
+		values[i] = row[col]
+	}
+	if idx < len(s.sample) {
+		if err := s.memAcc.ResizeItem(int64(s.sample[idx].Size()), int64(values.Size())); err != nil {
+			return err
+		}
+		s.sample[idx] = values
+		return nil
+	}
+	if err := s.memAcc.Grow(int64(values.Size())); err != nil {
+		return err
+	}
+	s.sample = append(s.sample, values)
+	return nil
+}
+
+// columnStatistic computes the statistic of the i-th sampled column.
+func (s *tableSampler) columnStatistic(i int) tableStatistic {
+	stat := tableStatistic{
+		rowCount:  s.rowCount,
+		nullCount: s.nullCounts[i],
+	}
+	values := make(datumsByValue, 0, len(s.sample))
+	for _, row := range s.sample {
+		if row[i] != parser.DNull {
+			values = append(values, row[i])
+		}
+	}
+	if len(values) == 0 {
+		return stat
+	}
+	sort.Sort(values)
+
+	// Count the distinct values of the sample, and those which appear only
+	// once.
+	var distinct, once int64
+	for j := 0; j < len(values); {
+		k := j + 1
+		for k < len(values) && values[k].Compare(values[j]) == 0 {
+			k++
+		}
+		distinct++
+		if k-j == 1 {
+			once++
+		}
+		j = k
+	}
+	numValues := s.rowCount - stat.nullCount
+	stat.distinctCount = estimateDistinctCount(int64(len(values)), numValues, distinct, once)
+	stat.histogram = makeHistogram(values, numValues, statsMaxHistogramBuckets)
+	return stat
+}
+
+// estimateDistinctCount estimates the number of distinct values among
+// numValues values, given a sample of sampleSize of them containing distinct
+// distinct values, once of which appear only once. It uses the Duj1
+// estimator from Haas et al., "Sampling-Based Estimation of the Number of
+// Distinct Values of an Attribute" (1995).
+func estimateDistinctCount(sampleSize, numValues, distinct, once int64) int64 {
+	if sampleSize >= numValues {
+		// The sample contains all the values.
+		return distinct
+	}
+	n, d, f1 := float64(sampleSize), float64(distinct), float64(once)
+	estimate := n * d / (n - f1 + f1*n/float64(numValues))
+	if estimate < d {
+		return distinct
+	}
+	if estimate > float64(numValues) {
+		return numValues
+	}
+	return int64(estimate + 0.5)
+}
+
+// makeHistogram builds an equi-depth histogram with at most maxBuckets
+// buckets from a sorted sample of the numValues non-NULL values of a column.
+// The counts of rows are scaled to the number of values.
+func makeHistogram(values []parser.Datum, numValues int64, maxBuckets int) []histogramBucket {
+	scale := float64(numValues) / float64(len(values))
+	// Every bucket holds at least depth values of the sample, except the
+	// last one.
+	depth := (len(values) + maxBuckets - 1) / maxBuckets
+	var buckets []histogramBucket
+	numRange := 0
+	for j := 0; j < len(values); {
+		k := j + 1
+		for k < len(values) && values[k].Compare(values[j]) == 0 {
+			k++
+		}
+		numEq := k - j
+		if numRange+numEq >= depth || k == len(values) {
+			buckets = append(buckets, histogramBucket{
+				NumEq:      int64(math.Floor(float64(numEq)*scale + 0.5)),
+				NumRange:   int64(math.Floor(float64(numRange)*scale + 0.5)),
+				UpperBound: values[j].String(),
+			})
+			numRange = 0
+		} else {
+			numRange += numEq
+		}
+		j = k
+	}
+	return buckets
+}
+
+func (s *tableSampler) close() {
+	s.memAcc.Close()
+}
+
+// datumsByValue sorts datums of the same type by value.
+type datumsByValue []parser.Datum
+
+func (d datumsByValue) Len() int           { return len(d) }
+func (d datumsByValue) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d datumsByValue) Less(i, j int) bool { return d[i].Compare(d[j]) < 0 }
+
+type createStatsNode struct {
+	p         *planner
+	n         *parser.CreateStats
+	tableDesc *sqlbase.TableDescriptor
+	columns   []sqlbase.ColumnDescriptor
+}
+
+// CreateStatistics collects statistics on the columns of a table, or on
+// all of them if none is specified.
+// Privileges: CREATE on table.
+func (p *planner) CreateStatistics(n *parser.CreateStats) (planNode, error) {
+	tn, err := n.Table.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	tableDesc, err := p.mustGetTableDesc(tn)
+	if err != nil {
+		return nil, err
+	}
+	if !tableDesc.IsTable() {
+		return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
+
+	if err := p.checkPrivilege(tableDesc, privilege.CREATE); err != nil {
+		return nil, err
+	}
+
+	columns := tableDesc.Columns
+	if len(n.ColumnNames) > 0 {
+		columns = make([]sqlbase.ColumnDescriptor, len(n.ColumnNames))
+		for i, name := range n.ColumnNames {
+			if columns[i], err = tableDesc.FindActiveColumnByName(name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &createStatsNode{p: p, n: n, tableDesc: tableDesc, columns: columns}, nil
+}
+
+func (n *createStatsNode) expandPlan() error {
+	return nil
+}
+
+func (n *createStatsNode) Start() error {
+	return n.p.createStatistics(n.tableDesc, string(n.n.Name), n.columns)
+}
+
+func (n *createStatsNode) Next() (bool, error)                 { return false, nil }
+func (n *createStatsNode) Columns() []ResultColumn             { return make([]ResultColumn, 0) }
+func (n *createStatsNode) Ordering() orderingInfo              { return orderingInfo{} }
+func (n *createStatsNode) Values() parser.DTuple               { return parser.DTuple{} }
+func (n *createStatsNode) DebugValues() debugValues            { return debugValues{} }
+func (n *createStatsNode) ExplainTypes(_ func(string, string)) {}
+func (n *createStatsNode) SetLimitHint(_ int64, _ bool)        {}
+func (n *createStatsNode) MarkDebug(mode explainMode)          {}
+func (n *createStatsNode) ExplainPlan(v bool) (string, string, []planNode) {
+	return "create statistics", "", nil
+}
+
+// createStatistics scans a table to collect statistics on some of its
+// columns, and stores them in the table statistics table, replacing those
+// previously collected with the same name.
+func (p *planner) createStatistics(
+	desc *sqlbase.TableDescriptor, name string, columns []sqlbase.ColumnDescriptor,
+) error {
+	scan := p.Scan()
+	if err := scan.initTable(p, desc, nil, publicColumns); err != nil {
+		return err
+	}
+	needed := make([]bool, len(scan.cols))
+	cols := make([]int, len(columns))
+	for i, col := range columns {
+		cols[i] = scan.colIdxMap[col.ID]
+		needed[cols[i]] = true
+	}
+	scan.setNeededColumns(needed)
+	if err := scan.Start(); err != nil {
+		return err
+	}
+
+	sampler := makeTableSampler(cols, p.makeMemoryAccount())
+	defer sampler.close()
+	for {
+		next, err := scan.Next()
+		if err != nil {
+			return err
+		}
+		if !next {
+			break
+		}
+		if err := sampler.addRow(scan.Values()); err != nil {
+			return err
+		}
+	}
+
+	const deleteStmt = `
+DELETE FROM system.table_statistics WHERE tableID = $1 AND name = $2 AND columnID = $3
+`
+	const insertStmt = `
+INSERT INTO system.table_statistics (
+  tableID, name, columnID, createdAt, rowCount, distinctCount, nullCount, histogram
+)
+VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+`
+	if c := p.tableStatsCache(); c != nil {
+		// Note that another planner may still load the previous statistics
+		// and cache them until the transaction commits.
+		defer c.invalidate(desc.ID)
+	}
+
+	ie := InternalExecutor{LeaseManager: p.leaseMgr}
+	createdAt := p.txn.Proto.OrigTimestamp.GoTime()
+	for i, col := range columns {
+		stat := sampler.columnStatistic(i)
+		stat.columnID = col.ID
+		var histogram interface{}
+		if stat.histogram != nil {
+			histogramBytes, err := json.Marshal(stat.histogram)
+			if err != nil {
+				return err
+			}
+			histogram = string(histogramBytes)
+		}
+		if _, err := ie.ExecuteStatementInTransaction(
+			p.txn, deleteStmt, int(desc.ID), name, int(col.ID),
+		); err != nil {
+			return err
+		}
+		if _, err := ie.ExecuteStatementInTransaction(
+			p.txn, insertStmt, int(desc.ID), name, int(col.ID), createdAt,
+			stat.rowCount, stat.distinctCount, stat.nullCount, histogram,
+		); err != nil {
+			return err
+		}
+	}
+
+	if r := p.statsRefresher(); r != nil {
+		r.statisticsCollected(desc.ID, sampler.rowCount)
+	}
+	return nil
+}
+
+// statsRefresher returns the table statistics refresher of the node, if
+// any.
+func (p *planner) statsRefresher() *TableStatsRefresher {
+	if p.execCtx == nil {
+		return nil
+	}
+	return p.execCtx.StatsRefresher
+}
+
+// tableStatsCache returns the table statistics cache of the node, if any.
+func (p *planner) tableStatsCache() *TableStatisticsCache {
+	if p.execCtx == nil {
+		return nil
+	}
+	return p.execCtx.TableStatsCache
+}
+
+// getTableStatistics returns the latest statistics collected on a table, or
+// nil if there are none or they can't be loaded.
+func (p *planner) getTableStatistics(desc *sqlbase.TableDescriptor) *tableStatistics {
+	c := p.tableStatsCache()
+	if c == nil || p.txn == nil || desc.ID <= keys.MaxReservedDescID {
+		// The statistics of the system tables aren't collected.
+		return nil
+	}
+	stats, err := c.getTableStatistics(p.txn, desc)
+	if err != nil {
+		log.Warningf(p.ctx(), "unable to load the statistics of table %d: %s", desc.ID, err)
+		return nil
+	}
+	return stats
+}
+
+// tableStatistics holds the latest statistics collected on the columns of a
+// table.
+type tableStatistics struct {
+	// rowCount is the row count of the table when statistics were last
+	// collected on it.
+	rowCount int64
+	columns  map[sqlbase.ColumnID]*tableStatistic
+}
+
+// TableStatisticsCache caches the statistics of the tables, which the
+// planner uses to estimate the cost of scanning them.
+type TableStatisticsCache struct {
+	leaseMgr *LeaseManager
+
+	mu struct {
+		syncutil.Mutex
+		entries map[sqlbase.ID]cachedTableStatistics
+	}
+}
+
+type cachedTableStatistics struct {
+	// stats is nil if no statistics were collected on the table.
+	stats    *tableStatistics
+	loadedAt time.Time
+}
+
+// NewTableStatisticsCache returns a new TableStatisticsCache.
+func NewTableStatisticsCache(leaseMgr *LeaseManager) *TableStatisticsCache {
+	c := &TableStatisticsCache{leaseMgr: leaseMgr}
+	c.mu.entries = make(map[sqlbase.ID]cachedTableStatistics)
+	return c
+}
+
+// getTableStatistics returns the statistics of a table, loading them if
+// they aren't cached or were cached too long ago.
+//
+// The statistics are loaded in the transaction of the caller, which may
+// have collected them itself: reading them in another transaction would
+// wait for it to finish. If it aborts, the cache holds statistics which
+// don't exist until they expire.
+func (c *TableStatisticsCache) getTableStatistics(
+	txn *client.Txn, desc *sqlbase.TableDescriptor,
+) (*tableStatistics, error) {
+	c.mu.Lock()
+	e, ok := c.mu.entries[desc.ID]
+	c.mu.Unlock()
+	if ok && timeutil.Since(e.loadedAt) < tableStatsCacheTTL {
+		return e.stats, nil
+	}
+
+	stats, err := c.loadTableStatistics(txn, desc)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.mu.entries[desc.ID] = cachedTableStatistics{stats: stats, loadedAt: timeutil.Now()}
+	c.mu.Unlock()
+	return stats, nil
+}
+
+// invalidate removes the statistics of a table from the cache.
+func (c *TableStatisticsCache) invalidate(tableID sqlbase.ID) {
+	c.mu.Lock()
+	delete(c.mu.entries, tableID)
+	c.mu.Unlock()
+}
+
+// loadTableStatistics reads the latest statistics collected on every column
+// of a table.
+func (c *TableStatisticsCache) loadTableStatistics(
+	txn *client.Txn, desc *sqlbase.TableDescriptor,
+) (*tableStatistics, error) {
+	p := makeInternalPlanner(txn, security.RootUser)
+	p.leaseMgr = c.leaseMgr
+	plan, err := p.query(`
+SELECT columnID, rowCount, distinctCount, nullCount, histogram FROM system.table_statistics
+WHERE tableID = $1 ORDER BY createdAt DESC
+`, int(desc.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := plan.Start(); err != nil {
+		return nil, err
+	}
+
+	var stats *tableStatistics
+	for {
+		next, err := plan.Next()
+		if err != nil {
+			return nil, err
+		}
+		if !next {
+			return stats, nil
+		}
+		row := plan.Values()
+		if stats == nil {
+			stats = &tableStatistics{
+				rowCount: int64(*row[1].(*parser.DInt)),
+				columns:  make(map[sqlbase.ColumnID]*tableStatistic),
+			}
+		}
+		colID := sqlbase.ColumnID(*row[0].(*parser.DInt))
+		if _, ok := stats.columns[colID]; ok {
+			// Older statistics of the column.
+			continue
+		}
+		col, err := desc.FindActiveColumnByID(colID)
+		if err != nil {
+			// The column was dropped.
+			continue
+		}
+		stat := &tableStatistic{
+			columnID:      colID,
+			rowCount:      int64(*row[1].(*parser.DInt)),
+			distinctCount: int64(*row[2].(*parser.DInt)),
+			nullCount:     int64(*row[3].(*parser.DInt)),
+		}
+		if row[4] != parser.DNull {
+			if stat.histogram, err = decodeHistogram(string(*row[4].(*parser.DString)), col); err != nil {
+				return nil, err
+			}
+		}
+		stats.columns[colID] = stat
+	}
+}
+
+// decodeHistogram decodes a histogram of a column, as stored in the table
+// statistics table.
+func decodeHistogram(s string, col *sqlbase.ColumnDescriptor) ([]histogramBucket, error) {
+	var buckets []histogramBucket
+	if err := json.Unmarshal([]byte(s), &buckets); err != nil {
+		return nil, err
+	}
+	var evalCtx parser.EvalContext
+	for i := range buckets {
+		expr, err := parser.ParseExprTraditional(buckets[i].UpperBound)
+		if err != nil {
+			return nil, err
+		}
+		typedExpr, err := parser.TypeCheckAndRequire(expr, nil, col.Type.ToDatumType(), "histogram")
+		if err != nil {
+			return nil, err
+		}
+		if buckets[i].upperBound, err = typedExpr.Eval(&evalCtx); err != nil {
+			return nil, err
+		}
+	}
+	return buckets, nil
+}
+
+// TableStatsRefresher collects statistics automatically on the tables whose
+// rows were modified by this node, once enough of them were modified since
+// their statistics were last collected.
+type TableStatsRefresher struct {
+	db       client.DB
+	leaseMgr *LeaseManager
+	cache    *TableStatisticsCache
+
+	mu struct {
+		syncutil.Mutex
+		tables map[sqlbase.ID]*staleTableInfo
+	}
+}
+
+// staleTableInfo tracks the modifications of the rows of a table.
+type staleTableInfo struct {
+	// modified is the number of rows modified since the statistics of the
+	// table were last collected.
+	modified int64
+	// rowCount is the row count of the table when its statistics were last
+	// collected, or -1 if it's unknown.
+	rowCount int64
+}
+
+// NewTableStatsRefresher returns a new TableStatsRefresher. The statistics
+// it collects are removed from the cache, if any.
+func NewTableStatsRefresher(
+	db client.DB, leaseMgr *LeaseManager, cache *TableStatisticsCache,
+) *TableStatsRefresher {
+	r := &TableStatsRefresher{db: db, leaseMgr: leaseMgr, cache: cache}
+	r.mu.tables = make(map[sqlbase.ID]*staleTableInfo)
+	return r
+}
+
+// NotifyMutation records that rows of a table were modified.
+func (r *TableStatsRefresher) NotifyMutation(tableID sqlbase.ID, rowsModified int) {
+	if rowsModified == 0 || tableID <= keys.MaxReservedDescID {
+		// The statistics of the system tables aren't collected.
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info, ok := r.mu.tables[tableID]
+	if !ok {
+		info = &staleTableInfo{rowCount: -1}
+		r.mu.tables[tableID] = info
+	}
+	info.modified += int64(rowsModified)
+}
+
+// statisticsCollected records that statistics were collected on a table.
+func (r *TableStatsRefresher) statisticsCollected(tableID sqlbase.ID, rowCount int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if info, ok := r.mu.tables[tableID]; ok {
+		info.modified = 0
+		info.rowCount = rowCount
+	}
+}
+
+// Start starts a goroutine that periodically refreshes the statistics of
+// the tables which have too many modified rows.
+func (r *TableStatsRefresher) Start(stopper *stop.Stopper) {
+	stopper.RunWorker(func() {
+		ticker := time.NewTicker(autoStatsRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := stopper.RunTask(r.refreshStaleTables); err != nil {
+					return
+				}
+
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// refreshStaleTables collects statistics on the tables which have too many
+// modified rows.
+func (r *TableStatsRefresher) refreshStaleTables() {
+	for _, tableID := range r.staleTables() {
+		if err := r.refreshTable(tableID); err != nil {
+			if err == sqlbase.ErrDescriptorNotFound {
+				// The table was dropped.
+				r.mu.Lock()
+				delete(r.mu.tables, tableID)
+				r.mu.Unlock()
+				continue
+			}
+			log.Warningf(context.TODO(), "unable to refresh the statistics of table %d: %s", tableID, err)
+		}
+	}
+}
+
+// staleTables returns the tables whose statistics must be refreshed. The
+// row count of the tables not yet known is read from their last statistics.
+func (r *TableStatsRefresher) staleTables() []sqlbase.ID {
+	r.mu.Lock()
+	var unknown []sqlbase.ID
+	for tableID, info := range r.mu.tables {
+		if info.rowCount < 0 {
+			unknown = append(unknown, tableID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, tableID := range unknown {
+		var rowCount int64
+		if err := r.db.Txn(func(txn *client.Txn) error {
+			p := makeInternalPlanner(txn, security.RootUser)
+			p.leaseMgr = r.leaseMgr
+			var err error
+			rowCount, err = r.tableRowCount(p, tableID)
+			return err
+		}); err != nil {
+			log.Warningf(context.TODO(), "unable to read the statistics of table %d: %s", tableID, err)
+			continue
+		}
+		r.mu.Lock()
+		if info, ok := r.mu.tables[tableID]; ok && info.rowCount < 0 {
+			info.rowCount = rowCount
+		}
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var stale []sqlbase.ID
+	for tableID, info := range r.mu.tables {
+		if info.rowCount >= 0 &&
+			float64(info.modified) >= autoStatsMinStaleRows+autoStatsFractionStaleRows*float64(info.rowCount) {
+			stale = append(stale, tableID)
+		}
+	}
+	return stale
+}
+
+// refreshTable collects statistics on all the columns of a table.
+func (r *TableStatsRefresher) refreshTable(tableID sqlbase.ID) error {
+	var rowCount int64
+	err := r.db.Txn(func(txn *client.Txn) error {
+		desc, err := sqlbase.GetTableDescFromID(txn, tableID)
+		if err != nil {
+			return err
+		}
+		if desc.Deleted() || !desc.IsTable() {
+			return sqlbase.ErrDescriptorNotFound
+		}
+		p := makeInternalPlanner(txn, security.RootUser)
+		p.leaseMgr = r.leaseMgr
+		if err := p.createStatistics(desc, autoStatsName, desc.Columns); err != nil {
+			return err
+		}
+		rowCount, err = r.tableRowCount(p, tableID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if r.cache != nil {
+		r.cache.invalidate(tableID)
+	}
+	r.statisticsCollected(tableID, rowCount)
+	return nil
+}
+
+// tableRowCount returns the row count of the last statistics collected on a
+// table.
+func (r *TableStatsRefresher) tableRowCount(p *planner, tableID sqlbase.ID) (int64, error) {
+	row, err := p.queryRow(`
+SELECT rowCount FROM system.table_statistics WHERE tableID = $1 ORDER BY createdAt DESC LIMIT 1
+`, int(tableID))
+	if err != nil || row == nil {
+		return 0, err
+	}
+	return int64(*row[0].(*parser.DInt)), nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/sql/mon"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestMakeHistogram(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var values []parser.Datum
+	for _, v := range []int{1, 2, 2, 3, 4, 4, 4, 5, 6, 7} {
+		values = append(values, parser.NewDInt(parser.DInt(v)))
+	}
+
+	testCases := []struct {
+		maxBuckets int
+		numValues  int64
+		expected   []histogramBucket
+	}{
+		{10, 10, []histogramBucket{
+			{NumEq: 1, UpperBound: "1"},
+			{NumEq: 2, UpperBound: "2"},
+			{NumEq: 1, UpperBound: "3"},
+			{NumEq: 3, UpperBound: "4"},
+			{NumEq: 1, UpperBound: "5"},
+			{NumEq: 1, UpperBound: "6"},
+			{NumEq: 1, UpperBound: "7"},
+		}},
+		{3, 10, []histogramBucket{
+			{NumEq: 1, NumRange: 3, UpperBound: "3"},
+			{NumEq: 1, NumRange: 3, UpperBound: "5"},
+			{NumEq: 1, NumRange: 1, UpperBound: "7"},
+		}},
+		// The counts are scaled when the values are a sample.
+		{3, 100, []histogramBucket{
+			{NumEq: 10, NumRange: 30, UpperBound: "3"},
+			{NumEq: 10, NumRange: 30, UpperBound: "5"},
+			{NumEq: 10, NumRange: 10, UpperBound: "7"},
+		}},
+	}
+	for _, tc := range testCases {
+		if h := makeHistogram(values, tc.numValues, tc.maxBuckets); !reflect.DeepEqual(h, tc.expected) {
+			t.Errorf("%d buckets, %d values: expected %v, got %v", tc.maxBuckets, tc.numValues, tc.expected, h)
+		}
+	}
+}
+
+func TestEstimateDistinctCount(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		sampleSize, numValues, distinct, once int64
+		expected                              int64
+	}{
+		// The sample contains all the values.
+		{100, 100, 42, 10, 42},
+		// No value appears only once; the sample probably contains all the
+		// distinct values.
+		{100, 1000, 10, 0, 10},
+		// All the values of the sample are distinct.
+		{100, 1000, 100, 100, 1000},
+		{100, 1000, 60, 30, 82},
+	}
+	for _, tc := range testCases {
+		if d := estimateDistinctCount(tc.sampleSize, tc.numValues, tc.distinct, tc.once); d != tc.expected {
+			t.Errorf("%+v: expected %d, got %d", tc, tc.expected, d)
+		}
+	}
+}
+
+func TestTableSampler(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const numRows = 3 * statsSampleSize
+	s := makeTableSampler([]int{1, 2}, mon.MemoryAccount{})
+	defer s.close()
+	for i := 0; i < numRows; i++ {
+		row := parser.DTuple{parser.NewDInt(parser.DInt(i)), parser.NewDInt(parser.DInt(i % 10)), parser.DNull}
+		if i%3 == 0 {
+			row[1] = parser.DNull
+		}
+		if err := s.addRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.sample) != statsSampleSize {
+		t.Fatalf("expected a sample of %d rows, got %d", statsSampleSize, len(s.sample))
+	}
+
+	stat := s.columnStatistic(0)
+	if stat.rowCount != numRows || stat.nullCount != numRows/3 || stat.distinctCount != 10 {
+		t.Errorf("unexpected statistic %+v", stat)
+	}
+	if len(stat.histogram) != 10 {
+		t.Errorf("expected 10 buckets, got %v", stat.histogram)
+	}
+	var total int64
+	for _, b := range stat.histogram {
+		total += b.NumEq + b.NumRange
+	}
+	if total < numRows*2/3-10 || total > numRows*2/3+10 {
+		t.Errorf("expected the histogram to count %d values, got %d", numRows*2/3, total)
+	}
+
+	stat = s.columnStatistic(1)
+	if stat.nullCount != numRows || stat.distinctCount != 0 || stat.histogram != nil {
+		t.Errorf("unexpected statistic %+v", stat)
+	}
+}
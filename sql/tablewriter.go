@@ -204,6 +204,10 @@ type tableUpsertEvaler interface {
 type tableUpserter struct {
 	ri            rowInserter
 	conflictIndex sqlbase.IndexDescriptor
+	// anyConflict is set for ON CONFLICT DO NOTHING without a conflict target,
+	// in which case rows conflicting with an existing row in any unique index
+	// are skipped, not only those conflicting in conflictIndex.
+	anyConflict bool
 
 	// These are set for ON CONFLICT DO UPDATE, but not for DO NOTHING
 	updateCols []sqlbase.ColumnDescriptor
@@ -320,11 +324,21 @@ func (tu *tableUpserter) flush(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	var uniqueConflicts []bool
+	if tu.anyConflict {
+		if uniqueConflicts, err = tu.uniqueIndexConflicts(ctx, existingRows); err != nil {
+			return err
+		}
+	}
 
 	b := tu.txn.NewBatch()
 	for i, insertRow := range tu.insertRows {
 		existingRow := existingRows[i]
 
+		if uniqueConflicts != nil && uniqueConflicts[i] {
+			// DO NOTHING on a conflict in a unique secondary index.
+			continue
+		}
 		if existingRow == nil {
 			err := tu.ri.insertRow(ctx, b, insertRow, false)
 			if err != nil {
@@ -352,6 +366,95 @@ func (tu *tableUpserter) flush(ctx context.Context) error {
 	return nil
 }
 
+// uniqueIndexConflicts returns, for each row in tu.insertRows, whether it
+// conflicts in one of the table's unique secondary indexes with an existing
+// entry or with an earlier row of the batch which is going to be inserted.
+// existingRows are the rows conflicting in the primary index, which are not
+// inserted.
+func (tu *tableUpserter) uniqueIndexConflicts(
+	ctx context.Context, existingRows []parser.DTuple,
+) ([]bool, error) {
+	conflicts := make([]bool, len(tu.insertRows))
+	// rowKeys holds, for each row in tu.insertRows, the keys of its entries
+	// in the unique secondary indexes.
+	rowKeys := make([][]roachpb.Key, len(tu.insertRows))
+
+	b := tu.txn.NewBatch()
+	// rowIdxs maps the requests in b to the index of their row in
+	// tu.insertRows.
+	var rowIdxs []int
+	for i := range tu.tableDesc.Indexes {
+		index := &tu.tableDesc.Indexes[i]
+		if !index.Unique {
+			continue
+		}
+		var pred *indexPredicate
+		if index.IsPartial() {
+			var err error
+			if pred, err = makeIndexPredicate(tu.tableDesc, index); err != nil {
+				return nil, err
+			}
+		}
+		for j, insertRow := range tu.insertRows {
+			if pred != nil {
+				matches, err := pred.matches(&tu.ri.helper.evalCtx, tu.ri.insertColIDtoRowIndex, insertRow)
+				if err != nil {
+					return nil, err
+				}
+				if !matches {
+					// The row can't conflict in a partial index that won't contain it.
+					continue
+				}
+			}
+			entry, err := sqlbase.EncodeSecondaryIndex(
+				tu.tableDesc, index, tu.ri.insertColIDtoRowIndex, insertRow)
+			if err != nil {
+				return nil, err
+			}
+			if log.V(2) {
+				log.Infof(ctx, "Get %s\n", entry.Key)
+			}
+			b.Get(entry.Key)
+			rowIdxs = append(rowIdxs, j)
+			rowKeys[j] = append(rowKeys[j], entry.Key)
+		}
+	}
+	if len(rowIdxs) == 0 {
+		return conflicts, nil
+	}
+
+	if err := tu.txn.Run(b); err != nil {
+		return nil, err
+	}
+	for i, result := range b.Results {
+		if len(result.Rows) == 1 && result.Rows[0].Value != nil {
+			conflicts[rowIdxs[i]] = true
+		}
+	}
+
+	// Like the rows conflicting in the primary index, the rows conflicting in
+	// a unique secondary index with an earlier row of the batch are skipped.
+	batchKeys := make(map[string]struct{})
+	for i, keys := range rowKeys {
+		if conflicts[i] || existingRows[i] != nil {
+			continue
+		}
+		for _, key := range keys {
+			if _, ok := batchKeys[string(key)]; ok {
+				conflicts[i] = true
+				break
+			}
+		}
+		if conflicts[i] {
+			continue
+		}
+		for _, key := range keys {
+			batchKeys[string(key)] = struct{}{}
+		}
+	}
+	return conflicts, nil
+}
+
 // upsertRowPKs returns the primary keys of any rows with potential upsert
 // conflicts.
 func (tu *tableUpserter) upsertRowPKs(ctx context.Context) ([]roachpb.Key, error) {
@@ -0,0 +1,217 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/base"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/retry"
+	"github.com/cockroachdb/cockroach/util/stop"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// temporarySessions tracks the sessions of a node that own temporary
+// tables. A temporary table of the node whose session is not tracked here
+// belongs to a session that went away without cleaning up after itself.
+type temporarySessions struct {
+	mu  syncutil.Mutex
+	ids map[string]struct{}
+}
+
+func (ts *temporarySessions) add(id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.ids == nil {
+		ts.ids = make(map[string]struct{})
+	}
+	ts.ids[id] = struct{}{}
+}
+
+func (ts *temporarySessions) remove(id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.ids, id)
+}
+
+func (ts *temporarySessions) contains(id string) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	_, ok := ts.ids[id]
+	return ok
+}
+
+// makeTemporaryInfo returns the information identifying the current session
+// as the owner of a new temporary table.
+func (p *planner) makeTemporaryInfo() (*sqlbase.TableDescriptor_TemporaryInfo, error) {
+	s := p.session
+	if s.tempSessions == nil || p.execCtx == nil {
+		return nil, errors.New("temporary tables can only be created by client sessions")
+	}
+	if s.tempSessionID == "" {
+		s.tempSessionID = makeQueryID(p.execCtx.Clock.Now(), p.evalCtx.NodeID)
+		s.tempSessions.add(s.tempSessionID)
+	}
+	return &sqlbase.TableDescriptor_TemporaryInfo{
+		SessionID: s.tempSessionID,
+		NodeID:    p.evalCtx.NodeID,
+	}, nil
+}
+
+// isOtherSessionTemporaryTable returns true if desc is a temporary table
+// created by another session. Such tables are invisible to the session.
+func (p *planner) isOtherSessionTemporaryTable(desc *sqlbase.TableDescriptor) bool {
+	return desc.IsTemporary() && desc.Temporary.SessionID != p.session.tempSessionID
+}
+
+// finishTemporaryTables drops the temporary tables created by the session.
+// If that fails, the tables are left to the TemporaryTableReaper.
+func (s *Session) finishTemporaryTables() {
+	if s.tempSessionID == "" {
+		return
+	}
+	if len(s.tempTableIDs) > 0 {
+		if err := dropTemporaryTables(
+			s.planner.execCtx, s.planner.evalCtx.NodeID, s.tempTableIDs,
+		); err != nil {
+			log.Warningf(s.context, "unable to drop temporary tables: %s", err)
+		}
+		s.tempTableIDs = nil
+	}
+	s.tempSessions.remove(s.tempSessionID)
+	s.tempSessionID = ""
+}
+
+// dropTemporaryTables drops the temporary tables with the given IDs, then
+// deletes their data. Tables already dropped are skipped.
+func dropTemporaryTables(execCtx *ExecutorContext, nodeID roachpb.NodeID, ids []sqlbase.ID) error {
+	var p *planner
+	if err := execCtx.DB.Txn(func(txn *client.Txn) error {
+		p = makeInternalPlanner(txn, security.RootUser)
+		p.leaseMgr = execCtx.LeaseManager
+		p.evalCtx.NodeID = nodeID
+		for _, id := range ids {
+			desc, err := sqlbase.GetTableDescFromID(txn, id)
+			if err == sqlbase.ErrDescriptorNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if desc.Deleted() {
+				continue
+			}
+			cascadeDroppedViews, err := p.removeDependentViews(desc)
+			if err != nil {
+				return err
+			}
+			if err := p.dropTableImpl(desc); err != nil {
+				return err
+			}
+			if err := MakeEventLogger(p.leaseMgr).InsertEventRecord(txn,
+				EventLogDropTable,
+				int32(desc.ID),
+				int32(nodeID),
+				struct {
+					TableName           string
+					Statement           string
+					User                string
+					CascadeDroppedViews []string
+				}{desc.Name, "", p.session.User, cascadeDroppedViews},
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Delete the data of the dropped tables right away rather than waiting
+	// for the SchemaChangeManager to do it.
+	for _, scEntry := range p.session.TxnState.schemaChangers.schemaChangers {
+		sc := &scEntry.sc
+		sc.db = *execCtx.DB
+		for r := retry.Start(base.DefaultRetryOptions()); r.Next(); {
+			err := sc.exec(nil, nil)
+			if err != nil && isSchemaChangeRetryError(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// StartTemporaryTableReaper starts a goroutine that drops the temporary
+// tables created by sessions of this node which ended without dropping them,
+// e.g. because the node crashed. The tables are found through the system
+// configuration received via gossip. The temporary tables of a node that
+// never restarts are not reclaimed.
+func (e *Executor) StartTemporaryTableReaper(stopper *stop.Stopper) {
+	stopper.RunWorker(func() {
+		descKeyPrefix := keys.MakeTablePrefix(uint32(sqlbase.DescriptorTable.ID))
+		gossipUpdateC := e.ctx.Gossip.RegisterSystemConfigChannel()
+		for {
+			select {
+			case <-gossipUpdateC:
+				cfg, _ := e.ctx.Gossip.GetSystemConfig()
+				var orphans []sqlbase.ID
+				for _, kv := range cfg.Values {
+					if !bytes.HasPrefix(kv.Key, descKeyPrefix) {
+						continue
+					}
+					var descriptor sqlbase.Descriptor
+					if err := kv.Value.GetProto(&descriptor); err != nil {
+						continue
+					}
+					table := descriptor.GetTable()
+					if table == nil || !table.IsTemporary() || table.Deleted() {
+						continue
+					}
+					if table.Temporary.NodeID != e.nodeID ||
+						e.tempSessions.contains(table.Temporary.SessionID) {
+						continue
+					}
+					orphans = append(orphans, table.ID)
+				}
+				if len(orphans) == 0 {
+					continue
+				}
+				if log.V(2) {
+					log.Infof(context.TODO(), "dropping orphaned temporary tables %v", orphans)
+				}
+				if err := dropTemporaryTables(&e.ctx, e.nodeID, orphans); err != nil {
+					log.Warningf(context.TODO(), "unable to drop orphaned temporary tables: %s", err)
+				}
+
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
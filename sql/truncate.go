@@ -17,18 +17,29 @@
 package sql
 
 import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/sql/parser"
 	"github.com/cockroachdb/cockroach/sql/privilege"
 	"github.com/cockroachdb/cockroach/sql/sqlbase"
-	"golang.org/x/net/context"
+	"github.com/cockroachdb/cockroach/util/protoutil"
 )
 
 // Truncate deletes all rows from a table.
 // Privileges: DROP on table.
 //   Notes: postgres requires TRUNCATE.
 //          mysql requires DROP (for mysql >= 5.1.16, DELETE before that).
+//
+// Rather than deleting the rows, a table is replaced by an empty copy with a
+// new ID, and the old table is dropped, which deletes its data
+// asynchronously. Interleaved tables share their data with other tables, so
+// their rows are deleted in place.
 func (p *planner) Truncate(n *parser.Truncate) (planNode, error) {
+	var tables []*sqlbase.TableDescriptor
+	toTruncate := make(map[sqlbase.ID]struct{})
 	for _, name := range n.Tables {
 		tn, err := name.NormalizeTableName()
 		if err != nil {
@@ -42,30 +53,198 @@ func (p *planner) Truncate(n *parser.Truncate) (planNode, error) {
 		if err != nil {
 			return nil, err
 		}
+		if !tableDesc.IsTable() {
+			return nil, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+		}
 
 		if err := p.checkPrivilege(tableDesc, privilege.DROP); err != nil {
 			return nil, err
 		}
+		if _, ok := toTruncate[tableDesc.ID]; !ok {
+			toTruncate[tableDesc.ID] = struct{}{}
+			tables = append(tables, tableDesc)
+		}
+	}
 
-		if err := truncateTable(tableDesc, p.txn); err != nil {
-			return nil, err
+	// The tables referencing the truncated tables through foreign keys must be
+	// truncated too, which requires CASCADE.
+	for i := 0; i < len(tables); i++ {
+		tableDesc := tables[i]
+		for _, idx := range tableDesc.AllNonDropIndexes() {
+			for _, ref := range idx.ReferencedBy {
+				if _, ok := toTruncate[ref.Table]; ok {
+					continue
+				}
+				other, err := sqlbase.GetTableDescFromID(p.txn, ref.Table)
+				if err != nil {
+					return nil, err
+				}
+				if n.DropBehavior != parser.DropCascade {
+					return nil, errors.Errorf("%q is referenced by foreign key from table %q",
+						tableDesc.Name, other.Name)
+				}
+				if err := p.checkPrivilege(other, privilege.DROP); err != nil {
+					return nil, err
+				}
+				toTruncate[other.ID] = struct{}{}
+				tables = append(tables, other)
+			}
 		}
+	}
 
-		fkTables := tablesNeededForFKs(*tableDesc, CheckDeletes)
-		if err := p.fillFKTableMap(fkTables); err != nil {
-			return nil, err
+	newIDs := make(map[sqlbase.ID]sqlbase.ID)
+	for _, tableDesc := range tables {
+		if tableDesc.IsInterleaved() {
+			continue
 		}
-		colMap := colIDtoRowIndexFromCols(tableDesc.Columns)
-		if helper, err := makeFKDeleteHelper(p.txn, *tableDesc, fkTables, colMap); err != nil {
+		newID, err := p.generateUniqueDescID()
+		if err != nil {
 			return nil, err
-		} else if err = helper.checkAll(nil); err != nil {
+		}
+		newIDs[tableDesc.ID] = newID
+	}
+
+	for _, tableDesc := range tables {
+		if newID, ok := newIDs[tableDesc.ID]; ok {
+			if err := p.replaceTable(tableDesc, newID, newIDs); err != nil {
+				return nil, err
+			}
+		} else if err := truncateTable(tableDesc, p.txn); err != nil {
 			return nil, err
 		}
 	}
 
+	// Point the remaining tables and views related to the replaced tables to
+	// the replacements.
+	updated := make(map[sqlbase.ID]struct{})
+	for _, tableDesc := range tables {
+		if _, ok := newIDs[tableDesc.ID]; !ok {
+			continue
+		}
+		var related []sqlbase.ID
+		for _, idx := range tableDesc.AllNonDropIndexes() {
+			if idx.ForeignKey.IsSet() {
+				related = append(related, idx.ForeignKey.Table)
+			}
+		}
+		related = append(related, tableDesc.DependedOnBy...)
+		for _, id := range related {
+			if _, ok := newIDs[id]; ok {
+				continue
+			}
+			if _, ok := updated[id]; ok {
+				continue
+			}
+			updated[id] = struct{}{}
+			desc, err := sqlbase.GetTableDescFromID(p.txn, id)
+			if err != nil {
+				return nil, err
+			}
+			remapTableReferences(desc, newIDs)
+			if err := p.saveNonmutationAndNotify(desc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &emptyNode{}, nil
 }
 
+// replaceTable replaces the table with an empty copy of it with ID newID and
+// drops the table. The references to the tables being replaced in the same
+// statement are pointed to their replacements, per newIDs.
+func (p *planner) replaceTable(
+	tableDesc *sqlbase.TableDescriptor, newID sqlbase.ID, newIDs map[sqlbase.ID]sqlbase.ID,
+) error {
+	newDesc := protoutil.Clone(tableDesc).(*sqlbase.TableDescriptor)
+	newDesc.ID = newID
+	newDesc.Version = 1
+	newDesc.UpVersion = false
+	newDesc.Lease = nil
+	remapTableReferences(newDesc, newIDs)
+
+	zoneKey, nameKey, _ := getKeysForTableDescriptor(tableDesc)
+	newDescKey := sqlbase.MakeDescMetadataKey(newID)
+	b := &client.Batch{}
+	b.CPut(nameKey, newID, tableDesc.ID)
+	b.CPut(newDescKey, sqlbase.WrapDescriptor(newDesc), nil)
+	var zone config.ZoneConfig
+	if gr, err := p.txn.Get(zoneKey); err != nil {
+		return err
+	} else if gr.Exists() {
+		if err := gr.ValueProto(&zone); err != nil {
+			return err
+		}
+		b.Put(sqlbase.MakeZoneKey(newID), &zone)
+	}
+	if err := p.txn.Run(b); err != nil {
+		return err
+	}
+
+	var lastMutationID sqlbase.MutationID
+	for _, m := range newDesc.Mutations {
+		if m.MutationID != lastMutationID {
+			p.notifySchemaChange(newID, m.MutationID)
+			lastMutationID = m.MutationID
+		}
+	}
+	if newDesc.IsTemporary() {
+		p.session.tempTableIDs = append(p.session.tempTableIDs, newID)
+	}
+	p.session.TxnState.tableReplacements = append(p.session.TxnState.tableReplacements,
+		tableReplacement{epoch: p.txn.Proto.Epoch, oldID: tableDesc.ID, newID: newID})
+
+	// Drop the table; the schema changer deletes its data once it is no
+	// longer in use.
+	if err := tableDesc.SetUpVersion(); err != nil {
+		return err
+	}
+	tableDesc.State = sqlbase.TableDescriptor_DROP
+	if err := p.writeTableDesc(tableDesc); err != nil {
+		return err
+	}
+	p.notifySchemaChange(tableDesc.ID, sqlbase.InvalidMutationID)
+
+	p.setTestingVerifyMetadata(func(systemConfig config.SystemConfig) error {
+		return expectDescriptorID(systemConfig, nameKey, newID)
+	})
+	return nil
+}
+
+// remapTableReferences points the references of desc to other tables
+// through foreign keys and view dependencies to the replacements of these
+// tables, per newIDs.
+func remapTableReferences(desc *sqlbase.TableDescriptor, newIDs map[sqlbase.ID]sqlbase.ID) {
+	remap := func(id *sqlbase.ID) {
+		if newID, ok := newIDs[*id]; ok {
+			*id = newID
+		}
+	}
+	remapIndex := func(idx *sqlbase.IndexDescriptor) {
+		if idx.ForeignKey.IsSet() {
+			remap(&idx.ForeignKey.Table)
+		}
+		for i := range idx.ReferencedBy {
+			remap(&idx.ReferencedBy[i].Table)
+		}
+	}
+	remapIndex(&desc.PrimaryIndex)
+	for i := range desc.Indexes {
+		remapIndex(&desc.Indexes[i])
+	}
+	for i := range desc.Mutations {
+		if idx := desc.Mutations[i].GetIndex(); idx != nil {
+			remapIndex(idx)
+		}
+	}
+	for i := range desc.DependsOn {
+		remap(&desc.DependsOn[i])
+	}
+	for i := range desc.DependedOnBy {
+		remap(&desc.DependedOnBy[i])
+	}
+}
+
 // truncateTable truncates the data of a table.
 // It deletes a range of data for the table, which includes the PK and all
 // indexes.
@@ -686,7 +686,7 @@ func TestRollbackToSavepointStatement(t *testing.T) {
 	}
 	// ROLLBACK TO SAVEPOINT with a wrong name
 	_, err = sqlDB.Exec("ROLLBACK TO SAVEPOINT foo")
-	if !testutils.IsError(err, "SAVEPOINT not supported except for COCKROACH_RESTART") {
+	if !testutils.IsError(err, "savepoint foo does not exist") {
 		t.Fatal("expected to fail here. err: ", err)
 	}
 
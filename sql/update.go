@@ -44,6 +44,9 @@ func (p *planner) makeEditNode(tn *parser.TableName, autoCommit bool, priv privi
 	if err != nil {
 		return editNodeBase{}, err
 	}
+	if !tableDesc.IsTable() {
+		return editNodeBase{}, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
 
 	if err := p.checkPrivilege(tableDesc, priv); err != nil {
 		return editNodeBase{}, err
@@ -62,6 +65,8 @@ type editNodeRun struct {
 	rows      planNode
 	tw        tableWriter
 	resultRow parser.DTuple
+	// rowsWritten is the number of rows passed to tw.
+	rowsWritten int
 
 	explain explainMode
 }
@@ -96,6 +101,15 @@ func (r *editNodeRun) expandEditNodePlan(en *editNodeBase, tw tableWriter) error
 	return r.rows.expandPlan()
 }
 
+// notifyMutation tells the table statistics refresher, if any, that rows of
+// the table were modified. The transaction may still abort; the refresher
+// only uses the count as an estimate of how stale the statistics are.
+func (en *editNodeBase) notifyMutation(rowsWritten int) {
+	if r := en.p.statsRefresher(); r != nil {
+		r.NotifyMutation(en.tableDesc.ID, rowsWritten)
+	}
+}
+
 func (r *editNodeRun) startEditNode() error {
 	if err := r.tw.start(); err != nil {
 		return err
@@ -110,7 +124,9 @@ type updateNode struct {
 	n             *parser.Update
 	updateCols    []sqlbase.ColumnDescriptor
 	updateColsIdx map[sqlbase.ColumnID]int // index in updateCols slice
+	numSetCols    int                      // number of updateCols assigned by SET
 	tw            tableUpdater
+	computeHelper computeHelper
 	checkHelper   checkHelper
 
 	run struct {
@@ -127,6 +143,11 @@ type updateNode struct {
 func (p *planner) Update(n *parser.Update, desiredTypes []parser.Datum, autoCommit bool) (planNode, error) {
 	tracing.AnnotateTrace()
 
+	defer func(ctes *cteScope) { p.ctes = ctes }(p.ctes)
+	if err := p.pushWith(n.With); err != nil {
+		return nil, err
+	}
+
 	tn, err := p.getAliasedTableName(n.Table)
 	if err != nil {
 		return nil, err
@@ -157,14 +178,26 @@ func (p *planner) Update(n *parser.Update, desiredTypes []parser.Datum, autoComm
 	if err != nil {
 		return nil, err
 	}
+	for _, col := range updateCols {
+		if col.IsComputed() {
+			return nil, fmt.Errorf("cannot write directly to computed column %q", col.Name)
+		}
+	}
 
 	defaultExprs, err := makeDefaultExprs(updateCols, &p.parser, &p.evalCtx)
 	if err != nil {
 		return nil, err
 	}
 
+	// The computed columns are rewritten along with the assigned columns. Their
+	// values are computed in Next() and don't have select targets.
+	numSetCols := len(updateCols)
+	computedCols := en.tableDesc.WritableComputedColumns()
+	hasComputed := len(computedCols) > 0
+	updateCols = append(updateCols, computedCols...)
+
 	var requestedCols []sqlbase.ColumnDescriptor
-	if len(n.Returning) > 0 || len(en.tableDesc.Checks) > 0 {
+	if len(n.Returning) > 0 || len(en.tableDesc.Checks) > 0 || hasComputed {
 		// TODO(dan): This could be made tighter, just the rows needed for RETURNING
 		// exprs.
 		requestedCols = en.tableDesc.Columns
@@ -220,7 +253,7 @@ func (p *planner) Update(n *parser.Update, desiredTypes []parser.Datum, autoComm
 		Exprs: targets,
 		From:  &parser.From{Tables: []parser.TableExpr{n.Table}},
 		Where: n.Where,
-	}, nil, nil, desiredTypesFromSelect, publicAndNonPublicColumns)
+	}, nil, nil, parser.LockNone, desiredTypesFromSelect, publicAndNonPublicColumns)
 	if err != nil {
 		return nil, err
 	}
@@ -258,6 +291,10 @@ func (p *planner) Update(n *parser.Update, desiredTypes []parser.Datum, autoComm
 		updateCols:    ru.updateCols,
 		updateColsIdx: updateColsIdx,
 		tw:            tw,
+		numSetCols:    numSetCols,
+	}
+	if err := un.computeHelper.init(p, tn, en.tableDesc); err != nil {
+		return nil, err
 	}
 	if err := un.checkHelper.init(p, tn, en.tableDesc); err != nil {
 		return nil, err
@@ -289,7 +326,9 @@ func (u *updateNode) Next() (bool, error) {
 	if !next {
 		if err == nil {
 			// We're done. Finish the batch.
-			err = u.tw.finalize(u.p.ctx())
+			if err = u.tw.finalize(u.p.ctx()); err == nil {
+				u.notifyMutation(u.run.rowsWritten)
+			}
 		}
 		return false, err
 	}
@@ -307,6 +346,18 @@ func (u *updateNode) Next() (bool, error) {
 	updateValues := oldValues[len(u.tw.ru.fetchCols):]
 	oldValues = oldValues[:len(u.tw.ru.fetchCols)]
 
+	if len(u.updateCols) > u.numSetCols {
+		// Make room for the computed columns, which follow the assigned ones.
+		setValues := updateValues
+		updateValues = make(parser.DTuple, len(u.updateCols))
+		copy(updateValues, setValues)
+		u.computeHelper.loadRow(u.tw.ru.fetchColIDtoRowIndex, oldValues, false)
+		u.computeHelper.loadRow(u.updateColsIdx, updateValues, true)
+		if err := u.computeHelper.compute(&u.p.evalCtx, u.updateColsIdx, updateValues); err != nil {
+			return false, err
+		}
+	}
+
 	u.checkHelper.loadRow(u.tw.ru.fetchColIDtoRowIndex, oldValues, false)
 	u.checkHelper.loadRow(u.updateColsIdx, updateValues, true)
 	if err := u.checkHelper.check(&u.p.evalCtx); err != nil {
@@ -332,6 +383,7 @@ func (u *updateNode) Next() (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	u.run.rowsWritten++
 
 	resultRow, err := u.rh.cookResultRow(newValues)
 	if err != nil {
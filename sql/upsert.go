@@ -190,8 +190,16 @@ func upsertExprsAndIndex(
 		return updateExprs, conflictIndex, nil
 	}
 
+	if onConflict.DoNothing && len(onConflict.Columns) == 0 {
+		// ON CONFLICT DO NOTHING without a conflict target does nothing on a
+		// conflict in any unique index. The primary index is used as the
+		// conflict index and tableUpserter checks the other unique indexes.
+		return nil, &tableDesc.PrimaryIndex, nil
+	}
+
 	indexMatch := func(index sqlbase.IndexDescriptor) bool {
-		if !index.Unique {
+		// Partial indexes don't enforce uniqueness over all rows.
+		if !index.Unique || index.IsPartial() {
 			return false
 		}
 		if len(index.ColumnNames) != len(onConflict.Columns) {
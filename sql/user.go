@@ -0,0 +1,114 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/pkg/errors"
+)
+
+// CreateUser creates a user, which can log in with the given password, if
+// any, in addition to client certificates. Only the bcrypt hash of the
+// password is stored.
+// Privileges: INSERT on system.users.
+func (p *planner) CreateUser(n *parser.CreateUser) (planNode, error) {
+	if err := p.checkRoleManagement(); err != nil {
+		return nil, err
+	}
+	name := string(n.Name)
+	if name == security.RootUser {
+		return nil, errors.Errorf("a user or role named %s already exists", name)
+	}
+	var hashedPassword []byte
+	if n.Password != nil {
+		d, err := n.Password.ResolveAsType(&p.semaCtx, parser.TypeString)
+		if err != nil {
+			return nil, err
+		}
+		password := string(*d.(*parser.DString))
+		if password == "" {
+			return nil, errors.Errorf("empty passwords are not permitted")
+		}
+		if hashedPassword, err = security.HashPassword([]byte(password)); err != nil {
+			return nil, err
+		}
+	}
+
+	ip := makeInternalPlanner(p.txn, security.RootUser)
+	ip.leaseMgr = p.leaseMgr
+	defer ip.releaseLeases()
+	exists, isRole, err := lookupUser(ip, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if n.IfNotExists && !isRole {
+			return &emptyNode{}, nil
+		}
+		return nil, errors.Errorf("a user or role named %s already exists", name)
+	}
+	if hashedPassword == nil {
+		_, err = ip.exec(`INSERT INTO system.users (username) VALUES ($1)`, name)
+	} else {
+		_, err = ip.exec(`INSERT INTO system.users (username, hashedPassword) VALUES ($1, $2)`,
+			name, hashedPassword)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// GetUserHashedPassword returns whether a user with the given name exists,
+// along with the hash of its password, if it has one. Roles are reported as
+// not existing, as they cannot log in.
+func (e *Executor) GetUserHashedPassword(username string) (bool, []byte, error) {
+	var exists bool
+	var hashedPassword []byte
+	err := e.ctx.DB.Txn(func(txn *client.Txn) error {
+		p := makeInternalPlanner(txn, security.RootUser)
+		p.leaseMgr = e.ctx.LeaseManager
+		defer p.releaseLeases()
+		row, err := p.queryRow(
+			`SELECT hashedPassword FROM system.users WHERE username = $1 AND NOT isRole`, username)
+		exists = row != nil
+		if err != nil || !exists {
+			return err
+		}
+		hashedPassword = nil
+		if row[0] != parser.DNull {
+			hashedPassword = []byte(*row[0].(*parser.DBytes))
+		}
+		return nil
+	})
+	return exists, hashedPassword, err
+}
+
+// IsRole returns whether the given name is that of a role rather than a
+// user. Roles cannot log in.
+func (e *Executor) IsRole(username string) (bool, error) {
+	var isRole bool
+	err := e.ctx.DB.Txn(func(txn *client.Txn) error {
+		p := makeInternalPlanner(txn, security.RootUser)
+		p.leaseMgr = e.ctx.LeaseManager
+		defer p.releaseLeases()
+		var err error
+		_, isRole, err = lookupUser(p, username)
+		return err
+	})
+	return isRole, err
+}
@@ -73,6 +73,9 @@ func (p *planner) ValuesClause(n *parser.ValuesClause, desiredTypes []parser.Dat
 			if p.parser.AggregateInExpr(expr) {
 				return nil, fmt.Errorf("aggregate functions are not allowed in VALUES")
 			}
+			if p.parser.WindowFuncInExpr(expr) {
+				return nil, fmt.Errorf("window functions are not allowed in VALUES")
+			}
 
 			desired := parser.NoTypePreference
 			if len(desiredTypes) > i {
@@ -0,0 +1,272 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"strings"
+
+	"github.com/cockroachdb/cockroach/sql/colexec"
+	"github.com/cockroachdb/cockroach/sql/parser"
+)
+
+// vectorizedNode runs the scan, filter and render expressions of a
+// selectNode reading a table, as well as the aggregation of its groupNode if
+// any, using the vectorized engine: the rows are processed in batches stored
+// column by column, instead of one at a time.
+type vectorizedNode struct {
+	// plan is the row-at-a-time plan replaced by the node. It provides the
+	// description of the results, and is run instead in EXPLAIN (DEBUG)
+	// mode.
+	plan  planNode
+	scan  *scanNode
+	group *groupNode
+
+	// colTypes holds the types of the columns of the scan. The columns which
+	// are not needed are ColUnhandled.
+	colTypes []colexec.ColType
+	// render holds the render expressions of the selectNode, whose variables
+	// are IndexedVars referring to the columns of the scan.
+	render []parser.TypedExpr
+	// groupCols and aggs describe, when grouping, the aggregation of the
+	// rendered rows producing the results of the groupNode.
+	groupCols []int
+	aggs      []colexec.AggSpec
+	// operators describes the operators run on the rows of the scan.
+	operators string
+
+	rows  *colexec.Materializer
+	row   parser.DTuple
+	debug bool
+}
+
+var _ planNode = &vectorizedNode{}
+
+// maybeVectorize returns the plan connecting the source of a selectTopNode
+// and its groupNode, if any, replaced by a vectorizedNode if the session
+// enables it and the vectorized engine supports the query. Otherwise the plan
+// is returned as is.
+func maybeVectorize(plan planNode, source planNode, group *groupNode) planNode {
+	sel, ok := source.(*selectNode)
+	if !ok {
+		return plan
+	}
+	p := sel.planner
+	if p.session == nil || !p.session.Vectorize || testDistSQL != 0 {
+		return plan
+	}
+	scan, ok := sel.source.plan.(*scanNode)
+	if !ok || scan.lockRows {
+		return plan
+	}
+
+	n := &vectorizedNode{
+		plan:     plan,
+		scan:     scan,
+		colTypes: make([]colexec.ColType, len(scan.resultColumns)),
+		render:   make([]parser.TypedExpr, len(sel.render)),
+	}
+	for i, col := range scan.resultColumns {
+		if scan.valNeededForCol[i] {
+			n.colTypes[i] = colexec.ColTypeFromDatum(col.Typ)
+		}
+	}
+
+	convFunc := func(expr parser.VariableExpr) (bool, parser.VariableExpr) {
+		qval, ok := expr.(*qvalue)
+		if !ok || qval.colRef.source != sel.source.info {
+			return false, nil
+		}
+		return true, scan.filterVars.IndexedVar(qval.colRef.colIdx)
+	}
+	for i, r := range sel.render {
+		if r == starDatumInstance {
+			// The argument of COUNT(*), which is ignored.
+			n.render[i] = parser.DBoolTrue
+			continue
+		}
+		if !exprCheckVars(r, convFunc) {
+			return plan
+		}
+		n.render[i] = exprConvertVars(r, convFunc)
+	}
+
+	if group != nil {
+		if group.having != nil || group.needOnlyOneRow {
+			return plan
+		}
+		for i := len(group.funcs); i < len(sel.render); i++ {
+			n.groupCols = append(n.groupCols, i)
+		}
+		for _, r := range group.render {
+			f, ok := r.(*aggregateFuncHolder)
+			if !ok {
+				return plan
+			}
+			aggFunc, ok := vectorizedAggFunc(f)
+			if !ok {
+				return plan
+			}
+			spec := colexec.AggSpec{Func: aggFunc, Col: -1}
+			for j, g := range group.funcs {
+				if g == f {
+					spec.Col = j
+				}
+			}
+			if spec.Col == -1 {
+				return plan
+			}
+			n.aggs = append(n.aggs, spec)
+		}
+		n.group = group
+	}
+
+	if err := n.build(colexec.BatchSize); err != nil {
+		// The engine doesn't support some of the expressions.
+		return plan
+	}
+	return n
+}
+
+// vectorizedAggFunc returns the aggregation of the vectorized engine
+// computing the same results as the aggregate function.
+func vectorizedAggFunc(f *aggregateFuncHolder) (colexec.AggFunc, bool) {
+	fn, ok := f.expr.(*parser.FuncExpr)
+	if !ok {
+		// The value of a grouping column.
+		return colexec.AggAny, true
+	}
+	if f.seen != nil {
+		// DISTINCT aggregations are not supported.
+		return 0, false
+	}
+	name, err := fn.Name.Normalize()
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(name.Function()) {
+	case "count":
+		if f.arg == starDatumInstance {
+			return colexec.AggCountRows, true
+		}
+		return colexec.AggCount, true
+	case "sum":
+		return colexec.AggSum, true
+	case "min":
+		return colexec.AggMin, true
+	case "max":
+		return colexec.AggMax, true
+	default:
+		return 0, false
+	}
+}
+
+// build sets up the operators reading the rows of the scan in batches of the
+// given size.
+func (n *vectorizedNode) build(batchSize int) error {
+	var op colexec.Operator = colexec.NewColumnarizer(n.colTypes, batchSize, n.scan.nextUnfilteredRow)
+	var operators []string
+	if n.scan.filter != nil {
+		var err error
+		if op, err = colexec.NewFilter(op, n.colTypes, n.scan.filter); err != nil {
+			return err
+		}
+		operators = append(operators, "filter")
+	}
+	op, types, err := colexec.NewProjection(op, n.colTypes, n.render)
+	if err != nil {
+		return err
+	}
+	operators = append(operators, "render")
+	numCols := len(n.render)
+	if n.group != nil {
+		op, _, err = colexec.NewHashAggregator(op, types, n.groupCols, n.aggs, n.group.memAcc.Grow)
+		if err != nil {
+			return err
+		}
+		operators = append(operators, "hash group")
+		numCols = len(n.aggs)
+	}
+	n.operators = strings.Join(operators, ", ")
+	n.rows = colexec.NewMaterializer(op, numCols)
+	return nil
+}
+
+func (n *vectorizedNode) Columns() []ResultColumn { return n.plan.Columns() }
+func (n *vectorizedNode) Ordering() orderingInfo  { return n.plan.Ordering() }
+
+func (n *vectorizedNode) Values() parser.DTuple {
+	if n.debug {
+		return n.plan.Values()
+	}
+	return n.row
+}
+
+func (n *vectorizedNode) MarkDebug(mode explainMode) {
+	n.debug = true
+	n.plan.MarkDebug(mode)
+}
+
+func (n *vectorizedNode) DebugValues() debugValues {
+	return n.plan.DebugValues()
+}
+
+func (n *vectorizedNode) expandPlan() error {
+	// The node is created once the plan it replaces is expanded.
+	return nil
+}
+
+func (n *vectorizedNode) Start() error {
+	if n.debug {
+		return n.plan.Start()
+	}
+	if err := n.scan.Start(); err != nil {
+		return err
+	}
+	if n.group == nil && n.scan.limitHint > 0 {
+		// Avoid reading more rows than needed to fill the first batch.
+		batchSize := n.scan.limitHint
+		if n.scan.limitSoft {
+			batchSize *= 2
+		}
+		if batchSize < colexec.BatchSize {
+			return n.build(int(batchSize))
+		}
+	}
+	return nil
+}
+
+func (n *vectorizedNode) Next() (bool, error) {
+	if n.debug {
+		return n.plan.Next()
+	}
+	row, err := n.rows.Next()
+	if err != nil || row == nil {
+		return false, err
+	}
+	n.row = row
+	return true, nil
+}
+
+func (n *vectorizedNode) ExplainPlan(_ bool) (name, description string, children []planNode) {
+	return "vectorized", n.operators, []planNode{n.scan}
+}
+
+func (n *vectorizedNode) ExplainTypes(regTypes func(string, string)) {
+	n.plan.ExplainTypes(regTypes)
+}
+
+func (n *vectorizedNode) SetLimitHint(numRows int64, soft bool) {
+	n.plan.SetLimitHint(numRows, soft)
+}
@@ -52,7 +52,9 @@ type virtualSchemaTable struct {
 // When adding a new virtualSchema, define a virtualSchema in a separate file, and
 // add that object to this slice.
 var virtualSchemas = []virtualSchema{
+	crdbInternal,
 	informationSchema,
+	pgCatalog,
 }
 
 //
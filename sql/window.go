@@ -0,0 +1,689 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/sql/mon"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/encoding"
+)
+
+// window constructs a windowNode according to window function applications. This may
+// adjust the render targets in the selectNode as necessary.
+func (p *planner) window(n *parser.SelectClause, s *selectNode) (*windowNode, error) {
+	// Determine if a window function is being applied. We use the selectNode's
+	// renders to determine this because window functions may be added to the
+	// selectNode by an ORDER BY clause, e.g.:
+	//   SELECT a FROM t ORDER BY rank() OVER (ORDER BY a)
+	if !p.parser.WindowFuncInExprs(s.render) {
+		return nil, nil
+	}
+
+	if p.parser.IsAggregate(n) {
+		return nil, errors.Errorf("window functions are not supported in queries with aggregation")
+	}
+
+	namedWindows, err := constructNamedWindows(n.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	window := &windowNode{
+		planner: p,
+		// The windowNode renders the columns currently rendered by the
+		// selectNode; anything added below is only used internally.
+		values:       valuesNode{columns: s.columns},
+		windowRender: make([]parser.TypedExpr, len(s.render)),
+		rowsMemAcc:   p.makeMemoryAccount(),
+	}
+
+	visitor := extractWindowFuncsVisitor{
+		n:            window,
+		s:            s,
+		namedWindows: namedWindows,
+	}
+
+	// Loop over the render expressions and extract any window functions. The
+	// renders which contain window functions are moved into the windowNode
+	// and replaced by NULL placeholders in the selectNode, so that the
+	// indexes of all renders remain the same. The arguments of the window
+	// functions and the expressions of their window definitions are added as
+	// renders to the selectNode instead.
+	for i := range window.windowRender {
+		if !p.parser.WindowFuncInExpr(s.render[i]) {
+			continue
+		}
+		typedExpr, err := visitor.extract(s.render[i])
+		if err != nil {
+			return nil, err
+		}
+		window.windowRender[i] = typedExpr
+		s.render[i] = parser.DNull
+	}
+
+	// Columns referenced by the windowed renders outside of window functions
+	// are now provided by the selectNode's renders.
+	window.ivarHelper = parser.MakeIndexedVarHelper(window, len(s.render))
+	for i, r := range window.windowRender {
+		if r == nil {
+			continue
+		}
+		expr, _ := parser.WalkExpr(window.replaceQValuesVisitor(s), r)
+		window.windowRender[i] = expr.(parser.TypedExpr)
+	}
+	window.wrappedColumns = s.columns
+
+	return window, nil
+}
+
+// constructNamedWindows checks the definitions of a WINDOW clause and
+// resolves them, so that window functions can refer to them by name.
+func constructNamedWindows(defs parser.Window) (map[parser.Name]*parser.WindowDef, error) {
+	namedWindows := make(map[parser.Name]*parser.WindowDef, len(defs))
+	for _, def := range defs {
+		name := parser.Name(sqlbase.NormalizeName(def.Name))
+		if _, ok := namedWindows[name]; ok {
+			return nil, errors.Errorf("window %q is already defined", name)
+		}
+		// The name of a window definition in the WINDOW clause is the name
+		// being defined, not a reference to another window.
+		spec := *def
+		spec.Name = ""
+		resolved, err := constructWindowDef(spec, namedWindows)
+		if err != nil {
+			return nil, err
+		}
+		namedWindows[name] = resolved
+	}
+	return namedWindows, nil
+}
+
+// constructWindowDef constructs a WindowDef using the provided WindowDef value and the
+// set of named window specifications present in the query's WINDOW clause.
+func constructWindowDef(
+	def parser.WindowDef, namedWindows map[parser.Name]*parser.WindowDef,
+) (*parser.WindowDef, error) {
+	modifyRef := false
+	var refName parser.Name
+	switch {
+	case def.RefName != "":
+		// SELECT rank() OVER (w) FROM t WINDOW w as (...)
+		// We copy the referenced window specification, and modify it if necessary.
+		refName = def.RefName
+		modifyRef = true
+	case def.Name != "":
+		// SELECT rank() OVER w FROM t WINDOW w as (...)
+		// We use the referenced window specification directly, without modification.
+		refName = def.Name
+	}
+	if refName == "" {
+		return &def, nil
+	}
+
+	normalized := parser.Name(sqlbase.NormalizeName(refName))
+	referencedSpec, ok := namedWindows[normalized]
+	if !ok {
+		return nil, errors.Errorf("window %q does not exist", normalized)
+	}
+	if !modifyRef {
+		return referencedSpec, nil
+	}
+
+	// Check for invalid modifications to the referenced window specification.
+	if len(def.Partitions) > 0 {
+		return nil, errors.Errorf("cannot override PARTITION BY clause of window %q", normalized)
+	}
+	if len(referencedSpec.OrderBy) > 0 && len(def.OrderBy) > 0 {
+		return nil, errors.Errorf("cannot copy window %q because it has an ORDER BY clause", normalized)
+	}
+
+	spec := *referencedSpec
+	spec.Name = def.Name
+	if len(def.OrderBy) > 0 {
+		spec.OrderBy = def.OrderBy
+	}
+	return &spec, nil
+}
+
+// A windowNode implements the planNode interface and handles windowing logic.
+// It "wraps" a planNode which is used to retrieve the un-windowed results.
+type windowNode struct {
+	planner *planner
+
+	// The "wrapped" node (which returns un-windowed results).
+	plan           planNode
+	wrappedColumns []ResultColumn
+
+	// A sparse array holding renders specific to this windowNode. This will
+	// contain nil entries for renders that do not contain window functions,
+	// and which therefore can be propagated directly from the "wrapped" node.
+	windowRender []parser.TypedExpr
+
+	// The window functions handled by this windowNode. computeWindows will
+	// populate a column in windowValues for each windowFuncHolder, in order.
+	funcs []*windowFuncHolder
+
+	// ivarHelper provides the IndexedVars through which windowRender refers
+	// to the values of the "wrapped" node.
+	ivarHelper parser.IndexedVarHelper
+
+	// The rows of the "wrapped" node, buffered until all rows are available.
+	wrappedRows []parser.DTuple
+	// The results of the window functions, indexed by function and then by
+	// row.
+	windowValues [][]parser.Datum
+	// The wrapped row for which the windowRender is currently being
+	// evaluated.
+	curRowIdx int
+
+	values    valuesNode
+	populated bool
+
+	// rowsMemAcc accounts for the buffered rows of the "wrapped" node and
+	// the rendered rows.
+	rowsMemAcc mon.MemoryAccount
+
+	explain explainMode
+}
+
+func (n *windowNode) Columns() []ResultColumn {
+	return n.values.Columns()
+}
+
+func (n *windowNode) Ordering() orderingInfo {
+	// The rows are returned in the order of the wrapped node, but since the
+	// windowed renders are not part of that ordering we do not report it.
+	return orderingInfo{}
+}
+
+func (n *windowNode) Values() parser.DTuple {
+	return n.values.Values()
+}
+
+func (n *windowNode) MarkDebug(mode explainMode) {
+	if mode != explainDebug {
+		panic(fmt.Sprintf("unknown debug mode %d", mode))
+	}
+	n.explain = mode
+	n.plan.MarkDebug(mode)
+}
+
+func (n *windowNode) DebugValues() debugValues {
+	if n.populated {
+		return n.values.DebugValues()
+	}
+
+	// We are emitting a "buffered" row.
+	vals := n.plan.DebugValues()
+	if vals.output == debugValueRow {
+		vals.output = debugValueBuffered
+	}
+	return vals
+}
+
+func (n *windowNode) expandPlan() error {
+	// We do not need to recurse into the child node here; selectTopNode
+	// does this for us.
+
+	for _, e := range n.windowRender {
+		if err := n.planner.expandSubqueryPlans(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *windowNode) Start() error {
+	if err := n.plan.Start(); err != nil {
+		return err
+	}
+
+	for _, e := range n.windowRender {
+		if err := n.planner.startSubqueryPlans(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *windowNode) Next() (bool, error) {
+	for !n.populated {
+		next, err := n.plan.Next()
+		if err != nil {
+			return false, err
+		}
+		if !next {
+			n.populated = true
+			if err := n.computeWindows(); err != nil {
+				return false, err
+			}
+			if err := n.populateValues(); err != nil {
+				return false, err
+			}
+			break
+		}
+		if n.explain == explainDebug && n.plan.DebugValues().output != debugValueRow {
+			// Pass through non-row debug values.
+			return true, nil
+		}
+
+		// The wrapped node may reuse its row across calls to Next, so we
+		// need to copy the values.
+		values := n.plan.Values()
+		row := make(parser.DTuple, len(values))
+		copy(row, values)
+		if err := n.rowsMemAcc.Grow(int64(row.Size())); err != nil {
+			return false, err
+		}
+		n.wrappedRows = append(n.wrappedRows, row)
+
+		if n.explain == explainDebug {
+			// Emit a "buffered" row.
+			return true, nil
+		}
+	}
+
+	return n.values.Next()
+}
+
+// computeWindows computes the results of all window functions, for each of
+// the rows returned by the "wrapped" node.
+func (n *windowNode) computeWindows() error {
+	n.windowValues = make([][]parser.Datum, len(n.funcs))
+	for i := range n.windowValues {
+		n.windowValues[i] = make([]parser.Datum, len(n.wrappedRows))
+	}
+
+	var scratch []byte
+	for windowIdx, windowFn := range n.funcs {
+		// Group the rows into partitions, preserving the order in which the
+		// partitions were first seen.
+		var partitionKeys []string
+		partitions := make(map[string][]parser.IndexedRow)
+		for rowIdx, row := range n.wrappedRows {
+			scratch = scratch[:0]
+			for _, idx := range windowFn.partitionIdxs {
+				encoded, err := sqlbase.EncodeDatum(scratch, row[idx])
+				if err != nil {
+					return err
+				}
+				scratch = encoded
+			}
+			key := string(scratch)
+			if _, ok := partitions[key]; !ok {
+				partitionKeys = append(partitionKeys, key)
+			}
+			partitions[key] = append(partitions[key], parser.IndexedRow{
+				Idx: rowIdx,
+				Row: row[windowFn.argIdxStart : windowFn.argIdxStart+windowFn.argCount],
+			})
+		}
+
+		for _, key := range partitionKeys {
+			partition := partitions[key]
+			sorter := partitionSorter{
+				rows:     partition,
+				wrapped:  n.wrappedRows,
+				ordering: windowFn.columnOrdering,
+			}
+			if len(sorter.ordering) > 0 {
+				sort.Sort(sorter)
+			}
+
+			builtin := windowFn.expr.GetWindowConstructor()()
+			frame := parser.WindowFrame{Rows: partition}
+			for frame.RowIdx = range partition {
+				if frame.RowIdx == frame.FirstPeerIdx+frame.PeerRowCount {
+					// This row starts a new peer group.
+					frame.FirstPeerIdx = frame.RowIdx
+					frame.PeerRowCount = 1
+					for frame.FirstPeerIdx+frame.PeerRowCount < len(partition) &&
+						sorter.compare(frame.FirstPeerIdx, frame.FirstPeerIdx+frame.PeerRowCount) == 0 {
+						frame.PeerRowCount++
+					}
+				}
+				res, err := builtin.Compute(frame)
+				if err != nil {
+					return err
+				}
+				n.windowValues[windowIdx][partition[frame.RowIdx].Idx] = res
+			}
+		}
+	}
+	return nil
+}
+
+// populateValues renders the results of the windowNode, in the order of the
+// rows returned by the "wrapped" node.
+func (n *windowNode) populateValues() error {
+	numCols := len(n.values.columns)
+	n.values.rows = make([]parser.DTuple, 0, len(n.wrappedRows))
+	var wrappedRowsSize int64
+	for i, wrappedRow := range n.wrappedRows {
+		n.curRowIdx = i
+		row := make(parser.DTuple, numCols)
+		for j := range row {
+			if r := n.windowRender[j]; r != nil {
+				res, err := r.Eval(&n.planner.evalCtx)
+				if err != nil {
+					return err
+				}
+				row[j] = res
+			} else {
+				row[j] = wrappedRow[j]
+			}
+		}
+		if err := n.rowsMemAcc.Grow(int64(row.Size())); err != nil {
+			return err
+		}
+		n.values.rows = append(n.values.rows, row)
+		wrappedRowsSize += int64(wrappedRow.Size())
+	}
+	// Release the buffered rows.
+	n.wrappedRows = nil
+	n.windowValues = nil
+	n.rowsMemAcc.Shrink(wrappedRowsSize)
+	return nil
+}
+
+func (n *windowNode) ExplainPlan(_ bool) (name, description string, children []planNode) {
+	name = "window"
+	var buf bytes.Buffer
+	for i, f := range n.funcs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		f.Format(&buf, parser.FmtSimple)
+	}
+
+	subplans := []planNode{n.plan}
+	for _, e := range n.windowRender {
+		subplans = n.planner.collectSubqueryPlans(e, subplans)
+	}
+	return name, buf.String(), subplans
+}
+
+func (n *windowNode) ExplainTypes(regTypes func(string, string)) {
+	cols := n.Columns()
+	for i, rexpr := range n.windowRender {
+		if rexpr != nil {
+			regTypes(fmt.Sprintf("render %s", cols[i].Name), parser.AsStringWithFlags(rexpr, parser.FmtShowTypes))
+		}
+	}
+}
+
+func (*windowNode) SetLimitHint(_ int64, _ bool) {}
+
+// wrap the supplied planNode with the windowNode if windowing is required.
+func (n *windowNode) wrap(plan planNode) planNode {
+	if n == nil {
+		return plan
+	}
+	n.plan = plan
+	return n
+}
+
+var _ parser.IndexedVarContainer = &windowNode{}
+
+// IndexedVarEval implements the parser.IndexedVarContainer interface.
+func (n *windowNode) IndexedVarEval(idx int, ctx *parser.EvalContext) (parser.Datum, error) {
+	return n.wrappedRows[n.curRowIdx][idx], nil
+}
+
+// IndexedVarReturnType implements the parser.IndexedVarContainer interface.
+func (n *windowNode) IndexedVarReturnType(idx int) parser.Datum {
+	return n.wrappedColumns[idx].Typ
+}
+
+// IndexedVarString implements the parser.IndexedVarContainer interface.
+func (n *windowNode) IndexedVarString(idx int) string {
+	return n.wrappedColumns[idx].Name
+}
+
+// replaceQValuesVisitor returns a visitor which replaces the qvalues in
+// windowed renders by IndexedVars referring to the corresponding renders of
+// the selectNode.
+func (n *windowNode) replaceQValuesVisitor(s *selectNode) parser.Visitor {
+	return &qvalueToIndexedVarVisitor{n: n, s: s}
+}
+
+type qvalueToIndexedVarVisitor struct {
+	n *windowNode
+	s *selectNode
+}
+
+var _ parser.Visitor = &qvalueToIndexedVarVisitor{}
+
+func (v *qvalueToIndexedVarVisitor) VisitPre(expr parser.Expr) (recurse bool, newExpr parser.Expr) {
+	switch t := expr.(type) {
+	case *qvalue:
+		idx, ok := v.s.findRenderIndexForCol(t.colRef)
+		if !ok {
+			panic(fmt.Sprintf("no render for column %s", t))
+		}
+		return false, v.n.ivarHelper.IndexedVar(idx)
+	case *parser.Subquery:
+		return false, expr
+	}
+	return true, expr
+}
+
+func (*qvalueToIndexedVarVisitor) VisitPost(expr parser.Expr) parser.Expr { return expr }
+
+type extractWindowFuncsVisitor struct {
+	n            *windowNode
+	s            *selectNode
+	namedWindows map[parser.Name]*parser.WindowDef
+	err          error
+}
+
+var _ parser.Visitor = &extractWindowFuncsVisitor{}
+
+func (v *extractWindowFuncsVisitor) VisitPre(expr parser.Expr) (recurse bool, newExpr parser.Expr) {
+	if v.err != nil {
+		return false, expr
+	}
+
+	switch t := expr.(type) {
+	case *parser.FuncExpr:
+		if !t.IsWindowFunctionApplication() {
+			return true, expr
+		}
+		f, err := v.newWindowFuncHolder(t)
+		if err != nil {
+			v.err = err
+			return false, expr
+		}
+		return false, f
+
+	case *qvalue:
+		// The column will be provided by a render of the selectNode.
+		if _, ok := v.s.findRenderIndexForCol(t.colRef); !ok {
+			v.s.addTypedRender(t, t.colRef.get().Name)
+		}
+		return false, expr
+
+	case *parser.Subquery:
+		return false, expr
+	}
+	return true, expr
+}
+
+func (*extractWindowFuncsVisitor) VisitPost(expr parser.Expr) parser.Expr { return expr }
+
+// newWindowFuncHolder creates a windowFuncHolder for the window function
+// application, adding the renders it needs to the selectNode.
+func (v *extractWindowFuncsVisitor) newWindowFuncHolder(
+	t *parser.FuncExpr,
+) (*windowFuncHolder, error) {
+	p := v.n.planner
+	for _, arg := range t.Exprs {
+		if p.parser.WindowFuncInExpr(arg) {
+			return nil, errors.Errorf("window function calls cannot be nested under %s", t.Name)
+		}
+	}
+
+	def, err := constructWindowDef(*t.WindowDef, v.namedWindows)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &windowFuncHolder{
+		window:      v.n,
+		funcIdx:     len(v.n.funcs),
+		argIdxStart: len(v.s.render),
+		argCount:    len(t.Exprs),
+	}
+
+	// The arguments have already been analyzed along with the render
+	// containing the window function.
+	for _, arg := range t.Exprs {
+		typedArg := arg.(parser.TypedExpr)
+		v.s.addTypedRender(typedArg, typedArg.String())
+	}
+
+	// The expressions of the window definition have not been analyzed yet,
+	// since they are not walked through as part of the window function. The
+	// holder keeps a copy of the window function application referring to
+	// the analyzed expressions instead.
+	typedDef := &parser.WindowDef{}
+	for _, partition := range def.Partitions {
+		if p.parser.WindowFuncInExpr(partition) {
+			return nil, errors.Errorf("window functions are not allowed in window definitions")
+		}
+		if err := v.s.addRender(parser.SelectExpr{Expr: partition}, nil); err != nil {
+			return nil, err
+		}
+		f.partitionIdxs = append(f.partitionIdxs, len(v.s.render)-1)
+		typedDef.Partitions = append(typedDef.Partitions, v.s.render[len(v.s.render)-1])
+	}
+	for _, o := range def.OrderBy {
+		if p.parser.WindowFuncInExpr(o.Expr) {
+			return nil, errors.Errorf("window functions are not allowed in window definitions")
+		}
+		if err := v.s.addRender(parser.SelectExpr{Expr: o.Expr}, nil); err != nil {
+			return nil, err
+		}
+		direction := encoding.Ascending
+		if o.Direction == parser.Descending {
+			direction = encoding.Descending
+		}
+		f.columnOrdering = append(f.columnOrdering, sqlbase.ColumnOrderInfo{
+			ColIdx:    len(v.s.render) - 1,
+			Direction: direction,
+		})
+		typedDef.OrderBy = append(typedDef.OrderBy, &parser.Order{
+			Expr:      v.s.render[len(v.s.render)-1],
+			Direction: o.Direction,
+		})
+	}
+	f.expr = t.CopyNode()
+	f.expr.WindowDef = typedDef
+
+	v.n.funcs = append(v.n.funcs, f)
+	return f, nil
+}
+
+// extract replaces the window function applications in the expression by
+// windowFuncHolders.
+func (v extractWindowFuncsVisitor) extract(typedExpr parser.TypedExpr) (parser.TypedExpr, error) {
+	expr, _ := parser.WalkExpr(&v, typedExpr)
+	if v.err != nil {
+		return nil, v.err
+	}
+	return expr.(parser.TypedExpr), nil
+}
+
+var _ parser.TypedExpr = &windowFuncHolder{}
+var _ parser.VariableExpr = &windowFuncHolder{}
+
+// windowFuncHolder stands in for a window function application in the
+// windowNode's renders, and evaluates to the result of the window function
+// for the row being rendered.
+type windowFuncHolder struct {
+	window  *windowNode
+	funcIdx int
+
+	expr *parser.FuncExpr
+
+	// The arguments of the window function are rendered by the "wrapped"
+	// node at [argIdxStart, argIdxStart+argCount).
+	argIdxStart int
+	argCount    int
+
+	// The renders of the "wrapped" node to partition and order by.
+	partitionIdxs  []int
+	columnOrdering sqlbase.ColumnOrdering
+}
+
+func (*windowFuncHolder) Variable() {}
+
+func (w *windowFuncHolder) Format(buf *bytes.Buffer, f parser.FmtFlags) {
+	w.expr.Format(buf, f)
+}
+func (w *windowFuncHolder) String() string { return parser.AsString(w) }
+
+func (w *windowFuncHolder) Walk(v parser.Visitor) parser.Expr { return w }
+
+func (w *windowFuncHolder) TypeCheck(_ *parser.SemaContext, desired parser.Datum) (parser.TypedExpr, error) {
+	return w, nil
+}
+
+func (w *windowFuncHolder) Eval(ctx *parser.EvalContext) (parser.Datum, error) {
+	return w.window.windowValues[w.funcIdx][w.window.curRowIdx].Eval(ctx)
+}
+
+func (w *windowFuncHolder) ReturnType() parser.Datum {
+	return w.expr.ReturnType()
+}
+
+// partitionSorter sorts the rows of a window partition according to the
+// ordering of the window definition.
+type partitionSorter struct {
+	rows     []parser.IndexedRow
+	wrapped  []parser.DTuple
+	ordering sqlbase.ColumnOrdering
+}
+
+var _ sort.Interface = partitionSorter{}
+
+func (ps partitionSorter) Len() int      { return len(ps.rows) }
+func (ps partitionSorter) Swap(i, j int) { ps.rows[i], ps.rows[j] = ps.rows[j], ps.rows[i] }
+func (ps partitionSorter) Less(i, j int) bool {
+	return ps.compare(i, j) < 0
+}
+
+// compare compares the rows at the given positions of the partition on the
+// ordering columns. Rows comparing equal are peers.
+func (ps partitionSorter) compare(i, j int) int {
+	ra, rb := ps.wrapped[ps.rows[i].Idx], ps.wrapped[ps.rows[j].Idx]
+	for _, o := range ps.ordering {
+		da, db := ra[o.ColIdx], rb[o.ColIdx]
+		if o.Direction == encoding.Descending {
+			da, db = db, da
+		}
+		if c := da.Compare(db); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/pkg/errors"
+)
+
+// cteScope is a common table expression that can be referred to by the
+// table references being planned. The scopes are linked from the innermost
+// CTE outwards, so that the query of a CTE is planned with only the CTEs
+// defined before it in scope.
+type cteScope struct {
+	parent *cteScope
+	name   parser.Name
+	cte    *parser.CTE
+	sel    *parser.Select
+}
+
+// pushWith brings the common table expressions of a WITH clause into scope.
+// Callers are responsible for restoring p.ctes once they are done planning
+// the statement the clause is attached to.
+func (p *planner) pushWith(with *parser.With) error {
+	if with == nil {
+		return nil
+	}
+	names := make(map[parser.Name]struct{}, len(with.CTEList))
+	for _, cte := range with.CTEList {
+		name := parser.Name(sqlbase.NormalizeName(cte.Name.Alias))
+		if _, ok := names[name]; ok {
+			return errors.Errorf("WITH query name %q specified more than once", name)
+		}
+		names[name] = struct{}{}
+
+		sel, ok := cte.Stmt.(*parser.Select)
+		if !ok {
+			return errors.Errorf("%s statements are not supported in WITH clauses",
+				cte.Stmt.StatementTag())
+		}
+		p.ctes = &cteScope{parent: p.ctes, name: name, cte: cte, sel: sel}
+	}
+	return nil
+}
+
+// findCTE looks up the innermost common table expression in scope with the
+// given name.
+func (p *planner) findCTE(tn *parser.TableName) *cteScope {
+	name := parser.Name(sqlbase.NormalizeName(tn.TableName))
+	for s := p.ctes; s != nil; s = s.parent {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// getCTEDataSource plans the query of a common table expression, to be used
+// as a data source by a statement referring to it by name.
+func (p *planner) getCTEDataSource(
+	s *cteScope, scanVisibility scanVisibility,
+) (planDataSource, error) {
+	defer func(ctes *cteScope) { p.ctes = ctes }(p.ctes)
+	p.ctes = s.parent
+	return p.getDataSource(&parser.AliasedTableExpr{
+		Expr: &parser.Subquery{Select: &parser.ParenSelect{Select: s.sel}},
+		As:   s.cte.Name,
+	}, nil, scanVisibility)
+}
@@ -0,0 +1,538 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sql
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/security"
+	"github.com/cockroachdb/cockroach/sql/parser"
+	"github.com/cockroachdb/cockroach/sql/sqlbase"
+	"github.com/cockroachdb/cockroach/util/protoutil"
+)
+
+// defaultZoneName is the name under which the zone config applying to the
+// objects without one of their own is shown.
+const defaultZoneName = ".default"
+
+// zonePath holds the IDs of the objects whose zone configs may apply to a
+// database or table, from the default zone to the object itself, along with
+// their names. For a partition of a table, the path is the one of the table,
+// whose zone config holds the subzone of the partition.
+type zonePath struct {
+	ids   []sqlbase.ID
+	names []string
+
+	// table, index and partition are set for a partition.
+	table     *sqlbase.TableDescriptor
+	index     *sqlbase.IndexDescriptor
+	partition string
+}
+
+// resolveZone returns the zonePath of the database, table or partition
+// specified.
+func (p *planner) resolveZone(s parser.ZoneSpecifier) (zonePath, error) {
+	path := zonePath{
+		ids:   []sqlbase.ID{keys.RootNamespaceID},
+		names: []string{defaultZoneName},
+	}
+	if s.Database != "" {
+		dbDesc, err := p.mustGetDatabaseDesc(string(s.Database))
+		if err != nil {
+			return zonePath{}, err
+		}
+		path.ids = append(path.ids, dbDesc.ID)
+		path.names = append(path.names, dbDesc.Name)
+		return path, nil
+	}
+
+	tn, err := s.Table.NormalizeWithDatabaseName(p.session.Database)
+	if err != nil {
+		return zonePath{}, err
+	}
+	tableDesc, err := p.mustGetTableDesc(tn)
+	if err != nil {
+		return zonePath{}, err
+	}
+	if !tableDesc.IsTable() {
+		return zonePath{}, sqlbase.NewWrongObjectTypeError(tn.String(), "table")
+	}
+	path.ids = append(path.ids, tableDesc.ParentID, tableDesc.ID)
+	path.names = append(path.names,
+		tn.Database(), parser.AsStringWithFlags(tn, parser.FmtQualifyTableNames))
+	if s.Partition != "" {
+		index, err := tableDesc.FindIndexByPartitionName(string(s.Partition))
+		if err != nil {
+			return zonePath{}, err
+		}
+		path.table = tableDesc
+		path.index = index
+		path.partition = string(s.Partition)
+	}
+	return path, nil
+}
+
+// findSubzone returns the index of the subzone of a partition in zone, or -1
+// if the partition doesn't have one.
+func findSubzone(zone *config.ZoneConfig, index *sqlbase.IndexDescriptor, partition string) int {
+	for i, subzone := range zone.Subzones {
+		if sqlbase.IndexID(subzone.IndexID) == index.ID &&
+			sqlbase.ReNormalizeName(subzone.PartitionName) == sqlbase.ReNormalizeName(partition) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setSubzoneSpans recomputes the spans of the subzones of the partitions of a
+// table, sorted by start key.
+func setSubzoneSpans(zone *config.ZoneConfig, tableDesc *sqlbase.TableDescriptor) error {
+	zone.SubzoneSpans = nil
+	for i, subzone := range zone.Subzones {
+		index, err := tableDesc.FindIndexByID(sqlbase.IndexID(subzone.IndexID))
+		if err != nil {
+			return err
+		}
+		spans, err := tableDesc.PartitionSpans(index, subzone.PartitionName)
+		if err != nil {
+			return err
+		}
+		for _, span := range spans {
+			zone.SubzoneSpans = append(zone.SubzoneSpans, config.SubzoneSpan{
+				Key: span.Key, EndKey: span.EndKey, SubzoneIndex: int32(i),
+			})
+		}
+	}
+	sort.Sort(subzoneSpansByKey(zone.SubzoneSpans))
+	return nil
+}
+
+type subzoneSpansByKey []config.SubzoneSpan
+
+func (s subzoneSpansByKey) Len() int           { return len(s) }
+func (s subzoneSpansByKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s subzoneSpansByKey) Less(i, j int) bool { return s[i].Key.Compare(s[j].Key) < 0 }
+
+// getZoneConfig returns the zone config applying to the last object of the
+// path, i.e. the one of the closest object which has one, along with the
+// index of that object in the path.
+func (p *planner) getZoneConfig(path zonePath) (int, config.ZoneConfig, error) {
+	for i := len(path.ids) - 1; i >= 0; i-- {
+		kv, err := p.txn.Get(sqlbase.MakeZoneKey(path.ids[i]))
+		if err != nil {
+			return 0, config.ZoneConfig{}, err
+		}
+		if !kv.Exists() {
+			continue
+		}
+		var zone config.ZoneConfig
+		if err := kv.ValueProto(&zone); err != nil {
+			return 0, config.ZoneConfig{}, err
+		}
+		return i, zone, nil
+	}
+	return 0, config.ZoneConfig{}, errors.New("no default zone config found")
+}
+
+// SetZoneConfig sets the fields of the zone config of a database or table
+// given by ALTER ... CONFIGURE ZONE USING, starting from the zone config
+// currently applying to it, or removes it with ALTER ... CONFIGURE ZONE
+// DISCARD. The zone configs are stored in the system.zones table and reach
+// the allocator through the system config gossip. The zone config of a
+// partition is stored as a subzone in the one of its table.
+// Privileges: root user.
+func (p *planner) SetZoneConfig(n *parser.SetZoneConfig) (planNode, error) {
+	if p.session.User != security.RootUser {
+		return nil, errors.Errorf("only %s is allowed to CONFIGURE ZONE", security.RootUser)
+	}
+	path, err := p.resolveZone(n.ZoneSpecifier)
+	if err != nil {
+		return nil, err
+	}
+	id := path.ids[len(path.ids)-1]
+	if path.partition != "" {
+		return p.setSubzoneConfig(path, n.Settings)
+	}
+
+	if len(n.Settings) == 0 {
+		if _, err := p.exec(`DELETE FROM system.zones WHERE id = $1`, id); err != nil {
+			return nil, err
+		}
+		return &emptyNode{}, nil
+	}
+
+	_, zone, err := p.getZoneConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.applyZoneSettings(&zone, n.Settings); err != nil {
+		return nil, err
+	}
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+	buf, err := protoutil.Marshal(&zone)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.exec(
+		`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`, id, buf,
+	); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// setSubzoneConfig sets or removes the subzone of a partition in the zone
+// config of its table. A table without a zone config of its own gets a copy
+// of the one it inherits, which then stops following changes to it; removing
+// the zone config of the table also removes the subzones of its partitions.
+func (p *planner) setSubzoneConfig(path zonePath, settings parser.ZoneSettings) (planNode, error) {
+	i, zone, err := p.getZoneConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if i != len(path.ids)-1 {
+		zone.Subzones, zone.SubzoneSpans = nil, nil
+	}
+	s := findSubzone(&zone, path.index, path.partition)
+	if len(settings) == 0 {
+		if s < 0 {
+			return &emptyNode{}, nil
+		}
+		zone.Subzones = append(zone.Subzones[:s], zone.Subzones[s+1:]...)
+	} else {
+		if s < 0 {
+			subzoneConfig := zone
+			subzoneConfig.Subzones, subzoneConfig.SubzoneSpans = nil, nil
+			zone.Subzones = append(zone.Subzones, config.Subzone{
+				IndexID:       uint32(path.index.ID),
+				PartitionName: path.partition,
+				Config:        subzoneConfig,
+			})
+			s = len(zone.Subzones) - 1
+		}
+		if err := p.applyZoneSettings(&zone.Subzones[s].Config, settings); err != nil {
+			return nil, err
+		}
+	}
+	if err := setSubzoneSpans(&zone, path.table); err != nil {
+		return nil, err
+	}
+	if err := zone.Validate(); err != nil {
+		return nil, err
+	}
+	buf, err := protoutil.Marshal(&zone)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.exec(
+		`UPSERT INTO system.zones (id, config) VALUES ($1, $2)`, path.ids[len(path.ids)-1], buf,
+	); err != nil {
+		return nil, err
+	}
+	return &emptyNode{}, nil
+}
+
+// applyZoneSettings sets the fields of zone given by settings. The number of
+// replicas and the constraints together determine the attributes of the
+// replicas: constraints given as a single list apply to all of them, while
+// per-replica constraints also set the number of replicas. Changing only the
+// number of replicas keeps the attributes of the existing replicas. The lease
+// preferences are given as a list of constraint lists, in order of
+// preference; '[]' removes them.
+func (p *planner) applyZoneSettings(zone *config.ZoneConfig, settings parser.ZoneSettings) error {
+	numReplicas := len(zone.ReplicaAttrs)
+	numReplicasSet := false
+	var constraints []roachpb.Attributes
+	perReplica := false
+	for _, s := range settings {
+		key := strings.ToLower(string(s.Key))
+		switch key {
+		case "num_replicas":
+			v, err := p.evalZoneIntSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			if v <= 0 {
+				return errors.Errorf("%s: must be positive", key)
+			}
+			numReplicas = int(v)
+			numReplicasSet = true
+		case "constraints":
+			v, err := p.evalZoneStringSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			constraints, perReplica, err = parseConstraints(v)
+			if err != nil {
+				return errors.Wrapf(err, "%s", key)
+			}
+		case "lease_preferences":
+			v, err := p.evalZoneStringSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			preferences, _, err := parseConstraints(v)
+			if err != nil {
+				return errors.Wrapf(err, "%s", key)
+			}
+			if len(preferences) == 1 && len(preferences[0].Attrs) == 0 {
+				preferences = nil
+			}
+			zone.LeasePreferences = preferences
+		case "range_min_bytes":
+			v, err := p.evalZoneIntSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			zone.RangeMinBytes = v
+		case "range_max_bytes":
+			v, err := p.evalZoneIntSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			zone.RangeMaxBytes = v
+		case "gc_ttlseconds":
+			v, err := p.evalZoneIntSetting(key, s.Value)
+			if err != nil {
+				return err
+			}
+			if v < 0 || v > math.MaxInt32 {
+				return errors.Errorf("%s: must be between 0 and %d", key, math.MaxInt32)
+			}
+			zone.GC.TTLSeconds = int32(v)
+		default:
+			return errors.Errorf("unknown zone config field %q", key)
+		}
+	}
+
+	if perReplica {
+		if numReplicasSet && numReplicas != len(constraints) {
+			return errors.Errorf("num_replicas %d doesn't match the %d per-replica constraints",
+				numReplicas, len(constraints))
+		}
+		zone.ReplicaAttrs = constraints
+		return nil
+	}
+	replicaAttrs := make([]roachpb.Attributes, numReplicas)
+	for i := range replicaAttrs {
+		switch {
+		case constraints != nil:
+			replicaAttrs[i] = constraints[0]
+		case i < len(zone.ReplicaAttrs):
+			replicaAttrs[i] = zone.ReplicaAttrs[i]
+		case len(zone.ReplicaAttrs) > 0:
+			replicaAttrs[i] = zone.ReplicaAttrs[len(zone.ReplicaAttrs)-1]
+		}
+	}
+	zone.ReplicaAttrs = replicaAttrs
+	return nil
+}
+
+func (p *planner) evalZoneIntSetting(key string, expr parser.Expr) (int64, error) {
+	typedValue, err := parser.TypeCheck(expr, nil, parser.TypeInt)
+	if err != nil {
+		return 0, err
+	}
+	d, err := typedValue.Eval(&p.evalCtx)
+	if err != nil {
+		return 0, err
+	}
+	if i, ok := d.(*parser.DInt); ok {
+		return int64(*i), nil
+	}
+	return 0, errors.Errorf("%s: cannot use %s of type %s", key, expr, d.Type())
+}
+
+func (p *planner) evalZoneStringSetting(key string, expr parser.Expr) (string, error) {
+	typedValue, err := parser.TypeCheck(expr, nil, parser.TypeString)
+	if err != nil {
+		return "", err
+	}
+	d, err := typedValue.Eval(&p.evalCtx)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := d.(*parser.DString); ok {
+		return string(*s), nil
+	}
+	return "", errors.Errorf("%s: cannot use %s of type %s", key, expr, d.Type())
+}
+
+// parseConstraints parses the constraints of the replicas, formatted either
+// as a single list applying to all of them, e.g. '[+ssd, -region=us-west]',
+// or as one list per replica, e.g. '[[+region=us-east], [+region=us-west]]'.
+// It returns the attributes held by the zone config for each list, and
+// whether the constraints are per-replica.
+func parseConstraints(s string) ([]roachpb.Attributes, bool, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, false, errors.Errorf("invalid constraints %q: expected [+attr, -attr, ...]", s)
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if !strings.HasPrefix(inner, "[") {
+		attrs, err := parseConstraintList(inner)
+		if err != nil {
+			return nil, false, err
+		}
+		return []roachpb.Attributes{attrs}, false, nil
+	}
+
+	var constraints []roachpb.Attributes
+	for inner != "" {
+		end := strings.Index(inner, "]")
+		if !strings.HasPrefix(inner, "[") || end < 0 {
+			return nil, false, errors.Errorf("invalid constraints %q: expected [[+attr, ...], ...]", s)
+		}
+		attrs, err := parseConstraintList(inner[1:end])
+		if err != nil {
+			return nil, false, err
+		}
+		constraints = append(constraints, attrs)
+		inner = strings.TrimSpace(inner[end+1:])
+		if inner != "" {
+			if !strings.HasPrefix(inner, ",") {
+				return nil, false, errors.Errorf("invalid constraints %q: expected [[+attr, ...], ...]", s)
+			}
+			inner = strings.TrimSpace(inner[1:])
+		}
+	}
+	return constraints, true, nil
+}
+
+// parseConstraintList parses a comma-separated list of constraints.
+func parseConstraintList(s string) (roachpb.Attributes, error) {
+	var constraints []config.Constraint
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.HasPrefix(c, "+") && !strings.HasPrefix(c, "-") {
+			return roachpb.Attributes{}, errors.Errorf("invalid constraint %q: expected +attr or -attr", c)
+		}
+		constraint, err := config.ParseConstraint(c)
+		if err != nil {
+			return roachpb.Attributes{}, err
+		}
+		constraints = append(constraints, constraint)
+	}
+	return config.MakeReplicaConstraints(constraints), nil
+}
+
+// formatConstraints formats the constraints of the replicas: a single list if
+// all the replicas have the same ones, or one list per replica otherwise.
+func formatConstraints(replicaAttrs []roachpb.Attributes) string {
+	var buf bytes.Buffer
+	uniform := true
+	for _, a := range replicaAttrs {
+		if a.SortedString() != replicaAttrs[0].SortedString() {
+			uniform = false
+			break
+		}
+	}
+	if uniform {
+		var a roachpb.Attributes
+		if len(replicaAttrs) > 0 {
+			a = replicaAttrs[0]
+		}
+		formatConstraintList(&buf, a)
+		return buf.String()
+	}
+	formatConstraintLists(&buf, replicaAttrs)
+	return buf.String()
+}
+
+// formatLeasePreferences formats the lease preferences as a list of
+// constraint lists.
+func formatLeasePreferences(preferences []roachpb.Attributes) string {
+	var buf bytes.Buffer
+	formatConstraintLists(&buf, preferences)
+	return buf.String()
+}
+
+func formatConstraintLists(buf *bytes.Buffer, lists []roachpb.Attributes) {
+	buf.WriteByte('[')
+	for i, a := range lists {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		formatConstraintList(buf, a)
+	}
+	buf.WriteByte(']')
+}
+
+func formatConstraintList(buf *bytes.Buffer, a roachpb.Attributes) {
+	buf.WriteByte('[')
+	for i, c := range config.ReplicaConstraints(a) {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(c.String())
+	}
+	buf.WriteByte(']')
+}
+
+// ShowZoneConfig shows the zone config applying to a database, table or
+// partition, and the object it is inherited from.
+// Privileges: None.
+func (p *planner) ShowZoneConfig(n *parser.ShowZoneConfig) (planNode, error) {
+	path, err := p.resolveZone(n.ZoneSpecifier)
+	if err != nil {
+		return nil, err
+	}
+	i, zone, err := p.getZoneConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	name := path.names[i]
+	if path.partition != "" && i == len(path.ids)-1 {
+		if s := findSubzone(&zone, path.index, path.partition); s >= 0 {
+			name = fmt.Sprintf("%s.%s", name, path.partition)
+			zone = zone.Subzones[s].Config
+		}
+	}
+	v := &valuesNode{
+		columns: []ResultColumn{
+			{Name: "zone", Typ: parser.TypeString},
+			{Name: "num_replicas", Typ: parser.TypeInt},
+			{Name: "constraints", Typ: parser.TypeString},
+			{Name: "range_min_bytes", Typ: parser.TypeInt},
+			{Name: "range_max_bytes", Typ: parser.TypeInt},
+			{Name: "gc_ttlseconds", Typ: parser.TypeInt},
+			{Name: "lease_preferences", Typ: parser.TypeString},
+		},
+	}
+	v.rows = append(v.rows, []parser.Datum{
+		parser.NewDString(name),
+		parser.NewDInt(parser.DInt(len(zone.ReplicaAttrs))),
+		parser.NewDString(formatConstraints(zone.ReplicaAttrs)),
+		parser.NewDInt(parser.DInt(zone.RangeMinBytes)),
+		parser.NewDInt(parser.DInt(zone.RangeMaxBytes)),
+		parser.NewDInt(parser.DInt(zone.GC.TTLSeconds)),
+		parser.NewDString(formatLeasePreferences(zone.LeasePreferences)),
+	})
+	return v, nil
+}
@@ -26,6 +26,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
 	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/cockroachdb/cockroach/util/syncutil"
@@ -41,8 +42,27 @@ const (
 
 	// priorities for various repair operations.
 	removeDeadReplicaPriority  float64 = 10000
+	removeLearnerPriority      float64 = 2000
 	addMissingReplicaPriority  float64 = 1000
 	removeExtraReplicaPriority float64 = 100
+	fixConstraintsPriority     float64 = 10
+)
+
+// enableLoadBasedLeaseRebalancing controls whether the leases are transferred
+// away from the stores serving many more queries per second than the others.
+var enableLoadBasedLeaseRebalancing = settings.RegisterBoolSetting(
+	"kv.allocator.load_based_lease_rebalancing.enabled",
+	"set to enable rebalancing of range leases based on the load on the stores",
+	true,
+)
+
+// leaseRebalancingThreshold is the fraction above the mean queries per second
+// of the stores from which a store is overloaded, and sheds leases to the
+// stores below the mean.
+var leaseRebalancingThreshold = settings.RegisterFloatSetting(
+	"kv.allocator.lease_rebalancing_threshold",
+	"minimum fraction above the mean queries per second of the stores at which leases are moved away from a store",
+	0.25,
 )
 
 // AllocatorAction enumerates the various replication adjustments that may be
@@ -56,6 +76,7 @@ const (
 	AllocatorRemove
 	AllocatorAdd
 	AllocatorRemoveDead
+	AllocatorRemoveLearner
 )
 
 // allocatorError indicates a retryable error condition which sends replicas
@@ -163,7 +184,14 @@ func (a *Allocator) ComputeAction(zone config.ZoneConfig, desc *roachpb.RangeDes
 		return AllocatorRemoveDead, removeDeadReplicaPriority + float64(quorum-liveReplicas)
 	}
 
-	// TODO(mrtracy): Handle non-homogeneous and mismatched attribute sets.
+	// A learner outside of a replica change was left behind by an interrupted
+	// one, and should be removed before adding a new replica.
+	for _, repl := range desc.Replicas {
+		if repl.IsLearner() {
+			return AllocatorRemoveLearner, removeLearnerPriority
+		}
+	}
+
 	need := len(zone.ReplicaAttrs)
 	have := len(desc.Replicas)
 	if have < need {
@@ -179,16 +207,33 @@ func (a *Allocator) ComputeAction(zone config.ZoneConfig, desc *roachpb.RangeDes
 		// they have a more fragile quorum.
 		return AllocatorRemove, removeExtraReplicaPriority - float64(have%2)
 	}
+	// The range has the right number of replicas, but some of them don't
+	// satisfy the constraints of the zone config. Add a replica which does if
+	// there is a store to hold it; the replica which doesn't is removed next.
+	if unmatched, _ := a.matchReplicaConstraints(zone, desc.Replicas); len(unmatched) > 0 {
+		existingNodes := make(nodeIDSet, len(desc.Replicas))
+		for _, repl := range desc.Replicas {
+			existingNodes[repl.NodeID] = struct{}{}
+		}
+		sl, _, _ := a.storePool.getStoreList(unmatched[0], a.options.Deterministic)
+		for _, store := range sl.stores {
+			if _, ok := existingNodes[store.Node.NodeID]; !ok {
+				return AllocatorAdd, fixConstraintsPriority
+			}
+		}
+	}
 
 	// Nothing to do.
 	return AllocatorNoop, 0
 }
 
-// AllocateTarget returns a suitable store for a new allocation with the
-// required attributes. Nodes already accommodating existing replicas are ruled
-// out as targets. If relaxConstraints is true, then the required attributes
-// will be relaxed as necessary, from least specific to most specific, in order
-// to allocate a target.
+// AllocateTarget returns a suitable store for a new allocation satisfying the
+// constraints held by the required attributes. Nodes already accommodating
+// existing replicas are ruled out as targets, and the stores in the
+// localities most diverse from the existing replicas are preferred. If
+// relaxConstraints is true, then the required attributes will be relaxed as
+// necessary, from least specific to most specific, in order to allocate a
+// target; the prohibited attributes are never relaxed.
 func (a *Allocator) AllocateTarget(
 	required roachpb.Attributes,
 	existing []roachpb.ReplicaDescriptor,
@@ -199,14 +244,25 @@ func (a *Allocator) AllocateTarget(
 		existingNodes[repl.NodeID] = struct{}{}
 	}
 
+	var positive, negative []config.Constraint
+	for _, c := range config.ReplicaConstraints(required) {
+		if c.Type == config.ConstraintProhibited {
+			negative = append(negative, c)
+		} else {
+			positive = append(positive, c)
+		}
+	}
+
 	// Because more redundancy is better than less, if relaxConstraints, the
 	// matching here is lenient, and tries to find a target by relaxing an
 	// attribute constraint, from last attribute to first.
-	for attrs := append([]string(nil), required.Attrs...); ; attrs = attrs[:len(attrs)-1] {
+	for ; ; positive = positive[:len(positive)-1] {
+		constraints := append(append([]config.Constraint(nil), positive...), negative...)
 		sl, aliveStoreCount, throttledStoreCount := a.storePool.getStoreList(
-			roachpb.Attributes{Attrs: attrs},
+			config.MakeReplicaConstraints(constraints),
 			a.options.Deterministic,
 		)
+		sl, _ = a.mostDiverse(sl, existing, existingNodes)
 		if target := a.selectGood(sl, existingNodes); target != nil {
 			return target, nil
 		}
@@ -216,7 +272,7 @@ func (a *Allocator) AllocateTarget(
 		if throttledStoreCount > 0 {
 			return nil, errors.Errorf("%d matching stores are currently throttled", throttledStoreCount)
 		}
-		if len(attrs) == 0 || !relaxConstraints {
+		if len(positive) == 0 || !relaxConstraints {
 			return nil, &allocatorError{
 				required:         required,
 				relaxConstraints: relaxConstraints,
@@ -229,12 +285,10 @@ func (a *Allocator) AllocateTarget(
 // RemoveTarget returns a suitable replica to remove from the provided replica
 // set. It attempts to consider which of the provided replicas would be the best
 // candidate for removal. It also will exclude any replica that belongs to the
-// range lease holder's store ID.
-//
-// TODO(mrtracy): removeTarget eventually needs to accept the attributes from
-// the zone config associated with the provided replicas. This will allow it to
-// make correct decisions in the case of ranges with heterogeneous replica
-// requirements (i.e. multiple data centers).
+// range lease holder's store ID. The replicas in the localities least diverse
+// from the other replicas are preferred. The constraints of the zone config
+// are taken into account by passing the replicas returned by
+// RemoveCandidates.
 func (a Allocator) RemoveTarget(existing []roachpb.ReplicaDescriptor, leaseStoreID roachpb.StoreID) (roachpb.ReplicaDescriptor, error) {
 	if len(existing) == 0 {
 		return roachpb.ReplicaDescriptor{}, errors.Errorf("must supply at least one replica to allocator.RemoveTarget()")
@@ -253,7 +307,7 @@ func (a Allocator) RemoveTarget(existing []roachpb.ReplicaDescriptor, leaseStore
 		sl.add(desc)
 	}
 
-	if bad := a.selectBad(sl); bad != nil {
+	if bad := a.selectBad(a.leastDiverse(sl, existing)); bad != nil {
 		for _, exist := range existing {
 			if exist.StoreID == bad.StoreID {
 				return exist, nil
@@ -276,6 +330,10 @@ func (a Allocator) RemoveTarget(existing []roachpb.ReplicaDescriptor, leaseStore
 // adding a new replica to the range, then removing the most undesirable
 // replica.
 //
+// A range is also rebalanced, regardless of the range counts, if a store in a
+// locality more diverse from the existing replicas than one of them is
+// available.
+//
 // Simply ignoring a rebalance opportunity in the event that the target chosen
 // by AllocateTarget() doesn't fit balancing criteria is perfectly fine, as
 // other stores in the cluster will also be doing their probabilistic best to
@@ -306,15 +364,22 @@ func (a Allocator) RebalanceTarget(
 			break
 		}
 	}
-	if !shouldRebalance {
-		return nil
-	}
 
 	existingNodes := make(nodeIDSet, len(existing))
 	for _, repl := range existing {
 		existingNodes[repl.NodeID] = struct{}{}
 	}
-	return a.improve(sl, existingNodes)
+	sl, score := a.mostDiverse(sl, existing, existingNodes)
+	if shouldRebalance {
+		return a.improve(sl, existingNodes)
+	}
+	// Even if the range counts are balanced, move a replica which shares its
+	// locality with another replica to a more diverse locality if there is
+	// one. RemoveTarget then removes one of the least diverse replicas.
+	if a.improvesDiversity(score, existing, leaseStoreID) {
+		return a.selectGood(sl, existingNodes)
+	}
+	return nil
 }
 
 // ShouldRebalance returns whether the specified store should attempt to
@@ -370,6 +435,301 @@ func (a Allocator) shouldRebalance(
 	return rcb.shouldRebalance(store, sl)
 }
 
+// diversityScore returns the diversity of the locality of the node of a store
+// from the localities of the nodes of the replicas of a range: the lowest
+// diversity score between them, as computed by Locality.DiversityScore. The
+// replica on the store itself and the replicas on stores which aren't known
+// to the store pool are ignored; the score is 1 if there are no others.
+func (a Allocator) diversityScore(
+	store roachpb.StoreDescriptor, existing []roachpb.ReplicaDescriptor,
+) float64 {
+	score := 1.0
+	for _, repl := range existing {
+		if repl.StoreID == store.StoreID {
+			continue
+		}
+		desc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+		if !ok {
+			continue
+		}
+		if s := store.Node.Locality.DiversityScore(desc.Node.Locality); s < score {
+			score = s
+		}
+	}
+	return score
+}
+
+// mostDiverse returns the store list restricted to the stores with the
+// highest diversity score from the existing replicas among the stores which
+// can accept a new replica, which are the stores which don't hold one of the
+// replicas, aren't on an excluded node and aren't too full, and that score,
+// or -1 if there are no such stores. The statistics of the list are kept, so
+// that the range counts of the stores are still compared with the mean of all
+// of them.
+func (a Allocator) mostDiverse(
+	sl StoreList, existing []roachpb.ReplicaDescriptor, excluded nodeIDSet,
+) (StoreList, float64) {
+	existingStores := make(map[roachpb.StoreID]struct{}, len(existing))
+	for _, repl := range existing {
+		existingStores[repl.StoreID] = struct{}{}
+	}
+	scores := make([]float64, len(sl.stores))
+	best := -1.0
+	for i, store := range sl.stores {
+		scores[i] = a.diversityScore(store, existing)
+		if _, ok := existingStores[store.StoreID]; ok {
+			continue
+		}
+		if _, ok := excluded[store.Node.NodeID]; ok {
+			continue
+		}
+		if store.Capacity.FractionUsed() > maxFractionUsedThreshold {
+			continue
+		}
+		if scores[i] > best {
+			best = scores[i]
+		}
+	}
+	var stores []roachpb.StoreDescriptor
+	for i, store := range sl.stores {
+		if scores[i] >= best {
+			stores = append(stores, store)
+		}
+	}
+	sl.stores = stores
+	return sl, best
+}
+
+// leastDiverse returns the store list restricted to the stores with the
+// lowest diversity score from the other existing replicas. The statistics
+// of the list are kept.
+func (a Allocator) leastDiverse(sl StoreList, existing []roachpb.ReplicaDescriptor) StoreList {
+	scores := make([]float64, len(sl.stores))
+	worst := 2.0
+	for i, store := range sl.stores {
+		scores[i] = a.diversityScore(store, existing)
+		if scores[i] < worst {
+			worst = scores[i]
+		}
+	}
+	var stores []roachpb.StoreDescriptor
+	for i, store := range sl.stores {
+		if scores[i] <= worst {
+			stores = append(stores, store)
+		}
+	}
+	sl.stores = stores
+	return sl
+}
+
+// improvesDiversity returns whether moving one of the replicas of a range,
+// other than the lease holder's, to a store with the given diversity score
+// from the replicas, as returned by mostDiverse, would increase the
+// diversity of the localities of the replicas.
+func (a Allocator) improvesDiversity(
+	candidateScore float64, existing []roachpb.ReplicaDescriptor, leaseStoreID roachpb.StoreID,
+) bool {
+	for _, repl := range existing {
+		if repl.StoreID == leaseStoreID {
+			continue
+		}
+		desc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+		if ok && a.diversityScore(desc, existing) < candidateScore {
+			return true
+		}
+	}
+	return false
+}
+
+// matchReplicaConstraints matches the existing replicas of a range to the
+// per-replica constraints of its zone config. It returns the constraints which
+// aren't satisfied by any of the existing replicas, and the replicas which
+// don't satisfy any of the remaining constraints. The matching is greedy, in
+// the order of the zone config. The replicas on stores which aren't known to
+// the store pool yet are assumed to satisfy the constraints.
+func (a Allocator) matchReplicaConstraints(
+	zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
+) ([]roachpb.Attributes, []roachpb.ReplicaDescriptor) {
+	var unmatched []roachpb.Attributes
+	matched := make([]bool, len(existing))
+	for _, required := range zone.ReplicaAttrs {
+		found := false
+		for i, repl := range existing {
+			if matched[i] {
+				continue
+			}
+			desc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+			if !ok || config.ConstraintsMatch(required, *desc.CombinedAttrs()) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, required)
+		}
+	}
+	var extra []roachpb.ReplicaDescriptor
+	for i, repl := range existing {
+		if !matched[i] {
+			extra = append(extra, repl)
+		}
+	}
+	return unmatched, extra
+}
+
+// TargetConstraints returns the attributes required from the store of a new
+// replica of a range: the first per-replica constraints of the zone config
+// which aren't satisfied by an existing replica.
+func (a Allocator) TargetConstraints(
+	zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
+) roachpb.Attributes {
+	if unmatched, _ := a.matchReplicaConstraints(zone, existing); len(unmatched) > 0 {
+		return unmatched[0]
+	}
+	if len(zone.ReplicaAttrs) == 0 {
+		return roachpb.Attributes{}
+	}
+	return zone.ReplicaAttrs[0]
+}
+
+// RemoveCandidates returns the replicas of a range among which RemoveTarget
+// should pick the one to remove: the replicas which don't satisfy the
+// constraints of the zone config if there are any, and all of them otherwise.
+func (a Allocator) RemoveCandidates(
+	zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
+) []roachpb.ReplicaDescriptor {
+	var extra []roachpb.ReplicaDescriptor
+	if required, ok := uniformConstraints(zone); ok {
+		for _, repl := range existing {
+			desc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+			if ok && !config.ConstraintsMatch(required, *desc.CombinedAttrs()) {
+				extra = append(extra, repl)
+			}
+		}
+	} else {
+		_, extra = a.matchReplicaConstraints(zone, existing)
+	}
+	if len(extra) > 0 {
+		return extra
+	}
+	return existing
+}
+
+// PreferredLeaseholders returns the replicas satisfying the first lease
+// preference of the zone config which is satisfied by any of the replicas, or
+// nil if there is no such preference.
+func (a Allocator) PreferredLeaseholders(
+	zone config.ZoneConfig, existing []roachpb.ReplicaDescriptor,
+) []roachpb.ReplicaDescriptor {
+	for _, preference := range zone.LeasePreferences {
+		var preferred []roachpb.ReplicaDescriptor
+		for _, repl := range existing {
+			desc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+			if ok && config.ConstraintsMatch(preference, *desc.CombinedAttrs()) {
+				preferred = append(preferred, repl)
+			}
+		}
+		if len(preferred) > 0 {
+			return preferred
+		}
+	}
+	return nil
+}
+
+// LeaseTarget returns the replica to transfer the lease of the range to in
+// order to honor the lease preferences of the zone config. It returns false
+// if the lease holder already satisfies the preferences, or if no other live
+// replica does.
+func (a Allocator) LeaseTarget(
+	zone config.ZoneConfig, desc *roachpb.RangeDescriptor, leaseStoreID roachpb.StoreID,
+) (roachpb.ReplicaDescriptor, bool) {
+	preferred := a.PreferredLeaseholders(zone, desc.Voters())
+	for _, repl := range preferred {
+		if repl.StoreID == leaseStoreID {
+			return roachpb.ReplicaDescriptor{}, false
+		}
+	}
+	dead := a.storePool.deadReplicas(desc.RangeID, preferred)
+	for _, repl := range preferred {
+		if !containsReplica(dead, repl) {
+			return repl, true
+		}
+	}
+	return roachpb.ReplicaDescriptor{}, false
+}
+
+// LoadBasedLeaseTarget returns the replica to transfer the lease of the range
+// to in order to shed load from the lease holder's store, when it serves many
+// more queries per second than the mean of the stores. The target is the
+// replica on the least loaded store below the mean, among the replicas
+// satisfying the lease preferences of the zone config if any. qps is the rate
+// of the requests served by the range, which moves along with the lease: the
+// lease isn't transferred if that would leave the target more loaded than the
+// lease holder.
+func (a Allocator) LoadBasedLeaseTarget(
+	zone config.ZoneConfig,
+	desc *roachpb.RangeDescriptor,
+	leaseStoreID roachpb.StoreID,
+	qps float64,
+) (roachpb.ReplicaDescriptor, bool) {
+	if !a.options.AllowRebalance || !enableLoadBasedLeaseRebalancing.Get() || qps <= 0 {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	leaseStore, ok := a.storePool.getStoreDescriptor(leaseStoreID)
+	if !ok {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	sl, _, _ := a.storePool.getStoreList(roachpb.Attributes{}, a.options.Deterministic)
+	leaseQPS := leaseStore.Capacity.QueriesPerSecond
+	if leaseQPS <= sl.qps.mean*(1+leaseRebalancingThreshold.Get()) {
+		return roachpb.ReplicaDescriptor{}, false
+	}
+	if log.V(3) {
+		log.Infof(context.TODO(), "load-based-lease-target (lease-holder=%d):\n%s", leaseStoreID, sl)
+	}
+
+	voters := desc.Voters()
+	candidates := a.PreferredLeaseholders(zone, voters)
+	if len(candidates) == 0 {
+		candidates = voters
+	}
+	dead := a.storePool.deadReplicas(desc.RangeID, candidates)
+	var target roachpb.ReplicaDescriptor
+	targetQPS := sl.qps.mean
+	for _, repl := range candidates {
+		if repl.StoreID == leaseStoreID || containsReplica(dead, repl) {
+			continue
+		}
+		storeDesc, ok := a.storePool.getStoreDescriptor(repl.StoreID)
+		if !ok {
+			continue
+		}
+		storeQPS := storeDesc.Capacity.QueriesPerSecond
+		if storeQPS < targetQPS && storeQPS+qps < leaseQPS-qps {
+			target, targetQPS = repl, storeQPS
+		}
+	}
+	return target, target.StoreID != 0
+}
+
+// uniformConstraints returns the constraints of the replicas of the zone
+// config, or false if they differ between replicas. Only the ranges whose
+// replicas all have the same constraints are rebalanced, since the replica
+// removed after adding a rebalance target could otherwise be subject to other
+// constraints than the target.
+func uniformConstraints(zone config.ZoneConfig) (roachpb.Attributes, bool) {
+	if len(zone.ReplicaAttrs) == 0 {
+		return roachpb.Attributes{}, true
+	}
+	for _, a := range zone.ReplicaAttrs[1:] {
+		if a.SortedString() != zone.ReplicaAttrs[0].SortedString() {
+			return roachpb.Attributes{}, false
+		}
+	}
+	return zone.ReplicaAttrs[0], true
+}
+
 // computeQuorum computes the quorum value for the given number of nodes.
 func computeQuorum(nodes int) int {
 	return (nodes / 2) + 1
@@ -375,6 +375,176 @@ func TestAllocatorRelaxConstraints(t *testing.T) {
 	}
 }
 
+var localityStores = []*roachpb.StoreDescriptor{
+	{
+		StoreID: 1,
+		Attrs:   roachpb.Attributes{Attrs: []string{"ssd"}},
+		Node: roachpb.NodeDescriptor{
+			NodeID: 1,
+			Attrs:  roachpb.Attributes{Attrs: []string{"region=us-east"}},
+		},
+		Capacity: roachpb.StoreCapacity{Capacity: 100, Available: 200},
+	},
+	{
+		StoreID: 2,
+		Attrs:   roachpb.Attributes{Attrs: []string{"hdd"}},
+		Node: roachpb.NodeDescriptor{
+			NodeID: 2,
+			Attrs:  roachpb.Attributes{Attrs: []string{"region=us-east"}},
+		},
+		Capacity: roachpb.StoreCapacity{Capacity: 100, Available: 200},
+	},
+	{
+		StoreID: 3,
+		Attrs:   roachpb.Attributes{Attrs: []string{"ssd"}},
+		Node: roachpb.NodeDescriptor{
+			NodeID: 3,
+			Attrs:  roachpb.Attributes{Attrs: []string{"region=us-west"}},
+		},
+		Capacity: roachpb.StoreCapacity{Capacity: 100, Available: 200},
+	},
+	{
+		StoreID: 4,
+		Attrs:   roachpb.Attributes{Attrs: []string{"ssd"}},
+		Node: roachpb.NodeDescriptor{
+			NodeID: 4,
+			Attrs:  roachpb.Attributes{Attrs: []string{"region=us-central"}},
+		},
+		Capacity: roachpb.StoreCapacity{Capacity: 100, Available: 200},
+	},
+}
+
+func makeReplicas(ids ...int) []roachpb.ReplicaDescriptor {
+	var replicas []roachpb.ReplicaDescriptor
+	for _, id := range ids {
+		replicas = append(replicas, roachpb.ReplicaDescriptor{
+			NodeID:    roachpb.NodeID(id),
+			StoreID:   roachpb.StoreID(id),
+			ReplicaID: roachpb.ReplicaID(id),
+		})
+	}
+	return replicas
+}
+
+// TestAllocatorProhibitedConstraints verifies that the stores with a
+// prohibited attribute are never chosen, even when relaxing the required
+// attributes.
+func TestAllocatorProhibitedConstraints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator()
+	defer stopper.Stop()
+	gossiputil.NewStoreGossiper(g).GossipStores(localityStores, t)
+
+	testCases := []struct {
+		required         []string
+		existing         []int
+		relaxConstraints bool
+		expIDs           []int
+	}{
+		{[]string{"ssd", "-region=us-east"}, nil, false, []int{3, 4}},
+		{[]string{"-region=us-east", "-region=us-west"}, nil, false, []int{4}},
+		{[]string{"-region=us-east", "-region=us-west"}, []int{4}, true, nil},
+		{[]string{"region=us-east", "-ssd"}, nil, false, []int{2}},
+		{[]string{"ssd", "gpu", "-region=us-west"}, nil, false, nil},
+		{[]string{"ssd", "gpu", "-region=us-west"}, nil, true, []int{1, 4}},
+		{[]string{"region=us-west", "-ssd"}, nil, true, []int{2}},
+	}
+	for i, test := range testCases {
+		result, err := a.AllocateTarget(
+			roachpb.Attributes{Attrs: test.required}, makeReplicas(test.existing...), test.relaxConstraints)
+		if test.expIDs == nil {
+			if err == nil {
+				t.Errorf("%d: expected error, got %+v", i, result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: unexpected error: %s", i, err)
+			continue
+		}
+		found := false
+		for _, id := range test.expIDs {
+			found = found || result.StoreID == roachpb.StoreID(id)
+		}
+		if !found {
+			t.Errorf("%d: expected one of stores %v, got %+v", i, test.expIDs, result)
+		}
+	}
+}
+
+// TestAllocatorPerReplicaConstraints verifies that the replicas of a range
+// are matched to the per-replica constraints of its zone config.
+func TestAllocatorPerReplicaConstraints(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator()
+	defer stopper.Stop()
+	gossiputil.NewStoreGossiper(g).GossipStores(localityStores, t)
+
+	zone := config.ZoneConfig{
+		ReplicaAttrs: []roachpb.Attributes{
+			{Attrs: []string{"region=us-east"}},
+			{Attrs: []string{"region=us-west"}},
+			{Attrs: []string{"region=us-central"}},
+		},
+		RangeMaxBytes: 64000,
+	}
+	for i, tc := range []struct {
+		existing []int
+		expected string
+	}{
+		{nil, "region=us-east"},
+		{[]int{1}, "region=us-west"},
+		{[]int{1, 3}, "region=us-central"},
+		{[]int{4, 3}, "region=us-east"},
+		{[]int{1, 3, 4}, "region=us-east"},
+	} {
+		if c := a.TargetConstraints(zone, makeReplicas(tc.existing...)); c.String() != tc.expected {
+			t.Errorf("%d: expected %s, got %s", i, tc.expected, c)
+		}
+	}
+
+	// The second replica in us-east is the one to remove.
+	if c := a.RemoveCandidates(zone, makeReplicas(1, 2, 3, 4)); len(c) != 1 || c[0].StoreID != 2 {
+		t.Errorf("expected store 2 to be the only candidate for removal, got %+v", c)
+	}
+	if c := a.RemoveCandidates(zone, makeReplicas(1, 3, 4)); len(c) != 3 {
+		t.Errorf("expected all the replicas to be candidates for removal, got %+v", c)
+	}
+	// The range is missing a replica in us-central, which store 4 can hold.
+	if action, priority := a.ComputeAction(zone, &roachpb.RangeDescriptor{
+		Replicas: makeReplicas(1, 2, 3),
+	}); action != AllocatorAdd || priority != fixConstraintsPriority {
+		t.Errorf("expected AllocatorAdd with priority %f, got %d with priority %f",
+			fixConstraintsPriority, action, priority)
+	}
+	if _, ok := uniformConstraints(zone); ok {
+		t.Error("expected the per-replica constraints not to be uniform")
+	}
+
+	// With uniform constraints, the replicas violating them are removed.
+	zone.ReplicaAttrs = []roachpb.Attributes{
+		{Attrs: []string{"-region=us-west"}},
+		{Attrs: []string{"-region=us-west"}},
+		{Attrs: []string{"-region=us-west"}},
+	}
+	if action, _ := a.ComputeAction(zone, &roachpb.RangeDescriptor{
+		Replicas: makeReplicas(1, 2, 3),
+	}); action != AllocatorAdd {
+		t.Errorf("expected AllocatorAdd, got %d", action)
+	}
+	if c := a.RemoveCandidates(zone, makeReplicas(1, 2, 3, 4)); len(c) != 1 || c[0].StoreID != 3 {
+		t.Errorf("expected store 3 to be the only candidate for removal, got %+v", c)
+	}
+	if c := a.RemoveCandidates(zone, makeReplicas(1, 2, 4)); len(c) != 3 {
+		t.Errorf("expected all the replicas to be candidates for removal, got %+v", c)
+	}
+	if action, _ := a.ComputeAction(zone, &roachpb.RangeDescriptor{
+		Replicas: makeReplicas(1, 2, 4),
+	}); action != AllocatorNoop {
+		t.Errorf("expected AllocatorNoop, got %d", action)
+	}
+}
+
 // TestAllocatorRebalance verifies that rebalance targets are chosen
 // randomly from amongst stores over the minAvailCapacityThreshold.
 func TestAllocatorRebalance(t *testing.T) {
@@ -587,6 +757,105 @@ func TestAllocatorRemoveTarget(t *testing.T) {
 	}
 }
 
+// TestAllocatorLocalityDiversity verifies that the allocator spreads the
+// replicas of a range across the most diverse localities available.
+func TestAllocatorLocalityDiversity(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator()
+	defer stopper.Stop()
+
+	// Stores 1 and 2 are in the same zone, store 3 in another zone of the same
+	// region and store 4 in another region. The range counts are balanced.
+	localities := [][]string{{"us", "a"}, {"us", "a"}, {"us", "b"}, {"eu", "c"}}
+	var stores []*roachpb.StoreDescriptor
+	for i, values := range localities {
+		stores = append(stores, &roachpb.StoreDescriptor{
+			StoreID: roachpb.StoreID(i + 1),
+			Node: roachpb.NodeDescriptor{
+				NodeID: roachpb.NodeID(i + 1),
+				Locality: roachpb.Locality{Tiers: []roachpb.Tier{
+					{Key: "region", Value: values[0]},
+					{Key: "zone", Value: values[1]},
+				}},
+			},
+			Capacity: roachpb.StoreCapacity{Capacity: 100, Available: 100, RangeCount: 5},
+		})
+	}
+	gossiputil.NewStoreGossiper(g).GossipStores(stores, t)
+
+	replicas := func(storeIDs ...roachpb.StoreID) []roachpb.ReplicaDescriptor {
+		var repls []roachpb.ReplicaDescriptor
+		for _, storeID := range storeIDs {
+			repls = append(repls, roachpb.ReplicaDescriptor{
+				NodeID:    roachpb.NodeID(storeID),
+				StoreID:   storeID,
+				ReplicaID: roachpb.ReplicaID(storeID),
+			})
+		}
+		return repls
+	}
+
+	allocateTestCases := []struct {
+		existing []roachpb.ReplicaDescriptor
+		expected []roachpb.StoreID
+	}{
+		{replicas(1), []roachpb.StoreID{4}},
+		{replicas(4), []roachpb.StoreID{1, 2, 3}},
+		{replicas(1, 4), []roachpb.StoreID{3}},
+		{replicas(3, 4), []roachpb.StoreID{1, 2}},
+	}
+	for i, tc := range allocateTestCases {
+		// The candidates are sampled randomly, so try several times.
+		for j := 0; j < 10; j++ {
+			result, err := a.AllocateTarget(roachpb.Attributes{}, tc.existing, false)
+			if err != nil {
+				t.Fatalf("%d: unable to perform allocation: %v", i, err)
+			}
+			found := false
+			for _, storeID := range tc.expected {
+				if result.StoreID == storeID {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%d: expected one of stores %v, got %d", i, tc.expected, result.StoreID)
+			}
+		}
+	}
+
+	removeTestCases := []struct {
+		existing     []roachpb.ReplicaDescriptor
+		leaseStoreID roachpb.StoreID
+		expected     roachpb.StoreID
+	}{
+		{replicas(1, 2, 3, 4), 1, 2},
+		{replicas(1, 2, 3, 4), 2, 1},
+		{replicas(1, 3, 4), 4, 1},
+	}
+	for i, tc := range removeTestCases {
+		result, err := a.RemoveTarget(tc.existing, tc.leaseStoreID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.StoreID != tc.expected {
+			t.Errorf("%d: expected store %d to be removed, got %d", i, tc.expected, result.StoreID)
+		}
+	}
+
+	// A replica sharing its zone with another one is rebalanced to the other
+	// zone of the region, although the range counts are balanced.
+	if result := a.RebalanceTarget(roachpb.Attributes{}, replicas(1, 2, 4), 1); result == nil {
+		t.Error("expected a rebalance target")
+	} else if result.StoreID != 3 {
+		t.Errorf("expected rebalance target store 3, got %d", result.StoreID)
+	}
+	// Nothing is gained by moving the replicas of a range already spread
+	// across zones.
+	if result := a.RebalanceTarget(roachpb.Attributes{}, replicas(1, 3, 4), 1); result != nil {
+		t.Errorf("expected no rebalance target, got store %d", result.StoreID)
+	}
+}
+
 func TestAllocatorComputeAction(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	stopper, _, sp, a, _ := createTestAllocator()
@@ -775,6 +1044,45 @@ func TestAllocatorComputeAction(t *testing.T) {
 			},
 			expectedAction: AllocatorRemoveDead,
 		},
+		// Needs three replicas, has two and a learner left behind.
+		{
+			zone: config.ZoneConfig{
+				ReplicaAttrs: []roachpb.Attributes{
+					{
+						Attrs: []string{"us-east"},
+					},
+					{
+						Attrs: []string{"us-east"},
+					},
+					{
+						Attrs: []string{"us-east"},
+					},
+				},
+				RangeMinBytes: 0,
+				RangeMaxBytes: 64000,
+			},
+			desc: roachpb.RangeDescriptor{
+				Replicas: []roachpb.ReplicaDescriptor{
+					{
+						StoreID:   1,
+						NodeID:    1,
+						ReplicaID: 1,
+					},
+					{
+						StoreID:   2,
+						NodeID:    2,
+						ReplicaID: 2,
+					},
+					{
+						StoreID:   3,
+						NodeID:    3,
+						ReplicaID: 3,
+						Type:      roachpb.LEARNER.Enum(),
+					},
+				},
+			},
+			expectedAction: AllocatorRemoveLearner,
+		},
 		// Needs Three replicas, have two
 		{
 			zone: config.ZoneConfig{
@@ -1306,3 +1614,103 @@ func Example_rebalancing() {
 	// 861 815 861 845 934 999 808 958 784 913 780 924 800 860 844 912 986 974 897 844
 	// Total bytes=941960698, ranges=1750
 }
+
+// TestAllocatorLeaseTarget verifies that the lease goes to a replica
+// satisfying the first lease preference satisfied by any of the replicas.
+func TestAllocatorLeaseTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator()
+	defer stopper.Stop()
+	gossiputil.NewStoreGossiper(g).GossipStores(localityStores, t)
+
+	zone := config.ZoneConfig{
+		RangeMaxBytes: 64000,
+		LeasePreferences: []roachpb.Attributes{
+			{Attrs: []string{"region=us-west"}},
+			{Attrs: []string{"region=us-east", "-hdd"}},
+		},
+	}
+	for i, tc := range []struct {
+		existing    []int
+		leaseholder int
+		expected    int
+	}{
+		// The lease holder satisfies the first preference.
+		{[]int{1, 3, 4}, 3, 0},
+		{[]int{1, 3, 4}, 1, 3},
+		{[]int{1, 3, 4}, 4, 3},
+		// No replica satisfies the first preference.
+		{[]int{1, 2, 4}, 1, 0},
+		{[]int{1, 2, 4}, 2, 1},
+		{[]int{1, 2, 4}, 4, 1},
+		// No replica satisfies any preference.
+		{[]int{2, 4}, 4, 0},
+	} {
+		desc := &roachpb.RangeDescriptor{Replicas: makeReplicas(tc.existing...)}
+		target, ok := a.LeaseTarget(zone, desc, roachpb.StoreID(tc.leaseholder))
+		if tc.expected == 0 {
+			if ok {
+				t.Errorf("%d: expected no lease target, got %+v", i, target)
+			}
+			continue
+		}
+		if !ok || target.StoreID != roachpb.StoreID(tc.expected) {
+			t.Errorf("%d: expected store %d, got %+v", i, tc.expected, target)
+		}
+	}
+
+	// Without lease preferences, the lease stays where it is.
+	zone.LeasePreferences = nil
+	desc := &roachpb.RangeDescriptor{Replicas: makeReplicas(1, 3, 4)}
+	if target, ok := a.LeaseTarget(zone, desc, 1); ok {
+		t.Errorf("expected no lease target, got %+v", target)
+	}
+}
+
+// TestAllocatorLoadBasedLeaseTarget verifies that the lease moves from an
+// overloaded store to the least loaded store below the mean, unless that
+// would leave the target more loaded than the lease holder.
+func TestAllocatorLoadBasedLeaseTarget(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper, g, _, a, _ := createTestAllocator()
+	defer stopper.Stop()
+	var stores []*roachpb.StoreDescriptor
+	for i, qps := range []float64{100, 10, 30, 20} {
+		desc := *localityStores[i]
+		desc.Capacity.QueriesPerSecond = qps
+		stores = append(stores, &desc)
+	}
+	gossiputil.NewStoreGossiper(g).GossipStores(stores, t)
+
+	zone := config.ZoneConfig{RangeMaxBytes: 64000}
+	desc := &roachpb.RangeDescriptor{Replicas: makeReplicas(1, 2, 3)}
+	for i, tc := range []struct {
+		leaseholder int
+		qps         float64
+		expected    int
+	}{
+		{1, 10, 2},
+		{1, 0, 0},
+		// Moving the load would overload the target.
+		{1, 50, 0},
+		// The lease holder isn't overloaded.
+		{3, 10, 0},
+	} {
+		target, ok := a.LoadBasedLeaseTarget(zone, desc, roachpb.StoreID(tc.leaseholder), tc.qps)
+		if tc.expected == 0 {
+			if ok {
+				t.Errorf("%d: expected no lease target, got %+v", i, target)
+			}
+			continue
+		}
+		if !ok || target.StoreID != roachpb.StoreID(tc.expected) {
+			t.Errorf("%d: expected store %d, got %+v", i, tc.expected, target)
+		}
+	}
+
+	// The lease preferences restrict the targets.
+	zone.LeasePreferences = []roachpb.Attributes{{Attrs: []string{"region=us-west"}}}
+	if target, ok := a.LoadBasedLeaseTarget(zone, desc, 1, 10); !ok || target.StoreID != 3 {
+		t.Errorf("expected store 3, got %+v", target)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
 	"github.com/cockroachdb/cockroach/storage"
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
@@ -313,6 +314,50 @@ func TestStoreRangeMergeLastRange(t *testing.T) {
 	}
 }
 
+// TestStoreRangeMergeQueue verifies that the merge queue merges an undersized
+// range into its successor once enabled, unless a zone config requires them
+// to stay split.
+func TestStoreRangeMergeQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	sCtx := storage.TestStoreContext()
+	sCtx.TestingKnobs.DisableSplitQueue = true
+	store, stopper, _ := createTestStoreWithContext(t, sCtx)
+	defer stopper.Stop()
+
+	for _, splitKey := range []string{"b", "c"} {
+		if err := store.DB().AdminSplit(splitKey); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The merge queue is disabled by default.
+	store.ForceMergeScanAndProcess()
+	if store.LookupReplica([]byte("a"), nil) == store.LookupReplica([]byte("b"), nil) {
+		t.Fatal("expected the ranges not to be merged")
+	}
+
+	s, ok := settings.Lookup("kv.range_merge.queue_enabled")
+	if !ok {
+		t.Fatal("merge queue setting not registered")
+	}
+	u := settings.MakeUpdater()
+	if err := u.Set("kv.range_merge.queue_enabled", settings.EncodeBool(true), s.Typ()); err != nil {
+		t.Fatal(err)
+	}
+	defer settings.MakeUpdater().Done()
+
+	store.ForceMergeScanAndProcess()
+	replicaA := store.LookupReplica([]byte("a"), nil)
+	if replicaB := store.LookupReplica([]byte("b"), nil); replicaA != replicaB {
+		t.Fatalf("ranges were not merged %s!=%s", replicaA, replicaB)
+	}
+	// The range starting at "c" holds the system tables, which are split
+	// from one another.
+	if replicaC := store.LookupReplica([]byte("c"), nil); replicaA == replicaC {
+		t.Fatalf("expected range %s not to be merged", replicaC)
+	}
+}
+
 // TestStoreRangeMergeNonCollocated attempts to merge two ranges
 // that are not on the same stores.
 func TestStoreRangeMergeNonCollocated(t *testing.T) {
@@ -296,6 +296,205 @@ func TestReplicateRange(t *testing.T) {
 	})
 }
 
+// TestReplicateRangeAddsLearner verifies that a replica is added to the range
+// as a learner, and promoted to a voter once it has caught up.
+func TestReplicateRangeAddsLearner(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var mu syncutil.Mutex
+	var added []roachpb.ReplicaType
+	sCtx := storage.TestStoreContext()
+	sCtx.TestingKnobs.TestingCommandFilter = func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+		et, ok := filterArgs.Req.(*roachpb.EndTransactionRequest)
+		if !ok || filterArgs.Sid != 1 {
+			return nil
+		}
+		if crt := et.InternalCommitTrigger.GetChangeReplicasTrigger(); crt != nil &&
+			crt.ChangeType == roachpb.ADD_REPLICA {
+			mu.Lock()
+			added = append(added, crt.Replica.GetType())
+			mu.Unlock()
+		}
+		return nil
+	}
+	mtc := &multiTestContext{storeContext: &sCtx}
+	mtc.Start(t, 2)
+	defer mtc.Stop()
+
+	rng, err := mtc.stores[0].GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rng.ChangeReplicas(
+		context.Background(),
+		roachpb.ADD_REPLICA,
+		roachpb.ReplicaDescriptor{
+			NodeID:  mtc.stores[1].Ident.NodeID,
+			StoreID: mtc.stores[1].Ident.StoreID,
+		},
+		rng.Desc(),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	if e := []roachpb.ReplicaType{roachpb.LEARNER, roachpb.VOTER}; !reflect.DeepEqual(added, e) {
+		t.Errorf("expected the replica to be added as %v, got %v", e, added)
+	}
+	mu.Unlock()
+	repDesc, ok := rng.Desc().GetReplicaDescriptor(mtc.stores[1].Ident.StoreID)
+	if !ok {
+		t.Fatalf("replica on store %d not found in %+v", mtc.stores[1].Ident.StoreID, rng.Desc())
+	}
+	if repDesc.IsLearner() {
+		t.Errorf("expected %+v to be promoted to a voter", repDesc)
+	}
+}
+
+// TestLearnerDoesNotCountTowardQuorum verifies that a learner which has not
+// yet been promoted is not needed to commit writes: with the learner's store
+// stopped, the sole voter still reaches quorum on its own.
+func TestLearnerDoesNotCountTowardQuorum(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := startMultiTestContext(t, 2)
+	defer mtc.Stop()
+
+	rng, err := mtc.stores[0].GetReplica(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner, desc, err := rng.AddLearner(
+		context.Background(),
+		roachpb.ReplicaDescriptor{
+			NodeID:  mtc.stores[1].Ident.NodeID,
+			StoreID: mtc.stores[1].Ident.StoreID,
+		},
+		rng.Desc(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repDesc, ok := desc.GetReplicaDescriptor(learner.StoreID); !ok || !repDesc.IsLearner() {
+		t.Fatalf("expected a learner on store %d in %+v", learner.StoreID, desc)
+	}
+
+	// Wait for raft to know about the learner before taking it down.
+	util.SucceedsSoon(t, func() error {
+		status := rng.RaftStatus()
+		if status == nil {
+			return errors.New("raft group not initialized")
+		}
+		pr, ok := status.Progress[uint64(learner.ReplicaID)]
+		if !ok {
+			return errors.Errorf("no progress for replica %d", learner.ReplicaID)
+		}
+		if !pr.IsLearner {
+			return errors.Errorf("expected replica %d to be tracked as a learner", learner.ReplicaID)
+		}
+		return nil
+	})
+
+	mtc.stopStore(1)
+
+	errCh := make(chan error, 1)
+	go func() {
+		incArgs := incrementArgs([]byte("a"), 5)
+		_, err := client.SendWrapped(rg1(mtc.stores[0]), nil, &incArgs)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("write did not commit without the learner; the learner counts toward quorum")
+	}
+
+	// Restart the learner so that the test context shuts down cleanly.
+	mtc.restartStore(1)
+}
+
+// TestSwapReplicas verifies that a replica is replaced by another one, which
+// is added as a learner and promoted before the replica is removed.
+func TestSwapReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	type change struct {
+		changeType  roachpb.ReplicaChangeType
+		replicaType roachpb.ReplicaType
+	}
+	var mu syncutil.Mutex
+	var changes []change
+	sCtx := storage.TestStoreContext()
+	sCtx.TestingKnobs.TestingCommandFilter = func(filterArgs storagebase.FilterArgs) *roachpb.Error {
+		et, ok := filterArgs.Req.(*roachpb.EndTransactionRequest)
+		if !ok || filterArgs.Sid != 1 {
+			return nil
+		}
+		if crt := et.InternalCommitTrigger.GetChangeReplicasTrigger(); crt != nil {
+			mu.Lock()
+			changes = append(changes, change{crt.ChangeType, crt.Replica.GetType()})
+			mu.Unlock()
+		}
+		return nil
+	}
+	mtc := &multiTestContext{storeContext: &sCtx}
+	mtc.Start(t, 3)
+	defer mtc.Stop()
+
+	rangeID := roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1)
+	mu.Lock()
+	changes = nil
+	mu.Unlock()
+
+	rng, err := mtc.stores[0].GetReplica(rangeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rng.SwapReplicas(
+		context.Background(),
+		roachpb.ReplicaDescriptor{
+			NodeID:  mtc.stores[2].Ident.NodeID,
+			StoreID: mtc.stores[2].Ident.StoreID,
+		},
+		roachpb.ReplicaDescriptor{
+			NodeID:  mtc.stores[1].Ident.NodeID,
+			StoreID: mtc.stores[1].Ident.StoreID,
+		},
+		rng.Desc(),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	expected := []change{
+		{roachpb.ADD_REPLICA, roachpb.LEARNER},
+		{roachpb.ADD_REPLICA, roachpb.VOTER},
+		{roachpb.REMOVE_REPLICA, roachpb.VOTER},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes %v, got %v", expected, changes)
+	}
+	mu.Unlock()
+
+	desc := rng.Desc()
+	var stores []roachpb.StoreID
+	for _, repDesc := range desc.Replicas {
+		stores = append(stores, repDesc.StoreID)
+	}
+	if e := []roachpb.StoreID{1, 3}; !reflect.DeepEqual(stores, e) {
+		t.Fatalf("expected replicas on stores %v, got %v", e, stores)
+	}
+
+	// The outgoing replica is eventually removed from its store.
+	util.SucceedsSoon(t, func() error {
+		if _, err := mtc.stores[1].GetReplica(rangeID); err == nil {
+			return errors.Errorf("expected replica on store 2 to be removed")
+		}
+		return nil
+	})
+}
+
 // TestRestoreReplicas ensures that consensus group membership is properly
 // persisted to disk and restored when a node is stopped and restarted.
 func TestRestoreReplicas(t *testing.T) {
@@ -1596,6 +1795,54 @@ func TestStoreRangeRebalance(t *testing.T) {
 	}
 }
 
+// TestRelocateRange verifies that RelocateRange moves the replicas of a range
+// to the target stores, and its lease to the first target.
+func TestRelocateRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	mtc := startMultiTestContext(t, 4)
+	defer mtc.Stop()
+
+	// Replicate the first range to the first three stores.
+	rangeID := roachpb.RangeID(1)
+	mtc.replicateRange(rangeID, 1, 2)
+
+	// Move the replica on the first store, which holds the lease, to the
+	// fourth one, and the lease along with it.
+	var targets []roachpb.ReplicaDescriptor
+	for _, i := range []int{3, 1, 2} {
+		targets = append(targets, roachpb.ReplicaDescriptor{
+			NodeID:  mtc.idents[i].NodeID,
+			StoreID: mtc.idents[i].StoreID,
+		})
+	}
+	rangeDesc := getRangeMetadata(roachpb.RKeyMin, mtc, t)
+	if err := storage.RelocateRange(context.Background(), mtc.dbs[0], rangeDesc, targets); err != nil {
+		t.Fatal(err)
+	}
+
+	rangeDesc = getRangeMetadata(roachpb.RKeyMin, mtc, t)
+	if len(rangeDesc.Replicas) != len(targets) {
+		t.Fatalf("expected replicas on stores %+v, found %+v", targets, rangeDesc.Replicas)
+	}
+	for _, target := range targets {
+		if _, ok := rangeDesc.GetReplicaDescriptor(target.StoreID); !ok {
+			t.Fatalf("expected replicas on stores %+v, found %+v", targets, rangeDesc.Replicas)
+		}
+	}
+	util.SucceedsSoon(t, func() error {
+		repl, err := mtc.stores[3].GetReplica(rangeID)
+		if err != nil {
+			return err
+		}
+		if lease, _ := repl.GetLease(); !lease.OwnedBy(targets[0].StoreID) {
+			return errors.Errorf("expected lease to be owned by store %d, found %+v",
+				targets[0].StoreID, lease)
+		}
+		return nil
+	})
+}
+
 // TestReplicateRogueRemovedNode ensures that a rogue removed node
 // (i.e. a node that has been removed from the range but doesn't know
 // it yet because it was down or partitioned away when it happened)
@@ -2074,6 +2321,20 @@ func TestCheckInconsistent(t *testing.T) {
 	case <-time.After(5 * time.Second):
 		t.Fatal("didn't receive notification from VerifyChecksum() that should have panicked")
 	}
+
+	// The diverging replica reports the failure; the others don't.
+	for i, s := range mtc.stores {
+		rng, err := s.GetReplica(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e, a := i == 1, rng.Inconsistent(); e != a {
+			t.Errorf("%d: expected inconsistent=%t, got %t", i, e, a)
+		}
+	}
+	if a := getCounter(t, mtc.stores[1], "range.consistency-failures"); a < 1 {
+		t.Errorf("expected consistency failures to be counted, got %d", a)
+	}
 }
 
 func TestTransferRaftLeadership(t *testing.T) {
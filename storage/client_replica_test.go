@@ -736,3 +736,221 @@ func TestLeaseExtensionNotBlockedByRead(t *testing.T) {
 		readBlocked <- struct{}{}
 	}
 }
+
+// TestFollowerReads verifies that a replica which does not hold the range
+// lease serves reads at or below the closed timestamp of the range.
+func TestFollowerReads(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	const target = time.Second
+	ctx := storage.TestStoreContext()
+	ctx.ClosedTimestampTargetDuration = target
+	mtc := &multiTestContext{}
+	mtc.storeContext = &ctx
+	mtc.Start(t, 2)
+	defer mtc.Stop()
+
+	key := roachpb.Key("a")
+	incArgs := incrementArgs(key, 5)
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.replicateRange(1, 1)
+	mtc.waitForValues(key, []int64{5, 5})
+	readTS := mtc.clock.Now()
+
+	// Once readTS is older than the target duration, the next write closes it.
+	mtc.manualClock.Increment(2 * target.Nanoseconds())
+	otherKey := roachpb.Key("b")
+	incArgs = incrementArgs(otherKey, 1)
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.waitForValues(otherKey, []int64{1, 1})
+
+	follower := 1
+	if lease, _ := mtc.stores[1].LookupReplica(roachpb.RKey(key), nil).GetLease(); lease.OwnedBy(mtc.stores[1].StoreID()) {
+		follower = 0
+	}
+	repDesc, err := mtc.stores[follower].LookupReplica(roachpb.RKey(key), nil).GetReplicaDescriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The follower serves the read at readTS.
+	gArgs := getArgs(key)
+	reply, pErr := client.SendWrappedWith(
+		mtc.senders[follower], nil, roachpb.Header{Timestamp: readTS, Replica: repDesc}, &gArgs)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	if v, err := reply.(*roachpb.GetResponse).Value.GetInt(); err != nil {
+		t.Fatal(err)
+	} else if v != 5 {
+		t.Fatalf("expected 5, got %d", v)
+	}
+
+	// A read at the current time still requires the lease.
+	_, pErr = client.SendWrappedWith(
+		mtc.senders[follower], nil, roachpb.Header{Replica: repDesc}, &gArgs)
+	if _, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok {
+		t.Fatalf("expected %T, got %v", &roachpb.NotLeaseHolderError{}, pErr)
+	}
+
+	// waitForFollowerRead waits for the follower to serve the read at readTS,
+	// once the lease holder closed it without any write to the range. The
+	// lease, which expired with the clock jump, is first renewed by a read.
+	waitForFollowerRead := func(follower int, readTS hlc.Timestamp, expected int64) {
+		mtc.manualClock.Increment(2 * target.Nanoseconds())
+		if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &gArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+		repDesc, err := mtc.stores[follower].LookupReplica(roachpb.RKey(key), nil).GetReplicaDescriptor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		util.SucceedsSoon(t, func() error {
+			reply, pErr := client.SendWrappedWith(
+				mtc.senders[follower], nil, roachpb.Header{Timestamp: readTS, Replica: repDesc}, &gArgs)
+			if pErr != nil {
+				return pErr.GoError()
+			}
+			if v, err := reply.(*roachpb.GetResponse).Value.GetInt(); err != nil {
+				t.Fatal(err)
+			} else if v != expected {
+				t.Fatalf("expected %d, got %d", expected, v)
+			}
+			return nil
+		})
+	}
+
+	// The lease holder of a range which isn't written to keeps closing
+	// timestamps.
+	incArgs = incrementArgs(key, 1)
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.waitForValues(key, []int64{6, 6})
+	waitForFollowerRead(follower, mtc.clock.Now(), 6)
+
+	// After a lease transfer, the former lease holder serves the reads below
+	// the timestamps closed by the new lease holder.
+	leaseHolder := 1 - follower
+	if err := mtc.stores[leaseHolder].LookupReplica(roachpb.RKey(key), nil).AdminTransferLease(
+		repDesc.StoreID); err != nil {
+		t.Fatal(err)
+	}
+	follower, leaseHolder = leaseHolder, follower
+	util.SucceedsSoon(t, func() error {
+		lease, _ := mtc.stores[follower].LookupReplica(roachpb.RKey(key), nil).GetLease()
+		if !lease.OwnedBy(mtc.stores[leaseHolder].StoreID()) {
+			return errors.Errorf("lease not transferred yet: %s", lease)
+		}
+		return nil
+	})
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.waitForValues(key, []int64{7, 7})
+	waitForFollowerRead(follower, mtc.clock.Now(), 7)
+}
+
+// TestFollowerReadsOnQuiescentRange verifies that the lease holder of an idle
+// range keeps closing timestamps for its followers without waking the range
+// up or loading its raft groups.
+func TestFollowerReadsOnQuiescentRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	// The target duration is short enough for the lease to stay valid while
+	// the clock moves, since renewing it would wake the range up.
+	const target = 50 * time.Millisecond
+	ctx := storage.TestStoreContext()
+	ctx.ClosedTimestampTargetDuration = target
+	ctx.RaftUnloadQuiescentTicks = 2
+	mtc := &multiTestContext{}
+	mtc.storeContext = &ctx
+	mtc.Start(t, 2)
+	defer mtc.Stop()
+
+	key := roachpb.Key("a")
+	incArgs := incrementArgs(key, 5)
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	mtc.replicateRange(1, 1)
+	mtc.waitForValues(key, []int64{5, 5})
+
+	follower := 1
+	if lease, _ := mtc.stores[1].LookupReplica(roachpb.RKey(key), nil).GetLease(); lease.OwnedBy(mtc.stores[1].StoreID()) {
+		follower = 0
+	}
+	repDesc, err := mtc.stores[follower].LookupReplica(roachpb.RKey(key), nil).GetReplicaDescriptor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkUnloaded := func() error {
+		for i, s := range mtc.stores {
+			if _, unloaded := s.LookupReplica(roachpb.RKey(key), nil).QuiescentState(); !unloaded {
+				return errors.Errorf("raft group of store %d not unloaded", i)
+			}
+		}
+		return nil
+	}
+	util.SucceedsSoon(t, checkUnloaded)
+
+	gArgs := getArgs(key)
+	for i := 0; i < 3; i++ {
+		readTS := mtc.clock.Now()
+		mtc.manualClock.Increment(2 * target.Nanoseconds())
+		util.SucceedsSoon(t, func() error {
+			reply, pErr := client.SendWrappedWith(
+				mtc.senders[follower], nil, roachpb.Header{Timestamp: readTS, Replica: repDesc}, &gArgs)
+			if pErr != nil {
+				return pErr.GoError()
+			}
+			if v, err := reply.(*roachpb.GetResponse).Value.GetInt(); err != nil {
+				t.Fatal(err)
+			} else if v != 5 {
+				t.Fatalf("expected 5, got %d", v)
+			}
+			return nil
+		})
+		if err := checkUnloaded(); err != nil {
+			t.Fatalf("closing timestamps woke the range up: %s", err)
+		}
+	}
+}
+
+// TestStoreDrainLeasesTransfersLeases verifies that a draining store transfers
+// its leases to the other replicas of their ranges.
+func TestStoreDrainLeasesTransfersLeases(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	mtc := startMultiTestContext(t, 2)
+	defer mtc.Stop()
+
+	key := roachpb.Key("a")
+	incArgs := incrementArgs(key, 1)
+	if _, pErr := client.SendWrapped(mtc.distSenders[0], nil, &incArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	rangeID := mtc.stores[0].LookupReplica(roachpb.RKey(key), nil).RangeID
+	mtc.replicateRange(rangeID, 1)
+
+	replica1 := mtc.stores[1].LookupReplica(roachpb.RKey(key), nil)
+	util.SucceedsSoon(t, func() error {
+		_, err := replica1.GetReplicaDescriptor()
+		return err
+	})
+
+	if err := mtc.stores[0].DrainLeases(true); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := mtc.stores[0].DrainLeases(false); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if lease, _ := replica1.GetLease(); !lease.OwnedBy(mtc.stores[1].StoreID()) {
+		t.Fatalf("expected the lease to be transferred to store %d, got %+v",
+			mtc.stores[1].StoreID(), lease)
+	}
+}
@@ -73,15 +73,25 @@ func TestComputeStatsForKeySpan(t *testing.T) {
 		{"a", "c", 1, 3},
 		{"b", "e", 2, 5},
 		{"e", "i", 2, 1},
+		// Spans straddling range boundaries only account for their keys.
+		{"bb", "d", 2, 2},
+		{"dd", "h", 3, 1},
 	} {
 		start, end := tcase.startKey, tcase.endKey
-		stats, count := mtc.stores[0].ComputeStatsForKeySpan(
-			roachpb.RKey(start), roachpb.RKey(end))
-		if a, e := count, tcase.expectedRanges; a != e {
-			t.Errorf("Expected %d ranges in span [%s - %s], found %d", e, start, end, a)
-		}
-		if a, e := stats.LiveCount, tcase.expectedKeys; a != e {
-			t.Errorf("Expected %d keys in span [%s - %s], found %d", e, start, end, a)
+		// All the replicas of the ranges are on the first store, which makes
+		// them the first replica of their range.
+		for _, firstReplicaOnly := range []bool{false, true} {
+			stats, count, err := mtc.stores[0].ComputeStatsForKeySpan(
+				roachpb.RKey(start), roachpb.RKey(end), firstReplicaOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a, e := count, tcase.expectedRanges; a != e {
+				t.Errorf("Expected %d ranges in span [%s - %s], found %d", e, start, end, a)
+			}
+			if a, e := stats.LiveCount, tcase.expectedKeys; a != e {
+				t.Errorf("Expected %d keys in span [%s - %s], found %d", e, start, end, a)
+			}
 		}
 	}
 }
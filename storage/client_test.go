@@ -428,6 +428,12 @@ func (t *multiTestContextKVTransport) SendNext(done chan kv.BatchCall) {
 	}
 }
 
+func (t *multiTestContextKVTransport) MoveToFront(replica roachpb.ReplicaDescriptor) {
+	if i := t.replicas.FindReplica(replica.StoreID); i > 0 {
+		t.replicas.MoveToFront(i)
+	}
+}
+
 func (t *multiTestContextKVTransport) Close() {
 	t.cancel()
 }
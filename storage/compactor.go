@@ -0,0 +1,125 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/envutil"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/stop"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// defaultCompactionThreshold is the number of bytes which must have been
+// cleared from a store before the compactor compacts the spans they were
+// cleared from.
+const defaultCompactionThreshold = 256 << 20 // 256 MiB
+
+// suggestedCompaction is a span from which the given number of bytes were
+// cleared.
+type suggestedCompaction struct {
+	span  roachpb.Span
+	bytes int64
+}
+
+// A compactor compacts the spans of a store's key space from which large
+// amounts of data were cleared, e.g. by removing a replica rebalanced away
+// from the store or by garbage collecting a dropped table. RocksDB only
+// reclaims the space taken by deleted keys once their deletion tombstones
+// are compacted down to the bottommost level, which can take a long time
+// for spans which aren't written to anymore.
+type compactor struct {
+	eng       engine.Engine
+	metrics   *storeMetrics
+	threshold int64
+	// ready is signaled when the suggested bytes exceed the threshold.
+	ready chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		spans []roachpb.Span
+		bytes int64
+	}
+}
+
+func newCompactor(eng engine.Engine, metrics *storeMetrics) *compactor {
+	return &compactor{
+		eng:       eng,
+		metrics:   metrics,
+		threshold: envutil.EnvOrDefaultBytes("compactor_threshold", defaultCompactionThreshold),
+		ready:     make(chan struct{}, 1),
+	}
+}
+
+// Suggest records that the given number of bytes were cleared from the span,
+// to be reclaimed once enough bytes were cleared overall.
+func (c *compactor) Suggest(span roachpb.Span, bytes int64) {
+	if bytes <= 0 {
+		return
+	}
+	c.mu.Lock()
+	c.mu.spans = append(c.mu.spans, span)
+	c.mu.bytes += bytes
+	ready := c.mu.bytes >= c.threshold
+	c.mu.Unlock()
+
+	if ready {
+		select {
+		case c.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start runs the compactor until the stopper stops.
+func (c *compactor) Start(ctx context.Context, stopper *stop.Stopper) {
+	stopper.RunWorker(func() {
+		for {
+			select {
+			case <-c.ready:
+				if err := c.process(ctx); err != nil {
+					log.Warningf(ctx, "failed to compact cleared spans: %s", err)
+				}
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// process compacts the suggested spans, after merging the overlapping ones.
+// The spans are dropped on failure: RocksDB compacts them eventually anyway.
+func (c *compactor) process(ctx context.Context) error {
+	c.mu.Lock()
+	spans, bytes := c.mu.spans, c.mu.bytes
+	c.mu.spans, c.mu.bytes = nil, 0
+	c.mu.Unlock()
+
+	spans, _ = roachpb.MergeSpans(spans)
+	for _, span := range spans {
+		if err := c.eng.CompactRange(span.Key, span.EndKey); err != nil {
+			return err
+		}
+		c.metrics.compactorCompactions.Inc(1)
+	}
+	c.metrics.compactorCompactedBytes.Inc(bytes)
+	if log.V(1) {
+		log.Infof(ctx, "compacted %d spans to reclaim %d bytes", len(spans), bytes)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/stop"
+)
+
+// TestCompactorSuggest verifies that the compactor waits for the suggested
+// bytes to exceed its threshold and merges the overlapping spans.
+func TestCompactorSuggest(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+
+	eng := engine.NewInMem(roachpb.Attributes{}, 1<<20, stopper)
+	metrics := newStoreMetrics()
+	c := newCompactor(eng, metrics)
+	c.threshold = 100
+
+	c.Suggest(roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("c")}, 60)
+	// Nothing was cleared from this span.
+	c.Suggest(roachpb.Span{Key: roachpb.Key("x"), EndKey: roachpb.Key("z")}, 0)
+	select {
+	case <-c.ready:
+		t.Fatal("unexpected compaction below the threshold")
+	default:
+	}
+
+	c.Suggest(roachpb.Span{Key: roachpb.Key("b"), EndKey: roachpb.Key("d")}, 60)
+	select {
+	case <-c.ready:
+	default:
+		t.Fatal("expected a compaction above the threshold")
+	}
+
+	if err := c.process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := metrics.compactorCompactions.Count(); n != 1 {
+		t.Errorf("expected the spans to be compacted at once, got %d compactions", n)
+	}
+	if n := metrics.compactorCompactedBytes.Count(); n != 120 {
+		t.Errorf("expected 120 compacted bytes, got %d", n)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.mu.spans) != 0 || c.mu.bytes != 0 {
+		t.Errorf("expected the suggestions to be consumed, got %d bytes in %s",
+			c.mu.bytes, c.mu.spans)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/cockroachdb/cockroach/util/uuid"
+)
+
+// contentionEventsToKeep is the number of most recent contention events
+// kept by a store. Older events are only accounted for in the statistics
+// aggregated by index.
+const contentionEventsToKeep = 100
+
+// ContentionEvent describes a request which waited for the transaction
+// holding an intent on a key it accessed.
+type ContentionEvent struct {
+	Key roachpb.Key
+	// TxnID is the ID of the waiting transaction, nil for non-transactional
+	// requests.
+	TxnID *uuid.UUID
+	// BlockingTxnID is the ID of the transaction holding the intent.
+	BlockingTxnID *uuid.UUID
+	// TableID and IndexID are zero if the key is not a table key.
+	TableID  uint32
+	IndexID  uint32
+	Duration time.Duration
+}
+
+// IndexContention aggregates the contention events on the keys of an index.
+type IndexContention struct {
+	TableID        uint32
+	IndexID        uint32
+	NumEvents      int64
+	CumulativeTime time.Duration
+}
+
+type indexContentionKey struct {
+	tableID, indexID uint32
+}
+
+// contentionRegistry records the contention events of a store.
+type contentionRegistry struct {
+	mu struct {
+		syncutil.Mutex
+		indexes map[indexContentionKey]*IndexContention
+		// events is a ring buffer holding the most recent events, next being
+		// the index of the oldest one once it is full.
+		events []ContentionEvent
+		next   int
+	}
+}
+
+func newContentionRegistry() *contentionRegistry {
+	c := &contentionRegistry{}
+	c.mu.indexes = map[indexContentionKey]*IndexContention{}
+	return c
+}
+
+// decodeTableIndex returns the table and index IDs of a table key, or zeroes
+// if the key is not a table key.
+func decodeTableIndex(key roachpb.Key) (uint32, uint32) {
+	rest, tableID, err := keys.DecodeTablePrefix(key)
+	if err != nil {
+		return 0, 0
+	}
+	_, indexID, err := encoding.DecodeUvarintAscending(rest)
+	if err != nil {
+		return uint32(tableID), 0
+	}
+	return uint32(tableID), uint32(indexID)
+}
+
+// record adds an event for each of the intents the waiting transaction, or
+// non-transactional request if txn is nil, waited for.
+func (c *contentionRegistry) record(
+	txn *roachpb.Transaction, intents []roachpb.Intent, duration time.Duration,
+) {
+	var txnID *uuid.UUID
+	if txn != nil {
+		txnID = txn.ID
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, intent := range intents {
+		tableID, indexID := decodeTableIndex(intent.Key)
+		event := ContentionEvent{
+			Key:           intent.Key,
+			TxnID:         txnID,
+			BlockingTxnID: intent.Txn.ID,
+			TableID:       tableID,
+			IndexID:       indexID,
+			Duration:      duration,
+		}
+		if len(c.mu.events) < contentionEventsToKeep {
+			c.mu.events = append(c.mu.events, event)
+		} else {
+			c.mu.events[c.mu.next] = event
+			c.mu.next = (c.mu.next + 1) % contentionEventsToKeep
+		}
+
+		if tableID == 0 {
+			continue
+		}
+		key := indexContentionKey{tableID: tableID, indexID: indexID}
+		stats, ok := c.mu.indexes[key]
+		if !ok {
+			stats = &IndexContention{TableID: tableID, IndexID: indexID}
+			c.mu.indexes[key] = stats
+		}
+		stats.NumEvents++
+		stats.CumulativeTime += duration
+	}
+}
+
+type indexContentionsByID []IndexContention
+
+func (s indexContentionsByID) Len() int      { return len(s) }
+func (s indexContentionsByID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s indexContentionsByID) Less(i, j int) bool {
+	if s[i].TableID != s[j].TableID {
+		return s[i].TableID < s[j].TableID
+	}
+	return s[i].IndexID < s[j].IndexID
+}
+
+// ContentionEvents returns the contention statistics of the store aggregated
+// by index, ordered by table and index ID, along with its most recent
+// contention events, oldest first.
+func (s *Store) ContentionEvents() ([]IndexContention, []ContentionEvent) {
+	c := s.contention
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	indexes := make([]IndexContention, 0, len(c.mu.indexes))
+	for _, stats := range c.mu.indexes {
+		indexes = append(indexes, *stats)
+	}
+	sort.Sort(indexContentionsByID(indexes))
+	events := make([]ContentionEvent, 0, len(c.mu.events))
+	events = append(events, c.mu.events[c.mu.next:]...)
+	events = append(events, c.mu.events[:c.mu.next]...)
+	return indexes, events
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestContentionRegistry verifies that the contention events are aggregated
+// by index, and that only the most recent ones are kept.
+func TestContentionRegistry(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	s := &Store{contention: newContentionRegistry()}
+
+	indexKey := func(tableID, indexID uint32) roachpb.Key {
+		key := keys.MakeTablePrefix(tableID)
+		return roachpb.Key(encoding.EncodeUvarintAscending(key, uint64(indexID)))
+	}
+	waiter := newTransaction("waiter", roachpb.Key("a"), 1, enginepb.SERIALIZABLE, nil)
+	blocker := newTransaction("blocker", roachpb.Key("b"), 1, enginepb.SERIALIZABLE, nil)
+	intent := func(key roachpb.Key) roachpb.Intent {
+		return roachpb.Intent{Span: roachpb.Span{Key: key}, Txn: blocker.TxnMeta}
+	}
+
+	s.contention.record(waiter, []roachpb.Intent{
+		intent(indexKey(51, 1)), intent(indexKey(51, 2)),
+	}, time.Second)
+	s.contention.record(nil, []roachpb.Intent{
+		intent(indexKey(51, 1)), intent(roachpb.Key("a")),
+	}, 2*time.Second)
+
+	indexes, events := s.ContentionEvents()
+	expIndexes := []IndexContention{
+		{TableID: 51, IndexID: 1, NumEvents: 2, CumulativeTime: 3 * time.Second},
+		{TableID: 51, IndexID: 2, NumEvents: 1, CumulativeTime: time.Second},
+	}
+	if !reflect.DeepEqual(indexes, expIndexes) {
+		t.Errorf("expected %+v, got %+v", expIndexes, indexes)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %d", len(events))
+	}
+	if e := events[0]; e.TxnID != waiter.ID || e.BlockingTxnID != blocker.ID ||
+		e.TableID != 51 || e.IndexID != 1 || e.Duration != time.Second {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e := events[3]; e.TxnID != nil || e.TableID != 0 || !e.Key.Equal(roachpb.Key("a")) {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	// Only the most recent events are kept, oldest first.
+	for i := 0; i < contentionEventsToKeep; i++ {
+		s.contention.record(waiter, []roachpb.Intent{intent(indexKey(52, 1))}, time.Duration(i))
+	}
+	_, events = s.ContentionEvents()
+	if len(events) != contentionEventsToKeep {
+		t.Fatalf("expected %d events, got %d", contentionEventsToKeep, len(events))
+	}
+	for i, e := range events {
+		if e.Duration != time.Duration(i) {
+			t.Fatalf("expected event %d to have lasted %s, got %s", i, time.Duration(i), e.Duration)
+		}
+	}
+}
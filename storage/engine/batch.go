@@ -16,12 +16,19 @@
 
 package engine
 
-import "github.com/cockroachdb/cockroach/util/hlc"
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/util/hlc"
+)
 
 const (
-	batchTypeDeletion byte = 0x0
-	batchTypeValue         = 0x1
-	batchTypeMerge         = 0x2
+	batchTypeDeletion      byte = 0x0
+	batchTypeValue              = 0x1
+	batchTypeMerge              = 0x2
+	batchTypeRangeDeletion      = 0xF
 
 	// The batch header is composed of an 8-byte sequence number (all zeroes) and
 	// 4-byte count of the number of entries in the batch.
@@ -30,7 +37,7 @@ const (
 	maxVarintLen32       = 5
 )
 
-// A rocksDBBatchBuilder constructs a RocksDB batch representation. From the
+// RocksDBBatchBuilder constructs a RocksDB batch representation. From the
 // RocksDB code, the representation of a batch is:
 //
 //   WriteBatch::rep_ :=
@@ -46,13 +53,15 @@ const (
 //      kTypeColumnFamilyDeletion varint32 varstring varstring
 //      kTypeColumnFamilySingleDeletion varint32 varstring varstring
 //      kTypeColumnFamilyMerge varint32 varstring varstring
+//      kTypeRangeDeletion varstring varstring
 //   varstring :=
 //      len: varint32
 //      data: uint8[len]
 //
-// The rocksDBBatchBuilder code currently only supports kTypeValue
-// (batchTypeValue), kTypeDeletion (batchTypeDeletion)and kTypeMerge
-// (batchTypeMerge) operations. Before a batch is written to the RocksDB
+// The RocksDBBatchBuilder code currently only supports kTypeValue
+// (batchTypeValue), kTypeDeletion (batchTypeDeletion), kTypeMerge
+// (batchTypeMerge) and kTypeRangeDeletion (batchTypeRangeDeletion)
+// operations. Before a batch is written to the RocksDB
 // write-ahead-log, the sequence number is 0. The "fixed32" format is little
 // endian.
 //
@@ -66,12 +75,12 @@ const (
 // the desired ordering as these keys do not sort lexicographically
 // correctly. Note that the encoding of these keys needs to match up with the
 // encoding in rocksdb/db.cc:EncodeKey().
-type rocksDBBatchBuilder struct {
+type RocksDBBatchBuilder struct {
 	repr  []byte
 	count int
 }
 
-func (b *rocksDBBatchBuilder) maybeInit() {
+func (b *RocksDBBatchBuilder) maybeInit() {
 	if b.repr == nil {
 		b.repr = make([]byte, headerSize, initialBatchSize)
 	}
@@ -80,7 +89,7 @@ func (b *rocksDBBatchBuilder) maybeInit() {
 // Finish returns the constructed batch representation. After calling Finish,
 // the builder may be used to construct another batch, but the returned []byte
 // is only valid until the next builder method is called.
-func (b *rocksDBBatchBuilder) Finish() []byte {
+func (b *RocksDBBatchBuilder) Finish() []byte {
 	buf := b.repr[8:headerSize]
 	v := uint32(b.count)
 	buf[0] = byte(v)
@@ -93,7 +102,7 @@ func (b *rocksDBBatchBuilder) Finish() []byte {
 	return repr
 }
 
-func (b *rocksDBBatchBuilder) grow(n int) {
+func (b *RocksDBBatchBuilder) grow(n int) {
 	newSize := len(b.repr) + n
 	if newSize > cap(b.repr) {
 		newCap := 2 * cap(b.repr)
@@ -139,7 +148,7 @@ func putUint64(b []byte, v uint64) {
 // encodeKey encodes an MVCC key into the batch, reserving extra bytes in
 // b.repr for use in encoding a value as well. This encoding must match with
 // the encoding in engine/db.cc:EncodeKey().
-func (b *rocksDBBatchBuilder) encodeKey(key MVCCKey, extra int) {
+func (b *RocksDBBatchBuilder) encodeKey(key MVCCKey, extra int) {
 	length := 1 + len(key.Key)
 	timestampLength := 0
 	if key.Timestamp != hlc.ZeroTimestamp {
@@ -169,7 +178,7 @@ func (b *rocksDBBatchBuilder) encodeKey(key MVCCKey, extra int) {
 	b.repr[len(b.repr)-1-extra] = byte(timestampLength)
 }
 
-func (b *rocksDBBatchBuilder) encodeKeyValue(key MVCCKey, value []byte, tag byte) {
+func (b *RocksDBBatchBuilder) encodeKeyValue(key MVCCKey, value []byte, tag byte) {
 	b.maybeInit()
 	b.count++
 
@@ -186,18 +195,205 @@ func (b *rocksDBBatchBuilder) encodeKeyValue(key MVCCKey, value []byte, tag byte
 	copy(b.repr[pos+n:], value)
 }
 
-func (b *rocksDBBatchBuilder) Put(key MVCCKey, value []byte) {
+// Put sets the given key to the value provided.
+func (b *RocksDBBatchBuilder) Put(key MVCCKey, value []byte) {
 	b.encodeKeyValue(key, value, batchTypeValue)
 }
 
-func (b *rocksDBBatchBuilder) Merge(key MVCCKey, value []byte) {
+// Merge is a high-performance write operation used for values which are
+// accumulated over several writes.
+func (b *RocksDBBatchBuilder) Merge(key MVCCKey, value []byte) {
 	b.encodeKeyValue(key, value, batchTypeMerge)
 }
 
-func (b *rocksDBBatchBuilder) Clear(key MVCCKey) {
+// Clear removes the item from the db with the given key.
+func (b *RocksDBBatchBuilder) Clear(key MVCCKey) {
 	b.maybeInit()
 	b.count++
 	pos := len(b.repr)
 	b.encodeKey(key, 0)
 	b.repr[pos] = batchTypeDeletion
 }
+
+// ClearRange removes the items from start (inclusive) to end (exclusive)
+// with a single range deletion.
+func (b *RocksDBBatchBuilder) ClearRange(start, end MVCCKey) {
+	b.maybeInit()
+	b.count++
+	pos := len(b.repr)
+	b.encodeKey(start, 0)
+	b.repr[pos] = batchTypeRangeDeletion
+	// The end key is encoded like the start key, without the tag.
+	pos = len(b.repr)
+	b.encodeKey(end, 0)
+	copy(b.repr[pos:], b.repr[pos+1:])
+	b.repr = b.repr[:len(b.repr)-1]
+}
+
+// Len returns the number of bytes in the batch representation constructed so
+// far.
+func (b *RocksDBBatchBuilder) Len() int {
+	return len(b.repr)
+}
+
+// Count returns the number of entries added to the batch so far.
+func (b *RocksDBBatchBuilder) Count() int {
+	return b.count
+}
+
+// RocksDBBatchReader is used to iterate over the entries in a RocksDB batch
+// representation, such as one constructed by RocksDBBatchBuilder. Only the
+// record types produced by RocksDBBatchBuilder are supported.
+//
+// Example:
+//   r, err := NewRocksDBBatchReader(repr)
+//   if err != nil {
+//     return err
+//   }
+//   for r.Next() {
+//     key, err := r.MVCCKey()
+//     ...
+//   }
+//   if err := r.Error(); err != nil {
+//     return err
+//   }
+type RocksDBBatchReader struct {
+	repr  []byte
+	count int
+	err   error
+
+	// The current entry.
+	typ   byte
+	key   []byte
+	value []byte
+	// The offset of the next entry in repr and the number of entries read.
+	offset int
+	read   int
+}
+
+// NewRocksDBBatchReader creates a RocksDBBatchReader for the given batch
+// representation.
+func NewRocksDBBatchReader(repr []byte) (*RocksDBBatchReader, error) {
+	if len(repr) < headerSize {
+		return nil, errors.Errorf("batch repr too small: %d < %d", len(repr), headerSize)
+	}
+	return &RocksDBBatchReader{
+		repr:   repr,
+		count:  int(binary.LittleEndian.Uint32(repr[8:headerSize])),
+		offset: headerSize,
+	}, nil
+}
+
+// Count returns the number of entries declared in the batch header.
+func (r *RocksDBBatchReader) Count() int {
+	return r.count
+}
+
+// Next advances to the next entry in the batch, returning false when the
+// batch is exhausted or an error was encountered.
+func (r *RocksDBBatchReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.offset >= len(r.repr) {
+		if r.read != r.count {
+			r.err = errors.Errorf("batch contains %d entries, but header declares %d", r.read, r.count)
+		}
+		return false
+	}
+	r.typ = r.repr[r.offset]
+	r.offset++
+	switch r.typ {
+	case batchTypeValue, batchTypeMerge, batchTypeRangeDeletion:
+		if r.key, r.err = r.varstring(); r.err != nil {
+			return false
+		}
+		if r.value, r.err = r.varstring(); r.err != nil {
+			return false
+		}
+	case batchTypeDeletion:
+		if r.key, r.err = r.varstring(); r.err != nil {
+			return false
+		}
+		r.value = nil
+	default:
+		r.err = errors.Errorf("unexpected batch record type: %d", r.typ)
+		return false
+	}
+	r.read++
+	return true
+}
+
+func (r *RocksDBBatchReader) varstring() ([]byte, error) {
+	v, n := binary.Uvarint(r.repr[r.offset:])
+	if n <= 0 {
+		return nil, errors.Errorf("invalid varint at batch offset %d", r.offset)
+	}
+	r.offset += n
+	end := r.offset + int(v)
+	if v > uint64(len(r.repr)) || end > len(r.repr) {
+		return nil, errors.Errorf("varstring at batch offset %d overflows batch", r.offset)
+	}
+	s := r.repr[r.offset:end:end]
+	r.offset = end
+	return s, nil
+}
+
+// Error returns the error, if any, encountered while reading the batch.
+func (r *RocksDBBatchReader) Error() error {
+	return r.err
+}
+
+// IsPut returns true if the current entry is a put (kTypeValue).
+func (r *RocksDBBatchReader) IsPut() bool {
+	return r.typ == batchTypeValue
+}
+
+// IsDelete returns true if the current entry is a deletion (kTypeDeletion).
+func (r *RocksDBBatchReader) IsDelete() bool {
+	return r.typ == batchTypeDeletion
+}
+
+// IsRangeDeletion returns true if the current entry is a range deletion
+// (kTypeRangeDeletion), whose start key is the key of the entry and whose
+// encoded end key is its value.
+func (r *RocksDBBatchReader) IsRangeDeletion() bool {
+	return r.typ == batchTypeRangeDeletion
+}
+
+// Value returns the value of the current entry. The returned slice aliases the
+// batch representation.
+func (r *RocksDBBatchReader) Value() []byte {
+	return r.value
+}
+
+// MVCCKey decodes and returns the key of the current entry. The key bytes of
+// the returned MVCCKey alias the batch representation.
+func (r *RocksDBBatchReader) MVCCKey() (MVCCKey, error) {
+	return decodeMVCCKey(r.key)
+}
+
+// decodeMVCCKey decodes an MVCC key encoded by RocksDBBatchBuilder.encodeKey.
+func decodeMVCCKey(buf []byte) (MVCCKey, error) {
+	if len(buf) == 0 {
+		return MVCCKey{}, errors.Errorf("invalid encoded mvcc key: %x", buf)
+	}
+	tsLen := int(buf[len(buf)-1])
+	keyPartEnd := len(buf) - 1 - tsLen
+	if keyPartEnd < 0 {
+		return MVCCKey{}, errors.Errorf("invalid encoded mvcc key: %x", buf)
+	}
+	key := MVCCKey{Key: buf[:keyPartEnd]}
+	switch tsLen {
+	case 0:
+	case 1 + 8, 1 + 8 + 4:
+		ts := buf[keyPartEnd+1 : len(buf)-1]
+		key.Timestamp.WallTime = int64(binary.BigEndian.Uint64(ts[:8]))
+		if tsLen == 1+8+4 {
+			key.Timestamp.Logical = int32(binary.BigEndian.Uint32(ts[8:]))
+		}
+	default:
+		return MVCCKey{}, errors.Errorf("invalid encoded mvcc key: %x", buf)
+	}
+	return key, nil
+}
@@ -584,7 +584,7 @@ func TestBatchBuilder(t *testing.T) {
 	batch := e.NewBatch().(*rocksDBBatch)
 	defer batch.Close()
 
-	builder := &rocksDBBatchBuilder{}
+	builder := &RocksDBBatchBuilder{}
 
 	testData := []struct {
 		key string
@@ -634,7 +634,7 @@ func TestBatchBuilderStress(t *testing.T) {
 			batch := e.NewBatch().(*rocksDBBatch)
 			defer batch.Close()
 
-			builder := &rocksDBBatchBuilder{}
+			builder := &RocksDBBatchBuilder{}
 
 			for j := 0; j < count; j++ {
 				var ts hlc.Timestamp
@@ -679,6 +679,76 @@ func TestBatchBuilderStress(t *testing.T) {
 	}
 }
 
+func TestBatchReader(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	builder := &RocksDBBatchBuilder{}
+	testData := []struct {
+		key   MVCCKey
+		value []byte
+		put   bool
+	}{
+		{MVCCKey{roachpb.Key("a"), hlc.Timestamp{}}, []byte("value"), true},
+		{MVCCKey{roachpb.Key("b"), hlc.Timestamp{WallTime: 1}}, nil, false},
+		{MVCCKey{roachpb.Key("c"), hlc.Timestamp{WallTime: 1, Logical: 1}}, []byte("c"), true},
+		{MVCCKey{roachpb.Key(""), hlc.Timestamp{WallTime: 2}}, []byte{}, true},
+	}
+	for _, d := range testData {
+		if d.put {
+			builder.Put(d.key, d.value)
+		} else {
+			builder.Clear(d.key)
+		}
+	}
+	if builder.Count() != len(testData) {
+		t.Fatalf("expected %d entries, but got %d", len(testData), builder.Count())
+	}
+
+	r, err := NewRocksDBBatchReader(builder.Finish())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count() != len(testData) {
+		t.Fatalf("expected %d entries, but got %d", len(testData), r.Count())
+	}
+	i := 0
+	for ; r.Next(); i++ {
+		d := testData[i]
+		key, err := r.MVCCKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !key.Equal(d.key) {
+			t.Errorf("%d: expected key %s, but got %s", i, d.key, key)
+		}
+		if r.IsPut() != d.put {
+			t.Errorf("%d: expected put=%t", i, d.put)
+		}
+		if !bytes.Equal(r.Value(), d.value) {
+			t.Errorf("%d: expected value %q, but got %q", i, d.value, r.Value())
+		}
+	}
+	if err := r.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if i != len(testData) {
+		t.Fatalf("expected %d entries, but read %d", len(testData), i)
+	}
+
+	// A truncated batch must be rejected.
+	builder.Put(testData[0].key, testData[0].value)
+	repr := builder.Finish()
+	r, err = NewRocksDBBatchReader(repr[:len(repr)-2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+	}
+	if r.Error() == nil {
+		t.Fatal("expected error reading truncated batch")
+	}
+}
+
 func TestBatchDistinct(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
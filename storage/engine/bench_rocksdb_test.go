@@ -35,6 +35,8 @@ func setupMVCCRocksDB(b testing.TB, loc string) (Engine, *stop.Stopper) {
 		memtableBudget,
 		0,
 		DefaultMaxOpenFiles,
+		RocksDBTuning{},
+		EncryptionOptions{},
 		stopper,
 	)
 	if err := rocksdb.Open(); err != nil {
@@ -273,7 +275,7 @@ func BenchmarkBatchBuilderPut(b *testing.B) {
 	b.ResetTimer()
 
 	const batchSize = 1000
-	batch := &rocksDBBatchBuilder{}
+	batch := &RocksDBBatchBuilder{}
 	for i := 0; i < b.N; i += batchSize {
 		end := i + batchSize
 		if end > b.N {
@@ -0,0 +1,223 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Encrypted files start with a plaintext header made of encryptionMagic, the
+// ID of the key the rest of the file is encrypted with and the initialization
+// vector of its AES-CTR keystream. This layout is mirrored by the encrypted
+// env in rocksdb/encryption.cc.
+const (
+	encryptionMagic      = "crdbenc1"
+	encryptionKeyIDSize  = sha256.Size
+	encryptionHeaderSize = len(encryptionMagic) + encryptionKeyIDSize + aes.BlockSize
+)
+
+// EncryptionKey is an AES key used to encrypt the files of a store.
+type EncryptionKey struct {
+	// ID identifies the key in the headers of the files it encrypts. It is
+	// the SHA-256 hash of the key.
+	ID  [encryptionKeyIDSize]byte
+	Key []byte
+}
+
+// NewEncryptionKey returns the encryption key made of the 16, 24 or 32 bytes
+// of an AES-128, AES-192 or AES-256 key.
+func NewEncryptionKey(key []byte) (*EncryptionKey, error) {
+	if _, err := aes.NewCipher(key); err != nil {
+		return nil, err
+	}
+	return &EncryptionKey{ID: sha256.Sum256(key), Key: key}, nil
+}
+
+// ReadEncryptionKeyFile reads the encryption key stored in the file at path,
+// which must contain the 16, 24 or 32 bytes of an AES-128, AES-192 or AES-256
+// key.
+func ReadEncryptionKeyFile(path string) (*EncryptionKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	k, err := NewEncryptionKey(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key file %s", path)
+	}
+	return k, nil
+}
+
+// GenerateEncryptionKeyFile writes a random AES key of the given size in bits
+// to a new file at path, readable only by its owner.
+func GenerateEncryptionKeyFile(path string, bits int) error {
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return errors.Errorf("key size must be 128, 192 or 256 bits, got %d", bits)
+	}
+	key := make([]byte, bits/8)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(key); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// String returns the hex encoded ID of the key.
+func (k *EncryptionKey) String() string {
+	return hex.EncodeToString(k.ID[:])
+}
+
+// EncryptionOptions configure the encryption of the files of a RocksDB store.
+//
+// New files are encrypted with the active key, or written in plaintext if
+// there is none. Existing files can be read if they're plaintext or encrypted
+// with the active key or one of the old keys. Keys are rotated by restarting
+// with a new active key and the previous one as an old key: the files
+// encrypted with the old key are gradually rewritten with the new one as they
+// get compacted.
+type EncryptionOptions struct {
+	ActiveKey *EncryptionKey
+	OldKeys   []*EncryptionKey
+}
+
+func (o EncryptionOptions) enabled() bool {
+	return o.ActiveKey != nil || len(o.OldKeys) > 0
+}
+
+// encodeEncryptionKeys encodes the keys as expected by DBOptions: each key is
+// encoded as its length (one byte), its ID and the key itself.
+func encodeEncryptionKeys(keys ...*EncryptionKey) []byte {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		if k == nil {
+			continue
+		}
+		buf.WriteByte(byte(len(k.Key)))
+		buf.Write(k.ID[:])
+		buf.Write(k.Key)
+	}
+	return buf.Bytes()
+}
+
+// EncryptionKeyUsage is the number of files of a store encrypted with a key,
+// and their size.
+type EncryptionKeyUsage struct {
+	// KeyID is the hex encoded ID of the key, or empty for plaintext files.
+	KeyID string
+	Files int64
+	Bytes int64
+}
+
+// EncryptionStatus describes the encryption of the files of a store.
+type EncryptionStatus struct {
+	// ActiveKeyID is the hex encoded ID of the key new files are encrypted
+	// with, or empty if they're written in plaintext.
+	ActiveKeyID string
+	// Keys holds the usage of each key found in the store, sorted by key ID.
+	// Once the files encrypted with an old key have all been rewritten, the
+	// old key is no longer needed.
+	Keys []EncryptionKeyUsage
+}
+
+// readEncryptionStatus reads the headers of the files in dir to report the
+// keys they're encrypted with. Subdirectories are skipped, as are the files
+// which aren't written through RocksDB.
+func readEncryptionStatus(dir string, activeKey *EncryptionKey) (EncryptionStatus, error) {
+	var status EncryptionStatus
+	if activeKey != nil {
+		status.ActiveKeyID = activeKey.String()
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return EncryptionStatus{}, err
+	}
+	usage := map[string]*EncryptionKeyUsage{}
+	for _, info := range infos {
+		switch name := info.Name(); {
+		case !info.Mode().IsRegular(), name == "LOCK",
+			name == versionFilename, name == versionFilenameTemp:
+			continue
+		}
+		keyID, size, err := readEncryptionHeader(filepath.Join(dir, info.Name()), info.Size())
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The file was deleted in the meantime.
+				continue
+			}
+			return EncryptionStatus{}, err
+		}
+		u, ok := usage[keyID]
+		if !ok {
+			u = &EncryptionKeyUsage{KeyID: keyID}
+			usage[keyID] = u
+		}
+		u.Files++
+		u.Bytes += size
+	}
+	for _, u := range usage {
+		status.Keys = append(status.Keys, *u)
+	}
+	sort.Sort(encryptionKeyUsageByID(status.Keys))
+	return status, nil
+}
+
+type encryptionKeyUsageByID []EncryptionKeyUsage
+
+func (u encryptionKeyUsageByID) Len() int           { return len(u) }
+func (u encryptionKeyUsageByID) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u encryptionKeyUsageByID) Less(i, j int) bool { return u[i].KeyID < u[j].KeyID }
+
+// readEncryptionHeader returns the hex encoded ID of the key the file is
+// encrypted with, or an empty string if it is plaintext, and the size of its
+// contents.
+func readEncryptionHeader(path string, size int64) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	header := make([]byte, encryptionHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", size, nil
+		}
+		return "", 0, err
+	}
+	if !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		return "", size, nil
+	}
+	keyID := header[len(encryptionMagic) : len(encryptionMagic)+encryptionKeyIDSize]
+	return hex.EncodeToString(keyID), size - int64(encryptionHeaderSize), nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/stop"
+)
+
+func TestEncryptionKeyFile(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, err := ioutil.TempDir("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	path := filepath.Join(dir, "key")
+	if err := GenerateEncryptionKeyFile(path, 100); !testutils.IsError(err, "key size must be") {
+		t.Fatalf("expected invalid key size error, got %v", err)
+	}
+	if err := GenerateEncryptionKeyFile(path, 256); err != nil {
+		t.Fatal(err)
+	}
+	// Existing key files are never overwritten.
+	if err := GenerateEncryptionKeyFile(path, 256); !os.IsExist(err) {
+		t.Fatalf("expected file exists error, got %v", err)
+	}
+	key, err := ReadEncryptionKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key.Key) != 32 {
+		t.Errorf("expected a 32 byte key, got %d bytes", len(key.Key))
+	}
+
+	badPath := filepath.Join(dir, "bad")
+	if err := ioutil.WriteFile(badPath, []byte("too short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadEncryptionKeyFile(badPath); !testutils.IsError(err, "invalid key file") {
+		t.Fatalf("expected invalid key file error, got %v", err)
+	}
+}
+
+func TestRocksDBEncryption(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	dir, err := ioutil.TempDir("", "testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	oldKey, err := NewEncryptionKey(bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := NewEncryptionKey(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := mvccKey("a")
+	value := bytes.Repeat([]byte("secret"), 100)
+
+	open := func(encryption EncryptionOptions) (*RocksDB, *stop.Stopper, error) {
+		stopper := stop.NewStopper()
+		db := NewRocksDB(
+			roachpb.Attributes{},
+			dir,
+			RocksDBCache{},
+			minMemtableBudget,
+			0,
+			DefaultMaxOpenFiles,
+			RocksDBTuning{},
+			encryption,
+			stopper,
+		)
+		if err := db.Open(); err != nil {
+			stopper.Stop()
+			return nil, nil, err
+		}
+		return db, stopper, nil
+	}
+
+	// Write the value with the old key and flush it to an sstable.
+	db, stopper, err := open(EncryptionOptions{ActiveKey: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	status, err := db.EncryptionStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ActiveKeyID != oldKey.String() {
+		t.Errorf("expected active key %s, got %s", oldKey, status.ActiveKeyID)
+	}
+	stopper.Stop()
+
+	checkStoreEncrypted(t, dir, value)
+
+	// The files can't be read without the key they were encrypted with.
+	if _, _, err := open(EncryptionOptions{ActiveKey: newKey}); !testutils.IsError(err, "neither the active key nor an old key") {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+
+	// Rotate the key: the files encrypted with the old one remain readable
+	// and are rewritten with the new one as they get compacted.
+	db, stopper, err = open(EncryptionOptions{ActiveKey: newKey, OldKeys: []*EncryptionKey{oldKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopper.Stop()
+	if v, err := db.Get(key); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(v, value) {
+		t.Fatalf("expected %q, got %q", value, v)
+	}
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	status, err = db.EncryptionStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.ActiveKeyID != newKey.String() {
+		t.Errorf("expected active key %s, got %s", newKey, status.ActiveKeyID)
+	}
+	var found bool
+	for _, u := range status.Keys {
+		if u.KeyID == "" {
+			t.Errorf("unexpected plaintext files: %+v", u)
+		}
+		if u.KeyID == newKey.String() && u.Files > 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected files encrypted with %s, got %+v", newKey, status.Keys)
+	}
+	checkStoreEncrypted(t, dir, value)
+}
+
+// checkStoreEncrypted fails the test if any of the files of the store in dir
+// is in plaintext or contains value. The files which aren't written through
+// RocksDB are skipped: the empty lock file and the version file.
+func checkStoreEncrypted(t *testing.T, dir string, value []byte) {
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		switch info.Name() {
+		case "LOCK", versionFilename, versionFilenameTemp:
+			return nil
+		}
+		keyID, _, err := readEncryptionHeader(path, info.Size())
+		if err != nil {
+			return err
+		}
+		if keyID == "" {
+			t.Errorf("%s is in plaintext", path)
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(b, value) {
+			t.Errorf("%s contains the value in plaintext", path)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/protoutil"
 )
 
@@ -119,6 +120,13 @@ type Reader interface {
 	// skipped). The caller must invoke Iterator.Close() when finished with the
 	// iterator to free resources.
 	NewIterator(prefix bool) Iterator
+	// NewTimeBoundIterator returns a new instance of an Iterator over this
+	// engine which may skip the sstables none of whose keys were written in
+	// the time interval [start, end]. Keys outside of the interval may still
+	// be returned and must be filtered by the caller, but no key written in
+	// the interval is skipped, nor is any intent. The caller must invoke
+	// Iterator.Close() when finished with the iterator to free resources.
+	NewTimeBoundIterator(start, end hlc.Timestamp) Iterator
 }
 
 // Writer is the write interface to an engine's data.
@@ -171,6 +179,22 @@ type Engine interface {
 	Flush() error
 	// GetStats retrieves stats from the engine.
 	GetStats() (*Stats, error)
+	// CompactRange forces compaction of the keys in [start, end), reclaiming
+	// the space taken by deleted keys. An empty key leaves the range
+	// unbounded on that side.
+	CompactRange(start, end roachpb.Key) error
+	// EncryptionStatus reports the keys the files of the engine are
+	// encrypted with.
+	EncryptionStatus() (EncryptionStatus, error)
+	// IngestExternalFile links an sstable with the given contents, as built by
+	// RocksDBSstFileWriter, into the engine. The key range of the file must
+	// not overlap any keys already present in the engine.
+	IngestExternalFile(data []byte) error
+	// DeleteRange removes all the entries from start (inclusive) to end
+	// (exclusive) with a single range deletion, whose cost doesn't depend on
+	// the number of entries removed. The deletion is applied directly to the
+	// engine, not to any of its batches.
+	DeleteRange(start, end MVCCKey) error
 	// NewBatch returns a new instance of a batched engine which wraps
 	// this engine. Batched engines accumulate all mutations and apply
 	// them atomically on a call to Commit().
@@ -191,6 +215,12 @@ type Batch interface {
 	// Commit atomically applies any batched updates to the underlying
 	// engine. This is a noop unless the engine was created via NewBatch().
 	Commit() error
+	// DeleteRange removes all the entries from start (inclusive) to end
+	// (exclusive) with a single range deletion, which is applied atomically
+	// with the other updates of the batch when it is committed. Unlike them,
+	// it is not visible to the reads from the batch, and it may be applied
+	// after them, so the batch must not write to the same range.
+	DeleteRange(start, end MVCCKey) error
 	// Distinct returns a view of the existing batch which only sees writes that
 	// were performed before the Distinct batch was created. That is, the
 	// returned batch will not read its own writes, but it will read writes to
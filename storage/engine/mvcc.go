@@ -485,6 +485,8 @@ type getBuffer struct {
 	value            roachpb.Value
 	allowUnsafeValue bool
 	isUnsafeValue    bool
+	// rangeTombstones are the MVCC range tombstones covering the keys read.
+	rangeTombstones rangeTombstones
 }
 
 var getBufferPool = sync.Pool{
@@ -533,15 +535,21 @@ func MVCCGet(
 	consistent bool,
 	txn *roachpb.Transaction,
 ) (*roachpb.Value, []roachpb.Intent, error) {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
-	return mvccGetUsingIter(ctx, iter, key, timestamp, consistent, txn)
+	return mvccGetUsingIter(ctx, iter, rts, key, timestamp, consistent, txn)
 }
 
 func mvccGetUsingIter(
 	ctx context.Context,
 	iter Iterator,
+	rts rangeTombstones,
 	key roachpb.Key,
 	timestamp hlc.Timestamp,
 	consistent bool,
@@ -553,6 +561,7 @@ func mvccGetUsingIter(
 
 	buf := newGetBuffer()
 	defer buf.release()
+	buf.rangeTombstones = rts
 
 	metaKey := MakeMVCCMetadataKey(key)
 	ok, _, _, err := mvccGetMetadata(iter, metaKey, &buf.meta)
@@ -790,6 +799,23 @@ func mvccGetInternal(
 		// already been read above, so there's nothing left to do.
 	}
 
+	if len(buf.rangeTombstones) > 0 {
+		// The version is deleted if a range tombstone was written above it. As
+		// for values, a tombstone in our future up to MaxTimestamp makes the
+		// read uncertain.
+		maxTS := timestamp
+		if txn != nil && !ownIntent && timestamp.Less(txn.MaxTimestamp) {
+			maxTS = txn.MaxTimestamp
+		}
+		if rtTS := buf.rangeTombstones.newestAt(metaKey.Key, maxTS); unsafeKey.Timestamp.Less(rtTS) {
+			if timestamp.Less(rtTS) {
+				return nil, nil, safeValue, roachpb.NewReadWithinUncertaintyIntervalError(
+					timestamp, rtTS)
+			}
+			return nil, ignoredIntents, safeValue, nil
+		}
+	}
+
 	if len(iter.unsafeValue()) == 0 {
 		// Value is deleted.
 		return nil, ignoredIntents, safeValue, nil
@@ -816,6 +842,9 @@ type putBuffer struct {
 	meta    enginepb.MVCCMetadata
 	newMeta enginepb.MVCCMetadata
 	newTxn  enginepb.TxnMeta
+	// rangeTombstones are the MVCC range tombstones covering the keys
+	// written.
+	rangeTombstones rangeTombstones
 }
 
 var putBufferPool = sync.Pool{
@@ -854,16 +883,21 @@ func MVCCPut(
 	value roachpb.Value,
 	txn *roachpb.Transaction,
 ) error {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
-	return mvccPutUsingIter(ctx, engine, iter, ms, key, timestamp, value, txn, nil /* valueFn */)
+	return mvccPutUsingIter(ctx, engine, iter, rts, ms, key, timestamp, value, txn, nil /* valueFn */)
 }
 
 // MVCCBlindPut is a fast-path of MVCCPut. See the MVCCPut comments for details
 // of the semantics. MVCCBlindPut skips retrieving the existing metadata for
 // the key requiring the caller to guarantee no versions for the key currently
-// exist.
+// exist, and that no range tombstone covers it.
 func MVCCBlindPut(
 	ctx context.Context,
 	engine Writer,
@@ -873,7 +907,7 @@ func MVCCBlindPut(
 	value roachpb.Value,
 	txn *roachpb.Transaction,
 ) error {
-	return mvccPutUsingIter(ctx, engine, nil, ms, key, timestamp, value, txn, nil /* valueFn */)
+	return mvccPutUsingIter(ctx, engine, nil, nil, ms, key, timestamp, value, txn, nil /* valueFn */)
 }
 
 // MVCCDelete marks the key deleted so that it will not be returned in
@@ -886,22 +920,28 @@ func MVCCDelete(
 	timestamp hlc.Timestamp,
 	txn *roachpb.Transaction,
 ) error {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
-	return mvccPutUsingIter(ctx, engine, iter, ms, key, timestamp, noValue, txn, nil /* valueFn */)
+	return mvccPutUsingIter(ctx, engine, iter, rts, ms, key, timestamp, noValue, txn, nil /* valueFn */)
 }
 
 var noValue = roachpb.Value{}
 
 // mvccPutUsingIter sets the value for a specified key using the provided
-// Iterator. The function takes a value and a valueFn, only one of which
+// Iterator and the range tombstones covering the key. The function takes a value and a valueFn, only one of which
 // should be provided. If the valueFn is nil, value's raw bytes will be set
 // for the key, else the bytes provided by the valueFn will be used.
 func mvccPutUsingIter(
 	ctx context.Context,
 	engine Writer,
 	iter Iterator,
+	rts rangeTombstones,
 	ms *enginepb.MVCCStats,
 	key roachpb.Key,
 	timestamp hlc.Timestamp,
@@ -918,6 +958,7 @@ func mvccPutUsingIter(
 	}
 
 	buf := newPutBuffer()
+	buf.rangeTombstones = rts
 
 	err := mvccPutInternal(ctx, engine, iter, ms, key, timestamp, rawBytes,
 		txn, buf, valueFn)
@@ -968,6 +1009,7 @@ func mvccPutInternal(
 			getBuf := newGetBuffer()
 			defer getBuf.release()
 			getBuf.meta = buf.meta // initialize get metadata from what we've already read
+			getBuf.rangeTombstones = buf.rangeTombstones
 			if exVal, _, _, err = mvccGetInternal(
 				ctx, iter, metaKey, readTS, true /* consistent */, safeValue, txn, getBuf); err != nil {
 				return nil, err
@@ -1003,76 +1045,74 @@ func mvccPutInternal(
 		return err
 	}
 
+	// The write must happen above both the latest version of the key and the
+	// range tombstones covering it.
+	latestTS := buf.rangeTombstones.newestAt(key, hlc.MaxTimestamp)
 	var meta *enginepb.MVCCMetadata
 	var maybeTooOldErr error
 	if ok {
-		// There is existing metadata for this key; ensure our write is permitted.
 		meta = &buf.meta
-
-		if meta.Txn != nil {
-			// There is an uncommitted write intent.
-			if txn == nil || !roachpb.TxnIDEqual(meta.Txn.ID, txn.ID) {
-				// The current Put operation does not come from the same
-				// transaction.
-				return &roachpb.WriteIntentError{Intents: []roachpb.Intent{{Span: roachpb.Span{Key: key}, Status: roachpb.PENDING, Txn: *meta.Txn}}}
-			} else if txn.Epoch < meta.Txn.Epoch {
-				return errors.Errorf("put with epoch %d came after put with epoch %d in txn %s",
-					txn.Epoch, meta.Txn.Epoch, txn.ID)
-			} else if txn.Sequence < meta.Txn.Sequence ||
-				(txn.Sequence == meta.Txn.Sequence && txn.BatchIndex <= meta.Txn.BatchIndex) {
-				// Replay error if we encounter an older sequence number or
-				// the same (or earlier) batch index for the same sequence.
-				return roachpb.NewTransactionRetryError()
+		latestTS.Forward(meta.Timestamp)
+	}
+	if ok && meta.Txn != nil {
+		// There is an uncommitted write intent; ensure our write is permitted.
+		if txn == nil || !roachpb.TxnIDEqual(meta.Txn.ID, txn.ID) {
+			// The current Put operation does not come from the same
+			// transaction.
+			return &roachpb.WriteIntentError{Intents: []roachpb.Intent{{Span: roachpb.Span{Key: key}, Status: roachpb.PENDING, Txn: *meta.Txn}}}
+		} else if txn.Epoch < meta.Txn.Epoch {
+			return errors.Errorf("put with epoch %d came after put with epoch %d in txn %s",
+				txn.Epoch, meta.Txn.Epoch, txn.ID)
+		} else if txn.Sequence < meta.Txn.Sequence ||
+			(txn.Sequence == meta.Txn.Sequence && txn.BatchIndex <= meta.Txn.BatchIndex) {
+			// Replay error if we encounter an older sequence number or
+			// the same (or earlier) batch index for the same sequence.
+			return roachpb.NewTransactionRetryError()
+		}
+		// Make sure we process valueFn before clearing any earlier
+		// version.  For example, a conditional put within same
+		// transaction should read previous write.
+		if value, err = maybeGetValue(ok, timestamp); err != nil {
+			return err
+		}
+		// We are replacing our own older write intent. If we are
+		// writing at the same timestamp we can simply overwrite it;
+		// otherwise we must explicitly delete the obsolete intent.
+		if !timestamp.Equal(meta.Timestamp) {
+			versionKey := metaKey
+			versionKey.Timestamp = meta.Timestamp
+			if err = engine.Clear(versionKey); err != nil {
+				return err
 			}
-			// Make sure we process valueFn before clearing any earlier
-			// version.  For example, a conditional put within same
-			// transaction should read previous write.
+		}
+	} else if !latestTS.Less(timestamp) {
+		// This is the case where we're trying to write under a
+		// committed value or a range tombstone. Obviously we can't do
+		// that, but we can increment our timestamp to one logical tick
+		// past the existing value and go on to write, but then return a
+		// write-too-old error indicating what the timestamp ended up
+		// being. This timestamp can then be used to increment the txn
+		// timestamp and be returned with the response.
+		actualTimestamp := latestTS.Next()
+		maybeTooOldErr = &roachpb.WriteTooOldError{Timestamp: timestamp, ActualTimestamp: actualTimestamp}
+		// If we're in a transaction, always get the value at the orig
+		// timestamp.
+		if txn != nil {
 			if value, err = maybeGetValue(ok, timestamp); err != nil {
 				return err
 			}
-			// We are replacing our own older write intent. If we are
-			// writing at the same timestamp we can simply overwrite it;
-			// otherwise we must explicitly delete the obsolete intent.
-			if !timestamp.Equal(meta.Timestamp) {
-				versionKey := metaKey
-				versionKey.Timestamp = meta.Timestamp
-				if err = engine.Clear(versionKey); err != nil {
-					return err
-				}
-			}
-		} else if !meta.Timestamp.Less(timestamp) {
-			// This is the case where we're trying to write under a
-			// committed value. Obviously we can't do that, but we can
-			// increment our timestamp to one logical tick past the existing
-			// value and go on to write, but then return a write-too-old
-			// error indicating what the timestamp ended up being. This
-			// timestamp can then be used to increment the txn timestamp and
-			// be returned with the response.
-			actualTimestamp := meta.Timestamp.Next()
-			maybeTooOldErr = &roachpb.WriteTooOldError{Timestamp: timestamp, ActualTimestamp: actualTimestamp}
-			// If we're in a transaction, always get the value at the orig
-			// timestamp.
-			if txn != nil {
-				if value, err = maybeGetValue(ok, timestamp); err != nil {
-					return err
-				}
-			} else {
-				// Outside of a transaction, read the latest value and advance
-				// the write timestamp to the latest value's timestamp + 1. The
-				// new timestamp is returned to the caller in maybeTooOldErr.
-				if value, err = maybeGetValue(ok, actualTimestamp); err != nil {
-					return err
-				}
-			}
-			timestamp = actualTimestamp
 		} else {
-			if value, err = maybeGetValue(ok, timestamp); err != nil {
+			// Outside of a transaction, read the latest value and advance
+			// the write timestamp to the latest value's timestamp + 1. The
+			// new timestamp is returned to the caller in maybeTooOldErr.
+			if value, err = maybeGetValue(ok, actualTimestamp); err != nil {
 				return err
 			}
 		}
+		timestamp = actualTimestamp
 	} else {
-		// There is no existing value for this key. Even if the new value is
-		// nil write a deletion tombstone for the key.
+		// Our write is above any existing value for this key. Even if the
+		// new value is nil write a deletion tombstone for the key.
 		if value, err = maybeGetValue(ok, timestamp); err != nil {
 			return err
 		}
@@ -1137,11 +1177,16 @@ func MVCCIncrement(
 	txn *roachpb.Transaction,
 	inc int64,
 ) (int64, error) {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return 0, err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
 	var int64Val int64
-	err := mvccPutUsingIter(ctx, engine, iter, ms, key, timestamp, noValue, txn, func(value *roachpb.Value) ([]byte, error) {
+	err = mvccPutUsingIter(ctx, engine, iter, rts, ms, key, timestamp, noValue, txn, func(value *roachpb.Value) ([]byte, error) {
 		if value != nil {
 			var err error
 			if int64Val, err = value.GetInt(); err != nil {
@@ -1180,17 +1225,22 @@ func MVCCConditionalPut(
 	expVal *roachpb.Value,
 	txn *roachpb.Transaction,
 ) error {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
-	return mvccConditionalPutUsingIter(ctx, engine, iter, ms, key, timestamp, value, expVal, txn)
+	return mvccConditionalPutUsingIter(ctx, engine, iter, rts, ms, key, timestamp, value, expVal, txn)
 }
 
 // MVCCBlindConditionalPut is a fast-path of MVCCConditionalPut. See the
 // MVCCConditionalPut comments for details of the
 // semantics. MVCCBlindConditionalPut skips retrieving the existing metadata
 // for the key requiring the caller to guarantee no versions for the key
-// currently exist.
+// currently exist, and that no range tombstone covers it.
 func MVCCBlindConditionalPut(
 	ctx context.Context,
 	engine Writer,
@@ -1201,13 +1251,14 @@ func MVCCBlindConditionalPut(
 	expVal *roachpb.Value,
 	txn *roachpb.Transaction,
 ) error {
-	return mvccConditionalPutUsingIter(ctx, engine, nil, ms, key, timestamp, value, expVal, txn)
+	return mvccConditionalPutUsingIter(ctx, engine, nil, nil, ms, key, timestamp, value, expVal, txn)
 }
 
 func mvccConditionalPutUsingIter(
 	ctx context.Context,
 	engine Writer,
 	iter Iterator,
+	rts rangeTombstones,
 	ms *enginepb.MVCCStats,
 	key roachpb.Key,
 	timestamp hlc.Timestamp,
@@ -1216,7 +1267,7 @@ func mvccConditionalPutUsingIter(
 	txn *roachpb.Transaction,
 ) error {
 	return mvccPutUsingIter(
-		ctx, engine, iter, ms, key, timestamp, noValue, txn,
+		ctx, engine, iter, rts, ms, key, timestamp, noValue, txn,
 		func(existVal *roachpb.Value) ([]byte, error) {
 			if expValPresent, existValPresent := expVal != nil, existVal != nil; expValPresent && existValPresent {
 				// Every type flows through here, so we can't use the typed getters.
@@ -1248,10 +1299,15 @@ func MVCCInitPut(
 	value roachpb.Value,
 	txn *roachpb.Transaction,
 ) error {
+	rts, err := mvccGetRangeTombstonesAt(engine, key, timestamp)
+	if err != nil {
+		return err
+	}
+
 	iter := engine.NewIterator(true)
 	defer iter.Close()
 
-	err := mvccPutUsingIter(ctx, engine, iter, ms, key, timestamp, noValue, txn,
+	err = mvccPutUsingIter(ctx, engine, iter, rts, ms, key, timestamp, noValue, txn,
 		func(existVal *roachpb.Value) ([]byte, error) {
 			if existVal != nil {
 				if !bytes.Equal(value.RawBytes, existVal.RawBytes) {
@@ -1333,6 +1389,13 @@ func MVCCDeleteRange(
 	var resumeKey roachpb.Key
 	var num int64
 	buf := newPutBuffer()
+	if timestamp != hlc.ZeroTimestamp {
+		var err error
+		if buf.rangeTombstones, err = mvccGetRangeTombstones(engine, key, endKey); err != nil {
+			buf.release()
+			return nil, nil, 0, err
+		}
+	}
 	iter := engine.NewIterator(true)
 	f := func(kv roachpb.KeyValue) (bool, error) {
 		if num == max {
@@ -1437,28 +1500,34 @@ func mvccScanInternal(
 	key,
 	endKey roachpb.Key,
 	max int64,
+	targetBytes int64,
 	timestamp hlc.Timestamp,
 	consistent bool,
 	txn *roachpb.Transaction,
 	reverse bool,
-) ([]roachpb.KeyValue, []roachpb.Intent, error) {
+) ([]roachpb.KeyValue, int64, []roachpb.Intent, error) {
 	var res []roachpb.KeyValue
+	var numBytes int64
 	if max == 0 {
-		return nil, nil, nil
+		return nil, 0, nil, nil
 	}
 	intents, err := MVCCIterate(ctx, engine, key, endKey, timestamp, consistent, txn, reverse,
 		func(kv roachpb.KeyValue) (bool, error) {
 			res = append(res, kv)
+			numBytes += int64(len(kv.Key) + len(kv.Value.RawBytes))
 			if int64(len(res)) == max {
 				return true, nil
 			}
+			if targetBytes > 0 && numBytes >= targetBytes {
+				return true, nil
+			}
 			return false, nil
 		})
 
 	if err != nil {
-		return nil, nil, err
+		return nil, 0, nil, err
 	}
-	return res, intents, nil
+	return res, numBytes, intents, nil
 }
 
 // MVCCScan scans the key range [start,end) key up to some maximum number of
@@ -1473,8 +1542,9 @@ func MVCCScan(
 	consistent bool,
 	txn *roachpb.Transaction,
 ) ([]roachpb.KeyValue, []roachpb.Intent, error) {
-	return mvccScanInternal(ctx, engine, key, endKey, max, timestamp,
+	res, _, intents, err := mvccScanInternal(ctx, engine, key, endKey, max, 0, timestamp,
 		consistent, txn, false /* !reverse */)
+	return res, intents, err
 }
 
 // MVCCReverseScan scans the key range [start,end) key up to some maximum number of
@@ -1489,8 +1559,30 @@ func MVCCReverseScan(
 	consistent bool,
 	txn *roachpb.Transaction,
 ) ([]roachpb.KeyValue, []roachpb.Intent, error) {
-	return mvccScanInternal(ctx, engine, key, endKey, max, timestamp,
+	res, _, intents, err := mvccScanInternal(ctx, engine, key, endKey, max, 0, timestamp,
 		consistent, txn, true /* reverse */)
+	return res, intents, err
+}
+
+// MVCCScanWithTargetBytes is like MVCCScan, or MVCCReverseScan if reverse
+// is set, but additionally stops after the first row which brings the size
+// of the results, counting both keys and values, to targetBytes or more. A
+// targetBytes of zero means no limit. The size of the results is returned
+// along with them.
+func MVCCScanWithTargetBytes(
+	ctx context.Context,
+	engine Reader,
+	key,
+	endKey roachpb.Key,
+	max int64,
+	targetBytes int64,
+	timestamp hlc.Timestamp,
+	consistent bool,
+	txn *roachpb.Transaction,
+	reverse bool,
+) ([]roachpb.KeyValue, int64, []roachpb.Intent, error) {
+	return mvccScanInternal(ctx, engine, key, endKey, max, targetBytes, timestamp,
+		consistent, txn, reverse)
 }
 
 // MVCCIterate iterates over the key range [start,end). At each step of the
@@ -1516,6 +1608,12 @@ func MVCCIterate(ctx context.Context,
 
 	buf := newGetBuffer()
 	defer buf.release()
+	if timestamp != hlc.ZeroTimestamp {
+		var err error
+		if buf.rangeTombstones, err = mvccGetRangeTombstones(engine, startKey, endKey); err != nil {
+			return nil, err
+		}
+	}
 
 	// getMetaFunc is used to get the meta and the meta key of the current
 	// row. encEndKey is used to judge whether iterator exceeds the boundary or
@@ -1660,6 +1758,83 @@ func MVCCIterate(ctx context.Context,
 	return intents, wiErr
 }
 
+// MVCCIterateRevisions iterates over the revisions of the keys in the range
+// [startKey,endKey) written in the time interval (startTime,endTime], in key
+// order and, for each key, from the newest revision to the oldest. At each
+// step, f() is invoked with the key and the revision, whose value has no
+// bytes if it is a deletion. Inline values and the provisional values of
+// intents are skipped. Intents written at or before endTime, which may or may
+// not end up as revisions in the interval, are returned together in a
+// WriteIntentError once the iteration is done.
+func MVCCIterateRevisions(
+	engine Reader,
+	startKey, endKey roachpb.Key,
+	startTime, endTime hlc.Timestamp,
+	f func(roachpb.KeyValue) error,
+) error {
+	if len(endKey) == 0 {
+		return emptyKeyError()
+	}
+
+	// Unless all the revisions are requested, skip the sstables which were
+	// entirely written outside of the interval.
+	var iter Iterator
+	if startTime == hlc.ZeroTimestamp {
+		iter = engine.NewIterator(false)
+	} else {
+		iter = engine.NewTimeBoundIterator(startTime.Next(), endTime)
+	}
+	defer iter.Close()
+
+	var meta enginepb.MVCCMetadata
+	var intents []roachpb.Intent
+	// The key and timestamp of the last intent, whose provisional value is
+	// skipped.
+	var intentKey roachpb.Key
+	var intentTS hlc.Timestamp
+	encEndKey := MakeMVCCMetadataKey(endKey)
+	for iter.Seek(MakeMVCCMetadataKey(startKey)); iter.Valid(); iter.Next() {
+		if !iter.Less(encEndKey) {
+			break
+		}
+		key := iter.Key()
+		if !key.IsValue() {
+			if err := iter.ValueProto(&meta); err != nil {
+				return err
+			}
+			if meta.Txn != nil {
+				intentKey = append(intentKey[:0], key.Key...)
+				intentTS = meta.Timestamp
+				if !endTime.Less(meta.Timestamp) {
+					intents = append(intents, roachpb.Intent{
+						Span: roachpb.Span{Key: key.Key}, Status: roachpb.PENDING, Txn: *meta.Txn,
+					})
+				}
+			}
+			continue
+		}
+		if key.Timestamp == intentTS && key.Key.Equal(intentKey) {
+			continue
+		}
+		if !startTime.Less(key.Timestamp) || endTime.Less(key.Timestamp) {
+			continue
+		}
+		if err := f(roachpb.KeyValue{
+			Key:   key.Key,
+			Value: roachpb.Value{RawBytes: iter.Value(), Timestamp: key.Timestamp},
+		}); err != nil {
+			return err
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(intents) > 0 {
+		return &roachpb.WriteIntentError{Intents: intents}
+	}
+	return nil
+}
+
 // MVCCResolveWriteIntent either commits or aborts (rolls back) an
 // extant write intent for a given txn according to commit parameter.
 // ResolveWriteIntent will skip write intents of other txns.
@@ -0,0 +1,340 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/util/hlc"
+)
+
+// MVCC range tombstones delete all the keys of a span at a timestamp with a
+// write whose size doesn't depend on the number of keys covered. Every
+// version of a key below the timestamp of a covering tombstone reads as
+// deleted, and writes to a covered key must happen above it.
+//
+// The tombstones are stored as non-overlapping fragments. All the
+// tombstones covering a fragment are versions of the key
+// keys.RangeTombstoneKey(start), where start is the start key of the
+// fragment, and the value of each version is the end key of the fragment.
+// Writing a tombstone which partially overlaps existing fragments splits
+// them at its boundaries.
+//
+// The fragments are range-local keys, and are accounted for in the system
+// stats of the range. The stats of the keys covered by a tombstone are left
+// unchanged, i.e. they're accounted for as if the tombstone didn't exist,
+// until they're garbage collected.
+//
+// Tombstones don't apply to inline values, and can't cover local keys.
+
+// rangeTombstone is a fragment of the MVCC range tombstones.
+type rangeTombstone struct {
+	span roachpb.Span
+	// The timestamps of the tombstones covering the fragment, newest first.
+	timestamps []hlc.Timestamp
+}
+
+// sysStats returns the contribution of the fragment to the system stats.
+func (rt rangeTombstone) sysStats() enginepb.MVCCStats {
+	metaKey := MakeMVCCMetadataKey(keys.RangeTombstoneKey(roachpb.RKey(rt.span.Key)))
+	valSize := int64(len(makeRangeTombstoneValue(rt.span.EndKey).RawBytes))
+	return enginepb.MVCCStats{
+		SysBytes: int64(metaKey.EncodedSize()) +
+			int64(len(rt.timestamps))*(mvccVersionTimestampSize+valSize),
+		SysCount: 1,
+	}
+}
+
+// rangeTombstones is a sorted slice of fragments.
+type rangeTombstones []rangeTombstone
+
+// newestAt returns the timestamp of the newest tombstone covering the
+// specified key at or below timestamp, or the zero timestamp if there is
+// none.
+func (rts rangeTombstones) newestAt(key roachpb.Key, timestamp hlc.Timestamp) hlc.Timestamp {
+	i := sort.Search(len(rts), func(i int) bool {
+		return bytes.Compare(key, rts[i].span.EndKey) < 0
+	})
+	if i == len(rts) || bytes.Compare(key, rts[i].span.Key) < 0 {
+		return hlc.ZeroTimestamp
+	}
+	for _, ts := range rts[i].timestamps {
+		if !timestamp.Less(ts) {
+			return ts
+		}
+	}
+	return hlc.ZeroTimestamp
+}
+
+// keysByOrder sorts keys in ascending order.
+type keysByOrder []roachpb.Key
+
+func (k keysByOrder) Len() int           { return len(k) }
+func (k keysByOrder) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+func (k keysByOrder) Less(i, j int) bool { return bytes.Compare(k[i], k[j]) < 0 }
+
+func makeRangeTombstoneValue(endKey roachpb.Key) roachpb.Value {
+	return roachpb.MakeValueFromBytes(endKey)
+}
+
+// needsRangeTombstones returns whether writes to or reads from key at the
+// specified timestamp are affected by range tombstones.
+func needsRangeTombstones(key roachpb.Key, timestamp hlc.Timestamp) bool {
+	return timestamp != hlc.ZeroTimestamp && bytes.Compare(key, keys.LocalMax) >= 0
+}
+
+// mvccGetRangeTombstones returns the fragments overlapping the span
+// [start, end), in order.
+func mvccGetRangeTombstones(engine Reader, start, end roachpb.Key) (rangeTombstones, error) {
+	if bytes.Compare(start, keys.LocalMax) < 0 {
+		start = keys.LocalMax
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return nil, nil
+	}
+
+	iter := engine.NewIterator(false)
+	defer iter.Close()
+
+	// A fragment starting before start may still cover it.
+	scanStart := start
+	iter.SeekReverse(MakeMVCCMetadataKey(keys.RangeTombstoneKey(roachpb.RKey(start))))
+	if iter.Valid() {
+		unsafeKey := iter.unsafeKey()
+		if bytes.HasPrefix(unsafeKey.Key, keys.LocalRangeTombstonePrefix) {
+			endKey, err := roachpb.Value{RawBytes: iter.unsafeValue()}.GetBytes()
+			if err != nil {
+				return nil, err
+			}
+			if bytes.Compare(start, endKey) < 0 {
+				rtStart, err := keys.DecodeRangeTombstoneKey(unsafeKey.Key)
+				if err != nil {
+					return nil, err
+				}
+				scanStart = append(roachpb.Key(nil), rtStart...)
+			}
+		}
+	} else if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	var rts rangeTombstones
+	encEndKey := MakeMVCCMetadataKey(keys.RangeTombstoneKey(roachpb.RKey(end)))
+	for iter.Seek(MakeMVCCMetadataKey(keys.RangeTombstoneKey(roachpb.RKey(scanStart)))); iter.Valid(); iter.Next() {
+		if !iter.Less(encEndKey) {
+			break
+		}
+		key := iter.Key()
+		rtStart, err := keys.DecodeRangeTombstoneKey(key.Key)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(rts); n > 0 && rts[n-1].span.Key.Equal(rtStart.AsRawKey()) {
+			rts[n-1].timestamps = append(rts[n-1].timestamps, key.Timestamp)
+			continue
+		}
+		endKey, err := roachpb.Value{RawBytes: iter.Value()}.GetBytes()
+		if err != nil {
+			return nil, err
+		}
+		rts = append(rts, rangeTombstone{
+			span:       roachpb.Span{Key: rtStart.AsRawKey(), EndKey: endKey},
+			timestamps: []hlc.Timestamp{key.Timestamp},
+		})
+	}
+	return rts, iter.Error()
+}
+
+// mvccGetRangeTombstonesAt returns the fragment covering key, if reads and
+// writes at the specified timestamp are affected by range tombstones.
+func mvccGetRangeTombstonesAt(
+	engine Reader, key roachpb.Key, timestamp hlc.Timestamp,
+) (rangeTombstones, error) {
+	if !needsRangeTombstones(key, timestamp) {
+		return nil, nil
+	}
+	return mvccGetRangeTombstones(engine, key, key.Next())
+}
+
+// mvccPutRangeTombstones replaces the fragments oldRTs with the fragments
+// newRTs, and updates the stats accordingly.
+func mvccPutRangeTombstones(
+	engine Writer, ms *enginepb.MVCCStats, oldRTs, newRTs rangeTombstones,
+) error {
+	for _, rt := range oldRTs {
+		key := MVCCKey{Key: keys.RangeTombstoneKey(roachpb.RKey(rt.span.Key))}
+		for _, ts := range rt.timestamps {
+			key.Timestamp = ts
+			if err := engine.Clear(key); err != nil {
+				return err
+			}
+		}
+		if ms != nil {
+			ms.Subtract(rt.sysStats())
+		}
+	}
+	for _, rt := range newRTs {
+		key := MVCCKey{Key: keys.RangeTombstoneKey(roachpb.RKey(rt.span.Key))}
+		value := makeRangeTombstoneValue(rt.span.EndKey)
+		for _, ts := range rt.timestamps {
+			key.Timestamp = ts
+			if err := engine.Put(key, value.RawBytes); err != nil {
+				return err
+			}
+		}
+		if ms != nil {
+			ms.Add(rt.sysStats())
+		}
+	}
+	return nil
+}
+
+// MVCCDeleteRangeUsingTombstone deletes all the keys in the span [startKey,
+// endKey) at the specified timestamp by writing an MVCC range tombstone.
+// Unlike MVCCDeleteRange, it doesn't write a deletion tombstone for each
+// key, and it can't be used within a transaction.
+//
+// An intent in the span results in a WriteIntentError. If a key in the span
+// was written at or above timestamp, or the span is already covered by a
+// tombstone at or above timestamp, the tombstone is written one logical tick
+// above the newest of them and a WriteTooOldError is returned, as for
+// non-transactional puts.
+func MVCCDeleteRangeUsingTombstone(
+	ctx context.Context,
+	engine ReadWriter,
+	ms *enginepb.MVCCStats,
+	startKey, endKey roachpb.Key,
+	timestamp hlc.Timestamp,
+) error {
+	if bytes.Compare(startKey, endKey) >= 0 {
+		return errors.Errorf("invalid range tombstone span [%s,%s)", startKey, endKey)
+	}
+	if bytes.Compare(startKey, keys.LocalMax) < 0 {
+		return errors.Errorf("range tombstone cannot cover local key %s", startKey)
+	}
+	if timestamp == hlc.ZeroTimestamp {
+		return errors.Errorf("range tombstone requires a timestamp")
+	}
+
+	// Look for intents and for versions at or above the timestamp. Only the
+	// sstables which may contain such keys need to be read.
+	var intents []roachpb.Intent
+	var latest hlc.Timestamp
+	iter := engine.NewTimeBoundIterator(timestamp, hlc.MaxTimestamp)
+	encEndKey := MakeMVCCMetadataKey(endKey)
+	var meta enginepb.MVCCMetadata
+	for iter.Seek(MakeMVCCMetadataKey(startKey)); iter.Valid(); iter.Next() {
+		if !iter.Less(encEndKey) {
+			break
+		}
+		unsafeKey := iter.unsafeKey()
+		if unsafeKey.IsValue() {
+			if !unsafeKey.Timestamp.Less(timestamp) {
+				latest.Forward(unsafeKey.Timestamp)
+			}
+			continue
+		}
+		if err := iter.ValueProto(&meta); err != nil {
+			iter.Close()
+			return err
+		}
+		if meta.Txn != nil {
+			intents = append(intents, roachpb.Intent{
+				Span: roachpb.Span{Key: iter.Key().Key}, Status: roachpb.PENDING, Txn: *meta.Txn,
+			})
+		}
+	}
+	err := iter.Error()
+	iter.Close()
+	if err != nil {
+		return err
+	}
+	if len(intents) > 0 {
+		return &roachpb.WriteIntentError{Intents: intents}
+	}
+
+	oldRTs, err := mvccGetRangeTombstones(engine, startKey, endKey)
+	if err != nil {
+		return err
+	}
+	for _, rt := range oldRTs {
+		if !rt.timestamps[0].Less(timestamp) {
+			latest.Forward(rt.timestamps[0])
+		}
+	}
+	var tooOldErr error
+	if latest != hlc.ZeroTimestamp {
+		tooOldErr = &roachpb.WriteTooOldError{Timestamp: timestamp, ActualTimestamp: latest.Next()}
+		timestamp = latest.Next()
+	}
+
+	// Split the existing fragments at the boundaries of the tombstone, and
+	// fill the gaps between them with new fragments.
+	bounds := []roachpb.Key{startKey, endKey}
+	for _, rt := range oldRTs {
+		bounds = append(bounds, rt.span.Key, rt.span.EndKey)
+	}
+	sort.Sort(keysByOrder(bounds))
+	var newRTs rangeTombstones
+	var oldIdx int
+	for i := 1; i < len(bounds); i++ {
+		span := roachpb.Span{Key: bounds[i-1], EndKey: bounds[i]}
+		if bytes.Equal(span.Key, span.EndKey) {
+			continue
+		}
+		for oldIdx < len(oldRTs) && bytes.Compare(oldRTs[oldIdx].span.EndKey, span.EndKey) < 0 {
+			oldIdx++
+		}
+		var timestamps []hlc.Timestamp
+		if bytes.Compare(span.Key, startKey) >= 0 && bytes.Compare(span.EndKey, endKey) <= 0 {
+			timestamps = append(timestamps, timestamp)
+		}
+		if oldIdx < len(oldRTs) && bytes.Compare(oldRTs[oldIdx].span.Key, span.Key) <= 0 {
+			timestamps = append(timestamps, oldRTs[oldIdx].timestamps...)
+		}
+		if len(timestamps) > 0 {
+			newRTs = append(newRTs, rangeTombstone{span: span, timestamps: timestamps})
+		}
+	}
+	if err := mvccPutRangeTombstones(engine, ms, oldRTs, newRTs); err != nil {
+		return err
+	}
+	return tooOldErr
+}
+
+// MVCCSplitRangeTombstones splits the fragment of the MVCC range tombstones
+// spanning splitKey, if any, so that no fragment crosses the boundary
+// between the two sides of a range split at splitKey.
+func MVCCSplitRangeTombstones(
+	ctx context.Context, engine ReadWriter, ms *enginepb.MVCCStats, splitKey roachpb.Key,
+) error {
+	oldRTs, err := mvccGetRangeTombstones(engine, splitKey, splitKey.Next())
+	if err != nil || len(oldRTs) == 0 || oldRTs[0].span.Key.Equal(splitKey) {
+		return err
+	}
+	rt := oldRTs[0]
+	newRTs := rangeTombstones{
+		{span: roachpb.Span{Key: rt.span.Key, EndKey: splitKey}, timestamps: rt.timestamps},
+		{span: roachpb.Span{Key: splitKey, EndKey: rt.span.EndKey}, timestamps: rt.timestamps},
+	}
+	return mvccPutRangeTombstones(engine, ms, oldRTs, newRTs)
+}
@@ -1230,6 +1230,60 @@ func TestMVCCScanMaxNum(t *testing.T) {
 	}
 }
 
+func TestMVCCScanWithTargetBytes(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	engine := createTestEngine(stopper)
+
+	for _, kv := range []struct {
+		key   roachpb.Key
+		value roachpb.Value
+	}{{testKey1, value1}, {testKey2, value2}, {testKey3, value3}, {testKey4, value4}} {
+		if err := MVCCPut(context.Background(), engine, nil, kv.key, makeTS(1, 0), kv.value, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rowSize := func(key roachpb.Key, value roachpb.Value) int64 {
+		return int64(len(key) + len(value.RawBytes))
+	}
+
+	testCases := []struct {
+		targetBytes int64
+		reverse     bool
+		expKeys     []roachpb.Key
+	}{
+		// No limit.
+		{0, false, []roachpb.Key{testKey1, testKey2, testKey3, testKey4}},
+		// Any limit returns at least one row.
+		{1, false, []roachpb.Key{testKey1}},
+		{1, true, []roachpb.Key{testKey4}},
+		// A limit reached exactly by a row stops the scan after that row.
+		{rowSize(testKey1, value1), false, []roachpb.Key{testKey1}},
+		{rowSize(testKey1, value1) + 1, false, []roachpb.Key{testKey1, testKey2}},
+		{rowSize(testKey4, value4) + rowSize(testKey3, value3), true, []roachpb.Key{testKey4, testKey3}},
+	}
+	for i, c := range testCases {
+		kvs, numBytes, _, err := MVCCScanWithTargetBytes(context.Background(), engine, testKey1,
+			testKey4.Next(), math.MaxInt64, c.targetBytes, makeTS(1, 0), true, nil, c.reverse)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []roachpb.Key
+		var expBytes int64
+		for _, kv := range kvs {
+			keys = append(keys, kv.Key)
+			expBytes += rowSize(kv.Key, kv.Value)
+		}
+		if !reflect.DeepEqual(keys, c.expKeys) {
+			t.Errorf("%d: expected keys %s, got %s", i, c.expKeys, keys)
+		}
+		if numBytes != expBytes {
+			t.Errorf("%d: expected %d bytes, got %d", i, expBytes, numBytes)
+		}
+	}
+}
+
 func TestMVCCScanWithKeyPrefix(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	stopper := stop.NewStopper()
@@ -1266,6 +1320,97 @@ func TestMVCCScanWithKeyPrefix(t *testing.T) {
 	}
 }
 
+func TestMVCCIterateRevisions(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	engine := createTestEngine(stopper)
+
+	ctx := context.Background()
+	if err := MVCCPut(ctx, engine, nil, testKey1, makeTS(1, 0), value1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey1, makeTS(2, 0), value2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey2, makeTS(2, 0), value3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCDelete(ctx, engine, nil, testKey2, makeTS(3, 0), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey3, makeTS(4, 0), value4, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	type revision struct {
+		key   roachpb.Key
+		ts    hlc.Timestamp
+		value []byte
+	}
+	testCases := []struct {
+		start, end hlc.Timestamp
+		expected   []revision
+	}{
+		{makeTS(0, 0), makeTS(4, 0), []revision{
+			{testKey1, makeTS(2, 0), value2.RawBytes},
+			{testKey1, makeTS(1, 0), value1.RawBytes},
+			{testKey2, makeTS(3, 0), nil},
+			{testKey2, makeTS(2, 0), value3.RawBytes},
+			{testKey3, makeTS(4, 0), value4.RawBytes},
+		}},
+		{makeTS(1, 0), makeTS(3, 0), []revision{
+			{testKey1, makeTS(2, 0), value2.RawBytes},
+			{testKey2, makeTS(3, 0), nil},
+			{testKey2, makeTS(2, 0), value3.RawBytes},
+		}},
+		{makeTS(3, 0), makeTS(3, 0), nil},
+	}
+	for i, tc := range testCases {
+		var revisions []revision
+		if err := MVCCIterateRevisions(engine, testKey1, testKey4, tc.start, tc.end,
+			func(kv roachpb.KeyValue) error {
+				revisions = append(revisions, revision{kv.Key, kv.Value.Timestamp, kv.Value.RawBytes})
+				return nil
+			}); err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != len(tc.expected) {
+			t.Fatalf("%d: expected %d revisions, got %+v", i, len(tc.expected), revisions)
+		}
+		for j, r := range revisions {
+			e := tc.expected[j]
+			if !r.key.Equal(e.key) || r.ts != e.ts || !bytes.Equal(r.value, e.value) {
+				t.Errorf("%d: expected revision %d to be %+v, got %+v", i, j, e, r)
+			}
+		}
+	}
+
+	// Intents written at or before the end of the interval are reported.
+	if err := MVCCPut(ctx, engine, nil, testKey2, makeTS(5, 0), value5, makeTxn(*txn1, makeTS(5, 0))); err != nil {
+		t.Fatal(err)
+	}
+	noop := func(roachpb.KeyValue) error { return nil }
+	if err := MVCCIterateRevisions(engine, testKey1, testKey4, makeTS(0, 0), makeTS(4, 0), noop); err != nil {
+		t.Fatal(err)
+	}
+	// The provisional value of the intent is skipped.
+	var n int
+	err := MVCCIterateRevisions(engine, testKey1, testKey4, makeTS(4, 0), makeTS(5, 0),
+		func(roachpb.KeyValue) error {
+			n++
+			return nil
+		})
+	if n != 0 {
+		t.Errorf("expected no revisions, got %d", n)
+	}
+	err = MVCCIterateRevisions(engine, testKey1, testKey4, makeTS(0, 0), makeTS(5, 0), noop)
+	if wiErr, ok := err.(*roachpb.WriteIntentError); !ok || len(wiErr.Intents) != 1 ||
+		!wiErr.Intents[0].Key.Equal(testKey2) {
+		t.Fatalf("expected a WriteIntentError for %s, got %v", testKey2, err)
+	}
+}
+
 func TestMVCCScanInTxn(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	stopper := stop.NewStopper()
@@ -1667,6 +1812,147 @@ func TestMVCCDeleteRangeConcurrentTxn(t *testing.T) {
 	}
 }
 
+func TestMVCCDeleteRangeUsingTombstone(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	engine := createTestEngine(stopper)
+	ctx := context.Background()
+
+	for _, kv := range []struct {
+		key   roachpb.Key
+		ts    hlc.Timestamp
+		value roachpb.Value
+	}{
+		{testKey1, makeTS(1, 0), value1},
+		{testKey2, makeTS(1, 0), value2},
+		{testKey3, makeTS(1, 0), value3},
+		{testKey4, makeTS(1, 0), value4},
+	} {
+		if err := MVCCPut(ctx, engine, nil, kv.key, kv.ts, kv.value, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ms enginepb.MVCCStats
+	if err := MVCCDeleteRangeUsingTombstone(ctx, engine, &ms, testKey1, testKey3, makeTS(2, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := MVCCPut(ctx, engine, nil, testKey2, makeTS(3, 0), value2, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expectGet := func(key roachpb.Key, ts hlc.Timestamp, expValue *roachpb.Value) {
+		value, _, err := MVCCGet(ctx, engine, key, ts, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expValue == nil {
+			if value != nil {
+				t.Errorf("%s@%s: expected no value, got %s", key, ts, value)
+			}
+		} else if value == nil || !bytes.Equal(value.RawBytes, expValue.RawBytes) {
+			t.Errorf("%s@%s: expected %s, got %v", key, ts, expValue, value)
+		}
+	}
+	expectScan := func(ts hlc.Timestamp, expKeys ...roachpb.Key) {
+		kvs, _, err := MVCCScan(ctx, engine, keyMin, keyMax, math.MaxInt64, ts, true, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actualKeys []roachpb.Key
+		for _, kv := range kvs {
+			actualKeys = append(actualKeys, kv.Key)
+		}
+		if !reflect.DeepEqual(actualKeys, expKeys) {
+			t.Errorf("@%s: expected keys %s, got %s", ts, expKeys, actualKeys)
+		}
+	}
+
+	// The versions below the tombstone are deleted, but remain visible to
+	// historical reads.
+	expectGet(testKey1, makeTS(1, 0), &value1)
+	expectGet(testKey1, makeTS(2, 0), nil)
+	expectGet(testKey2, makeTS(2, 0), nil)
+	expectGet(testKey2, makeTS(3, 0), &value2)
+	expectGet(testKey3, makeTS(2, 0), &value3)
+	expectScan(makeTS(1, 0), testKey1, testKey2, testKey3, testKey4)
+	expectScan(makeTS(2, 0), testKey3, testKey4)
+	expectScan(makeTS(3, 0), testKey2, testKey3, testKey4)
+
+	// A tombstone in the future of a transactional read makes it uncertain.
+	txn := txn1.Clone()
+	txn.Timestamp = makeTS(1, 0)
+	txn.MaxTimestamp = makeTS(2, 0)
+	if _, _, err := MVCCGet(ctx, engine, testKey1, makeTS(1, 0), true, &txn); err == nil {
+		t.Fatal("expected uncertainty error")
+	} else if _, ok := err.(*roachpb.ReadWithinUncertaintyIntervalError); !ok {
+		t.Fatalf("wanted a ReadWithinUncertaintyIntervalError, got %+v", err)
+	}
+
+	// Writes under the tombstone are pushed above it.
+	err := MVCCPut(ctx, engine, nil, testKey1, makeTS(2, 0), value1, nil)
+	if wtoErr, ok := err.(*roachpb.WriteTooOldError); !ok || !wtoErr.ActualTimestamp.Equal(makeTS(2, 1)) {
+		t.Fatalf("expected write too old error with actual timestamp %s, got %v", makeTS(2, 1), err)
+	}
+	expectGet(testKey1, makeTS(2, 0), nil)
+	expectGet(testKey1, makeTS(2, 1), &value1)
+
+	// A tombstone under a newer version is pushed above it as well, and
+	// splits the fragment it overlaps.
+	err = MVCCDeleteRangeUsingTombstone(ctx, engine, &ms, testKey2, testKey5, makeTS(2, 0))
+	if wtoErr, ok := err.(*roachpb.WriteTooOldError); !ok || !wtoErr.ActualTimestamp.Equal(makeTS(3, 1)) {
+		t.Fatalf("expected write too old error with actual timestamp %s, got %v", makeTS(3, 1), err)
+	}
+	expectScan(makeTS(3, 0), testKey1, testKey2, testKey3, testKey4)
+	expectScan(makeTS(3, 1), testKey1)
+	rts, err := mvccGetRangeTombstones(engine, keyMin, keyMax)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expRTs := rangeTombstones{
+		{span: roachpb.Span{Key: testKey1, EndKey: testKey2}, timestamps: []hlc.Timestamp{makeTS(2, 0)}},
+		{span: roachpb.Span{Key: testKey2, EndKey: testKey3}, timestamps: []hlc.Timestamp{makeTS(3, 1), makeTS(2, 0)}},
+		{span: roachpb.Span{Key: testKey3, EndKey: testKey5}, timestamps: []hlc.Timestamp{makeTS(3, 1)}},
+	}
+	if !reflect.DeepEqual(rts, expRTs) {
+		t.Errorf("expected range tombstones %+v, got %+v", expRTs, rts)
+	}
+
+	// Splits preserve the deleted keys.
+	if err := MVCCSplitRangeTombstones(ctx, engine, &ms, testKey4); err != nil {
+		t.Fatal(err)
+	}
+	expectScan(makeTS(3, 1), testKey1)
+	if rts, err = mvccGetRangeTombstones(engine, testKey4, testKey5); err != nil {
+		t.Fatal(err)
+	} else if len(rts) != 1 || !rts[0].span.Key.Equal(testKey4) {
+		t.Errorf("expected fragment starting at %s, got %+v", testKey4, rts)
+	}
+
+	// Intents can't be deleted.
+	if err := MVCCPut(ctx, engine, nil, testKey6, makeTS(4, 0), value6, txn1); err != nil {
+		t.Fatal(err)
+	}
+	err = MVCCDeleteRangeUsingTombstone(ctx, engine, &ms, testKey5, testKey6.Next(), makeTS(5, 0))
+	if _, ok := err.(*roachpb.WriteIntentError); !ok {
+		t.Fatalf("expected write intent error, got %v", err)
+	}
+
+	// The tombstones are accounted for as system keys.
+	iter := engine.NewIterator(false)
+	expMS, err := iter.ComputeStats(mvccKey(keys.LocalRangeTombstonePrefix),
+		mvccKey(keys.LocalRangeTombstoneMax), 0)
+	iter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.SysBytes != expMS.SysBytes || ms.SysCount != expMS.SysCount {
+		t.Errorf("expected sys stats %d/%d, got %d/%d",
+			expMS.SysBytes, expMS.SysCount, ms.SysBytes, ms.SysCount)
+	}
+}
+
 // TestMVCCUncommittedDeleteRangeVisible tests that the keys in an uncommitted
 // DeleteRange are visible to the same transaction at a higher epoch.
 func TestMVCCUncommittedDeleteRangeVisible(t *testing.T) {
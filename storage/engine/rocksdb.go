@@ -27,7 +27,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"golang.org/x/net/context"
@@ -256,6 +258,50 @@ func (c RocksDBCache) Release() {
 	}
 }
 
+// Compression is the algorithm the blocks of the sstables of a level are
+// compressed with. The values match those of rocksdb::CompressionType.
+type Compression uint8
+
+// The compression algorithms RocksDB is built with.
+const (
+	CompressionNone   Compression = 0
+	CompressionSnappy Compression = 1
+)
+
+var compressionNames = map[Compression]string{
+	CompressionNone:   "none",
+	CompressionSnappy: "snappy",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Compression(%d)", c)
+}
+
+// ParseCompression returns the compression algorithm with the given name.
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressionNames {
+		if strings.EqualFold(name, n) {
+			return c, nil
+		}
+	}
+	return 0, errors.Errorf("unsupported compression %q, must be none or snappy", name)
+}
+
+// RocksDBTuning holds the options overriding the defaults RocksDB is
+// configured with. The zero value keeps all the defaults.
+type RocksDBTuning struct {
+	// Compression holds the compression of each level, starting with L0. The
+	// last one applies to the remaining levels. Every level is compressed with
+	// snappy if it's empty.
+	Compression []Compression
+	// WriteBufferSize is the size of a memtable, which can't exceed the
+	// memtable budget. It's derived from the memtable budget if zero.
+	WriteBufferSize int64
+}
+
 // RocksDB is a wrapper around a RocksDB database instance.
 type RocksDB struct {
 	rdb            *C.DBEngine
@@ -265,6 +311,8 @@ type RocksDB struct {
 	memtableBudget int64              // Memory to use for the memory table.
 	maxSize        int64              // Used for calculating rebalancing and free space.
 	maxOpenFiles   int                // The maximum number of open files this instance will use.
+	tuning         RocksDBTuning      // Overrides of the default options.
+	encryption     EncryptionOptions  // The keys used to encrypt the files.
 	stopper        *stop.Stopper
 	deallocated    chan struct{} // Closed when the underlying handle is deallocated.
 }
@@ -278,6 +326,8 @@ func NewRocksDB(
 	cache RocksDBCache,
 	memtableBudget, maxSize int64,
 	maxOpenFiles int,
+	tuning RocksDBTuning,
+	encryption EncryptionOptions,
 	stopper *stop.Stopper,
 ) *RocksDB {
 	if dir == "" {
@@ -290,6 +340,8 @@ func NewRocksDB(
 		memtableBudget: memtableBudget,
 		maxSize:        maxSize,
 		maxOpenFiles:   maxOpenFiles,
+		tuning:         tuning,
+		encryption:     encryption,
 		stopper:        stopper,
 		deallocated:    make(chan struct{}),
 	}
@@ -333,6 +385,10 @@ func (r *RocksDB) Open() error {
 		return errors.Errorf("memtable budget must be at least %s: %s",
 			humanize.IBytes(minMemtableBudget), humanizeutil.IBytes(r.memtableBudget))
 	}
+	if r.tuning.WriteBufferSize > r.memtableBudget {
+		return errors.Errorf("write buffer size %s exceeds the memtable budget %s",
+			humanizeutil.IBytes(r.tuning.WriteBufferSize), humanizeutil.IBytes(r.memtableBudget))
+	}
 
 	var ver storageVersion
 	if len(r.dir) != 0 {
@@ -356,6 +412,20 @@ func (r *RocksDB) Open() error {
 		ver = versionCurrent
 	}
 
+	if r.encryption.enabled() {
+		if r.encryption.ActiveKey != nil {
+			log.Infof(context.TODO(), "encrypting rocksdb instance at %q with key %s",
+				r.dir, r.encryption.ActiveKey)
+		} else {
+			log.Infof(context.TODO(), "disabling encryption of rocksdb instance at %q", r.dir)
+		}
+	}
+	activeKey := encodeEncryptionKeys(r.encryption.ActiveKey)
+	oldKeys := encodeEncryptionKeys(r.encryption.OldKeys...)
+	compression := make([]byte, len(r.tuning.Compression))
+	for i, c := range r.tuning.Compression {
+		compression[i] = byte(c)
+	}
 	status := C.DBOpen(&r.rdb, goToCSlice([]byte(r.dir)),
 		C.DBOptions{
 			cache:           r.cache.cache,
@@ -366,6 +436,11 @@ func (r *RocksDB) Open() error {
 			logging_enabled: C.bool(log.V(3)),
 			num_cpu:         C.int(runtime.NumCPU()),
 			max_open_files:  C.int(r.maxOpenFiles),
+
+			write_buffer_size:     C.uint64_t(r.tuning.WriteBufferSize),
+			compression_per_level: goToCSlice(compression),
+			encryption_active_key: goToCSlice(activeKey),
+			encryption_old_keys:   goToCSlice(oldKeys),
 		})
 	if err := statusToError(status); err != nil {
 		return errors.Errorf("could not open rocksdb instance: %s", err)
@@ -543,6 +618,13 @@ func (r *RocksDB) Compact() error {
 	return statusToError(C.DBCompact(r.rdb))
 }
 
+// CompactRange forces compaction of the keys in [start, end). An empty key
+// leaves the range unbounded on that side.
+func (r *RocksDB) CompactRange(start, end roachpb.Key) error {
+	return statusToError(C.DBCompactRange(r.rdb,
+		goToCKey(MakeMVCCMetadataKey(start)), goToCKey(MakeMVCCMetadataKey(end))))
+}
+
 // Destroy destroys the underlying filesystem data associated with the database.
 func (r *RocksDB) Destroy() error {
 	return statusToError(C.DBDestroy(goToCSlice([]byte(r.dir))))
@@ -567,11 +649,55 @@ func (r *RocksDB) Checkpoint(dir string) error {
 	return statusToError(C.DBCheckpoint(r.rdb, goToCSlice([]byte(dir))))
 }
 
+// ingestFileCounter is used to give the files passed to IngestExternalFile
+// unique temporary names.
+var ingestFileCounter uint64
+
+// IngestExternalFile links an sstable with the given contents, as built by
+// RocksDBSstFileWriter, into the engine. The contents are first written to a
+// temporary file (in memory for an in-memory engine), which an on-disk engine
+// then hard-links into its directory. The key range of the file must not
+// overlap any keys already present in the engine.
+func (r *RocksDB) IngestExternalFile(data []byte) error {
+	path := fmt.Sprintf("ingest-%d.tmp", atomic.AddUint64(&ingestFileCounter, 1))
+	if len(r.dir) != 0 {
+		path = filepath.Join(r.dir, path)
+	}
+	cPath := goToCSlice([]byte(path))
+	if err := statusToError(C.DBEnvWriteFile(r.rdb, cPath, goToCSlice(data))); err != nil {
+		return err
+	}
+	err := statusToError(C.DBIngestExternalFile(r.rdb, cPath, C.bool(len(r.dir) != 0)))
+	if delErr := statusToError(C.DBEnvDeleteFile(r.rdb, cPath)); err == nil {
+		err = delErr
+	}
+	return err
+}
+
+// DeleteRange removes all the entries from start (inclusive) to end
+// (exclusive) with a RocksDB range deletion tombstone, which hides the
+// entries from reads until compactions drop them.
+func (r *RocksDB) DeleteRange(start, end MVCCKey) error {
+	if len(start.Key) == 0 {
+		return emptyKeyError()
+	}
+	if !start.Less(end) {
+		return errors.Errorf("invalid range [%s,%s)", start, end)
+	}
+	return statusToError(C.DBDeleteRange(r.rdb, goToCKey(start), goToCKey(end)))
+}
+
 // NewIterator returns an iterator over this rocksdb engine.
 func (r *RocksDB) NewIterator(prefix bool) Iterator {
 	return newRocksDBIterator(r.rdb, prefix, r)
 }
 
+// NewTimeBoundIterator returns an iterator over this rocksdb engine which
+// may skip the sstables outside of the time interval [start, end].
+func (r *RocksDB) NewTimeBoundIterator(start, end hlc.Timestamp) Iterator {
+	return newRocksDBTimeBoundIterator(r.rdb, start, end, r)
+}
+
 // NewSnapshot creates a snapshot handle from engine and returns a
 // read-only rocksDBSnapshot engine.
 func (r *RocksDB) NewSnapshot() Reader {
@@ -641,6 +767,16 @@ func (r *RocksDB) GetStats() (*Stats, error) {
 	}, nil
 }
 
+// EncryptionStatus reports the active encryption key of this engine's RocksDB
+// instance and the keys its files are encrypted with. In-memory instances
+// aren't encrypted and report no files.
+func (r *RocksDB) EncryptionStatus() (EncryptionStatus, error) {
+	if len(r.dir) == 0 {
+		return EncryptionStatus{}, nil
+	}
+	return readEncryptionStatus(r.dir, r.encryption.ActiveKey)
+}
+
 type rocksDBSnapshot struct {
 	parent *RocksDB
 	handle *C.DBEngine
@@ -681,6 +817,12 @@ func (r *rocksDBSnapshot) NewIterator(prefix bool) Iterator {
 	return newRocksDBIterator(r.handle, prefix, r)
 }
 
+// NewTimeBoundIterator returns a new time-bound Iterator over the engine
+// using the snapshot handle.
+func (r *rocksDBSnapshot) NewTimeBoundIterator(start, end hlc.Timestamp) Iterator {
+	return newRocksDBTimeBoundIterator(r.handle, start, end, r)
+}
+
 // reusableIterator wraps rocksDBIterator and allows reuse of an iterator
 // for the lifetime of a batch.
 type reusableIterator struct {
@@ -730,6 +872,13 @@ func (r *distinctBatch) NewIterator(prefix bool) Iterator {
 	return iter
 }
 
+// NewTimeBoundIterator returns a new time-bound iterator over the batch and
+// underlying engine. Unlike the iterators returned by NewIterator, it isn't
+// cached and must be closed before the batch.
+func (r *distinctBatch) NewTimeBoundIterator(start, end hlc.Timestamp) Iterator {
+	return newRocksDBTimeBoundIterator(r.batch, start, end, r)
+}
+
 func (r *distinctBatch) Get(key MVCCKey) ([]byte, error) {
 	return dbGet(r.batch, key)
 }
@@ -856,7 +1005,7 @@ type rocksDBBatch struct {
 	flushes            int
 	prefixIter         rocksDBBatchIterator
 	normalIter         rocksDBBatchIterator
-	builder            rocksDBBatchBuilder
+	builder            RocksDBBatchBuilder
 	distinct           distinctBatch
 	distinctOpen       bool
 	distinctNeedsFlush bool
@@ -950,6 +1099,24 @@ func (r *rocksDBBatch) Clear(key MVCCKey) error {
 	return nil
 }
 
+// DeleteRange removes all the entries from start (inclusive) to end
+// (exclusive) with a RocksDB range deletion tombstone when the batch is
+// committed.
+func (r *rocksDBBatch) DeleteRange(start, end MVCCKey) error {
+	if r.distinctOpen {
+		panic("distinct batch open")
+	}
+	if len(start.Key) == 0 {
+		return emptyKeyError()
+	}
+	if !start.Less(end) {
+		return errors.Errorf("invalid range [%s,%s)", start, end)
+	}
+	r.distinctNeedsFlush = true
+	r.builder.ClearRange(start, end)
+	return nil
+}
+
 // NewIterator returns an iterator over the batch and underlying engine. Note
 // that the returned iterator is cached and re-used for the lifetime of the
 // batch. A panic will be thrown if multiple prefix or normal (non-prefix)
@@ -973,6 +1140,18 @@ func (r *rocksDBBatch) NewIterator(prefix bool) Iterator {
 	return iter
 }
 
+// NewTimeBoundIterator returns a new time-bound iterator over the batch and
+// underlying engine. Unlike the iterators returned by NewIterator, it isn't
+// cached and must be closed before the batch. The mutations made to the batch
+// after the iterator is created are not visible to it.
+func (r *rocksDBBatch) NewTimeBoundIterator(start, end hlc.Timestamp) Iterator {
+	if r.distinctOpen {
+		panic("distinct batch open")
+	}
+	r.flushMutations()
+	return newRocksDBTimeBoundIterator(r.batch, start, end, r)
+}
+
 func (r *rocksDBBatch) Commit() error {
 	if r.batch == nil {
 		panic("this batch was already committed")
@@ -1061,6 +1240,19 @@ func newRocksDBIterator(rdb *C.DBEngine, prefix bool, engine Reader) Iterator {
 	return r
 }
 
+// newRocksDBTimeBoundIterator returns a new iterator over the supplied RocksDB
+// instance which may skip the sstables none of whose keys were written in the
+// time interval [start, end]. The caller must call rocksDBIterator.Close()
+// when finished with the iterator to free up resources.
+func newRocksDBTimeBoundIterator(
+	rdb *C.DBEngine, start, end hlc.Timestamp, engine Reader,
+) Iterator {
+	r := iterPool.Get().(*rocksDBIterator)
+	r.iter = C.DBNewTimeBoundIter(rdb, goToCTimestamp(start), goToCTimestamp(end))
+	r.engine = engine
+	return r
+}
+
 func (r *rocksDBIterator) init(rdb *C.DBEngine, prefix bool, engine Reader) {
 	r.iter = C.DBNewIter(rdb, C.bool(prefix))
 	r.engine = engine
@@ -1234,6 +1426,13 @@ func goToCKey(key MVCCKey) C.DBKey {
 	}
 }
 
+func goToCTimestamp(ts hlc.Timestamp) C.DBTimestamp {
+	return C.DBTimestamp{
+		wall_time: C.int64_t(ts.WallTime),
+		logical:   C.int32_t(ts.Logical),
+	}
+}
+
 func cToGoKey(key C.DBKey) MVCCKey {
 	// When converting a C.DBKey to an MVCCKey, give the underlying slice an
 	// extra byte of capacity in anticipation of roachpb.Key.Next() being
@@ -1423,3 +1622,124 @@ func dbIterate(rdb *C.DBEngine, engine Reader, start, end MVCCKey,
 	// Check for any errors during iteration.
 	return it.Error()
 }
+
+// RocksDBSstFileWriter creates a RocksDB sstable in memory. The entries must
+// be added in increasing key order.
+type RocksDBSstFileWriter struct {
+	fw *C.DBSstFileWriter
+	// DataSize tracks the total key and value bytes added so far.
+	DataSize int64
+}
+
+// MakeRocksDBSstFileWriter creates a new RocksDBSstFileWriter with the default
+// configuration.
+func MakeRocksDBSstFileWriter() (RocksDBSstFileWriter, error) {
+	fw := C.DBSstFileWriterNew()
+	if err := statusToError(C.DBSstFileWriterOpen(fw)); err != nil {
+		C.DBSstFileWriterClose(fw)
+		return RocksDBSstFileWriter{}, err
+	}
+	return RocksDBSstFileWriter{fw: fw}, nil
+}
+
+// Add puts a kv entry into the sstable being built. An error is returned if it
+// is not greater than any previously added entry (according to the comparator
+// configured during writer creation). `Close` cannot have been called.
+func (fw *RocksDBSstFileWriter) Add(kv MVCCKeyValue) error {
+	if fw.fw == nil {
+		return errors.New("cannot call Add on a closed writer")
+	}
+	if err := statusToError(C.DBSstFileWriterAdd(fw.fw, goToCKey(kv.Key), goToCSlice(kv.Value))); err != nil {
+		return err
+	}
+	fw.DataSize += int64(len(kv.Key.Key)) + int64(len(kv.Value))
+	return nil
+}
+
+// Finish finalizes the writer and returns the constructed file's contents. At
+// least one kv entry must have been added. The writer cannot be used
+// afterwards, but must still be closed.
+func (fw *RocksDBSstFileWriter) Finish() ([]byte, error) {
+	if fw.fw == nil {
+		return nil, errors.New("cannot call Finish on a closed writer")
+	}
+	var contents C.DBString
+	if err := statusToError(C.DBSstFileWriterFinish(fw.fw, &contents)); err != nil {
+		return nil, err
+	}
+	return cStringToGoBytes(contents), nil
+}
+
+// Close finishes and frees memory and other resources. Close is idempotent.
+func (fw *RocksDBSstFileWriter) Close() {
+	if fw.fw == nil {
+		return
+	}
+	C.DBSstFileWriterClose(fw.fw)
+	fw.fw = nil
+}
+
+// RocksDBSstFileReader allows iteration over the contents of sstables written
+// by RocksDBSstFileWriter. The files are loaded into a private in-memory
+// RocksDB instance, so they must not contain overlapping keys.
+type RocksDBSstFileReader struct {
+	rocksDB *RocksDB
+	stopper *stop.Stopper
+}
+
+// MakeRocksDBSstFileReader creates a new, empty RocksDBSstFileReader. It must
+// be closed when no longer needed.
+func MakeRocksDBSstFileReader() (RocksDBSstFileReader, error) {
+	cache := NewRocksDBCache(0)
+	defer cache.Release()
+	stopper := stop.NewStopper()
+	rocksDB := newMemRocksDB(roachpb.Attributes{}, cache, minMemtableBudget, stopper)
+	if err := rocksDB.Open(); err != nil {
+		stopper.Stop()
+		return RocksDBSstFileReader{}, err
+	}
+	return RocksDBSstFileReader{rocksDB: rocksDB, stopper: stopper}, nil
+}
+
+// IngestExternalFile links the sstable with the given contents into the
+// reader, making its entries visible to Iterate.
+func (fr *RocksDBSstFileReader) IngestExternalFile(data []byte) error {
+	if fr.rocksDB == nil {
+		return errors.New("cannot call IngestExternalFile on a closed reader")
+	}
+	return fr.rocksDB.IngestExternalFile(data)
+}
+
+// Iterate iterates over the keys between start inclusive and end exclusive of
+// all the ingested sstables, invoking f on each key/value pair. See
+// engine.Iterate for details.
+func (fr *RocksDBSstFileReader) Iterate(
+	start, end MVCCKey, f func(MVCCKeyValue) (bool, error),
+) error {
+	if fr.rocksDB == nil {
+		return errors.New("cannot call Iterate on a closed reader")
+	}
+	return fr.rocksDB.Iterate(start, end, f)
+}
+
+// NewIterator returns an iterator over the contents of all the ingested
+// sstables. The iterator must be closed before the reader.
+func (fr *RocksDBSstFileReader) NewIterator(prefix bool) Iterator {
+	return fr.rocksDB.NewIterator(prefix)
+}
+
+// NewTimeBoundIterator returns a time-bound iterator over the contents of all
+// the ingested sstables. The iterator must be closed before the reader.
+func (fr *RocksDBSstFileReader) NewTimeBoundIterator(start, end hlc.Timestamp) Iterator {
+	return fr.rocksDB.NewTimeBoundIterator(start, end)
+}
+
+// Close finishes the reader and frees the memory used by the ingested
+// sstables. Close is idempotent.
+func (fr *RocksDBSstFileReader) Close() {
+	if fr.rocksDB == nil {
+		return
+	}
+	fr.stopper.Stop()
+	fr.rocksDB = nil
+}
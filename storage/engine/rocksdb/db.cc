@@ -27,8 +27,11 @@
 #include "rocksdb/filter_policy.h"
 #include "rocksdb/merge_operator.h"
 #include "rocksdb/options.h"
+#include "rocksdb/immutable_options.h"
 #include "rocksdb/slice_transform.h"
+#include "rocksdb/sst_file_writer.h"
 #include "rocksdb/statistics.h"
+#include "rocksdb/table_properties.h"
 #include "rocksdb/table.h"
 #include "rocksdb/utilities/checkpoint.h"
 #include "rocksdb/utilities/write_batch_with_index.h"
@@ -37,6 +40,7 @@
 #include "cockroach/storage/engine/enginepb/mvcc.pb.h"
 #include "db.h"
 #include "encoding.h"
+#include "encryption.h"
 #include "eventlistener.h"
 
 extern "C" {
@@ -62,13 +66,14 @@ struct DBEngine {
   virtual DBSlice BatchRepr() = 0;
   virtual DBStatus Get(DBKey key, DBString* value) = 0;
   virtual DBIterator* NewIter(bool prefix) = 0;
+  virtual DBIterator* NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts) = 0;
   virtual DBStatus GetStats(DBStatsResult* stats) = 0;
 
   DBSSTable* GetSSTables(int* n);
 };
 
 struct DBImpl : public DBEngine {
-  std::unique_ptr<rocksdb::Env> memenv;
+  std::unique_ptr<rocksdb::Env> env;
   std::unique_ptr<rocksdb::DB> rep_deleter;
   rocksdb::ReadOptions const read_opts;
   std::shared_ptr<rocksdb::Cache> block_cache;
@@ -80,7 +85,7 @@ struct DBImpl : public DBEngine {
   DBImpl(rocksdb::DB* r, rocksdb::Env* m, std::shared_ptr<rocksdb::Cache> bc,
     std::shared_ptr<DBEventListener> event_listener)
       : DBEngine(r),
-        memenv(m),
+        env(m),
         rep_deleter(r),
         block_cache(bc),
         event_listener(event_listener) {
@@ -101,12 +106,17 @@ struct DBImpl : public DBEngine {
   virtual DBSlice BatchRepr();
   virtual DBStatus Get(DBKey key, DBString* value);
   virtual DBIterator* NewIter(bool prefix);
+  virtual DBIterator* NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts);
   virtual DBStatus GetStats(DBStatsResult* stats);
 };
 
 struct DBBatch : public DBEngine {
   int updates;
   rocksdb::WriteBatchWithIndex batch;
+  // The range deletions of the batch, which WriteBatchWithIndex doesn't
+  // support. They are written along with the batch when it is committed
+  // and are not visible to its reads.
+  std::vector<std::pair<std::string, std::string> > range_deletions;
   rocksdb::ReadOptions const read_opts;
 
   DBBatch(DBEngine* db);
@@ -121,6 +131,7 @@ struct DBBatch : public DBEngine {
   virtual DBSlice BatchRepr();
   virtual DBStatus Get(DBKey key, DBString* value);
   virtual DBIterator* NewIter(bool prefix);
+  virtual DBIterator* NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts);
   virtual DBStatus GetStats(DBStatsResult* stats);
 };
 
@@ -145,9 +156,22 @@ struct DBSnapshot : public DBEngine {
   virtual DBSlice BatchRepr();
   virtual DBStatus Get(DBKey key, DBString* value);
   virtual DBIterator* NewIter(bool prefix);
+  virtual DBIterator* NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts);
   virtual DBStatus GetStats(DBStatsResult* stats);
 };
 
+struct DBSstFileWriter {
+  std::unique_ptr<rocksdb::Options> options;
+  std::unique_ptr<rocksdb::Env> memenv;
+  rocksdb::SstFileWriter rep;
+
+  DBSstFileWriter(rocksdb::Options* o, rocksdb::Env* m)
+      : options(o),
+        memenv(m),
+        rep(rocksdb::EnvOptions(), rocksdb::ImmutableCFOptions(*o), o->comparator) {
+  }
+};
+
 struct DBIterator {
   std::unique_ptr<rocksdb::Iterator> rep;
 };
@@ -459,10 +483,130 @@ class DBPrefixExtractor : public rocksdb::SliceTransform {
   }
 };
 
+// The names of the sstable properties holding the encoded minimum and
+// maximum timestamps of the keys of the sstable.
+const char kTimeBoundMinProp[] = "crdb.ts.min";
+const char kTimeBoundMaxProp[] = "crdb.ts.max";
+
+// EncodeTimestamp encodes a timestamp the same way as the timestamp
+// suffix of an MVCC key (without the NUL prefix). Encoded timestamps
+// sort lexicographically in timestamp order.
+std::string EncodeTimestamp(DBTimestamp ts) {
+  std::string s;
+  s.reserve(kMVCCVersionTimestampSize);
+  EncodeUint64(&s, uint64_t(ts.wall_time));
+  if (ts.logical != 0) {
+    EncodeUint32(&s, uint32_t(ts.logical));
+  }
+  return s;
+}
+
+// TimeBoundTblPropCollector records the minimum and maximum timestamps
+// of the keys of an sstable in its properties, which allows iterators
+// that are only interested in a time interval to skip the sstables
+// entirely outside of it (see DBNewTimeBoundIter). Keys without a
+// timestamp are ignored, except for intents: the provisional value of
+// an intent may end up in a different sstable than its metadata, so
+// nothing is recorded for sstables containing intents, which are then
+// never skipped.
+class TimeBoundTblPropCollector : public rocksdb::TablePropertiesCollector {
+ public:
+  TimeBoundTblPropCollector()
+      : has_intents_(false) {
+  }
+
+  virtual const char* Name() const override {
+    return "TimeBoundTblPropCollector";
+  }
+
+  virtual rocksdb::Status AddUserKey(const rocksdb::Slice& user_key, const rocksdb::Slice& value,
+                                     rocksdb::EntryType type, rocksdb::SequenceNumber seq,
+                                     uint64_t file_size) override {
+    rocksdb::Slice key;
+    rocksdb::Slice ts;
+    if (!SplitKey(user_key, &key, &ts)) {
+      return rocksdb::Status::OK();
+    }
+    if (!ts.empty()) {
+      ts.remove_prefix(1);  // The NUL prefix.
+      if (ts_max_.empty() || ts.compare(ts_max_) > 0) {
+        ts_max_.assign(ts.data(), ts.size());
+      }
+      if (ts_min_.empty() || ts.compare(ts_min_) < 0) {
+        ts_min_.assign(ts.data(), ts.size());
+      }
+      return rocksdb::Status::OK();
+    }
+    if (type == rocksdb::kEntryPut) {
+      cockroach::storage::engine::enginepb::MVCCMetadata meta;
+      if (meta.ParseFromArray(value.data(), value.size()) && meta.has_txn()) {
+        has_intents_ = true;
+      }
+    }
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::Status Finish(rocksdb::UserCollectedProperties* properties) override {
+    if (!has_intents_ && !ts_max_.empty()) {
+      *properties = rocksdb::UserCollectedProperties{
+        {kTimeBoundMinProp, ts_min_},
+        {kTimeBoundMaxProp, ts_max_},
+      };
+    }
+    return rocksdb::Status::OK();
+  }
+
+  virtual rocksdb::UserCollectedProperties GetReadableProperties() const override {
+    return rocksdb::UserCollectedProperties{};
+  }
+
+ private:
+  std::string ts_min_;
+  std::string ts_max_;
+  bool has_intents_;
+};
+
+class TimeBoundTblPropCollectorFactory : public rocksdb::TablePropertiesCollectorFactory {
+ public:
+  virtual rocksdb::TablePropertiesCollector* CreateTablePropertiesCollector(
+      rocksdb::TablePropertiesCollectorFactory::Context context) override {
+    return new TimeBoundTblPropCollector();
+  }
+
+  virtual const char* Name() const override {
+    return "TimeBoundTblPropCollectorFactory";
+  }
+};
+
+// TimeBoundReadOptions returns a copy of the given read options which
+// skips the sstables whose recorded timestamps don't overlap the
+// interval [min_ts, max_ts]. The sstables without recorded timestamps,
+// such as the ones written before they were recorded or containing
+// intents, are never skipped.
+rocksdb::ReadOptions TimeBoundReadOptions(
+    const rocksdb::ReadOptions& read_opts, DBTimestamp min_ts, DBTimestamp max_ts) {
+  rocksdb::ReadOptions opts = read_opts;
+  opts.total_order_seek = true;
+  const std::string min = EncodeTimestamp(min_ts);
+  const std::string max = EncodeTimestamp(max_ts);
+  opts.table_filter = [min, max](const rocksdb::TableProperties& props) {
+    const auto& user_props = props.user_collected_properties;
+    auto tbl_min = user_props.find(kTimeBoundMinProp);
+    auto tbl_max = user_props.find(kTimeBoundMaxProp);
+    if (tbl_min == user_props.end() || tbl_max == user_props.end()) {
+      return true;
+    }
+    return tbl_max->second.compare(min) >= 0 && tbl_min->second.compare(max) <= 0;
+  };
+  return opts;
+}
+
 class DBBatchInserter : public rocksdb::WriteBatch::Handler {
  public:
-  DBBatchInserter(rocksdb::WriteBatchWithIndex* batch)
-      : batch_(batch) {
+  DBBatchInserter(rocksdb::WriteBatchWithIndex* batch,
+                  std::vector<std::pair<std::string, std::string> >* range_deletions)
+      : batch_(batch),
+        range_deletions_(range_deletions) {
   }
 
   virtual void Put(const rocksdb::Slice& key, const rocksdb::Slice& value) {
@@ -474,9 +618,19 @@ class DBBatchInserter : public rocksdb::WriteBatch::Handler {
   virtual void Merge(const rocksdb::Slice& key, const rocksdb::Slice& value) {
     batch_->Merge(key, value);
   }
+  virtual rocksdb::Status DeleteRangeCF(uint32_t column_family_id,
+                                        const rocksdb::Slice& begin_key,
+                                        const rocksdb::Slice& end_key) {
+    if (column_family_id != 0) {
+      return rocksdb::Status::InvalidArgument("DeleteRangeCF: unexpected column family");
+    }
+    range_deletions_->push_back(std::make_pair(begin_key.ToString(), end_key.ToString()));
+    return rocksdb::Status::OK();
+  }
 
  private:
   rocksdb::WriteBatchWithIndex* const batch_;
+  std::vector<std::pair<std::string, std::string> >* const range_deletions_;
 };
 
 // Method used to sort InternalTimeSeriesSamples.
@@ -1396,6 +1550,9 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   // Use the rocksdb options builder to configure the base options
   // using our memtable budget.
   rocksdb::Options options(rocksdb::GetOptions(db_opts.memtable_budget));
+  if (db_opts.write_buffer_size > 0) {
+    options.write_buffer_size = db_opts.write_buffer_size;
+  }
   // Increase parallelism for compactions based on the number of
   // cpus. This will use 1 high priority thread for flushes and
   // num_cpu-1 low priority threads for compactions.
@@ -1413,6 +1570,8 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   options.statistics = rocksdb::CreateDBStatistics();
   options.table_factory.reset(rocksdb::NewBlockBasedTableFactory(table_options));
   options.max_open_files = db_opts.max_open_files;
+  options.table_properties_collector_factories.emplace_back(
+      new TimeBoundTblPropCollectorFactory);
 
   // Merge two memtables when flushing to L0.
   options.min_write_buffer_number_to_merge = 2;
@@ -1446,14 +1605,36 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   options.target_file_size_base = options.max_bytes_for_level_base / 4;
   options.target_file_size_multiplier = 2;
 
+  // Override the compression of each level, the last one specified
+  // applying to the remaining levels.
+  if (db_opts.compression_per_level.len > 0) {
+    options.compression_per_level.resize(options.num_levels);
+    for (int i = 0; i < options.num_levels; i++) {
+      const int j = std::min(i, db_opts.compression_per_level.len - 1);
+      options.compression_per_level[i] =
+          static_cast<rocksdb::CompressionType>(db_opts.compression_per_level.data[j]);
+    }
+  }
+
   // Register listener for tracking RocksDB stats.
   std::shared_ptr<DBEventListener> event_listener(new DBEventListener);
   options.listeners.emplace_back(event_listener);
 
-  std::unique_ptr<rocksdb::Env> memenv;
+  // The env wrapping the default env, if any: an in-memory env for
+  // in-memory databases, or an encrypted env if encryption is enabled.
+  std::unique_ptr<rocksdb::Env> env;
   if (dir.len == 0) {
-    memenv.reset(rocksdb::NewMemEnv(rocksdb::Env::Default()));
-    options.env = memenv.get();
+    env.reset(rocksdb::NewMemEnv(rocksdb::Env::Default()));
+    options.env = env.get();
+  } else if (db_opts.encryption_active_key.len > 0 || db_opts.encryption_old_keys.len > 0) {
+    rocksdb::Env* encrypted_env;
+    rocksdb::Status status = NewEncryptedEnv(rocksdb::Env::Default(),
+        db_opts.encryption_active_key, db_opts.encryption_old_keys, &encrypted_env);
+    if (!status.ok()) {
+      return ToDBStatus(status);
+    }
+    env.reset(encrypted_env);
+    options.env = env.get();
   }
 
   rocksdb::DB *db_ptr;
@@ -1461,7 +1642,7 @@ DBStatus DBOpen(DBEngine **db, DBSlice dir, DBOptions db_opts) {
   if (!status.ok()) {
     return ToDBStatus(status);
   }
-  *db = new DBImpl(db_ptr, memenv.release(), table_options.block_cache, event_listener);
+  *db = new DBImpl(db_ptr, env.release(), table_options.block_cache, event_listener);
   return kSuccess;
 }
 
@@ -1484,6 +1665,21 @@ DBStatus DBCompact(DBEngine* db) {
   return ToDBStatus(db->rep->CompactRange(rocksdb::CompactRangeOptions(), NULL, NULL));
 }
 
+DBStatus DBCompactRange(DBEngine* db, DBKey start, DBKey end) {
+  rocksdb::CompactRangeOptions options;
+  // RocksDB doesn't compact the bottommost level by default as it
+  // expects it to be compacted already, which would leave the
+  // deletion tombstones of the range in place.
+  options.bottommost_level_compaction = rocksdb::BottommostLevelCompaction::kForce;
+  const std::string start_key(EncodeKey(start));
+  const std::string end_key(EncodeKey(end));
+  const rocksdb::Slice start_slice(start_key);
+  const rocksdb::Slice end_slice(end_key);
+  return ToDBStatus(db->rep->CompactRange(options,
+      start.key.len > 0 ? &start_slice : NULL,
+      end.key.len > 0 ? &end_slice : NULL));
+}
+
 DBStatus DBCheckpoint(DBEngine* db, DBSlice dir) {
   rocksdb::Checkpoint* cp = nullptr;
   rocksdb::Status status = rocksdb::Checkpoint::Create(db->rep, &cp);
@@ -1575,6 +1771,12 @@ DBStatus DBDelete(DBEngine *db, DBKey key) {
   return db->Delete(key);
 }
 
+DBStatus DBDeleteRange(DBEngine* db, DBKey start, DBKey end) {
+  rocksdb::WriteOptions options;
+  return ToDBStatus(db->rep->DeleteRange(options, db->rep->DefaultColumnFamily(),
+                                         EncodeKey(start), EncodeKey(end)));
+}
+
 DBStatus DBImpl::CommitBatch() {
   return FmtStatus("unsupported");
 }
@@ -1584,7 +1786,15 @@ DBStatus DBBatch::CommitBatch() {
     return kSuccess;
   }
   rocksdb::WriteOptions options;
-  return ToDBStatus(rep->Write(options, batch.GetWriteBatch()));
+  if (range_deletions.empty()) {
+    return ToDBStatus(rep->Write(options, batch.GetWriteBatch()));
+  }
+  // Write the range deletions atomically with the rest of the batch.
+  rocksdb::WriteBatch combined(batch.GetWriteBatch()->Data());
+  for (const auto& r : range_deletions) {
+    combined.DeleteRange(r.first, r.second);
+  }
+  return ToDBStatus(rep->Write(options, &combined));
 }
 
 DBStatus DBSnapshot::CommitBatch() {
@@ -1604,11 +1814,11 @@ DBStatus DBImpl::ApplyBatchRepr(DBSlice repr) {
 DBStatus DBBatch::ApplyBatchRepr(DBSlice repr) {
   // TODO(peter): It would be slightly more efficient to iterate over
   // repr directly instead of first converting it to a string.
-  DBBatchInserter inserter(&batch);
+  DBBatchInserter inserter(&batch, &range_deletions);
   rocksdb::WriteBatch batch(ToString(repr));
-  batch.Iterate(&inserter);
+  rocksdb::Status status = batch.Iterate(&inserter);
   updates += batch.Count();
-  return kSuccess;
+  return ToDBStatus(status);
 }
 
 DBStatus DBSnapshot::ApplyBatchRepr(DBSlice repr) {
@@ -1652,6 +1862,12 @@ DBIterator* DBImpl::NewIter(bool prefix) {
   return iter;
 }
 
+DBIterator* DBImpl::NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts) {
+  DBIterator* iter = new DBIterator;
+  iter->rep.reset(rep->NewIterator(TimeBoundReadOptions(read_opts, min_ts, max_ts)));
+  return iter;
+}
+
 DBIterator* DBBatch::NewIter(bool prefix) {
   DBIterator* iter = new DBIterator;
   rocksdb::ReadOptions opts = read_opts;
@@ -1663,6 +1879,14 @@ DBIterator* DBBatch::NewIter(bool prefix) {
   return iter;
 }
 
+DBIterator* DBBatch::NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts) {
+  DBIterator* iter = new DBIterator;
+  rocksdb::Iterator* base = rep->NewIterator(TimeBoundReadOptions(read_opts, min_ts, max_ts));
+  rocksdb::WBWIIterator* delta = batch.NewIterator();
+  iter->rep.reset(new BaseDeltaIterator(base, delta, false));
+  return iter;
+}
+
 DBIterator* DBSnapshot::NewIter(bool prefix) {
   DBIterator* iter = new DBIterator;
   rocksdb::ReadOptions opts = read_opts;
@@ -1672,6 +1896,12 @@ DBIterator* DBSnapshot::NewIter(bool prefix) {
   return iter;
 }
 
+DBIterator* DBSnapshot::NewTimeBoundIter(DBTimestamp min_ts, DBTimestamp max_ts) {
+  DBIterator* iter = new DBIterator;
+  iter->rep.reset(rep->NewIterator(TimeBoundReadOptions(read_opts, min_ts, max_ts)));
+  return iter;
+}
+
 // GetStats retrieves a subset of RocksDB stats that are relevant to
 // CockroachDB.
 DBStatus DBImpl::GetStats(DBStatsResult* stats) {
@@ -1713,6 +1943,10 @@ DBIterator* DBNewIter(DBEngine* db, bool prefix) {
   return db->NewIter(prefix);
 }
 
+DBIterator* DBNewTimeBoundIter(DBEngine* db, DBTimestamp min_ts, DBTimestamp max_ts) {
+  return db->NewTimeBoundIter(min_ts, max_ts);
+}
+
 void DBIterDestroy(DBIterator* iter) {
   delete iter;
 }
@@ -1970,3 +2204,99 @@ DBStatus DBGetStats(DBEngine* db, DBStatsResult* stats) {
 DBSSTable* DBGetSSTables(DBEngine* db, int* n) {
   return db->GetSSTables(n);
 }
+
+DBSstFileWriter* DBSstFileWriterNew() {
+  rocksdb::BlockBasedTableOptions table_options;
+  // Larger blocks (the default is 4kb) make for smaller files, at the
+  // expense of more scanning during lookups. The sstables built here
+  // are written once and read sequentially (e.g. backups), so size is
+  // what matters.
+  table_options.block_size = 64 * 1024;
+  table_options.format_version = 2;
+
+  rocksdb::Options* options = new rocksdb::Options();
+  options->comparator = &kComparator;
+  options->table_factory.reset(rocksdb::NewBlockBasedTableFactory(table_options));
+
+  std::unique_ptr<rocksdb::Env> memenv;
+  memenv.reset(rocksdb::NewMemEnv(rocksdb::Env::Default()));
+  options->env = memenv.get();
+
+  return new DBSstFileWriter(options, memenv.release());
+}
+
+DBStatus DBSstFileWriterOpen(DBSstFileWriter* fw) {
+  return ToDBStatus(fw->rep.Open("sst"));
+}
+
+DBStatus DBSstFileWriterAdd(DBSstFileWriter* fw, DBKey key, DBSlice val) {
+  return ToDBStatus(fw->rep.Add(EncodeKey(key), ToSlice(val)));
+}
+
+DBStatus DBSstFileWriterFinish(DBSstFileWriter* fw, DBString* data) {
+  rocksdb::Status status = fw->rep.Finish();
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+
+  uint64_t file_size;
+  status = fw->memenv->GetFileSize("sst", &file_size);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+
+  const rocksdb::EnvOptions soptions;
+  std::unique_ptr<rocksdb::SequentialFile> sst;
+  status = fw->memenv->NewSequentialFile("sst", &sst, soptions);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+
+  // scratch is returned as data and freed by the caller.
+  char* scratch = static_cast<char*>(malloc(file_size));
+  rocksdb::Slice sst_contents;
+  status = sst->Read(file_size, &sst_contents, scratch);
+  if (!status.ok()) {
+    free(scratch);
+    return ToDBStatus(status);
+  }
+  if (sst_contents.size() != file_size) {
+    free(scratch);
+    return FmtStatus("expected to read %d bytes but got %d",
+                     int(file_size), int(sst_contents.size()));
+  }
+  // Read may or may not have used scratch as the backing storage of
+  // sst_contents.
+  if (sst_contents.data() != scratch) {
+    memcpy(scratch, sst_contents.data(), sst_contents.size());
+  }
+  data->data = scratch;
+  data->len = sst_contents.size();
+  return kSuccess;
+}
+
+void DBSstFileWriterClose(DBSstFileWriter* fw) {
+  delete fw;
+}
+
+DBStatus DBEnvWriteFile(DBEngine* db, DBSlice path, DBSlice contents) {
+  const rocksdb::EnvOptions soptions;
+  std::unique_ptr<rocksdb::WritableFile> file;
+  rocksdb::Status status = db->rep->GetEnv()->NewWritableFile(ToString(path), &file, soptions);
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  status = file->Append(ToSlice(contents));
+  if (!status.ok()) {
+    return ToDBStatus(status);
+  }
+  return ToDBStatus(file->Close());
+}
+
+DBStatus DBIngestExternalFile(DBEngine* db, DBSlice path, bool move_file) {
+  return ToDBStatus(db->rep->AddFile(ToString(path), move_file));
+}
+
+DBStatus DBEnvDeleteFile(DBEngine* db, DBSlice path) {
+  return ToDBStatus(db->rep->GetEnv()->DeleteFile(ToString(path)));
+}
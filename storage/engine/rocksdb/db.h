@@ -43,6 +43,11 @@ typedef struct {
   int32_t logical;
 } DBKey;
 
+typedef struct {
+  int64_t wall_time;
+  int32_t logical;
+} DBTimestamp;
+
 typedef struct {
   bool valid;
   DBKey key;
@@ -57,6 +62,7 @@ typedef DBString DBStatus;
 typedef struct DBCache DBCache;
 typedef struct DBEngine DBEngine;
 typedef struct DBIterator DBIterator;
+typedef struct DBSstFileWriter DBSstFileWriter;
 
 // DBOptions contains local database options.
 typedef struct {
@@ -68,6 +74,19 @@ typedef struct {
   bool logging_enabled;
   int num_cpu;
   int max_open_files;
+  // The size of a memtable, or 0 to derive it from the memtable budget.
+  uint64_t write_buffer_size;
+  // The rocksdb::CompressionType of each level, starting with L0, one
+  // per byte. The last one applies to the remaining levels. The
+  // default compression applies to every level if empty.
+  DBSlice compression_per_level;
+  // The keys used to encrypt the files of the database, each encoded as
+  // its length (one byte), its 32-byte ID and the key itself. New files
+  // are encrypted with the active key, or written in plaintext if there
+  // is none. Files encrypted with the old keys can still be read. The
+  // files are left unencrypted if both are empty.
+  DBSlice encryption_active_key;
+  DBSlice encryption_old_keys;
 } DBOptions;
 
 // Create a new cache with the specified size.
@@ -100,6 +119,12 @@ DBStatus DBFlush(DBEngine* db);
 // Forces an immediate compaction over all keys.
 DBStatus DBCompact(DBEngine* db);
 
+// Forces an immediate compaction over the keys in the range [start,
+// end). An empty start or end key leaves the range unbounded on that
+// side. The bottommost level is rewritten as well so that the space
+// taken by deleted keys is reclaimed.
+DBStatus DBCompactRange(DBEngine* db, DBKey start, DBKey end);
+
 // Checkpoint creates a point-in-time snapshot of the database,
 // hard-linking sstable files and copying the manifest and other
 // files.
@@ -117,6 +142,11 @@ DBStatus DBGet(DBEngine* db, DBKey key, DBString* value);
 // Deletes the database entry for "key".
 DBStatus DBDelete(DBEngine* db, DBKey key);
 
+// Deletes all the database entries from start (inclusive) to end
+// (exclusive) with a single range deletion tombstone. It is only
+// valid to call this function on an engine created by DBOpen().
+DBStatus DBDeleteRange(DBEngine* db, DBKey start, DBKey end);
+
 // Applies a batch of operations (puts, merges and deletes) to the
 // database atomically. It is only valid to call this function on an
 // engine created by DBNewBatch.
@@ -152,6 +182,13 @@ DBEngine* DBNewBatch(DBEngine* db);
 // DBIterDestroy().
 DBIterator* DBNewIter(DBEngine* db, bool prefix);
 
+// Creates a new database iterator which skips the sstables none of
+// whose keys have a timestamp in the interval [min_ts, max_ts]. Keys
+// outside of the interval may still be returned, and sstables which
+// contain intents are never skipped. It is the callers responsibility
+// to call DBIterDestroy().
+DBIterator* DBNewTimeBoundIter(DBEngine* db, DBTimestamp min_ts, DBTimestamp max_ts);
+
 // Destroys an iterator, freeing up any associated memory.
 void DBIterDestroy(DBIterator* iter);
 
@@ -233,6 +270,37 @@ typedef struct {
 // table.
 DBSSTable* DBGetSSTables(DBEngine* db, int* n);
 
+// Creates a new SSTable writer. The sstable is built in memory and
+// its contents are returned by DBSstFileWriterFinish.
+DBSstFileWriter* DBSstFileWriterNew();
+
+// Opens the in-memory sstable for writing.
+DBStatus DBSstFileWriterOpen(DBSstFileWriter* fw);
+
+// Adds a kv entry to the sstable being built. An error is returned if
+// it is not greater than any previously added entry (according to the
+// comparator configured during writer creation).
+DBStatus DBSstFileWriterAdd(DBSstFileWriter* fw, DBKey key, DBSlice val);
+
+// Finalizes the sstable being built and returns its contents in
+// data, which must be freed by the caller.
+DBStatus DBSstFileWriterFinish(DBSstFileWriter* fw, DBString* data);
+
+// Closes the writer and frees memory and other resources.
+void DBSstFileWriterClose(DBSstFileWriter* fw);
+
+// Writes contents to the file at path in the engine's Env. For an
+// in-memory engine, the file is kept in memory.
+DBStatus DBEnvWriteFile(DBEngine* db, DBSlice path, DBSlice contents);
+
+// Adds the sstable at path (in the engine's Env) to the engine. The
+// keys of the file must not overlap any keys already present. If
+// move_file is true, the file is moved rather than copied.
+DBStatus DBIngestExternalFile(DBEngine* db, DBSlice path, bool move_file);
+
+// Deletes the file at path in the engine's Env.
+DBStatus DBEnvDeleteFile(DBEngine* db, DBSlice path);
+
 #ifdef __cplusplus
 }  // extern "C"
 #endif
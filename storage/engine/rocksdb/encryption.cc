@@ -0,0 +1,510 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+#include <map>
+#include <string.h>
+#include "encryption.h"
+#include "_cgo_export.h"
+
+namespace {
+
+// Encrypted files start with a plaintext header made of kMagic, the
+// ID of the key the rest of the file is encrypted with and the
+// initialization vector of its AES-CTR keystream. The offsets of the
+// encrypted files exposed by the env exclude the header. This layout
+// is mirrored by storage/engine/encryption.go.
+const char kMagic[] = "crdbenc1";
+const size_t kMagicSize = sizeof(kMagic) - 1;
+const size_t kKeyIDSize = 32;
+const size_t kIVSize = 16;
+const size_t kHeaderSize = kMagicSize + kKeyIDSize + kIVSize;
+
+// CipherStream is the AES-CTR keystream of an encrypted file. It is
+// set up once per file, when the file is created or opened, and is
+// computed in Go, whose AES implementation uses the hardware
+// instructions when available. Go holds the stream under the ID kept
+// here.
+class CipherStream {
+ public:
+  // New sets up the keystream of key and iv. If iv is empty, it is
+  // set to a new initialization vector read from Go's crypto/rand.
+  static rocksdb::Status New(const std::string& key, std::string* iv,
+                             std::unique_ptr<CipherStream>* result) {
+    const bool new_iv = iv->empty();
+    if (new_iv) {
+      iv->resize(kIVSize);
+    }
+    const uint64_t id = rocksDBEncryptionNewStream(
+        const_cast<char*>(key.data()), key.size(), &(*iv)[0], new_iv);
+    if (id == 0) {
+      return rocksdb::Status::IOError("unable to set up encryption keystream");
+    }
+    result->reset(new CipherStream(id));
+    return rocksdb::Status::OK();
+  }
+
+  ~CipherStream() {
+    rocksDBEncryptionFreeStream(id_);
+  }
+
+  // XOR applies the keystream to data, which is found at offset in the
+  // file (excluding the header).
+  rocksdb::Status XOR(uint64_t offset, char* data, size_t n) const {
+    if (n == 0) {
+      return rocksdb::Status::OK();
+    }
+    if (rocksDBEncryptionXOR(id_, offset, data, n) != 0) {
+      return rocksdb::Status::Corruption("unable to apply encryption keystream");
+    }
+    return rocksdb::Status::OK();
+  }
+
+ private:
+  explicit CipherStream(uint64_t id)
+      : id_(id) {
+  }
+
+  const uint64_t id_;
+};
+
+class EncryptedSequentialFile : public rocksdb::SequentialFile {
+ public:
+  EncryptedSequentialFile(std::unique_ptr<rocksdb::SequentialFile> file,
+                          std::unique_ptr<CipherStream> stream)
+      : file_(std::move(file)),
+        stream_(std::move(stream)),
+        offset_(0) {
+  }
+
+  virtual rocksdb::Status Read(size_t n, rocksdb::Slice* result, char* scratch) override {
+    rocksdb::Status status = file_->Read(n, result, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    if (result->data() != scratch) {
+      memmove(scratch, result->data(), result->size());
+    }
+    status = stream_->XOR(offset_, scratch, result->size());
+    if (!status.ok()) {
+      return status;
+    }
+    *result = rocksdb::Slice(scratch, result->size());
+    offset_ += result->size();
+    return status;
+  }
+
+  virtual rocksdb::Status Skip(uint64_t n) override {
+    rocksdb::Status status = file_->Skip(n);
+    if (status.ok()) {
+      offset_ += n;
+    }
+    return status;
+  }
+
+  virtual rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::SequentialFile> file_;
+  const std::unique_ptr<CipherStream> stream_;
+  uint64_t offset_;
+};
+
+class EncryptedRandomAccessFile : public rocksdb::RandomAccessFile {
+ public:
+  EncryptedRandomAccessFile(std::unique_ptr<rocksdb::RandomAccessFile> file,
+                            std::unique_ptr<CipherStream> stream)
+      : file_(std::move(file)),
+        stream_(std::move(stream)) {
+  }
+
+  virtual rocksdb::Status Read(uint64_t offset, size_t n, rocksdb::Slice* result,
+                               char* scratch) const override {
+    rocksdb::Status status = file_->Read(offset + kHeaderSize, n, result, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    if (result->data() != scratch) {
+      memmove(scratch, result->data(), result->size());
+    }
+    status = stream_->XOR(offset, scratch, result->size());
+    if (!status.ok()) {
+      return status;
+    }
+    *result = rocksdb::Slice(scratch, result->size());
+    return status;
+  }
+
+  virtual size_t GetUniqueId(char* id, size_t max_size) const override {
+    return file_->GetUniqueId(id, max_size);
+  }
+
+  virtual void Hint(AccessPattern pattern) override {
+    file_->Hint(pattern);
+  }
+
+  virtual rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::RandomAccessFile> file_;
+  const std::unique_ptr<CipherStream> stream_;
+};
+
+class EncryptedWritableFile : public rocksdb::WritableFile {
+ public:
+  EncryptedWritableFile(std::unique_ptr<rocksdb::WritableFile> file,
+                        std::unique_ptr<CipherStream> stream)
+      : file_(std::move(file)),
+        stream_(std::move(stream)),
+        offset_(0) {
+  }
+
+  virtual rocksdb::Status Append(const rocksdb::Slice& data) override {
+    std::string buf(data.data(), data.size());
+    rocksdb::Status status = stream_->XOR(offset_, &buf[0], buf.size());
+    if (!status.ok()) {
+      return status;
+    }
+    status = file_->Append(buf);
+    if (status.ok()) {
+      offset_ += buf.size();
+    }
+    return status;
+  }
+
+  virtual rocksdb::Status Truncate(uint64_t size) override {
+    rocksdb::Status status = file_->Truncate(size + kHeaderSize);
+    if (status.ok()) {
+      offset_ = size;
+    }
+    return status;
+  }
+
+  virtual rocksdb::Status Close() override { return file_->Close(); }
+  virtual rocksdb::Status Flush() override { return file_->Flush(); }
+  virtual rocksdb::Status Sync() override { return file_->Sync(); }
+  virtual rocksdb::Status Fsync() override { return file_->Fsync(); }
+  virtual bool IsSyncThreadSafe() const override { return file_->IsSyncThreadSafe(); }
+  virtual uint64_t GetFileSize() override { return offset_; }
+
+  virtual rocksdb::Status InvalidateCache(size_t offset, size_t length) override {
+    return file_->InvalidateCache(offset + kHeaderSize, length);
+  }
+
+ private:
+  std::unique_ptr<rocksdb::WritableFile> file_;
+  const std::unique_ptr<CipherStream> stream_;
+  uint64_t offset_;
+};
+
+class EncryptedRandomRWFile : public rocksdb::RandomRWFile {
+ public:
+  EncryptedRandomRWFile(std::unique_ptr<rocksdb::RandomRWFile> file,
+                        std::unique_ptr<CipherStream> stream)
+      : file_(std::move(file)),
+        stream_(std::move(stream)) {
+  }
+
+  virtual rocksdb::Status Write(uint64_t offset, const rocksdb::Slice& data) override {
+    std::string buf(data.data(), data.size());
+    rocksdb::Status status = stream_->XOR(offset, &buf[0], buf.size());
+    if (!status.ok()) {
+      return status;
+    }
+    return file_->Write(offset + kHeaderSize, buf);
+  }
+
+  virtual rocksdb::Status Read(uint64_t offset, size_t n, rocksdb::Slice* result,
+                               char* scratch) const override {
+    rocksdb::Status status = file_->Read(offset + kHeaderSize, n, result, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    if (result->data() != scratch) {
+      memmove(scratch, result->data(), result->size());
+    }
+    status = stream_->XOR(offset, scratch, result->size());
+    if (!status.ok()) {
+      return status;
+    }
+    *result = rocksdb::Slice(scratch, result->size());
+    return status;
+  }
+
+  virtual rocksdb::Status Flush() override { return file_->Flush(); }
+  virtual rocksdb::Status Sync() override { return file_->Sync(); }
+  virtual rocksdb::Status Fsync() override { return file_->Fsync(); }
+  virtual rocksdb::Status Close() override { return file_->Close(); }
+
+ private:
+  std::unique_ptr<rocksdb::RandomRWFile> file_;
+  const std::unique_ptr<CipherStream> stream_;
+};
+
+class EncryptedEnv : public rocksdb::EnvWrapper {
+ public:
+  // EncryptedEnv encrypts new files with the key with ID active_id,
+  // or writes them in plaintext if it is empty.
+  EncryptedEnv(rocksdb::Env* base, const std::string& active_id,
+               const std::map<std::string, std::string>& keys)
+      : rocksdb::EnvWrapper(base),
+        active_id_(active_id),
+        keys_(keys) {
+  }
+
+  virtual rocksdb::Status NewSequentialFile(const std::string& fname,
+                                            std::unique_ptr<rocksdb::SequentialFile>* result,
+                                            const rocksdb::EnvOptions& options) override {
+    bool encrypted;
+    std::unique_ptr<CipherStream> stream;
+    rocksdb::Status status = ReadHeader(fname, options, &encrypted, &stream);
+    if (!status.ok()) {
+      return status;
+    }
+    std::unique_ptr<rocksdb::SequentialFile> file;
+    status = target()->NewSequentialFile(fname, &file, options);
+    if (!status.ok() || !encrypted) {
+      *result = std::move(file);
+      return status;
+    }
+    status = file->Skip(kHeaderSize);
+    if (!status.ok()) {
+      return status;
+    }
+    result->reset(new EncryptedSequentialFile(std::move(file), std::move(stream)));
+    return status;
+  }
+
+  virtual rocksdb::Status NewRandomAccessFile(const std::string& fname,
+                                              std::unique_ptr<rocksdb::RandomAccessFile>* result,
+                                              const rocksdb::EnvOptions& options) override {
+    bool encrypted;
+    std::unique_ptr<CipherStream> stream;
+    rocksdb::Status status = ReadHeader(fname, options, &encrypted, &stream);
+    if (!status.ok()) {
+      return status;
+    }
+    std::unique_ptr<rocksdb::RandomAccessFile> file;
+    status = target()->NewRandomAccessFile(fname, &file, options);
+    if (!status.ok() || !encrypted) {
+      *result = std::move(file);
+      return status;
+    }
+    result->reset(new EncryptedRandomAccessFile(std::move(file), std::move(stream)));
+    return status;
+  }
+
+  virtual rocksdb::Status NewWritableFile(const std::string& fname,
+                                          std::unique_ptr<rocksdb::WritableFile>* result,
+                                          const rocksdb::EnvOptions& options) override {
+    std::unique_ptr<rocksdb::WritableFile> file;
+    rocksdb::Status status = target()->NewWritableFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    return WrapWritableFile(std::move(file), result);
+  }
+
+  virtual rocksdb::Status ReuseWritableFile(const std::string& fname,
+                                            const std::string& old_fname,
+                                            std::unique_ptr<rocksdb::WritableFile>* result,
+                                            const rocksdb::EnvOptions& options) override {
+    // The reused file is overwritten from its start, so it gets a new
+    // header (and initialization vector) like a new file. Its old
+    // contents are left past the new ones, which makes them garbage to
+    // the readers of the new ones, as they are for plaintext files.
+    std::unique_ptr<rocksdb::WritableFile> file;
+    rocksdb::Status status = target()->ReuseWritableFile(fname, old_fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    return WrapWritableFile(std::move(file), result);
+  }
+
+  virtual rocksdb::Status NewRandomRWFile(const std::string& fname,
+                                          std::unique_ptr<rocksdb::RandomRWFile>* result,
+                                          const rocksdb::EnvOptions& options) override {
+    // Files which don't exist yet or are empty are given a header,
+    // others are read as they were written.
+    uint64_t size;
+    if (!target()->GetFileSize(fname, &size).ok()) {
+      size = 0;
+    }
+    bool encrypted = false;
+    std::unique_ptr<CipherStream> stream;
+    rocksdb::Status status;
+    if (size > 0) {
+      status = ReadHeader(fname, options, &encrypted, &stream);
+      if (!status.ok()) {
+        return status;
+      }
+    }
+    std::unique_ptr<rocksdb::RandomRWFile> file;
+    status = target()->NewRandomRWFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    if (size == 0 && !active_id_.empty()) {
+      std::string header;
+      status = NewHeader(&header, &stream);
+      if (!status.ok()) {
+        return status;
+      }
+      status = file->Write(0, header);
+      if (!status.ok()) {
+        return status;
+      }
+      encrypted = true;
+    }
+    if (!encrypted) {
+      *result = std::move(file);
+      return status;
+    }
+    result->reset(new EncryptedRandomRWFile(std::move(file), std::move(stream)));
+    return status;
+  }
+
+  virtual rocksdb::Status GetFileSize(const std::string& fname, uint64_t* size) override {
+    bool encrypted;
+    rocksdb::Status status = ReadHeader(fname, rocksdb::EnvOptions(), &encrypted, nullptr);
+    if (!status.ok()) {
+      return status;
+    }
+    status = target()->GetFileSize(fname, size);
+    if (status.ok() && encrypted) {
+      *size -= kHeaderSize;
+    }
+    return status;
+  }
+
+ private:
+  // WrapWritableFile writes the header of a new file with the active
+  // key and wraps it to encrypt its contents, or returns it as is if
+  // new files are written in plaintext.
+  rocksdb::Status WrapWritableFile(std::unique_ptr<rocksdb::WritableFile> file,
+                                   std::unique_ptr<rocksdb::WritableFile>* result) {
+    if (active_id_.empty()) {
+      *result = std::move(file);
+      return rocksdb::Status::OK();
+    }
+    std::string header;
+    std::unique_ptr<CipherStream> stream;
+    rocksdb::Status status = NewHeader(&header, &stream);
+    if (!status.ok()) {
+      return status;
+    }
+    status = file->Append(header);
+    if (!status.ok()) {
+      return status;
+    }
+    result->reset(new EncryptedWritableFile(std::move(file), std::move(stream)));
+    return status;
+  }
+
+  // NewHeader sets up the keystream of a new file encrypted with the
+  // active key, using a new initialization vector, and returns the
+  // header to write at the start of the file.
+  rocksdb::Status NewHeader(std::string* header, std::unique_ptr<CipherStream>* stream) {
+    std::string iv;
+    rocksdb::Status status = CipherStream::New(keys_.at(active_id_), &iv, stream);
+    if (!status.ok()) {
+      return status;
+    }
+    header->assign(kMagic, kMagicSize);
+    header->append(active_id_);
+    header->append(iv);
+    return status;
+  }
+
+  // ReadHeader reads the header of the file to determine whether it
+  // is encrypted and, if so and stream is not null, sets up the
+  // keystream to decrypt it with.
+  rocksdb::Status ReadHeader(const std::string& fname, const rocksdb::EnvOptions& options,
+                             bool* encrypted, std::unique_ptr<CipherStream>* stream) {
+    *encrypted = false;
+    std::unique_ptr<rocksdb::RandomAccessFile> file;
+    rocksdb::Status status = target()->NewRandomAccessFile(fname, &file, options);
+    if (!status.ok()) {
+      return status;
+    }
+    char scratch[kHeaderSize];
+    rocksdb::Slice header;
+    status = file->Read(0, kHeaderSize, &header, scratch);
+    if (!status.ok()) {
+      return status;
+    }
+    if (header.size() < kHeaderSize || memcmp(header.data(), kMagic, kMagicSize) != 0) {
+      // The file was written in plaintext.
+      return status;
+    }
+    const std::string id(header.data() + kMagicSize, kKeyIDSize);
+    auto it = keys_.find(id);
+    if (it == keys_.end()) {
+      return rocksdb::Status::InvalidArgument(
+          fname, "encrypted with a key which is neither the active key nor an old key");
+    }
+    *encrypted = true;
+    if (stream == nullptr) {
+      return status;
+    }
+    std::string iv(header.data() + kMagicSize + kKeyIDSize, kIVSize);
+    return CipherStream::New(it->second, &iv, stream);
+  }
+
+  const std::string active_id_;
+  const std::map<std::string, std::string> keys_;
+};
+
+// DecodeKeys decodes the keys encoded in s as described for DBOptions
+// into a map from key ID to key, and returns the ID of the last one.
+rocksdb::Status DecodeKeys(DBSlice s, std::map<std::string, std::string>* keys,
+                           std::string* last_id) {
+  const char* p = s.data;
+  const char* const end = s.data + s.len;
+  while (p < end) {
+    const size_t key_size = static_cast<unsigned char>(*p++);
+    if (key_size != 16 && key_size != 24 && key_size != 32) {
+      return rocksdb::Status::InvalidArgument("invalid encryption key size");
+    }
+    if (end - p < static_cast<ptrdiff_t>(kKeyIDSize + key_size)) {
+      return rocksdb::Status::InvalidArgument("truncated encryption key");
+    }
+    *last_id = std::string(p, kKeyIDSize);
+    (*keys)[*last_id] = std::string(p + kKeyIDSize, key_size);
+    p += kKeyIDSize + key_size;
+  }
+  return rocksdb::Status::OK();
+}
+
+}  // namespace
+
+rocksdb::Status NewEncryptedEnv(rocksdb::Env* base, DBSlice active_key,
+                                DBSlice old_keys, rocksdb::Env** env) {
+  std::map<std::string, std::string> keys;
+  std::string active_id;
+  rocksdb::Status status = DecodeKeys(active_key, &keys, &active_id);
+  if (!status.ok()) {
+    return status;
+  }
+  std::string old_id;
+  status = DecodeKeys(old_keys, &keys, &old_id);
+  if (!status.ok()) {
+    return status;
+  }
+  *env = new EncryptedEnv(base, active_id, keys);
+  return status;
+}
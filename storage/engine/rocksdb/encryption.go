@@ -0,0 +1,121 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package rocksdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"unsafe"
+
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+import "C"
+
+// encryptionStream is the AES-CTR keystream of an encrypted file.
+type encryptionStream struct {
+	block cipher.Block
+	iv    []byte
+}
+
+// encryptionStreams holds the keystreams of the files opened by the
+// encrypted env in encryption.cc, which refers to them by ID. A keystream is
+// set up once per file, when it is created or opened, and released when the
+// file is closed.
+var encryptionStreams struct {
+	syncutil.RWMutex
+	lastID  uint64
+	streams map[uint64]*encryptionStream
+}
+
+const maxLen = 0x7fffffff
+
+// rocksDBEncryptionNewStream sets up the keystream of the key and the
+// initialization vector of a file, and returns its ID, or 0 if the key is
+// invalid. If newIV is set, the initialization vector is first filled with
+// random bytes from crypto/rand.
+//
+//export rocksDBEncryptionNewStream
+func rocksDBEncryptionNewStream(key *C.char, keyLen C.int, iv *C.char, newIV bool) C.uint64_t {
+	block, err := aes.NewCipher(C.GoBytes(unsafe.Pointer(key), keyLen))
+	if err != nil {
+		return 0
+	}
+	if newIV {
+		if _, err := rand.Read((*[maxLen]byte)(unsafe.Pointer(iv))[:aes.BlockSize:aes.BlockSize]); err != nil {
+			return 0
+		}
+	}
+	s := &encryptionStream{block: block, iv: C.GoBytes(unsafe.Pointer(iv), aes.BlockSize)}
+
+	encryptionStreams.Lock()
+	defer encryptionStreams.Unlock()
+	if encryptionStreams.streams == nil {
+		encryptionStreams.streams = map[uint64]*encryptionStream{}
+	}
+	encryptionStreams.lastID++
+	id := encryptionStreams.lastID
+	encryptionStreams.streams[id] = s
+	return C.uint64_t(id)
+}
+
+// rocksDBEncryptionFreeStream releases the keystream with the given ID.
+//
+//export rocksDBEncryptionFreeStream
+func rocksDBEncryptionFreeStream(id C.uint64_t) {
+	encryptionStreams.Lock()
+	delete(encryptionStreams.streams, uint64(id))
+	encryptionStreams.Unlock()
+}
+
+// rocksDBEncryptionXOR XORs the n bytes of data found at the given offset of
+// a file with its keystream.
+//
+//export rocksDBEncryptionXOR
+func rocksDBEncryptionXOR(id C.uint64_t, offset C.uint64_t, data *C.char, n C.int) C.int {
+	encryptionStreams.RLock()
+	s, ok := encryptionStreams.streams[uint64(id)]
+	encryptionStreams.RUnlock()
+	if !ok {
+		return 1
+	}
+	buf := (*[maxLen]byte)(unsafe.Pointer(data))[:n:n]
+	xorKeyStream(s.block, s.iv, uint64(offset), buf)
+	return 0
+}
+
+// xorKeyStream XORs data with the CTR keystream of the block cipher and
+// initialization vector, starting at the given offset of the stream. This
+// allows the encrypted files to be read and written at arbitrary offsets.
+func xorKeyStream(block cipher.Block, iv []byte, offset uint64, data []byte) {
+	// Add the index of the block containing offset to the big-endian counter.
+	ctr := make([]byte, aes.BlockSize)
+	idx := offset / aes.BlockSize
+	var carry uint64
+	for i := aes.BlockSize - 1; i >= 0; i-- {
+		sum := uint64(iv[i]) + idx&0xff + carry
+		ctr[i] = byte(sum)
+		carry = sum >> 8
+		idx >>= 8
+	}
+	stream := cipher.NewCTR(block, ctr)
+	// Discard the keystream preceding offset in its block.
+	if skip := offset % aes.BlockSize; skip > 0 {
+		var scratch [aes.BlockSize]byte
+		stream.XORKeyStream(scratch[:skip], scratch[:skip])
+	}
+	stream.XORKeyStream(data, data)
+}
@@ -0,0 +1,31 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied.  See the License for the specific language governing
+// permissions and limitations under the License.
+
+#ifndef ROACHLIB_ENCRYPTION_H
+#define ROACHLIB_ENCRYPTION_H
+
+#include <rocksdb/env.h>
+#include "db.h"
+
+// NewEncryptedEnv returns an Env wrapping base which encrypts the
+// files it creates with the active key using AES-CTR, and decrypts
+// the files it opens with the key they were created with, which must
+// be either the active key or one of the old keys. The keys are
+// encoded as described for DBOptions. Files are written in plaintext
+// if there is no active key, and files written in plaintext, e.g.
+// before encryption was enabled, are read as such.
+rocksdb::Status NewEncryptedEnv(rocksdb::Env* base, DBSlice active_key,
+                                DBSlice old_keys, rocksdb::Env** env);
+
+#endif // ROACHLIB_ENCRYPTION_H
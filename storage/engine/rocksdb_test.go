@@ -28,8 +28,11 @@ import (
 	"strconv"
 	"testing"
 
+	"golang.org/x/net/context"
+
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 	"github.com/cockroachdb/cockroach/util/stop"
 )
@@ -46,6 +49,8 @@ func TestMinMemtableBudget(t *testing.T) {
 		0,
 		0,
 		DefaultMaxOpenFiles,
+		RocksDBTuning{},
+		EncryptionOptions{},
 		stop.NewStopper(),
 	)
 	const expected = "memtable budget must be at least"
@@ -54,6 +59,272 @@ func TestMinMemtableBudget(t *testing.T) {
 	}
 }
 
+func TestMaxWriteBufferSize(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	rocksdb := NewRocksDB(
+		roachpb.Attributes{},
+		".",
+		RocksDBCache{},
+		minMemtableBudget,
+		0,
+		DefaultMaxOpenFiles,
+		RocksDBTuning{WriteBufferSize: 2 * minMemtableBudget},
+		EncryptionOptions{},
+		stop.NewStopper(),
+	)
+	const expected = "write buffer size .* exceeds the memtable budget"
+	if err := rocksdb.Open(); !testutils.IsError(err, expected) {
+		t.Fatalf("expected %s, but got %v", expected, err)
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		name     string
+		expected Compression
+		err      string
+	}{
+		{"none", CompressionNone, ""},
+		{"snappy", CompressionSnappy, ""},
+		{"Snappy", CompressionSnappy, ""},
+		{"zlib", 0, "unsupported compression"},
+		{"", 0, "unsupported compression"},
+	}
+	for _, tc := range testCases {
+		c, err := ParseCompression(tc.name)
+		if tc.err != "" {
+			if !testutils.IsError(err, tc.err) {
+				t.Errorf("%q: expected error %q, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.name, err)
+		} else if c != tc.expected {
+			t.Errorf("%q: expected %s, got %s", tc.name, tc.expected, c)
+		}
+	}
+}
+
+func TestRocksDBCompactRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	db := NewInMem(roachpb.Attributes{}, testCacheSize, stopper)
+
+	value := make([]byte, 1<<10)
+	for i := 0; i < 100; i++ {
+		if err := db.Put(mvccKey(fmt.Sprintf("%03d", i)), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err := db.Clear(mvccKey(fmt.Sprintf("%03d", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.GetSSTables()) == 0 {
+		t.Fatal("expected sstables before compacting")
+	}
+
+	// Compacting a span which doesn't contain the keys leaves them be.
+	if err := db.CompactRange(roachpb.Key("a"), roachpb.Key("b")); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.GetSSTables()) == 0 {
+		t.Fatal("expected sstables after compacting an unrelated span")
+	}
+
+	// Compacting the keys drops both them and their deletion tombstones.
+	if err := db.CompactRange(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ssts := db.GetSSTables(); len(ssts) != 0 {
+		t.Fatalf("expected no sstables after compacting, got %s", ssts)
+	}
+}
+
+func TestRocksDBDeleteRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	db := NewInMem(roachpb.Attributes{}, testCacheSize, stopper)
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		if err := db.Put(mvccKey(k), []byte(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Keys flushed to sstables and keys still in the memtable are removed
+	// alike.
+	if err := db.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put(mvccKey("bb"), []byte("bb")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteRange(mvccKey("b"), mvccKey("d")); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := Scan(db, mvccKey("a"), mvccKey("z"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, kv := range kvs {
+		found = append(found, string(kv.Key.Key))
+	}
+	if expected := []string{"a", "d"}; !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected keys %s, got %s", expected, found)
+	}
+
+	if err := db.DeleteRange(mvccKey("d"), mvccKey("a")); !testutils.IsError(err, "invalid range") {
+		t.Errorf("expected invalid range error, got %v", err)
+	}
+}
+
+func TestRocksDBBatchDeleteRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	db := NewInMem(roachpb.Attributes{}, testCacheSize, stopper)
+
+	scanKeys := func(r Reader) []string {
+		kvs, err := Scan(r, mvccKey("a"), mvccKey("z"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, kv := range kvs {
+			found = append(found, string(kv.Key.Key))
+		}
+		return found
+	}
+
+	// The range deletion is applied at commit together with the other writes
+	// of the batch, whether or not the batch was read from before.
+	for _, readBeforeCommit := range []bool{false, true} {
+		for _, k := range []string{"a", "b", "c", "d"} {
+			if err := db.Put(mvccKey(k), []byte(k)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		b := db.NewBatch()
+		if err := b.Put(mvccKey("e"), []byte("e")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.DeleteRange(mvccKey("b"), mvccKey("d")); err != nil {
+			t.Fatal(err)
+		}
+		if readBeforeCommit {
+			// The deletion is not visible to reads from the batch.
+			if found, expected := scanKeys(b), []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(found, expected) {
+				t.Errorf("%t: expected keys %s in batch, got %s", readBeforeCommit, expected, found)
+			}
+		}
+		if found, expected := scanKeys(db), []string{"a", "b", "c", "d"}; !reflect.DeepEqual(found, expected) {
+			t.Errorf("%t: expected keys %s before commit, got %s", readBeforeCommit, expected, found)
+		}
+		if err := b.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		b.Close()
+		if found, expected := scanKeys(db), []string{"a", "d", "e"}; !reflect.DeepEqual(found, expected) {
+			t.Errorf("%t: expected keys %s after commit, got %s", readBeforeCommit, expected, found)
+		}
+		if err := db.Clear(mvccKey("e")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := db.NewBatch()
+	defer b.Close()
+	if err := b.DeleteRange(mvccKey("d"), mvccKey("a")); !testutils.IsError(err, "invalid range") {
+		t.Errorf("expected invalid range error, got %v", err)
+	}
+}
+
+func TestRocksDBTimeBoundIterator(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	stopper := stop.NewStopper()
+	defer stopper.Stop()
+	db := NewInMem(roachpb.Attributes{}, testCacheSize, stopper)
+
+	// Write each of the following to its own sstable: an old value, a recent
+	// value and an old intent.
+	ctx := context.Background()
+	for _, kv := range []struct {
+		key string
+		ts  hlc.Timestamp
+		txn *roachpb.Transaction
+	}{
+		{"a", makeTS(1, 0), nil},
+		{"c", makeTS(5, 0), nil},
+		{"d", makeTS(1, 0), makeTxn(*txn1, makeTS(1, 0))},
+	} {
+		if err := MVCCPut(ctx, db, nil, roachpb.Key(kv.key), kv.ts, value1, kv.txn); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		start, end hlc.Timestamp
+		expected   []MVCCKey
+	}{
+		// All the sstables overlap the interval.
+		{makeTS(0, 0), makeTS(10, 0), []MVCCKey{
+			{Key: roachpb.Key("a"), Timestamp: makeTS(1, 0)},
+			{Key: roachpb.Key("c"), Timestamp: makeTS(5, 0)},
+			{Key: roachpb.Key("d")},
+			{Key: roachpb.Key("d"), Timestamp: makeTS(1, 0)},
+		}},
+		// The sstable with the old value is skipped, but not the one with the
+		// old intent.
+		{makeTS(4, 0), makeTS(10, 0), []MVCCKey{
+			{Key: roachpb.Key("c"), Timestamp: makeTS(5, 0)},
+			{Key: roachpb.Key("d")},
+			{Key: roachpb.Key("d"), Timestamp: makeTS(1, 0)},
+		}},
+		// Only the sstable with the old intent overlaps the interval.
+		{makeTS(6, 0), makeTS(10, 0), []MVCCKey{
+			{Key: roachpb.Key("d")},
+			{Key: roachpb.Key("d"), Timestamp: makeTS(1, 0)},
+		}},
+	}
+	for i, c := range testCases {
+		var keys []MVCCKey
+		iter := db.NewTimeBoundIterator(c.start, c.end)
+		for iter.Seek(NilKey); iter.Valid(); iter.Next() {
+			keys = append(keys, iter.Key())
+		}
+		if err := iter.Error(); err != nil {
+			t.Fatal(err)
+		}
+		iter.Close()
+		if !reflect.DeepEqual(keys, c.expected) {
+			t.Errorf("%d: expected keys %v, got %v", i, c.expected, keys)
+		}
+	}
+}
+
 func TestBatchIterReadOwnWrite(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -247,6 +518,8 @@ func openRocksDBWithVersion(t *testing.T, hasVersionFile bool, ver Version) erro
 		minMemtableBudget,
 		0,
 		DefaultMaxOpenFiles,
+		RocksDBTuning{},
+		EncryptionOptions{},
 		stopper,
 	)
 	return rocksdb.Open()
@@ -277,6 +550,8 @@ func TestCheckpoint(t *testing.T) {
 			minMemtableBudget,
 			0,
 			DefaultMaxOpenFiles,
+			RocksDBTuning{},
+			EncryptionOptions{},
 			stopper,
 		)
 		if err := db.Open(); err != nil {
@@ -312,6 +587,8 @@ func TestCheckpoint(t *testing.T) {
 			minMemtableBudget,
 			0,
 			DefaultMaxOpenFiles,
+			RocksDBTuning{},
+			EncryptionOptions{},
 			stopper,
 		)
 		if err := db.Open(); err != nil {
@@ -430,3 +707,96 @@ func TestReadAmplification(t *testing.T) {
 		t.Errorf("got %d, expected %d", a, e)
 	}
 }
+
+func TestRocksDBSstFileWriter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	fw, err := MakeRocksDBSstFileWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fw.Close()
+
+	for _, k := range []string{"a", "b", "c"} {
+		kv := MVCCKeyValue{
+			Key:   MVCCKey{Key: roachpb.Key(k), Timestamp: makeTS(1, 0)},
+			Value: []byte("value"),
+		}
+		if err := fw.Add(kv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fw.Add(MVCCKeyValue{Key: MakeMVCCMetadataKey(roachpb.Key("a"))}); err == nil {
+		t.Fatal("expected an error adding an out of order key")
+	}
+	if fw.DataSize != 3*(1+5) {
+		t.Errorf("expected a data size of %d, got %d", 3*(1+5), fw.DataSize)
+	}
+	data, err := fw.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) == 0 {
+		t.Fatal("expected a non-empty sstable")
+	}
+
+	fw.Close()
+	if _, err := fw.Finish(); !testutils.IsError(err, "closed writer") {
+		t.Fatalf("expected an error finishing a closed writer, got %v", err)
+	}
+}
+
+func TestRocksDBSstFileReader(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	makeSST := func(keys ...string) []byte {
+		fw, err := MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fw.Close()
+		for _, k := range keys {
+			kv := MVCCKeyValue{
+				Key:   MVCCKey{Key: roachpb.Key(k), Timestamp: makeTS(1, 0)},
+				Value: []byte("value-" + k),
+			}
+			if err := fw.Add(kv); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := fw.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	fr, err := MakeRocksDBSstFileReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Close()
+	if err := fr.IngestExternalFile(makeSST("a", "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fr.IngestExternalFile(makeSST("d", "e")); err != nil {
+		t.Fatal(err)
+	}
+
+	var kvs []string
+	if err := fr.Iterate(MVCCKey{Key: roachpb.Key("b")}, MVCCKey{Key: roachpb.Key("e")},
+		func(kv MVCCKeyValue) (bool, error) {
+			kvs = append(kvs, fmt.Sprintf("%s=%s", kv.Key.Key, kv.Value))
+			return false, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "[c=value-c d=value-d]"; fmt.Sprint(kvs) != expected {
+		t.Errorf("expected %s, got %s", expected, kvs)
+	}
+
+	fr.Close()
+	if err := fr.IngestExternalFile(makeSST("f")); !testutils.IsError(err, "closed reader") {
+		t.Fatalf("expected an error ingesting into a closed reader, got %v", err)
+	}
+}
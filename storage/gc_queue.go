@@ -124,7 +124,11 @@ func (*gcQueue) shouldQueue(now hlc.Timestamp, repl *Replica,
 
 	ms := repl.GetMVCCStats()
 	// GC score is the total GC'able bytes age normalized by 1 MB * the replica's TTL in seconds.
-	gcScore := float64(ms.GCByteAge(now.WallTime)) / float64(zone.GC.TTLSeconds) / float64(gcByteCountNormalization)
+	// Old versions are never GC'd in zones with a non-positive TTL.
+	var gcScore float64
+	if zone.GC.TTLSeconds > 0 {
+		gcScore = float64(ms.GCByteAge(now.WallTime)) / float64(zone.GC.TTLSeconds) / float64(gcByteCountNormalization)
+	}
 
 	// Intent score. This computes the average age of outstanding intents
 	// and normalizes.
@@ -301,8 +305,15 @@ func (gcq *gcQueue) process(
 	if err != nil {
 		return errors.Errorf("could not find zone config for range %s: %s", repl, err)
 	}
+	protected, err := sysCfg.GetProtectedTimestamp(roachpb.Span{
+		Key:    desc.StartKey.AsRawKey(),
+		EndKey: desc.EndKey.AsRawKey(),
+	})
+	if err != nil {
+		return errors.Errorf("could not find protected timestamps for range %s: %s", repl, err)
+	}
 
-	gcKeys, info, err := RunGC(ctx, desc, snap, now, zone.GC,
+	gcKeys, info, err := RunGC(ctx, desc, snap, now, zone.GC, protected,
 		func(now hlc.Timestamp, txn *roachpb.Transaction, typ roachpb.PushTxnType) {
 			pushTxn(gcq.store.DB(), now, txn, typ)
 		},
@@ -381,13 +392,15 @@ type lockableGCInfo struct {
 // Engine (which is not mutated). It uses the provided functions pushTxn and
 // resolveIntents to clarify the true status of and clean up after encountered
 // transactions. It returns a slice of gc'able keys from the data, transaction,
-// and abort spans.
+// and abort spans. If 'protected' is not zero, the versions needed to read the
+// range at that timestamp are kept regardless of the policy.
 func RunGC(
 	ctx context.Context,
 	desc *roachpb.RangeDescriptor,
 	snap engine.Reader,
 	now hlc.Timestamp,
 	policy config.GCPolicy,
+	protected hlc.Timestamp,
 	pushTxn pushFunc,
 	resolveIntents resolveFunc,
 ) ([]roachpb.GCRequest_GCKey, GCInfo, error) {
@@ -422,6 +435,10 @@ func RunGC(
 	}
 
 	gc := engine.MakeGarbageCollector(now, policy)
+	if protected != hlc.ZeroTimestamp && !gc.Threshold.Less(protected) {
+		// Reads are only allowed above the threshold.
+		gc.Threshold = protected.Prev()
+	}
 	infoMu.Threshold = gc.Threshold
 
 	var gcKeys []roachpb.GCRequest_GCKey
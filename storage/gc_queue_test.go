@@ -27,6 +27,7 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/storage/engine"
@@ -158,6 +159,45 @@ func TestGCQueueShouldQueue(t *testing.T) {
 	}
 }
 
+// TestGCQueueShouldQueueDisabledTTL verifies that the GC'able bytes don't
+// queue the replicas of a zone whose GC TTL is not positive, as their old
+// versions are never GC'd.
+func TestGCQueueShouldQueueDisabledTTL(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	cfg, ok := tc.gossip.GetSystemConfig()
+	if !ok {
+		t.Fatal("config not set")
+	}
+	zone := config.DefaultZoneConfig()
+	zone.GC.TTLSeconds = 0
+	config.TestingSetZoneConfig(keys.RootNamespaceID, zone)
+
+	bc := int64(gcByteCountNormalization)
+	now := makeTS(considerThreshold*intentAgeNormalization.Nanoseconds(), 0)
+	ms := enginepb.MVCCStats{
+		KeyBytes:        bc,
+		GCBytesAge:      1000 * bc * considerThreshold,
+		LastUpdateNanos: now.WallTime,
+	}
+	func() {
+		tc.rng.mu.Lock()
+		defer tc.rng.mu.Unlock()
+		if err := setMVCCStats(context.Background(), tc.rng.store.Engine(), tc.rng.RangeID, ms); err != nil {
+			t.Fatal(err)
+		}
+		tc.rng.mu.state.Stats = ms
+	}()
+
+	gcQ := newGCQueue(tc.store, tc.gossip)
+	if shouldQ, priority := gcQ.shouldQueue(now, tc.rng, cfg); shouldQ {
+		t.Errorf("expected the replica not to be queued, got priority %f", priority)
+	}
+}
+
 // TestGCQueueProcess creates test data in the range over various time
 // scales and verifies that scan queue process properly GCs test data.
 func TestGCQueueProcess(t *testing.T) {
@@ -336,6 +376,72 @@ func TestGCQueueProcess(t *testing.T) {
 	}
 }
 
+// TestGCQueueProcessProtected verifies that the versions needed to read
+// the protected spans at their protected timestamp are not GC'ed.
+func TestGCQueueProcessProtected(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	const now int64 = 48 * 60 * 60 * 1E9 // 2d past the epoch
+	tc.manualClock.Set(now)
+
+	ts1 := makeTS(now-2*24*60*60*1E9+1, 0) // 2d old
+	ts2 := makeTS(now-25*60*60*1E9, 0)     // past the 1d TTL
+	key1 := roachpb.Key("a")
+
+	for _, ts := range []hlc.Timestamp{ts1, ts2} {
+		pArgs := putArgs(key1, []byte("value"))
+		if _, err := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &pArgs); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Protect key1 at a timestamp which can only be read from ts1. The whole
+	// range is protected, as GC thresholds are per range.
+	defer config.TestingSetProtectedTimestamps(config.ProtectedTimestamp{
+		Timestamp: ts1.Next(),
+		Spans:     []roachpb.Span{{Key: key1, EndKey: key1.Next()}},
+	})()
+
+	cfg, ok := tc.gossip.GetSystemConfig()
+	if !ok {
+		t.Fatal("config not set")
+	}
+	gcQ := newGCQueue(tc.store, tc.gossip)
+	if err := gcQ.process(context.Background(), tc.clock.Now(), tc.rng, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	expKVs := []struct {
+		key roachpb.Key
+		ts  hlc.Timestamp
+	}{
+		{key1, ts2},
+		{key1, ts1},
+	}
+	kvs, err := engine.Scan(tc.store.Engine(), engine.MakeMVCCMetadataKey(key1),
+		engine.MakeMVCCMetadataKey(keys.MaxKey), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != len(expKVs) {
+		t.Fatalf("expected length %d; got %d", len(expKVs), len(kvs))
+	}
+	for i, kv := range kvs {
+		if !kv.Key.Key.Equal(expKVs[i].key) || !kv.Key.Timestamp.Equal(expKVs[i].ts) {
+			t.Errorf("%d: expected %q@%s; got %s", i, expKVs[i].key, expKVs[i].ts, kv.Key)
+		}
+	}
+
+	// The protected data can still be read at the protected timestamp.
+	gArgs := getArgs(key1)
+	if _, err := tc.SendWrappedWith(roachpb.Header{Timestamp: ts1.Next()}, &gArgs); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestGCQueueTransactionTable(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 
@@ -22,6 +22,8 @@
 package storage
 
 import (
+	"golang.org/x/net/context"
+
 	"github.com/cockroachdb/cockroach/internal/client"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
@@ -61,6 +63,18 @@ func (s *Store) ForceReplicationScanAndProcess() {
 	s.replicateQueue.DrainQueue(s.ctx.Clock)
 }
 
+// ForceMergeScanAndProcess iterates over all ranges and enqueues any that
+// may need to be merged.
+func (s *Store) ForceMergeScanAndProcess() {
+	s.mu.Lock()
+	for _, r := range s.mu.replicas {
+		s.mergeQueue.MaybeAdd(r, s.ctx.Clock.Now())
+	}
+	s.mu.Unlock()
+
+	s.mergeQueue.DrainQueue(s.ctx.Clock)
+}
+
 // ForceReplicaGCScanAndProcess iterates over all ranges and enqueues any that
 // may need to be GC'd.
 func (s *Store) ForceReplicaGCScanAndProcess() {
@@ -153,6 +167,21 @@ func (r *Replica) GetLastIndex() (uint64, error) {
 	return r.LastIndex()
 }
 
+// AddLearner exposes replica.addLearner for tests.
+func (r *Replica) AddLearner(
+	ctx context.Context, repDesc roachpb.ReplicaDescriptor, desc *roachpb.RangeDescriptor,
+) (roachpb.ReplicaDescriptor, *roachpb.RangeDescriptor, error) {
+	return r.addLearner(ctx, repDesc, desc)
+}
+
+// QuiescentState returns whether the raft group of the replica is quiescent,
+// and whether it is unloaded.
+func (r *Replica) QuiescentState() (quiescent bool, unloaded bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mu.quiescent, r.mu.internalRaftGroup == nil
+}
+
 // GetLease exposes replica.getLease for tests.
 func (r *Replica) GetLease() (*roachpb.Lease, *roachpb.Lease) {
 	return r.getLease()
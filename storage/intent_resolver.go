@@ -38,6 +38,23 @@ import (
 // TODO(bdarnell): how to determine best value?
 const intentResolverTaskLimit = 100
 
+// intentResolverBatchSize is the maximum number of intents of finalized
+// transactions resolved by a single batch. The intents of multiple
+// transactions are resolved together, which the DistSender turns into a
+// single request per range they belong to.
+const intentResolverBatchSize = 100
+
+// intentResolverBatchConcurrency is the maximum number of batches of intents
+// of finalized transactions resolved in parallel.
+const intentResolverBatchConcurrency = 8
+
+// finalizedTxnIntents holds the intents of a finalized transaction which
+// are queued for resolution, after which its record is garbage collected.
+type finalizedTxnIntents struct {
+	txn     enginepb.TxnMeta
+	intents []roachpb.Intent
+}
+
 // intentResolver manages the process of pushing transactions and
 // resolving intents.
 type intentResolver struct {
@@ -49,6 +66,11 @@ type intentResolver struct {
 		syncutil.Mutex
 		// Maps transaction ids to a refcount.
 		inFlight map[uuid.UUID]int
+		// The intents of finalized transactions waiting to be resolved, and
+		// the number of tasks resolving them.
+		pending        []finalizedTxnIntents
+		pendingIntents int
+		batchers       int
 	}
 }
 
@@ -308,49 +330,153 @@ func (ir *intentResolver) processIntentsAsync(r *Replica, intents []intentsWithA
 				return
 			}
 		} else { // EndTransaction
-			if err := stopper.RunLimitedAsyncTask(ir.sem, func() {
-				ctxWithTimeout, cancel := context.WithTimeout(ctx, base.NetworkTimeout)
-				defer cancel()
-
-				// For EndTransaction, we know the transaction is finalized so
-				// we can skip the push and go straight to the resolve.
-				//
-				// This mechanism assumes that when an EndTransaction fails,
-				// the client makes no assumptions about the result. For
-				// example, an attempt to explicitly rollback the transaction
-				// may succeed (triggering this code path), but the result may
-				// not make it back to the client.
-				if err := ir.resolveIntents(ctxWithTimeout, item.intents,
-					true /* wait */, false /* !poison */); err != nil {
-					log.Warningf(context.TODO(), "%s: failed to resolve intents: %s", r, err)
-					return
-				}
-
-				// We successfully resolved the intents, so we're able to GC from
-				// the txn span directly.
-				b := &client.Batch{}
-				txn := item.intents[0].Txn
-				gcArgs := roachpb.GCRequest{
-					Span: roachpb.Span{
-						Key:    txn.Key,
-						EndKey: roachpb.Key(txn.Key).Next(),
-					},
-				}
-				gcArgs.Keys = append(gcArgs.Keys, roachpb.GCRequest_GCKey{
-					Key: keys.TransactionKey(txn.Key, txn.ID),
-				})
-				b.AddRawRequest(&gcArgs)
-				if err := ir.store.db.Run(b); err != nil {
-					log.Warningf(context.TODO(), "could not GC completed transaction: %s", err)
-				}
+			if item.args.(*roachpb.EndTransactionRequest).Commit {
+				// The intents returned by EndTransaction are those outside
+				// of the range holding the transaction record.
+				ir.store.metrics.txnCrossRangeCommits.Inc(1)
+				ir.store.metrics.txnCrossRangeIntents.RecordValue(int64(len(item.intents)))
+			}
+			// For EndTransaction, we know the transaction is finalized so we
+			// can skip the push and go straight to the resolve.
+			//
+			// This mechanism assumes that when an EndTransaction fails, the
+			// client makes no assumptions about the result. For example, an
+			// attempt to explicitly rollback the transaction may succeed
+			// (triggering this code path), but the result may not make it back
+			// to the client.
+			if err := ir.queueFinalizedTxnIntents(finalizedTxnIntents{
+				txn:     item.intents[0].Txn,
+				intents: item.intents,
 			}); err != nil {
-				log.Warningf(context.TODO(), "failed to resolve intents: %s", err)
+				log.Warningf(context.TODO(), "%s: failed to resolve intents: %s", r, err)
 				return
 			}
 		}
 	}
 }
 
+// queueFinalizedTxnIntents queues the intents of a finalized transaction
+// for resolution, starting a task to resolve them unless the maximum
+// number of tasks are already running; the running tasks resolve the
+// queued intents until there is none left.
+func (ir *intentResolver) queueFinalizedTxnIntents(item finalizedTxnIntents) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	if ir.mu.batchers < intentResolverBatchConcurrency {
+		if err := ir.store.Stopper().RunAsyncTask(ir.resolveFinalizedTxnIntents); err != nil {
+			// When draining, the intents are only queued if a task is
+			// running to pick them up.
+			if ir.mu.batchers == 0 {
+				return err
+			}
+		} else {
+			ir.mu.batchers++
+		}
+	}
+	ir.mu.pending = append(ir.mu.pending, item)
+	ir.mu.pendingIntents += len(item.intents)
+	ir.store.metrics.intentResolverPendingIntents.Update(int64(ir.mu.pendingIntents))
+	return nil
+}
+
+// nextFinalizedTxnIntents dequeues the intents of finalized transactions to
+// resolve in a single batch, returning nil, and marking the calling task
+// as exiting, if there is none left.
+func (ir *intentResolver) nextFinalizedTxnIntents() []finalizedTxnIntents {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	// Always take at least one transaction, even if it has more intents
+	// than fit in a batch.
+	var n, numIntents int
+	for n < len(ir.mu.pending) {
+		numIntents += len(ir.mu.pending[n].intents)
+		if n > 0 && numIntents > intentResolverBatchSize {
+			numIntents -= len(ir.mu.pending[n].intents)
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		ir.mu.batchers--
+		return nil
+	}
+	items := ir.mu.pending[:n:n]
+	ir.mu.pending = ir.mu.pending[n:]
+	ir.mu.pendingIntents -= numIntents
+	ir.store.metrics.intentResolverPendingIntents.Update(int64(ir.mu.pendingIntents))
+	return items
+}
+
+// resolveFinalizedTxnIntents resolves the queued intents of finalized
+// transactions in batches until the queue is empty.
+func (ir *intentResolver) resolveFinalizedTxnIntents() {
+	for {
+		items := ir.nextFinalizedTxnIntents()
+		if items == nil {
+			return
+		}
+		ir.resolveFinalizedTxnIntentsBatch(items)
+	}
+}
+
+// resolveFinalizedTxnIntentsBatch resolves the intents of the given
+// finalized transactions with a single batch, then garbage collects the
+// records of the transactions whose intents were all resolved. If the batch
+// fails, the intents of each transaction are resolved on their own, so that
+// an error doesn't hold back the other transactions.
+func (ir *intentResolver) resolveFinalizedTxnIntentsBatch(items []finalizedTxnIntents) {
+	ctx, cancel := context.WithTimeout(context.TODO(), base.NetworkTimeout)
+	defer cancel()
+
+	var intents []roachpb.Intent
+	for _, item := range items {
+		intents = append(intents, item.intents...)
+	}
+	resolved := items
+	ir.store.metrics.intentResolverBatches.Inc(1)
+	if err := ir.resolveIntents(ctx, intents, true /* wait */, false /* !poison */); err != nil {
+		if len(items) == 1 {
+			ir.store.metrics.intentResolverFailures.Inc(int64(len(intents)))
+			log.Warningf(ctx, "failed to resolve intents: %s", err)
+			return
+		}
+		resolved = nil
+		for _, item := range items {
+			ir.store.metrics.intentResolverBatches.Inc(1)
+			if err := ir.resolveIntents(ctx, item.intents, true /* wait */, false /* !poison */); err != nil {
+				ir.store.metrics.intentResolverFailures.Inc(int64(len(item.intents)))
+				log.Warningf(ctx, "failed to resolve intents: %s", err)
+				continue
+			}
+			resolved = append(resolved, item)
+		}
+	}
+	if len(resolved) == 0 {
+		return
+	}
+
+	// We successfully resolved the intents, so we're able to GC from the txn
+	// spans directly.
+	b := &client.Batch{}
+	for _, item := range resolved {
+		ir.store.metrics.intentResolverIntents.Inc(int64(len(item.intents)))
+		txn := item.txn
+		gcArgs := roachpb.GCRequest{
+			Span: roachpb.Span{
+				Key:    txn.Key,
+				EndKey: roachpb.Key(txn.Key).Next(),
+			},
+		}
+		gcArgs.Keys = append(gcArgs.Keys, roachpb.GCRequest_GCKey{
+			Key: keys.TransactionKey(txn.Key, txn.ID),
+		})
+		b.AddRawRequest(&gcArgs)
+	}
+	if err := ir.store.db.Run(b); err != nil {
+		log.Warningf(ctx, "could not GC completed transactions: %s", err)
+	}
+}
+
 // resolveIntents resolves the given intents. `wait` is currently a
 // no-op; all intents are resolved synchronously.
 //
@@ -20,10 +20,14 @@ package storage
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
 	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util"
 	"github.com/cockroachdb/cockroach/util/leaktest"
 )
 
@@ -42,3 +46,153 @@ func TestPushTransactionsWithNonPendingIntent(t *testing.T) {
 		t.Errorf("expected error on aborted/resolved intent, but got %s", pErr)
 	}
 }
+
+// TestIntentResolverBatchesFinalizedTxnIntents verifies that the intents of
+// finalized transactions are dequeued in batches of bounded size.
+func TestIntentResolverBatchesFinalizedTxnIntents(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	ir := newIntentResolver(&Store{metrics: newStoreMetrics()})
+
+	makeIntents := func(n int) []roachpb.Intent {
+		return make([]roachpb.Intent, n)
+	}
+	ir.mu.batchers = 1
+	for _, n := range []int{10, 20, intentResolverBatchSize - 30, 1, 2 * intentResolverBatchSize, 5} {
+		ir.mu.pending = append(ir.mu.pending, finalizedTxnIntents{intents: makeIntents(n)})
+		ir.mu.pendingIntents += n
+	}
+
+	// A transaction with more intents than fit in a batch is resolved on its
+	// own.
+	for i, exp := range []int{3, 1, 1, 1} {
+		items := ir.nextFinalizedTxnIntents()
+		if len(items) != exp {
+			t.Fatalf("%d: expected %d transactions, got %d", i, exp, len(items))
+		}
+	}
+	if items := ir.nextFinalizedTxnIntents(); items != nil {
+		t.Fatalf("expected no transaction left, got %d", len(items))
+	}
+	if ir.mu.batchers != 0 || ir.mu.pendingIntents != 0 {
+		t.Fatalf("expected no task and no pending intents, got %d and %d",
+			ir.mu.batchers, ir.mu.pendingIntents)
+	}
+}
+
+// TestIntentResolverResolvesFinalizedTxnIntents verifies that the queued
+// intents of finalized transactions are resolved.
+func TestIntentResolverResolvesFinalizedTxnIntents(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	var keys []roachpb.Key
+	for _, key := range []roachpb.Key{roachpb.Key("a"), roachpb.Key("b"), roachpb.Key("c")} {
+		txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.clock)
+		_, btH := beginTxnArgs(key, txn)
+		put := putArgs(key, []byte("value"))
+		if _, pErr := maybeWrapWithBeginTransaction(tc.Sender(), context.Background(), btH, &put); pErr != nil {
+			t.Fatal(pErr)
+		}
+		// Commit without resolving the intent.
+		args, h := endTxnArgs(txn, true /* commit */)
+		if _, pErr := tc.SendWrappedWith(h, &args); pErr != nil {
+			t.Fatal(pErr)
+		}
+		if err := tc.store.intentResolver.queueFinalizedTxnIntents(finalizedTxnIntents{
+			txn: txn.TxnMeta,
+			intents: []roachpb.Intent{
+				{Span: roachpb.Span{Key: key}, Txn: txn.TxnMeta, Status: roachpb.COMMITTED},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	util.SucceedsSoon(t, func() error {
+		for _, key := range keys {
+			meta := &enginepb.MVCCMetadata{}
+			ok, _, _, err := tc.engine.GetProto(engine.MakeMVCCMetadataKey(key), meta)
+			if err != nil {
+				return err
+			}
+			if !ok || meta.Txn != nil {
+				return errors.Errorf("expected intent on %s to be resolved", key)
+			}
+		}
+		if n := tc.store.metrics.intentResolverIntents.Count(); n != int64(len(keys)) {
+			return errors.Errorf("expected %d resolved intents, got %d", len(keys), n)
+		}
+		return nil
+	})
+}
+
+// TestIntentResolverCountsCrossRangeCommits verifies that the intents
+// returned by the EndTransaction of committed transactions are accounted for
+// as cross-range commits, and that those of aborted transactions are not.
+func TestIntentResolverCountsCrossRangeCommits(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	var keys []roachpb.Key
+	for i, txnKeys := range [][]roachpb.Key{
+		{roachpb.Key("a"), roachpb.Key("b")},
+		{roachpb.Key("c")},
+	} {
+		commit := i == 0
+		txn := newTransaction("test", txnKeys[0], 1, enginepb.SERIALIZABLE, tc.clock)
+		var intents []roachpb.Intent
+		for _, key := range txnKeys {
+			txn.Sequence++
+			put := putArgs(key, []byte("value"))
+			if _, pErr := maybeWrapWithBeginTransaction(tc.Sender(), context.Background(), roachpb.Header{
+				Txn: txn,
+			}, &put); pErr != nil {
+				t.Fatal(pErr)
+			}
+			txn.Writing = true
+			status := roachpb.ABORTED
+			if commit {
+				status = roachpb.COMMITTED
+			}
+			intents = append(intents, roachpb.Intent{Span: roachpb.Span{Key: key}, Txn: txn.TxnMeta, Status: status})
+			keys = append(keys, key)
+		}
+		// Finalize without resolving the intents.
+		txn.Sequence++
+		args, h := endTxnArgs(txn, commit)
+		if _, pErr := tc.SendWrappedWith(h, &args); pErr != nil {
+			t.Fatal(pErr)
+		}
+		tc.store.intentResolver.processIntentsAsync(tc.rng, []intentsWithArg{{
+			args:    &args,
+			intents: intents,
+		}})
+	}
+
+	if n := tc.store.metrics.txnCrossRangeCommits.Count(); n != 1 {
+		t.Errorf("expected 1 cross-range commit, got %d", n)
+	}
+	hist := tc.store.metrics.txnCrossRangeIntents.Current()
+	if n, max := hist.TotalCount(), hist.Max(); n != 1 || max != 2 {
+		t.Errorf("expected 1 cross-range commit with 2 intents, got %d with up to %d", n, max)
+	}
+
+	util.SucceedsSoon(t, func() error {
+		for _, key := range keys {
+			meta := &enginepb.MVCCMetadata{}
+			ok, _, _, err := tc.engine.GetProto(engine.MakeMVCCMetadataKey(key), meta)
+			if err != nil {
+				return err
+			}
+			if ok && meta.Txn != nil {
+				return errors.Errorf("expected intent on %s to be resolved", key)
+			}
+		}
+		return nil
+	})
+}
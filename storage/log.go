@@ -165,6 +165,11 @@ func (s *Store) logChange(txn *client.Txn, changeType roachpb.ReplicaChangeType,
 	if !s.ctx.LogRangeEvents {
 		return nil
 	}
+	// Learners are transient: only the addition of a replica as a voter, once
+	// promoted, and the removal of voters are logged.
+	if replica.IsLearner() {
+		return nil
+	}
 
 	var logType RangeEventLogType
 	var infoStruct interface{}
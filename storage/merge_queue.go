@@ -0,0 +1,152 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/config"
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+)
+
+const (
+	// mergeQueueMaxSize is the max size of the merge queue.
+	mergeQueueMaxSize = 100
+	// mergeQueueTimerDuration is the duration between merges of queued ranges.
+	mergeQueueTimerDuration = 0 // zero duration to process merges greedily.
+)
+
+// mergeQueueEnabled controls whether the merge queue merges the undersized
+// ranges into their successors. Ranges split manually are merged back too,
+// which is why it is opt-in.
+var mergeQueueEnabled = settings.RegisterBoolSetting(
+	"kv.range_merge.queue_enabled",
+	"set to enable the automatic merging of undersized ranges into their successors",
+	false,
+)
+
+// mergeQueue manages a queue of ranges slated to subsume the range which
+// follows them, because both are undersized, e.g. after large deletions.
+type mergeQueue struct {
+	baseQueue
+}
+
+// newMergeQueue returns a new instance of mergeQueue.
+func newMergeQueue(store *Store, gossip *gossip.Gossip) *mergeQueue {
+	mq := &mergeQueue{}
+	mq.baseQueue = makeBaseQueue("merge", mq, store, gossip, queueConfig{
+		maxSize:              mergeQueueMaxSize,
+		needsLease:           true,
+		acceptsUnsplitRanges: false,
+	})
+	return mq
+}
+
+// mergeCandidate returns the replica of the range following rng on the same
+// store if rng should subsume it: rng must be smaller than the minimum size of
+// its zone, both ranges must have replicas on the same stores and no zone
+// config may require a split between them. The merged range must also stay
+// below half the maximum size of the zone, so that it isn't split again right
+// away. It also returns the priority of the merge, which is higher for smaller
+// ranges.
+func mergeCandidate(rng *Replica, sysCfg config.SystemConfig) (*Replica, float64, error) {
+	desc := rng.Desc()
+	if desc.EndKey.Equal(roachpb.RKeyMax) {
+		return nil, 0, nil
+	}
+	zone, err := sysCfg.GetZoneConfigForKey(desc.StartKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	size := rng.GetMVCCStats().Total()
+	if size >= zone.RangeMinBytes {
+		return nil, 0, nil
+	}
+	rhs := rng.store.LookupReplica(desc.EndKey, nil)
+	if rhs == nil {
+		return nil, 0, nil
+	}
+	rhsDesc := rhs.Desc()
+	if !rhsDesc.StartKey.Equal(desc.EndKey) || !replicaSetsEqual(desc.Replicas, rhsDesc.Replicas) {
+		return nil, 0, nil
+	}
+	if len(sysCfg.ComputeSplitKeys(desc.StartKey, rhsDesc.EndKey)) > 0 {
+		return nil, 0, nil
+	}
+	if size+rhs.GetMVCCStats().Total() >= zone.RangeMaxBytes/2 {
+		return nil, 0, nil
+	}
+	return rhs, 1 - float64(size)/float64(zone.RangeMinBytes), nil
+}
+
+// shouldQueue determines whether a range should be queued for merging with
+// the range which follows it.
+func (*mergeQueue) shouldQueue(now hlc.Timestamp, rng *Replica,
+	sysCfg config.SystemConfig) (shouldQ bool, priority float64) {
+	if !mergeQueueEnabled.Get() {
+		return false, 0
+	}
+	rhs, priority, err := mergeCandidate(rng, sysCfg)
+	if err != nil {
+		log.Error(context.TODO(), err)
+		return false, 0
+	}
+	return rhs != nil, priority
+}
+
+// process synchronously invokes admin merge to subsume the range following
+// the replica's range.
+func (*mergeQueue) process(
+	ctx context.Context,
+	now hlc.Timestamp,
+	rng *Replica,
+	sysCfg config.SystemConfig,
+) error {
+	if !mergeQueueEnabled.Get() {
+		return nil
+	}
+	// The ranges may have changed since the replica was queued.
+	rhs, _, err := mergeCandidate(rng, sysCfg)
+	if err != nil || rhs == nil {
+		return err
+	}
+	desc := rng.Desc()
+	log.Infof(ctx, "merging %s into %s", rhs, rng)
+	if _, pErr := client.SendWrappedWith(rng, ctx, roachpb.Header{
+		Timestamp: now,
+	}, &roachpb.AdminMergeRequest{
+		Span: roachpb.Span{Key: desc.StartKey.AsRawKey()},
+	}); pErr != nil {
+		return pErr.GoError()
+	}
+	return nil
+}
+
+// timer returns interval between processing successive queued merges.
+func (*mergeQueue) timer() time.Duration {
+	return mergeQueueTimerDuration
+}
+
+// purgatoryChan returns nil.
+func (*mergeQueue) purgatoryChan() <-chan struct{} {
+	return nil
+}
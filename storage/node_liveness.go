@@ -0,0 +1,221 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/gossip"
+	"github.com/cockroachdb/cockroach/internal/client"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/stop"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// maxHeartbeatAttempts is the number of times a heartbeat is retried when
+// the liveness record was concurrently modified.
+const maxHeartbeatAttempts = 3
+
+var (
+	// ErrNoLivenessRecord is returned when asking for the liveness of a node
+	// for which nothing is known.
+	ErrNoLivenessRecord = errors.New("node not in the liveness table")
+
+	// errLivenessChanged is returned when the liveness record of a node was
+	// modified concurrently with an update.
+	errLivenessChanged = errors.New("liveness record changed concurrently")
+)
+
+// NodeLiveness maintains the liveness records of the nodes of the cluster.
+// Each node periodically heartbeats its own record, which is stored in the
+// node liveness table and gossiped to the other nodes, to extend its
+// expiration. As long as a node's record is live, the epoch-based range
+// leases it holds under the record's epoch are valid, which saves it from
+// renewing the leases of its ranges individually. Another node can take
+// over these leases once the record expires, by incrementing its epoch.
+type NodeLiveness struct {
+	clock             *hlc.Clock
+	db                *client.DB
+	gossip            *gossip.Gossip
+	livenessThreshold time.Duration
+	heartbeatInterval time.Duration
+
+	mu struct {
+		syncutil.Mutex
+		nodes map[roachpb.NodeID]roachpb.Liveness
+	}
+}
+
+// NewNodeLiveness returns a new NodeLiveness. The liveness records of the
+// node are extended by livenessThreshold on every heartbeat, which happens
+// renewalDuration before they would expire.
+func NewNodeLiveness(
+	clock *hlc.Clock,
+	db *client.DB,
+	g *gossip.Gossip,
+	livenessThreshold time.Duration,
+	renewalDuration time.Duration,
+) *NodeLiveness {
+	nl := &NodeLiveness{
+		clock:             clock,
+		db:                db,
+		gossip:            g,
+		livenessThreshold: livenessThreshold,
+		heartbeatInterval: livenessThreshold - renewalDuration,
+	}
+	nl.mu.nodes = map[roachpb.NodeID]roachpb.Liveness{}
+	g.RegisterCallback(gossip.MakePrefixPattern(gossip.KeyNodeLivenessPrefix), nl.livenessGossipUpdate)
+	return nl
+}
+
+// StartHeartbeat starts a worker which periodically heartbeats the liveness
+// record of this node. The node ID must have been set in gossip.
+func (nl *NodeLiveness) StartHeartbeat(ctx context.Context, stopper *stop.Stopper) {
+	stopper.RunWorker(func() {
+		ticker := time.NewTicker(nl.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			if err := nl.Heartbeat(ctx); err != nil {
+				log.Warningf(ctx, "failed node liveness heartbeat: %s", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-stopper.ShouldStop():
+				return
+			}
+		}
+	})
+}
+
+// Heartbeat extends the expiration of the liveness record of this node,
+// creating it with an initial epoch of 1 if it doesn't exist yet.
+func (nl *NodeLiveness) Heartbeat(ctx context.Context) error {
+	nodeID := nl.gossip.GetNodeID()
+	var err error
+	for i := 0; i < maxHeartbeatAttempts; i++ {
+		newLiveness := roachpb.Liveness{
+			NodeID:     nodeID,
+			Epoch:      1,
+			Expiration: nl.clock.Now().Add(nl.livenessThreshold.Nanoseconds(), 0),
+		}
+		oldLiveness, lErr := nl.GetLiveness(nodeID)
+		if lErr == nil {
+			newLiveness.Epoch = oldLiveness.Epoch
+		} else if lErr != ErrNoLivenessRecord {
+			return lErr
+		}
+		// If the record was changed concurrently, for example because this
+		// node restarted or another node incremented its epoch, the actual
+		// record is now cached and the heartbeat can be retried.
+		if err = nl.updateLiveness(ctx, &newLiveness, oldLiveness); err != errLivenessChanged {
+			return err
+		}
+	}
+	return err
+}
+
+// IncrementEpoch increments the epoch of the given liveness record of
+// another node, which invalidates all the epoch-based leases the node holds
+// under its current epoch. This is only possible once the record has
+// expired; an error is returned if the node is still live, or if the record
+// was modified concurrently, in which case the actual record is cached.
+func (nl *NodeLiveness) IncrementEpoch(ctx context.Context, liveness *roachpb.Liveness) error {
+	if liveness.IsLive(nl.clock.Now(), nl.clock.MaxOffset()) {
+		return errors.Errorf("cannot increment epoch of live node %d", liveness.NodeID)
+	}
+	newLiveness := *liveness
+	newLiveness.Epoch++
+	return nl.updateLiveness(ctx, &newLiveness, liveness)
+}
+
+// GetLiveness returns the last known liveness record of the given node.
+func (nl *NodeLiveness) GetLiveness(nodeID roachpb.NodeID) (*roachpb.Liveness, error) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	if l, ok := nl.mu.nodes[nodeID]; ok {
+		return &l, nil
+	}
+	return nil, ErrNoLivenessRecord
+}
+
+// IsLive returns whether the given node is currently considered live.
+func (nl *NodeLiveness) IsLive(nodeID roachpb.NodeID) (bool, error) {
+	liveness, err := nl.GetLiveness(nodeID)
+	if err != nil {
+		return false, err
+	}
+	return liveness.IsLive(nl.clock.Now(), nl.clock.MaxOffset()), nil
+}
+
+// updateLiveness replaces the liveness record oldLiveness, which is nil if
+// the node has no record yet, with newLiveness and gossips the new record.
+// If the stored record doesn't match oldLiveness, the actual record is
+// cached instead and errLivenessChanged is returned.
+func (nl *NodeLiveness) updateLiveness(
+	ctx context.Context,
+	newLiveness *roachpb.Liveness,
+	oldLiveness *roachpb.Liveness,
+) error {
+	var expValue interface{}
+	if oldLiveness != nil {
+		expValue = oldLiveness
+	}
+	key := keys.NodeLivenessKey(newLiveness.NodeID)
+	if err := nl.db.CPut(key, newLiveness, expValue); err != nil {
+		if cErr, ok := err.(*roachpb.ConditionFailedError); ok && cErr.ActualValue != nil {
+			var actual roachpb.Liveness
+			if err := cErr.ActualValue.GetProto(&actual); err != nil {
+				return err
+			}
+			nl.maybeUpdate(actual)
+			return errLivenessChanged
+		}
+		return err
+	}
+	nl.maybeUpdate(*newLiveness)
+	if err := nl.gossip.AddInfoProto(gossip.MakeNodeLivenessKey(newLiveness.NodeID), newLiveness, 0); err != nil {
+		log.Warningf(ctx, "unable to gossip liveness of node %d: %s", newLiveness.NodeID, err)
+	}
+	return nil
+}
+
+// livenessGossipUpdate is the gossip callback used to keep the cached
+// liveness records up to date.
+func (nl *NodeLiveness) livenessGossipUpdate(key string, content roachpb.Value) {
+	var liveness roachpb.Liveness
+	if err := content.GetProto(&liveness); err != nil {
+		log.Errorf(context.TODO(), "unable to unmarshal liveness gossip for %s: %s", key, err)
+		return
+	}
+	nl.maybeUpdate(liveness)
+}
+
+// maybeUpdate caches the given liveness record unless a more recent one,
+// i.e. one with a higher epoch or a later expiration, is already known.
+func (nl *NodeLiveness) maybeUpdate(liveness roachpb.Liveness) {
+	nl.mu.Lock()
+	defer nl.mu.Unlock()
+	old, ok := nl.mu.nodes[liveness.NodeID]
+	if !ok || old.Epoch < liveness.Epoch ||
+		(old.Epoch == liveness.Epoch && old.Expiration.Less(liveness.Expiration)) {
+		nl.mu.nodes[liveness.NodeID] = liveness
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/base"
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/server"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/testutils/serverutils"
+	"github.com/cockroachdb/cockroach/util"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// waitForLiveness waits for the first heartbeat of the node of the given
+// server and returns its liveness record.
+func waitForLiveness(t *testing.T, s *server.TestServer) *roachpb.Liveness {
+	var liveness *roachpb.Liveness
+	util.SucceedsSoon(t, func() error {
+		var err error
+		liveness, err = s.NodeLiveness().GetLiveness(s.Gossip().GetNodeID())
+		return err
+	})
+	return liveness
+}
+
+func TestNodeLivenessHeartbeat(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	srv, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	s := srv.(*server.TestServer)
+	defer s.Stopper().Stop()
+
+	nl := s.NodeLiveness()
+	nodeID := s.Gossip().GetNodeID()
+	liveness := waitForLiveness(t, s)
+	if liveness.Epoch != 1 {
+		t.Errorf("expected initial epoch 1, got %d", liveness.Epoch)
+	}
+	if live, err := nl.IsLive(nodeID); err != nil || !live {
+		t.Errorf("expected node %d to be live, got %t (err: %v)", nodeID, live, err)
+	}
+
+	// A heartbeat extends the expiration without changing the epoch.
+	if err := nl.Heartbeat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	newLiveness, err := nl.GetLiveness(nodeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newLiveness.Epoch != 1 || !liveness.Expiration.Less(newLiveness.Expiration) {
+		t.Errorf("expected heartbeat to extend %+v, got %+v", liveness, newLiveness)
+	}
+
+	// The record is stored in the node liveness table.
+	var stored roachpb.Liveness
+	if err := s.DB().GetProto(keys.NodeLivenessKey(nodeID), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Epoch != 1 || stored.Expiration.Less(newLiveness.Expiration) {
+		t.Errorf("expected stored liveness to match %+v, got %+v", newLiveness, stored)
+	}
+
+	// The epoch of a live node can't be incremented.
+	if err := nl.IncrementEpoch(context.Background(), newLiveness); !testutils.IsError(err, "cannot increment epoch of live node") {
+		t.Errorf("unexpected error incrementing epoch of live node: %v", err)
+	}
+}
+
+func TestEpochRangeLeases(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	srv, _, _ := serverutils.StartServer(t, base.TestServerArgs{})
+	s := srv.(*server.TestServer)
+	defer s.Stopper().Stop()
+
+	if err := s.WaitForInitialSplits(); err != nil {
+		t.Fatal(err)
+	}
+	liveness := waitForLiveness(t, s)
+
+	leaseOf := func(key roachpb.Key) (*roachpb.Lease, error) {
+		rKey, err := keys.Addr(key)
+		if err != nil {
+			return nil, err
+		}
+		rangeID, _, err := s.Stores().LookupReplica(rKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		store, err := s.Stores().GetStore(s.GetFirstStoreID())
+		if err != nil {
+			return nil, err
+		}
+		repl, err := store.GetReplica(rangeID)
+		if err != nil {
+			return nil, err
+		}
+		lease, _ := repl.GetLease()
+		return lease, nil
+	}
+
+	// Leases requested before the first heartbeat are expiration-based, and
+	// are replaced by epoch-based leases the next time they're extended.
+	key := roachpb.Key("a")
+	util.SucceedsSoon(t, func() error {
+		if _, err := s.DB().Get(key); err != nil {
+			return err
+		}
+		lease, err := leaseOf(key)
+		if err != nil {
+			return err
+		}
+		if lease.Epoch == nil {
+			return errors.Errorf("expected epoch-based lease, got %s", lease)
+		}
+		if *lease.Epoch != liveness.Epoch {
+			return errors.Errorf("expected lease under epoch %d, got %s", liveness.Epoch, lease)
+		}
+		return nil
+	})
+
+	// The range holding the node liveness records keeps using
+	// expiration-based leases.
+	lease, err := leaseOf(keys.NodeLivenessKey(liveness.NodeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Epoch != nil {
+		t.Errorf("expected expiration-based lease for the node liveness range, got %s", lease)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/util/syncutil"
+)
+
+// errQuotaPoolClosed is returned when acquiring quota from a closed pool.
+var errQuotaPoolClosed = errors.New("quota pool closed")
+
+// quotaPool is a pool of a fixed amount of quota. Quota is acquired before
+// performing an operation and added back once its resources are released,
+// which bounds the amount of resources held by pending operations. Waiters
+// are woken up whenever quota is added back, and are not served in any
+// particular order.
+type quotaPool struct {
+	max int64
+
+	mu struct {
+		syncutil.Mutex
+		quota int64
+		// notify is closed, and replaced, whenever quota is added back or
+		// the pool is closed.
+		notify chan struct{}
+		closed bool
+	}
+}
+
+// newQuotaPool returns a new quotaPool holding max quota.
+func newQuotaPool(max int64) *quotaPool {
+	qp := &quotaPool{max: max}
+	qp.mu.quota = max
+	qp.mu.notify = make(chan struct{})
+	return qp
+}
+
+// capacity returns the amount of quota held by the pool when no quota is
+// acquired.
+func (qp *quotaPool) capacity() int64 {
+	return qp.max
+}
+
+// acquire blocks until v quota is available and acquires it. An error is
+// returned if the context is done or the pool is closed before then.
+// Acquiring more quota than the capacity of the pool never succeeds.
+func (qp *quotaPool) acquire(ctx context.Context, v int64) error {
+	for {
+		qp.mu.Lock()
+		if qp.mu.closed {
+			qp.mu.Unlock()
+			return errQuotaPoolClosed
+		}
+		if qp.mu.quota >= v {
+			qp.mu.quota -= v
+			qp.mu.Unlock()
+			return nil
+		}
+		notify := qp.mu.notify
+		qp.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// add adds back v previously acquired quota to the pool.
+func (qp *quotaPool) add(v int64) {
+	qp.mu.Lock()
+	defer qp.mu.Unlock()
+	if qp.mu.closed {
+		return
+	}
+	qp.mu.quota += v
+	if qp.mu.quota > qp.max {
+		qp.mu.quota = qp.max
+	}
+	close(qp.mu.notify)
+	qp.mu.notify = make(chan struct{})
+}
+
+// close closes the pool, failing the pending and future acquisitions.
+func (qp *quotaPool) close() {
+	qp.mu.Lock()
+	defer qp.mu.Unlock()
+	if !qp.mu.closed {
+		qp.mu.closed = true
+		close(qp.mu.notify)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestQuotaPool(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	qp := newQuotaPool(100)
+	ctx := context.Background()
+	if err := qp.acquire(ctx, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// An acquisition exceeding the remaining quota waits until enough quota
+	// is added back.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- qp.acquire(ctx, 50)
+	}()
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected acquisition to block, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	qp.add(5)
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected acquisition to block, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	qp.add(55)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// A waiting acquisition fails once its context is done.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		errCh <- qp.acquire(cancelCtx, 60)
+	}()
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// Closing the pool fails the waiting acquisitions and the later ones.
+	go func() {
+		errCh <- qp.acquire(ctx, 60)
+	}()
+	qp.close()
+	if err := <-errCh; err != errQuotaPoolClosed {
+		t.Fatalf("expected %v, got %v", errQuotaPoolClosed, err)
+	}
+	if err := qp.acquire(ctx, 1); err != errQuotaPoolClosed {
+		t.Fatalf("expected %v, got %v", errQuotaPoolClosed, err)
+	}
+	// Adding quota back to a closed pool is a no-op.
+	qp.add(50)
+}
@@ -43,6 +43,11 @@ const (
 	// entries. A stale entry is one which all replicas of the range have
 	// progressed past and thus is no longer needed and can be truncated.
 	RaftLogQueueStaleThreshold = 100
+	// RaftLogQueueStaleSize is the minimum size of the raft log at which
+	// stale entries are truncated even if there are fewer of them than
+	// RaftLogQueueStaleThreshold, which keeps a log of large entries from
+	// growing unbounded.
+	RaftLogQueueStaleSize = 64 << 10
 )
 
 // raftLogQueue manages a queue of replicas slated to have their raft logs
@@ -65,23 +70,23 @@ func newRaftLogQueue(store *Store, db *client.DB, gossip *gossip.Gossip) *raftLo
 	return rlq
 }
 
-// getTruncatableIndexes returns the number of truncatable indexes and the
-// oldest index that cannot be truncated for the replica.
-// See computeTruncatableIndex.
-func getTruncatableIndexes(r *Replica) (uint64, uint64, error) {
+// getTruncatableIndexes returns the number of truncatable indexes, the
+// oldest index that cannot be truncated for the replica and the size of its
+// raft log. See computeTruncatableIndex.
+func getTruncatableIndexes(r *Replica) (uint64, uint64, int64, error) {
 	rangeID := r.RangeID
 	raftStatus := r.RaftStatus()
 	if raftStatus == nil {
 		if log.V(1) {
 			log.Infof(context.TODO(), "the raft group doesn't exist for range %d", rangeID)
 		}
-		return 0, 0, nil
+		return 0, 0, 0, nil
 	}
 
 	// Is this the raft leader? We only perform log truncation on the raft leader
 	// which has the up to date info on followers.
 	if raftStatus.RaftState != raft.StateLeader {
-		return 0, 0, nil
+		return 0, 0, 0, nil
 	}
 
 	r.mu.Lock()
@@ -100,12 +105,12 @@ func getTruncatableIndexes(r *Replica) (uint64, uint64, error) {
 	firstIndex, err := r.FirstIndex()
 	r.mu.Unlock()
 	if err != nil {
-		return 0, 0, errors.Errorf("error retrieving first index for range %d: %s", rangeID, err)
+		return 0, 0, 0, errors.Errorf("error retrieving first index for range %d: %s", rangeID, err)
 	}
 
 	truncatableIndex := computeTruncatableIndex(raftStatus, raftLogSize, targetSize, firstIndex)
 	// Return the number of truncatable indexes.
-	return truncatableIndex - firstIndex, truncatableIndex, nil
+	return truncatableIndex - firstIndex, truncatableIndex, raftLogSize, nil
 }
 
 // computeTruncatableIndex returns the oldest index that cannot be
@@ -116,7 +121,9 @@ func getTruncatableIndexes(r *Replica) (uint64, uint64, error) {
 // currently truncate when the raft log size is bigger than the range
 // size. When there are no nodes behind, or we can't catch any of them up via
 // the raft log (due to a previous truncation) this returns the quorum
-// committed index.
+// committed index. The entries following a snapshot being sent to a node are
+// never truncated, as the node needs them to catch up once it has applied
+// the snapshot.
 func computeTruncatableIndex(
 	raftStatus *raft.Status,
 	raftLogSize, targetSize int64,
@@ -143,40 +150,62 @@ func computeTruncatableIndex(
 	if truncatableIndex > raftStatus.Commit {
 		truncatableIndex = raftStatus.Commit
 	}
+	// Never truncate past the index of a pending snapshot.
+	for _, progress := range raftStatus.Progress {
+		if progress.State != raft.ProgressStateSnapshot {
+			continue
+		}
+		pendingIndex := progress.PendingSnapshot
+		if pendingIndex < firstIndex {
+			pendingIndex = firstIndex
+		}
+		if pendingIndex < truncatableIndex {
+			truncatableIndex = pendingIndex
+		}
+	}
 	return truncatableIndex
 }
 
+// shouldTruncate returns whether the stale entries of a raft log should be
+// truncated, which is the case once there are RaftLogQueueStaleThreshold of
+// them, or as soon as there are any if the log is RaftLogQueueStaleSize or
+// larger.
+func shouldTruncate(truncatableIndexes uint64, raftLogSize int64) bool {
+	return truncatableIndexes >= RaftLogQueueStaleThreshold ||
+		(truncatableIndexes > 0 && raftLogSize >= RaftLogQueueStaleSize)
+}
+
 // shouldQueue determines whether a range should be queued for truncating. This
-// is true only if the replica is the raft leader and if the total number of
-// the range's raft log's stale entries exceeds RaftLogQueueStaleThreshold.
+// is true only if the replica is the raft leader and if its raft log's stale
+// entries should be truncated (see shouldTruncate).
 func (*raftLogQueue) shouldQueue(
 	now hlc.Timestamp, r *Replica, _ config.SystemConfig,
 ) (shouldQ bool, priority float64) {
-	truncatableIndexes, _, err := getTruncatableIndexes(r)
+	truncatableIndexes, _, raftLogSize, err := getTruncatableIndexes(r)
 	if err != nil {
 		log.Warning(context.TODO(), err)
 		return false, 0
 	}
 
-	return truncatableIndexes >= RaftLogQueueStaleThreshold, float64(truncatableIndexes)
+	return shouldTruncate(truncatableIndexes, raftLogSize), float64(truncatableIndexes)
 }
 
 // process truncates the raft log of the range if the replica is the raft
-// leader and if the total number of the range's raft log's stale entries
-// exceeds RaftLogQueueStaleThreshold.
+// leader and if its raft log's stale entries should be truncated (see
+// shouldTruncate).
 func (rlq *raftLogQueue) process(
 	ctx context.Context,
 	now hlc.Timestamp,
 	r *Replica,
 	_ config.SystemConfig,
 ) error {
-	truncatableIndexes, oldestIndex, err := getTruncatableIndexes(r)
+	truncatableIndexes, oldestIndex, raftLogSize, err := getTruncatableIndexes(r)
 	if err != nil {
 		return err
 	}
 
 	// Can and should the raft logs be truncated?
-	if truncatableIndexes >= RaftLogQueueStaleThreshold {
+	if shouldTruncate(truncatableIndexes, raftLogSize) {
 		if log.V(1) {
 			log.Infof(ctx, "%s: truncating raft log %d-%d",
 				r, oldestIndex-truncatableIndexes, oldestIndex)
@@ -100,6 +100,63 @@ func TestComputeTruncatableIndex(t *testing.T) {
 	}
 }
 
+// TestComputeTruncatableIndexPendingSnapshot verifies that the entries
+// following a snapshot being sent to a follower are kept.
+func TestComputeTruncatableIndexPendingSnapshot(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	const targetSize = 1000
+
+	testCases := []struct {
+		pendingSnapshot uint64
+		firstIndex      uint64
+		expected        uint64
+	}{
+		{8, 1, 8},
+		{12, 1, 10},
+		// Never return an index below the first index.
+		{4, 5, 5},
+	}
+	for i, c := range testCases {
+		status := &raft.Status{
+			Progress: map[uint64]raft.Progress{
+				1: {Match: 10},
+				2: {Match: 10},
+				3: {State: raft.ProgressStateSnapshot, PendingSnapshot: c.pendingSnapshot},
+			},
+		}
+		status.Commit = 10
+		out := computeTruncatableIndex(status, 2000, targetSize, c.firstIndex)
+		if out != c.expected {
+			t.Errorf("%d: computeTruncatableIndex(...) expected %d, but got %d", i, c.expected, out)
+		}
+	}
+}
+
+// TestShouldTruncate verifies that the raft log is truncated once it has
+// enough stale entries, or once it's large enough.
+func TestShouldTruncate(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		truncatableIndexes uint64
+		raftLogSize        int64
+		expected           bool
+	}{
+		{RaftLogQueueStaleThreshold - 1, 0, false},
+		{RaftLogQueueStaleThreshold, 0, true},
+		{1, RaftLogQueueStaleSize - 1, false},
+		{1, RaftLogQueueStaleSize, true},
+		{0, RaftLogQueueStaleSize, false},
+	}
+	for i, c := range testCases {
+		if out := shouldTruncate(c.truncatableIndexes, c.raftLogSize); out != c.expected {
+			t.Errorf("%d: shouldTruncate(%d, %d) expected %t, but got %t",
+				i, c.truncatableIndexes, c.raftLogSize, c.expected, out)
+		}
+	}
+}
+
 // TestGetTruncatableIndexes verifies that old raft log entries are correctly
 // removed.
 func TestGetTruncatableIndexes(t *testing.T) {
@@ -114,7 +171,7 @@ func TestGetTruncatableIndexes(t *testing.T) {
 
 	// Test on a new range which should not have a raft group yet.
 	rngNew := createRange(store, 100, roachpb.RKey("a"), roachpb.RKey("c"))
-	truncatableIndexes, oldestIndex, err := getTruncatableIndexes(rngNew)
+	truncatableIndexes, oldestIndex, _, err := getTruncatableIndexes(rngNew)
 	if err != nil {
 		t.Errorf("expected no error, got %s", err)
 	}
@@ -146,7 +203,7 @@ func TestGetTruncatableIndexes(t *testing.T) {
 		}
 	}
 
-	truncatableIndexes, oldestIndex, err = getTruncatableIndexes(r)
+	truncatableIndexes, oldestIndex, _, err = getTruncatableIndexes(r)
 	if err != nil {
 		t.Errorf("expected no error, got %s", err)
 	}
@@ -182,7 +239,7 @@ func TestGetTruncatableIndexes(t *testing.T) {
 	// client_raft_log_queue_test.
 	util.SucceedsSoon(t, func() error {
 		store.ForceRaftLogScanAndProcess()
-		truncatableIndexes, oldestIndex, err := getTruncatableIndexes(rngNew)
+		truncatableIndexes, oldestIndex, _, err := getTruncatableIndexes(rngNew)
 		if err != nil {
 			return errors.Errorf("expected no error, got %s", err)
 		}
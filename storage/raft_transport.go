@@ -88,8 +88,10 @@ type RaftTransport struct {
 	mu struct {
 		syncutil.Mutex
 		handlers map[roachpb.StoreID]raftMessageHandler
-		queues   map[bool]map[roachpb.ReplicaIdent]chan *RaftMessageRequest
-		breakers map[roachpb.NodeID]*circuit.Breaker
+		// throttles pace the snapshots sent by the stores.
+		throttles map[roachpb.StoreID]func(*RaftMessageRequest)
+		queues    map[bool]map[roachpb.ReplicaIdent]chan *RaftMessageRequest
+		breakers  map[roachpb.NodeID]*circuit.Breaker
 	}
 }
 
@@ -108,6 +110,7 @@ func NewRaftTransport(resolver NodeAddressResolver, grpcServer *grpc.Server, rpc
 		SnapshotStatusChan: make(chan RaftSnapshotStatus),
 	}
 	t.mu.handlers = make(map[roachpb.StoreID]raftMessageHandler)
+	t.mu.throttles = make(map[roachpb.StoreID]func(*RaftMessageRequest))
 	t.mu.queues = make(map[bool]map[roachpb.ReplicaIdent]chan *RaftMessageRequest)
 	t.mu.breakers = make(map[roachpb.NodeID]*circuit.Breaker)
 
@@ -167,10 +170,20 @@ func (t *RaftTransport) Listen(storeID roachpb.StoreID, handler raftMessageHandl
 	t.mu.Unlock()
 }
 
-// Stop unregisters a raftMessageHandler.
+// ThrottleSnapshots registers a function called before sending each snapshot
+// from the store, which blocks until the snapshot may be sent.
+func (t *RaftTransport) ThrottleSnapshots(storeID roachpb.StoreID, throttle func(*RaftMessageRequest)) {
+	t.mu.Lock()
+	t.mu.throttles[storeID] = throttle
+	t.mu.Unlock()
+}
+
+// Stop unregisters a raftMessageHandler and the snapshot throttle of the
+// store.
 func (t *RaftTransport) Stop(storeID roachpb.StoreID) {
 	t.mu.Lock()
 	delete(t.mu.handlers, storeID)
+	delete(t.mu.throttles, storeID)
 	t.mu.Unlock()
 }
 
@@ -265,6 +278,14 @@ func (t *RaftTransport) processQueue(ch chan *RaftMessageRequest, conn *grpc.Cli
 		case err := <-errCh:
 			return err
 		case req := <-ch:
+			if req.Message.Type == raftpb.MsgSnap {
+				t.mu.Lock()
+				throttle, ok := t.mu.throttles[req.FromReplica.StoreID]
+				t.mu.Unlock()
+				if ok {
+					throttle(req)
+				}
+			}
 			err := stream.Send(req)
 			if req.Message.Type == raftpb.MsgSnap {
 				select {
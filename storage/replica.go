@@ -51,6 +51,7 @@ import (
 	"github.com/cockroachdb/cockroach/util/grpcutil"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/metric"
 	"github.com/cockroachdb/cockroach/util/protoutil"
 	"github.com/cockroachdb/cockroach/util/syncutil"
 	"github.com/cockroachdb/cockroach/util/tracing"
@@ -80,8 +81,17 @@ const (
 	// put operations will possibly be optimized by determining whether
 	// the key space being written is starting out empty.
 	optimizePutThreshold = 10
+	// replicaQPSTimescale is the timescale of the moving averages of the rates
+	// of the batch requests, and of the write batch requests, served by a
+	// replica.
+	replicaQPSTimescale = 1 * time.Minute
 
 	replicaChangeTxnName = "change-replica"
+
+	// learnerCatchUpTimeout is the maximum duration for which a new replica
+	// is kept as a learner while catching up with the Raft log, before it is
+	// removed from the range.
+	learnerCatchUpTimeout = 1 * time.Minute
 )
 
 // This flag controls whether Transaction entries are automatically gc'ed
@@ -162,6 +172,13 @@ type pendingCmd struct {
 	proposedAtTicks int
 	raftCmd         roachpb.RaftCommand
 	done            chan roachpb.ResponseWithError // Used to signal waiting RPC handler
+	quotaSize       int64                          // Quota acquired from the proposal quota pool
+}
+
+// quotaRelease is the quota held by a command applied at a log index.
+type quotaRelease struct {
+	index uint64
+	size  int64
 }
 
 type replicaChecksum struct {
@@ -194,6 +211,11 @@ type Replica struct {
 	systemDBHash []byte
 	abortCache   *AbortCache // Avoids anomalous reads after abort
 	raftSender   RaftSender
+	// qps is the rate of the batch requests served by the replica. It guides
+	// the load-based lease rebalancing.
+	qps *metric.Rate
+	// wps is the rate of the write batch requests served by the replica.
+	wps *metric.Rate
 
 	// creatingReplica is set when a replica is created as uninitialized
 	// via a raft message.
@@ -204,6 +226,14 @@ type Replica struct {
 	// RWMutex.
 	readOnlyCmdMu syncutil.RWMutex
 
+	// Held from the application of a Raft command to the publication of its
+	// writes to the RangeFeeds. Acquired before the embedded RWMutex.
+	rangeFeedMu struct {
+		syncutil.Mutex
+		// The RangeFeeds registered on the replica.
+		feeds map[*rangeFeed]struct{}
+	}
+
 	// rangeDesc is a *RangeDescriptor that can be atomically read from in
 	// replica.Desc() without needing to acquire the replica.mu lock. All
 	// updates to state.Desc should be duplicated here
@@ -218,6 +248,13 @@ type Replica struct {
 		state storagebase.ReplicaState
 		// Counter used for assigning lease indexes for proposals.
 		lastAssignedLeaseIndex uint64
+		// The closed timestamp of the range, as carried by the last command
+		// applied. Reads at or below it may be served without the lease.
+		closedTimestamp hlc.Timestamp
+		// The closed timestamp assigned to the last command proposed by this
+		// replica as the lease holder, or sent by maybeSendClosedTimestampLocked.
+		// Never less than closedTimestamp.
+		proposedClosedTimestamp hlc.Timestamp
 		// Enforces at most one command is running per key(s).
 		cmdQ *CommandQueue
 		// Last index persisted to the raft log (not necessarily committed).
@@ -295,6 +332,11 @@ type Replica struct {
 		proposeRaftCommandFn func(*pendingCmd) error
 		// Computed checksum at a snapshot UUID.
 		checksums map[uuid.UUID]replicaChecksum
+		// The time of the last consistency check started by this replica as
+		// the lease holder, used to schedule the next one.
+		lastConsistencyCheck hlc.Timestamp
+		// Set if the last verified checksum didn't match the lease holder's.
+		inconsistent bool
 
 		// Set to an open channel while a snapshot is being generated.
 		// When no snapshot is in progress, this field may either be nil
@@ -307,6 +349,25 @@ type Replica struct {
 
 		// Counts Raft messages refused due to queue congestion.
 		droppedMessages int
+
+		// The pool of quota acquired by the commands proposed while this
+		// replica is the raft leader, nil otherwise. The quota of a command is
+		// released once it's applied and all the active followers have
+		// appended its entry, so that proposals are throttled when followers
+		// fall behind.
+		proposalQuota *quotaPool
+		// The quota of the applied commands which is still held, in the order
+		// of their log indexes.
+		quotaReleaseQueue []quotaRelease
+
+		// Set when the raft group is quiescent: all the replicas have applied
+		// the whole log and there is no pending command, so the raft group
+		// is neither ticked nor heartbeated until it's woken up by a proposal
+		// or an incoming raft message.
+		quiescent bool
+		// Counts the ticks for which the replica has been quiescent. The raft
+		// group is unloaded after RaftUnloadQuiescentTicks of them.
+		quiescentTicks int
 	}
 }
 
@@ -337,6 +398,7 @@ func (r *Replica) withRaftGroupLocked(f func(r *raft.RawNode) error) error {
 			return err
 		}
 		r.mu.internalRaftGroup = raftGroup
+		r.unquiesceLocked()
 
 		// Automatically campaign and elect a leader for this group if there's
 		// exactly one known node for this group.
@@ -384,6 +446,8 @@ func NewReplica(desc *roachpb.RangeDescriptor, store *Store, replicaID roachpb.R
 		RangeID:    desc.RangeID,
 		store:      store,
 		abortCache: NewAbortCache(desc.RangeID),
+		qps:        metric.NewRate(replicaQPSTimescale),
+		wps:        metric.NewRate(replicaQPSTimescale),
 	}
 
 	r.raftSender = store.ctx.Transport.MakeSender(
@@ -497,6 +561,12 @@ func (r *Replica) Destroy(origDesc roachpb.RangeDescriptor) error {
 	// Clear the map.
 	r.mu.pendingCmds = map[storagebase.CmdIDKey]*pendingCmd{}
 	r.mu.internalRaftGroup = nil
+	// Unblock the proposals waiting for quota.
+	if r.mu.proposalQuota != nil {
+		r.mu.proposalQuota.close()
+		r.mu.proposalQuota = nil
+		r.mu.quotaReleaseQueue = nil
+	}
 	r.mu.destroyed = errors.Errorf("replica %d (range %d) was garbage collected",
 		r.mu.replicaID, r.RangeID)
 	r.mu.Unlock()
@@ -619,7 +689,7 @@ func (r *Replica) redirectOnOrAcquireLease(ctx context.Context) *roachpb.Error {
 			r.mu.Lock()
 			defer r.mu.Unlock()
 			lease := r.mu.state.Lease
-			if lease.Covers(timestamp) {
+			if r.isLeaseValid(lease, timestamp) {
 				if !lease.OwnedBy(r.store.StoreID()) {
 					// If lease is currently held by another, redirect to holder.
 					return nil, roachpb.NewError(
@@ -641,8 +711,9 @@ func (r *Replica) redirectOnOrAcquireLease(ctx context.Context) *roachpb.Error {
 						newNotLeaseHolderError(&transferLease, r.store.StoreID(), r.mu.state.Desc))
 				}
 
-				// Should we extend the lease?
-				if _, ok := r.mu.pendingLeaseRequest.RequestPending(); !ok &&
+				// Should we extend the lease? Epoch-based leases don't need to be
+				// extended; they remain valid through the node liveness heartbeats.
+				if _, ok := r.mu.pendingLeaseRequest.RequestPending(); !ok && lease.Epoch == nil &&
 					!timestamp.Less(lease.StartStasis.Add(-int64(r.store.ctx.rangeLeaseRenewalDuration), 0)) {
 					if log.V(2) {
 						log.Warningf(ctx, "%s: extending lease %s at %s", r, lease, timestamp)
@@ -678,7 +749,7 @@ func (r *Replica) redirectOnOrAcquireLease(ctx context.Context) *roachpb.Error {
 				// concurrent change. Convert the error to a NotLeaseHolderError.
 				if _, ok := pErr.GetDetail().(*roachpb.LeaseRejectedError); ok {
 					lease, _ := r.getLease()
-					if !lease.Covers(r.store.Clock().Now()) {
+					if !r.isLeaseValid(lease, r.store.Clock().Now()) {
 						lease = nil
 					}
 					return roachpb.NewError(newNotLeaseHolderError(lease, r.store.StoreID(), r.Desc()))
@@ -874,6 +945,14 @@ func (r *Replica) RaftStatus() *raft.Status {
 	return nil
 }
 
+// Inconsistent returns whether the last consistency check of the range found
+// this replica to diverge from the lease holder.
+func (r *Replica) Inconsistent() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.mu.inconsistent
+}
+
 // State returns a copy of the internal state of the Replica, along with some
 // auxiliary information.
 func (r *Replica) State() storagebase.RangeInfo {
@@ -957,10 +1036,27 @@ func (r *Replica) Send(ctx context.Context, ba roachpb.BatchRequest) (*roachpb.B
 	}
 	if pErr != nil {
 		log.Tracef(ctx, "error: %s", pErr)
+	} else {
+		r.qps.Add(1)
+		if ba.IsWrite() {
+			r.wps.Add(1)
+		}
 	}
 	return br, pErr
 }
 
+// QueriesPerSecond returns the rate of the batch requests served by the
+// replica, averaged over the last minute.
+func (r *Replica) QueriesPerSecond() float64 {
+	return r.qps.Value()
+}
+
+// WritesPerSecond returns the rate of the write batch requests served by the
+// replica, averaged over the last minute.
+func (r *Replica) WritesPerSecond() float64 {
+	return r.wps.Value()
+}
+
 func (r *Replica) checkCmdHeader(header roachpb.Span) error {
 	if !r.ContainsKeyRange(header.Key, header.EndKey) {
 		mismatchErr := roachpb.NewRangeKeyMismatchError(header.Key, header.EndKey, r.Desc())
@@ -972,7 +1068,7 @@ func (r *Replica) checkCmdHeader(header roachpb.Span) error {
 					// Only return the correct range descriptor as a hint
 					// if we know the current lease holder for that range, which
 					// indicates that our knowledge is not stale.
-					if lease, _ := repl.getLease(); lease != nil && lease.Covers(r.store.Clock().Now()) {
+					if lease, _ := repl.getLease(); lease != nil && repl.isLeaseValid(lease, r.store.Clock().Now()) {
 						mismatchErr.SuggestedRange = repl.Desc()
 					}
 				}
@@ -1243,6 +1339,17 @@ func (r *Replica) addAdminCmd(ctx context.Context, ba roachpb.BatchRequest) (*ro
 	case *roachpb.AdminTransferLeaseRequest:
 		pErr = roachpb.NewError(r.AdminTransferLease(tArgs.Target))
 		resp = &roachpb.AdminTransferLeaseResponse{}
+	case *roachpb.AdminChangeReplicasRequest:
+		for _, target := range tArgs.Targets {
+			if err := r.ChangeReplicas(ctx, tArgs.ChangeType, target, r.Desc()); err != nil {
+				pErr = roachpb.NewError(err)
+				break
+			}
+		}
+		resp = &roachpb.AdminChangeReplicasResponse{}
+	case *roachpb.AdminScatterRequest:
+		pErr = roachpb.NewError(r.adminScatter(ctx))
+		resp = &roachpb.AdminScatterResponse{}
 	case *roachpb.CheckConsistencyRequest:
 		var reply roachpb.CheckConsistencyResponse
 		reply, pErr = r.CheckConsistency(*tArgs, r.Desc())
@@ -1264,10 +1371,21 @@ func (r *Replica) addAdminCmd(ctx context.Context, ba roachpb.BatchRequest) (*ro
 // overlapping writes currently processing through Raft ahead of us to
 // clear via the command queue.
 func (r *Replica) addReadOnlyCmd(ctx context.Context, ba roachpb.BatchRequest) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
-	// If the read is consistent, the read requires the range lease.
+	// If the read is consistent, the read requires the range lease, unless
+	// it is old enough to be served from the closed timestamp.
 	if ba.ReadConsistency != roachpb.INCONSISTENT {
 		if pErr = r.redirectOnOrAcquireLease(ctx); pErr != nil {
-			return nil, pErr
+			if _, ok := pErr.GetDetail().(*roachpb.NotLeaseHolderError); !ok {
+				return nil, pErr
+			}
+			r.mu.Lock()
+			followerRead := r.canServeFollowerReadLocked(ba)
+			r.mu.Unlock()
+			if !followerRead {
+				return nil, pErr
+			}
+			log.Trace(ctx, "serving follower read")
+			pErr = nil
 		}
 	}
 
@@ -1318,7 +1436,8 @@ func (r *Replica) assert5725(ba roachpb.BatchRequest) {
 	}
 }
 
-// addWriteCmd first adds the keys affected by this command as pending writes
+// addWriteCmd first blocks writes adding data to an oversized range until it
+// splits, then adds the keys affected by this command as pending writes
 // to the command queue. Next, the timestamp cache is checked to determine if
 // any newer accesses to this command's affected keys have been made. If so,
 // the command's timestamp is moved forward. Finally, the command is submitted
@@ -1327,6 +1446,12 @@ func (r *Replica) assert5725(ba roachpb.BatchRequest) {
 func (r *Replica) addWriteCmd(
 	ctx context.Context, ba roachpb.BatchRequest,
 ) (br *roachpb.BatchResponse, pErr *roachpb.Error) {
+	// Block the write before it enters the command queue if the range grew
+	// too large, so that the commands of the split are not held up by it.
+	if err := r.maybeBackpressureWriteBatch(ctx, ba); err != nil {
+		return nil, roachpb.NewError(err)
+	}
+
 	// Add the write to the command queue to gate subsequent overlapping
 	// commands until this command completes. Note that this must be
 	// done before getting the max timestamp for the key(s), as
@@ -1414,8 +1539,10 @@ func (r *Replica) prepareRaftCommandLocked(
 	if r.mu.lastAssignedLeaseIndex < r.mu.state.LeaseAppliedIndex {
 		r.mu.lastAssignedLeaseIndex = r.mu.state.LeaseAppliedIndex
 	}
+	var closedTS hlc.Timestamp
 	if !ba.IsLease() {
 		r.mu.lastAssignedLeaseIndex++
+		closedTS = r.closeTimestampLocked(&ba)
 	}
 	if log.V(4) {
 		log.Infof(ctx, "%s: prepared command %x: maxLeaseIndex=%d leaseAppliedIndex=%d",
@@ -1426,14 +1553,127 @@ func (r *Replica) prepareRaftCommandLocked(
 		idKey: idKey,
 		done:  make(chan roachpb.ResponseWithError, 1),
 		raftCmd: roachpb.RaftCommand{
-			RangeID:       r.RangeID,
-			OriginReplica: replica,
-			Cmd:           ba,
-			MaxLeaseIndex: r.mu.lastAssignedLeaseIndex,
+			RangeID:            r.RangeID,
+			OriginReplica:      replica,
+			Cmd:                ba,
+			MaxLeaseIndex:      r.mu.lastAssignedLeaseIndex,
+			ClosedTimestamp:    closedTS,
+			ProposerLeaseStart: r.mu.state.Lease.Start,
 		},
 	}
 }
 
+// closeTimestampLocked advances the closed timestamp of the range to the
+// target duration behind the present time and returns it. The timestamp of
+// ba is moved above the closed timestamp, much like applyTimestampCache does
+// for preceding reads. Commands apply in the order of their lease indexes,
+// so no command applied after ba writes at or below the returned timestamp.
+func (r *Replica) closeTimestampLocked(ba *roachpb.BatchRequest) hlc.Timestamp {
+	target := r.store.ctx.ClosedTimestampTargetDuration
+	if target == 0 {
+		return hlc.ZeroTimestamp
+	}
+	r.mu.proposedClosedTimestamp.Forward(r.mu.closedTimestamp)
+	if now := r.store.Clock().Now(); now.WallTime > int64(target) {
+		r.mu.proposedClosedTimestamp.Forward(hlc.Timestamp{WallTime: now.WallTime - int64(target)})
+	}
+	closedTS := r.mu.proposedClosedTimestamp
+	if ba.Txn != nil {
+		ba.Txn.Timestamp.Forward(closedTS.Next())
+	} else {
+		ba.Timestamp.Forward(closedTS.Next())
+	}
+	return closedTS
+}
+
+// maybeSendClosedTimestampLocked makes the lease holder of a range which
+// isn't written to send its closed timestamp to the other replicas, so that
+// they can keep serving reads in the recent past. The closed timestamp is
+// sent on the side of raft, which keeps the ranges at rest quiescent and
+// doesn't load their raft groups. This is only done once the closed
+// timestamp lags its target by a tenth of the target duration, and once all
+// the commands proposed by the lease holder have applied: the update then
+// covers them, and the commands proposed later are moved above it.
+func (r *Replica) maybeSendClosedTimestampLocked() {
+	target := r.store.ctx.ClosedTimestampTargetDuration
+	lease := r.mu.state.Lease
+	if target == 0 || lease == nil {
+		return
+	}
+	now := r.store.Clock().Now()
+	if r.mu.closedTimestamp.WallTime >= now.WallTime-int64(target+target/10) {
+		return
+	}
+	if !lease.OwnedBy(r.store.StoreID()) || !r.isLeaseValid(lease, now) {
+		return
+	}
+	if len(r.mu.pendingCmds) > 0 || r.mu.lastAssignedLeaseIndex > r.mu.state.LeaseAppliedIndex {
+		return
+	}
+	fromReplica, err := r.getReplicaDescriptorLocked()
+	if err != nil {
+		return
+	}
+
+	r.mu.proposedClosedTimestamp.Forward(hlc.Timestamp{WallTime: now.WallTime - int64(target)})
+	r.mu.closedTimestamp.Forward(r.mu.proposedClosedTimestamp)
+	update := &ClosedTimestampUpdate{
+		Timestamp:         r.mu.closedTimestamp,
+		LeaseAppliedIndex: r.mu.state.LeaseAppliedIndex,
+		LeaseStart:        lease.Start,
+	}
+	for _, toReplica := range r.mu.state.Desc.Replicas {
+		if toReplica.ReplicaID == fromReplica.ReplicaID {
+			continue
+		}
+		if !r.raftSender.SendAsync(&RaftMessageRequest{
+			RangeID:     r.RangeID,
+			FromReplica: fromReplica,
+			ToReplica:   toReplica,
+			Message: raftpb.Message{
+				Type: raftpb.MsgHeartbeat,
+				From: uint64(fromReplica.ReplicaID),
+				To:   uint64(toReplica.ReplicaID),
+			},
+			ClosedTimestamp: update,
+		}) {
+			r.mu.droppedMessages++
+		}
+	}
+}
+
+// maybeApplyClosedTimestamp advances the closed timestamp of the replica to
+// the one sent by the lease holder, provided the replica has applied all the
+// commands proposed under the same lease before the update was sent.
+func (r *Replica) maybeApplyClosedTimestamp(
+	fromReplica roachpb.ReplicaDescriptor, update ClosedTimestampUpdate,
+) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lease := r.mu.state.Lease
+	if lease == nil || !lease.OwnedBy(fromReplica.StoreID) || !lease.Start.Equal(update.LeaseStart) {
+		return
+	}
+	if r.mu.state.LeaseAppliedIndex < update.LeaseAppliedIndex {
+		return
+	}
+	r.mu.closedTimestamp.Forward(update.Timestamp)
+}
+
+// canServeFollowerReadLocked returns whether the read-only batch can be
+// served by this replica without holding the range lease, which is the case
+// if all values it may observe are at or below the closed timestamp.
+func (r *Replica) canServeFollowerReadLocked(ba roachpb.BatchRequest) bool {
+	if ba.ReadConsistency != roachpb.CONSISTENT || r.mu.closedTimestamp == hlc.ZeroTimestamp {
+		return false
+	}
+	ts := ba.Timestamp
+	if ba.Txn != nil {
+		ts.Forward(ba.Txn.MaxTimestamp)
+	}
+	return !r.mu.closedTimestamp.Less(ts)
+}
+
 func (r *Replica) insertRaftCommandLocked(pCmd *pendingCmd) {
 	idKey := pCmd.idKey
 	if _, ok := r.mu.pendingCmds[idKey]; ok {
@@ -1463,26 +1703,64 @@ func (r *Replica) proposeRaftCommand(
 	ctx context.Context, ba roachpb.BatchRequest,
 ) (
 	chan roachpb.ResponseWithError, func() bool, error) {
+	// As the raft leader, wait for the followers to catch up if the proposed
+	// commands they haven't appended yet exceed the proposal quota. Lease
+	// requests aren't throttled, as no other command can be proposed without
+	// a valid lease.
+	r.mu.Lock()
+	quota := r.mu.proposalQuota
+	r.mu.Unlock()
+	var quotaSize int64
+	if quota != nil && !ba.IsLease() {
+		quotaSize = int64(ba.Size())
+		if quotaSize > quota.capacity() {
+			quotaSize = quota.capacity()
+		}
+		log.Trace(ctx, "acquiring proposal quota")
+		if err := quota.acquire(ctx, quotaSize); err == errQuotaPoolClosed {
+			// The replica stopped being the raft leader.
+			quotaSize = 0
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.mu.proposalQuota != quota {
+		// The pool the quota was acquired from was closed in the meantime.
+		quotaSize = 0
+	}
+	releaseQuota := func() {
+		if quotaSize > 0 {
+			quota.add(quotaSize)
+		}
+	}
 	if r.mu.destroyed != nil {
+		releaseQuota()
 		return nil, nil, r.mu.destroyed
 	}
 	repDesc, ok := r.mu.state.Desc.GetReplicaDescriptor(r.store.StoreID())
 	if !ok {
+		releaseQuota()
 		return nil, nil, roachpb.NewRangeNotFoundError(r.RangeID)
 	}
 	pCmd := r.prepareRaftCommandLocked(ctx, makeIDKey(), repDesc, ba)
+	pCmd.quotaSize = quotaSize
 	r.insertRaftCommandLocked(pCmd)
 
 	if err := r.proposePendingCmdLocked(pCmd); err != nil {
 		delete(r.mu.pendingCmds, pCmd.idKey)
+		releaseQuota()
 		return nil, nil, err
 	}
 	tryAbandon := func() bool {
 		r.mu.Lock()
-		_, ok := r.mu.pendingCmds[pCmd.idKey]
+		p, ok := r.mu.pendingCmds[pCmd.idKey]
 		delete(r.mu.pendingCmds, pCmd.idKey)
+		if ok && p.quotaSize > 0 && r.mu.proposalQuota != nil {
+			r.mu.proposalQuota.add(p.quotaSize)
+		}
 		r.mu.Unlock()
 		return ok
 	}
@@ -1492,6 +1770,7 @@ func (r *Replica) proposeRaftCommand(
 // proposePendingCmdLocked proposes or re-proposes a command in r.mu.pendingCmds.
 // The replica lock must be held.
 func (r *Replica) proposePendingCmdLocked(p *pendingCmd) error {
+	r.unquiesceLocked()
 	p.proposedAtTicks = r.mu.ticks
 	if r.mu.proposeRaftCommandFn != nil {
 		return r.mu.proposeRaftCommandFn(p)
@@ -1531,7 +1810,7 @@ func defaultProposeRaftCommandLocked(r *Replica, p *pendingCmd) error {
 
 			return r.withRaftGroupLocked(func(raftGroup *raft.RawNode) error {
 				return raftGroup.ProposeConfChange(raftpb.ConfChange{
-					Type:    changeTypeInternalToRaft[crt.ChangeType],
+					Type:    confChangeType(crt),
 					NodeID:  uint64(crt.Replica.ReplicaID),
 					Context: encodedCtx,
 				})
@@ -1561,6 +1840,7 @@ func (r *Replica) handleRaftReady() error {
 		}
 		return nil
 	})
+	r.updateProposalQuotaLocked()
 	r.mu.Unlock()
 	if err != nil {
 		return err
@@ -1615,11 +1895,17 @@ func (r *Replica) handleRaftReady() error {
 
 	// We know that all of the writes from here forward will be to distinct keys.
 	writer := batch.Distinct()
+	raftBatch, raftWriter := batch, writer
+	if r.store.separateRaftLog() {
+		raftBatch = r.store.RaftEngine().NewBatch()
+		defer raftBatch.Close()
+		raftWriter = raftBatch.Distinct()
+	}
 	if len(rd.Entries) > 0 {
 		// All of the entries are appended to distinct keys, returning a new
 		// last index.
 		var err error
-		if lastIndex, raftLogSize, err = r.append(ctx, writer, lastIndex, raftLogSize, rd.Entries); err != nil {
+		if lastIndex, raftLogSize, err = r.append(ctx, writer, raftWriter, lastIndex, raftLogSize, rd.Entries); err != nil {
 			return err
 		}
 	}
@@ -1628,6 +1914,13 @@ func (r *Replica) handleRaftReady() error {
 			return err
 		}
 	}
+	// A raft log kept separately is committed first, so that the last index
+	// and HardState committed with the batch never refer to missing entries.
+	if raftBatch != batch {
+		if err := raftBatch.Commit(); err != nil {
+			return err
+		}
+	}
 	if err := batch.Commit(); err != nil {
 		return err
 	}
@@ -1723,18 +2016,85 @@ func (r *Replica) handleRaftReady() error {
 	})
 }
 
-func (r *Replica) tick() error {
+// updateProposalQuotaLocked creates the proposal quota pool when the replica
+// becomes the raft leader, and closes it when the replica stops being the
+// leader. As the leader, it releases the quota of the applied commands whose
+// entries all the active followers have appended. Followers which are being
+// sent a snapshot are ignored, as they catch up from the snapshot.
+func (r *Replica) updateProposalQuotaLocked() {
+	rg := r.mu.internalRaftGroup
+	if rg == nil {
+		return
+	}
+	status := rg.Status()
+	if status.RaftState != raft.StateLeader {
+		if r.mu.proposalQuota != nil {
+			r.mu.proposalQuota.close()
+			r.mu.proposalQuota = nil
+			r.mu.quotaReleaseQueue = nil
+		}
+		return
+	}
+	if r.mu.proposalQuota == nil {
+		r.mu.proposalQuota = newQuotaPool(r.store.ctx.RaftProposalQuota)
+	}
+
+	minIndex := status.Commit
+	for id, progress := range status.Progress {
+		if id == status.ID || !progress.RecentActive || progress.State == raft.ProgressStateSnapshot {
+			continue
+		}
+		if progress.Match < minIndex {
+			minIndex = progress.Match
+		}
+	}
+	var size int64
+	var n int
+	for _, release := range r.mu.quotaReleaseQueue {
+		if release.index > minIndex {
+			break
+		}
+		size += release.size
+		n++
+	}
+	if n > 0 {
+		r.mu.quotaReleaseQueue = r.mu.quotaReleaseQueue[n:]
+		r.mu.proposalQuota.add(size)
+	}
+}
+
+// tick ticks the raft group of the replica unless it's quiescent, and
+// returns whether it is. The raft group of a replica which has been
+// quiescent for RaftUnloadQuiescentTicks is unloaded, and lazily recreated
+// when the replica is woken up. A replica whose raft group isn't loaded is
+// considered quiescent.
+func (r *Replica) tick() (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// Advance the closed timestamp of the ranges which aren't written to,
+	// including the quiescent ones, without waking them up.
+	r.maybeSendClosedTimestampLocked()
+
 	// If the raft group is uninitialized, do not initialize raft groups on
 	// tick.
 	if r.mu.internalRaftGroup == nil {
-		return nil
+		return true, nil
+	}
+
+	if r.mu.quiescent {
+		r.mu.quiescentTicks++
+		if r.mu.quiescentTicks >= r.store.ctx.RaftUnloadQuiescentTicks {
+			r.unloadRaftGroupLocked()
+		}
+		return true, nil
 	}
 
 	r.mu.ticks++
 	r.mu.internalRaftGroup.Tick()
+	// The progress of the followers may have changed without the raft group
+	// becoming ready.
+	r.updateProposalQuotaLocked()
 	if !r.store.TestingKnobs().DisableRefreshReasonTicks &&
 		r.mu.ticks%r.store.ctx.RaftElectionTimeoutTicks == 0 {
 		// RaftElectionTimeoutTicks is a reasonable approximation of how long we
@@ -1745,10 +2105,131 @@ func (r *Replica) tick() error {
 		// cycles.
 		if err := r.refreshPendingCmdsLocked(
 			reasonTicks, r.store.ctx.RaftElectionTimeoutTicks); err != nil {
-			return err
+			return false, err
 		}
 	}
-	return nil
+	return r.maybeQuiesceLocked(), nil
+}
+
+// maybeQuiesceLocked quiesces the raft group if this replica is the leader,
+// the whole log is committed, applied and appended by all the followers, and
+// there is no pending command. The followers are told to quiesce along with a
+// heartbeat which lets them check that they're caught up too. Returns whether
+// the raft group was quiesced.
+func (r *Replica) maybeQuiesceLocked() bool {
+	if r.store.TestingKnobs().DisableQuiescence {
+		return false
+	}
+	rg := r.mu.internalRaftGroup
+	if len(r.mu.pendingCmds) > 0 || rg.HasReady() {
+		return false
+	}
+	status := rg.Status()
+	if status.RaftState != raft.StateLeader {
+		return false
+	}
+	lastIndex := r.mu.lastIndex
+	if status.Commit != lastIndex || r.mu.state.RaftAppliedIndex != lastIndex {
+		return false
+	}
+	for id, progress := range status.Progress {
+		if id != status.ID && progress.Match != lastIndex {
+			return false
+		}
+	}
+
+	fromReplica, err := r.getReplicaDescriptorByIDLocked(r.mu.replicaID, r.mu.lastToReplica)
+	if err != nil {
+		return false
+	}
+	var reqs []*RaftMessageRequest
+	for id := range status.Progress {
+		if id == status.ID {
+			continue
+		}
+		toReplica, err := r.getReplicaDescriptorByIDLocked(
+			roachpb.ReplicaID(id), r.mu.lastFromReplica)
+		if err != nil {
+			return false
+		}
+		reqs = append(reqs, &RaftMessageRequest{
+			RangeID:     r.RangeID,
+			ToReplica:   toReplica,
+			FromReplica: fromReplica,
+			Message: raftpb.Message{
+				Type:   raftpb.MsgHeartbeat,
+				From:   status.ID,
+				To:     id,
+				Term:   status.Term,
+				Commit: status.Commit,
+			},
+			Quiesce: true,
+		})
+	}
+	for _, req := range reqs {
+		if !r.raftSender.SendAsync(req) {
+			// The follower will keep receiving the regular heartbeats.
+			r.mu.droppedMessages++
+			return false
+		}
+	}
+	if log.V(3) {
+		log.Infof(context.TODO(), "%s: quiescing at index %d", r, lastIndex)
+	}
+	r.mu.quiescent = true
+	r.mu.quiescentTicks = 0
+	return true
+}
+
+// maybeQuiesceOnHeartbeat quiesces a follower whose leader sent it the given
+// quiesce heartbeat, if the follower is caught up with the leader. Returns
+// false if the heartbeat should be stepped into the raft group instead,
+// which lets the leader know that the follower isn't quiescent.
+func (r *Replica) maybeQuiesceOnHeartbeat(msg raftpb.Message) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rg := r.mu.internalRaftGroup
+	if rg == nil {
+		// The raft group isn't loaded, which is as quiet as it gets.
+		return true
+	}
+	if rg.HasReady() {
+		return false
+	}
+	status := rg.Status()
+	if status.Term != msg.Term || status.Commit != msg.Commit || status.Lead != msg.From {
+		return false
+	}
+	r.mu.quiescent = true
+	r.mu.quiescentTicks = 0
+	return true
+}
+
+// unquiesceLocked wakes up a quiescent raft group, which gets ticked again.
+func (r *Replica) unquiesceLocked() {
+	if r.mu.quiescent {
+		if log.V(3) {
+			log.Infof(context.TODO(), "%s: unquiescing", r)
+		}
+		r.mu.quiescent = false
+		r.mu.quiescentTicks = 0
+	}
+}
+
+// unloadRaftGroupLocked drops the in-memory raft group of the replica, which
+// is recreated from the persisted raft state on its next use.
+func (r *Replica) unloadRaftGroupLocked() {
+	if log.V(3) {
+		log.Infof(context.TODO(), "%s: unloading quiescent raft group", r)
+	}
+	r.mu.internalRaftGroup = nil
+	r.mu.quiescent = false
+	r.mu.quiescentTicks = 0
+	if r.mu.proposalQuota != nil {
+		r.mu.proposalQuota.close()
+		r.mu.proposalQuota = nil
+		r.mu.quotaReleaseQueue = nil
+	}
 }
 
 // pendingCmdSlice sorts by increasing MaxLeaseIndex.
@@ -1937,7 +2418,10 @@ func (r *Replica) processRaftCommand(
 		if l.Replica != origin && !ba.IsLease() {
 			return true
 		}
-		notCovered := !l.OwnedBy(origin.StoreID) || !l.Covers(ba.Timestamp)
+		// The validity of epoch-based leases depends on node liveness, which
+		// isn't part of the replicated state, so it was verified by the
+		// proposer only.
+		notCovered := !l.OwnedBy(origin.StoreID) || (l.Epoch == nil && !l.Covers(ba.Timestamp))
 		if notCovered && !ba.IsFreeze() && !ba.IsLease() {
 			// Verify the range lease is held, unless this command is trying
 			// to obtain it or is a freeze change (which can be proposed by any
@@ -1998,6 +2482,13 @@ func (r *Replica) processRaftCommand(
 		// to a replay), and assigning the required index would be tedious
 		// seeing that it would have to rewind sometimes.
 		leaseIndex = raftCmd.MaxLeaseIndex
+		// Only the closed timestamps of the current lease are reliable: a
+		// command proposed under an earlier lease of the same replica may
+		// apply after another replica held the lease and wrote below its
+		// closed timestamp.
+		if raftCmd.ProposerLeaseStart.Equal(r.mu.state.Lease.Start) {
+			r.mu.closedTimestamp.Forward(raftCmd.ClosedTimestamp)
+		}
 	} else {
 		// The command is trying to apply at a past log position. That's
 		// unfortunate and hopefully rare; we will refurbish on the proposer.
@@ -2040,6 +2531,13 @@ func (r *Replica) processRaftCommand(
 			}
 		}
 	}
+	if cmd != nil && cmd.quotaSize > 0 && r.mu.proposalQuota != nil {
+		// The quota of the command is released once the active followers
+		// have appended its entry. A refurbished incarnation of the command
+		// is proposed without quota.
+		r.mu.quotaReleaseQueue = append(r.mu.quotaReleaseQueue,
+			quotaRelease{index: index, size: cmd.quotaSize})
+	}
 	r.mu.Unlock()
 
 	log.Trace(ctx, "applying batch")
@@ -2050,6 +2548,8 @@ func (r *Replica) processRaftCommand(
 		log.Infof(context.TODO(), "%s: applying command with forced error: %v", r, forcedErr)
 	}
 
+	r.rangeFeedMu.Lock()
+	defer r.rangeFeedMu.Unlock()
 	br, propResult, pErr := r.applyRaftCommand(idKey, ctx, index, leaseIndex,
 		raftCmd.OriginReplica, raftCmd.Cmd, forcedErr)
 	pErr = r.maybeSetCorrupt(ctx, pErr)
@@ -2078,6 +2578,8 @@ func (r *Replica) processRaftCommand(
 		r.maybeAddToRaftLogQueue(index)
 	}
 
+	r.publishToRangeFeedsLocked(propResult.rangeFeedOps)
+
 	if cmd != nil {
 		cmd.done <- roachpb.ResponseWithError{Reply: br, Err: pErr}
 		close(cmd.done)
@@ -2173,6 +2675,13 @@ func (r *Replica) applyRaftCommand(
 	// the future.
 	writer.Close()
 
+	// The RangeFeeds need the writes of the command, which are read from the
+	// batch before it is committed.
+	var err error
+	if propResult.rangeFeedOps, err = r.rangeFeedOpsLocked(batch); err != nil {
+		log.Fatalf(ctx, "reading the writes of a batch should never fail: %s", err)
+	}
+
 	// TODO(tschottdorf): with proposer-eval'ed KV, the batch would not be
 	// committed at this point. Instead, it would be added to propResult.
 	if err := batch.Commit(); err != nil {
@@ -2561,6 +3070,16 @@ func (r *Replica) executeBatch(
 			maxKeys -= retResults
 		}
 
+		if ba.Header.TargetBytes > 0 {
+			// Keep track of the bytes remaining for the batch. Once the target
+			// is reached, the remaining span requests return without touching
+			// any keys, just as when the key limit is reached.
+			ba.Header.TargetBytes -= reply.Header().NumBytes
+			if ba.Header.TargetBytes <= 0 {
+				maxKeys = 0
+			}
+		}
+
 		// If transactional, we use ba.Txn for each individual command and
 		// accumulate updates to it.
 		// TODO(spencer,tschottdorf): need copy-on-write behavior for the
@@ -2714,7 +3233,7 @@ func (r *Replica) maybeGossipSystemConfig() {
 		return
 	}
 
-	if lease, _ := r.getLease(); !lease.OwnedBy(r.store.StoreID()) || !lease.Covers(r.store.Clock().Now()) {
+	if lease, _ := r.getLease(); !lease.OwnedBy(r.store.StoreID()) || !r.isLeaseValid(lease, r.store.Clock().Now()) {
 		// Do not gossip when a range lease is not held.
 		return
 	}
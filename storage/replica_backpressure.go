@@ -0,0 +1,111 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/retry"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+	"github.com/pkg/errors"
+)
+
+// backpressureRangeSizeMultiplier is the multiple of its maximum size above
+// which the writes adding data to a range are blocked until it splits. A
+// range which can't split, e.g. because its replicas are waiting for a
+// snapshot, would otherwise grow without bounds.
+var backpressureRangeSizeMultiplier = settings.RegisterFloatSetting(
+	"kv.range.backpressure_range_size_multiplier",
+	"multiple of the range max size above which writes to the range are blocked until it splits, or 0 to disable",
+	2,
+)
+
+// backpressureMaxWait is the longest a write waits for its range to split
+// before failing.
+var backpressureMaxWait = settings.RegisterDurationSetting(
+	"kv.range.backpressure_max_wait",
+	"the longest a write waits for its oversized range to split before failing",
+	30*time.Second,
+)
+
+// shouldBackpressureWrites returns whether the range exceeds its maximum
+// size by the backpressure multiplier.
+func (r *Replica) shouldBackpressureWrites() bool {
+	mult := backpressureRangeSizeMultiplier.Get()
+	if mult <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	maxBytes := r.mu.maxBytes
+	size := r.mu.state.Stats.Total()
+	return maxBytes > 0 && float64(size) > mult*float64(maxBytes)
+}
+
+// canBackpressureBatch returns whether the batch adds user data to the range.
+// The other writes, e.g. the transactions splitting the range, the
+// resolution of intents or the deletions, are never blocked: they are needed
+// for the range to make progress, or shrink it.
+func canBackpressureBatch(ba roachpb.BatchRequest) bool {
+	for _, union := range ba.Requests {
+		switch args := union.GetInner().(type) {
+		case *roachpb.PutRequest, *roachpb.ConditionalPutRequest,
+			*roachpb.InitPutRequest, *roachpb.IncrementRequest, *roachpb.MergeRequest:
+			if args.Header().Key.Compare(keys.UserTableDataMin) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maybeBackpressureWriteBatch blocks the batch until the range is below the
+// backpressure threshold if the batch adds data to it, and queues the range
+// for a split. An error is returned if the range doesn't split in time.
+func (r *Replica) maybeBackpressureWriteBatch(ctx context.Context, ba roachpb.BatchRequest) error {
+	if !canBackpressureBatch(ba) || !r.shouldBackpressureWrites() {
+		return nil
+	}
+	r.store.metrics.rangeBackpressuredWrites.Inc(1)
+	log.Trace(ctx, "backpressuring write to oversized range")
+	r.store.splitQueue.MaybeAdd(r, r.store.Clock().Now())
+
+	deadline := timeutil.Now().Add(backpressureMaxWait.Get())
+	opts := retry.Options{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Closer:         r.store.Stopper().ShouldQuiesce(),
+	}
+	for re := retry.StartWithCtx(ctx, opts); re.Next(); {
+		if !r.shouldBackpressureWrites() {
+			return nil
+		}
+		if timeutil.Now().After(deadline) {
+			return errors.Errorf("%s: range is too large to accept writes: %d bytes, max %d bytes",
+				r, r.GetMVCCStats().Total(), r.GetMaxBytes())
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return errors.Errorf("%s: stopped while backpressuring write", r)
+}
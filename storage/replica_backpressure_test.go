@@ -0,0 +1,74 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// TestReplicaBackpressure verifies that the writes adding user data to a
+// range exceeding its max size by the backpressure multiplier fail if the
+// range doesn't split, and that the other writes go through.
+func TestReplicaBackpressure(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	u := settings.MakeUpdater()
+	if err := u.Set("kv.range.backpressure_max_wait",
+		settings.EncodeDuration(10*time.Millisecond), backpressureMaxWait.Typ()); err != nil {
+		t.Fatal(err)
+	}
+	defer settings.MakeUpdater().Done()
+
+	// The split queue is disabled, so the range never splits.
+	tc.rng.SetMaxBytes(1)
+	userKey := roachpb.Key(keys.MakeTablePrefix(keys.MaxReservedDescID + 1))
+
+	pArgs := putArgs(userKey, []byte("value"))
+	if _, pErr := tc.SendWrapped(&pArgs); !testutils.IsPError(pErr, "range is too large to accept writes") {
+		t.Fatalf("expected the write to be backpressured, got %v", pErr)
+	}
+	if n := tc.store.metrics.rangeBackpressuredWrites.Count(); n != 1 {
+		t.Errorf("expected 1 backpressured write, got %d", n)
+	}
+
+	// Deletions and writes to the system keys are not backpressured.
+	dArgs := deleteArgs(userKey)
+	if _, pErr := tc.SendWrapped(&dArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	sysArgs := putArgs(roachpb.Key("a"), []byte("value"))
+	if _, pErr := tc.SendWrapped(&sysArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	// Backpressure can be disabled.
+	if err := u.Set("kv.range.backpressure_range_size_multiplier",
+		settings.EncodeFloat(0), backpressureRangeSizeMultiplier.Typ()); err != nil {
+		t.Fatal(err)
+	}
+	if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/coreos/etcd/raft"
 	"github.com/coreos/etcd/raft/raftpb"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
@@ -40,9 +41,11 @@ import (
 	"github.com/cockroachdb/cockroach/storage/engine"
 	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
 	"github.com/cockroachdb/cockroach/storage/storagebase"
+	"github.com/cockroachdb/cockroach/util/extstorage"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
 	"github.com/cockroachdb/cockroach/util/protoutil"
+	"github.com/cockroachdb/cockroach/util/retry"
 	"github.com/cockroachdb/cockroach/util/timeutil"
 	"github.com/cockroachdb/cockroach/util/uuid"
 )
@@ -171,6 +174,18 @@ func (r *Replica) executeCmd(
 	case *roachpb.ChangeFrozenRequest:
 		resp := reply.(*roachpb.ChangeFrozenResponse)
 		*resp, trigger, err = r.ChangeFrozen(ctx, batch, ms, h, *tArgs)
+	case *roachpb.IngestRequest:
+		resp := reply.(*roachpb.IngestResponse)
+		*resp, err = r.Ingest(ctx, batch, ms, h, *tArgs)
+	case *roachpb.ExportRequest:
+		resp := reply.(*roachpb.ExportResponse)
+		*resp, err = r.Export(ctx, batch, h, *tArgs)
+	case *roachpb.AddSSTableRequest:
+		resp := reply.(*roachpb.AddSSTableResponse)
+		*resp, err = r.AddSSTable(ctx, batch, ms, h, *tArgs)
+	case *roachpb.ClearRangeRequest:
+		resp := reply.(*roachpb.ClearRangeResponse)
+		*resp, err = r.ClearRange(ctx, batch, ms, h, *tArgs)
 	default:
 		err = errors.Errorf("unrecognized command %s", args.Method())
 	}
@@ -327,6 +342,21 @@ func (r *Replica) DeleteRange(
 	args roachpb.DeleteRangeRequest,
 ) (roachpb.DeleteRangeResponse, *roachpb.Span, int64, error) {
 	var reply roachpb.DeleteRangeResponse
+	if args.UseRangeTombstone {
+		if h.Txn != nil {
+			return reply, nil, 0, errors.Errorf("cannot write range tombstone within a transaction")
+		}
+		if args.ReturnKeys {
+			return reply, nil, 0, errors.Errorf("cannot return keys deleted by a range tombstone")
+		}
+		if maxKeys == 0 {
+			return reply, &args.Span, 0, nil
+		}
+		// The tombstone doesn't touch the keys it covers, so it doesn't count
+		// against the key limit.
+		err := engine.MVCCDeleteRangeUsingTombstone(ctx, batch, ms, args.Key, args.EndKey, h.Timestamp)
+		return reply, nil, 0, err
+	}
 	deleted, resumeKey, num, err := engine.MVCCDeleteRange(
 		ctx, batch, ms, args.Key, args.EndKey, maxKeys, h.Timestamp, h.Txn, args.ReturnKeys,
 	)
@@ -350,7 +380,8 @@ func (r *Replica) DeleteRange(
 
 // Scan scans the key range specified by start key through end key in ascending order up to some
 // maximum number of results. maxKeys stores the number of scan results remaining for this
-// batch (MaxInt64 for no limit).
+// batch (MaxInt64 for no limit). h.TargetBytes, if set, additionally bounds the size of the
+// results.
 func (r *Replica) Scan(
 	ctx context.Context,
 	batch engine.ReadWriter,
@@ -362,20 +393,24 @@ func (r *Replica) Scan(
 	if maxKeys == 0 {
 		return roachpb.ScanResponse{}, &span, 0, nil, nil
 	}
-	rows, intents, err := engine.MVCCScan(ctx, batch, args.Key, args.EndKey, maxKeys, h.Timestamp,
-		h.ReadConsistency == roachpb.CONSISTENT, h.Txn)
+	rows, numBytes, intents, err := engine.MVCCScanWithTargetBytes(ctx, batch, args.Key, args.EndKey,
+		maxKeys, h.TargetBytes, h.Timestamp, h.ReadConsistency == roachpb.CONSISTENT, h.Txn,
+		false /* !reverse */)
 	numKeys := int64(len(rows))
 	var retSpan *roachpb.Span
-	if numKeys == maxKeys {
+	if numKeys == maxKeys || (h.TargetBytes > 0 && numBytes >= h.TargetBytes) {
 		span.Key = rows[numKeys-1].Key.Next()
 		retSpan = &span
 	}
-	return roachpb.ScanResponse{Rows: rows}, retSpan, numKeys, intentsToTrigger(intents, &args), err
+	reply := roachpb.ScanResponse{Rows: rows}
+	reply.NumBytes = numBytes
+	return reply, retSpan, numKeys, intentsToTrigger(intents, &args), err
 }
 
 // ReverseScan scans the key range specified by start key through end key in descending order up to
 // some maximum number of results. maxKeys stores the number of scan results remaining for
-// this batch (MaxInt64 for no limit).
+// this batch (MaxInt64 for no limit). h.TargetBytes, if set, additionally bounds the size of
+// the results.
 func (r *Replica) ReverseScan(
 	ctx context.Context,
 	batch engine.ReadWriter,
@@ -387,15 +422,18 @@ func (r *Replica) ReverseScan(
 	if maxKeys == 0 {
 		return roachpb.ReverseScanResponse{}, &span, 0, nil, nil
 	}
-	rows, intents, err := engine.MVCCReverseScan(ctx, batch, args.Key, args.EndKey, maxKeys,
-		h.Timestamp, h.ReadConsistency == roachpb.CONSISTENT, h.Txn)
+	rows, numBytes, intents, err := engine.MVCCScanWithTargetBytes(ctx, batch, args.Key, args.EndKey,
+		maxKeys, h.TargetBytes, h.Timestamp, h.ReadConsistency == roachpb.CONSISTENT, h.Txn,
+		true /* reverse */)
 	numKeys := int64(len(rows))
 	var retSpan *roachpb.Span
-	if numKeys == maxKeys {
+	if numKeys == maxKeys || (h.TargetBytes > 0 && numBytes >= h.TargetBytes) {
 		span.EndKey = rows[numKeys-1].Key
 		retSpan = &span
 	}
-	return roachpb.ReverseScanResponse{Rows: rows}, retSpan, numKeys, intentsToTrigger(intents, &args), err
+	reply := roachpb.ReverseScanResponse{Rows: rows}
+	reply.NumBytes = numBytes
+	return reply, retSpan, numKeys, intentsToTrigger(intents, &args), err
 }
 
 func verifyTransaction(h roachpb.Header, args roachpb.Request) error {
@@ -1135,11 +1173,19 @@ func (r *Replica) HeartbeatTxn(
 ) (roachpb.HeartbeatTxnResponse, error) {
 	var reply roachpb.HeartbeatTxnResponse
 
-	if err := verifyTransaction(h, &args); err != nil {
-		return reply, err
+	// The transaction to heartbeat is specified by the request when
+	// heartbeats of multiple transactions are batched together.
+	txnMeta := args.Txn
+	if txnMeta == nil {
+		if err := verifyTransaction(h, &args); err != nil {
+			return reply, err
+		}
+		txnMeta = &h.Txn.TxnMeta
+	} else if !bytes.Equal(args.Key, txnMeta.Key) {
+		return reply, errors.Errorf("request key %s should match txn key %s", args.Key, txnMeta.Key)
 	}
 
-	key := keys.TransactionKey(h.Txn.Key, h.Txn.ID)
+	key := keys.TransactionKey(txnMeta.Key, txnMeta.ID)
 
 	var txn roachpb.Transaction
 	if ok, err := engine.MVCCGetProto(ctx, batch, key, hlc.ZeroTimestamp, true, nil, &txn); err != nil {
@@ -1149,7 +1195,7 @@ func (r *Replica) HeartbeatTxn(
 		// This could mean the heartbeat is a delayed relic or it could
 		// mean that the BeginTransaction call was delayed. In either
 		// case, there's no reason to persist a new transaction record.
-		return reply, errors.Errorf("heartbeat for transaction %s failed; record not present", h.Txn)
+		return reply, errors.Errorf("heartbeat for transaction %s failed; record not present", txnMeta.ID)
 	}
 
 	if txn.Status == roachpb.PENDING {
@@ -1191,6 +1237,7 @@ func (r *Replica) GC(
 
 	var reply roachpb.GCResponse
 	// Garbage collect the specified keys by expiration timestamps.
+	before := ms.KeyBytes + ms.ValBytes
 	err := engine.MVCCGarbageCollect(ctx, batch, ms, keys, h.Timestamp)
 	if err != nil {
 		return reply, nil, err
@@ -1204,6 +1251,15 @@ func (r *Replica) GC(
 	trigger := &PostCommitTrigger{
 		gcThreshold: &newThreshold,
 	}
+	// Let the compactor reclaim the space of the collected versions, which
+	// may add up to a lot when e.g. a table was dropped.
+	if cleared := before - (ms.KeyBytes + ms.ValBytes); cleared > 0 {
+		desc := r.Desc()
+		trigger.suggestedCompactions = []suggestedCompaction{{
+			span:  roachpb.Span{Key: desc.StartKey.AsRawKey(), EndKey: desc.EndKey.AsRawKey()},
+			bytes: cleared,
+		}}
+	}
 	return reply, trigger, setGCThreshold(ctx, batch, ms, r.Desc().RangeID, &newThreshold)
 }
 
@@ -1555,11 +1611,23 @@ func (r *Replica) TruncateLog(
 	start := keys.RaftLogKey(r.RangeID, 0)
 	end := keys.RaftLogKey(r.RangeID, args.Index)
 	var diff enginepb.MVCCStats
-	// Passing zero timestamp to MVCCDeleteRange is equivalent to a ranged clear
-	// but it also computes stats.
-	if _, _, _, err := engine.MVCCDeleteRange(ctx, batch, &diff, start, end, math.MaxInt64, /* max */
-		hlc.ZeroTimestamp, nil /* txn */, false /* returnKeys */); err != nil {
-		return reply, nil, err
+	if r.store.separateRaftLog() {
+		// The entries of a raft log kept separately are removed once the
+		// truncation is applied; only their size is computed here.
+		iter := r.store.RaftEngine().NewIterator(false)
+		logMS, err := iter.ComputeStats(engine.MakeMVCCMetadataKey(start), engine.MakeMVCCMetadataKey(end), 0)
+		iter.Close()
+		if err != nil {
+			return reply, nil, err
+		}
+		diff.Subtract(logMS)
+	} else {
+		// Passing zero timestamp to MVCCDeleteRange is equivalent to a ranged
+		// clear but it also computes stats.
+		if _, _, _, err := engine.MVCCDeleteRange(ctx, batch, &diff, start, end, math.MaxInt64, /* max */
+			hlc.ZeroTimestamp, nil /* txn */, false /* returnKeys */); err != nil {
+			return reply, nil, err
+		}
 	}
 	raftLogSize := r.mu.raftLogSize + diff.SysBytes
 	// Check raftLogSize since it isn't persisted between server restarts.
@@ -1610,7 +1678,7 @@ func (r *Replica) RequestLease(
 
 	// MIGRATION(tschottdorf): needed to apply Raft commands which got proposed
 	// before the StartStasis field was introduced.
-	if args.Lease.StartStasis.Equal(hlc.ZeroTimestamp) {
+	if args.Lease.Epoch == nil && args.Lease.StartStasis.Equal(hlc.ZeroTimestamp) {
 		args.Lease.StartStasis = args.Lease.Expiration
 	}
 
@@ -1632,9 +1700,14 @@ func (r *Replica) RequestLease(
 	// merge without ticking away from the minimal common start timestamp. It
 	// also has the positive side-effect of fixing #3561, which was caused by
 	// the absence of replay protection.
+	//
+	// An epoch-based lease of another node has no expiration; the requester
+	// made sure it was invalidated by incrementing the epoch of the node's
+	// liveness record, which is only possible after the lease holder
+	// stopped using it.
 	if prevLease.Replica.StoreID == 0 || isExtension {
 		effectiveStart.Backward(prevLease.Start)
-	} else {
+	} else if prevLease.Epoch == nil {
 		effectiveStart.Backward(prevLease.Expiration.Next())
 	}
 
@@ -1643,8 +1716,10 @@ func (r *Replica) RequestLease(
 			rErr.Message = "extension moved start timestamp backwards"
 			return roachpb.RequestLeaseResponse{}, newFailedLeaseTrigger(), rErr
 		}
-		args.Lease.Expiration.Forward(prevLease.Expiration)
-	} else if effectiveStart.Less(prevLease.Expiration) {
+		if args.Lease.Epoch == nil {
+			args.Lease.Expiration.Forward(prevLease.Expiration)
+		}
+	} else if prevLease.Epoch == nil && effectiveStart.Less(prevLease.Expiration) {
 		rErr.Message = "requested lease overlaps previous lease"
 		return roachpb.RequestLeaseResponse{}, newFailedLeaseTrigger(), rErr
 	}
@@ -1703,9 +1778,9 @@ func (r *Replica) applyNewLeaseLocked(
 	// a newFailedLeaseTrigger() to satisfy stats.
 
 	prevLease := r.mu.state.Lease
-	// Ensure Start < StartStasis <= Expiration.
-	if !lease.Start.Less(lease.StartStasis) ||
-		lease.Expiration.Less(lease.StartStasis) {
+	// Ensure Start < StartStasis <= Expiration for expiration-based leases.
+	if lease.Epoch == nil && (!lease.Start.Less(lease.StartStasis) ||
+		lease.Expiration.Less(lease.StartStasis)) {
 		// This amounts to a bug.
 		return roachpb.RequestLeaseResponse{}, newFailedLeaseTrigger(),
 			&roachpb.LeaseRejectedError{
@@ -2051,6 +2126,283 @@ func (r *Replica) ChangeFrozen(
 	return resp, trigger, nil
 }
 
+// Ingest writes the MVCC key/value pairs contained in the request's data
+// directly into the replica's engine. Unlike Put, it does not consult or
+// update existing values, intents or the timestamp cache, so it must only be
+// used on key spans which are not yet visible to clients (e.g. the span of a
+// table being imported). Entries falling outside of the request's span (which
+// DistSender may have truncated to this range) are skipped, so a single batch
+// of data may be sent to a span covering several ranges.
+func (r *Replica) Ingest(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	ms *enginepb.MVCCStats,
+	h roachpb.Header,
+	args roachpb.IngestRequest,
+) (roachpb.IngestResponse, error) {
+	var reply roachpb.IngestResponse
+
+	reader, err := engine.NewRocksDBBatchReader(args.Data)
+	if err != nil {
+		return reply, err
+	}
+
+	start := engine.MakeMVCCMetadataKey(args.Key)
+	end := engine.MakeMVCCMetadataKey(args.EndKey)
+	computeStats := func() (enginepb.MVCCStats, error) {
+		iter := batch.NewIterator(false)
+		defer iter.Close()
+		return iter.ComputeStats(start, end, h.Timestamp.WallTime)
+	}
+
+	// The ingested keys may shadow existing data, so compute the stats delta
+	// by scanning the span before and after writing.
+	before, err := computeStats()
+	if err != nil {
+		return reply, err
+	}
+	for reader.Next() {
+		if !reader.IsPut() {
+			return reply, errors.Errorf("ingest only supports puts")
+		}
+		key, err := reader.MVCCKey()
+		if err != nil {
+			return reply, err
+		}
+		if key.Timestamp == hlc.ZeroTimestamp {
+			// Ingesting metadata keys would allow writing intents and inline
+			// values behind MVCC's back.
+			return reply, errors.Errorf("cannot ingest key %s without a timestamp", key)
+		}
+		if key.Key.Compare(args.Key) < 0 || key.Key.Compare(args.EndKey) >= 0 {
+			continue
+		}
+		if err := batch.Put(key, reader.Value()); err != nil {
+			return reply, err
+		}
+	}
+	if err := reader.Error(); err != nil {
+		return reply, err
+	}
+	after, err := computeStats()
+	if err != nil {
+		return reply, err
+	}
+	ms.Subtract(before)
+	ms.Add(after)
+	return reply, nil
+}
+
+// Export writes the latest values of the keys in the request's span, as of
+// the request's timestamp, to an SSTable in the external storage directory
+// named by the request. Deleted keys are omitted. Intents in the span cause a
+// WriteIntentError, so that they are resolved before the request is retried.
+// No file is written if the span contains no data.
+//
+// If the request has a start time, the revisions written after it are
+// exported instead, deletions included. If the request asks for the file to
+// be returned, it is put in the response instead of being written to
+// external storage.
+func (r *Replica) Export(
+	ctx context.Context, batch engine.ReadWriter, h roachpb.Header, args roachpb.ExportRequest,
+) (roachpb.ExportResponse, error) {
+	var reply roachpb.ExportResponse
+	if h.Txn != nil {
+		return reply, errTransactionUnsupported
+	}
+
+	sst, err := engine.MakeRocksDBSstFileWriter()
+	if err != nil {
+		return reply, err
+	}
+	defer sst.Close()
+
+	add := func(kv roachpb.KeyValue) error {
+		key := engine.MVCCKey{Key: kv.Key, Timestamp: kv.Value.Timestamp}
+		return sst.Add(engine.MVCCKeyValue{Key: key, Value: kv.Value.RawBytes})
+	}
+	if args.StartTime != hlc.ZeroTimestamp {
+		err = engine.MVCCIterateRevisions(batch, args.Key, args.EndKey, args.StartTime, h.Timestamp, add)
+	} else {
+		_, err = engine.MVCCIterate(ctx, batch, args.Key, args.EndKey, h.Timestamp,
+			true /* consistent */, nil /* txn */, false /* !reverse */, func(kv roachpb.KeyValue) (bool, error) {
+				return false, add(kv)
+			})
+	}
+	if err != nil {
+		return reply, err
+	}
+	if sst.DataSize == 0 {
+		return reply, nil
+	}
+	data, err := sst.Finish()
+	if err != nil {
+		return reply, err
+	}
+	if args.ReturnSST {
+		reply.Files = []roachpb.ExportResponse_File{{
+			Span:     args.Span,
+			DataSize: sst.DataSize,
+			SST:      data,
+		}}
+		return reply, nil
+	}
+
+	// Retries and requests for other spans of this range must not overwrite
+	// each other's files.
+	id := uuid.MakeV4()
+	path := fmt.Sprintf("%d-%s.sst", r.RangeID, id.String())
+	uri, err := extstorage.Join(args.Storage, path)
+	if err != nil {
+		return reply, err
+	}
+	if err := extstorage.Write(ctx, uri, data); err != nil {
+		return reply, errors.Wrapf(err, "writing %s", uri)
+	}
+	reply.Files = []roachpb.ExportResponse_File{{
+		Span:     args.Span,
+		Path:     path,
+		DataSize: sst.DataSize,
+	}}
+	return reply, nil
+}
+
+// AddSSTable links the SSTable contained in the request directly into the
+// replica's engine. Like Ingest, it bypasses the transactional write path and
+// must only be used on key spans which are not yet visible to clients.
+//
+// The file can only be linked when all of its keys fall within the request's
+// span and don't overlap data already in the engine. Otherwise (e.g. when
+// DistSender truncated the span to this range, or a previous attempt already
+// wrote part of the span), the entries within the span are written through
+// the batch instead, which is slower but has the same effect.
+func (r *Replica) AddSSTable(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	ms *enginepb.MVCCStats,
+	h roachpb.Header,
+	args roachpb.AddSSTableRequest,
+) (roachpb.AddSSTableResponse, error) {
+	var reply roachpb.AddSSTableResponse
+	if h.Txn != nil {
+		return reply, errTransactionUnsupported
+	}
+
+	sst, err := engine.MakeRocksDBSstFileReader()
+	if err != nil {
+		return reply, err
+	}
+	defer sst.Close()
+	if err := sst.IngestExternalFile(args.Data); err != nil {
+		return reply, errors.Wrap(err, "reading sstable")
+	}
+
+	var inSpan []engine.MVCCKeyValue
+	contained := true
+	if err := sst.Iterate(engine.MVCCKey{Key: roachpb.KeyMin}, engine.MVCCKey{Key: roachpb.KeyMax},
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			if kv.Key.Timestamp == hlc.ZeroTimestamp {
+				// See the comment in Ingest.
+				return true, errors.Errorf("cannot ingest key %s without a timestamp", kv.Key)
+			}
+			if kv.Key.Key.Compare(args.Key) < 0 || kv.Key.Key.Compare(args.EndKey) >= 0 {
+				contained = false
+				return false, nil
+			}
+			inSpan = append(inSpan, engine.MVCCKeyValue{
+				Key:   engine.MVCCKey{Key: append(roachpb.Key(nil), kv.Key.Key...), Timestamp: kv.Key.Timestamp},
+				Value: append([]byte(nil), kv.Value...),
+			})
+			return false, nil
+		}); err != nil {
+		return reply, err
+	}
+
+	start := engine.MakeMVCCMetadataKey(args.Key)
+	end := engine.MakeMVCCMetadataKey(args.EndKey)
+	computeStats := func() (enginepb.MVCCStats, error) {
+		iter := batch.NewIterator(false)
+		defer iter.Close()
+		return iter.ComputeStats(start, end, h.Timestamp.WallTime)
+	}
+
+	// As with Ingest, the stats delta is computed by scanning the span before
+	// and after writing.
+	before, err := computeStats()
+	if err != nil {
+		return reply, err
+	}
+	linked := false
+	if contained {
+		if err := r.store.Engine().IngestExternalFile(args.Data); err != nil {
+			log.Warningf(ctx, "%s: unable to link sstable for %s, writing its entries instead: %s",
+				r, args.Span, err)
+		} else {
+			linked = true
+		}
+	}
+	if !linked {
+		for _, kv := range inSpan {
+			if err := batch.Put(kv.Key, kv.Value); err != nil {
+				return reply, err
+			}
+		}
+	}
+	after, err := computeStats()
+	if err != nil {
+		return reply, err
+	}
+	ms.Subtract(before)
+	ms.Add(after)
+	return reply, nil
+}
+
+// clearRangeBytesThreshold is the size of the data below which ClearRange
+// clears the keys of its span one by one, in the batch of the command,
+// rather than with a range deletion, as the tombstones of range deletions
+// slow down reads until they're compacted away.
+const clearRangeBytesThreshold = 512 << 10
+
+// ClearRange removes all the data in the request's span, including all the
+// versions of its keys and their intents. Unless the span holds little data,
+// this is done with a single range deletion in the batch of the command. The
+// stats of the span are subtracted from the range's stats.
+func (r *Replica) ClearRange(
+	ctx context.Context,
+	batch engine.ReadWriter,
+	ms *enginepb.MVCCStats,
+	h roachpb.Header,
+	args roachpb.ClearRangeRequest,
+) (roachpb.ClearRangeResponse, error) {
+	var reply roachpb.ClearRangeResponse
+	if h.Txn != nil {
+		return reply, errTransactionUnsupported
+	}
+
+	start := engine.MakeMVCCMetadataKey(args.Key)
+	end := engine.MakeMVCCMetadataKey(args.EndKey)
+	iter := batch.NewIterator(false)
+	stats, err := iter.ComputeStats(start, end, h.Timestamp.WallTime)
+	iter.Close()
+	if err != nil {
+		return reply, err
+	}
+	// The range deletion is committed along with the rest of the batch, and
+	// thus atomically with the stats update on every replica.
+	if b, ok := batch.(engine.Batch); ok && stats.Total() >= clearRangeBytesThreshold {
+		if err := b.DeleteRange(start, end); err != nil {
+			return reply, err
+		}
+	} else {
+		log.Tracef(ctx, "clearing %d bytes of %s key by key", stats.Total(), args.Span)
+		if _, err := engine.ClearRange(batch, start, end); err != nil {
+			return reply, err
+		}
+	}
+	ms.Subtract(stats)
+	return reply, nil
+}
+
 // ReplicaSnapshotDiff is a part of a []ReplicaSnapshotDiff which represents a diff between
 // two replica snapshots. For now it's only a diff between their KV pairs.
 type ReplicaSnapshotDiff struct {
@@ -2444,6 +2796,12 @@ func (r *Replica) splitTrigger(
 	// Preserve stats for pre-split range, excluding the current batch.
 	origBothMS := r.GetMVCCStats()
 
+	// Split the range tombstone fragment spanning the split key, so that
+	// each side only holds the fragments covering its own keys.
+	if err := engine.MVCCSplitRangeTombstones(ctx, batch, &bothDeltaMS, split.RightDesc.StartKey.AsRawKey()); err != nil {
+		return enginepb.MVCCStats{}, nil, errors.Wrap(err, "unable to split range tombstones")
+	}
+
 	// TODO(d4l3k): we should check which side of the split is smaller
 	// and compute stats for it instead of having a constraint that the
 	// left hand side is smaller.
@@ -2787,6 +3145,15 @@ func (r *Replica) mergeTrigger(
 	}
 	mergedMS.Add(msRange)
 
+	// Add in the stats for the RHS range's range tombstones.
+	rangeTombstoneStart := engine.MakeMVCCMetadataKey(keys.RangeTombstoneKey(merge.RightDesc.StartKey))
+	rangeTombstoneEnd := engine.MakeMVCCMetadataKey(keys.RangeTombstoneKey(merge.RightDesc.EndKey))
+	msRangeTombstones, err := iter.ComputeStats(rangeTombstoneStart, rangeTombstoneEnd, ts.WallTime)
+	if err != nil {
+		return nil, errors.Errorf("unable to compute RHS range's range tombstone stats: %s", err)
+	}
+	mergedMS.Add(msRangeTombstones)
+
 	// Set stats for updated range.
 	if err := setMVCCStats(ctx, batch, r.RangeID, mergedMS); err != nil {
 		return nil, errors.Errorf("unable to write MVCC stats: %s", err)
@@ -2916,11 +3283,18 @@ func (r *Replica) changeReplicasTrigger(
 // the new replica Raft log entries to apply, or by generating and sending a
 // snapshot. See Replica.Snapshot and Replica.Entries.
 //
+// A new replica is first added as a learner, which receives the Raft log but
+// doesn't vote, and is promoted to a voter by a second replica change once it
+// has caught up. This way the range never has an even number of voters, one
+// of which can't take part in quorums, while the snapshot streams. A learner
+// which doesn't catch up in time is removed again. SwapReplicas replaces a
+// replica by another one.
+//
 // Note that Replica.ChangeReplicas returns when the distributed transaction
 // has been committed to a quorum of replicas in the range. The actual
 // replication of data occurs asynchronously via a snapshot or application of
-// Raft log entries. This is important for the replicate queue to be aware
-// of. A node can process hundreds or thousands of ChangeReplicas operations
+// Raft log entries, except for the catch up of the learners. This is
+// important for the replicate queue to be aware of. A node can process hundreds or thousands of ChangeReplicas operations
 // per second even though the actual replication of data proceeds at a much
 // slower base. In order to avoid having this background replication overwhelm
 // the system, replication is throttled via a reservation system. When
@@ -2954,6 +3328,7 @@ func (r *Replica) ChangeReplicas(
 		if nodeUsedByExistingRep && existingRep.StoreID == repDesc.StoreID {
 			repDescIdx = i
 			repDesc.ReplicaID = existingRep.ReplicaID
+			repDesc.Type = existingRep.Type
 			break
 		}
 	}
@@ -2964,74 +3339,23 @@ func (r *Replica) ChangeReplicas(
 
 	switch changeType {
 	case roachpb.ADD_REPLICA:
+		// A learner left behind by a previous attempt only needs to be promoted.
+		if repDescIdx != -1 && desc.Replicas[repDescIdx].IsLearner() {
+			_, err := r.promoteLearner(ctx, desc.Replicas[repDescIdx], desc)
+			return err
+		}
 		// If the replica exists on the remote node, no matter in which store,
 		// abort the replica add.
 		if nodeUsed {
 			return errors.Errorf("adding replica %v which is already present in range %d", repDesc, rangeID)
 		}
 
-		log.Trace(ctx, "requesting reservation")
-		// Before we try to add a new replica, we first need to secure a
-		// reservation for the replica on the receiving store.
-		if err := r.store.allocator.storePool.reserve(
-			r.store.Ident,
-			repDesc.StoreID,
-			rangeID,
-			r.GetMVCCStats().Total(),
-		); err != nil {
-			return errors.Wrapf(err, "change replicas of range %d failed", rangeID)
-		}
-		log.Trace(ctx, "reservation granted")
-
-		// Send a pre-emptive snapshot. Note that the replica to which this
-		// snapshot is addressed has not yet had its replica ID initialized; this
-		// is intentional, and serves to avoid the following race with the replica
-		// GC queue:
-		//
-		// - snapshot received, a replica is lazily created with the "real" replica ID
-		// - the replica is eligible for GC because it is not yet a member of the range
-		// - GC queue runs, creating a raft tombstone with the replica's ID
-		// - the replica is added to the range
-		// - lazy creation of the replica fails due to the raft tombstone
-		//
-		// Instead, the replica GC queue will create a tombstone with replica ID
-		// zero, which is never legitimately used, and thus never interferes with
-		// raft operations. Racing with the replica GC queue can still partially
-		// negate the benefits of pre-emptive snapshots, but that is a recoverable
-		// degradation, not a catastrophic failure.
-		snap, err := r.GetSnapshot(ctx)
-		log.Trace(ctx, "generated snapshot")
+		learner, learnerDesc, err := r.addLearner(ctx, repDesc, desc)
 		if err != nil {
-			return errors.Wrapf(err, "change replicas of range %d failed", rangeID)
-		}
-
-		fromRepDesc, err := r.GetReplicaDescriptor()
-		if err != nil {
-			return errors.Wrapf(err, "change replicas of range %d failed", rangeID)
-		}
-
-		if repDesc.ReplicaID != 0 {
-			return errors.Errorf(
-				"must not specify a ReplicaID (%d) for new Replica",
-				repDesc.ReplicaID,
-			)
+			return err
 		}
-		r.raftSender.SendAsync(&RaftMessageRequest{
-			RangeID:     r.RangeID,
-			FromReplica: fromRepDesc,
-			ToReplica:   repDesc,
-			Message: raftpb.Message{
-				Type:     raftpb.MsgSnap,
-				To:       0, // special cased ReplicaID for preemptive snapshots
-				From:     uint64(fromRepDesc.ReplicaID),
-				Term:     snap.Metadata.Term,
-				Snapshot: snap,
-			},
-		})
-
-		repDesc.ReplicaID = updatedDesc.NextReplicaID
-		updatedDesc.NextReplicaID++
-		updatedDesc.Replicas = append(updatedDesc.Replicas, repDesc)
+		_, err = r.promoteLearner(ctx, learner, learnerDesc)
+		return err
 	case roachpb.REMOVE_REPLICA:
 		// If that exact node-store combination does not have the replica,
 		// abort the removal.
@@ -3041,7 +3365,307 @@ func (r *Replica) ChangeReplicas(
 		updatedDesc.Replicas[repDescIdx] = updatedDesc.Replicas[len(updatedDesc.Replicas)-1]
 		updatedDesc.Replicas = updatedDesc.Replicas[:len(updatedDesc.Replicas)-1]
 	}
+	return r.changeReplicasTxn(ctx, changeType, repDesc, desc, &updatedDesc)
+}
 
+// SwapReplicas replaces the replica remove of the range by a new replica on
+// the store of add. The new replica is added as a learner and promoted to a
+// voter like in ChangeReplicas, and remove is only removed once the new
+// replica has been promoted, so that the range never has fewer voters than
+// before. The two replica changes aren't atomic: if the removal fails, the
+// range is left with an additional voter, which the replicate queue removes.
+func (r *Replica) SwapReplicas(
+	ctx context.Context,
+	add, remove roachpb.ReplicaDescriptor,
+	desc *roachpb.RangeDescriptor,
+) error {
+	rangeID := desc.RangeID
+	removeDesc, ok := desc.GetReplicaDescriptor(remove.StoreID)
+	if !ok || removeDesc.IsLearner() {
+		return errors.Errorf("removing replica %v which is not a voter of range %d", remove, rangeID)
+	}
+	// The replica proposing the change holds the lease, which it would keep
+	// after its own removal.
+	if removeDesc.StoreID == r.store.StoreID() {
+		return errors.Errorf("removing replica %v which holds the lease of range %d", remove, rangeID)
+	}
+	for _, existingRep := range desc.Replicas {
+		if existingRep.NodeID == add.NodeID {
+			return errors.Errorf("adding replica %v which is already present in range %d", add, rangeID)
+		}
+	}
+
+	learner, learnerDesc, err := r.addLearner(ctx, add, desc)
+	if err != nil {
+		return err
+	}
+	promotedDesc, err := r.promoteLearner(ctx, learner, learnerDesc)
+	if err != nil {
+		return err
+	}
+	return r.ChangeReplicas(ctx, roachpb.REMOVE_REPLICA, removeDesc, promotedDesc)
+}
+
+// adminScatter moves each replica of the range but the lease holder's to a
+// store chosen by the allocator among the ones satisfying the zone config of
+// the range, and then transfers the lease to a randomly chosen replica. It is
+// used to spread the ranges of a table over the cluster, e.g. before a load
+// test, without waiting for the replicate queue to rebalance them. Replicas
+// for which no other store is suitable are left in place.
+func (r *Replica) adminScatter(ctx context.Context) error {
+	sysCfg, ok := r.store.Gossip().GetSystemConfig()
+	if !ok {
+		return errors.Errorf("%s: no system config available, cannot scatter range", r)
+	}
+	zone, err := sysCfg.GetZoneConfigForKey(r.Desc().StartKey)
+	if err != nil {
+		return err
+	}
+
+	for _, repl := range r.Desc().Voters() {
+		if repl.StoreID == r.store.StoreID() {
+			continue
+		}
+		desc := r.Desc()
+		var others []roachpb.ReplicaDescriptor
+		for _, other := range desc.Replicas {
+			if other.StoreID != repl.StoreID {
+				others = append(others, other)
+			}
+		}
+		// Nodes holding a replica of the range, including the one being
+		// moved, are ruled out as targets.
+		newStore, err := r.store.allocator.AllocateTarget(
+			r.store.allocator.TargetConstraints(zone, others), desc.Replicas, true)
+		if err != nil {
+			log.VTracef(1, ctx, "%s: not scattering replica %+v: %s", r, repl, err)
+			continue
+		}
+		newReplica := roachpb.ReplicaDescriptor{
+			NodeID:  newStore.Node.NodeID,
+			StoreID: newStore.StoreID,
+		}
+		log.VTracef(1, ctx, "%s: scattering replica %+v to %+v", r, repl, newReplica)
+		if err := r.SwapReplicas(ctx, newReplica, repl, desc); err != nil {
+			return err
+		}
+	}
+
+	voters := r.Desc().Voters()
+	randGen := r.store.allocator.randGen
+	randGen.Lock()
+	target := voters[randGen.Intn(len(voters))]
+	randGen.Unlock()
+	log.VTracef(1, ctx, "%s: scattering lease to %+v", r, target)
+	return r.AdminTransferLease(target.StoreID)
+}
+
+// RelocateRange moves the replicas of the range to the target stores, and its
+// lease to the first target. The replicas on the targets are added first, and
+// the ones which aren't on a target are removed once the lease has been
+// transferred, so that the range never has fewer replicas than either before
+// or after the relocation. The replica changes and the lease transfer are sent
+// through db, which routes them to the lease holder of the range.
+func RelocateRange(
+	ctx context.Context,
+	db *client.DB,
+	rangeDesc roachpb.RangeDescriptor,
+	targets []roachpb.ReplicaDescriptor,
+) error {
+	if len(targets) == 0 {
+		return errors.Errorf("no target stores to relocate range %d to", rangeDesc.RangeID)
+	}
+	var addTargets, removeTargets []roachpb.ReplicaDescriptor
+	for _, target := range targets {
+		if _, ok := rangeDesc.GetReplicaDescriptor(target.StoreID); !ok {
+			addTargets = append(addTargets, target)
+		}
+	}
+	for _, repl := range rangeDesc.Replicas {
+		found := false
+		for _, target := range targets {
+			found = found || target.StoreID == repl.StoreID
+		}
+		if !found {
+			removeTargets = append(removeTargets, repl)
+		}
+	}
+
+	key := rangeDesc.StartKey.AsRawKey()
+	if len(addTargets) > 0 {
+		log.Tracef(ctx, "range %d: adding replicas %+v", rangeDesc.RangeID, addTargets)
+		if err := db.AdminChangeReplicas(key, roachpb.ADD_REPLICA, addTargets); err != nil {
+			return err
+		}
+	}
+	log.Tracef(ctx, "range %d: transferring lease to %+v", rangeDesc.RangeID, targets[0])
+	if err := db.AdminTransferLease(key, targets[0].StoreID); err != nil {
+		return err
+	}
+	if len(removeTargets) > 0 {
+		log.Tracef(ctx, "range %d: removing replicas %+v", rangeDesc.RangeID, removeTargets)
+		if err := db.AdminChangeReplicas(key, roachpb.REMOVE_REPLICA, removeTargets); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addLearner adds a replica on the store of repDesc to the range as a
+// learner, after sending it a preemptive snapshot. It returns the learner and
+// the updated range descriptor.
+func (r *Replica) addLearner(
+	ctx context.Context,
+	repDesc roachpb.ReplicaDescriptor,
+	desc *roachpb.RangeDescriptor,
+) (roachpb.ReplicaDescriptor, *roachpb.RangeDescriptor, error) {
+	rangeID := desc.RangeID
+	updatedDesc := *desc
+	updatedDesc.Replicas = append([]roachpb.ReplicaDescriptor(nil), desc.Replicas...)
+
+	log.Trace(ctx, "requesting reservation")
+	// Before we try to add a new replica, we first need to secure a
+	// reservation for the replica on the receiving store.
+	if err := r.store.allocator.storePool.reserve(
+		r.store.Ident,
+		repDesc.StoreID,
+		rangeID,
+		r.GetMVCCStats().Total(),
+	); err != nil {
+		return roachpb.ReplicaDescriptor{}, nil, errors.Wrapf(err, "change replicas of range %d failed", rangeID)
+	}
+	log.Trace(ctx, "reservation granted")
+
+	// Send a pre-emptive snapshot. Note that the replica to which this
+	// snapshot is addressed has not yet had its replica ID initialized; this
+	// is intentional, and serves to avoid the following race with the replica
+	// GC queue:
+	//
+	// - snapshot received, a replica is lazily created with the "real" replica ID
+	// - the replica is eligible for GC because it is not yet a member of the range
+	// - GC queue runs, creating a raft tombstone with the replica's ID
+	// - the replica is added to the range
+	// - lazy creation of the replica fails due to the raft tombstone
+	//
+	// Instead, the replica GC queue will create a tombstone with replica ID
+	// zero, which is never legitimately used, and thus never interferes with
+	// raft operations. Racing with the replica GC queue can still partially
+	// negate the benefits of pre-emptive snapshots, but that is a recoverable
+	// degradation, not a catastrophic failure.
+	snap, err := r.GetSnapshot(ctx)
+	log.Trace(ctx, "generated snapshot")
+	if err != nil {
+		return roachpb.ReplicaDescriptor{}, nil, errors.Wrapf(err, "change replicas of range %d failed", rangeID)
+	}
+
+	fromRepDesc, err := r.GetReplicaDescriptor()
+	if err != nil {
+		return roachpb.ReplicaDescriptor{}, nil, errors.Wrapf(err, "change replicas of range %d failed", rangeID)
+	}
+
+	if repDesc.ReplicaID != 0 {
+		return roachpb.ReplicaDescriptor{}, nil, errors.Errorf(
+			"must not specify a ReplicaID (%d) for new Replica",
+			repDesc.ReplicaID,
+		)
+	}
+	r.raftSender.SendAsync(&RaftMessageRequest{
+		RangeID:     r.RangeID,
+		FromReplica: fromRepDesc,
+		ToReplica:   repDesc,
+		Message: raftpb.Message{
+			Type:     raftpb.MsgSnap,
+			To:       0, // special cased ReplicaID for preemptive snapshots
+			From:     uint64(fromRepDesc.ReplicaID),
+			Term:     snap.Metadata.Term,
+			Snapshot: snap,
+		},
+	})
+
+	repDesc.ReplicaID = updatedDesc.NextReplicaID
+	repDesc.Type = roachpb.LEARNER.Enum()
+	updatedDesc.NextReplicaID++
+	updatedDesc.Replicas = append(updatedDesc.Replicas, repDesc)
+	if err := r.changeReplicasTxn(ctx, roachpb.ADD_REPLICA, repDesc, desc, &updatedDesc); err != nil {
+		return roachpb.ReplicaDescriptor{}, nil, err
+	}
+	return repDesc, &updatedDesc, nil
+}
+
+// promoteLearner waits for the learner to catch up with the Raft log and
+// promotes it to a voter, returning the updated range descriptor. The learner
+// is removed from the range if it doesn't catch up in time.
+func (r *Replica) promoteLearner(
+	ctx context.Context,
+	learner roachpb.ReplicaDescriptor,
+	desc *roachpb.RangeDescriptor,
+) (*roachpb.RangeDescriptor, error) {
+	updatedDesc := *desc
+	updatedDesc.Replicas = nil
+	for _, repDesc := range desc.Replicas {
+		if repDesc.ReplicaID != learner.ReplicaID {
+			updatedDesc.Replicas = append(updatedDesc.Replicas, repDesc)
+		}
+	}
+
+	if err := r.waitForLearner(ctx, learner.ReplicaID); err != nil {
+		log.Warningf(ctx, "%s: removing learner %+v: %s", r, learner, err)
+		if rmErr := r.changeReplicasTxn(ctx, roachpb.REMOVE_REPLICA, learner, desc, &updatedDesc); rmErr != nil {
+			log.Warningf(ctx, "%s: unable to remove learner %+v: %s", r, learner, rmErr)
+		}
+		return nil, errors.Wrapf(err, "change replicas of range %d failed", desc.RangeID)
+	}
+
+	voter := learner
+	voter.Type = nil
+	updatedDesc.Replicas = append(updatedDesc.Replicas, voter)
+	log.Trace(ctx, "promoting learner")
+	if err := r.changeReplicasTxn(ctx, roachpb.ADD_REPLICA, voter, desc, &updatedDesc); err != nil {
+		return nil, err
+	}
+	return &updatedDesc, nil
+}
+
+// waitForLearner blocks until the learner with the given replica ID has
+// received the Raft log up to the commit index of the leader at the time of
+// the first call, or returns an error after learnerCatchUpTimeout.
+func (r *Replica) waitForLearner(ctx context.Context, replicaID roachpb.ReplicaID) error {
+	retryOptions := retry.Options{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Closer:         r.store.Stopper().ShouldQuiesce(),
+	}
+	deadline := timeutil.Now().Add(learnerCatchUpTimeout)
+	var commit uint64
+	for retry := retry.Start(retryOptions); retry.Next(); {
+		// The progress of the followers is only known to the leader, which is
+		// usually the lease holder changing the replicas.
+		if status := r.RaftStatus(); status != nil && status.RaftState == raft.StateLeader {
+			if commit == 0 {
+				commit = status.Commit
+			}
+			if progress, ok := status.Progress[uint64(replicaID)]; ok && progress.Match >= commit {
+				return nil
+			}
+		}
+		if timeutil.Now().After(deadline) {
+			return errors.Errorf("learner %d did not catch up within %s", replicaID, learnerCatchUpTimeout)
+		}
+	}
+	return errors.Errorf("learner %d did not catch up before shutdown", replicaID)
+}
+
+// changeReplicasTxn runs the transaction updating the range descriptor from
+// desc to updatedDesc, whose commit trigger carries out the replica change.
+func (r *Replica) changeReplicasTxn(
+	ctx context.Context,
+	changeType roachpb.ReplicaChangeType,
+	repDesc roachpb.ReplicaDescriptor,
+	desc *roachpb.RangeDescriptor,
+	updatedDesc *roachpb.RangeDescriptor,
+) error {
+	rangeID := desc.RangeID
 	descKey := keys.RangeDescriptorKey(desc.StartKey)
 
 	if err := r.store.DB().Txn(func(txn *client.Txn) error {
@@ -3063,12 +3687,12 @@ func (r *Replica) ChangeReplicas(
 
 			// Important: the range descriptor must be the first thing touched in the transaction
 			// so the transaction record is co-located with the range being modified.
-			if err := updateRangeDescriptor(b, descKey, desc, &updatedDesc); err != nil {
+			if err := updateRangeDescriptor(b, descKey, desc, updatedDesc); err != nil {
 				return err
 			}
 
 			// Update range descriptor addressing record(s).
-			if err := updateRangeAddressing(b, &updatedDesc); err != nil {
+			if err := updateRangeAddressing(b, updatedDesc); err != nil {
 				return err
 			}
 
@@ -3079,7 +3703,7 @@ func (r *Replica) ChangeReplicas(
 		}
 
 		// Log replica change into range event log.
-		if err := r.store.logChange(txn, changeType, repDesc, updatedDesc); err != nil {
+		if err := r.store.logChange(txn, changeType, repDesc, *updatedDesc); err != nil {
 			return err
 		}
 
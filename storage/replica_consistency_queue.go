@@ -47,18 +47,47 @@ func newReplicaConsistencyQueue(store *Store, gossip *gossip.Gossip) *replicaCon
 	return rcq
 }
 
+// shouldQueue returns whether the range is due for a consistency check. The
+// replica scanner visits each replica about once per ConsistencyCheckInterval,
+// so ranges which this replica helped check during the last half of it, e.g.
+// before the lease moved here, are skipped. The priority grows with the time
+// since the last check; ranges not checked since the replica was created or
+// the node restarted are due right away.
 func (*replicaConsistencyQueue) shouldQueue(now hlc.Timestamp, rng *Replica,
 	_ config.SystemConfig) (bool, float64) {
-	return true, 1.0
+	rng.mu.Lock()
+	lastCheck := rng.mu.lastConsistencyCheck
+	rng.mu.Unlock()
+	return consistencyShouldQueueImpl(now, lastCheck, rng.store.ctx.ConsistencyCheckInterval)
+}
+
+func consistencyShouldQueueImpl(
+	now, lastCheck hlc.Timestamp, interval time.Duration,
+) (bool, float64) {
+	if lastCheck == hlc.ZeroTimestamp {
+		return true, 1.0
+	}
+	elapsed := now.WallTime - lastCheck.WallTime
+	if interval <= 0 || 2*elapsed < interval.Nanoseconds() {
+		return false, 0
+	}
+	return true, float64(elapsed) / float64(interval.Nanoseconds())
 }
 
 // process() is called on every range for which this node is a lease holder.
 func (q *replicaConsistencyQueue) process(
 	ctx context.Context,
-	_ hlc.Timestamp,
+	now hlc.Timestamp,
 	rng *Replica,
 	_ config.SystemConfig,
 ) error {
+	// Record the attempt right away so that a failing check isn't retried
+	// before the next interval; the replicas record the time of the check
+	// again when verifying the checksum.
+	rng.mu.Lock()
+	rng.mu.lastConsistencyCheck = now
+	rng.mu.Unlock()
+	rng.store.metrics.rangeConsistencyChecks.Inc(1)
 	req := roachpb.CheckConsistencyRequest{}
 	_, pErr := rng.CheckConsistency(req, rng.Desc())
 	if pErr != nil {
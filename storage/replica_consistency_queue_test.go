@@ -0,0 +1,57 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestConsistencyQueueShouldQueue(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ts := func(t time.Duration) hlc.Timestamp {
+		return hlc.ZeroTimestamp.Add(t.Nanoseconds(), 0)
+	}
+
+	const interval = 10 * time.Hour
+	for i, test := range []struct {
+		now, lastCheck hlc.Timestamp
+		interval       time.Duration
+
+		shouldQ  bool
+		priority float64
+	}{
+		// Never checked: due right away.
+		{now: ts(time.Hour), lastCheck: hlc.ZeroTimestamp, interval: interval, shouldQ: true, priority: 1},
+		// Checked recently.
+		{now: ts(5*time.Hour - 1), lastCheck: ts(time.Nanosecond), interval: interval, shouldQ: false, priority: 0},
+		// Half the interval passed since the last check.
+		{now: ts(6 * time.Hour), lastCheck: ts(time.Hour), interval: interval, shouldQ: true, priority: 0.5},
+		// The priority grows with the time since the last check.
+		{now: ts(21 * time.Hour), lastCheck: ts(time.Hour), interval: interval, shouldQ: true, priority: 2},
+		// Checks are disabled.
+		{now: ts(21 * time.Hour), lastCheck: ts(time.Hour), interval: 0, shouldQ: false, priority: 0},
+	} {
+		if sq, pr := consistencyShouldQueueImpl(
+			test.now, test.lastCheck, test.interval,
+		); sq != test.shouldQ || pr != test.priority {
+			t.Errorf("%d: %+v: got (%t,%f)", i, test, sq, pr)
+		}
+	}
+}
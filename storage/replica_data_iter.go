@@ -69,6 +69,10 @@ func makeReplicaKeyRanges(d *roachpb.RangeDescriptor, metaFunc func(roachpb.Rang
 			start: engine.MakeMVCCMetadataKey(keys.MakeRangeKeyPrefix(d.StartKey)),
 			end:   engine.MakeMVCCMetadataKey(keys.MakeRangeKeyPrefix(d.EndKey)),
 		},
+		{
+			start: engine.MakeMVCCMetadataKey(keys.RangeTombstoneKey(d.StartKey)),
+			end:   engine.MakeMVCCMetadataKey(keys.RangeTombstoneKey(d.EndKey)),
+		},
 		{
 			start: engine.MakeMVCCMetadataKey(dataStartKey),
 			end:   engine.MakeMVCCMetadataKey(d.EndKey.AsRawKey()),
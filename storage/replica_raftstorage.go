@@ -63,14 +63,25 @@ func (r *Replica) InitialState() (raftpb.HardState, raftpb.ConfState, error) {
 	if raft.IsEmptyHardState(hs) || err != nil {
 		return raftpb.HardState{}, raftpb.ConfState{}, err
 	}
-	var cs raftpb.ConfState
-	for _, rep := range r.mu.state.Desc.Replicas {
-		cs.Nodes = append(cs.Nodes, uint64(rep.ReplicaID))
-	}
+	cs := confState(r.mu.state.Desc)
 
 	return hs, cs, nil
 }
 
+// confState synthesizes the Raft configuration of the range from its
+// descriptor.
+func confState(desc *roachpb.RangeDescriptor) raftpb.ConfState {
+	var cs raftpb.ConfState
+	for _, rep := range desc.Replicas {
+		if rep.IsLearner() {
+			cs.Learners = append(cs.Learners, uint64(rep.ReplicaID))
+		} else {
+			cs.Nodes = append(cs.Nodes, uint64(rep.ReplicaID))
+		}
+	}
+	return cs
+}
+
 // Entries implements the raft.Storage interface. Note that maxBytes is advisory
 // and this method will always return at least one entry even if it exceeds
 // maxBytes. Passing maxBytes equal to zero disables size checking.
@@ -78,14 +89,17 @@ func (r *Replica) InitialState() (raftpb.HardState, raftpb.ConfState, error) {
 // caching is insufficient.
 // Entries requires that the replica lock is held.
 func (r *Replica) Entries(lo, hi, maxBytes uint64) ([]raftpb.Entry, error) {
-	snap := r.store.NewSnapshot()
-	defer snap.Close()
-	return entries(context.Background(), snap, r.RangeID, lo, hi, maxBytes)
+	snap, raftSnap := r.store.newSnapshots()
+	defer closeSnapshots(snap, raftSnap)
+	return entries(context.Background(), snap, raftSnap, r.RangeID, lo, hi, maxBytes)
 }
 
+// entries reads the raft log entries in [lo, hi) from raftEng, which holds the
+// raft log of the range, and the rest of the raft state from e.
 func entries(
 	ctx context.Context,
 	e engine.Reader,
+	raftEng engine.Reader,
 	rangeID roachpb.RangeID,
 	lo, hi, maxBytes uint64,
 ) ([]raftpb.Entry, error) {
@@ -114,7 +128,7 @@ func entries(
 		return exceededMaxBytes, nil
 	}
 
-	if err := iterateEntries(ctx, e, rangeID, lo, hi, scanFunc); err != nil {
+	if err := iterateEntries(ctx, raftEng, rangeID, lo, hi, scanFunc); err != nil {
 		return nil, err
 	}
 
@@ -185,13 +199,15 @@ func iterateEntries(
 // Term implements the raft.Storage interface.
 // Term requires that the replica lock is held.
 func (r *Replica) Term(i uint64) (uint64, error) {
-	snap := r.store.NewSnapshot()
-	defer snap.Close()
-	return term(context.Background(), snap, r.RangeID, i)
+	snap, raftSnap := r.store.newSnapshots()
+	defer closeSnapshots(snap, raftSnap)
+	return term(context.Background(), snap, raftSnap, r.RangeID, i)
 }
 
-func term(ctx context.Context, eng engine.Reader, rangeID roachpb.RangeID, i uint64) (uint64, error) {
-	ents, err := entries(ctx, eng, rangeID, i, i+1, 0)
+func term(
+	ctx context.Context, eng, raftEng engine.Reader, rangeID roachpb.RangeID, i uint64,
+) (uint64, error) {
+	ents, err := entries(ctx, eng, raftEng, rangeID, i, i+1, 0)
 	if err == raft.ErrCompacted {
 		ts, err := loadTruncatedState(ctx, eng, rangeID)
 		if err != nil {
@@ -300,19 +316,22 @@ func (r *Replica) SnapshotWithContext(ctx context.Context) (raftpb.Snapshot, err
 	// reads from the channel, and can abandon the snapshot if it gets stale.
 	ch := make(chan (raftpb.Snapshot))
 
+	// The engine snapshots are taken while the replica lock is held, which
+	// keeps a raft log kept separately from being truncated in between them.
+	snap, raftSnap := r.store.newSnapshots()
+	log.Trace(ctx, "new engine snapshot")
+
 	if r.store.Stopper().RunAsyncTask(func() {
 		defer close(ch)
 		sp := r.store.Tracer().StartSpan(fmt.Sprintf("snapshot async %s", r))
 		ctxInner := opentracing.ContextWithSpan(context.Background(), sp)
 		defer sp.Finish()
-		snap := r.store.NewSnapshot()
-		log.Trace(ctxInner, "new engine snapshot")
-		defer snap.Close()
+		defer closeSnapshots(snap, raftSnap)
 		defer r.store.ReleaseRaftSnapshot()
 		// Delegate to a static function to make sure that we do not depend
 		// on any indirect calls to r.store.Engine() (or other in-memory
 		// state of the Replica). Everything must come from the snapshot.
-		snapData, err := snapshot(context.Background(), snap, rangeID, startKey)
+		snapData, err := snapshot(context.Background(), snap, raftSnap, rangeID, startKey)
 		if err != nil {
 			log.Errorf(ctxInner, "%s: error generating snapshot: %s", r, err)
 		} else {
@@ -331,6 +350,7 @@ func (r *Replica) SnapshotWithContext(ctx context.Context) (raftpb.Snapshot, err
 	}) == nil {
 		r.mu.snapshotChan = ch
 	} else {
+		closeSnapshots(snap, raftSnap)
 		r.store.ReleaseRaftSnapshot()
 	}
 
@@ -387,6 +407,7 @@ func (r *Replica) GetSnapshot(ctx context.Context) (raftpb.Snapshot, error) {
 func snapshot(
 	ctx context.Context,
 	snap engine.Reader,
+	raftSnap engine.Reader,
 	rangeID roachpb.RangeID,
 	startKey roachpb.RKey,
 ) (raftpb.Snapshot, error) {
@@ -450,7 +471,7 @@ func snapshot(
 		return false, err
 	}
 
-	if err := iterateEntries(ctx, snap, rangeID, firstIndex, endIndex, scanFunc); err != nil {
+	if err := iterateEntries(ctx, raftSnap, rangeID, firstIndex, endIndex, scanFunc); err != nil {
 		return raftpb.Snapshot{}, err
 	}
 
@@ -460,12 +481,9 @@ func snapshot(
 	}
 
 	// Synthesize our raftpb.ConfState from desc.
-	var cs raftpb.ConfState
-	for _, rep := range desc.Replicas {
-		cs.Nodes = append(cs.Nodes, uint64(rep.ReplicaID))
-	}
+	cs := confState(&desc)
 
-	term, err := term(ctx, snap, rangeID, appliedIndex)
+	term, err := term(ctx, snap, raftSnap, rangeID, appliedIndex)
 	if err != nil {
 		return raftpb.Snapshot{}, errors.Errorf("failed to fetch term of %d: %s", appliedIndex, err)
 	}
@@ -486,10 +504,13 @@ func snapshot(
 // append the given entries to the raft log. Takes the previous values of
 // r.mu.lastIndex and r.mu.raftLogSize, and returns new values. We do this
 // rather than modifying them directly because these modifications need to be
-// atomic with the commit of the batch.
+// atomic with the commit of the batch. The entries are written to raftBatch,
+// which is batch unless the raft log is kept separately, in which case it
+// must be committed before batch.
 func (r *Replica) append(
 	ctx context.Context,
 	batch engine.ReadWriter,
+	raftBatch engine.ReadWriter,
 	prevLastIndex uint64,
 	prevRaftLogSize int64,
 	entries []raftpb.Entry,
@@ -501,14 +522,14 @@ func (r *Replica) append(
 	for i := range entries {
 		ent := &entries[i]
 		key := keys.RaftLogKey(r.RangeID, ent.Index)
-		if err := engine.MVCCPutProto(ctx, batch, &diff, key, hlc.ZeroTimestamp, nil /* txn */, ent); err != nil {
+		if err := engine.MVCCPutProto(ctx, raftBatch, &diff, key, hlc.ZeroTimestamp, nil /* txn */, ent); err != nil {
 			return 0, 0, err
 		}
 	}
 	lastIndex := entries[len(entries)-1].Index
 	// Delete any previously appended log entries which never committed.
 	for i := lastIndex + 1; i <= prevLastIndex; i++ {
-		err := engine.MVCCDelete(ctx, batch, &diff, keys.RaftLogKey(r.RangeID, i),
+		err := engine.MVCCDelete(ctx, raftBatch, &diff, keys.RaftLogKey(r.RangeID, i),
 			hlc.ZeroTimestamp, nil /* txn */)
 		if err != nil {
 			return 0, 0, err
@@ -567,6 +588,11 @@ func (r *Replica) applySnapshot(
 	// Distinct() batch).
 	batch := r.store.Engine().NewBatch()
 	defer batch.Close()
+	raftBatch := batch
+	if r.store.separateRaftLog() {
+		raftBatch = r.store.RaftEngine().NewBatch()
+		defer raftBatch.Close()
+	}
 
 	snapData := roachpb.RaftSnapshotData{}
 	err := proto.Unmarshal(snap.Data, &snapData)
@@ -613,6 +639,13 @@ func (r *Replica) applySnapshot(
 			return err
 		}
 	}
+	if raftBatch != batch {
+		prefix := keys.RaftLogPrefix(desc.RangeID)
+		if _, err := engine.ClearRange(raftBatch, engine.MakeMVCCMetadataKey(prefix),
+			engine.MakeMVCCMetadataKey(prefix.PrefixEnd())); err != nil {
+			return err
+		}
+	}
 
 	// Determine the unreplicated key prefix so we can drop any
 	// unreplicated keys from the snapshot.
@@ -640,7 +673,7 @@ func (r *Replica) applySnapshot(
 	}
 
 	// Write the snapshot's Raft log into the range.
-	_, raftLogSize, err = r.append(ctx, batch, 0, raftLogSize, logEntries)
+	_, raftLogSize, err = r.append(ctx, batch, raftBatch, 0, raftLogSize, logEntries)
 	if err != nil {
 		return err
 	}
@@ -670,10 +703,21 @@ func (r *Replica) applySnapshot(
 		// which is identical to the current state?
 	}
 
+	// The raft log is committed first, so that the raft state committed
+	// along with the rest of the snapshot never refers to missing entries.
+	if raftBatch != batch {
+		if err := raftBatch.Commit(); err != nil {
+			return err
+		}
+	}
 	if err := batch.Commit(); err != nil {
 		return err
 	}
 
+	// The snapshot replaced the replica's data wholesale without producing
+	// the individual writes, so connected RangeFeeds have to start over.
+	r.disconnectRangeFeeds(roachpb.NewError(roachpb.NewRangeNotFoundError(r.RangeID)))
+
 	r.mu.Lock()
 	// We set the persisted last index to the last applied index. This is
 	// not a correctness issue, but means that we may have just transferred
@@ -746,3 +790,37 @@ func DecodeRaftCommand(data []byte) (commandID string, command []byte) {
 	}
 	return string(data[1 : 1+raftCommandIDLen]), data[1+raftCommandIDLen:]
 }
+
+// newSnapshots returns a snapshot of the store's engine and one of its raft
+// engine, which are the same snapshot unless the raft logs are kept
+// separately. They must be released with closeSnapshots.
+func (s *Store) newSnapshots() (snap, raftSnap engine.Reader) {
+	snap = s.engine.NewSnapshot()
+	raftSnap = snap
+	if s.separateRaftLog() {
+		raftSnap = s.raftEngine.NewSnapshot()
+	}
+	return snap, raftSnap
+}
+
+// closeSnapshots releases the snapshots returned by newSnapshots.
+func closeSnapshots(snap, raftSnap engine.Reader) {
+	snap.Close()
+	if raftSnap != snap {
+		raftSnap.Close()
+	}
+}
+
+// clearRaftLogSpan removes the raft log entries in the key span [start, end)
+// from raftEng, the separate raft engine of a store. Entries which linger
+// after a crash are harmless: they're below the truncated index of the log,
+// or beyond its last index, and are never read.
+func clearRaftLogSpan(raftEng engine.Engine, start, end roachpb.Key) error {
+	batch := raftEng.NewBatch()
+	defer batch.Close()
+	if _, err := engine.ClearRange(batch, engine.MakeMVCCMetadataKey(start),
+		engine.MakeMVCCMetadataKey(end)); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
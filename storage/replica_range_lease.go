@@ -22,6 +22,7 @@ package storage
 import (
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/keys"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/util/hlc"
 	"github.com/cockroachdb/cockroach/util/log"
@@ -94,20 +95,23 @@ func (p *pendingLeaseRequest) InitOrJoinRequest(
 	}
 	llChan := make(chan *roachpb.Error, 1)
 	// No request in progress. Let's propose a Lease command asynchronously.
-	// TODO(tschottdorf): get duration from configuration, either as a
-	// config flag or, later, dynamically adjusted.
-	startStasis := timestamp.Add(int64(replica.store.ctx.rangeLeaseActiveDuration), 0)
-	expiration := startStasis.Add(int64(replica.store.Clock().MaxOffset()), 0)
 	reqSpan := roachpb.Span{
 		Key: startKey,
 	}
 	var leaseReq roachpb.Request
 	reqLease := roachpb.Lease{
-		Start:       timestamp,
-		StartStasis: startStasis,
-		Expiration:  expiration,
-		Replica:     nextLeaseHolder,
+		Start:   timestamp,
+		Replica: nextLeaseHolder,
 	}
+	if epoch, ok := replica.leaseEpochLocked(nextLeaseHolder, timestamp); ok {
+		reqLease.Epoch = &epoch
+	} else {
+		// TODO(tschottdorf): get duration from configuration, either as a
+		// config flag or, later, dynamically adjusted.
+		reqLease.StartStasis = timestamp.Add(int64(replica.store.ctx.rangeLeaseActiveDuration), 0)
+		reqLease.Expiration = reqLease.StartStasis.Add(int64(replica.store.Clock().MaxOffset()), 0)
+	}
+	prevLease := *replica.mu.state.Lease
 	if transfer {
 		leaseReq = &roachpb.TransferLeaseRequest{
 			Span:  reqSpan,
@@ -126,11 +130,26 @@ func (p *pendingLeaseRequest) InitOrJoinRequest(
 		ba.Timestamp = replica.store.Clock().Now()
 		ba.RangeID = replica.RangeID
 		ba.Add(leaseReq)
+		// An epoch-based lease of another node remains valid until the epoch
+		// of the node's liveness record is incremented, so that has to
+		// happen before a new lease can be requested.
+		var err error
+		if !transfer && prevLease.Epoch != nil && !prevLease.OwnedBy(replica.store.StoreID()) {
+			if err = replica.invalidateEpochLease(context.Background(), prevLease); err != nil {
+				if log.V(1) {
+					log.Infof(context.TODO(), "%s: unable to invalidate lease %s: %s", replica, prevLease, err)
+				}
+				err = newNotLeaseHolderError(&prevLease, replica.store.StoreID(), replica.Desc())
+			}
+		}
 		// Send lease request directly to raft in order to skip unnecessary
 		// checks from normal request machinery, (e.g. the command queue).
 		// Note that the command itself isn't traced, but usually the caller
 		// waiting for the result has an active Trace.
-		ch, _, err := replica.proposeRaftCommand(context.Background(), ba)
+		var ch chan roachpb.ResponseWithError
+		if err == nil {
+			ch, _, err = replica.proposeRaftCommand(context.Background(), ba)
+		}
 		if err != nil {
 			execPErr = roachpb.NewError(err)
 		} else {
@@ -213,6 +232,78 @@ func (p *pendingLeaseRequest) TransferInProgress(
 	return roachpb.Lease{}, false
 }
 
+// isLeaseValid returns whether the given lease can be used to serve
+// requests at the given timestamp. An expiration-based lease is valid until
+// the beginning of its stasis period, while an epoch-based lease is valid
+// for as long as the liveness record of the lease holder's node is live and
+// carries the lease's epoch.
+func (r *Replica) isLeaseValid(lease *roachpb.Lease, timestamp hlc.Timestamp) bool {
+	if lease.Epoch == nil {
+		return lease.Covers(timestamp)
+	}
+	if r.store.ctx.NodeLiveness == nil {
+		return false
+	}
+	liveness, err := r.store.ctx.NodeLiveness.GetLiveness(lease.Replica.NodeID)
+	if err != nil || liveness.Epoch != *lease.Epoch {
+		return false
+	}
+	return liveness.IsLive(timestamp, r.store.Clock().MaxOffset())
+}
+
+// requiresExpiringLeaseLocked returns whether the range must use
+// expiration-based leases. This is the case of the ranges which the node
+// liveness heartbeats depend on: the range holding the liveness records,
+// and the meta ranges addressing it.
+func (r *Replica) requiresExpiringLeaseLocked() bool {
+	return r.mu.state.Desc.StartKey.Less(roachpb.RKey(keys.NodeLivenessKeyMax))
+}
+
+// leaseEpochLocked returns the epoch under which the given replica can hold
+// an epoch-based lease of the range requested at the given timestamp. The
+// second return value is false if an expiration-based lease must be used
+// instead, which is also the case while the liveness of the replica's node
+// is unknown or expired.
+func (r *Replica) leaseEpochLocked(
+	repDesc roachpb.ReplicaDescriptor, timestamp hlc.Timestamp,
+) (int64, bool) {
+	nl := r.store.ctx.NodeLiveness
+	if nl == nil || !r.store.ctx.EnableEpochRangeLeases || r.requiresExpiringLeaseLocked() {
+		return 0, false
+	}
+	liveness, err := nl.GetLiveness(repDesc.NodeID)
+	if err != nil || !liveness.IsLive(timestamp, r.store.Clock().MaxOffset()) {
+		return 0, false
+	}
+	return liveness.Epoch, true
+}
+
+// invalidateEpochLease makes sure the given epoch-based lease, held by
+// another node, is no longer valid by incrementing the epoch of the node's
+// liveness record. This fails if the node is still live.
+func (r *Replica) invalidateEpochLease(ctx context.Context, lease roachpb.Lease) error {
+	nl := r.store.ctx.NodeLiveness
+	if nl == nil {
+		return errors.Errorf("node liveness is required to invalidate lease %s", lease)
+	}
+	liveness, err := nl.GetLiveness(lease.Replica.NodeID)
+	if err != nil {
+		return err
+	}
+	if liveness.Epoch > *lease.Epoch {
+		// The lease was already invalidated.
+		return nil
+	}
+	if err := nl.IncrementEpoch(ctx, liveness); err != nil {
+		// The epoch may have been incremented concurrently.
+		if liveness, lErr := nl.GetLiveness(lease.Replica.NodeID); lErr == nil && liveness.Epoch > *lease.Epoch {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 // requestLeaseLocked executes a request to obtain or extend a lease
 // asynchronously and returns a channel on which the result will be posted. If
 // there's already a request in progress, we join in waiting for the results of
@@ -235,6 +326,12 @@ func (r *Replica) requestLeaseLocked(timestamp hlc.Timestamp) <-chan *roachpb.Er
 			newNotLeaseHolderError(&transferLease, r.store.StoreID(), r.mu.state.Desc))
 		return llChan
 	}
+	if repDesc.IsLearner() {
+		// Learners don't vote, and wouldn't be able to keep the lease.
+		llChan := make(chan *roachpb.Error, 1)
+		llChan <- roachpb.NewError(newNotLeaseHolderError(nil, r.store.StoreID(), r.mu.state.Desc))
+		return llChan
+	}
 	if r.store.IsDrainingLeases() {
 		// We've retired from active duty.
 		llChan := make(chan *roachpb.Error, 1)
@@ -287,6 +384,10 @@ func (r *Replica) AdminTransferLease(target roachpb.StoreID) error {
 		if nextLeaseHolder, ok = desc.GetReplicaDescriptor(target); !ok {
 			return nil, nil, errors.Errorf("unable to find store %d in range %+v", target, desc)
 		}
+		if nextLeaseHolder.IsLearner() {
+			return nil, nil, errors.Errorf("unable to transfer lease to learner %+v of range %+v",
+				nextLeaseHolder, desc)
+		}
 
 		if nextLease, ok := r.mu.pendingLeaseRequest.RequestPending(); ok &&
 			nextLease.Replica != nextLeaseHolder {
@@ -0,0 +1,473 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// This file contains replica methods related to RangeFeeds.
+
+package storage
+
+import (
+	"bytes"
+	"sort"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/keys"
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/util/hlc"
+	"github.com/cockroachdb/cockroach/util/protoutil"
+	"github.com/pkg/errors"
+)
+
+// rangeFeedBufferSize is the number of events buffered for a RangeFeed
+// client. A client falling further behind is disconnected, and is expected to
+// reconnect from its last checkpoint.
+const rangeFeedBufferSize = 4096
+
+// rangeFeedStream is the stream to a RangeFeed client.
+type rangeFeedStream interface {
+	Context() context.Context
+	Send(*roachpb.RangeFeedEvent) error
+}
+
+// rangeFeed is a RangeFeed registered on a replica. The writes committed to
+// its span are published to it as the commands writing them apply, and so are
+// checkpoints of its span as the closed timestamp of the range moves past the
+// intents in the span.
+//
+// All the fields but events and done are protected by Replica.rangeFeedMu.
+type rangeFeed struct {
+	span roachpb.RSpan
+	// The writes committed at or before startTS are not published.
+	startTS hlc.Timestamp
+	events  chan *roachpb.RangeFeedEvent
+	// done receives the error ending the feed when the replica disconnects it.
+	done chan *roachpb.Error
+
+	// intents maps the keys of the intents in the span to their timestamps.
+	// It is nil until the intents are read from the snapshot taken when the
+	// feed registered; the changes to the intents made meanwhile are queued
+	// in pendingIntents.
+	intents        map[string]hlc.Timestamp
+	pendingIntents []rangeFeedOp
+	// resolved is the timestamp of the last checkpoint.
+	resolved hlc.Timestamp
+}
+
+// rangeFeedOpType is the type of a rangeFeedOp.
+type rangeFeedOpType int
+
+const (
+	// rangeFeedWrite is a committed write.
+	rangeFeedWrite rangeFeedOpType = iota
+	// rangeFeedIntent is an intent written or moved to another timestamp.
+	rangeFeedIntent
+	// rangeFeedIntentRemoved is an intent resolved or aborted.
+	rangeFeedIntentRemoved
+)
+
+// rangeFeedOp is a change made by a command to the data of a range which is
+// of interest to its RangeFeeds. The value of an intent only carries its
+// timestamp.
+type rangeFeedOp struct {
+	typ   rangeFeedOpType
+	key   roachpb.Key
+	value roachpb.Value
+}
+
+// RangeFeed streams the writes committed to the span of the request, starting
+// with those committed after the timestamp of the request, and checkpoints of
+// the span, until the client goes away or an error occurs. The writes
+// committed at the time the feed registers are streamed first, from a
+// snapshot; the writes to a key are streamed in timestamp order. A zero
+// timestamp streams only the writes committed after the feed registers.
+//
+// Checkpoints are only streamed as the commands applied by the replica advance
+// the closed timestamp of the range: a range without any traffic doesn't
+// checkpoint its span.
+func (r *Replica) RangeFeed(args *roachpb.RangeFeedRequest, stream rangeFeedStream) *roachpb.Error {
+	rSpan := roachpb.RSpan{Key: roachpb.RKey(args.Span.Key), EndKey: roachpb.RKey(args.Span.EndKey)}
+	if bytes.Compare(args.Span.Key, keys.LocalMax) < 0 || !rSpan.Key.Less(rSpan.EndKey) {
+		return roachpb.NewErrorf("invalid RangeFeed span %s", args.Span)
+	}
+	feed := &rangeFeed{
+		span:     rSpan,
+		startTS:  args.Timestamp,
+		events:   make(chan *roachpb.RangeFeedEvent, rangeFeedBufferSize),
+		done:     make(chan *roachpb.Error, 1),
+		resolved: args.Timestamp,
+	}
+
+	// The commands hold rangeFeedMu from their application to the publication
+	// of their writes, so the snapshot either reflects a command or the feed
+	// gets its writes.
+	r.rangeFeedMu.Lock()
+	r.mu.Lock()
+	destroyed, desc := r.mu.destroyed, r.mu.state.Desc
+	r.mu.Unlock()
+	if destroyed != nil {
+		r.rangeFeedMu.Unlock()
+		return roachpb.NewError(roachpb.NewRangeNotFoundError(r.RangeID))
+	}
+	if !desc.ContainsKeyRange(rSpan.Key, rSpan.EndKey) {
+		r.rangeFeedMu.Unlock()
+		return roachpb.NewError(roachpb.NewRangeKeyMismatchError(args.Span.Key, args.Span.EndKey, desc))
+	}
+	if r.rangeFeedMu.feeds == nil {
+		r.rangeFeedMu.feeds = make(map[*rangeFeed]struct{})
+	}
+	r.rangeFeedMu.feeds[feed] = struct{}{}
+	snap := r.store.Engine().NewSnapshot()
+	r.rangeFeedMu.Unlock()
+
+	defer func() {
+		r.rangeFeedMu.Lock()
+		delete(r.rangeFeedMu.feeds, feed)
+		r.rangeFeedMu.Unlock()
+	}()
+
+	intents, err := r.rangeFeedCatchUp(snap, args.Span, args.Timestamp, stream)
+	snap.Close()
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	r.rangeFeedMu.Lock()
+	feed.intents = intents
+	for _, op := range feed.pendingIntents {
+		feed.updateIntent(op)
+	}
+	feed.pendingIntents = nil
+	r.rangeFeedMu.Unlock()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case e := <-feed.events:
+			if err := stream.Send(e); err != nil {
+				return roachpb.NewError(err)
+			}
+		case pErr := <-feed.done:
+			// The events published before the feed was disconnected are still
+			// valid.
+			for {
+				select {
+				case e := <-feed.events:
+					if err := stream.Send(e); err != nil {
+						return roachpb.NewError(err)
+					}
+				default:
+					return pErr
+				}
+			}
+		case <-ctx.Done():
+			return roachpb.NewError(ctx.Err())
+		case <-r.store.Stopper().ShouldQuiesce():
+			return roachpb.NewError(&roachpb.NodeUnavailableError{})
+		}
+	}
+}
+
+// rangeFeedCatchUp streams the writes to span committed after startTS in the
+// snapshot, oldest first for each key, and returns the intents in the span.
+func (r *Replica) rangeFeedCatchUp(
+	snap engine.Reader, span roachpb.Span, startTS hlc.Timestamp, stream rangeFeedStream,
+) (map[string]hlc.Timestamp, error) {
+	// A zero start timestamp only needs the intents: no revision is written
+	// after the maximum timestamp.
+	if startTS == hlc.ZeroTimestamp {
+		startTS = hlc.MaxTimestamp
+	}
+	// The revisions of a key are iterated newest first.
+	var revisions []roachpb.KeyValue
+	flush := func() error {
+		for i := len(revisions) - 1; i >= 0; i-- {
+			kv := revisions[i]
+			if err := stream.Send(&roachpb.RangeFeedEvent{
+				Val: &roachpb.RangeFeedValue{Key: kv.Key, Value: kv.Value},
+			}); err != nil {
+				return err
+			}
+		}
+		revisions = revisions[:0]
+		return nil
+	}
+	err := engine.MVCCIterateRevisions(snap, span.Key, span.EndKey, startTS, hlc.MaxTimestamp,
+		func(kv roachpb.KeyValue) error {
+			if len(revisions) > 0 && !revisions[0].Key.Equal(kv.Key) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			kv.Key = append(roachpb.Key(nil), kv.Key...)
+			kv.Value.RawBytes = append([]byte(nil), kv.Value.RawBytes...)
+			revisions = append(revisions, kv)
+			return nil
+		})
+	intents := make(map[string]hlc.Timestamp)
+	if wiErr, ok := err.(*roachpb.WriteIntentError); ok {
+		for _, intent := range wiErr.Intents {
+			intents[string(intent.Key)] = intent.Txn.Timestamp
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return intents, nil
+}
+
+// rangeFeedOpsLocked returns the changes made by a command, whose writes are
+// in the batch, to the spans of the RangeFeeds of the replica. The batch must
+// not be committed yet. Replica.rangeFeedMu must be held.
+func (r *Replica) rangeFeedOpsLocked(batch engine.Batch) ([]rangeFeedOp, error) {
+	if len(r.rangeFeedMu.feeds) == 0 {
+		return nil, nil
+	}
+	reader, err := engine.NewRocksDBBatchReader(batch.Repr())
+	if err != nil {
+		return nil, err
+	}
+
+	// The state of a key at the end of the batch.
+	type keyState struct {
+		key roachpb.Key
+		// The timestamp of the intent on the key, if any.
+		intent *hlc.Timestamp
+		// Whether the metadata of the key was cleared, which resolves or
+		// aborts its intent.
+		metaCleared bool
+		// The versions put and cleared.
+		puts    []engine.MVCCKeyValue
+		cleared bool
+	}
+	var states []*keyState
+	byKey := make(map[string]*keyState)
+	for reader.Next() {
+		mvccKey, err := reader.MVCCKey()
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Compare(mvccKey.Key, keys.LocalMax) < 0 || !r.inRangeFeedSpansLocked(mvccKey.Key) {
+			continue
+		}
+		st, ok := byKey[string(mvccKey.Key)]
+		if !ok {
+			st = &keyState{key: append(roachpb.Key(nil), mvccKey.Key...)}
+			byKey[string(mvccKey.Key)] = st
+			states = append(states, st)
+		}
+		switch {
+		case !mvccKey.IsValue() && reader.IsPut():
+			var meta enginepb.MVCCMetadata
+			if err := protoutil.Unmarshal(reader.Value(), &meta); err != nil {
+				return nil, err
+			}
+			if meta.Txn != nil {
+				ts := meta.Timestamp
+				st.intent, st.metaCleared = &ts, false
+			}
+		case !mvccKey.IsValue() && reader.IsDelete():
+			st.intent, st.metaCleared = nil, true
+		case reader.IsPut():
+			st.puts = append(st.puts, engine.MVCCKeyValue{
+				Key:   engine.MVCCKey{Key: st.key, Timestamp: mvccKey.Timestamp},
+				Value: append([]byte(nil), reader.Value()...),
+			})
+		case reader.IsDelete():
+			for i := range st.puts {
+				if st.puts[i].Key.Timestamp == mvccKey.Timestamp {
+					st.puts = append(st.puts[:i], st.puts[i+1:]...)
+					break
+				}
+			}
+			st.cleared = true
+		}
+	}
+	if err := reader.Error(); err != nil {
+		return nil, err
+	}
+
+	var ops []rangeFeedOp
+	for _, st := range states {
+		if st.intent != nil {
+			// The versions put under an intent are not committed yet.
+			ops = append(ops, rangeFeedOp{
+				typ: rangeFeedIntent, key: st.key, value: roachpb.Value{Timestamp: *st.intent},
+			})
+			continue
+		}
+		if st.metaCleared {
+			ops = append(ops, rangeFeedOp{typ: rangeFeedIntentRemoved, key: st.key})
+			if len(st.puts) == 0 && !st.cleared {
+				// The intent was committed in place: its value is the newest
+				// version of the key.
+				kv, err := newestVersion(batch, st.key)
+				if err != nil {
+					return nil, err
+				}
+				if kv.Key.Key != nil {
+					st.puts = append(st.puts, kv)
+				}
+			}
+		}
+		sort.Sort(versionsByTimestamp(st.puts))
+		for _, kv := range st.puts {
+			ops = append(ops, rangeFeedOp{
+				typ:   rangeFeedWrite,
+				key:   st.key,
+				value: roachpb.Value{RawBytes: kv.Value, Timestamp: kv.Key.Timestamp},
+			})
+		}
+	}
+	return ops, nil
+}
+
+// versionsByTimestamp sorts the versions of a key, oldest first.
+type versionsByTimestamp []engine.MVCCKeyValue
+
+func (v versionsByTimestamp) Len() int      { return len(v) }
+func (v versionsByTimestamp) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v versionsByTimestamp) Less(i, j int) bool {
+	return v[i].Key.Timestamp.Less(v[j].Key.Timestamp)
+}
+
+// newestVersion returns the newest version of the key, if any.
+func newestVersion(reader engine.Reader, key roachpb.Key) (engine.MVCCKeyValue, error) {
+	iter := reader.NewIterator(true /* prefix */)
+	defer iter.Close()
+	for iter.Seek(engine.MakeMVCCMetadataKey(key)); iter.Valid(); iter.Next() {
+		k := iter.Key()
+		if !k.Key.Equal(key) {
+			break
+		}
+		if k.IsValue() {
+			return engine.MVCCKeyValue{Key: k, Value: append([]byte(nil), iter.Value()...)}, nil
+		}
+	}
+	return engine.MVCCKeyValue{}, iter.Error()
+}
+
+// inRangeFeedSpansLocked returns whether the key is in the span of one of the
+// RangeFeeds of the replica. Replica.rangeFeedMu must be held.
+func (r *Replica) inRangeFeedSpansLocked(key roachpb.Key) bool {
+	for feed := range r.rangeFeedMu.feeds {
+		if feed.span.ContainsKey(roachpb.RKey(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// publishToRangeFeedsLocked publishes the changes made by a command to the
+// RangeFeeds of the replica, and checkpoints their spans if the closed
+// timestamp moved past their intents. A feed whose span is no longer in the
+// range, e.g. after a split, is disconnected. Replica.rangeFeedMu must be
+// held.
+func (r *Replica) publishToRangeFeedsLocked(ops []rangeFeedOp) {
+	if len(r.rangeFeedMu.feeds) == 0 {
+		return
+	}
+	r.mu.Lock()
+	closedTS, desc := r.mu.closedTimestamp, r.mu.state.Desc
+	r.mu.Unlock()
+
+	for feed := range r.rangeFeedMu.feeds {
+		if !desc.ContainsKeyRange(feed.span.Key, feed.span.EndKey) {
+			r.disconnectRangeFeedLocked(feed, roachpb.NewError(roachpb.NewRangeKeyMismatchError(
+				feed.span.Key.AsRawKey(), feed.span.EndKey.AsRawKey(), desc)))
+			continue
+		}
+		for _, op := range ops {
+			if !feed.span.ContainsKey(roachpb.RKey(op.key)) {
+				continue
+			}
+			if op.typ != rangeFeedWrite {
+				feed.updateIntent(op)
+				continue
+			}
+			if !feed.startTS.Less(op.value.Timestamp) {
+				continue
+			}
+			if !r.sendToRangeFeedLocked(feed, &roachpb.RangeFeedEvent{
+				Val: &roachpb.RangeFeedValue{Key: op.key, Value: op.value},
+			}) {
+				break
+			}
+		}
+		if _, ok := r.rangeFeedMu.feeds[feed]; !ok || feed.intents == nil {
+			continue
+		}
+		resolved := closedTS
+		for _, ts := range feed.intents {
+			if !resolved.Less(ts) {
+				resolved = ts.Prev()
+			}
+		}
+		if feed.resolved.Less(resolved) {
+			feed.resolved = resolved
+			r.sendToRangeFeedLocked(feed, &roachpb.RangeFeedEvent{
+				Checkpoint: &roachpb.RangeFeedCheckpoint{
+					Span:       roachpb.Span{Key: feed.span.Key.AsRawKey(), EndKey: feed.span.EndKey.AsRawKey()},
+					ResolvedTS: resolved,
+				},
+			})
+		}
+	}
+}
+
+// sendToRangeFeedLocked buffers an event for a RangeFeed, or disconnects the
+// feed if its client fell too far behind. Returns whether the feed is still
+// connected. Replica.rangeFeedMu must be held.
+func (r *Replica) sendToRangeFeedLocked(feed *rangeFeed, e *roachpb.RangeFeedEvent) bool {
+	select {
+	case feed.events <- e:
+		return true
+	default:
+		r.disconnectRangeFeedLocked(feed, roachpb.NewError(errors.Errorf(
+			"RangeFeed client fell more than %d events behind", rangeFeedBufferSize)))
+		return false
+	}
+}
+
+// disconnectRangeFeedLocked ends a RangeFeed with an error. Replica.rangeFeedMu
+// must be held.
+func (r *Replica) disconnectRangeFeedLocked(feed *rangeFeed, pErr *roachpb.Error) {
+	delete(r.rangeFeedMu.feeds, feed)
+	feed.done <- pErr
+}
+
+// disconnectRangeFeeds ends all the RangeFeeds of the replica with an error.
+func (r *Replica) disconnectRangeFeeds(pErr *roachpb.Error) {
+	r.rangeFeedMu.Lock()
+	defer r.rangeFeedMu.Unlock()
+	for feed := range r.rangeFeedMu.feeds {
+		r.disconnectRangeFeedLocked(feed, pErr)
+	}
+}
+
+// updateIntent applies a change to the intents in the span of the feed, or
+// queues it until the intents are read. Replica.rangeFeedMu must be held.
+func (f *rangeFeed) updateIntent(op rangeFeedOp) {
+	if f.intents == nil {
+		f.pendingIntents = append(f.pendingIntents, op)
+		return
+	}
+	if op.typ == rangeFeedIntentRemoved {
+		delete(f.intents, string(op.key))
+	} else {
+		f.intents[string(op.key)] = op.value.Timestamp
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/roachpb"
+	"github.com/cockroachdb/cockroach/storage/engine/enginepb"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+// testRangeFeedStream is a rangeFeedStream forwarding the events to a channel.
+type testRangeFeedStream struct {
+	ctx    context.Context
+	events chan *roachpb.RangeFeedEvent
+}
+
+func (s testRangeFeedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s testRangeFeedStream) Send(e *roachpb.RangeFeedEvent) error {
+	s.events <- e
+	return nil
+}
+
+// nextRangeFeedValue returns the next value event of the stream, skipping the
+// checkpoints.
+func nextRangeFeedValue(t *testing.T, events <-chan *roachpb.RangeFeedEvent) *roachpb.RangeFeedValue {
+	for {
+		select {
+		case e := <-events:
+			if e.Val != nil {
+				return e.Val
+			}
+			if e.Error != nil {
+				t.Fatal(e.Error.Error)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a RangeFeed value")
+		}
+	}
+}
+
+// TestReplicaRangeFeed verifies that a RangeFeed streams the writes committed
+// to its span after its timestamp, first from its snapshot and then as they
+// apply, and that transactional writes are only streamed once committed.
+func TestReplicaRangeFeed(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	startTS := tc.clock.Now()
+	pArgs := putArgs(roachpb.Key("a"), []byte("1"))
+	if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := testRangeFeedStream{ctx: ctx, events: make(chan *roachpb.RangeFeedEvent, 100)}
+	feedErr := make(chan *roachpb.Error, 1)
+	go func() {
+		feedErr <- tc.rng.RangeFeed(&roachpb.RangeFeedRequest{
+			Header: roachpb.Header{Timestamp: startTS},
+			Span:   roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("d")},
+		}, stream)
+	}()
+
+	// The write committed before the feed registered is caught up, which
+	// also means that the feed is registered.
+	if v := nextRangeFeedValue(t, stream.events); !v.Key.Equal(roachpb.Key("a")) {
+		t.Fatalf("expected a value for key a, got %s", v.Key)
+	}
+
+	// A write outside of the span isn't streamed, one inside is.
+	for _, key := range []string{"e", "b"} {
+		pArgs := putArgs(roachpb.Key(key), []byte("2"))
+		if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	if v := nextRangeFeedValue(t, stream.events); !v.Key.Equal(roachpb.Key("b")) {
+		t.Fatalf("expected a value for key b, got %s", v.Key)
+	}
+
+	// An intent is only streamed as it resolves.
+	key := roachpb.Key("c")
+	txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.clock)
+	pArgs = putArgs(key, []byte("3"))
+	txn.Sequence++
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Txn: txn}, &pArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	select {
+	case e := <-stream.events:
+		if e.Val != nil {
+			t.Fatalf("unexpected value for key %s before commit", e.Val.Key)
+		}
+	default:
+	}
+	rArgs := &roachpb.ResolveIntentRequest{
+		Span:      pArgs.Header(),
+		IntentTxn: txn.TxnMeta,
+		Status:    roachpb.COMMITTED,
+	}
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: txn.Timestamp}, rArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	v := nextRangeFeedValue(t, stream.events)
+	if !v.Key.Equal(key) {
+		t.Fatalf("expected a value for key %s, got %s", key, v.Key)
+	}
+	if !v.Value.Timestamp.Equal(txn.Timestamp) {
+		t.Fatalf("expected the value at %s, got %s", txn.Timestamp, v.Value.Timestamp)
+	}
+
+	cancel()
+	if pErr := <-feedErr; pErr == nil {
+		t.Fatal("expected the feed to end with an error")
+	}
+}
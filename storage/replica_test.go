@@ -19,8 +19,11 @@ package storage
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
@@ -2524,6 +2527,54 @@ func TestEndTransactionAfterHeartbeat(t *testing.T) {
 	}
 }
 
+// TestHeartbeatTxnBatched verifies that the heartbeats of multiple
+// transactions can be sent in a single non-transactional batch.
+func TestHeartbeatTxnBatched(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	var ba roachpb.BatchRequest
+	var txns []*roachpb.Transaction
+	for _, key := range []roachpb.Key{roachpb.Key("a"), roachpb.Key("b")} {
+		txn := newTransaction("test", key, 1, enginepb.SERIALIZABLE, tc.clock)
+		_, btH := beginTxnArgs(key, txn)
+		put := putArgs(key, key)
+		if _, pErr := maybeWrapWithBeginTransaction(tc.Sender(), context.Background(), btH, &put); pErr != nil {
+			t.Fatal(pErr)
+		}
+		txns = append(txns, txn)
+		ba.Add(&roachpb.HeartbeatTxnRequest{
+			Span: roachpb.Span{Key: key},
+			Now:  tc.clock.Now(),
+			Txn:  &txn.TxnMeta,
+		})
+	}
+
+	br, pErr := tc.Sender().Send(context.Background(), ba)
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	for i, resp := range br.Responses {
+		hBR := resp.GetInner().(*roachpb.HeartbeatTxnResponse)
+		if *hBR.Txn.ID != *txns[i].ID || hBR.Txn.Status != roachpb.PENDING ||
+			hBR.Txn.LastHeartbeat == nil {
+			t.Errorf("%d: unexpected heartbeat reply contents: %+v", i, hBR)
+		}
+	}
+
+	// The request key must match the key of the heartbeat transaction.
+	hb := &roachpb.HeartbeatTxnRequest{
+		Span: roachpb.Span{Key: roachpb.Key("b")},
+		Now:  tc.clock.Now(),
+		Txn:  &txns[0].TxnMeta,
+	}
+	if _, pErr := tc.SendWrapped(hb); !testutils.IsPError(pErr, "should match txn key") {
+		t.Errorf("expected key mismatch error, got %v", pErr)
+	}
+}
+
 // TestEndTransactionWithPushedTimestamp verifies that txn can be
 // ended (both commit or abort) correctly when the commit timestamp is
 // greater than the transaction timestamp, depending on the isolation
@@ -4132,6 +4183,350 @@ func TestReplicaStatsComputation(t *testing.T) {
 	verifyRangeStats(tc.engine, tc.rng.RangeID, expMS, t)
 }
 
+// TestReplicaIngest verifies that an Ingest request writes the key/value pairs
+// within its span directly to the engine and keeps the range stats correct.
+func TestReplicaIngest(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	ts := tc.clock.Now()
+	var builder engine.RocksDBBatchBuilder
+	for _, k := range []string{"a", "b", "z"} {
+		v := roachpb.MakeValueFromString("value-" + k)
+		builder.Put(engine.MVCCKey{Key: roachpb.Key(k), Timestamp: ts}, v.RawBytes)
+	}
+	iArgs := roachpb.IngestRequest{
+		Span: roachpb.Span{
+			Key:    roachpb.Key("a"),
+			EndKey: roachpb.Key("c"),
+		},
+		Data: builder.Finish(),
+	}
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &iArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	for k, exp := range map[string]string{"a": "value-a", "b": "value-b", "z": ""} {
+		gArgs := getArgs(roachpb.Key(k))
+		reply, pErr := tc.SendWrapped(&gArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		val := reply.(*roachpb.GetResponse).Value
+		if exp == "" {
+			if val != nil {
+				t.Errorf("%s: expected key outside of the ingested span to be absent, got %s", k, val)
+			}
+			continue
+		}
+		if val == nil {
+			t.Fatalf("%s: expected ingested value", k)
+		}
+		if s, err := val.GetBytes(); err != nil {
+			t.Fatal(err)
+		} else if string(s) != exp {
+			t.Errorf("%s: expected %q, got %q", k, exp, s)
+		}
+	}
+
+	// The stats maintained by the command must match a full recomputation.
+	var ms enginepb.MVCCStats
+	if err := engine.MVCCGetRangeStats(context.Background(), tc.engine, tc.rng.RangeID, &ms); err != nil {
+		t.Fatal(err)
+	}
+	expMS, err := ComputeStatsForRange(tc.rng.Desc(), tc.engine, ms.LastUpdateNanos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.LiveCount != expMS.LiveCount || ms.LiveBytes != expMS.LiveBytes ||
+		ms.KeyCount != expMS.KeyCount || ms.ValCount != expMS.ValCount {
+		t.Errorf("expected stats %+v, got %+v", expMS, ms)
+	}
+
+	// Deletions cannot be ingested.
+	builder.Clear(engine.MVCCKey{Key: roachpb.Key("a"), Timestamp: ts})
+	iArgs.Data = builder.Finish()
+	if _, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &iArgs); !testutils.IsPError(pErr, "ingest only supports puts") {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+}
+
+// TestReplicaAddSSTable verifies that an AddSSTable request makes the entries
+// of the SSTable within its span visible and keeps the range stats correct,
+// both when the file can be linked directly and when it overlaps existing
+// data or extends beyond the span.
+func TestReplicaAddSSTable(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	makeSST := func(ts hlc.Timestamp, keys ...string) []byte {
+		sst, err := engine.MakeRocksDBSstFileWriter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sst.Close()
+		for _, k := range keys {
+			v := roachpb.MakeValueFromString("value-" + k)
+			if err := sst.Add(engine.MVCCKeyValue{
+				Key:   engine.MVCCKey{Key: roachpb.Key(k), Timestamp: ts},
+				Value: v.RawBytes,
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, err := sst.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	addSSTable := func(ts hlc.Timestamp, data []byte) *roachpb.Error {
+		args := roachpb.AddSSTableRequest{
+			Span: roachpb.Span{
+				Key:    roachpb.Key("a"),
+				EndKey: roachpb.Key("c"),
+			},
+			Data: data,
+		}
+		_, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: ts}, &args)
+		return pErr
+	}
+	verify := func(expected map[string]bool) {
+		for k, present := range expected {
+			gArgs := getArgs(roachpb.Key(k))
+			reply, pErr := tc.SendWrapped(&gArgs)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			val := reply.(*roachpb.GetResponse).Value
+			if !present {
+				if val != nil {
+					t.Errorf("%s: expected key outside of the span to be absent, got %s", k, val)
+				}
+				continue
+			}
+			if val == nil {
+				t.Fatalf("%s: expected added value", k)
+			}
+			if s, err := val.GetBytes(); err != nil {
+				t.Fatal(err)
+			} else if string(s) != "value-"+k {
+				t.Errorf("%s: expected %q, got %q", k, "value-"+k, s)
+			}
+		}
+
+		// The stats maintained by the command must match a full recomputation.
+		var ms enginepb.MVCCStats
+		if err := engine.MVCCGetRangeStats(context.Background(), tc.engine, tc.rng.RangeID, &ms); err != nil {
+			t.Fatal(err)
+		}
+		expMS, err := ComputeStatsForRange(tc.rng.Desc(), tc.engine, ms.LastUpdateNanos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ms.LiveCount != expMS.LiveCount || ms.LiveBytes != expMS.LiveBytes ||
+			ms.KeyCount != expMS.KeyCount || ms.ValCount != expMS.ValCount {
+			t.Errorf("expected stats %+v, got %+v", expMS, ms)
+		}
+	}
+
+	ts := tc.clock.Now()
+	if pErr := addSSTable(ts, makeSST(ts, "a", "b")); pErr != nil {
+		t.Fatal(pErr)
+	}
+	verify(map[string]bool{"a": true, "b": true})
+
+	// This file overlaps the data added above and has a key outside the span,
+	// so its entries are written through the batch.
+	ts = tc.clock.Now()
+	if pErr := addSSTable(ts, makeSST(ts, "b", "bb", "z")); pErr != nil {
+		t.Fatal(pErr)
+	}
+	verify(map[string]bool{"a": true, "b": true, "bb": true, "z": false})
+
+	// Keys without timestamps cannot be added.
+	if pErr := addSSTable(ts, makeSST(hlc.ZeroTimestamp, "a")); !testutils.IsPError(pErr, "without a timestamp") {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+}
+
+// TestReplicaClearRange verifies that a ClearRange request removes all the
+// data in its span and keeps the range stats correct, both when it clears
+// the keys one by one and when it uses a range deletion.
+func TestReplicaClearRange(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	put := func(key string, value []byte) {
+		pArgs := putArgs(roachpb.Key(key), value)
+		if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	clearRange := func(start, end string) {
+		args := roachpb.ClearRangeRequest{
+			Span: roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)},
+		}
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	verify := func(expected map[string]bool) {
+		for k, present := range expected {
+			gArgs := getArgs(roachpb.Key(k))
+			reply, pErr := tc.SendWrapped(&gArgs)
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+			if val := reply.(*roachpb.GetResponse).Value; present != (val != nil) {
+				t.Errorf("%s: expected present=%t, got %v", k, present, val)
+			}
+		}
+
+		var ms enginepb.MVCCStats
+		if err := engine.MVCCGetRangeStats(context.Background(), tc.engine, tc.rng.RangeID, &ms); err != nil {
+			t.Fatal(err)
+		}
+		expMS, err := ComputeStatsForRange(tc.rng.Desc(), tc.engine, ms.LastUpdateNanos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ms.LiveCount != expMS.LiveCount || ms.LiveBytes != expMS.LiveBytes ||
+			ms.KeyCount != expMS.KeyCount || ms.ValCount != expMS.ValCount {
+			t.Errorf("expected stats %+v, got %+v", expMS, ms)
+		}
+	}
+
+	// A small span is cleared key by key, including older versions.
+	put("a", []byte("value"))
+	put("b1", []byte("value"))
+	put("b1", []byte("new value"))
+	put("b2", []byte("value"))
+	clearRange("b", "c")
+	verify(map[string]bool{"a": true, "b1": false, "b2": false})
+
+	// A span holding more data than the threshold is cleared with a range
+	// deletion.
+	largeValue := make([]byte, clearRangeBytesThreshold/4)
+	for _, k := range []string{"d1", "d2", "d3", "d4", "d5"} {
+		put(k, largeValue)
+	}
+	put("e", []byte("value"))
+	clearRange("d", "e")
+	verify(map[string]bool{"a": true, "d1": false, "d3": false, "d5": false, "e": true})
+
+	// ClearRange can't be used within a transaction.
+	b := tc.engine.NewBatch()
+	defer b.Close()
+	var ms enginepb.MVCCStats
+	args := roachpb.ClearRangeRequest{
+		Span: roachpb.Span{Key: roachpb.Key("a"), EndKey: roachpb.Key("b")},
+	}
+	txn := newTransaction("test", roachpb.Key("a"), 1, enginepb.SERIALIZABLE, tc.clock)
+	if _, err := tc.rng.ClearRange(context.Background(), b, &ms, roachpb.Header{Txn: txn}, args); !testutils.IsError(err, errTransactionUnsupported.Error()) {
+		t.Fatalf("transactional ClearRange returned unexpected error: %v", err)
+	}
+}
+
+// TestReplicaExport verifies that an Export request writes the data in its
+// span to an SSTable in external storage, and writes nothing when there is no
+// data to export.
+func TestReplicaExport(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{}
+	tc.Start(t)
+	defer tc.Stop()
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, k := range []string{"a", "b", "c"} {
+		pArgs := putArgs(roachpb.Key(k), []byte("value-"+k))
+		if _, pErr := tc.SendWrapped(&pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+	afterPuts := tc.clock.Now()
+	dArgs := deleteArgs(roachpb.Key("b"))
+	if _, pErr := tc.SendWrapped(&dArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+
+	export := func(start, end string, startTime hlc.Timestamp) []roachpb.ExportResponse_File {
+		eArgs := roachpb.ExportRequest{
+			Span:      roachpb.Span{Key: roachpb.Key(start), EndKey: roachpb.Key(end)},
+			Storage:   "nodelocal://" + dir,
+			StartTime: startTime,
+			ReturnSST: startTime != hlc.ZeroTimestamp,
+		}
+		reply, pErr := tc.SendWrappedWith(roachpb.Header{Timestamp: tc.clock.Now()}, &eArgs)
+		if pErr != nil {
+			t.Fatal(pErr)
+		}
+		return reply.(*roachpb.ExportResponse).Files
+	}
+
+	files := export("a", "z", hlc.ZeroTimestamp)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %+v", files)
+	}
+	// Only the latest values of a and c are exported; b is deleted.
+	v := roachpb.MakeValueFromBytes([]byte("value-a"))
+	if expected := int64(2 * (len("a") + len(v.RawBytes))); files[0].DataSize != expected {
+		t.Errorf("expected a data size of %d, got %d", expected, files[0].DataSize)
+	}
+	info, err := os.Stat(filepath.Join(dir, files[0].Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() == 0 {
+		t.Errorf("expected a non-empty file")
+	}
+
+	if files := export("x", "y", hlc.ZeroTimestamp); len(files) != 0 {
+		t.Errorf("expected no files for an empty span, got %+v", files)
+	}
+
+	// Only the deletion of b was written after the puts, and the file is
+	// returned instead of written.
+	files = export("a", "z", afterPuts)
+	if len(files) != 1 || files[0].Path != "" {
+		t.Fatalf("expected 1 returned file, got %+v", files)
+	}
+	sst, err := engine.MakeRocksDBSstFileReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sst.Close()
+	if err := sst.IngestExternalFile(files[0].SST); err != nil {
+		t.Fatal(err)
+	}
+	var kvs []engine.MVCCKeyValue
+	if err := sst.Iterate(engine.MVCCKey{Key: roachpb.KeyMin}, engine.MVCCKey{Key: roachpb.KeyMax},
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			kvs = append(kvs, kv)
+			return false, nil
+		}); err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || !kvs[0].Key.Key.Equal(roachpb.Key("b")) || len(kvs[0].Value) != 0 {
+		t.Errorf("expected the deletion of b, got %+v", kvs)
+	}
+}
+
 // TestMerge verifies that the Merge command is behaving as expected. Time
 // series data is used, as it is the only data type currently fully supported by
 // the merge command.
@@ -4300,6 +4695,83 @@ func TestTruncateLog(t *testing.T) {
 	}
 }
 
+// TestTruncateLogSeparateRaftEngine verifies that the raft log of a store
+// which keeps it in a separate engine is written to and truncated from that
+// engine only.
+func TestTruncateLogSeparateRaftEngine(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	tc := testContext{stopper: stop.NewStopper()}
+	tc.engine = engine.NewInMem(roachpb.Attributes{}, 1<<20, tc.stopper)
+	raftEng := engine.NewInMem(roachpb.Attributes{}, 1<<20, tc.stopper)
+	ctx := TestStoreContext()
+	ctx.RaftEngines = map[engine.Engine]engine.Engine{tc.engine: raftEng}
+	tc.StartWithStoreContext(t, ctx)
+	defer tc.Stop()
+	tc.rng.store.SetRaftLogQueueActive(false)
+
+	var indexes []uint64
+	for i := 0; i < 10; i++ {
+		args := incrementArgs([]byte("a"), int64(i))
+		if _, pErr := tc.SendWrapped(&args); pErr != nil {
+			t.Fatal(pErr)
+		}
+		idx, err := tc.rng.GetLastIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	rangeID := tc.rng.RangeID
+	logEntries := func(eng engine.Reader) []uint64 {
+		var logIndexes []uint64
+		prefix := keys.RaftLogPrefix(rangeID)
+		if err := eng.Iterate(
+			engine.MakeMVCCMetadataKey(prefix),
+			engine.MakeMVCCMetadataKey(prefix.PrefixEnd()),
+			func(kv engine.MVCCKeyValue) (bool, error) {
+				var ent raftpb.Entry
+				if err := (roachpb.Value{RawBytes: kv.Value}).GetProto(&ent); err != nil {
+					return false, err
+				}
+				logIndexes = append(logIndexes, ent.Index)
+				return false, nil
+			},
+		); err != nil {
+			t.Fatal(err)
+		}
+		return logIndexes
+	}
+	if entries := logEntries(tc.engine); len(entries) != 0 {
+		t.Fatalf("expected no log entries in the store's engine, got %d", entries)
+	}
+	if entries := logEntries(raftEng); len(entries) == 0 || entries[len(entries)-1] != indexes[9] {
+		t.Fatalf("expected log entries up to %d in the raft engine, got %d", indexes[9], entries)
+	}
+
+	truncateArgs := truncateLogArgs(indexes[5], rangeID)
+	if _, pErr := tc.SendWrapped(&truncateArgs); pErr != nil {
+		t.Fatal(pErr)
+	}
+	util.SucceedsSoon(t, func() error {
+		if entries := logEntries(raftEng); len(entries) == 0 || entries[0] != indexes[5] {
+			return errors.Errorf("expected log entries from %d in the raft engine, got %d", indexes[5], entries)
+		}
+		return nil
+	})
+
+	// What remains of the log can still be read.
+	tc.rng.mu.Lock()
+	entries, err := tc.rng.Entries(indexes[5], indexes[9], math.MaxUint64)
+	tc.rng.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != int(indexes[9]-indexes[5]) {
+		t.Errorf("expected %d entries, got %d", indexes[9]-indexes[5], len(entries))
+	}
+}
+
 // TestConditionFailedError tests that a ConditionFailedError correctly
 // bubbles up from MVCC to Range.
 func TestConditionFailedError(t *testing.T) {
@@ -6042,7 +6514,10 @@ func TestReplicaBurstPendingCommandsAndRepropose(t *testing.T) {
 func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	var tc testContext
-	tc.Start(t)
+	ctx := TestStoreContext()
+	// The replica must keep ticking while there is no pending command.
+	ctx.TestingKnobs.DisableQuiescence = true
+	tc.StartWithStoreContext(t, ctx)
 	defer tc.Stop()
 
 	// Grab processRaftMu in order to block normal raft replica processing. This
@@ -6068,7 +6543,7 @@ func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 		ticks := r.mu.ticks
 		r.mu.Unlock()
 		for ; (ticks % electionTicks) != 0; ticks++ {
-			if err := r.tick(); err != nil {
+			if _, err := r.tick(); err != nil {
 				t.Fatal(err)
 			}
 		}
@@ -6098,7 +6573,7 @@ func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 		r.mu.Unlock()
 
 		// Tick raft.
-		if err := r.tick(); err != nil {
+		if _, err := r.tick(); err != nil {
 			t.Fatal(err)
 		}
 
@@ -6127,6 +6602,64 @@ func TestReplicaRefreshPendingCommandsTicks(t *testing.T) {
 	}
 }
 
+// TestReplicaQuiesce verifies that an idle range quiesces, that its raft
+// group is unloaded after a while, and that a write wakes it up.
+func TestReplicaQuiesce(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	var tc testContext
+	ctx := TestStoreContext()
+	ctx.RaftUnloadQuiescentTicks = 2
+	tc.StartWithStoreContext(t, ctx)
+	defer tc.Stop()
+	r := tc.rng
+
+	isQuiescent := func() (bool, bool) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.mu.quiescent, r.mu.internalRaftGroup == nil
+	}
+
+	util.SucceedsSoon(t, func() error {
+		if _, unloaded := isQuiescent(); !unloaded {
+			return errors.New("raft group not unloaded yet")
+		}
+		return nil
+	})
+	if quiescent, err := r.tick(); err != nil {
+		t.Fatal(err)
+	} else if !quiescent {
+		t.Fatal("expected a replica without raft group to be quiescent")
+	}
+
+	// Hold processRaftMu so that the raft group can't quiesce again before
+	// it's checked.
+	tc.store.processRaftMu.Lock()
+	args := putArgs(roachpb.Key("a"), []byte("value"))
+	ba := roachpb.BatchRequest{}
+	ba.Timestamp = tc.clock.Now()
+	ba.Add(&args)
+	if _, _, err := r.proposeRaftCommand(context.Background(), ba); err != nil {
+		tc.store.processRaftMu.Unlock()
+		t.Fatal(err)
+	}
+	quiescent, unloaded := isQuiescent()
+	tc.store.processRaftMu.Unlock()
+	if quiescent || unloaded {
+		t.Fatalf("expected a loaded, active raft group; quiescent=%t unloaded=%t",
+			quiescent, unloaded)
+	}
+
+	util.SucceedsSoon(t, func() error {
+		if quiescent, unloaded := isQuiescent(); !quiescent && !unloaded {
+			return errors.New("raft group not quiescent yet")
+		}
+		return nil
+	})
+	if _, pErr := tc.SendWrapped(&args); pErr != nil {
+		t.Fatal(pErr)
+	}
+}
+
 // TestReplicaDoubleRefurbish exercises a code path in which a command is seen
 // fit for refurbishment, but has already been refurbished earlier (with that
 // command being in-flight). See #7185.
@@ -62,6 +62,10 @@ type proposalResult struct {
 	// We are interested in this delta only to report it to the Store, which
 	// keeps a running total of all of its Replicas' stats.
 	delta enginepb.MVCCStats
+
+	// The changes made by the command which are published to the RangeFeeds
+	// of the replica.
+	rangeFeedOps []rangeFeedOp
 }
 
 // PostCommitTrigger is returned from Raft processing as a side effect which
@@ -97,6 +101,10 @@ type PostCommitTrigger struct {
 
 	computeChecksum *roachpb.ComputeChecksumRequest
 	verifyChecksum  *roachpb.VerifyChecksumRequest
+
+	// suggestedCompactions are the spans of the replica cleared of enough
+	// data for the store's compactor to consider reclaiming their space.
+	suggestedCompactions []suggestedCompaction
 }
 
 // updateTrigger takes a previous and new commit trigger and combines their
@@ -168,6 +176,9 @@ func updateTrigger(old, new *PostCommitTrigger) *PostCommitTrigger {
 		if new.verifyChecksum != nil {
 			old.verifyChecksum = new.verifyChecksum
 		}
+		if new.suggestedCompactions != nil {
+			old.suggestedCompactions = append(old.suggestedCompactions, new.suggestedCompactions...)
+		}
 	}
 	return old
 }
@@ -236,8 +247,17 @@ func (r *Replica) verifyChecksumTrigger(
 		// version incompatibility.
 		return
 	}
-	if c.checksum != nil && !bytes.Equal(c.checksum, args.Checksum) {
+	if c.checksum == nil {
+		return
+	}
+	inconsistent := !bytes.Equal(c.checksum, args.Checksum)
+	r.mu.Lock()
+	r.mu.lastConsistencyCheck = r.store.Clock().Now()
+	r.mu.inconsistent = inconsistent
+	r.mu.Unlock()
+	if inconsistent {
 		// Replication consistency problem!
+		r.store.metrics.rangeConsistencyFailures.Inc(1)
 		logFunc := log.Errorf
 
 		// Collect some more debug information.
@@ -313,14 +333,14 @@ func (r *Replica) leasePostCommitTrigger(
 			// Gossip the first range whenever its lease is acquired. We check to
 			// make sure the lease is active so that a trailing replica won't process
 			// an old lease request and attempt to gossip the first range.
-			if r.IsFirstRange() && trigger.lease.Covers(r.store.Clock().Now()) {
+			if r.IsFirstRange() && r.isLeaseValid(trigger.lease, r.store.Clock().Now()) {
 				func() {
 					r.mu.Lock()
 					defer r.mu.Unlock()
 					r.gossipFirstRangeLocked(ctx)
 				}()
 			}
-		} else if trigger.lease.Covers(r.store.Clock().Now()) {
+		} else if r.isLeaseValid(trigger.lease, r.store.Clock().Now()) {
 			if err := r.withRaftGroup(func(raftGroup *raft.RawNode) error {
 				if raftGroup.Status().RaftState == raft.StateLeader {
 					// If this replica is the raft leader but it is not the new lease
@@ -398,9 +418,21 @@ func (r *Replica) handleTrigger(
 		r.mu.state.GCThreshold = *trigger.gcThreshold
 		r.mu.Unlock()
 	}
+	for _, sc := range trigger.suggestedCompactions {
+		r.store.compactor.Suggest(sc.span, sc.bytes)
+	}
 	if trigger.truncatedState != nil {
 		r.mu.Lock()
 		r.mu.state.TruncatedState = trigger.truncatedState
+		// A raft log kept separately isn't truncated by the command itself.
+		// Its entries are removed while the replica lock is held, which keeps
+		// them from disappearing from under a snapshot of the log.
+		if r.store.separateRaftLog() {
+			if err := clearRaftLogSpan(r.store.RaftEngine(), keys.RaftLogKey(r.RangeID, 0),
+				keys.RaftLogKey(r.RangeID, trigger.truncatedState.Index+1)); err != nil {
+				log.Warningf(ctx, "%s: unable to truncate raft log: %s", r, err)
+			}
+		}
 		r.mu.Unlock()
 	}
 	if trigger.raftLogSize != nil {
@@ -109,8 +109,18 @@ func (rq *replicateQueue) shouldQueue(
 	if lease, _ := repl.getLease(); lease != nil {
 		leaseStoreID = lease.Replica.StoreID
 	}
-	target := rq.allocator.RebalanceTarget(
-		zone.ReplicaAttrs[0], desc.Replicas, leaseStoreID)
+	if _, ok := rq.allocator.LeaseTarget(zone, desc, leaseStoreID); ok {
+		return true, 0
+	}
+	if _, ok := rq.allocator.LoadBasedLeaseTarget(
+		zone, desc, leaseStoreID, repl.QueriesPerSecond()); ok {
+		return true, 0
+	}
+	required, ok := uniformConstraints(zone)
+	if !ok {
+		return false, 0
+	}
+	target := rq.allocator.RebalanceTarget(required, desc.Replicas, leaseStoreID)
 	return target != nil, 0
 }
 
@@ -140,7 +150,8 @@ func (rq *replicateQueue) process(
 	switch action {
 	case AllocatorAdd:
 		log.Trace(ctx, "adding a new replica")
-		newStore, err := rq.allocator.AllocateTarget(zone.ReplicaAttrs[0], desc.Replicas, true)
+		newStore, err := rq.allocator.AllocateTarget(
+			rq.allocator.TargetConstraints(zone, desc.Replicas), desc.Replicas, true)
 		if err != nil {
 			return err
 		}
@@ -157,7 +168,21 @@ func (rq *replicateQueue) process(
 		log.Trace(ctx, "removing a replica")
 		// We require the lease in order to process replicas, so
 		// repl.store.StoreID() corresponds to the lease-holder's store ID.
-		removeReplica, err := rq.allocator.RemoveTarget(desc.Replicas, repl.store.StoreID())
+		candidates := rq.allocator.RemoveCandidates(zone, desc.Replicas)
+		if len(candidates) == 1 && candidates[0].StoreID == repl.store.StoreID() {
+			// The lease holder's replica is the one violating the constraints of
+			// the zone config. Hand the lease over to another replica, which will
+			// remove it, preferably one satisfying the lease preferences.
+			voters := desc.Voters()
+			others := append(rq.allocator.PreferredLeaseholders(zone, voters), voters...)
+			for _, other := range others {
+				if other.StoreID != repl.store.StoreID() {
+					log.VTracef(1, ctx, "%s: transferring lease to %+v to remove own replica", repl, other)
+					return repl.AdminTransferLease(other.StoreID)
+				}
+			}
+		}
+		removeReplica, err := rq.allocator.RemoveTarget(candidates, repl.store.StoreID())
 		if err != nil {
 			return err
 		}
@@ -182,15 +207,42 @@ func (rq *replicateQueue) process(
 		if err = repl.ChangeReplicas(ctx, roachpb.REMOVE_REPLICA, deadReplica, desc); err != nil {
 			return err
 		}
+	case AllocatorRemoveLearner:
+		log.Trace(ctx, "removing a learner")
+		for _, learner := range desc.Replicas {
+			if !learner.IsLearner() {
+				continue
+			}
+			log.VTracef(1, ctx, "%s: removing learner %+v left behind by a replica change", repl, learner)
+			if err = repl.ChangeReplicas(ctx, roachpb.REMOVE_REPLICA, learner, desc); err != nil {
+				return err
+			}
+			break
+		}
 	case AllocatorNoop:
+		if target, ok := rq.allocator.LeaseTarget(zone, desc, repl.store.StoreID()); ok {
+			// The lease holder doesn't satisfy the lease preferences of the zone
+			// config. Transferring the lease gives up the responsibility of
+			// processing the range, so there's no point in requeueing it.
+			log.VTracef(1, ctx, "%s: transferring lease to %+v to honor the lease preferences", repl, target)
+			return repl.AdminTransferLease(target.StoreID)
+		}
+		if target, ok := rq.allocator.LoadBasedLeaseTarget(
+			zone, desc, repl.store.StoreID(), repl.QueriesPerSecond()); ok {
+			log.VTracef(1, ctx, "%s: transferring lease to %+v to shed load", repl, target)
+			return repl.AdminTransferLease(target.StoreID)
+		}
 		log.Trace(ctx, "considering a rebalance")
 		// The Noop case will result if this replica was queued in order to
 		// rebalance. Attempt to find a rebalancing target.
 		//
 		// We require the lease in order to process replicas, so
 		// repl.store.StoreID() corresponds to the lease-holder's store ID.
-		rebalanceStore := rq.allocator.RebalanceTarget(
-			zone.ReplicaAttrs[0], desc.Replicas, repl.store.StoreID())
+		var rebalanceStore *roachpb.StoreDescriptor
+		if required, ok := uniformConstraints(zone); ok {
+			rebalanceStore = rq.allocator.RebalanceTarget(
+				required, desc.Replicas, repl.store.StoreID())
+		}
 		if rebalanceStore == nil {
 			log.VTracef(1, ctx, "%s: no suitable rebalance target", repl)
 			// No action was necessary and no rebalance target was found. Return
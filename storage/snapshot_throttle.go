@@ -0,0 +1,131 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cockroachdb/cockroach/settings"
+	"github.com/cockroachdb/cockroach/util/metric"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+)
+
+func validateSnapshotRate(v int64) error {
+	if v < 0 {
+		return errors.Errorf("rate must not be negative, got %d", v)
+	}
+	return nil
+}
+
+// preemptiveSnapshotRate is the rate, in bytes per second, at which each
+// store sends and receives the preemptive snapshots used to add replicas when
+// up-replicating or rebalancing.
+var preemptiveSnapshotRate = settings.RegisterValidatedIntSetting(
+	"kv.snapshot_rebalance.max_rate",
+	"the rate limit (bytes/sec) of the preemptive snapshots sent and received by each store, or 0 for no limit",
+	0,
+	validateSnapshotRate,
+)
+
+// raftSnapshotRate is the rate, in bytes per second, at which each store
+// sends and receives the Raft snapshots used to catch up replicas which fell
+// behind the truncated Raft log.
+var raftSnapshotRate = settings.RegisterValidatedIntSetting(
+	"kv.snapshot_recovery.max_rate",
+	"the rate limit (bytes/sec) of the Raft snapshots sent and received by each store, or 0 for no limit",
+	0,
+	validateSnapshotRate,
+)
+
+// isPreemptiveSnapshot returns whether the snapshot carried by the request is
+// a preemptive snapshot. Preemptive snapshots are addressed to the special
+// replica ID zero.
+func isPreemptiveSnapshot(req *RaftMessageRequest) bool {
+	return req.Message.To == 0
+}
+
+// rateLimiter paces transfers to a rate of bytes per second which can change
+// at any time. Each transfer is scheduled after the previous ones, and waits
+// until the time at which the previous ones are done at the current rate.
+type rateLimiter struct {
+	// rate returns the current rate, or 0 for no limit.
+	rate func() int64
+
+	mu struct {
+		syncutil.Mutex
+		// next is the time at which the next transfer may start.
+		next time.Time
+	}
+}
+
+// wait blocks until n bytes may be transferred, or until quiesce is closed.
+func (l *rateLimiter) wait(n int64, quiesce <-chan struct{}) {
+	rate := l.rate()
+	if rate <= 0 {
+		return
+	}
+	now := timeutil.Now()
+	l.mu.Lock()
+	if l.mu.next.Before(now) {
+		l.mu.next = now
+	}
+	start := l.mu.next
+	l.mu.next = start.Add(time.Duration(float64(n) / float64(rate) * float64(time.Second)))
+	l.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		var timer timeutil.Timer
+		defer timer.Stop()
+		timer.Reset(d)
+		select {
+		case <-timer.C:
+			timer.Read = true
+		case <-quiesce:
+		}
+	}
+}
+
+// snapshotThrottle paces the snapshots sent or received by a store according
+// to the rate limits of their type, and counts their bytes.
+type snapshotThrottle struct {
+	preemptive, raft           rateLimiter
+	preemptiveBytes, raftBytes *metric.Counter
+}
+
+func newSnapshotThrottle(preemptiveBytes, raftBytes *metric.Counter) *snapshotThrottle {
+	t := &snapshotThrottle{
+		preemptiveBytes: preemptiveBytes,
+		raftBytes:       raftBytes,
+	}
+	t.preemptive.rate = preemptiveSnapshotRate.Get
+	t.raft.rate = raftSnapshotRate.Get
+	return t
+}
+
+// wait blocks until the snapshot carried by the request may be transferred,
+// or until quiesce is closed.
+func (t *snapshotThrottle) wait(req *RaftMessageRequest, quiesce <-chan struct{}) {
+	n := int64(req.Message.Snapshot.Size())
+	if isPreemptiveSnapshot(req) {
+		t.preemptiveBytes.Inc(n)
+		t.preemptive.wait(n, quiesce)
+	} else {
+		t.raftBytes.Inc(n)
+		t.raft.wait(n, quiesce)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/raft/raftpb"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	"github.com/cockroachdb/cockroach/util/metric"
+	"github.com/cockroachdb/cockroach/util/timeutil"
+)
+
+// TestRateLimiter verifies that the transfers are paced to the current rate
+// of the limiter.
+func TestRateLimiter(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	rate := int64(0)
+	l := rateLimiter{rate: func() int64 { return rate }}
+
+	// Without a limit, the transfers don't wait.
+	start := timeutil.Now()
+	for i := 0; i < 10; i++ {
+		l.wait(1<<30, nil)
+	}
+	if elapsed := timeutil.Since(start); elapsed > time.Second {
+		t.Fatalf("expected no wait without a limit, waited %s", elapsed)
+	}
+
+	// At 1000 bytes per second, the third transfer of 100 bytes starts 200ms
+	// after the first one.
+	rate = 1000
+	start = timeutil.Now()
+	for i := 0; i < 3; i++ {
+		l.wait(100, nil)
+	}
+	if elapsed := timeutil.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected the transfers to take at least 200ms, took %s", elapsed)
+	}
+
+	// Quiescing interrupts the wait.
+	quiesce := make(chan struct{})
+	close(quiesce)
+	start = timeutil.Now()
+	l.wait(1<<30, quiesce)
+	l.wait(1<<30, quiesce)
+	if elapsed := timeutil.Since(start); elapsed > time.Second {
+		t.Fatalf("expected quiescing to interrupt the wait, waited %s", elapsed)
+	}
+}
+
+// TestSnapshotThrottleMetrics verifies that the throttle counts the bytes of
+// the snapshots by type.
+func TestSnapshotThrottleMetrics(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	registry := metric.NewRegistry()
+	preemptive, raft := registry.Counter("preemptive"), registry.Counter("raft")
+	throttle := newSnapshotThrottle(preemptive, raft)
+
+	snap := raftpb.Snapshot{Data: make([]byte, 100)}
+	throttle.wait(&RaftMessageRequest{Message: raftpb.Message{To: 0, Snapshot: snap}}, nil)
+	throttle.wait(&RaftMessageRequest{Message: raftpb.Message{To: 2, Snapshot: snap}}, nil)
+	throttle.wait(&RaftMessageRequest{Message: raftpb.Message{To: 2, Snapshot: snap}}, nil)
+
+	size := int64(snap.Size())
+	if c := preemptive.Count(); c != size {
+		t.Errorf("expected %d preemptive snapshot bytes, got %d", size, c)
+	}
+	if c := raft.Count(); c != 2*size {
+		t.Errorf("expected %d Raft snapshot bytes, got %d", 2*size, c)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -63,6 +64,8 @@ const (
 	defaultHeartbeatIntervalTicks   = 3
 	defaultRaftElectionTimeoutTicks = 15
 	defaultAsyncSnapshotMaxAge      = time.Minute
+	defaultRaftProposalQuota        = 1 << 20 // 1 MB
+	defaultRaftUnloadQuiescentTicks = 300
 	// ttlStoreGossip is time-to-live for store-related info.
 	ttlStoreGossip = 2 * time.Minute
 
@@ -84,6 +87,16 @@ var changeTypeInternalToRaft = map[roachpb.ReplicaChangeType]raftpb.ConfChangeTy
 	roachpb.REMOVE_REPLICA: raftpb.ConfChangeRemoveNode,
 }
 
+// confChangeType returns the type of the Raft configuration change carrying
+// out the replica change of the trigger. Replicas join Raft groups as
+// learners, and are promoted to voters by adding them again.
+func confChangeType(crt *roachpb.ChangeReplicasTrigger) raftpb.ConfChangeType {
+	if crt.ChangeType == roachpb.ADD_REPLICA && crt.Replica.IsLearner() {
+		return raftpb.ConfChangeAddLearnerNode
+	}
+	return changeTypeInternalToRaft[crt.ChangeType]
+}
+
 var storeReplicaRaftReadyConcurrency = 2 * runtime.NumCPU()
 
 // TestStoreContext has some fields initialized with values relevant in tests.
@@ -114,7 +127,8 @@ func newRaftConfig(
 		HeartbeatTick: storeCtx.RaftHeartbeatIntervalTicks,
 		Storage:       strg,
 		Logger:        logger,
-		CheckQuorum:   true,
+		PreVote:       !storeCtx.RaftDisablePreVote,
+		CheckQuorum:   !storeCtx.RaftDisableCheckQuorum,
 		// TODO(bdarnell): make these configurable; evaluate defaults.
 		MaxSizePerMsg:   1024 * 1024,
 		MaxInflightMsgs: 256,
@@ -296,10 +310,12 @@ type Store struct {
 	ctx                     StoreContext
 	db                      *client.DB
 	engine                  engine.Engine            // The underlying key-value store
+	raftEngine              engine.Engine            // Holds the raft logs; engine unless separate
 	allocator               Allocator                // Makes allocation decisions
 	rangeIDAlloc            *idAllocator             // Range ID allocator
 	gcQueue                 *gcQueue                 // Garbage collection queue
 	splitQueue              *splitQueue              // Range splitting queue
+	mergeQueue              *mergeQueue              // Range merging queue
 	verifyQueue             *verifyQueue             // Checksum verification queue
 	replicateQueue          *replicateQueue          // Replication queue
 	replicaGCQueue          *replicaGCQueue          // Replica GC queue
@@ -307,8 +323,10 @@ type Store struct {
 	scanner                 *replicaScanner          // Replica scanner
 	replicaConsistencyQueue *replicaConsistencyQueue // Replica consistency check queue
 	consistencyScanner      *replicaScanner          // Consistency checker scanner
+	compactor               *compactor               // Reclaims the space of cleared spans
 	metrics                 *storeMetrics
 	intentResolver          *intentResolver
+	contention              *contentionRegistry
 	wakeRaftLoop            chan struct{}
 	// 1 if the store was started, 0 if it wasn't. To be accessed using atomic
 	// ops.
@@ -320,6 +338,9 @@ type Store struct {
 	initComplete sync.WaitGroup // Signaled by async init tasks
 	bookie       *bookie
 
+	// The throttles pacing the snapshots sent and received by the store.
+	snapshotSendThrottle, snapshotRecvThrottle *snapshotThrottle
+
 	// This is 1 if there is an active raft snapshot. This field must be checked
 	// and set atomically.
 	// TODO(marc): This may be better inside of `mu`, but is not currently feasible.
@@ -462,6 +483,11 @@ type StoreContext struct {
 	// is more direct than using a sql.Executor.
 	SQLExecutor sqlutil.InternalExecutor
 
+	// RaftEngines maps the engines of the stores which keep their raft logs
+	// in a separate engine to that engine. The logs of the other stores are
+	// kept in their engine along with the rest of their data.
+	RaftEngines map[engine.Engine]engine.Engine
+
 	// RangeRetryOptions are the retry options when retryable errors are
 	// encountered sending commands to ranges.
 	RangeRetryOptions retry.Options
@@ -479,6 +505,27 @@ type StoreContext struct {
 	// for local networks.
 	RaftElectionTimeoutTicks int
 
+	// RaftDisablePreVote disables the pre-vote phase of Raft elections. With
+	// pre-vote, a replica which was partitioned away only bumps its term once
+	// it knows it can win the election, so that it doesn't depose a healthy
+	// leader when it rejoins.
+	RaftDisablePreVote bool
+
+	// RaftDisableCheckQuorum disables the Raft check-quorum mode, in which a
+	// leader steps down when it hasn't heard from a quorum for an election
+	// timeout and followers ignore the votes requested while they hear from
+	// their leader.
+	RaftDisableCheckQuorum bool
+
+	// RaftProposalQuota is the size of the commands a raft leader may have
+	// proposed which its active followers haven't all appended to their log
+	// yet. Further proposals wait until the followers catch up.
+	RaftProposalQuota int64
+
+	// RaftUnloadQuiescentTicks is the number of ticks after which the raft
+	// group of a quiescent replica is unloaded from memory.
+	RaftUnloadQuiescentTicks int
+
 	// ScanInterval is the default value for the scan interval
 	ScanInterval time.Duration
 
@@ -518,6 +565,21 @@ type StoreContext struct {
 	// it up (counted from when the snapshot generation is completed).
 	AsyncSnapshotMaxAge time.Duration
 
+	// NodeLiveness is used to determine the validity of epoch-based range
+	// leases. It is only required if EnableEpochRangeLeases is set.
+	NodeLiveness *NodeLiveness
+
+	// EnableEpochRangeLeases causes the store to request epoch-based range
+	// leases, which are tied to the liveness record of the lease holder's
+	// node, for the ranges which don't hold node liveness records.
+	EnableEpochRangeLeases bool
+
+	// ClosedTimestampTargetDuration is how far behind the present time the
+	// lease holders of the store close timestamps, allowing followers to serve
+	// reads at or below them. Zero disables closed timestamps and with them
+	// follower reads.
+	ClosedTimestampTargetDuration time.Duration
+
 	TestingKnobs StoreTestingKnobs
 
 	// rangeLeaseActiveDuration is the duration of the active period of leader
@@ -552,6 +614,8 @@ type StoreTestingKnobs struct {
 	LeaseTransferBlockedOnExtensionEvent func(nextLeader roachpb.ReplicaDescriptor)
 	// DisableSplitQueue disables the split queue.
 	DisableSplitQueue bool
+	// DisableMergeQueue disables the merge queue.
+	DisableMergeQueue bool
 	// DisableReplicateQueue disables the replication queue.
 	DisableReplicateQueue bool
 	// DisableScanner disables the replica scanner.
@@ -565,6 +629,8 @@ type StoreTestingKnobs struct {
 	// DisableRefreshReasonTicks disables refreshing pending commands
 	// periodically.
 	DisableRefreshReasonTicks bool
+	// DisableQuiescence disables the quiescence of idle raft groups.
+	DisableQuiescence bool
 	// DisableProcessRaft disables the process raft loop.
 	DisableProcessRaft bool
 }
@@ -580,6 +646,7 @@ type storeMetrics struct {
 	// Range data metrics.
 	replicaCount                 *metric.Counter // Does not include reserved replicas.
 	reservedReplicaCount         *metric.Counter
+	quiescentReplicaCount        *metric.Gauge
 	leaderRangeCount             *metric.Gauge
 	replicatedRangeCount         *metric.Gauge
 	replicationPendingRangeCount *metric.Gauge
@@ -629,6 +696,35 @@ type storeMetrics struct {
 	rangeSnapshotsGenerated         *metric.Counter
 	rangeSnapshotsNormalApplied     *metric.Counter
 	rangeSnapshotsPreemptiveApplied *metric.Counter
+	// Bytes of the snapshots sent and received, by type.
+	rangeSnapshotsNormalSentBytes     *metric.Counter
+	rangeSnapshotsPreemptiveSentBytes *metric.Counter
+	rangeSnapshotsNormalRcvdBytes     *metric.Counter
+	rangeSnapshotsPreemptiveRcvdBytes *metric.Counter
+	// Consistency checks started as lease holder, and checksum mismatches
+	// found on this store's replicas.
+	rangeConsistencyChecks   *metric.Counter
+	rangeConsistencyFailures *metric.Counter
+	// Writes blocked until their oversized range split.
+	rangeBackpressuredWrites *metric.Counter
+
+	// Resolution of the intents of finalized transactions: the batches sent,
+	// the intents resolved or which failed to be, and the intents queued.
+	intentResolverBatches        *metric.Counter
+	intentResolverIntents        *metric.Counter
+	intentResolverFailures       *metric.Counter
+	intentResolverPendingIntents *metric.Gauge
+
+	// Transactions committed by this store's replicas which had written
+	// outside of the range holding their record, and the number of intent
+	// spans they had outside of it.
+	txnCrossRangeCommits *metric.Counter
+	txnCrossRangeIntents *metric.Histogram
+
+	// Compactions of the spans cleared from the store, and the bytes they
+	// were suggested to reclaim.
+	compactorCompactions    *metric.Counter
+	compactorCompactedBytes *metric.Counter
 
 	// Raft processing metrics.
 	raftSelectDurationNanos  *metric.Counter
@@ -650,6 +746,7 @@ func newStoreMetrics() *storeMetrics {
 		registry:                     storeRegistry,
 		replicaCount:                 storeRegistry.Counter("replicas"),
 		reservedReplicaCount:         storeRegistry.Counter("replicas.reserved"),
+		quiescentReplicaCount:        storeRegistry.Gauge("replicas.quiescent"),
 		leaderRangeCount:             storeRegistry.Gauge("ranges.leader"),
 		replicatedRangeCount:         storeRegistry.Gauge("ranges.replicated"),
 		replicationPendingRangeCount: storeRegistry.Gauge("ranges.replication-pending"),
@@ -689,12 +786,33 @@ func newStoreMetrics() *storeMetrics {
 		rdbReadAmplification:        storeRegistry.Gauge("rocksdb.read-amplification"),
 
 		// Range event metrics.
-		rangeSplits:                     storeRegistry.Counter("range.splits"),
-		rangeAdds:                       storeRegistry.Counter("range.adds"),
-		rangeRemoves:                    storeRegistry.Counter("range.removes"),
-		rangeSnapshotsGenerated:         storeRegistry.Counter("range.snapshots.generated"),
-		rangeSnapshotsNormalApplied:     storeRegistry.Counter("range.snapshots.normal-applied"),
-		rangeSnapshotsPreemptiveApplied: storeRegistry.Counter("range.snapshots.preemptive-applied"),
+		rangeSplits:                       storeRegistry.Counter("range.splits"),
+		rangeAdds:                         storeRegistry.Counter("range.adds"),
+		rangeRemoves:                      storeRegistry.Counter("range.removes"),
+		rangeSnapshotsGenerated:           storeRegistry.Counter("range.snapshots.generated"),
+		rangeSnapshotsNormalApplied:       storeRegistry.Counter("range.snapshots.normal-applied"),
+		rangeSnapshotsPreemptiveApplied:   storeRegistry.Counter("range.snapshots.preemptive-applied"),
+		rangeSnapshotsNormalSentBytes:     storeRegistry.Counter("range.snapshots.normal-sent-bytes"),
+		rangeSnapshotsPreemptiveSentBytes: storeRegistry.Counter("range.snapshots.preemptive-sent-bytes"),
+		rangeSnapshotsNormalRcvdBytes:     storeRegistry.Counter("range.snapshots.normal-received-bytes"),
+		rangeSnapshotsPreemptiveRcvdBytes: storeRegistry.Counter("range.snapshots.preemptive-received-bytes"),
+		rangeConsistencyChecks:            storeRegistry.Counter("range.consistency-checks"),
+		rangeConsistencyFailures:          storeRegistry.Counter("range.consistency-failures"),
+		rangeBackpressuredWrites:          storeRegistry.Counter("range.backpressured-writes"),
+
+		// Intent resolver metrics.
+		intentResolverBatches:        storeRegistry.Counter("intentresolver.batches"),
+		intentResolverIntents:        storeRegistry.Counter("intentresolver.intents.resolved"),
+		intentResolverFailures:       storeRegistry.Counter("intentresolver.intents.failed"),
+		intentResolverPendingIntents: storeRegistry.Gauge("intentresolver.intents.pending"),
+
+		// Transaction metrics.
+		txnCrossRangeCommits: storeRegistry.Counter("txn.cross-range.commits"),
+		txnCrossRangeIntents: storeRegistry.Histogram("txn.cross-range.intents", 60*time.Second, 1000, 1),
+
+		// Compactor metrics.
+		compactorCompactions:    storeRegistry.Counter("compactor.compactions"),
+		compactorCompactedBytes: storeRegistry.Counter("compactor.compacted-bytes"),
 
 		// Raft processing metrics.
 		raftSelectDurationNanos:  storeRegistry.Counter("process-raft.waitingnanos"),
@@ -811,12 +929,26 @@ func (sc *StoreContext) setDefaults() {
 	if sc.AsyncSnapshotMaxAge == 0 {
 		sc.AsyncSnapshotMaxAge = defaultAsyncSnapshotMaxAge
 	}
+	if sc.RaftProposalQuota == 0 {
+		sc.RaftProposalQuota = defaultRaftProposalQuota
+	}
+	if sc.RaftUnloadQuiescentTicks == 0 {
+		sc.RaftUnloadQuiescentTicks = defaultRaftUnloadQuiescentTicks
+	}
 
 	raftElectionTimeout := time.Duration(sc.RaftElectionTimeoutTicks) * sc.RaftTickInterval
 	sc.rangeLeaseActiveDuration = rangeLeaseRaftElectionTimeoutMultiplier * raftElectionTimeout
 	sc.rangeLeaseRenewalDuration = sc.rangeLeaseActiveDuration / rangeLeaseRenewalDivisor
 }
 
+// RangeLeaseDurations returns the active and renewal durations of the
+// expiration-based range leases requested by the stores created with this
+// context.
+func (sc *StoreContext) RangeLeaseDurations() (active, renewal time.Duration) {
+	sc.setDefaults()
+	return sc.rangeLeaseActiveDuration, sc.rangeLeaseRenewalDuration
+}
+
 // NewStore returns a new instance of a store.
 func NewStore(ctx StoreContext, eng engine.Engine, nodeDesc *roachpb.NodeDescriptor) *Store {
 	// TODO(tschottdorf) find better place to set these defaults.
@@ -836,7 +968,17 @@ func NewStore(ctx StoreContext, eng engine.Engine, nodeDesc *roachpb.NodeDescrip
 		metrics:      newStoreMetrics(),
 	}
 	s.intentResolver = newIntentResolver(s)
+	s.contention = newContentionRegistry()
 	s.drainLeases.Store(false)
+	s.snapshotSendThrottle = newSnapshotThrottle(
+		s.metrics.rangeSnapshotsPreemptiveSentBytes, s.metrics.rangeSnapshotsNormalSentBytes)
+	s.snapshotRecvThrottle = newSnapshotThrottle(
+		s.metrics.rangeSnapshotsPreemptiveRcvdBytes, s.metrics.rangeSnapshotsNormalRcvdBytes)
+	s.raftEngine = eng
+	if raftEng, ok := ctx.RaftEngines[eng]; ok {
+		s.raftEngine = raftEng
+	}
+	s.compactor = newCompactor(s.engine, s.metrics)
 
 	s.mu.Lock()
 	s.mu.replicas = map[roachpb.RangeID]*Replica{}
@@ -851,11 +993,12 @@ func NewStore(ctx StoreContext, eng engine.Engine, nodeDesc *roachpb.NodeDescrip
 		s.scanner = newReplicaScanner(ctx.ScanInterval, ctx.ScanMaxIdleTime, newStoreRangeSet(s))
 		s.gcQueue = newGCQueue(s, s.ctx.Gossip)
 		s.splitQueue = newSplitQueue(s, s.db, s.ctx.Gossip)
+		s.mergeQueue = newMergeQueue(s, s.ctx.Gossip)
 		s.verifyQueue = newVerifyQueue(s, s.ctx.Gossip, s.ReplicaCount)
 		s.replicateQueue = newReplicateQueue(s, s.ctx.Gossip, s.allocator, s.ctx.Clock, s.ctx.AllocatorOptions)
 		s.replicaGCQueue = newReplicaGCQueue(s, s.db, s.ctx.Gossip)
 		s.raftLogQueue = newRaftLogQueue(s, s.db, s.ctx.Gossip)
-		s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.verifyQueue, s.replicateQueue, s.replicaGCQueue, s.raftLogQueue)
+		s.scanner.AddQueues(s.gcQueue, s.splitQueue, s.mergeQueue, s.verifyQueue, s.replicateQueue, s.replicaGCQueue, s.raftLogQueue)
 
 		// Add consistency check scanner.
 		s.consistencyScanner = newReplicaScanner(ctx.ConsistencyCheckInterval, 0, newStoreRangeSet(s))
@@ -866,6 +1009,9 @@ func NewStore(ctx StoreContext, eng engine.Engine, nodeDesc *roachpb.NodeDescrip
 	if ctx.TestingKnobs.DisableSplitQueue {
 		s.setSplitQueueActive(false)
 	}
+	if ctx.TestingKnobs.DisableMergeQueue {
+		s.setMergeQueueActive(false)
+	}
 	if ctx.TestingKnobs.DisableReplicateQueue {
 		s.setReplicateQueueActive(false)
 	}
@@ -882,9 +1028,11 @@ func (s *Store) String() string {
 }
 
 // DrainLeases (when called with 'true') prevents all of the Store's
-// Replicas from acquiring or extending range leases and waits until all of
-// them have expired. If an error is returned, the draining state is still
-// active, but there may be active leases held by some of the Store's Replicas.
+// Replicas from acquiring or extending range leases, transfers the active
+// ones to other replicas of their ranges and waits until none of them is held
+// by the Store anymore, either because it was transferred or because it
+// expired. If an error is returned, the draining state is still active, but
+// there may be active leases held by some of the Store's Replicas.
 // When called with 'false', returns to the normal mode of operation.
 func (s *Store) DrainLeases(drain bool) error {
 	s.drainLeases.Store(drain)
@@ -897,10 +1045,13 @@ func (s *Store) DrainLeases(drain bool) error {
 		now := s.Clock().Now()
 		newStoreRangeSet(s).Visit(func(r *Replica) bool {
 			lease, nextLease := r.getLease()
+			if lease.OwnedBy(s.StoreID()) && r.isLeaseValid(lease, now) && s.transferLeaseAway(r) {
+				return true
+			}
 			// If we own an active lease or we're trying to obtain a lease
 			// (and that request is fresh enough), wait.
-			if (lease.OwnedBy(s.StoreID()) && lease.Covers(now)) ||
-				(nextLease != nil && nextLease.Covers(now)) {
+			if (lease.OwnedBy(s.StoreID()) && r.isLeaseValid(lease, now)) ||
+				(nextLease != nil && r.isLeaseValid(nextLease, now)) {
 
 				err = fmt.Errorf("replica %s still has an active lease", r)
 			}
@@ -910,6 +1061,50 @@ func (s *Store) DrainLeases(drain bool) error {
 	})
 }
 
+// transferLeaseAway transfers the lease of the replica, held by the Store, to
+// another replica of the range which isn't on a dead store, so that the
+// clients don't have to wait for the lease to expire. The replicas satisfying
+// the lease preferences of the zone config of the range are tried first. It
+// returns whether the lease was transferred.
+func (s *Store) transferLeaseAway(r *Replica) bool {
+	desc := r.Desc()
+	candidates := desc.Voters()
+	var dead []roachpb.ReplicaDescriptor
+	if s.ctx.StorePool != nil {
+		dead = s.ctx.StorePool.deadReplicas(desc.RangeID, desc.Replicas)
+		if s.ctx.Gossip != nil {
+			if cfg, ok := s.ctx.Gossip.GetSystemConfig(); ok {
+				if zone, err := cfg.GetZoneConfigForKey(desc.StartKey); err == nil {
+					candidates = append(s.allocator.PreferredLeaseholders(zone, candidates), candidates...)
+				}
+			}
+		}
+	}
+	for _, repl := range candidates {
+		if repl.StoreID == s.StoreID() || containsReplica(dead, repl) {
+			continue
+		}
+		if err := r.AdminTransferLease(repl.StoreID); err != nil {
+			if log.V(1) {
+				log.Infof(context.TODO(), "%s: unable to transfer lease to store %d: %s",
+					r, repl.StoreID, err)
+			}
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func containsReplica(repls []roachpb.ReplicaDescriptor, repl roachpb.ReplicaDescriptor) bool {
+	for _, r := range repls {
+		if r.ReplicaID == repl.ReplicaID {
+			return true
+		}
+	}
+	return false
+}
+
 // context returns a base context to pass along with commands being executed,
 // derived from the supplied context (which is not allowed to be nil).
 func (s *Store) context(ctx context.Context) context.Context {
@@ -979,6 +1174,9 @@ func (s *Store) Start(stopper *stop.Stopper) error {
 		if q := s.splitQueue; q != nil {
 			q.Close()
 		}
+		if q := s.mergeQueue; q != nil {
+			q.Close()
+		}
 		if q := s.verifyQueue; q != nil {
 			q.Close()
 		}
@@ -1010,6 +1208,9 @@ func (s *Store) Start(stopper *stop.Stopper) error {
 		if err := s.engine.Open(); err != nil {
 			return err
 		}
+		if err := s.raftEngine.Open(); err != nil {
+			return err
+		}
 
 		// Read store ident and return a not-bootstrapped error if necessary.
 		ok, err := engine.MVCCGetProto(ctx, s.engine, keys.StoreIdentKey(), hlc.ZeroTimestamp, true, nil, &s.Ident)
@@ -1020,6 +1221,12 @@ func (s *Store) Start(stopper *stop.Stopper) error {
 		}
 	}
 
+	if s.separateRaftLog() {
+		if err := s.migrateRaftLogs(ctx); err != nil {
+			return err
+		}
+	}
+
 	// If the nodeID is 0, it has not be assigned yet.
 	if s.nodeDesc.NodeID != 0 && s.Ident.NodeID != s.nodeDesc.NodeID {
 		return errors.Errorf("node id:%d does not equal the one in node descriptor:%d", s.Ident.NodeID, s.nodeDesc.NodeID)
@@ -1084,6 +1291,9 @@ func (s *Store) Start(stopper *stop.Stopper) error {
 
 	// Start Raft processing goroutines.
 	s.ctx.Transport.Listen(s.StoreID(), s.handleRaftMessage)
+	s.ctx.Transport.ThrottleSnapshots(s.StoreID(), func(req *RaftMessageRequest) {
+		s.snapshotSendThrottle.wait(req, s.stopper.ShouldQuiesce())
+	})
 	s.processRaft()
 
 	doneUnfreezing := make(chan struct{})
@@ -1144,6 +1354,9 @@ func (s *Store) Start(stopper *stop.Stopper) error {
 	case <-time.After(10 * time.Second):
 	}
 
+	// Start compacting the spans cleared from the store.
+	s.compactor.Start(ctx, s.stopper)
+
 	// Gossip is only ever nil while bootstrapping a cluster and
 	// in unittests.
 	if s.ctx.Gossip != nil {
@@ -1300,16 +1513,21 @@ func (s *Store) maybeGossipSystemConfig() error {
 }
 
 // systemGossipUpdate is a callback for gossip updates to
-// the system config which affect range split boundaries.
+// the system config which affect range split boundaries and
+// the GC policies of the zones.
 func (s *Store) systemGossipUpdate(cfg config.SystemConfig) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	// For every range, update its MaxBytes and check if it needs to be split.
+	// A lowered GC TTL may also make it eligible for GC right away, instead of
+	// waiting for the next scanner pass.
+	now := s.ctx.Clock.Now()
 	for _, rng := range s.mu.replicas {
 		if zone, err := cfg.GetZoneConfigForKey(rng.Desc().StartKey); err == nil {
 			rng.SetMaxBytes(zone.RangeMaxBytes)
 		}
-		s.splitQueue.MaybeAdd(rng, s.ctx.Clock.Now())
+		s.splitQueue.MaybeAdd(rng, now)
+		s.gcQueue.MaybeAdd(rng, now)
 	}
 }
 
@@ -1350,6 +1568,9 @@ func (s *Store) Bootstrap(ident roachpb.StoreIdent, stopper *stop.Stopper) error
 	if err := s.engine.Open(); err != nil {
 		return err
 	}
+	if err := s.raftEngine.Open(); err != nil {
+		return err
+	}
 	s.Ident = ident
 	kvs, err := engine.Scan(s.engine,
 		engine.MakeMVCCMetadataKey(roachpb.Key(roachpb.RKeyMin)),
@@ -1554,6 +1775,60 @@ func (s *Store) Clock() *hlc.Clock { return s.ctx.Clock }
 // Engine accessor.
 func (s *Store) Engine() engine.Engine { return s.engine }
 
+// RaftEngine returns the engine holding the raft logs of the store's
+// replicas, which is the store's engine unless the logs are kept separately.
+func (s *Store) RaftEngine() engine.Engine { return s.raftEngine }
+
+// separateRaftLog returns whether the raft logs are kept in an engine of
+// their own, in which case their writes aren't atomic with the writes to the
+// rest of the store's data.
+func (s *Store) separateRaftLog() bool { return s.raftEngine != s.engine }
+
+// migrateRaftLogs moves the raft log entries left in the store's engine,
+// which were written before the logs were kept separately, to the raft
+// engine. The entries are committed to the raft engine before they're
+// removed from the store's engine, so none are lost if the process crashes
+// in between.
+func (s *Store) migrateRaftLogs(ctx context.Context) error {
+	batch := s.engine.NewBatch()
+	defer batch.Close()
+	raftBatch := s.raftEngine.NewBatch()
+	defer raftBatch.Close()
+
+	var count int
+	if err := s.engine.Iterate(
+		engine.MakeMVCCMetadataKey(keys.LocalRangeIDPrefix.AsRawKey()),
+		engine.MakeMVCCMetadataKey(keys.LocalRangeIDPrefix.PrefixEnd().AsRawKey()),
+		func(kv engine.MVCCKeyValue) (bool, error) {
+			_, _, suffix, _, err := keys.DecodeRangeIDKey(kv.Key.Key)
+			if err != nil {
+				return false, err
+			}
+			if !bytes.Equal(suffix, keys.LocalRaftLogSuffix) {
+				return false, nil
+			}
+			if err := raftBatch.Put(kv.Key, kv.Value); err != nil {
+				return false, err
+			}
+			count++
+			return false, batch.Clear(kv.Key)
+		},
+	); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	if err := raftBatch.Commit(); err != nil {
+		return err
+	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+	log.Infof(ctx, "moved %d raft log entries to the raft engine", count)
+	return nil
+}
+
 // DB accessor.
 func (s *Store) DB() *client.DB { return s.ctx.DB }
 
@@ -1869,6 +2144,10 @@ func (s *Store) removeReplicaImpl(rep *Replica, origDesc roachpb.RangeDescriptor
 		return errors.Errorf("cannot remove replica %s; replica ID has changed (%s >= %s)",
 			rep, repDesc.ReplicaID, origDesc.NextReplicaID)
 	}
+	// The replica's data either goes away or, after a merge, is served by the
+	// subsuming range, so its RangeFeeds can't continue.
+	rep.disconnectRangeFeeds(roachpb.NewError(roachpb.NewRangeNotFoundError(rep.RangeID)))
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1882,7 +2161,8 @@ func (s *Store) removeReplicaImpl(rep *Replica, origDesc roachpb.RangeDescriptor
 	// Adjust stats before calling Destroy. This can be called before or after
 	// Destroy, but this configuration helps avoid races in stat verification
 	// tests.
-	s.metrics.subtractMVCCStats(rep.GetMVCCStats())
+	ms := rep.GetMVCCStats()
+	s.metrics.subtractMVCCStats(ms)
 	s.metrics.replicaCount.Dec(1)
 
 	// TODO(bdarnell): This is fairly expensive to do under store.Mutex, but
@@ -1892,6 +2172,10 @@ func (s *Store) removeReplicaImpl(rep *Replica, origDesc roachpb.RangeDescriptor
 		if err := rep.Destroy(origDesc); err != nil {
 			return err
 		}
+		s.compactor.Suggest(roachpb.Span{
+			Key:    desc.StartKey.AsRawKey(),
+			EndKey: desc.EndKey.AsRawKey(),
+		}, ms.Total())
 	}
 
 	return nil
@@ -1908,6 +2192,13 @@ func (s *Store) destroyReplicaData(desc *roachpb.RangeDescriptor) error {
 		_ = batch.Clear(iter.Key())
 	}
 
+	if s.separateRaftLog() {
+		prefix := keys.RaftLogPrefix(desc.RangeID)
+		if err := clearRaftLogSpan(s.raftEngine, prefix, prefix.PrefixEnd()); err != nil {
+			return err
+		}
+	}
+
 	// Save a tombstone. The range cannot be re-replicated onto this
 	// node without having a replica ID of at least desc.NextReplicaID.
 	tombstoneKey := keys.RaftTombstoneKey(desc.RangeID)
@@ -2006,6 +2297,7 @@ func (s *Store) Descriptor() (*roachpb.StoreDescriptor, error) {
 		return nil, err
 	}
 	capacity.RangeCount = int32(s.ReplicaCount())
+	capacity.QueriesPerSecond = s.queriesPerSecond()
 	// Initialize the store descriptor.
 	return &roachpb.StoreDescriptor{
 		StoreID:  s.Ident.StoreID,
@@ -2051,6 +2343,80 @@ func (s *Store) ReplicaCount() int {
 	return len(s.mu.replicas)
 }
 
+// queriesPerSecond returns the rate of the batch requests served by the
+// replicas of the store.
+func (s *Store) queriesPerSecond() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var qps float64
+	for _, r := range s.mu.replicas {
+		qps += r.QueriesPerSecond()
+	}
+	return qps
+}
+
+// maxHotReplicas is the maximum number of replicas returned by
+// Store.HotReplicas.
+const maxHotReplicas = 128
+
+// HotReplicaInfo describes a replica serving requests, along with the rates
+// of the requests it serves.
+type HotReplicaInfo struct {
+	Desc *roachpb.RangeDescriptor
+	// TableID and IndexID are those of the table and index the range starts
+	// in, or zero if the range doesn't start in a table or an index.
+	TableID          uint32
+	IndexID          uint32
+	QueriesPerSecond float64
+	WritesPerSecond  float64
+}
+
+// HotReplicas returns the replicas of the store which served requests
+// recently, by decreasing rate of served requests. At most maxHotReplicas of
+// them are returned.
+func (s *Store) HotReplicas() []HotReplicaInfo {
+	var hot []HotReplicaInfo
+	newStoreRangeSet(s).Visit(func(r *Replica) bool {
+		qps := r.QueriesPerSecond()
+		if qps <= 0 {
+			return true
+		}
+		desc := r.Desc()
+		tableID, indexID := decodeTableIndex(desc.StartKey.AsRawKey())
+		hot = append(hot, HotReplicaInfo{
+			Desc:             desc,
+			TableID:          tableID,
+			IndexID:          indexID,
+			QueriesPerSecond: qps,
+			WritesPerSecond:  r.WritesPerSecond(),
+		})
+		return true
+	})
+	sort.Sort(hotReplicasByQPS(hot))
+	if len(hot) > maxHotReplicas {
+		hot = hot[:maxHotReplicas]
+	}
+	return hot
+}
+
+type hotReplicasByQPS []HotReplicaInfo
+
+func (h hotReplicasByQPS) Len() int      { return len(h) }
+func (h hotReplicasByQPS) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h hotReplicasByQPS) Less(i, j int) bool {
+	return h[i].QueriesPerSecond > h[j].QueriesPerSecond
+}
+
+// RangeFeed streams the changes to the span of the request from the replica
+// of the range addressed by the header. It returns when the feed ends.
+func (s *Store) RangeFeed(args *roachpb.RangeFeedRequest, stream rangeFeedStream) *roachpb.Error {
+	rng, err := s.GetReplica(args.RangeID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	return rng.RangeFeed(args, stream)
+}
+
 // Send fetches a range based on the header's replica, assembles method, args &
 // reply into a Raft Cmd struct and executes the command using the fetched
 // range.
@@ -2211,7 +2577,7 @@ func (s *Store) Send(ctx context.Context, ba roachpb.BatchRequest) (br *roachpb.
 		// because this is the code path with the requesting client
 		// waiting. We don't want every replica to attempt to resolve the
 		// intent independently, so we can't do it there.
-		if _, ok := pErr.GetDetail().(*roachpb.WriteIntentError); ok && pErr.Index != nil {
+		if wiErr, ok := pErr.GetDetail().(*roachpb.WriteIntentError); ok && pErr.Index != nil {
 			var pushType roachpb.PushTxnType
 			if ba.IsWrite() {
 				pushType = roachpb.PUSH_ABORT
@@ -2229,7 +2595,10 @@ func (s *Store) Send(ctx context.Context, ba roachpb.BatchRequest) (br *roachpb.
 			// after our operation started. This allows us to not have to
 			// restart for uncertainty as we come back and read.
 			h.Timestamp.Forward(now)
+			intents := wiErr.Intents
+			pushStart := timeutil.Now()
 			pErr = s.intentResolver.processWriteIntentError(ctx, pErr, args, h, pushType)
+			s.contention.record(ba.Txn, intents, timeutil.Since(pushStart))
 			// Preserve the error index.
 			pErr.Index = index
 		}
@@ -2335,6 +2704,12 @@ func (s *Store) maybeUpdateTransaction(txn *roachpb.Transaction, now hlc.Timesta
 // handleRaftMessage dispatches a raft message to the appropriate Replica. It
 // requires that s.processRaftMu and s.mu are not held.
 func (s *Store) handleRaftMessage(req *RaftMessageRequest) error {
+	if req.Message.Type == raftpb.MsgSnap {
+		// Pace the snapshots before blocking the processing of Raft. This
+		// holds up the stream carrying them, and thus their sender.
+		s.snapshotRecvThrottle.wait(req, s.stopper.ShouldQuiesce())
+	}
+
 	s.processRaftMu.Lock()
 	defer s.processRaftMu.Unlock()
 
@@ -2486,7 +2861,23 @@ func (s *Store) handleRaftMessage(req *RaftMessageRequest) error {
 			r.store.StoreID(), req.RangeID)
 	}
 
+	if req.ClosedTimestamp != nil {
+		r.maybeApplyClosedTimestamp(req.FromReplica, *req.ClosedTimestamp)
+		return nil
+	}
+
+	if req.Quiesce {
+		if req.Message.Type != raftpb.MsgHeartbeat {
+			return errors.Errorf("unexpected quiesce message %s", req.Message.Type)
+		}
+		if r.maybeQuiesceOnHeartbeat(req.Message) {
+			return nil
+		}
+	}
+
 	if err := r.withRaftGroup(func(raftGroup *raft.RawNode) error {
+		// Any other message wakes up a quiescent raft group.
+		r.unquiesceLocked()
 		return raftGroup.Step(req.Message)
 	}); err != nil {
 		return err
@@ -2601,17 +2992,28 @@ func (s *Store) processRaft() {
 				tickerStart := timeutil.Now()
 				s.processRaftMu.Lock()
 				s.mu.Lock()
-				for _, r := range s.mu.replicas {
-					if err := r.tick(); err != nil {
+				var tickedRangeIDs []roachpb.RangeID
+				var quiescentCount int64
+				for rangeID, r := range s.mu.replicas {
+					quiescent, err := r.tick()
+					if err != nil {
 						log.Error(context.TODO(), err)
 					}
+					if quiescent {
+						quiescentCount++
+					} else {
+						tickedRangeIDs = append(tickedRangeIDs, rangeID)
+					}
 				}
-				// Enqueue all ranges for readiness checks. Note that we
+				s.metrics.quiescentReplicaCount.Update(quiescentCount)
+				// Enqueue the ticked ranges for readiness checks. Quiescent
+				// ranges are left alone, which also avoids loading the raft
+				// groups of the replicas nothing happened to. Note that we
 				// could not hold the pendingRaftGroups lock during the
 				// previous loop because of lock ordering constraints with
 				// r.tick().
 				s.pendingRaftGroups.Lock()
-				for rangeID := range s.mu.replicas {
+				for _, rangeID := range tickedRangeIDs {
 					s.pendingRaftGroups.value[rangeID] = struct{}{}
 				}
 				s.pendingRaftGroups.Unlock()
@@ -2755,7 +3157,7 @@ func (s *Store) computeReplicationStatus(now int64) (
 			}
 
 			// If any replica holds the range lease, the range is available.
-			if lease, _ := rng.getLease(); lease.Covers(timestamp) {
+			if lease, _ := rng.getLease(); rng.isLeaseValid(lease, timestamp) {
 				availableRangeCount++
 			} else {
 				// If there is no range lease, then as long as more than 50%
@@ -2816,23 +3218,67 @@ func (s *Store) ComputeMetrics() error {
 	return nil
 }
 
-// ComputeStatsForKeySpan computes the aggregated MVCCStats for all replicas on
-// this store which contain any keys in the supplied range.
-func (s *Store) ComputeStatsForKeySpan(startKey, endKey roachpb.RKey) (enginepb.MVCCStats, int) {
+// ComputeStatsForKeySpan computes the aggregated MVCCStats of the keys of the
+// span [startKey, endKey) held by the replicas on this store, and returns
+// them along with the number of replicas containing keys of the span. The
+// stats of the replicas fully contained in the span are those maintained by
+// the replicas, the ones of the replicas straddling a boundary of the span are
+// computed by scanning the keys of the span they hold. If firstReplicaOnly is
+// set, only the replicas which come first in the descriptor of their range are
+// accounted for, so that summing the results of all the stores of a cluster
+// accounts for each range once instead of once per replica.
+func (s *Store) ComputeStatsForKeySpan(
+	startKey, endKey roachpb.RKey, firstReplicaOnly bool,
+) (enginepb.MVCCStats, int, error) {
 	var output enginepb.MVCCStats
 	var count int
 
+	var partial []*Replica
 	s.mu.Lock()
-	defer s.mu.Unlock()
 	s.visitReplicasLocked(startKey, endKey, func(repl *Replica) bool {
+		desc := repl.Desc()
+		if firstReplicaOnly &&
+			(len(desc.Replicas) == 0 || desc.Replicas[0].StoreID != s.StoreID()) {
+			return true
+		}
+		count++
+		if desc.StartKey.Less(startKey) || endKey.Less(desc.EndKey) {
+			partial = append(partial, repl)
+			return true
+		}
 		repl.mu.Lock()
 		output.Add(repl.mu.state.Stats)
 		repl.mu.Unlock()
-		count++
 		return true
 	})
+	s.mu.Unlock()
 
-	return output, count
+	// Scan the keys of the span held by the straddling replicas outside of the
+	// store lock.
+	if len(partial) > 0 {
+		nowNanos := s.Clock().PhysicalNow()
+		iter := s.engine.NewIterator(false)
+		defer iter.Close()
+		for _, repl := range partial {
+			rspan := repl.Desc().RSpan()
+			if rspan.Key.Less(startKey) {
+				rspan.Key = startKey
+			}
+			if endKey.Less(rspan.EndKey) {
+				rspan.EndKey = endKey
+			}
+			ms, err := iter.ComputeStats(
+				engine.MakeMVCCMetadataKey(rspan.Key.AsRawKey()),
+				engine.MakeMVCCMetadataKey(rspan.EndKey.AsRawKey()),
+				nowNanos)
+			if err != nil {
+				return enginepb.MVCCStats{}, 0, err
+			}
+			output.Add(ms)
+		}
+	}
+
+	return output, count, nil
 }
 
 // FrozenStatus returns all of the Store's Replicas which are frozen (if the
@@ -2881,6 +3327,9 @@ func (s *Store) setReplicateQueueActive(active bool) {
 func (s *Store) setSplitQueueActive(active bool) {
 	s.splitQueue.SetDisabled(!active)
 }
+func (s *Store) setMergeQueueActive(active bool) {
+	s.mergeQueue.SetDisabled(!active)
+}
 func (s *Store) setScannerActive(active bool) {
 	s.scanner.SetDisabled(!active)
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/config"
 	"github.com/cockroachdb/cockroach/gossip"
 	"github.com/cockroachdb/cockroach/roachpb"
 	"github.com/cockroachdb/cockroach/rpc"
@@ -103,7 +104,8 @@ const (
 	storeMatchAvailable                   // The store is alive, available and its attributes matched.
 )
 
-// match checks the store against the attributes and returns a storeMatch.
+// match checks the store against the constraints held by the attributes
+// and returns a storeMatch.
 func (sd *storeDetail) match(now time.Time, required roachpb.Attributes) storeMatch {
 	// The store must be alive and it must have a descriptor to be considered
 	// alive.
@@ -111,8 +113,8 @@ func (sd *storeDetail) match(now time.Time, required roachpb.Attributes) storeMa
 		return storeMatchDead
 	}
 
-	// Does the store match the attributes?
-	if !required.IsSubset(*sd.desc.CombinedAttrs()) {
+	// Does the store satisfy the constraints?
+	if !config.ConstraintsMatch(required, *sd.desc.CombinedAttrs()) {
 		return storeMatchAlive
 	}
 
@@ -430,11 +432,11 @@ func (s *stat) update(x float64) {
 	s.s = s.s + (x-oldMean)*(x-s.mean)
 }
 
-// StoreList holds a list of store descriptors and associated count and used
-// stats for those stores.
+// StoreList holds a list of store descriptors and associated count, used
+// and queries per second stats for those stores.
 type StoreList struct {
-	stores      []roachpb.StoreDescriptor
-	count, used stat
+	stores           []roachpb.StoreDescriptor
+	count, used, qps stat
 
 	// candidateCount tracks range count stats for stores that are eligible to
 	// be rebalance targets (their used capacity percentage must be lower than
@@ -445,9 +447,11 @@ type StoreList struct {
 func (sl StoreList) String() string {
 	var buf bytes.Buffer
 	fmt.Fprintf(&buf, "  candidate-count: mean=%v\n", sl.candidateCount.mean)
+	fmt.Fprintf(&buf, "  qps: mean=%.2f\n", sl.qps.mean)
 	for _, desc := range sl.stores {
-		fmt.Fprintf(&buf, "  %d: range-count=%d fraction-used=%.2f\n",
-			desc.StoreID, desc.Capacity.RangeCount, desc.Capacity.FractionUsed())
+		fmt.Fprintf(&buf, "  %d: range-count=%d fraction-used=%.2f qps=%.2f\n",
+			desc.StoreID, desc.Capacity.RangeCount, desc.Capacity.FractionUsed(),
+			desc.Capacity.QueriesPerSecond)
 	}
 	return buf.String()
 }
@@ -458,6 +462,7 @@ func (sl *StoreList) add(s roachpb.StoreDescriptor) {
 	sl.stores = append(sl.stores, s)
 	sl.count.update(float64(s.Capacity.RangeCount))
 	sl.used.update(s.Capacity.FractionUsed())
+	sl.qps.update(s.Capacity.QueriesPerSecond)
 	if s.Capacity.FractionUsed() <= maxFractionUsedThreshold {
 		sl.candidateCount.update(float64(s.Capacity.RangeCount))
 	}
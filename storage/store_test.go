@@ -188,6 +188,24 @@ func createTestStoreWithContext(t testing.TB, ctx *StoreContext) (
 }
 
 // TestStoreInitAndBootstrap verifies store initialization and bootstrap.
+// TestNewRaftConfig verifies that pre-vote and check-quorum are enabled
+// unless the store context disables them.
+func TestNewRaftConfig(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	ctx := TestStoreContext()
+	if cfg := newRaftConfig(nil, 1, 0, ctx, nil); !cfg.PreVote || !cfg.CheckQuorum {
+		t.Errorf("expected pre-vote and check-quorum to be enabled, got %t and %t",
+			cfg.PreVote, cfg.CheckQuorum)
+	}
+	ctx.RaftDisablePreVote = true
+	ctx.RaftDisableCheckQuorum = true
+	if cfg := newRaftConfig(nil, 1, 0, ctx, nil); cfg.PreVote || cfg.CheckQuorum {
+		t.Errorf("expected pre-vote and check-quorum to be disabled, got %t and %t",
+			cfg.PreVote, cfg.CheckQuorum)
+	}
+}
+
 func TestStoreInitAndBootstrap(t *testing.T) {
 	defer leaktest.AfterTest(t)()
 	ctx := TestStoreContext()
@@ -2149,3 +2167,39 @@ func TestStoreGCThreshold(t *testing.T) {
 
 	assertThreshold(threshold)
 }
+
+// TestStoreHotReplicas verifies that the replicas serving requests are
+// reported along with the rates of the requests they serve.
+func TestStoreHotReplicas(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+	store, _, stopper := createTestStore(t)
+	defer stopper.Stop()
+
+	rng := store.LookupReplica(roachpb.RKey("a"), nil)
+	for i := 0; i < 10; i++ {
+		pArgs := putArgs([]byte("a"), []byte("value"))
+		if _, pErr := client.SendWrapped(store.testSender(), nil, &pArgs); pErr != nil {
+			t.Fatal(pErr)
+		}
+	}
+
+	// The rates are only updated every second.
+	util.SucceedsSoon(t, func() error {
+		hot := store.HotReplicas()
+		for i := 1; i < len(hot); i++ {
+			if hot[i-1].QueriesPerSecond < hot[i].QueriesPerSecond {
+				t.Fatalf("hot replicas not sorted by QPS: %+v", hot)
+			}
+		}
+		for _, info := range hot {
+			if info.Desc.RangeID != rng.RangeID {
+				continue
+			}
+			if info.QueriesPerSecond <= 0 || info.WritesPerSecond <= 0 {
+				return errors.Errorf("expected positive rates, got %+v", info)
+			}
+			return nil
+		}
+		return errors.Errorf("range %d not reported as hot: %+v", rng.RangeID, hot)
+	})
+}
@@ -132,6 +132,18 @@ func (ls *Stores) VisitStores(visitor func(s *Store) error) error {
 	return nil
 }
 
+// RangeFeed streams the changes to the span of the request from the store
+// addressed by the header.
+func (ls *Stores) RangeFeed(
+	args *roachpb.RangeFeedRequest, stream roachpb.Internal_RangeFeedServer,
+) *roachpb.Error {
+	store, err := ls.GetStore(args.Replica.StoreID)
+	if err != nil {
+		return roachpb.NewError(err)
+	}
+	return store.RangeFeed(args, stream)
+}
+
 // Send implements the client.Sender interface. The store is looked up from the
 // store map if specified by the request; otherwise, the command is being
 // executed locally, and the replica is determined via lookup through each
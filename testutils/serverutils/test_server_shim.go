@@ -81,6 +81,10 @@ type TestServerInterface interface {
 	// server's SQL server. Runs in O(# of metrics) time, which is fine for test
 	// code.
 	MustGetSQLNetworkCounter(name string) int64
+	// MustGetSQLHistogramCount returns the number of values recorded in a
+	// histogram metric from the server's SQL Executor. The name must include
+	// the histogram's time window suffix (e.g. "sql.latency-1m").
+	MustGetSQLHistogramCount(name string) int64
 	// WriteSummaries records summaries of time-series data, which is required for
 	// any tests that query server stats.
 	WriteSummaries() error
@@ -66,6 +66,7 @@ import NodeGraphs from "./containers/nodeGraphs";
 import NodeLogs from "./containers/nodeLogs";
 import Raft from "./containers/raft";
 import RaftRanges from "./containers/raftRanges";
+import DistSQLPlan from "./containers/distsqlPlan";
 
 ReactDOM.render(
   <Provider store={store}>
@@ -103,6 +104,7 @@ ReactDOM.render(
           <IndexRedirect to="ranges" />
           <Route path="ranges" component={ RaftRanges } />
         </Route>
+        <Route path="distsql-plan" component={ DistSQLPlan } />
       </Route>
     </Router>
   </Provider>,
@@ -0,0 +1,191 @@
+import * as _ from "lodash";
+import * as React from "react";
+import { IInjectedProps } from "react-router";
+
+/**
+ * DiagramCell is a box of the diagram: a processor core, an input
+ * synchronizer or an output router.
+ */
+interface DiagramCell {
+  title: string;
+  details?: string[];
+}
+
+interface DiagramProcessor {
+  nodeIdx: number;
+  inputs?: DiagramCell[];
+  core: DiagramCell;
+  outputs?: DiagramCell[];
+}
+
+/**
+ * DiagramEdge is a stream between two processors. sourceOutput and destInput
+ * are 1-based indexes into the outputs and inputs of the processors; 0 refers
+ * to the core.
+ */
+interface DiagramEdge {
+  sourceProc: number;
+  sourceOutput: number;
+  destProc: number;
+  destInput: number;
+}
+
+/**
+ * Diagram is the physical plan diagram generated by EXPLAIN (DISTSQL); see
+ * sql/distsql/flow_diagram.go.
+ */
+interface Diagram {
+  nodeNames: string[];
+  processors: DiagramProcessor[];
+  edges: DiagramEdge[];
+}
+
+const NODE_WIDTH = 280;
+const NODE_PADDING = 20;
+const LINE_HEIGHT = 16;
+const CELL_SPACING = 10;
+const PROCESSOR_SPACING = 50;
+
+interface Point {
+  x: number;
+  y: number;
+}
+
+interface CellLayout {
+  cell: DiagramCell;
+  x: number;
+  y: number;
+  width: number;
+  height: number;
+}
+
+function cellHeight(cell: DiagramCell) {
+  return LINE_HEIGHT * (1 + (cell.details ? cell.details.length : 0)) + 8;
+}
+
+/**
+ * decodeDiagram decodes the diagram encoded in the URL of the page by
+ * distsql.PlanDiagramURL: a JSON object in unpadded URL-safe base64.
+ */
+export function decodeDiagram(encoded: string): Diagram {
+  let base64 = encoded.replace(/-/g, "+").replace(/_/g, "/");
+  while (base64.length % 4 !== 0) {
+    base64 += "=";
+  }
+  return JSON.parse(atob(base64));
+}
+
+/**
+ * DistSQLPlanDiagram renders a physical plan diagram. The processors are
+ * drawn in a column per node, with their input synchronizers above and their
+ * output routers below them.
+ */
+class DistSQLPlanDiagram extends React.Component<{ diagram: Diagram }, {}> {
+  render() {
+    let diagram = this.props.diagram;
+    let nodeHeights = _.map(diagram.nodeNames, () => NODE_PADDING + LINE_HEIGHT);
+    let inputs: CellLayout[][] = [];
+    let cores: CellLayout[] = [];
+    let outputs: CellLayout[][] = [];
+
+    // Lay out the cells of each processor in the column of its node.
+    _.each(diagram.processors, (p, i) => {
+      let x = p.nodeIdx * (NODE_WIDTH + NODE_PADDING) + 2 * NODE_PADDING;
+      let width = NODE_WIDTH - 2 * NODE_PADDING;
+      let y = nodeHeights[p.nodeIdx] + PROCESSOR_SPACING / 2;
+      let layoutRow = (cells: DiagramCell[]) => {
+        if (!cells || cells.length === 0) {
+          return [];
+        }
+        let cellWidth = (width - CELL_SPACING * (cells.length - 1)) / cells.length;
+        let height = _.max(_.map(cells, cellHeight));
+        let row = _.map(cells, (cell, j) => ({
+          cell, x: x + j * (cellWidth + CELL_SPACING), y, width: cellWidth, height,
+        }));
+        y += height + CELL_SPACING;
+        return row;
+      };
+      inputs[i] = layoutRow(p.inputs);
+      cores[i] = layoutRow([p.core])[0];
+      outputs[i] = layoutRow(p.outputs);
+      nodeHeights[p.nodeIdx] = y + PROCESSOR_SPACING / 2;
+    });
+
+    let totalHeight = _.max(nodeHeights) + NODE_PADDING;
+    let totalWidth = diagram.nodeNames.length * (NODE_WIDTH + NODE_PADDING) + NODE_PADDING;
+
+    let bottom = (c: CellLayout): Point => ({ x: c.x + c.width / 2, y: c.y + c.height });
+    let top = (c: CellLayout): Point => ({ x: c.x + c.width / 2, y: c.y });
+
+    return <svg width={totalWidth} height={totalHeight} className="distsql-plan">
+      <defs>
+        <marker id="arrow" markerWidth="10" markerHeight="10" refX="9" refY="3" orient="auto">
+          <path d="M0,0 L0,6 L9,3 z" fill="#666" />
+        </marker>
+      </defs>
+      {
+        _.map(diagram.nodeNames, (name, i) => {
+          let x = i * (NODE_WIDTH + NODE_PADDING) + NODE_PADDING;
+          return <g key={"node" + i}>
+            <rect x={x} y={NODE_PADDING / 2} width={NODE_WIDTH} height={totalHeight - NODE_PADDING}
+                  fill="#f4f6f8" stroke="#ccc" />
+            <text x={x + NODE_WIDTH / 2} y={NODE_PADDING / 2 + LINE_HEIGHT} textAnchor="middle" fontWeight="bold">
+              {name}
+            </text>
+          </g>;
+        })
+      }
+      {
+        _.map(_.flatten([_.flatten(inputs), cores, _.flatten(outputs)]), (c: CellLayout, i) =>
+          <g key={"cell" + i}>
+            <rect x={c.x} y={c.y} width={c.width} height={c.height} rx="4" fill="#fff" stroke="#666" />
+            <text x={c.x + c.width / 2} y={c.y + LINE_HEIGHT} textAnchor="middle" fontWeight="bold">
+              {c.cell.title}
+            </text>
+            {
+              _.map(c.cell.details, (d, j) =>
+                <text key={j} x={c.x + c.width / 2} y={c.y + LINE_HEIGHT * (j + 2)} textAnchor="middle" fontSize="11">
+                  {d}
+                </text>)
+            }
+          </g>)
+      }
+      {
+        _.map(diagram.edges, (e, i) => {
+          let source = e.sourceOutput === 0 ? cores[e.sourceProc] : outputs[e.sourceProc][e.sourceOutput - 1];
+          let dest = e.destInput === 0 ? cores[e.destProc] : inputs[e.destProc][e.destInput - 1];
+          let from = bottom(source);
+          let to = top(dest);
+          return <line key={"edge" + i} x1={from.x} y1={from.y} x2={to.x} y2={to.y}
+                       stroke="#666" markerEnd="url(#arrow)" />;
+        })
+      }
+    </svg>;
+  }
+}
+
+/**
+ * Renders the page showing the physical plan diagram returned by EXPLAIN
+ * (DISTSQL), which is encoded in the "plan" query parameter.
+ */
+export default class extends React.Component<IInjectedProps, {}> {
+  static title() {
+    return <h2>DistSQL Plan</h2>;
+  }
+
+  render() {
+    let encoded: string = (this.props.location.query as any).plan;
+    if (!encoded) {
+      return <div className="section">No plan to display.</div>;
+    }
+    let diagram: Diagram;
+    try {
+      diagram = decodeDiagram(encoded);
+    } catch (e) {
+      return <div className="section">Invalid plan: {e.message}</div>;
+    }
+    return <div className="section">
+      <DistSQLPlanDiagram diagram={diagram} />
+    </div>;
+  }
+}
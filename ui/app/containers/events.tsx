@@ -36,6 +36,12 @@ export function getEventInfo(e: Event) {
     case "drop_table":
       content = <span>User {info.User} <strong>dropped table</strong> {info.TableName}</span>;
       break;
+    case "create_view":
+      content = <span>User {info.User} <strong>created view</strong> {info.ViewName}</span>;
+      break;
+    case "drop_view":
+      content = <span>User {info.User} <strong>dropped view</strong> {info.ViewName}</span>;
+      break;
     case "node_join":
       content = <span>Node {targetId} <strong>joined the cluster</strong></span>;
       break;
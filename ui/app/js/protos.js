@@ -1817,6 +1817,15 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                     "options": {
                                         "(gogoproto.nullable)": false
                                     }
+                                },
+                                {
+                                    "rule": "repeated",
+                                    "type": "IndexStats",
+                                    "name": "index_stats",
+                                    "id": 6,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
                                 }
                             ],
                             "messages": [
@@ -1839,6 +1848,32 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                             "id": 2
                                         }
                                     ]
+                                },
+                                {
+                                    "name": "IndexStats",
+                                    "fields": [
+                                        {
+                                            "rule": "optional",
+                                            "type": "string",
+                                            "name": "index_name",
+                                            "id": 1
+                                        },
+                                        {
+                                            "rule": "optional",
+                                            "type": "int64",
+                                            "name": "range_count",
+                                            "id": 2
+                                        },
+                                        {
+                                            "rule": "optional",
+                                            "type": "storage.engine.enginepb.MVCCStats",
+                                            "name": "stats",
+                                            "id": 3,
+                                            "options": {
+                                                "(gogoproto.nullable)": false
+                                            }
+                                        }
+                                    ]
                                 }
                             ]
                         },
@@ -2147,6 +2182,43 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                 }
                             ]
                         },
+                        {
+                            "name": "CompactRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "store_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "StoreID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.StoreID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "bytes",
+                                    "name": "start_key",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.Key"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "bytes",
+                                    "name": "end_key",
+                                    "id": 3,
+                                    "options": {
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.Key"
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "CompactResponse",
+                            "fields": []
+                        },
                         {
                             "name": "DetailsRequest",
                             "fields": [
@@ -2246,6 +2318,12 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                     "options": {
                                         "(gogoproto.nullable)": false
                                     }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "bool",
+                                    "name": "inconsistent",
+                                    "id": 5
                                 }
                             ]
                         },
@@ -2387,6 +2465,183 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                 }
                             ]
                         },
+                        {
+                            "name": "StatementsRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "node_id",
+                                    "id": 1
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ActiveQuery",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "ID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "node_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "NodeID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.NodeID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "username",
+                                    "id": 3
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "client_address",
+                                    "id": 4
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "sql",
+                                    "id": 5,
+                                    "options": {
+                                        "(gogoproto.customname)": "SQL"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "start",
+                                    "id": 6
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "Phase",
+                                    "name": "phase",
+                                    "id": 7
+                                }
+                            ],
+                            "enums": [
+                                {
+                                    "name": "Phase",
+                                    "values": [
+                                        {
+                                            "name": "PREPARING",
+                                            "id": 0
+                                        },
+                                        {
+                                            "name": "EXECUTING",
+                                            "id": 1
+                                        }
+                                    ]
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ListQueriesRequest",
+                            "fields": []
+                        },
+                        {
+                            "name": "ListQueriesError",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "node_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "NodeID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.NodeID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "message",
+                                    "id": 2
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ListQueriesResponse",
+                            "options": {
+                                "(gogoproto.nullable)": false
+                            },
+                            "fields": [
+                                {
+                                    "rule": "repeated",
+                                    "type": "ActiveQuery",
+                                    "name": "queries",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                },
+                                {
+                                    "rule": "repeated",
+                                    "type": "ListQueriesError",
+                                    "name": "errors",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "CancelQueryRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "node_id",
+                                    "id": 1
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "query_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "QueryID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "username",
+                                    "id": 3
+                                }
+                            ]
+                        },
+                        {
+                            "name": "CancelQueryResponse",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "bool",
+                                    "name": "canceled",
+                                    "id": 1
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "error",
+                                    "id": 2
+                                }
+                            ]
+                        },
                         {
                             "name": "RaftRangeNode",
                             "fields": [
@@ -2504,6 +2759,12 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                     "options": {
                                         "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.RKey"
                                     }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "bool",
+                                    "name": "first_replica_only",
+                                    "id": 4
                                 }
                             ]
                         },
@@ -2543,6 +2804,314 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                     "id": 2
                                 }
                             ]
+                        },
+                        {
+                            "name": "StoresRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "node_id",
+                                    "id": 1
+                                }
+                            ]
+                        },
+                        {
+                            "name": "EncryptionKeyUsage",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "key_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "KeyID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "files",
+                                    "id": 2
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "bytes",
+                                    "id": 3
+                                }
+                            ]
+                        },
+                        {
+                            "name": "StoreDetails",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "store_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "StoreID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.StoreID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "encryption_active_key_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "EncryptionActiveKeyID"
+                                    }
+                                },
+                                {
+                                    "rule": "repeated",
+                                    "type": "EncryptionKeyUsage",
+                                    "name": "encryption_keys",
+                                    "id": 3,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "StoresResponse",
+                            "fields": [
+                                {
+                                    "rule": "repeated",
+                                    "type": "StoreDetails",
+                                    "name": "stores",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ContentionEventsRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "node_id",
+                                    "id": 1
+                                }
+                            ]
+                        },
+                        {
+                            "name": "IndexContention",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "table_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "TableID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "index_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "IndexID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "num_contention_events",
+                                    "id": 3
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "cumulative_contention_nanos",
+                                    "id": 4
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ContentionEvent",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "key",
+                                    "id": 1
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "txn_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "TxnID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "blocking_txn_id",
+                                    "id": 3,
+                                    "options": {
+                                        "(gogoproto.customname)": "BlockingTxnID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "table_id",
+                                    "id": 4,
+                                    "options": {
+                                        "(gogoproto.customname)": "TableID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "index_id",
+                                    "id": 5,
+                                    "options": {
+                                        "(gogoproto.customname)": "IndexID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "duration_nanos",
+                                    "id": 6
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "store_id",
+                                    "id": 7,
+                                    "options": {
+                                        "(gogoproto.customname)": "StoreID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.StoreID"
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "ContentionEventsResponse",
+                            "fields": [
+                                {
+                                    "rule": "repeated",
+                                    "type": "IndexContention",
+                                    "name": "indexes",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                },
+                                {
+                                    "rule": "repeated",
+                                    "type": "ContentionEvent",
+                                    "name": "events",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                }
+                            ]
+                        },
+                        {
+                            "name": "HotRangesRequest",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "node_id",
+                                    "id": 1
+                                }
+                            ]
+                        },
+                        {
+                            "name": "HotRange",
+                            "fields": [
+                                {
+                                    "rule": "optional",
+                                    "type": "int64",
+                                    "name": "range_id",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.customname)": "RangeID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.RangeID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "int32",
+                                    "name": "store_id",
+                                    "id": 2,
+                                    "options": {
+                                        "(gogoproto.customname)": "StoreID",
+                                        "(gogoproto.casttype)": "github.com/cockroachdb/cockroach/roachpb.StoreID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "start_key",
+                                    "id": 3
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "string",
+                                    "name": "end_key",
+                                    "id": 4
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "table_id",
+                                    "id": 5,
+                                    "options": {
+                                        "(gogoproto.customname)": "TableID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "uint32",
+                                    "name": "index_id",
+                                    "id": 6,
+                                    "options": {
+                                        "(gogoproto.customname)": "IndexID"
+                                    }
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "double",
+                                    "name": "queries_per_second",
+                                    "id": 7
+                                },
+                                {
+                                    "rule": "optional",
+                                    "type": "double",
+                                    "name": "writes_per_second",
+                                    "id": 8
+                                }
+                            ]
+                        },
+                        {
+                            "name": "HotRangesResponse",
+                            "fields": [
+                                {
+                                    "rule": "repeated",
+                                    "type": "HotRange",
+                                    "name": "ranges",
+                                    "id": 1,
+                                    "options": {
+                                        "(gogoproto.nullable)": false
+                                    }
+                                }
+                            ]
                         }
                     ],
                     "enums": [
@@ -2657,6 +3226,14 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                         "(google.api.http).get": "/_admin/v1/health"
                                     }
                                 },
+                                "Compact": {
+                                    "request": "CompactRequest",
+                                    "response": "CompactResponse",
+                                    "options": {
+                                        "(google.api.http).post": "/_admin/v1/compact",
+                                        "(google.api.http).body": "*"
+                                    }
+                                },
                                 "Drain": {
                                     "request": "DrainRequest",
                                     "response": "DrainResponse",
@@ -2715,6 +3292,27 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                         "(google.api.http).get": "/_status/ranges/{node_id}"
                                     }
                                 },
+                                "Stores": {
+                                    "request": "StoresRequest",
+                                    "response": "StoresResponse",
+                                    "options": {
+                                        "(google.api.http).get": "/_status/stores/{node_id}"
+                                    }
+                                },
+                                "ContentionEvents": {
+                                    "request": "ContentionEventsRequest",
+                                    "response": "ContentionEventsResponse",
+                                    "options": {
+                                        "(google.api.http).get": "/_status/contention/{node_id}"
+                                    }
+                                },
+                                "HotRanges": {
+                                    "request": "HotRangesRequest",
+                                    "response": "HotRangesResponse",
+                                    "options": {
+                                        "(google.api.http).get": "/_status/hotranges/{node_id}"
+                                    }
+                                },
                                 "Gossip": {
                                     "request": "GossipRequest",
                                     "response": "gossip.InfoStatus",
@@ -2730,6 +3328,28 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                         "(google.api.http).body": "*"
                                     }
                                 },
+                                "ListLocalQueries": {
+                                    "request": "ListQueriesRequest",
+                                    "response": "ListQueriesResponse",
+                                    "options": {
+                                        "(google.api.http).get": "/_status/local_queries"
+                                    }
+                                },
+                                "ListQueries": {
+                                    "request": "ListQueriesRequest",
+                                    "response": "ListQueriesResponse",
+                                    "options": {
+                                        "(google.api.http).get": "/_status/queries"
+                                    }
+                                },
+                                "CancelQuery": {
+                                    "request": "CancelQueryRequest",
+                                    "response": "CancelQueryResponse",
+                                    "options": {
+                                        "(google.api.http).post": "/_status/cancel_query/{node_id}",
+                                        "(google.api.http).body": "*"
+                                    }
+                                },
                                 "Stacks": {
                                     "request": "StacksRequest",
                                     "response": "JSONResponse",
@@ -2740,6 +3360,11 @@ module.exports = require("protobufjs").newBuilder({})['import']({
                                     "response": "JSONResponse",
                                     "options": {}
                                 },
+                                "Statements": {
+                                    "request": "StatementsRequest",
+                                    "response": "JSONResponse",
+                                    "options": {}
+                                },
                                 "Logs": {
                                     "request": "LogsRequest",
                                     "response": "JSONResponse",
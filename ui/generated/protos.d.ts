@@ -2503,6 +2503,8 @@ export interface serverpbBuilder {
 	HealthResponse: serverpb.HealthResponseBuilder;
 	ClusterFreezeRequest: serverpb.ClusterFreezeRequestBuilder;
 	ClusterFreezeResponse: serverpb.ClusterFreezeResponseBuilder;
+	CompactRequest: serverpb.CompactRequestBuilder;
+	CompactResponse: serverpb.CompactResponseBuilder;
 	DetailsRequest: serverpb.DetailsRequestBuilder;
 	DetailsResponse: serverpb.DetailsResponseBuilder;
 	NodesRequest: serverpb.NodesRequestBuilder;
@@ -2518,6 +2520,13 @@ export interface serverpbBuilder {
 	LogFileRequest: serverpb.LogFileRequestBuilder;
 	StacksRequest: serverpb.StacksRequestBuilder;
 	MetricsRequest: serverpb.MetricsRequestBuilder;
+	StatementsRequest: serverpb.StatementsRequestBuilder;
+	ActiveQuery: serverpb.ActiveQueryBuilder;
+	ListQueriesRequest: serverpb.ListQueriesRequestBuilder;
+	ListQueriesError: serverpb.ListQueriesErrorBuilder;
+	ListQueriesResponse: serverpb.ListQueriesResponseBuilder;
+	CancelQueryRequest: serverpb.CancelQueryRequestBuilder;
+	CancelQueryResponse: serverpb.CancelQueryResponseBuilder;
 	RaftRangeNode: serverpb.RaftRangeNodeBuilder;
 	RaftRangeError: serverpb.RaftRangeErrorBuilder;
 	RaftRangeStatus: serverpb.RaftRangeStatusBuilder;
@@ -2526,6 +2535,17 @@ export interface serverpbBuilder {
 	SpanStatsRequest: serverpb.SpanStatsRequestBuilder;
 	SpanStatsResponse: serverpb.SpanStatsResponseBuilder;
 	PrettySpan: serverpb.PrettySpanBuilder;
+	StoresRequest: serverpb.StoresRequestBuilder;
+	EncryptionKeyUsage: serverpb.EncryptionKeyUsageBuilder;
+	StoreDetails: serverpb.StoreDetailsBuilder;
+	StoresResponse: serverpb.StoresResponseBuilder;
+	ContentionEventsRequest: serverpb.ContentionEventsRequestBuilder;
+	IndexContention: serverpb.IndexContentionBuilder;
+	ContentionEvent: serverpb.ContentionEventBuilder;
+	ContentionEventsResponse: serverpb.ContentionEventsResponseBuilder;
+	HotRangesRequest: serverpb.HotRangesRequestBuilder;
+	HotRange: serverpb.HotRangeBuilder;
+	HotRangesResponse: serverpb.HotRangesResponseBuilder;
 	ZoneConfigurationLevel: serverpb.ZoneConfigurationLevel;
 	DrainMode: serverpb.DrainMode;
 	
@@ -3146,6 +3166,15 @@ getMissingNodes?() : TableStatsResponse.MissingNode[];
 
 
 
+index_stats?: TableStatsResponse.IndexStats[];
+		
+
+getIndexStats?() : TableStatsResponse.IndexStats[];
+		setIndexStats?(indexStats : TableStatsResponse.IndexStats[]): void;
+		
+
+
+
 }
 
 	export interface TableStatsResponseMessage extends TableStatsResponse {
@@ -3162,6 +3191,7 @@ export interface TableStatsResponseBuilder {
 	decode(buffer: ByteBuffer) : TableStatsResponseMessage;
 	decode64(buffer: string) : TableStatsResponseMessage;
 	MissingNode: TableStatsResponse.MissingNodeBuilder;
+	IndexStats: TableStatsResponse.IndexStatsBuilder;
 	
 }
 
@@ -3212,6 +3242,60 @@ export interface MissingNodeBuilder {
 }
 
 
+declare module cockroach.server.serverpb.TableStatsResponse {
+
+	export interface IndexStats {
+
+		
+
+index_name?: string;
+		
+
+getIndexName?() : string;
+		setIndexName?(indexName : string): void;
+		
+
+
+
+range_count?: Long;
+		
+
+getRangeCount?() : Long;
+		setRangeCount?(rangeCount : Long): void;
+		
+
+
+
+stats?: storage.engine.enginepb.MVCCStats;
+		
+
+getStats?() : storage.engine.enginepb.MVCCStats;
+		setStats?(stats : storage.engine.enginepb.MVCCStats): void;
+		
+
+
+
+}
+
+	export interface IndexStatsMessage extends IndexStats {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface IndexStatsBuilder {
+	new(data?: IndexStats): IndexStatsMessage;
+	decode(buffer: ArrayBuffer) : IndexStatsMessage;
+	decode(buffer: ByteBuffer) : IndexStatsMessage;
+	decode64(buffer: string) : IndexStatsMessage;
+	
+}
+
+}
+
+
 
 declare module cockroach.server.serverpb {
 
@@ -4037,6 +4121,87 @@ export interface ClusterFreezeResponseBuilder {
 }
 
 
+declare module cockroach.server.serverpb {
+
+	export interface CompactRequest {
+
+		
+
+store_id?: number;
+		
+
+getStoreId?() : number;
+		setStoreId?(storeId : number): void;
+		
+
+
+
+start_key?: ByteBuffer;
+		
+
+getStartKey?() : ByteBuffer;
+		setStartKey?(startKey : ByteBuffer): void;
+		
+
+
+
+end_key?: ByteBuffer;
+		
+
+getEndKey?() : ByteBuffer;
+		setEndKey?(endKey : ByteBuffer): void;
+		
+
+
+
+}
+
+	export interface CompactRequestMessage extends CompactRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface CompactRequestBuilder {
+	new(data?: CompactRequest): CompactRequestMessage;
+	decode(buffer: ArrayBuffer) : CompactRequestMessage;
+	decode(buffer: ByteBuffer) : CompactRequestMessage;
+	decode64(buffer: string) : CompactRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface CompactResponse {
+
+		
+
+}
+
+	export interface CompactResponseMessage extends CompactResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface CompactResponseBuilder {
+	new(data?: CompactResponse): CompactResponseMessage;
+	decode(buffer: ArrayBuffer) : CompactResponseMessage;
+	decode(buffer: ByteBuffer) : CompactResponseMessage;
+	decode64(buffer: string) : CompactResponseMessage;
+	
+}
+
+}
+
+
 declare module cockroach.server.serverpb {
 
 	export interface DetailsRequest {
@@ -4259,6 +4424,15 @@ getState?() : storage.storagebase.RangeInfo;
 
 
 
+inconsistent?: boolean;
+		
+
+getInconsistent?() : boolean;
+		setInconsistent?(inconsistent : boolean): void;
+		
+
+
+
 }
 
 	export interface RangeInfoMessage extends RangeInfo {
@@ -4660,31 +4834,22 @@ export interface MetricsRequestBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface RaftRangeNode {
-
-		
-
-node_id?: number;
-		
+	export interface StatementsRequest {
 
-getNodeId?() : number;
-		setNodeId?(nodeId : number): void;
 		
 
-
-
-range?: RangeInfo;
+node_id?: string;
 		
 
-getRange?() : RangeInfo;
-		setRange?(range : RangeInfo): void;
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
 		
 
 
 
 }
 
-	export interface RaftRangeNodeMessage extends RaftRangeNode {
+	export interface StatementsRequestMessage extends StatementsRequest {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4692,11 +4857,11 @@ getRange?() : RangeInfo;
 	toString(): string;
 }
 
-export interface RaftRangeNodeBuilder {
-	new(data?: RaftRangeNode): RaftRangeNodeMessage;
-	decode(buffer: ArrayBuffer) : RaftRangeNodeMessage;
-	decode(buffer: ByteBuffer) : RaftRangeNodeMessage;
-	decode64(buffer: string) : RaftRangeNodeMessage;
+export interface StatementsRequestBuilder {
+	new(data?: StatementsRequest): StatementsRequestMessage;
+	decode(buffer: ArrayBuffer) : StatementsRequestMessage;
+	decode(buffer: ByteBuffer) : StatementsRequestMessage;
+	decode64(buffer: string) : StatementsRequestMessage;
 	
 }
 
@@ -4705,76 +4870,82 @@ export interface RaftRangeNodeBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface RaftRangeError {
+	export interface ActiveQuery {
 
 		
 
-message?: string;
+id?: string;
 		
 
-getMessage?() : string;
-		setMessage?(message : string): void;
+getId?() : string;
+		setId?(id : string): void;
 		
 
 
 
-}
 
-	export interface RaftRangeErrorMessage extends RaftRangeError {
-	toArrayBuffer(): ArrayBuffer;
-	encode(): ByteBuffer;
-	encodeJSON(): string;
-	toBase64(): string;
-	toString(): string;
-}
+node_id?: number;
+		
 
-export interface RaftRangeErrorBuilder {
-	new(data?: RaftRangeError): RaftRangeErrorMessage;
-	decode(buffer: ArrayBuffer) : RaftRangeErrorMessage;
-	decode(buffer: ByteBuffer) : RaftRangeErrorMessage;
-	decode64(buffer: string) : RaftRangeErrorMessage;
-	
-}
+getNodeId?() : number;
+		setNodeId?(nodeId : number): void;
+		
 
-}
 
 
-declare module cockroach.server.serverpb {
 
-	export interface RaftRangeStatus {
+username?: string;
+		
 
+getUsername?() : string;
+		setUsername?(username : string): void;
 		
 
-range_id?: Long;
+
+
+
+client_address?: string;
 		
 
-getRangeId?() : Long;
-		setRangeId?(rangeId : Long): void;
+getClientAddress?() : string;
+		setClientAddress?(clientAddress : string): void;
 		
 
 
 
-errors?: RaftRangeError[];
+
+sql?: string;
 		
 
-getErrors?() : RaftRangeError[];
-		setErrors?(errors : RaftRangeError[]): void;
+getSql?() : string;
+		setSql?(sql : string): void;
 		
 
 
 
-nodes?: RaftRangeNode[];
+
+start?: Long;
 		
 
-getNodes?() : RaftRangeNode[];
-		setNodes?(nodes : RaftRangeNode[]): void;
+getStart?() : Long;
+		setStart?(start : Long): void;
+		
+
+
+
+
+phase?: Phase;
+		
+
+getPhase?() : Phase;
+		setPhase?(phase : Phase): void;
 		
 
 
 
 }
 
-	export interface RaftRangeStatusMessage extends RaftRangeStatus {
+	export interface ActiveQueryMessage extends ActiveQuery {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4782,26 +4953,36 @@ getNodes?() : RaftRangeNode[];
 	toString(): string;
 }
 
-export interface RaftRangeStatusBuilder {
-	new(data?: RaftRangeStatus): RaftRangeStatusMessage;
-	decode(buffer: ArrayBuffer) : RaftRangeStatusMessage;
-	decode(buffer: ByteBuffer) : RaftRangeStatusMessage;
-	decode64(buffer: string) : RaftRangeStatusMessage;
+export interface ActiveQueryBuilder {
+	new(data?: ActiveQuery): ActiveQueryMessage;
+	decode(buffer: ArrayBuffer) : ActiveQueryMessage;
+	decode(buffer: ByteBuffer) : ActiveQueryMessage;
+	decode64(buffer: string) : ActiveQueryMessage;
+	Phase: ActiveQuery.Phase;
 	
 }
 
 }
 
 
+declare module cockroach.server.serverpb.ActiveQuery {
+	export const enum Phase {
+		PREPARING = 0,
+		EXECUTING = 1,
+		
+}
+}
+
+
 declare module cockroach.server.serverpb {
 
-	export interface RaftDebugRequest {
+	export interface ListQueriesRequest {
 
 		
 
 }
 
-	export interface RaftDebugRequestMessage extends RaftDebugRequest {
+	export interface ListQueriesRequestMessage extends ListQueriesRequest {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4809,11 +4990,11 @@ declare module cockroach.server.serverpb {
 	toString(): string;
 }
 
-export interface RaftDebugRequestBuilder {
-	new(data?: RaftDebugRequest): RaftDebugRequestMessage;
-	decode(buffer: ArrayBuffer) : RaftDebugRequestMessage;
-	decode(buffer: ByteBuffer) : RaftDebugRequestMessage;
-	decode64(buffer: string) : RaftDebugRequestMessage;
+export interface ListQueriesRequestBuilder {
+	new(data?: ListQueriesRequest): ListQueriesRequestMessage;
+	decode(buffer: ArrayBuffer) : ListQueriesRequestMessage;
+	decode(buffer: ByteBuffer) : ListQueriesRequestMessage;
+	decode64(buffer: string) : ListQueriesRequestMessage;
 	
 }
 
@@ -4822,22 +5003,32 @@ export interface RaftDebugRequestBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface RaftDebugResponse {
+	export interface ListQueriesError {
 
 		
 
-ranges?: ProtoBufMap<Long, RaftRangeStatus>;
+node_id?: number;
 		
 
-getRanges?() : ProtoBufMap<Long, RaftRangeStatus>;
-		setRanges?(ranges : ProtoBufMap<Long, RaftRangeStatus>): void;
+getNodeId?() : number;
+		setNodeId?(nodeId : number): void;
+		
+
+
+
+
+message?: string;
+		
+
+getMessage?() : string;
+		setMessage?(message : string): void;
 		
 
 
 
 }
 
-	export interface RaftDebugResponseMessage extends RaftDebugResponse {
+	export interface ListQueriesErrorMessage extends ListQueriesError {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4845,11 +5036,11 @@ getRanges?() : ProtoBufMap<Long, RaftRangeStatus>;
 	toString(): string;
 }
 
-export interface RaftDebugResponseBuilder {
-	new(data?: RaftDebugResponse): RaftDebugResponseMessage;
-	decode(buffer: ArrayBuffer) : RaftDebugResponseMessage;
-	decode(buffer: ByteBuffer) : RaftDebugResponseMessage;
-	decode64(buffer: string) : RaftDebugResponseMessage;
+export interface ListQueriesErrorBuilder {
+	new(data?: ListQueriesError): ListQueriesErrorMessage;
+	decode(buffer: ArrayBuffer) : ListQueriesErrorMessage;
+	decode(buffer: ByteBuffer) : ListQueriesErrorMessage;
+	decode64(buffer: string) : ListQueriesErrorMessage;
 	
 }
 
@@ -4858,40 +5049,32 @@ export interface RaftDebugResponseBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface SpanStatsRequest {
-
-		
+	export interface ListQueriesResponse {
 
-node_id?: string;
 		
 
-getNodeId?() : string;
-		setNodeId?(nodeId : string): void;
+queries?: ActiveQuery[];
 		
 
-
-
-start_key?: ByteBuffer;
+getQueries?() : ActiveQuery[];
+		setQueries?(queries : ActiveQuery[]): void;
 		
 
-getStartKey?() : ByteBuffer;
-		setStartKey?(startKey : ByteBuffer): void;
-		
 
 
 
-end_key?: ByteBuffer;
+errors?: ListQueriesError[];
 		
 
-getEndKey?() : ByteBuffer;
-		setEndKey?(endKey : ByteBuffer): void;
+getErrors?() : ListQueriesError[];
+		setErrors?(errors : ListQueriesError[]): void;
 		
 
 
 
 }
 
-	export interface SpanStatsRequestMessage extends SpanStatsRequest {
+	export interface ListQueriesResponseMessage extends ListQueriesResponse {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4899,8 +5082,371 @@ getEndKey?() : ByteBuffer;
 	toString(): string;
 }
 
-export interface SpanStatsRequestBuilder {
-	new(data?: SpanStatsRequest): SpanStatsRequestMessage;
+export interface ListQueriesResponseBuilder {
+	new(data?: ListQueriesResponse): ListQueriesResponseMessage;
+	decode(buffer: ArrayBuffer) : ListQueriesResponseMessage;
+	decode(buffer: ByteBuffer) : ListQueriesResponseMessage;
+	decode64(buffer: string) : ListQueriesResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface CancelQueryRequest {
+
+		
+
+node_id?: string;
+		
+
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
+		
+
+
+
+
+query_id?: string;
+		
+
+getQueryId?() : string;
+		setQueryId?(queryId : string): void;
+		
+
+
+
+
+username?: string;
+		
+
+getUsername?() : string;
+		setUsername?(username : string): void;
+		
+
+
+
+}
+
+	export interface CancelQueryRequestMessage extends CancelQueryRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface CancelQueryRequestBuilder {
+	new(data?: CancelQueryRequest): CancelQueryRequestMessage;
+	decode(buffer: ArrayBuffer) : CancelQueryRequestMessage;
+	decode(buffer: ByteBuffer) : CancelQueryRequestMessage;
+	decode64(buffer: string) : CancelQueryRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface CancelQueryResponse {
+
+		
+
+canceled?: boolean;
+		
+
+getCanceled?() : boolean;
+		setCanceled?(canceled : boolean): void;
+		
+
+
+
+
+error?: string;
+		
+
+getError?() : string;
+		setError?(error : string): void;
+		
+
+
+
+}
+
+	export interface CancelQueryResponseMessage extends CancelQueryResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface CancelQueryResponseBuilder {
+	new(data?: CancelQueryResponse): CancelQueryResponseMessage;
+	decode(buffer: ArrayBuffer) : CancelQueryResponseMessage;
+	decode(buffer: ByteBuffer) : CancelQueryResponseMessage;
+	decode64(buffer: string) : CancelQueryResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface RaftRangeNode {
+
+		
+
+node_id?: number;
+		
+
+getNodeId?() : number;
+		setNodeId?(nodeId : number): void;
+		
+
+
+
+range?: RangeInfo;
+		
+
+getRange?() : RangeInfo;
+		setRange?(range : RangeInfo): void;
+		
+
+
+
+}
+
+	export interface RaftRangeNodeMessage extends RaftRangeNode {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface RaftRangeNodeBuilder {
+	new(data?: RaftRangeNode): RaftRangeNodeMessage;
+	decode(buffer: ArrayBuffer) : RaftRangeNodeMessage;
+	decode(buffer: ByteBuffer) : RaftRangeNodeMessage;
+	decode64(buffer: string) : RaftRangeNodeMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface RaftRangeError {
+
+		
+
+message?: string;
+		
+
+getMessage?() : string;
+		setMessage?(message : string): void;
+		
+
+
+
+}
+
+	export interface RaftRangeErrorMessage extends RaftRangeError {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface RaftRangeErrorBuilder {
+	new(data?: RaftRangeError): RaftRangeErrorMessage;
+	decode(buffer: ArrayBuffer) : RaftRangeErrorMessage;
+	decode(buffer: ByteBuffer) : RaftRangeErrorMessage;
+	decode64(buffer: string) : RaftRangeErrorMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface RaftRangeStatus {
+
+		
+
+range_id?: Long;
+		
+
+getRangeId?() : Long;
+		setRangeId?(rangeId : Long): void;
+		
+
+
+
+errors?: RaftRangeError[];
+		
+
+getErrors?() : RaftRangeError[];
+		setErrors?(errors : RaftRangeError[]): void;
+		
+
+
+
+nodes?: RaftRangeNode[];
+		
+
+getNodes?() : RaftRangeNode[];
+		setNodes?(nodes : RaftRangeNode[]): void;
+		
+
+
+
+}
+
+	export interface RaftRangeStatusMessage extends RaftRangeStatus {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface RaftRangeStatusBuilder {
+	new(data?: RaftRangeStatus): RaftRangeStatusMessage;
+	decode(buffer: ArrayBuffer) : RaftRangeStatusMessage;
+	decode(buffer: ByteBuffer) : RaftRangeStatusMessage;
+	decode64(buffer: string) : RaftRangeStatusMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface RaftDebugRequest {
+
+		
+
+}
+
+	export interface RaftDebugRequestMessage extends RaftDebugRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface RaftDebugRequestBuilder {
+	new(data?: RaftDebugRequest): RaftDebugRequestMessage;
+	decode(buffer: ArrayBuffer) : RaftDebugRequestMessage;
+	decode(buffer: ByteBuffer) : RaftDebugRequestMessage;
+	decode64(buffer: string) : RaftDebugRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface RaftDebugResponse {
+
+		
+
+ranges?: ProtoBufMap<Long, RaftRangeStatus>;
+		
+
+getRanges?() : ProtoBufMap<Long, RaftRangeStatus>;
+		setRanges?(ranges : ProtoBufMap<Long, RaftRangeStatus>): void;
+		
+
+
+
+}
+
+	export interface RaftDebugResponseMessage extends RaftDebugResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface RaftDebugResponseBuilder {
+	new(data?: RaftDebugResponse): RaftDebugResponseMessage;
+	decode(buffer: ArrayBuffer) : RaftDebugResponseMessage;
+	decode(buffer: ByteBuffer) : RaftDebugResponseMessage;
+	decode64(buffer: string) : RaftDebugResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface SpanStatsRequest {
+
+		
+
+node_id?: string;
+		
+
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
+		
+
+
+
+start_key?: ByteBuffer;
+		
+
+getStartKey?() : ByteBuffer;
+		setStartKey?(startKey : ByteBuffer): void;
+		
+
+
+
+end_key?: ByteBuffer;
+		
+
+getEndKey?() : ByteBuffer;
+		setEndKey?(endKey : ByteBuffer): void;
+		
+
+
+
+first_replica_only?: boolean;
+		
+
+getFirstReplicaOnly?() : boolean;
+		setFirstReplicaOnly?(firstReplicaOnly : boolean): void;
+		
+
+
+
+}
+
+	export interface SpanStatsRequestMessage extends SpanStatsRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface SpanStatsRequestBuilder {
+	new(data?: SpanStatsRequest): SpanStatsRequestMessage;
 	decode(buffer: ArrayBuffer) : SpanStatsRequestMessage;
 	decode(buffer: ByteBuffer) : SpanStatsRequestMessage;
 	decode64(buffer: string) : SpanStatsRequestMessage;
@@ -4912,31 +5458,355 @@ export interface SpanStatsRequestBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface SpanStatsResponse {
+	export interface SpanStatsResponse {
+
+		
+
+range_count?: number;
+		
+
+getRangeCount?() : number;
+		setRangeCount?(rangeCount : number): void;
+		
+
+
+
+total_stats?: storage.engine.enginepb.MVCCStats;
+		
+
+getTotalStats?() : storage.engine.enginepb.MVCCStats;
+		setTotalStats?(totalStats : storage.engine.enginepb.MVCCStats): void;
+		
+
+
+
+}
+
+	export interface SpanStatsResponseMessage extends SpanStatsResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface SpanStatsResponseBuilder {
+	new(data?: SpanStatsResponse): SpanStatsResponseMessage;
+	decode(buffer: ArrayBuffer) : SpanStatsResponseMessage;
+	decode(buffer: ByteBuffer) : SpanStatsResponseMessage;
+	decode64(buffer: string) : SpanStatsResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface PrettySpan {
+
+		
+
+start_key?: string;
+		
+
+getStartKey?() : string;
+		setStartKey?(startKey : string): void;
+		
+
+
+
+end_key?: string;
+		
+
+getEndKey?() : string;
+		setEndKey?(endKey : string): void;
+		
+
+
+
+}
+
+	export interface PrettySpanMessage extends PrettySpan {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface PrettySpanBuilder {
+	new(data?: PrettySpan): PrettySpanMessage;
+	decode(buffer: ArrayBuffer) : PrettySpanMessage;
+	decode(buffer: ByteBuffer) : PrettySpanMessage;
+	decode64(buffer: string) : PrettySpanMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface StoresRequest {
+
+		
+
+node_id?: string;
+		
+
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
+		
+
+
+
+}
+
+	export interface StoresRequestMessage extends StoresRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface StoresRequestBuilder {
+	new(data?: StoresRequest): StoresRequestMessage;
+	decode(buffer: ArrayBuffer) : StoresRequestMessage;
+	decode(buffer: ByteBuffer) : StoresRequestMessage;
+	decode64(buffer: string) : StoresRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface EncryptionKeyUsage {
+
+		
+
+key_id?: string;
+		
+
+getKeyId?() : string;
+		setKeyId?(keyId : string): void;
+		
+
+
+
+files?: Long;
+		
+
+getFiles?() : Long;
+		setFiles?(files : Long): void;
+		
+
+
+
+bytes?: Long;
+		
+
+getBytes?() : Long;
+		setBytes?(bytes : Long): void;
+		
+
+
+
+}
+
+	export interface EncryptionKeyUsageMessage extends EncryptionKeyUsage {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface EncryptionKeyUsageBuilder {
+	new(data?: EncryptionKeyUsage): EncryptionKeyUsageMessage;
+	decode(buffer: ArrayBuffer) : EncryptionKeyUsageMessage;
+	decode(buffer: ByteBuffer) : EncryptionKeyUsageMessage;
+	decode64(buffer: string) : EncryptionKeyUsageMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface StoreDetails {
+
+		
+
+store_id?: number;
+		
+
+getStoreId?() : number;
+		setStoreId?(storeId : number): void;
+		
+
+
+
+encryption_active_key_id?: string;
+		
+
+getEncryptionActiveKeyId?() : string;
+		setEncryptionActiveKeyId?(encryptionActiveKeyId : string): void;
+		
+
+
+
+encryption_keys?: EncryptionKeyUsage[];
+		
+
+getEncryptionKeys?() : EncryptionKeyUsage[];
+		setEncryptionKeys?(encryptionKeys : EncryptionKeyUsage[]): void;
+		
+
+
+
+}
+
+	export interface StoreDetailsMessage extends StoreDetails {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface StoreDetailsBuilder {
+	new(data?: StoreDetails): StoreDetailsMessage;
+	decode(buffer: ArrayBuffer) : StoreDetailsMessage;
+	decode(buffer: ByteBuffer) : StoreDetailsMessage;
+	decode64(buffer: string) : StoreDetailsMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface StoresResponse {
+
+		
+
+stores?: StoreDetails[];
+		
+
+getStores?() : StoreDetails[];
+		setStores?(stores : StoreDetails[]): void;
+		
+
+
+
+}
+
+	export interface StoresResponseMessage extends StoresResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface StoresResponseBuilder {
+	new(data?: StoresResponse): StoresResponseMessage;
+	decode(buffer: ArrayBuffer) : StoresResponseMessage;
+	decode(buffer: ByteBuffer) : StoresResponseMessage;
+	decode64(buffer: string) : StoresResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface ContentionEventsRequest {
 
 		
 
-range_count?: number;
+node_id?: string;
 		
 
-getRangeCount?() : number;
-		setRangeCount?(rangeCount : number): void;
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
 		
 
 
 
-total_stats?: storage.engine.enginepb.MVCCStats;
+}
+
+	export interface ContentionEventsRequestMessage extends ContentionEventsRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface ContentionEventsRequestBuilder {
+	new(data?: ContentionEventsRequest): ContentionEventsRequestMessage;
+	decode(buffer: ArrayBuffer) : ContentionEventsRequestMessage;
+	decode(buffer: ByteBuffer) : ContentionEventsRequestMessage;
+	decode64(buffer: string) : ContentionEventsRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface IndexContention {
+
 		
 
-getTotalStats?() : storage.engine.enginepb.MVCCStats;
-		setTotalStats?(totalStats : storage.engine.enginepb.MVCCStats): void;
+table_id?: number;
+		
+
+getTableId?() : number;
+		setTableId?(tableId : number): void;
+		
+
+
+
+index_id?: number;
+		
+
+getIndexId?() : number;
+		setIndexId?(indexId : number): void;
+		
+
+
+
+num_contention_events?: Long;
+		
+
+getNumContentionEvents?() : Long;
+		setNumContentionEvents?(numContentionEvents : Long): void;
+		
+
+
+
+cumulative_contention_nanos?: Long;
+		
+
+getCumulativeContentionNanos?() : Long;
+		setCumulativeContentionNanos?(cumulativeContentionNanos : Long): void;
 		
 
 
 
 }
 
-	export interface SpanStatsResponseMessage extends SpanStatsResponse {
+	export interface IndexContentionMessage extends IndexContention {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4944,11 +5814,11 @@ getTotalStats?() : storage.engine.enginepb.MVCCStats;
 	toString(): string;
 }
 
-export interface SpanStatsResponseBuilder {
-	new(data?: SpanStatsResponse): SpanStatsResponseMessage;
-	decode(buffer: ArrayBuffer) : SpanStatsResponseMessage;
-	decode(buffer: ByteBuffer) : SpanStatsResponseMessage;
-	decode64(buffer: string) : SpanStatsResponseMessage;
+export interface IndexContentionBuilder {
+	new(data?: IndexContention): IndexContentionMessage;
+	decode(buffer: ArrayBuffer) : IndexContentionMessage;
+	decode(buffer: ByteBuffer) : IndexContentionMessage;
+	decode64(buffer: string) : IndexContentionMessage;
 	
 }
 
@@ -4957,10 +5827,199 @@ export interface SpanStatsResponseBuilder {
 
 declare module cockroach.server.serverpb {
 
-	export interface PrettySpan {
+	export interface ContentionEvent {
+
+		
+
+key?: string;
+		
+
+getKey?() : string;
+		setKey?(key : string): void;
+		
+
+
+
+txn_id?: string;
+		
+
+getTxnId?() : string;
+		setTxnId?(txnId : string): void;
+		
+
+
+
+blocking_txn_id?: string;
+		
+
+getBlockingTxnId?() : string;
+		setBlockingTxnId?(blockingTxnId : string): void;
+		
+
+
+
+table_id?: number;
+		
+
+getTableId?() : number;
+		setTableId?(tableId : number): void;
+		
+
+
+
+index_id?: number;
+		
+
+getIndexId?() : number;
+		setIndexId?(indexId : number): void;
+		
+
+
+
+duration_nanos?: Long;
+		
+
+getDurationNanos?() : Long;
+		setDurationNanos?(durationNanos : Long): void;
+		
+
+
+
+store_id?: number;
+		
+
+getStoreId?() : number;
+		setStoreId?(storeId : number): void;
+		
+
+
+
+}
+
+	export interface ContentionEventMessage extends ContentionEvent {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface ContentionEventBuilder {
+	new(data?: ContentionEvent): ContentionEventMessage;
+	decode(buffer: ArrayBuffer) : ContentionEventMessage;
+	decode(buffer: ByteBuffer) : ContentionEventMessage;
+	decode64(buffer: string) : ContentionEventMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface ContentionEventsResponse {
+
+		
+
+indexes?: IndexContention[];
+		
+
+getIndexes?() : IndexContention[];
+		setIndexes?(indexes : IndexContention[]): void;
+		
+
 
+
+events?: ContentionEvent[];
+		
+
+getEvents?() : ContentionEvent[];
+		setEvents?(events : ContentionEvent[]): void;
+		
+
+
+
+}
+
+	export interface ContentionEventsResponseMessage extends ContentionEventsResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface ContentionEventsResponseBuilder {
+	new(data?: ContentionEventsResponse): ContentionEventsResponseMessage;
+	decode(buffer: ArrayBuffer) : ContentionEventsResponseMessage;
+	decode(buffer: ByteBuffer) : ContentionEventsResponseMessage;
+	decode64(buffer: string) : ContentionEventsResponseMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface HotRangesRequest {
+
+		
+
+node_id?: string;
+		
+
+getNodeId?() : string;
+		setNodeId?(nodeId : string): void;
+		
+
+
+
+}
+
+	export interface HotRangesRequestMessage extends HotRangesRequest {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface HotRangesRequestBuilder {
+	new(data?: HotRangesRequest): HotRangesRequestMessage;
+	decode(buffer: ArrayBuffer) : HotRangesRequestMessage;
+	decode(buffer: ByteBuffer) : HotRangesRequestMessage;
+	decode64(buffer: string) : HotRangesRequestMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface HotRange {
+
+		
+
+range_id?: Long;
+		
+
+getRangeId?() : Long;
+		setRangeId?(rangeId : Long): void;
+		
+
+
+
+store_id?: number;
+		
+
+getStoreId?() : number;
+		setStoreId?(storeId : number): void;
 		
 
+
+
 start_key?: string;
 		
 
@@ -4979,9 +6038,45 @@ getEndKey?() : string;
 
 
 
+table_id?: number;
+		
+
+getTableId?() : number;
+		setTableId?(tableId : number): void;
+		
+
+
+
+index_id?: number;
+		
+
+getIndexId?() : number;
+		setIndexId?(indexId : number): void;
+		
+
+
+
+queries_per_second?: number;
+		
+
+getQueriesPerSecond?() : number;
+		setQueriesPerSecond?(queriesPerSecond : number): void;
+		
+
+
+
+writes_per_second?: number;
+		
+
+getWritesPerSecond?() : number;
+		setWritesPerSecond?(writesPerSecond : number): void;
+		
+
+
+
 }
 
-	export interface PrettySpanMessage extends PrettySpan {
+	export interface HotRangeMessage extends HotRange {
 	toArrayBuffer(): ArrayBuffer;
 	encode(): ByteBuffer;
 	encodeJSON(): string;
@@ -4989,11 +6084,47 @@ getEndKey?() : string;
 	toString(): string;
 }
 
-export interface PrettySpanBuilder {
-	new(data?: PrettySpan): PrettySpanMessage;
-	decode(buffer: ArrayBuffer) : PrettySpanMessage;
-	decode(buffer: ByteBuffer) : PrettySpanMessage;
-	decode64(buffer: string) : PrettySpanMessage;
+export interface HotRangeBuilder {
+	new(data?: HotRange): HotRangeMessage;
+	decode(buffer: ArrayBuffer) : HotRangeMessage;
+	decode(buffer: ByteBuffer) : HotRangeMessage;
+	decode64(buffer: string) : HotRangeMessage;
+	
+}
+
+}
+
+
+declare module cockroach.server.serverpb {
+
+	export interface HotRangesResponse {
+
+		
+
+ranges?: HotRange[];
+		
+
+getRanges?() : HotRange[];
+		setRanges?(ranges : HotRange[]): void;
+		
+
+
+
+}
+
+	export interface HotRangesResponseMessage extends HotRangesResponse {
+	toArrayBuffer(): ArrayBuffer;
+	encode(): ByteBuffer;
+	encodeJSON(): string;
+	toBase64(): string;
+	toString(): string;
+}
+
+export interface HotRangesResponseBuilder {
+	new(data?: HotRangesResponse): HotRangesResponseMessage;
+	decode(buffer: ArrayBuffer) : HotRangesResponseMessage;
+	decode(buffer: ByteBuffer) : HotRangesResponseMessage;
+	decode64(buffer: string) : HotRangesResponseMessage;
 	
 }
 
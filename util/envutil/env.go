@@ -170,6 +170,20 @@ func EnvOrDefaultInt64(name string, value int64) int64 {
 	return value
 }
 
+// EnvOrDefaultFloat returns the value set by the specified environment
+// variable, if any, otherwise the specified default value.
+func EnvOrDefaultFloat(name string, value float64) float64 {
+	if str, present := getEnv(name); present {
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			log.Errorf(context.TODO(), "error parsing %s: %s", VarName(name), err)
+			return value
+		}
+		return v
+	}
+	return value
+}
+
 // EnvOrDefaultBytes returns the value set by the specified environment
 // variable, if any, otherwise the specified default value.
 func EnvOrDefaultBytes(name string, value int64) int64 {
@@ -0,0 +1,261 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package extstorage reads and writes files in the external storage
+// services used by bulk operations such as IMPORT, EXPORT and BACKUP.
+package extstorage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/pkg/errors"
+)
+
+// The URI query parameters used to pass credentials.
+const (
+	s3AccessKeyParam = "AWS_ACCESS_KEY_ID"
+	s3SecretParam    = "AWS_SECRET_ACCESS_KEY"
+	s3RegionParam    = "AWS_REGION"
+	gsTokenParam     = "BEARER_TOKEN"
+)
+
+// External storage is named by URIs. The supported schemes are:
+//
+//   http://host/path, https://host/path
+//     Files are read with GET and written with PUT requests.
+//   s3://bucket/key?AWS_ACCESS_KEY_ID=...&AWS_SECRET_ACCESS_KEY=...
+//     Requests are signed with the given credentials. AWS_REGION defaults to
+//     us-east-1.
+//   gs://bucket/object[?BEARER_TOKEN=...]
+//     Requests go through the Google Cloud Storage XML API, authorized with
+//     the given OAuth token if any.
+//   nodelocal:///path
+//     The local filesystem of whichever node accesses the file. Files read
+//     this way must be present at the same path on every node.
+
+// Open opens the file named by uri for reading.
+func Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "nodelocal" {
+		p, err := nodelocalPath(u)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(p)
+	}
+	req, err := makeExternalRequest("GET", u, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := doExternalRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Write writes content to the file named by uri, replacing it if
+// it exists.
+func Write(ctx context.Context, uri string, content []byte) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "nodelocal" {
+		p, err := nodelocalPath(u)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(p, content, 0644)
+	}
+	req, err := makeExternalRequest("PUT", u, content, time.Now())
+	if err != nil {
+		return err
+	}
+	resp, err := doExternalRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Join returns the URI of the file with the given name in the
+// directory named by uri.
+func Join(uri, name string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(u.Path, name)
+	return u.String(), nil
+}
+
+func nodelocalPath(u *url.URL) (string, error) {
+	if u.Host != "" {
+		return "", errors.Errorf("nodelocal URIs must not specify a host: %s", u)
+	}
+	return u.Path, nil
+}
+
+// makeExternalRequest returns the HTTP request for the given method on the
+// file named by u, which must not be a nodelocal URI.
+func makeExternalRequest(method string, u *url.URL, body []byte, now time.Time) (*http.Request, error) {
+	var req *http.Request
+	var err error
+	switch u.Scheme {
+	case "http", "https":
+		req, err = http.NewRequest(method, u.String(), bytes.NewReader(body))
+	case "s3":
+		req, err = makeS3Request(method, u, body, now)
+	case "gs":
+		req, err = http.NewRequest(method,
+			"https://storage.googleapis.com/"+u.Host+s3URIEncode(u.Path), bytes.NewReader(body))
+		if err == nil {
+			if token := u.Query().Get(gsTokenParam); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+	default:
+		return nil, errors.Errorf("unsupported storage scheme %q in %s", u.Scheme, u)
+	}
+	return req, err
+}
+
+func doExternalRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
+	req.Cancel = ctx.Done()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, errors.Errorf("error accessing %s: %s", req.URL.Path, resp.Status)
+	}
+	return resp, nil
+}
+
+// makeS3Request returns a request for the object named by an s3:// URI,
+// signed with AWS Signature Version 4 using the credentials in its query
+// parameters.
+func makeS3Request(method string, u *url.URL, body []byte, now time.Time) (*http.Request, error) {
+	q := u.Query()
+	accessKey, secret := q.Get(s3AccessKeyParam), q.Get(s3SecretParam)
+	if accessKey == "" || secret == "" {
+		return nil, errors.Errorf("s3 URIs must specify %s and %s", s3AccessKeyParam, s3SecretParam)
+	}
+	region := q.Get(s3RegionParam)
+	if region == "" {
+		region = "us-east-1"
+	}
+	host := u.Host + ".s3.amazonaws.com"
+	objPath := s3URIEncode(u.Path)
+	if objPath == "" {
+		objPath = "/"
+	}
+	req, err := http.NewRequest(method, "https://"+host+objPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	bodyHash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(bodyHash[:])
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders string
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method, objPath, "", canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+	signature := hex.EncodeToString(
+		hmacSHA256(awsSigningKey(secret, date, region, "s3"), stringToSign))
+
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+// awsSigningKey derives the AWS Signature Version 4 signing key.
+func awsSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3URIEncode escapes every byte of an object path other than the unreserved
+// characters and '/', as required by the canonical request.
+func s3URIEncode(path string) string {
+	var buf []byte
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			buf = append(buf, c)
+		default:
+			buf = append(buf, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(buf)
+}
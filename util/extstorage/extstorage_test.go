@@ -0,0 +1,86 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package extstorage
+
+import (
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	"github.com/cockroachdb/cockroach/util/leaktest"
+)
+
+func TestAWSSigningKey(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	// Example from the AWS Signature Version 4 documentation.
+	key := awsSigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	const expected = "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if actual := hex.EncodeToString(key); actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestMakeS3Request(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	u, err := url.Parse("s3://bucket/dir/a b.csv?AWS_ACCESS_KEY_ID=id&AWS_SECRET_ACCESS_KEY=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2016, 12, 1, 10, 0, 0, 0, time.UTC)
+	req, err := makeS3Request("GET", u, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://bucket.s3.amazonaws.com/dir/a%20b.csv"; req.URL.String() != expected {
+		t.Errorf("expected %s, got %s", expected, req.URL)
+	}
+	if d := req.Header.Get("x-amz-date"); d != "20161201T100000Z" {
+		t.Errorf("unexpected x-amz-date %s", d)
+	}
+	auth := req.Header.Get("Authorization")
+	const prefix = "AWS4-HMAC-SHA256 Credential=id/20161201/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(auth, prefix) {
+		t.Errorf("unexpected Authorization header %s", auth)
+	}
+
+	u.RawQuery = "AWS_ACCESS_KEY_ID=id"
+	if _, err := makeS3Request("GET", u, nil, now); !testutils.IsError(err, "must specify") {
+		t.Errorf("expected missing credentials error, got %v", err)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	for _, tc := range []struct {
+		uri, expected string
+	}{
+		{"ftp://host/file", `unsupported storage scheme "ftp"`},
+		{"nodelocal://host/file", "must not specify a host"},
+		{"nodelocal:///does/not/exist", "no such file"},
+	} {
+		if _, err := Open(context.Background(), tc.uri); !testutils.IsError(err, tc.expected) {
+			t.Errorf("%s: expected %q, got %v", tc.uri, tc.expected, err)
+		}
+	}
+}
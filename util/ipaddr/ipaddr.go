@@ -0,0 +1,177 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package ipaddr implements IPv4 and IPv6 host addresses with network masks,
+// as stored in INET columns.
+package ipaddr
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Family is the address family of an IPAddr. Its values are ordered so that
+// IPv4 addresses sort before IPv6 addresses, as in PostgreSQL.
+type Family byte
+
+const (
+	// IPv4Family is the family of IPv4 addresses.
+	IPv4Family Family = 4
+	// IPv6Family is the family of IPv6 addresses.
+	IPv6Family Family = 6
+)
+
+// len returns the number of bytes in addresses of the family.
+func (f Family) len() int {
+	if f == IPv4Family {
+		return net.IPv4len
+	}
+	return net.IPv6len
+}
+
+// IPAddr is an IPv4 or IPv6 host address together with the length of its
+// network mask. As in PostgreSQL's inet type, the bits of the address outside
+// of the network are preserved.
+type IPAddr struct {
+	Family Family
+	// Addr is net.IPv4len bytes long for IPv4 addresses and net.IPv6len
+	// bytes long for IPv6 addresses.
+	Addr net.IP
+	// Mask is the number of leading bits of Addr that make up the network.
+	Mask byte
+}
+
+// Parse parses an address of the form "addr" or "addr/masklen". If the mask
+// length is omitted, the address is a single host.
+func Parse(s string) (IPAddr, error) {
+	addr, mask, hasMask := s, "", false
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		addr, mask, hasMask = s[:i], s[i+1:], true
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return IPAddr{}, errors.Errorf("invalid IP address: %q", addr)
+	}
+	var r IPAddr
+	// IPv4-mapped IPv6 addresses such as ::ffff:1.2.3.4 remain IPv6.
+	if strings.IndexByte(addr, ':') < 0 {
+		r = IPAddr{Family: IPv4Family, Addr: ip.To4()}
+	} else {
+		r = IPAddr{Family: IPv6Family, Addr: ip.To16()}
+	}
+	bits := 8 * r.Family.len()
+	r.Mask = byte(bits)
+	if hasMask {
+		m, err := strconv.Atoi(mask)
+		if err != nil || m < 0 || m > bits {
+			return IPAddr{}, errors.Errorf("invalid mask length: %q", mask)
+		}
+		r.Mask = byte(m)
+	}
+	return r, nil
+}
+
+// String returns the address in the format accepted by Parse. The mask
+// length is omitted for single hosts.
+func (ip IPAddr) String() string {
+	if int(ip.Mask) == 8*ip.Family.len() {
+		return ip.Host()
+	}
+	return ip.Host() + "/" + strconv.Itoa(int(ip.Mask))
+}
+
+// Host returns the address without its mask length.
+func (ip IPAddr) Host() string {
+	if ip.Family == IPv6Family && ip.Addr.To4() != nil {
+		// net.IP formats IPv4-mapped IPv6 addresses in dotted decimal.
+		return "::ffff:" + ip.Addr.String()
+	}
+	return ip.Addr.String()
+}
+
+// network returns the address with the bits outside of the network cleared.
+func (ip IPAddr) network() net.IP {
+	return ip.Addr.Mask(net.CIDRMask(int(ip.Mask), 8*ip.Family.len()))
+}
+
+// Compare returns -1, 0 or 1 depending on whether ip sorts before, equal to
+// or after o. Addresses are ordered by family, then network, then mask
+// length and finally by the full address.
+func (ip IPAddr) Compare(o IPAddr) int {
+	if ip.Family != o.Family {
+		if ip.Family < o.Family {
+			return -1
+		}
+		return 1
+	}
+	if c := bytes.Compare(ip.network(), o.network()); c != 0 {
+		return c
+	}
+	if ip.Mask != o.Mask {
+		if ip.Mask < o.Mask {
+			return -1
+		}
+		return 1
+	}
+	return bytes.Compare(ip.Addr, o.Addr)
+}
+
+// Contains returns true if o lies within the network of ip and is not equal
+// to it, i.e. if ip >> o.
+func (ip IPAddr) Contains(o IPAddr) bool {
+	if ip.Family != o.Family || ip.Mask >= o.Mask {
+		return false
+	}
+	return ip.network().Equal(o.Addr.Mask(net.CIDRMask(int(ip.Mask), 8*ip.Family.len())))
+}
+
+// ContainedBy returns true if ip lies within the network of o and is not
+// equal to it, i.e. if ip << o.
+func (ip IPAddr) ContainedBy(o IPAddr) bool {
+	return o.Contains(ip)
+}
+
+// Encode appends an encoding of ip to appendTo. Encodings compare bytewise
+// in the same order as Compare, so they can be used in keys.
+func (ip IPAddr) Encode(appendTo []byte) []byte {
+	appendTo = append(appendTo, byte(ip.Family))
+	appendTo = append(appendTo, ip.network()...)
+	appendTo = append(appendTo, ip.Mask)
+	return append(appendTo, ip.Addr...)
+}
+
+// Decode decodes an address encoded by Encode, returning the remaining bytes.
+func Decode(b []byte) (IPAddr, []byte, error) {
+	if len(b) == 0 {
+		return IPAddr{}, nil, errors.Errorf("insufficient bytes to decode IP address")
+	}
+	ip := IPAddr{Family: Family(b[0])}
+	if ip.Family != IPv4Family && ip.Family != IPv6Family {
+		return IPAddr{}, nil, errors.Errorf("unknown IP address family: %d", ip.Family)
+	}
+	n := ip.Family.len()
+	if len(b) < 2+2*n {
+		return IPAddr{}, nil, errors.Errorf("insufficient bytes to decode IP address")
+	}
+	ip.Mask = b[1+n]
+	if int(ip.Mask) > 8*n {
+		return IPAddr{}, nil, errors.Errorf("invalid mask length: %d", ip.Mask)
+	}
+	ip.Addr = append(net.IP(nil), b[2+n:2+2*n]...)
+	return ip, b[2+2*n:], nil
+}
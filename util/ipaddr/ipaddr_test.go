@@ -0,0 +1,152 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package ipaddr
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+)
+
+func mustParse(t *testing.T, s string) IPAddr {
+	ip, err := Parse(s)
+	if err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	return ip
+}
+
+func TestParseFormat(t *testing.T) {
+	testData := []struct {
+		in       string
+		expected string
+		family   Family
+		mask     byte
+	}{
+		{`192.168.0.1`, `192.168.0.1`, IPv4Family, 32},
+		{`192.168.0.1/32`, `192.168.0.1`, IPv4Family, 32},
+		{`192.168.0.1/24`, `192.168.0.1/24`, IPv4Family, 24},
+		{`10.0.0.0/8`, `10.0.0.0/8`, IPv4Family, 8},
+		{`0.0.0.0/0`, `0.0.0.0/0`, IPv4Family, 0},
+		{`::1`, `::1`, IPv6Family, 128},
+		{`2001:DB8::/32`, `2001:db8::/32`, IPv6Family, 32},
+		{`::ffff:1.2.3.4`, `::ffff:1.2.3.4`, IPv6Family, 128},
+	}
+	for _, d := range testData {
+		ip := mustParse(t, d.in)
+		if s := ip.String(); s != d.expected {
+			t.Errorf("%s: expected %s, but found %s", d.in, d.expected, s)
+		}
+		if ip.Family != d.family || ip.Mask != d.mask {
+			t.Errorf("%s: expected family %d and mask %d, but found %d and %d",
+				d.in, d.family, d.mask, ip.Family, ip.Mask)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testData := []struct {
+		in       string
+		expected string
+	}{
+		{``, `invalid IP address`},
+		{`1.2.3`, `invalid IP address`},
+		{`1.2.3.4/`, `invalid mask length`},
+		{`1.2.3.4/33`, `invalid mask length`},
+		{`::1/129`, `invalid mask length`},
+		{`::1/-1`, `invalid mask length`},
+	}
+	for _, d := range testData {
+		_, err := Parse(d.in)
+		if !testutils.IsError(err, d.expected) {
+			t.Errorf("%s: expected %q, but found %v", d.in, d.expected, err)
+		}
+	}
+}
+
+func TestCompareEncode(t *testing.T) {
+	// Each address sorts after the previous one.
+	ordered := []string{
+		`0.0.0.0/0`,
+		`10.0.0.0/8`,
+		`10.0.0.1/8`,
+		`10.1.0.0/16`,
+		`10.1.0.0`,
+		`10.1.0.1`,
+		`192.168.0.0/24`,
+		`::/0`,
+		`::1`,
+		`2001:db8::/32`,
+	}
+	for i := 1; i < len(ordered); i++ {
+		prev, cur := mustParse(t, ordered[i-1]), mustParse(t, ordered[i])
+		if c := prev.Compare(cur); c != -1 {
+			t.Errorf("expected %s < %s, but found %d", prev, cur, c)
+		}
+		if c := cur.Compare(prev); c != 1 {
+			t.Errorf("expected %s > %s, but found %d", cur, prev, c)
+		}
+		if c := cur.Compare(cur); c != 0 {
+			t.Errorf("expected %s = %s, but found %d", cur, cur, c)
+		}
+		if bytes.Compare(prev.Encode(nil), cur.Encode(nil)) != -1 {
+			t.Errorf("expected encoding of %s to sort before %s", prev, cur)
+		}
+	}
+
+	for _, s := range ordered {
+		b := mustParse(t, s).Encode([]byte("prefix"))
+		ip, rem, err := Decode(b[len("prefix"):])
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if len(rem) != 0 {
+			t.Errorf("%s: %d trailing bytes", s, len(rem))
+		}
+		if r := ip.String(); r != s {
+			t.Errorf("expected %s, but found %s", s, r)
+		}
+		if _, _, err := Decode(b[len("prefix") : len(b)-1]); err == nil {
+			t.Errorf("%s: expected error decoding truncated value", s)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	testData := []struct {
+		a, b     string
+		expected bool
+	}{
+		{`10.0.0.0/8`, `10.1.2.3`, true},
+		{`10.0.0.0/8`, `10.1.0.0/16`, true},
+		{`10.0.0.0/8`, `10.0.0.0/8`, false},
+		{`10.0.0.0/8`, `11.0.0.1`, false},
+		{`10.1.0.0/16`, `10.0.0.0/8`, false},
+		{`0.0.0.0/0`, `1.2.3.4`, true},
+		{`0.0.0.0/0`, `::1`, false},
+		{`2001:db8::/32`, `2001:db8::1`, true},
+	}
+	for _, d := range testData {
+		a, b := mustParse(t, d.a), mustParse(t, d.b)
+		if r := a.Contains(b); r != d.expected {
+			t.Errorf("%s >> %s: expected %t, but found %t", d.a, d.b, d.expected, r)
+		}
+		if r := b.ContainedBy(a); r != d.expected {
+			t.Errorf("%s << %s: expected %t, but found %t", d.b, d.a, d.expected, r)
+		}
+	}
+}
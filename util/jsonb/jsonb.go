@@ -0,0 +1,734 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package jsonb implements the JSON documents stored in JSONB columns.
+//
+// A document is represented by the Go values produced by encoding/json when
+// decoding into an interface{} with numbers preserved: nil, bool,
+// json.Number, string, []interface{} and map[string]interface{}. Numbers are
+// normalized to their plain decimal representation when parsed, so that equal
+// documents have a single representation.
+package jsonb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/inf.v0"
+
+	"github.com/cockroachdb/cockroach/util/encoding"
+	"github.com/pkg/errors"
+)
+
+// Parse parses the textual representation of a JSON document.
+func Parse(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("empty JSON document")
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("trailing characters after JSON document")
+	}
+	return normalize(v)
+}
+
+// normalize rewrites the numbers in v in their plain decimal form.
+func normalize(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case json.Number:
+		d, err := numberToDec(t)
+		if err != nil {
+			return nil, err
+		}
+		return json.Number(d.String()), nil
+	case []interface{}:
+		for i := range t {
+			e, err := normalize(t[i])
+			if err != nil {
+				return nil, err
+			}
+			t[i] = e
+		}
+	case map[string]interface{}:
+		for k := range t {
+			e, err := normalize(t[k])
+			if err != nil {
+				return nil, err
+			}
+			t[k] = e
+		}
+	}
+	return v, nil
+}
+
+// numberToDec converts a JSON number, possibly using an exponent, to an
+// inf.Dec.
+func numberToDec(n json.Number) (*inf.Dec, error) {
+	s := string(n)
+	exp := int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		// Bound the exponent to avoid materializing huge numbers.
+		if exp, err = strconv.ParseInt(s[i+1:], 10, 16); err != nil {
+			return nil, errors.Errorf("invalid JSON number: %s", n)
+		}
+		s = s[:i]
+	}
+	d, ok := new(inf.Dec).SetString(s)
+	if !ok {
+		return nil, errors.Errorf("invalid JSON number: %s", n)
+	}
+	d.SetScale(d.Scale() - inf.Scale(exp))
+	if d.Scale() < 0 {
+		// Render large exponents with trailing zeros rather than a negative
+		// scale.
+		d.Round(d, 0, inf.RoundDown)
+	}
+	return d, nil
+}
+
+// FromNumber returns the JSON number with the given decimal representation.
+func FromNumber(s string) (interface{}, error) {
+	return normalize(json.Number(s))
+}
+
+// sortedKeys returns the keys of the object m in the order used to format,
+// compare and encode objects: shorter keys first, then bytewise. This is the
+// order in which PostgreSQL stores the keys of a jsonb object.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Sort(byKeyOrder(keys))
+	return keys
+}
+
+type byKeyOrder []string
+
+func (k byKeyOrder) Len() int      { return len(k) }
+func (k byKeyOrder) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byKeyOrder) Less(i, j int) bool {
+	if len(k[i]) != len(k[j]) {
+		return len(k[i]) < len(k[j])
+	}
+	return k[i] < k[j]
+}
+
+// Format writes the textual representation of v to buf, in the same format
+// as PostgreSQL, e.g. {"a": 1, "b": [true, null]}.
+func Format(buf *bytes.Buffer, v interface{}) {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case json.Number:
+		buf.WriteString(string(t))
+	case string:
+		formatString(buf, t)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range t {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			Format(buf, e)
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for i, k := range sortedKeys(t) {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			formatString(buf, k)
+			buf.WriteString(": ")
+			Format(buf, t[k])
+		}
+		buf.WriteByte('}')
+	default:
+		panic(fmt.Sprintf("unexpected JSON value of type %T", v))
+	}
+}
+
+// String returns the textual representation of v.
+func String(v interface{}) string {
+	var buf bytes.Buffer
+	Format(&buf, v)
+	return buf.String()
+}
+
+func formatString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// TypeOf returns the name of the type of the JSON value v, as returned by
+// the jsonb_typeof SQL function.
+func TypeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	panic(fmt.Sprintf("unexpected JSON value of type %T", v))
+}
+
+// typeRank orders the JSON types as PostgreSQL does:
+// null < string < number < boolean < array < object.
+func typeRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case string:
+		return 1
+	case json.Number:
+		return 2
+	case bool:
+		return 3
+	case []interface{}:
+		return 4
+	case map[string]interface{}:
+		return 5
+	}
+	panic(fmt.Sprintf("unexpected JSON value of type %T", v))
+}
+
+// Compare returns -1, 0 or 1 depending on whether a sorts before, equal to
+// or after b. Values of different types are ordered by type; arrays and
+// objects with fewer elements sort before those with more elements, and are
+// otherwise compared element by element.
+func Compare(a, b interface{}) int {
+	if ra, rb := typeRank(a), typeRank(b); ra != rb {
+		if ra < rb {
+			return -1
+		}
+		return 1
+	}
+	switch ta := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		tb := b.(bool)
+		switch {
+		case ta == tb:
+			return 0
+		case !ta:
+			return -1
+		}
+		return 1
+	case json.Number:
+		da, errA := numberToDec(ta)
+		db, errB := numberToDec(b.(json.Number))
+		if errA != nil || errB != nil {
+			return strings.Compare(string(ta), string(b.(json.Number)))
+		}
+		return da.Cmp(db)
+	case string:
+		return strings.Compare(ta, b.(string))
+	case []interface{}:
+		tb := b.([]interface{})
+		if c := compareLen(len(ta), len(tb)); c != 0 {
+			return c
+		}
+		for i := range ta {
+			if c := Compare(ta[i], tb[i]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case map[string]interface{}:
+		tb := b.(map[string]interface{})
+		if c := compareLen(len(ta), len(tb)); c != 0 {
+			return c
+		}
+		keysA, keysB := sortedKeys(ta), sortedKeys(tb)
+		for i := range keysA {
+			if keysA[i] != keysB[i] {
+				if (byKeyOrder{keysA[i], keysB[i]}).Less(0, 1) {
+					return -1
+				}
+				return 1
+			}
+		}
+		for _, k := range keysA {
+			if c := Compare(ta[k], tb[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	panic(fmt.Sprintf("unexpected JSON value of type %T", a))
+}
+
+func compareLen(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Contains returns whether a contains b, as defined by the PostgreSQL @>
+// operator: scalars contain equal scalars, objects contain objects whose
+// key/value pairs they each contain, and arrays contain arrays whose elements
+// are each contained by some of their elements. As a special case, a
+// top-level array contains the scalars it has as elements.
+func Contains(a, b interface{}) bool {
+	if ta, ok := a.([]interface{}); ok {
+		switch b.(type) {
+		case []interface{}, map[string]interface{}:
+		default:
+			for _, e := range ta {
+				if Compare(e, b) == 0 {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return contains(a, b)
+}
+
+func contains(a, b interface{}) bool {
+	switch tb := b.(type) {
+	case []interface{}:
+		ta, ok := a.([]interface{})
+		if !ok {
+			return false
+		}
+	outer:
+		for _, eb := range tb {
+			for _, ea := range ta {
+				if contains(ea, eb) {
+					continue outer
+				}
+			}
+			return false
+		}
+		return true
+	case map[string]interface{}:
+		ta, ok := a.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, vb := range tb {
+			va, ok := ta[k]
+			if !ok || !contains(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+	return Compare(a, b) == 0
+}
+
+// Exists returns whether the string key exists as a top-level key of the
+// object v, as an element of the array v, or is equal to the string v, as
+// defined by the PostgreSQL ? operator.
+func Exists(v interface{}, key string) bool {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		_, ok := t[key]
+		return ok
+	case []interface{}:
+		for _, e := range t {
+			if s, ok := e.(string); ok && s == key {
+				return true
+			}
+		}
+	case string:
+		return t == key
+	}
+	return false
+}
+
+// FetchKey returns the value of the field key of the object v. ok is false
+// if v is not an object or does not contain key.
+func FetchKey(v interface{}, key string) (_ interface{}, ok bool) {
+	m, isObj := v.(map[string]interface{})
+	if !isObj {
+		return nil, false
+	}
+	e, ok := m[key]
+	return e, ok
+}
+
+// FetchIndex returns the element at the zero-based index idx of the array v,
+// negative indexes counting from the end of the array. ok is false if v is
+// not an array or idx is out of bounds.
+func FetchIndex(v interface{}, idx int) (_ interface{}, ok bool) {
+	a, isArr := v.([]interface{})
+	if !isArr {
+		return nil, false
+	}
+	if idx < 0 {
+		idx += len(a)
+	}
+	if idx < 0 || idx >= len(a) {
+		return nil, false
+	}
+	return a[idx], true
+}
+
+// Set returns a copy of v in which the value designated by path is replaced
+// by newVal, as defined by the PostgreSQL jsonb_set function. The elements of
+// path are object keys or array indexes. If the last element of path does not
+// exist in its parent and createMissing is true, it is added: arrays are
+// prepended to or appended to when the index is out of bounds. v is returned
+// unchanged if any other element of path does not exist.
+func Set(v interface{}, path []string, newVal interface{}, createMissing bool) (interface{}, error) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+	default:
+		return nil, errors.New("cannot set path in scalar")
+	}
+	return set(v, path, 0, newVal, createMissing)
+}
+
+func set(
+	v interface{}, path []string, pos int, newVal interface{}, createMissing bool,
+) (interface{}, error) {
+	if pos == len(path) {
+		return newVal, nil
+	}
+	last := pos == len(path)-1
+	switch t := v.(type) {
+	case map[string]interface{}:
+		e, ok := t[path[pos]]
+		if !ok && !(last && createMissing) {
+			return v, nil
+		}
+		if ok {
+			var err error
+			if e, err = set(e, path, pos+1, newVal, createMissing); err != nil {
+				return nil, err
+			}
+		} else {
+			e = newVal
+		}
+		res := make(map[string]interface{}, len(t)+1)
+		for k, val := range t {
+			res[k] = val
+		}
+		res[path[pos]] = e
+		return res, nil
+	case []interface{}:
+		idx, err := strconv.Atoi(path[pos])
+		if err != nil {
+			return nil, errors.Errorf("path element at position %d is not an integer: %q",
+				pos+1, path[pos])
+		}
+		if idx < 0 {
+			idx += len(t)
+		}
+		if idx >= 0 && idx < len(t) {
+			e, err := set(t[idx], path, pos+1, newVal, createMissing)
+			if err != nil {
+				return nil, err
+			}
+			res := make([]interface{}, len(t))
+			copy(res, t)
+			res[idx] = e
+			return res, nil
+		}
+		if !(last && createMissing) {
+			return v, nil
+		}
+		res := make([]interface{}, 0, len(t)+1)
+		if idx < 0 {
+			res = append(res, newVal)
+			return append(res, t...), nil
+		}
+		res = append(res, t...)
+		return append(res, newVal), nil
+	}
+	return v, nil
+}
+
+// Tags of the binary encoding of JSON values.
+const (
+	nullTag byte = iota
+	falseTag
+	trueTag
+	numberTag
+	stringTag
+	arrayTag
+	objectTag
+)
+
+// Encode appends the binary encoding of v to appendTo and returns the
+// result. Each value is encoded as a tag byte followed, for numbers and
+// strings, by the length of their text and the text itself, and, for arrays
+// and objects, by their number of elements and the encoding of each element.
+// Object keys are encoded in sorted order, each followed by its value.
+func Encode(appendTo []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(appendTo, nullTag)
+	case bool:
+		if t {
+			return append(appendTo, trueTag)
+		}
+		return append(appendTo, falseTag)
+	case json.Number:
+		return encodeString(append(appendTo, numberTag), string(t))
+	case string:
+		return encodeString(append(appendTo, stringTag), t)
+	case []interface{}:
+		appendTo = encoding.EncodeNonsortingUvarint(append(appendTo, arrayTag), uint64(len(t)))
+		for _, e := range t {
+			appendTo = Encode(appendTo, e)
+		}
+		return appendTo
+	case map[string]interface{}:
+		appendTo = encoding.EncodeNonsortingUvarint(append(appendTo, objectTag), uint64(len(t)))
+		for _, k := range sortedKeys(t) {
+			appendTo = encodeString(appendTo, k)
+			appendTo = Encode(appendTo, t[k])
+		}
+		return appendTo
+	}
+	panic(fmt.Sprintf("unexpected JSON value of type %T", v))
+}
+
+func encodeString(appendTo []byte, s string) []byte {
+	appendTo = encoding.EncodeNonsortingUvarint(appendTo, uint64(len(s)))
+	return append(appendTo, s...)
+}
+
+// Decode decodes a value encoded by Encode, returning the value and the
+// remainder of b.
+func Decode(b []byte) (_ interface{}, remaining []byte, _ error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("insufficient bytes to decode JSON value")
+	}
+	tag := b[0]
+	b = b[1:]
+	switch tag {
+	case nullTag:
+		return nil, b, nil
+	case falseTag:
+		return false, b, nil
+	case trueTag:
+		return true, b, nil
+	case numberTag:
+		s, b, err := decodeString(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		return json.Number(s), b, nil
+	case stringTag:
+		return decodeString(b)
+	case arrayTag:
+		b, n, err := decodeLength(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], b, err = Decode(b); err != nil {
+				return nil, nil, err
+			}
+		}
+		return a, b, nil
+	case objectTag:
+		b, n, err := decodeLength(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			var k string
+			if k, b, err = decodeString(b); err != nil {
+				return nil, nil, err
+			}
+			if m[k], b, err = Decode(b); err != nil {
+				return nil, nil, err
+			}
+		}
+		return m, b, nil
+	}
+	return nil, nil, errors.Errorf("unknown JSON value tag %d", tag)
+}
+
+func decodeLength(b []byte) ([]byte, int, error) {
+	b, l, n, err := encoding.DecodeNonsortingUvarint(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if l == 0 || n > uint64(len(b)) {
+		// Each element or character takes at least one byte.
+		return nil, 0, errors.New("invalid length in JSON value")
+	}
+	return b, int(n), nil
+}
+
+func decodeString(b []byte) (string, []byte, error) {
+	b, n, err := decodeLength(b)
+	if err != nil {
+		return "", nil, err
+	}
+	s := string(b[:n])
+	if !utf8.ValidString(s) {
+		return "", nil, errors.New("invalid UTF-8 in JSON value")
+	}
+	return s, b[n:], nil
+}
+
+// Tags of the inverted index encoding of JSON values, in addition to the
+// tags of the binary encoding.
+const (
+	emptyArrayTag byte = objectTag + 1 + iota
+	emptyObjectTag
+)
+
+// EncodeInvertedIndexKeys returns the keys of the inverted index entries for
+// the document v, in sorted order and without duplicates. There is one key
+// for each path from the root of the document to a scalar or to an empty
+// array or object. Each key is the sequence of the object keys and array
+// traversals along its path, followed by the encoding of the value at its
+// end. Array positions are not part of the keys, so that documents
+// containing each other share keys.
+func EncodeInvertedIndexKeys(v interface{}) [][]byte {
+	keys := encodeInvertedIndexKeys(nil, nil, v, true /* includeEmpty */)
+	if len(keys) < 2 {
+		return keys
+	}
+	sort.Sort(byteSlices(keys))
+	res := keys[:1]
+	for _, k := range keys[1:] {
+		if !bytes.Equal(k, res[len(res)-1]) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// InvertedIndexContainsKeys returns inverted index keys that every document
+// containing v has entries for. It returns no keys when every document may
+// contain v: when v is a scalar, which a top-level array contains when it
+// is one of its elements, or when v only has empty arrays and objects.
+func InvertedIndexContainsKeys(v interface{}) [][]byte {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		return encodeInvertedIndexKeys(nil, nil, v, false /* includeEmpty */)
+	}
+	return nil
+}
+
+// encodeInvertedIndexKeys appends to keys the keys of the inverted index
+// entries for the value v found at the encoded path. Empty arrays and
+// objects only produce keys when includeEmpty is set.
+func encodeInvertedIndexKeys(keys [][]byte, path []byte, v interface{}, includeEmpty bool) [][]byte {
+	// Each key gets its own copy of the path.
+	path = path[:len(path):len(path)]
+	switch t := v.(type) {
+	case nil:
+		return append(keys, append(path, nullTag))
+	case bool:
+		if t {
+			return append(keys, append(path, trueTag))
+		}
+		return append(keys, append(path, falseTag))
+	case json.Number:
+		d, err := numberToDec(t)
+		if err != nil {
+			panic(err)
+		}
+		// The decimal key encoding is independent of the scale of d, so that
+		// equal numbers have equal keys.
+		return append(keys, encoding.EncodeDecimalAscending(append(path, numberTag), d))
+	case string:
+		return append(keys, encoding.EncodeStringAscending(append(path, stringTag), t))
+	case []interface{}:
+		if len(t) == 0 {
+			if includeEmpty {
+				keys = append(keys, append(path, emptyArrayTag))
+			}
+			return keys
+		}
+		path = append(path, arrayTag)
+		for _, e := range t {
+			keys = encodeInvertedIndexKeys(keys, path, e, includeEmpty)
+		}
+		return keys
+	case map[string]interface{}:
+		if len(t) == 0 {
+			if includeEmpty {
+				keys = append(keys, append(path, emptyObjectTag))
+			}
+			return keys
+		}
+		for _, k := range sortedKeys(t) {
+			keyPath := encoding.EncodeStringAscending(append(path, objectTag), k)
+			keys = encodeInvertedIndexKeys(keys, keyPath, t[k], includeEmpty)
+		}
+		return keys
+	}
+	panic(fmt.Sprintf("unexpected JSON value of type %T", v))
+}
+
+type byteSlices [][]byte
+
+func (b byteSlices) Len() int           { return len(b) }
+func (b byteSlices) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byteSlices) Less(i, j int) bool { return bytes.Compare(b[i], b[j]) < 0 }
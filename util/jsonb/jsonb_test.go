@@ -0,0 +1,324 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package jsonb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cockroachdb/cockroach/testutils"
+	_ "github.com/cockroachdb/cockroach/util/log"
+)
+
+func mustParse(t *testing.T, s string) interface{} {
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatalf("%s: %v", s, err)
+	}
+	return v
+}
+
+func TestParseFormat(t *testing.T) {
+	testData := []struct {
+		in       string
+		expected string
+	}{
+		{`null`, `null`},
+		{` true `, `true`},
+		{`1.50`, `1.50`},
+		{`-0`, `0`},
+		{`1e3`, `1000`},
+		{`1.5E-2`, `0.015`},
+		{`"a\"bé\n"`, `"a\"bé\n"`},
+		{`[1,[2, "x"],{}]`, `[1, [2, "x"], {}]`},
+		{`{"bb": 1, "a": 2, "ab": 3, "a": 4}`, `{"a": 4, "ab": 3, "bb": 1}`},
+	}
+	for _, d := range testData {
+		if s := String(mustParse(t, d.in)); s != d.expected {
+			t.Errorf("%s: expected %s, but found %s", d.in, d.expected, s)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testData := []struct {
+		in       string
+		expected string
+	}{
+		{``, `empty JSON document`},
+		{`{"a": 1`, `unexpected EOF`},
+		{`[1] [2]`, `trailing characters after JSON document`},
+		{`1e99999`, `invalid JSON number: 1e99999`},
+		{`{1: 2}`, `invalid character`},
+	}
+	for _, d := range testData {
+		_, err := Parse(d.in)
+		if !testutils.IsError(err, d.expected) {
+			t.Errorf("%s: expected %q, but found %v", d.in, d.expected, err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each value sorts after the previous one.
+	ordered := []string{
+		`null`,
+		`""`,
+		`"a"`,
+		`"b"`,
+		`-1`,
+		`1`,
+		`1.5`,
+		`false`,
+		`true`,
+		`[]`,
+		`[2]`,
+		`[1, 2]`,
+		`[1, 3]`,
+		`{}`,
+		`{"b": 1}`,
+		`{"b": 2}`,
+		`{"aa": 1}`,
+		`{"a": 1, "b": 1}`,
+	}
+	for i := 1; i < len(ordered); i++ {
+		prev, cur := mustParse(t, ordered[i-1]), mustParse(t, ordered[i])
+		if c := Compare(prev, cur); c != -1 {
+			t.Errorf("expected %s < %s, but found %d", ordered[i-1], ordered[i], c)
+		}
+		if c := Compare(cur, prev); c != 1 {
+			t.Errorf("expected %s > %s, but found %d", ordered[i], ordered[i-1], c)
+		}
+		if c := Compare(cur, cur); c != 0 {
+			t.Errorf("expected %s = %s, but found %d", ordered[i], ordered[i], c)
+		}
+	}
+	if c := Compare(mustParse(t, `1.0`), mustParse(t, `1`)); c != 0 {
+		t.Errorf("expected 1.0 = 1, but found %d", c)
+	}
+}
+
+func TestContains(t *testing.T) {
+	testData := []struct {
+		a, b     string
+		expected bool
+	}{
+		{`1`, `1`, true},
+		{`1`, `2`, false},
+		{`"a"`, `["a"]`, false},
+		{`["a", "b"]`, `"a"`, true},
+		{`["a", "b"]`, `["b"]`, true},
+		{`["a", "b"]`, `["b", "b", "a"]`, true},
+		{`["a", "b"]`, `["c"]`, false},
+		{`[1, [2, 3]]`, `[[3]]`, true},
+		{`[1, [2, 3]]`, `[3]`, false},
+		{`{"a": 1, "b": {"c": [1, 2]}}`, `{}`, true},
+		{`{"a": 1, "b": {"c": [1, 2]}}`, `{"b": {"c": [2]}}`, true},
+		{`{"a": 1, "b": {"c": [1, 2]}}`, `{"b": {"c": 2}}`, false},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+		{`{"a": 1}`, `["a"]`, false},
+	}
+	for _, d := range testData {
+		if r := Contains(mustParse(t, d.a), mustParse(t, d.b)); r != d.expected {
+			t.Errorf("%s @> %s: expected %t, but found %t", d.a, d.b, d.expected, r)
+		}
+	}
+}
+
+func TestExists(t *testing.T) {
+	testData := []struct {
+		v        string
+		key      string
+		expected bool
+	}{
+		{`{"a": 1}`, `a`, true},
+		{`{"a": 1}`, `b`, false},
+		{`["a", 1]`, `a`, true},
+		{`["a", 1]`, `1`, false},
+		{`"a"`, `a`, true},
+		{`1`, `1`, false},
+	}
+	for _, d := range testData {
+		if r := Exists(mustParse(t, d.v), d.key); r != d.expected {
+			t.Errorf("%s ? %s: expected %t, but found %t", d.v, d.key, d.expected, r)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	v := mustParse(t, `{"a": [1, "b", null]}`)
+	a, ok := FetchKey(v, "a")
+	if !ok {
+		t.Fatal("expected key a to exist")
+	}
+	if _, ok := FetchKey(v, "b"); ok {
+		t.Error("expected key b not to exist")
+	}
+	if _, ok := FetchKey(a, "a"); ok {
+		t.Error("expected arrays to have no keys")
+	}
+	testData := []struct {
+		idx      int
+		expected string
+	}{
+		{0, `1`},
+		{1, `"b"`},
+		{2, `null`},
+		{-1, `null`},
+		{-3, `1`},
+	}
+	for _, d := range testData {
+		e, ok := FetchIndex(a, d.idx)
+		if !ok {
+			t.Errorf("%d: expected element to exist", d.idx)
+		} else if s := String(e); s != d.expected {
+			t.Errorf("%d: expected %s, but found %s", d.idx, d.expected, s)
+		}
+	}
+	for _, idx := range []int{3, -4} {
+		if _, ok := FetchIndex(a, idx); ok {
+			t.Errorf("%d: expected element not to exist", idx)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	testData := []struct {
+		v             string
+		path          []string
+		newVal        string
+		createMissing bool
+		expected      string
+	}{
+		{`{"a": 1}`, []string{"a"}, `2`, true, `{"a": 2}`},
+		{`{"a": 1}`, []string{"b"}, `2`, true, `{"a": 1, "b": 2}`},
+		{`{"a": 1}`, []string{"b"}, `2`, false, `{"a": 1}`},
+		{`{"a": 1}`, []string{"b", "c"}, `2`, true, `{"a": 1}`},
+		{`{"a": [1, {"b": 2}]}`, []string{"a", "1", "b"}, `[3]`, true, `{"a": [1, {"b": [3]}]}`},
+		{`[1, 2]`, []string{"-1"}, `3`, true, `[1, 3]`},
+		{`[1, 2]`, []string{"5"}, `3`, true, `[1, 2, 3]`},
+		{`[1, 2]`, []string{"-5"}, `3`, true, `[3, 1, 2]`},
+		{`[1, 2]`, []string{"5"}, `3`, false, `[1, 2]`},
+		{`[1, 2]`, []string{}, `3`, true, `3`},
+	}
+	for _, d := range testData {
+		v := mustParse(t, d.v)
+		r, err := Set(v, d.path, mustParse(t, d.newVal), d.createMissing)
+		if err != nil {
+			t.Errorf("%s %v: %v", d.v, d.path, err)
+			continue
+		}
+		if s := String(r); s != d.expected {
+			t.Errorf("%s %v: expected %s, but found %s", d.v, d.path, d.expected, s)
+		}
+		if s := String(v); s != String(mustParse(t, d.v)) {
+			t.Errorf("%s %v: input modified to %s", d.v, d.path, s)
+		}
+	}
+
+	if _, err := Set(mustParse(t, `1`), []string{"a"}, nil, true); !testutils.IsError(err,
+		"cannot set path in scalar") {
+		t.Errorf("expected scalar error, but found %v", err)
+	}
+	if _, err := Set(mustParse(t, `[1]`), []string{"a"}, nil, true); !testutils.IsError(err,
+		`path element at position 1 is not an integer: "a"`) {
+		t.Errorf("expected path element error, but found %v", err)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	testData := []string{
+		`null`,
+		`false`,
+		`true`,
+		`-12.340`,
+		`""`,
+		`"héllo"`,
+		`[]`,
+		`[1, "a", [null, {}]]`,
+		`{"a": {"b": [true]}, "bb": "c"}`,
+	}
+	for _, s := range testData {
+		b := Encode([]byte("prefix"), mustParse(t, s))
+		v, rem, err := Decode(b[len("prefix"):])
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if len(rem) != 0 {
+			t.Errorf("%s: %d trailing bytes", s, len(rem))
+		}
+		if r := String(v); r != s {
+			t.Errorf("expected %s, but found %s", s, r)
+		}
+		// Truncated encodings must not decode.
+		if _, _, err := Decode(b[len("prefix") : len(b)-1]); err == nil && len(b) > len("prefix")+1 {
+			t.Errorf("%s: expected error decoding truncated value", s)
+		}
+	}
+}
+
+func TestInvertedIndexKeys(t *testing.T) {
+	testData := []struct {
+		in      string
+		numKeys int
+	}{
+		{`null`, 1},
+		{`1.50`, 1},
+		{`[]`, 1},
+		{`{}`, 1},
+		{`[1, 1.0, "1", [1]]`, 3},
+		{`{"a": [1, 2], "b": {"c": {}}, "d": [[]]}`, 4},
+	}
+	for _, d := range testData {
+		if keys := EncodeInvertedIndexKeys(mustParse(t, d.in)); len(keys) != d.numKeys {
+			t.Errorf("%s: expected %d keys, but found %d", d.in, d.numKeys, len(keys))
+		}
+	}
+
+	// The keys required to contain a document are keys of the documents
+	// containing it.
+	containsData := []struct {
+		a, b    string
+		numKeys int
+	}{
+		{`["a", "b"]`, `"a"`, 0},
+		{`["a", "b"]`, `["b", "b", "a"]`, 3},
+		{`[1, [2, 3]]`, `[[3]]`, 1},
+		{`{"a": 1, "b": {"c": [1, 2]}}`, `{}`, 0},
+		{`{"a": 1, "b": {"c": [1, 2]}}`, `{"b": {"c": [2]}}`, 1},
+		{`{"a": 1.0, "b": {"c": [1, 2]}}`, `{"a": 1, "b": {"c": []}}`, 1},
+	}
+	for _, d := range containsData {
+		a, b := mustParse(t, d.a), mustParse(t, d.b)
+		if !Contains(a, b) {
+			t.Fatalf("%s does not contain %s", d.a, d.b)
+		}
+		keys := InvertedIndexContainsKeys(b)
+		if len(keys) != d.numKeys {
+			t.Errorf("%s: expected %d keys, but found %d", d.b, d.numKeys, len(keys))
+		}
+		docKeys := EncodeInvertedIndexKeys(a)
+	outer:
+		for _, k := range keys {
+			for _, dk := range docKeys {
+				if bytes.Equal(k, dk) {
+					continue outer
+				}
+			}
+			t.Errorf("%s @> %s: key %q is not a key of %s", d.a, d.b, k, d.a)
+		}
+	}
+}
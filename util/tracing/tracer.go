@@ -19,9 +19,11 @@ package tracing
 import (
 	"bytes"
 	"encoding/gob"
+	"sync"
 
 	"golang.org/x/net/context"
 
+	"github.com/cockroachdb/cockroach/settings"
 	"github.com/cockroachdb/cockroach/util/caller"
 	"github.com/cockroachdb/cockroach/util/envutil"
 	"github.com/lightstep/lightstep-tracer-go"
@@ -87,6 +89,33 @@ func defaultOptions(recorder func(basictracer.RawSpan)) basictracer.Options {
 
 var lightstepToken = envutil.EnvOrDefaultString("lightstep_token", "")
 
+// zipkinCollector is the URL to which the sampled spans are sent, using the
+// v2 API of Zipkin (e.g. http://localhost:9411/api/v2/spans). Jaeger
+// collectors accept the spans on their Zipkin-compatible endpoint.
+var zipkinCollector = envutil.EnvOrDefaultString("zipkin_collector", "")
+
+// traceSampleRate is the fraction of the traces sent to the Zipkin
+// collector. The env var only sets its default.
+var traceSampleRate = settings.RegisterFloatSetting(
+	"trace.zipkin.sample_rate",
+	"fraction of the traces sent to the Zipkin collector",
+	envutil.EnvOrDefaultFloat("trace_sample_rate", 0.01),
+)
+
+var zipkin struct {
+	once     sync.Once
+	recorder *zipkinRecorder
+}
+
+// getZipkinRecorder returns the recorder sending the spans of all the tracers
+// of the process to the Zipkin collector.
+func getZipkinRecorder() *zipkinRecorder {
+	zipkin.once.Do(func() {
+		zipkin.recorder = newZipkinRecorder(zipkinCollector)
+	})
+	return zipkin.recorder
+}
+
 // newTracer implements NewTracer and allows that function to be mocked out via Disable().
 var newTracer = func() opentracing.Tracer {
 	if lightstepToken != "" {
@@ -94,11 +123,18 @@ var newTracer = func() opentracing.Tracer {
 			AccessToken: lightstepToken,
 		})
 	}
+	if zipkinCollector != "" {
+		opts := defaultOptions(getZipkinRecorder().RecordSpan)
+		opts.ShouldSample = func(traceID uint64) bool {
+			return makeSampler(traceSampleRate.Get())(traceID)
+		}
+		return basictracer.NewWithOptions(opts)
+	}
 	return basictracer.NewWithOptions(defaultOptions(func(_ basictracer.RawSpan) {}))
 }
 
 // NewTracer creates a Tracer which records to the net/trace
-// endpoint.
+// endpoint, and to LightStep or to a Zipkin collector if configured.
 func NewTracer() opentracing.Tracer {
 	return newTracer()
 }
@@ -0,0 +1,212 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/cockroachdb/cockroach/util/log"
+	"github.com/cockroachdb/cockroach/util/syncutil"
+	basictracer "github.com/opentracing/basictracer-go"
+)
+
+const (
+	// zipkinServiceName is the name under which the spans are reported.
+	zipkinServiceName = "cockroach"
+	// zipkinBatchSize is the number of spans after which the buffered spans
+	// are sent without waiting for zipkinFlushInterval.
+	zipkinBatchSize = 100
+	// zipkinMaxBuffered is the number of spans buffered past which new spans
+	// are dropped, if the collector can't keep up.
+	zipkinMaxBuffered = 10000
+	// zipkinFlushInterval is the longest a span is buffered before being
+	// sent.
+	zipkinFlushInterval = time.Second
+)
+
+// zipkinEndpoint is the process reporting a span.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// zipkinAnnotation is an event logged in a span. The timestamps are in
+// microseconds since the epoch.
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// zipkinSpan is a span in the JSON format of the v2 API of Zipkin, which is
+// also accepted by the Zipkin-compatible endpoint of Jaeger collectors.
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name"`
+	Timestamp     int64              `json:"timestamp"`
+	Duration      int64              `json:"duration"`
+	LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+}
+
+func toMicros(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Microsecond)
+}
+
+func makeZipkinSpan(sp basictracer.RawSpan) zipkinSpan {
+	zs := zipkinSpan{
+		TraceID:       fmt.Sprintf("%016x", sp.Context.TraceID),
+		ID:            fmt.Sprintf("%016x", sp.Context.SpanID),
+		Name:          sp.Operation,
+		Timestamp:     toMicros(sp.Start),
+		Duration:      sp.Duration.Nanoseconds() / int64(time.Microsecond),
+		LocalEndpoint: zipkinEndpoint{ServiceName: zipkinServiceName},
+	}
+	if sp.ParentSpanID != 0 {
+		zs.ParentID = fmt.Sprintf("%016x", sp.ParentSpanID)
+	}
+	// Zipkin ignores the duration of a span if it is zero.
+	if zs.Duration == 0 {
+		zs.Duration = 1
+	}
+	for _, l := range sp.Logs {
+		zs.Annotations = append(zs.Annotations, zipkinAnnotation{
+			Timestamp: toMicros(l.Timestamp),
+			Value:     l.Event,
+		})
+	}
+	if len(sp.Tags) > 0 {
+		zs.Tags = make(map[string]string, len(sp.Tags))
+		for k, v := range sp.Tags {
+			zs.Tags[k] = fmt.Sprint(v)
+		}
+	}
+	return zs
+}
+
+// zipkinRecorder is a span recorder which sends the sampled spans to a
+// Zipkin collector. The spans are buffered and sent in batches in the
+// background.
+type zipkinRecorder struct {
+	url    string
+	client *http.Client
+	// flushC is signaled when a full batch of spans is buffered.
+	flushC chan struct{}
+
+	mu struct {
+		syncutil.Mutex
+		spans   []zipkinSpan
+		dropped int
+	}
+}
+
+func newZipkinRecorder(url string) *zipkinRecorder {
+	r := &zipkinRecorder{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		flushC: make(chan struct{}, 1),
+	}
+	go r.loop()
+	return r
+}
+
+// RecordSpan implements basictracer.SpanRecorder.
+func (r *zipkinRecorder) RecordSpan(sp basictracer.RawSpan) {
+	if !sp.Context.Sampled {
+		return
+	}
+	zs := makeZipkinSpan(sp)
+	r.mu.Lock()
+	if len(r.mu.spans) >= zipkinMaxBuffered {
+		r.mu.dropped++
+		r.mu.Unlock()
+		return
+	}
+	r.mu.spans = append(r.mu.spans, zs)
+	full := len(r.mu.spans) >= zipkinBatchSize
+	r.mu.Unlock()
+	if full {
+		select {
+		case r.flushC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// loop sends the buffered spans every zipkinFlushInterval, or as soon as a
+// batch is full. It runs for the lifetime of the process.
+func (r *zipkinRecorder) loop() {
+	ticker := time.NewTicker(zipkinFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.flushC:
+		}
+		if err := r.flush(); err != nil {
+			log.Warningf(context.TODO(), "unable to send trace spans to %s: %s", r.url, err)
+		}
+	}
+}
+
+// flush sends the buffered spans to the collector.
+func (r *zipkinRecorder) flush() error {
+	r.mu.Lock()
+	spans, dropped := r.mu.spans, r.mu.dropped
+	r.mu.spans, r.mu.dropped = nil, 0
+	r.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warningf(context.TODO(), "dropped %d trace spans which could not be sent in time", dropped)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(spans)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response from the collector: %s", resp.Status)
+	}
+	return nil
+}
+
+// makeSampler returns a sampling function keeping the given fraction of the
+// traces. The trace IDs being random, a trace is kept if its ID falls in the
+// matching fraction of their range.
+func makeSampler(rate float64) func(traceID uint64) bool {
+	switch {
+	case rate <= 0:
+		return func(uint64) bool { return false }
+	case rate >= 1:
+		return func(uint64) bool { return true }
+	}
+	threshold := uint64(rate * math.MaxUint64)
+	return func(traceID uint64) bool { return traceID < threshold }
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 The Cockroach Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or
+// implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tracing
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/cockroach/util/leaktest"
+	basictracer "github.com/opentracing/basictracer-go"
+	opentracing "github.com/opentracing/opentracing-go"
+)
+
+func TestZipkinRecorder(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	var received []zipkinSpan
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var spans []zipkinSpan
+		if err := json.Unmarshal(body, &spans); err != nil {
+			t.Error(err)
+			return
+		}
+		received = append(received, spans...)
+	}))
+	defer ts.Close()
+
+	// The recorder is created without its flushing loop, so as to flush it
+	// explicitly.
+	r := &zipkinRecorder{url: ts.URL, client: http.DefaultClient, flushC: make(chan struct{}, 1)}
+
+	start := time.Unix(1, 500000)
+	r.RecordSpan(basictracer.RawSpan{
+		Context:      basictracer.SpanContext{TraceID: 0xabc, SpanID: 2, Sampled: true},
+		ParentSpanID: 1,
+		Operation:    "node 1",
+		Start:        start,
+		Duration:     3 * time.Millisecond,
+		Tags:         opentracing.Tags{"node": 1},
+		Logs:         []opentracing.LogData{{Timestamp: start.Add(time.Millisecond), Event: "read"}},
+	})
+	// Unsampled spans are not sent.
+	r.RecordSpan(basictracer.RawSpan{
+		Context:   basictracer.SpanContext{TraceID: 0xdef, SpanID: 3},
+		Operation: "unsampled",
+	})
+	if err := r.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []zipkinSpan{{
+		TraceID:       "0000000000000abc",
+		ID:            "0000000000000002",
+		ParentID:      "0000000000000001",
+		Name:          "node 1",
+		Timestamp:     1000500,
+		Duration:      3000,
+		LocalEndpoint: zipkinEndpoint{ServiceName: "cockroach"},
+		Annotations:   []zipkinAnnotation{{Timestamp: 1001500, Value: "read"}},
+		Tags:          map[string]string{"node": "1"},
+	}}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %+v, got %+v", expected, received)
+	}
+
+	// Nothing is sent when no span is buffered.
+	received = nil
+	if err := r.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if received != nil {
+		t.Errorf("unexpected spans sent: %+v", received)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	defer leaktest.AfterTest(t)()
+
+	testCases := []struct {
+		rate     float64
+		traceID  uint64
+		expected bool
+	}{
+		{0, 0, false},
+		{1, math.MaxUint64, true},
+		{0.5, 0, true},
+		{0.5, math.MaxUint64 / 4, true},
+		{0.5, math.MaxUint64 / 4 * 3, false},
+	}
+	for _, tc := range testCases {
+		if sampled := makeSampler(tc.rate)(tc.traceID); sampled != tc.expected {
+			t.Errorf("rate %f, trace %x: expected %t, got %t", tc.rate, tc.traceID, tc.expected, sampled)
+		}
+	}
+}